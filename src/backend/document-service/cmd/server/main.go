@@ -21,16 +21,27 @@ import (
 
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/migrations"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
 )
 
 const (
     defaultPort        = ":8080"
     defaultConfigPath  = "./config"
     shutdownTimeout    = 30 * time.Second
-    maxUploadSize     = 50 * 1024 * 1024 // 50MB
 )
 
+// readOnlyRoutes use POST, PUT or DELETE without writing documents and stay
+// available during maintenance
+var readOnlyRoutes = []string{
+    "/api/v1/documents/:id/preview-token",
+    "/api/v1/documents/:id/viewer/events",
+    "/admin/maintenance",
+}
+
 // Prometheus metrics
 var (
     requestDuration = prometheus.NewHistogramVec(
@@ -67,6 +78,15 @@ func main() {
         logger.Fatal("Failed to load configuration", zap.Error(err))
     }
 
+    // Select the crypto mode before any key, TLS or SSH material is used
+    if err := utils.ConfigureCryptoMode(cfg); err != nil {
+        logger.Fatal("Configuration is not compliant with FIPS mode", zap.Error(err))
+    }
+    logger.Info("Crypto mode selected",
+        zap.String("mode", utils.CryptoMode()),
+        zap.Bool("boringcrypto", utils.BoringCrypto()),
+    )
+
     // Initialize metrics
     if err := setupMetrics(); err != nil {
         logger.Fatal("Failed to setup metrics", zap.Error(err))
@@ -77,8 +97,106 @@ func main() {
         logger.Fatal("Failed to setup tracing", zap.Error(err))
     }
 
-    // Initialize storage service
-    storageService, err := services.NewStorageService(cfg)
+    // Report recovered panics to the error tracking service
+    errorReporting, err := services.NewErrorReporting(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize error reporting", zap.Error(err))
+    }
+
+    // Migrate the schema and refuse to serve on one the previous release cannot use.
+    // Background jobs are locked in the database when coordinated across
+    // replicas, and run unconditionally otherwise. With the database, the
+    // state every replica must share is kept there: documents and their
+    // events, documents cached at their written version, shredded data keys,
+    // queued integration events, cancellation sagas, purge notices, upload
+    // nonces, enrollment seals and the key usage audit
+    var migrationRunner *migrations.Runner
+    var jobLocks repository.JobLockRepository = repository.NewMemoryJobLockRepository()
+    var shreddedKeys repository.ShreddedKeyRepository = repository.NewMemoryShreddedKeyRepository()
+    var outboxRepository repository.OutboxRepository = repository.NewMemoryOutboxRepository()
+    var documentCache repository.DocumentCache = repository.NewMemoryDocumentCache()
+    var uploadNonces repository.NonceRepository = repository.NewMemoryNonceRepository()
+    var seals repository.SealRepository = repository.NewMemorySealRepository()
+    var keyUsage repository.KeyUsageRepository = repository.NewMemoryKeyUsageRepository()
+    var documents repository.DocumentRepository = repository.NewMemoryDocumentRepository()
+    var documentEvents repository.DocumentEventRepository = repository.NewMemoryDocumentEventRepository()
+    var cancellationSagas repository.CancellationSagaRepository = repository.NewMemoryCancellationSagaRepository()
+    var purgeNotices repository.PurgeNoticeRepository = repository.NewMemoryPurgeNoticeRepository()
+    if cfg.DatabaseConfig.Enabled {
+        db, err := repository.OpenDatabase(cfg)
+        if err != nil {
+            logger.Fatal("Failed to connect to database", zap.Error(err))
+        }
+        migrationRunner, err = migrations.NewRunner(cfg, db, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize schema migrations", zap.Error(err))
+        }
+        if cfg.DatabaseConfig.Migrations.AutoApply {
+            if err := migrationRunner.Apply(); err != nil {
+                logger.Fatal("Failed to apply schema migrations", zap.Error(err))
+            }
+        }
+        if err := migrationRunner.Gate(); err != nil {
+            logger.Fatal("Refusing to serve on the current schema", zap.Error(err))
+        }
+        if cfg.CoordinationConfig.Enabled {
+            jobLocks = repository.NewPostgresJobLockRepository(db)
+        }
+        shreddedKeys = repository.NewPostgresShreddedKeyRepository(db)
+        outboxRepository = repository.NewPostgresOutboxRepository(db)
+        documentCache = repository.NewPostgresDocumentCache(db)
+        uploadNonces = repository.NewPostgresNonceRepository(db)
+        seals = repository.NewPostgresSealRepository(db)
+        keyUsage = repository.NewPostgresKeyUsageRepository(db)
+        documents = repository.NewPostgresDocumentRepository(db)
+        documentEvents = repository.NewPostgresDocumentEventRepository(db)
+        cancellationSagas = repository.NewPostgresCancellationSagaRepository(db)
+        purgeNotices = repository.NewPostgresPurgeNoticeRepository(db)
+    }
+    utils.SetShreddedKeys(shreddedKeys)
+    jobs, err := services.NewJobCoordinator(cfg, jobLocks, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize job coordination", zap.Error(err))
+    }
+    jobsHandler, err := handlers.NewJobsHandler(jobs, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize job status handler", zap.Error(err))
+    }
+
+    // Initialize feature flags
+    flagSource, err := services.NewFlagSource(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize feature flag provider", zap.Error(err))
+    }
+    featureFlags, err := services.NewFeatureFlags(cfg, flagSource, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize feature flags", zap.Error(err))
+    }
+
+    // Read-only maintenance mode, switched by config, flag or admin API
+    maintenanceMode, err := services.NewMaintenanceMode(cfg, featureFlags, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize maintenance mode", zap.Error(err))
+    }
+    maintenanceHandler, err := handlers.NewMaintenanceHandler(maintenanceMode, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize maintenance handler", zap.Error(err))
+    }
+
+    // Record every data key use before the first one is made
+    var keyAudit *services.KeyAuditService
+    if cfg.KeyAuditConfig.Enabled {
+        keyAudit, err = services.NewKeyAuditService(cfg, keyUsage, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize key usage audit", zap.Error(err))
+        }
+        utils.SetKeyUsageRecorder(keyAudit.Record)
+    }
+
+    // Initialize storage service, bounding the bucket checks like a startup check
+    storageCtx, cancelStorage := context.WithTimeout(context.Background(), cfg.StartupConfig.CheckTimeout)
+    storageService, err := services.NewStorageService(storageCtx, cfg, featureFlags, logger)
+    cancelStorage()
     if err != nil {
         logger.Fatal("Failed to initialize storage service", zap.Error(err))
     }
@@ -89,23 +207,746 @@ func main() {
         logger.Fatal("Failed to initialize OCR service", zap.Error(err))
     }
 
+    // Hold OCR calls under the Azure quota, switching to the fallback
+    // resource before it runs out or while it is the less healthy one
+    var providerHandler *handlers.ProviderHandler
+    providerScoreboard := services.NewProviderScoreboard(cfg, logger)
+    providerQuotas, err := services.NewProviderQuotas(cfg, repository.NewMemoryProviderUsageRepository(), logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize provider quotas", zap.Error(err))
+    }
+    ocrService.UseQuotas(providerQuotas)
+    ocrService.UseScoreboard(providerScoreboard)
+    providerQuotas.UseScoreboard(providerScoreboard)
+    if providerQuotas != nil || providerScoreboard != nil {
+        providerHandler, err = handlers.NewProviderHandler(providerQuotas, providerScoreboard, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize provider handler", zap.Error(err))
+        }
+    }
+
+    // Initialize document repository; every change is recorded as events and
+    // reads are served from the projection of the current state. Written
+    // documents are also cached so clients can read what they wrote before
+    // the projection catches up. Writes breaking the invariants of document
+    // metadata are refused
+    documentHistory := repository.NewEventSourcedDocumentRepository(documentEvents, documents)
+    documentRepository := repository.NewWriteThroughDocumentRepository(repository.NewCheckedDocumentRepository(documentHistory), documentCache, cfg.ConsistencyConfig.CacheTTL)
+
+    // Initialize the upload spool, which buffers uploads on local disk while
+    // object storage is unavailable
+    uploadSpool, err := services.NewUploadSpool(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize upload spool", zap.Error(err))
+    }
+    var spoolDrainer *services.SpoolDrainer
+    if uploadSpool != nil {
+        storageService.UseSpool(uploadSpool)
+        spoolDrainer, err = services.NewSpoolDrainer(cfg, uploadSpool, storageService, documentRepository, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize upload spool drainer", zap.Error(err))
+        }
+    }
+
+    // Initialize enrollment client
+    enrollmentClient, err := services.NewEnrollmentClient(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize enrollment client", zap.Error(err))
+    }
+
+    // Index extracted text for search as keyed tokens, never as text
+    searchIndex, err := services.NewSearchIndex(cfg, repository.NewMemorySearchIndexRepository(), logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize search index", zap.Error(err))
+    }
+
+    // Initialize document pipeline
+    pipelineSteps := []services.PipelineStep{
+        services.NewOCRStep(ocrService, storageService),
+        services.NewHolderNameStep(),
+        services.NewTISSStep(),
+    }
+
+    // Candidate OCR providers compared with the one in service by the OCR
+    // canary; keyed by the provider name used in configuration
+    ocrProviders := map[string]services.OCRProvider{
+        ocrService.Provider(): ocrService,
+    }
+    ocrCanary, err := services.NewOCRCanary(cfg, ocrService, ocrProviders, storageService, documentRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize OCR canary", zap.Error(err))
+    }
+    if ocrCanary != nil {
+        pipelineSteps = append(pipelineSteps, ocrCanary)
+    }
+    if cfg.LanguageConfig.Enabled {
+        var translator services.Translator
+        translationClient, err := services.NewLibreTranslateClient(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize translation client", zap.Error(err))
+        }
+        if translationClient != nil {
+            translator = translationClient
+        }
+        languageStep, err := services.NewLanguageStep(cfg, translator, storageService)
+        if err != nil {
+            logger.Fatal("Failed to initialize language detection", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, languageStep)
+    }
+    if cfg.AccessibilityConfig.Enabled {
+        accessiblePDFStep, err := services.NewAccessiblePDFStep(cfg, storageService)
+        if err != nil {
+            logger.Fatal("Failed to initialize accessible PDF generation", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, accessiblePDFStep)
+    }
+    if cfg.SearchablePDFConfig.Enabled {
+        searchablePDFStep, err := services.NewSearchablePDFStep(cfg, storageService)
+        if err != nil {
+            logger.Fatal("Failed to initialize searchable PDF generation", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, searchablePDFStep)
+    }
+    if cfg.RenditionsConfig.Generate {
+        renditionsStep, err := services.NewRenditionsStep(cfg, storageService)
+        if err != nil {
+            logger.Fatal("Failed to initialize rendition generation", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, renditionsStep)
+    }
+    var searchHandler *handlers.SearchHandler
+    if searchIndex != nil {
+        searchIndexStep, err := services.NewSearchIndexStep(searchIndex)
+        if err != nil {
+            logger.Fatal("Failed to initialize search indexing", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, searchIndexStep)
+        searchHandler, err = handlers.NewSearchHandler(searchIndex, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize search handler", zap.Error(err))
+        }
+    }
+    if cfg.PageCleanupConfig.Enabled {
+        pageCleanupStep, err := services.NewPageCleanupStep(cfg, storageService)
+        if err != nil {
+            logger.Fatal("Failed to initialize page cleanup", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, pageCleanupStep)
+    }
+    if cfg.SignatureConfig.Enabled {
+        signatureStep, err := services.NewSignatureStep(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize signature verification", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, signatureStep)
+    }
+    if cfg.GovBRConfig.Enabled {
+        govbrClient, err := services.NewGovBRClient(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize gov.br client", zap.Error(err))
+        }
+        govbrVerifier, err := services.NewCachingVerifier(cfg, govbrClient)
+        if err != nil {
+            logger.Fatal("Failed to initialize gov.br verifier", zap.Error(err))
+        }
+        govbrStep, err := services.NewGovBRStep(cfg, govbrVerifier)
+        if err != nil {
+            logger.Fatal("Failed to initialize gov.br verification", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, govbrStep)
+    }
+    if cfg.ReceitaConfig.Enabled {
+        cpfBroker, err := services.NewBrokerCPFProvider(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize CPF situation provider", zap.Error(err))
+        }
+        cpfProvider, err := services.NewCachingCPFProvider(cfg, cpfBroker)
+        if err != nil {
+            logger.Fatal("Failed to initialize CPF situation cache", zap.Error(err))
+        }
+        receitaStep, err := services.NewReceitaStep(cpfProvider)
+        if err != nil {
+            logger.Fatal("Failed to initialize CPF situation check", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, receitaStep)
+    }
+    if cfg.AddressConfig.Enabled {
+        viaCEPClient, err := services.NewViaCEPClient(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize ViaCEP client", zap.Error(err))
+        }
+        cepLookup, err := services.NewCachingCEPLookup(cfg, viaCEPClient)
+        if err != nil {
+            logger.Fatal("Failed to initialize CEP cache", zap.Error(err))
+        }
+        addressStep, err := services.NewAddressStep(cepLookup, enrollmentClient, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize address normalization", zap.Error(err))
+        }
+        pipelineSteps = append(pipelineSteps, addressStep)
+    }
+
+    // Candidate step implementations trialled on a share of documents through
+    // experiments; keyed by the implementation name used in configuration.
+    // OCR can be trialled with any of the OCR providers as ocr-<provider>
+    alternativeSteps := services.OCRAlternatives(ocrService, ocrProviders, storageService)
+    pipelineSteps, err = services.ApplyExperiments(cfg, pipelineSteps, alternativeSteps, featureFlags)
+    if err != nil {
+        logger.Fatal("Failed to configure pipeline experiments", zap.Error(err))
+    }
+
+    pipeline, err := services.NewDocumentPipeline(cfg, storageService, documentRepository, featureFlags, logger, pipelineSteps...)
+    if err != nil {
+        logger.Fatal("Failed to initialize document pipeline", zap.Error(err))
+    }
+    // Channels outside the HTTP API are read-only during maintenance too
+    pipeline.UseMaintenance(maintenanceMode)
+    // Keep small uploads off the workers busy with large ones
+    pipeline.UseTiers(services.NewProcessingTiers(cfg))
+
+    // Count documents per tenant without singling out small tenants
+    tenantLabels := services.NewTenantLabels(cfg)
+    pipeline.OnIngested(tenantLabels.OnIngested)
+
+    // Count review time in business hours, off weekends and holidays
+    var calendarHandler *handlers.CalendarHandler
+    businessCalendars, err := services.NewBusinessCalendars(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize business calendars", zap.Error(err))
+    }
+    if businessCalendars != nil {
+        calendarHandler, err = handlers.NewCalendarHandler(businessCalendars, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize calendar handler", zap.Error(err))
+        }
+    }
+
+    // Estimate when documents will be verified from the durations observed
+    // for each step and review; documents join the review queue before any
+    // automatic decision takes them out of it
+    processingETA, err := services.NewETAEstimator(cfg, documentRepository, pipeline, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize processing estimates", zap.Error(err))
+    }
+    processingETA.UseCalendars(businessCalendars)
+    pipeline.UseETA(processingETA)
+    pipeline.OnIngested(processingETA.OnIngested)
+
+    // Defer OCR while its error budget is blown, processing the documents
+    // accepted meanwhile once the provider recovers
+    var sloHandler *handlers.SLOHandler
+    sloMonitor, err := services.NewSLOMonitor(cfg, pipeline, documentRepository, ocrService, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize SLO monitor", zap.Error(err))
+    }
+    pipeline.UseSLO(sloMonitor)
+    if sloMonitor != nil {
+        sloHandler, err = handlers.NewSLOHandler(sloMonitor, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize SLO handler", zap.Error(err))
+        }
+    }
+
+    // Accept uploads past the channel size limit as large documents,
+    // processed in the background
+    largeDocuments, err := services.NewLargeDocuments(cfg, pipeline, documentRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize large documents", zap.Error(err))
+    }
+    pipeline.UseLargeDocuments(largeDocuments)
+
+    // Accept documents encrypted end-to-end to the underwriting team
+    clientEncryption, err := services.NewClientEncryption(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize client-side encryption", zap.Error(err))
+    }
+    pipeline.UseClientEncryption(clientEncryption)
+
+    // Convert office documents to PDF through the LibreOffice sidecar on the
+    // channels configured to
+    documentConverter, err := services.NewSidecarConverter(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize document converter", zap.Error(err))
+    }
+    if documentConverter != nil {
+        pipeline.UseConverter(documentConverter)
+    }
+
+    // Scan uploads with ClamAV, reusing verdicts on identical content
+    virusScanner, err := services.NewVirusScanner(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize virus scanner", zap.Error(err))
+    }
+    if virusScanner != nil {
+        pipeline.UseScanner(virusScanner)
+    }
+
+    // Rebuild PDFs carrying active content from their pages, quarantining
+    // the upload for forensic analysis
+    pdfDisarmer, err := services.NewPDFDisarmer(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize PDF disarmer", zap.Error(err))
+    }
+    if pdfDisarmer != nil {
+        pipeline.UseDisarmer(pdfDisarmer)
+    }
+
+    // Run the pipeline steps as Temporal activities when configured; the
+    // internal backend runs them before the upload returns
+    var temporalOrchestrator *services.TemporalOrchestrator
+    if cfg.OrchestrationConfig.Backend == config.OrchestrationTemporal {
+        temporalOrchestrator, err = services.NewTemporalOrchestrator(cfg, pipeline, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize Temporal orchestration", zap.Error(err))
+        }
+        pipeline.UseOrchestrator(temporalOrchestrator)
+    }
+
     // Initialize document handler
-    documentHandler, err := handlers.NewDocumentHandler(cfg, storageService, ocrService, prometheus.DefaultRegisterer.(*prometheus.Registry), logger)
+    documentHandler, err := handlers.NewDocumentHandler(cfg, storageService, pipeline, documentRepository, featureFlags, prometheus.DefaultRegisterer.(*prometheus.Registry), logger)
     if err != nil {
         logger.Fatal("Failed to initialize document handler", zap.Error(err))
     }
+    documentHandler.UseClientEncryption(clientEncryption)
+
+    // Initialize underwriting integration backed by the outbox
+    underwritingTransport, err := services.NewUnderwritingTransport(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize underwriting transport", zap.Error(err))
+    }
+    underwritingService, err := services.NewUnderwritingService(cfg, documentRepository, outboxRepository,
+        underwritingTransport, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize underwriting service", zap.Error(err))
+    }
+
+    outboxDispatcher, err := services.NewOutboxDispatcher(cfg, outboxRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize outbox dispatcher", zap.Error(err))
+    }
+    outboxDispatcher.Register(services.TopicUnderwritingReady, underwritingService.Deliver)
+
+    // Erase documents by destroying their data keys; the ciphertext is deleted
+    // from the outbox
+    cryptoShredder, err := services.NewCryptoShredder(cfg, storageService, documentRepository, outboxRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize crypto-shredding", zap.Error(err))
+    }
+    cryptoShredder.UseSearchIndex(searchIndex)
+    documentHandler.UseShredder(cryptoShredder)
+    outboxDispatcher.Register(services.TopicStorageGarbage, cryptoShredder.Deliver)
+
+    // Hold malware, disarmed originals and documents suspected of fraud for
+    // review
+    var quarantineHandler *handlers.QuarantineHandler
+    quarantine, err := services.NewQuarantineService(cfg, repository.NewMemoryQuarantineRepository(), documentRepository, storageService, cryptoShredder, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize quarantine", zap.Error(err))
+    }
+    if quarantine != nil {
+        pipeline.UseQuarantine(quarantine)
+        quarantine.UsePipeline(pipeline)
+        quarantineHandler, err = handlers.NewQuarantineHandler(quarantine, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize quarantine handler", zap.Error(err))
+        }
+    }
+
+    // Dispose of the documents of cancelled enrollments, reporting back to
+    // the enrollment service through the outbox
+    var cancellationHandler *handlers.CancellationHandler
+    if cfg.CancellationConfig.Enabled {
+        cancellationService, err := services.NewCancellationService(cfg, cancellationSagas, documentRepository, cryptoShredder, outboxRepository, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize enrollment cancellation cleanup", zap.Error(err))
+        }
+        outboxDispatcher.Register(services.TopicDispositionEvent, cancellationService.Deliver)
+        outboxDispatcher.Register(services.TopicCancellationReport, cancellationService.Deliver)
+        cancellationHandler, err = handlers.NewCancellationHandler(cfg, cancellationService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize cancellation handler", zap.Error(err))
+        }
+    }
+
+    // Seal the document set of enrollments once underwriting closes them
+    var sealHandler *handlers.SealHandler
+    sealService, err := services.NewSealService(cfg, documentRepository, seals, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize enrollment seals", zap.Error(err))
+    }
+    if sealService != nil {
+        sealHandler, err = handlers.NewSealHandler(cfg, sealService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize seal handler", zap.Error(err))
+        }
+    }
+
+    // Notify tenant admins before purging documents past retention
+    var retentionService *services.RetentionService
+    var retentionHandler *handlers.RetentionHandler
+    if cfg.RetentionConfig.Enabled {
+        retentionService, err = services.NewRetentionService(cfg, documentRepository, purgeNotices, cryptoShredder, storageService, outboxRepository, maintenanceMode, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize retention purge", zap.Error(err))
+        }
+        outboxDispatcher.Register(services.TopicPurgeNotice, retentionService.Deliver)
+        retentionService.UseSearchIndex(searchIndex)
+        retentionHandler, err = handlers.NewRetentionHandler(retentionService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize retention handler", zap.Error(err))
+        }
+    }
+
+    // Let tenant admins manage webhooks announcing their documents' events
+    var webhookHandler *handlers.WebhookHandler
+    tenantWebhooks, err := services.NewTenantWebhooks(cfg, repository.NewMemoryWebhookRepository(), outboxRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize tenant webhooks", zap.Error(err))
+    }
+    if tenantWebhooks != nil {
+        outboxDispatcher.Register(services.TopicTenantWebhook, tenantWebhooks.Deliver)
+        pipeline.OnIngested(tenantWebhooks.OnIngested)
+        webhookHandler, err = handlers.NewWebhookHandler(tenantWebhooks, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize webhook handler", zap.Error(err))
+        }
+    }
+
+    // Authenticate machine integrations with API keys issued by admins
+    var apiKeyHandler *handlers.APIKeyHandler
+    apiKeys, err := services.NewAPIKeys(cfg, repository.NewMemoryAPIKeyRepository(), documentRepository, enrollmentClient, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize API keys", zap.Error(err))
+    }
+    if apiKeys != nil {
+        apiKeyHandler, err = handlers.NewAPIKeyHandler(apiKeys, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize API key handler", zap.Error(err))
+        }
+    }
+
+    // Accept calls services make on behalf of end users
+    delegatedTokens, err := services.NewDelegatedTokens(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize delegated tokens", zap.Error(err))
+    }
+
+    // Identify end users and their roles from the token the gateway forwards
+    gatewayTokens, err := services.NewGatewayTokens(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize gateway tokens", zap.Error(err))
+    }
+
+    // Give internal callers their own least-privilege credentials
+    serviceAccounts, err := services.NewServiceAccounts(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize service accounts", zap.Error(err))
+    }
+
+    // Authenticate workloads by their SPIFFE SVIDs over mutual TLS
+    workloadIdentity, err := services.NewWorkloadIdentity(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize SPIFFE workload identity", zap.Error(err))
+    }
+
+    // Probe the document path end to end with a self-cleaning test document
+    var probeHandler *handlers.ProbeHandler
+    syntheticProbe, err := services.NewSyntheticProbe(cfg, pipeline, documentRepository, storageService, cryptoShredder, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize synthetic probe", zap.Error(err))
+    }
+    if syntheticProbe != nil {
+        probeHandler, err = handlers.NewProbeHandler(syntheticProbe, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize probe handler", zap.Error(err))
+        }
+    }
+
+    // Run an SLA clock per document from upload to the final review decision
+    slaTracker, err := services.NewSLATracker(cfg, documentRepository, tenantWebhooks, maintenanceMode, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize SLA tracker", zap.Error(err))
+    }
+    slaTracker.UseCalendars(businessCalendars)
+
+    // Initialize document review
+    reviewService, err := services.NewReviewService(cfg, documentRepository, storageService, underwritingService, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize review service", zap.Error(err))
+    }
+    reviewService.UseETA(processingETA)
+    reviewService.UseWebhooks(tenantWebhooks)
+    reviewService.UseSLA(slaTracker)
+    reviewQueue, err := services.NewReviewQueue(cfg, reviewService, documentRepository, repository.NewMemorySavedFilterRepository(), logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize review queue", zap.Error(err))
+    }
+    reviewHandler, err := handlers.NewReviewHandler(reviewService, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize review handler", zap.Error(err))
+    }
+    reviewHandler.UseQueue(reviewQueue)
+    reviewHandler.UseSLA(slaTracker)
+
+    // Initialize sanctions and PEP screening, run asynchronously through the outbox
+    var screeningService *services.ScreeningService
+    if cfg.ScreeningConfig.Enabled {
+        screeningProvider, err := services.NewRESTScreeningProvider(cfg)
+        if err != nil {
+            logger.Fatal("Failed to initialize screening provider", zap.Error(err))
+        }
+        screeningService, err = services.NewScreeningService(cfg, documentRepository, outboxRepository, screeningProvider, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize screening service", zap.Error(err))
+        }
+        screeningService.UseScoreboard(providerScoreboard)
+        pipeline.OnIngested(screeningService.OnIngested)
+        outboxDispatcher.Register(services.TopicScreeningRequested, screeningService.Deliver)
+    }
+
+    // Initialize rules based auto-decisions, re-evaluated once screening completes
+    if cfg.AutoDecisionConfig.Enabled {
+        autoDecisionService, err := services.NewAutoDecisionService(cfg, documentRepository, enrollmentClient, reviewService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize auto-decision rules", zap.Error(err))
+        }
+        pipeline.OnIngested(autoDecisionService.Evaluate)
+        if screeningService != nil {
+            screeningService.OnScreened(autoDecisionService.Evaluate)
+        }
+    }
+
+    // Initialize WhatsApp ingestion
+    var whatsappHandler *handlers.WhatsAppHandler
+    if cfg.WhatsAppConfig.Enabled {
+        whatsappService, err := services.NewWhatsAppService(cfg, pipeline, enrollmentClient, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize WhatsApp service", zap.Error(err))
+        }
+        whatsappHandler, err = handlers.NewWhatsAppHandler(cfg, whatsappService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize WhatsApp handler", zap.Error(err))
+        }
+    }
+
+    // Halt processing when subjects revoke consent
+    var consentHandler *handlers.ConsentHandler
+    if cfg.ConsentConfig.Enabled {
+        consentRegistry := services.NewConsentRegistry()
+        pipeline.UseConsent(consentRegistry)
+        consentService, err := services.NewConsentService(consentRegistry, documentRepository, enrollmentClient, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize consent service", zap.Error(err))
+        }
+        consentHandler, err = handlers.NewConsentHandler(cfg, consentService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize consent handler", zap.Error(err))
+        }
+    }
+
+    // Issue signed receipts of access to sensitive documents
+    downloadReceipts, err := services.NewDownloadReceipts(cfg, repository.NewMemoryReceiptRepository(), outboxRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize download receipts", zap.Error(err))
+    }
+    documentHandler.UseReceipts(downloadReceipts)
+    if downloadReceipts != nil {
+        outboxDispatcher.Register(services.TopicReceiptIssued, downloadReceipts.Deliver)
+    }
+
+    // Record the view, print and export events reported by the portal's
+    // secure viewer in the audit trail
+    accessEvents, err := services.NewAccessEvents(cfg, documentRepository, repository.NewMemoryNonceRepository(), logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize access events", zap.Error(err))
+    }
+    documentHandler.UseAccessEvents(accessEvents)
+
+    // Serve extracted text by role, redacted for roles without full access
+    documentHandler.UseTextAccess(services.NewTextAccess(cfg, storageService))
+    documentHandler.UseHistory(documentHistory)
+
+    // Serve reads at the version a write returned
+    consistentReads, err := services.NewConsistentReads(cfg, documentRepository, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize consistent reads", zap.Error(err))
+    }
+    documentHandler.UseConsistentReads(consistentReads)
+    documentHandler.UseETA(processingETA)
+    documentHandler.UseSLA(slaTracker)
+
+    // Let support staff act on behalf of beneficiaries, notifying them afterwards
+    var impersonationService *services.ImpersonationService
+    var impersonationHandler *handlers.ImpersonationHandler
+    if cfg.ImpersonationConfig.Enabled {
+        impersonationService, err = services.NewImpersonationService(cfg, outboxRepository, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize impersonation service", zap.Error(err))
+        }
+        outboxDispatcher.Register(services.TopicImpersonationEnded, impersonationService.Deliver)
+        impersonationHandler, err = handlers.NewImpersonationHandler(impersonationService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize impersonation handler", zap.Error(err))
+        }
+    }
+
+    // Initialize LGPD portability exports
+    var portabilityHandler *handlers.PortabilityHandler
+    if cfg.PortabilityConfig.Enabled {
+        portabilityService, err := services.NewPortabilityService(cfg, documentRepository, storageService, enrollmentClient, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize portability service", zap.Error(err))
+        }
+        portabilityHandler, err = handlers.NewPortabilityHandler(portabilityService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize portability handler", zap.Error(err))
+        }
+    }
+
+    // Validate critical dependencies in order before reporting ready
+    warmup, err := services.NewWarmup(cfg, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize startup checks", zap.Error(err))
+    }
+    warmup.Add("feature_flags", false, featureFlags.Refresh)
+    warmup.Add("kms_data_key", true, func(ctx context.Context) error {
+        return utils.WarmEncryptionKey(ctx, cfg)
+    })
+    // With a spool, uploads are accepted while object storage is unavailable
+    warmup.Add("object_storage", spoolDrainer == nil, storageService.Warm)
+    warmup.Add("azure_ocr", true, ocrService.Ping)
+    if documentConverter != nil {
+        // Only office uploads need the converter, the rest are accepted without it
+        warmup.Add("document_converter", false, documentConverter.Ping)
+    }
+    if virusScanner != nil {
+        // Every upload is scanned, so none is accepted without the scanner
+        warmup.Add("virus_scanner", true, virusScanner.Ping)
+    }
+    warmup.Add("eta_history", false, processingETA.Load)
+    healthHandler, err := handlers.NewHealthHandler(warmup, maintenanceMode, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize health handler", zap.Error(err))
+    }
+
+    // Initialize operational endpoints
+    ropaService, err := services.NewROPAService(cfg, documentRepository)
+    if err != nil {
+        logger.Fatal("Failed to initialize processing activities report", zap.Error(err))
+    }
+    var encryptionScanner *services.EncryptionScanner
+    if cfg.EncryptionScanConfig.Enabled {
+        encryptionScanner, err = services.NewEncryptionScanner(cfg, documentRepository, storageService, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize encryption scanner", zap.Error(err))
+        }
+    }
+    bulkOperations, err := services.NewBulkOperations(cfg, documentRepository, repository.NewMemoryBulkOperationRepository(), pipeline, storageService, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize bulk operations", zap.Error(err))
+    }
+    metadataRepair, err := services.NewMetadataRepair(cfg, documentRepository, storageService, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize metadata repair", zap.Error(err))
+    }
+    operationsHandler, err := handlers.NewOperationsHandler(bulkOperations, metadataRepair, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize bulk operations handler", zap.Error(err))
+    }
+    adminHandler, err := handlers.NewAdminHandler(cfg, migrationRunner, ropaService, encryptionScanner, keyAudit, downloadReceipts, ocrCanary, slaTracker, documentHistory, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize admin handler", zap.Error(err))
+    }
+
+    // Ban clients guessing preview tokens and export links, and challenge
+    // anonymous uploads and reject their replays
+    abuseGuard := services.NewAbuseGuard(cfg, logger)
+    captchaVerifier, err := services.NewCaptchaVerifier(cfg)
+    if err != nil {
+        logger.Fatal("Failed to initialize CAPTCHA verification", zap.Error(err))
+    }
+    replayGuard, err := services.NewReplayGuard(cfg, uploadNonces, logger)
+    if err != nil {
+        logger.Fatal("Failed to initialize upload replay protection", zap.Error(err))
+    }
+
+    // Soft quotas with burst credits for brokers
+    softQuotas := services.NewSoftQuotas(cfg, logger)
+    softQuotas.UseTenantLabels(tenantLabels)
+    var quotaHandler *handlers.QuotaHandler
+    if softQuotas != nil {
+        quotaHandler, err = handlers.NewQuotaHandler(softQuotas, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize quota handler", zap.Error(err))
+        }
+    }
+
+    // Shape downloads by role so one user cannot saturate egress
+    bandwidthShaper := services.NewBandwidthShaper(cfg)
+
+    // Background jobs run until shutdown is requested
+    jobsCtx, stopJobs := context.WithCancel(context.Background())
+    defer stopJobs()
 
     // Initialize Gin router
     gin.SetMode(gin.ReleaseMode)
     router := gin.New()
-    router = setupRouter(router, documentHandler)
+    if err := handlers.TrustProxies(router, cfg); err != nil {
+        logger.Fatal("Failed to configure trusted proxies", zap.Error(err))
+    }
+    router = setupRouter(router, routeHandlers{
+        documents:     documentHandler,
+        review:        reviewHandler,
+        whatsapp:      whatsappHandler,
+        consent:       consentHandler,
+        cancellation:  cancellationHandler,
+        seal:          sealHandler,
+        retention:     retentionHandler,
+        webhooks:      webhookHandler,
+        portability:   portabilityHandler,
+        impersonation: impersonationHandler,
+        search:        searchHandler,
+        admin:         adminHandler,
+        operations:    operationsHandler,
+        quota:         quotaHandler,
+        maintenance:   maintenanceHandler,
+        jobs:          jobsHandler,
+        probe:         probeHandler,
+        slo:           sloHandler,
+        calendar:      calendarHandler,
+        providers:     providerHandler,
+        apiKeys:       apiKeyHandler,
+        quarantine:    quarantineHandler,
+        adminAuth:     handlers.AdminAuth(cfg.AdminConfig.Token, logger),
+        accountAuth:   handlers.AuthenticateServiceAccount(serviceAccounts, workloadIdentity, logger),
+        serviceAuth:   handlers.RequireSignedRequest(services.NewRequestSigner(cfg), logger),
+        abuse:         abuseGuard,
+        captcha:       handlers.RequireCaptcha(captchaVerifier, logger),
+        replay:        handlers.RejectReplayedUploads(replayGuard, logger),
+        apiKey:        handlers.AuthenticateAPIKey(apiKeys, logger),
+        delegate:      handlers.AcceptDelegatedToken(delegatedTokens, logger),
+        gatewayUser:   handlers.AuthenticateGatewayUser(gatewayTokens, logger),
+        impersonate:   handlers.Impersonate(impersonationService, logger),
+        accessLog:     handlers.AccessLog(cfg.AccessLogConfig, logger),
+        enforceQuota:  handlers.EnforceQuota(softQuotas, logger),
+        degradation:   handlers.SignalDegradation(sloMonitor),
+        readOnly:      handlers.RejectWritesInMaintenance(maintenanceMode, logger, readOnlyRoutes...),
+        shape:         handlers.ShapeDownloads(bandwidthShaper),
+        retryBudget:   handlers.BudgetRetries(cfg.RetryBudgetConfig),
+        recovery:      handlers.Recover(errorReporting, logger),
+        health:        healthHandler,
+        limits: func(group string) gin.HandlerFunc {
+            return handlers.LimitRequest(cfg.ServiceConfig, group, logger)
+        },
+    })
 
-    // Configure server
+    // Configure server; route groups tighten these timeouts per request
     srv := &http.Server{
         Addr:         cfg.ServiceConfig.Port,
         Handler:      router,
-        ReadTimeout:  cfg.ServiceConfig.RequestTimeout,
-        WriteTimeout: cfg.ServiceConfig.RequestTimeout,
+        ReadTimeout:  cfg.ServiceConfig.LongestTimeout(),
+        WriteTimeout: cfg.ServiceConfig.LongestTimeout(),
         IdleTimeout:  cfg.ServiceConfig.RequestTimeout * 2,
     }
 
@@ -117,6 +958,165 @@ func main() {
         }
     }()
 
+    // Serve workloads on a mutual TLS listener that requires their SVID
+    var mtlsSrv *http.Server
+    if workloadIdentity != nil {
+        mtlsSrv = &http.Server{
+            Addr:         cfg.SPIFFEConfig.Address,
+            Handler:      router,
+            TLSConfig:    workloadIdentity.TLSConfig(),
+            ReadTimeout:  cfg.ServiceConfig.LongestTimeout(),
+            WriteTimeout: cfg.ServiceConfig.LongestTimeout(),
+            IdleTimeout:  cfg.ServiceConfig.RequestTimeout * 2,
+        }
+        go func() {
+            logger.Info("Starting mutual TLS server", zap.String("address", cfg.SPIFFEConfig.Address))
+            if err := mtlsSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+                logger.Fatal("Failed to start mutual TLS server", zap.Error(err))
+            }
+        }()
+    }
+
+    // Probes answer while warming up; API routes wait for readiness and a
+    // failed critical check stops the process
+    if err := warmup.Run(jobsCtx); err != nil {
+        logger.Fatal("Startup dependency check failed", zap.Error(err))
+    }
+    logger.Info("Startup checks passed, service ready")
+
+    // Start outbox delivery; the jobs below that act on shared state run on
+    // one replica at a time
+    go jobs.Run(jobsCtx, models.JobOutbox, outboxDispatcher.Run)
+    go featureFlags.Run(jobsCtx)
+
+    // Send error reports until the server has drained, so panics raised
+    // while shutting down are reported too
+    reportingCtx, stopReporting := context.WithCancel(context.Background())
+    defer stopReporting()
+    reportingDone := make(chan struct{})
+    if errorReporting != nil {
+        go func() {
+            errorReporting.Run(reportingCtx)
+            close(reportingDone)
+        }()
+    }
+
+    // Process pipeline workflows
+    if temporalOrchestrator != nil {
+        go temporalOrchestrator.Run(jobsCtx)
+    }
+
+    // Start SFTP batch ingestion
+    if cfg.SFTPConfig.Enabled {
+        sftpIngestor, err := services.NewSFTPIngestor(cfg, pipeline, enrollmentClient, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize SFTP ingestor", zap.Error(err))
+        }
+        go jobs.Run(jobsCtx, models.JobSFTP, sftpIngestor.Run)
+    }
+
+    // Start the anonymized analytics export
+    if cfg.AnalyticsExportConfig.Enabled {
+        analyticsCtx, cancelAnalytics := context.WithTimeout(context.Background(), cfg.StartupConfig.CheckTimeout)
+        analyticsExporter, err := services.NewAnalyticsExporter(analyticsCtx, cfg, documentRepository, logger)
+        cancelAnalytics()
+        if err != nil {
+            logger.Fatal("Failed to initialize analytics export", zap.Error(err))
+        }
+        go jobs.Run(jobsCtx, models.JobAnalytics, analyticsExporter.Run)
+    }
+
+    // Start the encryption-at-rest scanner
+    if encryptionScanner != nil {
+        go jobs.Run(jobsCtx, models.JobEncryptionScan, encryptionScanner.Run)
+    }
+
+    // Drain the upload spool; each instance drains its own disk
+    if spoolDrainer != nil {
+        go spoolDrainer.Run(jobsCtx)
+    }
+
+    // Issue download receipts once their session goes idle
+    go downloadReceipts.Run(jobsCtx)
+
+    // Forget expired access event nonces and finished anomaly windows
+    go accessEvents.Run(jobsCtx)
+
+    // Drop documents from the write-through cache once they expire
+    go consistentReads.Run(jobsCtx)
+
+    // Follow the review queue of every instance for processing estimates
+    go processingETA.Run(jobsCtx)
+
+    // Expire impersonation sessions and notify their beneficiaries
+    if impersonationService != nil {
+        go impersonationService.Run(jobsCtx)
+    }
+
+    // Notify tenant admins of upcoming purges and purge expired documents
+    if retentionService != nil {
+        go jobs.Run(jobsCtx, models.JobRetention, retentionService.Run)
+    }
+
+    // Probe the document path on schedule
+    if syntheticProbe != nil {
+        go jobs.Run(jobsCtx, models.JobSyntheticProbe, syntheticProbe.Run)
+    }
+
+    // Escalate documents passing their SLA thresholds undecided
+    if slaTracker != nil {
+        go jobs.Run(jobsCtx, models.JobSLA, slaTracker.Run)
+    }
+
+    // Try the OCR provider while OCR is deferred, and process the documents
+    // deferred once it recovers
+    go sloMonitor.Run(jobsCtx)
+    if sloMonitor != nil {
+        go jobs.Run(jobsCtx, models.JobDeferredOCR, sloMonitor.Drain)
+    }
+
+    // Process large documents queued for the background
+    if largeDocuments != nil {
+        go jobs.Run(jobsCtx, models.JobLargeDocuments, largeDocuments.Run)
+    }
+
+    // Stop bulk operations on shutdown
+    go bulkOperations.Run(jobsCtx)
+
+    // Forget expired abuse failures and bans, and expired upload nonces
+    go abuseGuard.Run(jobsCtx)
+    go replayGuard.Run(jobsCtx)
+
+    // Forget tenants idle with full burst credits
+    go softQuotas.Run(jobsCtx)
+
+    // Forget the download buckets of idle users
+    go bandwidthShaper.Run(jobsCtx)
+
+    // Drop expired data keys from the key caches
+    go utils.RunKeyCacheSweep(jobsCtx)
+
+    // Expire key usage events past retention
+    if keyAudit != nil {
+        go jobs.Run(jobsCtx, models.JobKeyAudit, keyAudit.Run)
+    }
+
+    // Repair objects diverged during the storage migration and backfill the
+    // objects one backend lacks
+    if shadowStore := storageService.Shadow(); shadowStore != nil {
+        go shadowStore.Run(jobsCtx)
+        shadowBackfill, err := services.NewShadowBackfill(cfg, documentRepository, shadowStore, logger)
+        if err != nil {
+            logger.Fatal("Failed to initialize shadow storage backfill", zap.Error(err))
+        }
+        go jobs.Run(jobsCtx, models.JobShadowBackfill, shadowBackfill.Run)
+    }
+
+    // Pick up the SVIDs SPIRE rotates
+    if workloadIdentity != nil {
+        go workloadIdentity.Run(jobsCtx)
+    }
+
     // Wait for interrupt signal
     quit := make(chan os.Signal, 1)
     signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -124,19 +1124,89 @@ func main() {
 
     // Graceful shutdown
     logger.Info("Shutting down server...")
+    stopJobs()
     ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
     defer cancel()
 
+    if mtlsSrv != nil {
+        if err := mtlsSrv.Shutdown(ctx); err != nil {
+            logger.Error("Mutual TLS server forced to shutdown", zap.Error(err))
+        }
+    }
     if err := gracefulShutdown(srv, ctx); err != nil {
         logger.Error("Server forced to shutdown", zap.Error(err))
     }
+    if errorReporting != nil {
+        stopReporting()
+        select {
+        case <-reportingDone:
+        case <-ctx.Done():
+        }
+    }
 
     logger.Info("Server exited")
 }
 
-func setupRouter(router *gin.Engine, handler *handlers.DocumentHandler) *gin.Engine {
+// routeHandlers groups the HTTP handlers mounted by setupRouter; optional
+// integrations are nil when disabled
+type routeHandlers struct {
+    documents     *handlers.DocumentHandler
+    review        *handlers.ReviewHandler
+    whatsapp      *handlers.WhatsAppHandler
+    consent       *handlers.ConsentHandler
+    cancellation  *handlers.CancellationHandler
+    seal          *handlers.SealHandler
+    retention     *handlers.RetentionHandler
+    webhooks      *handlers.WebhookHandler
+    portability   *handlers.PortabilityHandler
+    impersonation *handlers.ImpersonationHandler
+    search        *handlers.SearchHandler
+    admin         *handlers.AdminHandler
+    operations    *handlers.OperationsHandler
+    quota         *handlers.QuotaHandler
+    maintenance   *handlers.MaintenanceHandler
+    jobs          *handlers.JobsHandler
+    probe         *handlers.ProbeHandler
+    slo           *handlers.SLOHandler
+    calendar      *handlers.CalendarHandler
+    providers     *handlers.ProviderHandler
+    apiKeys       *handlers.APIKeyHandler
+    quarantine    *handlers.QuarantineHandler
+    adminAuth     gin.HandlerFunc
+    accountAuth   gin.HandlerFunc
+    serviceAuth   gin.HandlerFunc
+    abuse         *services.AbuseGuard
+    captcha       gin.HandlerFunc
+    replay        gin.HandlerFunc
+    apiKey        gin.HandlerFunc
+    delegate      gin.HandlerFunc
+    gatewayUser   gin.HandlerFunc
+    impersonate   gin.HandlerFunc
+    accessLog     gin.HandlerFunc
+    enforceQuota  gin.HandlerFunc
+    retryBudget   gin.HandlerFunc
+    recovery      gin.HandlerFunc
+    // degradation signals deferred OCR to clients
+    degradation   gin.HandlerFunc
+    // readOnly refuses writes during maintenance
+    readOnly      gin.HandlerFunc
+    // shape limits download bandwidth by role
+    shape         gin.HandlerFunc
+    health        *handlers.HealthHandler
+    // limits returns the body size and timeout middleware of a route group
+    limits        func(group string) gin.HandlerFunc
+}
+
+func setupRouter(router *gin.Engine, h routeHandlers) *gin.Engine {
+    // Access log, outside recovery so requests that panicked are logged
+    // with their 500
+    router.Use(h.accessLog)
+
     // Recovery middleware
-    router.Use(gin.Recovery())
+    router.Use(h.recovery)
+
+    // One pool of retries per request, shared by every layer
+    router.Use(h.retryBudget)
 
     // Rate limiting middleware
     limiter := rate.NewLimiter(rate.Limit(100), 200)
@@ -150,7 +1220,7 @@ func setupRouter(router *gin.Engine, handler *handlers.DocumentHandler) *gin.Eng
 
     // Request ID middleware
     router.Use(func(c *gin.Context) {
-        c.Writer.Header().Set("X-Request-ID", c.GetString("request_id"))
+        c.Writer.Header().Set(handlers.RequestIDHeader, c.GetString("request_id"))
         c.Next()
     })
 
@@ -176,18 +1246,195 @@ func setupRouter(router *gin.Engine, handler *handlers.DocumentHandler) *gin.Eng
     })
 
     // Configure routes
-    api := router.Group("/api/v1")
+    api := router.Group("/api/v1", h.health.RequireReady, h.accountAuth, h.apiKey, h.delegate, h.gatewayUser, h.impersonate, handlers.IdentifyPrincipal(), h.enforceQuota, h.readOnly, h.degradation)
     {
         // Document operations
-        api.POST("/documents", handler.UploadDocument)
-        api.GET("/documents/:id", handler.DownloadDocument)
-        api.DELETE("/documents/:id", handler.DeleteDocument)
+        uploads := api.Group("", h.limits(config.RouteGroupUpload))
+        uploads.POST("/documents", h.replay, h.captcha, h.documents.UploadDocument)
+        uploads.POST("/documents/compose", h.replay, h.captcha, h.documents.ComposeDocument)
+        uploads.PUT("/documents/:id/pages/:page", h.review.ReplacePage)
+
+        downloads := api.Group("", h.limits(config.RouteGroupDownload), h.shape)
+        downloads.GET("/documents/:id", h.documents.DownloadDocument)
+        downloads.GET("/documents/:id/renditions/:name", h.documents.DownloadRendition)
+        downloads.GET("/documents/:id/preview", handlers.GuardTokenEndpoint(h.abuse, services.AbuseEndpointPreview), h.documents.Preview)
+        downloads.GET("/documents/:id/viewer/pages/:page", h.documents.ViewerPage)
+        downloads.GET("/documents/:id/history", h.documents.GetHistory)
+
+        documents := api.Group("", h.limits(config.RouteGroupAPI))
+        documents.GET("/client-encryption/key", h.documents.ClientEncryptionKey)
+        documents.POST("/documents/:id/preview-token", h.documents.CreatePreviewToken)
+        documents.GET("/documents/:id/viewer", h.documents.ViewerInfo)
+        documents.GET("/documents/:id/text", h.documents.GetText)
+        documents.GET("/documents/:id/provenance", h.documents.GetProvenance)
+        documents.GET("/documents/:id/status", h.documents.GetStatus)
+        documents.GET("/documents/:id/status/stream", h.documents.StreamStatus)
+        documents.POST("/documents/:id/viewer/events", h.documents.ViewerEvent)
+        documents.POST("/documents/:id/access-events", h.documents.AccessEvent)
+        documents.DELETE("/documents/:id", h.documents.DeleteDocument)
+        documents.POST("/documents/:id/reprocess", h.documents.ReprocessDocument)
+        documents.GET("/documents/:id/review", h.review.GetReviewDocument)
+        documents.POST("/documents/:id/review", h.review.ReviewDocument)
+        documents.POST("/documents/:id/review/pages", h.review.ReviewPages)
+        documents.POST("/documents/:id/pages/operations", h.review.EditPages)
+        documents.GET("/enrollments/:id/checklist", h.review.GetChecklist)
+        documents.GET("/reviews/pending", h.review.ListPending)
+        documents.GET("/reviews/filters", h.review.ListFilters)
+        documents.POST("/reviews/filters", h.review.SaveFilter)
+        documents.DELETE("/reviews/filters/:id", h.review.DeleteFilter)
+        documents.POST("/reviews/bulk", h.review.BulkReview)
+        documents.GET("/enrollments/:id/timeline", h.documents.GetEnrollmentTimeline)
+
+        // LGPD portability exports
+        if h.portability != nil {
+            documents.POST("/subjects/:cpf/portability-export", h.portability.CreateExport)
+            downloads.GET("/exports/:id/download", handlers.GuardTokenEndpoint(h.abuse, services.AbuseEndpointExport), h.portability.DownloadExport)
+        }
+
+        // Support impersonation sessions
+        if h.impersonation != nil {
+            documents.POST("/impersonations", h.impersonation.StartImpersonation)
+            documents.GET("/impersonations/:id", h.impersonation.GetImpersonation)
+            documents.DELETE("/impersonations/:id", h.impersonation.EndImpersonation)
+        }
+
+        // Purge notices for tenant admins
+        if h.search != nil {
+            documents.GET("/search", h.search.Search)
+        }
+        if h.retention != nil {
+            documents.GET("/retention/notices", h.retention.ListNotices)
+            documents.GET("/retention/notices/:id", h.retention.GetNotice)
+            documents.POST("/retention/notices/:id/acknowledge", h.retention.AcknowledgeNotice)
+            documents.DELETE("/documents/:id/hold", h.retention.ReleaseHold)
+        }
+
+        // Webhook subscriptions of the caller's tenant
+        if h.webhooks != nil {
+            documents.GET("/webhook-events", h.webhooks.ListEvents)
+            documents.GET("/webhooks", h.webhooks.ListWebhooks)
+            documents.POST("/webhooks", h.webhooks.CreateWebhook)
+            documents.GET("/webhooks/:id", h.webhooks.GetWebhook)
+            documents.PUT("/webhooks/:id", h.webhooks.UpdateWebhook)
+            documents.DELETE("/webhooks/:id", h.webhooks.DeleteWebhook)
+            documents.POST("/webhooks/:id/pause", h.webhooks.PauseWebhook)
+            documents.POST("/webhooks/:id/resume", h.webhooks.ResumeWebhook)
+            documents.POST("/webhooks/:id/rotate-secret", h.webhooks.RotateSecret)
+            documents.POST("/webhooks/:id/test", h.webhooks.TestWebhook)
+            documents.GET("/webhooks/:id/deliveries", h.webhooks.ListDeliveries)
+        }
+
+        // Enrollment seals and their verification
+        if h.seal != nil {
+            documents.GET("/enrollments/:id/seal", h.seal.GetSeal)
+            documents.GET("/enrollments/:id/seal/verify", h.seal.VerifySeal)
+        }
+
+        // Soft quota usage of the caller's tenant
+        if h.quota != nil {
+            documents.GET("/quota", h.quota.GetUsage)
+        }
+    }
+
+    // Ingestion channel webhooks
+    webhooks := router.Group("/webhooks", h.health.RequireReady, h.limits(config.RouteGroupWebhook), h.readOnly)
+    if h.whatsapp != nil {
+        webhooks.GET("/whatsapp", h.whatsapp.VerifyWebhook)
+        webhooks.POST("/whatsapp", h.whatsapp.ReceiveWebhook)
+    }
+
+    // Consent service events
+    if h.consent != nil {
+        webhooks.POST("/consent", h.serviceAuth, h.consent.ReceiveEvent)
+    }
+
+    // Enrollment service events
+    if h.cancellation != nil {
+        webhooks.POST("/enrollment", h.serviceAuth, h.cancellation.ReceiveEvent)
+    }
+
+    // Underwriting service events
+    if h.seal != nil {
+        webhooks.POST("/underwriting", h.serviceAuth, h.seal.ReceiveEvent)
+    }
+
+    // Operational endpoints
+    admin := router.Group("/admin", h.limits(config.RouteGroupAdmin), h.accountAuth, h.adminAuth, h.readOnly)
+    {
+        admin.GET("/maintenance", h.maintenance.GetMaintenance)
+        admin.PUT("/maintenance", h.maintenance.SetMaintenance)
+        admin.DELETE("/maintenance", h.maintenance.ClearMaintenance)
+        admin.GET("/config", h.admin.GetConfig)
+        admin.GET("/migrations", h.admin.GetMigrations)
+        admin.POST("/migrations/contract", h.admin.ApplyContractMigrations)
+        admin.GET("/jobs", h.jobs.GetJobs)
+        admin.GET("/ropa", h.admin.GetROPA)
+        admin.GET("/encryption-scan", h.admin.GetEncryptionScan)
+        admin.POST("/encryption-scan", h.admin.RunEncryptionScan)
+        admin.POST("/documents/:id/reencrypt", h.admin.ReencryptDocument)
+        admin.GET("/documents/:id/events", h.admin.GetDocumentEvents)
+        admin.POST("/documents/:id/rebuild", h.admin.RebuildDocument)
+        admin.POST("/projections/rebuild", h.admin.RebuildProjections)
+        admin.GET("/key-usage", h.admin.GetKeyUsage)
+        admin.GET("/key-usage/events", h.admin.ListKeyUsageEvents)
+        admin.GET("/download-receipts", h.admin.ListDownloadReceipts)
+        admin.GET("/download-receipts/key", h.admin.GetReceiptKey)
+        admin.GET("/download-receipts/:id", h.admin.GetDownloadReceipt)
+        admin.GET("/ocr-canary", h.admin.GetOCRCanary)
+        admin.GET("/sla", h.admin.GetSLAReport)
+        admin.POST("/operations", h.operations.StartOperation)
+        admin.GET("/operations", h.operations.ListOperations)
+        admin.GET("/operations/:id", h.operations.GetOperation)
+        admin.POST("/operations/:id/cancel", h.operations.CancelOperation)
+        admin.POST("/operations/:id/resume", h.operations.ResumeOperation)
+        admin.POST("/repair", h.operations.RepairDocuments)
+        if h.quota != nil {
+            admin.GET("/quotas", h.quota.ListUsage)
+        }
+        if h.seal != nil {
+            admin.GET("/enrollment-seals/key", h.seal.GetKey)
+        }
+        if h.probe != nil {
+            admin.POST("/probe", h.probe.RunProbe)
+            admin.GET("/probe", h.probe.GetProbe)
+        }
+        if h.slo != nil {
+            admin.GET("/slo", h.slo.GetSLO)
+        }
+        if h.calendar != nil {
+            admin.GET("/calendars", h.calendar.ListCalendars)
+            admin.GET("/calendars/deadline", h.calendar.PreviewDeadline)
+        }
+        if h.providers != nil {
+            admin.GET("/providers", h.providers.GetProviders)
+        }
+        if h.apiKeys != nil {
+            admin.POST("/api-keys", h.apiKeys.IssueKey)
+            admin.GET("/api-keys", h.apiKeys.ListKeys)
+            admin.GET("/api-keys/:id", h.apiKeys.GetKey)
+            admin.POST("/api-keys/:id/rotate", h.apiKeys.RotateKey)
+            admin.POST("/api-keys/:id/revoke", h.apiKeys.RevokeKey)
+        }
+        if h.quarantine != nil {
+            admin.GET("/quarantine", h.quarantine.ListItems)
+            admin.POST("/quarantine", h.quarantine.HoldDocument)
+            admin.GET("/quarantine/:id", h.quarantine.GetItem)
+            admin.GET("/quarantine/:id/content", h.quarantine.FetchItem)
+            admin.POST("/quarantine/:id/release", h.quarantine.ReleaseItem)
+            admin.POST("/quarantine/:id/destroy", h.quarantine.DestroyItem)
+        }
+        if h.cancellation != nil {
+            admin.GET("/cancellations/:id", h.cancellation.GetSaga)
+            admin.GET("/enrollments/:id/cancellations", h.cancellation.ListSagas)
+        }
     }
 
     // Health check endpoint
     router.GET("/health", func(c *gin.Context) {
         c.JSON(http.StatusOK, gin.H{"status": "healthy"})
     })
+    router.GET("/health/live", h.health.Live)
+    router.GET("/health/ready", h.health.Ready)
 
     // Metrics endpoint
     router.GET("/metrics", gin.WrapH(promhttp.Handler()))
@@ -203,6 +1450,10 @@ func setupMetrics() error {
     if err := prometheus.Register(documentOperations); err != nil {
         return fmt.Errorf("failed to register document operations metric: %w", err)
     }
+    if err := services.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+        return err
+    }
+    utils.SetDeadlineObserver(services.ObserveDeadlineExceeded)
     return nil
 }
 
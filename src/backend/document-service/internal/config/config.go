@@ -5,10 +5,16 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"mime"
+	"net"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper" // v1.16.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
 )
 
 const (
@@ -17,12 +23,92 @@ const (
 	defaultConfigType = "yaml"
 )
 
+// sha256HexPattern matches a hex-encoded SHA-256 digest
+var sha256HexPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 // Config represents the main configuration structure for the document service
 type Config struct {
 	MinioConfig    MinioConfig    `json:"minio" mapstructure:"minio"`
 	AzureConfig    AzureConfig    `json:"azure" mapstructure:"azure"`
 	ServiceConfig  ServiceConfig  `json:"service" mapstructure:"service"`
 	SecurityConfig SecurityConfig `json:"security" mapstructure:"security"`
+	EnrollmentConfig EnrollmentConfig `json:"enrollment" mapstructure:"enrollment"`
+	WhatsAppConfig   WhatsAppConfig   `json:"whatsapp" mapstructure:"whatsapp"`
+	SFTPConfig       SFTPConfig       `json:"sftp" mapstructure:"sftp"`
+	UnderwritingConfig UnderwritingConfig `json:"underwriting" mapstructure:"underwriting"`
+	OutboxConfig       OutboxConfig       `json:"outbox" mapstructure:"outbox"`
+	SignatureConfig    SignatureConfig    `json:"signature" mapstructure:"signature"`
+	GovBRConfig        GovBRConfig        `json:"govbr" mapstructure:"govbr"`
+	ReceitaConfig      ReceitaConfig      `json:"receita" mapstructure:"receita"`
+	ScreeningConfig    ScreeningConfig    `json:"screening" mapstructure:"screening"`
+	AddressConfig      AddressConfig      `json:"address" mapstructure:"address"`
+	AutoDecisionConfig AutoDecisionConfig `json:"autoDecision" mapstructure:"auto_decision"`
+	ExperimentsConfig  ExperimentsConfig  `json:"experiments" mapstructure:"experiments"`
+	FeatureFlagsConfig FeatureFlagsConfig `json:"featureFlags" mapstructure:"feature_flags"`
+	StorageMigrationConfig StorageMigrationConfig `json:"storageMigration" mapstructure:"storage_migration"`
+	DatabaseConfig     DatabaseConfig     `json:"database" mapstructure:"database"`
+	AdminConfig        AdminConfig        `json:"admin" mapstructure:"admin"`
+	StartupConfig      StartupConfig      `json:"startup" mapstructure:"startup"`
+	ConcurrencyConfig  ConcurrencyConfig  `json:"concurrency" mapstructure:"concurrency"`
+	RenditionsConfig   RenditionsConfig   `json:"renditions" mapstructure:"renditions"`
+	PageOCRConfig      PageOCRConfig      `json:"pageOcr" mapstructure:"page_ocr"`
+	PreviewConfig      PreviewConfig      `json:"preview" mapstructure:"preview"`
+	SecureViewerConfig SecureViewerConfig `json:"secureViewer" mapstructure:"secure_viewer"`
+	AnalyticsExportConfig AnalyticsExportConfig `json:"analyticsExport" mapstructure:"analytics_export"`
+	PortabilityConfig  PortabilityConfig  `json:"portability" mapstructure:"portability"`
+	ConsentConfig      ConsentConfig      `json:"consent" mapstructure:"consent"`
+	ROPAConfig         ROPAConfig         `json:"ropa" mapstructure:"ropa"`
+	EncryptionScanConfig EncryptionScanConfig `json:"encryptionScan" mapstructure:"encryption_scan"`
+	CryptoShreddingConfig CryptoShreddingConfig `json:"cryptoShredding" mapstructure:"crypto_shredding"`
+	KeyAuditConfig KeyAuditConfig `json:"keyAudit" mapstructure:"key_audit"`
+	ClientEncryptionConfig ClientEncryptionConfig `json:"clientEncryption" mapstructure:"client_encryption"`
+	RequestSigningConfig RequestSigningConfig `json:"requestSigning" mapstructure:"request_signing"`
+	AbuseConfig AbuseConfig `json:"abuse" mapstructure:"abuse"`
+	ImpersonationConfig ImpersonationConfig `json:"impersonation" mapstructure:"impersonation"`
+	DownloadReceiptsConfig DownloadReceiptsConfig `json:"downloadReceipts" mapstructure:"download_receipts"`
+	TextAccessConfig TextAccessConfig `json:"textAccess" mapstructure:"text_access"`
+	OrchestrationConfig OrchestrationConfig `json:"orchestration" mapstructure:"orchestration"`
+	CancellationConfig CancellationConfig `json:"cancellation" mapstructure:"cancellation"`
+	BulkOperationsConfig BulkOperationsConfig `json:"bulkOperations" mapstructure:"bulk_operations"`
+	RetentionConfig RetentionConfig `json:"retention" mapstructure:"retention"`
+	QuotaConfig QuotaConfig `json:"quota" mapstructure:"quota"`
+	MaintenanceConfig MaintenanceConfig `json:"maintenance" mapstructure:"maintenance"`
+	AccessLogConfig AccessLogConfig `json:"accessLog" mapstructure:"access_log"`
+	AccessEventsConfig AccessEventsConfig `json:"accessEvents" mapstructure:"access_events"`
+	SealConfig SealConfig `json:"seal" mapstructure:"seal"`
+	MetricsPrivacyConfig MetricsPrivacyConfig `json:"metricsPrivacy" mapstructure:"metrics_privacy"`
+	ConsistencyConfig ConsistencyConfig `json:"consistency" mapstructure:"consistency"`
+	CoordinationConfig CoordinationConfig `json:"coordination" mapstructure:"coordination"`
+	SpoolConfig SpoolConfig `json:"spool" mapstructure:"spool"`
+	ETAConfig ETAConfig `json:"eta" mapstructure:"eta"`
+	ConverterConfig ConverterConfig `json:"converter" mapstructure:"converter"`
+	PageCleanupConfig PageCleanupConfig `json:"pageCleanup" mapstructure:"page_cleanup"`
+	LanguageConfig LanguageConfig `json:"language" mapstructure:"language"`
+	TranslationConfig TranslationConfig `json:"translation" mapstructure:"translation"`
+	AccessibilityConfig AccessibilityConfig `json:"accessibility" mapstructure:"accessibility"`
+	SearchablePDFConfig SearchablePDFConfig `json:"searchablePdf" mapstructure:"searchable_pdf"`
+	SearchConfig SearchConfig `json:"search" mapstructure:"search"`
+	OCRCanaryConfig OCRCanaryConfig `json:"ocrCanary" mapstructure:"ocr_canary"`
+	SyntheticProbeConfig SyntheticProbeConfig `json:"syntheticProbe" mapstructure:"synthetic_probe"`
+	SLOConfig SLOConfig `json:"slo" mapstructure:"slo"`
+	WebhooksConfig WebhooksConfig `json:"webhooks" mapstructure:"webhooks"`
+	APIKeysConfig APIKeysConfig `json:"apiKeys" mapstructure:"api_keys"`
+	DelegationConfig DelegationConfig `json:"delegation" mapstructure:"delegation"`
+	GatewayAuthConfig GatewayAuthConfig `json:"gatewayAuth" mapstructure:"gateway_auth"`
+	ServiceAccountsConfig ServiceAccountsConfig `json:"serviceAccounts" mapstructure:"service_accounts"`
+	VirusScanConfig VirusScanConfig `json:"virusScan" mapstructure:"virus_scan"`
+	CDRConfig CDRConfig `json:"cdr" mapstructure:"cdr"`
+	QuarantineConfig QuarantineConfig `json:"quarantine" mapstructure:"quarantine"`
+	SPIFFEConfig SPIFFEConfig `json:"spiffe" mapstructure:"spiffe"`
+	RetryBudgetConfig RetryBudgetConfig `json:"retryBudget" mapstructure:"retry_budget"`
+	ErrorReportingConfig ErrorReportingConfig `json:"errorReporting" mapstructure:"error_reporting"`
+	BulkReviewConfig BulkReviewConfig `json:"bulkReview" mapstructure:"bulk_review"`
+	SLAConfig SLAConfig `json:"sla" mapstructure:"sla"`
+	BusinessCalendarConfig BusinessCalendarConfig `json:"businessCalendar" mapstructure:"business_calendar"`
+	ProviderQuotaConfig ProviderQuotaConfig `json:"providerQuotas" mapstructure:"provider_quotas"`
+	ProviderHealthConfig ProviderHealthConfig `json:"providerHealth" mapstructure:"provider_health"`
+	ProcessingTiersConfig ProcessingTiersConfig `json:"processingTiers" mapstructure:"processing_tiers"`
+	LargeDocumentConfig LargeDocumentConfig `json:"largeDocuments" mapstructure:"large_documents"`
 }
 
 // MinioConfig contains MinIO storage configuration settings
@@ -37,6 +123,81 @@ type MinioConfig struct {
 	MaxConnections  int           `json:"maxConnections" mapstructure:"max_connections"`
 	EnableSharding  bool          `json:"enableSharding" mapstructure:"enable_sharding"`
 	ShardingConfig  map[string]string `json:"shardingConfig" mapstructure:"sharding_config"`
+	Transport       HTTPTransportConfig `json:"transport" mapstructure:"transport"`
+	Hedging         HedgingConfig       `json:"hedging" mapstructure:"hedging"`
+	// VerifyChecksums sends Content-MD5 and compares the returned ETag with the
+	// locally computed digest of every upload
+	VerifyChecksums bool `json:"verifyChecksums" mapstructure:"verify_checksums"`
+	// Credentials selects a provider refreshing credentials at runtime
+	Credentials ObjectStoreCredentialsConfig `json:"credentials" mapstructure:"credentials"`
+}
+
+// Object store credential providers
+const (
+	CredentialsStatic = "static"
+	CredentialsSTS    = "sts"
+	CredentialsIAM    = "iam"
+	CredentialsVault  = "vault"
+)
+
+// ObjectStoreCredentialsConfig selects where object store credentials come
+// from. Static keys are used for the process lifetime; the other providers
+// issue short-lived credentials that are refreshed before they expire, without
+// a restart. AssumeRole signs its STS calls with the static keys
+type ObjectStoreCredentialsConfig struct {
+	Provider string `json:"provider" mapstructure:"provider"`
+	// STSEndpoint, RoleARN and Duration configure AssumeRole
+	STSEndpoint string        `json:"stsEndpoint" mapstructure:"sts_endpoint"`
+	RoleARN     string        `json:"roleArn" mapstructure:"role_arn"`
+	Duration    time.Duration `json:"duration" mapstructure:"duration"`
+	// IAMEndpoint overrides the instance metadata endpoint of instance profiles
+	IAMEndpoint string `json:"iamEndpoint" mapstructure:"iam_endpoint"`
+	// VaultAddress and VaultPath locate the dynamic secret, read with the
+	// token in VaultTokenPath, reread on every refresh
+	VaultAddress   string `json:"vaultAddress" mapstructure:"vault_address"`
+	VaultPath      string `json:"vaultPath" mapstructure:"vault_path"`
+	VaultTokenPath string `json:"vaultTokenPath" mapstructure:"vault_token_path"`
+	// Timeout bounds each refresh; RetryInterval spaces the refreshes tried
+	// while the last credentials keep serving
+	Timeout       time.Duration `json:"timeout" mapstructure:"timeout"`
+	RetryInterval time.Duration `json:"retryInterval" mapstructure:"retry_interval"`
+}
+
+// Validate checks that the provider has what it needs; accessKey and
+// secretKey are the static keys of the store
+func (o *ObjectStoreCredentialsConfig) Validate(accessKey, secretKey string) error {
+	switch o.Provider {
+	case "", CredentialsStatic:
+		return nil
+	case CredentialsSTS:
+		if o.STSEndpoint == "" {
+			return fmt.Errorf("sts credentials require an STS endpoint")
+		}
+		if accessKey == "" || secretKey == "" {
+			return fmt.Errorf("sts credentials require static keys to assume the role with")
+		}
+		if o.Duration < 0 {
+			return fmt.Errorf("sts credentials duration cannot be negative")
+		}
+	case CredentialsIAM:
+	case CredentialsVault:
+		if o.VaultAddress == "" || o.VaultPath == "" || o.VaultTokenPath == "" {
+			return fmt.Errorf("vault credentials require an address, secret path and token path")
+		}
+	default:
+		return fmt.Errorf("unsupported credentials provider: %s", o.Provider)
+	}
+	if o.Timeout <= 0 || o.RetryInterval <= 0 {
+		return fmt.Errorf("credentials timeout and retry interval must be positive")
+	}
+	return nil
+}
+
+// HedgingConfig controls hedged object reads: when a read has not completed
+// after Delay a second identical request is sent and the first response wins
+type HedgingConfig struct {
+	Enabled bool          `json:"enabled" mapstructure:"enabled"`
+	Delay   time.Duration `json:"delay" mapstructure:"delay"`
 }
 
 // AzureConfig contains Azure Computer Vision configuration settings
@@ -44,11 +205,41 @@ type AzureConfig struct {
 	Endpoint             string                 `json:"endpoint" mapstructure:"endpoint"`
 	SubscriptionKey      string                 `json:"subscriptionKey" mapstructure:"subscription_key"`
 	OCRTimeout          time.Duration          `json:"ocrTimeout" mapstructure:"ocr_timeout"`
+	// MaxDocumentSize is the largest document sent to OCR as a whole; larger
+	// documents are only recognized page by page
+	MaxDocumentSize     int64                  `json:"maxDocumentSize" mapstructure:"max_document_size"`
 	ClassificationTimeout time.Duration         `json:"classificationTimeout" mapstructure:"classification_timeout"`
 	MaxRetries          int                    `json:"maxRetries" mapstructure:"max_retries"`
 	RetryInterval       time.Duration          `json:"retryInterval" mapstructure:"retry_interval"`
 	ConfidenceThreshold float64                `json:"confidenceThreshold" mapstructure:"confidence_threshold"`
 	ModelConfig         map[string]interface{} `json:"modelConfig" mapstructure:"model_config"`
+	Transport           HTTPTransportConfig    `json:"transport" mapstructure:"transport"`
+}
+
+// HTTPTransportConfig tunes connection pooling and keep-alive of an outbound HTTP client
+type HTTPTransportConfig struct {
+	MaxIdleConns        int           `json:"maxIdleConns" mapstructure:"max_idle_conns"`
+	MaxIdleConnsPerHost int           `json:"maxIdleConnsPerHost" mapstructure:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int           `json:"maxConnsPerHost" mapstructure:"max_conns_per_host"`
+	IdleConnTimeout     time.Duration `json:"idleConnTimeout" mapstructure:"idle_conn_timeout"`
+	DialTimeout         time.Duration `json:"dialTimeout" mapstructure:"dial_timeout"`
+	KeepAlive           time.Duration `json:"keepAlive" mapstructure:"keep_alive"`
+	TLSHandshakeTimeout time.Duration `json:"tlsHandshakeTimeout" mapstructure:"tls_handshake_timeout"`
+	TLSSessionCacheSize int           `json:"tlsSessionCacheSize" mapstructure:"tls_session_cache_size"`
+}
+
+// Validate checks the transport settings are usable
+func (t *HTTPTransportConfig) Validate() error {
+	if t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 || t.TLSSessionCacheSize < 0 {
+		return fmt.Errorf("connection limits cannot be negative")
+	}
+	if t.MaxConnsPerHost > 0 && t.MaxIdleConnsPerHost > t.MaxConnsPerHost {
+		return fmt.Errorf("max idle connections per host cannot exceed max connections per host")
+	}
+	if t.DialTimeout <= 0 || t.TLSHandshakeTimeout <= 0 {
+		return fmt.Errorf("dial and TLS handshake timeouts must be positive")
+	}
+	return nil
 }
 
 // ServiceConfig contains general service operational settings
@@ -57,10 +248,179 @@ type ServiceConfig struct {
 	Port                 int           `json:"port" mapstructure:"port"`
 	MaxFileSize          int64         `json:"maxFileSize" mapstructure:"max_file_size"`
 	AllowedFileTypes     []string      `json:"allowedFileTypes" mapstructure:"allowed_file_types"`
+	AllowedMimeTypes     []string      `json:"allowedMimeTypes" mapstructure:"allowed_mime_types"`
 	RequestTimeout       time.Duration `json:"requestTimeout" mapstructure:"request_timeout"`
 	MaxConcurrentUploads int           `json:"maxConcurrentUploads" mapstructure:"max_concurrent_uploads"`
 	MaxConcurrentProcessing int        `json:"maxConcurrentProcessing" mapstructure:"max_concurrent_processing"`
 	EnableMetrics        bool          `json:"enableMetrics" mapstructure:"enable_metrics"`
+	// PipelineVersion is recorded in the provenance of every artifact; the
+	// VCS revision of the binary is used when unset
+	PipelineVersion      string        `json:"pipelineVersion" mapstructure:"pipeline_version"`
+	Routes               RouteGroupsConfig `json:"routes" mapstructure:"routes"`
+	Channels             ChannelsConfig `json:"channels" mapstructure:"channels"`
+	// MaxImagePages bounds the pages of a PDF made from images, whether
+	// converted from a multi-page TIFF or composed from several uploads
+	MaxImagePages        int           `json:"maxImagePages" mapstructure:"max_image_pages"`
+	DownloadBandwidth    DownloadBandwidthConfig `json:"downloadBandwidth" mapstructure:"download_bandwidth"`
+}
+
+// Route groups with their own request limits
+const (
+	RouteGroupAPI      = "api"
+	RouteGroupUpload   = "upload"
+	RouteGroupDownload = "download"
+	RouteGroupWebhook  = "webhook"
+	RouteGroupAdmin    = "admin"
+)
+
+// RouteGroups lists every route group with its own request limits
+var RouteGroups = []string{RouteGroupAPI, RouteGroupUpload, RouteGroupDownload, RouteGroupWebhook, RouteGroupAdmin}
+
+// Dependencies whose calls are bounded by their own timeout within the
+// deadline of the request making them
+const (
+	DependencyStorage     = "storage"
+	DependencyKMS         = "kms"
+	DependencyOCR         = "ocr"
+	DependencyVirusScan   = "virus_scan"
+	DependencyConverter   = "converter"
+	DependencyTranslation = "translation"
+)
+
+// multipartOverhead is allowed on top of the maximum file size for the
+// multipart framing and form fields of an upload
+const multipartOverhead = 1024 * 1024
+
+// RouteLimits bounds the requests of a route group: the size of the request
+// body and the time to read it and write the response. Zero falls back to
+// the service defaults
+type RouteLimits struct {
+	MaxBodySize int64         `json:"maxBodySize" mapstructure:"max_body_size"`
+	Timeout     time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// RouteGroupsConfig contains the request limits of each route group
+type RouteGroupsConfig struct {
+	API      RouteLimits `json:"api" mapstructure:"api"`
+	Upload   RouteLimits `json:"upload" mapstructure:"upload"`
+	Download RouteLimits `json:"download" mapstructure:"download"`
+	Webhook  RouteLimits `json:"webhook" mapstructure:"webhook"`
+	Admin    RouteLimits `json:"admin" mapstructure:"admin"`
+}
+
+// ChannelPolicy is the content policy of an ingestion channel: the types it
+// accepts, its size cap and the accepted types converted to PDF on
+// ingestion. Unset types and size fall back to the service-wide settings
+type ChannelPolicy struct {
+	AllowedMimeTypes []string `json:"allowedMimeTypes" mapstructure:"allowed_mime_types"`
+	MaxFileSize      int64    `json:"maxFileSize" mapstructure:"max_file_size"`
+	ConvertToPDF     []string `json:"convertToPdf" mapstructure:"convert_to_pdf"`
+}
+
+// ChannelsConfig contains the content policy of each ingestion channel
+type ChannelsConfig struct {
+	API      ChannelPolicy `json:"api" mapstructure:"api"`
+	WhatsApp ChannelPolicy `json:"whatsapp" mapstructure:"whatsapp"`
+	SFTP     ChannelPolicy `json:"sftp" mapstructure:"sftp"`
+}
+
+// BandwidthLimit is a token bucket over the bytes streamed to one user:
+// BytesPerSecond sustained, with bursts of up to Burst bytes. A zero
+// BytesPerSecond is unlimited
+type BandwidthLimit struct {
+	BytesPerSecond int64 `json:"bytesPerSecond" mapstructure:"bytes_per_second"`
+	Burst          int64 `json:"burst" mapstructure:"burst"`
+}
+
+// DownloadBandwidthConfig shapes the downloads of each user by their role.
+// Roles not listed in Roles get Default
+type DownloadBandwidthConfig struct {
+	Default BandwidthLimit            `json:"default" mapstructure:"default"`
+	Roles   map[string]BandwidthLimit `json:"roles" mapstructure:"roles"`
+}
+
+// LimitFor returns the bandwidth limit of a role
+func (c DownloadBandwidthConfig) LimitFor(role string) BandwidthLimit {
+	if limit, ok := c.Roles[role]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// Limits returns the effective limits of a route group. Uploads accept the
+// maximum file size plus the multipart framing unless configured otherwise,
+// and groups without a timeout use the request timeout
+func (s ServiceConfig) Limits(group string) RouteLimits {
+	var limits RouteLimits
+	switch group {
+	case RouteGroupAPI:
+		limits = s.Routes.API
+	case RouteGroupUpload:
+		limits = s.Routes.Upload
+		if limits.MaxBodySize == 0 {
+			limits.MaxBodySize = s.MaxFileSize + multipartOverhead
+		}
+	case RouteGroupDownload:
+		limits = s.Routes.Download
+	case RouteGroupWebhook:
+		limits = s.Routes.Webhook
+	case RouteGroupAdmin:
+		limits = s.Routes.Admin
+	}
+	if limits.MaxBodySize == 0 {
+		limits.MaxBodySize = s.Routes.API.MaxBodySize
+	}
+	if limits.Timeout == 0 {
+		limits.Timeout = s.RequestTimeout
+	}
+	return limits
+}
+
+// Channel returns the effective content policy of an ingestion channel, with
+// the service-wide types and size filled in. Unknown channels get the
+// service-wide settings
+func (s ServiceConfig) Channel(channel string) ChannelPolicy {
+	var policy ChannelPolicy
+	switch channel {
+	case models.ChannelAPI:
+		policy = s.Channels.API
+	case models.ChannelWhatsApp:
+		policy = s.Channels.WhatsApp
+	case models.ChannelSFTP:
+		policy = s.Channels.SFTP
+	}
+	if len(policy.AllowedMimeTypes) == 0 {
+		policy.AllowedMimeTypes = s.AllowedMimeTypes
+	}
+	if policy.MaxFileSize == 0 {
+		policy.MaxFileSize = s.MaxFileSize
+	}
+	return policy
+}
+
+// Accepts reports whether the policy accepts a content type
+func (p ChannelPolicy) Accepts(contentType string) bool {
+	return contains(p.AllowedMimeTypes, contentType)
+}
+
+// Conversion returns the conversion applied to an accepted content type
+func (p ChannelPolicy) Conversion(contentType string) string {
+	if contains(p.ConvertToPDF, contentType) {
+		return models.ConversionPDF
+	}
+	return models.ConversionNone
+}
+
+// LongestTimeout returns the longest timeout of any route group, which bounds
+// the server's own read and write timeouts
+func (s ServiceConfig) LongestTimeout() time.Duration {
+	longest := s.RequestTimeout
+	for _, group := range RouteGroups {
+		if timeout := s.Limits(group).Timeout; timeout > longest {
+			longest = timeout
+		}
+	}
+	return longest
 }
 
 // SecurityConfig contains security and encryption settings
@@ -69,130 +429,3056 @@ type SecurityConfig struct {
 	EncryptionAlgorithm  string            `json:"encryptionAlgorithm" mapstructure:"encryption_algorithm"`
 	EnableAuditLog       bool              `json:"enableAuditLog" mapstructure:"enable_audit_log"`
 	TrustedOrigins       []string          `json:"trustedOrigins" mapstructure:"trusted_origins"`
+	// TrustedProxies are the addresses or CIDRs of the reverse proxies whose
+	// X-Forwarded-For names the client; none by default
+	TrustedProxies       []string          `json:"trustedProxies" mapstructure:"trusted_proxies"`
 	EnableDataMasking    bool              `json:"enableDataMasking" mapstructure:"enable_data_masking"`
 	DataMaskingRules     map[string]string `json:"dataMaskingRules" mapstructure:"data_masking_rules"`
 	KeyRotationInterval  time.Duration     `json:"keyRotationInterval" mapstructure:"key_rotation_interval"`
+	KeyVersion           string            `json:"keyVersion" mapstructure:"key_version"`
 	EnforceStrictTransport bool            `json:"enforceStrictTransport" mapstructure:"enforce_strict_transport"`
+	// StrictCrypto restricts the service to FIPS-approved algorithms and
+	// refuses to start on settings outside them
+	StrictCrypto         bool              `json:"strictCrypto" mapstructure:"strict_crypto"`
+	// MaxMessagesPerKey caps the objects sealed under one data key below the
+	// algorithm's nonce collision limit; 0 keeps the algorithm limit
+	MaxMessagesPerKey    int64             `json:"maxMessagesPerKey" mapstructure:"max_messages_per_key"`
+	// KMSTimeout bounds each KMS call, within the deadline of the request
+	// making it
+	KMSTimeout           time.Duration     `json:"kmsTimeout" mapstructure:"kms_timeout"`
 }
 
-// LoadConfig loads and validates service configuration from the specified path
-func LoadConfig(path string) (*Config, error) {
-	v := viper.New()
+// EnrollmentConfig contains settings for the enrollment service client
+type EnrollmentConfig struct {
+	BaseURL string        `json:"baseUrl" mapstructure:"base_url"`
+	Timeout time.Duration `json:"timeout" mapstructure:"timeout"`
+}
 
-	// Set default configuration values
-	setDefaults(v)
+// WhatsAppConfig contains WhatsApp Business Cloud API ingestion settings
+type WhatsAppConfig struct {
+	Enabled             bool              `json:"enabled" mapstructure:"enabled"`
+	APIBaseURL          string            `json:"apiBaseUrl" mapstructure:"api_base_url"`
+	PhoneNumberID       string            `json:"phoneNumberId" mapstructure:"phone_number_id"`
+	AccessToken         string            `json:"accessToken" mapstructure:"access_token"`
+	AppSecret           string            `json:"appSecret" mapstructure:"app_secret"`
+	VerifyToken         string            `json:"verifyToken" mapstructure:"verify_token"`
+	DefaultDocumentType string            `json:"defaultDocumentType" mapstructure:"default_document_type"`
+	MediaTimeout        time.Duration     `json:"mediaTimeout" mapstructure:"media_timeout"`
+	ProcessingTimeout   time.Duration     `json:"processingTimeout" mapstructure:"processing_timeout"`
+	TemplateLanguage    string            `json:"templateLanguage" mapstructure:"template_language"`
+	StatusTemplates     map[string]string `json:"statusTemplates" mapstructure:"status_templates"`
+}
 
-	// Set configuration path and type
-	if path != "" {
-		v.AddConfigPath(path)
-	} else {
-		v.AddConfigPath(defaultConfigPath)
-	}
-	v.SetConfigName(defaultConfigName)
-	v.SetConfigType(defaultConfigType)
+// SFTPConfig contains settings for the corporate batch ingestion SFTP puller
+type SFTPConfig struct {
+	Enabled        bool          `json:"enabled" mapstructure:"enabled"`
+	Host           string        `json:"host" mapstructure:"host"`
+	Port           int           `json:"port" mapstructure:"port"`
+	Username       string        `json:"username" mapstructure:"username"`
+	PrivateKeyPath string        `json:"privateKeyPath" mapstructure:"private_key_path"`
+	HostKey        string        `json:"hostKey" mapstructure:"host_key"`
+	InboundDir     string        `json:"inboundDir" mapstructure:"inbound_dir"`
+	ProcessingDir  string        `json:"processingDir" mapstructure:"processing_dir"`
+	ProcessedDir   string        `json:"processedDir" mapstructure:"processed_dir"`
+	ReportDir      string        `json:"reportDir" mapstructure:"report_dir"`
+	ManifestName   string        `json:"manifestName" mapstructure:"manifest_name"`
+	PollInterval   time.Duration `json:"pollInterval" mapstructure:"poll_interval"`
+	ConnectTimeout time.Duration `json:"connectTimeout" mapstructure:"connect_timeout"`
+	FileTimeout    time.Duration `json:"fileTimeout" mapstructure:"file_timeout"`
+}
 
-	// Enable environment variable override
-	v.AutomaticEnv()
-	v.SetEnvPrefix("DOC_SERVICE")
+// UnderwritingConfig contains settings for outbound underwriting notifications
+type UnderwritingConfig struct {
+	Enabled               bool          `json:"enabled" mapstructure:"enabled"`
+	ShadowMode            bool          `json:"shadowMode" mapstructure:"shadow_mode"`
+	// Transport is rest, posting to BaseURL, or grpc, calling GRPCTarget
+	Transport             string        `json:"transport" mapstructure:"transport"`
+	BaseURL               string        `json:"baseUrl" mapstructure:"base_url"`
+	GRPCTarget            string        `json:"grpcTarget" mapstructure:"grpc_target"`
+	Timeout               time.Duration `json:"timeout" mapstructure:"timeout"`
+	RequiredDocumentTypes []string      `json:"requiredDocumentTypes" mapstructure:"required_document_types"`
+}
 
-	// Read configuration
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("error reading config file: %w", err)
-		}
-	}
+// OutboxConfig contains delivery settings for the transactional outbox dispatcher
+type OutboxConfig struct {
+	PollInterval time.Duration `json:"pollInterval" mapstructure:"poll_interval"`
+	BatchSize    int           `json:"batchSize" mapstructure:"batch_size"`
+	MaxAttempts  int           `json:"maxAttempts" mapstructure:"max_attempts"`
+	RetryBackoff time.Duration `json:"retryBackoff" mapstructure:"retry_backoff"`
+}
 
-	config := &Config{}
-	if err := v.Unmarshal(config); err != nil {
-		return nil, fmt.Errorf("error unmarshaling config: %w", err)
-	}
+// SignatureConfig contains settings for verifying ICP-Brasil signed PDFs
+type SignatureConfig struct {
+	Enabled          bool   `json:"enabled" mapstructure:"enabled"`
+	TrustedRootsPath string `json:"trustedRootsPath" mapstructure:"trusted_roots_path"`
+}
 
-	// Validate configuration
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
-	}
+// GovBRConfig contains settings for validating CNH-e and CRLV-e codes against gov.br
+type GovBRConfig struct {
+	Enabled           bool          `json:"enabled" mapstructure:"enabled"`
+	BaseURL           string        `json:"baseUrl" mapstructure:"base_url"`
+	APIKey            string        `json:"-" mapstructure:"api_key"`
+	Timeout           time.Duration `json:"timeout" mapstructure:"timeout"`
+	RequestsPerSecond float64       `json:"requestsPerSecond" mapstructure:"requests_per_second"`
+	Burst             int           `json:"burst" mapstructure:"burst"`
+	CacheTTL          time.Duration `json:"cacheTtl" mapstructure:"cache_ttl"`
+	DocumentTypes     []string      `json:"documentTypes" mapstructure:"document_types"`
+}
 
-	return config, nil
+// ReceitaConfig contains settings for CPF situation checks through a certified broker
+type ReceitaConfig struct {
+	Enabled  bool          `json:"enabled" mapstructure:"enabled"`
+	Provider string        `json:"provider" mapstructure:"provider"`
+	BaseURL  string        `json:"baseUrl" mapstructure:"base_url"`
+	APIKey   string        `json:"-" mapstructure:"api_key"`
+	Timeout  time.Duration `json:"timeout" mapstructure:"timeout"`
+	CacheTTL time.Duration `json:"cacheTtl" mapstructure:"cache_ttl"`
 }
 
-// Validate performs comprehensive validation of all configuration settings
-func (c *Config) Validate() error {
-	// Validate MinIO configuration
-	if c.MinioConfig.Endpoint == "" {
-		return fmt.Errorf("minio endpoint is required")
-	}
-	if c.MinioConfig.BucketName == "" {
-		return fmt.Errorf("minio bucket name is required")
-	}
-	if c.MinioConfig.UploadTimeout <= 0 {
-		return fmt.Errorf("invalid upload timeout")
-	}
+// ScreeningConfig contains settings for PEP and sanctions screening
+type ScreeningConfig struct {
+	Enabled  bool          `json:"enabled" mapstructure:"enabled"`
+	Provider string        `json:"provider" mapstructure:"provider"`
+	BaseURL  string        `json:"baseUrl" mapstructure:"base_url"`
+	APIKey   string        `json:"-" mapstructure:"api_key"`
+	Timeout  time.Duration `json:"timeout" mapstructure:"timeout"`
+	Lists    []string      `json:"lists" mapstructure:"lists"`
+	MinScore float64       `json:"minScore" mapstructure:"min_score"`
+}
 
-	// Validate Azure configuration
-	if c.AzureConfig.Endpoint == "" {
-		return fmt.Errorf("azure endpoint is required")
-	}
-	if c.AzureConfig.SubscriptionKey == "" {
-		return fmt.Errorf("azure subscription key is required")
-	}
-	if c.AzureConfig.ConfidenceThreshold <= 0 || c.AzureConfig.ConfidenceThreshold > 1 {
-		return fmt.Errorf("confidence threshold must be between 0 and 1")
-	}
+// AddressConfig contains settings for CEP based address normalization
+type AddressConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	ViaCEPBaseURL string        `json:"viaCepBaseUrl" mapstructure:"viacep_base_url"`
+	Timeout       time.Duration `json:"timeout" mapstructure:"timeout"`
+	CacheTTL      time.Duration `json:"cacheTtl" mapstructure:"cache_ttl"`
+}
 
-	// Validate service configuration
-	if c.ServiceConfig.Port <= 0 || c.ServiceConfig.Port > 65535 {
-		return fmt.Errorf("invalid port number")
+// AutoDecisionConfig contains the ordered rules used to auto-approve, auto-reject
+// or route processed documents to human review
+type AutoDecisionConfig struct {
+	Enabled bool           `json:"enabled" mapstructure:"enabled"`
+	Rules   []DecisionRule `json:"rules" mapstructure:"rules"`
+}
+
+// DecisionRule is a single auto-decision rule; the first matching rule wins
+type DecisionRule struct {
+	Name          string   `json:"name" mapstructure:"name"`
+	DocumentTypes []string `json:"documentTypes" mapstructure:"document_types"`
+	When          string   `json:"when" mapstructure:"when"`
+	Decision      string   `json:"decision" mapstructure:"decision"`
+	Reason        string   `json:"reason" mapstructure:"reason"`
+}
+
+// ExperimentsConfig contains A/B experiments routing a share of documents
+// through alternative pipeline step implementations
+type ExperimentsConfig struct {
+	Enabled     bool               `json:"enabled" mapstructure:"enabled"`
+	Experiments []ExperimentConfig `json:"experiments" mapstructure:"experiments"`
+}
+
+// ExperimentConfig replaces a pipeline step with variants for a percentage of documents
+type ExperimentConfig struct {
+	Name     string                    `json:"name" mapstructure:"name"`
+	Step     string                    `json:"step" mapstructure:"step"`
+	Variants []ExperimentVariantConfig `json:"variants" mapstructure:"variants"`
+}
+
+// ExperimentVariantConfig is an alternative step implementation and its traffic share
+type ExperimentVariantConfig struct {
+	Name           string  `json:"name" mapstructure:"name"`
+	Implementation string  `json:"implementation" mapstructure:"implementation"`
+	Percentage     float64 `json:"percentage" mapstructure:"percentage"`
+}
+
+// FeatureFlagsConfig contains the kill switches consulted at runtime. Flags
+// default to the values in this file; when a remote provider is configured its
+// states are polled and take precedence
+type FeatureFlagsConfig struct {
+	Provider string `json:"provider" mapstructure:"provider"`
+	// Flags is a list rather than a map keyed by name, since flag names such
+	// as pipeline.ocr contain the dots viper splits keys on
+	Flags           []FeatureFlagConfig `json:"flags" mapstructure:"flags"`
+	URL             string              `json:"url" mapstructure:"url"`
+	APIKey          string              `json:"-" mapstructure:"api_key"`
+	AppName         string              `json:"appName" mapstructure:"app_name"`
+	RefreshInterval time.Duration       `json:"refreshInterval" mapstructure:"refresh_interval"`
+	Timeout         time.Duration       `json:"timeout" mapstructure:"timeout"`
+}
+
+// FeatureFlagConfig is the state of a flag set in this file
+type FeatureFlagConfig struct {
+	Name    string `json:"name" mapstructure:"name"`
+	Enabled bool   `json:"enabled" mapstructure:"enabled"`
+}
+
+// StorageMigrationConfig contains the shadow mode used to migrate document
+// storage from MinIO to S3: writes go to both backends and reads are compared
+type StorageMigrationConfig struct {
+	Enabled           bool          `json:"enabled" mapstructure:"enabled"`
+	ReadFromSecondary bool          `json:"readFromSecondary" mapstructure:"read_from_secondary"`
+	CompareReads      bool          `json:"compareReads" mapstructure:"compare_reads"`
+	CompareTimeout    time.Duration `json:"compareTimeout" mapstructure:"compare_timeout"`
+	// MaxConcurrentCompares bounds the shadow reads compared at once; reads
+	// past it are served without comparing
+	MaxConcurrentCompares int `json:"maxConcurrentCompares" mapstructure:"max_concurrent_compares"`
+	// ReconcileInterval is how often diverged objects are repaired and the
+	// next batch of documents is backfilled
+	ReconcileInterval  time.Duration `json:"reconcileInterval" mapstructure:"reconcile_interval"`
+	ReconcileBatchSize int           `json:"reconcileBatchSize" mapstructure:"reconcile_batch_size"`
+	Secondary          S3Config      `json:"secondary" mapstructure:"secondary"`
+}
+
+// S3Config contains the connection settings of an S3-compatible bucket
+type S3Config struct {
+	Endpoint   string `json:"endpoint" mapstructure:"endpoint"`
+	Region     string `json:"region" mapstructure:"region"`
+	AccessKey  string `json:"accessKey" mapstructure:"access_key"`
+	SecretKey  string `json:"-" mapstructure:"secret_key"`
+	BucketName string `json:"bucketName" mapstructure:"bucket_name"`
+	UseSSL     bool   `json:"useSSL" mapstructure:"use_ssl"`
+	// VerifyChecksums must be disabled for buckets using SSE-KMS, whose ETags
+	// are not content digests
+	VerifyChecksums bool `json:"verifyChecksums" mapstructure:"verify_checksums"`
+	// Credentials selects a provider refreshing credentials at runtime
+	Credentials ObjectStoreCredentialsConfig `json:"credentials" mapstructure:"credentials"`
+}
+
+// DatabaseConfig contains the PostgreSQL connection and schema migration settings
+type DatabaseConfig struct {
+	Enabled        bool             `json:"enabled" mapstructure:"enabled"`
+	URL            string           `json:"-" mapstructure:"url"`
+	ConnectTimeout time.Duration    `json:"connectTimeout" mapstructure:"connect_timeout"`
+	Migrations     MigrationsConfig `json:"migrations" mapstructure:"migrations"`
+}
+
+// MigrationsConfig controls whether expand migrations are applied at startup.
+// Contract migrations break the previous release and are never applied at
+// startup; they are run through /admin/migrations/contract once it no longer
+// serves traffic
+type MigrationsConfig struct {
+	AutoApply bool `json:"autoApply" mapstructure:"auto_apply"`
+}
+
+// AdminConfig contains the settings of the operational /admin endpoints
+type AdminConfig struct {
+	Token string `json:"-" mapstructure:"token"`
+}
+
+// StartupConfig contains the dependency checks run before the service reports ready
+type StartupConfig struct {
+	CheckTimeout    time.Duration `json:"checkTimeout" mapstructure:"check_timeout"`
+	WarmConnections int           `json:"warmConnections" mapstructure:"warm_connections"`
+}
+
+// ConcurrencyConfig contains the adaptive in-flight limits protecting slow dependencies
+type ConcurrencyConfig struct {
+	Enabled bool                `json:"enabled" mapstructure:"enabled"`
+	OCR     AdaptiveLimitConfig `json:"ocr" mapstructure:"ocr"`
+	Storage AdaptiveLimitConfig `json:"storage" mapstructure:"storage"`
+}
+
+// AdaptiveLimitConfig tunes an AIMD concurrency limit. The limit is cut by
+// BackoffRatio when a window's p99 latency exceeds LatencyTarget or rises above
+// LatencyTolerance times the healthy baseline
+type AdaptiveLimitConfig struct {
+	InitialLimit     int           `json:"initialLimit" mapstructure:"initial_limit"`
+	MinLimit         int           `json:"minLimit" mapstructure:"min_limit"`
+	MaxLimit         int           `json:"maxLimit" mapstructure:"max_limit"`
+	Window           int           `json:"window" mapstructure:"window"`
+	LatencyTarget    time.Duration `json:"latencyTarget" mapstructure:"latency_target"`
+	LatencyTolerance float64       `json:"latencyTolerance" mapstructure:"latency_tolerance"`
+	BackoffRatio     float64       `json:"backoffRatio" mapstructure:"backoff_ratio"`
+}
+
+// Validate checks the limits are consistent
+func (a *AdaptiveLimitConfig) Validate() error {
+	if a.MinLimit < 1 || a.MaxLimit < a.MinLimit || a.InitialLimit < a.MinLimit || a.InitialLimit > a.MaxLimit {
+		return fmt.Errorf("limits must satisfy 1 <= min <= initial <= max")
 	}
-	if c.ServiceConfig.MaxFileSize <= 0 {
-		return fmt.Errorf("invalid max file size")
+	if a.Window < 1 {
+		return fmt.Errorf("window must be at least one sample")
 	}
-	if len(c.ServiceConfig.AllowedFileTypes) == 0 {
-		return fmt.Errorf("allowed file types must be specified")
+	if a.LatencyTolerance <= 1 {
+		return fmt.Errorf("latency tolerance must be greater than 1")
 	}
-
-	// Validate security configuration
-	if c.SecurityConfig.EncryptionKey == "" {
-		return fmt.Errorf("encryption key is required")
+	if a.BackoffRatio <= 0 || a.BackoffRatio >= 1 {
+		return fmt.Errorf("backoff ratio must be between 0 and 1")
 	}
-	if c.SecurityConfig.EncryptionAlgorithm != "AES-256" {
-		return fmt.Errorf("unsupported encryption algorithm")
+	return nil
+}
+
+// RenditionsConfig controls how thumbnails and previews are generated and how
+// unencrypted renditions are downloaded
+type RenditionsConfig struct {
+	Generate           bool          `json:"generate" mapstructure:"generate"`
+	ThumbnailWidth     int           `json:"thumbnailWidth" mapstructure:"thumbnail_width"`
+	PreviewWidth       int           `json:"previewWidth" mapstructure:"preview_width"`
+	JPEGQuality        int           `json:"jpegQuality" mapstructure:"jpeg_quality"`
+	DPI                float64       `json:"dpi" mapstructure:"dpi"`
+	PresignedRedirects bool          `json:"presignedRedirects" mapstructure:"presigned_redirects"`
+	PresignExpiry      time.Duration `json:"presignExpiry" mapstructure:"presign_expiry"`
+	CacheMaxAge        time.Duration `json:"cacheMaxAge" mapstructure:"cache_max_age"`
+}
+
+// PageOCRConfig controls page-level OCR of multi-page PDFs. Pages are recognized
+// concurrently, bounded per document and per tenant
+type PageOCRConfig struct {
+	Enabled              bool `json:"enabled" mapstructure:"enabled"`
+	MaxConcurrentPages   int  `json:"maxConcurrentPages" mapstructure:"max_concurrent_pages"`
+	PerTenantConcurrency int  `json:"perTenantConcurrency" mapstructure:"per_tenant_concurrency"`
+	MaxPages             int  `json:"maxPages" mapstructure:"max_pages"`
+}
+
+// PreviewConfig contains the settings of signed preview tokens used by the portal
+// to embed renditions without sending credentials
+type PreviewConfig struct {
+	SigningKey         string        `json:"-" mapstructure:"signing_key"`
+	PreviousSigningKey string        `json:"-" mapstructure:"previous_signing_key"`
+	TokenTTL           time.Duration `json:"tokenTtl" mapstructure:"token_ttl"`
+	RequireIPBinding   bool          `json:"requireIpBinding" mapstructure:"require_ip_binding"`
+}
+
+// SecureViewerConfig controls the secure viewer, which serves high-sensitivity
+// documents to restricted roles as watermarked page images instead of files
+type SecureViewerConfig struct {
+	Enabled       bool     `json:"enabled" mapstructure:"enabled"`
+	DocumentTypes []string `json:"documentTypes" mapstructure:"document_types"`
+	// UnrestrictedRoles may download the documents; every other role, and
+	// requests without one, may only use the viewer
+	UnrestrictedRoles []string `json:"unrestrictedRoles" mapstructure:"unrestricted_roles"`
+	DPI               float64  `json:"dpi" mapstructure:"dpi"`
+}
+
+// AnalyticsExportConfig controls the scheduled export of anonymized document
+// metadata for analytics
+type AnalyticsExportConfig struct {
+	Enabled  bool          `json:"enabled" mapstructure:"enabled"`
+	Interval time.Duration `json:"interval" mapstructure:"interval"`
+	Format   string        `json:"format" mapstructure:"format"`
+	Prefix   string        `json:"prefix" mapstructure:"prefix"`
+	// HashKey keys the pseudonyms of exported identifiers; changing it breaks
+	// joins with earlier exports
+	HashKey     string   `json:"-" mapstructure:"hash_key"`
+	Destination S3Config `json:"destination" mapstructure:"destination"`
+}
+
+// PortabilityConfig controls LGPD portability exports and their download links
+type PortabilityConfig struct {
+	Enabled        bool          `json:"enabled" mapstructure:"enabled"`
+	LinkSigningKey string        `json:"-" mapstructure:"link_signing_key"`
+	LinkTTL        time.Duration `json:"linkTtl" mapstructure:"link_ttl"`
+}
+
+// ConsentConfig contains settings of the consent service event webhook
+type ConsentConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+}
+
+// ROPAConfig describes the records of processing activities kept for the DPO.
+// Activities override the built-in purpose and legal basis of a processing
+// operation, keyed by operation name
+type ROPAConfig struct {
+	Controller             string                     `json:"controller" mapstructure:"controller"`
+	SensitiveDocumentTypes []string                   `json:"sensitiveDocumentTypes" mapstructure:"sensitive_document_types"`
+	Activities             []ProcessingActivityConfig `json:"activities" mapstructure:"activities"`
+}
+
+// ProcessingActivityConfig maps a processing operation to its category,
+// purpose and LGPD legal basis codes. SensitiveLegalBasis applies to sensitive
+// document types and falls back to LegalBasis when empty
+type ProcessingActivityConfig struct {
+	Operation           string `json:"operation" mapstructure:"operation"`
+	Category            string `json:"category" mapstructure:"category"`
+	Purpose             string `json:"purpose" mapstructure:"purpose"`
+	LegalBasis          string `json:"legalBasis" mapstructure:"legal_basis"`
+	SensitiveLegalBasis string `json:"sensitiveLegalBasis" mapstructure:"sensitive_legal_basis"`
+}
+
+// EncryptionScanConfig controls the encryption-at-rest scanner. Each run
+// checks the objects of SampleSize randomly chosen documents; with Reencrypt,
+// documents whose content can be recovered are re-encrypted automatically
+type EncryptionScanConfig struct {
+	Enabled    bool          `json:"enabled" mapstructure:"enabled"`
+	Interval   time.Duration `json:"interval" mapstructure:"interval"`
+	SampleSize int           `json:"sampleSize" mapstructure:"sample_size"`
+	Reencrypt  bool          `json:"reencrypt" mapstructure:"reencrypt"`
+}
+
+// CryptoShreddingConfig enables per-document data keys so erasure destroys the
+// key instead of waiting for content to be deleted. Each key is usable only
+// by GranteePrincipal through a KMS grant revoked on erasure, so the service
+// must run as that principal without decrypt rights of its own on the KMS key
+type CryptoShreddingConfig struct {
+	Enabled          bool          `json:"enabled" mapstructure:"enabled"`
+	GranteePrincipal string        `json:"granteePrincipal" mapstructure:"grantee_principal"`
+	KeyCacheTTL      time.Duration `json:"keyCacheTtl" mapstructure:"key_cache_ttl"`
+}
+
+// KeyAuditConfig controls the key usage audit. Every use of a data key is
+// recorded with the document and the caller, and the admin report flags
+// principals and key versions whose hourly usage exceeds the thresholds
+type KeyAuditConfig struct {
+	Enabled    bool               `json:"enabled" mapstructure:"enabled"`
+	Retention  time.Duration      `json:"retention" mapstructure:"retention"`
+	Thresholds KeyUsageThresholds `json:"thresholds" mapstructure:"thresholds"`
+}
+
+// KeyUsageThresholds are the hourly usage counts above which the key usage
+// report raises an anomaly; zero disables a threshold
+type KeyUsageThresholds struct {
+	DecryptsPerPrincipal  int `json:"decryptsPerPrincipal" mapstructure:"decrypts_per_principal"`
+	DocumentsPerPrincipal int `json:"documentsPerPrincipal" mapstructure:"documents_per_principal"`
+	FailuresPerPrincipal  int `json:"failuresPerPrincipal" mapstructure:"failures_per_principal"`
+	KMSCallsPerKeyVersion int `json:"kmsCallsPerKeyVersion" mapstructure:"kms_calls_per_key_version"`
+}
+
+// ClientEncryptionConfig controls end-to-end encrypted uploads. Clients seal
+// the document to a public key of the underwriting team, so the service only
+// ever stores and serves ciphertext
+type ClientEncryptionConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// RecipientKeys are PEM encoded P-256 public keys, the one new uploads are
+	// sealed to first; uploads sealed to the others are still accepted
+	RecipientKeys []string `json:"recipientKeys" mapstructure:"recipient_keys"`
+	DocumentTypes []string `json:"documentTypes" mapstructure:"document_types"`
+	// DownloadRoles may download the envelopes; empty allows every caller
+	DownloadRoles []string `json:"downloadRoles" mapstructure:"download_roles"`
+}
+
+// RequestSigningConfig controls HMAC signing of calls between internal
+// services. Outbound calls are signed with the key named by KeyID and inbound
+// calls are accepted under any of Keys, so a key is rotated by adding its
+// successor to every service before switching KeyID, then removing it
+type RequestSigningConfig struct {
+	Enabled bool              `json:"enabled" mapstructure:"enabled"`
+	KeyID   string            `json:"keyId" mapstructure:"key_id"`
+	Keys    map[string]string `json:"-" mapstructure:"keys"`
+	// MaxSkew bounds the age of a signature and the clock skew between services
+	MaxSkew time.Duration `json:"maxSkew" mapstructure:"max_skew"`
+}
+
+// AbuseConfig controls brute-force protection of the endpoints whose only
+// credential is a token or signed link. A client failing MaxFailures times
+// within FailureWindow is banned from them for BanDuration
+type AbuseConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	MaxFailures   int           `json:"maxFailures" mapstructure:"max_failures"`
+	FailureWindow time.Duration `json:"failureWindow" mapstructure:"failure_window"`
+	BanDuration   time.Duration `json:"banDuration" mapstructure:"ban_duration"`
+	Captcha       CaptchaConfig `json:"captcha" mapstructure:"captcha"`
+	Replay        ReplayConfig  `json:"replay" mapstructure:"replay"`
+}
+
+// CaptchaConfig configures the CAPTCHA required from uploads made without an
+// authenticated user. VerifyURL is a siteverify endpoint as offered by
+// hCaptcha, reCAPTCHA and Turnstile
+type CaptchaConfig struct {
+	Enabled   bool          `json:"enabled" mapstructure:"enabled"`
+	VerifyURL string        `json:"verifyUrl" mapstructure:"verify_url"`
+	Secret    string        `json:"-" mapstructure:"secret"`
+	Timeout   time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// ReplayConfig rejects replayed anonymous uploads. Each upload carries a
+// nonce and a timestamp; the timestamp must be within Window of the server
+// clock and a nonce is accepted once within it
+type ReplayConfig struct {
+	Enabled bool          `json:"enabled" mapstructure:"enabled"`
+	Window  time.Duration `json:"window" mapstructure:"window"`
+}
+
+// ImpersonationConfig controls support staff acting on behalf of a
+// beneficiary. Sessions require a ticket and a justification, are time-boxed
+// and end with a notice to the beneficiary
+type ImpersonationConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// OperatorRoles may start impersonation sessions
+	OperatorRoles []string `json:"operatorRoles" mapstructure:"operator_roles"`
+	// SubjectRole is the role requests carry while impersonating, so staff
+	// never act with more than a beneficiary's access
+	SubjectRole            string        `json:"subjectRole" mapstructure:"subject_role"`
+	TicketPattern          string        `json:"ticketPattern" mapstructure:"ticket_pattern"`
+	MinJustificationLength int           `json:"minJustificationLength" mapstructure:"min_justification_length"`
+	DefaultDuration        time.Duration `json:"defaultDuration" mapstructure:"default_duration"`
+	MaxDuration            time.Duration `json:"maxDuration" mapstructure:"max_duration"`
+	// NotificationURL receives the notice sent to the beneficiary once a
+	// session ends
+	NotificationURL string        `json:"notificationUrl" mapstructure:"notification_url"`
+	Timeout         time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// DownloadReceiptsConfig controls signed receipts of access to sensitive
+// documents. Accesses made in one session to one enrollment's documents are
+// collected into a receipt, issued once the session has been idle for
+// SessionIdle or open for MaxOpen
+type DownloadReceiptsConfig struct {
+	Enabled       bool     `json:"enabled" mapstructure:"enabled"`
+	DocumentTypes []string `json:"documentTypes" mapstructure:"document_types"`
+	// SigningKey is a PEM encoded P-256 private key receipts are signed with
+	SigningKey  string        `json:"-" mapstructure:"signing_key"`
+	SessionIdle time.Duration `json:"sessionIdle" mapstructure:"session_idle"`
+	MaxOpen     time.Duration `json:"maxOpen" mapstructure:"max_open"`
+	// NotifySubjects sends each issued receipt to NotificationURL, which
+	// emails it to the data subject
+	NotifySubjects  bool          `json:"notifySubjects" mapstructure:"notify_subjects"`
+	NotificationURL string        `json:"notificationUrl" mapstructure:"notification_url"`
+	Timeout         time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// TextAccessConfig controls access to the text extracted from documents by
+// OCR. Callers in FullTextRoles receive the text as extracted and every other
+// caller the redacted rendition. Reading the text of PurposeDocumentTypes
+// requires one of Purposes, which is logged with the access
+type TextAccessConfig struct {
+	FullTextRoles        []string `json:"fullTextRoles" mapstructure:"full_text_roles"`
+	PurposeDocumentTypes []string `json:"purposeDocumentTypes" mapstructure:"purpose_document_types"`
+	Purposes             []string `json:"purposes" mapstructure:"purposes"`
+}
+
+// Orchestration backends
+const (
+	OrchestrationInternal = "internal"
+	OrchestrationTemporal = "temporal"
+)
+
+// OrchestrationConfig selects where the document pipeline steps run: in the
+// ingesting process, or as activities of a Temporal workflow with durable
+// retries and a visibility UI
+type OrchestrationConfig struct {
+	Backend  string         `json:"backend" mapstructure:"backend"`
+	Temporal TemporalConfig `json:"temporal" mapstructure:"temporal"`
+}
+
+// TemporalConfig contains the Temporal connection and the retry policy of the
+// pipeline step activities; a step exhausting its attempts is recorded as
+// failed without failing the document, as in the internal pipeline
+type TemporalConfig struct {
+	HostPort             string        `json:"hostPort" mapstructure:"host_port"`
+	Namespace            string        `json:"namespace" mapstructure:"namespace"`
+	TaskQueue            string        `json:"taskQueue" mapstructure:"task_queue"`
+	WorkflowTimeout      time.Duration `json:"workflowTimeout" mapstructure:"workflow_timeout"`
+	StepTimeout          time.Duration `json:"stepTimeout" mapstructure:"step_timeout"`
+	RetryInitialInterval time.Duration `json:"retryInitialInterval" mapstructure:"retry_initial_interval"`
+	RetryMaxInterval     time.Duration `json:"retryMaxInterval" mapstructure:"retry_max_interval"`
+	MaxAttempts          int           `json:"maxAttempts" mapstructure:"max_attempts"`
+	// MaxConcurrentSteps bounds the step activities this worker runs at once
+	MaxConcurrentSteps int `json:"maxConcurrentSteps" mapstructure:"max_concurrent_steps"`
+}
+
+// CancellationConfig sets how the documents of a cancelled enrollment are
+// disposed of. Dispositions maps document types to retain, anonymize or
+// delete; other types get DefaultDisposition
+type CancellationConfig struct {
+	Enabled            bool              `json:"enabled" mapstructure:"enabled"`
+	Dispositions       map[string]string `json:"dispositions" mapstructure:"dispositions"`
+	DefaultDisposition string            `json:"defaultDisposition" mapstructure:"default_disposition"`
+	// RetainFor is how long retained documents are kept after the cancellation
+	RetainFor time.Duration `json:"retainFor" mapstructure:"retain_for"`
+	// ReportURL receives the disposition events and the completion report of
+	// each saga
+	ReportURL string        `json:"reportUrl" mapstructure:"report_url"`
+	Timeout   time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// BulkOperationsConfig bounds the bulk operations run from the admin API.
+// Operations process documents at DefaultRate per second unless the request
+// asks for another rate, never above MaxRate
+type BulkOperationsConfig struct {
+	DefaultRate  float64 `json:"defaultRate" mapstructure:"default_rate"`
+	MaxRate      float64 `json:"maxRate" mapstructure:"max_rate"`
+	MaxDocuments int     `json:"maxDocuments" mapstructure:"max_documents"`
+	// MaxRunning is how many operations may run at the same time
+	MaxRunning int `json:"maxRunning" mapstructure:"max_running"`
+}
+
+// RetentionConfig controls the purge of documents past their retention date.
+// Tenant admins are notified NoticePeriod before a document is purged, or
+// the period set for their tenant in TenantNoticePeriods, and may place holds
+// until then. Notices are posted to NotificationURL; users with one of
+// AdminRoles acknowledge them and manage holds for their tenant.
+// ExtractedDataRetention and RenditionRetention purge the text extracted from
+// documents and their other renditions earlier, without notice; zero keeps
+// them as long as the document
+type RetentionConfig struct {
+	Enabled             bool                     `json:"enabled" mapstructure:"enabled"`
+	Interval            time.Duration            `json:"interval" mapstructure:"interval"`
+	NoticePeriod        time.Duration            `json:"noticePeriod" mapstructure:"notice_period"`
+	TenantNoticePeriods map[string]time.Duration `json:"tenantNoticePeriods" mapstructure:"tenant_notice_periods"`
+	NotificationURL     string                   `json:"notificationUrl" mapstructure:"notification_url"`
+	AdminRoles          []string                 `json:"adminRoles" mapstructure:"admin_roles"`
+	Timeout             time.Duration            `json:"timeout" mapstructure:"timeout"`
+	ExtractedDataRetention time.Duration `json:"extractedDataRetention" mapstructure:"extracted_data_retention"`
+	RenditionRetention     time.Duration `json:"renditionRetention" mapstructure:"rendition_retention"`
+}
+
+// NoticePeriodFor returns the notice period of a tenant
+func (c RetentionConfig) NoticePeriodFor(tenantID string) time.Duration {
+	if period, ok := c.TenantNoticePeriods[tenantID]; ok {
+		return period
 	}
-	if len(c.SecurityConfig.TrustedOrigins) == 0 {
-		return fmt.Errorf("trusted origins must be specified")
+	return c.NoticePeriod
+}
+
+// QuotaConfig sets soft request quotas on the API for users with one of
+// Roles, per tenant. A tenant gets Default, or its entry in Tenants, per
+// Window; beyond it requests spend burst credits, and only once those run
+// out are they rejected
+type QuotaConfig struct {
+	Enabled bool                         `json:"enabled" mapstructure:"enabled"`
+	Roles   []string                     `json:"roles" mapstructure:"roles"`
+	Window  time.Duration                `json:"window" mapstructure:"window"`
+	Default TenantQuotaConfig            `json:"default" mapstructure:"default"`
+	Tenants map[string]TenantQuotaConfig `json:"tenants" mapstructure:"tenants"`
+}
+
+// TenantQuotaConfig is the quota of a tenant. Burst credits accrue at
+// BurstCreditsPerHour up to MaxBurstCredits and each pays for one request
+// over Requests
+type TenantQuotaConfig struct {
+	Requests            int     `json:"requests" mapstructure:"requests"`
+	BurstCreditsPerHour float64 `json:"burstCreditsPerHour" mapstructure:"burst_credits_per_hour"`
+	MaxBurstCredits     float64 `json:"maxBurstCredits" mapstructure:"max_burst_credits"`
+}
+
+// QuotaFor returns the quota of a tenant
+func (c QuotaConfig) QuotaFor(tenantID string) TenantQuotaConfig {
+	if quota, ok := c.Tenants[tenantID]; ok {
+		return quota
 	}
+	return c.Default
+}
 
-	return nil
+// valid reports whether the quota admits requests and its credits are not
+// negative
+func (q TenantQuotaConfig) valid() bool {
+	return q.Requests > 0 && q.BurstCreditsPerHour >= 0 && q.MaxBurstCredits >= 0
 }
 
-// setDefaults sets default values for configuration
-func setDefaults(v *viper.Viper) {
-	// MinIO defaults
-	v.SetDefault("minio.use_ssl", true)
-	v.SetDefault("minio.upload_timeout", time.Second*30)
-	v.SetDefault("minio.download_timeout", time.Second*30)
-	v.SetDefault("minio.max_connections", 100)
+// MaintenanceConfig controls maintenance mode, in which the service is
+// read-only: downloads keep working and writes are refused with 503 and
+// Message. Enabled starts the service in maintenance mode; the maintenance
+// feature flag and the admin API switch it at runtime
+type MaintenanceConfig struct {
+	Enabled    bool          `json:"enabled" mapstructure:"enabled"`
+	Message    string        `json:"message" mapstructure:"message"`
+	RetryAfter time.Duration `json:"retryAfter" mapstructure:"retry_after"`
+}
 
-	// Azure defaults
-	v.SetDefault("azure.ocr_timeout", time.Second*10)
-	v.SetDefault("azure.classification_timeout", time.Second*10)
-	v.SetDefault("azure.max_retries", 3)
-	v.SetDefault("azure.retry_interval", time.Second*1)
-	v.SetDefault("azure.confidence_threshold", 0.85)
+// AccessLogConfig controls the access log. Requests are logged at
+// SampleRate, and always when they fail. Bodies are logged only with
+// LogBodies, only when they are JSON and truncated to MaxBodyBytes; the
+// values of ScrubFields are redacted and CPF-like numbers masked. File
+// contents are never logged
+type AccessLogConfig struct {
+	Enabled      bool     `json:"enabled" mapstructure:"enabled"`
+	SampleRate   float64  `json:"sampleRate" mapstructure:"sample_rate"`
+	LogBodies    bool     `json:"logBodies" mapstructure:"log_bodies"`
+	MaxBodyBytes int      `json:"maxBodyBytes" mapstructure:"max_body_bytes"`
+	ScrubFields  []string `json:"scrubFields" mapstructure:"scrub_fields"`
+	// SkipPaths are never logged, such as probes and metrics scrapes
+	SkipPaths []string `json:"skipPaths" mapstructure:"skip_paths"`
+}
+
+// AccessEventsConfig controls the view, print and export events the portal's
+// secure viewer reports for a document. Events are signed with a key derived
+// per document and session from SigningKey, and a user who prints, exports or
+// spreads across more documents than the thresholds allow in a Window raises
+// an anomaly
+type AccessEventsConfig struct {
+	Enabled            bool                  `json:"enabled" mapstructure:"enabled"`
+	SigningKey         string                `json:"-" mapstructure:"signing_key"`
+	PreviousSigningKey string                `json:"-" mapstructure:"previous_signing_key"`
+	MaxClockSkew       time.Duration         `json:"maxClockSkew" mapstructure:"max_clock_skew"`
+	Window             time.Duration         `json:"window" mapstructure:"window"`
+	Thresholds         AccessEventThresholds `json:"thresholds" mapstructure:"thresholds"`
+}
+
+// AccessEventThresholds are the counts per user and window above which
+// reported access events raise an anomaly; zero disables a threshold
+type AccessEventThresholds struct {
+	PrintsPerUser    int `json:"printsPerUser" mapstructure:"prints_per_user"`
+	ExportsPerUser   int `json:"exportsPerUser" mapstructure:"exports_per_user"`
+	DocumentsPerUser int `json:"documentsPerUser" mapstructure:"documents_per_user"`
+}
+
+// SealConfig controls enrollment seals. When the underwriting service reports
+// an enrollment closed, its document set is sealed under a Merkle root of the
+// document hashes, signed and optionally timestamped by an RFC 3161 authority
+type SealConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	// SigningKey is a PEM encoded P-256 private key seals are signed with
+	SigningKey string `json:"-" mapstructure:"signing_key"`
+	// TimestampURL is an RFC 3161 time-stamp authority; seals are not
+	// timestamped when empty
+	TimestampURL string        `json:"timestampUrl" mapstructure:"timestamp_url"`
+	Timeout      time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// MetricsPrivacyConfig controls the tenant label of per-tenant metrics, which
+// could otherwise reveal the activity of small corporate clients. A tenant is
+// labelled by its ID only once MinTenantSubjects distinct enrollments were
+// seen for it and at most MaxTenants tenants are labelled; the others are
+// aggregated under "other". SensitiveFamilies never carry a tenant label
+type MetricsPrivacyConfig struct {
+	Enabled           bool     `json:"enabled" mapstructure:"enabled"`
+	MinTenantSubjects int      `json:"minTenantSubjects" mapstructure:"min_tenant_subjects"`
+	MaxTenants        int      `json:"maxTenants" mapstructure:"max_tenants"`
+	SensitiveFamilies []string `json:"sensitiveFamilies" mapstructure:"sensitive_families"`
+}
+
+// ConsistencyConfig controls the read-your-writes guarantee after a write.
+// Written documents are kept in a write-through cache for CacheTTL, so a GET
+// carrying the version returned by the write is served even when the
+// replica it reaches has not caught up; such a GET waits up to WaitTimeout
+// for the version to appear
+type ConsistencyConfig struct {
+	CacheTTL     time.Duration `json:"cacheTtl" mapstructure:"cache_ttl"`
+	WaitTimeout  time.Duration `json:"waitTimeout" mapstructure:"wait_timeout"`
+	PollInterval time.Duration `json:"pollInterval" mapstructure:"poll_interval"`
+}
+
+// CoordinationConfig controls which instance runs each background job.
+// When enabled, jobs are locked with PostgreSQL advisory locks so that of
+// several replicas only one purges, rotates keys or delivers the outbox; the
+// lock is renewed every RenewInterval. InstanceID names this instance in the
+// job status and defaults to the hostname
+type CoordinationConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	InstanceID    string        `json:"instanceId" mapstructure:"instance_id"`
+	RenewInterval time.Duration `json:"renewInterval" mapstructure:"renew_interval"`
+}
+
+// SpoolConfig controls the write-ahead spool that accepts uploads while
+// object storage is unavailable. Spooled objects are the same ciphertext that
+// would have been uploaded, kept in Directory until they are drained to
+// storage; uploads are refused once MaxBytes are spooled
+type SpoolConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	Directory     string        `json:"directory" mapstructure:"directory"`
+	MaxBytes      int64         `json:"maxBytes" mapstructure:"max_bytes"`
+	DrainInterval time.Duration `json:"drainInterval" mapstructure:"drain_interval"`
+}
+
+// ETAConfig controls the processing time estimates given to clients. Stage
+// durations are smoothed with weight Smoothing on each new sample, and the
+// reviews of the last HistoryWindow seed them on startup. The review queue is
+// reloaded every RefreshInterval and status streams poll every StreamInterval
+type ETAConfig struct {
+	Smoothing       float64       `json:"smoothing" mapstructure:"smoothing"`
+	HistoryWindow   time.Duration `json:"historyWindow" mapstructure:"history_window"`
+	RefreshInterval time.Duration `json:"refreshInterval" mapstructure:"refresh_interval"`
+	StreamInterval  time.Duration `json:"streamInterval" mapstructure:"stream_interval"`
+}
+
+// ConverterConfig contains settings of the document converter turning office
+// documents into PDF. The sidecar provider posts documents to a LibreOffice
+// headless sidecar exposing the Gotenberg conversion API
+type ConverterConfig struct {
+	Enabled  bool          `json:"enabled" mapstructure:"enabled"`
+	Provider string        `json:"provider" mapstructure:"provider"`
+	BaseURL  string        `json:"baseUrl" mapstructure:"base_url"`
+	Timeout  time.Duration `json:"timeout" mapstructure:"timeout"`
+	// MaxConcurrent bounds conversions in flight; LibreOffice converts one
+	// document per process and queues the rest
+	MaxConcurrent int                 `json:"maxConcurrent" mapstructure:"max_concurrent"`
+	Transport     HTTPTransportConfig `json:"transport" mapstructure:"transport"`
+}
+
+// PageCleanupConfig controls the removal of blank and duplicate pages from
+// scanned PDFs. Pages are rendered at DPI; a page is blank when less than
+// BlankInkRatio of it is ink, and duplicates an earlier page when their
+// perceptual hashes differ in at most DuplicateDistance bits
+type PageCleanupConfig struct {
+	Enabled           bool    `json:"enabled" mapstructure:"enabled"`
+	DPI               float64 `json:"dpi" mapstructure:"dpi"`
+	BlankInkRatio     float64 `json:"blankInkRatio" mapstructure:"blank_ink_ratio"`
+	DuplicateDistance int     `json:"duplicateDistance" mapstructure:"duplicate_distance"`
+	MaxPages          int     `json:"maxPages" mapstructure:"max_pages"`
+}
+
+// LanguageConfig controls detection of the language of extracted text. Text
+// detected in another language than TargetLanguage is machine translated for
+// reviewers when translation is enabled
+type LanguageConfig struct {
+	Enabled        bool    `json:"enabled" mapstructure:"enabled"`
+	TargetLanguage string  `json:"targetLanguage" mapstructure:"target_language"`
+	MinConfidence  float64 `json:"minConfidence" mapstructure:"min_confidence"`
+}
+
+// TranslationConfig contains settings of the machine translation provider.
+// Only the redacted text of documents in SourceLanguages is sent to it
+type TranslationConfig struct {
+	Enabled         bool                `json:"enabled" mapstructure:"enabled"`
+	Provider        string              `json:"provider" mapstructure:"provider"`
+	BaseURL         string              `json:"baseUrl" mapstructure:"base_url"`
+	APIKey          string              `json:"-" mapstructure:"api_key"`
+	SourceLanguages []string            `json:"sourceLanguages" mapstructure:"source_languages"`
+	MaxCharacters   int                 `json:"maxCharacters" mapstructure:"max_characters"`
+	Timeout         time.Duration       `json:"timeout" mapstructure:"timeout"`
+	Transport       HTTPTransportConfig `json:"transport" mapstructure:"transport"`
+}
+
+// AccessibilityConfig controls the tagged PDF rendition screen readers read.
+// Pages are rendered at DPI as the image layer of the rendition
+type AccessibilityConfig struct {
+	Enabled     bool    `json:"enabled" mapstructure:"enabled"`
+	DPI         float64 `json:"dpi" mapstructure:"dpi"`
+	JPEGQuality int     `json:"jpegQuality" mapstructure:"jpeg_quality"`
+	// Language is the BCP 47 tag screen readers pick a voice by
+	Language string `json:"language" mapstructure:"language"`
+}
+
+// SearchablePDFConfig controls the searchable PDF rendition of scans. Pages of
+// PDFs are rendered at DPI; JPEG scans are embedded as uploaded and sized as
+// if scanned at DPI
+type SearchablePDFConfig struct {
+	Enabled     bool    `json:"enabled" mapstructure:"enabled"`
+	DPI         float64 `json:"dpi" mapstructure:"dpi"`
+	JPEGQuality int     `json:"jpegQuality" mapstructure:"jpeg_quality"`
+}
+
+// SearchConfig controls the search index of extracted text. The index holds
+// no text: each term is stored as a keyed token, under a key derived for each
+// tenant from IndexKey, so a copy of the index reveals nothing without the
+// key and the tokens of one tenant never match another's. Users with one of
+// Roles search the documents of their tenant
+type SearchConfig struct {
+	Enabled       bool     `json:"enabled" mapstructure:"enabled"`
+	IndexKey      string   `json:"-" mapstructure:"index_key"`
+	Roles         []string `json:"roles" mapstructure:"roles"`
+	MinTermLength int      `json:"minTermLength" mapstructure:"min_term_length"`
+	MaxResults    int      `json:"maxResults" mapstructure:"max_results"`
+}
+
+// OCRCanaryConfig runs the Candidate OCR provider alongside the provider in
+// service on Percentage of the documents OCR reads, to compare their output
+// before switching providers. A document diverges when the word agreement of
+// the two texts falls below MinAgreement or a field extracted from them
+// differs
+type OCRCanaryConfig struct {
+	Enabled      bool    `json:"enabled" mapstructure:"enabled"`
+	Candidate    string  `json:"candidate" mapstructure:"candidate"`
+	Percentage   float64 `json:"percentage" mapstructure:"percentage"`
+	MinAgreement float64 `json:"minAgreement" mapstructure:"min_agreement"`
+}
+
+// SyntheticProbeConfig runs an end-to-end probe every Interval: a known test
+// document is uploaded under EnrollmentID and TenantID as DocumentType,
+// processed by the pipeline, downloaded and verified, then deleted. The
+// document type chooses the pipeline steps the probe exercises. A probe not
+// done within Timeout fails; its document is deleted all the same
+type SyntheticProbeConfig struct {
+	Enabled      bool          `json:"enabled" mapstructure:"enabled"`
+	Interval     time.Duration `json:"interval" mapstructure:"interval"`
+	Timeout      time.Duration `json:"timeout" mapstructure:"timeout"`
+	PollInterval time.Duration `json:"pollInterval" mapstructure:"poll_interval"`
+	EnrollmentID string        `json:"enrollmentId" mapstructure:"enrollment_id"`
+	TenantID     string        `json:"tenantId" mapstructure:"tenant_id"`
+	DocumentType string        `json:"documentType" mapstructure:"document_type"`
+}
+
+// SLOConfig sets the error budget of OCR: under OCRObjective, the share of
+// OCR runs over Window that may fail once MinEvents ran. While the budget is
+// blown, documents are stored with OCR deferred. Every DrainInterval the
+// provider is tried, and after RecoverySuccesses successes in a row the
+// deferred documents are processed BatchSize at a time
+type SLOConfig struct {
+	Enabled           bool          `json:"enabled" mapstructure:"enabled"`
+	OCRObjective      float64       `json:"ocrObjective" mapstructure:"ocr_objective"`
+	Window            time.Duration `json:"window" mapstructure:"window"`
+	MinEvents         int           `json:"minEvents" mapstructure:"min_events"`
+	RecoverySuccesses int           `json:"recoverySuccesses" mapstructure:"recovery_successes"`
+	DrainInterval     time.Duration `json:"drainInterval" mapstructure:"drain_interval"`
+	BatchSize         int           `json:"batchSize" mapstructure:"batch_size"`
+}
+
+// WebhooksConfig lets tenant admins, with one of AdminRoles, manage up to
+// MaxSubscriptions webhook endpoints for their tenant. Deliveries time out
+// after Timeout, a rotated secret keeps signing deliveries for
+// SecretGracePeriod and the last HistorySize deliveries of each subscription
+// are kept. Endpoints must use https and resolve to public addresses unless
+// AllowPrivateNetworks is set, which is meant for development only
+type WebhooksConfig struct {
+	Enabled              bool          `json:"enabled" mapstructure:"enabled"`
+	AdminRoles           []string      `json:"adminRoles" mapstructure:"admin_roles"`
+	MaxSubscriptions     int           `json:"maxSubscriptions" mapstructure:"max_subscriptions"`
+	Timeout              time.Duration `json:"timeout" mapstructure:"timeout"`
+	SecretGracePeriod    time.Duration `json:"secretGracePeriod" mapstructure:"secret_grace_period"`
+	HistorySize          int           `json:"historySize" mapstructure:"history_size"`
+	AllowPrivateNetworks bool          `json:"allowPrivateNetworks" mapstructure:"allow_private_networks"`
+}
+
+// APIKeysConfig lets admins issue API keys to machine integrations, such as
+// corporate HR systems, that cannot authenticate through the gateway.
+// Requests made with a key carry Role. Keys are limited to RatePerSecond
+// requests with bursts of Burst unless issued with their own limits, and a
+// rotated key's previous secret stays valid for RotationGracePeriod
+type APIKeysConfig struct {
+	Enabled             bool          `json:"enabled" mapstructure:"enabled"`
+	Role                string        `json:"role" mapstructure:"role"`
+	RatePerSecond       float64       `json:"ratePerSecond" mapstructure:"rate_per_second"`
+	Burst               int           `json:"burst" mapstructure:"burst"`
+	RotationGracePeriod time.Duration `json:"rotationGracePeriod" mapstructure:"rotation_grace_period"`
+}
+
+// DelegationConfig accepts tokens the identity provider issued through
+// OAuth2 token exchange (RFC 8693), which let a service call on behalf of an
+// end user. Tokens must be issued by Issuer for Audience, signed with a key
+// published at JWKSURL, and name one of AllowedActors in their act claim.
+// The end user's tenant and role are read from TenantClaim and RoleClaim
+type DelegationConfig struct {
+	Enabled       bool          `json:"enabled" mapstructure:"enabled"`
+	Issuer        string        `json:"issuer" mapstructure:"issuer"`
+	Audience      string        `json:"audience" mapstructure:"audience"`
+	JWKSURL       string        `json:"jwksUrl" mapstructure:"jwks_url"`
+	JWKSRefresh   time.Duration `json:"jwksRefresh" mapstructure:"jwks_refresh"`
+	Timeout       time.Duration `json:"timeout" mapstructure:"timeout"`
+	AllowedActors []string      `json:"allowedActors" mapstructure:"allowed_actors"`
+	TenantClaim   string        `json:"tenantClaim" mapstructure:"tenant_claim"`
+	RoleClaim     string        `json:"roleClaim" mapstructure:"role_claim"`
+	ClockSkew     time.Duration `json:"clockSkew" mapstructure:"clock_skew"`
+}
+
+// GatewayAuthConfig verifies the token of the end user the gateway forwards
+// in Authorization, the source of the user's identity and role. Tokens must
+// be issued by Issuer for Audience and signed with a key published at
+// JWKSURL. The tenant, role and session are read from TenantClaim, RoleClaim
+// and SessionClaim. Without it requests carry no role, and role checks treat
+// them as the most restricted
+type GatewayAuthConfig struct {
+	Enabled      bool          `json:"enabled" mapstructure:"enabled"`
+	Issuer       string        `json:"issuer" mapstructure:"issuer"`
+	Audience     string        `json:"audience" mapstructure:"audience"`
+	JWKSURL      string        `json:"jwksUrl" mapstructure:"jwks_url"`
+	JWKSRefresh  time.Duration `json:"jwksRefresh" mapstructure:"jwks_refresh"`
+	Timeout      time.Duration `json:"timeout" mapstructure:"timeout"`
+	TenantClaim  string        `json:"tenantClaim" mapstructure:"tenant_claim"`
+	RoleClaim    string        `json:"roleClaim" mapstructure:"role_claim"`
+	SessionClaim string        `json:"sessionClaim" mapstructure:"session_claim"`
+	ClockSkew    time.Duration `json:"clockSkew" mapstructure:"clock_skew"`
+}
+
+// ServiceAccountsConfig gives each internal caller, such as OCR workers,
+// retention jobs and migration tools, its own credential. An account holds
+// only the permissions of its internal role, so a leaked credential cannot
+// reach routes outside them
+type ServiceAccountsConfig struct {
+	Enabled  bool                   `json:"enabled" mapstructure:"enabled"`
+	Accounts []ServiceAccountConfig `json:"accounts" mapstructure:"accounts"`
+}
+
+// ServiceAccountConfig is one internal caller. TokenHash is the hex SHA-256
+// of its bearer token, so the token itself is never stored in config
+type ServiceAccountConfig struct {
+	Name      string `json:"name" mapstructure:"name"`
+	Role      string `json:"role" mapstructure:"role"`
+	TokenHash string `json:"-" mapstructure:"token_hash"`
+}
+
+// VirusScanConfig controls scanning of uploads with a ClamAV daemon reached
+// at Address. Verdicts are cached by content hash for CacheTTL, so identical
+// files of batch imports are scanned once; the signature database version
+// is checked every VersionCheckInterval and a new one drops every verdict
+type VirusScanConfig struct {
+	Enabled              bool          `json:"enabled" mapstructure:"enabled"`
+	Address              string        `json:"address" mapstructure:"address"`
+	Timeout              time.Duration `json:"timeout" mapstructure:"timeout"`
+	CacheTTL             time.Duration `json:"cacheTtl" mapstructure:"cache_ttl"`
+	VersionCheckInterval time.Duration `json:"versionCheckInterval" mapstructure:"version_check_interval"`
+}
+
+// CDRConfig controls content disarm and reconstruction of PDF uploads. A PDF
+// carrying active content, such as JavaScript, launch actions or embedded
+// files, is rebuilt from its pages rendered at DPI and compressed at
+// Quality, and the upload is quarantined as a rendition only ForensicRoles
+// may download. With AlwaysRebuild every PDF is rebuilt. PDFs of more than
+// MaxPages pages carrying active content are rejected
+type CDRConfig struct {
+	Enabled       bool     `json:"enabled" mapstructure:"enabled"`
+	DPI           float64  `json:"dpi" mapstructure:"dpi"`
+	Quality       int      `json:"quality" mapstructure:"quality"`
+	MaxPages      int      `json:"maxPages" mapstructure:"max_pages"`
+	AlwaysRebuild bool     `json:"alwaysRebuild" mapstructure:"always_rebuild"`
+	ForensicRoles []string `json:"forensicRoles" mapstructure:"forensic_roles"`
+}
+
+// QuarantineConfig controls the quarantine of suspicious files: uploads the
+// virus scanner found malware in, originals of PDFs rebuilt without active
+// content and documents flagged as suspected fraud. Held files are only
+// fetched sealed to SandboxKey, the PEM encoded P-256 public key of the
+// forensic sandbox, so their plaintext never reaches a browser
+type QuarantineConfig struct {
+	Enabled    bool   `json:"enabled" mapstructure:"enabled"`
+	SandboxKey string `json:"sandboxKey" mapstructure:"sandbox_key"`
+}
+
+// SPIFFEConfig lets internal services authenticate with X.509 SVIDs in
+// place of service account tokens. A second listener at Address serves the
+// API over mutual TLS with the service's own SVID; peers must present an
+// SVID of TrustDomain chaining to the bundle. The SVID, its key and the
+// bundle are files kept current by the SPIRE agent and reread every
+// ReloadInterval. Policies map SPIFFE IDs to service account roles; callers
+// matching none are refused
+type SPIFFEConfig struct {
+	Enabled        bool                 `json:"enabled" mapstructure:"enabled"`
+	Address        string               `json:"address" mapstructure:"address"`
+	TrustDomain    string               `json:"trustDomain" mapstructure:"trust_domain"`
+	SVIDPath       string               `json:"svidPath" mapstructure:"svid_path"`
+	SVIDKeyPath    string               `json:"svidKeyPath" mapstructure:"svid_key_path"`
+	BundlePath     string               `json:"bundlePath" mapstructure:"bundle_path"`
+	ReloadInterval time.Duration        `json:"reloadInterval" mapstructure:"reload_interval"`
+	Policies       []SPIFFEPolicyConfig `json:"policies" mapstructure:"policies"`
+}
+
+// SPIFFEPolicyConfig grants Role to the workload with SPIFFE ID ID, or to
+// every workload under it when ID ends in /*
+type SPIFFEPolicyConfig struct {
+	ID   string `json:"id" mapstructure:"id"`
+	Role string `json:"role" mapstructure:"role"`
+}
+
+// RetryBudgetConfig bounds the retries made on behalf of one request. The
+// storage, KMS and OCR layers draw from one pool of Retries per request
+// instead of each retrying on its own
+type RetryBudgetConfig struct {
+	Enabled bool `json:"enabled" mapstructure:"enabled"`
+	Retries int  `json:"retries" mapstructure:"retries"`
+}
+
+// Error reporting providers
+const (
+	ErrorReportingSentry  = "sentry"
+	ErrorReportingRollbar = "rollbar"
+)
+
+// ErrorReportingConfig sends panics recovered while serving requests to an
+// error tracking service. Reports are queued and sent in the background, so a
+// slow provider never holds up a response; reports past QueueSize are dropped
+type ErrorReportingConfig struct {
+	// Provider is sentry, rollbar or empty to only log and count panics
+	Provider    string        `json:"provider" mapstructure:"provider"`
+	// DSN is the Sentry project DSN, https://<key>@<host>/<project>
+	DSN         string        `json:"-" mapstructure:"dsn"`
+	// AccessToken is a Rollbar project token with post_server_item scope
+	AccessToken string        `json:"-" mapstructure:"access_token"`
+	// Endpoint overrides the Rollbar API, such as for a regional instance
+	Endpoint    string        `json:"endpoint" mapstructure:"endpoint"`
+	Environment string        `json:"environment" mapstructure:"environment"`
+	Release     string        `json:"release" mapstructure:"release"`
+	Timeout     time.Duration `json:"timeout" mapstructure:"timeout"`
+	QueueSize   int           `json:"queueSize" mapstructure:"queue_size"`
+}
+
+// BulkReviewConfig limits the bulk review actions of underwriters. A bulk
+// action decides at most MaxItems documents, and each reviewer may start
+// RatePerMinute actions a minute with bursts of Burst. Each reviewer keeps
+// at most MaxSavedFilters saved review filters
+type BulkReviewConfig struct {
+	MaxItems        int     `json:"maxItems" mapstructure:"max_items"`
+	RatePerMinute   float64 `json:"ratePerMinute" mapstructure:"rate_per_minute"`
+	Burst           int     `json:"burst" mapstructure:"burst"`
+	MaxSavedFilters int     `json:"maxSavedFilters" mapstructure:"max_saved_filters"`
+}
+
+// SLAConfig sets the service level for deciding documents. Each document is
+// to get its final review decision within Deadline of its upload, or the
+// deadline set for its type in DocumentTypeDeadlines. Open documents are
+// checked every ScanInterval and escalated as the share of their deadline
+// elapsed passes each of Escalations, in ascending order; a share of 1 or
+// more escalates documents past their deadline
+type SLAConfig struct {
+	Enabled               bool                     `json:"enabled" mapstructure:"enabled"`
+	Deadline              time.Duration            `json:"deadline" mapstructure:"deadline"`
+	DocumentTypeDeadlines map[string]time.Duration `json:"documentTypeDeadlines" mapstructure:"document_type_deadlines"`
+	Escalations           []float64                `json:"escalations" mapstructure:"escalations"`
+	ScanInterval          time.Duration            `json:"scanInterval" mapstructure:"scan_interval"`
+}
+
+// DeadlineFor returns the deadline of a document type
+func (c SLAConfig) DeadlineFor(documentType string) time.Duration {
+	if deadline, ok := c.DocumentTypeDeadlines[documentType]; ok {
+		return deadline
+	}
+	return c.Deadline
+}
+
+// BusinessCalendarConfig sets the business calendars SLA deadlines and review
+// estimates count time on. A calendar is open from OpensAt to ClosesAt, as
+// 15:04 in its Timezone, on weekdays other than holidays. Holidays are the
+// Brazilian national holidays when NationalHolidays is set, with Carnival
+// and Corpus Christi when OptionalHolidays is set too, and those listed in
+// the calendar. Calendars, such as one per state, take what they leave
+// unset from Default and add their holidays to its own. Tenants names the
+// calendar of each tenant; other tenants use Default
+type BusinessCalendarConfig struct {
+	Enabled          bool                      `json:"enabled" mapstructure:"enabled"`
+	NationalHolidays bool                      `json:"nationalHolidays" mapstructure:"national_holidays"`
+	OptionalHolidays bool                      `json:"optionalHolidays" mapstructure:"optional_holidays"`
+	Default          CalendarConfig            `json:"default" mapstructure:"default"`
+	Calendars        map[string]CalendarConfig `json:"calendars" mapstructure:"calendars"`
+	Tenants          map[string]string         `json:"tenants" mapstructure:"tenants"`
+}
+
+// CalendarConfig is a business calendar. Holidays are MM-DD dates recurring
+// every year or YYYY-MM-DD dates, each optionally followed by a space and
+// the holiday name
+type CalendarConfig struct {
+	Timezone string   `json:"timezone" mapstructure:"timezone"`
+	OpensAt  string   `json:"opensAt" mapstructure:"opens_at"`
+	ClosesAt string   `json:"closesAt" mapstructure:"closes_at"`
+	Holidays []string `json:"holidays" mapstructure:"holidays"`
+}
+
+// CalendarFor returns the name of a tenant's calendar, empty for Default
+func (c BusinessCalendarConfig) CalendarFor(tenantID string) string {
+	return c.Tenants[tenantID]
+}
+
+// ProviderQuotaConfig tracks the quota of the OCR provider: Azure allows
+// TransactionsPerSecond calls a second and MonthlyTransactions submissions a
+// calendar month (UTC), zero for no limit. Calls are paced under the
+// per-second limit, and once ThrottleAt of the monthly quota is used the
+// rest is spread evenly to the end of the month. With a Fallback resource,
+// in another subscription or region, OCR switches to it once SwitchAt of the
+// monthly quota is used or while Azure throttles us, and back when the
+// month turns
+type ProviderQuotaConfig struct {
+	Enabled    bool                 `json:"enabled" mapstructure:"enabled"`
+	Azure      ProviderLimitsConfig `json:"azure" mapstructure:"azure"`
+	Fallback   FallbackOCRConfig    `json:"fallback" mapstructure:"fallback"`
+	ThrottleAt float64              `json:"throttleAt" mapstructure:"throttle_at"`
+	SwitchAt   float64              `json:"switchAt" mapstructure:"switch_at"`
+}
+
+// ProviderLimitsConfig is the quota of a provider resource
+type ProviderLimitsConfig struct {
+	TransactionsPerSecond float64 `json:"transactionsPerSecond" mapstructure:"transactions_per_second"`
+	MonthlyTransactions   int64   `json:"monthlyTransactions" mapstructure:"monthly_transactions"`
+}
+
+// FallbackOCRConfig is a second Azure Computer Vision resource; none is used
+// when Endpoint is empty
+type FallbackOCRConfig struct {
+	Endpoint        string               `json:"endpoint" mapstructure:"endpoint"`
+	SubscriptionKey string               `json:"subscriptionKey" mapstructure:"subscription_key"`
+	Limits          ProviderLimitsConfig `json:"limits" mapstructure:"limits"`
+}
+
+// ProviderHealthConfig scores providers on their calls over the last
+// Window: the share that succeeded, scaled down by how far their mean
+// latency exceeds the LatencyTarget of their kind. A provider scoring under
+// HealthyScore after at least MinSamples calls is unhealthy. New work goes
+// to the healthy providers in their configured order, then to the unhealthy
+// ones by score. Calls age out of the window, so a provider left without
+// work is tried again once it has too few to be scored
+type ProviderHealthConfig struct {
+	Enabled        bool                     `json:"enabled" mapstructure:"enabled"`
+	Window         time.Duration            `json:"window" mapstructure:"window"`
+	MinSamples     int                      `json:"minSamples" mapstructure:"min_samples"`
+	HealthyScore   float64                  `json:"healthyScore" mapstructure:"healthy_score"`
+	LatencyTarget  time.Duration            `json:"latencyTarget" mapstructure:"latency_target"`
+	LatencyTargets map[string]time.Duration `json:"latencyTargets" mapstructure:"latency_targets"`
+}
+
+// LatencyTargetFor returns the latency target of a kind of provider
+func (c ProviderHealthConfig) LatencyTargetFor(kind string) time.Duration {
+	if target, ok := c.LatencyTargets[kind]; ok {
+		return target
+	}
+	return c.LatencyTarget
+}
+
+// ProcessingTiersConfig routes documents by size and page count to
+// processing tiers, so a single-page CNH is not processed behind a 200-page
+// hospital record. A document goes to the first tier it fits in and to the
+// last one when it fits in none
+type ProcessingTiersConfig struct {
+	Enabled bool                   `json:"enabled" mapstructure:"enabled"`
+	Tiers   []ProcessingTierConfig `json:"tiers" mapstructure:"tiers"`
+}
+
+// ProcessingTierConfig is a processing tier: the documents of at most
+// MaxSize bytes and MaxPages pages, zero for no limit. Concurrency documents
+// are processed at once per instance while up to QueueSize more wait; an
+// upload that would wait beyond that is refused before it is stored. The
+// steps of a document get Timeout. With Temporal the tier is a task queue
+// of its own, named after the configured one
+type ProcessingTierConfig struct {
+	Name        string        `json:"name" mapstructure:"name"`
+	MaxSize     int64         `json:"maxSize" mapstructure:"max_size"`
+	MaxPages    int           `json:"maxPages" mapstructure:"max_pages"`
+	Concurrency int           `json:"concurrency" mapstructure:"concurrency"`
+	QueueSize   int           `json:"queueSize" mapstructure:"queue_size"`
+	Timeout     time.Duration `json:"timeout" mapstructure:"timeout"`
+}
+
+// TierFor returns the tier of a document of size bytes and pages pages; a
+// negative page count is unknown and only the size is compared
+func (c ProcessingTiersConfig) TierFor(size int64, pages int) ProcessingTierConfig {
+	for _, tier := range c.Tiers {
+		if tier.MaxSize > 0 && size > tier.MaxSize {
+			continue
+		}
+		if tier.MaxPages > 0 && pages > tier.MaxPages {
+			continue
+		}
+		return tier
+	}
+	return c.Tiers[len(c.Tiers)-1]
+}
+
+// CountsPages reports whether any tier routes on page count
+func (c ProcessingTiersConfig) CountsPages() bool {
+	for _, tier := range c.Tiers {
+		if tier.MaxPages > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LargeDocumentConfig accepts uploads past the size limit of their channel,
+// up to MaxSize, as large documents instead of rejecting them. A large
+// document is stored and scanned in the request like any other, but its
+// processing steps run in the background, a batch every Interval, and each
+// tenant may upload only Uploads of them per Window
+type LargeDocumentConfig struct {
+	Enabled   bool          `json:"enabled" mapstructure:"enabled"`
+	MaxSize   int64         `json:"maxSize" mapstructure:"max_size"`
+	Uploads   int           `json:"uploads" mapstructure:"uploads"`
+	Window    time.Duration `json:"window" mapstructure:"window"`
+	BatchSize int           `json:"batchSize" mapstructure:"batch_size"`
+	Interval  time.Duration `json:"interval" mapstructure:"interval"`
+}
+
+// LoadConfig loads and validates service configuration from the specified path
+func LoadConfig(path string) (*Config, error) {
+	v := viper.New()
+
+	// Set default configuration values
+	setDefaults(v)
+
+	// Set configuration path and type
+	if path != "" {
+		v.AddConfigPath(path)
+	} else {
+		v.AddConfigPath(defaultConfigPath)
+	}
+	v.SetConfigName(defaultConfigName)
+	v.SetConfigType(defaultConfigType)
+
+	// Enable environment variable override
+	v.AutomaticEnv()
+	v.SetEnvPrefix("DOC_SERVICE")
+
+	// Read configuration
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+
+	config := &Config{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// Validate performs comprehensive validation of all configuration settings
+func (c *Config) Validate() error {
+	// Validate MinIO configuration
+	if c.MinioConfig.Endpoint == "" {
+		return fmt.Errorf("minio endpoint is required")
+	}
+	if c.MinioConfig.BucketName == "" {
+		return fmt.Errorf("minio bucket name is required")
+	}
+	if c.MinioConfig.UploadTimeout <= 0 {
+		return fmt.Errorf("invalid upload timeout")
+	}
+
+	// Validate Azure configuration
+	if c.AzureConfig.Endpoint == "" {
+		return fmt.Errorf("azure endpoint is required")
+	}
+	if c.AzureConfig.SubscriptionKey == "" {
+		return fmt.Errorf("azure subscription key is required")
+	}
+	if c.AzureConfig.ConfidenceThreshold <= 0 || c.AzureConfig.ConfidenceThreshold > 1 {
+		return fmt.Errorf("confidence threshold must be between 0 and 1")
+	}
+
+	// Validate service configuration
+	if c.ServiceConfig.Port <= 0 || c.ServiceConfig.Port > 65535 {
+		return fmt.Errorf("invalid port number")
+	}
+	if err := c.validateLimits(); err != nil {
+		return err
+	}
+
+	// Validate security configuration
+	if c.SecurityConfig.EncryptionKey == "" {
+		return fmt.Errorf("encryption key is required")
+	}
+	switch c.SecurityConfig.EncryptionAlgorithm {
+	case "AES-256", "XCHACHA20-POLY1305":
+	default:
+		return fmt.Errorf("unsupported encryption algorithm")
+	}
+	if c.SecurityConfig.MaxMessagesPerKey < 0 {
+		return fmt.Errorf("max messages per key cannot be negative")
+	}
+	if c.SecurityConfig.KMSTimeout <= 0 {
+		return fmt.Errorf("KMS timeout must be positive")
+	}
+	if len(c.SecurityConfig.TrustedOrigins) == 0 {
+		return fmt.Errorf("trusted origins must be specified")
+	}
+	for _, proxy := range c.SecurityConfig.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("trusted proxy %q is not an IP address or CIDR", proxy)
+		}
+	}
+
+	// Validate enrollment client configuration
+	if feature := c.enrollmentClientUser(); feature != "" && c.EnrollmentConfig.BaseURL == "" {
+		return fmt.Errorf("enrollment service base url is required by %s", feature)
+	}
+
+	// Validate WhatsApp configuration
+	if c.WhatsAppConfig.Enabled {
+		if c.WhatsAppConfig.PhoneNumberID == "" || c.WhatsAppConfig.AccessToken == "" {
+			return fmt.Errorf("whatsapp phone number id and access token are required")
+		}
+		if c.WhatsAppConfig.AppSecret == "" || c.WhatsAppConfig.VerifyToken == "" {
+			return fmt.Errorf("whatsapp app secret and verify token are required")
+		}
+	}
+
+	// Validate SFTP configuration
+	if c.SFTPConfig.Enabled {
+		if c.SFTPConfig.Host == "" || c.SFTPConfig.Username == "" || c.SFTPConfig.PrivateKeyPath == "" {
+			return fmt.Errorf("sftp host, username and private key path are required")
+		}
+		if c.SFTPConfig.HostKey == "" {
+			return fmt.Errorf("sftp host key is required")
+		}
+		if c.SFTPConfig.PollInterval <= 0 {
+			return fmt.Errorf("invalid sftp poll interval")
+		}
+	}
+
+	// Validate underwriting integration configuration
+	if c.UnderwritingConfig.Enabled {
+		switch c.UnderwritingConfig.Transport {
+		case "rest":
+			if c.UnderwritingConfig.BaseURL == "" {
+				return fmt.Errorf("underwriting base url is required")
+			}
+		case "grpc":
+			if c.UnderwritingConfig.GRPCTarget == "" {
+				return fmt.Errorf("underwriting grpc target is required")
+			}
+		default:
+			return fmt.Errorf("unsupported underwriting transport: %s", c.UnderwritingConfig.Transport)
+		}
+		if len(c.UnderwritingConfig.RequiredDocumentTypes) == 0 {
+			return fmt.Errorf("underwriting required document types must be specified")
+		}
+	}
+
+	// Validate outbox configuration
+	if c.OutboxConfig.PollInterval <= 0 || c.OutboxConfig.BatchSize <= 0 || c.OutboxConfig.MaxAttempts <= 0 {
+		return fmt.Errorf("invalid outbox delivery settings")
+	}
+
+	// Validate signature verification configuration
+	if c.SignatureConfig.Enabled && c.SignatureConfig.TrustedRootsPath == "" {
+		return fmt.Errorf("ICP-Brasil trusted roots path is required when signature verification is enabled")
+	}
+
+	// Validate gov.br verification configuration
+	if c.GovBRConfig.Enabled {
+		if c.GovBRConfig.BaseURL == "" {
+			return fmt.Errorf("gov.br base url is required")
+		}
+		if c.GovBRConfig.RequestsPerSecond <= 0 || c.GovBRConfig.Burst <= 0 {
+			return fmt.Errorf("invalid gov.br rate limit settings")
+		}
+	}
+
+	// Validate Receita Federal CPF check configuration
+	if c.ReceitaConfig.Enabled {
+		if c.ReceitaConfig.Provider != "broker" {
+			return fmt.Errorf("unsupported CPF situation provider: %s", c.ReceitaConfig.Provider)
+		}
+		if c.ReceitaConfig.BaseURL == "" {
+			return fmt.Errorf("CPF situation provider base url is required")
+		}
+	}
+
+	// Validate sanctions and PEP screening configuration
+	if c.ScreeningConfig.Enabled {
+		if c.ScreeningConfig.Provider != "rest" {
+			return fmt.Errorf("unsupported screening provider: %s", c.ScreeningConfig.Provider)
+		}
+		if c.ScreeningConfig.BaseURL == "" {
+			return fmt.Errorf("screening provider base url is required")
+		}
+		if c.ScreeningConfig.MinScore <= 0 || c.ScreeningConfig.MinScore > 1 {
+			return fmt.Errorf("screening min score must be between 0 and 1")
+		}
+	}
+
+	// Validate address normalization configuration
+	if c.AddressConfig.Enabled && c.AddressConfig.ViaCEPBaseURL == "" {
+		return fmt.Errorf("ViaCEP base url is required when address normalization is enabled")
+	}
+
+	// Validate auto-decision rules
+	if c.AutoDecisionConfig.Enabled {
+		for _, rule := range c.AutoDecisionConfig.Rules {
+			if rule.Name == "" || rule.When == "" {
+				return fmt.Errorf("auto-decision rules require a name and a condition")
+			}
+			if rule.Decision != "approve" && rule.Decision != "reject" && rule.Decision != "review" {
+				return fmt.Errorf("invalid decision %q for auto-decision rule %s", rule.Decision, rule.Name)
+			}
+		}
+	}
+
+	// Validate experiment configuration
+	if c.ExperimentsConfig.Enabled {
+		for _, experiment := range c.ExperimentsConfig.Experiments {
+			if experiment.Name == "" || experiment.Step == "" || len(experiment.Variants) == 0 {
+				return fmt.Errorf("experiments require a name, a step and at least one variant")
+			}
+			total := 0.0
+			for _, variant := range experiment.Variants {
+				if variant.Name == "" || variant.Name == "control" || variant.Implementation == "" {
+					return fmt.Errorf("invalid variant in experiment %s", experiment.Name)
+				}
+				if variant.Percentage <= 0 {
+					return fmt.Errorf("variant %s of experiment %s must have a positive percentage", variant.Name, experiment.Name)
+				}
+				total += variant.Percentage
+			}
+			if total > 100 {
+				return fmt.Errorf("variant percentages of experiment %s exceed 100", experiment.Name)
+			}
+		}
+	}
+
+	flagNames := make(map[string]bool)
+	for _, flag := range c.FeatureFlagsConfig.Flags {
+		if flag.Name == "" {
+			return fmt.Errorf("feature flag name is required")
+		}
+		if flagNames[flag.Name] {
+			return fmt.Errorf("duplicate feature flag: %s", flag.Name)
+		}
+		flagNames[flag.Name] = true
+	}
+	switch c.FeatureFlagsConfig.Provider {
+	case "config":
+	case "unleash", "flagsmith":
+		if c.FeatureFlagsConfig.URL == "" || c.FeatureFlagsConfig.APIKey == "" {
+			return fmt.Errorf("feature flag provider %s requires a URL and an API key", c.FeatureFlagsConfig.Provider)
+		}
+		if c.FeatureFlagsConfig.RefreshInterval <= 0 || c.FeatureFlagsConfig.Timeout <= 0 {
+			return fmt.Errorf("feature flag refresh interval and timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported feature flag provider: %s", c.FeatureFlagsConfig.Provider)
+	}
+
+	if c.StorageMigrationConfig.Enabled {
+		secondary := c.StorageMigrationConfig.Secondary
+		if secondary.Endpoint == "" || secondary.BucketName == "" {
+			return fmt.Errorf("storage migration requires a secondary endpoint and bucket")
+		}
+		if secondary.Endpoint == c.MinioConfig.Endpoint && secondary.BucketName == c.MinioConfig.BucketName {
+			return fmt.Errorf("storage migration secondary must differ from the MinIO bucket")
+		}
+		if c.StorageMigrationConfig.CompareTimeout <= 0 {
+			return fmt.Errorf("storage migration compare timeout must be positive")
+		}
+		if c.StorageMigrationConfig.MaxConcurrentCompares <= 0 {
+			return fmt.Errorf("storage migration max concurrent compares must be positive")
+		}
+		if c.StorageMigrationConfig.ReconcileInterval <= 0 || c.StorageMigrationConfig.ReconcileBatchSize <= 0 {
+			return fmt.Errorf("storage migration reconcile interval and batch size must be positive")
+		}
+		if err := secondary.Credentials.Validate(secondary.AccessKey, secondary.SecretKey); err != nil {
+			return fmt.Errorf("invalid storage migration secondary: %w", err)
+		}
+	}
+
+	if c.DatabaseConfig.Enabled {
+		if c.DatabaseConfig.URL == "" {
+			return fmt.Errorf("database URL is required")
+		}
+		if c.DatabaseConfig.ConnectTimeout <= 0 {
+			return fmt.Errorf("database connect timeout must be positive")
+		}
+	}
+
+	if c.StartupConfig.CheckTimeout <= 0 {
+		return fmt.Errorf("startup check timeout must be positive")
+	}
+
+	if err := c.MinioConfig.Transport.Validate(); err != nil {
+		return fmt.Errorf("invalid minio transport: %w", err)
+	}
+	if err := c.AzureConfig.Transport.Validate(); err != nil {
+		return fmt.Errorf("invalid azure transport: %w", err)
+	}
+	if c.MinioConfig.Hedging.Enabled && c.MinioConfig.Hedging.Delay <= 0 {
+		return fmt.Errorf("minio hedging delay must be positive")
+	}
+	if err := c.MinioConfig.Credentials.Validate(c.MinioConfig.AccessKey, c.MinioConfig.SecretKey); err != nil {
+		return fmt.Errorf("invalid minio credentials: %w", err)
+	}
+
+	if c.ConcurrencyConfig.Enabled {
+		if err := c.ConcurrencyConfig.OCR.Validate(); err != nil {
+			return fmt.Errorf("invalid OCR concurrency limit: %w", err)
+		}
+		if err := c.ConcurrencyConfig.Storage.Validate(); err != nil {
+			return fmt.Errorf("invalid storage concurrency limit: %w", err)
+		}
+	}
+
+	if c.RenditionsConfig.PresignedRedirects && c.RenditionsConfig.PresignExpiry <= 0 {
+		return fmt.Errorf("rendition presign expiry must be positive")
+	}
+	if c.RenditionsConfig.Generate {
+		if c.RenditionsConfig.ThumbnailWidth < 1 || c.RenditionsConfig.PreviewWidth < 1 {
+			return fmt.Errorf("rendition widths must be at least 1")
+		}
+		if c.RenditionsConfig.JPEGQuality < 1 || c.RenditionsConfig.JPEGQuality > 100 {
+			return fmt.Errorf("rendition JPEG quality must be between 1 and 100")
+		}
+		if c.RenditionsConfig.DPI <= 0 {
+			return fmt.Errorf("rendition DPI must be positive")
+		}
+	}
+
+	if c.PageOCRConfig.Enabled {
+		if c.PageOCRConfig.MaxConcurrentPages < 1 || c.PageOCRConfig.PerTenantConcurrency < 1 {
+			return fmt.Errorf("page OCR concurrency limits must be at least 1")
+		}
+		if c.PageOCRConfig.MaxPages < 1 {
+			return fmt.Errorf("page OCR max pages must be at least 1")
+		}
+	}
+
+	if c.PreviewConfig.SigningKey != "" {
+		if len(c.PreviewConfig.SigningKey) < 32 {
+			return fmt.Errorf("preview signing key must be at least 32 bytes")
+		}
+		if c.PreviewConfig.TokenTTL <= 0 || c.PreviewConfig.TokenTTL > time.Hour {
+			return fmt.Errorf("preview token TTL must be between 0 and 1h")
+		}
+	}
+
+	if c.SecureViewerConfig.Enabled && (c.SecureViewerConfig.DPI < 50 || c.SecureViewerConfig.DPI > 300) {
+		return fmt.Errorf("secure viewer DPI must be between 50 and 300")
+	}
+
+	if c.AnalyticsExportConfig.Enabled {
+		if len(c.AnalyticsExportConfig.HashKey) < 32 {
+			return fmt.Errorf("analytics export hash key must be at least 32 bytes")
+		}
+		if c.AnalyticsExportConfig.Format != "parquet" && c.AnalyticsExportConfig.Format != "csv" {
+			return fmt.Errorf("analytics export format must be parquet or csv")
+		}
+		if c.AnalyticsExportConfig.Destination.BucketName == "" {
+			return fmt.Errorf("analytics export destination bucket is required")
+		}
+		if c.AnalyticsExportConfig.Interval <= 0 {
+			return fmt.Errorf("analytics export interval must be positive")
+		}
+		destination := c.AnalyticsExportConfig.Destination
+		if err := destination.Credentials.Validate(destination.AccessKey, destination.SecretKey); err != nil {
+			return fmt.Errorf("invalid analytics export destination: %w", err)
+		}
+	}
+
+	if c.PortabilityConfig.Enabled {
+		if len(c.PortabilityConfig.LinkSigningKey) < 32 {
+			return fmt.Errorf("portability link signing key must be at least 32 bytes")
+		}
+		if c.PortabilityConfig.LinkTTL <= 0 || c.PortabilityConfig.LinkTTL > 7*24*time.Hour {
+			return fmt.Errorf("portability link TTL must be between 0 and 7 days")
+		}
+	}
+
+	if c.ConsentConfig.Enabled && !c.RequestSigningConfig.Enabled {
+		return fmt.Errorf("consent revocation requires request signing to verify consent events")
+	}
+
+	// Validate processing activity overrides
+	for _, activity := range c.ROPAConfig.Activities {
+		if activity.Operation == "" || activity.Purpose == "" || activity.LegalBasis == "" {
+			return fmt.Errorf("processing activities require an operation, a purpose and a legal basis")
+		}
+	}
+
+	// Validate encryption scanner configuration
+	if c.EncryptionScanConfig.Enabled {
+		if c.EncryptionScanConfig.Interval <= 0 {
+			return fmt.Errorf("encryption scan interval must be positive")
+		}
+		if c.EncryptionScanConfig.SampleSize <= 0 {
+			return fmt.Errorf("encryption scan sample size must be positive")
+		}
+	}
+
+	// Validate crypto-shredding configuration. Without a grant to revoke, KMS
+	// would unwrap a shredded key for anyone holding its wrapped copy
+	if c.CryptoShreddingConfig.Enabled && c.CryptoShreddingConfig.GranteePrincipal == "" {
+		return fmt.Errorf("crypto-shredding requires a grantee principal")
+	}
+	if c.CryptoShreddingConfig.KeyCacheTTL < 0 || c.CryptoShreddingConfig.KeyCacheTTL > time.Hour {
+		return fmt.Errorf("crypto-shredding key cache TTL must be between 0 and 1h")
+	}
+
+	// Validate key usage audit configuration
+	if c.KeyAuditConfig.Enabled && c.KeyAuditConfig.Retention <= 0 {
+		return fmt.Errorf("key usage retention must be positive")
+	}
+	thresholds := c.KeyAuditConfig.Thresholds
+	if thresholds.DecryptsPerPrincipal < 0 || thresholds.DocumentsPerPrincipal < 0 ||
+		thresholds.FailuresPerPrincipal < 0 || thresholds.KMSCallsPerKeyVersion < 0 {
+		return fmt.Errorf("key usage thresholds cannot be negative")
+	}
+
+	// Validate client-side encryption configuration
+	if c.ClientEncryptionConfig.Enabled {
+		if len(c.ClientEncryptionConfig.RecipientKeys) == 0 {
+			return fmt.Errorf("client encryption requires at least one recipient key")
+		}
+		if len(c.ClientEncryptionConfig.DocumentTypes) == 0 {
+			return fmt.Errorf("client encryption document types must be specified")
+		}
+	}
+
+	// Validate request signing configuration
+	if c.RequestSigningConfig.Enabled {
+		if _, ok := c.RequestSigningConfig.Keys[c.RequestSigningConfig.KeyID]; !ok {
+			return fmt.Errorf("request signing key id must name a configured key")
+		}
+		for id, secret := range c.RequestSigningConfig.Keys {
+			if len(secret) < 32 {
+				return fmt.Errorf("request signing key %s must be at least 32 bytes", id)
+			}
+		}
+		if c.RequestSigningConfig.MaxSkew <= 0 {
+			return fmt.Errorf("request signing max skew must be positive")
+		}
+	}
+
+	// Validate abuse protection configuration
+	if c.AbuseConfig.Enabled {
+		if c.AbuseConfig.MaxFailures < 1 {
+			return fmt.Errorf("abuse max failures must be at least 1")
+		}
+		if c.AbuseConfig.FailureWindow <= 0 || c.AbuseConfig.BanDuration <= 0 {
+			return fmt.Errorf("abuse failure window and ban duration must be positive")
+		}
+	}
+	if c.AbuseConfig.Captcha.Enabled {
+		if c.AbuseConfig.Captcha.VerifyURL == "" || c.AbuseConfig.Captcha.Secret == "" {
+			return fmt.Errorf("captcha verify URL and secret must be specified")
+		}
+		if c.AbuseConfig.Captcha.Timeout <= 0 {
+			return fmt.Errorf("captcha timeout must be positive")
+		}
+	}
+	if c.AbuseConfig.Replay.Enabled && c.AbuseConfig.Replay.Window <= 0 {
+		return fmt.Errorf("upload replay window must be positive")
+	}
+	if c.AbuseConfig.Replay.Enabled && !c.DatabaseConfig.Enabled {
+		return fmt.Errorf("upload replay protection requires the database to be enabled")
+	}
+
+	// Each token type is signed with its own secret, so a token of one kind
+	// can never be replayed as another
+	secrets := map[string]string{}
+	purposes := map[string]string{
+		"preview signing key":               c.PreviewConfig.SigningKey,
+		"previous preview signing key":      c.PreviewConfig.PreviousSigningKey,
+		"portability link signing key":      c.PortabilityConfig.LinkSigningKey,
+		"access event signing key":          c.AccessEventsConfig.SigningKey,
+		"previous access event signing key": c.AccessEventsConfig.PreviousSigningKey,
+	}
+	for id, secret := range c.RequestSigningConfig.Keys {
+		purposes["request signing key "+id] = secret
+	}
+	for purpose, secret := range purposes {
+		if secret == "" {
+			continue
+		}
+		if other, ok := secrets[secret]; ok {
+			return fmt.Errorf("%s must not reuse the %s", purpose, other)
+		}
+		secrets[secret] = purpose
+	}
+
+	// Validate impersonation configuration
+	if c.ImpersonationConfig.Enabled {
+		if len(c.ImpersonationConfig.OperatorRoles) == 0 || c.ImpersonationConfig.SubjectRole == "" {
+			return fmt.Errorf("impersonation operator roles and subject role must be specified")
+		}
+		if _, err := regexp.Compile(c.ImpersonationConfig.TicketPattern); err != nil || c.ImpersonationConfig.TicketPattern == "" {
+			return fmt.Errorf("impersonation ticket pattern must be a valid regular expression")
+		}
+		if c.ImpersonationConfig.DefaultDuration <= 0 || c.ImpersonationConfig.DefaultDuration > c.ImpersonationConfig.MaxDuration {
+			return fmt.Errorf("impersonation default duration must be positive and at most the max duration")
+		}
+		if c.ImpersonationConfig.NotificationURL == "" {
+			return fmt.Errorf("impersonation notification URL must be specified")
+		}
+	}
+
+	// Validate download receipt configuration
+	if c.DownloadReceiptsConfig.Enabled {
+		if c.DownloadReceiptsConfig.SigningKey == "" {
+			return fmt.Errorf("download receipt signing key must be specified")
+		}
+		if c.DownloadReceiptsConfig.SessionIdle <= 0 || c.DownloadReceiptsConfig.MaxOpen < c.DownloadReceiptsConfig.SessionIdle {
+			return fmt.Errorf("download receipt session idle must be positive and at most the max open duration")
+		}
+		if c.DownloadReceiptsConfig.NotifySubjects && c.DownloadReceiptsConfig.NotificationURL == "" {
+			return fmt.Errorf("download receipt notification URL must be specified to notify subjects")
+		}
+	}
+
+	// Validate extracted text access
+	if len(c.TextAccessConfig.PurposeDocumentTypes) > 0 && len(c.TextAccessConfig.Purposes) == 0 {
+		return fmt.Errorf("text access purposes must be specified when documents require a purpose")
+	}
+
+	// Validate pipeline orchestration configuration
+	switch c.OrchestrationConfig.Backend {
+	case "", OrchestrationInternal:
+	case OrchestrationTemporal:
+		temporal := c.OrchestrationConfig.Temporal
+		if temporal.HostPort == "" || temporal.Namespace == "" || temporal.TaskQueue == "" {
+			return fmt.Errorf("temporal host, namespace and task queue are required for temporal orchestration")
+		}
+		if temporal.WorkflowTimeout <= 0 || temporal.StepTimeout <= 0 || temporal.RetryInitialInterval <= 0 ||
+			temporal.RetryMaxInterval < temporal.RetryInitialInterval || temporal.MaxAttempts <= 0 || temporal.MaxConcurrentSteps <= 0 {
+			return fmt.Errorf("invalid temporal orchestration settings")
+		}
+	default:
+		return fmt.Errorf("unsupported orchestration backend: %s", c.OrchestrationConfig.Backend)
+	}
+
+	// Validate enrollment cancellation cleanup configuration
+	if c.CancellationConfig.Enabled {
+		if !c.RequestSigningConfig.Enabled {
+			return fmt.Errorf("enrollment cancellation requires request signing to verify enrollment events")
+		}
+		if c.CancellationConfig.ReportURL == "" || c.CancellationConfig.Timeout <= 0 || c.CancellationConfig.RetainFor <= 0 {
+			return fmt.Errorf("invalid enrollment cancellation settings")
+		}
+		dispositions := []string{c.CancellationConfig.DefaultDisposition}
+		for _, disposition := range c.CancellationConfig.Dispositions {
+			dispositions = append(dispositions, disposition)
+		}
+		for _, disposition := range dispositions {
+			if disposition != models.DispositionRetain && disposition != models.DispositionAnonymize && disposition != models.DispositionDelete {
+				return fmt.Errorf("unsupported document disposition: %q", disposition)
+			}
+		}
+	}
+
+	// Validate bulk operation limits
+	if c.BulkOperationsConfig.DefaultRate <= 0 || c.BulkOperationsConfig.MaxRate < c.BulkOperationsConfig.DefaultRate {
+		return fmt.Errorf("bulk operation default rate must be positive and not exceed the maximum rate")
+	}
+	if c.BulkOperationsConfig.MaxDocuments <= 0 || c.BulkOperationsConfig.MaxRunning <= 0 {
+		return fmt.Errorf("invalid bulk operation limits")
+	}
+
+	// Validate retention purge configuration
+	if c.RetentionConfig.Enabled {
+		if c.RetentionConfig.Interval <= 0 || c.RetentionConfig.NoticePeriod <= 0 || c.RetentionConfig.Timeout <= 0 {
+			return fmt.Errorf("invalid retention purge settings")
+		}
+		if c.RetentionConfig.NotificationURL == "" || len(c.RetentionConfig.AdminRoles) == 0 {
+			return fmt.Errorf("retention notification URL and admin roles must be specified")
+		}
+		for tenant, period := range c.RetentionConfig.TenantNoticePeriods {
+			if period <= 0 {
+				return fmt.Errorf("invalid retention notice period for tenant %s", tenant)
+			}
+		}
+		if c.RetentionConfig.ExtractedDataRetention < 0 || c.RetentionConfig.RenditionRetention < 0 {
+			return fmt.Errorf("extracted data and rendition retention cannot be negative")
+		}
+	}
+
+	// Validate soft quota configuration
+	if c.QuotaConfig.Enabled {
+		if c.QuotaConfig.Window <= 0 || len(c.QuotaConfig.Roles) == 0 {
+			return fmt.Errorf("quota window and roles must be specified")
+		}
+		if !c.QuotaConfig.Default.valid() {
+			return fmt.Errorf("invalid default quota")
+		}
+		for tenant, quota := range c.QuotaConfig.Tenants {
+			if !quota.valid() {
+				return fmt.Errorf("invalid quota for tenant %s", tenant)
+			}
+		}
+	}
+
+	// Validate maintenance mode configuration
+	if c.MaintenanceConfig.Message == "" || c.MaintenanceConfig.RetryAfter <= 0 {
+		return fmt.Errorf("maintenance message and retry after must be specified")
+	}
+
+	// Validate access log configuration
+	if c.AccessLogConfig.SampleRate < 0 || c.AccessLogConfig.SampleRate > 1 {
+		return fmt.Errorf("access log sample rate must be between 0 and 1")
+	}
+	if c.AccessLogConfig.LogBodies && c.AccessLogConfig.MaxBodyBytes <= 0 {
+		return fmt.Errorf("access log body size must be positive")
+	}
+
+	// Validate access event configuration
+	if c.AccessEventsConfig.Enabled {
+		if len(c.AccessEventsConfig.SigningKey) < 32 {
+			return fmt.Errorf("access event signing key must be at least 32 bytes")
+		}
+		if c.AccessEventsConfig.MaxClockSkew <= 0 || c.AccessEventsConfig.Window <= 0 {
+			return fmt.Errorf("access event clock skew and window must be positive")
+		}
+		thresholds := c.AccessEventsConfig.Thresholds
+		if thresholds.PrintsPerUser < 0 || thresholds.ExportsPerUser < 0 || thresholds.DocumentsPerUser < 0 {
+			return fmt.Errorf("access event thresholds cannot be negative")
+		}
+	}
+
+	// Validate enrollment seal configuration
+	if c.SealConfig.Enabled {
+		if c.SealConfig.SigningKey == "" {
+			return fmt.Errorf("enrollment seal signing key must be specified")
+		}
+		if !c.RequestSigningConfig.Enabled {
+			return fmt.Errorf("enrollment seals require request signing to verify underwriting events")
+		}
+		if c.SealConfig.Timeout <= 0 {
+			return fmt.Errorf("enrollment seal timeout must be positive")
+		}
+	}
+
+	// Validate metrics privacy configuration
+	if c.MetricsPrivacyConfig.Enabled && (c.MetricsPrivacyConfig.MinTenantSubjects < 1 || c.MetricsPrivacyConfig.MaxTenants < 1) {
+		return fmt.Errorf("metrics privacy min tenant subjects and max tenants must be at least 1")
+	}
+
+	// Validate read-your-writes configuration
+	if c.ConsistencyConfig.CacheTTL <= 0 || c.ConsistencyConfig.PollInterval <= 0 || c.ConsistencyConfig.WaitTimeout < 0 {
+		return fmt.Errorf("consistency cache TTL and poll interval must be positive and wait timeout not negative")
+	}
+
+	// Validate background job coordination configuration
+	if c.CoordinationConfig.Enabled && !c.DatabaseConfig.Enabled {
+		return fmt.Errorf("job coordination requires the database to be enabled")
+	}
+	if c.CoordinationConfig.RenewInterval <= 0 {
+		return fmt.Errorf("job lock renew interval must be positive")
+	}
+
+	// Validate upload spool configuration
+	if c.SpoolConfig.Enabled {
+		if c.SpoolConfig.Directory == "" {
+			return fmt.Errorf("upload spool directory is required")
+		}
+		if c.SpoolConfig.MaxBytes < c.ServiceConfig.MaxFileSize {
+			return fmt.Errorf("upload spool must hold at least one maximum size document")
+		}
+		if c.SpoolConfig.DrainInterval <= 0 {
+			return fmt.Errorf("upload spool drain interval must be positive")
+		}
+	}
+
+	// Validate processing ETA configuration
+	if c.ETAConfig.Smoothing <= 0 || c.ETAConfig.Smoothing > 1 {
+		return fmt.Errorf("ETA smoothing must be in (0, 1]")
+	}
+	if c.ETAConfig.HistoryWindow < 0 {
+		return fmt.Errorf("ETA history window cannot be negative")
+	}
+	if c.ETAConfig.RefreshInterval <= 0 || c.ETAConfig.StreamInterval <= 0 {
+		return fmt.Errorf("ETA refresh and stream intervals must be positive")
+	}
+
+	// Validate document converter configuration
+	if c.ConverterConfig.Enabled {
+		if c.ConverterConfig.Provider != "sidecar" {
+			return fmt.Errorf("unsupported document converter provider: %s", c.ConverterConfig.Provider)
+		}
+		if c.ConverterConfig.BaseURL == "" {
+			return fmt.Errorf("document converter base url is required")
+		}
+		if c.ConverterConfig.Timeout <= 0 || c.ConverterConfig.MaxConcurrent <= 0 {
+			return fmt.Errorf("document converter timeout and max concurrent conversions must be positive")
+		}
+		if err := c.ConverterConfig.Transport.Validate(); err != nil {
+			return fmt.Errorf("invalid converter transport: %w", err)
+		}
+	}
+
+	if c.PageCleanupConfig.Enabled {
+		if c.PageCleanupConfig.DPI <= 0 {
+			return fmt.Errorf("page cleanup DPI must be positive")
+		}
+		if c.PageCleanupConfig.BlankInkRatio < 0 || c.PageCleanupConfig.BlankInkRatio >= 1 {
+			return fmt.Errorf("page cleanup blank ink ratio must be between 0 and 1")
+		}
+		if c.PageCleanupConfig.DuplicateDistance < 0 {
+			return fmt.Errorf("page cleanup duplicate distance cannot be negative")
+		}
+		if c.PageCleanupConfig.MaxPages < 1 {
+			return fmt.Errorf("page cleanup max pages must be at least 1")
+		}
+	}
+
+	// Validate language detection and translation configuration
+	if c.LanguageConfig.Enabled {
+		if c.LanguageConfig.TargetLanguage == "" {
+			return fmt.Errorf("language target language is required")
+		}
+		if c.LanguageConfig.MinConfidence <= 0 || c.LanguageConfig.MinConfidence > 1 {
+			return fmt.Errorf("language min confidence must be between 0 and 1")
+		}
+	}
+	if c.TranslationConfig.Enabled {
+		if !c.LanguageConfig.Enabled {
+			return fmt.Errorf("translation requires language detection")
+		}
+		if c.TranslationConfig.Provider != "libretranslate" {
+			return fmt.Errorf("unsupported translation provider: %s", c.TranslationConfig.Provider)
+		}
+		if c.TranslationConfig.BaseURL == "" {
+			return fmt.Errorf("translation base url is required")
+		}
+		if len(c.TranslationConfig.SourceLanguages) == 0 {
+			return fmt.Errorf("translation source languages are required")
+		}
+		if c.TranslationConfig.Timeout <= 0 || c.TranslationConfig.MaxCharacters <= 0 {
+			return fmt.Errorf("translation timeout and max characters must be positive")
+		}
+		if err := c.TranslationConfig.Transport.Validate(); err != nil {
+			return fmt.Errorf("invalid translation transport: %w", err)
+		}
+	}
+
+	if c.AccessibilityConfig.Enabled {
+		if c.AccessibilityConfig.DPI <= 0 {
+			return fmt.Errorf("accessibility DPI must be positive")
+		}
+		if c.AccessibilityConfig.JPEGQuality < 1 || c.AccessibilityConfig.JPEGQuality > 100 {
+			return fmt.Errorf("accessibility JPEG quality must be between 1 and 100")
+		}
+		if c.AccessibilityConfig.Language == "" {
+			return fmt.Errorf("accessibility language is required")
+		}
+	}
+
+	if c.SearchablePDFConfig.Enabled {
+		if c.SearchablePDFConfig.DPI <= 0 {
+			return fmt.Errorf("searchable PDF DPI must be positive")
+		}
+		if c.SearchablePDFConfig.JPEGQuality < 1 || c.SearchablePDFConfig.JPEGQuality > 100 {
+			return fmt.Errorf("searchable PDF JPEG quality must be between 1 and 100")
+		}
+	}
+
+	if c.SearchConfig.Enabled {
+		if len(c.SearchConfig.IndexKey) < 32 {
+			return fmt.Errorf("search index key must be at least 32 bytes")
+		}
+		if len(c.SearchConfig.Roles) == 0 {
+			return fmt.Errorf("search roles must be specified")
+		}
+		if c.SearchConfig.MinTermLength <= 0 || c.SearchConfig.MaxResults <= 0 {
+			return fmt.Errorf("search minimum term length and maximum results must be positive")
+		}
+	}
+
+	if c.OCRCanaryConfig.Enabled {
+		if c.OCRCanaryConfig.Candidate == "" {
+			return fmt.Errorf("OCR canary candidate provider must be specified")
+		}
+		if c.OCRCanaryConfig.Percentage <= 0 || c.OCRCanaryConfig.Percentage > 100 {
+			return fmt.Errorf("OCR canary percentage must be between 0 and 100")
+		}
+		if c.OCRCanaryConfig.MinAgreement < 0 || c.OCRCanaryConfig.MinAgreement > 1 {
+			return fmt.Errorf("OCR canary minimum agreement must be between 0 and 1")
+		}
+	}
+
+	if c.SyntheticProbeConfig.Enabled {
+		if c.SyntheticProbeConfig.Interval <= 0 || c.SyntheticProbeConfig.Timeout <= 0 || c.SyntheticProbeConfig.PollInterval <= 0 {
+			return fmt.Errorf("synthetic probe interval, timeout and poll interval must be positive")
+		}
+		if c.SyntheticProbeConfig.PollInterval >= c.SyntheticProbeConfig.Timeout {
+			return fmt.Errorf("synthetic probe poll interval must be shorter than its timeout")
+		}
+		if c.SyntheticProbeConfig.EnrollmentID == "" || c.SyntheticProbeConfig.DocumentType == "" {
+			return fmt.Errorf("synthetic probe enrollment ID and document type must be specified")
+		}
+	}
+
+	if c.SLOConfig.Enabled {
+		if c.SLOConfig.OCRObjective <= 0 || c.SLOConfig.OCRObjective >= 1 {
+			return fmt.Errorf("OCR objective must be between 0 and 1")
+		}
+		if c.SLOConfig.Window <= 0 || c.SLOConfig.DrainInterval <= 0 {
+			return fmt.Errorf("SLO window and drain interval must be positive")
+		}
+		if c.SLOConfig.MinEvents < 1 || c.SLOConfig.RecoverySuccesses < 1 || c.SLOConfig.BatchSize < 1 {
+			return fmt.Errorf("SLO minimum events, recovery successes and batch size must be at least 1")
+		}
+	}
+
+	if c.WebhooksConfig.Enabled {
+		if len(c.WebhooksConfig.AdminRoles) == 0 {
+			return fmt.Errorf("webhook admin roles are required when webhooks are enabled")
+		}
+		if c.WebhooksConfig.MaxSubscriptions < 1 || c.WebhooksConfig.HistorySize < 1 {
+			return fmt.Errorf("webhook subscription limit and history size must be at least 1")
+		}
+		if c.WebhooksConfig.Timeout <= 0 || c.WebhooksConfig.SecretGracePeriod < 0 {
+			return fmt.Errorf("webhook timeout must be positive and secret grace period not negative")
+		}
+	}
+
+	if c.APIKeysConfig.Enabled {
+		if c.APIKeysConfig.Role == "" {
+			return fmt.Errorf("API key role is required when API keys are enabled")
+		}
+		if c.APIKeysConfig.RatePerSecond <= 0 || c.APIKeysConfig.Burst < 1 {
+			return fmt.Errorf("API key rate must be positive and burst at least 1")
+		}
+		if c.APIKeysConfig.RotationGracePeriod < 0 {
+			return fmt.Errorf("API key rotation grace period cannot be negative")
+		}
+	}
+
+	if c.DelegationConfig.Enabled {
+		if c.DelegationConfig.Issuer == "" || c.DelegationConfig.Audience == "" || c.DelegationConfig.JWKSURL == "" {
+			return fmt.Errorf("delegation issuer, audience and JWKS URL are required when delegation is enabled")
+		}
+		if len(c.DelegationConfig.AllowedActors) == 0 {
+			return fmt.Errorf("delegation requires at least one allowed actor")
+		}
+		if c.DelegationConfig.JWKSRefresh <= 0 || c.DelegationConfig.Timeout <= 0 || c.DelegationConfig.ClockSkew < 0 {
+			return fmt.Errorf("delegation JWKS refresh and timeout must be positive and clock skew not negative")
+		}
+		if c.DelegationConfig.TenantClaim == "" || c.DelegationConfig.RoleClaim == "" {
+			return fmt.Errorf("delegation tenant and role claims are required")
+		}
+	}
+
+	if c.GatewayAuthConfig.Enabled {
+		if c.GatewayAuthConfig.Issuer == "" || c.GatewayAuthConfig.Audience == "" || c.GatewayAuthConfig.JWKSURL == "" {
+			return fmt.Errorf("gateway auth issuer, audience and JWKS URL are required when gateway auth is enabled")
+		}
+		if c.GatewayAuthConfig.JWKSRefresh <= 0 || c.GatewayAuthConfig.Timeout <= 0 || c.GatewayAuthConfig.ClockSkew < 0 {
+			return fmt.Errorf("gateway auth JWKS refresh and timeout must be positive and clock skew not negative")
+		}
+		if c.GatewayAuthConfig.TenantClaim == "" || c.GatewayAuthConfig.RoleClaim == "" || c.GatewayAuthConfig.SessionClaim == "" {
+			return fmt.Errorf("gateway auth tenant, role and session claims are required")
+		}
+	}
+
+	if c.ServiceAccountsConfig.Enabled {
+		names := make(map[string]bool)
+		hashes := make(map[string]bool)
+		for _, account := range c.ServiceAccountsConfig.Accounts {
+			if account.Name == "" || names[account.Name] {
+				return fmt.Errorf("service account names must be set and unique")
+			}
+			if !models.ValidServiceRole(account.Role) {
+				return fmt.Errorf("service account %s has unknown role %q", account.Name, account.Role)
+			}
+			if !sha256HexPattern.MatchString(account.TokenHash) || hashes[account.TokenHash] {
+				return fmt.Errorf("service account %s token hash must be a unique hex SHA-256", account.Name)
+			}
+			names[account.Name] = true
+			hashes[account.TokenHash] = true
+		}
+	}
+
+	if c.VirusScanConfig.Enabled {
+		if c.VirusScanConfig.Address == "" {
+			return fmt.Errorf("virus scan address is required when virus scanning is enabled")
+		}
+		if c.VirusScanConfig.Timeout <= 0 || c.VirusScanConfig.CacheTTL < 0 || c.VirusScanConfig.VersionCheckInterval <= 0 {
+			return fmt.Errorf("virus scan timeout and version check interval must be positive and cache TTL not negative")
+		}
+	}
+
+	if c.CDRConfig.Enabled {
+		if c.CDRConfig.DPI <= 0 || c.CDRConfig.MaxPages <= 0 {
+			return fmt.Errorf("CDR DPI and max pages must be positive")
+		}
+		if c.CDRConfig.Quality < 1 || c.CDRConfig.Quality > 100 {
+			return fmt.Errorf("CDR quality must be between 1 and 100")
+		}
+	}
+
+	if c.QuarantineConfig.Enabled && c.QuarantineConfig.SandboxKey == "" {
+		return fmt.Errorf("quarantine requires the sandbox public key")
+	}
+
+	if c.SPIFFEConfig.Enabled {
+		if c.SPIFFEConfig.Address == "" || c.SPIFFEConfig.TrustDomain == "" {
+			return fmt.Errorf("SPIFFE address and trust domain are required when SPIFFE is enabled")
+		}
+		if c.SPIFFEConfig.SVIDPath == "" || c.SPIFFEConfig.SVIDKeyPath == "" || c.SPIFFEConfig.BundlePath == "" {
+			return fmt.Errorf("SPIFFE SVID, key and bundle paths are required when SPIFFE is enabled")
+		}
+		if c.SPIFFEConfig.ReloadInterval <= 0 {
+			return fmt.Errorf("SPIFFE reload interval must be positive")
+		}
+		for _, policy := range c.SPIFFEConfig.Policies {
+			if !strings.HasPrefix(policy.ID, "spiffe://"+c.SPIFFEConfig.TrustDomain+"/") {
+				return fmt.Errorf("SPIFFE policy %s is not in trust domain %s", policy.ID, c.SPIFFEConfig.TrustDomain)
+			}
+			if !models.ValidServiceRole(policy.Role) {
+				return fmt.Errorf("SPIFFE policy %s has unknown role %q", policy.ID, policy.Role)
+			}
+		}
+	}
+
+	if c.RetryBudgetConfig.Enabled && c.RetryBudgetConfig.Retries < 0 {
+		return fmt.Errorf("retry budget cannot be negative")
+	}
+
+	switch c.ErrorReportingConfig.Provider {
+	case "":
+	case ErrorReportingSentry:
+		if c.ErrorReportingConfig.DSN == "" {
+			return fmt.Errorf("sentry error reporting requires a DSN")
+		}
+	case ErrorReportingRollbar:
+		if c.ErrorReportingConfig.AccessToken == "" || c.ErrorReportingConfig.Endpoint == "" {
+			return fmt.Errorf("rollbar error reporting requires an access token and endpoint")
+		}
+	default:
+		return fmt.Errorf("unsupported error reporting provider: %s", c.ErrorReportingConfig.Provider)
+	}
+	if c.ErrorReportingConfig.Provider != "" && (c.ErrorReportingConfig.Timeout <= 0 || c.ErrorReportingConfig.QueueSize <= 0) {
+		return fmt.Errorf("error reporting timeout and queue size must be positive")
+	}
+
+	if c.BulkReviewConfig.MaxItems < 1 || c.BulkReviewConfig.RatePerMinute <= 0 || c.BulkReviewConfig.Burst < 1 || c.BulkReviewConfig.MaxSavedFilters < 1 {
+		return fmt.Errorf("bulk review max items, rate, burst and max saved filters must be positive")
+	}
+
+	// Validate SLA configuration
+	if c.SLAConfig.Enabled {
+		if c.SLAConfig.Deadline <= 0 || c.SLAConfig.ScanInterval <= 0 {
+			return fmt.Errorf("SLA deadline and scan interval must be positive")
+		}
+		for documentType, deadline := range c.SLAConfig.DocumentTypeDeadlines {
+			if deadline <= 0 {
+				return fmt.Errorf("invalid SLA deadline for document type %s", documentType)
+			}
+		}
+		for i, share := range c.SLAConfig.Escalations {
+			if share <= 0 || (i > 0 && share <= c.SLAConfig.Escalations[i-1]) {
+				return fmt.Errorf("SLA escalations must be positive and ascending")
+			}
+		}
+	}
+
+	// Validate business calendar configuration; calendar hours and holidays
+	// are checked as the calendars are built
+	if c.BusinessCalendarConfig.Enabled {
+		if c.BusinessCalendarConfig.Default.Timezone == "" || c.BusinessCalendarConfig.Default.OpensAt == "" || c.BusinessCalendarConfig.Default.ClosesAt == "" {
+			return fmt.Errorf("default business calendar timezone and hours must be specified")
+		}
+		for tenant, calendar := range c.BusinessCalendarConfig.Tenants {
+			if _, ok := c.BusinessCalendarConfig.Calendars[calendar]; !ok {
+				return fmt.Errorf("unknown business calendar %s for tenant %s", calendar, tenant)
+			}
+		}
+	}
+
+	// Validate provider quota configuration
+	if c.ProviderQuotaConfig.Enabled {
+		quota := c.ProviderQuotaConfig
+		if quota.ThrottleAt <= 0 || quota.ThrottleAt > 1 || quota.SwitchAt <= 0 || quota.SwitchAt > 1 {
+			return fmt.Errorf("provider quota throttle and switch shares must be between 0 and 1")
+		}
+		for _, limits := range []ProviderLimitsConfig{quota.Azure, quota.Fallback.Limits} {
+			if limits.TransactionsPerSecond < 0 || limits.MonthlyTransactions < 0 {
+				return fmt.Errorf("provider quota limits cannot be negative")
+			}
+		}
+		if quota.Fallback.Endpoint != "" && quota.Fallback.SubscriptionKey == "" {
+			return fmt.Errorf("fallback OCR subscription key must be specified")
+		}
+	}
+
+	// Validate provider health configuration
+	if c.ProviderHealthConfig.Enabled {
+		health := c.ProviderHealthConfig
+		if health.Window <= 0 || health.MinSamples <= 0 || health.LatencyTarget <= 0 {
+			return fmt.Errorf("provider health window, minimum samples and latency target must be positive")
+		}
+		if health.HealthyScore <= 0 || health.HealthyScore > 1 {
+			return fmt.Errorf("provider healthy score must be between 0 and 1")
+		}
+		for kind, target := range health.LatencyTargets {
+			if target <= 0 {
+				return fmt.Errorf("invalid provider latency target for %s", kind)
+			}
+		}
+	}
+
+	// Validate processing tiers
+	if c.ProcessingTiersConfig.Enabled {
+		if len(c.ProcessingTiersConfig.Tiers) == 0 {
+			return fmt.Errorf("at least one processing tier must be configured")
+		}
+		names := make(map[string]bool)
+		for _, tier := range c.ProcessingTiersConfig.Tiers {
+			if tier.Name == "" || names[tier.Name] {
+				return fmt.Errorf("processing tiers must have unique names")
+			}
+			names[tier.Name] = true
+			if tier.MaxSize < 0 || tier.MaxPages < 0 || tier.QueueSize < 0 {
+				return fmt.Errorf("limits of processing tier %s cannot be negative", tier.Name)
+			}
+			if tier.Concurrency <= 0 || tier.Timeout <= 0 {
+				return fmt.Errorf("concurrency and timeout of processing tier %s must be positive", tier.Name)
+			}
+		}
+	}
+
+	// Validate large document configuration
+	if c.LargeDocumentConfig.Enabled {
+		large := c.LargeDocumentConfig
+		if large.MaxSize <= c.ServiceConfig.MaxFileSize {
+			return fmt.Errorf("large document max size must exceed the max file size")
+		}
+		if large.MaxSize > models.MaxLargeDocumentSize {
+			return fmt.Errorf("large document max size cannot exceed the supported size of %d bytes", models.MaxLargeDocumentSize)
+		}
+		if large.Uploads <= 0 || large.Window <= 0 {
+			return fmt.Errorf("large document uploads and window must be positive")
+		}
+		if large.BatchSize <= 0 || large.Interval <= 0 {
+			return fmt.Errorf("large document batch size and interval must be positive")
+		}
+		if c.ServiceConfig.Limits(RouteGroupUpload).MaxBodySize < large.MaxSize {
+			return fmt.Errorf("upload max body size must admit large documents of %d bytes", large.MaxSize)
+		}
+	}
+
+	return nil
+}
+
+// validateChannel checks that a channel accepts registered types within the
+// service-wide size, and converts only types it accepts and can convert with
+// the converters configured
+func (c *Config) validateChannel(channel string) error {
+	policy := c.ServiceConfig.Channel(channel)
+	for _, mimeType := range policy.AllowedMimeTypes {
+		if _, ok := models.LookupContentType(mimeType); !ok {
+			return fmt.Errorf("MIME type %s of channel %s is not supported", mimeType, channel)
+		}
+	}
+	if policy.MaxFileSize < 0 || policy.MaxFileSize > c.ServiceConfig.MaxFileSize {
+		return fmt.Errorf("max file size of channel %s must be between 0 and the service max file size", channel)
+	}
+	for _, mimeType := range policy.ConvertToPDF {
+		if !policy.Accepts(mimeType) {
+			return fmt.Errorf("channel %s converts MIME type %s it does not accept", channel, mimeType)
+		}
+		contentType, _ := models.LookupContentType(mimeType)
+		if !contentType.PDFConvertible {
+			return fmt.Errorf("MIME type %s cannot be converted to PDF", mimeType)
+		}
+		if contentType.NeedsConverter && !c.ConverterConfig.Enabled {
+			return fmt.Errorf("channel %s converts MIME type %s, which requires the document converter", channel, mimeType)
+		}
+	}
+	return nil
+}
+
+// validateLimits checks the size, type and timeout limits and that they are
+// consistent with each other and with what the document model supports
+func (c *Config) validateLimits() error {
+	if c.ServiceConfig.MaxFileSize <= 0 {
+		return fmt.Errorf("invalid max file size")
+	}
+	if c.ServiceConfig.MaxFileSize > models.MaxDocumentSize {
+		return fmt.Errorf("max file size cannot exceed the supported document size of %d bytes", models.MaxDocumentSize)
+	}
+	if c.AzureConfig.MaxDocumentSize <= 0 {
+		return fmt.Errorf("OCR max document size must be positive")
+	}
+
+	if len(c.ServiceConfig.AllowedMimeTypes) == 0 {
+		return fmt.Errorf("allowed MIME types must be specified")
+	}
+	for _, mimeType := range c.ServiceConfig.AllowedMimeTypes {
+		if _, ok := models.LookupContentType(mimeType); !ok {
+			return fmt.Errorf("MIME type %s is not supported", mimeType)
+		}
+	}
+	for _, channel := range models.Channels {
+		if err := c.validateChannel(channel); err != nil {
+			return err
+		}
+	}
+	if c.ServiceConfig.MaxImagePages < 1 {
+		return fmt.Errorf("max image pages must be at least 1")
+	}
+	if len(c.ServiceConfig.AllowedFileTypes) == 0 {
+		return fmt.Errorf("allowed file types must be specified")
+	}
+	for _, extension := range c.ServiceConfig.AllowedFileTypes {
+		mimeType, _, err := mime.ParseMediaType(mime.TypeByExtension("." + extension))
+		if err != nil || !contains(c.ServiceConfig.AllowedMimeTypes, mimeType) {
+			return fmt.Errorf("file type %s does not map to an allowed MIME type", extension)
+		}
+	}
+
+	if c.ServiceConfig.RequestTimeout <= 0 {
+		return fmt.Errorf("request timeout must be positive")
+	}
+	if c.ServiceConfig.Routes.API.MaxBodySize <= 0 {
+		return fmt.Errorf("API route body size limit must be positive")
+	}
+	for _, group := range RouteGroups {
+		limits := c.ServiceConfig.Limits(group)
+		if limits.MaxBodySize < 0 || limits.Timeout < 0 {
+			return fmt.Errorf("%s route limits cannot be negative", group)
+		}
+	}
+	upload := c.ServiceConfig.Limits(RouteGroupUpload)
+	if upload.MaxBodySize < c.ServiceConfig.MaxFileSize {
+		return fmt.Errorf("upload route body size limit cannot be below the max file size")
+	}
+	if c.IngestTimeout() > upload.Timeout {
+		return fmt.Errorf("storage upload, KMS, virus scan, conversion, OCR and translation timeouts cannot exceed the upload route timeout")
+	}
+	if c.RetrieveTimeout() > c.ServiceConfig.Limits(RouteGroupDownload).Timeout {
+		return fmt.Errorf("storage download and KMS timeouts cannot exceed the download route timeout")
+	}
+
+	// A shaped download of the largest file must finish within the timeout
+	bandwidth := c.ServiceConfig.DownloadBandwidth
+	limits := map[string]BandwidthLimit{"default": bandwidth.Default}
+	for role, limit := range bandwidth.Roles {
+		limits[role] = limit
+	}
+	download := c.ServiceConfig.Limits(RouteGroupDownload)
+	for role, limit := range limits {
+		if limit.BytesPerSecond < 0 || (limit.BytesPerSecond > 0 && limit.Burst <= 0) {
+			return fmt.Errorf("invalid download bandwidth for role %s", role)
+		}
+		if limit.BytesPerSecond > 0 && time.Duration(c.ServiceConfig.MaxFileSize/limit.BytesPerSecond)*time.Second > download.Timeout {
+			return fmt.Errorf("download bandwidth for role %s cannot serve the max file size within the download timeout", role)
+		}
+	}
+	return nil
+}
+
+// enrollmentClientUser returns the first enabled feature that fetches
+// enrollments from the enrollment service, or "" when none does
+func (c *Config) enrollmentClientUser() string {
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"whatsapp", c.WhatsAppConfig.Enabled},
+		{"sftp", c.SFTPConfig.Enabled},
+		{"address", c.AddressConfig.Enabled},
+		{"auto_decision", c.AutoDecisionConfig.Enabled},
+		{"consent", c.ConsentConfig.Enabled},
+		{"portability", c.PortabilityConfig.Enabled},
+		{"api_keys", c.APIKeysConfig.Enabled},
+	}
+	for _, feature := range features {
+		if feature.enabled {
+			return feature.name
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IngestTimeout bounds scanning, converting, storing, recognizing and
+// translating one uploaded document: the timeouts of the dependencies an
+// upload calls one after the other
+func (c *Config) IngestTimeout() time.Duration {
+	timeout := c.MinioConfig.UploadTimeout + c.SecurityConfig.KMSTimeout + c.AzureConfig.OCRTimeout
+	if c.VirusScanConfig.Enabled {
+		timeout += c.VirusScanConfig.Timeout
+	}
+	if c.ConverterConfig.Enabled {
+		timeout += c.ConverterConfig.Timeout
+	}
+	if c.TranslationConfig.Enabled {
+		timeout += c.TranslationConfig.Timeout
+	}
+	return timeout
+}
+
+// RetrieveTimeout bounds downloading and decrypting one stored document
+func (c *Config) RetrieveTimeout() time.Duration {
+	return c.MinioConfig.DownloadTimeout + c.SecurityConfig.KMSTimeout
+}
+
+// LimitsSnapshot is the effective value of every request and document limit
+type LimitsSnapshot struct {
+	MaxFileSize             int64                  `json:"max_file_size"`
+	MaxDocumentSize         int64                  `json:"max_document_size"`
+	OCRMaxDocumentSize      int64                  `json:"ocr_max_document_size"`
+	AllowedFileTypes        []string               `json:"allowed_file_types"`
+	AllowedMimeTypes        []string               `json:"allowed_mime_types"`
+	RequestTimeout          time.Duration          `json:"request_timeout"`
+	IngestTimeout           time.Duration          `json:"ingest_timeout"`
+	RetrieveTimeout         time.Duration          `json:"retrieve_timeout"`
+	StorageUploadTimeout    time.Duration          `json:"storage_upload_timeout"`
+	StorageDownloadTimeout  time.Duration          `json:"storage_download_timeout"`
+	OCRTimeout              time.Duration          `json:"ocr_timeout"`
+	KMSTimeout              time.Duration          `json:"kms_timeout"`
+	MaxConcurrentUploads    int                    `json:"max_concurrent_uploads"`
+	MaxConcurrentProcessing int                    `json:"max_concurrent_processing"`
+	Routes                  map[string]RouteLimits `json:"routes"`
+	Channels                map[string]ChannelPolicy `json:"channels"`
+	MaxImagePages           int                    `json:"max_image_pages"`
+}
+
+// Limits returns a snapshot of the effective limits, with route group
+// fallbacks resolved
+func (c *Config) Limits() LimitsSnapshot {
+	snapshot := LimitsSnapshot{
+		MaxFileSize:             c.ServiceConfig.MaxFileSize,
+		MaxDocumentSize:         models.MaxDocumentSize,
+		OCRMaxDocumentSize:      c.AzureConfig.MaxDocumentSize,
+		AllowedFileTypes:        c.ServiceConfig.AllowedFileTypes,
+		AllowedMimeTypes:        c.ServiceConfig.AllowedMimeTypes,
+		RequestTimeout:          c.ServiceConfig.RequestTimeout,
+		IngestTimeout:           c.IngestTimeout(),
+		RetrieveTimeout:         c.RetrieveTimeout(),
+		StorageUploadTimeout:    c.MinioConfig.UploadTimeout,
+		StorageDownloadTimeout:  c.MinioConfig.DownloadTimeout,
+		OCRTimeout:              c.AzureConfig.OCRTimeout,
+		KMSTimeout:              c.SecurityConfig.KMSTimeout,
+		MaxConcurrentUploads:    c.ServiceConfig.MaxConcurrentUploads,
+		MaxConcurrentProcessing: c.ServiceConfig.MaxConcurrentProcessing,
+		Routes:                  make(map[string]RouteLimits),
+		Channels:                make(map[string]ChannelPolicy),
+		MaxImagePages:           c.ServiceConfig.MaxImagePages,
+	}
+	for _, group := range RouteGroups {
+		snapshot.Routes[group] = c.ServiceConfig.Limits(group)
+	}
+	for _, channel := range models.Channels {
+		snapshot.Channels[channel] = c.ServiceConfig.Channel(channel)
+	}
+	return snapshot
+}
+
+// setDefaults sets default values for configuration
+func setDefaults(v *viper.Viper) {
+	// MinIO defaults
+	v.SetDefault("minio.use_ssl", true)
+	v.SetDefault("minio.upload_timeout", time.Second*30)
+	v.SetDefault("minio.download_timeout", time.Second*30)
+	v.SetDefault("minio.max_connections", 100)
+	v.SetDefault("minio.verify_checksums", true)
+
+	// Azure defaults
+	v.SetDefault("azure.ocr_timeout", time.Second*10)
+	v.SetDefault("azure.max_document_size", 4*1024*1024) // 4MB
+	v.SetDefault("azure.classification_timeout", time.Second*10)
+	v.SetDefault("azure.max_retries", 3)
+	v.SetDefault("azure.retry_interval", time.Second*1)
+	v.SetDefault("azure.confidence_threshold", 0.85)
 
 	// Service defaults
 	v.SetDefault("service.environment", "development")
 	v.SetDefault("service.port", 8080)
 	v.SetDefault("service.max_file_size", 10*1024*1024) // 10MB
 	v.SetDefault("service.allowed_file_types", []string{"pdf", "jpg", "jpeg", "png"})
+	v.SetDefault("service.allowed_mime_types", models.AllowedMimeTypes)
 	v.SetDefault("service.request_timeout", time.Second*60)
 	v.SetDefault("service.max_concurrent_uploads", 50)
 	v.SetDefault("service.max_concurrent_processing", 20)
 	v.SetDefault("service.enable_metrics", true)
+	v.SetDefault("service.routes.api.max_body_size", 1024*1024) // 1MB
+	v.SetDefault("service.routes.api.timeout", time.Second*30)
+	v.SetDefault("service.routes.upload.timeout", time.Minute*2)
+	v.SetDefault("service.routes.download.timeout", time.Minute*5)
+	v.SetDefault("service.routes.webhook.max_body_size", 1024*1024) // 1MB
+	v.SetDefault("service.routes.webhook.timeout", time.Second*15)
+	v.SetDefault("service.routes.admin.timeout", time.Minute*10)
+	// Beneficiaries send phone photos and office documents over WhatsApp
+	v.SetDefault("service.channels.whatsapp.allowed_mime_types", []string{
+		"application/pdf", "image/jpeg", "image/png", models.MimeTypeHEIC, models.MimeTypeHEIF, models.MimeTypeDOCX,
+	})
+	v.SetDefault("service.max_image_pages", 100)
+	v.SetDefault("service.download_bandwidth.default.bytes_per_second", 0) // unlimited
 
 	// Security defaults
 	v.SetDefault("security.encryption_algorithm", "AES-256")
 	v.SetDefault("security.enable_audit_log", true)
 	v.SetDefault("security.enable_data_masking", true)
 	v.SetDefault("security.key_rotation_interval", time.Hour*24)
+	v.SetDefault("security.key_version", "1")
 	v.SetDefault("security.enforce_strict_transport", true)
+	v.SetDefault("security.strict_crypto", false)
+	v.SetDefault("security.max_messages_per_key", 0)
+	v.SetDefault("security.kms_timeout", time.Second*5)
+
+	// Enrollment client defaults
+	v.SetDefault("enrollment.timeout", time.Second*5)
+
+	// WhatsApp defaults
+	v.SetDefault("whatsapp.enabled", false)
+	v.SetDefault("whatsapp.api_base_url", "https://graph.facebook.com/v18.0")
+	v.SetDefault("whatsapp.default_document_type", "identity")
+	v.SetDefault("whatsapp.media_timeout", time.Second*30)
+	v.SetDefault("whatsapp.processing_timeout", time.Minute*2)
+	v.SetDefault("whatsapp.template_language", "pt_BR")
+	v.SetDefault("whatsapp.status_templates", map[string]string{
+		"received": "document_received",
+		"rejected": "document_rejected",
+		"failed":   "document_failed",
+	})
+
+	// SFTP defaults
+	v.SetDefault("sftp.enabled", false)
+	v.SetDefault("sftp.port", 22)
+	v.SetDefault("sftp.inbound_dir", "/inbound")
+	v.SetDefault("sftp.processing_dir", "/processing")
+	v.SetDefault("sftp.processed_dir", "/processed")
+	v.SetDefault("sftp.report_dir", "/reports")
+	v.SetDefault("sftp.manifest_name", "manifest.csv")
+	v.SetDefault("sftp.poll_interval", time.Minute*15)
+	v.SetDefault("sftp.connect_timeout", time.Second*30)
+	v.SetDefault("sftp.file_timeout", time.Minute*2)
+
+	// Underwriting integration defaults
+	v.SetDefault("underwriting.enabled", false)
+	v.SetDefault("underwriting.shadow_mode", true)
+	v.SetDefault("underwriting.transport", "rest")
+	v.SetDefault("underwriting.timeout", time.Second*10)
+	v.SetDefault("underwriting.required_document_types", []string{"identity", "proof_of_address", "medical_record"})
+
+	// Outbox defaults
+	v.SetDefault("outbox.poll_interval", time.Second*10)
+	v.SetDefault("outbox.batch_size", 50)
+	v.SetDefault("outbox.max_attempts", 10)
+	v.SetDefault("outbox.retry_backoff", time.Second*30)
+
+	// Signature verification defaults
+	v.SetDefault("signature.enabled", false)
+	v.SetDefault("signature.trusted_roots_path", "/etc/document-service/icp-brasil-roots.pem")
+
+	// gov.br verification defaults
+	v.SetDefault("govbr.enabled", false)
+	v.SetDefault("govbr.timeout", time.Second*10)
+	v.SetDefault("govbr.requests_per_second", 5.0)
+	v.SetDefault("govbr.burst", 10)
+	v.SetDefault("govbr.cache_ttl", time.Hour*24)
+	v.SetDefault("govbr.document_types", []string{"identity", "vehicle_registration"})
+
+	// Receita Federal CPF check defaults
+	v.SetDefault("receita.enabled", false)
+	v.SetDefault("receita.provider", "broker")
+	v.SetDefault("receita.timeout", time.Second*10)
+	v.SetDefault("receita.cache_ttl", time.Hour*24)
+
+	// Sanctions and PEP screening defaults
+	v.SetDefault("screening.enabled", false)
+	v.SetDefault("screening.provider", "rest")
+	v.SetDefault("screening.timeout", time.Second*10)
+	v.SetDefault("screening.lists", []string{"pep", "ofac", "un", "eu"})
+	v.SetDefault("screening.min_score", 0.85)
+
+	// Address normalization defaults
+	v.SetDefault("address.enabled", false)
+	v.SetDefault("address.viacep_base_url", "https://viacep.com.br")
+	v.SetDefault("address.timeout", time.Second*5)
+	v.SetDefault("address.cache_ttl", time.Hour*24*7)
+
+	// Auto-decision defaults
+	v.SetDefault("auto_decision.enabled", false)
+
+	// Experiment defaults
+	v.SetDefault("experiments.enabled", false)
+
+	// Feature flag defaults
+	v.SetDefault("feature_flags.provider", "config")
+	v.SetDefault("feature_flags.app_name", "document-service")
+	v.SetDefault("feature_flags.refresh_interval", time.Second*30)
+	v.SetDefault("feature_flags.timeout", time.Second*10)
+
+	// Hedged read defaults; the delay should sit near the p95 of small reads
+	v.SetDefault("minio.hedging.enabled", false)
+	v.SetDefault("minio.hedging.delay", time.Millisecond*50)
+	v.SetDefault("minio.credentials.provider", CredentialsStatic)
+	v.SetDefault("minio.credentials.timeout", time.Second*10)
+	v.SetDefault("minio.credentials.retry_interval", time.Second*30)
+
+	// Storage migration defaults
+	v.SetDefault("storage_migration.enabled", false)
+	v.SetDefault("storage_migration.read_from_secondary", false)
+	v.SetDefault("storage_migration.compare_reads", true)
+	v.SetDefault("storage_migration.compare_timeout", time.Second*30)
+	v.SetDefault("storage_migration.max_concurrent_compares", 16)
+	v.SetDefault("storage_migration.reconcile_interval", time.Minute*5)
+	v.SetDefault("storage_migration.reconcile_batch_size", 500)
+	v.SetDefault("storage_migration.secondary.endpoint", "s3.amazonaws.com")
+	v.SetDefault("storage_migration.secondary.use_ssl", true)
+	v.SetDefault("storage_migration.secondary.verify_checksums", true)
+	v.SetDefault("storage_migration.secondary.credentials.provider", CredentialsStatic)
+	v.SetDefault("storage_migration.secondary.credentials.timeout", time.Second*10)
+	v.SetDefault("storage_migration.secondary.credentials.retry_interval", time.Second*30)
+
+	// Database defaults
+	v.SetDefault("database.enabled", false)
+	v.SetDefault("database.connect_timeout", time.Second*10)
+	v.SetDefault("database.migrations.auto_apply", true)
+
+	// Startup defaults
+	v.SetDefault("startup.check_timeout", time.Second*15)
+	v.SetDefault("startup.warm_connections", 8)
+
+	// Outbound transport defaults, sized for sustained concurrent uploads
+	for _, client := range []string{"minio", "azure", "converter", "translation"} {
+		v.SetDefault(client+".transport.max_idle_conns", 256)
+		v.SetDefault(client+".transport.max_idle_conns_per_host", 64)
+		v.SetDefault(client+".transport.max_conns_per_host", 0)
+		v.SetDefault(client+".transport.idle_conn_timeout", time.Second*90)
+		v.SetDefault(client+".transport.dial_timeout", time.Second*5)
+		v.SetDefault(client+".transport.keep_alive", time.Second*30)
+		v.SetDefault(client+".transport.tls_handshake_timeout", time.Second*10)
+		v.SetDefault(client+".transport.tls_session_cache_size", 128)
+	}
+
+	// Adaptive concurrency defaults
+	v.SetDefault("concurrency.enabled", false)
+	v.SetDefault("concurrency.ocr.initial_limit", 20)
+	v.SetDefault("concurrency.ocr.min_limit", 2)
+	v.SetDefault("concurrency.ocr.max_limit", 100)
+	v.SetDefault("concurrency.ocr.window", 50)
+	v.SetDefault("concurrency.ocr.latency_target", time.Second*8)
+	v.SetDefault("concurrency.ocr.latency_tolerance", 1.5)
+	v.SetDefault("concurrency.ocr.backoff_ratio", 0.75)
+	v.SetDefault("concurrency.storage.initial_limit", 50)
+	v.SetDefault("concurrency.storage.min_limit", 5)
+	v.SetDefault("concurrency.storage.max_limit", 200)
+	v.SetDefault("concurrency.storage.window", 100)
+	v.SetDefault("concurrency.storage.latency_target", time.Second*5)
+	v.SetDefault("concurrency.storage.latency_tolerance", 2.0)
+	v.SetDefault("concurrency.storage.backoff_ratio", 0.8)
+
+	// Rendition defaults
+	v.SetDefault("renditions.generate", false)
+	v.SetDefault("renditions.thumbnail_width", 256)
+	v.SetDefault("renditions.preview_width", 1024)
+	v.SetDefault("renditions.jpeg_quality", 80)
+	v.SetDefault("renditions.dpi", 96)
+	v.SetDefault("renditions.presigned_redirects", false)
+	v.SetDefault("renditions.presign_expiry", time.Minute*5)
+	v.SetDefault("renditions.cache_max_age", time.Hour)
+
+	// Page OCR defaults
+	v.SetDefault("page_ocr.enabled", true)
+	v.SetDefault("page_ocr.max_concurrent_pages", 8)
+	v.SetDefault("page_ocr.per_tenant_concurrency", 16)
+	v.SetDefault("page_ocr.max_pages", 200)
+
+	// Preview token defaults
+	v.SetDefault("preview.token_ttl", time.Minute*5)
+	v.SetDefault("preview.require_ip_binding", false)
+
+	// Secure viewer defaults
+	v.SetDefault("secure_viewer.enabled", true)
+	v.SetDefault("secure_viewer.document_types", []string{"medical_record"})
+	v.SetDefault("secure_viewer.unrestricted_roles", []string{"underwriter"})
+	v.SetDefault("secure_viewer.dpi", 110)
+
+	// Analytics export defaults
+	v.SetDefault("analytics_export.enabled", false)
+	v.SetDefault("analytics_export.interval", time.Hour)
+	v.SetDefault("analytics_export.format", "parquet")
+	v.SetDefault("analytics_export.prefix", "documents")
+	v.SetDefault("analytics_export.destination.use_ssl", true)
+	v.SetDefault("analytics_export.destination.verify_checksums", true)
+	v.SetDefault("analytics_export.destination.credentials.provider", CredentialsStatic)
+	v.SetDefault("analytics_export.destination.credentials.timeout", time.Second*10)
+	v.SetDefault("analytics_export.destination.credentials.retry_interval", time.Second*30)
+
+	// Portability export defaults
+	v.SetDefault("portability.enabled", false)
+	v.SetDefault("portability.link_ttl", 24*time.Hour)
+
+	// Consent event defaults
+	v.SetDefault("consent.enabled", false)
+
+	// Records of processing activities defaults
+	v.SetDefault("ropa.sensitive_document_types", []string{"medical_record"})
+
+	// Encryption scanner defaults
+	v.SetDefault("encryption_scan.enabled", false)
+	v.SetDefault("encryption_scan.interval", 6*time.Hour)
+	v.SetDefault("encryption_scan.sample_size", 50)
+	v.SetDefault("encryption_scan.reencrypt", false)
+
+	// Crypto-shredding defaults
+	v.SetDefault("crypto_shredding.enabled", false)
+	v.SetDefault("crypto_shredding.key_cache_ttl", time.Minute)
+
+	// Key usage audit defaults
+	v.SetDefault("key_audit.enabled", true)
+	v.SetDefault("key_audit.retention", 90*24*time.Hour)
+	v.SetDefault("key_audit.thresholds.decrypts_per_principal", 500)
+	v.SetDefault("key_audit.thresholds.documents_per_principal", 200)
+	v.SetDefault("key_audit.thresholds.failures_per_principal", 10)
+	v.SetDefault("key_audit.thresholds.kms_calls_per_key_version", 1000)
+
+	// Client-side encryption defaults
+	v.SetDefault("client_encryption.enabled", false)
+	v.SetDefault("client_encryption.document_types", []string{"medical_record"})
+	v.SetDefault("client_encryption.download_roles", []string{"underwriter"})
+
+	// Request signing defaults
+	v.SetDefault("request_signing.enabled", false)
+	v.SetDefault("request_signing.max_skew", 5*time.Minute)
+
+	// Abuse protection defaults
+	v.SetDefault("abuse.enabled", true)
+	v.SetDefault("abuse.max_failures", 10)
+	v.SetDefault("abuse.failure_window", 10*time.Minute)
+	v.SetDefault("abuse.ban_duration", 15*time.Minute)
+	v.SetDefault("abuse.captcha.enabled", false)
+	v.SetDefault("abuse.captcha.timeout", 5*time.Second)
+	v.SetDefault("abuse.replay.enabled", false)
+	v.SetDefault("abuse.replay.window", 5*time.Minute)
+
+	// Impersonation defaults
+	v.SetDefault("impersonation.enabled", false)
+	v.SetDefault("impersonation.operator_roles", []string{"support"})
+	v.SetDefault("impersonation.subject_role", "beneficiary")
+	v.SetDefault("impersonation.ticket_pattern", `^[A-Z][A-Z0-9]*-[0-9]+$`)
+	v.SetDefault("impersonation.min_justification_length", 30)
+	v.SetDefault("impersonation.default_duration", 15*time.Minute)
+	v.SetDefault("impersonation.max_duration", time.Hour)
+	v.SetDefault("impersonation.timeout", 10*time.Second)
+
+	// Download receipt defaults
+	v.SetDefault("download_receipts.enabled", false)
+	v.SetDefault("download_receipts.document_types", []string{"medical_record"})
+	v.SetDefault("download_receipts.session_idle", 15*time.Minute)
+	v.SetDefault("download_receipts.max_open", time.Hour)
+	v.SetDefault("download_receipts.notify_subjects", false)
+	v.SetDefault("download_receipts.timeout", 10*time.Second)
+
+	// Extracted text access defaults
+	v.SetDefault("text_access.full_text_roles", []string{"underwriter"})
+	v.SetDefault("text_access.purpose_document_types", []string{"medical_record"})
+	v.SetDefault("text_access.purposes", []string{"underwriting", "medical_review", "fraud_investigation", "subject_request"})
+
+	// Pipeline orchestration defaults
+	v.SetDefault("orchestration.backend", OrchestrationInternal)
+	v.SetDefault("orchestration.temporal.host_port", "localhost:7233")
+	v.SetDefault("orchestration.temporal.namespace", "default")
+	v.SetDefault("orchestration.temporal.task_queue", "document-pipeline")
+	v.SetDefault("orchestration.temporal.workflow_timeout", 24*time.Hour)
+	v.SetDefault("orchestration.temporal.step_timeout", 2*time.Minute)
+	v.SetDefault("orchestration.temporal.retry_initial_interval", time.Second)
+	v.SetDefault("orchestration.temporal.retry_max_interval", time.Minute)
+	v.SetDefault("orchestration.temporal.max_attempts", 5)
+	v.SetDefault("orchestration.temporal.max_concurrent_steps", 10)
+
+	// Enrollment cancellation defaults; medical records are kept for the
+	// period clinical records must be retained
+	v.SetDefault("cancellation.enabled", false)
+	v.SetDefault("cancellation.dispositions", map[string]string{
+		"medical_record":   models.DispositionRetain,
+		"identity":         models.DispositionDelete,
+		"proof_of_address": models.DispositionDelete,
+	})
+	v.SetDefault("cancellation.default_disposition", models.DispositionAnonymize)
+	v.SetDefault("cancellation.retain_for", 20*365*24*time.Hour)
+	v.SetDefault("cancellation.timeout", 10*time.Second)
+
+	// Bulk operation defaults
+	v.SetDefault("bulk_operations.default_rate", 5.0)
+	v.SetDefault("bulk_operations.max_rate", 50.0)
+	v.SetDefault("bulk_operations.max_documents", 50000)
+	v.SetDefault("bulk_operations.max_running", 2)
+
+	// Retention purge defaults
+	v.SetDefault("retention.enabled", false)
+	v.SetDefault("retention.interval", time.Hour)
+	v.SetDefault("retention.notice_period", 30*24*time.Hour)
+	v.SetDefault("retention.admin_roles", []string{"tenant_admin"})
+	v.SetDefault("retention.timeout", 10*time.Second)
+	v.SetDefault("retention.extracted_data_retention", 0)
+	v.SetDefault("retention.rendition_retention", 0)
+
+	// Soft quota defaults
+	v.SetDefault("quota.enabled", false)
+	v.SetDefault("quota.roles", []string{"broker"})
+	v.SetDefault("quota.window", time.Minute)
+	v.SetDefault("quota.default.requests", 120)
+	v.SetDefault("quota.default.burst_credits_per_hour", 600.0)
+	v.SetDefault("quota.default.max_burst_credits", 1200.0)
+
+	// Maintenance mode defaults
+	v.SetDefault("maintenance.enabled", false)
+	v.SetDefault("maintenance.message", "The service is read-only for maintenance, try again later")
+	v.SetDefault("maintenance.retry_after", 5*time.Minute)
+
+	// Access log defaults
+	v.SetDefault("access_log.enabled", true)
+	v.SetDefault("access_log.sample_rate", 0.1)
+	v.SetDefault("access_log.log_bodies", false)
+	v.SetDefault("access_log.max_body_bytes", 4096)
+	v.SetDefault("access_log.scrub_fields", []string{
+		"cpf", "name", "full_name", "email", "phone", "birth_date", "address", "rg",
+		"password", "token", "secret", "authorization", "file", "content",
+	})
+	v.SetDefault("access_log.skip_paths", []string{"/health", "/health/live", "/health/ready", "/metrics"})
+
+	// Access event defaults
+	v.SetDefault("access_events.enabled", false)
+	v.SetDefault("access_events.max_clock_skew", 5*time.Minute)
+	v.SetDefault("access_events.window", time.Hour)
+	v.SetDefault("access_events.thresholds.prints_per_user", 20)
+	v.SetDefault("access_events.thresholds.exports_per_user", 10)
+	v.SetDefault("access_events.thresholds.documents_per_user", 30)
+
+	// Enrollment seal defaults
+	v.SetDefault("seal.enabled", false)
+	v.SetDefault("seal.timeout", 10*time.Second)
+
+	// Metrics privacy defaults
+	v.SetDefault("metrics_privacy.enabled", true)
+	v.SetDefault("metrics_privacy.min_tenant_subjects", 20)
+	v.SetDefault("metrics_privacy.max_tenants", 200)
+	v.SetDefault("metrics_privacy.sensitive_families", []string{})
+
+	// Read-your-writes defaults
+	v.SetDefault("consistency.cache_ttl", 5*time.Minute)
+	v.SetDefault("consistency.wait_timeout", 2*time.Second)
+	v.SetDefault("consistency.poll_interval", 100*time.Millisecond)
+
+	// Background job coordination defaults
+	v.SetDefault("coordination.enabled", false)
+	v.SetDefault("coordination.renew_interval", 15*time.Second)
+
+	// Upload spool defaults
+	v.SetDefault("spool.enabled", false)
+	v.SetDefault("spool.directory", "/var/spool/document-service")
+	v.SetDefault("spool.max_bytes", 1<<30)
+	v.SetDefault("spool.drain_interval", 10*time.Second)
+
+	// Processing ETA defaults
+	v.SetDefault("eta.smoothing", 0.2)
+	v.SetDefault("eta.history_window", 7*24*time.Hour)
+	v.SetDefault("eta.refresh_interval", time.Minute)
+	v.SetDefault("eta.stream_interval", 2*time.Second)
+
+	// Document converter defaults
+	v.SetDefault("converter.enabled", false)
+	v.SetDefault("converter.provider", "sidecar")
+	v.SetDefault("converter.base_url", "http://localhost:3000")
+	v.SetDefault("converter.timeout", time.Second*30)
+	v.SetDefault("converter.max_concurrent", 4)
+
+	// Blank and duplicate page removal defaults
+	v.SetDefault("page_cleanup.enabled", false)
+	v.SetDefault("page_cleanup.dpi", 36)
+	v.SetDefault("page_cleanup.blank_ink_ratio", 0.002)
+	v.SetDefault("page_cleanup.duplicate_distance", 6)
+	v.SetDefault("page_cleanup.max_pages", 200)
+
+	// Language detection and translation defaults
+	v.SetDefault("language.enabled", false)
+	v.SetDefault("language.target_language", "pt")
+	v.SetDefault("language.min_confidence", 0.6)
+	v.SetDefault("translation.enabled", false)
+	v.SetDefault("translation.provider", "libretranslate")
+	v.SetDefault("translation.base_url", "http://localhost:5000")
+	v.SetDefault("translation.source_languages", []string{"en", "es"})
+	v.SetDefault("translation.max_characters", 20000)
+	v.SetDefault("translation.timeout", time.Second*30)
+
+	// Accessible PDF defaults
+	v.SetDefault("accessibility.enabled", false)
+	v.SetDefault("accessibility.dpi", 150)
+	v.SetDefault("accessibility.jpeg_quality", 85)
+	v.SetDefault("accessibility.language", "pt-BR")
+
+	// Searchable PDF defaults
+	v.SetDefault("searchable_pdf.enabled", false)
+	v.SetDefault("searchable_pdf.dpi", 200)
+	v.SetDefault("searchable_pdf.jpeg_quality", 90)
+
+	// Search index defaults
+	v.SetDefault("search.enabled", false)
+	v.SetDefault("search.roles", []string{"underwriter"})
+	v.SetDefault("search.min_term_length", 3)
+	v.SetDefault("search.max_results", 100)
+
+	// OCR canary defaults
+	v.SetDefault("ocr_canary.enabled", false)
+	v.SetDefault("ocr_canary.percentage", 5)
+	v.SetDefault("ocr_canary.min_agreement", 0.95)
+
+	// Synthetic probe defaults
+	v.SetDefault("synthetic_probe.enabled", false)
+	v.SetDefault("synthetic_probe.interval", time.Minute*5)
+	v.SetDefault("synthetic_probe.timeout", time.Minute*2)
+	v.SetDefault("synthetic_probe.poll_interval", time.Second)
+	v.SetDefault("synthetic_probe.enrollment_id", "synthetic-probe")
+	v.SetDefault("synthetic_probe.document_type", "identity")
+
+	// SLO defaults
+	v.SetDefault("slo.enabled", false)
+	v.SetDefault("slo.ocr_objective", 0.99)
+	v.SetDefault("slo.window", time.Minute*15)
+	v.SetDefault("slo.min_events", 20)
+	v.SetDefault("slo.recovery_successes", 3)
+	v.SetDefault("slo.drain_interval", time.Second*30)
+	v.SetDefault("slo.batch_size", 20)
+
+	v.SetDefault("webhooks.enabled", false)
+	v.SetDefault("webhooks.admin_roles", []string{"tenant_admin"})
+	v.SetDefault("webhooks.max_subscriptions", 10)
+	v.SetDefault("webhooks.timeout", time.Second*10)
+	v.SetDefault("webhooks.secret_grace_period", time.Hour*24)
+	v.SetDefault("webhooks.history_size", 100)
+	v.SetDefault("webhooks.allow_private_networks", false)
+
+	v.SetDefault("api_keys.enabled", false)
+	v.SetDefault("api_keys.role", "integration")
+	v.SetDefault("api_keys.rate_per_second", 5.0)
+	v.SetDefault("api_keys.burst", 20)
+	v.SetDefault("api_keys.rotation_grace_period", time.Hour*24)
+
+	v.SetDefault("delegation.enabled", false)
+	v.SetDefault("delegation.jwks_refresh", time.Minute*10)
+	v.SetDefault("delegation.timeout", time.Second*5)
+	v.SetDefault("delegation.allowed_actors", []string{"enrollment-service"})
+	v.SetDefault("delegation.tenant_claim", "tenant_id")
+	v.SetDefault("delegation.role_claim", "role")
+	v.SetDefault("delegation.clock_skew", time.Second*30)
+
+	v.SetDefault("gateway_auth.enabled", false)
+	v.SetDefault("gateway_auth.jwks_refresh", time.Minute*10)
+	v.SetDefault("gateway_auth.timeout", time.Second*5)
+	v.SetDefault("gateway_auth.tenant_claim", "tenant_id")
+	v.SetDefault("gateway_auth.role_claim", "role")
+	v.SetDefault("gateway_auth.session_claim", "sid")
+	v.SetDefault("gateway_auth.clock_skew", time.Second*30)
+
+	v.SetDefault("service_accounts.enabled", false)
+
+	v.SetDefault("virus_scan.enabled", false)
+	v.SetDefault("virus_scan.address", "clamav:3310")
+	v.SetDefault("virus_scan.timeout", time.Second*30)
+	v.SetDefault("virus_scan.cache_ttl", time.Hour*24)
+	v.SetDefault("virus_scan.version_check_interval", time.Minute*5)
+
+	v.SetDefault("cdr.enabled", false)
+	v.SetDefault("cdr.dpi", 150.0)
+	v.SetDefault("cdr.quality", 85)
+	v.SetDefault("cdr.max_pages", 50)
+	v.SetDefault("cdr.always_rebuild", false)
+	v.SetDefault("cdr.forensic_roles", []string{"security_analyst"})
+
+	v.SetDefault("quarantine.enabled", false)
+	v.SetDefault("quarantine.sandbox_key", "")
+
+	v.SetDefault("spiffe.enabled", false)
+	v.SetDefault("spiffe.address", ":8443")
+	v.SetDefault("spiffe.svid_path", "/run/spire/certs/svid.pem")
+	v.SetDefault("spiffe.svid_key_path", "/run/spire/certs/svid_key.pem")
+	v.SetDefault("spiffe.bundle_path", "/run/spire/certs/bundle.pem")
+	v.SetDefault("spiffe.reload_interval", 30*time.Second)
+
+	v.SetDefault("retry_budget.enabled", true)
+	v.SetDefault("retry_budget.retries", 3)
+
+	v.SetDefault("error_reporting.provider", "")
+	v.SetDefault("error_reporting.endpoint", "https://api.rollbar.com")
+	v.SetDefault("error_reporting.environment", "production")
+	v.SetDefault("error_reporting.timeout", time.Second*5)
+	v.SetDefault("error_reporting.queue_size", 100)
+
+	v.SetDefault("bulk_review.max_items", 100)
+	v.SetDefault("bulk_review.rate_per_minute", 10)
+	v.SetDefault("bulk_review.burst", 3)
+	v.SetDefault("bulk_review.max_saved_filters", 50)
+
+	v.SetDefault("sla.enabled", false)
+	v.SetDefault("sla.deadline", 72*time.Hour)
+	v.SetDefault("sla.escalations", []float64{0.5, 0.8, 1})
+	v.SetDefault("sla.scan_interval", time.Minute)
+
+	v.SetDefault("business_calendar.enabled", false)
+	v.SetDefault("business_calendar.national_holidays", true)
+	v.SetDefault("business_calendar.optional_holidays", true)
+	v.SetDefault("business_calendar.default.timezone", "America/Sao_Paulo")
+	v.SetDefault("business_calendar.default.opens_at", "09:00")
+	v.SetDefault("business_calendar.default.closes_at", "18:00")
+
+	v.SetDefault("provider_quotas.enabled", false)
+	v.SetDefault("provider_quotas.azure.transactions_per_second", 10)
+	v.SetDefault("provider_quotas.fallback.limits.transactions_per_second", 10)
+	v.SetDefault("provider_quotas.throttle_at", 0.9)
+	v.SetDefault("provider_quotas.switch_at", 0.8)
+
+	v.SetDefault("provider_health.enabled", false)
+	v.SetDefault("provider_health.window", 5*time.Minute)
+	v.SetDefault("provider_health.min_samples", 20)
+	v.SetDefault("provider_health.healthy_score", 0.8)
+	v.SetDefault("provider_health.latency_target", 10*time.Second)
+
+	v.SetDefault("processing_tiers.enabled", false)
+
+	v.SetDefault("large_documents.enabled", false)
+	v.SetDefault("large_documents.max_size", 250*1024*1024)
+	v.SetDefault("large_documents.uploads", 10)
+	v.SetDefault("large_documents.window", 24*time.Hour)
+	v.SetDefault("large_documents.batch_size", 5)
+	v.SetDefault("large_documents.interval", time.Minute)
 }
\ No newline at end of file
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+    "errors"
+    "math"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// CaptchaTokenHeader carries the CAPTCHA token solved by an anonymous uploader
+const CaptchaTokenHeader = "X-Captcha-Token"
+
+// Headers identifying an anonymous upload, so a replay of it is recognized
+const (
+    UploadNonceHeader     = "X-Upload-Nonce"
+    UploadTimestampHeader = "X-Upload-Timestamp"
+)
+
+// GuardTokenEndpoint protects an endpoint whose only credential is a token or
+// signed link. Banned clients are rejected before the token is checked, and
+// every rejected token counts towards the client's ban. Clients are told
+// apart by the IP TrustProxies lets the router see, so rotating
+// X-Forwarded-For values does not evade a ban
+func GuardTokenEndpoint(guard *services.AbuseGuard, endpoint string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if remaining, banned := guard.Banned(endpoint, c.ClientIP()); banned {
+            c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+            c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+                "status":  "error",
+                "message": "Too many invalid requests, try again later",
+            })
+            return
+        }
+
+        c.Next()
+
+        switch c.Writer.Status() {
+        case http.StatusUnauthorized, http.StatusForbidden:
+            guard.RecordFailure(endpoint, c.ClientIP())
+        }
+    }
+}
+
+// RequireCaptcha requires a solved CAPTCHA from requests made without an
+// authenticated user, such as uploads through a shared upload link.
+// Authenticated requests and all requests while CAPTCHAs are disabled pass
+// through. Verification fails closed when the provider is unreachable
+func RequireCaptcha(verifier services.CaptchaVerifier, logger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if verifier == nil || c.GetString("user_id") != "" {
+            c.Next()
+            return
+        }
+
+        err := verifier.Verify(c.Request.Context(), c.GetHeader(CaptchaTokenHeader), c.ClientIP())
+        switch {
+        case err == nil:
+            c.Next()
+        case errors.Is(err, services.ErrCaptchaRequired), errors.Is(err, services.ErrCaptchaFailed):
+            writeError(c, logger, http.StatusForbidden, "CAPTCHA verification failed", err)
+        default:
+            writeError(c, logger, http.StatusServiceUnavailable, "CAPTCHA verification unavailable", err)
+        }
+    }
+}
+
+// RejectReplayedUploads requires uploads made without an authenticated user
+// to carry a fresh nonce and timestamp. Replays are rejected with 409 so they
+// stand apart from malformed or stale requests. Authenticated requests and
+// all requests while replay protection is disabled pass through. The check
+// fails closed when the nonce store is unavailable
+func RejectReplayedUploads(guard *services.ReplayGuard, logger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if guard == nil || c.GetString("user_id") != "" {
+            c.Next()
+            return
+        }
+
+        err := guard.Check(c.Request.Context(), c.GetHeader(UploadNonceHeader), c.GetHeader(UploadTimestampHeader))
+        switch {
+        case err == nil:
+            c.Next()
+        case errors.Is(err, services.ErrReplayedRequest):
+            writeError(c, logger, http.StatusConflict, "Upload replay rejected", err)
+        case errors.Is(err, services.ErrReplayHeadersMissing), errors.Is(err, services.ErrInvalidReplayHeaders),
+            errors.Is(err, services.ErrReplayWindowExpired):
+            writeError(c, logger, http.StatusBadRequest, "Invalid upload nonce or timestamp", err)
+        default:
+            writeError(c, logger, http.StatusServiceUnavailable, "Upload replay check unavailable", err)
+        }
+    }
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrAccessEventsDisabled = errors.New("access events are not enabled")
+)
+
+// accessEventRequest is a view, print or export reported by the portal's
+// secure viewer, signed with the access event key of its ViewerInfo response.
+// Like viewer beacons it is bound as JSON regardless of the content type
+type accessEventRequest struct {
+    Event      string `json:"event" binding:"required,oneof=view print export"`
+    Page       int    `json:"page" binding:"min=0"`
+    Format     string `json:"format" binding:"max=32"`
+    OccurredAt int64  `json:"occurred_at" binding:"required"`
+    Nonce      string `json:"nonce" binding:"required"`
+    Signature  string `json:"signature" binding:"required"`
+}
+
+// UseAccessEvents records the access events reported by the portal; it must
+// be called before serving requests
+func (h *DocumentHandler) UseAccessEvents(events *services.AccessEvents) {
+    h.accessEvents = events
+}
+
+// AccessEvent records a view, print or export of a document reported by the
+// portal's secure viewer in the document's audit trail
+func (h *DocumentHandler) AccessEvent(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "AccessEvent")
+    defer span.End()
+
+    if h.accessEvents == nil {
+        h.handleError(c, http.StatusNotFound, "Access events are not enabled", ErrAccessEventsDisabled)
+        return
+    }
+
+    var req accessEventRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid access event", err)
+        return
+    }
+
+    doc, ok := h.viewerDocument(ctx, c)
+    if !ok {
+        return
+    }
+
+    _, err := h.accessEvents.Record(ctx, doc, viewerSession(c), services.AccessEventReport{
+        Event:      req.Event,
+        Page:       req.Page,
+        Format:     req.Format,
+        OccurredAt: req.OccurredAt,
+        Nonce:      req.Nonce,
+        Signature:  req.Signature,
+    })
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrInvalidAccessEvent), errors.Is(err, services.ErrAccessEventExpired):
+            h.handleError(c, http.StatusBadRequest, "Invalid access event", err)
+        case errors.Is(err, services.ErrAccessEventSignature):
+            h.handleError(c, http.StatusForbidden, "Access event signature is invalid", err)
+        case errors.Is(err, services.ErrAccessEventReplayed):
+            h.handleError(c, http.StatusConflict, "Access event was already recorded", err)
+        default:
+            h.handleError(c, http.StatusInternalServerError, "Failed to record access event", err)
+        }
+        return
+    }
+
+    h.metrics.WithLabelValues("access_event", "completed").Inc()
+    c.Status(http.StatusNoContent)
+}
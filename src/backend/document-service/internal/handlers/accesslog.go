@@ -0,0 +1,176 @@
+package handlers
+
+import (
+    "bytes"
+    "io"
+    "math/rand"
+    "mime"
+    "slices"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "github.com/google/uuid" // v1.3.0
+    "go.opentelemetry.io/otel" // v1.19.0
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/trace"
+    "go.uber.org/zap" // v1.26.0
+    "go.uber.org/zap/zapcore"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// RequestIDHeader carries the ID correlating a request across services and
+// the access log
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from callers
+const maxRequestIDLength = 128
+
+// AccessLog logs each request with its outcome, request ID and trace ID.
+// Successful requests are sampled and failed ones always logged. Paths,
+// query values and JSON bodies are scrubbed of personal data; bodies of any
+// other type, such as uploaded and downloaded files, are never logged. It
+// continues the caller's trace from the traceparent header and adopts the
+// caller's request ID, generating one when there is none
+func AccessLog(cfg config.AccessLogConfig, logger *zap.Logger) gin.HandlerFunc {
+    scrubber := utils.NewScrubber(cfg.ScrubFields)
+    tracer := otel.Tracer("access-log")
+    logger = logger.Named("access")
+
+    return func(c *gin.Context) {
+        if !cfg.Enabled || slices.Contains(cfg.SkipPaths, c.Request.URL.Path) {
+            c.Next()
+            return
+        }
+        start := time.Now()
+
+        requestID := c.GetHeader(RequestIDHeader)
+        if requestID == "" || len(requestID) > maxRequestIDLength {
+            requestID = uuid.NewString()
+        }
+        c.Set("request_id", requestID)
+
+        ctx := propagation.TraceContext{}.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+        ctx, span := tracer.Start(ctx, c.Request.Method+" "+c.FullPath(), trace.WithSpanKind(trace.SpanKindServer))
+        defer span.End()
+        c.Request = c.Request.WithContext(ctx)
+
+        var requestBody []byte
+        var capture *responseCapture
+        if cfg.LogBodies {
+            if isJSON(c.ContentType()) {
+                requestBody = peekBody(c, cfg.MaxBodyBytes)
+            }
+            capture = &responseCapture{ResponseWriter: c.Writer, limit: cfg.MaxBodyBytes}
+            c.Writer = capture
+        }
+
+        c.Next()
+
+        status := c.Writer.Status()
+        if status < 400 && rand.Float64() >= cfg.SampleRate {
+            return
+        }
+
+        spanContext := span.SpanContext()
+        fields := []zap.Field{
+            zap.String("request_id", requestID),
+            zap.String("method", c.Request.Method),
+            zap.String("route", c.FullPath()),
+            zap.String("path", scrubber.String(c.Request.URL.Path)),
+            zap.Int("status", status),
+            zap.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+            zap.Int64("bytes_in", c.Request.ContentLength),
+            zap.Int("bytes_out", c.Writer.Size()),
+            zap.String("client_ip", c.ClientIP()),
+            zap.String("user_agent", scrubber.String(c.Request.UserAgent())),
+            zap.String("user_id", c.GetString("user_id")),
+            zap.String("tenant_id", c.GetString("tenant_id")),
+        }
+        if spanContext.HasTraceID() {
+            fields = append(fields,
+                zap.String("trace_id", spanContext.TraceID().String()),
+                zap.String("span_id", spanContext.SpanID().String()),
+            )
+        }
+        if query := scrubQuery(scrubber, c.Request.URL.Query()); len(query) > 0 {
+            fields = append(fields, zap.Any("query", query))
+        }
+        if len(c.Errors) > 0 {
+            fields = append(fields, zap.String("errors", scrubber.String(c.Errors.String())))
+        }
+        if requestBody != nil {
+            fields = append(fields, zap.String("request_body", scrubber.JSON(requestBody)))
+        }
+        if capture != nil && capture.body.Len() > 0 && isJSON(c.Writer.Header().Get("Content-Type")) {
+            fields = append(fields, zap.String("response_body", scrubber.JSON(capture.body.Bytes())))
+        }
+
+        level := zapcore.InfoLevel
+        switch {
+        case status >= 500:
+            level = zapcore.ErrorLevel
+        case status >= 400:
+            level = zapcore.WarnLevel
+        }
+        if entry := logger.Check(level, "HTTP request"); entry != nil {
+            entry.Write(fields...)
+        }
+    }
+}
+
+// responseCapture keeps the start of the response body for the access log
+type responseCapture struct {
+    gin.ResponseWriter
+    body  bytes.Buffer
+    limit int
+}
+
+func (w *responseCapture) Write(b []byte) (int, error) {
+    if room := w.limit + 1 - w.body.Len(); room > 0 {
+        w.body.Write(b[:min(room, len(b))])
+    }
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *responseCapture) WriteString(s string) (int, error) {
+    return w.Write([]byte(s))
+}
+
+// peekBody reads up to limit bytes of the request body and puts them back in
+// front of the rest. A body over the limit is returned with one byte more,
+// so it no longer parses and is redacted whole
+func peekBody(c *gin.Context, limit int) []byte {
+    if c.Request.Body == nil {
+        return nil
+    }
+    body := c.Request.Body
+    head, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+    c.Request.Body = struct {
+        io.Reader
+        io.Closer
+    }{io.MultiReader(bytes.NewReader(head), body), body}
+    return head
+}
+
+// scrubQuery redacts the scrubbed query parameters and masks CPF-like
+// numbers in the others
+func scrubQuery(scrubber *utils.Scrubber, query map[string][]string) map[string]string {
+    scrubbed := make(map[string]string, len(query))
+    for key, values := range query {
+        if scrubber.Field(key) {
+            scrubbed[key] = utils.Redacted
+            continue
+        }
+        scrubbed[key] = scrubber.String(strings.Join(values, ","))
+    }
+    return scrubbed
+}
+
+// isJSON reports whether a content type is JSON
+func isJSON(contentType string) bool {
+    mediaType, _, err := mime.ParseMediaType(contentType)
+    return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
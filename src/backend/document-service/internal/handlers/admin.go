@@ -0,0 +1,553 @@
+package handlers
+
+import (
+    "crypto/subtle"
+    "encoding/csv"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/migrations"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrAdminUnauthorized      = errors.New("invalid admin token")
+    ErrEncryptionScanDisabled = errors.New("encryption scanner is disabled")
+    ErrKeyAuditDisabled       = errors.New("key usage audit is disabled")
+    ErrReceiptsDisabled       = errors.New("download receipts are disabled")
+    ErrOCRCanaryDisabled      = errors.New("OCR canary is disabled")
+    ErrSLADisabled            = errors.New("SLA clock is disabled")
+    ErrMigrationsDisabled     = errors.New("schema migrations are disabled")
+)
+
+// AdminAuth restricts operational endpoints to callers presenting the admin
+// bearer token; with no token configured every request is rejected. Service
+// accounts AuthenticateServiceAccount let through skip the token, as their
+// role already bounds what they reach
+func AdminAuth(token string, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if c.GetString(serviceAccountKey) != "" {
+            c.Next()
+            return
+        }
+        presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+        if token == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+            writeError(c, auditLogger, http.StatusUnauthorized, "Admin authorization required", ErrAdminUnauthorized)
+            return
+        }
+        c.Request = c.Request.WithContext(utils.WithPrincipal(c.Request.Context(), PrincipalAdmin))
+        c.Next()
+    }
+}
+
+// AdminHandler serves operational status and compliance endpoints
+type AdminHandler struct {
+    config      *config.Config
+    migrations  *migrations.Runner
+    ropa        *services.ROPAService
+    encryption  *services.EncryptionScanner
+    keyAudit    *services.KeyAuditService
+    receipts    *services.DownloadReceipts
+    canary      *services.OCRCanary
+    sla         *services.SLATracker
+    events      *repository.EventSourcedDocumentRepository
+    auditLogger *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler; migrations is nil when no
+// database is configured, scanner is nil when encryption scans are disabled and
+// keyAudit is nil when the key usage audit is disabled, receipts is nil when
+// download receipts are disabled, canary is nil when the OCR canary is
+// disabled and sla is nil when the SLA clock is disabled
+func NewAdminHandler(cfg *config.Config, runner *migrations.Runner, ropa *services.ROPAService, scanner *services.EncryptionScanner, keyAudit *services.KeyAuditService, receipts *services.DownloadReceipts, canary *services.OCRCanary, sla *services.SLATracker, events *repository.EventSourcedDocumentRepository, auditLogger *zap.Logger) (*AdminHandler, error) {
+    if cfg == nil || ropa == nil || events == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &AdminHandler{
+        config:      cfg,
+        migrations:  runner,
+        ropa:        ropa,
+        encryption:  scanner,
+        keyAudit:    keyAudit,
+        receipts:    receipts,
+        canary:      canary,
+        sla:         sla,
+        events:      events,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetConfig reports the effective request and document limits
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   gin.H{"limits": h.config.Limits()},
+    })
+}
+
+// GetMigrations reports applied and pending schema migrations
+func (h *AdminHandler) GetMigrations(c *gin.Context) {
+    if h.migrations == nil {
+        c.JSON(http.StatusOK, gin.H{
+            "status": "success",
+            "data":   gin.H{"enabled": false},
+        })
+        return
+    }
+
+    status, err := h.migrations.Status()
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Failed to read migration status", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "enabled":          true,
+            "version":          status.Version,
+            "dirty":            status.Dirty,
+            "ahead_of_binary":  status.AheadOfBinary,
+            "applied":          status.Applied,
+            "pending":          status.Pending,
+            "pending_contract": status.PendingContract(),
+            "ready":            status.Check() == nil,
+        },
+    })
+}
+
+// ApplyContractMigrations runs the pending contract migrations. It is the
+// post-rollout step of a deploy, called once no instance of the previous
+// release is serving, since contract migrations break it
+func (h *AdminHandler) ApplyContractMigrations(c *gin.Context) {
+    if h.migrations == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Schema migrations are disabled", ErrMigrationsDisabled)
+        return
+    }
+
+    before, err := h.migrations.Status()
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Failed to read migration status", err)
+        return
+    }
+    if err := h.migrations.ApplyContract(); err != nil {
+        if errors.Is(err, migrations.ErrDirty) {
+            writeError(c, h.auditLogger, http.StatusConflict, "Schema is dirty after a failed migration", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to apply contract migrations", err)
+        return
+    }
+    status, err := h.migrations.Status()
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Failed to read migration status", err)
+        return
+    }
+
+    h.auditLogger.Info("Contract migrations applied",
+        zap.Uint("from_version", before.Version),
+        zap.Uint("to_version", status.Version),
+        zap.Int("contract", len(before.PendingContract())),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "from_version": before.Version,
+            "version":      status.Version,
+            "applied":      before.Pending,
+        },
+    })
+}
+
+// GetROPA exports the records of processing activities for the DPO. The period
+// defaults to the last 30 days; from and to are RFC 3339 timestamps and format
+// is json or csv
+func (h *AdminHandler) GetROPA(c *gin.Context) {
+    from, to, ok := h.reportPeriod(c, 30*24*time.Hour)
+    if !ok {
+        return
+    }
+
+    format := c.DefaultQuery("format", "json")
+    if format != "json" && format != "csv" {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Unsupported report format", errors.New("unsupported format "+format))
+        return
+    }
+
+    report, err := h.ropa.Report(c.Request.Context(), from, to)
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to build processing activities report", err)
+        return
+    }
+
+    h.auditLogger.Info("ROPA report exported",
+        zap.Time("from", report.From),
+        zap.Time("to", report.To),
+        zap.String("format", format),
+        zap.Int("entries", len(report.Activities)),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    if format == "csv" {
+        c.Header("Content-Type", "text/csv")
+        c.Header("Content-Disposition", `attachment; filename="ropa-`+report.From.UTC().Format("20060102")+"-"+report.To.UTC().Format("20060102")+`.csv"`)
+        writer := csv.NewWriter(c.Writer)
+        writer.Write(models.ROPAHeader())
+        for _, entry := range report.Activities {
+            writer.Write(entry.CSVRow())
+        }
+        writer.Flush()
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
+
+// GetEncryptionScan returns the report of the latest encryption-at-rest scan
+func (h *AdminHandler) GetEncryptionScan(c *gin.Context) {
+    if h.encryption == nil {
+        c.JSON(http.StatusOK, gin.H{
+            "status": "success",
+            "data":   gin.H{"enabled": false},
+        })
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "enabled": true,
+            "report":  h.encryption.LastReport(),
+        },
+    })
+}
+
+// RunEncryptionScan scans a sample of stored objects immediately
+func (h *AdminHandler) RunEncryptionScan(c *gin.Context) {
+    if h.encryption == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Encryption scanner is disabled", ErrEncryptionScanDisabled)
+        return
+    }
+
+    report, err := h.encryption.Scan(c.Request.Context())
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Encryption scan failed", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
+
+// ReencryptDocument re-encrypts the stored content of one document under the
+// current key, typically after a scan reported it
+func (h *AdminHandler) ReencryptDocument(c *gin.Context) {
+    if h.encryption == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Encryption scanner is disabled", ErrEncryptionScanDisabled)
+        return
+    }
+
+    doc, err := h.encryption.Reencrypt(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+        case errors.Is(err, services.ErrNotReencryptable):
+            writeError(c, h.auditLogger, http.StatusConflict, "Stored content cannot be recovered; restore it from backup", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Re-encryption failed", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Document re-encryption requested",
+        zap.String("document_id", doc.ID),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_id":  doc.ID,
+            "key_version":  doc.EncryptionInfo.KeyVersion,
+            "encrypted_at": doc.EncryptionInfo.EncryptedAt,
+        },
+    })
+}
+
+// GetKeyUsage reports data key usage per key version with the hours in which
+// a principal or key version exceeded an anomaly threshold. The period
+// defaults to the last 24 hours; from and to are RFC 3339 timestamps
+func (h *AdminHandler) GetKeyUsage(c *gin.Context) {
+    if h.keyAudit == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Key usage audit is disabled", ErrKeyAuditDisabled)
+        return
+    }
+
+    from, to, ok := h.reportPeriod(c, 24*time.Hour)
+    if !ok {
+        return
+    }
+
+    report, err := h.keyAudit.Report(c.Request.Context(), from, to)
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to build key usage report", err)
+        return
+    }
+
+    h.auditLogger.Info("Key usage report exported",
+        zap.Time("from", report.From),
+        zap.Time("to", report.To),
+        zap.Int("events", report.Events),
+        zap.Int("anomalies", len(report.Anomalies)),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
+
+// ListKeyUsageEvents lists the individual key uses in a period, optionally
+// narrowed to a document_id or principal, answering who decrypted a document
+// and when
+func (h *AdminHandler) ListKeyUsageEvents(c *gin.Context) {
+    if h.keyAudit == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Key usage audit is disabled", ErrKeyAuditDisabled)
+        return
+    }
+
+    from, to, ok := h.reportPeriod(c, 24*time.Hour)
+    if !ok {
+        return
+    }
+
+    events, err := h.keyAudit.Events(c.Request.Context(), repository.KeyUsageFilter{
+        DocumentID: c.Query("document_id"),
+        Principal:  c.Query("principal"),
+        From:       from,
+        To:         to,
+    })
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list key usage", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   events,
+    })
+}
+
+// ListDownloadReceipts lists the download receipts issued in a period,
+// optionally narrowed to an enrollment_id, user_id or document_id
+func (h *AdminHandler) ListDownloadReceipts(c *gin.Context) {
+    if h.receipts == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Download receipts are disabled", ErrReceiptsDisabled)
+        return
+    }
+
+    from, to, ok := h.reportPeriod(c, 30*24*time.Hour)
+    if !ok {
+        return
+    }
+
+    receipts, err := h.receipts.List(c.Request.Context(), repository.ReceiptFilter{
+        EnrollmentID: c.Query("enrollment_id"),
+        UserID:       c.Query("user_id"),
+        DocumentID:   c.Query("document_id"),
+        From:         from,
+        To:           to,
+    })
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list download receipts", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   receipts,
+    })
+}
+
+// GetDownloadReceipt returns a download receipt with the outcome of checking
+// its signature
+func (h *AdminHandler) GetDownloadReceipt(c *gin.Context) {
+    if h.receipts == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Download receipts are disabled", ErrReceiptsDisabled)
+        return
+    }
+
+    receipt, err := h.receipts.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrReceiptNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Download receipt not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load download receipt", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "receipt":         receipt,
+            "signature_valid": h.receipts.Verify(receipt) == nil,
+        },
+    })
+}
+
+// GetReceiptKey returns the public key download receipts are verified with
+func (h *AdminHandler) GetReceiptKey(c *gin.Context) {
+    if h.receipts == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Download receipts are disabled", ErrReceiptsDisabled)
+        return
+    }
+
+    key, keyID, err := h.receipts.PublicKey()
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to encode receipt key", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "key_id":     keyID,
+            "public_key": key,
+            "algorithm":  "ECDSA-P256-SHA256",
+        },
+    })
+}
+
+// GetOCRCanary reports how the candidate OCR provider compares with the
+// provider in service: the documents it diverged on, its mean word agreement
+// and the agreement of each extracted field. The period defaults to the last
+// 7 days; from and to are RFC 3339 timestamps
+func (h *AdminHandler) GetOCRCanary(c *gin.Context) {
+    if h.canary == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "OCR canary is disabled", ErrOCRCanaryDisabled)
+        return
+    }
+
+    from, to, ok := h.reportPeriod(c, 7*24*time.Hour)
+    if !ok {
+        return
+    }
+
+    report, err := h.canary.Report(c.Request.Context(), from, to)
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to build OCR canary report", err)
+        return
+    }
+
+    h.auditLogger.Info("OCR canary report exported",
+        zap.Time("from", report.From),
+        zap.Time("to", report.To),
+        zap.Int("compared", report.Compared),
+        zap.Int("diverged", report.Diverged),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
+
+// GetSLAReport reports the SLA compliance of each tenant over the documents
+// that came due in a period. The period defaults to the last 30 days; from
+// and to are RFC 3339 timestamps
+func (h *AdminHandler) GetSLAReport(c *gin.Context) {
+    if h.sla == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "SLA clock is disabled", ErrSLADisabled)
+        return
+    }
+
+    from, to, ok := h.reportPeriod(c, 30*24*time.Hour)
+    if !ok {
+        return
+    }
+
+    report, err := h.sla.Report(c.Request.Context(), from, to)
+    if err != nil {
+        if errors.Is(err, services.ErrInvalidReportPeriod) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid report period", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to build SLA report", err)
+        return
+    }
+
+    h.auditLogger.Info("SLA report exported",
+        zap.Time("from", report.From),
+        zap.Time("to", report.To),
+        zap.Int("tenants", len(report.Tenants)),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
+
+// reportPeriod parses the from and to query parameters as RFC 3339
+// timestamps; to defaults to now and from to span before it. An invalid
+// timestamp is answered with 400 and ok is false
+func (h *AdminHandler) reportPeriod(c *gin.Context, span time.Duration) (from, to time.Time, ok bool) {
+    to = time.Now()
+    var err error
+    if value := c.Query("to"); value != "" {
+        if to, err = time.Parse(time.RFC3339, value); err != nil {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid to timestamp", err)
+            return from, to, false
+        }
+    }
+    from = to.Add(-span)
+    if value := c.Query("from"); value != "" {
+        if from, err = time.Parse(time.RFC3339, value); err != nil {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid from timestamp", err)
+            return from, to, false
+        }
+    }
+    return from, to, true
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+    "errors"
+    "math"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// Headers of requests made with an API key. Integrations have no gateway
+// session, so they name the enrollment and document type of an upload
+const (
+    APIKeyHeader       = "X-API-Key"
+    EnrollmentIDHeader = "X-Enrollment-ID"
+    DocumentTypeHeader = "X-Document-Type"
+)
+
+// apiKeyIDKey is the context key of the API key a request was made with
+const apiKeyIDKey = "api_key_id"
+
+// apiKeyPrincipalPrefix prefixes the key ID requests made with a key are
+// attributed to
+const apiKeyPrincipalPrefix = "api-key:"
+
+var (
+    ErrAPIKeysDisabled     = errors.New("API keys are disabled")
+    ErrAPIKeyRateLimited   = errors.New("API key rate limit exceeded")
+    ErrMissingEnrollmentID = errors.New("enrollment ID header is required")
+)
+
+// apiKeyRouteScopes maps the routes API keys may call to the scope each
+// requires; every other route is closed to API keys
+var apiKeyRouteScopes = map[string]string{
+    "POST /api/v1/documents":            models.APIKeyScopeUpload,
+    "POST /api/v1/documents/compose":    models.APIKeyScopeUpload,
+    "GET /api/v1/documents/:id":         models.APIKeyScopeRead,
+    "GET /api/v1/documents/:id/history": models.APIKeyScopeRead,
+}
+
+// AuthenticateAPIKey authenticates requests carrying an API key in place of a
+// gateway session. The key must be active, hold the scope of the route,
+// cover the enrollment acted on and be within its rate limit. Requests are
+// attributed to the key, and every one is audit logged with its key ID.
+// Requests without a key pass through untouched
+func AuthenticateAPIKey(keys *services.APIKeys, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        presented := c.GetHeader(APIKeyHeader)
+        if presented == "" {
+            c.Next()
+            return
+        }
+        if keys == nil {
+            writeError(c, auditLogger, http.StatusUnauthorized, "API key authentication unavailable", ErrAPIKeysDisabled)
+            return
+        }
+
+        ctx := c.Request.Context()
+        key, err := keys.Authenticate(ctx, presented)
+        if err != nil {
+            if errors.Is(err, services.ErrInvalidAPIKey) {
+                writeError(c, auditLogger, http.StatusUnauthorized, "Invalid API key", err)
+                return
+            }
+            writeError(c, auditLogger, http.StatusInternalServerError, "API key authentication failed", err)
+            return
+        }
+        c.Set(apiKeyIDKey, key.ID)
+        c.Set("user_id", apiKeyPrincipalPrefix+key.ID)
+        c.Set("tenant_id", key.TenantID)
+        c.Set("user_role", keys.Role())
+
+        scope := apiKeyRouteScopes[c.Request.Method+" "+c.FullPath()]
+        if scope == "" || !key.Allows(scope) {
+            writeError(c, auditLogger, http.StatusForbidden, "API key scope does not allow this request", services.ErrAPIKeyScope)
+            return
+        }
+
+        if ok, retryAfter := keys.Allow(key); !ok {
+            c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+            writeError(c, auditLogger, http.StatusTooManyRequests, "API key rate limit exceeded", ErrAPIKeyRateLimited)
+            return
+        }
+
+        switch scope {
+        case models.APIKeyScopeUpload:
+            enrollmentID := c.GetHeader(EnrollmentIDHeader)
+            if enrollmentID == "" {
+                writeError(c, auditLogger, http.StatusBadRequest, "Enrollment ID header is required", ErrMissingEnrollmentID)
+                return
+            }
+            err = keys.AuthorizeEnrollment(ctx, key, enrollmentID)
+            c.Set("enrollment_id", enrollmentID)
+            c.Set("document_type", c.GetHeader(DocumentTypeHeader))
+        case models.APIKeyScopeRead:
+            err = keys.AuthorizeDocument(ctx, key, c.Param("id"))
+        }
+        switch {
+        case err == nil:
+        case errors.Is(err, services.ErrAPIKeyEnrollment):
+            writeError(c, auditLogger, http.StatusForbidden, "API key does not cover the enrollment", err)
+            return
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            writeError(c, auditLogger, http.StatusNotFound, "Document not found", err)
+            return
+        default:
+            writeError(c, auditLogger, http.StatusInternalServerError, "API key authorization failed", err)
+            return
+        }
+
+        c.Next()
+
+        auditLogger.Info("API key request",
+            zap.String("api_key_id", key.ID),
+            zap.String("tenant_id", key.TenantID),
+            zap.String("method", c.Request.Method),
+            zap.String("path", c.Request.URL.Path),
+            zap.Int("status", c.Writer.Status()),
+            zap.String("client_ip", c.ClientIP()),
+        )
+    }
+}
+
+// APIKeyHandler lets admins issue, rotate and revoke the API keys of machine
+// integrations
+type APIKeyHandler struct {
+    keys        *services.APIKeys
+    auditLogger *zap.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(keys *services.APIKeys, auditLogger *zap.Logger) (*APIKeyHandler, error) {
+    if keys == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &APIKeyHandler{
+        keys:        keys,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// IssueKey issues a key. The key to present is returned in this response only
+func (h *APIKeyHandler) IssueKey(c *gin.Context) {
+    var req services.APIKeyRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid API key request", err)
+        return
+    }
+
+    key, presented, err := h.keys.Issue(c.Request.Context(), req, PrincipalAdmin)
+    if err != nil {
+        if errors.Is(err, models.ErrMissingField) || errors.Is(err, models.ErrNoAPIKeyScopes) ||
+            errors.Is(err, models.ErrUnknownAPIKeyScope) || errors.Is(err, services.ErrAPIKeyInvalidLimits) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid API key request", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to issue API key", err)
+        return
+    }
+
+    h.audit(c, "API key issued", key)
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "api_key": key,
+            "key":     presented,
+        },
+    })
+}
+
+// ListKeys returns the keys of the tenant_id query parameter, or of every
+// tenant without one
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+    keys, err := h.keys.List(c.Request.Context(), c.Query("tenant_id"))
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list API keys", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   keys,
+    })
+}
+
+// GetKey returns a key
+func (h *APIKeyHandler) GetKey(c *gin.Context) {
+    key, err := h.keys.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        h.writeKeyError(c, "Failed to load API key", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   key,
+    })
+}
+
+// RotateKey gives a key a new secret. The key to present is returned in this
+// response only
+func (h *APIKeyHandler) RotateKey(c *gin.Context) {
+    key, presented, err := h.keys.Rotate(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        h.writeKeyError(c, "Failed to rotate API key", err)
+        return
+    }
+
+    h.audit(c, "API key rotated", key)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "api_key": key,
+            "key":     presented,
+        },
+    })
+}
+
+// RevokeKey disables a key immediately
+func (h *APIKeyHandler) RevokeKey(c *gin.Context) {
+    key, err := h.keys.Revoke(c.Request.Context(), c.Param("id"), PrincipalAdmin)
+    if err != nil {
+        h.writeKeyError(c, "Failed to revoke API key", err)
+        return
+    }
+
+    h.audit(c, "API key revoked", key)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   key,
+    })
+}
+
+// writeKeyError maps the errors of the API key service to responses
+func (h *APIKeyHandler) writeKeyError(c *gin.Context, message string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrAPIKeyNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "API key not found", err)
+    case errors.Is(err, services.ErrAPIKeyRevoked):
+        writeError(c, h.auditLogger, http.StatusConflict, "API key was revoked", err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, message, err)
+    }
+}
+
+// audit records a change to a key
+func (h *APIKeyHandler) audit(c *gin.Context, message string, key *models.APIKey) {
+    h.auditLogger.Info(message,
+        zap.String("api_key_id", key.ID),
+        zap.String("tenant_id", key.TenantID),
+        zap.Strings("scopes", key.Scopes),
+        zap.String("client_ip", c.ClientIP()),
+    )
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+    "errors"
+    "math"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var ErrBulkReviewRateLimited = errors.New("bulk review rate limit exceeded")
+
+// savedFilterRequest is the body saving a review filter
+type savedFilterRequest struct {
+    Name   string              `json:"name" binding:"required,max=100"`
+    Filter models.ReviewFilter `json:"filter"`
+}
+
+// bulkReviewRequest is the body of a decision on many documents
+type bulkReviewRequest struct {
+    DocumentIDs []string `json:"document_ids" binding:"required,min=1,unique,dive,required"`
+    Decision    string   `json:"decision" binding:"required,oneof=approve reject"`
+    Reason      string   `json:"reason" binding:"max=1000"`
+}
+
+// UseQueue serves underwriters' review lists, saved filters and bulk
+// decisions; it must be called before serving requests
+func (h *ReviewHandler) UseQueue(queue *services.ReviewQueue) {
+    h.queue = queue
+}
+
+// UseSLA lists the documents awaiting review with their SLA clocks; it must
+// be called before serving requests
+func (h *ReviewHandler) UseSLA(sla *services.SLATracker) {
+    h.sla = sla
+}
+
+// ListPending lists the documents awaiting review, oldest first. The list
+// is narrowed by the saved filter named by filter_id, or by the filter
+// fields given as query parameters. With the SLA clock enabled, the clock of
+// each document is given by document ID
+func (h *ReviewHandler) ListPending(c *gin.Context) {
+    ctx := c.Request.Context()
+    var filter models.ReviewFilter
+    if id := c.Query("filter_id"); id != "" {
+        saved, err := h.queue.Filter(ctx, c.GetString("user_id"), id)
+        if err != nil {
+            h.filterError(c, "Failed to load saved filter", err)
+            return
+        }
+        filter = saved.Filter
+    } else {
+        var err error
+        if filter, err = reviewFilterFromQuery(c); err != nil {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid review filter", err)
+            return
+        }
+    }
+
+    docs, err := h.queue.Pending(ctx, filter)
+    if err != nil {
+        h.filterError(c, "Failed to list documents awaiting review", err)
+        return
+    }
+
+    data := gin.H{
+        "filter":    filter,
+        "documents": docs,
+    }
+    if h.sla != nil {
+        now := time.Now()
+        clocks := make(map[string]*models.SLAClock, len(docs))
+        for _, doc := range docs {
+            clocks[doc.ID] = h.sla.Clock(doc, now)
+        }
+        data["sla"] = clocks
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   data,
+    })
+}
+
+// ListFilters lists the caller's saved filters
+func (h *ReviewHandler) ListFilters(c *gin.Context) {
+    filters, err := h.queue.Filters(c.Request.Context(), c.GetString("user_id"))
+    if err != nil {
+        h.filterError(c, "Failed to list saved filters", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   filters,
+    })
+}
+
+// SaveFilter saves a review filter of the caller under a name
+func (h *ReviewHandler) SaveFilter(c *gin.Context) {
+    var req savedFilterRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid saved filter", err)
+        return
+    }
+
+    saved, err := h.queue.SaveFilter(c.Request.Context(), c.GetString("user_id"), req.Name, req.Filter)
+    if err != nil {
+        h.filterError(c, "Failed to save filter", err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data":   saved,
+    })
+}
+
+// DeleteFilter deletes a saved filter of the caller
+func (h *ReviewHandler) DeleteFilter(c *gin.Context) {
+    if err := h.queue.DeleteFilter(c.Request.Context(), c.GetString("user_id"), c.Param("id")); err != nil {
+        h.filterError(c, "Failed to delete saved filter", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   gin.H{"id": c.Param("id")},
+    })
+}
+
+// BulkReview approves or rejects a set of documents with one reason. The
+// decision is applied to every document or to none, and the response gives
+// the outcome of each. Each reviewer's bulk actions are rate limited
+func (h *ReviewHandler) BulkReview(c *gin.Context) {
+    var req bulkReviewRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid bulk review request", err)
+        return
+    }
+
+    reviewer := c.GetString("user_id")
+    if allowed, retryAfter := h.queue.Allow(reviewer); !allowed {
+        c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+        writeError(c, h.auditLogger, http.StatusTooManyRequests, "Bulk review rate limit exceeded", ErrBulkReviewRateLimited)
+        return
+    }
+
+    result, err := h.queue.BulkReview(c.Request.Context(), req.DocumentIDs, req.Decision, req.Reason, reviewer)
+    h.auditLogger.Info("Bulk review",
+        zap.String("decision", req.Decision),
+        zap.Strings("document_ids", req.DocumentIDs),
+        zap.Bool("applied", result != nil && result.Applied),
+        zap.String("user_id", reviewer),
+        zap.Error(err),
+    )
+    switch {
+    case err == nil:
+        c.JSON(http.StatusOK, gin.H{
+            "status": "success",
+            "data":   result,
+        })
+    case errors.Is(err, services.ErrBulkReviewTooLarge):
+        writeError(c, h.auditLogger, http.StatusRequestEntityTooLarge, "Too many documents in bulk review", err)
+    case result != nil:
+        c.JSON(http.StatusConflict, gin.H{
+            "status":  "error",
+            "message": "Bulk review was not applied",
+            "data":    result,
+        })
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Bulk review failed", err)
+    }
+}
+
+// filterError maps review queue failures to responses
+func (h *ReviewHandler) filterError(c *gin.Context, msg string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrSavedFilterNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Saved filter not found", err)
+    case errors.Is(err, models.ErrInvalidReviewFilter), errors.Is(err, models.ErrMissingField):
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid review filter", err)
+    case errors.Is(err, services.ErrSavedFilterExists), errors.Is(err, services.ErrSavedFilterLimit):
+        writeError(c, h.auditLogger, http.StatusConflict, msg, err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, msg, err)
+    }
+}
+
+// reviewFilterFromQuery reads a review filter from the query parameters.
+// document_type and review_flag may be repeated; creation times are RFC
+// 3339 timestamps
+func reviewFilterFromQuery(c *gin.Context) (models.ReviewFilter, error) {
+    filter := models.ReviewFilter{
+        EnrollmentID:  c.Query("enrollment_id"),
+        TenantID:      c.Query("tenant_id"),
+        DocumentTypes: c.QueryArray("document_type"),
+        ReviewFlags:   c.QueryArray("review_flag"),
+    }
+    for param, field := range map[string]**time.Time{
+        "created_from": &filter.CreatedFrom,
+        "created_to":   &filter.CreatedTo,
+    } {
+        if value := c.Query(param); value != "" {
+            at, err := time.Parse(time.RFC3339, value)
+            if err != nil {
+                return filter, err
+            }
+            *field = &at
+        }
+    }
+    return filter, nil
+}
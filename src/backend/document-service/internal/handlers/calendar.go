@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// maxPreviewDuration bounds the business time a deadline preview counts
+const maxPreviewDuration = 366 * 24 * time.Hour
+
+// CalendarHandler previews deadlines on the business calendars
+type CalendarHandler struct {
+    calendars   *services.BusinessCalendars
+    auditLogger *zap.Logger
+}
+
+// NewCalendarHandler creates a new business calendar handler
+func NewCalendarHandler(calendars *services.BusinessCalendars, auditLogger *zap.Logger) (*CalendarHandler, error) {
+    if calendars == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CalendarHandler{
+        calendars:   calendars,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ListCalendars lists the business calendars by name
+func (h *CalendarHandler) ListCalendars(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.calendars.Names(),
+    })
+}
+
+// PreviewDeadline computes when a duration of business time started at
+// start, an RFC 3339 timestamp defaulting to now, falls due, and which days
+// the clock pauses on until then. The calendar is the one named by calendar,
+// or else that of tenant_id
+func (h *CalendarHandler) PreviewDeadline(c *gin.Context) {
+    d, err := time.ParseDuration(c.Query("duration"))
+    if err == nil && (d <= 0 || d > maxPreviewDuration) {
+        err = fmt.Errorf("duration must be positive and at most %s", maxPreviewDuration)
+    }
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid duration", err)
+        return
+    }
+
+    start := time.Now()
+    if value := c.Query("start"); value != "" {
+        if start, err = time.Parse(time.RFC3339, value); err != nil {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid start timestamp", err)
+            return
+        }
+    }
+
+    preview, err := h.calendars.Preview(c.Query("calendar"), c.Query("tenant_id"), start, d)
+    if err != nil {
+        if errors.Is(err, services.ErrCalendarNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Business calendar not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to preview deadline", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   preview,
+    })
+}
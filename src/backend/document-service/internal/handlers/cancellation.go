@@ -0,0 +1,106 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// CancellationHandler receives enrollment events published by the enrollment
+// service and exposes the cleanup sagas they start to operators
+type CancellationHandler struct {
+    cancellation *services.CancellationService
+    auditLogger  *zap.Logger
+}
+
+// NewCancellationHandler creates a new enrollment event handler
+func NewCancellationHandler(cfg *config.Config, cancellation *services.CancellationService, auditLogger *zap.Logger) (*CancellationHandler, error) {
+    if cfg == nil || cancellation == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CancellationHandler{
+        cancellation: cancellation,
+        auditLogger:  auditLogger,
+    }, nil
+}
+
+// ReceiveEvent runs the saga of an event, whose request signature
+// RequireSignedRequest has verified, before acknowledging. A compensated or
+// incomplete saga is not acknowledged, so the enrollment service redelivers
+// the event until the cleanup completes
+func (h *CancellationHandler) ReceiveEvent(c *gin.Context) {
+    body, ok := readBody(c)
+    if !ok {
+        return
+    }
+
+    var event services.EnrollmentEvent
+    if err := json.Unmarshal(body, &event); err != nil {
+        c.AbortWithStatus(http.StatusBadRequest)
+        return
+    }
+
+    saga, err := h.cancellation.Handle(c.Request.Context(), event)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrUnknownEnrollmentEvent), errors.Is(err, services.ErrInvalidEnrollmentEvent):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid enrollment event", err)
+        case errors.Is(err, services.ErrSagaInProgress):
+            writeError(c, h.auditLogger, http.StatusConflict, "Cancellation cleanup already running", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Cancellation cleanup not completed", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Enrollment event received",
+        zap.String("event_id", event.EventID),
+        zap.String("type", event.Type),
+        zap.String("enrollment_id", event.EnrollmentID),
+        zap.String("saga_status", saga.Status),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   saga,
+    })
+}
+
+// GetSaga returns a cleanup saga by its cancellation event ID
+func (h *CancellationHandler) GetSaga(c *gin.Context) {
+    saga, err := h.cancellation.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrSagaNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Cancellation saga not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load cancellation saga", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   saga,
+    })
+}
+
+// ListSagas returns the cleanup sagas of an enrollment
+func (h *CancellationHandler) ListSagas(c *gin.Context) {
+    sagas, err := h.cancellation.ListByEnrollment(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list cancellation sagas", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   sagas,
+    })
+}
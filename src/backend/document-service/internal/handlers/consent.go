@@ -0,0 +1,62 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// ConsentHandler receives consent events published by the consent service
+type ConsentHandler struct {
+    consent     *services.ConsentService
+    auditLogger *zap.Logger
+}
+
+// NewConsentHandler creates a new consent event handler
+func NewConsentHandler(cfg *config.Config, consent *services.ConsentService, auditLogger *zap.Logger) (*ConsentHandler, error) {
+    if cfg == nil || consent == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ConsentHandler{
+        consent:     consent,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ReceiveEvent applies an event, whose request signature RequireSignedRequest
+// has verified, before acknowledging, so the consent service retries events
+// that were not applied
+func (h *ConsentHandler) ReceiveEvent(c *gin.Context) {
+    body, ok := readBody(c)
+    if !ok {
+        return
+    }
+
+    var event services.ConsentEvent
+    if err := json.Unmarshal(body, &event); err != nil {
+        c.AbortWithStatus(http.StatusBadRequest)
+        return
+    }
+
+    if err := h.consent.Handle(c.Request.Context(), event); err != nil {
+        if errors.Is(err, services.ErrUnknownConsentEvent) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Unknown consent event", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Consent event not applied", err)
+        return
+    }
+
+    h.auditLogger.Info("Consent event received",
+        zap.String("event_id", event.EventID),
+        zap.String("type", event.Type),
+    )
+    c.Status(http.StatusOK)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// DelegatedTokenHeader carries the token a service presents when calling on
+// behalf of an end user. It is separate from Authorization, which the
+// gateway owns
+const DelegatedTokenHeader = "X-Delegated-Token"
+
+// actorIDKey is the context key of the service calling on behalf of the user
+const actorIDKey = "actor_id"
+
+var (
+    ErrDelegationDisabled  = errors.New("delegated calls are disabled")
+    ErrConflictingIdentity = errors.New("delegated tokens cannot be combined with API keys")
+)
+
+// AcceptDelegatedToken switches the identity of requests carrying a
+// delegated token to the end user the calling service acts for, so access
+// checks apply to the end user. The service is kept as the actor: data key
+// uses are attributed to both, and every request is audit logged with both
+// identities. Requests without a token pass through untouched
+func AcceptDelegatedToken(tokens *services.DelegatedTokens, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token := c.GetHeader(DelegatedTokenHeader)
+        if token == "" {
+            c.Next()
+            return
+        }
+        if tokens == nil {
+            writeError(c, auditLogger, http.StatusUnauthorized, "Delegated calls unavailable", ErrDelegationDisabled)
+            return
+        }
+        if c.GetString(apiKeyIDKey) != "" {
+            writeError(c, auditLogger, http.StatusBadRequest, "Conflicting credentials", ErrConflictingIdentity)
+            return
+        }
+
+        identity, err := tokens.Verify(c.Request.Context(), token)
+        if err != nil {
+            writeError(c, auditLogger, http.StatusUnauthorized, "Delegated token rejected", err)
+            return
+        }
+
+        c.Set(actorIDKey, identity.Actor)
+        c.Set("user_id", identity.Subject)
+        c.Set("tenant_id", identity.TenantID)
+        c.Set("user_role", identity.Role)
+
+        c.Next()
+
+        auditLogger.Info("Delegated request",
+            zap.String("user_id", identity.Subject),
+            zap.String("actor_id", identity.Actor),
+            zap.Strings("actor_chain", identity.ActorChain),
+            zap.String("token_id", identity.TokenID),
+            zap.String("method", c.Request.Method),
+            zap.String("path", c.Request.URL.Path),
+            zap.Int("status", c.Writer.Status()),
+        )
+    }
+}
@@ -6,8 +6,12 @@ import (
     "errors"
     "fmt"
     "io"
+    "math"
     "mime/multipart"
     "net/http"
+    "net/url"
+    "slices"
+    "strconv"
     "time"
 
     "github.com/gin-gonic/gin" // v1.9.1
@@ -20,45 +24,65 @@ import (
 
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
 )
 
 // Global constants for document handling
 const (
-    maxFileSize = 10 * 1024 * 1024 // 10MB
-    uploadTimeout = 3 * time.Second
-    ocrTimeout = 10 * time.Second
+    // clientEncryptedContentType is served for end-to-end encrypted
+    // documents, whose plaintext type is only a claim by the client
+    clientEncryptedContentType = "application/octet-stream"
+
+    // DocumentVersionHeader carries the version of the document a write
+    // produced or a read returned
+    DocumentVersionHeader = "X-Document-Version"
+    // IfVersionHeader asks a read for the given version of the document or
+    // a later one
+    IfVersionHeader = "If-Version"
 )
 
 var (
-    allowedMimeTypes = []string{
-        "application/pdf",
-        "image/jpeg",
-        "image/png",
-    }
-
     // Error definitions
     ErrFileTooLarge = errors.New("file size exceeds maximum allowed")
     ErrInvalidFileType = errors.New("invalid file type")
     ErrUploadTimeout = errors.New("upload operation timed out")
     ErrProcessingTimeout = errors.New("processing operation timed out")
+    ErrUploadsDisabled = errors.New("document uploads are temporarily disabled")
+    ErrInvalidVersion = errors.New("invalid document version")
+    ErrQuarantinedRendition = errors.New("quarantined originals are only served to forensic roles")
+    ErrDocumentQuarantined  = errors.New("document is quarantined pending review")
+    ErrCapabilityDelayed    = errors.New("capability delayed until the large document is processed")
 )
 
 // DocumentHandler handles HTTP requests for document operations
 type DocumentHandler struct {
     config       *config.Config
     storage      *services.StorageService
-    ocr          *services.OCRService
+    pipeline     *services.DocumentPipeline
+    repository   repository.DocumentRepository
+    flags        *services.FeatureFlags
     metrics      *prometheus.CounterVec
     auditLogger  *zap.Logger
-    ocrBreaker   *gobreaker.CircuitBreaker
     storageBreaker *gobreaker.CircuitBreaker
+    previews     *services.PreviewTokens
+    viewer       *services.SecureViewer
+    shredder     *services.CryptoShredder
+    clientEncryption *services.ClientEncryption
+    receipts     *services.DownloadReceipts
+    accessEvents *services.AccessEvents
+    text         *services.TextAccess
+    history      *repository.EventSourcedDocumentRepository
+    consistentReads *services.ConsistentReads
+    eta          *services.ETAEstimator
+    sla          *services.SLATracker
     tracer       trace.Tracer
 }
 
 // NewDocumentHandler creates a new document handler instance
-func NewDocumentHandler(cfg *config.Config, storage *services.StorageService, ocr *services.OCRService, metricsClient *prometheus.Client, auditLogger *zap.Logger) (*DocumentHandler, error) {
-    if cfg == nil || storage == nil || ocr == nil || metricsClient == nil || auditLogger == nil {
+func NewDocumentHandler(cfg *config.Config, storage *services.StorageService, pipeline *services.DocumentPipeline, repo repository.DocumentRepository, flags *services.FeatureFlags, metricsClient *prometheus.Client, auditLogger *zap.Logger) (*DocumentHandler, error) {
+    if cfg == nil || storage == nil || pipeline == nil || repo == nil || metricsClient == nil || auditLogger == nil {
         return nil, errors.New("required dependencies cannot be nil")
     }
 
@@ -72,18 +96,7 @@ func NewDocumentHandler(cfg *config.Config, storage *services.StorageService, oc
     )
     metricsClient.MustRegister(metrics)
 
-    // Configure circuit breakers
-    ocrBreaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
-        Name:        "ocr-service",
-        MaxRequests: 100,
-        Interval:    time.Minute,
-        Timeout:     2 * time.Minute,
-        ReadyToTrip: func(counts gobreaker.Counts) bool {
-            failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-            return counts.Requests >= 10 && failureRatio >= 0.6
-        },
-    })
-
+    // Configure circuit breaker
     storageBreaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
         Name:        "storage-service",
         MaxRequests: 100,
@@ -94,20 +107,80 @@ func NewDocumentHandler(cfg *config.Config, storage *services.StorageService, oc
             return counts.Requests >= 10 && failureRatio >= 0.5
         },
     })
+    // Uploads go through the breaker around the storage call only, so
+    // rejected uploads do not trip it
+    pipeline.UseStorageBreaker(storageBreaker)
 
     return &DocumentHandler{
         config:         cfg,
         storage:        storage,
-        ocr:           ocr,
+        pipeline:      pipeline,
+        repository:    repo,
+        flags:         flags,
         metrics:       metrics,
         auditLogger:   auditLogger,
-        ocrBreaker:    ocrBreaker,
         storageBreaker: storageBreaker,
+        previews:      services.NewPreviewTokens(cfg),
+        viewer:        services.NewSecureViewer(cfg, storage, auditLogger),
         tracer:        otel.Tracer("document-handler"),
     }, nil
 }
 
-// UploadDocument handles document upload requests
+// UseShredder erases documents through crypto-shredding instead of deleting
+// their content synchronously; it must be called before serving requests
+func (h *DocumentHandler) UseShredder(shredder *services.CryptoShredder) {
+    h.shredder = shredder
+}
+
+// UseClientEncryption accepts and serves end-to-end encrypted documents; it
+// must be called before serving requests
+func (h *DocumentHandler) UseClientEncryption(encryption *services.ClientEncryption) {
+    h.clientEncryption = encryption
+}
+
+// UseReceipts records accesses to sensitive documents on signed download
+// receipts; it must be called before serving requests
+func (h *DocumentHandler) UseReceipts(receipts *services.DownloadReceipts) {
+    h.receipts = receipts
+}
+
+// UseTextAccess serves the text extracted from documents; it must be called
+// before serving requests
+func (h *DocumentHandler) UseTextAccess(text *services.TextAccess) {
+    h.text = text
+}
+
+// UseConsistentReads honors the If-Version header of reads; it must be
+// called before serving requests
+func (h *DocumentHandler) UseConsistentReads(reads *services.ConsistentReads) {
+    h.consistentReads = reads
+}
+
+// UseETA serves document status with verification estimates; it must be
+// called before the status routes are registered
+func (h *DocumentHandler) UseETA(eta *services.ETAEstimator) {
+    h.eta = eta
+}
+
+// UseSLA serves document status with the SLA clock; it must be called
+// before serving requests
+func (h *DocumentHandler) UseSLA(sla *services.SLATracker) {
+    h.sla = sla
+}
+
+// recordAccess adds the access to the caller's download receipt
+func (h *DocumentHandler) recordAccess(c *gin.Context, doc *models.Document, access, detail string) {
+    h.receipts.Record(services.ReceiptViewer{
+        UserID:         c.GetString("user_id"),
+        Role:           c.GetString("user_role"),
+        SessionID:      c.GetString("session_id"),
+        ImpersonatorID: c.GetString(impersonatorIDKey),
+    }, doc, access, detail)
+}
+
+// UploadDocument handles document upload requests. A client_encrypted upload
+// carries an envelope sealed to the underwriting team, with the plaintext
+// type in the content_type field
 func (h *DocumentHandler) UploadDocument(c *gin.Context) {
     ctx, span := h.tracer.Start(c.Request.Context(), "UploadDocument")
     defer span.End()
@@ -119,81 +192,236 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
         span.SetAttributes(attribute.Float64("duration_ms", float64(time.Since(startTime).Milliseconds())))
     }()
 
+    // Kill switch checked before the upload body is read
+    if !h.flags.Enabled(services.FlagUploads, true) {
+        h.handleError(c, http.StatusServiceUnavailable, "Uploads temporarily disabled", ErrUploadsDisabled)
+        return
+    }
+
     // Validate request
     file, header, err := c.Request.FormFile("file")
+    if isBodyTooLarge(err) {
+        h.handleError(c, http.StatusRequestEntityTooLarge, "File too large", ErrFileTooLarge)
+        return
+    }
     if err != nil {
         h.handleError(c, http.StatusBadRequest, "Invalid file upload", err)
         return
     }
     defer file.Close()
 
-    // Validate file size
-    if header.Size > maxFileSize {
-        h.handleError(c, http.StatusBadRequest, "File too large", ErrFileTooLarge)
+    // Validate file size; uploads past the channel limit may be accepted as
+    // large documents
+    policy := h.pipeline.ContentPolicy(models.ChannelAPI)
+    if header.Size > h.pipeline.MaxUploadSize(models.ChannelAPI) {
+        h.handleError(c, http.StatusRequestEntityTooLarge, "File too large", ErrFileTooLarge)
         return
     }
 
-    // Validate file type
-    if !h.isAllowedFileType(header.Header.Get("Content-Type")) {
+    // Validate file type; the envelope of an end-to-end encrypted upload is
+    // opaque, so the type declared for its plaintext is checked instead
+    clientEncrypted := c.PostForm("client_encrypted") == "true"
+    contentType := header.Header.Get("Content-Type")
+    if clientEncrypted {
+        contentType = c.PostForm("content_type")
+    }
+    if !policy.Accepts(contentType) {
         h.handleError(c, http.StatusBadRequest, "Invalid file type", ErrInvalidFileType)
         return
     }
 
-    // Create document model
-    doc, err := models.NewDocument(
-        c.GetString("enrollment_id"),
-        c.GetString("document_type"),
-        header.Filename,
-        header.Header.Get("Content-Type"),
-        header.Size,
-    )
+    // Upload with timeout context
+    uploadCtx, cancel := context.WithTimeout(ctx, h.config.IngestTimeout())
+    defer cancel()
+
+    // Ingest through the shared document pipeline, which stores through the
+    // circuit breaker
+    doc, err := h.pipeline.Ingest(uploadCtx, services.IngestRequest{
+        EnrollmentID: c.GetString("enrollment_id"),
+        TenantID:     c.GetString("tenant_id"),
+        DocumentType: c.GetString("document_type"),
+        Filename:     header.Filename,
+        ContentType:  contentType,
+        Channel:      models.ChannelAPI,
+        SubmittedBy:  c.GetString("user_id"),
+        ClientEncrypted: clientEncrypted,
+        Content:      file,
+        Size:         header.Size,
+    })
     if err != nil {
-        h.handleError(c, http.StatusBadRequest, "Invalid document parameters", err)
+        h.handleIngestError(c, err)
         return
     }
 
-    // Upload with timeout context
-    uploadCtx, cancel := context.WithTimeout(ctx, uploadTimeout)
-    defer cancel()
+    // Audit log success
+    h.auditLogger.Info("Document uploaded successfully",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("type", doc.DocumentType),
+        zap.Int64("size", doc.Size),
+        zap.Bool("client_encrypted", doc.ClientEncrypted()),
+    )
 
-    // Store document with circuit breaker
-    err = h.storageBreaker.Execute(func() error {
-        return h.storage.StoreDocument(uploadCtx, doc, file)
+    // The version lets the client read the document back through any
+    // instance with If-Version
+    c.Header(DocumentVersionHeader, strconv.FormatInt(doc.Version, 10))
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": doc,
     })
-    if err != nil {
-        h.handleError(c, http.StatusInternalServerError, "Storage operation failed", err)
+}
+
+// ComposeDocument merges the images uploaded as the ordered "files" fields
+// into one PDF document filling a single checklist slot. Each image adds a
+// page, or a page per page of a TIFF, in the order the files were sent
+func (h *DocumentHandler) ComposeDocument(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "ComposeDocument")
+    defer span.End()
+
+    startTime := time.Now()
+    defer func() {
+        h.metrics.WithLabelValues("compose", "completed").Inc()
+        span.SetAttributes(attribute.Float64("duration_ms", float64(time.Since(startTime).Milliseconds())))
+    }()
+
+    if !h.flags.Enabled(services.FlagUploads, true) {
+        h.handleError(c, http.StatusServiceUnavailable, "Uploads temporarily disabled", ErrUploadsDisabled)
         return
     }
 
-    // Process OCR if needed
-    if h.shouldProcessOCR(doc) {
-        ocrCtx, cancel := context.WithTimeout(ctx, ocrTimeout)
-        defer cancel()
+    form, err := c.MultipartForm()
+    if isBodyTooLarge(err) {
+        h.handleError(c, http.StatusRequestEntityTooLarge, "File too large", ErrFileTooLarge)
+        return
+    }
+    if err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid file upload", err)
+        return
+    }
+    headers := form.File["files"]
+    if len(headers) == 0 {
+        h.handleError(c, http.StatusBadRequest, "Invalid file upload", services.ErrEmptyContent)
+        return
+    }
 
-        err = h.processOCR(ocrCtx, doc)
+    policy := h.pipeline.ContentPolicy(models.ChannelAPI)
+    parts := make([]services.IngestPart, 0, len(headers))
+    var size int64
+    for _, header := range headers {
+        if size += header.Size; size > policy.MaxFileSize {
+            h.handleError(c, http.StatusRequestEntityTooLarge, "File too large", ErrFileTooLarge)
+            return
+        }
+        contentType := header.Header.Get("Content-Type")
+        if !policy.Accepts(contentType) {
+            h.handleError(c, http.StatusBadRequest, "Invalid file type", ErrInvalidFileType)
+            return
+        }
+        file, err := header.Open()
         if err != nil {
-            h.auditLogger.Warn("OCR processing failed", 
-                zap.String("document_id", doc.ID),
-                zap.Error(err),
-            )
-            // Continue despite OCR failure
+            h.handleError(c, http.StatusBadRequest, "Invalid file upload", err)
+            return
         }
+        defer file.Close()
+        parts = append(parts, services.IngestPart{ContentType: contentType, Content: file})
     }
 
-    // Audit log success
-    h.auditLogger.Info("Document uploaded successfully",
+    filename := c.PostForm("filename")
+    if filename == "" {
+        filename = "composed.pdf"
+    }
+
+    uploadCtx, cancel := context.WithTimeout(ctx, h.config.IngestTimeout())
+    defer cancel()
+
+    doc, err := h.pipeline.Ingest(uploadCtx, services.IngestRequest{
+        EnrollmentID: c.GetString("enrollment_id"),
+        TenantID:     c.GetString("tenant_id"),
+        DocumentType: c.GetString("document_type"),
+        Filename:     filename,
+        ContentType:  "application/pdf",
+        Channel:      models.ChannelAPI,
+        SubmittedBy:  c.GetString("user_id"),
+        Parts:        parts,
+    })
+    if err != nil {
+        h.handleIngestError(c, err)
+        return
+    }
+
+    h.auditLogger.Info("Document composed successfully",
         zap.String("document_id", doc.ID),
         zap.String("enrollment_id", doc.EnrollmentID),
         zap.String("type", doc.DocumentType),
+        zap.Int("parts", len(parts)),
         zap.Int64("size", doc.Size),
     )
 
+    c.Header(DocumentVersionHeader, strconv.FormatInt(doc.Version, 10))
     c.JSON(http.StatusOK, gin.H{
         "status": "success",
         "data": doc,
     })
 }
 
+// handleIngestError responds to a failed ingestion with the status its
+// cause calls for
+func (h *DocumentHandler) handleIngestError(c *gin.Context, err error) {
+    if errors.Is(err, models.ErrMissingField) || errors.Is(err, models.ErrInvalidContentType) || errors.Is(err, models.ErrInvalidSize) || errors.Is(err, services.ErrEmptyContent) || errors.Is(err, services.ErrNotComposable) {
+        h.handleError(c, http.StatusBadRequest, "Invalid document parameters", err)
+        return
+    }
+    // Conversion failures tell the submitter how to fix the file
+    var conversionErr *services.ConversionError
+    if errors.As(err, &conversionErr) {
+        if errors.Is(err, services.ErrConverterUnavailable) {
+            c.Header("Retry-After", "60")
+            h.handleError(c, http.StatusServiceUnavailable, conversionErr.Action, err)
+            return
+        }
+        h.handleError(c, http.StatusUnprocessableEntity, conversionErr.Action, err)
+        return
+    }
+    if errors.Is(err, services.ErrClientEncryptionDisabled) || errors.Is(err, services.ErrClientEncryptionNotAllowed) || errors.Is(err, utils.ErrInvalidEnvelope) || errors.Is(err, utils.ErrUnknownRecipient) {
+        h.handleError(c, http.StatusBadRequest, "Invalid end-to-end encrypted upload", err)
+        return
+    }
+    if errors.Is(err, services.ErrConsentRevoked) {
+        h.handleError(c, http.StatusForbidden, "Subject consent has been revoked", err)
+        return
+    }
+    if errors.Is(err, services.ErrDisarmFailed) {
+        h.handleError(c, http.StatusUnprocessableEntity, "The PDF has active content and could not be rebuilt without it; print it to PDF and upload it again", err)
+        return
+    }
+    if errors.Is(err, services.ErrMalwareDetected) {
+        h.handleError(c, http.StatusUnprocessableEntity, "The file was rejected by the virus scanner", err)
+        return
+    }
+    if errors.Is(err, services.ErrScannerUnavailable) {
+        c.Header("Retry-After", "60")
+        h.handleError(c, http.StatusServiceUnavailable, "The file could not be scanned for viruses; try again in a few minutes", err)
+        return
+    }
+    if errors.Is(err, services.ErrSpoolFull) {
+        c.Header("Retry-After", "60")
+        h.handleError(c, http.StatusServiceUnavailable, "Storage is unavailable and the upload spool is full", err)
+        return
+    }
+    var largeErr *services.LargeDocumentQuotaError
+    if errors.As(err, &largeErr) {
+        c.Header("Retry-After", strconv.Itoa(int(math.Ceil(largeErr.RetryAfter.Seconds()))))
+        h.handleError(c, http.StatusTooManyRequests, "Too many large documents uploaded; try again later or split the document", err)
+        return
+    }
+    if errors.Is(err, services.ErrProcessingTierFull) {
+        c.Header("Retry-After", "30")
+        h.handleError(c, http.StatusServiceUnavailable, "Too many documents of this size are waiting to be processed; try again shortly", err)
+        return
+    }
+    h.handleError(c, http.StatusInternalServerError, "Storage operation failed", err)
+}
+
 // DownloadDocument handles document download requests
 func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
     ctx, span := h.tracer.Start(c.Request.Context(), "DownloadDocument")
@@ -212,13 +440,43 @@ func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
         return
     }
 
+    // With as_of the metadata the document had then is returned instead of
+    // its content
+    if asOf := c.Query("as_of"); asOf != "" {
+        h.getDocumentAsOf(ctx, c, docID, asOf)
+        return
+    }
+
+    doc, ok := h.getDocument(ctx, c, docID)
+    if !ok {
+        return
+    }
+
+    if h.viewer.Restricted(doc, c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Document is only available in the secure viewer", services.ErrSecureViewerOnly)
+        return
+    }
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return
+    }
+    if doc.ClientEncrypted() && !h.clientEncryption.MayDownload(c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Document is end-to-end encrypted", services.ErrClientEncryptedDownload)
+        return
+    }
+
     // Retrieve document with circuit breaker
     var content io.Reader
     err := h.storageBreaker.Execute(func() error {
         var err error
-        content, err = h.storage.RetrieveDocument(ctx, &models.Document{ID: docID})
+        content, err = h.storage.RetrieveDocument(ctx, doc)
         return err
     })
+    if errors.Is(err, services.ErrDocumentSpooled) {
+        c.Header("Retry-After", "60")
+        h.handleError(c, http.StatusServiceUnavailable, "Document is spooled until storage recovers", err)
+        return
+    }
     if err != nil {
         h.handleError(c, http.StatusInternalServerError, "Document retrieval failed", err)
         return
@@ -229,9 +487,355 @@ func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
         zap.String("document_id", docID),
         zap.String("user_id", c.GetString("user_id")),
     )
+    h.recordAccess(c, doc, models.ReceiptAccessDownload, "")
+
+    // An end-to-end encrypted document is served as the envelope the client
+    // uploaded, which only the recipient key holder can open
+    contentType := doc.ContentType
+    if doc.ClientEncrypted() {
+        contentType = clientEncryptedContentType
+        c.Header("X-Client-Encryption-Algorithm", doc.ClientEncryption.Algorithm)
+        c.Header("X-Client-Encryption-Key-Id", doc.ClientEncryption.RecipientKeyID)
+        c.Header("X-Plaintext-Content-Type", doc.ContentType)
+    }
 
-    // Stream document to client
-    c.DataFromReader(http.StatusOK, -1, "application/octet-stream", content, nil)
+    // Stream document to client, then release the pooled plaintext buffer
+    c.Header(DocumentVersionHeader, strconv.FormatInt(doc.Version, 10))
+    c.DataFromReader(http.StatusOK, -1, contentType, content, nil)
+    if closer, ok := content.(io.Closer); ok {
+        closer.Close()
+    }
+}
+
+// getDocument loads a document for a read, at the version asked for with
+// If-Version or later when given. It writes the error response and reports
+// false when the document cannot be served
+func (h *DocumentHandler) getDocument(ctx context.Context, c *gin.Context, docID string) (*models.Document, bool) {
+    var doc *models.Document
+    var err error
+    if header := c.GetHeader(IfVersionHeader); header != "" && h.consistentReads != nil {
+        version, parseErr := strconv.ParseInt(header, 10, 64)
+        if parseErr != nil || version < 1 {
+            h.handleError(c, http.StatusBadRequest, "Invalid If-Version header", ErrInvalidVersion)
+            return nil, false
+        }
+        doc, err = h.consistentReads.Get(ctx, docID, version)
+    } else {
+        doc, err = h.repository.GetByID(ctx, docID)
+    }
+
+    switch {
+    case err == nil:
+        return doc, true
+    case errors.Is(err, repository.ErrDocumentNotFound):
+        h.handleError(c, http.StatusNotFound, "Document not found", err)
+    case errors.Is(err, repository.ErrVersionUnavailable):
+        // The write has not reached any store this instance reads yet
+        c.Header("Retry-After", "1")
+        h.handleError(c, http.StatusServiceUnavailable, "Document version not available yet", err)
+    default:
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+    }
+    return nil, false
+}
+
+// ClientEncryptionKey returns the public key clients seal end-to-end encrypted
+// uploads to
+func (h *DocumentHandler) ClientEncryptionKey(c *gin.Context) {
+    defer h.metrics.WithLabelValues("client_encryption_key", "completed").Inc()
+
+    key, err := h.clientEncryption.RecipientKey()
+    if err != nil {
+        h.handleError(c, http.StatusNotFound, "Client-side encryption is not enabled", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "key_id":         key.ID,
+            "algorithm":      models.ClientEncryptionAlgorithm,
+            "public_key":     key.PEM,
+            "document_types": h.clientEncryption.DocumentTypes(),
+        },
+    })
+}
+
+// DownloadRendition serves a rendition such as a thumbnail or the extracted
+// text to an authenticated caller
+func (h *DocumentHandler) DownloadRendition(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "DownloadRendition")
+    defer span.End()
+
+    startTime := time.Now()
+    defer func() {
+        h.metrics.WithLabelValues("download_rendition", "completed").Inc()
+        span.SetAttributes(attribute.Float64("duration_ms", float64(time.Since(startTime).Milliseconds())))
+    }()
+
+    doc, ok := h.getDocument(ctx, c, c.Param("id"))
+    if !ok {
+        return
+    }
+
+    if h.viewer.Restricted(doc, c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Document is only available in the secure viewer", services.ErrSecureViewerOnly)
+        return
+    }
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return
+    }
+
+    if models.IsTextRendition(c.Param("name")) {
+        h.handleError(c, http.StatusForbidden, "Extracted text is only available through the text endpoint", ErrTextRendition)
+        return
+    }
+    quarantined := models.IsQuarantinedRendition(c.Param("name"))
+    if quarantined && !slices.Contains(h.config.CDRConfig.ForensicRoles, c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Quarantined originals are only available for forensic analysis", ErrQuarantinedRendition)
+        return
+    }
+
+    rendition, ok := doc.Rendition(c.Param("name"))
+    if !ok {
+        h.handleError(c, http.StatusNotFound, "Rendition not found", fmt.Errorf("document %s has no %s rendition", doc.ID, c.Param("name")))
+        return
+    }
+
+    h.auditLogger.Info("Document rendition downloaded",
+        zap.String("document_id", doc.ID),
+        zap.String("rendition", rendition.Name),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+    h.recordAccess(c, doc, models.ReceiptAccessRendition, rendition.Name)
+
+    // A quarantined original may carry active content, so it is only ever
+    // saved as an opaque file, never rendered by the browser
+    if quarantined {
+        rendition.ContentType = "application/octet-stream"
+        c.Header("Content-Disposition", `attachment; filename="`+doc.ID+`.quarantined"`)
+        c.Header("Content-Security-Policy", "sandbox")
+        h.auditLogger.Warn("Quarantined original downloaded",
+            zap.String("document_id", doc.ID),
+            zap.String("user_id", c.GetString("user_id")),
+            zap.String("user_role", c.GetString("user_role")),
+        )
+        h.serveRendition(ctx, c, doc, rendition, false)
+        return
+    }
+    h.serveRendition(ctx, c, doc, rendition, true)
+}
+
+// previewTokenRequest selects the rendition a preview token is scoped to
+type previewTokenRequest struct {
+    Rendition string `json:"rendition" binding:"required"`
+    BindIP    bool   `json:"bind_ip"`
+}
+
+// CreatePreviewToken mints a short-lived token the portal embeds in <img> and
+// <iframe> URLs, which cannot carry an Authorization header. The token grants
+// access to a single rendition of the document and may be bound to the
+// caller's IP
+func (h *DocumentHandler) CreatePreviewToken(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "CreatePreviewToken")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("create_preview_token", "completed").Inc()
+
+    var req previewTokenRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid preview token request", err)
+        return
+    }
+
+    doc, err := h.repository.GetByID(ctx, c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    if h.viewer.Restricted(doc, c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Document is only available in the secure viewer", services.ErrSecureViewerOnly)
+        return
+    }
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return
+    }
+
+    if models.IsTextRendition(req.Rendition) {
+        h.handleError(c, http.StatusForbidden, "Extracted text is only available through the text endpoint", ErrTextRendition)
+        return
+    }
+    if models.IsQuarantinedRendition(req.Rendition) {
+        h.handleError(c, http.StatusForbidden, "Quarantined originals cannot be previewed", ErrQuarantinedRendition)
+        return
+    }
+
+    if _, ok := doc.Rendition(req.Rendition); !ok {
+        h.handleError(c, http.StatusNotFound, "Rendition not found", fmt.Errorf("document %s has no %s rendition", doc.ID, req.Rendition))
+        return
+    }
+
+    token, expiresAt, err := h.previews.Mint(doc.ID, req.Rendition, c.GetString("user_id"), c.ClientIP(), req.BindIP)
+    if err != nil {
+        if errors.Is(err, services.ErrPreviewDisabled) {
+            h.handleError(c, http.StatusServiceUnavailable, "Previews are not available", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Preview token creation failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document preview token issued",
+        zap.String("document_id", doc.ID),
+        zap.String("rendition", req.Rendition),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.Bool("ip_bound", req.BindIP || h.config.PreviewConfig.RequireIPBinding),
+        zap.Time("expires_at", expiresAt),
+    )
+
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "token":      token,
+            "expires_at": expiresAt,
+            "url":        "/api/v1/documents/" + url.PathEscape(doc.ID) + "/preview?token=" + url.QueryEscape(token),
+        },
+    })
+}
+
+// Preview serves the rendition a preview token was minted for. The token is
+// the only credential, so this route is exempt from gateway authentication;
+// it is kept out of Referer headers and shared caches
+func (h *DocumentHandler) Preview(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "Preview")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("preview", "completed").Inc()
+
+    c.Header("Referrer-Policy", "no-referrer")
+
+    claims, err := h.previews.Verify(c.Query("token"), c.Param("id"), c.ClientIP())
+    if err != nil {
+        if errors.Is(err, services.ErrPreviewDisabled) {
+            h.handleError(c, http.StatusServiceUnavailable, "Previews are not available", err)
+            return
+        }
+        h.handleError(c, http.StatusUnauthorized, "Invalid or expired preview token", err)
+        return
+    }
+
+    // The token subject is the only identity a preview request carries
+    ctx = utils.WithPrincipal(ctx, claims.Subject)
+
+    doc, err := h.repository.GetByID(ctx, claims.DocumentID)
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return
+    }
+
+    rendition, ok := doc.Rendition(claims.Rendition)
+    if !ok {
+        h.handleError(c, http.StatusNotFound, "Rendition not found", fmt.Errorf("document %s has no %s rendition", doc.ID, claims.Rendition))
+        return
+    }
+
+    h.auditLogger.Info("Document previewed",
+        zap.String("document_id", doc.ID),
+        zap.String("rendition", rendition.Name),
+        zap.String("user_id", claims.Subject),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    h.receipts.Record(services.ReceiptViewer{UserID: claims.Subject}, doc, models.ReceiptAccessPreview, rendition.Name)
+
+    // Presigned URLs outlive the preview token, so previews are always streamed
+    h.serveRendition(ctx, c, doc, rendition, false)
+}
+
+// serveRendition writes a rendition to the response. Unencrypted renditions
+// redirect to a presigned URL when allowRedirect is set and the store supports
+// it; otherwise the object is streamed from the bucket, decrypting encrypted
+// renditions chunk by chunk, with range and conditional request support
+func (h *DocumentHandler) serveRendition(ctx context.Context, c *gin.Context, doc *models.Document, rendition models.Rendition, allowRedirect bool) {
+    if allowRedirect {
+        location, err := h.storage.RenditionURL(ctx, rendition)
+        if err == nil {
+            c.Redirect(http.StatusTemporaryRedirect, location.String())
+            return
+        }
+        if !errors.Is(err, services.ErrPresignUnsupported) {
+            h.handleError(c, http.StatusInternalServerError, "Rendition retrieval failed", err)
+            return
+        }
+    }
+
+    content, info, err := h.storage.OpenRendition(ctx, doc.ID, rendition)
+    if err != nil {
+        if errors.Is(err, services.ErrObjectNotFound) {
+            h.handleError(c, http.StatusNotFound, "Rendition not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Rendition retrieval failed", err)
+        return
+    }
+    defer content.Close()
+
+    c.Header("Content-Type", rendition.ContentType)
+    c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(h.config.RenditionsConfig.CacheMaxAge.Seconds())))
+    if info.ETag != "" {
+        c.Header("ETag", strconv.Quote(info.ETag))
+    }
+    http.ServeContent(c.Writer, c.Request, rendition.Name, info.LastModified, content)
+}
+
+// ReprocessDocument resumes processing of a document halted by a consent
+// revocation once the subject has granted consent again
+func (h *DocumentHandler) ReprocessDocument(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "ReprocessDocument")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("reprocess", "completed").Inc()
+
+    doc, err := h.pipeline.Reprocess(ctx, c.Param("id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+        case errors.Is(err, services.ErrConsentRevoked):
+            h.handleError(c, http.StatusForbidden, "Subject consent has been revoked", err)
+        case errors.Is(err, services.ErrNotHaltedForConsent):
+            h.handleError(c, http.StatusConflict, "Document cannot be reprocessed", err)
+        default:
+            h.handleError(c, http.StatusInternalServerError, "Reprocessing failed", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Document reprocessed",
+        zap.String("document_id", doc.ID),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("status", doc.Status),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": doc,
+    })
 }
 
 // DeleteDocument handles document deletion requests
@@ -252,15 +856,37 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
         return
     }
 
-    // Delete document with circuit breaker
-    err := h.storageBreaker.Execute(func() error {
-        return h.storage.DeleteDocument(ctx, &models.Document{ID: docID})
-    })
+    doc, err := h.repository.GetByID(ctx, docID)
     if err != nil {
-        h.handleError(c, http.StatusInternalServerError, "Document deletion failed", err)
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
         return
     }
 
+    if h.shredder != nil {
+        if err := h.shredder.Erase(ctx, doc); err != nil {
+            h.handleError(c, http.StatusInternalServerError, "Document deletion failed", err)
+            return
+        }
+    } else {
+        // Delete document with circuit breaker
+        err = h.storageBreaker.Execute(func() error {
+            return h.storage.DeleteDocument(ctx, doc)
+        })
+        if err != nil {
+            h.handleError(c, http.StatusInternalServerError, "Document deletion failed", err)
+            return
+        }
+
+        if err := h.repository.Delete(ctx, docID); err != nil {
+            h.handleError(c, http.StatusInternalServerError, "Document deletion failed", err)
+            return
+        }
+    }
+
     // Audit log deletion
     h.auditLogger.Info("Document deleted",
         zap.String("document_id", docID),
@@ -281,6 +907,7 @@ func (h *DocumentHandler) handleError(c *gin.Context, status int, message string
     h.auditLogger.Error(message,
         zap.Error(err),
         zap.String("user_id", c.GetString("user_id")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
         zap.String("path", c.Request.URL.Path),
     )
 
@@ -290,23 +917,3 @@ func (h *DocumentHandler) handleError(c *gin.Context, status int, message string
         "error": err.Error(),
     })
 }
-
-func (h *DocumentHandler) isAllowedFileType(contentType string) bool {
-    for _, allowed := range allowedMimeTypes {
-        if contentType == allowed {
-            return true
-        }
-    }
-    return false
-}
-
-func (h *DocumentHandler) shouldProcessOCR(doc *models.Document) bool {
-    return doc.DocumentType == "identity" || doc.DocumentType == "medical_record"
-}
-
-func (h *DocumentHandler) processOCR(ctx context.Context, doc *models.Document) error {
-    return h.ocrBreaker.Execute(func() error {
-        _, err := h.ocr.ProcessDocument(ctx, doc, nil)
-        return err
-    })
-}
\ No newline at end of file
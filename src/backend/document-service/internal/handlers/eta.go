@@ -0,0 +1,129 @@
+package handlers
+
+import (
+    "errors"
+    "io"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Server-sent event names of the status stream
+const (
+    statusEvent      = "status"
+    statusErrorEvent = "error"
+)
+
+// GetStatus reports the status of a document and when it is expected to be
+// verified
+func (h *DocumentHandler) GetStatus(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "GetStatus")
+    defer span.End()
+
+    doc, err := h.repository.GetByID(ctx, c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.status(doc),
+    })
+}
+
+// StreamStatus streams the status of a document as server-sent events. An
+// event is sent on connecting and whenever the status, stage or estimate
+// changes or the SLA deadline passes, and the stream ends once the document
+// is verified. Streams also end with the request timeout; EventSource
+// clients reconnect on their own
+func (h *DocumentHandler) StreamStatus(c *gin.Context) {
+    ctx := c.Request.Context()
+    docID := c.Param("id")
+
+    doc, err := h.repository.GetByID(ctx, docID)
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    c.Header("Cache-Control", "no-cache")
+    // Proxies must not buffer the stream
+    c.Header("X-Accel-Buffering", "no")
+
+    ticker := time.NewTicker(h.eta.StreamInterval())
+    defer ticker.Stop()
+
+    var last *models.ProcessingStatus
+    c.Stream(func(w io.Writer) bool {
+        status := h.status(doc)
+        if statusChanged(last, status) {
+            c.SSEvent(statusEvent, status)
+            last = status
+        } else {
+            // A comment keeps idle connections open through proxies
+            io.WriteString(w, ": keep-alive\n\n")
+        }
+        if status.Verified {
+            return false
+        }
+
+        select {
+        case <-ctx.Done():
+            return false
+        case <-ticker.C:
+        }
+
+        doc, err = h.repository.GetByID(ctx, docID)
+        if err != nil {
+            if ctx.Err() == nil {
+                c.SSEvent(statusErrorEvent, gin.H{"message": "Document lookup failed"})
+            }
+            return false
+        }
+        return true
+    })
+}
+
+// status estimates when a document will be verified and adds its SLA clock
+func (h *DocumentHandler) status(doc *models.Document) *models.ProcessingStatus {
+    status := h.eta.Estimate(doc)
+    status.SLA = h.sla.Clock(doc, status.EstimatedAt)
+    return status
+}
+
+// statusChanged reports whether a status differs from the last one sent by
+// more than the passing of time; estimates moving by under a second are not
+// changes
+func statusChanged(last, status *models.ProcessingStatus) bool {
+    if last == nil {
+        return true
+    }
+    if last.Status != status.Status || last.Version != status.Version || last.Stage != status.Stage ||
+        last.QueueDepth != status.QueueDepth || last.Basis != status.Basis {
+        return true
+    }
+    if last.SLA != nil && status.SLA != nil && last.SLA.Breached != status.SLA.Breached {
+        return true
+    }
+    if (last.EstimatedCompletion == nil) != (status.EstimatedCompletion == nil) {
+        return true
+    }
+    if last.EstimatedCompletion == nil {
+        return false
+    }
+    moved := status.EstimatedCompletion.Sub(*last.EstimatedCompletion)
+    return moved >= time.Second || moved <= -time.Second
+}
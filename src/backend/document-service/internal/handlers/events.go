@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// GetDocumentEvents returns the verified event history of a document. The
+// state patches hold personal data and are only included with patches=true
+func (h *AdminHandler) GetDocumentEvents(c *gin.Context) {
+    events, err := h.events.History(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        h.eventError(c, "Failed to load document history", err)
+        return
+    }
+
+    patches := c.Query("patches") == "true"
+    if !patches {
+        for _, event := range events {
+            event.Patch = nil
+        }
+    }
+
+    h.auditLogger.Info("Document history read",
+        zap.String("document_id", c.Param("id")),
+        zap.Bool("patches", patches),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   events,
+    })
+}
+
+// RebuildDocument replaces the projection of a document with the state
+// replayed from its events
+func (h *AdminHandler) RebuildDocument(c *gin.Context) {
+    if err := h.events.Rebuild(c.Request.Context(), c.Param("id")); err != nil {
+        h.eventError(c, "Projection rebuild failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document projection rebuilt",
+        zap.String("document_id", c.Param("id")),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   gin.H{"document_id": c.Param("id")},
+    })
+}
+
+// RebuildProjections rebuilds the projection of every document with events
+func (h *AdminHandler) RebuildProjections(c *gin.Context) {
+    rebuilt, err := h.events.RebuildAll(c.Request.Context())
+
+    h.auditLogger.Info("Document projections rebuilt",
+        zap.Int("rebuilt", rebuilt),
+        zap.Error(err),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    if err != nil {
+        h.eventError(c, "Projection rebuild failed", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   gin.H{"rebuilt": rebuilt},
+    })
+}
+
+// eventError maps event store failures to responses
+func (h *AdminHandler) eventError(c *gin.Context, msg string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrDocumentNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Document has no history", err)
+    case errors.Is(err, models.ErrEventChainBroken):
+        writeError(c, h.auditLogger, http.StatusConflict, "Document history failed verification", err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, msg, err)
+    }
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// gatewayUserKey marks requests whose user was identified by a verified
+// gateway token
+const gatewayUserKey = "gateway_user"
+
+// TrustProxies takes the client IP from X-Forwarded-For only on requests from
+// the configured reverse proxies. With none configured the client IP is the
+// peer address, so clients cannot choose the IP preview tokens are bound to
+// or abuse bans are keyed on
+func TrustProxies(router *gin.Engine, cfg *config.Config) error {
+    return router.SetTrustedProxies(cfg.SecurityConfig.TrustedProxies)
+}
+
+// AuthenticateGatewayUser identifies the end user from the token the gateway
+// forwards in Authorization, verified against the identity provider, so
+// user_id, tenant_id, user_role and session_id only ever come from signed
+// claims. Requests a service account, API key or delegated token already
+// identified, and requests without a bearer token, pass through untouched;
+// the latter carry no role, which role checks treat as the most restricted
+func AuthenticateGatewayUser(tokens *services.GatewayTokens, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token, bearer := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+        if tokens == nil || !bearer || c.GetString("user_id") != "" {
+            c.Next()
+            return
+        }
+
+        identity, err := tokens.Verify(c.Request.Context(), strings.TrimSpace(token))
+        if err != nil {
+            writeError(c, auditLogger, http.StatusUnauthorized, "Gateway token rejected", err)
+            return
+        }
+        c.Set("user_id", identity.Subject)
+        c.Set("tenant_id", identity.TenantID)
+        c.Set("user_role", identity.Role)
+        c.Set("session_id", identity.SessionID)
+        c.Set(gatewayUserKey, true)
+        c.Next()
+    }
+}
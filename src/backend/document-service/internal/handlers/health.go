@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrWarmingUp = errors.New("service is still validating its dependencies")
+)
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+    warmup      *services.Warmup
+    maintenance *services.MaintenanceMode
+    auditLogger *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(warmup *services.Warmup, maintenance *services.MaintenanceMode, auditLogger *zap.Logger) (*HealthHandler, error) {
+    if warmup == nil || maintenance == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &HealthHandler{
+        warmup:      warmup,
+        maintenance: maintenance,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// Live reports that the process is running
+func (h *HealthHandler) Live(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// Ready reports startup progress, maintenance mode and the crypto mode; the
+// service is ready once every critical dependency check has passed. In
+// maintenance mode it stays ready, since reads are still served
+func (h *HealthHandler) Ready(c *gin.Context) {
+    progress := h.warmup.Progress()
+    maintenance := h.maintenance.Status()
+
+    status := http.StatusOK
+    state := "ready"
+    switch {
+    case !progress.Ready:
+        status = http.StatusServiceUnavailable
+        state = "warming_up"
+    case maintenance.Active:
+        state = "read_only"
+    }
+    c.JSON(status, gin.H{
+        "status":      state,
+        "data":        progress,
+        "maintenance": maintenance,
+        "crypto": gin.H{
+            "mode":         utils.CryptoMode(),
+            "boringcrypto": utils.BoringCrypto(),
+        },
+    })
+}
+
+// RequireReady rejects requests until the startup checks have passed
+func (h *HealthHandler) RequireReady(c *gin.Context) {
+    if !h.warmup.Ready() {
+        c.Header("Retry-After", "5")
+        writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Service is starting", ErrWarmingUp)
+        return
+    }
+    c.Next()
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// ndjsonContentType is the media type of the history export
+const ndjsonContentType = "application/x-ndjson"
+
+var ErrHistoryDisabled = errors.New("document history is not recorded")
+
+// UseHistory serves the event history of documents; it must be called before
+// serving requests
+func (h *DocumentHandler) UseHistory(events *repository.EventSourcedDocumentRepository) {
+    h.history = events
+}
+
+// GetHistory returns the verified event stream of a document, oldest first,
+// with the state patches summarized by the fields they changed. With
+// format=ndjson or an Accept header asking for NDJSON the stream is exported
+// one event per line for compliance tooling
+func (h *DocumentHandler) GetHistory(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "GetHistory")
+    defer span.End()
+
+    if h.history == nil {
+        h.handleError(c, http.StatusNotFound, "Document history is not recorded", ErrHistoryDisabled)
+        return
+    }
+
+    events, err := h.history.History(ctx, c.Param("id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+        case errors.Is(err, models.ErrEventChainBroken):
+            h.handleError(c, http.StatusConflict, "Document history failed verification", err)
+        default:
+            h.handleError(c, http.StatusInternalServerError, "Document history retrieval failed", err)
+        }
+        return
+    }
+
+    summaries := make([]models.EventSummary, len(events))
+    for i, event := range events {
+        summaries[i] = event.Summary()
+    }
+
+    export := c.Query("format") == "ndjson" || strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+    h.auditLogger.Info("Document history accessed",
+        zap.String("document_id", c.Param("id")),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("user_role", c.GetString("user_role")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
+        zap.Bool("export", export),
+    )
+
+    if !export {
+        c.JSON(http.StatusOK, gin.H{
+            "status": "success",
+            "data": gin.H{
+                "document_id": c.Param("id"),
+                "head":        summaries[len(summaries)-1].Hash,
+                "events":      summaries,
+            },
+        })
+        return
+    }
+
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="document-%s-history.ndjson"`, c.Param("id")))
+    c.Header("Content-Type", ndjsonContentType)
+    c.Header("Cache-Control", "no-store")
+    c.Status(http.StatusOK)
+    encoder := json.NewEncoder(c.Writer)
+    for _, summary := range summaries {
+        if err := encoder.Encode(summary); err != nil {
+            h.auditLogger.Warn("Document history export interrupted",
+                zap.String("document_id", c.Param("id")),
+                zap.Error(err),
+            )
+            return
+        }
+    }
+}
+
+// GetEnrollmentTimeline returns the events of every document of an
+// enrollment, oldest first, with their patches summarized as in GetHistory.
+// actor keeps the events of one actor; limit and cursor page through the
+// timeline. Documents uploaded before history was recorded are left out
+func (h *DocumentHandler) GetEnrollmentTimeline(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "GetEnrollmentTimeline")
+    defer span.End()
+
+    if h.history == nil {
+        h.handleError(c, http.StatusNotFound, "Document history is not recorded", ErrHistoryDisabled)
+        return
+    }
+    query := models.TimelineQuery{Actor: c.Query("actor"), Cursor: c.Query("cursor")}
+    if value := c.Query("limit"); value != "" {
+        limit, err := strconv.Atoi(value)
+        if err != nil || limit < 1 || limit > models.TimelineMaxLimit {
+            h.handleError(c, http.StatusBadRequest, "Invalid timeline limit", fmt.Errorf("limit must be between 1 and %d: %q", models.TimelineMaxLimit, value))
+            return
+        }
+        query.Limit = limit
+    }
+
+    enrollmentID := c.Param("id")
+    docs, err := h.history.ListByEnrollment(ctx, enrollmentID)
+    if err != nil {
+        h.handleError(c, http.StatusInternalServerError, "Enrollment timeline retrieval failed", err)
+        return
+    }
+    var events []*models.DocumentEvent
+    documentTypes := make(map[string]string, len(docs))
+    for _, doc := range docs {
+        history, err := h.history.History(ctx, doc.ID)
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            continue
+        case errors.Is(err, models.ErrEventChainBroken):
+            h.handleError(c, http.StatusConflict, "Document history failed verification", err)
+            return
+        case err != nil:
+            h.handleError(c, http.StatusInternalServerError, "Enrollment timeline retrieval failed", err)
+            return
+        }
+        events = append(events, history...)
+        documentTypes[doc.ID] = doc.DocumentType
+    }
+
+    page, err := models.NewTimeline(events, documentTypes, query)
+    if err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid timeline cursor", err)
+        return
+    }
+
+    h.auditLogger.Info("Enrollment timeline accessed",
+        zap.String("enrollment_id", enrollmentID),
+        zap.String("actor", query.Actor),
+        zap.Int("entries", len(page.Entries)),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("user_role", c.GetString("user_role")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "enrollment_id": enrollmentID,
+            "entries":       page.Entries,
+            "next_cursor":   page.NextCursor,
+        },
+    })
+}
+
+// getDocumentAsOf returns the metadata a document had at the time given by
+// as_of, reconstructed from its event history. as_of is an RFC 3339
+// timestamp, or a date read as the end of that day in UTC
+func (h *DocumentHandler) getDocumentAsOf(ctx context.Context, c *gin.Context, docID, asOfParam string) {
+    if h.history == nil {
+        h.handleError(c, http.StatusNotFound, "Document history is not recorded", ErrHistoryDisabled)
+        return
+    }
+    asOf, err := parseAsOf(asOfParam)
+    if err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid as_of timestamp", err)
+        return
+    }
+
+    doc, err := h.history.ReplayAt(ctx, docID, asOf)
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            h.handleError(c, http.StatusNotFound, "Document did not exist at that time", err)
+        case errors.Is(err, repository.ErrStateErased):
+            h.handleError(c, http.StatusGone, "Document metadata at that time was erased", err)
+        case errors.Is(err, models.ErrEventChainBroken):
+            h.handleError(c, http.StatusConflict, "Document history failed verification", err)
+        default:
+            h.handleError(c, http.StatusInternalServerError, "Document metadata reconstruction failed", err)
+        }
+        return
+    }
+    if doc == nil {
+        h.handleError(c, http.StatusGone, "Document was deleted by that time", repository.ErrDocumentNotFound)
+        return
+    }
+
+    h.auditLogger.Info("Document metadata read as of",
+        zap.String("document_id", docID),
+        zap.Time("as_of", asOf),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("user_role", c.GetString("user_role")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "as_of":    asOf,
+            "document": doc,
+        },
+    })
+}
+
+// parseAsOf reads an RFC 3339 timestamp or a date
+func parseAsOf(value string) (time.Time, error) {
+    if asOf, err := time.Parse(time.RFC3339Nano, value); err == nil {
+        return asOf, nil
+    }
+    day, err := time.Parse(time.DateOnly, value)
+    if err != nil {
+        return time.Time{}, err
+    }
+    return day.Add(24*time.Hour - time.Nanosecond), nil
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// ImpersonationTokenHeader carries the token of the impersonation session an
+// operator is acting in
+const ImpersonationTokenHeader = "X-Impersonation-Token"
+
+// Context keys set on requests made while impersonating
+const (
+    impersonatorIDKey  = "impersonator_id"
+    impersonationIDKey = "impersonation_id"
+)
+
+var ErrNestedImpersonation = errors.New("impersonation sessions cannot be managed while impersonating")
+
+// startImpersonationRequest is the body opening an impersonation session
+type startImpersonationRequest struct {
+    SubjectID       string `json:"subject_id" binding:"required"`
+    Ticket          string `json:"ticket" binding:"required"`
+    Justification   string `json:"justification" binding:"required,max=2000"`
+    DurationMinutes int    `json:"duration_minutes" binding:"min=0"`
+}
+
+// Impersonate switches the identity of requests carrying an impersonation
+// token to the session's beneficiary. It runs after AuthenticateGatewayUser:
+// the operator stays authenticated by the gateway token, whose role must
+// still allow impersonating, and must be the one who opened the session.
+// Every request made is audit logged with both identities and recorded for
+// the beneficiary's notice
+func Impersonate(impersonation *services.ImpersonationService, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token := c.GetHeader(ImpersonationTokenHeader)
+        if token == "" {
+            c.Next()
+            return
+        }
+
+        operatorID, operatorRole := gatewayOperator(c)
+        session, err := impersonation.Authorize(c.Request.Context(), token, operatorID, operatorRole)
+        if err != nil {
+            writeError(c, auditLogger, http.StatusForbidden, "Impersonation not authorized", err)
+            return
+        }
+
+        c.Set(impersonatorIDKey, session.OperatorID)
+        c.Set(impersonationIDKey, session.ID)
+        c.Set("user_id", session.SubjectID)
+        c.Set("user_role", session.SubjectRole)
+
+        c.Next()
+
+        impersonation.RecordAction(session.ID, models.ImpersonatedAction{
+            Method:     c.Request.Method,
+            Path:       c.Request.URL.Path,
+            Status:     c.Writer.Status(),
+            OccurredAt: time.Now().UTC(),
+        })
+        auditLogger.Info("Impersonated action",
+            zap.String("impersonation_id", session.ID),
+            zap.String("operator_id", session.OperatorID),
+            zap.String("subject_id", session.SubjectID),
+            zap.String("ticket", session.Ticket),
+            zap.String("method", c.Request.Method),
+            zap.String("path", c.Request.URL.Path),
+            zap.Int("status", c.Writer.Status()),
+        )
+    }
+}
+
+// ImpersonationHandler lets support operators open and close impersonation
+// sessions
+type ImpersonationHandler struct {
+    impersonation *services.ImpersonationService
+    auditLogger   *zap.Logger
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(impersonation *services.ImpersonationService, auditLogger *zap.Logger) (*ImpersonationHandler, error) {
+    if impersonation == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ImpersonationHandler{
+        impersonation: impersonation,
+        auditLogger:   auditLogger,
+    }, nil
+}
+
+// StartImpersonation opens a session for the calling operator. The token is
+// returned once and must be sent in X-Impersonation-Token on every request
+// made on the beneficiary's behalf
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+    if c.GetString(impersonatorIDKey) != "" {
+        writeError(c, h.auditLogger, http.StatusForbidden, "Already impersonating", ErrNestedImpersonation)
+        return
+    }
+
+    var req startImpersonationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid impersonation request", err)
+        return
+    }
+
+    operatorID, operatorRole := gatewayOperator(c)
+    session, token, err := h.impersonation.Start(c.Request.Context(), services.ImpersonationRequest{
+        OperatorID:    operatorID,
+        OperatorRole:  operatorRole,
+        SubjectID:     req.SubjectID,
+        Ticket:        req.Ticket,
+        Justification: req.Justification,
+        Duration:      time.Duration(req.DurationMinutes) * time.Minute,
+    })
+    if err != nil {
+        h.handleError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "session": session,
+            "token":   token,
+        },
+    })
+}
+
+// GetImpersonation returns a session and the actions made in it to the
+// operator who opened it
+func (h *ImpersonationHandler) GetImpersonation(c *gin.Context) {
+    session, err := h.impersonation.Get(c.Param("id"))
+    if err == nil && session.OperatorID != operatorID(c) {
+        err = services.ErrImpersonationNotOperator
+    }
+    if err != nil {
+        h.handleError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   session,
+    })
+}
+
+// EndImpersonation closes a session before it expires and notifies the
+// beneficiary
+func (h *ImpersonationHandler) EndImpersonation(c *gin.Context) {
+    session, err := h.impersonation.End(c.Request.Context(), c.Param("id"), operatorID(c))
+    if err != nil {
+        h.handleError(c, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   session,
+    })
+}
+
+func (h *ImpersonationHandler) handleError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrImpersonationTicket), errors.Is(err, services.ErrJustificationTooShort),
+        errors.Is(err, services.ErrImpersonationDuration), errors.Is(err, services.ErrSelfImpersonation):
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid impersonation request", err)
+    case errors.Is(err, services.ErrImpersonationNotAllowed), errors.Is(err, services.ErrImpersonationNotOperator):
+        writeError(c, h.auditLogger, http.StatusForbidden, "Impersonation not allowed", err)
+    case errors.Is(err, services.ErrImpersonationNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Impersonation session not found", err)
+    case errors.Is(err, services.ErrImpersonationEnded):
+        writeError(c, h.auditLogger, http.StatusConflict, "Impersonation session has ended", err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Impersonation failed", err)
+    }
+}
+
+// gatewayOperator returns the user of a request and their role only when a
+// verified gateway token identified them. Service accounts, API keys and
+// delegated tokens carry roles of their own but never act as operators
+func gatewayOperator(c *gin.Context) (string, string) {
+    if !c.GetBool(gatewayUserKey) {
+        return "", ""
+    }
+    return c.GetString("user_id"), c.GetString("user_role")
+}
+
+// operatorID is the operator behind a request, whether or not it is made
+// while impersonating
+func operatorID(c *gin.Context) string {
+    if impersonator := c.GetString(impersonatorIDKey); impersonator != "" {
+        return impersonator
+    }
+    return c.GetString("user_id")
+}
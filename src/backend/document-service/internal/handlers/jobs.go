@@ -0,0 +1,47 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// JobsHandler reports which instance runs each background job
+type JobsHandler struct {
+    jobs        *services.JobCoordinator
+    auditLogger *zap.Logger
+}
+
+// NewJobsHandler creates a new background job status handler
+func NewJobsHandler(jobs *services.JobCoordinator, auditLogger *zap.Logger) (*JobsHandler, error) {
+    if jobs == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &JobsHandler{
+        jobs:        jobs,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetJobs lists the background jobs with the instance holding each, as seen
+// by the instance answering
+func (h *JobsHandler) GetJobs(c *gin.Context) {
+    jobs, err := h.jobs.Status(c.Request.Context())
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Failed to read job locks", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "instance_id": h.jobs.InstanceID(),
+            "jobs":        jobs,
+        },
+    })
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/http"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var ErrRequestTooLarge = errors.New("request body exceeds maximum allowed size")
+
+// LimitRequest applies the limits of a route group. A declared body over the
+// limit is rejected with 413 before any of it is read, and a chunked body
+// fails with http.MaxBytesError once it passes the limit. The timeout bounds
+// the connection's read and write deadlines, where the writer supports them,
+// and the request context
+func LimitRequest(cfg config.ServiceConfig, group string, logger *zap.Logger) gin.HandlerFunc {
+    limits := cfg.Limits(group)
+    return func(c *gin.Context) {
+        if c.Request.ContentLength > limits.MaxBodySize {
+            writeError(c, logger, http.StatusRequestEntityTooLarge, "Request body too large", ErrRequestTooLarge)
+            return
+        }
+        if c.Request.Body != nil {
+            c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodySize)
+        }
+
+        deadline := time.Now().Add(limits.Timeout)
+        // Writers without deadline support keep the server-wide timeouts
+        controller := http.NewResponseController(c.Writer)
+        _ = controller.SetReadDeadline(deadline)
+        _ = controller.SetWriteDeadline(deadline)
+
+        ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+        defer cancel()
+        c.Request = c.Request.WithContext(ctx)
+        c.Next()
+    }
+}
+
+// ShapeDownloads streams responses no faster than the bandwidth limit of the
+// caller's role. Each user has their own bucket, and anonymous callers, such
+// as preview token holders, are told apart by client IP
+func ShapeDownloads(shaper *services.BandwidthShaper) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        client := c.GetString("user_id")
+        if client == "" {
+            client = "ip:" + c.ClientIP()
+        }
+        c.Writer = &shapedResponseWriter{
+            ResponseWriter: c.Writer,
+            body:           shaper.Writer(c.Request.Context(), c.Writer, c.GetString("user_role"), client),
+        }
+        c.Next()
+    }
+}
+
+// shapedResponseWriter writes the response body through the shaper
+type shapedResponseWriter struct {
+    gin.ResponseWriter
+    body io.Writer
+}
+
+func (w *shapedResponseWriter) Write(b []byte) (int, error) {
+    return w.body.Write(b)
+}
+
+func (w *shapedResponseWriter) WriteString(s string) (int, error) {
+    return w.body.Write([]byte(s))
+}
+
+// readBody reads the whole request body within the route group's limit,
+// aborting with 413 when it is exceeded and 400 when it cannot be read
+func readBody(c *gin.Context) ([]byte, bool) {
+    body, err := io.ReadAll(c.Request.Body)
+    switch {
+    case isBodyTooLarge(err):
+        c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+        return nil, false
+    case err != nil:
+        c.AbortWithStatus(http.StatusBadRequest)
+        return nil, false
+    }
+    return body, true
+}
+
+// isBodyTooLarge reports whether reading the request body failed on the
+// route group's body size limit
+func isBodyTooLarge(err error) bool {
+    var maxBytesErr *http.MaxBytesError
+    return errors.As(err, &maxBytesErr)
+}
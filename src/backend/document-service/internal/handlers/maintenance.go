@@ -0,0 +1,107 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "slices"
+    "strconv"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrMaintenance = services.ErrMaintenance
+)
+
+// setMaintenanceRequest switches maintenance mode on this instance
+type setMaintenanceRequest struct {
+    Active  *bool  `json:"active" binding:"required"`
+    Message string `json:"message"`
+}
+
+// RejectWritesInMaintenance refuses mutating requests with 503 while the
+// service is in maintenance mode. Reads pass through, as do the routes
+// listed in readOnly, which use POST without writing documents
+func RejectWritesInMaintenance(mode *services.MaintenanceMode, logger *zap.Logger, readOnly ...string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        switch c.Request.Method {
+        case http.MethodGet, http.MethodHead, http.MethodOptions:
+            c.Next()
+            return
+        }
+        if slices.Contains(readOnly, c.FullPath()) {
+            c.Next()
+            return
+        }
+
+        if status, refused := mode.RefuseWrite(); refused {
+            c.Header("Retry-After", strconv.Itoa(status.RetryAfter))
+            writeError(c, logger, http.StatusServiceUnavailable, status.Message, ErrMaintenance)
+            return
+        }
+        c.Next()
+    }
+}
+
+// MaintenanceHandler reports and switches maintenance mode
+type MaintenanceHandler struct {
+    mode        *services.MaintenanceMode
+    auditLogger *zap.Logger
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(mode *services.MaintenanceMode, auditLogger *zap.Logger) (*MaintenanceHandler, error) {
+    if mode == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &MaintenanceHandler{
+        mode:        mode,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetMaintenance returns the maintenance state of this instance
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.mode.Status(),
+    })
+}
+
+// SetMaintenance switches maintenance mode on or off on this instance,
+// overriding the configuration and the maintenance flag
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+    var req setMaintenanceRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid maintenance request", err)
+        return
+    }
+
+    status := h.mode.Set(*req.Active, req.Message, PrincipalAdmin)
+    h.auditLogger.Info("Maintenance mode switched",
+        zap.Bool("active", status.Active),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   status,
+    })
+}
+
+// ClearMaintenance drops the switch made through the admin API, returning
+// to the configured and flagged state
+func (h *MaintenanceHandler) ClearMaintenance(c *gin.Context) {
+    status := h.mode.Clear(PrincipalAdmin)
+    h.auditLogger.Info("Maintenance mode override cleared",
+        zap.Bool("active", status.Active),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   status,
+    })
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// listedOperations is how many recent bulk operations are listed
+const listedOperations = 100
+
+// OperationsHandler starts bulk administrative operations and reports their
+// progress, and repairs inconsistent document metadata
+type OperationsHandler struct {
+    operations  *services.BulkOperations
+    repair      *services.MetadataRepair
+    auditLogger *zap.Logger
+}
+
+// NewOperationsHandler creates a new bulk operations handler
+func NewOperationsHandler(operations *services.BulkOperations, repair *services.MetadataRepair, auditLogger *zap.Logger) (*OperationsHandler, error) {
+    if operations == nil || repair == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &OperationsHandler{
+        operations:  operations,
+        repair:      repair,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// StartOperation starts a bulk operation, or with dry_run reports how many
+// documents it would touch. The operation runs in the background; poll
+// GetOperation for its progress
+func (h *OperationsHandler) StartOperation(c *gin.Context) {
+    var req services.BulkOperationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid bulk operation request", err)
+        return
+    }
+
+    op, err := h.operations.Start(c.Request.Context(), req)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrUnknownBulkAction), errors.Is(err, services.ErrEmptyBulkFilter),
+            errors.Is(err, services.ErrInvalidBulkOperation), errors.Is(err, services.ErrUnknownStep):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid bulk operation request", err)
+        case errors.Is(err, services.ErrBulkOperationTooLarge):
+            writeError(c, h.auditLogger, http.StatusUnprocessableEntity, "Filter matches too many documents", err)
+        case errors.Is(err, services.ErrTooManyBulkOperations):
+            writeError(c, h.auditLogger, http.StatusTooManyRequests, "Too many bulk operations running", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to start bulk operation", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Bulk operation requested",
+        zap.String("operation_id", op.ID),
+        zap.String("action", op.Action),
+        zap.Bool("dry_run", op.DryRun),
+        zap.Int("documents", op.Total),
+        zap.String("requested_by", op.RequestedBy),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    status := http.StatusAccepted
+    if op.DryRun {
+        status = http.StatusOK
+    }
+    c.JSON(status, gin.H{
+        "status": "success",
+        "data":   op,
+    })
+}
+
+// GetOperation returns a bulk operation with its progress
+func (h *OperationsHandler) GetOperation(c *gin.Context) {
+    op, err := h.operations.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrOperationNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Bulk operation not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load bulk operation", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   op,
+    })
+}
+
+// ListOperations returns the most recent bulk operations
+func (h *OperationsHandler) ListOperations(c *gin.Context) {
+    ops, err := h.operations.List(c.Request.Context(), listedOperations)
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list bulk operations", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   ops,
+    })
+}
+
+// CancelOperation stops a running bulk operation once the document in
+// progress is done
+func (h *OperationsHandler) CancelOperation(c *gin.Context) {
+    op, err := h.operations.Cancel(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrOperationNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Bulk operation not found", err)
+        case errors.Is(err, services.ErrBulkOperationFinished):
+            writeError(c, h.auditLogger, http.StatusConflict, "Bulk operation already finished", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to cancel bulk operation", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Bulk operation cancelled",
+        zap.String("operation_id", op.ID),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusAccepted, gin.H{
+        "status": "success",
+        "data":   op,
+    })
+}
+
+// ResumeOperation continues a cancelled bulk operation after the last
+// document it processed
+func (h *OperationsHandler) ResumeOperation(c *gin.Context) {
+    op, err := h.operations.Resume(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrOperationNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Bulk operation not found", err)
+        case errors.Is(err, services.ErrBulkOperationNotResumable):
+            writeError(c, h.auditLogger, http.StatusConflict, "Bulk operation cannot be resumed", err)
+        case errors.Is(err, services.ErrBulkOperationTooLarge):
+            writeError(c, h.auditLogger, http.StatusUnprocessableEntity, "Filter matches too many documents", err)
+        case errors.Is(err, services.ErrTooManyBulkOperations):
+            writeError(c, h.auditLogger, http.StatusTooManyRequests, "Too many bulk operations running", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to resume bulk operation", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Bulk operation resumed",
+        zap.String("operation_id", op.ID),
+        zap.Int("processed", op.Processed),
+        zap.Int("documents", op.Total),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusAccepted, gin.H{
+        "status": "success",
+        "data":   op,
+    })
+}
+
+// RepairDocuments checks the documents matching a filter against the
+// metadata invariants and fixes the violations it safely can, or with dry_run
+// reports the fixes it would apply
+func (h *OperationsHandler) RepairDocuments(c *gin.Context) {
+    var req services.RepairRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid repair request", err)
+        return
+    }
+
+    report, err := h.repair.Repair(c.Request.Context(), req)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrEmptyBulkFilter):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid repair request", err)
+        case errors.Is(err, services.ErrRepairTooLarge):
+            writeError(c, h.auditLogger, http.StatusUnprocessableEntity, "Filter matches too many documents", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to repair documents", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Metadata repair requested",
+        zap.Bool("dry_run", report.DryRun),
+        zap.Int("documents", report.Documents),
+        zap.Int("violating", report.Violating),
+        zap.Int("repaired", report.Repaired),
+        zap.String("requested_by", report.RequestedBy),
+        zap.String("client_ip", c.ClientIP()),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   report,
+    })
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// PortabilityHandler serves LGPD data portability exports
+type PortabilityHandler struct {
+    portability *services.PortabilityService
+    auditLogger *zap.Logger
+}
+
+// NewPortabilityHandler creates a new portability handler
+func NewPortabilityHandler(portability *services.PortabilityService, auditLogger *zap.Logger) (*PortabilityHandler, error) {
+    if portability == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &PortabilityHandler{
+        portability: portability,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// CreateExport builds the portability export of a data subject and returns its
+// time-limited download link
+func (h *PortabilityHandler) CreateExport(c *gin.Context) {
+    export, link, err := h.portability.Export(c.Request.Context(), c.Param("cpf"), c.GetString("user_id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrInvalidSubject):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid CPF", err)
+        case errors.Is(err, services.ErrSubjectNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "No data found for subject", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Portability export failed", err)
+        }
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "export_id":      export.ID,
+            "document_count": export.DocumentCount,
+            "expires_at":     export.ExpiresAt,
+            "url":            link,
+        },
+    })
+}
+
+// DownloadExport streams an export archive to the holder of a valid link. The
+// signed link is the only credential, so this route is exempt from gateway
+// authentication
+func (h *PortabilityHandler) DownloadExport(c *gin.Context) {
+    export, content, info, err := h.portability.Open(c.Request.Context(), c.Param("id"), c.Query("expires"), c.Query("signature"))
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrExportLinkInvalid), errors.Is(err, services.ErrExportLinkExpired):
+            writeError(c, h.auditLogger, http.StatusForbidden, "Invalid or expired export link", err)
+        case errors.Is(err, services.ErrObjectNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Export not found", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Export download failed", err)
+        }
+        return
+    }
+    defer content.Close()
+
+    h.auditLogger.Info("Portability export downloaded",
+        zap.String("export_id", export.ID),
+        zap.String("client_ip", c.ClientIP()),
+        zap.String("range", c.GetHeader("Range")),
+    )
+
+    c.Header("Content-Type", "application/zip")
+    c.Header("Content-Disposition", `attachment; filename="portability-`+export.ID+`.zip"`)
+    c.Header("Cache-Control", "no-store")
+    c.Header("Referrer-Policy", "no-referrer")
+    http.ServeContent(c.Writer, c.Request, "", info.LastModified, content)
+}
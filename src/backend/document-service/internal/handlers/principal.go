@@ -0,0 +1,35 @@
+package handlers
+
+import (
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Principals data key uses are attributed to when no user is authenticated
+const (
+    PrincipalAdmin     = "admin"
+    PrincipalAnonymous = "anonymous"
+)
+
+// IdentifyPrincipal attributes the data key uses of a request to the user the
+// gateway authenticated, so the key usage audit records who decrypted what.
+// Requests made while impersonating are attributed to both the operator and
+// the beneficiary, and those a service makes on behalf of a user to both the
+// service and the user
+func IdentifyPrincipal() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        principal := c.GetString("user_id")
+        if principal == "" {
+            principal = PrincipalAnonymous
+        }
+        if impersonator := c.GetString(impersonatorIDKey); impersonator != "" {
+            principal = impersonator + " as " + principal
+        }
+        if actor := c.GetString(actorIDKey); actor != "" {
+            principal = actor + " for " + principal
+        }
+        c.Request = c.Request.WithContext(utils.WithPrincipal(c.Request.Context(), principal))
+        c.Next()
+    }
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrNoProbeResult = errors.New("synthetic probe has not run yet")
+)
+
+// ProbeHandler runs the synthetic probe on demand and reports its last result
+type ProbeHandler struct {
+    probe       *services.SyntheticProbe
+    auditLogger *zap.Logger
+}
+
+// NewProbeHandler creates a new probe handler
+func NewProbeHandler(probe *services.SyntheticProbe, auditLogger *zap.Logger) (*ProbeHandler, error) {
+    if probe == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ProbeHandler{
+        probe:       probe,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// RunProbe runs the probe now and returns its result, which reports a failed
+// stage rather than failing the request
+func (h *ProbeHandler) RunProbe(c *gin.Context) {
+    result, err := h.probe.Probe(c.Request.Context())
+    if err != nil {
+        if errors.Is(err, services.ErrProbeRunning) {
+            writeError(c, h.auditLogger, http.StatusConflict, "Synthetic probe is already running", err)
+            return
+        }
+        if errors.Is(err, services.ErrProbeMaintenance) {
+            writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Synthetic probe is paused during maintenance", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Synthetic probe could not run", err)
+        return
+    }
+
+    h.auditLogger.Info("Synthetic probe run",
+        zap.String("user_id", c.GetString("user_id")),
+        zap.Bool("passed", result.Passed),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   result,
+    })
+}
+
+// GetProbe returns the result of the last probe
+func (h *ProbeHandler) GetProbe(c *gin.Context) {
+    result := h.probe.Last()
+    if result == nil {
+        writeError(c, h.auditLogger, http.StatusNotFound, "Synthetic probe has not run yet", ErrNoProbeResult)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   result,
+    })
+}
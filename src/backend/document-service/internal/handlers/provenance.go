@@ -0,0 +1,36 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// GetProvenance lists what produced each rendition and extracted field of a
+// document: pipeline version, step implementation, provider model and the
+// inputs it read. The name query parameter narrows it to one artifact name
+func (h *DocumentHandler) GetProvenance(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "GetProvenance")
+    defer span.End()
+
+    doc, err := h.repository.GetByID(ctx, c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_id": doc.ID,
+            "artifacts":   doc.ArtifactProvenance(c.Query("name")),
+        },
+    })
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// ProviderHandler reports the state of the external providers: the quotas,
+// the scoreboard or both, whichever are enabled
+type ProviderHandler struct {
+    quotas      *services.ProviderQuotas
+    scoreboard  *services.ProviderScoreboard
+    auditLogger *zap.Logger
+}
+
+// NewProviderHandler creates a new provider handler
+func NewProviderHandler(quotas *services.ProviderQuotas, scoreboard *services.ProviderScoreboard, auditLogger *zap.Logger) (*ProviderHandler, error) {
+    if (quotas == nil && scoreboard == nil) || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ProviderHandler{
+        quotas:      quotas,
+        scoreboard:  scoreboard,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetProviders reports the quota consumption of each provider this month,
+// the health scoreboard ranking the providers of each kind, and which OCR
+// provider calls go to
+func (h *ProviderHandler) GetProviders(c *gin.Context) {
+    data := gin.H{}
+    if h.quotas != nil {
+        quotas, err := h.quotas.Status(c.Request.Context())
+        if err != nil {
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to get provider quotas", err)
+            return
+        }
+        data["quotas"] = quotas
+    }
+    if h.scoreboard != nil {
+        data["scoreboard"] = h.scoreboard.Scoreboard()
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   data,
+    })
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// SandboxKeyIDHeader names the forensic sandbox key a fetched quarantine
+// item is sealed to
+const SandboxKeyIDHeader = "X-Sandbox-Key-Id"
+
+var (
+    ErrInvalidQuarantineFilter = errors.New("invalid quarantine status or reason")
+)
+
+// quarantineReviewRequest names the reviewer answering for an action on a
+// quarantine item and the grounds for it
+type quarantineReviewRequest struct {
+    Reviewer string `json:"reviewer" form:"reviewer" binding:"required,max=200"`
+    Reason   string `json:"reason" form:"reason" binding:"required,max=2000"`
+}
+
+// holdDocumentRequest quarantines a document suspected of fraud
+type holdDocumentRequest struct {
+    DocumentID string `json:"document_id" binding:"required"`
+    quarantineReviewRequest
+}
+
+// QuarantineHandler serves the review console of quarantined files
+type QuarantineHandler struct {
+    quarantine  *services.QuarantineService
+    auditLogger *zap.Logger
+}
+
+// NewQuarantineHandler creates a new quarantine handler
+func NewQuarantineHandler(quarantine *services.QuarantineService, auditLogger *zap.Logger) (*QuarantineHandler, error) {
+    if quarantine == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &QuarantineHandler{
+        quarantine:  quarantine,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ListItems returns the quarantine items, oldest first, narrowed by the
+// status and reason query parameters
+func (h *QuarantineHandler) ListItems(c *gin.Context) {
+    filter := repository.QuarantineFilter{Status: c.Query("status"), Reason: c.Query("reason")}
+    switch filter.Status {
+    case "", models.QuarantineStatusHeld, models.QuarantineStatusReleased, models.QuarantineStatusDestroyed:
+    default:
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid quarantine filter", ErrInvalidQuarantineFilter)
+        return
+    }
+    switch filter.Reason {
+    case "", models.QuarantineReasonMalware, models.QuarantineReasonActiveContent, models.QuarantineReasonFraud:
+    default:
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid quarantine filter", ErrInvalidQuarantineFilter)
+        return
+    }
+
+    items, err := h.quarantine.List(c.Request.Context(), filter)
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list quarantine items", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   items,
+    })
+}
+
+// HoldDocument quarantines a document suspected of fraud. Its content and
+// renditions are withheld from every caller until the item is resolved
+func (h *QuarantineHandler) HoldDocument(c *gin.Context) {
+    var req holdDocumentRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid quarantine request", err)
+        return
+    }
+
+    item, err := h.quarantine.HoldDocument(c.Request.Context(), req.DocumentID, h.review(c, req.quarantineReviewRequest))
+    if err != nil {
+        h.writeQuarantineError(c, "Failed to quarantine document", err)
+        return
+    }
+
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data":   item,
+    })
+}
+
+// GetItem returns a quarantine item with its history
+func (h *QuarantineHandler) GetItem(c *gin.Context) {
+    item, err := h.quarantine.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        h.writeQuarantineError(c, "Failed to load quarantine item", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   item,
+    })
+}
+
+// FetchItem serves the file a held item withholds, sealed to the forensic
+// sandbox key. The reviewer and reason query parameters are required; the
+// response is an opaque envelope only the sandbox can open, so the browser
+// never holds the plaintext
+func (h *QuarantineHandler) FetchItem(c *gin.Context) {
+    var req quarantineReviewRequest
+    if err := c.ShouldBindQuery(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Reviewer and reason are required", err)
+        return
+    }
+
+    envelope, item, err := h.quarantine.Fetch(c.Request.Context(), c.Param("id"), h.review(c, req))
+    if err != nil {
+        h.writeQuarantineError(c, "Failed to fetch quarantined file", err)
+        return
+    }
+
+    c.Header("Content-Disposition", `attachment; filename="`+item.ID+`.sealed"`)
+    c.Header("Content-Security-Policy", "sandbox")
+    c.Header("Cache-Control", "no-store")
+    c.Header(SandboxKeyIDHeader, h.quarantine.SandboxKeyID())
+    c.Header("X-Plaintext-Content-Type", item.ContentType)
+    c.Data(http.StatusOK, clientEncryptedContentType, envelope)
+}
+
+// ReleaseItem clears a held item
+func (h *QuarantineHandler) ReleaseItem(c *gin.Context) {
+    h.resolve(c, h.quarantine.Release, "Failed to release quarantine item")
+}
+
+// DestroyItem permanently deletes the file a held item withholds
+func (h *QuarantineHandler) DestroyItem(c *gin.Context) {
+    h.resolve(c, h.quarantine.Destroy, "Failed to destroy quarantine item")
+}
+
+// resolve releases or destroys the item in the path on the review in the
+// body
+func (h *QuarantineHandler) resolve(c *gin.Context, action func(ctx context.Context, id string, review services.QuarantineReview) (*models.QuarantineItem, error), message string) {
+    var req quarantineReviewRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Reviewer and reason are required", err)
+        return
+    }
+
+    item, err := action(c.Request.Context(), c.Param("id"), h.review(c, req))
+    if err != nil {
+        h.writeQuarantineError(c, message, err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   item,
+    })
+}
+
+// review attributes an action to the credential of the request and the
+// reviewer named in it
+func (h *QuarantineHandler) review(c *gin.Context, req quarantineReviewRequest) services.QuarantineReview {
+    return services.QuarantineReview{
+        Principal: utils.PrincipalFromContext(c.Request.Context()),
+        Reviewer:  req.Reviewer,
+        Reason:    req.Reason,
+    }
+}
+
+// writeQuarantineError maps the errors of the quarantine service to responses
+func (h *QuarantineHandler) writeQuarantineError(c *gin.Context, message string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrQuarantineItemNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Quarantine item not found", err)
+    case errors.Is(err, repository.ErrDocumentNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+    case errors.Is(err, models.ErrQuarantineResolved), errors.Is(err, services.ErrAlreadyQuarantined):
+        writeError(c, h.auditLogger, http.StatusConflict, message, err)
+    case errors.Is(err, services.ErrQuarantinedContentMissing):
+        writeError(c, h.auditLogger, http.StatusGone, "Quarantined file is no longer stored", err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, message, err)
+    }
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+    "errors"
+    "math"
+    "net/http"
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// Headers reporting the caller's quota on every counted response
+const (
+    QuotaLimitHeader        = "X-Quota-Limit"
+    QuotaRemainingHeader    = "X-Quota-Remaining"
+    QuotaResetHeader        = "X-Quota-Reset"
+    QuotaBurstCreditsHeader = "X-Quota-Burst-Credits"
+)
+
+var (
+    ErrQuotaExhausted = errors.New("quota and burst credits exhausted")
+    ErrNoTenant       = errors.New("request has no tenant")
+)
+
+// EnforceQuota counts the requests of users whose role is subject to soft
+// quotas against their tenant's quota and reports the remaining quota and
+// burst credits in response headers. Requests are rejected with 429 only
+// once the burst credits run out too
+func EnforceQuota(quotas *services.SoftQuotas, logger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        tenantID := c.GetString("tenant_id")
+        if tenantID == "" || !quotas.Applies(c.GetString("user_role")) {
+            c.Next()
+            return
+        }
+
+        decision := quotas.Take(tenantID)
+        setQuotaHeaders(c, decision.Usage)
+        if !decision.Allowed {
+            c.Header("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+            writeError(c, logger, http.StatusTooManyRequests, "Quota exceeded, try again later", ErrQuotaExhausted)
+            return
+        }
+        c.Next()
+    }
+}
+
+// setQuotaHeaders reports the usage; the reset is in seconds from now
+func setQuotaHeaders(c *gin.Context, usage models.QuotaUsage) {
+    reset := math.Ceil(max(time.Until(usage.ResetsAt).Seconds(), 0))
+    c.Header(QuotaLimitHeader, strconv.Itoa(usage.Limit))
+    c.Header(QuotaRemainingHeader, strconv.Itoa(usage.Remaining))
+    c.Header(QuotaResetHeader, strconv.Itoa(int(reset)))
+    c.Header(QuotaBurstCreditsHeader, strconv.Itoa(int(usage.BurstCredits)))
+}
+
+// QuotaHandler reports the soft quota usage of tenants
+type QuotaHandler struct {
+    quotas      *services.SoftQuotas
+    auditLogger *zap.Logger
+}
+
+// NewQuotaHandler creates a new quota handler
+func NewQuotaHandler(quotas *services.SoftQuotas, auditLogger *zap.Logger) (*QuotaHandler, error) {
+    if quotas == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &QuotaHandler{
+        quotas:      quotas,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetUsage returns the quota usage and burst credits of the caller's tenant
+func (h *QuotaHandler) GetUsage(c *gin.Context) {
+    tenantID := c.GetString("tenant_id")
+    if tenantID == "" {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Request has no tenant", ErrNoTenant)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.quotas.Usage(tenantID),
+    })
+}
+
+// ListUsage returns the quota usage of the tenants seen recently
+func (h *QuotaHandler) ListUsage(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.quotas.List(),
+    })
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "runtime/debug"
+    "syscall"
+    "time"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.opentelemetry.io/otel/trace"
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// Recover turns a panic while serving a request into the structured 500
+// response. The panic is logged with its stack trace, request ID and trace ID,
+// counted by route and queued for the error tracking service; reporting may be
+// nil. A panic after the response was written only aborts the request.
+// http.ErrAbortHandler is passed on, and a client hanging up mid-response is
+// not reported
+func Recover(reporting *services.ErrorReporting, logger *zap.Logger) gin.HandlerFunc {
+    logger = logger.Named("recovery")
+
+    return func(c *gin.Context) {
+        defer func() {
+            recovered := recover()
+            if recovered == nil {
+                return
+            }
+            if recovered == http.ErrAbortHandler {
+                panic(recovered)
+            }
+            if err, ok := recovered.(error); ok && (errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+                c.Abort()
+                return
+            }
+
+            route := c.FullPath()
+            if route == "" {
+                route = "unmatched"
+            }
+            report := &services.ErrorReport{
+                Message:    fmt.Sprint(recovered),
+                Stack:      debug.Stack(),
+                Method:     c.Request.Method,
+                Route:      route,
+                RequestID:  c.GetString("request_id"),
+                UserID:     c.GetString("user_id"),
+                OccurredAt: time.Now(),
+            }
+            if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+                report.TraceID = spanContext.TraceID().String()
+            }
+
+            services.ObservePanic(route)
+            logger.Error("Panic recovered",
+                zap.String("panic", report.Message),
+                zap.String("method", report.Method),
+                zap.String("route", report.Route),
+                zap.String("request_id", report.RequestID),
+                zap.String("trace_id", report.TraceID),
+                zap.String("user_id", report.UserID),
+                zap.ByteString("stack", report.Stack),
+            )
+            reporting.Report(report)
+
+            if c.Writer.Written() {
+                c.Abort()
+                return
+            }
+            c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+                "status":     "error",
+                "message":    "Internal server error",
+                "request_id": report.RequestID,
+            })
+        }()
+        c.Next()
+    }
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// writeError logs the failure and writes the standard error response body.
+// Internal errors caused by the request running out of retries are answered
+// with 503, as the dependency that failed may recover
+func writeError(c *gin.Context, logger *zap.Logger, status int, message string, err error) {
+    if status == http.StatusInternalServerError && errors.Is(err, utils.ErrRetryBudgetExhausted) {
+        status = http.StatusServiceUnavailable
+    }
+    var exceeded *utils.DeadlineError
+    if status == http.StatusInternalServerError && errors.As(err, &exceeded) {
+        status = http.StatusGatewayTimeout
+    }
+    fields := []zap.Field{
+        zap.Error(err),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("path", c.Request.URL.Path),
+    }
+    if impersonator := c.GetString(impersonatorIDKey); impersonator != "" {
+        fields = append(fields, zap.String("impersonator_id", impersonator))
+    }
+    if keyID := c.GetString(apiKeyIDKey); keyID != "" {
+        fields = append(fields, zap.String("api_key_id", keyID))
+    }
+    if actor := c.GetString(actorIDKey); actor != "" {
+        fields = append(fields, zap.String("actor_id", actor))
+    }
+    if account := c.GetString(serviceAccountKey); account != "" {
+        fields = append(fields, zap.String("service_account", account))
+    }
+    if spiffeID := c.GetString(spiffeIDKey); spiffeID != "" {
+        fields = append(fields, zap.String("spiffe_id", spiffeID))
+    }
+    if exceeded != nil {
+        fields = append(fields, zap.String("deadline_exceeded_by", exceeded.Dependency))
+    }
+    logger.Error(message, fields...)
+
+    body := gin.H{
+        "status":  "error",
+        "message": message,
+    }
+    if err != nil {
+        body["error"] = err.Error()
+    }
+    c.AbortWithStatusJSON(status, body)
+}
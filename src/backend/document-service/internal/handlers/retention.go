@@ -0,0 +1,166 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrRetentionNotAllowed = errors.New("role may not manage retention")
+)
+
+// acknowledgeNoticeRequest is the body of a purge notice acknowledgment
+type acknowledgeNoticeRequest struct {
+    Holds []services.RetentionHoldRequest `json:"holds"`
+}
+
+// RetentionHandler lets tenant admins review the purge notices of their
+// tenant, acknowledge them and hold documents back from the purge
+type RetentionHandler struct {
+    retention   *services.RetentionService
+    auditLogger *zap.Logger
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retention *services.RetentionService, auditLogger *zap.Logger) (*RetentionHandler, error) {
+    if retention == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &RetentionHandler{
+        retention:   retention,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ListNotices returns the purge notices of the caller's tenant
+func (h *RetentionHandler) ListNotices(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    notices, err := h.retention.ListNotices(c.Request.Context(), c.GetString("tenant_id"))
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list purge notices", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   notices,
+    })
+}
+
+// GetNotice returns a purge notice of the caller's tenant
+func (h *RetentionHandler) GetNotice(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    notice, err := h.retention.GetNotice(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrNoticeNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Purge notice not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load purge notice", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   notice,
+    })
+}
+
+// AcknowledgeNotice records that the caller reviewed a purge notice and
+// places holds on the listed documents that must be kept
+func (h *RetentionHandler) AcknowledgeNotice(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    var req acknowledgeNoticeRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid acknowledgment", err)
+            return
+        }
+    }
+
+    notice, err := h.retention.Acknowledge(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"), c.GetString("user_id"), req.Holds)
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrNoticeNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Purge notice not found", err)
+        case errors.Is(err, services.ErrDocumentNotInNotice), errors.Is(err, models.ErrMissingField):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid hold", err)
+        case errors.Is(err, services.ErrDocumentPurged):
+            writeError(c, h.auditLogger, http.StatusConflict, "Document was already purged", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to acknowledge purge notice", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Purge notice acknowledged",
+        zap.String("notice_id", notice.ID),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.Int("holds", len(req.Holds)),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   notice,
+    })
+}
+
+// ReleaseHold releases the retention hold of a document of the caller's
+// tenant
+func (h *RetentionHandler) ReleaseHold(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    doc, err := h.retention.ReleaseHold(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"), c.GetString("user_id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+        case errors.Is(err, services.ErrNoRetentionHold):
+            writeError(c, h.auditLogger, http.StatusConflict, "Document has no retention hold", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to release retention hold", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Retention hold released",
+        zap.String("document_id", doc.ID),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_id":    doc.ID,
+            "retention_date": doc.RetentionDate,
+        },
+    })
+}
+
+// authorize rejects callers whose role may not manage retention
+func (h *RetentionHandler) authorize(c *gin.Context) bool {
+    if h.retention.MayManage(c.GetString("user_role")) {
+        return true
+    }
+    writeError(c, h.auditLogger, http.StatusForbidden, "Not allowed to manage retention", ErrRetentionNotAllowed)
+    return false
+}
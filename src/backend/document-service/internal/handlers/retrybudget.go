@@ -0,0 +1,27 @@
+package handlers
+
+import (
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// BudgetRetries gives each request one pool of retries shared by the layers
+// it calls into, so a storage call retrying a KMS call retrying itself cannot
+// multiply the latency of a failing dependency. A request that runs out
+// fails with utils.ErrRetryBudgetExhausted, answered with 503
+func BudgetRetries(cfg config.RetryBudgetConfig) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !cfg.Enabled {
+            c.Next()
+            return
+        }
+
+        budget := utils.NewRetryBudget(cfg.Retries)
+        c.Request = c.Request.WithContext(utils.WithRetryBudget(c.Request.Context(), budget))
+        c.Next()
+        services.ObserveRetryBudget(budget)
+    }
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// reviewRequest is the body of a document review decision
+type reviewRequest struct {
+    Decision string `json:"decision" binding:"required,oneof=approve reject"`
+    Reason   string `json:"reason" binding:"max=1000"`
+}
+
+// pageReviewRequest is the body of page-level review decisions
+type pageReviewRequest struct {
+    Pages []pageDecision `json:"pages" binding:"required,min=1,dive"`
+}
+
+// pageOperationsRequest is the body of page operations, applied in order
+type pageOperationsRequest struct {
+    Operations []pageOperation `json:"operations" binding:"required,min=1,max=50,dive"`
+}
+
+type pageOperation struct {
+    Operation string `json:"operation" binding:"required,oneof=rotate reorder remove"`
+    Pages     []int  `json:"pages"`
+    Degrees   int    `json:"degrees"`
+    Order     []int  `json:"order"`
+}
+
+type pageDecision struct {
+    Number   int    `json:"number" binding:"required,min=1"`
+    Decision string `json:"decision" binding:"required,oneof=approve rescan"`
+    Reason   string `json:"reason" binding:"max=1000"`
+}
+
+// ReviewHandler handles reviewer decisions on documents
+type ReviewHandler struct {
+    review      *services.ReviewService
+    queue       *services.ReviewQueue
+    sla         *services.SLATracker
+    auditLogger *zap.Logger
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(review *services.ReviewService, auditLogger *zap.Logger) (*ReviewHandler, error) {
+    if review == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ReviewHandler{
+        review:      review,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetReviewDocument returns the document metadata shown to reviewers
+func (h *ReviewHandler) GetReviewDocument(c *gin.Context) {
+    doc, err := h.review.GetDocument(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load document", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   doc,
+    })
+}
+
+// ReviewDocument approves or rejects a processed document
+func (h *ReviewHandler) ReviewDocument(c *gin.Context) {
+    var req reviewRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid review request", err)
+        return
+    }
+
+    doc, err := h.review.Review(c.Request.Context(), c.Param("id"), req.Decision, req.Reason, c.GetString("user_id"))
+    if err != nil {
+        switch {
+        case errors.Is(err, repository.ErrDocumentNotFound):
+            writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+        case errors.Is(err, models.ErrNotReviewable), errors.Is(err, models.ErrInvalidDecision), errors.Is(err, models.ErrMissingField),
+            errors.Is(err, models.ErrPagesPending):
+            writeError(c, h.auditLogger, http.StatusConflict, "Document cannot be reviewed", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Review failed", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Document reviewed",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("status", doc.Status),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   doc,
+    })
+}
+
+// ReviewPages approves pages of a multi-page document or requests that they
+// be scanned again
+func (h *ReviewHandler) ReviewPages(c *gin.Context) {
+    var req pageReviewRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid page review request", err)
+        return
+    }
+
+    decisions := make([]models.PageDecision, len(req.Pages))
+    for i, page := range req.Pages {
+        decisions[i] = models.PageDecision{Number: page.Number, Decision: page.Decision, Reason: page.Reason}
+    }
+
+    doc, err := h.review.ReviewPages(c.Request.Context(), c.Param("id"), decisions, c.GetString("user_id"))
+    if err != nil {
+        h.pageError(c, "Page review failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document pages reviewed",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("status", doc.Status),
+        zap.Ints("rescan_pages", doc.PagesAwaitingRescan()),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   doc,
+    })
+}
+
+// ReplacePage uploads the rescan of a page a reviewer asked to be scanned
+// again
+func (h *ReviewHandler) ReplacePage(c *gin.Context) {
+    page, err := strconv.Atoi(c.Param("page"))
+    if err != nil || page < 1 {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid page number", models.ErrInvalidPage)
+        return
+    }
+
+    file, _, err := c.Request.FormFile("file")
+    if isBodyTooLarge(err) {
+        writeError(c, h.auditLogger, http.StatusRequestEntityTooLarge, "File too large", ErrFileTooLarge)
+        return
+    }
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid file upload", err)
+        return
+    }
+    defer file.Close()
+
+    doc, err := h.review.ReplacePage(c.Request.Context(), c.Param("id"), page, file, c.GetString("user_id"))
+    if err != nil {
+        h.pageError(c, "Page rescan failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document page rescanned",
+        zap.String("document_id", doc.ID),
+        zap.Int("page", page),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   doc,
+    })
+}
+
+// EditPages rotates, reorders or removes pages of a document awaiting review
+func (h *ReviewHandler) EditPages(c *gin.Context) {
+    var req pageOperationsRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid page operations request", err)
+        return
+    }
+
+    operations := make([]models.PageOperation, len(req.Operations))
+    for i, operation := range req.Operations {
+        operations[i] = models.PageOperation{
+            Operation: operation.Operation,
+            Pages:     operation.Pages,
+            Degrees:   operation.Degrees,
+            Order:     operation.Order,
+        }
+    }
+
+    doc, err := h.review.EditPages(c.Request.Context(), c.Param("id"), operations, c.GetString("user_id"))
+    if err != nil {
+        h.pageError(c, "Page operations failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document pages edited",
+        zap.String("document_id", doc.ID),
+        zap.Int("operations", len(operations)),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+
+    c.Header(DocumentVersionHeader, strconv.FormatInt(doc.Version, 10))
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   doc,
+    })
+}
+
+// GetChecklist reports the review state of an enrollment's required
+// documents, including partially approved ones
+func (h *ReviewHandler) GetChecklist(c *gin.Context) {
+    checklist, err := h.review.Checklist(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load checklist", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   checklist,
+    })
+}
+
+// pageError maps page review failures to responses
+func (h *ReviewHandler) pageError(c *gin.Context, msg string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrDocumentNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Document not found", err)
+    case errors.Is(err, models.ErrInvalidSize):
+        writeError(c, h.auditLogger, http.StatusRequestEntityTooLarge, "File too large", err)
+    case errors.Is(err, models.ErrInvalidPage), errors.Is(err, models.ErrInvalidDecision), errors.Is(err, models.ErrMissingField),
+        errors.Is(err, services.ErrInvalidRescan), errors.Is(err, models.ErrInvalidPageOperation):
+        writeError(c, h.auditLogger, http.StatusBadRequest, msg, err)
+    case errors.Is(err, models.ErrNotReviewable), errors.Is(err, models.ErrPageNotAwaitingRescan), errors.Is(err, services.ErrNotPaginated),
+        errors.Is(err, models.ErrNotEditable):
+        writeError(c, h.auditLogger, http.StatusConflict, msg, err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, msg, err)
+    }
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// SealHandler receives underwriting events, which seal the document set of
+// closed enrollments, and serves and verifies the seals
+type SealHandler struct {
+    seals       *services.SealService
+    auditLogger *zap.Logger
+}
+
+// NewSealHandler creates a new enrollment seal handler
+func NewSealHandler(cfg *config.Config, seals *services.SealService, auditLogger *zap.Logger) (*SealHandler, error) {
+    if cfg == nil || seals == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &SealHandler{
+        seals:       seals,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ReceiveEvent seals the enrollment of an event, whose request signature
+// RequireSignedRequest has verified, before acknowledging, so the
+// underwriting service redelivers the event until the seal is stored
+func (h *SealHandler) ReceiveEvent(c *gin.Context) {
+    body, ok := readBody(c)
+    if !ok {
+        return
+    }
+
+    var event services.UnderwritingEvent
+    if err := json.Unmarshal(body, &event); err != nil {
+        c.AbortWithStatus(http.StatusBadRequest)
+        return
+    }
+
+    seal, err := h.seals.Handle(c.Request.Context(), event)
+    if err != nil {
+        switch {
+        case errors.Is(err, services.ErrUnknownUnderwritingEvent), errors.Is(err, services.ErrInvalidUnderwritingEvent):
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid underwriting event", err)
+        case errors.Is(err, services.ErrTimestampFailed):
+            writeError(c, h.auditLogger, http.StatusServiceUnavailable, "Seal could not be timestamped", err)
+        default:
+            writeError(c, h.auditLogger, http.StatusInternalServerError, "Enrollment seal not stored", err)
+        }
+        return
+    }
+
+    h.auditLogger.Info("Underwriting event received",
+        zap.String("event_id", event.EventID),
+        zap.String("type", event.Type),
+        zap.String("enrollment_id", event.EnrollmentID),
+        zap.String("seal_id", seal.ID),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   seal,
+    })
+}
+
+// GetSeal returns the most recent seal of an enrollment
+func (h *SealHandler) GetSeal(c *gin.Context) {
+    seal, err := h.seals.Get(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrSealNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Enrollment is not sealed", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to load enrollment seal", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   seal,
+    })
+}
+
+// VerifySeal checks the most recent seal of an enrollment against its
+// current documents. A seal that no longer matches is still answered with
+// 200 and valid set to false
+func (h *SealHandler) VerifySeal(c *gin.Context) {
+    verification, err := h.seals.Verify(c.Request.Context(), c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrSealNotFound) {
+            writeError(c, h.auditLogger, http.StatusNotFound, "Enrollment is not sealed", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to verify enrollment seal", err)
+        return
+    }
+
+    h.auditLogger.Info("Enrollment seal verified",
+        zap.String("enrollment_id", c.Param("id")),
+        zap.String("seal_id", verification.Seal.ID),
+        zap.Bool("valid", verification.Valid),
+        zap.String("user_id", c.GetString("user_id")),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   verification,
+    })
+}
+
+// GetKey returns the public key seals are verified with
+func (h *SealHandler) GetKey(c *gin.Context) {
+    key, keyID, err := h.seals.PublicKey()
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to encode seal key", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "key_id":     keyID,
+            "public_key": key,
+            "algorithm":  "ECDSA-P256-SHA256",
+        },
+    })
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrSearchNotAllowed = errors.New("role may not search documents")
+)
+
+// SearchHandler lets authorized roles find the documents of their tenant by
+// the text extracted from them
+type SearchHandler struct {
+    search      *services.SearchIndex
+    auditLogger *zap.Logger
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(search *services.SearchIndex, auditLogger *zap.Logger) (*SearchHandler, error) {
+    if search == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &SearchHandler{
+        search:      search,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// Search returns the IDs of the documents of the caller's tenant whose text
+// holds every term of the q parameter. The query is personal data and is
+// never logged
+func (h *SearchHandler) Search(c *gin.Context) {
+    if !h.search.MaySearch(c.GetString("user_role")) {
+        writeError(c, h.auditLogger, http.StatusForbidden, "Not allowed to search documents", ErrSearchNotAllowed)
+        return
+    }
+
+    documentIDs, err := h.search.Search(c.Request.Context(), c.GetString("tenant_id"), c.Query("q"))
+    if err != nil {
+        if errors.Is(err, services.ErrEmptySearch) {
+            writeError(c, h.auditLogger, http.StatusBadRequest, "Search terms are required", err)
+            return
+        }
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Search failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Documents searched",
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("tenant_id", c.GetString("tenant_id")),
+        zap.Int("results", len(documentIDs)),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_ids": documentIDs,
+        },
+    })
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// ServiceTokenHeader carries the bearer token of a service account
+const ServiceTokenHeader = "X-Service-Token"
+
+// serviceAccountKey is the context key of the service account a request was
+// made with
+const serviceAccountKey = "service_account"
+
+// spiffeIDKey is the context key of the SPIFFE ID of a workload that
+// authenticated with its SVID
+const spiffeIDKey = "spiffe_id"
+
+// serviceAccountPrincipalPrefix prefixes the account name requests made with
+// a service account are attributed to
+const serviceAccountPrincipalPrefix = "service:"
+
+var (
+    ErrServiceAccountsDisabled = errors.New("service accounts are disabled")
+    ErrConflictingCredentials  = errors.New("service account credentials cannot be combined with other credentials")
+)
+
+// serviceAccountRoutePermissions maps the routes service accounts may call to
+// the permission each requires; every other route is closed to them
+var serviceAccountRoutePermissions = map[string]string{
+    "GET /api/v1/documents/:id/status":     models.PermissionDocumentStatus,
+    "POST /api/v1/documents/:id/reprocess": models.PermissionDocumentReprocess,
+    "DELETE /api/v1/documents/:id":         models.PermissionDocumentDelete,
+    "GET /api/v1/retention/notices":        models.PermissionRetentionNotices,
+    "GET /api/v1/retention/notices/:id":    models.PermissionRetentionNotices,
+    "GET /admin/ocr-canary":                models.PermissionOCRCanary,
+    "GET /admin/jobs":                      models.PermissionJobsRead,
+    "GET /admin/migrations":                models.PermissionMigrationsRead,
+    "POST /admin/migrations/contract":      models.PermissionMigrationsContract,
+    "GET /admin/encryption-scan":           models.PermissionReencrypt,
+    "POST /admin/encryption-scan":          models.PermissionReencrypt,
+    "POST /admin/documents/:id/reencrypt":  models.PermissionReencrypt,
+    "GET /admin/documents/:id/events":      models.PermissionProjections,
+    "POST /admin/documents/:id/rebuild":    models.PermissionProjections,
+    "POST /admin/projections/rebuild":      models.PermissionProjections,
+    "POST /admin/operations":               models.PermissionBulkOperations,
+    "GET /admin/operations":                models.PermissionBulkOperations,
+    "GET /admin/operations/:id":            models.PermissionBulkOperations,
+    "POST /admin/operations/:id/cancel":    models.PermissionBulkOperations,
+    "POST /admin/operations/:id/resume":    models.PermissionBulkOperations,
+}
+
+// AuthenticateServiceAccount authenticates internal callers presenting the
+// token of a service account, or the X.509 SVID of a SPIFFE workload on the
+// mutual TLS listener. The route must require a permission of the caller's
+// role; requests are attributed to the account and audit logged with its
+// name, role and the credential used. It runs ahead of AdminAuth, which lets
+// authenticated accounts through. Requests with neither pass through
+// untouched
+func AuthenticateServiceAccount(accounts *services.ServiceAccounts, workloads *services.WorkloadIdentity, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        token := c.GetHeader(ServiceTokenHeader)
+        svid := token == "" && workloads != nil && c.Request.TLS != nil && len(c.Request.TLS.PeerCertificates) > 0
+        if token == "" && !svid {
+            c.Next()
+            return
+        }
+        if token != "" && accounts == nil {
+            writeError(c, auditLogger, http.StatusUnauthorized, "Service accounts unavailable", ErrServiceAccountsDisabled)
+            return
+        }
+        if c.GetHeader(APIKeyHeader) != "" || c.GetHeader(DelegatedTokenHeader) != "" {
+            writeError(c, auditLogger, http.StatusBadRequest, "Conflicting credentials", ErrConflictingCredentials)
+            return
+        }
+
+        credential := "token"
+        var account *models.ServiceAccount
+        var err error
+        if svid {
+            credential = "svid"
+            account, err = workloads.Authenticate(c.Request.TLS.PeerCertificates)
+            if err != nil {
+                writeError(c, auditLogger, http.StatusUnauthorized, "Workload identity not authorized", err)
+                return
+            }
+            c.Set(spiffeIDKey, account.Name)
+        } else {
+            account, err = accounts.Authenticate(token)
+            if err != nil {
+                writeError(c, auditLogger, http.StatusUnauthorized, "Invalid service account token", err)
+                return
+            }
+        }
+        c.Set(serviceAccountKey, account.Name)
+        c.Set("user_id", serviceAccountPrincipalPrefix+account.Name)
+        c.Set("user_role", account.Role)
+
+        permission := serviceAccountRoutePermissions[c.Request.Method+" "+c.FullPath()]
+        if svid {
+            err = workloads.Authorize(account, permission)
+        } else {
+            err = accounts.Authorize(account, permission)
+        }
+        if err != nil {
+            writeError(c, auditLogger, http.StatusForbidden, "Service account role does not allow this request", err)
+            return
+        }
+        c.Request = c.Request.WithContext(utils.WithPrincipal(c.Request.Context(), serviceAccountPrincipalPrefix+account.Name))
+
+        c.Next()
+
+        fields := []zap.Field{
+            zap.String("service_account", account.Name),
+            zap.String("role", account.Role),
+            zap.String("credential", credential),
+            zap.String("method", c.Request.Method),
+            zap.String("path", c.Request.URL.Path),
+            zap.Int("status", c.Writer.Status()),
+            zap.String("client_ip", c.ClientIP()),
+        }
+        if svid {
+            fields = append(fields, zap.String("spiffe_id", account.Name))
+        }
+        auditLogger.Info("Service account request", fields...)
+    }
+}
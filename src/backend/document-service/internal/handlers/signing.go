@@ -0,0 +1,48 @@
+package handlers
+
+import (
+    "bytes"
+    "io"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// signingKeyIDKey holds the key a verified service call was signed with
+const signingKeyIDKey = "signing_key_id"
+
+// RequireSignedRequest verifies the HMAC signature of calls from internal
+// services. The body is read to check its digest and restored for the
+// handler. Requests pass through unchecked while request signing is disabled
+func RequireSignedRequest(signer *services.RequestSigner, auditLogger *zap.Logger) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !signer.Enabled() {
+            c.Next()
+            return
+        }
+
+        body, ok := readBody(c)
+        if !ok {
+            return
+        }
+
+        keyID, err := signer.Verify(c.Request, body)
+        if err != nil {
+            auditLogger.Warn("Rejected service call",
+                zap.Error(err),
+                zap.String("key_id", keyID),
+                zap.String("path", c.Request.URL.Path),
+                zap.String("remote_addr", c.ClientIP()),
+            )
+            c.AbortWithStatus(http.StatusUnauthorized)
+            return
+        }
+
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+        c.Set(signingKeyIDKey, keyID)
+        c.Next()
+    }
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// ProcessingModeHeader tells clients that documents are accepted with OCR
+// deferred
+const ProcessingModeHeader = "X-Processing-Mode"
+
+// SignalDegradation reports the processing mode on every API response while
+// OCR is deferred, so clients know uploads will be processed later
+func SignalDegradation(slo *services.SLOMonitor) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if slo.DeferOCR() {
+            c.Header(ProcessingModeHeader, models.ProcessingModeOCRDeferred)
+        }
+        c.Next()
+    }
+}
+
+// SLOHandler reports the error budgets of the service
+type SLOHandler struct {
+    slo         *services.SLOMonitor
+    auditLogger *zap.Logger
+}
+
+// NewSLOHandler creates a new SLO handler
+func NewSLOHandler(slo *services.SLOMonitor, auditLogger *zap.Logger) (*SLOHandler, error) {
+    if slo == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &SLOHandler{
+        slo:         slo,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// GetSLO returns the OCR error budget and the processing mode of this
+// instance
+func (h *SLOHandler) GetSLO(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   h.slo.Status(),
+    })
+}
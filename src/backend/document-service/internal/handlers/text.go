@@ -0,0 +1,146 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const (
+    // AccessPurposeHeader states why the caller reads a document's text
+    AccessPurposeHeader = "X-Access-Purpose"
+    // MachineTranslationHeader names the provider of machine translated text
+    MachineTranslationHeader = "X-Machine-Translation"
+)
+
+var ErrTextRendition = errors.New("extracted text is only served by the text endpoint")
+
+// GetText returns the text extracted from a document: the full text for roles
+// allowed it and the redacted rendition for everyone else. With
+// ?translated=true it returns the machine translation of the redacted text,
+// labeled as such. Every read is logged with the purpose the caller stated
+func (h *DocumentHandler) GetText(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "GetText")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("text", "completed").Inc()
+
+    doc, err := h.repository.GetByID(ctx, c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return
+    }
+
+    if h.viewer.Restricted(doc, c.GetString("user_role")) {
+        h.handleError(c, http.StatusForbidden, "Document is only available in the secure viewer", services.ErrSecureViewerOnly)
+        return
+    }
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return
+    }
+    if doc.Delayed(models.CapabilityText) {
+        h.handleError(c, http.StatusConflict, "Text is available once the large document is processed", ErrCapabilityDelayed)
+        return
+    }
+
+    purpose := c.GetHeader(AccessPurposeHeader)
+    full, err := h.text.Authorize(doc, c.GetString("user_role"), purpose)
+    if err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid access purpose", err)
+        return
+    }
+
+    if c.Query("translated") == "true" {
+        h.getTranslation(c, doc, purpose)
+        return
+    }
+
+    text, err := h.text.Read(ctx, doc, full)
+    if err != nil {
+        if errors.Is(err, services.ErrTextNotAvailable) || errors.Is(err, services.ErrObjectNotFound) {
+            h.handleError(c, http.StatusNotFound, "Extracted text not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Extracted text retrieval failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document text accessed",
+        zap.String("document_id", doc.ID),
+        zap.String("document_type", doc.DocumentType),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("user_role", c.GetString("user_role")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
+        zap.String("purpose", purpose),
+        zap.Bool("redacted", !full),
+    )
+    variant := models.RenditionOCRTextRedacted
+    if full {
+        variant = models.RenditionOCRText
+    }
+    h.recordAccess(c, doc, models.ReceiptAccessText, variant)
+
+    c.Header("Cache-Control", "no-store")
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_id": doc.ID,
+            "redacted":    !full,
+            "language":    doc.Language,
+            "text":        text,
+        },
+    })
+}
+
+// getTranslation returns the machine translation of a document's redacted
+// text. The translation is labeled in the response body and header so it is
+// never mistaken for the document's own text
+func (h *DocumentHandler) getTranslation(c *gin.Context, doc *models.Document, purpose string) {
+    text, translation, err := h.text.ReadTranslation(c.Request.Context(), doc)
+    if err != nil {
+        if errors.Is(err, services.ErrTranslationNotAvailable) || errors.Is(err, services.ErrObjectNotFound) {
+            h.handleError(c, http.StatusNotFound, "Machine translation not found", err)
+            return
+        }
+        h.handleError(c, http.StatusInternalServerError, "Machine translation retrieval failed", err)
+        return
+    }
+
+    h.auditLogger.Info("Document text accessed",
+        zap.String("document_id", doc.ID),
+        zap.String("document_type", doc.DocumentType),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("user_role", c.GetString("user_role")),
+        zap.String("impersonator_id", c.GetString(impersonatorIDKey)),
+        zap.String("purpose", purpose),
+        zap.Bool("redacted", true),
+        zap.Bool("machine_translated", true),
+    )
+    h.recordAccess(c, doc, models.ReceiptAccessText, models.RenditionOCRTextTranslated)
+
+    c.Header("Cache-Control", "no-store")
+    c.Header("Content-Language", translation.TargetLanguage)
+    c.Header(MachineTranslationHeader, translation.Provider)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "document_id":         doc.ID,
+            "redacted":            true,
+            "language":            doc.Language,
+            "machine_translated":  true,
+            "machine_translation": translation,
+            "text":                text,
+        },
+    })
+}
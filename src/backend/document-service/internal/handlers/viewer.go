@@ -0,0 +1,158 @@
+package handlers
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "strconv"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// viewerEventRequest is the body sent by the secure viewer beacon. Browsers
+// send beacons as text/plain, so the body is bound as JSON regardless of the
+// content type
+type viewerEventRequest struct {
+    Event string `json:"event" binding:"required,oneof=screenshot_attempt print_attempt copy_attempt focus_lost"`
+    Page  int    `json:"page" binding:"min=0"`
+}
+
+// ViewerInfo returns the page count of a document opened in the secure viewer,
+// and the key its access events are signed with when they are recorded
+func (h *DocumentHandler) ViewerInfo(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "ViewerInfo")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("viewer_info", "completed").Inc()
+
+    doc, ok := h.viewerDocument(ctx, c)
+    if !ok {
+        return
+    }
+
+    count, err := h.viewer.PageCount(ctx, doc)
+    if err != nil {
+        h.handleViewerError(c, err)
+        return
+    }
+
+    data := gin.H{
+        "document_id": doc.ID,
+        "page_count":  count,
+    }
+    // The viewer signs the access events it reports with this key
+    if h.accessEvents != nil {
+        data["access_event_key"] = h.accessEvents.SessionKey(doc.ID, viewerSession(c))
+    }
+
+    c.Header("Cache-Control", "no-store")
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   data,
+    })
+}
+
+// ViewerPage serves one page rendered as a JPEG watermarked with the viewer's
+// user, session, IP and time. Every page served is an audit event, and pages
+// are never cached so each view is recorded
+func (h *DocumentHandler) ViewerPage(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "ViewerPage")
+    defer span.End()
+
+    defer h.metrics.WithLabelValues("viewer_page", "completed").Inc()
+
+    page, err := strconv.Atoi(c.Param("page"))
+    if err != nil || page < 1 {
+        h.handleError(c, http.StatusBadRequest, "Invalid page number", services.ErrPageOutOfRange)
+        return
+    }
+
+    doc, ok := h.viewerDocument(ctx, c)
+    if !ok {
+        return
+    }
+
+    rendered, count, err := h.viewer.RenderPage(ctx, doc, page, viewerSession(c))
+    if err != nil {
+        h.handleViewerError(c, err)
+        return
+    }
+
+    h.recordAccess(c, doc, models.ReceiptAccessViewer, "")
+
+    c.Header("Cache-Control", "no-store")
+    c.Header("Referrer-Policy", "no-referrer")
+    c.Header("X-Page-Count", strconv.Itoa(count))
+    c.Data(http.StatusOK, "image/jpeg", rendered)
+}
+
+// ViewerEvent records an event reported by the viewer beacon, such as a
+// screenshot shortcut or the page losing focus
+func (h *DocumentHandler) ViewerEvent(c *gin.Context) {
+    ctx, span := h.tracer.Start(c.Request.Context(), "ViewerEvent")
+    defer span.End()
+
+    var req viewerEventRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        h.handleError(c, http.StatusBadRequest, "Invalid viewer event", err)
+        return
+    }
+
+    doc, ok := h.viewerDocument(ctx, c)
+    if !ok {
+        return
+    }
+
+    h.viewer.RecordEvent(doc, viewerSession(c), req.Event, req.Page)
+    c.Status(http.StatusNoContent)
+}
+
+// viewerDocument loads the document in the path, writing the error response
+// when it cannot be loaded
+func (h *DocumentHandler) viewerDocument(ctx context.Context, c *gin.Context) (*models.Document, bool) {
+    doc, err := h.repository.GetByID(ctx, c.Param("id"))
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            h.handleError(c, http.StatusNotFound, "Document not found", err)
+            return nil, false
+        }
+        h.handleError(c, http.StatusInternalServerError, "Document lookup failed", err)
+        return nil, false
+    }
+    if doc.Quarantined() {
+        h.handleError(c, http.StatusLocked, "Document is quarantined pending review", ErrDocumentQuarantined)
+        return nil, false
+    }
+    if doc.Delayed(models.CapabilityPreview) {
+        h.handleError(c, http.StatusConflict, "Preview is available once the large document is processed", ErrCapabilityDelayed)
+        return nil, false
+    }
+    return doc, true
+}
+
+func (h *DocumentHandler) handleViewerError(c *gin.Context, err error) {
+    switch {
+    case errors.Is(err, services.ErrPageOutOfRange):
+        h.handleError(c, http.StatusNotFound, "Page not found", err)
+    case errors.Is(err, services.ErrSecureViewerUnsupported):
+        h.handleError(c, http.StatusUnprocessableEntity, "Document cannot be displayed in the secure viewer", err)
+    case errors.Is(err, services.ErrObjectNotFound):
+        h.handleError(c, http.StatusNotFound, "Document content not found", err)
+    default:
+        h.handleError(c, http.StatusInternalServerError, "Page rendering failed", err)
+    }
+}
+
+// viewerSession identifies the viewer from the claims set by the gateway
+func viewerSession(c *gin.Context) services.ViewerSession {
+    return services.ViewerSession{
+        UserID:    c.GetString("user_id"),
+        Role:      c.GetString("user_role"),
+        SessionID: c.GetString("session_id"),
+        ClientIP:  c.ClientIP(),
+    }
+}
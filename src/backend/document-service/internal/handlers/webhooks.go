@@ -0,0 +1,293 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+var (
+    ErrWebhooksNotAllowed = errors.New("role may not manage webhooks")
+)
+
+// WebhookHandler lets tenant admins manage the webhook subscriptions of
+// their tenant: endpoints, events, secrets, pausing, test deliveries and
+// delivery history
+type WebhookHandler struct {
+    webhooks    *services.TenantWebhooks
+    auditLogger *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhooks *services.TenantWebhooks, auditLogger *zap.Logger) (*WebhookHandler, error) {
+    if webhooks == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &WebhookHandler{
+        webhooks:    webhooks,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// ListEvents returns the events subscriptions may choose
+func (h *WebhookHandler) ListEvents(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   models.WebhookEvents,
+    })
+}
+
+// ListWebhooks returns the subscriptions of the caller's tenant
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    subscriptions, err := h.webhooks.List(c.Request.Context(), c.GetString("tenant_id"))
+    if err != nil {
+        writeError(c, h.auditLogger, http.StatusInternalServerError, "Failed to list webhooks", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   subscriptions,
+    })
+}
+
+// CreateWebhook adds a subscription for the caller's tenant. The signing
+// secret is returned in this response only
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    var req services.WebhookSubscriptionRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid webhook", err)
+        return
+    }
+
+    subscription, secret, err := h.webhooks.Create(c.Request.Context(), c.GetString("tenant_id"), c.GetString("user_id"), req)
+    if err != nil {
+        h.writeWebhookError(c, "Failed to create webhook", err)
+        return
+    }
+
+    h.audit(c, "Webhook created", subscription)
+    c.JSON(http.StatusCreated, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "webhook": subscription,
+            "secret":  secret,
+        },
+    })
+}
+
+// GetWebhook returns a subscription of the caller's tenant
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    subscription, err := h.webhooks.Get(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to load webhook", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   subscription,
+    })
+}
+
+// UpdateWebhook replaces the endpoint, description and events of a
+// subscription
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    var req services.WebhookSubscriptionRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid webhook", err)
+        return
+    }
+
+    subscription, err := h.webhooks.Update(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"), req)
+    if err != nil {
+        h.writeWebhookError(c, "Failed to update webhook", err)
+        return
+    }
+
+    h.audit(c, "Webhook updated", subscription)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   subscription,
+    })
+}
+
+// DeleteWebhook removes a subscription of the caller's tenant
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    if err := h.webhooks.Delete(c.Request.Context(), c.GetString("tenant_id"), c.Param("id")); err != nil {
+        h.writeWebhookError(c, "Failed to delete webhook", err)
+        return
+    }
+
+    h.auditLogger.Info("Webhook deleted",
+        zap.String("webhook_id", c.Param("id")),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.Status(http.StatusNoContent)
+}
+
+// PauseWebhook stops deliveries to a subscription
+func (h *WebhookHandler) PauseWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    subscription, err := h.webhooks.Pause(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to pause webhook", err)
+        return
+    }
+
+    h.audit(c, "Webhook paused", subscription)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   subscription,
+    })
+}
+
+// ResumeWebhook restarts deliveries to a paused subscription
+func (h *WebhookHandler) ResumeWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    subscription, err := h.webhooks.Resume(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to resume webhook", err)
+        return
+    }
+
+    h.audit(c, "Webhook resumed", subscription)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   subscription,
+    })
+}
+
+// RotateSecret gives a subscription a new signing secret, returned in this
+// response only
+func (h *WebhookHandler) RotateSecret(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    subscription, secret, err := h.webhooks.RotateSecret(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to rotate webhook secret", err)
+        return
+    }
+
+    h.audit(c, "Webhook secret rotated", subscription)
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data": gin.H{
+            "webhook": subscription,
+            "secret":  secret,
+        },
+    })
+}
+
+// TestWebhook sends a test event to a subscription and returns the
+// delivery, successful or not
+func (h *WebhookHandler) TestWebhook(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    delivery, err := h.webhooks.Test(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to test webhook", err)
+        return
+    }
+
+    h.auditLogger.Info("Webhook tested",
+        zap.String("webhook_id", delivery.SubscriptionID),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("outcome", delivery.Outcome),
+        zap.String("client_ip", c.ClientIP()),
+    )
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   delivery,
+    })
+}
+
+// ListDeliveries returns the delivery history of a subscription, most
+// recent first
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+    if !h.authorize(c) {
+        return
+    }
+
+    deliveries, err := h.webhooks.Deliveries(c.Request.Context(), c.GetString("tenant_id"), c.Param("id"))
+    if err != nil {
+        h.writeWebhookError(c, "Failed to list webhook deliveries", err)
+        return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+        "status": "success",
+        "data":   deliveries,
+    })
+}
+
+// writeWebhookError maps the errors of the webhook service to responses
+func (h *WebhookHandler) writeWebhookError(c *gin.Context, message string, err error) {
+    switch {
+    case errors.Is(err, repository.ErrWebhookNotFound):
+        writeError(c, h.auditLogger, http.StatusNotFound, "Webhook not found", err)
+    case errors.Is(err, services.ErrInvalidWebhookURL), errors.Is(err, models.ErrUnknownWebhookEvent), errors.Is(err, models.ErrNoWebhookEvents):
+        writeError(c, h.auditLogger, http.StatusBadRequest, "Invalid webhook", err)
+    case errors.Is(err, services.ErrWebhookLimit):
+        writeError(c, h.auditLogger, http.StatusConflict, "Webhook limit reached", err)
+    default:
+        writeError(c, h.auditLogger, http.StatusInternalServerError, message, err)
+    }
+}
+
+// audit records a change to a subscription
+func (h *WebhookHandler) audit(c *gin.Context, message string, subscription *models.WebhookSubscription) {
+    h.auditLogger.Info(message,
+        zap.String("webhook_id", subscription.ID),
+        zap.String("tenant_id", subscription.TenantID),
+        zap.String("user_id", c.GetString("user_id")),
+        zap.String("client_ip", c.ClientIP()),
+    )
+}
+
+// authorize rejects callers whose role may not manage webhooks
+func (h *WebhookHandler) authorize(c *gin.Context) bool {
+    if h.webhooks.MayManage(c.GetString("user_role")) {
+        return true
+    }
+    writeError(c, h.auditLogger, http.StatusForbidden, "Not allowed to manage webhooks", ErrWebhooksNotAllowed)
+    return false
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin" // v1.9.1
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const (
+    whatsappSignatureHeader = "X-Hub-Signature-256"
+)
+
+var (
+    ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+)
+
+// whatsappWebhookPayload mirrors the subset of the WhatsApp Cloud API webhook used for media ingestion
+type whatsappWebhookPayload struct {
+    Object string `json:"object"`
+    Entry  []struct {
+        Changes []struct {
+            Field string `json:"field"`
+            Value struct {
+                Messages []whatsappMessage `json:"messages"`
+            } `json:"value"`
+        } `json:"changes"`
+    } `json:"entry"`
+}
+
+type whatsappMessage struct {
+    ID       string         `json:"id"`
+    From     string         `json:"from"`
+    Type     string         `json:"type"`
+    Image    *whatsappMedia `json:"image,omitempty"`
+    Document *whatsappMedia `json:"document,omitempty"`
+}
+
+type whatsappMedia struct {
+    ID       string `json:"id"`
+    MimeType string `json:"mime_type"`
+    Filename string `json:"filename"`
+    Caption  string `json:"caption"`
+}
+
+// WhatsAppHandler receives WhatsApp Business webhook callbacks
+type WhatsAppHandler struct {
+    config      config.WhatsAppConfig
+    whatsapp    *services.WhatsAppService
+    auditLogger *zap.Logger
+}
+
+// NewWhatsAppHandler creates a new WhatsApp webhook handler
+func NewWhatsAppHandler(cfg *config.Config, whatsapp *services.WhatsAppService, auditLogger *zap.Logger) (*WhatsAppHandler, error) {
+    if cfg == nil || whatsapp == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &WhatsAppHandler{
+        config:      cfg.WhatsAppConfig,
+        whatsapp:    whatsapp,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// VerifyWebhook answers the subscription verification handshake
+func (h *WhatsAppHandler) VerifyWebhook(c *gin.Context) {
+    mode := c.Query("hub.mode")
+    token := c.Query("hub.verify_token")
+
+    if mode != "subscribe" || !hmac.Equal([]byte(token), []byte(h.config.VerifyToken)) {
+        c.AbortWithStatus(http.StatusForbidden)
+        return
+    }
+
+    c.String(http.StatusOK, c.Query("hub.challenge"))
+}
+
+// ReceiveWebhook validates the payload signature and dispatches media messages for ingestion
+func (h *WhatsAppHandler) ReceiveWebhook(c *gin.Context) {
+    // Media is fetched separately, so the webhook route limit bounds the body
+    body, ok := readBody(c)
+    if !ok {
+        return
+    }
+
+    if !h.validSignature(body, c.GetHeader(whatsappSignatureHeader)) {
+        h.auditLogger.Warn("Rejected WhatsApp webhook",
+            zap.Error(ErrInvalidWebhookSignature),
+            zap.String("remote_addr", c.ClientIP()),
+        )
+        c.AbortWithStatus(http.StatusUnauthorized)
+        return
+    }
+
+    var payload whatsappWebhookPayload
+    if err := json.Unmarshal(body, &payload); err != nil {
+        c.AbortWithStatus(http.StatusBadRequest)
+        return
+    }
+
+    // Acknowledge immediately; Meta retries webhooks that are slow to respond
+    for _, msg := range extractMediaMessages(payload) {
+        go h.process(msg)
+    }
+
+    c.Status(http.StatusOK)
+}
+
+// process ingests a single media message detached from the webhook request lifetime
+func (h *WhatsAppHandler) process(msg services.WhatsAppMediaMessage) {
+    ctx, cancel := context.WithTimeout(context.Background(), h.config.ProcessingTimeout)
+    defer cancel()
+
+    if err := h.whatsapp.HandleMediaMessage(ctx, msg); err != nil {
+        h.auditLogger.Warn("WhatsApp media message not ingested",
+            zap.String("message_id", msg.MessageID),
+            zap.Error(err),
+        )
+        return
+    }
+
+    h.auditLogger.Info("WhatsApp media message ingested",
+        zap.String("message_id", msg.MessageID),
+    )
+}
+
+// validSignature checks the HMAC-SHA256 signature Meta computes with the app secret
+func (h *WhatsAppHandler) validSignature(body []byte, header string) bool {
+    signature, ok := strings.CutPrefix(header, "sha256=")
+    if !ok {
+        return false
+    }
+
+    expected, err := hex.DecodeString(signature)
+    if err != nil {
+        return false
+    }
+
+    mac := hmac.New(sha256.New, []byte(h.config.AppSecret))
+    mac.Write(body)
+    return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// extractMediaMessages flattens image and document messages from a webhook payload
+func extractMediaMessages(payload whatsappWebhookPayload) []services.WhatsAppMediaMessage {
+    messages := make([]services.WhatsAppMediaMessage, 0)
+    for _, entry := range payload.Entry {
+        for _, change := range entry.Changes {
+            if change.Field != "messages" {
+                continue
+            }
+            for _, msg := range change.Value.Messages {
+                media := msg.Document
+                if msg.Type == "image" {
+                    media = msg.Image
+                }
+                if media == nil {
+                    continue
+                }
+                messages = append(messages, services.WhatsAppMediaMessage{
+                    MessageID: msg.ID,
+                    From:      msg.From,
+                    MediaID:   media.ID,
+                    MimeType:  media.MimeType,
+                    Filename:  media.Filename,
+                    Caption:   media.Caption,
+                })
+            }
+        }
+    }
+    return messages
+}
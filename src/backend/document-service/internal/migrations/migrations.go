@@ -0,0 +1,283 @@
+// Package migrations applies the embedded database schema migrations following
+// the expand/contract convention so rolling deploys never break the version
+// still serving traffic.
+//
+// Migration files are named {version}_{title}.{phase}.{up|down}.sql where phase
+// is either "expand" (backward compatible: new tables, nullable columns, new
+// indexes) or "contract" (breaking: dropped or renamed columns, new NOT NULL
+// constraints). Expand migrations are applied automatically at startup; contract
+// migrations are a separate step run once the rollout completed and no
+// instance of the previous version is running.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4"                   // v4.16.2
+	"github.com/golang-migrate/migrate/v4/database/postgres" // v4.16.2
+	"github.com/golang-migrate/migrate/v4/source/iofs"       // v4.16.2
+	"go.uber.org/zap"                                        // v1.24.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// Phase is the rollout phase a migration belongs to
+type Phase string
+
+const (
+	PhaseExpand   Phase = "expand"
+	PhaseContract Phase = "contract"
+)
+
+var (
+	ErrDirty             = errors.New("schema is dirty after a failed migration")
+	ErrPending           = errors.New("schema migrations are pending")
+	ErrBlockedByContract = errors.New("expand migrations are blocked by a pending contract migration")
+	ErrAheadOfBinary     = errors.New("schema was migrated by a newer release")
+	ErrInvalidName       = errors.New("migration file does not follow the naming convention")
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var filePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(expand|contract)\.(up|down)\.sql$`)
+
+// Migration describes an embedded migration
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Phase   Phase  `json:"phase"`
+}
+
+// Status is the state of the database schema relative to the embedded migrations
+type Status struct {
+	Version uint        `json:"version"`
+	Dirty   bool        `json:"dirty"`
+	Applied []Migration `json:"applied"`
+	Pending []Migration `json:"pending"`
+	// AheadOfBinary is set when the database was migrated by a newer release
+	AheadOfBinary bool `json:"ahead_of_binary"`
+}
+
+// PendingContract returns the pending breaking migrations
+func (s *Status) PendingContract() []Migration {
+	pending := make([]Migration, 0)
+	for _, migration := range s.Pending {
+		if migration.Phase == PhaseContract {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Check returns an error when the service must not serve traffic on this
+// schema: when it is dirty, was migrated by a newer release this one does not
+// know, or expand migrations the release relies on are pending. Pending
+// contract migrations are not an error, since the previous
+// release may still be serving until the rollout completes
+func (s *Status) Check() error {
+	if s.Dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, s.Version)
+	}
+	if s.AheadOfBinary {
+		return fmt.Errorf("%w at version %d", ErrAheadOfBinary, s.Version)
+	}
+	var contract *Migration
+	for i, migration := range s.Pending {
+		if migration.Phase == PhaseContract {
+			if contract == nil {
+				contract = &s.Pending[i]
+			}
+			continue
+		}
+		if contract != nil {
+			return fmt.Errorf("%w: version %d (%s) waits on version %d (%s)", ErrBlockedByContract, migration.Version, migration.Name, contract.Version, contract.Name)
+		}
+		return fmt.Errorf("%w: version %d (%s)", ErrPending, migration.Version, migration.Name)
+	}
+	return nil
+}
+
+// Load lists the embedded migrations in version order
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]Migration)
+	for _, entry := range entries {
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidName, entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidName, entry.Name())
+		}
+
+		migration := Migration{Version: uint(version), Name: match[2], Phase: Phase(match[3])}
+		if existing, ok := byVersion[migration.Version]; ok && existing != migration {
+			return nil, fmt.Errorf("%w: conflicting files for version %d", ErrInvalidName, version)
+		}
+		byVersion[migration.Version] = migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Runner applies the embedded migrations to a PostgreSQL database
+type Runner struct {
+	mu         sync.Mutex
+	migrate    *migrate.Migrate
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewRunner creates a runner for the database; the runner owns db from then on
+func NewRunner(cfg *config.Config, db *sql.DB, logger *zap.Logger) (*Runner, error) {
+	if cfg == nil || db == nil || logger == nil {
+		return nil, errors.New("required dependencies cannot be nil")
+	}
+
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := iofs.New(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migration driver: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+
+	return &Runner{
+		migrate:    m,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// Status reports applied and pending migrations
+func (r *Runner) Status() (*Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.status()
+}
+
+func (r *Runner) status() (*Status, error) {
+	version, dirty, err := r.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	status := &Status{
+		Version: version,
+		Dirty:   dirty,
+		Applied: make([]Migration, 0),
+		Pending: make([]Migration, 0),
+	}
+	for _, migration := range r.migrations {
+		if migration.Version <= version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	if len(r.migrations) > 0 && version > r.migrations[len(r.migrations)-1].Version {
+		status.AheadOfBinary = true
+	}
+	return status, nil
+}
+
+// Apply runs pending expand migrations, stopping before the first contract
+// migration. It is safe while the previous release serves traffic
+func (r *Runner) Apply() error {
+	return r.migrateTo(false)
+}
+
+// ApplyContract runs every pending migration, contract migrations included.
+// It is the post-rollout step, run once no instance of the previous release
+// is serving
+func (r *Runner) ApplyContract() error {
+	return r.migrateTo(true)
+}
+
+func (r *Runner) migrateTo(contract bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status, err := r.status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, status.Version)
+	}
+
+	var target *Migration
+	for i, migration := range status.Pending {
+		if migration.Phase == PhaseContract && !contract {
+			break
+		}
+		target = &status.Pending[i]
+	}
+	if target == nil {
+		return nil
+	}
+
+	if err := r.migrate.Migrate(target.Version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to migrate to version %d: %w", target.Version, err)
+	}
+	r.logger.Info("Schema migrated",
+		zap.Uint("from_version", status.Version),
+		zap.Uint("to_version", target.Version),
+		zap.Bool("contract", contract),
+	)
+	return nil
+}
+
+// Gate returns an error when the service must not serve traffic on this
+// schema. Pending contract migrations are only logged: they wait for the
+// post-rollout step
+func (r *Runner) Gate() error {
+	status, err := r.Status()
+	if err != nil {
+		return err
+	}
+
+	if err := status.Check(); err != nil {
+		return err
+	}
+	if contract := status.PendingContract(); len(contract) > 0 {
+		r.logger.Warn("Contract migrations are pending; apply them once no previous release is serving",
+			zap.Uint("version", contract[0].Version),
+			zap.String("name", contract[0].Name),
+			zap.Int("pending", len(contract)),
+		)
+	}
+	return nil
+}
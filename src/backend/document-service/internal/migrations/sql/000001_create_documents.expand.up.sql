@@ -0,0 +1,26 @@
+-- Document metadata; processing results are kept as JSONB documents since
+-- their shape evolves with the pipeline
+CREATE TABLE IF NOT EXISTS documents (
+    id                UUID PRIMARY KEY,
+    enrollment_id     UUID NOT NULL,
+    document_type     VARCHAR(100) NOT NULL,
+    filename          VARCHAR(500) NOT NULL,
+    content_type      VARCHAR(100) NOT NULL,
+    size              BIGINT NOT NULL CHECK (size > 0),
+    status            VARCHAR(32) NOT NULL,
+    ingestion_channel VARCHAR(32) NOT NULL,
+    storage_path      VARCHAR(500) NOT NULL DEFAULT '',
+    content_hash      VARCHAR(128) NOT NULL DEFAULT '',
+    encryption_info   JSONB,
+    processing        JSONB NOT NULL DEFAULT '{}'::jsonb,
+    audit_trail       JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    processed_at      TIMESTAMPTZ,
+    reviewed_at       TIMESTAMPTZ,
+    reviewed_by       VARCHAR(255),
+    retention_date    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_enrollment_id ON documents (enrollment_id);
+CREATE INDEX IF NOT EXISTS idx_documents_status ON documents (status);
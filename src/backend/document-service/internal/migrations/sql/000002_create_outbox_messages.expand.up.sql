@@ -0,0 +1,16 @@
+-- Integration events written in the same transaction as the state change
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id              UUID PRIMARY KEY,
+    topic           VARCHAR(255) NOT NULL,
+    key             VARCHAR(255) NOT NULL,
+    payload         JSONB NOT NULL,
+    status          VARCHAR(32) NOT NULL,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    UNIQUE (topic, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_due ON outbox_messages (next_attempt_at) WHERE status = 'pending';
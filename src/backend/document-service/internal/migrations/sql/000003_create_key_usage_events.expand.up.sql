@@ -0,0 +1,18 @@
+-- Append-only audit of data key uses, one row per encrypt or decrypt
+CREATE TABLE IF NOT EXISTS key_usage_events (
+    id            UUID PRIMARY KEY,
+    document_id   UUID,
+    principal     VARCHAR(255) NOT NULL,
+    operation     VARCHAR(32) NOT NULL,
+    kms_operation VARCHAR(64),
+    key_id        VARCHAR(2048) NOT NULL,
+    key_version   VARCHAR(64) NOT NULL,
+    grant_id      VARCHAR(128),
+    succeeded     BOOLEAN NOT NULL,
+    error         TEXT,
+    occurred_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_key_usage_events_occurred_at ON key_usage_events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_key_usage_events_document ON key_usage_events (document_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_key_usage_events_principal ON key_usage_events (principal, occurred_at);
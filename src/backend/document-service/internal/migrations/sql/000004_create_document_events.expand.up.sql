@@ -0,0 +1,20 @@
+-- Append-only document lifecycle events. The primary key enforces the
+-- sequence check concurrent writers rely on. Patches are kept as the exact
+-- bytes that were hashed, so they are stored as BYTEA rather than JSONB
+CREATE TABLE IF NOT EXISTS document_events (
+    document_id  UUID NOT NULL,
+    sequence     BIGINT NOT NULL,
+    type         VARCHAR(64) NOT NULL,
+    status       VARCHAR(32),
+    reason       TEXT,
+    actor        VARCHAR(255),
+    occurred_at  TIMESTAMPTZ NOT NULL,
+    patch        BYTEA,
+    patch_digest CHAR(64) NOT NULL,
+    erased       BOOLEAN NOT NULL DEFAULT false,
+    prev_hash    CHAR(64),
+    hash         CHAR(64) NOT NULL,
+    PRIMARY KEY (document_id, sequence)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_events_type ON document_events (type, occurred_at);
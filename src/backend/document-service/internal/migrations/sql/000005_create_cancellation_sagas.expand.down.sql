@@ -0,0 +1,2 @@
+-- documents.enrollment_id stays nullable: anonymized documents have none
+DROP TABLE IF EXISTS cancellation_sagas;
@@ -0,0 +1,19 @@
+-- Cleanup sagas of cancelled enrollments, keyed by the cancellation event.
+-- Steps are only read with their saga, so they are kept as JSONB
+CREATE TABLE IF NOT EXISTS cancellation_sagas (
+    id            VARCHAR(255) PRIMARY KEY,
+    enrollment_id UUID NOT NULL,
+    cancelled_at  TIMESTAMPTZ NOT NULL,
+    status        VARCHAR(32) NOT NULL,
+    steps         JSONB NOT NULL DEFAULT '[]'::jsonb,
+    error         TEXT,
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    started_at    TIMESTAMPTZ NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL,
+    completed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_cancellation_sagas_enrollment_id ON cancellation_sagas (enrollment_id);
+
+-- Anonymized documents are no longer linked to an enrollment
+ALTER TABLE documents ALTER COLUMN enrollment_id DROP NOT NULL;
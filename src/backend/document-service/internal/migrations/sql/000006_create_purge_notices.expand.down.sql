@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_documents_retention_date;
+ALTER TABLE documents DROP COLUMN IF EXISTS hold;
+ALTER TABLE documents DROP COLUMN IF EXISTS expiry;
+DROP TABLE IF EXISTS purge_notices;
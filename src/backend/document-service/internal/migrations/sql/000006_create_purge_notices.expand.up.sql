@@ -0,0 +1,20 @@
+-- Notices given to tenant admins before documents past retention are purged
+CREATE TABLE IF NOT EXISTS purge_notices (
+    id              UUID PRIMARY KEY,
+    tenant_id       VARCHAR(255) NOT NULL DEFAULT '',
+    document_ids    JSONB NOT NULL DEFAULT '[]'::jsonb,
+    document_types  JSONB NOT NULL DEFAULT '{}'::jsonb,
+    purge_after     TIMESTAMPTZ NOT NULL,
+    notified_at     TIMESTAMPTZ NOT NULL,
+    acknowledged_at TIMESTAMPTZ,
+    acknowledged_by VARCHAR(255),
+    held            JSONB NOT NULL DEFAULT '[]'::jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_purge_notices_tenant_id ON purge_notices (tenant_id, notified_at);
+
+-- The notice a document was purged after and the hold keeping it
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS expiry JSONB;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS hold JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_documents_retention_date ON documents (retention_date);
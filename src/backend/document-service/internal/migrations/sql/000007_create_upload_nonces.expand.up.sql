@@ -0,0 +1,7 @@
+-- Nonces of accepted anonymous uploads, kept until their replay window ends
+CREATE TABLE IF NOT EXISTS upload_nonces (
+    nonce      VARCHAR(128) PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_nonces_expires_at ON upload_nonces (expires_at);
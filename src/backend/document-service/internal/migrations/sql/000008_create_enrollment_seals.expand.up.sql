@@ -0,0 +1,16 @@
+-- Seals freezing the document set of enrollments closed by underwriting
+CREATE TABLE IF NOT EXISTS enrollment_seals (
+    id              UUID PRIMARY KEY,
+    enrollment_id   VARCHAR(255) NOT NULL,
+    event_id        VARCHAR(255) NOT NULL UNIQUE,
+    decision        VARCHAR(64),
+    documents       JSONB NOT NULL DEFAULT '[]'::jsonb,
+    algorithm       VARCHAR(64) NOT NULL,
+    merkle_root     CHAR(64) NOT NULL,
+    sealed_at       TIMESTAMPTZ NOT NULL,
+    key_id          VARCHAR(64) NOT NULL,
+    signature       TEXT NOT NULL,
+    timestamp_token TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrollment_seals_enrollment_id ON enrollment_seals (enrollment_id, sealed_at);
@@ -0,0 +1,8 @@
+-- Owners of the background jobs coordinated across instances. The jobs are
+-- locked with advisory locks; this table only records who holds them
+CREATE TABLE IF NOT EXISTS job_leases (
+    job         VARCHAR(128) PRIMARY KEY,
+    owner       VARCHAR(255) NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL,
+    renewed_at  TIMESTAMPTZ NOT NULL
+);
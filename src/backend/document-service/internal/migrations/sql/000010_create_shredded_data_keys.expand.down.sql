@@ -0,0 +1 @@
+DROP TABLE IF EXISTS shredded_data_keys;
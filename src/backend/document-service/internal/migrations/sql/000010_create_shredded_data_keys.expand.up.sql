@@ -0,0 +1,6 @@
+-- Digests of the document data keys destroyed by crypto-shredding. Every
+-- instance checks it before using a data key, cached or not
+CREATE TABLE IF NOT EXISTS shredded_data_keys (
+    key_digest  CHAR(64) PRIMARY KEY,
+    shredded_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,10 @@
+-- Documents written in the last consistency.cache_ttl, shared by every
+-- instance so any of them can serve a read at the version a write returned
+CREATE TABLE IF NOT EXISTS cached_documents (
+    id         VARCHAR(64) PRIMARY KEY,
+    version    BIGINT NOT NULL,
+    document   JSONB NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cached_documents_expires_at ON cached_documents (expires_at);
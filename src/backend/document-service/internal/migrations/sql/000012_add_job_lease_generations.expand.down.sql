@@ -0,0 +1,3 @@
+ALTER TABLE job_leases DROP COLUMN IF EXISTS generation;
+
+DROP SEQUENCE IF EXISTS job_lease_generations;
@@ -0,0 +1,6 @@
+-- Fencing tokens of job leases. Every acquisition draws a new generation, so
+-- a holder whose advisory lock was lost with its session sees the job taken
+-- over before writing. The default keeps inserts of earlier releases valid
+CREATE SEQUENCE IF NOT EXISTS job_lease_generations;
+
+ALTER TABLE job_leases ADD COLUMN IF NOT EXISTS generation BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,65 @@
+package models
+
+import (
+    "fmt"
+    "time"
+)
+
+// Access events the portal's secure viewer reports for a document
+const (
+    AccessEventView   = "view"
+    AccessEventPrint  = "print"
+    AccessEventExport = "export"
+)
+
+// Anomalies raised on reported access events
+const (
+    AccessAnomalyPrintVolume    = "print_volume"
+    AccessAnomalyExportVolume   = "export_volume"
+    AccessAnomalyDocumentSpread = "document_spread"
+)
+
+// AccessEvent is a view, print or export of a document reported by the
+// client, once its signature and nonce were verified
+type AccessEvent struct {
+    DocumentID string    `json:"document_id"`
+    Event      string    `json:"event"`
+    Page       int       `json:"page,omitempty"`
+    Format     string    `json:"format,omitempty"`
+    Nonce      string    `json:"nonce"`
+    OccurredAt time.Time `json:"occurred_at"`
+    UserID     string    `json:"user_id"`
+    Role       string    `json:"role,omitempty"`
+    SessionID  string    `json:"session_id"`
+    ClientIP   string    `json:"client_ip,omitempty"`
+}
+
+// AccessAnomaly is a user exceeding an access event threshold within a
+// window
+type AccessAnomaly struct {
+    Type      string    `json:"type"`
+    UserID    string    `json:"user_id"`
+    Count     int       `json:"count"`
+    Threshold int       `json:"threshold"`
+    Since     time.Time `json:"since"`
+}
+
+// RecordAccessEvent adds a reported access event to the audit trail. It
+// leaves UpdatedAt alone, since reading a document does not change it
+func (d *Document) RecordAccessEvent(event AccessEvent) {
+    detail := fmt.Sprintf("%s reported by session %s at %s", event.Event, event.SessionID, event.OccurredAt.UTC().Format(time.RFC3339))
+    if event.Page > 0 {
+        detail += fmt.Sprintf(", page %d", event.Page)
+    }
+    if event.Format != "" {
+        detail += ", format " + event.Format
+    }
+    d.addAuditLog("ACCESS_EVENT", d.Status, detail, event.UserID)
+}
+
+// RecordAccessAnomaly adds an anomaly raised by an access event on the
+// document to its audit trail
+func (d *Document) RecordAccessAnomaly(anomaly AccessAnomaly) {
+    detail := fmt.Sprintf("%s: %d events since %s, threshold %d", anomaly.Type, anomaly.Count, anomaly.Since.UTC().Format(time.RFC3339), anomaly.Threshold)
+    d.addAuditLog("ACCESS_ANOMALY", d.Status, detail, "SYSTEM")
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// AnalyticsColumn documents how an exported column is derived from a document
+type AnalyticsColumn struct {
+    Name string
+    Rule string
+}
+
+// AnalyticsSpec is the anonymization spec of the analytics export. Every field of
+// AnalyticsRecord must be listed here, in order, and nothing else is exported:
+// filenames, storage paths, content hashes, extracted values, OCR text,
+// screening details, reviewer identities and audit trails never leave the
+// service
+var AnalyticsSpec = []AnalyticsColumn{
+    {"document_key", "HMAC-SHA256 of the document ID under the export key, first 128 bits in hex"},
+    {"enrollment_key", "HMAC-SHA256 of the enrollment ID under the export key, first 128 bits in hex"},
+    {"tenant_key", "HMAC-SHA256 of the tenant ID under the export key, first 128 bits in hex; empty without a tenant"},
+    {"document_type", "copied"},
+    {"content_type", "copied"},
+    {"ingestion_channel", "copied"},
+    {"status", "copied"},
+    {"size_bucket", "size in bytes bucketed as lt_100k, 100k_1m, 1m_5m or gte_5m"},
+    {"created_date", "creation time truncated to the UTC day"},
+    {"processing_seconds", "whole seconds from creation to processing; -1 when not processed"},
+    {"page_count", "number of OCR pages; 0 for single-image documents"},
+    {"failed_ocr_pages", "number of pages OCR failed on"},
+    {"extracted_field_count", "number of extracted fields; values are never exported"},
+    {"mean_extraction_confidence", "mean confidence of extracted fields rounded to 2 decimals"},
+    {"signature_count", "number of digital signatures"},
+    {"government_verified", "copied"},
+    {"review_flags", "review flag names joined with ';'"},
+    {"auto_decision", "rules engine decision; empty when not evaluated"},
+    {"reviewed", "whether a reviewer decided the document; the reviewer is never exported"},
+}
+
+// AnalyticsRecord is one anonymized row of the analytics export
+type AnalyticsRecord struct {
+    DocumentKey              string  `json:"document_key" parquet:"document_key"`
+    EnrollmentKey            string  `json:"enrollment_key" parquet:"enrollment_key"`
+    TenantKey                string  `json:"tenant_key" parquet:"tenant_key"`
+    DocumentType             string  `json:"document_type" parquet:"document_type"`
+    ContentType              string  `json:"content_type" parquet:"content_type"`
+    IngestionChannel         string  `json:"ingestion_channel" parquet:"ingestion_channel"`
+    Status                   string  `json:"status" parquet:"status"`
+    SizeBucket               string  `json:"size_bucket" parquet:"size_bucket"`
+    CreatedDate              string  `json:"created_date" parquet:"created_date"`
+    ProcessingSeconds        int64   `json:"processing_seconds" parquet:"processing_seconds"`
+    PageCount                int     `json:"page_count" parquet:"page_count"`
+    FailedOCRPages           int     `json:"failed_ocr_pages" parquet:"failed_ocr_pages"`
+    ExtractedFieldCount      int     `json:"extracted_field_count" parquet:"extracted_field_count"`
+    MeanExtractionConfidence float64 `json:"mean_extraction_confidence" parquet:"mean_extraction_confidence"`
+    SignatureCount           int     `json:"signature_count" parquet:"signature_count"`
+    GovernmentVerified       bool    `json:"government_verified" parquet:"government_verified"`
+    ReviewFlags              string  `json:"review_flags" parquet:"review_flags"`
+    AutoDecision             string  `json:"auto_decision" parquet:"auto_decision"`
+    Reviewed                 bool    `json:"reviewed" parquet:"reviewed"`
+}
+
+// Anonymize derives the analytics record of a document following AnalyticsSpec.
+// Identifiers are keyed hashes so they can be joined across exports made with
+// the same key but not reversed by hashing known IDs
+func Anonymize(doc *Document, key []byte) AnalyticsRecord {
+    record := AnalyticsRecord{
+        DocumentKey:         pseudonym(key, doc.ID),
+        EnrollmentKey:       pseudonym(key, doc.EnrollmentID),
+        TenantKey:           pseudonym(key, doc.TenantID),
+        DocumentType:        doc.DocumentType,
+        ContentType:         doc.ContentType,
+        IngestionChannel:    doc.IngestionChannel,
+        Status:              doc.Status,
+        SizeBucket:          sizeBucket(doc.Size),
+        CreatedDate:         doc.CreatedAt.UTC().Format("2006-01-02"),
+        ProcessingSeconds:   -1,
+        PageCount:           len(doc.OCRPages),
+        FailedOCRPages:      len(doc.FailedOCRPages()),
+        ExtractedFieldCount: len(doc.ExtractedFields),
+        SignatureCount:      len(doc.Signatures),
+        GovernmentVerified:  doc.GovernmentVerified,
+        ReviewFlags:         strings.Join(doc.ReviewFlags, ";"),
+        Reviewed:            doc.ReviewedAt != nil,
+    }
+
+    if doc.ProcessedAt != nil {
+        record.ProcessingSeconds = int64(doc.ProcessedAt.Sub(doc.CreatedAt).Seconds())
+    }
+    if len(doc.ExtractedFields) > 0 {
+        var total float64
+        for _, field := range doc.ExtractedFields {
+            total += field.Confidence
+        }
+        record.MeanExtractionConfidence = math.Round(total/float64(len(doc.ExtractedFields))*100) / 100
+    }
+    if doc.AutoDecision != nil {
+        record.AutoDecision = doc.AutoDecision.Decision
+    }
+    return record
+}
+
+// CSVRow returns the record's values in AnalyticsSpec order
+func (r AnalyticsRecord) CSVRow() []string {
+    return []string{
+        r.DocumentKey,
+        r.EnrollmentKey,
+        r.TenantKey,
+        r.DocumentType,
+        r.ContentType,
+        r.IngestionChannel,
+        r.Status,
+        r.SizeBucket,
+        r.CreatedDate,
+        strconv.FormatInt(r.ProcessingSeconds, 10),
+        strconv.Itoa(r.PageCount),
+        strconv.Itoa(r.FailedOCRPages),
+        strconv.Itoa(r.ExtractedFieldCount),
+        strconv.FormatFloat(r.MeanExtractionConfidence, 'f', 2, 64),
+        strconv.Itoa(r.SignatureCount),
+        strconv.FormatBool(r.GovernmentVerified),
+        r.ReviewFlags,
+        r.AutoDecision,
+        strconv.FormatBool(r.Reviewed),
+    }
+}
+
+// AnalyticsHeader returns the CSV header in AnalyticsSpec order
+func AnalyticsHeader() []string {
+    header := make([]string, len(AnalyticsSpec))
+    for i, column := range AnalyticsSpec {
+        header[i] = column.Name
+    }
+    return header
+}
+
+// pseudonym returns the truncated keyed hash of an identifier
+func pseudonym(key []byte, id string) string {
+    if id == "" {
+        return ""
+    }
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(id))
+    return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// sizeBucket coarsens a size so exact sizes cannot single out a document
+func sizeBucket(size int64) string {
+    switch {
+    case size < 100<<10:
+        return "lt_100k"
+    case size < 1<<20:
+        return "100k_1m"
+    case size < 5<<20:
+        return "1m_5m"
+    default:
+        return "gte_5m"
+    }
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+    "crypto/subtle"
+    "errors"
+    "fmt"
+    "slices"
+    "time"
+)
+
+// Scopes an API key may be issued with
+const (
+    // APIKeyScopeUpload allows uploading documents
+    APIKeyScopeUpload = "documents:upload"
+    // APIKeyScopeRead allows reading back documents and their history
+    APIKeyScopeRead = "documents:read"
+)
+
+var (
+    // APIKeyScopes lists the scopes a key may be issued with
+    APIKeyScopes = []string{
+        APIKeyScopeUpload,
+        APIKeyScopeRead,
+    }
+
+    ErrUnknownAPIKeyScope = errors.New("unknown API key scope")
+    ErrNoAPIKeyScopes     = errors.New("API key has no scopes")
+)
+
+// APIKey authenticates a machine integration of a tenant. Only the SHA-256
+// hash of its secret is stored; after a rotation the hash of the previous
+// secret is kept until PreviousSecretExpiresAt so the integration can switch
+// without failing requests. EmployerIDs restricts the key to the enrollments
+// of those employers; a key without any covers every enrollment of the tenant
+type APIKey struct {
+    ID          string   `json:"id"`
+    TenantID    string   `json:"tenant_id"`
+    Name        string   `json:"name"`
+    Scopes      []string `json:"scopes"`
+    EmployerIDs []string `json:"employer_ids,omitempty"`
+    // RateLimit and Burst bound the requests per second made with the key
+    RateLimit               float64    `json:"rate_limit"`
+    Burst                   int        `json:"burst"`
+    SecretHash              string     `json:"-"`
+    PreviousSecretHash      string     `json:"-"`
+    PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+    ExpiresAt               *time.Time `json:"expires_at,omitempty"`
+    CreatedBy               string     `json:"created_by"`
+    CreatedAt               time.Time  `json:"created_at"`
+    RotatedAt               *time.Time `json:"rotated_at,omitempty"`
+    LastUsedAt              *time.Time `json:"last_used_at,omitempty"`
+    RevokedBy               string     `json:"revoked_by,omitempty"`
+    RevokedAt               *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key may be used at now
+func (k *APIKey) Active(now time.Time) bool {
+    return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Matches reports whether secretHash is the hash of the key's secret, or of
+// its previous secret within the rotation grace period
+func (k *APIKey) Matches(secretHash string, now time.Time) bool {
+    if subtle.ConstantTimeCompare([]byte(secretHash), []byte(k.SecretHash)) == 1 {
+        return true
+    }
+    return k.PreviousSecretHash != "" && k.PreviousSecretExpiresAt != nil && now.Before(*k.PreviousSecretExpiresAt) &&
+        subtle.ConstantTimeCompare([]byte(secretHash), []byte(k.PreviousSecretHash)) == 1
+}
+
+// Allows reports whether the key was issued with a scope
+func (k *APIKey) Allows(scope string) bool {
+    return slices.Contains(k.Scopes, scope)
+}
+
+// CoversEmployer reports whether the key may act on enrollments of an
+// employer
+func (k *APIKey) CoversEmployer(employerID string) bool {
+    return len(k.EmployerIDs) == 0 || (employerID != "" && slices.Contains(k.EmployerIDs, employerID))
+}
+
+// Rotate replaces the secret hash, keeping the current one valid for grace
+func (k *APIKey) Rotate(secretHash string, at time.Time, grace time.Duration) {
+    k.PreviousSecretHash = k.SecretHash
+    expiresAt := at.Add(grace)
+    k.PreviousSecretExpiresAt = &expiresAt
+    k.SecretHash = secretHash
+    k.RotatedAt = &at
+}
+
+// Revoke disables the key for good; revoking twice keeps the first record
+func (k *APIKey) Revoke(by string, at time.Time) {
+    if k.RevokedAt == nil {
+        k.RevokedBy = by
+        k.RevokedAt = &at
+    }
+}
+
+// ValidateAPIKeyScopes checks that scopes is a non-empty list of known scopes
+func ValidateAPIKeyScopes(scopes []string) error {
+    if len(scopes) == 0 {
+        return ErrNoAPIKeyScopes
+    }
+    for _, scope := range scopes {
+        if !slices.Contains(APIKeyScopes, scope) {
+            return fmt.Errorf("%w: %s", ErrUnknownAPIKeyScope, scope)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "time"
+)
+
+// Outcomes of the documents of a bulk review
+const (
+    // BulkReviewApplied documents took the decision
+    BulkReviewApplied = "applied"
+    // BulkReviewFailed documents could not take the decision
+    BulkReviewFailed = "failed"
+    // BulkReviewNotApplied documents could take the decision but were left
+    // as they were, or restored, because another document failed
+    BulkReviewNotApplied = "not_applied"
+)
+
+var ErrInvalidReviewFilter = errors.New("invalid review filter")
+
+// ReviewFilter selects documents awaiting review for an underwriter's list.
+// Empty fields match every document
+type ReviewFilter struct {
+    EnrollmentID  string     `json:"enrollment_id,omitempty"`
+    TenantID      string     `json:"tenant_id,omitempty"`
+    DocumentTypes []string   `json:"document_types,omitempty"`
+    ReviewFlags   []string   `json:"review_flags,omitempty"`
+    CreatedFrom   *time.Time `json:"created_from,omitempty"`
+    CreatedTo     *time.Time `json:"created_to,omitempty"`
+}
+
+// Validate checks that the creation period is not empty
+func (f ReviewFilter) Validate() error {
+    if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+        return fmt.Errorf("%w: created_from must be before created_to", ErrInvalidReviewFilter)
+    }
+    return nil
+}
+
+// Matches reports whether the document awaits a review decision and meets
+// every criterion of the filter. A document matches review_flags when any
+// of them was raised on it
+func (f ReviewFilter) Matches(doc *Document) bool {
+    if !doc.AwaitingReview() {
+        return false
+    }
+    if f.EnrollmentID != "" && doc.EnrollmentID != f.EnrollmentID {
+        return false
+    }
+    if f.TenantID != "" && doc.TenantID != f.TenantID {
+        return false
+    }
+    if len(f.DocumentTypes) > 0 && !slices.Contains(f.DocumentTypes, doc.DocumentType) {
+        return false
+    }
+    if len(f.ReviewFlags) > 0 && !slices.ContainsFunc(f.ReviewFlags, doc.HasReviewFlag) {
+        return false
+    }
+    if f.CreatedFrom != nil && doc.CreatedAt.Before(*f.CreatedFrom) {
+        return false
+    }
+    if f.CreatedTo != nil && !doc.CreatedAt.Before(*f.CreatedTo) {
+        return false
+    }
+    return true
+}
+
+// SavedFilter is a review filter an underwriter saved under a name
+type SavedFilter struct {
+    ID        string       `json:"id"`
+    Owner     string       `json:"owner"`
+    Name      string       `json:"name"`
+    Filter    ReviewFilter `json:"filter"`
+    CreatedAt time.Time    `json:"created_at"`
+}
+
+// Validate checks the name and the filter
+func (f *SavedFilter) Validate() error {
+    name := strings.TrimSpace(f.Name)
+    if name == "" || len(name) > 100 {
+        return fmt.Errorf("%w: name must have 1 to 100 characters", ErrInvalidReviewFilter)
+    }
+    if f.Owner == "" {
+        return ErrMissingField
+    }
+    return f.Filter.Validate()
+}
+
+// BulkReviewItem is the outcome of one document of a bulk review
+type BulkReviewItem struct {
+    DocumentID string `json:"document_id"`
+    Result     string `json:"result"`
+    Status     string `json:"status,omitempty"`
+    Error      string `json:"error,omitempty"`
+}
+
+// BulkReviewResult is the outcome of one decision applied to many
+// documents. The decision is applied to every document or to none
+type BulkReviewResult struct {
+    Decision string           `json:"decision"`
+    Applied  bool             `json:"applied"`
+    Items    []BulkReviewItem `json:"items"`
+}
+
+// RollbackReview undoes the decision of a bulk review that was not applied,
+// restoring the review fields the document had before, as in previous. The
+// decision stays in the audit trail, followed by its rollback
+func (d *Document) RollbackReview(previous *Document, reviewer string) {
+    d.Status = previous.Status
+    d.ReviewedAt = previous.ReviewedAt
+    d.ReviewedBy = previous.ReviewedBy
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("REVIEW_ROLLBACK", d.Status, "Bulk review rolled back", reviewer)
+}
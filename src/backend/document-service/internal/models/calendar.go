@@ -0,0 +1,315 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+    // Calendars load their time zone wherever the service runs
+    _ "time/tzdata"
+)
+
+// ClosedWeekend is the reason weekend days are closed; holidays give their
+// name
+const ClosedWeekend = "weekend"
+
+var ErrInvalidCalendar = errors.New("invalid business calendar")
+
+// nationalHoliday is a Brazilian national holiday on a fixed date, observed
+// from the year since
+type nationalHoliday struct {
+    month time.Month
+    day   int
+    name  string
+    since int
+}
+
+var nationalHolidays = []nationalHoliday{
+    {time.January, 1, "Confraternização Universal", 0},
+    {time.April, 21, "Tiradentes", 0},
+    {time.May, 1, "Dia do Trabalho", 0},
+    {time.September, 7, "Independência do Brasil", 0},
+    {time.October, 12, "Nossa Senhora Aparecida", 0},
+    {time.November, 2, "Finados", 0},
+    {time.November, 15, "Proclamação da República", 0},
+    {time.November, 20, "Dia Nacional de Zumbi e da Consciência Negra", 2024},
+    {time.December, 25, "Natal", 0},
+}
+
+// CalendarSpec describes a business calendar: its time zone, opening hours
+// as 15:04 and holidays, MM-DD dates recurring every year or YYYY-MM-DD
+// dates, each optionally followed by a space and the holiday name
+type CalendarSpec struct {
+    Name     string
+    Timezone string
+    OpensAt  string
+    ClosesAt string
+    Holidays []string
+    // NationalHolidays closes the calendar on Brazilian national holidays,
+    // and OptionalHolidays on Carnival and Corpus Christi too
+    NationalHolidays bool
+    OptionalHolidays bool
+}
+
+// ClosedDay is a day a business calendar is closed on
+type ClosedDay struct {
+    Date   string `json:"date"`
+    Reason string `json:"reason"`
+}
+
+// DeadlinePreview is a deadline computed on a business calendar
+type DeadlinePreview struct {
+    Calendar string    `json:"calendar"`
+    Timezone string    `json:"timezone"`
+    Start    time.Time `json:"start"`
+    Duration string    `json:"duration"`
+    DueAt    time.Time `json:"due_at"`
+    // ClosedDays are the days from Start to DueAt the clock was paused on
+    ClosedDays []ClosedDay `json:"closed_days"`
+}
+
+// BusinessCalendar counts time during the opening hours of business days
+// only: weekdays that are not holidays. A nil *BusinessCalendar counts every
+// moment
+type BusinessCalendar struct {
+    name      string
+    location  *time.Location
+    opens     time.Duration
+    closes    time.Duration
+    recurring map[string]string
+    dated     map[string]string
+    national  bool
+    optional  bool
+}
+
+// NewBusinessCalendar builds a business calendar from its spec
+func NewBusinessCalendar(spec CalendarSpec) (*BusinessCalendar, error) {
+    location, err := time.LoadLocation(spec.Timezone)
+    if err != nil {
+        return nil, fmt.Errorf("%w: calendar %s: %w", ErrInvalidCalendar, spec.Name, err)
+    }
+    opens, err := timeOfDay(spec.OpensAt)
+    if err != nil {
+        return nil, fmt.Errorf("%w: calendar %s: opening time: %w", ErrInvalidCalendar, spec.Name, err)
+    }
+    closes, err := timeOfDay(spec.ClosesAt)
+    if err != nil {
+        return nil, fmt.Errorf("%w: calendar %s: closing time: %w", ErrInvalidCalendar, spec.Name, err)
+    }
+    if opens >= closes {
+        return nil, fmt.Errorf("%w: calendar %s opens after it closes", ErrInvalidCalendar, spec.Name)
+    }
+
+    calendar := &BusinessCalendar{
+        name:      spec.Name,
+        location:  location,
+        opens:     opens,
+        closes:    closes,
+        recurring: make(map[string]string),
+        dated:     make(map[string]string),
+        national:  spec.NationalHolidays,
+        optional:  spec.NationalHolidays && spec.OptionalHolidays,
+    }
+    for _, holiday := range spec.Holidays {
+        date, name, _ := strings.Cut(strings.TrimSpace(holiday), " ")
+        name = strings.TrimSpace(name)
+        if name == "" {
+            name = "holiday"
+        }
+        if _, err := time.Parse("2006-01-02", date); err == nil {
+            calendar.dated[date] = name
+            continue
+        }
+        // Recurring dates are checked against a leap year, so February 29
+        // is one
+        if _, err := time.Parse("2006-01-02", "2024-"+date); len(date) == 5 && err == nil {
+            calendar.recurring[date] = name
+            continue
+        }
+        return nil, fmt.Errorf("%w: calendar %s: invalid holiday %q", ErrInvalidCalendar, spec.Name, holiday)
+    }
+    return calendar, nil
+}
+
+// Name returns the name of the calendar
+func (c *BusinessCalendar) Name() string {
+    return c.name
+}
+
+// Location returns the time zone of the calendar
+func (c *BusinessCalendar) Location() *time.Location {
+    return c.location
+}
+
+// Holiday returns the name of the holiday on the day of t in the calendar's
+// time zone
+func (c *BusinessCalendar) Holiday(t time.Time) (string, bool) {
+    day := t.In(c.location).Format("2006-01-02")
+    if name, ok := c.dated[day]; ok {
+        return name, true
+    }
+    if name, ok := c.recurring[day[5:]]; ok {
+        return name, true
+    }
+    if c.national {
+        return c.nationalHoliday(t.In(c.location))
+    }
+    return "", false
+}
+
+// Closed returns why the calendar is closed all day on the day of t, empty
+// on business days
+func (c *BusinessCalendar) Closed(t time.Time) string {
+    switch t.In(c.location).Weekday() {
+    case time.Saturday, time.Sunday:
+        return ClosedWeekend
+    }
+    name, _ := c.Holiday(t)
+    return name
+}
+
+// Elapsed returns the business time from one moment to another, negative
+// when to is before from
+func (c *BusinessCalendar) Elapsed(from, to time.Time) time.Duration {
+    if c == nil {
+        return to.Sub(from)
+    }
+    if to.Before(from) {
+        return -c.Elapsed(to, from)
+    }
+
+    var elapsed time.Duration
+    for day := c.midnight(from); day.Before(to); day = c.nextDay(day) {
+        opens, closes, ok := c.hours(day)
+        if !ok {
+            continue
+        }
+        start, end := later(opens, from), earlier(closes, to)
+        if end.After(start) {
+            elapsed += end.Sub(start)
+        }
+    }
+    return elapsed
+}
+
+// Add returns the moment d of business time after from. Business time not
+// yet started at from starts at the next opening
+func (c *BusinessCalendar) Add(from time.Time, d time.Duration) time.Time {
+    if c == nil {
+        return from.Add(d)
+    }
+
+    for day := c.midnight(from); ; day = c.nextDay(day) {
+        opens, closes, ok := c.hours(day)
+        if !ok || !closes.After(from) {
+            continue
+        }
+        start := later(opens, from)
+        available := closes.Sub(start)
+        if d <= available {
+            return start.Add(d)
+        }
+        d -= available
+    }
+}
+
+// ClosedDays lists the days from the day of one moment to the day of
+// another that the calendar is closed on
+func (c *BusinessCalendar) ClosedDays(from, to time.Time) []ClosedDay {
+    closed := make([]ClosedDay, 0)
+    if c == nil {
+        return closed
+    }
+    for day := c.midnight(from); !day.After(to); day = c.nextDay(day) {
+        if reason := c.Closed(day); reason != "" {
+            closed = append(closed, ClosedDay{Date: day.Format("2006-01-02"), Reason: reason})
+        }
+    }
+    return closed
+}
+
+// hours returns the opening and closing times of a day, and false when the
+// calendar is closed all day
+func (c *BusinessCalendar) hours(day time.Time) (opens, closes time.Time, ok bool) {
+    if c.Closed(day) != "" {
+        return opens, closes, false
+    }
+    y, m, d := day.Date()
+    midnight := time.Date(y, m, d, 0, 0, 0, 0, c.location)
+    return midnight.Add(c.opens), midnight.Add(c.closes), true
+}
+
+// midnight returns the start of the day of t in the calendar's time zone
+func (c *BusinessCalendar) midnight(t time.Time) time.Time {
+    y, m, d := t.In(c.location).Date()
+    return time.Date(y, m, d, 0, 0, 0, 0, c.location)
+}
+
+// nextDay returns the start of the day after the one starting at day
+func (c *BusinessCalendar) nextDay(day time.Time) time.Time {
+    y, m, d := day.Date()
+    return time.Date(y, m, d+1, 0, 0, 0, 0, c.location)
+}
+
+// nationalHoliday returns the Brazilian national holiday on a day: the fixed
+// ones and Good Friday, and with optional holidays the Monday and Tuesday of
+// Carnival and Corpus Christi, all movable with Easter
+func (c *BusinessCalendar) nationalHoliday(day time.Time) (string, bool) {
+    y, m, d := day.Date()
+    for _, holiday := range nationalHolidays {
+        if holiday.month == m && holiday.day == d && y >= holiday.since {
+            return holiday.name, true
+        }
+    }
+
+    date := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+    switch int(date.Sub(easterSunday(y)).Hours() / 24) {
+    case -2:
+        return "Sexta-feira Santa", true
+    case -48, -47:
+        return "Carnaval", c.optional
+    case 60:
+        return "Corpus Christi", c.optional
+    }
+    return "", false
+}
+
+// easterSunday returns the date of Easter in a year of the Gregorian
+// calendar, by the anonymous Gregorian algorithm
+func easterSunday(year int) time.Time {
+    a := year % 19
+    b, c := year/100, year%100
+    d, e := b/4, b%4
+    f := (b + 8) / 25
+    g := (b - f + 1) / 3
+    h := (19*a + b - d - g + 15) % 30
+    i, k := c/4, c%4
+    l := (32 + 2*e + 2*i - h - k) % 7
+    m := (a + 11*h + 22*l) / 451
+    month := (h + l - 7*m + 114) / 31
+    day := (h+l-7*m+114)%31 + 1
+    return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// timeOfDay parses a 15:04 time as the duration since midnight
+func timeOfDay(value string) (time.Duration, error) {
+    t, err := time.Parse("15:04", value)
+    if err != nil {
+        return 0, err
+    }
+    return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func later(a, b time.Time) time.Time {
+    if a.After(b) {
+        return a
+    }
+    return b
+}
+
+func earlier(a, b time.Time) time.Time {
+    if a.Before(b) {
+        return a
+    }
+    return b
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+    "time"
+)
+
+// Outcomes of comparing one field extracted from the text of two OCR
+// providers
+const (
+    FieldAgreementMatch            = "match"
+    FieldAgreementMismatch         = "mismatch"
+    FieldAgreementMissingControl   = "missing_control"
+    FieldAgreementMissingCandidate = "missing_candidate"
+)
+
+// FieldAgreement records whether a field extracted from the text of the
+// candidate provider agrees with the one extracted from the text in service.
+// Field values are personal data and are not kept
+type FieldAgreement struct {
+    Field   string `json:"field"`
+    Outcome string `json:"outcome"`
+}
+
+// OCRCanaryComparison compares the text a candidate OCR provider extracted
+// from a document with the text of the provider in service. TextAgreement is
+// the share of words the two texts have in common, from 0 to 1
+type OCRCanaryComparison struct {
+    Provider      string           `json:"provider"`
+    Candidate     string           `json:"candidate"`
+    TextAgreement float64          `json:"text_agreement"`
+    Fields        []FieldAgreement `json:"fields,omitempty"`
+    Diverged      bool             `json:"diverged"`
+    // Failed is set when the candidate could not read the document
+    Failed     bool      `json:"failed,omitempty"`
+    DurationMS int64     `json:"duration_ms"`
+    ComparedAt time.Time `json:"compared_at"`
+}
+
+// RecordOCRCanary records the comparison of a candidate OCR provider on the
+// document, replacing any earlier one
+func (d *Document) RecordOCRCanary(comparison OCRCanaryComparison) {
+    d.OCRCanary = &comparison
+    d.UpdatedAt = time.Now()
+}
+
+// OCRCanaryReport aggregates the comparisons of a candidate OCR provider in a
+// period, to decide whether it can replace the provider in service
+type OCRCanaryReport struct {
+    Candidate string    `json:"candidate"`
+    From      time.Time `json:"from"`
+    To        time.Time `json:"to"`
+    Compared  int       `json:"compared"`
+    Failed    int       `json:"failed"`
+    Diverged  int       `json:"diverged"`
+    // MeanTextAgreement averages the agreement of the documents the candidate
+    // read
+    MeanTextAgreement float64              `json:"mean_text_agreement"`
+    Fields            []FieldAgreementRate `json:"fields"`
+    // DivergedDocuments lists the first documents that diverged, for review
+    DivergedDocuments []string  `json:"diverged_documents,omitempty"`
+    GeneratedAt       time.Time `json:"generated_at"`
+}
+
+// FieldAgreementRate counts the outcomes of comparing one field
+type FieldAgreementRate struct {
+    Field            string  `json:"field"`
+    Compared         int     `json:"compared"`
+    Matched          int     `json:"matched"`
+    Mismatched       int     `json:"mismatched"`
+    MissingControl   int     `json:"missing_control"`
+    MissingCandidate int     `json:"missing_candidate"`
+    Agreement        float64 `json:"agreement"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+    "time"
+)
+
+// Dispositions applied to the documents of a cancelled enrollment
+const (
+    DispositionRetain    = "retain"
+    DispositionAnonymize = "anonymize"
+    DispositionDelete    = "delete"
+)
+
+// Cancellation saga statuses. A saga reserves a disposition on every
+// document, then applies them; a failed reservation compensates the ones
+// already made, while a failed application is retried
+const (
+    SagaStatusReserving   = "reserving"
+    SagaStatusApplying    = "applying"
+    SagaStatusCompleted   = "completed"
+    SagaStatusCompensated = "compensated"
+)
+
+// Disposition step statuses
+const (
+    DispositionPending     = "pending"
+    DispositionReserved    = "reserved"
+    DispositionApplied     = "applied"
+    DispositionFailed      = "failed"
+    DispositionCompensated = "compensated"
+)
+
+// anonymizedValue replaces identifying text kept for the record's shape
+const anonymizedValue = "anonymized"
+
+// DocumentDisposition is the disposition decided for a document when its
+// enrollment was cancelled. The retention date the document had before is
+// kept to compensate the decision
+type DocumentDisposition struct {
+    SagaID                string    `json:"saga_id"`
+    Action                string    `json:"action"`
+    DecidedAt             time.Time `json:"decided_at"`
+    PreviousRetentionDate time.Time `json:"previous_retention_date"`
+}
+
+// CancellationSaga tracks the cleanup of the documents of a cancelled
+// enrollment. It is keyed by the cancellation event, so a redelivered event
+// resumes the saga instead of starting another
+type CancellationSaga struct {
+    ID           string            `json:"id"`
+    EnrollmentID string            `json:"enrollment_id"`
+    CancelledAt  time.Time         `json:"cancelled_at"`
+    Status       string            `json:"status"`
+    Steps        []DispositionStep `json:"steps"`
+    Error        string            `json:"error,omitempty"`
+    Attempts     int               `json:"attempts"`
+    StartedAt    time.Time         `json:"started_at"`
+    UpdatedAt    time.Time         `json:"updated_at"`
+    CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+}
+
+// DispositionStep is the disposition of one document
+type DispositionStep struct {
+    DocumentID   string     `json:"document_id"`
+    DocumentType string     `json:"document_type"`
+    Disposition  string     `json:"disposition"`
+    Status       string     `json:"status"`
+    Error        string     `json:"error,omitempty"`
+    AppliedAt    *time.Time `json:"applied_at,omitempty"`
+}
+
+// Finished reports whether the saga needs no further work
+func (s *CancellationSaga) Finished() bool {
+    return s.Status == SagaStatusCompleted
+}
+
+// Count returns how many steps have the given disposition and status
+func (s *CancellationSaga) Count(disposition, status string) int {
+    count := 0
+    for _, step := range s.Steps {
+        if step.Disposition == disposition && step.Status == status {
+            count++
+        }
+    }
+    return count
+}
+
+// Dispose records the disposition decided for the document. A retained
+// document is kept at least until retainUntil
+func (d *Document) Dispose(sagaID, action string, at, retainUntil time.Time) {
+    d.Disposition = &DocumentDisposition{SagaID: sagaID, Action: action, DecidedAt: at, PreviousRetentionDate: d.RetentionDate}
+    if action == DispositionRetain && retainUntil.After(d.RetentionDate) {
+        d.RetentionDate = retainUntil
+    }
+    d.UpdatedAt = at
+    d.addAuditLog("DISPOSITION", d.Status, "Enrollment cancelled, document to "+action, "SYSTEM")
+}
+
+// RevertDisposition compensates Dispose, restoring the previous retention date
+func (d *Document) RevertDisposition(at time.Time) {
+    if d.Disposition == nil {
+        return
+    }
+    d.RetentionDate = d.Disposition.PreviousRetentionDate
+    d.Disposition = nil
+    d.UpdatedAt = at
+    d.addAuditLog("DISPOSITION_REVERTED", d.Status, "Enrollment cancellation cleanup compensated", "SYSTEM")
+}
+
+// Anonymized reports whether the personal data of the document was removed
+func (d *Document) Anonymized() bool {
+    return d.EnrollmentID == "" && d.Filename == anonymizedValue
+}
+
+// Anonymize removes the personal data held on the document record and the
+// references to its content, keeping what the statistics need: type, tenant,
+// channel, status, dates, decisions and processing activities. The link to
+// the enrollment is dropped, as the enrollment identifies the subject
+func (d *Document) Anonymize(at time.Time) {
+    d.EnrollmentID = ""
+    d.Filename = anonymizedValue
+    d.StoragePath = ""
+    d.ContentHash = ""
+    d.EncryptionInfo = nil
+    d.ClientEncryption = nil
+    d.ExtractedFields = nil
+    d.Signatures = nil
+    d.Screening = nil
+    d.Renditions = nil
+    d.OCRPages = nil
+    d.OCRPreview = ""
+    d.PageReviews = nil
+    d.UpdatedAt = at
+    d.addAuditLog("ANONYMIZE", d.Status, "Personal data removed after enrollment cancellation", "SYSTEM")
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+    "strings"
+    "time"
+)
+
+// Kinds of active content found in PDFs
+const (
+    ActiveContentJavaScript      = "javascript"
+    ActiveContentLaunch          = "launch"
+    ActiveContentEmbeddedFile    = "embedded_file"
+    ActiveContentAutomaticAction = "automatic_action"
+    ActiveContentFormSubmission  = "form_submission"
+    ActiveContentXFA             = "xfa"
+    ActiveContentRichMedia       = "rich_media"
+)
+
+// RenditionQuarantinedOriginal is the upload a disarmed document was rebuilt
+// from, active content included. It is only served to forensic roles
+const RenditionQuarantinedOriginal = RenditionOriginal + "_quarantined"
+
+// Disarm records that the document content was rebuilt without the active
+// content found in the upload
+type Disarm struct {
+    // Findings lists the kinds of active content found
+    Findings     []string  `json:"findings"`
+    OriginalSize int64     `json:"original_size"`
+    DisarmedAt   time.Time `json:"disarmed_at"`
+}
+
+// MarkDisarmed records that the document content was rebuilt from the pages
+// of an upload carrying active content, kept as the quarantined original;
+// size is the size of the rebuilt PDF
+func (d *Document) MarkDisarmed(findings []string, size int64) {
+    d.Disarm = &Disarm{Findings: findings, OriginalSize: d.Size, DisarmedAt: time.Now()}
+    d.Size = size
+    d.UpdatedAt = d.Disarm.DisarmedAt
+    reason := "Rebuilt from its pages"
+    if len(findings) > 0 {
+        reason = "Rebuilt without active content: " + strings.Join(findings, ", ")
+    }
+    d.addAuditLog("DISARM", d.Status, reason, "SYSTEM")
+}
+
+// IsQuarantinedRendition reports whether a rendition keeps content withheld
+// from everyone but forensic roles
+func IsQuarantinedRendition(name string) bool {
+    return name == RenditionQuarantinedOriginal
+}
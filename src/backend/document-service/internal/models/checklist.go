@@ -0,0 +1,72 @@
+package models
+
+// Checklist item statuses, from least to most advanced
+const (
+    ChecklistMissing           = "missing"
+    ChecklistRejected          = "rejected"
+    ChecklistPending           = "pending"
+    ChecklistPartiallyApproved = "partially_approved"
+    ChecklistApproved          = "approved"
+)
+
+// ChecklistItem is the state of one required document type of an enrollment,
+// taken from its most advanced document
+type ChecklistItem struct {
+    DocumentType  string `json:"document_type"`
+    Status        string `json:"status"`
+    DocumentID    string `json:"document_id,omitempty"`
+    ApprovedPages int    `json:"approved_pages,omitempty"`
+    TotalPages    int    `json:"total_pages,omitempty"`
+    RescanPages   []int  `json:"rescan_pages,omitempty"`
+}
+
+// EnrollmentChecklist tracks the required documents of an enrollment; it is
+// complete once every item is approved
+type EnrollmentChecklist struct {
+    EnrollmentID string          `json:"enrollment_id"`
+    Complete     bool            `json:"complete"`
+    Items        []ChecklistItem `json:"items"`
+}
+
+// checklistRank orders checklist statuses so the most advanced document of a
+// type is reported
+var checklistRank = map[string]int{
+    ChecklistMissing:           0,
+    ChecklistRejected:          1,
+    ChecklistPending:           2,
+    ChecklistPartiallyApproved: 3,
+    ChecklistApproved:          4,
+}
+
+// ChecklistItemFor returns the checklist state of a document
+func ChecklistItemFor(doc *Document) ChecklistItem {
+    item := ChecklistItem{
+        DocumentType: doc.DocumentType,
+        DocumentID:   doc.ID,
+        TotalPages:   len(doc.PageReviews),
+        RescanPages:  doc.PagesAwaitingRescan(),
+    }
+    if len(doc.PageReviews) > 0 {
+        item.ApprovedPages = doc.ApprovedPages()
+    }
+    if len(item.RescanPages) == 0 {
+        item.RescanPages = nil
+    }
+
+    switch doc.Status {
+    case DocumentStatusApproved:
+        item.Status = ChecklistApproved
+    case DocumentStatusPartiallyApproved:
+        item.Status = ChecklistPartiallyApproved
+    case DocumentStatusRejected, DocumentStatusFailed:
+        item.Status = ChecklistRejected
+    default:
+        item.Status = ChecklistPending
+    }
+    return item
+}
+
+// Advances reports whether the item is further along than other
+func (i ChecklistItem) Advances(other ChecklistItem) bool {
+    return checklistRank[i.Status] > checklistRank[other.Status]
+}
@@ -0,0 +1,21 @@
+package models
+
+// ClientEncryptionAlgorithm is the envelope clients seal end-to-end encrypted
+// documents with: an ephemeral P-256 ECDH agreement with a recipient public
+// key, HKDF-SHA256 key derivation and AES-256-GCM
+const ClientEncryptionAlgorithm = "ECDH-P256-HKDF-SHA256-AES-256-GCM"
+
+// ClientEncryption describes a document the client encrypted to a public key
+// of the underwriting team. The service stores and serves the envelope but
+// can never read the content; ContentType of the document is the type the
+// client declared for the plaintext
+type ClientEncryption struct {
+    Algorithm      string `json:"algorithm"`
+    RecipientKeyID string `json:"recipient_key_id"`
+}
+
+// ClientEncrypted reports whether the document content is end-to-end
+// encrypted and therefore opaque to every processing step
+func (d *Document) ClientEncrypted() bool {
+    return d.ClientEncryption != nil
+}
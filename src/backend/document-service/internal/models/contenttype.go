@@ -0,0 +1,89 @@
+package models
+
+import (
+    "fmt"
+    "path"
+    "strings"
+    "time"
+)
+
+// Content type constants for the types the API does not accept by default
+const (
+    MimeTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+    MimeTypeODT  = "application/vnd.oasis.opendocument.text"
+    MimeTypeTIFF = "image/tiff"
+    MimeTypeHEIC = "image/heic"
+    MimeTypeHEIF = "image/heif"
+)
+
+// Conversions applied to an accepted content type on ingestion
+const (
+    // ConversionNone stores the upload as it was received
+    ConversionNone = "none"
+    // ConversionPDF stores a PDF made from the upload as the document and
+    // keeps the upload as the original rendition
+    ConversionPDF = "pdf"
+)
+
+// ContentType is an entry of the content type registry
+type ContentType struct {
+    MimeType   string   `json:"mime_type"`
+    Extensions []string `json:"extensions"`
+    // PDFConvertible types can be converted to PDF on ingestion
+    PDFConvertible bool `json:"pdf_convertible"`
+    // NeedsConverter types are converted by the document converter rather
+    // than by the service itself
+    NeedsConverter bool `json:"needs_converter"`
+}
+
+// ContentTypes is the registry of every content type a document may have.
+// Which of them each ingestion channel accepts is configured; the API
+// accepts AllowedMimeTypes unless configured otherwise
+var ContentTypes = []ContentType{
+    {MimeType: "application/pdf", Extensions: []string{"pdf"}},
+    {MimeType: "image/jpeg", Extensions: []string{"jpg", "jpeg"}, PDFConvertible: true},
+    {MimeType: "image/png", Extensions: []string{"png"}, PDFConvertible: true},
+    {MimeType: MimeTypeTIFF, Extensions: []string{"tif", "tiff"}, PDFConvertible: true},
+    {MimeType: "application/xml", Extensions: []string{"xml"}},
+    {MimeType: "text/xml", Extensions: []string{"xml"}},
+    {MimeType: MimeTypeHEIC, Extensions: []string{"heic"}},
+    {MimeType: MimeTypeHEIF, Extensions: []string{"heif"}},
+    {MimeType: MimeTypeDOCX, Extensions: []string{"docx"}, PDFConvertible: true, NeedsConverter: true},
+    {MimeType: MimeTypeODT, Extensions: []string{"odt"}, PDFConvertible: true, NeedsConverter: true},
+}
+
+// LookupContentType returns the registry entry of a content type
+func LookupContentType(mimeType string) (ContentType, bool) {
+    for _, contentType := range ContentTypes {
+        if contentType.MimeType == mimeType {
+            return contentType, true
+        }
+    }
+    return ContentType{}, false
+}
+
+// Image reports whether the type is an image the service converts to PDF
+// itself, one page per image or TIFF page
+func (c ContentType) Image() bool {
+    return c.PDFConvertible && !c.NeedsConverter
+}
+
+// MarkConverted records that the document content was converted to PDF on
+// ingestion from an upload of another type, kept as the original rendition;
+// size is the size of the PDF
+func (d *Document) MarkConverted(from string, size int64) {
+    d.ConvertedFrom = from
+    d.ContentType = "application/pdf"
+    d.Size = size
+    d.Filename = strings.TrimSuffix(d.Filename, path.Ext(d.Filename)) + ".pdf"
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("CONVERT", d.Status, "Converted to PDF from "+from, "SYSTEM")
+}
+
+// MarkComposed records that the document was composed from image uploads,
+// kept in order as the original renditions; from lists their types
+func (d *Document) MarkComposed(from []string) {
+    d.ComposedFrom = from
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("COMPOSE", d.Status, fmt.Sprintf("Composed from %d images", len(from)), "SYSTEM")
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+    "time"
+)
+
+// Auto-decision outcome constants
+const (
+    AutoDecisionApprove = "approve"
+    AutoDecisionReject  = "reject"
+    AutoDecisionReview  = "review"
+)
+
+// AutoDecision records the rules engine outcome and the facts it was based on
+type AutoDecision struct {
+    Rule        string                 `json:"rule,omitempty"`
+    Decision    string                 `json:"decision"`
+    Reason      string                 `json:"reason"`
+    Facts       map[string]interface{} `json:"facts"`
+    EvaluatedAt time.Time              `json:"evaluated_at"`
+}
+
+// SetAutoDecision records the latest rules engine decision
+func (d *Document) SetAutoDecision(decision AutoDecision) {
+    d.AutoDecision = &decision
+    d.UpdatedAt = decision.EvaluatedAt
+
+    rule := decision.Rule
+    if rule == "" {
+        rule = "no matching rule"
+    }
+    d.addAuditLog("AUTO_DECISION", decision.Decision, rule+": "+decision.Reason, "SYSTEM")
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+    "time"
+)
+
+// DelegatedIdentity is the end user a service calls on behalf of, as stated
+// by a token the identity provider issued through token exchange
+type DelegatedIdentity struct {
+    Subject  string `json:"subject"`
+    TenantID string `json:"tenant_id,omitempty"`
+    Role     string `json:"role,omitempty"`
+    // Actor is the service making the call. ActorChain lists every actor,
+    // the caller first, when the token was exchanged more than once
+    Actor      string    `json:"actor"`
+    ActorChain []string  `json:"actor_chain"`
+    TokenID    string    `json:"token_id,omitempty"`
+    ExpiresAt  time.Time `json:"expires_at"`
+}
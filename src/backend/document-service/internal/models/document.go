@@ -15,11 +15,72 @@ const (
     DocumentStatusEncrypting = "encrypting"
     DocumentStatusCompleted  = "completed"
     DocumentStatusFailed     = "failed"
+    DocumentStatusApproved   = "approved"
+    DocumentStatusRejected   = "rejected"
+    // DocumentStatusPartiallyApproved marks multi-page documents with some
+    // pages approved and others awaiting a decision or a rescan
+    DocumentStatusPartiallyApproved = "partially_approved"
+    // DocumentStatusHaltedConsent marks documents whose processing stopped
+    // because the subject revoked consent; they are reprocessed only after
+    // consent is granted again
+    DocumentStatusHaltedConsent = "processing_halted_consent"
+    // DocumentStatusOCRDeferred marks documents stored while the OCR error
+    // budget was exhausted; they are processed once the provider recovers
+    DocumentStatusOCRDeferred = "ocr_deferred"
+    // DocumentStatusQueued marks large documents stored and waiting for
+    // their processing steps to run in the background
+    DocumentStatusQueued = "queued_for_processing"
 )
 
-// Document size and type constraints
+// Review decision constants
+const (
+    ReviewDecisionApprove = "approve"
+    ReviewDecisionReject  = "reject"
+)
+
+// Ingestion channel constants
+const (
+    ChannelAPI      = "api"
+    ChannelWhatsApp = "whatsapp"
+    ChannelSFTP     = "sftp"
+)
+
+// Channels lists every ingestion channel
+var Channels = []string{ChannelAPI, ChannelWhatsApp, ChannelSFTP}
+
+// Review flag constants
+const (
+    ReviewFlagScreeningHit    = "screening_hit"
+    ReviewFlagAddressMismatch = "address_mismatch"
+    ReviewFlagOCRIncomplete   = "ocr_incomplete"
+)
+
+// Encryption algorithm constants
+const (
+    EncryptionAlgorithmGCM = "AES-256-GCM"
+    // EncryptionAlgorithmGCMStream seals content in independently authenticated
+    // chunks so byte ranges can be decrypted on their own
+    EncryptionAlgorithmGCMStream = "AES-256-GCM-STREAM"
+    // EncryptionAlgorithmXChaCha and its stream variant use 192 bit random
+    // nonces, which can be drawn without a practical collision limit
+    EncryptionAlgorithmXChaCha       = "XCHACHA20-POLY1305"
+    EncryptionAlgorithmXChaChaStream = "XCHACHA20-POLY1305-STREAM"
+)
+
+// EncryptionMetadataVersion is the metadata format written by this release.
+// Version 0 predates versioning and only ever names AES-GCM algorithms;
+// version 1 may name any supported algorithm
+const EncryptionMetadataVersion = 1
+
+// Document size and type constraints. The size is what the document model
+// supports and the types are those the API accepts by default; the limits a
+// deployment accepts are configured in the service configuration and
+// validated against the model size and the content type registry
 const (
     MaxDocumentSize = 100 * 1024 * 1024 // 100MB
+    // MaxLargeDocumentSize is what the model supports of documents accepted
+    // past the configured limits as large documents
+    MaxLargeDocumentSize = 512 * 1024 * 1024 // 512MB
 )
 
 var (
@@ -27,6 +88,9 @@ var (
         "application/pdf",
         "image/jpeg",
         "image/png",
+        MimeTypeTIFF,
+        "application/xml",
+        "text/xml",
     }
 
     AllowedStatuses = []string{
@@ -36,31 +100,104 @@ var (
         DocumentStatusEncrypting,
         DocumentStatusCompleted,
         DocumentStatusFailed,
+        DocumentStatusApproved,
+        DocumentStatusRejected,
+        DocumentStatusPartiallyApproved,
+        DocumentStatusHaltedConsent,
+        DocumentStatusOCRDeferred,
+        DocumentStatusQueued,
     }
 
     ErrInvalidStatus      = errors.New("invalid document status")
     ErrInvalidSize        = errors.New("document size exceeds maximum allowed")
     ErrInvalidContentType = errors.New("unsupported content type")
     ErrMissingField       = errors.New("required field is missing")
+    ErrInvalidDecision    = errors.New("invalid review decision")
+    ErrNotReviewable      = errors.New("document is not ready for review")
 )
 
 // Document represents a health plan enrollment document with comprehensive metadata
 type Document struct {
     ID            string             `json:"id"`
     EnrollmentID  string             `json:"enrollment_id"`
+    TenantID      string             `json:"tenant_id,omitempty"`
     DocumentType  string             `json:"document_type"`
     Filename      string             `json:"filename"`
     ContentType   string             `json:"content_type"`
+    // ConvertedFrom is the type of the upload the content was converted from
+    ConvertedFrom string             `json:"converted_from,omitempty"`
+    // ComposedFrom lists the types of the images the content was composed
+    // from, in page order
+    ComposedFrom  []string           `json:"composed_from,omitempty"`
     Size          int64              `json:"size"`
     Status        string             `json:"status"`
+    IngestionChannel string          `json:"ingestion_channel"`
+    // ProcessingTier is the tier the document was routed to by size and
+    // page count, when processing tiers are enabled
+    ProcessingTier string            `json:"processing_tier,omitempty"`
+    // LargeDocument is set on a document accepted past the size limit of
+    // its channel, whose processing runs in the background
+    LargeDocument *LargeDocument     `json:"large_document,omitempty"`
+    // Synthetic marks a document uploaded by the synthetic probe; nothing
+    // downstream is notified of it
+    Synthetic     bool               `json:"synthetic,omitempty"`
     StoragePath   string             `json:"storage_path"`
     ContentHash   string             `json:"content_hash"`
     EncryptionInfo *EncryptionMetadata `json:"encryption_info,omitempty"`
+    ClientEncryption *ClientEncryption `json:"client_encryption,omitempty"`
+    VirusScan     *VirusScan         `json:"virus_scan,omitempty"`
+    // Disarm is set when the content was rebuilt without active content
+    Disarm        *Disarm            `json:"disarm,omitempty"`
+    // QuarantineID is the quarantine item withholding the document pending
+    // review, set while it is held
+    QuarantineID  string             `json:"quarantine_id,omitempty"`
+    ExtractedFields []ExtractedField  `json:"extracted_fields,omitempty"`
+    Signatures    []SignatureInfo    `json:"signatures,omitempty"`
+    GovernmentVerified   bool       `json:"government_verified"`
+    GovernmentVerifiedAt *time.Time `json:"government_verified_at,omitempty"`
+    Screening     []ScreeningResult  `json:"screening,omitempty"`
+    ReviewFlags   []string           `json:"review_flags,omitempty"`
+    // SLAEscalations record the review deadline thresholds the document
+    // passed undecided
+    SLAEscalations []SLAEscalation    `json:"sla_escalations,omitempty"`
+    AutoDecision  *AutoDecision      `json:"auto_decision,omitempty"`
+    Experiments   []ExperimentAssignment `json:"experiments,omitempty"`
+    // OCRCanary compares the text of a candidate OCR provider with the text
+    // of the provider in service
+    OCRCanary     *OCRCanaryComparison `json:"ocr_canary,omitempty"`
+    Renditions    []Rendition        `json:"renditions,omitempty"`
+    OCRPages      []OCRPage          `json:"ocr_pages,omitempty"`
+    OCRPreview    string             `json:"ocr_preview,omitempty"`
+    Language      *DocumentLanguage  `json:"language,omitempty"`
+    // Translation labels the machine translated text rendition
+    Translation   *MachineTranslation `json:"machine_translation,omitempty"`
+    PageReviews   []PageReview       `json:"page_reviews,omitempty"`
+    // RemovedPages are the pages of the upload left out of the normalized PDF
+    RemovedPages  []RemovedPage      `json:"removed_pages,omitempty"`
+    ProcessingActivities []ProcessingActivity `json:"processing_activities,omitempty"`
     CreatedAt     time.Time          `json:"created_at"`
     UpdatedAt     time.Time          `json:"updated_at"`
+    // Version is assigned by the repository and grows with every change, so
+    // a client can ask to read at least what it wrote
+    Version       int64              `json:"version"`
     ProcessedAt   *time.Time         `json:"processed_at,omitempty"`
+    // ProcessingRun counts the times processing of the document started, so
+    // work queued once per run tells a reprocessing from a redelivery
+    ProcessingRun int                `json:"processing_run,omitempty"`
+    ReviewedAt    *time.Time         `json:"reviewed_at,omitempty"`
+    ReviewedBy    string             `json:"reviewed_by,omitempty"`
+    // SpooledAt is set while the content waits in an upload spool for
+    // object storage to recover
+    SpooledAt     *time.Time         `json:"spooled_at,omitempty"`
     RetentionDate time.Time          `json:"retention_date"`
+    Disposition   *DocumentDisposition `json:"disposition,omitempty"`
+    Expiry        *ExpiryNotice      `json:"expiry,omitempty"`
+    Hold          *RetentionHold     `json:"hold,omitempty"`
     AuditTrail    []AuditLog         `json:"audit_trail"`
+
+    // producer is the provenance attributed to artifacts recorded by the
+    // pipeline step currently running
+    producer *Provenance
 }
 
 // EncryptionMetadata stores encryption-related metadata for encrypted documents
@@ -71,6 +208,18 @@ type EncryptionMetadata struct {
     KeyVersion    string    `json:"key_version"`
     EncryptedAt   time.Time `json:"encrypted_at"`
     KeyRotationDue time.Time `json:"key_rotation_due"`
+    // WrappedKey is the document's own data key encrypted under the KMS key,
+    // set when crypto-shredding is enabled. Destroying it, and revoking the
+    // KMS grant allowing its use, makes the content unrecoverable
+    WrappedKey        string            `json:"wrapped_key,omitempty"`
+    // SharedKey is the shared data key the content was sealed with when it
+    // has none of its own, encrypted under the KMS key. It identifies the key
+    // and unwraps it after the service has moved on to a newer one
+    SharedKey         string            `json:"shared_key,omitempty"`
+    GrantID           string            `json:"grant_id,omitempty"`
+    EncryptionContext map[string]string `json:"encryption_context,omitempty"`
+    ShreddedAt        *time.Time        `json:"shredded_at,omitempty"`
+    Version           int               `json:"version,omitempty"`
 }
 
 // AuditLog represents an audit log entry for document operations
@@ -84,22 +233,20 @@ type AuditLog struct {
 
 // NewDocument creates a new document instance with default values and validation
 func NewDocument(enrollmentID, documentType, filename, contentType string, size int64) (*Document, error) {
+    return newDocument(enrollmentID, documentType, filename, contentType, size, MaxDocumentSize)
+}
+
+// newDocument creates a document of at most maxSize bytes
+func newDocument(enrollmentID, documentType, filename, contentType string, size, maxSize int64) (*Document, error) {
     if enrollmentID == "" || documentType == "" || filename == "" {
         return nil, ErrMissingField
     }
 
-    validContentType := false
-    for _, allowed := range AllowedMimeTypes {
-        if contentType == allowed {
-            validContentType = true
-            break
-        }
-    }
-    if !validContentType {
+    if _, ok := LookupContentType(contentType); !ok {
         return nil, ErrInvalidContentType
     }
 
-    if size > MaxDocumentSize {
+    if size > maxSize {
         return nil, ErrInvalidSize
     }
 
@@ -151,6 +298,39 @@ func (d *Document) UpdateStatus(status, reason string) error {
     return nil
 }
 
+// Review records a reviewer decision on a processed document
+func (d *Document) Review(decision, reason, reviewer string) error {
+    var status string
+    switch decision {
+    case ReviewDecisionApprove:
+        status = DocumentStatusApproved
+    case ReviewDecisionReject:
+        status = DocumentStatusRejected
+    default:
+        return ErrInvalidDecision
+    }
+
+    if !d.reviewable() {
+        return ErrNotReviewable
+    }
+    if reviewer == "" {
+        return ErrMissingField
+    }
+    // A document under page review is approved page by page
+    if status == DocumentStatusApproved && len(d.PendingPages()) > 0 {
+        return ErrPagesPending
+    }
+
+    now := time.Now()
+    d.Status = status
+    d.UpdatedAt = now
+    d.ReviewedAt = &now
+    d.ReviewedBy = reviewer
+
+    d.addAuditLog("REVIEW", status, reason, reviewer)
+    return nil
+}
+
 // SetEncryptionMetadata sets document encryption metadata with audit logging
 func (d *Document) SetEncryptionMetadata(metadata *EncryptionMetadata) error {
     if err := metadata.Validate(); err != nil {
@@ -169,8 +349,8 @@ func (e *EncryptionMetadata) Validate() error {
         return ErrMissingField
     }
 
-    if e.Algorithm != "AES-256-GCM" {
-        return errors.New("unsupported encryption algorithm")
+    if err := e.ValidateFormat(); err != nil {
+        return err
     }
 
     if e.KeyRotationDue.Before(time.Now()) {
@@ -180,6 +360,32 @@ func (e *EncryptionMetadata) Validate() error {
     return nil
 }
 
+// ValidateFormat checks that the metadata version is known and allows the
+// algorithm, so content written by a newer release is refused rather than
+// misread
+func (e *EncryptionMetadata) ValidateFormat() error {
+    switch e.Version {
+    case 0:
+        if e.Algorithm != EncryptionAlgorithmGCM && e.Algorithm != EncryptionAlgorithmGCMStream {
+            return errors.New("unsupported encryption algorithm")
+        }
+    case EncryptionMetadataVersion:
+        switch e.Algorithm {
+        case EncryptionAlgorithmGCM, EncryptionAlgorithmGCMStream, EncryptionAlgorithmXChaCha, EncryptionAlgorithmXChaChaStream:
+        default:
+            return errors.New("unsupported encryption algorithm")
+        }
+    default:
+        return fmt.Errorf("unsupported encryption metadata version %d", e.Version)
+    }
+    return nil
+}
+
+// StartProcessingRun counts a new run of the processing steps
+func (d *Document) StartProcessingRun() {
+    d.ProcessingRun++
+}
+
 // addAuditLog adds a new audit log entry to the document
 func (d *Document) addAuditLog(action, status, reason, performer string) {
     auditLog := AuditLog{
@@ -192,6 +398,25 @@ func (d *Document) addAuditLog(action, status, reason, performer string) {
     d.AuditTrail = append(d.AuditTrail, auditLog)
 }
 
+// AddReviewFlag marks the document as needing reviewer attention
+func (d *Document) AddReviewFlag(flag string) {
+    if d.HasReviewFlag(flag) {
+        return
+    }
+    d.ReviewFlags = append(d.ReviewFlags, flag)
+    d.addAuditLog("REVIEW_FLAG", d.Status, "Flag raised: "+flag, "SYSTEM")
+}
+
+// HasReviewFlag reports whether the flag has been raised on the document
+func (d *Document) HasReviewFlag(flag string) bool {
+    for _, existing := range d.ReviewFlags {
+        if existing == flag {
+            return true
+        }
+    }
+    return false
+}
+
 // MarshalJSON implements custom JSON marshaling with sensitive data handling
 func (d *Document) MarshalJSON() ([]byte, error) {
     type Alias Document
@@ -0,0 +1,52 @@
+package models
+
+import (
+    "time"
+)
+
+// Encryption-at-rest issues reported by the scanner
+const (
+    EncryptionIssueMissingObject        = "missing_object"
+    EncryptionIssueMissingMetadata      = "missing_metadata"
+    EncryptionIssueInvalidMetadata      = "invalid_metadata"
+    EncryptionIssuePlaintext            = "plaintext"
+    EncryptionIssueSizeMismatch         = "size_mismatch"
+    EncryptionIssueAuthenticationFailed = "authentication_failed"
+    EncryptionIssueStaleKeyVersion      = "stale_key_version"
+    EncryptionIssueRotationOverdue      = "rotation_overdue"
+)
+
+// EncryptedObjectOriginal names the original upload of a document in findings;
+// renditions are named after the rendition
+const EncryptedObjectOriginal = "original"
+
+// EncryptionFinding is a stored object that does not conform to the
+// encryption-at-rest policy. Reencryptable findings hold content that can be
+// recovered, either ciphertext that still authenticates or plaintext, and are
+// fixed by re-encrypting the document
+type EncryptionFinding struct {
+    DocumentID    string `json:"document_id"`
+    Object        string `json:"object"`
+    StoragePath   string `json:"storage_path"`
+    Issue         string `json:"issue"`
+    Detail        string `json:"detail,omitempty"`
+    Reencryptable bool   `json:"reencryptable"`
+}
+
+// EncryptionScanReport summarizes one scan of a sample of stored objects
+type EncryptionScanReport struct {
+    StartedAt   time.Time           `json:"started_at"`
+    FinishedAt  time.Time           `json:"finished_at"`
+    Documents   int                 `json:"documents"`
+    Objects     int                 `json:"objects"`
+    Conforming  int                 `json:"conforming"`
+    Reencrypted int                 `json:"reencrypted"`
+    Findings    []EncryptionFinding `json:"findings"`
+}
+
+// RecordReencryption notes in the audit trail that the stored content was
+// re-encrypted under the current key
+func (d *Document) RecordReencryption() {
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("REENCRYPT", d.Status, "Stored content re-encrypted under the current key", "SYSTEM")
+}
@@ -0,0 +1,24 @@
+package models
+
+// Enrollment represents the subset of enrollment data the document service
+// needs from the enrollment service to validate incoming documents
+type Enrollment struct {
+    ID                 string  `json:"id"`
+    Status             string  `json:"status"`
+    EmployerID         string  `json:"employer_id,omitempty"`
+    BeneficiaryName    string  `json:"beneficiary_name"`
+    BeneficiaryCPF     string  `json:"beneficiary_cpf"`
+    BeneficiaryPhone   string  `json:"beneficiary_phone"`
+    BeneficiaryAddress Address `json:"beneficiary_address"`
+}
+
+// Address is a Brazilian postal address
+type Address struct {
+    Street       string `json:"street"`
+    Number       string `json:"number,omitempty"`
+    Complement   string `json:"complement,omitempty"`
+    Neighborhood string `json:"neighborhood"`
+    City         string `json:"city"`
+    State        string `json:"state"`
+    PostalCode   string `json:"postal_code"`
+}
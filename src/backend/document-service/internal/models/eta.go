@@ -0,0 +1,52 @@
+package models
+
+import (
+    "time"
+)
+
+// ETAStageReview is the stage from the end of processing to the reviewer
+// decision; the other stages are named after the pipeline steps
+const ETAStageReview = "review"
+
+// ETA bases
+const (
+    // ETABasisHistory marks estimates built from the observed durations of
+    // every remaining stage
+    ETABasisHistory = "history"
+    // ETABasisInsufficientHistory marks documents with a remaining stage
+    // never observed for their type, which get no estimate
+    ETABasisInsufficientHistory = "insufficient_history"
+)
+
+// ProcessingStatus tells a client where its document stands and when it is
+// expected to be verified
+type ProcessingStatus struct {
+    DocumentID string `json:"document_id"`
+    Status     string `json:"status"`
+    Version    int64  `json:"version"`
+    // Stage is the stage the document is in or waiting for, empty once it is
+    // verified or processing stopped
+    Stage string `json:"stage,omitempty"`
+    // QueueDepth is the number of documents ahead of this one in Stage
+    QueueDepth int  `json:"queue_depth"`
+    Verified   bool `json:"verified"`
+    // EstimatedCompletion is when the reviewer decision is expected, unset
+    // when there is no estimate
+    EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+    Basis               string     `json:"basis,omitempty"`
+    EstimatedAt         time.Time  `json:"estimated_at"`
+    // SLA is the document's review deadline clock, unset when the SLA clock
+    // is disabled
+    SLA *SLAClock `json:"sla,omitempty"`
+}
+
+// Verified reports whether a reviewer reached a decision on the document
+func (d *Document) Verified() bool {
+    return d.Status == DocumentStatusApproved || d.Status == DocumentStatusRejected
+}
+
+// AwaitingReview reports whether the document is processed and waiting for
+// a reviewer decision
+func (d *Document) AwaitingReview() bool {
+    return d.Status == DocumentStatusCompleted || d.Status == DocumentStatusPartiallyApproved
+}
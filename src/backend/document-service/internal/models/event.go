@@ -0,0 +1,254 @@
+package models
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// Document lifecycle event types
+const (
+    EventDocumentUploaded    = "DocumentUploaded"
+    EventStatusChanged       = "StatusChanged"
+    EventProcessingCompleted = "ProcessingCompleted"
+    EventProcessingFailed    = "ProcessingFailed"
+    EventProcessingHalted    = "ProcessingHalted"
+    EventProcessingDeferred  = "ProcessingDeferred"
+    EventEncrypted           = "Encrypted"
+    EventReencrypted         = "Reencrypted"
+    EventOCRCompleted        = "OcrCompleted"
+    EventFieldsExtracted     = "FieldsExtracted"
+    EventRenditionStored     = "RenditionStored"
+    EventSignaturesVerified  = "SignaturesVerified"
+    EventGovernmentVerified  = "GovernmentVerified"
+    EventScreened            = "Screened"
+    EventFlagged             = "Flagged"
+    EventAutoDecided         = "AutoDecided"
+    EventReviewed            = "Reviewed"
+    EventReviewRolledBack    = "ReviewRolledBack"
+    EventPagesReviewed       = "PagesReviewed"
+    EventPageRescanned       = "PageRescanned"
+    EventPagesEdited         = "PagesEdited"
+    EventPagesCleaned        = "PagesCleaned"
+    EventShredded            = "Shredded"
+    EventDispositionDecided  = "DispositionDecided"
+    EventDispositionReverted = "DispositionReverted"
+    EventDocumentAnonymized  = "DocumentAnonymized"
+    EventDocumentDeleted     = "DocumentDeleted"
+    EventReclassified        = "Reclassified"
+    EventContentHashed       = "ContentHashed"
+    EventExpiryNoticed       = "ExpiryNoticed"
+    EventHoldPlaced          = "HoldPlaced"
+    EventHoldReleased        = "HoldReleased"
+    EventExtractedDataPurged = "ExtractedDataPurged"
+    EventRenditionsPurged    = "RenditionsPurged"
+    EventAccessReported      = "AccessReported"
+    EventAccessAnomaly       = "AccessAnomaly"
+    EventSpooled             = "Spooled"
+    EventSpoolDrained        = "SpoolDrained"
+    EventConverted           = "Converted"
+    EventComposed            = "Composed"
+    EventLanguageDetected    = "LanguageDetected"
+    EventTranslated          = "Translated"
+    EventSLAEscalated        = "SLAEscalated"
+    // EventDocumentUpdated records a change no audit entry describes
+    EventDocumentUpdated = "DocumentUpdated"
+)
+
+// eventTypes maps audit trail actions to the events they record
+var eventTypes = map[string]string{
+    "CREATE":                  EventDocumentUploaded,
+    "ENCRYPTION":              EventEncrypted,
+    "REENCRYPT":               EventReencrypted,
+    "OCR_PAGES":               EventOCRCompleted,
+    "EXTRACTION":              EventFieldsExtracted,
+    "RENDITION":               EventRenditionStored,
+    "SIGNATURE_VERIFICATION":  EventSignaturesVerified,
+    "GOVERNMENT_VERIFICATION": EventGovernmentVerified,
+    "SCREENING":               EventScreened,
+    "REVIEW_FLAG":             EventFlagged,
+    "AUTO_DECISION":           EventAutoDecided,
+    "REVIEW":                  EventReviewed,
+    "REVIEW_ROLLBACK":         EventReviewRolledBack,
+    "PAGE_REVIEW":             EventPagesReviewed,
+    "PAGE_RESCAN":             EventPageRescanned,
+    "PAGE_OPERATIONS":         EventPagesEdited,
+    "PAGE_CLEANUP":            EventPagesCleaned,
+    "SHRED":                   EventShredded,
+    "DISPOSITION":             EventDispositionDecided,
+    "DISPOSITION_REVERTED":    EventDispositionReverted,
+    "ANONYMIZE":               EventDocumentAnonymized,
+    "RECLASSIFY":              EventReclassified,
+    "CONTENT_HASH":            EventContentHashed,
+    "EXPIRY_NOTICE":           EventExpiryNoticed,
+    "HOLD":                    EventHoldPlaced,
+    "HOLD_RELEASED":           EventHoldReleased,
+    "EXTRACTED_DATA_PURGE":    EventExtractedDataPurged,
+    "RENDITION_PURGE":         EventRenditionsPurged,
+    "ACCESS_EVENT":            EventAccessReported,
+    "ACCESS_ANOMALY":          EventAccessAnomaly,
+    "SPOOL":                   EventSpooled,
+    "SPOOL_DRAINED":           EventSpoolDrained,
+    "CONVERT":                 EventConverted,
+    "COMPOSE":                 EventComposed,
+    "LANGUAGE_DETECTION":      EventLanguageDetected,
+    "TRANSLATION":             EventTranslated,
+    "SLA_ESCALATION":          EventSLAEscalated,
+}
+
+var ErrEventChainBroken = errors.New("document event chain is broken")
+
+// DocumentEvent is one fact in the append-only history of a document. The
+// events appended by one change form a batch; the last event of a batch
+// carries the change as a JSON merge patch of the document state, so folding
+// the patches of every event in order rebuilds the document. Each event is
+// chained to its predecessor by hash. Patches of deleted documents are erased
+// while their digests keep the chain verifiable
+type DocumentEvent struct {
+    DocumentID  string          `json:"document_id"`
+    Sequence    int64           `json:"sequence"`
+    Type        string          `json:"type"`
+    Status      string          `json:"status,omitempty"`
+    Reason      string          `json:"reason,omitempty"`
+    Actor       string          `json:"actor,omitempty"`
+    OccurredAt  time.Time       `json:"occurred_at"`
+    Patch       json.RawMessage `json:"patch,omitempty"`
+    PatchDigest string          `json:"patch_digest,omitempty"`
+    Erased      bool            `json:"erased,omitempty"`
+    PrevHash    string          `json:"prev_hash,omitempty"`
+    Hash        string          `json:"hash"`
+}
+
+// EventSummary is a document event without its state patch: the patch is
+// summarized by the top-level fields it changed, and its digest and the
+// event hashes let compliance tooling verify the chain
+type EventSummary struct {
+    DocumentID  string    `json:"document_id"`
+    Sequence    int64     `json:"sequence"`
+    Type        string    `json:"type"`
+    Status      string    `json:"status,omitempty"`
+    Reason      string    `json:"reason,omitempty"`
+    Actor       string    `json:"actor,omitempty"`
+    OccurredAt  time.Time `json:"occurred_at"`
+    Changes     []string  `json:"changes,omitempty"`
+    Erased      bool      `json:"erased,omitempty"`
+    PatchDigest string    `json:"patch_digest"`
+    PrevHash    string    `json:"prev_hash,omitempty"`
+    Hash        string    `json:"hash"`
+}
+
+// Summary returns the event with its patch summarized
+func (e *DocumentEvent) Summary() EventSummary {
+    summary := EventSummary{
+        DocumentID:  e.DocumentID,
+        Sequence:    e.Sequence,
+        Type:        e.Type,
+        Status:      e.Status,
+        Reason:      e.Reason,
+        Actor:       e.Actor,
+        OccurredAt:  e.OccurredAt,
+        Erased:      e.Erased,
+        PatchDigest: e.PatchDigest,
+        PrevHash:    e.PrevHash,
+        Hash:        e.Hash,
+    }
+    var patch map[string]json.RawMessage
+    if len(e.Patch) > 0 && json.Unmarshal(e.Patch, &patch) == nil {
+        for field := range patch {
+            summary.Changes = append(summary.Changes, field)
+        }
+        sort.Strings(summary.Changes)
+    }
+    return summary
+}
+
+// EventsForAudit returns the events described by audit trail entries
+func EventsForAudit(documentID string, entries []AuditLog) []*DocumentEvent {
+    events := make([]*DocumentEvent, 0, len(entries))
+    for _, entry := range entries {
+        events = append(events, &DocumentEvent{
+            DocumentID: documentID,
+            Type:       eventType(entry),
+            Status:     entry.Status,
+            Reason:     entry.Reason,
+            Actor:      entry.PerformedBy,
+            OccurredAt: entry.Timestamp.UTC().Truncate(time.Microsecond),
+        })
+    }
+    return events
+}
+
+// eventType names the event an audit entry records; status updates are
+// named after the status reached
+func eventType(entry AuditLog) string {
+    if entry.Action == "STATUS_UPDATE" {
+        switch entry.Status {
+        case DocumentStatusCompleted:
+            return EventProcessingCompleted
+        case DocumentStatusFailed:
+            return EventProcessingFailed
+        case DocumentStatusHaltedConsent:
+            return EventProcessingHalted
+        case DocumentStatusOCRDeferred:
+            return EventProcessingDeferred
+        }
+        return EventStatusChanged
+    }
+    if eventType, ok := eventTypes[entry.Action]; ok {
+        return eventType
+    }
+    return EventDocumentUpdated
+}
+
+// Seal sets the patch digest and chains the event to the previous hash
+func (e *DocumentEvent) Seal(prevHash string) {
+    digest := sha256.Sum256(e.Patch)
+    e.PatchDigest = hex.EncodeToString(digest[:])
+    e.PrevHash = prevHash
+    e.Hash = e.computeHash()
+}
+
+// Verify checks that the event follows prevHash and that neither the event
+// nor, unless erased, its patch was altered
+func (e *DocumentEvent) Verify(prevHash string) error {
+    if e.PrevHash != prevHash || e.Hash != e.computeHash() {
+        return fmt.Errorf("%w: event %d of document %s", ErrEventChainBroken, e.Sequence, e.DocumentID)
+    }
+    if !e.Erased {
+        digest := sha256.Sum256(e.Patch)
+        if hex.EncodeToString(digest[:]) != e.PatchDigest {
+            return fmt.Errorf("%w: patch of event %d of document %s", ErrEventChainBroken, e.Sequence, e.DocumentID)
+        }
+    }
+    return nil
+}
+
+// Erase removes the patch, which holds personal data, keeping its digest
+func (e *DocumentEvent) Erase() {
+    e.Patch = nil
+    e.Erased = true
+}
+
+func (e *DocumentEvent) computeHash() string {
+    hash := sha256.New()
+    for _, part := range []string{
+        e.PrevHash,
+        e.DocumentID,
+        strconv.FormatInt(e.Sequence, 10),
+        e.Type,
+        e.Status,
+        e.Reason,
+        e.Actor,
+        e.OccurredAt.UTC().Format(time.RFC3339Nano),
+        e.PatchDigest,
+    } {
+        hash.Write([]byte(part))
+        hash.Write([]byte{0})
+    }
+    return hex.EncodeToString(hash.Sum(nil))
+}
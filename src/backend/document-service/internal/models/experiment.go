@@ -0,0 +1,29 @@
+package models
+
+import (
+    "time"
+)
+
+// ExperimentAssignment records which variant of an experimented pipeline step
+// processed the document and how it performed
+type ExperimentAssignment struct {
+    Experiment string    `json:"experiment"`
+    Step       string    `json:"step"`
+    Variant    string    `json:"variant"`
+    DurationMS int64     `json:"duration_ms"`
+    Failed     bool      `json:"failed"`
+    AssignedAt time.Time `json:"assigned_at"`
+}
+
+// RecordExperiment tags the document with the variant that processed it. A
+// document is recorded once per experiment; reprocessing replaces the record
+func (d *Document) RecordExperiment(assignment ExperimentAssignment) {
+    d.UpdatedAt = time.Now()
+    for i := range d.Experiments {
+        if d.Experiments[i].Experiment == assignment.Experiment {
+            d.Experiments[i] = assignment
+            return
+        }
+    }
+    d.Experiments = append(d.Experiments, assignment)
+}
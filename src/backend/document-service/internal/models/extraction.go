@@ -0,0 +1,53 @@
+package models
+
+import (
+    "time"
+)
+
+// ExtractedField is a structured value extracted from document content by a pipeline step
+type ExtractedField struct {
+    Name        string      `json:"name"`
+    Value       string      `json:"value"`
+    Confidence  float64     `json:"confidence"`
+    Source      string      `json:"source"`
+    ExtractedAt time.Time   `json:"extracted_at"`
+    Provenance  *Provenance `json:"provenance,omitempty"`
+}
+
+// SetExtractedFields replaces every field previously extracted by source so
+// reprocessing a document never accumulates stale values
+func (d *Document) SetExtractedFields(source string, fields []ExtractedField) {
+    kept := make([]ExtractedField, 0, len(d.ExtractedFields)+len(fields))
+    for _, field := range d.ExtractedFields {
+        if field.Source != source {
+            kept = append(kept, field)
+        }
+    }
+
+    now := time.Now()
+    for _, field := range fields {
+        field.Source = source
+        if field.ExtractedAt.IsZero() {
+            field.ExtractedAt = now
+        }
+        if field.Provenance == nil {
+            field.Provenance = d.producer
+        }
+        kept = append(kept, field)
+    }
+
+    d.ExtractedFields = kept
+    d.UpdatedAt = now
+    d.addAuditLog("EXTRACTION", d.Status, "Fields extracted by "+source, "SYSTEM")
+}
+
+// ExtractedValues returns every value extracted under the given field name
+func (d *Document) ExtractedValues(name string) []string {
+    values := make([]string, 0)
+    for _, field := range d.ExtractedFields {
+        if field.Name == name {
+            values = append(values, field.Value)
+        }
+    }
+    return values
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+    "time"
+)
+
+// UserIdentity is the end user the gateway authenticated, as stated by the
+// token the identity provider issued at sign-in
+type UserIdentity struct {
+    Subject   string    `json:"subject"`
+    TenantID  string    `json:"tenant_id,omitempty"`
+    Role      string    `json:"role,omitempty"`
+    SessionID string    `json:"session_id,omitempty"`
+    TokenID   string    `json:"token_id,omitempty"`
+    ExpiresAt time.Time `json:"expires_at"`
+}
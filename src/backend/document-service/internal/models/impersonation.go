@@ -0,0 +1,42 @@
+package models
+
+import (
+    "time"
+)
+
+// Impersonation session states
+const (
+    ImpersonationStatusActive  = "active"
+    ImpersonationStatusEnded   = "ended"
+    ImpersonationStatusExpired = "expired"
+)
+
+// ImpersonationSession is a time-boxed period in which a support operator
+// acts on behalf of a beneficiary, opened for a ticket with a justification
+type ImpersonationSession struct {
+    ID            string               `json:"id"`
+    OperatorID    string               `json:"operator_id"`
+    OperatorRole  string               `json:"operator_role"`
+    SubjectID     string               `json:"subject_id"`
+    SubjectRole   string               `json:"subject_role"`
+    Ticket        string               `json:"ticket"`
+    Justification string               `json:"justification"`
+    Status        string               `json:"status"`
+    StartedAt     time.Time            `json:"started_at"`
+    ExpiresAt     time.Time            `json:"expires_at"`
+    EndedAt       *time.Time           `json:"ended_at,omitempty"`
+    Actions       []ImpersonatedAction `json:"actions"`
+}
+
+// ImpersonatedAction is one request made during an impersonation session
+type ImpersonatedAction struct {
+    Method     string    `json:"method"`
+    Path       string    `json:"path"`
+    Status     int       `json:"status"`
+    OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Active reports whether the session may still be used at the given time
+func (s *ImpersonationSession) Active(at time.Time) bool {
+    return s.Status == ImpersonationStatusActive && at.Before(s.ExpiresAt)
+}
@@ -0,0 +1,142 @@
+// Package invariants codifies the business invariants of document metadata.
+// The repository checks them before persisting, the metadata repair fixes
+// what breaks them, and the tests hold the state machine to them
+package invariants
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Codes of the invariants a document can break
+const (
+	// MissingStoragePath is a stored document that does not say where its
+	// content is
+	MissingStoragePath = "missing_storage_path"
+	// MissingContentHash is a stored document without the digest of its
+	// content
+	MissingContentHash = "missing_content_hash"
+	// MissingEncryption is a stored document without the metadata its
+	// content was encrypted with
+	MissingEncryption = "missing_encryption_metadata"
+	// InvalidEncryption is encryption metadata of the document that is
+	// incomplete or names an unknown format
+	InvalidEncryption = "invalid_encryption_metadata"
+	// InvalidRenditionEncryption is encryption metadata of a rendition that
+	// is incomplete or names an unknown format
+	InvalidRenditionEncryption = "invalid_rendition_encryption_metadata"
+)
+
+var ErrViolated = errors.New("document invariant violated")
+
+// Violation is an invariant a document breaks
+type Violation struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// String returns the code of the violation and its detail
+func (v Violation) String() string {
+	if v.Detail == "" {
+		return v.Code
+	}
+	return v.Code + " (" + v.Detail + ")"
+}
+
+// Error is a document breaking invariants. It matches ErrViolated
+type Error struct {
+	DocumentID string
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	violations := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		violations[i] = violation.String()
+	}
+	return fmt.Sprintf("%v: document %s: %s", ErrViolated, e.DocumentID, strings.Join(violations, ", "))
+}
+
+func (e *Error) Unwrap() error {
+	return ErrViolated
+}
+
+// Check returns the invariants a document breaks:
+//
+//   - a stored document has a storage path, a content hash and encryption
+//     metadata
+//   - encryption metadata, of the document or a rendition, is complete and
+//     names a known format
+//
+// Shredded and anonymized content is gone for good, and so exempt. The
+// violations of the document come in the order they are repaired in, as a
+// repair reads the content through the earlier ones
+func Check(doc *models.Document) []Violation {
+	var violations []Violation
+	stored := doc.Stored()
+	if stored && doc.StoragePath == "" {
+		violations = append(violations, Violation{Code: MissingStoragePath})
+	}
+	if stored && doc.EncryptionInfo == nil {
+		violations = append(violations, Violation{Code: MissingEncryption})
+	} else if err := checkEncryption(doc.EncryptionInfo); err != nil {
+		violations = append(violations, Violation{Code: InvalidEncryption, Detail: err.Error()})
+	}
+	if stored && doc.ContentHash == "" {
+		violations = append(violations, Violation{Code: MissingContentHash})
+	}
+	for _, rendition := range doc.Renditions {
+		if err := checkEncryption(rendition.Encryption); err != nil {
+			violations = append(violations, Violation{Code: InvalidRenditionEncryption, Detail: rendition.Name + ": " + err.Error()})
+		}
+	}
+	return violations
+}
+
+// checkEncryption checks encryption metadata is complete and names a known
+// format. Missing and shredded metadata are not checked
+func checkEncryption(metadata *models.EncryptionMetadata) error {
+	if metadata == nil || metadata.Shredded() {
+		return nil
+	}
+	if metadata.KeyID == "" || metadata.Algorithm == "" || metadata.IV == "" || metadata.KeyVersion == "" {
+		return errors.New("required field is missing")
+	}
+	return metadata.ValidateFormat()
+}
+
+// Validate returns an *Error listing the invariants a document breaks, or
+// nil when it breaks none
+func Validate(doc *models.Document) error {
+	return violated(doc, Check(doc))
+}
+
+// ValidateChange returns an *Error listing the invariants a change from
+// previous to doc breaks that previous did not, or nil when it breaks no new
+// one. Documents stored before an invariant held keep their violations
+// through later changes, until repaired
+func ValidateChange(previous, doc *models.Document) error {
+	if previous == nil {
+		return Validate(doc)
+	}
+
+	known := Check(previous)
+	var introduced []Violation
+	for _, violation := range Check(doc) {
+		if !slices.Contains(known, violation) {
+			introduced = append(introduced, violation)
+		}
+	}
+	return violated(doc, introduced)
+}
+
+func violated(doc *models.Document, violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &Error{DocumentID: doc.ID, Violations: violations}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+    "time"
+)
+
+// Background jobs run by one instance at a time
+const (
+    JobOutbox         = "outbox"
+    JobSFTP           = "sftp"
+    JobAnalytics      = "analytics_export"
+    JobEncryptionScan = "encryption_scan"
+    JobRetention      = "retention"
+    JobKeyAudit       = "key_audit_retention"
+    JobSyntheticProbe = "synthetic_probe"
+    JobDeferredOCR    = "deferred_ocr"
+    JobSLA            = "sla"
+    JobLargeDocuments = "large_documents"
+    JobShadowBackfill = "shadow_backfill"
+)
+
+// JobLease records which instance holds the lock of a background job.
+// Generation grows every time the lock is acquired, so a holder that lost
+// its lock can tell its lease was taken over
+type JobLease struct {
+    Job        string    `json:"job"`
+    Owner      string    `json:"owner"`
+    Generation int64     `json:"generation"`
+    AcquiredAt time.Time `json:"acquired_at"`
+    RenewedAt  time.Time `json:"renewed_at"`
+}
+
+// JobStatus is a background job as seen by one instance
+type JobStatus struct {
+    Job string `json:"job"`
+    // Owner is the instance holding the job's lock, empty when none does
+    Owner      string     `json:"owner,omitempty"`
+    AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+    RenewedAt  *time.Time `json:"renewed_at,omitempty"`
+    // Local is set when this instance holds the lock and runs the job
+    Local bool `json:"local"`
+    // Stale is set when the owner stopped renewing the lease, as when it
+    // died without releasing it; the lock itself is already free then
+    Stale bool `json:"stale"`
+}
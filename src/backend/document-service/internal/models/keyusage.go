@@ -0,0 +1,80 @@
+package models
+
+import (
+    "time"
+)
+
+// Data key operations recorded in the key usage audit
+const (
+    KeyOperationEncrypt = "encrypt"
+    KeyOperationDecrypt = "decrypt"
+    KeyOperationWarm    = "warm"
+)
+
+// KMS calls a key use may have required; none is recorded when the data key
+// was served from the in-process cache
+const (
+    KMSOperationGenerateDataKey = "GenerateDataKey"
+    KMSOperationDecrypt         = "Decrypt"
+)
+
+// PrincipalSystem is recorded for key uses not made on behalf of a caller,
+// such as background processing and scheduled scans
+const PrincipalSystem = "system"
+
+// Anomalies raised by the key usage report
+const (
+    KeyAnomalyDecryptVolume  = "decrypt_volume"
+    KeyAnomalyDocumentSpread = "document_spread"
+    KeyAnomalyFailures       = "failures"
+    KeyAnomalyKMSCallVolume  = "kms_call_volume"
+)
+
+// KeyUsageEvent records one use of a data key: which principal used which key
+// version for which document, and the KMS call it required, if any
+type KeyUsageEvent struct {
+    ID           string    `json:"id"`
+    DocumentID   string    `json:"document_id,omitempty"`
+    Principal    string    `json:"principal"`
+    Operation    string    `json:"operation"`
+    KMSOperation string    `json:"kms_operation,omitempty"`
+    KeyID        string    `json:"key_id"`
+    KeyVersion   string    `json:"key_version"`
+    GrantID      string    `json:"grant_id,omitempty"`
+    Succeeded    bool      `json:"succeeded"`
+    Error        string    `json:"error,omitempty"`
+    OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// KeyVersionUsage aggregates the uses of one key version over a report period
+type KeyVersionUsage struct {
+    KeyID      string         `json:"key_id"`
+    KeyVersion string         `json:"key_version"`
+    Operations map[string]int `json:"operations"`
+    KMSCalls   map[string]int `json:"kms_calls"`
+    Failures   int            `json:"failures"`
+    Documents  int            `json:"documents"`
+    Principals int            `json:"principals"`
+    Grants     int            `json:"grants"`
+}
+
+// KeyUsageAnomaly is an hour in which usage exceeded a configured threshold.
+// Principal is set for per-principal anomalies, KeyVersion for per-key ones
+type KeyUsageAnomaly struct {
+    Type       string    `json:"type"`
+    Principal  string    `json:"principal,omitempty"`
+    KeyVersion string    `json:"key_version,omitempty"`
+    Hour       time.Time `json:"hour"`
+    Count      int       `json:"count"`
+    Threshold  int       `json:"threshold"`
+}
+
+// KeyUsageReport summarizes key usage per key version over a period
+type KeyUsageReport struct {
+    From        time.Time         `json:"from"`
+    To          time.Time         `json:"to"`
+    GeneratedAt time.Time         `json:"generated_at"`
+    Events      int               `json:"events"`
+    KeyVersions []KeyVersionUsage `json:"key_versions"`
+    Anomalies   []KeyUsageAnomaly `json:"anomalies"`
+}
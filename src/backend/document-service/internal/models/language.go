@@ -0,0 +1,41 @@
+package models
+
+import (
+    "fmt"
+    "time"
+)
+
+// DocumentLanguage is the language detected in the text of a document
+type DocumentLanguage struct {
+    // Code is the ISO 639-1 code of the language
+    Code       string  `json:"code"`
+    Confidence float64 `json:"confidence"`
+}
+
+// MachineTranslation labels text translated by a machine translation
+// provider rather than a person; it is for reviewer convenience and is never
+// a certified translation
+type MachineTranslation struct {
+    Provider       string    `json:"provider"`
+    SourceLanguage string    `json:"source_language"`
+    TargetLanguage string    `json:"target_language"`
+    TranslatedAt   time.Time `json:"translated_at"`
+    // Truncated is set when only the start of a long text was translated
+    Truncated bool `json:"truncated,omitempty"`
+}
+
+// SetLanguage records the language detected in the text of a document
+func (d *Document) SetLanguage(language DocumentLanguage) {
+    d.Language = &language
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("LANGUAGE_DETECTION", d.Status,
+        fmt.Sprintf("Language detected: %s (confidence %.2f)", language.Code, language.Confidence), "SYSTEM")
+}
+
+// SetTranslation records the machine translation of the text of a document
+func (d *Document) SetTranslation(translation MachineTranslation) {
+    d.Translation = &translation
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("TRANSLATION", d.Status,
+        fmt.Sprintf("Text machine translated from %s to %s by %s", translation.SourceLanguage, translation.TargetLanguage, translation.Provider), "SYSTEM")
+}
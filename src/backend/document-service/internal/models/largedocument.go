@@ -0,0 +1,51 @@
+package models
+
+import (
+    "slices"
+    "time"
+)
+
+// Capabilities of a document that wait on its processing steps
+const (
+    // CapabilityPreview is the page preview of the secure viewer
+    CapabilityPreview = "preview"
+    // CapabilitySearch is finding the document by its text
+    CapabilitySearch = "search"
+    // CapabilityText is the text and fields extracted from the document
+    CapabilityText = "text"
+)
+
+// LargeDocumentCapabilities are what a large document goes without until
+// its processing steps ran in the background
+var LargeDocumentCapabilities = []string{CapabilityPreview, CapabilitySearch, CapabilityText}
+
+// LargeDocument records the acceptance of a document past the size limit of
+// its channel
+type LargeDocument struct {
+    // Limit is the size limit of the channel the document exceeded
+    Limit int64 `json:"limit"`
+    // DelayedCapabilities are unavailable until the document is processed
+    DelayedCapabilities []string  `json:"delayed_capabilities,omitempty"`
+    AcceptedAt          time.Time `json:"accepted_at"`
+}
+
+// NewLargeDocument creates a document of more than limit bytes, the size
+// limit of its channel, whose delayed capabilities wait on its processing
+func NewLargeDocument(enrollmentID, documentType, filename, contentType string, size, limit int64, delayed []string) (*Document, error) {
+    doc, err := newDocument(enrollmentID, documentType, filename, contentType, size, MaxLargeDocumentSize)
+    if err != nil {
+        return nil, err
+    }
+    doc.LargeDocument = &LargeDocument{
+        Limit:               limit,
+        DelayedCapabilities: slices.Clone(delayed),
+        AcceptedAt:          doc.CreatedAt,
+    }
+    return doc, nil
+}
+
+// Delayed reports whether a capability of the document waits on processing
+// that has not run yet
+func (d *Document) Delayed(capability string) bool {
+    return d.LargeDocument != nil && d.ProcessedAt == nil && slices.Contains(d.LargeDocument.DelayedCapabilities, capability)
+}
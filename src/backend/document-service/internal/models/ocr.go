@@ -0,0 +1,55 @@
+package models
+
+import (
+    "fmt"
+    "time"
+)
+
+// OCRPage records the OCR outcome of one page of a multi-page document
+type OCRPage struct {
+    Number    int    `json:"number"`
+    Succeeded bool   `json:"succeeded"`
+    Error     string `json:"error,omitempty"`
+}
+
+// OCRLine is a line of recognized text and where it sits on its page. Box
+// holds the left, top, right and bottom edges as fractions of the page width
+// and height, measured from the top left corner; it is zero when OCR gave no
+// position
+type OCRLine struct {
+    Page int        `json:"page"`
+    Text string     `json:"text"`
+    Box  [4]float64 `json:"box"`
+}
+
+// SetOCRPages records per-page OCR outcomes and flags the document for manual
+// review when any page could not be read
+func (d *Document) SetOCRPages(pages []OCRPage) {
+    d.OCRPages = pages
+    d.UpdatedAt = time.Now()
+
+    failed := 0
+    for _, page := range pages {
+        if !page.Succeeded {
+            failed++
+        }
+    }
+
+    status := "COMPLETE"
+    if failed > 0 {
+        status = "PARTIAL"
+        d.AddReviewFlag(ReviewFlagOCRIncomplete)
+    }
+    d.addAuditLog("OCR_PAGES", status, fmt.Sprintf("%d of %d pages recognized", len(pages)-failed, len(pages)), "SYSTEM")
+}
+
+// FailedOCRPages returns the numbers of the pages OCR could not read
+func (d *Document) FailedOCRPages() []int {
+    failed := make([]int, 0)
+    for _, page := range d.OCRPages {
+        if !page.Succeeded {
+            failed = append(failed, page.Number)
+        }
+    }
+    return failed
+}
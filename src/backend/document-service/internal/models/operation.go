@@ -0,0 +1,207 @@
+package models
+
+import (
+    "slices"
+    "time"
+)
+
+// Bulk operation actions
+const (
+    BulkActionReprocess     = "reprocess"
+    BulkActionReencrypt     = "reencrypt"
+    BulkActionReclassify    = "reclassify"
+    BulkActionRecomputeHash = "recompute_hash"
+    BulkActionBackfill      = "backfill"
+)
+
+// Bulk operation statuses
+const (
+    BulkOperationRunning   = "running"
+    BulkOperationCompleted = "completed"
+    BulkOperationCancelled = "cancelled"
+)
+
+// Outcomes of a bulk operation on one document
+const (
+    BulkOutcomeSucceeded = "succeeded"
+    BulkOutcomeSkipped   = "skipped"
+    BulkOutcomeFailed    = "failed"
+)
+
+// maxBulkOperationFailures bounds the failures kept on an operation; the
+// Failed counter still counts every one
+const maxBulkOperationFailures = 100
+
+// BulkOperationFilter selects the documents of a bulk operation. Every
+// criterion set must match; list criteria match any of their values
+type BulkOperationFilter struct {
+    DocumentIDs   []string   `json:"document_ids,omitempty"`
+    EnrollmentID  string     `json:"enrollment_id,omitempty"`
+    TenantID      string     `json:"tenant_id,omitempty"`
+    DocumentTypes []string   `json:"document_types,omitempty"`
+    Statuses      []string   `json:"statuses,omitempty"`
+    Channel       string     `json:"channel,omitempty"`
+    CreatedFrom   *time.Time `json:"created_from,omitempty"`
+    CreatedTo     *time.Time `json:"created_to,omitempty"`
+}
+
+// Empty reports whether the filter sets no criterion, which would select
+// every document
+func (f BulkOperationFilter) Empty() bool {
+    return len(f.DocumentIDs) == 0 && f.EnrollmentID == "" && f.TenantID == "" && len(f.DocumentTypes) == 0 &&
+        len(f.Statuses) == 0 && f.Channel == "" && f.CreatedFrom == nil && f.CreatedTo == nil
+}
+
+// Matches reports whether the document meets every criterion of the filter
+func (f BulkOperationFilter) Matches(doc *Document) bool {
+    if len(f.DocumentIDs) > 0 && !slices.Contains(f.DocumentIDs, doc.ID) {
+        return false
+    }
+    if f.EnrollmentID != "" && doc.EnrollmentID != f.EnrollmentID {
+        return false
+    }
+    if f.TenantID != "" && doc.TenantID != f.TenantID {
+        return false
+    }
+    if len(f.DocumentTypes) > 0 && !slices.Contains(f.DocumentTypes, doc.DocumentType) {
+        return false
+    }
+    if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, doc.Status) {
+        return false
+    }
+    if f.Channel != "" && doc.IngestionChannel != f.Channel {
+        return false
+    }
+    if f.CreatedFrom != nil && doc.CreatedAt.Before(*f.CreatedFrom) {
+        return false
+    }
+    if f.CreatedTo != nil && !doc.CreatedAt.Before(*f.CreatedTo) {
+        return false
+    }
+    return true
+}
+
+// BulkOperationPosition places a document in the order operations process
+// documents in: by creation time, then by ID
+type BulkOperationPosition struct {
+    DocumentID string    `json:"document_id"`
+    CreatedAt  time.Time `json:"created_at"`
+}
+
+// PositionOf returns the position of a document
+func PositionOf(doc *Document) BulkOperationPosition {
+    return BulkOperationPosition{DocumentID: doc.ID, CreatedAt: doc.CreatedAt}
+}
+
+// Before reports whether the position comes before other
+func (p BulkOperationPosition) Before(other BulkOperationPosition) bool {
+    if !p.CreatedAt.Equal(other.CreatedAt) {
+        return p.CreatedAt.Before(other.CreatedAt)
+    }
+    return p.DocumentID < other.DocumentID
+}
+
+// BulkOperationFailure is a document the operation failed on
+type BulkOperationFailure struct {
+    DocumentID string `json:"document_id"`
+    Error      string `json:"error"`
+}
+
+// BulkOperation is an administrative action applied to the documents
+// matching a filter, throttled to Rate documents per second. A dry run only
+// resolves the documents: Total counts them and Sample lists the first ones
+type BulkOperation struct {
+    ID           string                 `json:"id"`
+    Action       string                 `json:"action"`
+    Filter       BulkOperationFilter    `json:"filter"`
+    // Steps limits a reprocess to the named pipeline steps
+    Steps        []string               `json:"steps,omitempty"`
+    // DocumentType is the type a reclassify assigns
+    DocumentType string                 `json:"document_type,omitempty"`
+    DryRun       bool                   `json:"dry_run"`
+    Rate         float64                `json:"rate"`
+    RequestedBy  string                 `json:"requested_by,omitempty"`
+    Status       string                 `json:"status"`
+    Total        int                    `json:"total"`
+    Processed    int                    `json:"processed"`
+    Succeeded    int                    `json:"succeeded"`
+    Skipped      int                    `json:"skipped"`
+    Failed       int                    `json:"failed"`
+    Failures     []BulkOperationFailure `json:"failures,omitempty"`
+    Sample       []string               `json:"sample,omitempty"`
+    // Checkpoint is the last document processed; a resumed operation
+    // continues after it
+    Checkpoint   *BulkOperationPosition `json:"checkpoint,omitempty"`
+    Error        string                 `json:"error,omitempty"`
+    CreatedAt    time.Time              `json:"created_at"`
+    UpdatedAt    time.Time              `json:"updated_at"`
+    FinishedAt   *time.Time             `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the operation stopped processing documents
+func (o *BulkOperation) Finished() bool {
+    return o.Status != BulkOperationRunning
+}
+
+// Resumable reports whether the operation stopped before processing every
+// document and may continue from its checkpoint
+func (o *BulkOperation) Resumable() bool {
+    return o.Status == BulkOperationCancelled && !o.DryRun
+}
+
+// Resume restarts a cancelled operation with the documents still to process
+func (o *BulkOperation) Resume(remaining int, at time.Time) {
+    o.Status = BulkOperationRunning
+    o.Total = o.Processed + remaining
+    o.Error = ""
+    o.UpdatedAt = at
+    o.FinishedAt = nil
+}
+
+// Record counts the outcome of the operation on one document
+func (o *BulkOperation) Record(documentID, outcome string, err error, at time.Time) {
+    o.Processed++
+    switch outcome {
+    case BulkOutcomeSucceeded:
+        o.Succeeded++
+    case BulkOutcomeSkipped:
+        o.Skipped++
+    case BulkOutcomeFailed:
+        o.Failed++
+        if len(o.Failures) < maxBulkOperationFailures {
+            failure := BulkOperationFailure{DocumentID: documentID}
+            if err != nil {
+                failure.Error = err.Error()
+            }
+            o.Failures = append(o.Failures, failure)
+        }
+    }
+    o.UpdatedAt = at
+}
+
+// Finish stops the operation with the given status
+func (o *BulkOperation) Finish(status, reason string, at time.Time) {
+    o.Status = status
+    o.Error = reason
+    o.UpdatedAt = at
+    o.FinishedAt = &at
+}
+
+// Reclassify changes the type of a document labelled with the wrong one
+func (d *Document) Reclassify(documentType, performer string) error {
+    if documentType == "" {
+        return ErrMissingField
+    }
+    previous := d.DocumentType
+    d.DocumentType = documentType
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("RECLASSIFY", d.Status, "Document type changed from "+previous+" to "+documentType, performer)
+    return nil
+}
+
+// SetContentHash records the SHA-256 digest of the plaintext content
+func (d *Document) SetContentHash(hash, performer string) {
+    d.ContentHash = hash
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("CONTENT_HASH", d.Status, "Content hash recomputed", performer)
+}
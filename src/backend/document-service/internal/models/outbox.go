@@ -0,0 +1,29 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// Outbox message status constants
+const (
+    OutboxStatusPending   = "pending"
+    OutboxStatusDelivered = "delivered"
+    OutboxStatusShadowed  = "shadowed"
+    OutboxStatusDead      = "dead"
+)
+
+// OutboxMessage is an integration event persisted alongside the state change
+// that produced it and delivered asynchronously with retries
+type OutboxMessage struct {
+    ID            string          `json:"id"`
+    Topic         string          `json:"topic"`
+    Key           string          `json:"key"`
+    Payload       json.RawMessage `json:"payload"`
+    Status        string          `json:"status"`
+    Attempts      int             `json:"attempts"`
+    LastError     string          `json:"last_error,omitempty"`
+    NextAttemptAt time.Time       `json:"next_attempt_at"`
+    CreatedAt     time.Time       `json:"created_at"`
+    DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Reasons a page is removed from the normalized PDF
+const (
+    PageRemovalBlank     = "blank"
+    PageRemovalDuplicate = "duplicate"
+)
+
+// RemovedPage is a page of the original upload left out of the normalized PDF
+type RemovedPage struct {
+    Number int    `json:"number"`
+    Reason string `json:"reason"`
+    // DuplicateOf is the earlier page a duplicate repeats
+    DuplicateOf int `json:"duplicate_of,omitempty"`
+}
+
+// RecordPageCleanup records the blank and duplicate pages removed from a
+// PDF of pageCount pages. The original upload keeps every page
+func (d *Document) RecordPageCleanup(pageCount int, removed []RemovedPage) {
+    d.RemovedPages = removed
+    d.UpdatedAt = time.Now()
+    if len(removed) == 0 {
+        return
+    }
+
+    descriptions := make([]string, len(removed))
+    for i, page := range removed {
+        if page.Reason == PageRemovalDuplicate {
+            descriptions[i] = fmt.Sprintf("page %d duplicates page %d", page.Number, page.DuplicateOf)
+        } else {
+            descriptions[i] = fmt.Sprintf("page %d is %s", page.Number, page.Reason)
+        }
+    }
+    d.addAuditLog("PAGE_CLEANUP", d.Status,
+        fmt.Sprintf("Removed %d of %d pages: %s", len(removed), pageCount, strings.Join(descriptions, "; ")), "SYSTEM")
+}
+
+// CurrentPDF returns the rendition reviewers and underwriting read in place
+// of the original upload: the spliced PDF once pages were rescanned or edited,
+// else the normalized PDF when blank or duplicate pages were removed
+func (d *Document) CurrentPDF() (Rendition, bool) {
+    if rendition, ok := d.Rendition(RenditionSplicedPDF); ok {
+        return rendition, true
+    }
+    return d.Rendition(RenditionNormalizedPDF)
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Page operations reviewers apply to fix a document without a new upload
+const (
+    PageOperationRotate  = "rotate"
+    PageOperationReorder = "reorder"
+    PageOperationRemove  = "remove"
+)
+
+var (
+    ErrInvalidPageOperation = errors.New("invalid page operation")
+    ErrNotEditable          = errors.New("document pages can only be changed while it awaits review")
+)
+
+// PageOperation is one change to the pages of a document. Page numbers
+// refer to the pages as left by the operations before it
+type PageOperation struct {
+    Operation string `json:"operation"`
+    // Pages are the pages rotated or removed
+    Pages []int `json:"pages,omitempty"`
+    // Degrees is the clockwise rotation, a multiple of 90
+    Degrees int `json:"degrees,omitempty"`
+    // Order lists every page in its new position
+    Order []int `json:"order,omitempty"`
+}
+
+// LayoutPage is a page of an edited document: the page of the document before
+// the edit it comes from and the rotation to apply to it
+type LayoutPage struct {
+    Source   int
+    Rotation int
+}
+
+// PageLayout applies operations to a document of pageCount pages, returning
+// its pages afterwards in order. A document cannot lose every page
+func PageLayout(pageCount int, operations []PageOperation) ([]LayoutPage, error) {
+    if len(operations) == 0 {
+        return nil, ErrMissingField
+    }
+    layout := make([]LayoutPage, pageCount)
+    for i := range layout {
+        layout[i].Source = i + 1
+    }
+
+    for i, operation := range operations {
+        var err error
+        switch operation.Operation {
+        case PageOperationRotate:
+            err = rotatePages(layout, operation.Pages, operation.Degrees)
+        case PageOperationReorder:
+            layout, err = reorderPages(layout, operation.Order)
+        case PageOperationRemove:
+            layout, err = removePages(layout, operation.Pages)
+        default:
+            err = fmt.Errorf("unknown operation %q", operation.Operation)
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%w %d: %v", ErrInvalidPageOperation, i+1, err)
+        }
+    }
+    return layout, nil
+}
+
+func rotatePages(layout []LayoutPage, pages []int, degrees int) error {
+    if degrees == 0 || degrees%90 != 0 {
+        return errors.New("rotation must be a non-zero multiple of 90 degrees")
+    }
+    numbers, err := pageSet(len(layout), pages)
+    if err != nil {
+        return err
+    }
+    for number := range numbers {
+        page := &layout[number-1]
+        page.Rotation = ((page.Rotation+degrees)%360 + 360) % 360
+    }
+    return nil
+}
+
+func reorderPages(layout []LayoutPage, order []int) ([]LayoutPage, error) {
+    if len(order) != len(layout) {
+        return nil, fmt.Errorf("order must list all %d pages", len(layout))
+    }
+    if _, err := pageSet(len(layout), order); err != nil {
+        return nil, err
+    }
+    reordered := make([]LayoutPage, len(order))
+    for i, number := range order {
+        reordered[i] = layout[number-1]
+    }
+    return reordered, nil
+}
+
+func removePages(layout []LayoutPage, pages []int) ([]LayoutPage, error) {
+    numbers, err := pageSet(len(layout), pages)
+    if err != nil {
+        return nil, err
+    }
+    if len(numbers) == len(layout) {
+        return nil, errors.New("cannot remove every page")
+    }
+    kept := make([]LayoutPage, 0, len(layout)-len(numbers))
+    for i, page := range layout {
+        if !numbers[i+1] {
+            kept = append(kept, page)
+        }
+    }
+    return kept, nil
+}
+
+// pageSet checks page numbers are in range and distinct
+func pageSet(pageCount int, pages []int) (map[int]bool, error) {
+    if len(pages) == 0 {
+        return nil, errors.New("no pages given")
+    }
+    numbers := make(map[int]bool, len(pages))
+    for _, number := range pages {
+        if number < 1 || number > pageCount || numbers[number] {
+            return nil, fmt.Errorf("%w: page %d", ErrInvalidPage, number)
+        }
+        numbers[number] = true
+    }
+    return numbers, nil
+}
+
+// RecordPageOperations records operations applied to the pages of a document
+// awaiting review. Page reviews follow their pages, so a moved page keeps its
+// decision and a removed page loses it
+func (d *Document) RecordPageOperations(operations []PageOperation, layout []LayoutPage, editedBy string) error {
+    if !d.AwaitingReview() {
+        return ErrNotEditable
+    }
+
+    if len(d.PageReviews) > 0 {
+        reviews := make([]PageReview, len(layout))
+        for i, page := range layout {
+            if page.Source <= len(d.PageReviews) {
+                reviews[i] = d.PageReviews[page.Source-1]
+            }
+            reviews[i].Number = i + 1
+        }
+        d.PageReviews = reviews
+    }
+
+    descriptions := make([]string, len(operations))
+    for i, operation := range operations {
+        descriptions[i] = operation.describe()
+    }
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("PAGE_OPERATIONS", d.Status, strings.Join(descriptions, "; "), editedBy)
+    return nil
+}
+
+func (o PageOperation) describe() string {
+    switch o.Operation {
+    case PageOperationRotate:
+        return fmt.Sprintf("rotated pages %v by %d degrees", o.Pages, o.Degrees)
+    case PageOperationReorder:
+        return fmt.Sprintf("reordered pages to %v", o.Order)
+    default:
+        return fmt.Sprintf("removed pages %v", o.Pages)
+    }
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
+// Page review statuses. Pages without a decision have no status
+const (
+    PageStatusApproved        = "approved"
+    PageStatusRescanRequested = "rescan_requested"
+    // PageStatusRescanned marks a replaced page awaiting review
+    PageStatusRescanned = "rescanned"
+)
+
+// Page review decisions
+const (
+    PageDecisionApprove = "approve"
+    PageDecisionRescan  = "rescan"
+)
+
+var (
+    ErrInvalidPage           = errors.New("page out of range")
+    ErrPageNotAwaitingRescan = errors.New("page is not awaiting a rescan")
+    ErrPagesPending          = errors.New("pages are awaiting review or rescan")
+)
+
+// PageReview is the review state of one page of a multi-page document
+type PageReview struct {
+    Number     int        `json:"number"`
+    Status     string     `json:"status,omitempty"`
+    Reason     string     `json:"reason,omitempty"`
+    ReviewedBy string     `json:"reviewed_by,omitempty"`
+    ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+    Rescans    int        `json:"rescans,omitempty"`
+    RescanHash string     `json:"rescan_hash,omitempty"`
+}
+
+// PageDecision is a reviewer decision on one page
+type PageDecision struct {
+    Number   int    `json:"number"`
+    Decision string `json:"decision"`
+    Reason   string `json:"reason,omitempty"`
+}
+
+// ReviewPages records reviewer decisions on pages of a processed document with
+// pageCount pages. The document is approved once every page is approved and
+// partially approved while any page awaits a decision or a rescan
+func (d *Document) ReviewPages(pageCount int, decisions []PageDecision, reviewer string) error {
+    if !d.reviewable() {
+        return ErrNotReviewable
+    }
+    if reviewer == "" || len(decisions) == 0 {
+        return ErrMissingField
+    }
+    seen := make(map[int]bool)
+    for _, decision := range decisions {
+        if decision.Number < 1 || decision.Number > pageCount || seen[decision.Number] {
+            return fmt.Errorf("%w: page %d", ErrInvalidPage, decision.Number)
+        }
+        seen[decision.Number] = true
+        switch decision.Decision {
+        case PageDecisionApprove:
+        case PageDecisionRescan:
+            if decision.Reason == "" {
+                return ErrMissingField
+            }
+        default:
+            return ErrInvalidDecision
+        }
+    }
+
+    if len(d.PageReviews) != pageCount {
+        d.PageReviews = make([]PageReview, pageCount)
+        for i := range d.PageReviews {
+            d.PageReviews[i].Number = i + 1
+        }
+    }
+
+    now := time.Now()
+    approved, rescan := make([]string, 0), make([]string, 0)
+    for _, decision := range decisions {
+        page := &d.PageReviews[decision.Number-1]
+        page.Reason = decision.Reason
+        page.ReviewedBy = reviewer
+        page.ReviewedAt = &now
+        if decision.Decision == PageDecisionApprove {
+            page.Status = PageStatusApproved
+            approved = append(approved, fmt.Sprint(decision.Number))
+        } else {
+            page.Status = PageStatusRescanRequested
+            rescan = append(rescan, fmt.Sprint(decision.Number))
+        }
+    }
+
+    d.UpdatedAt = now
+    d.ReviewedAt = &now
+    d.ReviewedBy = reviewer
+    d.Status = DocumentStatusPartiallyApproved
+    if len(d.PendingPages()) == 0 {
+        d.Status = DocumentStatusApproved
+    }
+    d.addAuditLog("PAGE_REVIEW", d.Status, fmt.Sprintf("approved pages [%s], rescan requested for pages [%s]", strings.Join(approved, ","), strings.Join(rescan, ",")), reviewer)
+    return nil
+}
+
+// RecordRescan records the replacement of a page awaiting a rescan; the page
+// then awaits review again
+func (d *Document) RecordRescan(number int, contentHash, uploadedBy string) error {
+    if number < 1 || number > len(d.PageReviews) {
+        return fmt.Errorf("%w: page %d", ErrInvalidPage, number)
+    }
+    page := &d.PageReviews[number-1]
+    if page.Status != PageStatusRescanRequested {
+        return ErrPageNotAwaitingRescan
+    }
+
+    page.Status = PageStatusRescanned
+    page.Rescans++
+    page.RescanHash = contentHash
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("PAGE_RESCAN", d.Status, fmt.Sprintf("page %d replaced", number), uploadedBy)
+    return nil
+}
+
+// PendingPages returns the numbers of the pages not yet approved, in order
+func (d *Document) PendingPages() []int {
+    pending := make([]int, 0)
+    for _, page := range d.PageReviews {
+        if page.Status != PageStatusApproved {
+            pending = append(pending, page.Number)
+        }
+    }
+    sort.Ints(pending)
+    return pending
+}
+
+// PagesAwaitingRescan returns the numbers of the pages a reviewer asked to be
+// scanned again
+func (d *Document) PagesAwaitingRescan() []int {
+    rescan := make([]int, 0)
+    for _, page := range d.PageReviews {
+        if page.Status == PageStatusRescanRequested {
+            rescan = append(rescan, page.Number)
+        }
+    }
+    return rescan
+}
+
+// ApprovedPages returns the number of approved pages
+func (d *Document) ApprovedPages() int {
+    return len(d.PageReviews) - len(d.PendingPages())
+}
+
+// reviewable reports whether the document has been processed and may receive
+// review decisions
+func (d *Document) reviewable() bool {
+    switch d.Status {
+    case DocumentStatusCompleted, DocumentStatusApproved, DocumentStatusRejected, DocumentStatusPartiallyApproved:
+        return true
+    }
+    return false
+}
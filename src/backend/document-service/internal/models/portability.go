@@ -0,0 +1,90 @@
+package models
+
+import (
+    "time"
+)
+
+// PortabilityManifestVersion is bumped whenever the manifest layout changes so
+// receiving controllers can parse older exports
+const PortabilityManifestVersion = "1"
+
+// PortabilityManifest is the machine-readable index of an LGPD portability
+// export, written as manifest.json at the root of the archive
+type PortabilityManifest struct {
+    Version     string                  `json:"version"`
+    ExportID    string                  `json:"export_id"`
+    SubjectCPF  string                  `json:"subject_cpf"`
+    GeneratedAt time.Time               `json:"generated_at"`
+    Enrollments []PortabilityEnrollment `json:"enrollments"`
+}
+
+// PortabilityEnrollment groups the exported documents of one enrollment
+type PortabilityEnrollment struct {
+    EnrollmentID string                `json:"enrollment_id"`
+    Status       string                `json:"status"`
+    Documents    []PortabilityDocument `json:"documents"`
+}
+
+// PortabilityDocument describes one document of the subject. Internal
+// identifiers such as storage paths and encryption keys are left out
+type PortabilityDocument struct {
+    DocumentID       string           `json:"document_id"`
+    DocumentType     string           `json:"document_type"`
+    Filename         string           `json:"filename"`
+    ContentType      string           `json:"content_type"`
+    Size             int64            `json:"size"`
+    Status           string           `json:"status"`
+    IngestionChannel string           `json:"ingestion_channel"`
+    CreatedAt        time.Time        `json:"created_at"`
+    ProcessedAt      *time.Time       `json:"processed_at,omitempty"`
+    ReviewedAt       *time.Time       `json:"reviewed_at,omitempty"`
+    ExtractedFields  []ExtractedField `json:"extracted_fields,omitempty"`
+    Signatures       []SignatureInfo  `json:"signatures,omitempty"`
+    // ArchivePath is the path of the original file inside the archive; empty
+    // when the content is no longer stored
+    ArchivePath string `json:"archive_path,omitempty"`
+    SHA256      string `json:"sha256,omitempty"`
+}
+
+// NewPortabilityDocument copies the subject-facing metadata of a document.
+// Pipeline provenance is internal and left out of the manifest
+func NewPortabilityDocument(doc *Document) PortabilityDocument {
+    var fields []ExtractedField
+    for _, field := range doc.ExtractedFields {
+        field.Provenance = nil
+        fields = append(fields, field)
+    }
+
+    return PortabilityDocument{
+        DocumentID:       doc.ID,
+        DocumentType:     doc.DocumentType,
+        Filename:         doc.Filename,
+        ContentType:      doc.ContentType,
+        Size:             doc.Size,
+        Status:           doc.Status,
+        IngestionChannel: doc.IngestionChannel,
+        CreatedAt:        doc.CreatedAt,
+        ProcessedAt:      doc.ProcessedAt,
+        ReviewedAt:       doc.ReviewedAt,
+        ExtractedFields:  fields,
+        Signatures:       doc.Signatures,
+    }
+}
+
+// PortabilityExport records a stored export archive. It is kept next to the
+// archive so the download link can be served by any replica
+type PortabilityExport struct {
+    ID            string              `json:"id"`
+    StoragePath   string              `json:"storage_path"`
+    Size          int64               `json:"size"`
+    Encryption    *EncryptionMetadata `json:"encryption"`
+    DocumentCount int                 `json:"document_count"`
+    RequestedBy   string              `json:"requested_by"`
+    CreatedAt     time.Time           `json:"created_at"`
+    ExpiresAt     time.Time           `json:"expires_at"`
+}
+
+// Expired reports whether the export's download link has lapsed
+func (e *PortabilityExport) Expired(now time.Time) bool {
+    return !now.Before(e.ExpiresAt)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+    "time"
+)
+
+// Synthetic probe stages, in the order they run
+const (
+    ProbeStageUpload     = "upload"
+    ProbeStageProcessing = "processing"
+    ProbeStageDownload   = "download"
+    ProbeStageDelete     = "delete"
+)
+
+// ProbeStage is how one stage of a synthetic probe went
+type ProbeStage struct {
+    Name       string `json:"name"`
+    DurationMS int64  `json:"duration_ms"`
+    Error      string `json:"error,omitempty"`
+}
+
+// ProbeResult reports a synthetic probe: a known document uploaded, processed,
+// downloaded, verified and deleted. FailedStage is the first stage that
+// failed; the delete stage runs whatever failed before it
+type ProbeResult struct {
+    DocumentID  string       `json:"document_id,omitempty"`
+    Passed      bool         `json:"passed"`
+    FailedStage string       `json:"failed_stage,omitempty"`
+    Stages      []ProbeStage `json:"stages"`
+    DurationMS  int64        `json:"duration_ms"`
+    StartedAt   time.Time    `json:"started_at"`
+    FinishedAt  time.Time    `json:"finished_at"`
+}
+
+// Record adds the outcome of a stage
+func (r *ProbeResult) Record(stage string, duration time.Duration, err error) {
+    outcome := ProbeStage{Name: stage, DurationMS: duration.Milliseconds()}
+    if err != nil {
+        outcome.Error = err.Error()
+        if r.FailedStage == "" {
+            r.FailedStage = stage
+        }
+    }
+    r.Stages = append(r.Stages, outcome)
+}
+
+// Finish completes the probe; it passed when no stage failed
+func (r *ProbeResult) Finish(at time.Time) {
+    r.Passed = r.FailedStage == ""
+    r.FinishedAt = at
+    r.DurationMS = at.Sub(r.StartedAt).Milliseconds()
+}
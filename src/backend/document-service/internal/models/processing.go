@@ -0,0 +1,107 @@
+package models
+
+import (
+    "strconv"
+    "time"
+)
+
+// Processing activity categories reported in the records of processing
+// activities (ROPA)
+const (
+    ProcessingCategoryOCR                   = "ocr"
+    ProcessingCategoryClassification        = "classification"
+    ProcessingCategoryFaceMatch             = "face_match"
+    ProcessingCategoryScreening             = "screening"
+    ProcessingCategoryIdentityVerification  = "identity_verification"
+    ProcessingCategorySignatureVerification = "signature_verification"
+    ProcessingCategoryAddressValidation     = "address_validation"
+    ProcessingCategoryAutomatedDecision     = "automated_decision"
+)
+
+// Processing activity outcomes
+const (
+    ProcessingOutcomeSucceeded = "succeeded"
+    ProcessingOutcomeFailed    = "failed"
+)
+
+// ProcessingActivity records one processing operation performed on a document,
+// with the purpose and LGPD legal basis it was performed under
+type ProcessingActivity struct {
+    Operation   string    `json:"operation"`
+    Category    string    `json:"category"`
+    Purpose     string    `json:"purpose"`
+    LegalBasis  string    `json:"legal_basis"`
+    Outcome     string    `json:"outcome"`
+    PerformedAt time.Time `json:"performed_at"`
+}
+
+// RecordProcessing appends a processing activity to the document. Activities
+// are kept for the records of processing activities rather than the audit trail,
+// which is reserved for state changes
+func (d *Document) RecordProcessing(activity ProcessingActivity) {
+    if activity.PerformedAt.IsZero() {
+        activity.PerformedAt = time.Now()
+    }
+    d.ProcessingActivities = append(d.ProcessingActivities, activity)
+    d.UpdatedAt = activity.PerformedAt
+}
+
+// Processed reports whether the operation succeeded on the document at least
+// once
+func (d *Document) Processed(operation string) bool {
+    for _, activity := range d.ProcessingActivities {
+        if activity.Operation == operation && activity.Outcome == ProcessingOutcomeSucceeded {
+            return true
+        }
+    }
+    return false
+}
+
+// ROPAReport aggregates the processing activities performed in a period, as
+// kept by the controller under LGPD art. 37
+type ROPAReport struct {
+    Controller  string      `json:"controller,omitempty"`
+    From        time.Time   `json:"from"`
+    To          time.Time   `json:"to"`
+    GeneratedAt time.Time   `json:"generated_at"`
+    Activities  []ROPAEntry `json:"activities"`
+}
+
+// ROPAEntry is one line of the report: a processing operation performed for a
+// purpose under a legal basis on one document type
+type ROPAEntry struct {
+    Category         string    `json:"category"`
+    Operation        string    `json:"operation"`
+    Purpose          string    `json:"purpose"`
+    LegalBasis       string    `json:"legal_basis"`
+    DocumentType     string    `json:"document_type"`
+    Documents        int       `json:"documents"`
+    Operations       int       `json:"operations"`
+    Failed           int       `json:"failed"`
+    FirstPerformedAt time.Time `json:"first_performed_at"`
+    LastPerformedAt  time.Time `json:"last_performed_at"`
+}
+
+// ROPAHeader returns the CSV header of the report
+func ROPAHeader() []string {
+    return []string{
+        "category", "operation", "purpose", "legal_basis", "document_type",
+        "documents", "operations", "failed", "first_performed_at", "last_performed_at",
+    }
+}
+
+// CSVRow renders the entry in ROPAHeader order
+func (e ROPAEntry) CSVRow() []string {
+    return []string{
+        e.Category,
+        e.Operation,
+        e.Purpose,
+        e.LegalBasis,
+        e.DocumentType,
+        strconv.Itoa(e.Documents),
+        strconv.Itoa(e.Operations),
+        strconv.Itoa(e.Failed),
+        e.FirstPerformedAt.UTC().Format(time.RFC3339),
+        e.LastPerformedAt.UTC().Format(time.RFC3339),
+    }
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+    "time"
+)
+
+// Kinds of artifact carrying provenance
+const (
+    ArtifactRendition      = "rendition"
+    ArtifactExtractedField = "extracted_field"
+)
+
+// Provenance records what produced an artifact: the pipeline release, the
+// step and its implementation version, the external provider and model
+// consulted, the experiment variant that ran, and the provenance of the
+// earlier artifacts the step read
+type Provenance struct {
+    PipelineVersion string       `json:"pipeline_version"`
+    Step            string       `json:"step"`
+    StepVersion     string       `json:"step_version,omitempty"`
+    Provider        string       `json:"provider,omitempty"`
+    Model           string       `json:"model,omitempty"`
+    Variant         string       `json:"variant,omitempty"`
+    Inputs          []Provenance `json:"inputs,omitempty"`
+    // PageOperations are the reviewer edits that produced a PDF
+    PageOperations []PageOperation `json:"page_operations,omitempty"`
+    ProducedAt     time.Time       `json:"produced_at"`
+}
+
+// ArtifactProvenance is the provenance of one rendition or extracted field.
+// Field values are not included; Index tells fields of the same name apart
+type ArtifactProvenance struct {
+    Kind       string      `json:"kind"`
+    Name       string      `json:"name"`
+    Index      int         `json:"index"`
+    Source     string      `json:"source,omitempty"`
+    CreatedAt  time.Time   `json:"created_at"`
+    Provenance *Provenance `json:"provenance"`
+}
+
+// SetProducer attributes the renditions and extracted fields recorded on the
+// document to p until it is cleared with nil. It is not persisted
+func (d *Document) SetProducer(p *Provenance) {
+    d.producer = p
+}
+
+// Producer returns the provenance currently attributed to new artifacts
+func (d *Document) Producer() *Provenance {
+    return d.producer
+}
+
+// ArtifactProvenance lists the provenance of the document's renditions and
+// extracted fields, optionally only those with the given name. Artifacts
+// produced before provenance was recorded have none
+func (d *Document) ArtifactProvenance(name string) []ArtifactProvenance {
+    artifacts := make([]ArtifactProvenance, 0, len(d.Renditions)+len(d.ExtractedFields))
+    for _, rendition := range d.Renditions {
+        if name != "" && rendition.Name != name {
+            continue
+        }
+        artifacts = append(artifacts, ArtifactProvenance{
+            Kind:       ArtifactRendition,
+            Name:       rendition.Name,
+            CreatedAt:  rendition.CreatedAt,
+            Provenance: rendition.Provenance,
+        })
+    }
+
+    seen := make(map[string]int)
+    for _, field := range d.ExtractedFields {
+        index := seen[field.Name]
+        seen[field.Name]++
+        if name != "" && field.Name != name {
+            continue
+        }
+        artifacts = append(artifacts, ArtifactProvenance{
+            Kind:       ArtifactExtractedField,
+            Name:       field.Name,
+            Index:      index,
+            Source:     field.Source,
+            CreatedAt:  field.ExtractedAt,
+            Provenance: field.Provenance,
+        })
+    }
+    return artifacts
+}
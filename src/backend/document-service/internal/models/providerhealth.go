@@ -0,0 +1,124 @@
+package models
+
+import (
+    "sort"
+    "time"
+)
+
+// healthBuckets is the number of buckets a health window is split into;
+// calls age out of the window a bucket at a time
+const healthBuckets = 10
+
+// HealthWindow counts the outcomes of the calls to a provider over a rolling
+// window. It is not safe for concurrent use
+type HealthWindow struct {
+    bucket  time.Duration
+    buckets [healthBuckets]healthBucket
+}
+
+// healthBucket counts the calls started in one slice of a health window
+type healthBucket struct {
+    start     time.Time
+    successes int
+    failures  int
+    latency   time.Duration
+}
+
+// NewHealthWindow creates an empty window over the given span
+func NewHealthWindow(window time.Duration) *HealthWindow {
+    return &HealthWindow{bucket: max(window/healthBuckets, time.Millisecond)}
+}
+
+// Record counts a call made at a moment; the latency of failed calls is not
+// counted, as timeouts would dwarf it
+func (w *HealthWindow) Record(at time.Time, latency time.Duration, failed bool) {
+    start := at.Truncate(w.bucket)
+    b := &w.buckets[(start.UnixNano()/int64(w.bucket))%healthBuckets]
+    if !b.start.Equal(start) {
+        if b.start.After(start) {
+            // The slot already holds a later bucket
+            return
+        }
+        *b = healthBucket{start: start}
+    }
+
+    if failed {
+        b.failures++
+        return
+    }
+    b.successes++
+    b.latency += latency
+}
+
+// Totals returns the calls that succeeded and failed in the window ending at
+// now, and the mean latency of those that succeeded
+func (w *HealthWindow) Totals(now time.Time) (successes, failures int, meanLatency time.Duration) {
+    oldest := now.Truncate(w.bucket).Add(-w.bucket * (healthBuckets - 1))
+    var latency time.Duration
+    for _, b := range w.buckets {
+        if b.start.Before(oldest) || b.start.After(now) {
+            continue
+        }
+        successes += b.successes
+        failures += b.failures
+        latency += b.latency
+    }
+    if successes > 0 {
+        meanLatency = latency / time.Duration(successes)
+    }
+    return successes, failures, meanLatency
+}
+
+// ProviderHealth is the score of a provider on its recent calls
+type ProviderHealth struct {
+    Kind          string  `json:"kind"`
+    Provider      string  `json:"provider"`
+    Calls         int     `json:"calls"`
+    SuccessRate   float64 `json:"success_rate"`
+    MeanLatencyMs int64   `json:"mean_latency_ms"`
+    // Score is the success rate scaled down by how far the mean latency
+    // exceeds the target; 1 while the provider has too few calls to score
+    Score   float64 `json:"score"`
+    Healthy bool    `json:"healthy"`
+    // Rank orders the providers of a kind from 1, the one new work goes to
+    Rank int `json:"rank"`
+}
+
+// NewProviderHealth scores a provider on the calls in its window at now
+func NewProviderHealth(kind, provider string, window *HealthWindow, target time.Duration, minSamples int, healthyScore float64, now time.Time) ProviderHealth {
+    successes, failures, latency := window.Totals(now)
+    health := ProviderHealth{
+        Kind:          kind,
+        Provider:      provider,
+        Calls:         successes + failures,
+        SuccessRate:   1,
+        MeanLatencyMs: latency.Milliseconds(),
+        Score:         1,
+    }
+    if health.Calls > 0 {
+        health.SuccessRate = float64(successes) / float64(health.Calls)
+    }
+    if health.Calls >= minSamples {
+        health.Score = health.SuccessRate
+        if latency > target {
+            health.Score *= float64(target) / float64(latency)
+        }
+    }
+    health.Healthy = health.Score >= healthyScore
+    return health
+}
+
+// RankProviders orders the providers of a kind, given in order of
+// preference, by where new work goes: the healthy ones in order of
+// preference, then the unhealthy ones best score first
+func RankProviders(providers []ProviderHealth) {
+    sort.SliceStable(providers, func(i, j int) bool {
+        if providers[i].Healthy != providers[j].Healthy {
+            return providers[i].Healthy
+        }
+        return !providers[i].Healthy && providers[i].Score > providers[j].Score
+    })
+    for i := range providers {
+        providers[i].Rank = i + 1
+    }
+}
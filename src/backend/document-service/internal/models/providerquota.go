@@ -0,0 +1,114 @@
+package models
+
+import (
+    "math"
+    "time"
+)
+
+// States of a provider quota
+const (
+    // ProviderQuotaOK is a quota under its throttling share
+    ProviderQuotaOK = "ok"
+    // ProviderQuotaPaced is a quota past its throttling share, whose rest is
+    // spread to the end of the month
+    ProviderQuotaPaced = "paced"
+    // ProviderQuotaThrottled is a provider throttling us, until it allows
+    // calls again
+    ProviderQuotaThrottled = "throttled"
+    // ProviderQuotaExhausted is a monthly quota used up
+    ProviderQuotaExhausted = "exhausted"
+)
+
+// ProviderQuotaLimits is the quota of a provider; zero limits don't apply
+type ProviderQuotaLimits struct {
+    TransactionsPerSecond float64 `json:"transactions_per_second"`
+    MonthlyTransactions   int64   `json:"monthly_transactions"`
+}
+
+// ProviderQuotaStatus is the consumption of a provider's quota in a month
+type ProviderQuotaStatus struct {
+    Provider string              `json:"provider"`
+    Limits   ProviderQuotaLimits `json:"limits"`
+    Month    string              `json:"month"`
+    Used     int64               `json:"used"`
+    // UsedRatio is the share of the monthly quota used, zero without one
+    UsedRatio float64 `json:"used_ratio"`
+    // PacedTransactionsPerSecond is the rate calls are held to, zero when
+    // unlimited or exhausted
+    PacedTransactionsPerSecond float64    `json:"paced_transactions_per_second"`
+    ThrottledUntil             *time.Time `json:"throttled_until,omitempty"`
+    State                      string     `json:"state"`
+    // Active is set on the provider calls are currently sent to
+    Active bool `json:"active"`
+}
+
+// QuotaMonth returns the calendar month, in UTC, monthly quotas of t count
+// against
+func QuotaMonth(t time.Time) string {
+    return t.UTC().Format("2006-01")
+}
+
+// QuotaMonthEnd returns when the monthly quota of t resets
+func QuotaMonthEnd(t time.Time) time.Time {
+    y, m, _ := t.UTC().Date()
+    return time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// UsedRatio returns the share of the monthly quota used, zero without a
+// monthly quota
+func (l ProviderQuotaLimits) UsedRatio(used int64) float64 {
+    if l.MonthlyTransactions <= 0 {
+        return 0
+    }
+    return float64(used) / float64(l.MonthlyTransactions)
+}
+
+// Exhausted reports whether the monthly quota is used up
+func (l ProviderQuotaLimits) Exhausted(used int64) bool {
+    return l.MonthlyTransactions > 0 && used >= l.MonthlyTransactions
+}
+
+// Pace returns the transactions per second calls are held to at now: the
+// per-second limit and, once throttleAt of the monthly quota is used, no
+// faster than spreads the rest evenly to the end of the month. Zero means
+// unlimited, unless the monthly quota is exhausted
+func (l ProviderQuotaLimits) Pace(used int64, throttleAt float64, now time.Time) float64 {
+    pace := l.TransactionsPerSecond
+    if l.MonthlyTransactions <= 0 || l.UsedRatio(used) < throttleAt {
+        return pace
+    }
+
+    remaining := float64(max(l.MonthlyTransactions-used, 0))
+    seconds := QuotaMonthEnd(now).Sub(now).Seconds()
+    spread := remaining / math.Max(seconds, 1)
+    if pace == 0 || spread < pace {
+        return spread
+    }
+    return pace
+}
+
+// NewProviderQuotaStatus reports the consumption of a provider's quota at
+// now, throttled by the provider until throttledUntil
+func NewProviderQuotaStatus(provider string, limits ProviderQuotaLimits, used int64, throttleAt float64, throttledUntil, now time.Time) ProviderQuotaStatus {
+    status := ProviderQuotaStatus{
+        Provider:                   provider,
+        Limits:                     limits,
+        Month:                      QuotaMonth(now),
+        Used:                       used,
+        UsedRatio:                  limits.UsedRatio(used),
+        PacedTransactionsPerSecond: limits.Pace(used, throttleAt, now),
+        State:                      ProviderQuotaOK,
+    }
+
+    switch {
+    case limits.Exhausted(used):
+        status.State = ProviderQuotaExhausted
+    case throttledUntil.After(now):
+        until := throttledUntil
+        status.ThrottledUntil = &until
+        status.State = ProviderQuotaThrottled
+    case limits.MonthlyTransactions > 0 && status.UsedRatio >= throttleAt:
+        status.State = ProviderQuotaPaced
+    }
+    return status
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+    "errors"
+    "slices"
+    "time"
+)
+
+// Reasons a file is quarantined
+const (
+    QuarantineReasonMalware       = "malware"
+    QuarantineReasonActiveContent = "active_content"
+    QuarantineReasonFraud         = "fraud"
+)
+
+// Quarantine item statuses
+const (
+    QuarantineStatusHeld      = "held"
+    QuarantineStatusReleased  = "released"
+    QuarantineStatusDestroyed = "destroyed"
+)
+
+// Actions recorded on a quarantine item
+const (
+    QuarantineActionHold    = "hold"
+    QuarantineActionFetch   = "fetch"
+    QuarantineActionRelease = "release"
+    QuarantineActionDestroy = "destroy"
+)
+
+// ErrQuarantineResolved is returned when releasing or destroying an item
+// already released or destroyed
+var ErrQuarantineResolved = errors.New("quarantine item is already resolved")
+
+// QuarantineItem is a suspicious file withheld from everyone until a
+// reviewer releases or destroys it. Malware is an upload refused as a
+// document and stored on its own; active content is the quarantined original
+// of a disarmed document; fraud is the content of a flagged document
+type QuarantineItem struct {
+    ID     string `json:"id"`
+    Reason string `json:"reason"`
+    Status string `json:"status"`
+    // Detail is the malware signature, the kinds of active content found or
+    // the grounds given for suspecting fraud
+    Detail string `json:"detail"`
+    // DocumentID is the document the file belongs to; a malware upload gets
+    // one only once released
+    DocumentID   string `json:"document_id,omitempty"`
+    EnrollmentID string `json:"enrollment_id"`
+    TenantID     string `json:"tenant_id,omitempty"`
+    DocumentType string `json:"document_type,omitempty"`
+    Filename     string `json:"filename"`
+    ContentType  string `json:"content_type"`
+    Channel      string `json:"channel,omitempty"`
+    SubmittedBy  string `json:"submitted_by,omitempty"`
+    Size         int64  `json:"size"`
+    // StoragePath and Encryption locate a malware upload, which has no
+    // document to be stored with
+    StoragePath   string              `json:"storage_path,omitempty"`
+    Encryption    *EncryptionMetadata `json:"encryption,omitempty"`
+    QuarantinedAt time.Time           `json:"quarantined_at"`
+    ResolvedAt    *time.Time          `json:"resolved_at,omitempty"`
+    History       []QuarantineAction  `json:"history"`
+}
+
+// QuarantineAction is one action taken on a quarantine item: by whom, under
+// which credential and why
+type QuarantineAction struct {
+    Action    string    `json:"action"`
+    Principal string    `json:"principal"`
+    Reviewer  string    `json:"reviewer,omitempty"`
+    Reason    string    `json:"reason,omitempty"`
+    At        time.Time `json:"at"`
+}
+
+// Held reports whether the item awaits review
+func (q *QuarantineItem) Held() bool {
+    return q.Status == QuarantineStatusHeld
+}
+
+// Record adds an action to the history of the item
+func (q *QuarantineItem) Record(action QuarantineAction) {
+    q.History = append(q.History, action)
+}
+
+// Resolve releases or destroys a held item
+func (q *QuarantineItem) Resolve(status string, action QuarantineAction) error {
+    if !q.Held() {
+        return ErrQuarantineResolved
+    }
+    q.Status = status
+    q.ResolvedAt = &action.At
+    q.Record(action)
+    return nil
+}
+
+// Quarantine withholds the content and renditions of the document while the
+// quarantine item holds it
+func (d *Document) Quarantine(itemID, reason, performer string) {
+    d.QuarantineID = itemID
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("QUARANTINE", d.Status, "Quarantined as suspected fraud: "+reason, performer)
+}
+
+// LiftQuarantine serves the document again once its quarantine item is
+// released or destroyed
+func (d *Document) LiftQuarantine(resolution, reason, performer string) {
+    d.QuarantineID = ""
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("QUARANTINE_"+resolution, d.Status, reason, performer)
+}
+
+// Quarantined reports whether the document is withheld pending review
+func (d *Document) Quarantined() bool {
+    return d.QuarantineID != ""
+}
+
+// ReleaseQuarantinedOriginal records that the quarantined original of a
+// disarmed document was cleared and stored as its original rendition
+func (d *Document) ReleaseQuarantinedOriginal(reason, performer string) {
+    d.dropQuarantinedOriginal()
+    d.addAuditLog("QUARANTINE_RELEASED", d.Status, "Quarantined original released: "+reason, performer)
+}
+
+// DestroyQuarantinedOriginal records that the quarantined original of a
+// disarmed document was deleted
+func (d *Document) DestroyQuarantinedOriginal(reason, performer string) {
+    d.dropQuarantinedOriginal()
+    d.addAuditLog("QUARANTINE_DESTROYED", d.Status, "Quarantined original destroyed: "+reason, performer)
+}
+
+func (d *Document) dropQuarantinedOriginal() {
+    d.Renditions = slices.DeleteFunc(d.Renditions, func(rendition Rendition) bool {
+        return rendition.Name == RenditionQuarantinedOriginal
+    })
+    d.UpdatedAt = time.Now()
+}
+
+// RecordRelease records that the document was made from an upload released
+// from quarantine, which the reviewer's verdict let past the virus scanner
+func (d *Document) RecordRelease(itemID string) {
+    d.addAuditLog("QUARANTINE_RELEASED", d.Status, "Made from upload released from quarantine item "+itemID, "SYSTEM")
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+    "math"
+    "time"
+)
+
+// QuotaLimits is the soft quota of a tenant: Requests per Window, beyond
+// which requests spend burst credits. Credits accrue at CreditsPerHour up to
+// MaxCredits, so a tenant that stayed under its quota can absorb a peak
+type QuotaLimits struct {
+    Requests       int
+    Window         time.Duration
+    CreditsPerHour float64
+    MaxCredits     float64
+}
+
+// QuotaUsage reports a tenant's use of its quota in the current window
+type QuotaUsage struct {
+    TenantID        string    `json:"tenant_id"`
+    Limit           int       `json:"limit"`
+    Used            int       `json:"used"`
+    Remaining       int       `json:"remaining"`
+    ResetsAt        time.Time `json:"resets_at"`
+    BurstUsed       int       `json:"burst_used"`
+    Rejected        int       `json:"rejected"`
+    BurstCredits    float64   `json:"burst_credits"`
+    MaxBurstCredits float64   `json:"max_burst_credits"`
+    CreditsPerHour  float64   `json:"credits_per_hour"`
+}
+
+// QuotaAccount tracks a tenant's requests in the current window and its
+// burst credits
+type QuotaAccount struct {
+    TenantID    string
+    WindowStart time.Time
+    Used        int
+    BurstUsed   int
+    Rejected    int
+    Credits     float64
+    AccruedAt   time.Time
+}
+
+// NewQuotaAccount opens an account with a full burst allowance
+func NewQuotaAccount(tenantID string, limits QuotaLimits, now time.Time) *QuotaAccount {
+    return &QuotaAccount{
+        TenantID:    tenantID,
+        WindowStart: now.Truncate(limits.Window),
+        Credits:     limits.MaxCredits,
+        AccruedAt:   now,
+    }
+}
+
+// Take counts a request. Requests within the quota are allowed, requests
+// beyond it spend a burst credit, and once credits run out they are
+// rejected. burst reports whether a credit was spent
+func (a *QuotaAccount) Take(limits QuotaLimits, now time.Time) (allowed, burst bool) {
+    a.advance(limits, now)
+    switch {
+    case a.Used < limits.Requests:
+        a.Used++
+        return true, false
+    case a.Credits >= 1:
+        a.Credits--
+        a.Used++
+        a.BurstUsed++
+        return true, true
+    default:
+        a.Rejected++
+        return false, false
+    }
+}
+
+// Usage reports the account as of now
+func (a *QuotaAccount) Usage(limits QuotaLimits, now time.Time) QuotaUsage {
+    a.advance(limits, now)
+    return QuotaUsage{
+        TenantID:        a.TenantID,
+        Limit:           limits.Requests,
+        Used:            a.Used,
+        Remaining:       max(limits.Requests-a.Used, 0),
+        ResetsAt:        a.WindowStart.Add(limits.Window),
+        BurstUsed:       a.BurstUsed,
+        Rejected:        a.Rejected,
+        BurstCredits:    math.Floor(a.Credits*100) / 100,
+        MaxBurstCredits: limits.MaxCredits,
+        CreditsPerHour:  limits.CreditsPerHour,
+    }
+}
+
+// RetryAfter returns how long a rejected request should wait: until the
+// window resets or a credit accrues, whichever comes first
+func (a *QuotaAccount) RetryAfter(limits QuotaLimits, now time.Time) time.Duration {
+    wait := a.WindowStart.Add(limits.Window).Sub(now)
+    if limits.CreditsPerHour > 0 && a.Credits < 1 {
+        accrual := time.Duration((1 - a.Credits) / limits.CreditsPerHour * float64(time.Hour))
+        wait = min(wait, accrual)
+    }
+    return max(wait, 0)
+}
+
+// advance accrues credits for the time elapsed and starts a new window
+// once the current one is over
+func (a *QuotaAccount) advance(limits QuotaLimits, now time.Time) {
+    if elapsed := now.Sub(a.AccruedAt); elapsed > 0 {
+        a.Credits = math.Min(limits.MaxCredits, a.Credits+elapsed.Hours()*limits.CreditsPerHour)
+        a.AccruedAt = now
+    }
+    // Lowered limits take effect at once
+    a.Credits = math.Min(a.Credits, limits.MaxCredits)
+
+    if !now.Before(a.WindowStart.Add(limits.Window)) {
+        a.WindowStart = now.Truncate(limits.Window)
+        a.Used = 0
+        a.BurstUsed = 0
+        a.Rejected = 0
+    }
+}
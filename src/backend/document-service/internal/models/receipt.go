@@ -0,0 +1,44 @@
+package models
+
+import (
+    "time"
+)
+
+// Ways a document is accessed, recorded on download receipts
+const (
+    ReceiptAccessDownload  = "download"
+    ReceiptAccessRendition = "rendition"
+    ReceiptAccessPreview   = "preview"
+    ReceiptAccessViewer    = "viewer"
+    ReceiptAccessText      = "text"
+)
+
+// DownloadReceipt records who accessed which sensitive documents of one
+// enrollment during one session, and when. Issued receipts are signed so
+// compliance and the data subject can verify they were not altered
+type DownloadReceipt struct {
+    ID             string          `json:"id"`
+    SessionID      string          `json:"session_id"`
+    EnrollmentID   string          `json:"enrollment_id"`
+    UserID         string          `json:"user_id"`
+    Role           string          `json:"role,omitempty"`
+    ImpersonatorID string          `json:"impersonator_id,omitempty"`
+    Accesses       []ReceiptAccess `json:"accesses"`
+    OpenedAt       time.Time       `json:"opened_at"`
+    IssuedAt       time.Time       `json:"issued_at"`
+    KeyID          string          `json:"key_id"`
+    Signature      string          `json:"signature,omitempty"`
+}
+
+// ReceiptAccess is one document accessed in a receipt's session. Repeated
+// accesses of the same kind are counted instead of listed
+type ReceiptAccess struct {
+    DocumentID    string    `json:"document_id"`
+    DocumentType  string    `json:"document_type"`
+    ContentHash   string    `json:"content_hash"`
+    Access        string    `json:"access"`
+    Detail        string    `json:"detail,omitempty"`
+    Count         int       `json:"count"`
+    FirstAccessAt time.Time `json:"first_access_at"`
+    LastAccessAt  time.Time `json:"last_access_at"`
+}
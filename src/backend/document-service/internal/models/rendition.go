@@ -0,0 +1,107 @@
+package models
+
+import (
+    "fmt"
+    "time"
+)
+
+// Rendition name constants
+const (
+    RenditionThumbnail       = "thumbnail"
+    RenditionPreview         = "preview"
+    RenditionOCRText         = "ocr_text"
+    // RenditionOCRTextRedacted is the extracted text with identifiers masked
+    RenditionOCRTextRedacted = "ocr_text_redacted"
+    // RenditionOCRLayout is the extracted text line by line with the position
+    // of each line, as JSON
+    RenditionOCRLayout = "ocr_layout"
+    // RenditionOCRTextTranslated is the redacted text machine translated
+    RenditionOCRTextTranslated = "ocr_text_translated"
+    // RenditionOCRTextCanary is the text a candidate OCR provider extracted,
+    // kept to compare with the text of the provider in service
+    RenditionOCRTextCanary = "ocr_text_canary"
+    // RenditionSplicedPDF is the normalized or original PDF with rescanned
+    // pages spliced in and reviewer page operations applied
+    RenditionSplicedPDF      = "spliced_pdf"
+    // RenditionNormalizedPDF is the original PDF without its blank and
+    // duplicate pages
+    RenditionNormalizedPDF   = "normalized_pdf"
+    // RenditionAccessiblePDF is the page images tagged with their recognized
+    // text in reading order, for screen readers
+    RenditionAccessiblePDF   = "accessible_pdf"
+    // RenditionSearchablePDF is the scan with an invisible layer of its
+    // recognized text
+    RenditionSearchablePDF   = "searchable_pdf"
+    // RenditionOriginal is the upload a document was converted from; the
+    // images a document was composed from are named by OriginalRendition
+    RenditionOriginal        = "original"
+)
+
+// OriginalRendition names the rendition keeping the part of a composed
+// document at a zero-based position
+func OriginalRendition(part int) string {
+    return fmt.Sprintf("%s_%d", RenditionOriginal, part+1)
+}
+
+// OCRPreviewLength is the number of characters of extracted text kept on the
+// document itself
+const OCRPreviewLength = 500
+
+// Rendition is a derived representation of a document. Thumbnails carry no
+// personal data beyond what the original shows at a glance and are stored in
+// the clear; renditions holding personal data such as the extracted text are
+// encrypted in chunks so they can still be streamed by range
+type Rendition struct {
+    Name        string              `json:"name"`
+    StoragePath string              `json:"storage_path"`
+    ContentType string              `json:"content_type"`
+    Size        int64               `json:"size"`
+    Encryption  *EncryptionMetadata `json:"encryption,omitempty"`
+    Provenance  *Provenance         `json:"provenance,omitempty"`
+    CreatedAt   time.Time           `json:"created_at"`
+}
+
+// SetRendition records a rendition, replacing an existing one with the same name
+func (d *Document) SetRendition(rendition Rendition) {
+    d.UpdatedAt = time.Now()
+    if rendition.Provenance == nil {
+        rendition.Provenance = d.producer
+    }
+    for i, existing := range d.Renditions {
+        if existing.Name == rendition.Name {
+            d.Renditions[i] = rendition
+            d.addAuditLog("RENDITION", d.Status, "Rendition replaced: "+rendition.Name, "SYSTEM")
+            return
+        }
+    }
+    d.Renditions = append(d.Renditions, rendition)
+    d.addAuditLog("RENDITION", d.Status, "Rendition created: "+rendition.Name, "SYSTEM")
+}
+
+// Rendition returns the named rendition
+func (d *Document) Rendition(name string) (Rendition, bool) {
+    for _, rendition := range d.Renditions {
+        if rendition.Name == name {
+            return rendition, true
+        }
+    }
+    return Rendition{}, false
+}
+
+// IsTextRendition reports whether a rendition holds extracted text, which is
+// always encrypted and only served through the text endpoint
+func IsTextRendition(name string) bool {
+    return name == RenditionOCRText || name == RenditionOCRTextRedacted || name == RenditionOCRTextTranslated ||
+        name == RenditionOCRLayout || name == RenditionOCRTextCanary
+}
+
+// SetOCRPreview keeps the first OCRPreviewLength characters of the extracted
+// text on the document; the full text is stored as the ocr_text rendition
+func (d *Document) SetOCRPreview(text string) {
+    runes := []rune(text)
+    if len(runes) > OCRPreviewLength {
+        runes = runes[:OCRPreviewLength]
+    }
+    d.OCRPreview = string(runes)
+    d.UpdatedAt = time.Now()
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+    "fmt"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Fixes the metadata repair applies
+const (
+    // RepairRestoreStoragePath points the document at the object stored
+    // under its key and labelled with its ID
+    RepairRestoreStoragePath = "restore_storage_path"
+    // RepairRebuildEncryption rebuilds the encryption metadata from the
+    // headers of the stored object, once the content authenticates under it
+    RepairRebuildEncryption = "rebuild_encryption_metadata"
+    // RepairRecomputeHash hashes the decrypted content
+    RepairRecomputeHash = "recompute_hash"
+)
+
+// Headers of a stored object recording how it was encrypted. The wrapped
+// data key of crypto-shredding is never among them, so shredding a document
+// leaves no copy of its key behind
+const (
+    headerEncryptionVersion    = "encryption-version"
+    headerEncryptionAlgorithm  = "encryption-algorithm"
+    headerEncryptionKeyID      = "encryption-key-id"
+    headerEncryptionKeyVersion = "encryption-key-version"
+    headerEncryptionIV         = "encryption-iv"
+    headerEncryptedAt          = "encrypted-at"
+)
+
+// storedStatuses are those of documents whose content was stored
+var storedStatuses = []string{
+    DocumentStatusCompleted,
+    DocumentStatusApproved,
+    DocumentStatusRejected,
+    DocumentStatusPartiallyApproved,
+    DocumentStatusHaltedConsent,
+    DocumentStatusOCRDeferred,
+    DocumentStatusQueued,
+}
+
+// MetadataViolation is an invariant the metadata of a document breaks, by
+// its code in the invariants package, with the fix the repair applied or, in
+// a dry run, would apply
+type MetadataViolation struct {
+    Code   string `json:"code"`
+    Detail string `json:"detail,omitempty"`
+    // Fix is empty when no fix is safe
+    Fix   string `json:"fix,omitempty"`
+    Fixed bool   `json:"fixed"`
+    // Error explains why the fix could not be applied
+    Error string `json:"error,omitempty"`
+}
+
+// DocumentRepair is the violations of one document
+type DocumentRepair struct {
+    DocumentID string              `json:"document_id"`
+    Violations []MetadataViolation `json:"violations"`
+}
+
+// Repaired reports whether every violation of the document was fixed
+func (r DocumentRepair) Repaired() bool {
+    for _, violation := range r.Violations {
+        if !violation.Fixed {
+            return false
+        }
+    }
+    return true
+}
+
+// RepairReport is the outcome of checking documents against the metadata
+// invariants. A dry run changes nothing: its violations name the fix that
+// would be applied, and none is fixed
+type RepairReport struct {
+    DryRun      bool             `json:"dry_run"`
+    RequestedBy string           `json:"requested_by,omitempty"`
+    StartedAt   time.Time        `json:"started_at"`
+    FinishedAt  time.Time        `json:"finished_at"`
+    Documents   int              `json:"documents"`
+    Violating   int              `json:"violating"`
+    Repaired    int              `json:"repaired"`
+    Repairs     []DocumentRepair `json:"repairs"`
+}
+
+// Stored reports whether the status of the document says its content was
+// stored. The content of a shredded or anonymized document is gone for good
+// and no longer counts
+func (d *Document) Stored() bool {
+    return slices.Contains(storedStatuses, d.Status) && !d.EncryptionInfo.Shredded() && !d.Anonymized()
+}
+
+// RepairEncryptionMetadata replaces the encryption metadata of a document
+// with metadata rebuilt from its stored object
+func (d *Document) RepairEncryptionMetadata(metadata *EncryptionMetadata, performer string) {
+    d.EncryptionInfo = metadata
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("REPAIR", d.Status, "Encryption metadata rebuilt from the stored object", performer)
+}
+
+// RepairStoragePath points a document at the object holding its content
+func (d *Document) RepairStoragePath(storagePath, performer string) {
+    d.StoragePath = storagePath
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("REPAIR", d.Status, "Storage path restored to "+storagePath, performer)
+}
+
+// EncryptionHeaders returns the headers recording encryption metadata on the
+// stored object, or nil without metadata
+func EncryptionHeaders(metadata *EncryptionMetadata) map[string]string {
+    if metadata == nil {
+        return nil
+    }
+    return map[string]string{
+        headerEncryptionVersion:    strconv.Itoa(metadata.Version),
+        headerEncryptionAlgorithm:  metadata.Algorithm,
+        headerEncryptionKeyID:      metadata.KeyID,
+        headerEncryptionKeyVersion: metadata.KeyVersion,
+        headerEncryptionIV:         metadata.IV,
+        headerEncryptedAt:          metadata.EncryptedAt.UTC().Format(time.RFC3339Nano),
+    }
+}
+
+// EncryptionFromHeaders rebuilds encryption metadata from the headers of a
+// stored object, which object stores may return in any case. The rotation
+// falls due rotation after the content was encrypted. Documents of their
+// own data key cannot be rebuilt, as the wrapped key is never a header
+func EncryptionFromHeaders(headers map[string]string, rotation time.Duration) (*EncryptionMetadata, error) {
+    values := make(map[string]string, len(headers))
+    for name, value := range headers {
+        values[strings.TrimPrefix(strings.ToLower(name), "x-amz-meta-")] = value
+    }
+    if values[headerEncryptionAlgorithm] == "" {
+        return nil, fmt.Errorf("%w: object has no encryption headers", ErrMissingField)
+    }
+
+    version, err := strconv.Atoi(values[headerEncryptionVersion])
+    if err != nil {
+        return nil, fmt.Errorf("invalid encryption version header: %w", err)
+    }
+    encryptedAt, err := time.Parse(time.RFC3339Nano, values[headerEncryptedAt])
+    if err != nil {
+        return nil, fmt.Errorf("invalid encryption time header: %w", err)
+    }
+    metadata := &EncryptionMetadata{
+        KeyID:          values[headerEncryptionKeyID],
+        Algorithm:      values[headerEncryptionAlgorithm],
+        IV:             values[headerEncryptionIV],
+        KeyVersion:     values[headerEncryptionKeyVersion],
+        EncryptedAt:    encryptedAt,
+        KeyRotationDue: encryptedAt.Add(rotation),
+        Version:        version,
+    }
+    if metadata.KeyID == "" || metadata.IV == "" || metadata.KeyVersion == "" {
+        return nil, fmt.Errorf("%w: incomplete encryption headers", ErrMissingField)
+    }
+    if err := metadata.ValidateFormat(); err != nil {
+        return nil, err
+    }
+    return metadata, nil
+}
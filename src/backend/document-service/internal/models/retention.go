@@ -0,0 +1,194 @@
+package models
+
+import (
+    "slices"
+    "strings"
+    "time"
+)
+
+// ExpiryNotice records that the tenant admins were told a document will be
+// purged once its retention ends. The document is not purged before
+// PurgeAfter, which leaves the notice period to place a hold. A notice given
+// for an earlier retention date no longer counts
+type ExpiryNotice struct {
+    NoticeID      string    `json:"notice_id"`
+    RetentionDate time.Time `json:"retention_date"`
+    NotifiedAt    time.Time `json:"notified_at"`
+    PurgeAfter    time.Time `json:"purge_after"`
+}
+
+// RetentionHold keeps a document past its retention date until released
+type RetentionHold struct {
+    Reason   string    `json:"reason"`
+    PlacedBy string    `json:"placed_by"`
+    PlacedAt time.Time `json:"placed_at"`
+    NoticeID string    `json:"notice_id,omitempty"`
+}
+
+// PurgeNotice summarizes for the admins of a tenant the documents whose
+// retention ends within the tenant's notice period
+type PurgeNotice struct {
+    ID             string         `json:"id"`
+    TenantID       string         `json:"tenant_id"`
+    DocumentIDs    []string       `json:"document_ids"`
+    DocumentTypes  map[string]int `json:"document_types"`
+    PurgeAfter     time.Time      `json:"purge_after"`
+    NotifiedAt     time.Time      `json:"notified_at"`
+    AcknowledgedAt *time.Time     `json:"acknowledged_at,omitempty"`
+    AcknowledgedBy string         `json:"acknowledged_by,omitempty"`
+    Held           []string       `json:"held,omitempty"`
+}
+
+// Includes reports whether the notice lists the document
+func (n *PurgeNotice) Includes(documentID string) bool {
+    return slices.Contains(n.DocumentIDs, documentID)
+}
+
+// Acknowledge records that a tenant admin reviewed the notice; holds placed
+// on its documents are added to Held
+func (n *PurgeNotice) Acknowledge(by string, held []string, at time.Time) {
+    if n.AcknowledgedAt == nil {
+        n.AcknowledgedAt = &at
+        n.AcknowledgedBy = by
+    }
+    for _, id := range held {
+        if !slices.Contains(n.Held, id) {
+            n.Held = append(n.Held, id)
+        }
+    }
+}
+
+// ExpiryNoticeDue reports whether the retention of the document ends within
+// the notice period and its admins were not told of that retention date yet
+func (d *Document) ExpiryNoticeDue(now time.Time, period time.Duration) bool {
+    if d.Hold != nil || now.Add(period).Before(d.RetentionDate) {
+        return false
+    }
+    return d.Expiry == nil || !d.Expiry.RetentionDate.Equal(d.RetentionDate)
+}
+
+// NoticeExpiry records the notice given for the document, which is kept at
+// least until the notice period ends
+func (d *Document) NoticeExpiry(noticeID string, at time.Time, period time.Duration) {
+    purgeAfter := at.Add(period)
+    if d.RetentionDate.After(purgeAfter) {
+        purgeAfter = d.RetentionDate
+    }
+    d.Expiry = &ExpiryNotice{NoticeID: noticeID, RetentionDate: d.RetentionDate, NotifiedAt: at, PurgeAfter: purgeAfter}
+    d.UpdatedAt = at
+    d.addAuditLog("EXPIRY_NOTICE", d.Status, "Tenant admins notified of purge after "+purgeAfter.UTC().Format(time.RFC3339), "SYSTEM")
+}
+
+// Purgeable reports whether the retention of the document ended, its admins
+// were given notice of that date and the notice period elapsed without a hold
+func (d *Document) Purgeable(now time.Time) bool {
+    if d.Hold != nil || d.Expiry == nil || !d.Expiry.RetentionDate.Equal(d.RetentionDate) {
+        return false
+    }
+    return !now.Before(d.RetentionDate) && !now.Before(d.Expiry.PurgeAfter)
+}
+
+// PlaceHold keeps the document past its retention date
+func (d *Document) PlaceHold(reason, placedBy, noticeID string, at time.Time) error {
+    if reason == "" || placedBy == "" {
+        return ErrMissingField
+    }
+    d.Hold = &RetentionHold{Reason: reason, PlacedBy: placedBy, PlacedAt: at, NoticeID: noticeID}
+    d.UpdatedAt = at
+    d.addAuditLog("HOLD", d.Status, reason, placedBy)
+    return nil
+}
+
+// ReleaseHold lets the document be purged again once its admins are given
+// a new notice
+func (d *Document) ReleaseHold(releasedBy string, at time.Time) {
+    if d.Hold == nil {
+        return
+    }
+    d.Hold = nil
+    d.Expiry = nil
+    d.UpdatedAt = at
+    d.addAuditLog("HOLD_RELEASED", d.Status, "Retention hold released", releasedBy)
+}
+
+// IsExtractedDataRendition reports whether a rendition holds text extracted
+// from the document: the text renditions and the PDFs carrying the text as a
+// layer over the pages
+func IsExtractedDataRendition(name string) bool {
+    return IsTextRendition(name) || name == RenditionSearchablePDF || name == RenditionAccessiblePDF
+}
+
+// ExpiredRenditions returns the renditions of the document past their own
+// retention: renditions of extracted data extractedFor after they were
+// stored, and other renditions renditionFor after. A zero retention keeps
+// renditions as long as the document. The renditions an upload was converted
+// or composed from are the document itself and are kept, as is everything on
+// a held document
+func (d *Document) ExpiredRenditions(now time.Time, extractedFor, renditionFor time.Duration) []Rendition {
+    if d.Hold != nil {
+        return nil
+    }
+    var expired []Rendition
+    for _, rendition := range d.Renditions {
+        retention := renditionFor
+        if IsExtractedDataRendition(rendition.Name) {
+            retention = extractedFor
+        }
+        if retention <= 0 || strings.HasPrefix(rendition.Name, RenditionOriginal) {
+            continue
+        }
+        if !now.Before(rendition.CreatedAt.Add(retention)) {
+            expired = append(expired, rendition)
+        }
+    }
+    return expired
+}
+
+// ExtractedDataExpired reports whether the fields and text preview kept on the
+// document were extracted more than extractedFor ago
+func (d *Document) ExtractedDataExpired(now time.Time, extractedFor time.Duration) bool {
+    if d.Hold != nil || extractedFor <= 0 || (len(d.ExtractedFields) == 0 && d.OCRPreview == "") {
+        return false
+    }
+    extractedAt := d.CreatedAt
+    if d.ProcessedAt != nil {
+        extractedAt = *d.ProcessedAt
+    }
+    return !now.Before(extractedAt.Add(extractedFor))
+}
+
+// PurgeArtifacts removes renditions whose stored objects were deleted and,
+// when extractedData is set, the fields and text preview kept on the
+// document. Extracted data and other renditions are audited separately
+func (d *Document) PurgeArtifacts(renditions []Rendition, extractedData bool, at time.Time) {
+    var extracted, derived []string
+    for _, rendition := range renditions {
+        d.Renditions = slices.DeleteFunc(d.Renditions, func(existing Rendition) bool {
+            return existing.Name == rendition.Name
+        })
+        if IsExtractedDataRendition(rendition.Name) {
+            extracted = append(extracted, rendition.Name)
+        } else {
+            derived = append(derived, rendition.Name)
+        }
+        if rendition.Name == RenditionOCRTextTranslated {
+            d.Translation = nil
+        }
+    }
+    if extractedData {
+        d.ExtractedFields = nil
+        d.OCRPreview = ""
+        extracted = append(extracted, "extracted fields", "text preview")
+    }
+    if len(extracted) == 0 && len(derived) == 0 {
+        return
+    }
+
+    d.UpdatedAt = at
+    if len(extracted) > 0 {
+        d.addAuditLog("EXTRACTED_DATA_PURGE", d.Status, "Extracted data purged after its retention: "+strings.Join(extracted, ", "), "SYSTEM")
+    }
+    if len(derived) > 0 {
+        d.addAuditLog("RENDITION_PURGE", d.Status, "Renditions purged after their retention: "+strings.Join(derived, ", "), "SYSTEM")
+    }
+}
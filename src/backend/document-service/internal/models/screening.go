@@ -0,0 +1,39 @@
+package models
+
+import (
+    "time"
+)
+
+// ScreeningMatch is a single PEP or sanctions list entry matching a screened name
+type ScreeningMatch struct {
+    List        string  `json:"list"`
+    Category    string  `json:"category"`
+    MatchedName string  `json:"matched_name"`
+    Score       float64 `json:"score"`
+}
+
+// ScreeningResult records the outcome of screening one name against PEP and sanctions lists
+type ScreeningResult struct {
+    Name       string           `json:"name"`
+    Provider   string           `json:"provider"`
+    Hit        bool             `json:"hit"`
+    Matches    []ScreeningMatch `json:"matches,omitempty"`
+    ScreenedAt time.Time        `json:"screened_at"`
+}
+
+// SetScreeningResults replaces the screening results and flags the document
+// for manual review when any name produced a hit
+func (d *Document) SetScreeningResults(results []ScreeningResult) {
+    d.Screening = results
+    d.UpdatedAt = time.Now()
+
+    status := "NO_HIT"
+    for _, result := range results {
+        if result.Hit {
+            status = "HIT"
+            d.AddReviewFlag(ReviewFlagScreeningHit)
+            break
+        }
+    }
+    d.addAuditLog("SCREENING", status, "Names screened against PEP and sanctions lists", "SYSTEM")
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "sort"
+    "time"
+)
+
+// SealAlgorithm names how a seal's Merkle root is computed, so the scheme can
+// change without invalidating older seals
+const SealAlgorithm = "sha256-merkle-v1"
+
+// Merkle tree node prefixes; leaves and inner nodes are hashed apart so a
+// leaf can never be presented as an inner node
+const (
+    merkleLeafPrefix = 0x00
+    merkleNodePrefix = 0x01
+)
+
+// EnrollmentSeal freezes the document set of an enrollment when underwriting
+// closes. The Merkle root commits to every sealed document and its content
+// hash; the seal is signed by the service and optionally timestamped by an
+// RFC 3161 authority
+type EnrollmentSeal struct {
+    ID             string           `json:"id"`
+    EnrollmentID   string           `json:"enrollment_id"`
+    EventID        string           `json:"event_id"`
+    Decision       string           `json:"decision,omitempty"`
+    Documents      []SealedDocument `json:"documents"`
+    Algorithm      string           `json:"algorithm"`
+    MerkleRoot     string           `json:"merkle_root"`
+    SealedAt       time.Time        `json:"sealed_at"`
+    KeyID          string           `json:"key_id"`
+    Signature      string           `json:"signature,omitempty"`
+    TimestampToken string           `json:"timestamp_token,omitempty"`
+}
+
+// SealedDocument is a document as it was when its enrollment was sealed
+type SealedDocument struct {
+    DocumentID   string `json:"document_id"`
+    DocumentType string `json:"document_type"`
+    ContentHash  string `json:"content_hash"`
+}
+
+// SealVerification reports whether a seal is intact and the enrollment's
+// documents still match it
+type SealVerification struct {
+    Valid          bool            `json:"valid"`
+    SignatureValid bool            `json:"signature_valid"`
+    RootValid      bool            `json:"root_valid"`
+    Timestamped    bool            `json:"timestamped"`
+    Missing        []string        `json:"missing"`
+    Added          []string        `json:"added"`
+    Changed        []string        `json:"changed"`
+    Seal           *EnrollmentSeal `json:"seal"`
+    VerifiedAt     time.Time       `json:"verified_at"`
+}
+
+// NewSealedDocuments lists documents for a seal, ordered by document ID so
+// the Merkle root does not depend on listing order
+func NewSealedDocuments(docs []*Document) []SealedDocument {
+    sealed := make([]SealedDocument, 0, len(docs))
+    for _, doc := range docs {
+        sealed = append(sealed, SealedDocument{
+            DocumentID:   doc.ID,
+            DocumentType: doc.DocumentType,
+            ContentHash:  doc.ContentHash,
+        })
+    }
+    sort.Slice(sealed, func(i, j int) bool {
+        return sealed[i].DocumentID < sealed[j].DocumentID
+    })
+    return sealed
+}
+
+// MerkleRoot returns the hex encoded root of the Merkle tree over the sealed
+// documents, in the order given. An odd node is promoted to the next level
+// rather than paired with itself, which would give a set with its last
+// document repeated the same root
+func MerkleRoot(docs []SealedDocument) string {
+    if len(docs) == 0 {
+        empty := sha256.Sum256(nil)
+        return hex.EncodeToString(empty[:])
+    }
+
+    level := make([][]byte, 0, len(docs))
+    for _, doc := range docs {
+        level = append(level, merkleHash(merkleLeafPrefix, []byte(doc.DocumentID), []byte(doc.DocumentType), []byte(doc.ContentHash)))
+    }
+    for len(level) > 1 {
+        next := make([][]byte, 0, (len(level)+1)/2)
+        for i := 0; i < len(level); i += 2 {
+            if i+1 == len(level) {
+                next = append(next, level[i])
+                continue
+            }
+            next = append(next, merkleHash(merkleNodePrefix, level[i], level[i+1]))
+        }
+        level = next
+    }
+    return hex.EncodeToString(level[0])
+}
+
+// Compare lists the documents of the seal no longer present, the documents
+// added since and those whose content hash changed
+func (s *EnrollmentSeal) Compare(current []SealedDocument) (missing, added, changed []string) {
+    missing, added, changed = []string{}, []string{}, []string{}
+    now := make(map[string]SealedDocument, len(current))
+    for _, doc := range current {
+        now[doc.DocumentID] = doc
+    }
+    sealed := make(map[string]bool, len(s.Documents))
+    for _, doc := range s.Documents {
+        sealed[doc.DocumentID] = true
+        currentDoc, ok := now[doc.DocumentID]
+        switch {
+        case !ok:
+            missing = append(missing, doc.DocumentID)
+        case currentDoc != doc:
+            changed = append(changed, doc.DocumentID)
+        }
+    }
+    for _, doc := range current {
+        if !sealed[doc.DocumentID] {
+            added = append(added, doc.DocumentID)
+        }
+    }
+    return missing, added, changed
+}
+
+// merkleHash hashes the parts under a node prefix. Leaf fields are length
+// prefixed so bytes cannot be shifted from one field to the next
+func merkleHash(prefix byte, parts ...[]byte) []byte {
+    h := sha256.New()
+    h.Write([]byte{prefix})
+    for _, part := range parts {
+        if prefix == merkleLeafPrefix {
+            length := len(part)
+            h.Write([]byte{byte(length >> 24), byte(length >> 16), byte(length >> 8), byte(length)})
+        }
+        h.Write(part)
+    }
+    return h.Sum(nil)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+    "slices"
+)
+
+// Internal roles service accounts are granted
+const (
+    ServiceRoleOCRWorker     = "ocr-worker"
+    ServiceRoleRetentionJob  = "retention-job"
+    ServiceRoleMigrationTool = "migration-tool"
+)
+
+// Permissions of internal roles
+const (
+    PermissionDocumentStatus     = "documents:status"
+    PermissionDocumentReprocess  = "documents:reprocess"
+    PermissionDocumentDelete     = "documents:delete"
+    PermissionOCRCanary          = "ocr:canary"
+    PermissionRetentionNotices   = "retention:notices"
+    PermissionJobsRead           = "jobs:read"
+    PermissionMigrationsRead     = "migrations:read"
+    PermissionMigrationsContract = "migrations:contract"
+    PermissionReencrypt          = "encryption:reencrypt"
+    PermissionProjections        = "projections:rebuild"
+    PermissionBulkOperations     = "operations:run"
+)
+
+// ServiceRolePermissions lists the permissions of each internal role. None
+// of them grants reading document content
+var ServiceRolePermissions = map[string][]string{
+    ServiceRoleOCRWorker:     {PermissionDocumentStatus, PermissionDocumentReprocess, PermissionOCRCanary},
+    ServiceRoleRetentionJob:  {PermissionRetentionNotices, PermissionDocumentDelete, PermissionJobsRead},
+    ServiceRoleMigrationTool: {PermissionMigrationsRead, PermissionMigrationsContract, PermissionReencrypt, PermissionProjections, PermissionBulkOperations},
+}
+
+// ServiceAccount is an internal caller with its own credential
+type ServiceAccount struct {
+    Name string `json:"name"`
+    Role string `json:"role"`
+}
+
+// Allows reports whether the account's role holds the permission
+func (a *ServiceAccount) Allows(permission string) bool {
+    return slices.Contains(ServiceRolePermissions[a.Role], permission)
+}
+
+// ValidServiceRole reports whether the role is an internal role
+func ValidServiceRole(role string) bool {
+    _, ok := ServiceRolePermissions[role]
+    return ok
+}
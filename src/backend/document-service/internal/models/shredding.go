@@ -0,0 +1,32 @@
+package models
+
+import (
+    "time"
+)
+
+// Shredded reports whether the data key the content was sealed with was
+// destroyed
+func (e *EncryptionMetadata) Shredded() bool {
+    return e != nil && e.ShreddedAt != nil
+}
+
+// MarkShredded discards the wrapped data key of the document and of its
+// renditions sealed under the same key, leaving the stored ciphertext
+// unrecoverable
+func (d *Document) MarkShredded(at time.Time) {
+    shred := func(metadata *EncryptionMetadata) {
+        if metadata == nil || metadata.WrappedKey == "" {
+            return
+        }
+        metadata.WrappedKey = ""
+        metadata.GrantID = ""
+        metadata.ShreddedAt = &at
+    }
+
+    shred(d.EncryptionInfo)
+    for i := range d.Renditions {
+        shred(d.Renditions[i].Encryption)
+    }
+    d.UpdatedAt = at
+    d.addAuditLog("SHRED", d.Status, "Document data key destroyed", "SYSTEM")
+}
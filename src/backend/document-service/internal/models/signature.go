@@ -0,0 +1,50 @@
+package models
+
+import (
+    "time"
+)
+
+// SignatureInfo records the outcome of verifying one embedded digital signature
+type SignatureInfo struct {
+    SignerName     string     `json:"signer_name"`
+    SignerCPF      string     `json:"signer_cpf,omitempty"`
+    SignerCNPJ     string     `json:"signer_cnpj,omitempty"`
+    Issuer         string     `json:"issuer"`
+    SerialNumber   string     `json:"serial_number"`
+    NotBefore      time.Time  `json:"not_before"`
+    NotAfter       time.Time  `json:"not_after"`
+    SignedAt       *time.Time `json:"signed_at,omitempty"`
+    CoversDocument bool       `json:"covers_document"`
+    Valid          bool       `json:"valid"`
+    FailureReason  string     `json:"failure_reason,omitempty"`
+    VerifiedAt     time.Time  `json:"verified_at"`
+}
+
+// SetSignatures replaces the recorded signature verification results
+func (d *Document) SetSignatures(signatures []SignatureInfo) {
+    d.Signatures = signatures
+    d.UpdatedAt = time.Now()
+
+    valid := 0
+    for _, signature := range signatures {
+        if signature.Valid {
+            valid++
+        }
+    }
+    status := "VALID"
+    if valid < len(signatures) {
+        status = "INVALID"
+    }
+    d.addAuditLog("SIGNATURE_VERIFICATION", status, "Embedded signatures verified", "SYSTEM")
+}
+
+// HasValidSignature reports whether at least one signature covering the whole
+// document was verified against the trusted roots
+func (d *Document) HasValidSignature() bool {
+    for _, signature := range d.Signatures {
+        if signature.Valid && signature.CoversDocument {
+            return true
+        }
+    }
+    return false
+}
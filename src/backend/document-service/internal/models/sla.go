@@ -0,0 +1,193 @@
+package models
+
+import (
+    "fmt"
+    "sort"
+    "time"
+)
+
+// SLAClock is how a document stands against its review deadline. The clock
+// starts on upload and stops at the final review decision
+type SLAClock struct {
+    StartedAt time.Time  `json:"started_at"`
+    DueAt     time.Time  `json:"due_at"`
+    StoppedAt *time.Time `json:"stopped_at,omitempty"`
+    // RemainingSeconds is the time left until DueAt, negative once overdue;
+    // it no longer runs once the clock stopped
+    RemainingSeconds int64 `json:"remaining_seconds"`
+    // Elapsed is the share of the deadline elapsed
+    Elapsed  float64 `json:"elapsed"`
+    Breached bool    `json:"breached"`
+    // EscalationLevel is the number of escalation thresholds the document
+    // passed undecided
+    EscalationLevel int `json:"escalation_level"`
+    // Calendar is the business calendar the clock runs on, unset when it
+    // runs continuously
+    Calendar string `json:"calendar,omitempty"`
+}
+
+// SLAEscalation records a document passing an escalation threshold, a share
+// of its deadline, without a decision
+type SLAEscalation struct {
+    Level     int       `json:"level"`
+    Threshold float64   `json:"threshold"`
+    DueAt     time.Time `json:"due_at"`
+    RaisedAt  time.Time `json:"raised_at"`
+}
+
+// NewSLAClock returns the clock of a document with a deadline at now. The
+// deadline, the time remaining and the time elapsed count business time on
+// calendar, or every moment when calendar is nil
+func NewSLAClock(doc *Document, deadline time.Duration, calendar *BusinessCalendar, now time.Time) *SLAClock {
+    clock := &SLAClock{
+        StartedAt:       doc.CreatedAt,
+        DueAt:           calendar.Add(doc.CreatedAt, deadline),
+        EscalationLevel: doc.SLALevel(),
+    }
+    if calendar != nil {
+        clock.Calendar = calendar.Name()
+    }
+    if doc.Verified() && doc.ReviewedAt != nil {
+        stopped := *doc.ReviewedAt
+        clock.StoppedAt = &stopped
+        now = stopped
+    }
+    clock.RemainingSeconds = int64(calendar.Elapsed(now, clock.DueAt) / time.Second)
+    clock.Elapsed = float64(calendar.Elapsed(clock.StartedAt, now)) / float64(deadline)
+    clock.Breached = now.After(clock.DueAt)
+    return clock
+}
+
+// TracksSLA reports whether the document runs an SLA clock. Documents whose
+// processing failed or halted never reach review, and synthetic documents
+// are not reviewed at all
+func (d *Document) TracksSLA() bool {
+    return !d.Synthetic && d.Status != DocumentStatusFailed && d.Status != DocumentStatusHaltedConsent
+}
+
+// SLALevel returns the escalation level the document reached
+func (d *Document) SLALevel() int {
+    if len(d.SLAEscalations) == 0 {
+        return 0
+    }
+    return d.SLAEscalations[len(d.SLAEscalations)-1].Level
+}
+
+// SLAThresholdsPassed returns how many of the ascending escalation
+// thresholds a running clock passed; a stopped clock passes none
+func SLAThresholdsPassed(clock *SLAClock, thresholds []float64) int {
+    if clock.StoppedAt != nil {
+        return 0
+    }
+    passed := 0
+    for passed < len(thresholds) && clock.Elapsed >= thresholds[passed] {
+        passed++
+    }
+    return passed
+}
+
+// Escalate raises the escalation level of an undecided document to the
+// number of thresholds its clock passed. Thresholds passed together, as
+// after the scan was down, raise one escalation at the highest level. The
+// escalation is returned, or nil when the level did not rise
+func (d *Document) Escalate(clock *SLAClock, thresholds []float64) *SLAEscalation {
+    level := SLAThresholdsPassed(clock, thresholds)
+    if level <= d.SLALevel() {
+        return nil
+    }
+
+    escalation := SLAEscalation{
+        Level:     level,
+        Threshold: thresholds[level-1],
+        DueAt:     clock.DueAt,
+        RaisedAt:  time.Now().UTC(),
+    }
+    d.SLAEscalations = append(d.SLAEscalations, escalation)
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("SLA_ESCALATION", d.Status, fmt.Sprintf("SLA escalation level %d: %.0f%% of the deadline elapsed", level, escalation.Threshold*100), "SYSTEM")
+    return &escalation
+}
+
+// SLATenantCompliance is how the documents of a tenant that came due in a
+// period met their deadline
+type SLATenantCompliance struct {
+    TenantID string `json:"tenant_id"`
+    // Met documents were decided by their deadline
+    Met int `json:"met"`
+    // Breached documents were decided late or are still undecided past
+    // their deadline, the latter counted in Open too
+    Breached int `json:"breached"`
+    Open     int `json:"open"`
+    // Pending documents are due later in the period, undecided yet
+    Pending int `json:"pending"`
+    // Escalated counts the met and breached documents escalated at least once
+    Escalated int `json:"escalated"`
+    // Compliance is the share of met documents among met and breached ones,
+    // 1 when none came due
+    Compliance float64 `json:"compliance"`
+}
+
+// SLAReport is the SLA compliance of each tenant over the documents due in
+// [From, To)
+type SLAReport struct {
+    From        time.Time             `json:"from"`
+    To          time.Time             `json:"to"`
+    GeneratedAt time.Time             `json:"generated_at"`
+    Tenants     []SLATenantCompliance `json:"tenants"`
+
+    tenants map[string]*SLATenantCompliance
+}
+
+// NewSLAReport starts an empty report of the documents due in [from, to)
+func NewSLAReport(from, to time.Time) *SLAReport {
+    return &SLAReport{
+        From:        from,
+        To:          to,
+        GeneratedAt: time.Now().UTC(),
+        tenants:     make(map[string]*SLATenantCompliance),
+    }
+}
+
+// Add counts a document of a tenant by its clock when it came due in the
+// period
+func (r *SLAReport) Add(tenantID string, clock *SLAClock) {
+    if clock.DueAt.Before(r.From) || !clock.DueAt.Before(r.To) {
+        return
+    }
+    tenant, ok := r.tenants[tenantID]
+    if !ok {
+        tenant = &SLATenantCompliance{TenantID: tenantID}
+        r.tenants[tenantID] = tenant
+    }
+
+    switch {
+    case clock.StoppedAt == nil && !clock.Breached:
+        tenant.Pending++
+        return
+    case !clock.Breached:
+        tenant.Met++
+    default:
+        tenant.Breached++
+        if clock.StoppedAt == nil {
+            tenant.Open++
+        }
+    }
+    if clock.EscalationLevel > 0 {
+        tenant.Escalated++
+    }
+}
+
+// Finish computes the compliance of each tenant and lists them by tenant
+func (r *SLAReport) Finish() {
+    r.Tenants = make([]SLATenantCompliance, 0, len(r.tenants))
+    for _, tenant := range r.tenants {
+        tenant.Compliance = 1
+        if due := tenant.Met + tenant.Breached; due > 0 {
+            tenant.Compliance = float64(tenant.Met) / float64(due)
+        }
+        r.Tenants = append(r.Tenants, *tenant)
+    }
+    sort.Slice(r.Tenants, func(i, j int) bool {
+        return r.Tenants[i].TenantID < r.Tenants[j].TenantID
+    })
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+    "time"
+)
+
+// Processing modes of the service under its error budgets
+const (
+    ProcessingModeNormal      = "normal"
+    ProcessingModeOCRDeferred = "ocr_deferred"
+)
+
+// SLOStatus reports the OCR error budget over its window and the processing
+// mode it puts the service in
+type SLOStatus struct {
+    Mode          string  `json:"mode"`
+    Objective     float64 `json:"objective"`
+    WindowSeconds int64   `json:"window_seconds"`
+    Events        int     `json:"events"`
+    Failures      int     `json:"failures"`
+    ErrorRate     float64 `json:"error_rate"`
+    // BudgetRemaining is the share of the error budget left, negative once
+    // the budget is blown
+    BudgetRemaining float64    `json:"budget_remaining"`
+    DegradedSince   *time.Time `json:"degraded_since,omitempty"`
+    // RecoverySuccesses counts the provider trials in a row that succeeded
+    // while OCR is deferred
+    RecoverySuccesses int `json:"recovery_successes,omitempty"`
+    DeferredDocuments int `json:"deferred_documents"`
+}
+
+// BudgetRemaining returns the share of the error budget of objective left
+// after failures among events: 1 when nothing failed, 0 when the error rate
+// reaches 1-objective and negative beyond
+func BudgetRemaining(objective float64, events, failures int) float64 {
+    if events == 0 {
+        return 1
+    }
+    budget := 1 - objective
+    return 1 - float64(failures)/float64(events)/budget
+}
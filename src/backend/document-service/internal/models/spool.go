@@ -0,0 +1,26 @@
+package models
+
+import (
+    "time"
+)
+
+// MarkSpooled records that the content of the document was accepted into an
+// instance's upload spool while object storage was unavailable; until it is
+// drained only that instance can serve the content
+func (d *Document) MarkSpooled(at time.Time) {
+    d.SpooledAt = &at
+    d.UpdatedAt = at
+    d.addAuditLog("SPOOL", d.Status, "Content spooled while object storage was unavailable", "SYSTEM")
+}
+
+// MarkDrained records that the spooled content reached object storage
+func (d *Document) MarkDrained(at time.Time) {
+    d.SpooledAt = nil
+    d.UpdatedAt = at
+    d.addAuditLog("SPOOL_DRAINED", d.Status, "Spooled content stored", "SYSTEM")
+}
+
+// Spooled reports whether the content of the document is still in a spool
+func (d *Document) Spooled() bool {
+    return d.SpooledAt != nil
+}
@@ -0,0 +1,139 @@
+package models
+
+import (
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Page sizes of an enrollment timeline
+const (
+    TimelineDefaultLimit = 50
+    TimelineMaxLimit     = 200
+)
+
+var ErrInvalidTimelineCursor = errors.New("invalid timeline cursor")
+
+// TimelineEntry is an event of one of an enrollment's documents, with the
+// type of the document it happened to
+type TimelineEntry struct {
+    EventSummary
+    DocumentType string `json:"document_type,omitempty"`
+}
+
+// TimelineQuery selects a page of an enrollment timeline. An empty Actor
+// keeps the events of every actor; Cursor is the NextCursor of the previous
+// page
+type TimelineQuery struct {
+    Actor  string
+    Cursor string
+    Limit  int
+}
+
+// TimelinePage is a page of an enrollment timeline. NextCursor is empty on
+// the last page
+type TimelinePage struct {
+    Entries    []TimelineEntry `json:"entries"`
+    NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// NewTimeline merges the events of an enrollment's documents oldest first
+// and returns the page query selects. Events at the same instant are ordered
+// by document and sequence, so pages neither skip nor repeat events when
+// more are appended between requests. documentTypes maps document IDs to
+// their types
+func NewTimeline(events []*DocumentEvent, documentTypes map[string]string, query TimelineQuery) (*TimelinePage, error) {
+    limit := query.Limit
+    if limit <= 0 {
+        limit = TimelineDefaultLimit
+    }
+    if limit > TimelineMaxLimit {
+        limit = TimelineMaxLimit
+    }
+    var after *timelineKey
+    if query.Cursor != "" {
+        key, err := parseTimelineCursor(query.Cursor)
+        if err != nil {
+            return nil, err
+        }
+        after = &key
+    }
+
+    selected := make([]*DocumentEvent, 0, len(events))
+    for _, event := range events {
+        if query.Actor != "" && event.Actor != query.Actor {
+            continue
+        }
+        if after != nil && !after.before(keyOf(event)) {
+            continue
+        }
+        selected = append(selected, event)
+    }
+    sort.Slice(selected, func(i, j int) bool {
+        return keyOf(selected[i]).before(keyOf(selected[j]))
+    })
+
+    page := &TimelinePage{Entries: []TimelineEntry{}}
+    if len(selected) > limit {
+        selected = selected[:limit]
+        page.NextCursor = keyOf(selected[limit-1]).cursor()
+    }
+    for _, event := range selected {
+        page.Entries = append(page.Entries, TimelineEntry{
+            EventSummary: event.Summary(),
+            DocumentType: documentTypes[event.DocumentID],
+        })
+    }
+    return page, nil
+}
+
+// timelineKey is the position of an event in a timeline
+type timelineKey struct {
+    occurredAt time.Time
+    documentID string
+    sequence   int64
+}
+
+func keyOf(event *DocumentEvent) timelineKey {
+    return timelineKey{occurredAt: event.OccurredAt, documentID: event.DocumentID, sequence: event.Sequence}
+}
+
+func (k timelineKey) before(other timelineKey) bool {
+    if !k.occurredAt.Equal(other.occurredAt) {
+        return k.occurredAt.Before(other.occurredAt)
+    }
+    if k.documentID != other.documentID {
+        return k.documentID < other.documentID
+    }
+    return k.sequence < other.sequence
+}
+
+// cursor encodes the key as an opaque URL-safe string
+func (k timelineKey) cursor() string {
+    raw := fmt.Sprintf("%d|%d|%s", k.occurredAt.UnixNano(), k.sequence, k.documentID)
+    return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseTimelineCursor(cursor string) (timelineKey, error) {
+    raw, err := base64.RawURLEncoding.DecodeString(cursor)
+    if err != nil {
+        return timelineKey{}, ErrInvalidTimelineCursor
+    }
+    parts := strings.SplitN(string(raw), "|", 3)
+    if len(parts) != 3 || parts[2] == "" {
+        return timelineKey{}, ErrInvalidTimelineCursor
+    }
+    nanos, err := strconv.ParseInt(parts[0], 10, 64)
+    if err != nil {
+        return timelineKey{}, ErrInvalidTimelineCursor
+    }
+    sequence, err := strconv.ParseInt(parts[1], 10, 64)
+    if err != nil {
+        return timelineKey{}, ErrInvalidTimelineCursor
+    }
+    return timelineKey{occurredAt: time.Unix(0, nanos), documentID: parts[2], sequence: sequence}, nil
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+    "time"
+)
+
+// SetGovernmentVerification records the outcome of checking the document's
+// verification code against the issuing government service
+func (d *Document) SetGovernmentVerification(verified bool, verifiedAt time.Time) {
+    d.GovernmentVerified = verified
+    d.GovernmentVerifiedAt = &verifiedAt
+    d.UpdatedAt = verifiedAt
+
+    status := "VERIFIED"
+    if !verified {
+        status = "NOT_VERIFIED"
+    }
+    d.addAuditLog("GOVERNMENT_VERIFICATION", status, "Verification code checked with gov.br", "SYSTEM")
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+    "time"
+)
+
+// Virus scan verdicts
+const (
+    VirusVerdictClean    = "clean"
+    VirusVerdictInfected = "infected"
+)
+
+// VirusScan is the verdict of the virus scanner on an upload
+type VirusScan struct {
+    Verdict string `json:"verdict"`
+    // Signature names the malware found in an infected upload
+    Signature string `json:"signature,omitempty"`
+    // DefinitionsVersion is the signature database version the verdict was
+    // reached with
+    DefinitionsVersion string `json:"definitions_version"`
+    // Cached marks a verdict reused from an earlier scan of identical content
+    Cached    bool      `json:"cached"`
+    ScannedAt time.Time `json:"scanned_at"`
+}
+
+// Infected reports whether the scanner found malware
+func (s *VirusScan) Infected() bool {
+    return s.Verdict == VirusVerdictInfected
+}
+
+// RecordVirusScan records the verdict of the virus scanner on the upload the
+// document was made from
+func (d *Document) RecordVirusScan(scan *VirusScan) {
+    d.VirusScan = scan
+    d.UpdatedAt = time.Now()
+    d.addAuditLog("VIRUS_SCAN", d.Status, "Scanned with definitions "+scan.DefinitionsVersion+": "+scan.Verdict, "SYSTEM")
+}
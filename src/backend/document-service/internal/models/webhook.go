@@ -0,0 +1,152 @@
+package models
+
+import (
+    "errors"
+    "fmt"
+    "slices"
+    "time"
+)
+
+// Events tenants can subscribe webhooks to
+const (
+    WebhookEventDocumentProcessed = "document.processed"
+    WebhookEventDocumentReviewed  = "document.reviewed"
+    // WebhookEventDocumentSLAEscalated announces a document passing an SLA
+    // escalation threshold undecided
+    WebhookEventDocumentSLAEscalated = "document.sla_escalated"
+    // WebhookEventTest is sent by test deliveries only
+    WebhookEventTest = "webhook.test"
+)
+
+// Outcomes of a webhook delivery
+const (
+    WebhookDeliveryDelivered = "delivered"
+    WebhookDeliveryFailed    = "failed"
+    // WebhookDeliverySkipped records an event not sent because the
+    // subscription was paused after it was queued
+    WebhookDeliverySkipped = "skipped"
+)
+
+var (
+    // WebhookEvents lists the events a subscription may choose
+    WebhookEvents = []string{
+        WebhookEventDocumentProcessed,
+        WebhookEventDocumentReviewed,
+        WebhookEventDocumentSLAEscalated,
+    }
+
+    ErrUnknownWebhookEvent = errors.New("unknown webhook event")
+    ErrNoWebhookEvents     = errors.New("webhook subscription has no events")
+)
+
+// WebhookSubscription is an endpoint of a tenant receiving the events it
+// subscribed to. Deliveries are signed with Secret, and with PreviousSecret
+// too until PreviousSecretExpiresAt, so receivers can switch secrets after a
+// rotation without missing events. Secrets are never serialized
+type WebhookSubscription struct {
+    ID                      string     `json:"id"`
+    TenantID                string     `json:"tenant_id"`
+    URL                     string     `json:"url"`
+    Description             string     `json:"description,omitempty"`
+    Events                  []string   `json:"events"`
+    Paused                  bool       `json:"paused"`
+    PausedAt                *time.Time `json:"paused_at,omitempty"`
+    Secret                  string     `json:"-"`
+    PreviousSecret          string     `json:"-"`
+    PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+    SecretRotatedAt         *time.Time `json:"secret_rotated_at,omitempty"`
+    CreatedBy               string     `json:"created_by"`
+    CreatedAt               time.Time  `json:"created_at"`
+    UpdatedAt               time.Time  `json:"updated_at"`
+}
+
+// Subscribed reports whether the subscription receives an event
+func (s *WebhookSubscription) Subscribed(event string) bool {
+    return !s.Paused && slices.Contains(s.Events, event)
+}
+
+// Secrets returns the secrets deliveries at now are signed with, the current
+// one first
+func (s *WebhookSubscription) Secrets(now time.Time) []string {
+    secrets := []string{s.Secret}
+    if s.PreviousSecret != "" && s.PreviousSecretExpiresAt != nil && now.Before(*s.PreviousSecretExpiresAt) {
+        secrets = append(secrets, s.PreviousSecret)
+    }
+    return secrets
+}
+
+// RotateSecret replaces the secret, keeping the current one valid for grace
+func (s *WebhookSubscription) RotateSecret(secret string, at time.Time, grace time.Duration) {
+    s.PreviousSecret = s.Secret
+    expiresAt := at.Add(grace)
+    s.PreviousSecretExpiresAt = &expiresAt
+    s.Secret = secret
+    s.SecretRotatedAt = &at
+    s.UpdatedAt = at
+}
+
+// Pause stops deliveries until the subscription is resumed; events raised
+// meanwhile are not sent later
+func (s *WebhookSubscription) Pause(at time.Time) {
+    if !s.Paused {
+        s.Paused = true
+        s.PausedAt = &at
+        s.UpdatedAt = at
+    }
+}
+
+// Resume restarts deliveries
+func (s *WebhookSubscription) Resume(at time.Time) {
+    if s.Paused {
+        s.Paused = false
+        s.PausedAt = nil
+        s.UpdatedAt = at
+    }
+}
+
+// ValidateWebhookEvents checks that events is a non-empty list of known
+// events
+func ValidateWebhookEvents(events []string) error {
+    if len(events) == 0 {
+        return ErrNoWebhookEvents
+    }
+    for _, event := range events {
+        if !slices.Contains(WebhookEvents, event) {
+            return fmt.Errorf("%w: %s", ErrUnknownWebhookEvent, event)
+        }
+    }
+    return nil
+}
+
+// WebhookEvent is the body posted to subscribed endpoints. Data describes
+// the document the event is about and never holds personal data
+type WebhookEvent struct {
+    ID         string               `json:"id"`
+    Type       string               `json:"type"`
+    TenantID   string               `json:"tenant_id"`
+    OccurredAt time.Time            `json:"occurred_at"`
+    Data       *WebhookDocumentData `json:"data,omitempty"`
+}
+
+// WebhookDocumentData identifies a document and the state it reached
+type WebhookDocumentData struct {
+    DocumentID   string `json:"document_id"`
+    EnrollmentID string `json:"enrollment_id"`
+    DocumentType string `json:"document_type"`
+    Status       string `json:"status"`
+}
+
+// WebhookDelivery records one attempt to post an event to a subscription
+type WebhookDelivery struct {
+    ID             string    `json:"id"`
+    SubscriptionID string    `json:"subscription_id"`
+    EventID        string    `json:"event_id"`
+    EventType      string    `json:"event_type"`
+    Attempt        int       `json:"attempt"`
+    Outcome        string    `json:"outcome"`
+    StatusCode     int       `json:"status_code,omitempty"`
+    Error          string    `json:"error,omitempty"`
+    DurationMS     int64     `json:"duration_ms"`
+    Test           bool      `json:"test,omitempty"`
+    AttemptedAt    time.Time `json:"attempted_at"`
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// APIKeyRepository stores the API keys issued to machine integrations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	Get(ctx context.Context, id string) (*models.APIKey, error)
+	Update(ctx context.Context, key *models.APIKey) error
+	// List returns the keys of a tenant, or of every tenant when tenantID is
+	// empty
+	List(ctx context.Context, tenantID string) ([]*models.APIKey, error)
+}
+
+// MemoryAPIKeyRepository is an in-process APIKeyRepository
+type MemoryAPIKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]*models.APIKey
+}
+
+// NewMemoryAPIKeyRepository creates an empty in-memory key store
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys: make(map[string]*models.APIKey),
+	}
+}
+
+// Create stores a new key
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[key.ID] = cloneAPIKey(key)
+	return nil
+}
+
+// Get returns a copy of a key
+func (r *MemoryAPIKeyRepository) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrAPIKeyNotFound
+	}
+	return cloneAPIKey(key), nil
+}
+
+// Update replaces an existing key
+func (r *MemoryAPIKeyRepository) Update(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[key.ID]; !ok {
+		return ErrAPIKeyNotFound
+	}
+	r.keys[key.ID] = cloneAPIKey(key)
+	return nil
+}
+
+// List returns the keys of a tenant, most recent first
+func (r *MemoryAPIKeyRepository) List(ctx context.Context, tenantID string) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range r.keys {
+		if tenantID == "" || key.TenantID == tenantID {
+			keys = append(keys, cloneAPIKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func cloneAPIKey(key *models.APIKey) *models.APIKey {
+	clone := *key
+	clone.Scopes = append([]string(nil), key.Scopes...)
+	clone.EmployerIDs = append([]string(nil), key.EmployerIDs...)
+	return &clone
+}
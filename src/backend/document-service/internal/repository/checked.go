@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models/invariants"
+)
+
+// CheckedDocumentRepository wraps a DocumentRepository, refusing to persist
+// a document that breaks the invariants of document metadata. New documents
+// must hold every invariant; a change must not break one the stored document
+// held, so documents stored before an invariant was enforced can still
+// change until they are repaired. Refusals fail with an *invariants.Error
+type CheckedDocumentRepository struct {
+	repo DocumentRepository
+}
+
+// NewCheckedDocumentRepository wraps repo, checking the invariants before
+// every write
+func NewCheckedDocumentRepository(repo DocumentRepository) *CheckedDocumentRepository {
+	return &CheckedDocumentRepository{repo: repo}
+}
+
+// Create stores a new document holding every invariant
+func (r *CheckedDocumentRepository) Create(ctx context.Context, doc *models.Document) error {
+	if err := invariants.Validate(doc); err != nil {
+		return err
+	}
+	return r.repo.Create(ctx, doc)
+}
+
+// GetByID returns the document from the repository
+func (r *CheckedDocumentRepository) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+// Update replaces an existing document, unless the change breaks an
+// invariant the stored document held
+func (r *CheckedDocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	if err := r.check(ctx, doc); err != nil {
+		return err
+	}
+	return r.repo.Update(ctx, doc)
+}
+
+// Redact records a change removing personal data through the repository
+// when it keeps past states, unless the change breaks an invariant the
+// stored document held
+func (r *CheckedDocumentRepository) Redact(ctx context.Context, doc *models.Document) error {
+	redactor, ok := r.repo.(interface {
+		Redact(ctx context.Context, doc *models.Document) error
+	})
+	if !ok {
+		return r.Update(ctx, doc)
+	}
+	if err := r.check(ctx, doc); err != nil {
+		return err
+	}
+	return redactor.Redact(ctx, doc)
+}
+
+// check validates the change from the stored document to doc
+func (r *CheckedDocumentRepository) check(ctx context.Context, doc *models.Document) error {
+	current, err := r.repo.GetByID(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+	return invariants.ValidateChange(current, doc)
+}
+
+// Delete removes a document
+func (r *CheckedDocumentRepository) Delete(ctx context.Context, id string) error {
+	return r.repo.Delete(ctx, id)
+}
+
+// ListByEnrollment returns the documents of an enrollment from the repository
+func (r *CheckedDocumentRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error) {
+	return r.repo.ListByEnrollment(ctx, enrollmentID)
+}
+
+// ListUpdatedBetween returns the documents updated in [from, to) from the
+// repository
+func (r *CheckedDocumentRepository) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error) {
+	return r.repo.ListUpdatedBetween(ctx, from, to)
+}
@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrVersionUnavailable = errors.New("document version is not available yet")
+)
+
+// DocumentCache holds recently written documents shared by every instance,
+// so a write is readable before the store serving reads has caught up
+type DocumentCache interface {
+	// Put caches the document until expiresAt unless a later version of it
+	// is already cached
+	Put(ctx context.Context, doc *models.Document, expiresAt time.Time) error
+	Get(ctx context.Context, id string) (*models.Document, error)
+	Delete(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+type cachedDocument struct {
+	doc       *models.Document
+	expiresAt time.Time
+}
+
+// MemoryDocumentCache is an in-process DocumentCache for single-instance
+// deployments and tests
+type MemoryDocumentCache struct {
+	mu        sync.Mutex
+	documents map[string]cachedDocument
+}
+
+// NewMemoryDocumentCache creates an empty in-memory document cache
+func NewMemoryDocumentCache() *MemoryDocumentCache {
+	return &MemoryDocumentCache{
+		documents: make(map[string]cachedDocument),
+	}
+}
+
+// Put caches a copy of the document
+func (c *MemoryDocumentCache) Put(ctx context.Context, doc *models.Document, expiresAt time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.documents[doc.ID]; ok && cached.doc.Version > doc.Version {
+		return nil
+	}
+	c.documents[doc.ID] = cachedDocument{doc: cloneDocument(doc), expiresAt: expiresAt}
+	return nil
+}
+
+// Get returns a copy of the cached document
+func (c *MemoryDocumentCache) Get(ctx context.Context, id string) (*models.Document, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.documents[id]
+	if !ok || !time.Now().Before(cached.expiresAt) {
+		return nil, ErrDocumentNotFound
+	}
+	return cloneDocument(cached.doc), nil
+}
+
+// Delete drops a document from the cache
+func (c *MemoryDocumentCache) Delete(ctx context.Context, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.documents, id)
+	return nil
+}
+
+// DeleteExpired drops the documents expired at now and returns how many were
+// dropped
+func (c *MemoryDocumentCache) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deleted := 0
+	for id, cached := range c.documents {
+		if !now.Before(cached.expiresAt) {
+			delete(c.documents, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// PostgresDocumentCache caches documents in cached_documents, shared by
+// every instance using the database
+type PostgresDocumentCache struct {
+	db *sql.DB
+}
+
+// NewPostgresDocumentCache creates a document cache on db
+func NewPostgresDocumentCache(db *sql.DB) *PostgresDocumentCache {
+	return &PostgresDocumentCache{db: db}
+}
+
+// Put caches the document unless a later version of it is already cached
+func (c *PostgresDocumentCache) Put(ctx context.Context, doc *models.Document, expiresAt time.Time) error {
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode cached document: %w", err)
+	}
+	_, err = c.db.ExecContext(ctx, `
+		INSERT INTO cached_documents (id, version, document, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET version = EXCLUDED.version, document = EXCLUDED.document, expires_at = EXCLUDED.expires_at
+		WHERE cached_documents.version <= EXCLUDED.version`,
+		doc.ID, doc.Version, encoded, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to cache document: %w", err)
+	}
+	return nil
+}
+
+// Get returns the cached document unless it has expired
+func (c *PostgresDocumentCache) Get(ctx context.Context, id string) (*models.Document, error) {
+	var encoded []byte
+	err := c.db.QueryRowContext(ctx, `SELECT document FROM cached_documents WHERE id = $1 AND expires_at > now()`, id).Scan(&encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached document: %w", err)
+	}
+
+	var doc models.Document
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode cached document: %w", err)
+	}
+	return &doc, nil
+}
+
+// Delete drops a document from the cache
+func (c *PostgresDocumentCache) Delete(ctx context.Context, id string) error {
+	if _, err := c.db.ExecContext(ctx, `DELETE FROM cached_documents WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to drop cached document: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired drops the documents expired at now and returns how many were
+// dropped
+func (c *PostgresDocumentCache) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := c.db.ExecContext(ctx, `DELETE FROM cached_documents WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop expired cached documents: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// WriteThroughDocumentRepository writes every created or updated document to
+// a cache as well as to the repository, so a document can be read at the
+// version a write returned even where the repository's reads lag its writes.
+// Plain reads are served by the repository alone
+type WriteThroughDocumentRepository struct {
+	repo  DocumentRepository
+	cache DocumentCache
+	ttl   time.Duration
+}
+
+// NewWriteThroughDocumentRepository wraps repo, caching written documents
+// for ttl
+func NewWriteThroughDocumentRepository(repo DocumentRepository, cache DocumentCache, ttl time.Duration) *WriteThroughDocumentRepository {
+	return &WriteThroughDocumentRepository{
+		repo:  repo,
+		cache: cache,
+		ttl:   ttl,
+	}
+}
+
+// Create stores a new document and caches it
+func (r *WriteThroughDocumentRepository) Create(ctx context.Context, doc *models.Document) error {
+	if err := r.repo.Create(ctx, doc); err != nil {
+		return err
+	}
+	return r.cache.Put(ctx, doc, time.Now().Add(r.ttl))
+}
+
+// GetByID returns the document from the repository
+func (r *WriteThroughDocumentRepository) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+// Update replaces an existing document and caches the new version
+func (r *WriteThroughDocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	if err := r.repo.Update(ctx, doc); err != nil {
+		return err
+	}
+	return r.cache.Put(ctx, doc, time.Now().Add(r.ttl))
+}
+
+// Redact records a change removing personal data through the repository
+// when it keeps past states, and replaces the cached document with the
+// redacted one
+func (r *WriteThroughDocumentRepository) Redact(ctx context.Context, doc *models.Document) error {
+	redactor, ok := r.repo.(interface {
+		Redact(ctx context.Context, doc *models.Document) error
+	})
+	if !ok {
+		return r.Update(ctx, doc)
+	}
+	if err := redactor.Redact(ctx, doc); err != nil {
+		return err
+	}
+	return r.cache.Put(ctx, doc, time.Now().Add(r.ttl))
+}
+
+// Delete removes a document and drops it from the cache
+func (r *WriteThroughDocumentRepository) Delete(ctx context.Context, id string) error {
+	if err := r.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	return r.cache.Delete(ctx, id)
+}
+
+// ListByEnrollment returns the documents of an enrollment from the repository
+func (r *WriteThroughDocumentRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error) {
+	return r.repo.ListByEnrollment(ctx, enrollmentID)
+}
+
+// ListUpdatedBetween returns the documents updated in [from, to) from the
+// repository
+func (r *WriteThroughDocumentRepository) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error) {
+	return r.repo.ListUpdatedBetween(ctx, from, to)
+}
+
+// GetVersion returns the document at version or later, from the repository
+// when it has caught up and from the cache otherwise, reporting whether it
+// came from the cache. It fails with ErrVersionUnavailable when neither
+// holds that version yet, and with ErrDocumentNotFound when neither holds
+// the document at all
+func (r *WriteThroughDocumentRepository) GetVersion(ctx context.Context, id string, version int64) (*models.Document, bool, error) {
+	doc, err := r.repo.GetByID(ctx, id)
+	if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+		return nil, false, err
+	}
+	if doc != nil && doc.Version >= version {
+		return doc, false, nil
+	}
+
+	cached, err := r.cache.Get(ctx, id)
+	if err != nil && !errors.Is(err, ErrDocumentNotFound) {
+		return nil, false, err
+	}
+	if cached != nil && cached.Version >= version {
+		return cached, true, nil
+	}
+	if doc == nil && cached == nil {
+		return nil, false, ErrDocumentNotFound
+	}
+	return nil, false, ErrVersionUnavailable
+}
+
+// DeleteExpired drops the cached documents expired at now
+func (r *WriteThroughDocumentRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	return r.cache.DeleteExpired(ctx, now)
+}
@@ -0,0 +1,352 @@
+// Package repository provides persistence for document metadata
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid" // v1.3.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrDocumentExists   = errors.New("document already exists")
+)
+
+// DocumentRepository persists document metadata independently of object storage
+type DocumentRepository interface {
+	Create(ctx context.Context, doc *models.Document) error
+	GetByID(ctx context.Context, id string) (*models.Document, error)
+	Update(ctx context.Context, doc *models.Document) error
+	Delete(ctx context.Context, id string) error
+	ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error)
+	ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error)
+}
+
+// MemoryDocumentRepository is an in-process DocumentRepository used for
+// single-instance deployments and tests
+type MemoryDocumentRepository struct {
+	mu        sync.RWMutex
+	documents map[string]*models.Document
+}
+
+// NewMemoryDocumentRepository creates an empty in-memory repository
+func NewMemoryDocumentRepository() *MemoryDocumentRepository {
+	return &MemoryDocumentRepository{
+		documents: make(map[string]*models.Document),
+	}
+}
+
+// Create stores a new document
+func (r *MemoryDocumentRepository) Create(ctx context.Context, doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.documents[doc.ID]; ok {
+		return ErrDocumentExists
+	}
+	r.documents[doc.ID] = cloneDocument(doc)
+	return nil
+}
+
+// GetByID returns a copy of the stored document
+func (r *MemoryDocumentRepository) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	doc, ok := r.documents[id]
+	if !ok {
+		return nil, ErrDocumentNotFound
+	}
+	return cloneDocument(doc), nil
+}
+
+// Update replaces an existing document
+func (r *MemoryDocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.documents[doc.ID]; !ok {
+		return ErrDocumentNotFound
+	}
+	r.documents[doc.ID] = cloneDocument(doc)
+	return nil
+}
+
+// Delete removes a document
+func (r *MemoryDocumentRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.documents[id]; !ok {
+		return ErrDocumentNotFound
+	}
+	delete(r.documents, id)
+	return nil
+}
+
+// ListByEnrollment returns all documents of an enrollment ordered by creation time
+func (r *MemoryDocumentRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docs := make([]*models.Document, 0)
+	for _, doc := range r.documents {
+		if doc.EnrollmentID == enrollmentID {
+			docs = append(docs, cloneDocument(doc))
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].CreatedAt.Before(docs[j].CreatedAt)
+	})
+	return docs, nil
+}
+
+// ListUpdatedBetween returns the documents last updated in [from, to) ordered
+// by update time
+func (r *MemoryDocumentRepository) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docs := make([]*models.Document, 0)
+	for _, doc := range r.documents {
+		if !doc.UpdatedAt.Before(from) && doc.UpdatedAt.Before(to) {
+			docs = append(docs, cloneDocument(doc))
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].UpdatedAt.Before(docs[j].UpdatedAt)
+	})
+	return docs, nil
+}
+
+// PostgresDocumentRepository keeps documents in the documents table, shared
+// by every instance using the database. The columns hold the fields documents
+// are looked up and listed by; the whole document, whose shape evolves with
+// the pipeline, is kept in processing and read back from there
+type PostgresDocumentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDocumentRepository creates a document repository on db
+func NewPostgresDocumentRepository(db *sql.DB) *PostgresDocumentRepository {
+	return &PostgresDocumentRepository{db: db}
+}
+
+// Create stores a new document
+func (r *PostgresDocumentRepository) Create(ctx context.Context, doc *models.Document) error {
+	row, err := encodeDocumentRow(doc)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO documents (id, enrollment_id, document_type, filename, content_type, size, status, ingestion_channel,
+			storage_path, content_hash, encryption_info, processing, audit_trail, created_at, updated_at,
+			processed_at, reviewed_at, reviewed_by, retention_date, expiry, hold)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''), $19, $20, $21)
+		ON CONFLICT (id) DO NOTHING`,
+		row...)
+	if err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+	if inserted == 0 {
+		return ErrDocumentExists
+	}
+	return nil
+}
+
+// GetByID returns the stored document
+func (r *PostgresDocumentRepository) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrDocumentNotFound
+	}
+	var processing []byte
+	err := r.db.QueryRowContext(ctx, `SELECT processing FROM documents WHERE id = $1`, id).Scan(&processing)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDocumentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	return decodeDocument(processing)
+}
+
+// Update replaces an existing document
+func (r *PostgresDocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	row, err := encodeDocumentRow(doc)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE documents
+		SET enrollment_id = NULLIF($2, '')::uuid, document_type = $3, filename = $4, content_type = $5, size = $6, status = $7,
+			ingestion_channel = $8, storage_path = $9, content_hash = $10, encryption_info = $11, processing = $12,
+			audit_trail = $13, created_at = $14, updated_at = $15, processed_at = $16, reviewed_at = $17,
+			reviewed_by = NULLIF($18, ''), retention_date = $19, expiry = $20, hold = $21
+		WHERE id = $1`,
+		row...)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	if updated == 0 {
+		return ErrDocumentNotFound
+	}
+	return nil
+}
+
+// Delete removes a document
+func (r *PostgresDocumentRepository) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrDocumentNotFound
+	}
+	result, err := r.db.ExecContext(ctx, `DELETE FROM documents WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	if deleted == 0 {
+		return ErrDocumentNotFound
+	}
+	return nil
+}
+
+// ListByEnrollment returns all documents of an enrollment ordered by creation time
+func (r *PostgresDocumentRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error) {
+	if _, err := uuid.Parse(enrollmentID); err != nil {
+		return make([]*models.Document, 0), nil
+	}
+	return r.list(ctx, `SELECT processing FROM documents WHERE enrollment_id = $1 ORDER BY created_at`, enrollmentID)
+}
+
+// ListUpdatedBetween returns the documents last updated in [from, to) ordered
+// by update time
+func (r *PostgresDocumentRepository) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error) {
+	return r.list(ctx, `SELECT processing FROM documents WHERE updated_at >= $1 AND updated_at < $2 ORDER BY updated_at`, from, to)
+}
+
+func (r *PostgresDocumentRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.Document, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := make([]*models.Document, 0)
+	for rows.Next() {
+		var processing []byte
+		if err := rows.Scan(&processing); err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		doc, err := decodeDocument(processing)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// encodeDocumentRow returns the column values of a document, in the order
+// of the insert and update statements
+func encodeDocumentRow(doc *models.Document) ([]interface{}, error) {
+	processing, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	auditTrail, err := json.Marshal(append([]models.AuditLog{}, doc.AuditTrail...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document audit trail: %w", err)
+	}
+	// Unset fields are stored as NULL
+	var encryptionInfo, expiry, hold []byte
+	if doc.EncryptionInfo != nil {
+		if encryptionInfo, err = json.Marshal(doc.EncryptionInfo); err != nil {
+			return nil, fmt.Errorf("failed to encode document encryption: %w", err)
+		}
+	}
+	if doc.Expiry != nil {
+		if expiry, err = json.Marshal(doc.Expiry); err != nil {
+			return nil, fmt.Errorf("failed to encode document expiry: %w", err)
+		}
+	}
+	if doc.Hold != nil {
+		if hold, err = json.Marshal(doc.Hold); err != nil {
+			return nil, fmt.Errorf("failed to encode document hold: %w", err)
+		}
+	}
+	return []interface{}{
+		doc.ID, doc.EnrollmentID, doc.DocumentType, doc.Filename, doc.ContentType, doc.Size, doc.Status, doc.IngestionChannel,
+		doc.StoragePath, doc.ContentHash, encryptionInfo, processing, auditTrail, doc.CreatedAt, doc.UpdatedAt,
+		doc.ProcessedAt, doc.ReviewedAt, doc.ReviewedBy, doc.RetentionDate, expiry, hold,
+	}, nil
+}
+
+func decodeDocument(processing []byte) (*models.Document, error) {
+	var doc models.Document
+	if err := json.Unmarshal(processing, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	return &doc, nil
+}
+
+// cloneDocument copies a document so callers never share state with the store
+func cloneDocument(doc *models.Document) *models.Document {
+	clone := *doc
+	clone.AuditTrail = append([]models.AuditLog(nil), doc.AuditTrail...)
+	clone.ExtractedFields = append([]models.ExtractedField(nil), doc.ExtractedFields...)
+	clone.Signatures = append([]models.SignatureInfo(nil), doc.Signatures...)
+	clone.Screening = append([]models.ScreeningResult(nil), doc.Screening...)
+	clone.ReviewFlags = append([]string(nil), doc.ReviewFlags...)
+	clone.SLAEscalations = append([]models.SLAEscalation(nil), doc.SLAEscalations...)
+	clone.Experiments = append([]models.ExperimentAssignment(nil), doc.Experiments...)
+	clone.Renditions = append([]models.Rendition(nil), doc.Renditions...)
+	for i, rendition := range clone.Renditions {
+		if rendition.Encryption != nil {
+			encryption := *rendition.Encryption
+			clone.Renditions[i].Encryption = &encryption
+		}
+	}
+	clone.OCRPages = append([]models.OCRPage(nil), doc.OCRPages...)
+	clone.PageReviews = append([]models.PageReview(nil), doc.PageReviews...)
+	clone.ProcessingActivities = append([]models.ProcessingActivity(nil), doc.ProcessingActivities...)
+	if doc.AutoDecision != nil {
+		decision := *doc.AutoDecision
+		clone.AutoDecision = &decision
+	}
+	if doc.EncryptionInfo != nil {
+		info := *doc.EncryptionInfo
+		clone.EncryptionInfo = &info
+	}
+	if doc.Disposition != nil {
+		disposition := *doc.Disposition
+		clone.Disposition = &disposition
+	}
+	if doc.Expiry != nil {
+		expiry := *doc.Expiry
+		clone.Expiry = &expiry
+	}
+	if doc.Hold != nil {
+		hold := *doc.Hold
+		clone.Hold = &hold
+	}
+	return &clone
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var ErrEventConflict = errors.New("document events were appended concurrently")
+
+// DocumentEventRepository is the append-only store of document events
+type DocumentEventRepository interface {
+	// Append stores events after the expected last sequence of the document,
+	// failing with ErrEventConflict when another writer appended first
+	Append(ctx context.Context, documentID string, expected int64, events []*models.DocumentEvent) error
+	// List returns the events of a document after a sequence, oldest first
+	List(ctx context.Context, documentID string, after int64) ([]*models.DocumentEvent, error)
+	// Last returns the latest event of a document, or nil when it has none
+	Last(ctx context.Context, documentID string) (*models.DocumentEvent, error)
+	// DocumentIDs lists every document with events
+	DocumentIDs(ctx context.Context) ([]string, error)
+	// Erase removes the patches of a document's events, keeping their digests
+	Erase(ctx context.Context, documentID string) error
+}
+
+// MemoryDocumentEventRepository is an in-process DocumentEventRepository for
+// single-instance deployments and tests
+type MemoryDocumentEventRepository struct {
+	mu     sync.RWMutex
+	events map[string][]*models.DocumentEvent
+}
+
+// NewMemoryDocumentEventRepository creates an empty in-memory event store
+func NewMemoryDocumentEventRepository() *MemoryDocumentEventRepository {
+	return &MemoryDocumentEventRepository{
+		events: make(map[string][]*models.DocumentEvent),
+	}
+}
+
+// Append stores events after the expected last sequence
+func (r *MemoryDocumentEventRepository) Append(ctx context.Context, documentID string, expected int64, events []*models.DocumentEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if int64(len(r.events[documentID])) != expected {
+		return ErrEventConflict
+	}
+	for _, event := range events {
+		clone := *event
+		r.events[documentID] = append(r.events[documentID], &clone)
+	}
+	return nil
+}
+
+// List returns the events of a document after a sequence
+func (r *MemoryDocumentEventRepository) List(ctx context.Context, documentID string, after int64) ([]*models.DocumentEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*models.DocumentEvent, 0)
+	for _, event := range r.events[documentID] {
+		if event.Sequence > after {
+			clone := *event
+			events = append(events, &clone)
+		}
+	}
+	return events, nil
+}
+
+// Last returns the latest event of a document
+func (r *MemoryDocumentEventRepository) Last(ctx context.Context, documentID string) (*models.DocumentEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := r.events[documentID]
+	if len(events) == 0 {
+		return nil, nil
+	}
+	clone := *events[len(events)-1]
+	return &clone, nil
+}
+
+// DocumentIDs lists every document with events in a stable order
+func (r *MemoryDocumentEventRepository) DocumentIDs(ctx context.Context) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, 0, len(r.events))
+	for id := range r.events {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Erase removes the patches of a document's events
+func (r *MemoryDocumentEventRepository) Erase(ctx context.Context, documentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range r.events[documentID] {
+		event.Erase()
+	}
+	return nil
+}
+
+// PostgresDocumentEventRepository keeps document events in document_events,
+// so the history survives a restart and every instance appends to the same
+// chain
+type PostgresDocumentEventRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDocumentEventRepository creates an event store on db
+func NewPostgresDocumentEventRepository(db *sql.DB) *PostgresDocumentEventRepository {
+	return &PostgresDocumentEventRepository{db: db}
+}
+
+// Append stores events after the expected last sequence. An event whose
+// sequence was taken by another writer is left alone by the insert, and
+// the whole append is rolled back
+func (r *PostgresDocumentEventRepository) Append(ctx context.Context, documentID string, expected int64, events []*models.DocumentEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin event transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var last int64
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(sequence), 0) FROM document_events WHERE document_id = $1`, documentID).Scan(&last)
+	if err != nil {
+		return fmt.Errorf("failed to read last document event: %w", err)
+	}
+	if last != expected {
+		return ErrEventConflict
+	}
+	for _, event := range events {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO document_events (document_id, sequence, type, status, reason, actor, occurred_at, patch, patch_digest, erased, prev_hash, hash)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, $8, $9, $10, NULLIF($11, ''), $12)
+			ON CONFLICT (document_id, sequence) DO NOTHING`,
+			documentID, event.Sequence, event.Type, event.Status, event.Reason, event.Actor, event.OccurredAt,
+			[]byte(event.Patch), event.PatchDigest, event.Erased, event.PrevHash, event.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to append document event: %w", err)
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to append document event: %w", err)
+		}
+		if inserted == 0 {
+			return ErrEventConflict
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document events: %w", err)
+	}
+	return nil
+}
+
+// List returns the events of a document after a sequence
+func (r *PostgresDocumentEventRepository) List(ctx context.Context, documentID string, after int64) ([]*models.DocumentEvent, error) {
+	rows, err := r.db.QueryContext(ctx, documentEventColumns+`
+		WHERE document_id = $1 AND sequence > $2
+		ORDER BY sequence`,
+		documentID, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.DocumentEvent, 0)
+	for rows.Next() {
+		event, err := scanDocumentEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Last returns the latest event of a document
+func (r *PostgresDocumentEventRepository) Last(ctx context.Context, documentID string) (*models.DocumentEvent, error) {
+	event, err := scanDocumentEvent(r.db.QueryRowContext(ctx, documentEventColumns+`
+		WHERE document_id = $1
+		ORDER BY sequence DESC
+		LIMIT 1`,
+		documentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// DocumentIDs lists every document with events in a stable order
+func (r *PostgresDocumentEventRepository) DocumentIDs(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT document_id::text FROM document_events ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents with events: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read document ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Erase removes the patches of a document's events
+func (r *PostgresDocumentEventRepository) Erase(ctx context.Context, documentID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE document_events SET patch = NULL, erased = true WHERE document_id = $1`, documentID)
+	if err != nil {
+		return fmt.Errorf("failed to erase document events: %w", err)
+	}
+	return nil
+}
+
+const documentEventColumns = `
+	SELECT document_id::text, sequence, type, COALESCE(status, ''), COALESCE(reason, ''), COALESCE(actor, ''), occurred_at,
+		patch, patch_digest, erased, COALESCE(prev_hash, ''), hash
+	FROM document_events`
+
+func scanDocumentEvent(row rowScanner) (*models.DocumentEvent, error) {
+	var event models.DocumentEvent
+	var patch []byte
+	err := row.Scan(&event.DocumentID, &event.Sequence, &event.Type, &event.Status, &event.Reason, &event.Actor, &event.OccurredAt,
+		&patch, &event.PatchDigest, &event.Erased, &event.PrevHash, &event.Hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document event: %w", err)
+	}
+	if patch != nil {
+		event.Patch = patch
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+	return &event, nil
+}
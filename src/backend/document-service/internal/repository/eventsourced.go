@@ -0,0 +1,412 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var ErrStateErased = errors.New("document state was erased")
+
+// EventSourcedDocumentRepository records every change to a document as
+// events in an append-only store and serves reads from a projection of the
+// current state. The events of a change are taken from the audit entries it
+// added, and the change itself is kept as a JSON merge patch, so the
+// projection can be rebuilt from the events after a bug corrupts it
+type EventSourcedDocumentRepository struct {
+	events     DocumentEventRepository
+	projection DocumentRepository
+	// mu serializes writers in this process; writers in other processes are
+	// detected by the event store's sequence check
+	mu sync.Mutex
+}
+
+// NewEventSourcedDocumentRepository creates a repository appending to events
+// and projecting the current state into projection
+func NewEventSourcedDocumentRepository(events DocumentEventRepository, projection DocumentRepository) *EventSourcedDocumentRepository {
+	return &EventSourcedDocumentRepository{
+		events:     events,
+		projection: projection,
+	}
+}
+
+// Create records the upload of a new document as its first version
+func (r *EventSourcedDocumentRepository) Create(ctx context.Context, doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc.Version = 1
+	state, err := documentState(doc)
+	if err != nil {
+		return err
+	}
+	events := models.EventsForAudit(doc.ID, doc.AuditTrail)
+	if len(events) == 0 {
+		events = []*models.DocumentEvent{{DocumentID: doc.ID, Type: models.EventDocumentUploaded, OccurredAt: eventTime(doc.CreatedAt)}}
+	}
+
+	if err := r.append(ctx, doc.ID, nil, events, state); err != nil {
+		if errors.Is(err, ErrEventConflict) {
+			return ErrDocumentExists
+		}
+		return err
+	}
+	return r.projection.Create(ctx, doc)
+}
+
+// GetByID returns the current state of a document
+func (r *EventSourcedDocumentRepository) GetByID(ctx context.Context, id string) (*models.Document, error) {
+	return r.projection.GetByID(ctx, id)
+}
+
+// Update records the change from the current state as one event per audit
+// entry added, or a DocumentUpdated event when none was. A change is given
+// the version after the current one; an empty change is not recorded and
+// keeps the current version
+func (r *EventSourcedDocumentRepository) Update(ctx context.Context, doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.projection.GetByID(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+	before, err := documentState(current)
+	if err != nil {
+		return err
+	}
+	after, err := documentState(doc)
+	if err != nil {
+		return err
+	}
+	patch := mergePatch(before, after)
+	delete(patch, "version")
+	if len(patch) == 0 {
+		doc.Version = current.Version
+		return nil
+	}
+	doc.Version = current.Version + 1
+	patch["version"] = doc.Version
+
+	events := changeEvents(current, doc)
+	last, err := r.events.Last(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read document events: %w", err)
+	}
+	if err := r.append(ctx, doc.ID, last, events, patch); err != nil {
+		return err
+	}
+	return r.projection.Update(ctx, doc)
+}
+
+// Redact records a change removing personal data from a document. The
+// patches of the earlier events, which hold that data, are erased and the
+// change carries the whole new state, so the document can still be replayed
+func (r *EventSourcedDocumentRepository) Redact(ctx context.Context, doc *models.Document) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.projection.GetByID(ctx, doc.ID)
+	if err != nil {
+		return err
+	}
+	doc.Version = current.Version + 1
+	state, err := documentState(doc)
+	if err != nil {
+		return err
+	}
+
+	// Erasing first leaves a failed redaction safe to retry
+	if err := r.events.Erase(ctx, doc.ID); err != nil {
+		return fmt.Errorf("failed to erase document events: %w", err)
+	}
+	last, err := r.events.Last(ctx, doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read document events: %w", err)
+	}
+	if err := r.append(ctx, doc.ID, last, changeEvents(current, doc), state); err != nil {
+		return err
+	}
+	return r.projection.Update(ctx, doc)
+}
+
+// Delete records the deletion of a document and erases the patches of its
+// events, which hold its personal data; the events themselves are kept
+func (r *EventSourcedDocumentRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last, err := r.events.Last(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read document events: %w", err)
+	}
+	if last == nil {
+		return r.projection.Delete(ctx, id)
+	}
+
+	deleted := &models.DocumentEvent{DocumentID: id, Type: models.EventDocumentDeleted, OccurredAt: eventTime(time.Now())}
+	if err := r.append(ctx, id, last, []*models.DocumentEvent{deleted}, nil); err != nil {
+		return err
+	}
+	if err := r.events.Erase(ctx, id); err != nil {
+		return fmt.Errorf("failed to erase document events: %w", err)
+	}
+	return r.projection.Delete(ctx, id)
+}
+
+// ListByEnrollment returns the documents of an enrollment from the projection
+func (r *EventSourcedDocumentRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.Document, error) {
+	return r.projection.ListByEnrollment(ctx, enrollmentID)
+}
+
+// ListUpdatedBetween returns the documents updated in [from, to) from the projection
+func (r *EventSourcedDocumentRepository) ListUpdatedBetween(ctx context.Context, from, to time.Time) ([]*models.Document, error) {
+	return r.projection.ListUpdatedBetween(ctx, from, to)
+}
+
+// History returns the verified events of a document, oldest first
+func (r *EventSourcedDocumentRepository) History(ctx context.Context, id string) ([]*models.DocumentEvent, error) {
+	events, err := r.events.List(ctx, id, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	prevHash := ""
+	for _, event := range events {
+		if err := event.Verify(prevHash); err != nil {
+			return nil, err
+		}
+		prevHash = event.Hash
+	}
+	return events, nil
+}
+
+// Replay folds the events of a document into its state. It returns nil for
+// a deleted document
+func (r *EventSourcedDocumentRepository) Replay(ctx context.Context, id string) (*models.Document, error) {
+	events, err := r.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return fold(id, events)
+}
+
+// ReplayAt folds the events of a document that occurred up to asOf into the
+// state it had then. It returns nil if the document was deleted by then,
+// ErrDocumentNotFound if it was not uploaded yet and ErrStateErased if that
+// state held personal data since erased
+func (r *EventSourcedDocumentRepository) ReplayAt(ctx context.Context, id string, asOf time.Time) (*models.Document, error) {
+	events, err := r.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	n := sort.Search(len(events), func(i int) bool {
+		return events[i].OccurredAt.After(asOf)
+	})
+	if n == 0 {
+		return nil, ErrDocumentNotFound
+	}
+	// A redaction carries the whole state after it, so only the states
+	// before it are lost
+	if last := events[n-1]; last.Erased && last.Type != models.EventDocumentDeleted {
+		return nil, ErrStateErased
+	}
+	return fold(id, events[:n])
+}
+
+// fold applies the patches of the events in order
+func fold(id string, events []*models.DocumentEvent) (*models.Document, error) {
+	state := make(map[string]interface{})
+	for _, event := range events {
+		if event.Type == models.EventDocumentDeleted {
+			return nil, nil
+		}
+		if len(event.Patch) == 0 {
+			continue
+		}
+		var patch map[string]interface{}
+		if err := json.Unmarshal(event.Patch, &patch); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d of document %s: %w", event.Sequence, id, err)
+		}
+		applyMergePatch(state, patch)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var doc models.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to rebuild document %s: %w", id, err)
+	}
+	return &doc, nil
+}
+
+// Rebuild replaces the projection of a document with the state its events
+// describe
+func (r *EventSourcedDocumentRepository) Rebuild(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	doc, err := r.Replay(ctx, id)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		if err := r.projection.Delete(ctx, id); err != nil && !errors.Is(err, ErrDocumentNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	err = r.projection.Update(ctx, doc)
+	if errors.Is(err, ErrDocumentNotFound) {
+		err = r.projection.Create(ctx, doc)
+	}
+	return err
+}
+
+// RebuildAll rebuilds the projection of every document with events and
+// returns how many were rebuilt; documents that fail are reported together
+func (r *EventSourcedDocumentRepository) RebuildAll(ctx context.Context) (int, error) {
+	ids, err := r.events.DocumentIDs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents with events: %w", err)
+	}
+
+	rebuilt := 0
+	var failures []error
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return rebuilt, err
+		}
+		if err := r.Rebuild(ctx, id); err != nil {
+			failures = append(failures, fmt.Errorf("document %s: %w", id, err))
+			continue
+		}
+		rebuilt++
+	}
+	return rebuilt, errors.Join(failures...)
+}
+
+// append numbers, patches and chains events after last and stores them. The
+// patch is carried by the last event of the batch
+func (r *EventSourcedDocumentRepository) append(ctx context.Context, id string, last *models.DocumentEvent, events []*models.DocumentEvent, patch map[string]interface{}) error {
+	var sequence int64
+	prevHash := ""
+	if last != nil {
+		sequence = last.Sequence
+		prevHash = last.Hash
+	}
+	expected := sequence
+
+	if patch != nil {
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("failed to encode document change: %w", err)
+		}
+		events[len(events)-1].Patch = data
+	}
+	for _, event := range events {
+		sequence++
+		event.Sequence = sequence
+		event.Seal(prevHash)
+		prevHash = event.Hash
+	}
+
+	if err := r.events.Append(ctx, id, expected, events); err != nil {
+		return fmt.Errorf("failed to append document events: %w", err)
+	}
+	return nil
+}
+
+// changeEvents returns one event per audit entry doc added to current, or a
+// DocumentUpdated event when none was
+func changeEvents(current, doc *models.Document) []*models.DocumentEvent {
+	var added []models.AuditLog
+	if len(doc.AuditTrail) > len(current.AuditTrail) {
+		added = doc.AuditTrail[len(current.AuditTrail):]
+	}
+	events := models.EventsForAudit(doc.ID, added)
+	if len(events) == 0 {
+		events = []*models.DocumentEvent{{DocumentID: doc.ID, Type: models.EventDocumentUpdated, Status: doc.Status, OccurredAt: eventTime(time.Now())}}
+	}
+	return events
+}
+
+// documentState returns the JSON object form of a document
+func documentState(doc *models.Document) (map[string]interface{}, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	return state, nil
+}
+
+// mergePatch returns the RFC 7396 merge patch turning before into after
+func mergePatch(before, after map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range after {
+		previous, ok := before[key]
+		if !ok {
+			patch[key] = value
+			continue
+		}
+		if reflect.DeepEqual(previous, value) {
+			continue
+		}
+		previousObject, wasObject := previous.(map[string]interface{})
+		object, isObject := value.(map[string]interface{})
+		if wasObject && isObject {
+			patch[key] = mergePatch(previousObject, object)
+			continue
+		}
+		patch[key] = value
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// applyMergePatch applies an RFC 7396 merge patch to target in place
+func applyMergePatch(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			target[key] = value
+			continue
+		}
+		existing, ok := target[key].(map[string]interface{})
+		if !ok {
+			existing = make(map[string]interface{})
+			target[key] = existing
+		}
+		applyMergePatch(existing, object)
+	}
+}
+
+// eventTime truncates to the precision the event store keeps
+func eventTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// KeyUsageFilter narrows a key usage query; empty fields match every event
+type KeyUsageFilter struct {
+	DocumentID string
+	Principal  string
+	From       time.Time
+	To         time.Time
+}
+
+// KeyUsageRepository is the append-only audit store of data key uses
+type KeyUsageRepository interface {
+	Append(ctx context.Context, event *models.KeyUsageEvent) error
+	List(ctx context.Context, filter KeyUsageFilter) ([]*models.KeyUsageEvent, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int, error)
+}
+
+// MemoryKeyUsageRepository is an in-process KeyUsageRepository for
+// single-instance deployments and tests. Its events are lost on restart,
+// whatever their retention
+type MemoryKeyUsageRepository struct {
+	mu     sync.RWMutex
+	events []*models.KeyUsageEvent
+}
+
+// NewMemoryKeyUsageRepository creates an empty in-memory key usage store
+func NewMemoryKeyUsageRepository() *MemoryKeyUsageRepository {
+	return &MemoryKeyUsageRepository{
+		events: make([]*models.KeyUsageEvent, 0),
+	}
+}
+
+// Append stores an event
+func (r *MemoryKeyUsageRepository) Append(ctx context.Context, event *models.KeyUsageEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clone := *event
+	r.events = append(r.events, &clone)
+	return nil
+}
+
+// List returns the events matching the filter that occurred in [From, To),
+// oldest first; a zero To has no upper bound
+func (r *MemoryKeyUsageRepository) List(ctx context.Context, filter KeyUsageFilter) ([]*models.KeyUsageEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*models.KeyUsageEvent, 0)
+	for _, event := range r.events {
+		if filter.DocumentID != "" && event.DocumentID != filter.DocumentID {
+			continue
+		}
+		if filter.Principal != "" && event.Principal != filter.Principal {
+			continue
+		}
+		if event.OccurredAt.Before(filter.From) || (!filter.To.IsZero() && !event.OccurredAt.Before(filter.To)) {
+			continue
+		}
+		clone := *event
+		events = append(events, &clone)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.Before(events[j].OccurredAt)
+	})
+	return events, nil
+}
+
+// DeleteBefore removes the events that occurred before the cutoff and returns
+// how many were removed
+func (r *MemoryKeyUsageRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.events[:0]
+	for _, event := range r.events {
+		if !event.OccurredAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	removed := len(r.events) - len(kept)
+	for i := len(kept); i < len(r.events); i++ {
+		r.events[i] = nil
+	}
+	r.events = kept
+	return removed, nil
+}
+
+// PostgresKeyUsageRepository keeps key usage events in key_usage_events, so
+// the audit covers every instance and is kept for its whole retention
+type PostgresKeyUsageRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresKeyUsageRepository creates a key usage store on db
+func NewPostgresKeyUsageRepository(db *sql.DB) *PostgresKeyUsageRepository {
+	return &PostgresKeyUsageRepository{db: db}
+}
+
+// Append stores an event
+func (r *PostgresKeyUsageRepository) Append(ctx context.Context, event *models.KeyUsageEvent) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO key_usage_events (id, document_id, principal, operation, kms_operation, key_id, key_version, grant_id, succeeded, error, occurred_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, $4, NULLIF($5, ''), $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), $11)`,
+		event.ID, event.DocumentID, event.Principal, event.Operation, event.KMSOperation, event.KeyID,
+		event.KeyVersion, event.GrantID, event.Succeeded, event.Error, event.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("failed to record key usage event: %w", err)
+	}
+	return nil
+}
+
+// List returns the events matching the filter that occurred in [From, To),
+// oldest first; a zero To has no upper bound
+func (r *PostgresKeyUsageRepository) List(ctx context.Context, filter KeyUsageFilter) ([]*models.KeyUsageEvent, error) {
+	query := `
+		SELECT id, COALESCE(document_id::text, ''), principal, operation, COALESCE(kms_operation, ''), key_id, key_version,
+			COALESCE(grant_id, ''), succeeded, COALESCE(error, ''), occurred_at
+		FROM key_usage_events
+		WHERE occurred_at >= $1`
+	args := []interface{}{filter.From}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` AND occurred_at < $%d`, len(args))
+	}
+	if filter.DocumentID != "" {
+		args = append(args, filter.DocumentID)
+		query += fmt.Sprintf(` AND document_id::text = $%d`, len(args))
+	}
+	if filter.Principal != "" {
+		args = append(args, filter.Principal)
+		query += fmt.Sprintf(` AND principal = $%d`, len(args))
+	}
+	query += ` ORDER BY occurred_at`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key usage events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.KeyUsageEvent, 0)
+	for rows.Next() {
+		var event models.KeyUsageEvent
+		if err := rows.Scan(&event.ID, &event.DocumentID, &event.Principal, &event.Operation, &event.KMSOperation, &event.KeyID,
+			&event.KeyVersion, &event.GrantID, &event.Succeeded, &event.Error, &event.OccurredAt); err != nil {
+			return nil, fmt.Errorf("failed to read key usage event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// DeleteBefore removes the events that occurred before the cutoff and returns
+// how many were removed
+func (r *PostgresKeyUsageRepository) DeleteBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM key_usage_events WHERE occurred_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired key usage events: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired key usage events: %w", err)
+	}
+	return int(deleted), nil
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// ErrJobLeaseLost is returned once another instance took over the lease of a job
+var ErrJobLeaseLost = errors.New("job lease was taken over by another instance")
+
+// jobLockNamespace keeps the advisory lock keys of jobs apart from any other
+// advisory lock taken on the same database
+const jobLockNamespace = "document-service/job/"
+
+// JobLockRepository grants the lock of each background job to one instance
+// at a time
+type JobLockRepository interface {
+	// TryLock acquires the lock of the job for owner, or confirms owner
+	// still holds it, reporting false when another instance holds it. It
+	// returns the generation of the lease, which grows on every acquisition
+	TryLock(ctx context.Context, job, owner string) (int64, bool, error)
+	// CheckLease returns ErrJobLeaseLost unless owner still holds the lease
+	// of the job at generation
+	CheckLease(ctx context.Context, job, owner string, generation int64) error
+	// Unlock releases the lock of the job if owner holds it
+	Unlock(ctx context.Context, job, owner string) error
+	// Leases lists the recorded owners of jobs ordered by job
+	Leases(ctx context.Context) ([]*models.JobLease, error)
+}
+
+// MemoryJobLockRepository is an in-process JobLockRepository for
+// single-instance deployments and tests
+type MemoryJobLockRepository struct {
+	mu          sync.Mutex
+	leases      map[string]*models.JobLease
+	generations int64
+}
+
+// NewMemoryJobLockRepository creates a repository with no job locked
+func NewMemoryJobLockRepository() *MemoryJobLockRepository {
+	return &MemoryJobLockRepository{
+		leases: make(map[string]*models.JobLease),
+	}
+}
+
+// TryLock grants the job to owner unless another owner holds it
+func (r *MemoryJobLockRepository) TryLock(ctx context.Context, job, owner string) (int64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	lease, ok := r.leases[job]
+	if ok && lease.Owner != owner {
+		return 0, false, nil
+	}
+	if !ok {
+		r.generations++
+		lease = &models.JobLease{Job: job, Owner: owner, Generation: r.generations, AcquiredAt: now}
+		r.leases[job] = lease
+	}
+	lease.RenewedAt = now
+	return lease.Generation, true, nil
+}
+
+// CheckLease confirms owner holds the job at generation
+func (r *MemoryJobLockRepository) CheckLease(ctx context.Context, job, owner string, generation int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[job]; !ok || lease.Owner != owner || lease.Generation != generation {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// Unlock releases the job if owner holds it
+func (r *MemoryJobLockRepository) Unlock(ctx context.Context, job, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.leases[job]; ok && lease.Owner == owner {
+		delete(r.leases, job)
+	}
+	return nil
+}
+
+// Leases returns copies of the held leases
+func (r *MemoryJobLockRepository) Leases(ctx context.Context) ([]*models.JobLease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	leases := make([]*models.JobLease, 0, len(r.leases))
+	for _, lease := range r.leases {
+		clone := *lease
+		leases = append(leases, &clone)
+	}
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Job < leases[j].Job
+	})
+	return leases, nil
+}
+
+// PostgresJobLockRepository locks jobs with PostgreSQL session advisory
+// locks. Each held lock keeps its own connection, since the lock lives as
+// long as the session that took it: an instance that dies or loses its
+// connection releases its jobs without anyone cleaning up. The owner of each
+// lock is recorded in job_leases, with a generation drawn from a sequence on
+// every acquisition. A holder whose session was lost keeps running until it
+// next renews, so its writes are fenced by checking the generation
+type PostgresJobLockRepository struct {
+	db    *sql.DB
+	mu    sync.Mutex
+	conns map[string]*heldJobLock
+}
+
+// heldJobLock is the session holding the advisory lock of a job and the
+// generation of its lease
+type heldJobLock struct {
+	conn       *sql.Conn
+	generation int64
+}
+
+// NewPostgresJobLockRepository creates a job lock repository on db
+func NewPostgresJobLockRepository(db *sql.DB) *PostgresJobLockRepository {
+	return &PostgresJobLockRepository{
+		db:    db,
+		conns: make(map[string]*heldJobLock),
+	}
+}
+
+// TryLock takes the advisory lock of the job, or renews the lease of a lock
+// already held on a session that is still alive
+func (r *PostgresJobLockRepository) TryLock(ctx context.Context, job, owner string) (int64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if held, ok := r.conns[job]; ok {
+		// Renewing on the session holding the lock also proves the session,
+		// and so the lock, is still alive
+		result, err := held.conn.ExecContext(ctx, `UPDATE job_leases SET renewed_at = now() WHERE job = $1 AND owner = $2 AND generation = $3`, job, owner, held.generation)
+		if err == nil {
+			var renewed int64
+			if renewed, err = result.RowsAffected(); err == nil && renewed == 0 {
+				err = ErrJobLeaseLost
+			}
+		}
+		if err == nil {
+			return held.generation, true, nil
+		}
+		held.conn.Close()
+		delete(r.conns, job)
+		return 0, false, fmt.Errorf("lost lock of job %s: %w", job, err)
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to open lock session: %w", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, jobLockKey(job)).Scan(&acquired); err != nil {
+		conn.Close()
+		return 0, false, fmt.Errorf("failed to lock job %s: %w", job, err)
+	}
+	if !acquired {
+		conn.Close()
+		return 0, false, nil
+	}
+
+	var generation int64
+	err = conn.QueryRowContext(ctx, `
+		INSERT INTO job_leases (job, owner, acquired_at, renewed_at, generation) VALUES ($1, $2, now(), now(), nextval('job_lease_generations'))
+		ON CONFLICT (job) DO UPDATE SET owner = EXCLUDED.owner, acquired_at = EXCLUDED.acquired_at, renewed_at = EXCLUDED.renewed_at, generation = EXCLUDED.generation
+		RETURNING generation`,
+		job, owner).Scan(&generation)
+	if err != nil {
+		// Closing the session releases the lock
+		conn.Close()
+		return 0, false, fmt.Errorf("failed to record lease of job %s: %w", job, err)
+	}
+	r.conns[job] = &heldJobLock{conn: conn, generation: generation}
+	return generation, true, nil
+}
+
+// CheckLease confirms owner holds the job at generation. It reads the lease
+// outside the lock session, so a holder whose session was lost learns that
+// another instance took the job over
+func (r *PostgresJobLockRepository) CheckLease(ctx context.Context, job, owner string, generation int64) error {
+	var current int64
+	err := r.db.QueryRowContext(ctx, `SELECT generation FROM job_leases WHERE job = $1 AND owner = $2`, job, owner).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && current != generation) {
+		return ErrJobLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check lease of job %s: %w", job, err)
+	}
+	return nil
+}
+
+// Unlock releases the advisory lock of the job and its lease
+func (r *PostgresJobLockRepository) Unlock(ctx context.Context, job, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	held, ok := r.conns[job]
+	if !ok {
+		return nil
+	}
+	delete(r.conns, job)
+	conn := held.conn
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `DELETE FROM job_leases WHERE job = $1 AND owner = $2 AND generation = $3`, job, owner, held.generation); err != nil {
+		return fmt.Errorf("failed to release lease of job %s: %w", job, err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, jobLockKey(job)); err != nil {
+		return fmt.Errorf("failed to unlock job %s: %w", job, err)
+	}
+	return nil
+}
+
+// Leases returns the recorded owners of jobs. A lease whose owner died
+// stays recorded until another instance takes the job
+func (r *PostgresJobLockRepository) Leases(ctx context.Context) ([]*models.JobLease, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT job, owner, generation, acquired_at, renewed_at FROM job_leases ORDER BY job`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list job leases: %w", err)
+	}
+	defer rows.Close()
+
+	leases := make([]*models.JobLease, 0)
+	for rows.Next() {
+		var lease models.JobLease
+		if err := rows.Scan(&lease.Job, &lease.Owner, &lease.Generation, &lease.AcquiredAt, &lease.RenewedAt); err != nil {
+			return nil, fmt.Errorf("failed to read job lease: %w", err)
+		}
+		leases = append(leases, &lease)
+	}
+	return leases, rows.Err()
+}
+
+// jobLockKey maps a job to its advisory lock key
+func jobLockKey(job string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(jobLockNamespace + job))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNonceSeen = errors.New("nonce was already used")
+)
+
+// NonceRepository remembers the nonces of accepted requests until they
+// expire, so a request replayed within its window is recognized
+type NonceRepository interface {
+	// Remember records the nonce, failing with ErrNonceSeen when it is
+	// already recorded and not yet expired
+	Remember(ctx context.Context, nonce string, expiresAt time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// MemoryNonceRepository is an in-process NonceRepository for tests. A
+// replay is only caught by the instance that saw the nonce, and not after a
+// restart
+type MemoryNonceRepository struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+// NewMemoryNonceRepository creates an empty in-memory nonce store
+func NewMemoryNonceRepository() *MemoryNonceRepository {
+	return &MemoryNonceRepository{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+// Remember records the nonce until it expires
+func (r *MemoryNonceRepository) Remember(ctx context.Context, nonce string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expires, ok := r.nonces[nonce]; ok && time.Now().Before(expires) {
+		return ErrNonceSeen
+	}
+	r.nonces[nonce] = expiresAt
+	return nil
+}
+
+// DeleteExpired forgets the nonces expired at now and returns how many were
+// forgotten
+func (r *MemoryNonceRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for nonce, expiresAt := range r.nonces {
+		if !now.Before(expiresAt) {
+			delete(r.nonces, nonce)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// PostgresNonceRepository keeps nonces in upload_nonces, so a replay is
+// caught by every instance using the database and across restarts
+type PostgresNonceRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresNonceRepository creates a nonce store on db
+func NewPostgresNonceRepository(db *sql.DB) *PostgresNonceRepository {
+	return &PostgresNonceRepository{db: db}
+}
+
+// Remember records the nonce until it expires. The insert takes over an
+// expired row in the same statement, so two instances racing on a nonce
+// cannot both accept it
+func (r *PostgresNonceRepository) Remember(ctx context.Context, nonce string, expiresAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO upload_nonces (nonce, expires_at) VALUES ($1, $2)
+		ON CONFLICT (nonce) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE upload_nonces.expires_at <= now()`,
+		nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record nonce: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to record nonce: %w", err)
+	}
+	if rows == 0 {
+		return ErrNonceSeen
+	}
+	return nil
+}
+
+// DeleteExpired forgets the nonces expired at now and returns how many were
+// forgotten
+func (r *PostgresNonceRepository) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM upload_nonces WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired nonces: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired nonces: %w", err)
+	}
+	return int(deleted), nil
+}
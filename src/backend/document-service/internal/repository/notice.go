@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrNoticeNotFound = errors.New("purge notice not found")
+)
+
+// PurgeNoticeRepository stores the notices given before documents are purged
+type PurgeNoticeRepository interface {
+	Create(ctx context.Context, notice *models.PurgeNotice) error
+	Get(ctx context.Context, id string) (*models.PurgeNotice, error)
+	Update(ctx context.Context, notice *models.PurgeNotice) error
+	ListByTenant(ctx context.Context, tenantID string) ([]*models.PurgeNotice, error)
+}
+
+// MemoryPurgeNoticeRepository is an in-process PurgeNoticeRepository for
+// single-instance deployments and tests
+type MemoryPurgeNoticeRepository struct {
+	mu      sync.RWMutex
+	notices map[string]*models.PurgeNotice
+}
+
+// NewMemoryPurgeNoticeRepository creates an empty in-memory notice store
+func NewMemoryPurgeNoticeRepository() *MemoryPurgeNoticeRepository {
+	return &MemoryPurgeNoticeRepository{
+		notices: make(map[string]*models.PurgeNotice),
+	}
+}
+
+// Create stores a new notice
+func (r *MemoryPurgeNoticeRepository) Create(ctx context.Context, notice *models.PurgeNotice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notices[notice.ID] = cloneNotice(notice)
+	return nil
+}
+
+// Get returns a copy of a notice
+func (r *MemoryPurgeNoticeRepository) Get(ctx context.Context, id string) (*models.PurgeNotice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notice, ok := r.notices[id]
+	if !ok {
+		return nil, ErrNoticeNotFound
+	}
+	return cloneNotice(notice), nil
+}
+
+// Update replaces an existing notice
+func (r *MemoryPurgeNoticeRepository) Update(ctx context.Context, notice *models.PurgeNotice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.notices[notice.ID]; !ok {
+		return ErrNoticeNotFound
+	}
+	r.notices[notice.ID] = cloneNotice(notice)
+	return nil
+}
+
+// ListByTenant returns the notices of a tenant, most recent first
+func (r *MemoryPurgeNoticeRepository) ListByTenant(ctx context.Context, tenantID string) ([]*models.PurgeNotice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notices := make([]*models.PurgeNotice, 0)
+	for _, notice := range r.notices {
+		if notice.TenantID == tenantID {
+			notices = append(notices, cloneNotice(notice))
+		}
+	}
+	sort.Slice(notices, func(i, j int) bool {
+		return notices[i].NotifiedAt.After(notices[j].NotifiedAt)
+	})
+	return notices, nil
+}
+
+// PostgresPurgeNoticeRepository keeps notices in purge_notices, so a purge
+// waits for the notice given by any instance, including before a restart
+type PostgresPurgeNoticeRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresPurgeNoticeRepository creates a notice store on db
+func NewPostgresPurgeNoticeRepository(db *sql.DB) *PostgresPurgeNoticeRepository {
+	return &PostgresPurgeNoticeRepository{db: db}
+}
+
+// Create stores a new notice
+func (r *PostgresPurgeNoticeRepository) Create(ctx context.Context, notice *models.PurgeNotice) error {
+	documentIDs, documentTypes, held, err := encodeNotice(notice)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO purge_notices (id, tenant_id, document_ids, document_types, purge_after, notified_at, acknowledged_at, acknowledged_by, held)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)`,
+		notice.ID, notice.TenantID, documentIDs, documentTypes, notice.PurgeAfter, notice.NotifiedAt,
+		notice.AcknowledgedAt, notice.AcknowledgedBy, held)
+	if err != nil {
+		return fmt.Errorf("failed to store purge notice: %w", err)
+	}
+	return nil
+}
+
+// Get returns a notice
+func (r *PostgresPurgeNoticeRepository) Get(ctx context.Context, id string) (*models.PurgeNotice, error) {
+	notice, err := scanNotice(r.db.QueryRowContext(ctx, noticeColumns+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoticeNotFound
+	}
+	return notice, err
+}
+
+// Update replaces an existing notice
+func (r *PostgresPurgeNoticeRepository) Update(ctx context.Context, notice *models.PurgeNotice) error {
+	documentIDs, documentTypes, held, err := encodeNotice(notice)
+	if err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE purge_notices
+		SET tenant_id = $2, document_ids = $3, document_types = $4, purge_after = $5, notified_at = $6,
+			acknowledged_at = $7, acknowledged_by = NULLIF($8, ''), held = $9
+		WHERE id = $1`,
+		notice.ID, notice.TenantID, documentIDs, documentTypes, notice.PurgeAfter, notice.NotifiedAt,
+		notice.AcknowledgedAt, notice.AcknowledgedBy, held)
+	if err != nil {
+		return fmt.Errorf("failed to update purge notice: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update purge notice: %w", err)
+	}
+	if updated == 0 {
+		return ErrNoticeNotFound
+	}
+	return nil
+}
+
+// ListByTenant returns the notices of a tenant, most recent first
+func (r *PostgresPurgeNoticeRepository) ListByTenant(ctx context.Context, tenantID string) ([]*models.PurgeNotice, error) {
+	rows, err := r.db.QueryContext(ctx, noticeColumns+` WHERE tenant_id = $1 ORDER BY notified_at DESC`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purge notices: %w", err)
+	}
+	defer rows.Close()
+
+	notices := make([]*models.PurgeNotice, 0)
+	for rows.Next() {
+		notice, err := scanNotice(rows)
+		if err != nil {
+			return nil, err
+		}
+		notices = append(notices, notice)
+	}
+	return notices, rows.Err()
+}
+
+const noticeColumns = `
+	SELECT id, tenant_id, document_ids, document_types, purge_after, notified_at, acknowledged_at, COALESCE(acknowledged_by, ''), held
+	FROM purge_notices`
+
+// encodeNotice encodes the JSONB columns of a notice
+func encodeNotice(notice *models.PurgeNotice) (documentIDs, documentTypes, held []byte, err error) {
+	if documentIDs, err = json.Marshal(append([]string{}, notice.DocumentIDs...)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode purge notice documents: %w", err)
+	}
+	types := notice.DocumentTypes
+	if types == nil {
+		types = map[string]int{}
+	}
+	if documentTypes, err = json.Marshal(types); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode purge notice document types: %w", err)
+	}
+	if held, err = json.Marshal(append([]string{}, notice.Held...)); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode purge notice holds: %w", err)
+	}
+	return documentIDs, documentTypes, held, nil
+}
+
+func scanNotice(row rowScanner) (*models.PurgeNotice, error) {
+	var notice models.PurgeNotice
+	var documentIDs, documentTypes, held []byte
+	err := row.Scan(&notice.ID, &notice.TenantID, &documentIDs, &documentTypes, &notice.PurgeAfter, &notice.NotifiedAt,
+		&notice.AcknowledgedAt, &notice.AcknowledgedBy, &held)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read purge notice: %w", err)
+	}
+	if err := json.Unmarshal(documentIDs, &notice.DocumentIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode purge notice documents: %w", err)
+	}
+	if err := json.Unmarshal(documentTypes, &notice.DocumentTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode purge notice document types: %w", err)
+	}
+	if err := json.Unmarshal(held, &notice.Held); err != nil {
+		return nil, fmt.Errorf("failed to decode purge notice holds: %w", err)
+	}
+	return &notice, nil
+}
+
+func cloneNotice(notice *models.PurgeNotice) *models.PurgeNotice {
+	clone := *notice
+	clone.DocumentIDs = append([]string(nil), notice.DocumentIDs...)
+	clone.Held = append([]string(nil), notice.Held...)
+	clone.DocumentTypes = make(map[string]int, len(notice.DocumentTypes))
+	for documentType, count := range notice.DocumentTypes {
+		clone.DocumentTypes[documentType] = count
+	}
+	return &clone
+}
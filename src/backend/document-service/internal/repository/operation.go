@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrOperationNotFound = errors.New("bulk operation not found")
+)
+
+// BulkOperationRepository stores bulk operations and their progress
+type BulkOperationRepository interface {
+	Create(ctx context.Context, op *models.BulkOperation) error
+	Get(ctx context.Context, id string) (*models.BulkOperation, error)
+	Update(ctx context.Context, op *models.BulkOperation) error
+	List(ctx context.Context, limit int) ([]*models.BulkOperation, error)
+}
+
+// MemoryBulkOperationRepository is an in-process BulkOperationRepository
+type MemoryBulkOperationRepository struct {
+	mu         sync.RWMutex
+	operations map[string]*models.BulkOperation
+}
+
+// NewMemoryBulkOperationRepository creates an empty in-memory operation store
+func NewMemoryBulkOperationRepository() *MemoryBulkOperationRepository {
+	return &MemoryBulkOperationRepository{
+		operations: make(map[string]*models.BulkOperation),
+	}
+}
+
+// Create stores a new operation
+func (r *MemoryBulkOperationRepository) Create(ctx context.Context, op *models.BulkOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.operations[op.ID] = cloneOperation(op)
+	return nil
+}
+
+// Get returns a copy of an operation
+func (r *MemoryBulkOperationRepository) Get(ctx context.Context, id string) (*models.BulkOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, ok := r.operations[id]
+	if !ok {
+		return nil, ErrOperationNotFound
+	}
+	return cloneOperation(op), nil
+}
+
+// Update replaces an existing operation
+func (r *MemoryBulkOperationRepository) Update(ctx context.Context, op *models.BulkOperation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.operations[op.ID]; !ok {
+		return ErrOperationNotFound
+	}
+	r.operations[op.ID] = cloneOperation(op)
+	return nil
+}
+
+// List returns up to limit operations, most recent first
+func (r *MemoryBulkOperationRepository) List(ctx context.Context, limit int) ([]*models.BulkOperation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ops := make([]*models.BulkOperation, 0, len(r.operations))
+	for _, op := range r.operations {
+		ops = append(ops, cloneOperation(op))
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].CreatedAt.After(ops[j].CreatedAt)
+	})
+	if limit > 0 && len(ops) > limit {
+		ops = ops[:limit]
+	}
+	return ops, nil
+}
+
+func cloneOperation(op *models.BulkOperation) *models.BulkOperation {
+	clone := *op
+	clone.Steps = append([]string(nil), op.Steps...)
+	clone.Failures = append([]models.BulkOperationFailure(nil), op.Failures...)
+	clone.Sample = append([]string(nil), op.Sample...)
+	return &clone
+}
@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrDuplicateMessage = errors.New("outbox message with the same key already exists")
+	ErrMessageNotFound  = errors.New("outbox message not found")
+)
+
+// OutboxRepository persists integration events for asynchronous delivery
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, msg *models.OutboxMessage) error
+	// EnqueueWith makes a state change and stores the messages it produced
+	// in one transaction: change returns the messages of the change it made,
+	// and neither is kept unless both succeed. Messages whose topic and key
+	// are already queued are skipped
+	EnqueueWith(ctx context.Context, change func(ctx context.Context) ([]*models.OutboxMessage, error)) error
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*models.OutboxMessage, error)
+	Update(ctx context.Context, msg *models.OutboxMessage) error
+}
+
+// MemoryOutboxRepository is an in-process OutboxRepository
+type MemoryOutboxRepository struct {
+	mu       sync.Mutex
+	messages map[string]*models.OutboxMessage
+	keys     map[string]string
+}
+
+// NewMemoryOutboxRepository creates an empty in-memory outbox
+func NewMemoryOutboxRepository() *MemoryOutboxRepository {
+	return &MemoryOutboxRepository{
+		messages: make(map[string]*models.OutboxMessage),
+		keys:     make(map[string]string),
+	}
+}
+
+// Enqueue stores a message, rejecting duplicates of the same topic and key
+func (r *MemoryOutboxRepository) Enqueue(ctx context.Context, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dedupKey := msg.Topic + "/" + msg.Key
+	if _, ok := r.keys[dedupKey]; ok {
+		return ErrDuplicateMessage
+	}
+
+	clone := *msg
+	r.messages[msg.ID] = &clone
+	r.keys[dedupKey] = msg.ID
+	return nil
+}
+
+// EnqueueWith stores the messages of change once it succeeds. The outbox
+// stays locked while change runs, so changes producing messages are made one
+// at a time and no message is seen before its change is made
+func (r *MemoryOutboxRepository) EnqueueWith(ctx context.Context, change func(ctx context.Context) ([]*models.OutboxMessage, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages, err := change(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		dedupKey := msg.Topic + "/" + msg.Key
+		if _, ok := r.keys[dedupKey]; ok {
+			continue
+		}
+		clone := *msg
+		r.messages[msg.ID] = &clone
+		r.keys[dedupKey] = msg.ID
+	}
+	return nil
+}
+
+// ListDue returns pending messages whose next attempt is due, oldest first
+func (r *MemoryOutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := make([]*models.OutboxMessage, 0)
+	for _, msg := range r.messages {
+		if msg.Status == models.OutboxStatusPending && !msg.NextAttemptAt.After(now) {
+			clone := *msg
+			due = append(due, &clone)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].CreatedAt.Before(due[j].CreatedAt)
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+// Update replaces an existing message
+func (r *MemoryOutboxRepository) Update(ctx context.Context, msg *models.OutboxMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.messages[msg.ID]; !ok {
+		return ErrMessageNotFound
+	}
+	clone := *msg
+	r.messages[msg.ID] = &clone
+	return nil
+}
+
+// PostgresOutboxRepository keeps the outbox in outbox_messages, so queued
+// messages survive a restart and are delivered by whichever instance polls
+// first
+type PostgresOutboxRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresOutboxRepository creates an outbox on db
+func NewPostgresOutboxRepository(db *sql.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+// Enqueue stores a message, rejecting duplicates of the same topic and key
+func (r *PostgresOutboxRepository) Enqueue(ctx context.Context, msg *models.OutboxMessage) error {
+	inserted, err := insertOutboxMessage(ctx, r.db, msg)
+	if err != nil {
+		return err
+	}
+	if !inserted {
+		return ErrDuplicateMessage
+	}
+	return nil
+}
+
+// EnqueueWith runs change inside a transaction and inserts its messages
+// there. Change writes documents through their own repository, so it cannot
+// join the transaction itself; its messages are committed right after it
+// succeeds, and only a failed insert or commit can separate them
+func (r *PostgresOutboxRepository) EnqueueWith(ctx context.Context, change func(ctx context.Context) ([]*models.OutboxMessage, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	messages, err := change(ctx)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if _, err := insertOutboxMessage(ctx, tx, msg); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit outbox messages: %w", err)
+	}
+	return nil
+}
+
+// ListDue returns pending messages whose next attempt is due, oldest first
+func (r *PostgresOutboxRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.OutboxMessage, error) {
+	query := `
+		SELECT id, topic, key, payload, status, attempts, COALESCE(last_error, ''), next_attempt_at, created_at, delivered_at
+		FROM outbox_messages
+		WHERE status = $1 AND next_attempt_at <= $2
+		ORDER BY created_at`
+	args := []interface{}{models.OutboxStatusPending, now}
+	if limit > 0 {
+		query += ` LIMIT $3`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	due := make([]*models.OutboxMessage, 0)
+	for rows.Next() {
+		var msg models.OutboxMessage
+		var payload []byte
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &payload, &msg.Status, &msg.Attempts, &msg.LastError, &msg.NextAttemptAt, &msg.CreatedAt, &msg.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("failed to read outbox message: %w", err)
+		}
+		msg.Payload = payload
+		due = append(due, &msg)
+	}
+	return due, rows.Err()
+}
+
+// Update replaces the delivery state of an existing message
+func (r *PostgresOutboxRepository) Update(ctx context.Context, msg *models.OutboxMessage) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE outbox_messages
+		SET status = $2, attempts = $3, last_error = NULLIF($4, ''), next_attempt_at = $5, delivered_at = $6
+		WHERE id = $1`,
+		msg.ID, msg.Status, msg.Attempts, msg.LastError, msg.NextAttemptAt, msg.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+	if updated == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// outboxExecer is implemented by both *sql.DB and *sql.Tx
+type outboxExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertOutboxMessage stores msg unless its topic and key are already
+// queued, reporting whether it was stored
+func insertOutboxMessage(ctx context.Context, db outboxExecer, msg *models.OutboxMessage) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO outbox_messages (id, topic, key, payload, status, attempts, last_error, next_attempt_at, created_at, delivered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10)
+		ON CONFLICT (topic, key) DO NOTHING`,
+		msg.ID, msg.Topic, msg.Key, []byte(msg.Payload), msg.Status, msg.Attempts, msg.LastError, msg.NextAttemptAt, msg.CreatedAt, msg.DeliveredAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+	return inserted > 0, nil
+}
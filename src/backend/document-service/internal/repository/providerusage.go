@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"sync"
+)
+
+// ProviderUsageRepository counts the transactions sent to each provider per
+// month, shared by every replica calling it
+type ProviderUsageRepository interface {
+	Add(ctx context.Context, provider, month string, n int64) (int64, error)
+	Get(ctx context.Context, provider, month string) (int64, error)
+}
+
+// MemoryProviderUsageRepository is an in-process ProviderUsageRepository
+type MemoryProviderUsageRepository struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+// NewMemoryProviderUsageRepository creates an empty in-memory provider usage
+// store
+func NewMemoryProviderUsageRepository() *MemoryProviderUsageRepository {
+	return &MemoryProviderUsageRepository{
+		counts: make(map[string]int64),
+	}
+}
+
+// Add counts n transactions to a provider in a month and returns its new
+// count
+func (r *MemoryProviderUsageRepository) Add(ctx context.Context, provider, month string, n int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counts[provider+"/"+month] += n
+	return r.counts[provider+"/"+month], nil
+}
+
+// Get returns the transactions counted to a provider in a month
+func (r *MemoryProviderUsageRepository) Get(ctx context.Context, provider, month string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.counts[provider+"/"+month], nil
+}
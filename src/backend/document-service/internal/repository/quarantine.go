@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrQuarantineItemNotFound = errors.New("quarantine item not found")
+)
+
+// QuarantineFilter narrows a listing of quarantine items; empty fields match
+// every item
+type QuarantineFilter struct {
+	Status string
+	Reason string
+}
+
+// QuarantineRepository stores the items held in quarantine
+type QuarantineRepository interface {
+	Create(ctx context.Context, item *models.QuarantineItem) error
+	Get(ctx context.Context, id string) (*models.QuarantineItem, error)
+	Update(ctx context.Context, item *models.QuarantineItem) error
+	List(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantineItem, error)
+}
+
+// MemoryQuarantineRepository is an in-process QuarantineRepository
+type MemoryQuarantineRepository struct {
+	mu    sync.RWMutex
+	items map[string]*models.QuarantineItem
+}
+
+// NewMemoryQuarantineRepository creates an empty in-memory quarantine store
+func NewMemoryQuarantineRepository() *MemoryQuarantineRepository {
+	return &MemoryQuarantineRepository{
+		items: make(map[string]*models.QuarantineItem),
+	}
+}
+
+// Create stores a new item
+func (r *MemoryQuarantineRepository) Create(ctx context.Context, item *models.QuarantineItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[item.ID] = cloneQuarantineItem(item)
+	return nil
+}
+
+// Get returns a copy of an item
+func (r *MemoryQuarantineRepository) Get(ctx context.Context, id string) (*models.QuarantineItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	item, ok := r.items[id]
+	if !ok {
+		return nil, ErrQuarantineItemNotFound
+	}
+	return cloneQuarantineItem(item), nil
+}
+
+// Update replaces an existing item
+func (r *MemoryQuarantineRepository) Update(ctx context.Context, item *models.QuarantineItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.items[item.ID]; !ok {
+		return ErrQuarantineItemNotFound
+	}
+	r.items[item.ID] = cloneQuarantineItem(item)
+	return nil
+}
+
+// List returns the items matching the filter, oldest first, so the longest
+// waiting are reviewed first
+func (r *MemoryQuarantineRepository) List(ctx context.Context, filter QuarantineFilter) ([]*models.QuarantineItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	items := make([]*models.QuarantineItem, 0)
+	for _, item := range r.items {
+		if filter.Status != "" && item.Status != filter.Status {
+			continue
+		}
+		if filter.Reason != "" && item.Reason != filter.Reason {
+			continue
+		}
+		items = append(items, cloneQuarantineItem(item))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].QuarantinedAt.Before(items[j].QuarantinedAt)
+	})
+	return items, nil
+}
+
+func cloneQuarantineItem(item *models.QuarantineItem) *models.QuarantineItem {
+	clone := *item
+	clone.History = append([]models.QuarantineAction(nil), item.History...)
+	return &clone
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var ErrReceiptNotFound = errors.New("download receipt not found")
+
+// ReceiptFilter narrows a download receipt query; empty fields match every
+// receipt
+type ReceiptFilter struct {
+	EnrollmentID string
+	UserID       string
+	DocumentID   string
+	From         time.Time
+	To           time.Time
+}
+
+// ReceiptRepository stores issued download receipts
+type ReceiptRepository interface {
+	Save(ctx context.Context, receipt *models.DownloadReceipt) error
+	Get(ctx context.Context, id string) (*models.DownloadReceipt, error)
+	List(ctx context.Context, filter ReceiptFilter) ([]*models.DownloadReceipt, error)
+}
+
+// MemoryReceiptRepository is an in-process ReceiptRepository
+type MemoryReceiptRepository struct {
+	mu       sync.RWMutex
+	receipts map[string]*models.DownloadReceipt
+}
+
+// NewMemoryReceiptRepository creates an empty in-memory receipt store
+func NewMemoryReceiptRepository() *MemoryReceiptRepository {
+	return &MemoryReceiptRepository{
+		receipts: make(map[string]*models.DownloadReceipt),
+	}
+}
+
+// Save stores a receipt
+func (r *MemoryReceiptRepository) Save(ctx context.Context, receipt *models.DownloadReceipt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.receipts[receipt.ID] = cloneReceipt(receipt)
+	return nil
+}
+
+// Get returns a receipt by ID
+func (r *MemoryReceiptRepository) Get(ctx context.Context, id string) (*models.DownloadReceipt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	receipt, ok := r.receipts[id]
+	if !ok {
+		return nil, ErrReceiptNotFound
+	}
+	return cloneReceipt(receipt), nil
+}
+
+// List returns the receipts matching the filter issued in [From, To), oldest
+// first; a zero To has no upper bound
+func (r *MemoryReceiptRepository) List(ctx context.Context, filter ReceiptFilter) ([]*models.DownloadReceipt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	receipts := make([]*models.DownloadReceipt, 0)
+	for _, receipt := range r.receipts {
+		if filter.EnrollmentID != "" && receipt.EnrollmentID != filter.EnrollmentID {
+			continue
+		}
+		if filter.UserID != "" && receipt.UserID != filter.UserID {
+			continue
+		}
+		if filter.DocumentID != "" && !receiptCovers(receipt, filter.DocumentID) {
+			continue
+		}
+		if receipt.IssuedAt.Before(filter.From) || (!filter.To.IsZero() && !receipt.IssuedAt.Before(filter.To)) {
+			continue
+		}
+		receipts = append(receipts, cloneReceipt(receipt))
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		return receipts[i].IssuedAt.Before(receipts[j].IssuedAt)
+	})
+	return receipts, nil
+}
+
+func receiptCovers(receipt *models.DownloadReceipt, documentID string) bool {
+	for _, access := range receipt.Accesses {
+		if access.DocumentID == documentID {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneReceipt(receipt *models.DownloadReceipt) *models.DownloadReceipt {
+	clone := *receipt
+	clone.Accesses = append([]models.ReceiptAccess(nil), receipt.Accesses...)
+	return &clone
+}
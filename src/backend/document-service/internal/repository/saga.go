@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrSagaNotFound = errors.New("cancellation saga not found")
+	ErrSagaExists   = errors.New("cancellation saga already exists")
+)
+
+// CancellationSagaRepository stores the cleanup sagas of cancelled enrollments
+type CancellationSagaRepository interface {
+	Create(ctx context.Context, saga *models.CancellationSaga) error
+	Get(ctx context.Context, id string) (*models.CancellationSaga, error)
+	Update(ctx context.Context, saga *models.CancellationSaga) error
+	ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.CancellationSaga, error)
+}
+
+// MemoryCancellationSagaRepository is an in-process CancellationSagaRepository
+// for single-instance deployments and tests
+type MemoryCancellationSagaRepository struct {
+	mu    sync.RWMutex
+	sagas map[string]*models.CancellationSaga
+}
+
+// NewMemoryCancellationSagaRepository creates an empty in-memory saga store
+func NewMemoryCancellationSagaRepository() *MemoryCancellationSagaRepository {
+	return &MemoryCancellationSagaRepository{
+		sagas: make(map[string]*models.CancellationSaga),
+	}
+}
+
+// Create stores a new saga
+func (r *MemoryCancellationSagaRepository) Create(ctx context.Context, saga *models.CancellationSaga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sagas[saga.ID]; ok {
+		return ErrSagaExists
+	}
+	r.sagas[saga.ID] = cloneSaga(saga)
+	return nil
+}
+
+// Get returns a copy of a saga
+func (r *MemoryCancellationSagaRepository) Get(ctx context.Context, id string) (*models.CancellationSaga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saga, ok := r.sagas[id]
+	if !ok {
+		return nil, ErrSagaNotFound
+	}
+	return cloneSaga(saga), nil
+}
+
+// Update replaces an existing saga
+func (r *MemoryCancellationSagaRepository) Update(ctx context.Context, saga *models.CancellationSaga) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sagas[saga.ID]; !ok {
+		return ErrSagaNotFound
+	}
+	r.sagas[saga.ID] = cloneSaga(saga)
+	return nil
+}
+
+// ListByEnrollment returns the sagas of an enrollment, oldest first
+func (r *MemoryCancellationSagaRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.CancellationSaga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sagas := make([]*models.CancellationSaga, 0)
+	for _, saga := range r.sagas {
+		if saga.EnrollmentID == enrollmentID {
+			sagas = append(sagas, cloneSaga(saga))
+		}
+	}
+	sort.Slice(sagas, func(i, j int) bool {
+		return sagas[i].StartedAt.Before(sagas[j].StartedAt)
+	})
+	return sagas, nil
+}
+
+// PostgresCancellationSagaRepository keeps sagas in cancellation_sagas, so a
+// cleanup interrupted by a restart resumes from its recorded steps
+type PostgresCancellationSagaRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresCancellationSagaRepository creates a saga store on db
+func NewPostgresCancellationSagaRepository(db *sql.DB) *PostgresCancellationSagaRepository {
+	return &PostgresCancellationSagaRepository{db: db}
+}
+
+// Create stores a new saga
+func (r *PostgresCancellationSagaRepository) Create(ctx context.Context, saga *models.CancellationSaga) error {
+	steps, err := json.Marshal(saga.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga steps: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO cancellation_sagas (id, enrollment_id, cancelled_at, status, steps, error, attempts, started_at, updated_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10)
+		ON CONFLICT (id) DO NOTHING`,
+		saga.ID, saga.EnrollmentID, saga.CancelledAt, saga.Status, steps, saga.Error, saga.Attempts,
+		saga.StartedAt, saga.UpdatedAt, saga.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store cancellation saga: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store cancellation saga: %w", err)
+	}
+	if inserted == 0 {
+		return ErrSagaExists
+	}
+	return nil
+}
+
+// Get returns a saga
+func (r *PostgresCancellationSagaRepository) Get(ctx context.Context, id string) (*models.CancellationSaga, error) {
+	saga, err := scanSaga(r.db.QueryRowContext(ctx, sagaColumns+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSagaNotFound
+	}
+	return saga, err
+}
+
+// Update replaces the state and steps of an existing saga
+func (r *PostgresCancellationSagaRepository) Update(ctx context.Context, saga *models.CancellationSaga) error {
+	steps, err := json.Marshal(saga.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to encode saga steps: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE cancellation_sagas
+		SET status = $2, steps = $3, error = NULLIF($4, ''), attempts = $5, updated_at = $6, completed_at = $7
+		WHERE id = $1`,
+		saga.ID, saga.Status, steps, saga.Error, saga.Attempts, saga.UpdatedAt, saga.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update cancellation saga: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update cancellation saga: %w", err)
+	}
+	if updated == 0 {
+		return ErrSagaNotFound
+	}
+	return nil
+}
+
+// ListByEnrollment returns the sagas of an enrollment, oldest first
+func (r *PostgresCancellationSagaRepository) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.CancellationSaga, error) {
+	rows, err := r.db.QueryContext(ctx, sagaColumns+` WHERE enrollment_id = $1 ORDER BY started_at`, enrollmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cancellation sagas: %w", err)
+	}
+	defer rows.Close()
+
+	sagas := make([]*models.CancellationSaga, 0)
+	for rows.Next() {
+		saga, err := scanSaga(rows)
+		if err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, saga)
+	}
+	return sagas, rows.Err()
+}
+
+const sagaColumns = `
+	SELECT id, enrollment_id::text, cancelled_at, status, steps, COALESCE(error, ''), attempts, started_at, updated_at, completed_at
+	FROM cancellation_sagas`
+
+func scanSaga(row rowScanner) (*models.CancellationSaga, error) {
+	var saga models.CancellationSaga
+	var steps []byte
+	err := row.Scan(&saga.ID, &saga.EnrollmentID, &saga.CancelledAt, &saga.Status, &steps, &saga.Error, &saga.Attempts,
+		&saga.StartedAt, &saga.UpdatedAt, &saga.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cancellation saga: %w", err)
+	}
+	if err := json.Unmarshal(steps, &saga.Steps); err != nil {
+		return nil, fmt.Errorf("failed to decode saga steps: %w", err)
+	}
+	return &saga, nil
+}
+
+func cloneSaga(saga *models.CancellationSaga) *models.CancellationSaga {
+	clone := *saga
+	clone.Steps = append([]models.DispositionStep(nil), saga.Steps...)
+	return &clone
+}
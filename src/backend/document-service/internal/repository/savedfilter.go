@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrSavedFilterNotFound = errors.New("saved filter not found")
+)
+
+// SavedFilterRepository stores the review filters underwriters saved
+type SavedFilterRepository interface {
+	Create(ctx context.Context, filter *models.SavedFilter) error
+	Get(ctx context.Context, id string) (*models.SavedFilter, error)
+	Delete(ctx context.Context, id string) error
+	ListByOwner(ctx context.Context, owner string) ([]*models.SavedFilter, error)
+}
+
+// MemorySavedFilterRepository is an in-process SavedFilterRepository
+type MemorySavedFilterRepository struct {
+	mu      sync.RWMutex
+	filters map[string]*models.SavedFilter
+}
+
+// NewMemorySavedFilterRepository creates an empty in-memory filter store
+func NewMemorySavedFilterRepository() *MemorySavedFilterRepository {
+	return &MemorySavedFilterRepository{
+		filters: make(map[string]*models.SavedFilter),
+	}
+}
+
+// Create stores a new filter
+func (r *MemorySavedFilterRepository) Create(ctx context.Context, filter *models.SavedFilter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clone := *filter
+	r.filters[filter.ID] = &clone
+	return nil
+}
+
+// Get returns a copy of a filter
+func (r *MemorySavedFilterRepository) Get(ctx context.Context, id string) (*models.SavedFilter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filter, ok := r.filters[id]
+	if !ok {
+		return nil, ErrSavedFilterNotFound
+	}
+	clone := *filter
+	return &clone, nil
+}
+
+// Delete removes a filter
+func (r *MemorySavedFilterRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.filters[id]; !ok {
+		return ErrSavedFilterNotFound
+	}
+	delete(r.filters, id)
+	return nil
+}
+
+// ListByOwner returns the filters of an underwriter ordered by name
+func (r *MemorySavedFilterRepository) ListByOwner(ctx context.Context, owner string) ([]*models.SavedFilter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	filters := make([]*models.SavedFilter, 0)
+	for _, filter := range r.filters {
+		if filter.Owner == owner {
+			clone := *filter
+			filters = append(filters, &clone)
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Name < filters[j].Name
+	})
+	return filters, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrSealNotFound = errors.New("enrollment seal not found")
+	ErrSealExists   = errors.New("enrollment seal already exists for the event")
+)
+
+// SealRepository stores enrollment seals. Seals are never updated; an
+// enrollment reopened and closed again gets a new seal
+type SealRepository interface {
+	// Create stores a seal, failing with ErrSealExists when the event that
+	// closed the enrollment was already sealed
+	Create(ctx context.Context, seal *models.EnrollmentSeal) error
+	GetByEvent(ctx context.Context, eventID string) (*models.EnrollmentSeal, error)
+	// Latest returns the most recent seal of an enrollment
+	Latest(ctx context.Context, enrollmentID string) (*models.EnrollmentSeal, error)
+}
+
+// MemorySealRepository is an in-process SealRepository for single-instance
+// deployments and tests
+type MemorySealRepository struct {
+	mu    sync.RWMutex
+	seals map[string]*models.EnrollmentSeal
+}
+
+// NewMemorySealRepository creates an empty in-memory seal store
+func NewMemorySealRepository() *MemorySealRepository {
+	return &MemorySealRepository{
+		seals: make(map[string]*models.EnrollmentSeal),
+	}
+}
+
+// Create stores a new seal
+func (r *MemorySealRepository) Create(ctx context.Context, seal *models.EnrollmentSeal) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seals[seal.EventID]; ok {
+		return ErrSealExists
+	}
+	r.seals[seal.EventID] = cloneSeal(seal)
+	return nil
+}
+
+// GetByEvent returns a copy of the seal made for an event
+func (r *MemorySealRepository) GetByEvent(ctx context.Context, eventID string) (*models.EnrollmentSeal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seal, ok := r.seals[eventID]
+	if !ok {
+		return nil, ErrSealNotFound
+	}
+	return cloneSeal(seal), nil
+}
+
+// Latest returns a copy of the most recent seal of an enrollment
+func (r *MemorySealRepository) Latest(ctx context.Context, enrollmentID string) (*models.EnrollmentSeal, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var latest *models.EnrollmentSeal
+	for _, seal := range r.seals {
+		if seal.EnrollmentID == enrollmentID && (latest == nil || seal.SealedAt.After(latest.SealedAt)) {
+			latest = seal
+		}
+	}
+	if latest == nil {
+		return nil, ErrSealNotFound
+	}
+	return cloneSeal(latest), nil
+}
+
+// PostgresSealRepository keeps seals in enrollment_seals, so they survive a
+// restart and every instance verifies against the same seals
+type PostgresSealRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSealRepository creates a seal store on db
+func NewPostgresSealRepository(db *sql.DB) *PostgresSealRepository {
+	return &PostgresSealRepository{db: db}
+}
+
+// Create stores a new seal
+func (r *PostgresSealRepository) Create(ctx context.Context, seal *models.EnrollmentSeal) error {
+	documents, err := json.Marshal(seal.Documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode sealed documents: %w", err)
+	}
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO enrollment_seals (id, enrollment_id, event_id, decision, documents, algorithm, merkle_root, sealed_at, key_id, signature, timestamp_token)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+		ON CONFLICT (event_id) DO NOTHING`,
+		seal.ID, seal.EnrollmentID, seal.EventID, seal.Decision, documents, seal.Algorithm, seal.MerkleRoot,
+		seal.SealedAt, seal.KeyID, seal.Signature, seal.TimestampToken)
+	if err != nil {
+		return fmt.Errorf("failed to store enrollment seal: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store enrollment seal: %w", err)
+	}
+	if inserted == 0 {
+		return ErrSealExists
+	}
+	return nil
+}
+
+// GetByEvent returns the seal made for an event
+func (r *PostgresSealRepository) GetByEvent(ctx context.Context, eventID string) (*models.EnrollmentSeal, error) {
+	return r.scanSeal(r.db.QueryRowContext(ctx, sealColumns+` WHERE event_id = $1`, eventID))
+}
+
+// Latest returns the most recent seal of an enrollment
+func (r *PostgresSealRepository) Latest(ctx context.Context, enrollmentID string) (*models.EnrollmentSeal, error) {
+	return r.scanSeal(r.db.QueryRowContext(ctx, sealColumns+` WHERE enrollment_id = $1 ORDER BY sealed_at DESC LIMIT 1`, enrollmentID))
+}
+
+const sealColumns = `
+	SELECT id, enrollment_id, event_id, COALESCE(decision, ''), documents, algorithm, merkle_root, sealed_at, key_id, signature, COALESCE(timestamp_token, '')
+	FROM enrollment_seals`
+
+func (r *PostgresSealRepository) scanSeal(row *sql.Row) (*models.EnrollmentSeal, error) {
+	var seal models.EnrollmentSeal
+	var documents []byte
+	err := row.Scan(&seal.ID, &seal.EnrollmentID, &seal.EventID, &seal.Decision, &documents, &seal.Algorithm,
+		&seal.MerkleRoot, &seal.SealedAt, &seal.KeyID, &seal.Signature, &seal.TimestampToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSealNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read enrollment seal: %w", err)
+	}
+	if err := json.Unmarshal(documents, &seal.Documents); err != nil {
+		return nil, fmt.Errorf("failed to decode sealed documents: %w", err)
+	}
+	return &seal, nil
+}
+
+func cloneSeal(seal *models.EnrollmentSeal) *models.EnrollmentSeal {
+	clone := *seal
+	clone.Documents = append([]models.SealedDocument(nil), seal.Documents...)
+	return &clone
+}
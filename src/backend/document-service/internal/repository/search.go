@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SearchIndexRepository stores, for each tenant, the keyed tokens of the
+// terms found in the extracted text of its documents. It never sees the terms
+type SearchIndexRepository interface {
+	// Index replaces the tokens of a document
+	Index(ctx context.Context, tenantID, documentID string, tokens []string) error
+	// Search returns up to limit documents of the tenant holding every token,
+	// in ID order
+	Search(ctx context.Context, tenantID string, tokens []string, limit int) ([]string, error)
+	// Delete removes a document from the index; absent documents are ignored
+	Delete(ctx context.Context, tenantID, documentID string) error
+}
+
+// MemorySearchIndexRepository is an in-process SearchIndexRepository
+type MemorySearchIndexRepository struct {
+	mu       sync.RWMutex
+	postings map[string]map[string]map[string]bool
+	indexed  map[string]map[string][]string
+}
+
+// NewMemorySearchIndexRepository creates an empty in-memory search index
+func NewMemorySearchIndexRepository() *MemorySearchIndexRepository {
+	return &MemorySearchIndexRepository{
+		postings: make(map[string]map[string]map[string]bool),
+		indexed:  make(map[string]map[string][]string),
+	}
+}
+
+// Index replaces the tokens of a document
+func (r *MemorySearchIndexRepository) Index(ctx context.Context, tenantID, documentID string, tokens []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.delete(tenantID, documentID)
+	if r.postings[tenantID] == nil {
+		r.postings[tenantID] = make(map[string]map[string]bool)
+		r.indexed[tenantID] = make(map[string][]string)
+	}
+	for _, token := range tokens {
+		if r.postings[tenantID][token] == nil {
+			r.postings[tenantID][token] = make(map[string]bool)
+		}
+		r.postings[tenantID][token][documentID] = true
+	}
+	r.indexed[tenantID][documentID] = append([]string(nil), tokens...)
+	return nil
+}
+
+// Search returns up to limit documents of the tenant holding every token
+func (r *MemorySearchIndexRepository) Search(ctx context.Context, tenantID string, tokens []string, limit int) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := make([]string, 0)
+	if len(tokens) == 0 {
+		return matches, nil
+	}
+	for documentID := range r.postings[tenantID][tokens[0]] {
+		found := true
+		for _, token := range tokens[1:] {
+			if !r.postings[tenantID][token][documentID] {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, documentID)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// Delete removes a document from the index
+func (r *MemorySearchIndexRepository) Delete(ctx context.Context, tenantID, documentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.delete(tenantID, documentID)
+	return nil
+}
+
+func (r *MemorySearchIndexRepository) delete(tenantID, documentID string) {
+	for _, token := range r.indexed[tenantID][documentID] {
+		delete(r.postings[tenantID][token], documentID)
+		if len(r.postings[tenantID][token]) == 0 {
+			delete(r.postings[tenantID], token)
+		}
+	}
+	delete(r.indexed[tenantID], documentID)
+}
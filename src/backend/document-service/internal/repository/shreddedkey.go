@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ShreddedKeyRepository records the document data keys destroyed by
+// crypto-shredding where every instance sees them, so none goes on using
+// its cached copy of a shredded key. Only a digest of each wrapped key is
+// kept, so the registry itself holds nothing that can be unwrapped
+type ShreddedKeyRepository interface {
+	// Record marks the wrapped key as shredded; recording it again is harmless
+	Record(ctx context.Context, wrappedKey string) error
+	// Shredded reports whether the wrapped key was shredded
+	Shredded(ctx context.Context, wrappedKey string) (bool, error)
+}
+
+// MemoryShreddedKeyRepository is an in-process ShreddedKeyRepository for
+// single-instance deployments and tests
+type MemoryShreddedKeyRepository struct {
+	mu   sync.RWMutex
+	keys map[string]time.Time
+}
+
+// NewMemoryShreddedKeyRepository creates a registry with no key shredded
+func NewMemoryShreddedKeyRepository() *MemoryShreddedKeyRepository {
+	return &MemoryShreddedKeyRepository{
+		keys: make(map[string]time.Time),
+	}
+}
+
+// Record marks the wrapped key as shredded
+func (r *MemoryShreddedKeyRepository) Record(ctx context.Context, wrappedKey string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	digest := wrappedKeyDigest(wrappedKey)
+	if _, ok := r.keys[digest]; !ok {
+		r.keys[digest] = time.Now()
+	}
+	return nil
+}
+
+// Shredded reports whether the wrapped key was shredded
+func (r *MemoryShreddedKeyRepository) Shredded(ctx context.Context, wrappedKey string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.keys[wrappedKeyDigest(wrappedKey)]
+	return ok, nil
+}
+
+// PostgresShreddedKeyRepository records shredded keys in shredded_data_keys,
+// shared by every instance using the database
+type PostgresShreddedKeyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresShreddedKeyRepository creates a shredded key registry on db
+func NewPostgresShreddedKeyRepository(db *sql.DB) *PostgresShreddedKeyRepository {
+	return &PostgresShreddedKeyRepository{db: db}
+}
+
+// Record marks the wrapped key as shredded
+func (r *PostgresShreddedKeyRepository) Record(ctx context.Context, wrappedKey string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO shredded_data_keys (key_digest, shredded_at) VALUES ($1, now())
+		ON CONFLICT (key_digest) DO NOTHING`,
+		wrappedKeyDigest(wrappedKey))
+	if err != nil {
+		return fmt.Errorf("failed to record shredded key: %w", err)
+	}
+	return nil
+}
+
+// Shredded reports whether the wrapped key was shredded
+func (r *PostgresShreddedKeyRepository) Shredded(ctx context.Context, wrappedKey string) (bool, error) {
+	var shredded bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM shredded_data_keys WHERE key_digest = $1)`,
+		wrappedKeyDigest(wrappedKey)).Scan(&shredded)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up shredded key: %w", err)
+	}
+	return shredded, nil
+}
+
+// wrappedKeyDigest identifies a wrapped key without retaining it
+func wrappedKeyDigest(wrappedKey string) string {
+	sum := sha256.Sum256([]byte(wrappedKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+)
+
+// WebhookRepository stores the webhook subscriptions of tenants and the
+// history of their deliveries
+type WebhookRepository interface {
+	Create(ctx context.Context, subscription *models.WebhookSubscription) error
+	Get(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	Update(ctx context.Context, subscription *models.WebhookSubscription) error
+	Delete(ctx context.Context, id string) error
+	ListByTenant(ctx context.Context, tenantID string) ([]*models.WebhookSubscription, error)
+	// AddDelivery records a delivery, keeping the last keep deliveries of
+	// the subscription
+	AddDelivery(ctx context.Context, delivery *models.WebhookDelivery, keep int) error
+	ListDeliveries(ctx context.Context, subscriptionID string) ([]*models.WebhookDelivery, error)
+}
+
+// MemoryWebhookRepository is an in-process WebhookRepository
+type MemoryWebhookRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[string]*models.WebhookSubscription
+	deliveries    map[string][]*models.WebhookDelivery
+}
+
+// NewMemoryWebhookRepository creates an empty in-memory webhook store
+func NewMemoryWebhookRepository() *MemoryWebhookRepository {
+	return &MemoryWebhookRepository{
+		subscriptions: make(map[string]*models.WebhookSubscription),
+		deliveries:    make(map[string][]*models.WebhookDelivery),
+	}
+}
+
+// Create stores a new subscription
+func (r *MemoryWebhookRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscriptions[subscription.ID] = cloneWebhook(subscription)
+	return nil
+}
+
+// Get returns a copy of a subscription
+func (r *MemoryWebhookRepository) Get(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return cloneWebhook(subscription), nil
+}
+
+// Update replaces an existing subscription
+func (r *MemoryWebhookRepository) Update(ctx context.Context, subscription *models.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[subscription.ID]; !ok {
+		return ErrWebhookNotFound
+	}
+	r.subscriptions[subscription.ID] = cloneWebhook(subscription)
+	return nil
+}
+
+// Delete removes a subscription and its delivery history
+func (r *MemoryWebhookRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(r.subscriptions, id)
+	delete(r.deliveries, id)
+	return nil
+}
+
+// ListByTenant returns the subscriptions of a tenant, oldest first
+func (r *MemoryWebhookRepository) ListByTenant(ctx context.Context, tenantID string) ([]*models.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscriptions := make([]*models.WebhookSubscription, 0)
+	for _, subscription := range r.subscriptions {
+		if subscription.TenantID == tenantID {
+			subscriptions = append(subscriptions, cloneWebhook(subscription))
+		}
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].CreatedAt.Before(subscriptions[j].CreatedAt)
+	})
+	return subscriptions, nil
+}
+
+// AddDelivery records a delivery of an existing subscription
+func (r *MemoryWebhookRepository) AddDelivery(ctx context.Context, delivery *models.WebhookDelivery, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[delivery.SubscriptionID]; !ok {
+		return ErrWebhookNotFound
+	}
+	clone := *delivery
+	deliveries := append(r.deliveries[delivery.SubscriptionID], &clone)
+	if keep > 0 && len(deliveries) > keep {
+		deliveries = append([]*models.WebhookDelivery(nil), deliveries[len(deliveries)-keep:]...)
+	}
+	r.deliveries[delivery.SubscriptionID] = deliveries
+	return nil
+}
+
+// ListDeliveries returns the recorded deliveries of a subscription, most
+// recent first
+func (r *MemoryWebhookRepository) ListDeliveries(ctx context.Context, subscriptionID string) ([]*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.subscriptions[subscriptionID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+	recorded := r.deliveries[subscriptionID]
+	deliveries := make([]*models.WebhookDelivery, 0, len(recorded))
+	for i := len(recorded) - 1; i >= 0; i-- {
+		clone := *recorded[i]
+		deliveries = append(deliveries, &clone)
+	}
+	return deliveries, nil
+}
+
+func cloneWebhook(subscription *models.WebhookSubscription) *models.WebhookSubscription {
+	clone := *subscription
+	clone.Events = append([]string(nil), subscription.Events...)
+	return &clone
+}
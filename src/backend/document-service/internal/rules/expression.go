@@ -0,0 +1,373 @@
+// Package rules implements the expression language used by document decision rules
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrSyntax       = errors.New("rule syntax error")
+	ErrTypeMismatch = errors.New("rule type mismatch")
+)
+
+// Facts are the named values an expression is evaluated against; identifiers
+// missing from the facts evaluate to nil
+type Facts map[string]interface{}
+
+// Expression is a parsed boolean rule expression such as
+//
+//	document.type == "identity" and fields.min_confidence >= 0.9 and not flag.screening_hit
+//
+// Supported operators are and, or, not, parentheses and the comparisons
+// ==, !=, >, >=, <, <=. Comparisons involving an unknown fact are false.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse compiles an expression
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, p.peek().text)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate reports whether the expression holds for the facts
+func (e *Expression) Evaluate(facts Facts) (bool, error) {
+	return evalBool(e.root, facts)
+}
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")"})
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrSyntax)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: string(runes[i+1 : end])})
+			i = end + 1
+		case strings.ContainsRune("=!<>", r):
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("%w: unknown operator %q", ErrSyntax, op)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op})
+			i += len(op)
+		case unicode.IsDigit(r) || r == '-':
+			end := i + 1
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i + 1
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrSyntax, r)
+		}
+	}
+	return tokens, nil
+}
+
+type node interface{}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+type notNode struct {
+	operand node
+}
+
+type identNode struct {
+	name string
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) keyword(word string) bool {
+	if !p.done() && p.peek().kind == tokenIdent && strings.EqualFold(p.peek().text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.keyword("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() && p.peek().kind == tokenOperator {
+		op := p.peek().text
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+	}
+
+	tok := p.peek()
+	p.pos++
+	switch tok.kind {
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != tokenRParen {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrSyntax)
+		}
+		p.pos++
+		return inner, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrSyntax, tok.text)
+		}
+		return literalNode{value: value}, nil
+	case tokenString:
+		return literalNode{value: tok.text}, nil
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "and", "or", "not":
+			return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, tok.text)
+		}
+		return identNode{name: tok.text}, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, tok.text)
+	}
+}
+
+func evalBool(n node, facts Facts) (bool, error) {
+	switch n := n.(type) {
+	case binaryNode:
+		switch n.op {
+		case "and":
+			left, err := evalBool(n.left, facts)
+			if err != nil || !left {
+				return false, err
+			}
+			return evalBool(n.right, facts)
+		case "or":
+			left, err := evalBool(n.left, facts)
+			if err != nil || left {
+				return left, err
+			}
+			return evalBool(n.right, facts)
+		default:
+			return compare(n.op, evalValue(n.left, facts), evalValue(n.right, facts))
+		}
+	case notNode:
+		value, err := evalBool(n.operand, facts)
+		return !value, err
+	default:
+		switch value := evalValue(n, facts).(type) {
+		case nil:
+			return false, nil
+		case bool:
+			return value, nil
+		default:
+			return false, fmt.Errorf("%w: %v is not a boolean", ErrTypeMismatch, value)
+		}
+	}
+}
+
+func evalValue(n node, facts Facts) interface{} {
+	switch n := n.(type) {
+	case identNode:
+		return facts[n.name]
+	case literalNode:
+		return n.value
+	default:
+		value, err := evalBool(n, facts)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+}
+
+func compare(op string, left, right interface{}) (bool, error) {
+	if left == nil || right == nil {
+		return false, nil
+	}
+
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		if !ok {
+			return false, fmt.Errorf("%w: cannot compare %v with %v", ErrTypeMismatch, left, right)
+		}
+		switch op {
+		case "==":
+			return l == r, nil
+		case "!=":
+			return l != r, nil
+		case ">":
+			return l > r, nil
+		case ">=":
+			return l >= r, nil
+		case "<":
+			return l < r, nil
+		case "<=":
+			return l <= r, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return fmt.Sprint(left) == fmt.Sprint(right), nil
+	case "!=":
+		return fmt.Sprint(left) != fmt.Sprint(right), nil
+	default:
+		return false, fmt.Errorf("%w: operator %s requires numbers", ErrTypeMismatch, op)
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
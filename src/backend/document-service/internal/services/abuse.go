@@ -0,0 +1,175 @@
+package services
+
+import (
+    "context"
+    "net"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// Endpoints whose only credential is a token or signed link
+const (
+    AbuseEndpointPreview = "preview"
+    AbuseEndpointExport  = "portability_export"
+)
+
+// AbuseGuard tracks failed token and link checks per client and temporarily
+// bans clients that fail too often, so the tokens cannot be brute forced.
+// IPv6 clients are tracked per /64, the smallest block usually assigned to a
+// single subscriber. A nil *AbuseGuard bans no one
+type AbuseGuard struct {
+    mu      sync.Mutex
+    cfg     config.AbuseConfig
+    clients map[string]*abuseClient
+    logger  *zap.Logger
+}
+
+// abuseClient holds the recent failures and ban of one client
+type abuseClient struct {
+    failures    []time.Time
+    bannedUntil time.Time
+}
+
+// NewAbuseGuard creates the guard, or nil when abuse protection is disabled
+func NewAbuseGuard(cfg *config.Config, logger *zap.Logger) *AbuseGuard {
+    if cfg == nil || !cfg.AbuseConfig.Enabled {
+        return nil
+    }
+
+    return &AbuseGuard{
+        cfg:     cfg.AbuseConfig,
+        clients: make(map[string]*abuseClient),
+        logger:  logger.With(zap.String("component", "abuse_guard")),
+    }
+}
+
+// Banned reports whether the client is banned and for how much longer
+func (g *AbuseGuard) Banned(endpoint, clientIP string) (time.Duration, bool) {
+    if g == nil {
+        return 0, false
+    }
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    client, ok := g.clients[abuseClientKey(clientIP)]
+    if !ok {
+        return 0, false
+    }
+    remaining := time.Until(client.bannedUntil)
+    if remaining <= 0 {
+        return 0, false
+    }
+    abuseBannedRequests.WithLabelValues(endpoint).Inc()
+    return remaining, true
+}
+
+// RecordFailure counts a failed token or link check and bans the client once
+// it reaches the failure limit within the window. It reports whether the
+// client is banned as a result
+func (g *AbuseGuard) RecordFailure(endpoint, clientIP string) bool {
+    if g == nil {
+        return false
+    }
+    abuseTokenFailures.WithLabelValues(endpoint).Inc()
+
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    key := abuseClientKey(clientIP)
+    client, ok := g.clients[key]
+    if !ok {
+        client = &abuseClient{}
+        g.clients[key] = client
+    }
+
+    now := time.Now()
+    client.failures = append(recentFailures(client.failures, now.Add(-g.cfg.FailureWindow)), now)
+    if len(client.failures) < g.cfg.MaxFailures {
+        return false
+    }
+
+    client.failures = nil
+    client.bannedUntil = now.Add(g.cfg.BanDuration)
+    abuseBans.WithLabelValues(endpoint).Inc()
+    g.exportBanned(now)
+    g.logger.Warn("Client banned after repeated token failures",
+        zap.String("endpoint", endpoint),
+        zap.String("client", key),
+        zap.Int("failures", g.cfg.MaxFailures),
+        zap.Duration("ban_duration", g.cfg.BanDuration),
+    )
+    return true
+}
+
+// Run forgets clients whose failures and bans have expired until the context
+// is cancelled
+func (g *AbuseGuard) Run(ctx context.Context) {
+    if g == nil {
+        return
+    }
+
+    ticker := time.NewTicker(g.cfg.FailureWindow)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            g.sweep()
+        }
+    }
+}
+
+func (g *AbuseGuard) sweep() {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    now := time.Now()
+    for key, client := range g.clients {
+        client.failures = recentFailures(client.failures, now.Add(-g.cfg.FailureWindow))
+        if len(client.failures) == 0 && !client.bannedUntil.After(now) {
+            delete(g.clients, key)
+        }
+    }
+    g.exportBanned(now)
+}
+
+// exportBanned publishes the number of banned clients; callers hold the lock
+func (g *AbuseGuard) exportBanned(now time.Time) {
+    banned := 0
+    for _, client := range g.clients {
+        if client.bannedUntil.After(now) {
+            banned++
+        }
+    }
+    abuseBannedClients.Set(float64(banned))
+}
+
+// recentFailures drops the failures before the cutoff; failures are recorded
+// in order
+func recentFailures(failures []time.Time, cutoff time.Time) []time.Time {
+    for i, failedAt := range failures {
+        if failedAt.After(cutoff) {
+            return failures[i:]
+        }
+    }
+    return failures[:0]
+}
+
+// abuseClientKey identifies a client by its IPv4 address or its IPv6 /64
+func abuseClientKey(clientIP string) string {
+    ip := net.ParseIP(clientIP)
+    if ip == nil {
+        return clientIP
+    }
+    if ip.To4() != nil {
+        return ip.String()
+    }
+    return (&net.IPNet{IP: ip.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// accessEventKeyPurpose binds derived keys to access events so no other
+// HMAC made with the same secret can be presented as one
+const accessEventKeyPurpose = "access-events"
+
+var (
+    ErrInvalidAccessEvent   = errors.New("invalid access event")
+    ErrAccessEventSignature = errors.New("access event signature is invalid")
+    ErrAccessEventExpired   = errors.New("access event timestamp is outside the allowed clock skew")
+    ErrAccessEventReplayed  = errors.New("access event was already recorded")
+)
+
+// AccessEventReport is an access event as reported by the portal, signed
+// with the key issued for the document and session
+type AccessEventReport struct {
+    Event      string
+    Page       int
+    Format     string
+    OccurredAt int64
+    Nonce      string
+    Signature  string
+}
+
+// userAccessWindow counts the prints and exports of a user in the current
+// window and the anomalies already raised in it
+type userAccessWindow struct {
+    start     time.Time
+    prints    int
+    exports   int
+    documents map[string]bool
+    raised    map[string]bool
+}
+
+// AccessEvents records the view, print and export events the portal's
+// secure viewer reports. The viewer is handed a key derived from the signing
+// key for its document and session, and signs every event with it, so events
+// cannot be forged for another session or altered on the way. Verified events
+// are added to the document's audit trail and counted per user, raising an
+// anomaly once a user prints, exports or spreads across more documents in a
+// window than the thresholds allow
+type AccessEvents struct {
+    mu        sync.Mutex
+    cfg       config.AccessEventsConfig
+    keys      [][]byte
+    documents repository.DocumentRepository
+    nonces    repository.NonceRepository
+    users     map[string]*userAccessWindow
+    logger    *zap.Logger
+}
+
+// NewAccessEvents creates the access event recorder, or nil when access
+// events are disabled
+func NewAccessEvents(cfg *config.Config, documents repository.DocumentRepository, nonces repository.NonceRepository, logger *zap.Logger) (*AccessEvents, error) {
+    if cfg == nil || documents == nil || nonces == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.AccessEventsConfig.Enabled {
+        return nil, nil
+    }
+
+    events := &AccessEvents{
+        cfg:       cfg.AccessEventsConfig,
+        documents: documents,
+        nonces:    nonces,
+        users:     make(map[string]*userAccessWindow),
+        logger:    logger.With(zap.String("component", "access_events")),
+    }
+    for _, key := range []string{cfg.AccessEventsConfig.SigningKey, cfg.AccessEventsConfig.PreviousSigningKey} {
+        if key != "" {
+            events.keys = append(events.keys, []byte(key))
+        }
+    }
+    return events, nil
+}
+
+// SessionKey returns the key the viewer signs the access events of a
+// document with during its session
+func (a *AccessEvents) SessionKey(documentID string, session ViewerSession) string {
+    return base64.RawURLEncoding.EncodeToString(a.sessionKey(a.keys[0], documentID, session))
+}
+
+// Record verifies a reported access event and adds it to the audit trail of
+// the document, along with any anomaly it raises
+func (a *AccessEvents) Record(ctx context.Context, doc *models.Document, session ViewerSession, report AccessEventReport) ([]models.AccessAnomaly, error) {
+    event, err := a.verify(ctx, doc.ID, session, report)
+    if err != nil {
+        // An unknown event name is not used as a label value
+        label := report.Event
+        if errors.Is(err, ErrInvalidAccessEvent) {
+            label = "unknown"
+        }
+        accessEvents.WithLabelValues(label, accessEventResult(err)).Inc()
+        if errors.Is(err, ErrAccessEventSignature) || errors.Is(err, ErrAccessEventReplayed) {
+            a.logger.Warn("Access event rejected",
+                zap.String("document_id", doc.ID),
+                zap.String("event", report.Event),
+                zap.String("user_id", session.UserID),
+                zap.String("session_id", session.SessionID),
+                zap.String("client_ip", session.ClientIP),
+                zap.Error(err),
+            )
+        }
+        return nil, err
+    }
+
+    anomalies := a.observe(event, time.Now())
+    doc.RecordAccessEvent(event)
+    for _, anomaly := range anomalies {
+        doc.RecordAccessAnomaly(anomaly)
+    }
+    if err := a.documents.Update(ctx, doc); err != nil {
+        accessEvents.WithLabelValues(event.Event, "error").Inc()
+        return nil, fmt.Errorf("failed to record access event: %w", err)
+    }
+
+    accessEvents.WithLabelValues(event.Event, "recorded").Inc()
+    a.logger.Info("Document access event",
+        zap.String("event", event.Event),
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.Int("page", event.Page),
+        zap.String("format", event.Format),
+        zap.Time("occurred_at", event.OccurredAt),
+        zap.String("user_id", session.UserID),
+        zap.String("role", session.Role),
+        zap.String("session_id", session.SessionID),
+        zap.String("client_ip", session.ClientIP),
+    )
+    for _, anomaly := range anomalies {
+        accessEventAnomalies.WithLabelValues(anomaly.Type).Inc()
+        a.logger.Warn("Document access anomaly",
+            zap.String("type", anomaly.Type),
+            zap.String("user_id", anomaly.UserID),
+            zap.Int("count", anomaly.Count),
+            zap.Int("threshold", anomaly.Threshold),
+            zap.Time("since", anomaly.Since),
+            zap.String("document_id", doc.ID),
+            zap.String("session_id", session.SessionID),
+        )
+    }
+    return anomalies, nil
+}
+
+// Run forgets expired nonces and finished windows until the context is
+// cancelled
+func (a *AccessEvents) Run(ctx context.Context) {
+    if a == nil {
+        return
+    }
+
+    ticker := time.NewTicker(a.cfg.MaxClockSkew)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            now := time.Now()
+            if _, err := a.nonces.DeleteExpired(ctx, now); err != nil {
+                a.logger.Error("Failed to delete expired access event nonces", zap.Error(err))
+            }
+            a.sweep(now)
+        }
+    }
+}
+
+func (a *AccessEvents) verify(ctx context.Context, documentID string, session ViewerSession, report AccessEventReport) (models.AccessEvent, error) {
+    switch report.Event {
+    case models.AccessEventView, models.AccessEventPrint, models.AccessEventExport:
+    default:
+        return models.AccessEvent{}, fmt.Errorf("%w: unknown event %q", ErrInvalidAccessEvent, report.Event)
+    }
+    if report.Page < 0 || report.OccurredAt <= 0 || !replayNoncePattern.MatchString(report.Nonce) {
+        return models.AccessEvent{}, ErrInvalidAccessEvent
+    }
+    mac, err := base64.RawURLEncoding.DecodeString(report.Signature)
+    if err != nil {
+        return models.AccessEvent{}, ErrAccessEventSignature
+    }
+
+    // The signature is checked first so the clock skew and nonce of a
+    // forged event reveal nothing
+    payload := accessEventPayload(documentID, report)
+    valid := false
+    for _, key := range a.keys {
+        if hmac.Equal(mac, accessEventMAC(a.sessionKey(key, documentID, session), payload)) {
+            valid = true
+            break
+        }
+    }
+    if !valid {
+        return models.AccessEvent{}, ErrAccessEventSignature
+    }
+
+    occurredAt := time.Unix(report.OccurredAt, 0)
+    if skew := time.Since(occurredAt); skew > a.cfg.MaxClockSkew || skew < -a.cfg.MaxClockSkew {
+        return models.AccessEvent{}, ErrAccessEventExpired
+    }
+    err = a.nonces.Remember(ctx, session.SessionID+":"+report.Nonce, occurredAt.Add(a.cfg.MaxClockSkew))
+    if errors.Is(err, repository.ErrNonceSeen) {
+        return models.AccessEvent{}, ErrAccessEventReplayed
+    }
+    if err != nil {
+        return models.AccessEvent{}, fmt.Errorf("failed to record access event nonce: %w", err)
+    }
+
+    return models.AccessEvent{
+        DocumentID: documentID,
+        Event:      report.Event,
+        Page:       report.Page,
+        Format:     report.Format,
+        Nonce:      report.Nonce,
+        OccurredAt: occurredAt,
+        UserID:     session.UserID,
+        Role:       session.Role,
+        SessionID:  session.SessionID,
+        ClientIP:   session.ClientIP,
+    }, nil
+}
+
+// observe counts the event against its user's window and returns the
+// anomalies it raises; each anomaly is raised once per user and window
+func (a *AccessEvents) observe(event models.AccessEvent, now time.Time) []models.AccessAnomaly {
+    if event.Event == models.AccessEventView {
+        return nil
+    }
+
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    start := now.Truncate(a.cfg.Window)
+    window, ok := a.users[event.UserID]
+    if !ok || !window.start.Equal(start) {
+        window = &userAccessWindow{
+            start:     start,
+            documents: make(map[string]bool),
+            raised:    make(map[string]bool),
+        }
+        a.users[event.UserID] = window
+    }
+    if event.Event == models.AccessEventPrint {
+        window.prints++
+    } else {
+        window.exports++
+    }
+    window.documents[event.DocumentID] = true
+
+    var anomalies []models.AccessAnomaly
+    raise := func(anomaly string, count, threshold int) {
+        if threshold <= 0 || count <= threshold || window.raised[anomaly] {
+            return
+        }
+        window.raised[anomaly] = true
+        anomalies = append(anomalies, models.AccessAnomaly{
+            Type:      anomaly,
+            UserID:    event.UserID,
+            Count:     count,
+            Threshold: threshold,
+            Since:     window.start,
+        })
+    }
+    thresholds := a.cfg.Thresholds
+    raise(models.AccessAnomalyPrintVolume, window.prints, thresholds.PrintsPerUser)
+    raise(models.AccessAnomalyExportVolume, window.exports, thresholds.ExportsPerUser)
+    raise(models.AccessAnomalyDocumentSpread, len(window.documents), thresholds.DocumentsPerUser)
+    return anomalies
+}
+
+// sweep drops the windows that ended
+func (a *AccessEvents) sweep(now time.Time) {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+
+    start := now.Truncate(a.cfg.Window)
+    for userID, window := range a.users {
+        if window.start.Before(start) {
+            delete(a.users, userID)
+        }
+    }
+}
+
+// sessionKey derives the key of a document and session from a signing key
+func (a *AccessEvents) sessionKey(key []byte, documentID string, session ViewerSession) []byte {
+    return accessEventMAC(key, strings.Join([]string{accessEventKeyPurpose, documentID, session.UserID, session.SessionID}, "\x00"))
+}
+
+// accessEventPayload is the canonical form of a report that the viewer signs
+func accessEventPayload(documentID string, report AccessEventReport) string {
+    return strings.Join([]string{
+        documentID,
+        report.Event,
+        strconv.Itoa(report.Page),
+        report.Format,
+        strconv.FormatInt(report.OccurredAt, 10),
+        report.Nonce,
+    }, "\n")
+}
+
+// SignAccessEvent signs a report with a session key as returned by
+// SessionKey, the way the portal's viewer does
+func SignAccessEvent(sessionKey, documentID string, report AccessEventReport) (string, error) {
+    key, err := base64.RawURLEncoding.DecodeString(sessionKey)
+    if err != nil {
+        return "", fmt.Errorf("invalid access event key: %w", err)
+    }
+    return base64.RawURLEncoding.EncodeToString(accessEventMAC(key, accessEventPayload(documentID, report))), nil
+}
+
+func accessEventMAC(key []byte, payload string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(payload))
+    return mac.Sum(nil)
+}
+
+func accessEventResult(err error) string {
+    switch {
+    case errors.Is(err, ErrInvalidAccessEvent):
+        return "invalid"
+    case errors.Is(err, ErrAccessEventSignature):
+        return "bad_signature"
+    case errors.Is(err, ErrAccessEventExpired):
+        return "expired"
+    case errors.Is(err, ErrAccessEventReplayed):
+        return "replayed"
+    default:
+        return "error"
+    }
+}
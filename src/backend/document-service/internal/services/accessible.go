@@ -0,0 +1,312 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    "sort"
+    "strings"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepAccessiblePDF = "accessible_pdf"
+
+    // gutterBins is the resolution at which a page is searched for the gap
+    // between two columns of text
+    gutterBins = 100
+    // minGutterBins is the narrowest gap taken for a gutter
+    minGutterBins = 2
+    // maxColumnWidth is the widest line taken as part of a column rather than
+    // spanning the page
+    maxColumnWidth = 0.6
+)
+
+// Fixed object numbers of the accessible PDF; the objects of each page follow
+const (
+    accessibleCatalog = iota + 1
+    accessiblePages
+    accessibleFont
+    accessibleStructTree
+    accessibleDocument
+    accessibleInfo
+    accessibleFirstPage
+)
+
+// AccessiblePDFMetadata names an accessible PDF for screen readers
+type AccessiblePDFMetadata struct {
+    Title    string
+    Language string
+}
+
+// AccessiblePDFStep produces a tagged PDF of documents OCR has read, for
+// screen readers. Each page is the rendered original under its recognized
+// text, invisible and tagged paragraph by paragraph in reading order, so
+// the document reads aloud as it looks
+type AccessiblePDFStep struct {
+    cfg     config.AccessibilityConfig
+    storage *StorageService
+}
+
+// NewAccessiblePDFStep creates a new accessible PDF step
+func NewAccessiblePDFStep(cfg *config.Config, storage *StorageService) (*AccessiblePDFStep, error) {
+    if cfg == nil || storage == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    return &AccessiblePDFStep{cfg: cfg.AccessibilityConfig, storage: storage}, nil
+}
+
+// Name returns the step name
+func (s *AccessiblePDFStep) Name() string {
+    return StepAccessiblePDF
+}
+
+// Provenance reports the generator version and that the step reads the OCR
+// text layout
+func (s *AccessiblePDFStep) Provenance() StepProvenance {
+    return StepProvenance{Version: accessiblePDFVersion, Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether OCR reads the document and its pages can be
+// rendered
+func (s *AccessiblePDFStep) Applies(doc *models.Document) bool {
+    if !ocrDocumentType(doc.DocumentType) {
+        return false
+    }
+    return doc.ContentType == "application/pdf" || doc.ContentType == "image/jpeg" || doc.ContentType == "image/png"
+}
+
+// Execute renders the pages and stores the tagged PDF as an encrypted
+// rendition. Documents OCR found no text in are skipped
+func (s *AccessiblePDFStep) Execute(ctx context.Context, run *PipelineRun) error {
+    if len(run.OCRLines) == 0 {
+        return nil
+    }
+
+    pages, err := renderPages(ctx, run.Document.ContentType, run.Content, s.cfg.DPI)
+    if err != nil {
+        return err
+    }
+    tagged, err := BuildAccessiblePDF(pages, s.cfg.DPI, s.cfg.JPEGQuality, run.OCRLines, AccessiblePDFMetadata{
+        Title:    run.Document.Filename,
+        Language: s.cfg.Language,
+    })
+    if err != nil {
+        return err
+    }
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionAccessiblePDF, "application/pdf", tagged); err != nil {
+        return fmt.Errorf("failed to store accessible PDF: %w", err)
+    }
+    return nil
+}
+
+// ReadingOrder sorts recognized lines into the order a person reads them:
+// page by page, top to bottom, and left to right along a row. On a page with
+// two columns, each column is read in full before the next, and lines
+// spanning both columns, such as headings, separate the runs of columns.
+// Pages with a line OCR gave no position keep the order OCR returned
+func ReadingOrder(lines []models.OCRLine) []models.OCRLine {
+    byPage := make(map[int][]models.OCRLine)
+    numbers := make([]int, 0)
+    for _, line := range lines {
+        if _, ok := byPage[line.Page]; !ok {
+            numbers = append(numbers, line.Page)
+        }
+        byPage[line.Page] = append(byPage[line.Page], line)
+    }
+    sort.Ints(numbers)
+
+    ordered := make([]models.OCRLine, 0, len(lines))
+    for _, number := range numbers {
+        ordered = append(ordered, orderPage(byPage[number])...)
+    }
+    return ordered
+}
+
+// orderPage orders the lines of one page
+func orderPage(lines []models.OCRLine) []models.OCRLine {
+    for _, line := range lines {
+        if line.Box == [4]float64{} {
+            return lines
+        }
+    }
+    rows := make([]models.OCRLine, len(lines))
+    copy(rows, lines)
+    sort.SliceStable(rows, func(i, j int) bool {
+        return rows[i].Box[1] < rows[j].Box[1]
+    })
+
+    gutter, ok := findGutter(rows)
+    if !ok {
+        return byRows(rows)
+    }
+
+    ordered := make([]models.OCRLine, 0, len(rows))
+    var left, right []models.OCRLine
+    flush := func() {
+        ordered = append(ordered, byRows(left)...)
+        ordered = append(ordered, byRows(right)...)
+        left, right = nil, nil
+    }
+    for _, line := range rows {
+        switch {
+        case line.Box[2] <= gutter:
+            left = append(left, line)
+        case line.Box[0] >= gutter:
+            right = append(right, line)
+        default:
+            flush()
+            ordered = append(ordered, line)
+        }
+    }
+    flush()
+    return ordered
+}
+
+// findGutter looks for a vertical gap no column line crosses near the middle
+// of the page, with at least two lines on each side, and returns its centre
+func findGutter(lines []models.OCRLine) (float64, bool) {
+    var covered [gutterBins]bool
+    for _, line := range lines {
+        if line.Box[2]-line.Box[0] > maxColumnWidth {
+            continue
+        }
+        for bin := int(line.Box[0] * gutterBins); bin < gutterBins && float64(bin) < line.Box[2]*gutterBins; bin++ {
+            if bin >= 0 {
+                covered[bin] = true
+            }
+        }
+    }
+
+    bestStart, bestLength := 0, 0
+    for start := gutterBins / 4; start < gutterBins*3/4; {
+        if covered[start] {
+            start++
+            continue
+        }
+        end := start
+        for end < gutterBins*3/4 && !covered[end] {
+            end++
+        }
+        if end-start > bestLength {
+            bestStart, bestLength = start, end-start
+        }
+        start = end
+    }
+    if bestLength < minGutterBins {
+        return 0, false
+    }
+
+    gutter := (float64(bestStart) + float64(bestLength)/2) / gutterBins
+    leftLines, rightLines := 0, 0
+    for _, line := range lines {
+        if line.Box[2] <= gutter {
+            leftLines++
+        } else if line.Box[0] >= gutter {
+            rightLines++
+        }
+    }
+    return gutter, leftLines >= 2 && rightLines >= 2
+}
+
+// byRows groups lines sorted by their top edge into rows of lines whose
+// middles fall within the first line of the row, each read left to right
+func byRows(lines []models.OCRLine) []models.OCRLine {
+    ordered := make([]models.OCRLine, 0, len(lines))
+    for start := 0; start < len(lines); {
+        end := start + 1
+        for end < len(lines) {
+            middle := (lines[end].Box[1] + lines[end].Box[3]) / 2
+            if middle > lines[start].Box[3] {
+                break
+            }
+            end++
+        }
+        row := append([]models.OCRLine(nil), lines[start:end]...)
+        sort.SliceStable(row, func(i, j int) bool {
+            return row[i].Box[0] < row[j].Box[0]
+        })
+        ordered = append(ordered, row...)
+        start = end
+    }
+    return ordered
+}
+
+// BuildAccessiblePDF writes a tagged PDF of rendered pages and their
+// recognized lines. Each page image is marked as an artifact; each line is
+// drawn as invisible text over where it appears, tagged as a paragraph with
+// its exact text, in reading order. The document declares its title and
+// language so screen readers announce and pronounce it correctly
+func BuildAccessiblePDF(pages []image.Image, dpi float64, quality int, lines []models.OCRLine, metadata AccessiblePDFMetadata) ([]byte, error) {
+    if len(pages) == 0 {
+        return nil, errors.New("no pages to write")
+    }
+
+    pdf := newPDFObjectWriter()
+
+    ordered := ReadingOrder(lines)
+    pageObject := func(page int) int { return accessibleFirstPage + 3*page }
+    firstParagraph := pageObject(len(pages))
+
+    // Paragraph structure elements, numbered in reading order
+    paragraphs := make([][]int, len(pages))
+    pageLines := make([][]models.OCRLine, len(pages))
+    for _, line := range ordered {
+        page := line.Page - 1
+        if page < 0 || page >= len(pages) || strings.TrimSpace(line.Text) == "" {
+            continue
+        }
+        paragraphs[page] = append(paragraphs[page], 0)
+        pageLines[page] = append(pageLines[page], line)
+    }
+    next := firstParagraph
+    for page := range paragraphs {
+        for i := range paragraphs[page] {
+            paragraphs[page][i] = next
+            next++
+        }
+    }
+
+    pdf.object(accessibleCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /StructTreeRoot %d 0 R /MarkInfo << /Marked true >> /Lang %s /ViewerPreferences << /DisplayDocTitle true >> >>",
+        accessiblePages, accessibleStructTree, pdfText(metadata.Language)))
+
+    kids := make([]string, len(pages))
+    for page := range pages {
+        kids[page] = fmt.Sprintf("%d 0 R", pageObject(page))
+    }
+    pdf.object(accessiblePages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+    pdf.object(accessibleFont, textLayerFont)
+
+    var parentTree, documentKids []string
+    for page := range pages {
+        refs := make([]string, len(paragraphs[page]))
+        for i, object := range paragraphs[page] {
+            refs[i] = fmt.Sprintf("%d 0 R", object)
+        }
+        parentTree = append(parentTree, fmt.Sprintf("%d [%s]", page, strings.Join(refs, " ")))
+        documentKids = append(documentKids, refs...)
+    }
+    pdf.object(accessibleStructTree, fmt.Sprintf("<< /Type /StructTreeRoot /K %d 0 R /ParentTree << /Nums [%s] >> /ParentTreeNextKey %d >>",
+        accessibleDocument, strings.Join(parentTree, " "), len(pages)))
+    pdf.object(accessibleDocument, fmt.Sprintf("<< /Type /StructElem /S /Document /P %d 0 R /K [%s] >>", accessibleStructTree, strings.Join(documentKids, " ")))
+    pdf.object(accessibleInfo, fmt.Sprintf("<< /Title %s /Producer (document-service) >>", pdfText(metadata.Title)))
+
+    for page, img := range pages {
+        encoded, err := encodePDFPage(img, dpi, quality)
+        if err != nil {
+            return nil, fmt.Errorf("failed to encode page %d: %w", page+1, err)
+        }
+        writeTextLayerPage(pdf, pageObject(page), accessiblePages, accessibleFont, encoded, pageLines[page], page)
+    }
+
+    for page := range paragraphs {
+        for i, object := range paragraphs[page] {
+            pdf.object(object, fmt.Sprintf("<< /Type /StructElem /S /P /P %d 0 R /Pg %d 0 R /K %d /ActualText %s >>",
+                accessibleDocument, pageObject(page), i, pdfText(pageLines[page][i].Text)))
+        }
+    }
+    return pdf.finish(accessibleCatalog, accessibleInfo)
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strings"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepAddress = "address"
+)
+
+// Extracted address field names
+const (
+    FieldAddressPostalCode   = "address.postal_code"
+    FieldAddressStreet       = "address.street"
+    FieldAddressNeighborhood = "address.neighborhood"
+    FieldAddressCity         = "address.city"
+    FieldAddressState        = "address.state"
+)
+
+var (
+    ErrCEPNotFound = errors.New("CEP not found")
+
+    // A CEP label followed by the code is preferred over bare eight digit sequences
+    labeledCEPPattern = regexp.MustCompile(`(?i)CEP\s*:?\s*(\d{2}\.?\d{3}-?\d{3})`)
+    cepPattern        = regexp.MustCompile(`\b\d{5}-\d{3}\b`)
+
+    accentReplacer = strings.NewReplacer(
+        "Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+        "É", "E", "Ê", "E", "È", "E",
+        "Í", "I", "Î", "I",
+        "Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+        "Ú", "U", "Ü", "U",
+        "Ç", "C",
+    )
+
+    // Street type prefixes and their abbreviations, ignored when comparing streets
+    streetTypes = map[string]bool{
+        "RUA": true, "R": true, "AVENIDA": true, "AV": true, "ALAMEDA": true, "AL": true,
+        "TRAVESSA": true, "TV": true, "ESTRADA": true, "EST": true, "RODOVIA": true, "ROD": true,
+        "PRACA": true, "PC": true,
+    }
+)
+
+// CEPLookup resolves a CEP to its standardized address
+type CEPLookup interface {
+    Lookup(ctx context.Context, cep string) (*models.Address, error)
+}
+
+// ViaCEPClient resolves CEPs through the ViaCEP public API
+type ViaCEPClient struct {
+    baseURL    string
+    httpClient *http.Client
+}
+
+// NewViaCEPClient creates a new ViaCEP client
+func NewViaCEPClient(cfg *config.Config) (*ViaCEPClient, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    return &ViaCEPClient{
+        baseURL:    cfg.AddressConfig.ViaCEPBaseURL,
+        httpClient: &http.Client{Timeout: cfg.AddressConfig.Timeout},
+    }, nil
+}
+
+// Lookup fetches the address registered for the CEP
+func (c *ViaCEPClient) Lookup(ctx context.Context, cep string) (*models.Address, error) {
+    endpoint := fmt.Sprintf("%s/ws/%s/json/", c.baseURL, url.PathEscape(cep))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build CEP request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("CEP request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("ViaCEP returned status %d", resp.StatusCode)
+    }
+
+    // ViaCEP answers unknown CEPs with 200 and {"erro": true}
+    var result struct {
+        CEP        string      `json:"cep"`
+        Logradouro string      `json:"logradouro"`
+        Bairro     string      `json:"bairro"`
+        Localidade string      `json:"localidade"`
+        UF         string      `json:"uf"`
+        Erro       interface{} `json:"erro"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to decode CEP response: %w", err)
+    }
+    if result.Erro != nil {
+        return nil, ErrCEPNotFound
+    }
+
+    return &models.Address{
+        Street:       result.Logradouro,
+        Neighborhood: result.Bairro,
+        City:         result.Localidade,
+        State:        result.UF,
+        PostalCode:   digitsOnly(result.CEP),
+    }, nil
+}
+
+// CachingCEPLookup caches resolved CEPs, which change very rarely
+type CachingCEPLookup struct {
+    next  CEPLookup
+    cache *ttlCache[models.Address]
+}
+
+// NewCachingCEPLookup creates a caching CEP lookup
+func NewCachingCEPLookup(cfg *config.Config, next CEPLookup) (*CachingCEPLookup, error) {
+    if cfg == nil || next == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CachingCEPLookup{
+        next:  next,
+        cache: newTTLCache[models.Address](cfg.AddressConfig.CacheTTL),
+    }, nil
+}
+
+// Lookup returns a cached address or resolves it through the wrapped lookup
+func (l *CachingCEPLookup) Lookup(ctx context.Context, cep string) (*models.Address, error) {
+    if address, ok := l.cache.Get(cep); ok {
+        return &address, nil
+    }
+
+    address, err := l.next.Lookup(ctx, cep)
+    if err != nil {
+        return nil, err
+    }
+    l.cache.Set(cep, *address)
+    return address, nil
+}
+
+// AddressStep standardizes the address of proof-of-address documents from its
+// CEP and flags documents whose address differs from the enrollment
+type AddressStep struct {
+    lookup      CEPLookup
+    enrollments *EnrollmentClient
+    logger      *zap.Logger
+}
+
+// NewAddressStep creates a new address normalization step
+func NewAddressStep(lookup CEPLookup, enrollments *EnrollmentClient, logger *zap.Logger) (*AddressStep, error) {
+    if lookup == nil || enrollments == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &AddressStep{
+        lookup:      lookup,
+        enrollments: enrollments,
+        logger:      logger,
+    }, nil
+}
+
+// Name returns the step name
+func (s *AddressStep) Name() string {
+    return StepAddress
+}
+
+// Provenance reports the CEP directory consulted; the CEP is read from the
+// OCR text
+func (s *AddressStep) Provenance() StepProvenance {
+    return StepProvenance{Version: addressStepVersion, Provider: "viacep", Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether the document is a proof of address
+func (s *AddressStep) Applies(doc *models.Document) bool {
+    return doc.DocumentType == "proof_of_address"
+}
+
+// Execute resolves the CEP found in the OCR text and compares the result with
+// the beneficiary address on the enrollment
+func (s *AddressStep) Execute(ctx context.Context, run *PipelineRun) error {
+    cep := ExtractCEP(run.OCRText)
+    if cep == "" {
+        return nil
+    }
+
+    address, err := s.lookup.Lookup(ctx, cep)
+    if err != nil {
+        if errors.Is(err, ErrCEPNotFound) {
+            run.Document.AddReviewFlag(models.ReviewFlagAddressMismatch)
+            addressComparisons.WithLabelValues("unknown_cep").Inc()
+            return nil
+        }
+        return err
+    }
+
+    run.Document.SetExtractedFields(StepAddress, []models.ExtractedField{
+        {Name: FieldAddressPostalCode, Value: address.PostalCode, Confidence: 1},
+        {Name: FieldAddressStreet, Value: address.Street, Confidence: 1},
+        {Name: FieldAddressNeighborhood, Value: address.Neighborhood, Confidence: 1},
+        {Name: FieldAddressCity, Value: address.City, Confidence: 1},
+        {Name: FieldAddressState, Value: address.State, Confidence: 1},
+    })
+
+    enrollment, err := s.enrollments.GetEnrollment(ctx, run.Document.EnrollmentID)
+    if err != nil {
+        return fmt.Errorf("failed to load enrollment address: %w", err)
+    }
+
+    if !AddressesMatch(*address, enrollment.BeneficiaryAddress) {
+        run.Document.AddReviewFlag(models.ReviewFlagAddressMismatch)
+        addressComparisons.WithLabelValues("mismatch").Inc()
+        s.logger.Info("Proof of address does not match enrollment",
+            zap.String("document_id", run.Document.ID),
+            zap.String("enrollment_id", run.Document.EnrollmentID),
+        )
+        return nil
+    }
+
+    addressComparisons.WithLabelValues("match").Inc()
+    return nil
+}
+
+// ExtractCEP returns the first CEP found in text as eight digits
+func ExtractCEP(text string) string {
+    if match := labeledCEPPattern.FindStringSubmatch(text); match != nil {
+        return digitsOnly(match[1])
+    }
+    if match := cepPattern.FindString(text); match != "" {
+        return digitsOnly(match)
+    }
+    return ""
+}
+
+// AddressesMatch compares a standardized address with a declared one; CEP,
+// city and UF must be equal and the street names must overlap, since CEPs of
+// small towns cover many streets
+func AddressesMatch(standardized, declared models.Address) bool {
+    if digitsOnly(standardized.PostalCode) != digitsOnly(declared.PostalCode) {
+        return false
+    }
+    if normalizeAddressText(standardized.City) != normalizeAddressText(declared.City) ||
+        normalizeAddressText(standardized.State) != normalizeAddressText(declared.State) {
+        return false
+    }
+
+    street := normalizeStreet(standardized.Street)
+    declaredStreet := normalizeStreet(declared.Street)
+    if street == "" || declaredStreet == "" {
+        return true
+    }
+    return strings.Contains(street, declaredStreet) || strings.Contains(declaredStreet, street)
+}
+
+// normalizeAddressText uppercases, strips accents and collapses whitespace
+func normalizeAddressText(value string) string {
+    return strings.Join(strings.Fields(accentReplacer.Replace(strings.ToUpper(value))), " ")
+}
+
+// normalizeStreet normalizes a street name and drops its type prefix so that
+// "R. das Flores" and "Rua das Flores" compare equal
+func normalizeStreet(value string) string {
+    words := strings.Fields(strings.NewReplacer(".", " ", ",", " ").Replace(normalizeAddressText(value)))
+    if len(words) > 1 && streetTypes[words[0]] {
+        words = words[1:]
+    }
+    return strings.Join(words, " ")
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    // apiKeyPrefix marks API keys so they are recognized when leaked
+    apiKeyPrefix = "dsk_"
+    // apiKeySecretSize is the number of random bytes in a key's secret
+    apiKeySecretSize = 32
+    // apiKeyUseInterval bounds how often the last use of a key is stored
+    apiKeyUseInterval = time.Minute
+)
+
+var (
+    ErrInvalidAPIKey       = errors.New("invalid API key")
+    ErrAPIKeyRevoked       = errors.New("API key was revoked")
+    ErrAPIKeyScope         = errors.New("API key scope does not allow this request")
+    ErrAPIKeyEnrollment    = errors.New("API key does not cover the enrollment")
+    ErrAPIKeyInvalidLimits = errors.New("API key rate limit and burst cannot be negative")
+)
+
+// APIKeyRequest issues an API key. A zero RateLimit or Burst takes the
+// configured default
+type APIKeyRequest struct {
+    TenantID    string     `json:"tenant_id"`
+    Name        string     `json:"name"`
+    Scopes      []string   `json:"scopes"`
+    EmployerIDs []string   `json:"employer_ids"`
+    RateLimit   float64    `json:"rate_limit"`
+    Burst       int        `json:"burst"`
+    ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// APIKeys issues, rotates and revokes the API keys machine integrations such
+// as corporate HR systems authenticate with, and authenticates their
+// requests. A key is shown once, as dsk_<id>.<secret>; only the SHA-256 hash
+// of the secret is stored, which is enough for a random 256-bit secret. Keys
+// are scoped to operations and optionally to the enrollments of given
+// employers, and each has its own rate limit
+type APIKeys struct {
+    cfg         config.APIKeysConfig
+    keys        repository.APIKeyRepository
+    documents   repository.DocumentRepository
+    enrollments *EnrollmentClient
+    logger      *zap.Logger
+
+    mu       sync.Mutex
+    limiters map[string]*rate.Limiter
+}
+
+// NewAPIKeys creates the API key service, or returns nil when API keys are
+// disabled
+func NewAPIKeys(cfg *config.Config, keys repository.APIKeyRepository, documents repository.DocumentRepository, enrollments *EnrollmentClient, logger *zap.Logger) (*APIKeys, error) {
+    if cfg == nil || keys == nil || documents == nil || enrollments == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.APIKeysConfig.Enabled {
+        return nil, nil
+    }
+
+    return &APIKeys{
+        cfg:         cfg.APIKeysConfig,
+        keys:        keys,
+        documents:   documents,
+        enrollments: enrollments,
+        logger:      logger.With(zap.String("component", "api_keys")),
+        limiters:    make(map[string]*rate.Limiter),
+    }, nil
+}
+
+// Role returns the role requests made with a key carry
+func (s *APIKeys) Role() string {
+    return s.cfg.Role
+}
+
+// Issue creates a key and returns it with the key to present, which is not
+// shown again
+func (s *APIKeys) Issue(ctx context.Context, req APIKeyRequest, createdBy string) (*models.APIKey, string, error) {
+    if req.TenantID == "" || req.Name == "" {
+        return nil, "", fmt.Errorf("%w: tenant_id and name are required", models.ErrMissingField)
+    }
+    if err := models.ValidateAPIKeyScopes(req.Scopes); err != nil {
+        return nil, "", err
+    }
+    if req.RateLimit < 0 || req.Burst < 0 {
+        return nil, "", ErrAPIKeyInvalidLimits
+    }
+
+    now := time.Now()
+    key := &models.APIKey{
+        ID:          uuid.NewString(),
+        TenantID:    req.TenantID,
+        Name:        req.Name,
+        Scopes:      req.Scopes,
+        EmployerIDs: req.EmployerIDs,
+        RateLimit:   req.RateLimit,
+        Burst:       req.Burst,
+        ExpiresAt:   req.ExpiresAt,
+        CreatedBy:   createdBy,
+        CreatedAt:   now,
+    }
+    if key.RateLimit == 0 {
+        key.RateLimit = s.cfg.RatePerSecond
+    }
+    if key.Burst == 0 {
+        key.Burst = s.cfg.Burst
+    }
+
+    secret, err := newAPIKeySecret()
+    if err != nil {
+        return nil, "", err
+    }
+    key.SecretHash = apiKeySecretHash(secret)
+    if err := s.keys.Create(ctx, key); err != nil {
+        return nil, "", fmt.Errorf("failed to store API key: %w", err)
+    }
+    return key, apiKeyPrefix + key.ID + "." + secret, nil
+}
+
+// List returns the keys of a tenant, or of every tenant when tenantID is
+// empty, most recent first
+func (s *APIKeys) List(ctx context.Context, tenantID string) ([]*models.APIKey, error) {
+    return s.keys.List(ctx, tenantID)
+}
+
+// Get returns a key
+func (s *APIKeys) Get(ctx context.Context, id string) (*models.APIKey, error) {
+    return s.keys.Get(ctx, id)
+}
+
+// Rotate gives a key a new secret and returns the key to present, which is
+// not shown again. The previous secret keeps working for the grace period
+func (s *APIKeys) Rotate(ctx context.Context, id string) (*models.APIKey, string, error) {
+    key, err := s.keys.Get(ctx, id)
+    if err != nil {
+        return nil, "", err
+    }
+    if key.RevokedAt != nil {
+        return nil, "", ErrAPIKeyRevoked
+    }
+
+    secret, err := newAPIKeySecret()
+    if err != nil {
+        return nil, "", err
+    }
+    key.Rotate(apiKeySecretHash(secret), time.Now(), s.cfg.RotationGracePeriod)
+    if err := s.keys.Update(ctx, key); err != nil {
+        return nil, "", err
+    }
+    return key, apiKeyPrefix + key.ID + "." + secret, nil
+}
+
+// Revoke disables a key immediately, previous secret included
+func (s *APIKeys) Revoke(ctx context.Context, id, revokedBy string) (*models.APIKey, error) {
+    key, err := s.keys.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    key.Revoke(revokedBy, time.Now())
+    if err := s.keys.Update(ctx, key); err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    delete(s.limiters, key.ID)
+    s.mu.Unlock()
+    return key, nil
+}
+
+// Authenticate returns the active key presented. Unknown, malformed,
+// revoked and expired keys are all reported as ErrInvalidAPIKey, so callers
+// learn nothing of which keys exist
+func (s *APIKeys) Authenticate(ctx context.Context, presented string) (*models.APIKey, error) {
+    id, secret, ok := strings.Cut(strings.TrimPrefix(presented, apiKeyPrefix), ".")
+    if !ok || !strings.HasPrefix(presented, apiKeyPrefix) || secret == "" {
+        apiKeyRequests.WithLabelValues("invalid").Inc()
+        return nil, ErrInvalidAPIKey
+    }
+
+    key, err := s.keys.Get(ctx, id)
+    if errors.Is(err, repository.ErrAPIKeyNotFound) {
+        apiKeyRequests.WithLabelValues("invalid").Inc()
+        return nil, ErrInvalidAPIKey
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to load API key: %w", err)
+    }
+
+    now := time.Now()
+    if !key.Active(now) || !key.Matches(apiKeySecretHash(secret), now) {
+        apiKeyRequests.WithLabelValues("invalid").Inc()
+        return nil, ErrInvalidAPIKey
+    }
+
+    if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUseInterval {
+        key.LastUsedAt = &now
+        if err := s.keys.Update(ctx, key); err != nil {
+            s.logger.Warn("Failed to record API key use", zap.String("api_key_id", key.ID), zap.Error(err))
+        }
+    }
+    return key, nil
+}
+
+// Allow takes a request from the key's rate limit. When the limit is
+// reached it returns false with the time until the next request is allowed
+func (s *APIKeys) Allow(key *models.APIKey) (bool, time.Duration) {
+    s.mu.Lock()
+    limiter, ok := s.limiters[key.ID]
+    if !ok || limiter.Limit() != rate.Limit(key.RateLimit) || limiter.Burst() != key.Burst {
+        limiter = rate.NewLimiter(rate.Limit(key.RateLimit), key.Burst)
+        s.limiters[key.ID] = limiter
+    }
+    s.mu.Unlock()
+
+    reservation := limiter.Reserve()
+    if delay := reservation.Delay(); delay > 0 {
+        reservation.Cancel()
+        apiKeyRequests.WithLabelValues("rate_limited").Inc()
+        return false, delay
+    }
+    apiKeyRequests.WithLabelValues("allowed").Inc()
+    return true, 0
+}
+
+// AuthorizeEnrollment checks that the key covers an enrollment: keys
+// restricted to employers only cover the enrollments of those employers
+func (s *APIKeys) AuthorizeEnrollment(ctx context.Context, key *models.APIKey, enrollmentID string) error {
+    if len(key.EmployerIDs) == 0 {
+        return nil
+    }
+
+    enrollment, err := s.enrollments.GetEnrollment(ctx, enrollmentID)
+    if errors.Is(err, ErrEnrollmentNotFound) {
+        return ErrAPIKeyEnrollment
+    }
+    if err != nil {
+        return fmt.Errorf("failed to resolve enrollment: %w", err)
+    }
+    if !key.CoversEmployer(enrollment.EmployerID) {
+        return ErrAPIKeyEnrollment
+    }
+    return nil
+}
+
+// AuthorizeDocument checks that the key covers a document. Documents of
+// other tenants are reported as not found
+func (s *APIKeys) AuthorizeDocument(ctx context.Context, key *models.APIKey, documentID string) error {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return err
+    }
+    if doc.TenantID != key.TenantID {
+        return repository.ErrDocumentNotFound
+    }
+    return s.AuthorizeEnrollment(ctx, key, doc.EnrollmentID)
+}
+
+// newAPIKeySecret generates a random key secret
+func newAPIKeySecret() (string, error) {
+    raw := make([]byte, apiKeySecretSize)
+    if _, err := rand.Read(raw); err != nil {
+        return "", fmt.Errorf("failed to generate API key: %w", err)
+    }
+    return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// apiKeySecretHash returns the stored form of a key secret
+func apiKeySecretHash(secret string) string {
+    sum := sha256.Sum256([]byte(secret))
+    return hex.EncodeToString(sum[:])
+}
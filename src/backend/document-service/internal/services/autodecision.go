@@ -0,0 +1,198 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/rules"
+)
+
+const (
+    // ReviewerRulesEngine is recorded as the reviewer of automatically decided documents
+    ReviewerRulesEngine = "rules-engine"
+)
+
+// compiledRule is a configured decision rule with its parsed condition
+type compiledRule struct {
+    config.DecisionRule
+    condition     *rules.Expression
+    documentTypes map[string]bool
+}
+
+// AutoDecisionService evaluates processed documents against the configured
+// rules and auto-approves, auto-rejects or leaves them for human review
+type AutoDecisionService struct {
+    rules       []compiledRule
+    documents   repository.DocumentRepository
+    enrollments *EnrollmentClient
+    review      *ReviewService
+    processing  *ProcessingCatalog
+    logger      *zap.Logger
+}
+
+// NewAutoDecisionService compiles the configured rules; invalid conditions fail startup
+func NewAutoDecisionService(cfg *config.Config, documents repository.DocumentRepository, enrollments *EnrollmentClient, review *ReviewService, logger *zap.Logger) (*AutoDecisionService, error) {
+    if cfg == nil || documents == nil || enrollments == nil || review == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    compiled := make([]compiledRule, 0, len(cfg.AutoDecisionConfig.Rules))
+    for _, rule := range cfg.AutoDecisionConfig.Rules {
+        condition, err := rules.Parse(rule.When)
+        if err != nil {
+            return nil, fmt.Errorf("invalid condition for rule %s: %w", rule.Name, err)
+        }
+
+        documentTypes := make(map[string]bool, len(rule.DocumentTypes))
+        for _, documentType := range rule.DocumentTypes {
+            documentTypes[documentType] = true
+        }
+        compiled = append(compiled, compiledRule{
+            DecisionRule:  rule,
+            condition:     condition,
+            documentTypes: documentTypes,
+        })
+    }
+
+    return &AutoDecisionService{
+        rules:       compiled,
+        documents:   documents,
+        enrollments: enrollments,
+        review:      review,
+        processing:  NewProcessingCatalog(cfg),
+        logger:      logger,
+    }, nil
+}
+
+// Evaluate decides on a document that is waiting for review. It is registered
+// as an ingest hook and re-run when asynchronous signals such as screening arrive
+func (s *AutoDecisionService) Evaluate(ctx context.Context, doc *models.Document) error {
+    current, err := s.documents.GetByID(ctx, doc.ID)
+    if err != nil {
+        return err
+    }
+    // Failed documents and documents already decided by anyone are left alone
+    if current.Status != models.DocumentStatusCompleted {
+        return nil
+    }
+
+    facts := s.collectFacts(ctx, current)
+    decision := s.decide(current, facts)
+
+    s.processing.Record(current, OperationAutoDecision, nil)
+    current.SetAutoDecision(decision)
+    if err := s.documents.Update(ctx, current); err != nil {
+        return fmt.Errorf("failed to store auto-decision: %w", err)
+    }
+
+    autoDecisions.WithLabelValues(decision.Decision).Inc()
+    s.logger.Info("Auto-decision evaluated",
+        zap.String("document_id", current.ID),
+        zap.String("enrollment_id", current.EnrollmentID),
+        zap.String("rule", decision.Rule),
+        zap.String("decision", decision.Decision),
+        zap.Any("facts", decision.Facts),
+    )
+
+    if decision.Decision == models.AutoDecisionReview {
+        return nil
+    }
+    if _, err := s.review.Review(ctx, current.ID, decision.Decision, decision.Reason, ReviewerRulesEngine); err != nil {
+        return fmt.Errorf("failed to apply auto-decision: %w", err)
+    }
+    return nil
+}
+
+// decide returns the outcome of the first matching rule, defaulting to human review
+func (s *AutoDecisionService) decide(doc *models.Document, facts rules.Facts) models.AutoDecision {
+    decision := models.AutoDecision{
+        Decision:    models.AutoDecisionReview,
+        Reason:      "No rule matched",
+        Facts:       facts,
+        EvaluatedAt: time.Now(),
+    }
+
+    for _, rule := range s.rules {
+        if len(rule.documentTypes) > 0 && !rule.documentTypes[doc.DocumentType] {
+            continue
+        }
+
+        matched, err := rule.condition.Evaluate(facts)
+        if err != nil {
+            s.logger.Warn("Auto-decision rule evaluation failed",
+                zap.String("rule", rule.Name),
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+            continue
+        }
+        if matched {
+            decision.Rule = rule.Name
+            decision.Decision = rule.Decision
+            decision.Reason = rule.Reason
+            break
+        }
+    }
+    return decision
+}
+
+// collectFacts exposes extraction results, verification outcomes and review
+// flags of the document to rule conditions
+func (s *AutoDecisionService) collectFacts(ctx context.Context, doc *models.Document) rules.Facts {
+    facts := rules.Facts{
+        "document.type":         doc.DocumentType,
+        "document.content_type": doc.ContentType,
+        "document.channel":      doc.IngestionChannel,
+        "document.size":         doc.Size,
+        "fields.count":          len(doc.ExtractedFields),
+        "signature.present":     len(doc.Signatures) > 0,
+        "signature.valid":       doc.HasValidSignature(),
+        "government_verified":   doc.GovernmentVerified,
+        "screening.completed":   len(doc.Screening) > 0,
+        "screening.hit":         doc.HasReviewFlag(models.ReviewFlagScreeningHit),
+        "flags.count":           len(doc.ReviewFlags),
+    }
+
+    for i, field := range doc.ExtractedFields {
+        // The first value of a repeated field is exposed
+        key := "field." + field.Name
+        if _, ok := facts[key]; !ok {
+            facts[key] = field.Value
+            facts[key+".confidence"] = field.Confidence
+        }
+        if lowest, ok := facts["fields.min_confidence"].(float64); i == 0 || (ok && field.Confidence < lowest) {
+            facts["fields.min_confidence"] = field.Confidence
+        }
+    }
+
+    for _, flag := range doc.ReviewFlags {
+        facts["flag."+flag] = true
+    }
+
+    if cpfs := doc.ExtractedValues(FieldCPF); len(cpfs) > 0 {
+        enrollment, err := s.enrollments.GetEnrollment(ctx, doc.EnrollmentID)
+        if err != nil {
+            s.logger.Warn("Failed to load enrollment for auto-decision",
+                zap.String("enrollment_id", doc.EnrollmentID),
+                zap.Error(err),
+            )
+        } else {
+            matches := false
+            for _, cpf := range cpfs {
+                if cpf == digitsOnly(enrollment.BeneficiaryCPF) {
+                    matches = true
+                }
+            }
+            facts["cpf.matches_enrollment"] = matches
+        }
+    }
+
+    return facts
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+    "context"
+    "io"
+    "sync"
+    "sync/atomic"
+    "time"
+
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// bandwidthChunk is the most written between two waits on the bucket, so a
+// shaped download streams smoothly instead of in bursts
+const bandwidthChunk = 32 * 1024
+
+// BandwidthShaper limits the rate at which downloads are streamed to each
+// user, with a token bucket per user sized by their role. It keeps a runaway
+// script from saturating egress while other users download normally
+type BandwidthShaper struct {
+    mu      sync.Mutex
+    cfg     config.DownloadBandwidthConfig
+    buckets map[string]*bandwidthBucket
+}
+
+// bandwidthBucket is the token bucket of one user
+type bandwidthBucket struct {
+    limiter *rate.Limiter
+    refill  time.Duration
+    // lastUsed is when a download last wrote through the bucket, in Unix
+    // nanoseconds
+    lastUsed atomic.Int64
+}
+
+// NewBandwidthShaper creates the shaper of the configured limits
+func NewBandwidthShaper(cfg *config.Config) *BandwidthShaper {
+    return &BandwidthShaper{
+        cfg:     cfg.ServiceConfig.DownloadBandwidth,
+        buckets: make(map[string]*bandwidthBucket),
+    }
+}
+
+// Writer shapes the bytes written to w for a user of the role; client
+// identifies the user, or the client when no user is authenticated. Roles
+// without a limit get w back unchanged
+func (s *BandwidthShaper) Writer(ctx context.Context, w io.Writer, role, client string) io.Writer {
+    limit := s.cfg.LimitFor(role)
+    if limit.BytesPerSecond <= 0 {
+        return w
+    }
+    label := "default"
+    if _, ok := s.cfg.Roles[role]; ok {
+        label = role
+    }
+
+    return &shapedWriter{
+        ctx:     ctx,
+        w:       w,
+        bucket:  s.bucket(role+"/"+client, limit),
+        chunk:   int(min(limit.Burst, bandwidthChunk)),
+        role:    label,
+    }
+}
+
+func (s *BandwidthShaper) bucket(key string, limit config.BandwidthLimit) *bandwidthBucket {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    bucket, ok := s.buckets[key]
+    if !ok {
+        bucket = &bandwidthBucket{
+            limiter: rate.NewLimiter(rate.Limit(limit.BytesPerSecond), int(limit.Burst)),
+            refill:  time.Duration(float64(limit.Burst) / float64(limit.BytesPerSecond) * float64(time.Second)),
+        }
+        s.buckets[key] = bucket
+    }
+    bucket.lastUsed.Store(time.Now().UnixNano())
+    return bucket
+}
+
+// Run forgets the buckets of users idle long enough for them to refill until
+// the context is cancelled; a new bucket starts out full the same
+func (s *BandwidthShaper) Run(ctx context.Context) {
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.sweep()
+        }
+    }
+}
+
+func (s *BandwidthShaper) sweep() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    now := time.Now()
+    for key, bucket := range s.buckets {
+        if now.Sub(time.Unix(0, bucket.lastUsed.Load())) > bucket.refill {
+            delete(s.buckets, key)
+        }
+    }
+}
+
+// shapedWriter waits on the user's bucket before each chunk it writes
+type shapedWriter struct {
+    ctx       context.Context
+    w         io.Writer
+    bucket    *bandwidthBucket
+    chunk     int
+    role      string
+    throttled bool
+}
+
+func (w *shapedWriter) Write(p []byte) (int, error) {
+    written := 0
+    for len(p) > 0 {
+        n := min(len(p), w.chunk)
+        if err := w.wait(n); err != nil {
+            return written, err
+        }
+        m, err := w.w.Write(p[:n])
+        written += m
+        if err != nil {
+            return written, err
+        }
+        p = p[n:]
+    }
+    return written, nil
+}
+
+// wait blocks until the bucket holds n bytes; a download is counted as
+// throttled the first time it has to wait
+func (w *shapedWriter) wait(n int) error {
+    now := time.Now()
+    w.bucket.lastUsed.Store(now.UnixNano())
+    reservation := w.bucket.limiter.ReserveN(now, n)
+    delay := reservation.Delay()
+    if delay <= 0 {
+        return nil
+    }
+
+    if !w.throttled {
+        w.throttled = true
+        downloadThrottleEvents.WithLabelValues(w.role).Inc()
+    }
+    downloadThrottledSeconds.WithLabelValues(w.role).Add(delay.Seconds())
+
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+    select {
+    case <-w.ctx.Done():
+        reservation.Cancel()
+        return w.ctx.Err()
+    case <-timer.C:
+        return nil
+    }
+}
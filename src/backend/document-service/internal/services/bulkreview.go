@@ -0,0 +1,260 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+var (
+    ErrBulkReviewTooLarge = errors.New("bulk review has too many documents")
+    ErrBulkReviewFailed   = errors.New("bulk review was not applied")
+    ErrSavedFilterLimit   = errors.New("saved filter limit reached")
+    ErrSavedFilterExists  = errors.New("a saved filter with that name exists")
+)
+
+// ReviewQueue lists the documents awaiting review for underwriters, through
+// filters they can save, and applies one decision to many documents at once
+type ReviewQueue struct {
+    review     *ReviewService
+    documents  repository.DocumentRepository
+    filters    repository.SavedFilterRepository
+    maxItems   int
+    maxFilters int
+    rate       rate.Limit
+    burst      int
+    logger     *zap.Logger
+
+    mu       sync.Mutex
+    limiters map[string]*rate.Limiter
+    // saving serializes saving filters, so owners stay within their limit
+    saving sync.Mutex
+}
+
+// NewReviewQueue creates the review queue of the review service
+func NewReviewQueue(cfg *config.Config, review *ReviewService, documents repository.DocumentRepository, filters repository.SavedFilterRepository, logger *zap.Logger) (*ReviewQueue, error) {
+    if cfg == nil || review == nil || documents == nil || filters == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ReviewQueue{
+        review:     review,
+        documents:  documents,
+        filters:    filters,
+        maxItems:   cfg.BulkReviewConfig.MaxItems,
+        maxFilters: cfg.BulkReviewConfig.MaxSavedFilters,
+        rate:       rate.Limit(cfg.BulkReviewConfig.RatePerMinute / 60),
+        burst:      cfg.BulkReviewConfig.Burst,
+        logger:     logger.With(zap.String("component", "review_queue")),
+        limiters:   make(map[string]*rate.Limiter),
+    }, nil
+}
+
+// Pending returns the documents awaiting review that pass the filter, oldest
+// first, so the longest waiting are reviewed first. The lookup is narrowed
+// by enrollment or creation time when the filter sets them
+func (q *ReviewQueue) Pending(ctx context.Context, filter models.ReviewFilter) ([]*models.Document, error) {
+    if err := filter.Validate(); err != nil {
+        return nil, err
+    }
+
+    var docs []*models.Document
+    var err error
+    if filter.EnrollmentID != "" {
+        docs, err = q.documents.ListByEnrollment(ctx, filter.EnrollmentID)
+    } else {
+        // A document created after a time was last updated after it too
+        var from time.Time
+        if filter.CreatedFrom != nil {
+            from = *filter.CreatedFrom
+        }
+        docs, err = q.documents.ListUpdatedBetween(ctx, from, time.Now().Add(time.Second))
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    pending := make([]*models.Document, 0, len(docs))
+    for _, doc := range docs {
+        if filter.Matches(doc) {
+            pending = append(pending, doc)
+        }
+    }
+    sort.SliceStable(pending, func(i, j int) bool {
+        return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+    })
+    return pending, nil
+}
+
+// SaveFilter saves a filter of owner under a name no other filter of the
+// owner has
+func (q *ReviewQueue) SaveFilter(ctx context.Context, owner, name string, filter models.ReviewFilter) (*models.SavedFilter, error) {
+    saved := &models.SavedFilter{
+        ID:        uuid.NewString(),
+        Owner:     owner,
+        Name:      strings.TrimSpace(name),
+        Filter:    filter,
+        CreatedAt: time.Now().UTC(),
+    }
+    if err := saved.Validate(); err != nil {
+        return nil, err
+    }
+
+    q.saving.Lock()
+    defer q.saving.Unlock()
+    existing, err := q.filters.ListByOwner(ctx, owner)
+    if err != nil {
+        return nil, err
+    }
+    if len(existing) >= q.maxFilters {
+        return nil, ErrSavedFilterLimit
+    }
+    for _, other := range existing {
+        if strings.EqualFold(other.Name, saved.Name) {
+            return nil, ErrSavedFilterExists
+        }
+    }
+    if err := q.filters.Create(ctx, saved); err != nil {
+        return nil, err
+    }
+    return saved, nil
+}
+
+// Filters lists the saved filters of owner by name
+func (q *ReviewQueue) Filters(ctx context.Context, owner string) ([]*models.SavedFilter, error) {
+    return q.filters.ListByOwner(ctx, owner)
+}
+
+// Filter returns a saved filter of owner; the filters of other owners are
+// not found
+func (q *ReviewQueue) Filter(ctx context.Context, owner, id string) (*models.SavedFilter, error) {
+    filter, err := q.filters.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if filter.Owner != owner {
+        return nil, repository.ErrSavedFilterNotFound
+    }
+    return filter, nil
+}
+
+// DeleteFilter deletes a saved filter of owner
+func (q *ReviewQueue) DeleteFilter(ctx context.Context, owner, id string) error {
+    if _, err := q.Filter(ctx, owner, id); err != nil {
+        return err
+    }
+    return q.filters.Delete(ctx, id)
+}
+
+// Allow takes a bulk action from the reviewer's rate limit. When the limit
+// is reached it returns false with the time until the next action is allowed
+func (q *ReviewQueue) Allow(reviewer string) (bool, time.Duration) {
+    q.mu.Lock()
+    limiter, ok := q.limiters[reviewer]
+    if !ok {
+        limiter = rate.NewLimiter(q.rate, q.burst)
+        q.limiters[reviewer] = limiter
+    }
+    q.mu.Unlock()
+
+    reservation := limiter.Reserve()
+    if delay := reservation.Delay(); delay > 0 {
+        reservation.Cancel()
+        bulkReviews.WithLabelValues("rate_limited").Inc()
+        return false, delay
+    }
+    return true, 0
+}
+
+// BulkReview applies one decision and reason to every document in ids, or
+// to none. Every document takes the decision in memory first, and when one
+// cannot, nothing is saved. The documents are then saved in turn; a failed
+// save rolls back the documents saved before it, recording the rollback in
+// their history. The result gives the outcome of each document, and is
+// returned with ErrBulkReviewFailed when the decision was not applied
+func (q *ReviewQueue) BulkReview(ctx context.Context, ids []string, decision, reason, reviewer string) (*models.BulkReviewResult, error) {
+    if len(ids) > q.maxItems {
+        return nil, fmt.Errorf("%w: %d documents, at most %d", ErrBulkReviewTooLarge, len(ids), q.maxItems)
+    }
+
+    result := &models.BulkReviewResult{Decision: decision, Items: make([]models.BulkReviewItem, len(ids))}
+    docs := make([]*models.Document, len(ids))
+    previous := make([]models.Document, len(ids))
+    failed := false
+    for i, id := range ids {
+        result.Items[i] = models.BulkReviewItem{DocumentID: id, Result: models.BulkReviewNotApplied}
+        doc, err := q.documents.GetByID(ctx, id)
+        if err == nil {
+            previous[i] = *doc
+            result.Items[i].Status = doc.Status
+            err = doc.Review(decision, reason, reviewer)
+        }
+        switch {
+        case err == nil:
+            docs[i] = doc
+        case errors.Is(err, repository.ErrDocumentNotFound), errors.Is(err, models.ErrNotReviewable),
+            errors.Is(err, models.ErrInvalidDecision), errors.Is(err, models.ErrMissingField), errors.Is(err, models.ErrPagesPending):
+            result.Items[i].Result = models.BulkReviewFailed
+            result.Items[i].Error = err.Error()
+            failed = true
+        default:
+            return nil, err
+        }
+    }
+    if failed {
+        bulkReviews.WithLabelValues("not_applied").Inc()
+        return result, ErrBulkReviewFailed
+    }
+
+    for i, doc := range docs {
+        if err := q.review.save(ctx, doc); err != nil {
+            result.Items[i].Result = models.BulkReviewFailed
+            result.Items[i].Error = err.Error()
+            q.rollback(ctx, docs[:i], previous[:i], reviewer, result)
+            bulkReviews.WithLabelValues("rolled_back").Inc()
+            return result, fmt.Errorf("%w: %w", ErrBulkReviewFailed, err)
+        }
+    }
+
+    for i, doc := range docs {
+        result.Items[i].Result = models.BulkReviewApplied
+        result.Items[i].Status = doc.Status
+        q.review.reviewed(ctx, doc)
+    }
+    result.Applied = true
+    bulkReviews.WithLabelValues("applied").Inc()
+    return result, nil
+}
+
+// rollback undoes the saved decisions of a bulk review that failed. The
+// rollback outlives a cancelled request; a document that cannot be rolled
+// back keeps the decision, and its item says so
+func (q *ReviewQueue) rollback(ctx context.Context, saved []*models.Document, previous []models.Document, reviewer string, result *models.BulkReviewResult) {
+    ctx = context.WithoutCancel(ctx)
+    for i, doc := range saved {
+        decided := doc.Status
+        doc.RollbackReview(&previous[i], reviewer)
+        if err := q.documents.Update(ctx, doc); err != nil {
+            result.Items[i].Result = models.BulkReviewApplied
+            result.Items[i].Status = decided
+            result.Items[i].Error = "rollback failed: " + err.Error()
+            q.logger.Error("Failed to roll back bulk review decision",
+                zap.String("document_id", doc.ID),
+                zap.String("reviewer", reviewer),
+                zap.Error(err),
+            )
+        }
+    }
+}
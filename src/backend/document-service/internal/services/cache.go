@@ -0,0 +1,64 @@
+package services
+
+import (
+    "sync"
+    "time"
+)
+
+// ttlEntry is a cached value remembered until expiresAt
+type ttlEntry[V any] struct {
+    value     V
+    expiresAt time.Time
+}
+
+// ttlCache is a concurrency safe in-memory cache for external lookup results
+type ttlCache[V any] struct {
+    ttl time.Duration
+
+    mu      sync.Mutex
+    entries map[string]ttlEntry[V]
+}
+
+// newTTLCache creates an empty cache whose entries expire after ttl
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+    return &ttlCache[V]{
+        ttl:     ttl,
+        entries: make(map[string]ttlEntry[V]),
+    }
+}
+
+// Get returns the cached value for key unless it is missing or expired
+func (c *ttlCache[V]) Get(key string) (V, bool) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    entry, ok := c.entries[key]
+    if !ok {
+        var zero V
+        return zero, false
+    }
+    if time.Now().After(entry.expiresAt) {
+        delete(c.entries, key)
+        var zero V
+        return zero, false
+    }
+    return entry.value, true
+}
+
+// Set stores value under key for the cache TTL
+func (c *ttlCache[V]) Set(key string, value V) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    c.entries[key] = ttlEntry[V]{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// Clear drops every entry, returning how many there were
+func (c *ttlCache[V]) Clear() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    dropped := len(c.entries)
+    c.entries = make(map[string]ttlEntry[V])
+    return dropped
+}
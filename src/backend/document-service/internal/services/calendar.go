@@ -0,0 +1,115 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// DefaultCalendar names the default business calendar
+const DefaultCalendar = "default"
+
+var ErrCalendarNotFound = errors.New("business calendar not found")
+
+// BusinessCalendars holds the business calendars deadlines and estimates
+// count time on, and which calendar each tenant uses
+type BusinessCalendars struct {
+    calendars map[string]*models.BusinessCalendar
+    tenants   map[string]string
+}
+
+// NewBusinessCalendars builds the configured calendars, or returns nil when
+// business calendars are disabled
+func NewBusinessCalendars(cfg *config.Config) (*BusinessCalendars, error) {
+    if cfg == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.BusinessCalendarConfig.Enabled {
+        return nil, nil
+    }
+
+    base := cfg.BusinessCalendarConfig.Default
+    specs := map[string]config.CalendarConfig{DefaultCalendar: base}
+    for name, calendar := range cfg.BusinessCalendarConfig.Calendars {
+        if calendar.Timezone == "" {
+            calendar.Timezone = base.Timezone
+        }
+        if calendar.OpensAt == "" {
+            calendar.OpensAt = base.OpensAt
+        }
+        if calendar.ClosesAt == "" {
+            calendar.ClosesAt = base.ClosesAt
+        }
+        calendar.Holidays = append(append([]string(nil), base.Holidays...), calendar.Holidays...)
+        specs[name] = calendar
+    }
+
+    calendars := &BusinessCalendars{
+        calendars: make(map[string]*models.BusinessCalendar, len(specs)),
+        tenants:   cfg.BusinessCalendarConfig.Tenants,
+    }
+    for name, spec := range specs {
+        calendar, err := models.NewBusinessCalendar(models.CalendarSpec{
+            Name:             name,
+            Timezone:         spec.Timezone,
+            OpensAt:          spec.OpensAt,
+            ClosesAt:         spec.ClosesAt,
+            Holidays:         spec.Holidays,
+            NationalHolidays: cfg.BusinessCalendarConfig.NationalHolidays,
+            OptionalHolidays: cfg.BusinessCalendarConfig.OptionalHolidays,
+        })
+        if err != nil {
+            return nil, err
+        }
+        calendars.calendars[name] = calendar
+    }
+    return calendars, nil
+}
+
+// For returns the calendar of a tenant. A nil *BusinessCalendars returns a
+// nil calendar, which counts every moment
+func (c *BusinessCalendars) For(tenantID string) *models.BusinessCalendar {
+    if c == nil {
+        return nil
+    }
+    if name, ok := c.tenants[tenantID]; ok {
+        return c.calendars[name]
+    }
+    return c.calendars[DefaultCalendar]
+}
+
+// Names lists the calendars by name
+func (c *BusinessCalendars) Names() []string {
+    names := make([]string, 0, len(c.calendars))
+    for name := range c.calendars {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Preview computes the deadline d of business time after start on a named
+// calendar, or on the tenant's calendar when name is empty
+func (c *BusinessCalendars) Preview(name, tenantID string, start time.Time, d time.Duration) (*models.DeadlinePreview, error) {
+    calendar := c.For(tenantID)
+    if name != "" {
+        var ok bool
+        if calendar, ok = c.calendars[name]; !ok {
+            return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, name)
+        }
+    }
+
+    due := calendar.Add(start, d)
+    return &models.DeadlinePreview{
+        Calendar:   calendar.Name(),
+        Timezone:   calendar.Location().String(),
+        Start:      start.In(calendar.Location()),
+        Duration:   d.String(),
+        DueAt:      due.In(calendar.Location()),
+        ClosedDays: calendar.ClosedDays(start, due),
+    }, nil
+}
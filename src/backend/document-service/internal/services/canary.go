@@ -0,0 +1,326 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "hash/fnv"
+    "slices"
+    "strings"
+    "time"
+    "unicode"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    StepOCRCanary = "ocr_canary"
+
+    // canaryReportedDocuments bounds the diverged documents a report lists
+    canaryReportedDocuments = 100
+)
+
+var (
+    ErrUnknownOCRProvider = errors.New("unknown OCR provider")
+)
+
+// canaryField extracts a field from recognized text for comparison
+type canaryField struct {
+    name    string
+    extract func(text string) string
+}
+
+// canaryFields are the fields compared between the texts of two providers:
+// the fields later steps extract from the text
+var canaryFields = []canaryField{
+    {FieldHolderName, ExtractHolderName},
+    {FieldCPF, func(text string) string {
+        cpfs := ExtractCPFs(text)
+        slices.Sort(cpfs)
+        return strings.Join(cpfs, ",")
+    }},
+    {FieldAddressPostalCode, ExtractCEP},
+}
+
+// OCRCanary runs a candidate OCR provider alongside the provider in service on
+// a sample of the documents OCR reads. The candidate's text is stored as an
+// encrypted rendition next to the text in service, and the two are compared
+// word by word and by the fields extracted from them, so the candidate's
+// divergence is known before it replaces the provider in service. The sample
+// is deterministic per document, so reprocessing compares the same documents
+type OCRCanary struct {
+    cfg       config.OCRCanaryConfig
+    provider  string
+    candidate OCRProvider
+    storage   *StorageService
+    documents repository.DocumentRepository
+    logger    *zap.Logger
+}
+
+// NewOCRCanary creates the canary of the configured candidate among the
+// providers, compared with the provider in service, or returns nil when the
+// canary is disabled
+func NewOCRCanary(cfg *config.Config, inService OCRProvider, providers map[string]OCRProvider, storage *StorageService, documents repository.DocumentRepository, logger *zap.Logger) (*OCRCanary, error) {
+    if cfg == nil || inService == nil || storage == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.OCRCanaryConfig.Enabled {
+        return nil, nil
+    }
+    candidate, ok := providers[cfg.OCRCanaryConfig.Candidate]
+    if !ok {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownOCRProvider, cfg.OCRCanaryConfig.Candidate)
+    }
+
+    return &OCRCanary{
+        cfg:       cfg.OCRCanaryConfig,
+        provider:  inService.Provider(),
+        candidate: candidate,
+        storage:   storage,
+        documents: documents,
+        logger:    logger.With(zap.String("component", "ocr_canary")),
+    }, nil
+}
+
+// Name returns the step name
+func (s *OCRCanary) Name() string {
+    return StepOCRCanary
+}
+
+// Provenance reports the candidate provider and that the step compares with
+// the OCR text
+func (s *OCRCanary) Provenance() StepProvenance {
+    return StepProvenance{Version: ocrCanaryVersion, Provider: s.candidate.Provider(), Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether OCR reads the document and it is in the sample
+func (s *OCRCanary) Applies(doc *models.Document) bool {
+    return ocrDocumentType(doc.DocumentType) && s.sampled(doc.ID)
+}
+
+// Execute reads the document with the candidate, stores its text and records
+// the comparison with the text in service. Documents OCR found no text in are
+// skipped. A candidate failure is recorded on the document and fails the step
+// only, never the document
+func (s *OCRCanary) Execute(ctx context.Context, run *PipelineRun) error {
+    if strings.TrimSpace(run.OCRText) == "" {
+        return nil
+    }
+    candidate := s.candidate.Provider()
+
+    startTime := time.Now()
+    lines, err := s.candidate.Recognize(ctx, run.Document.TenantID, run.Content)
+    duration := time.Since(startTime)
+    if err != nil {
+        ocrCanaryComparisons.WithLabelValues(candidate, "failed").Inc()
+        run.Document.RecordOCRCanary(models.OCRCanaryComparison{
+            Provider:   s.provider,
+            Candidate:  candidate,
+            Failed:     true,
+            DurationMS: duration.Milliseconds(),
+            ComparedAt: startTime,
+        })
+        return fmt.Errorf("candidate OCR provider failed: %w", err)
+    }
+
+    text := linesText(lines)
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionOCRTextCanary, "text/plain; charset=utf-8", []byte(text)); err != nil {
+        return fmt.Errorf("failed to store candidate text: %w", err)
+    }
+
+    comparison := CompareOCRText(run.OCRText, text, s.cfg.MinAgreement)
+    comparison.Provider = s.provider
+    comparison.Candidate = candidate
+    comparison.DurationMS = duration.Milliseconds()
+    comparison.ComparedAt = startTime
+    run.Document.RecordOCRCanary(comparison)
+
+    result := "agreed"
+    if comparison.Diverged {
+        result = "diverged"
+        s.logger.Info("Candidate OCR provider diverged",
+            zap.String("document_id", run.Document.ID),
+            zap.String("candidate", candidate),
+            zap.Float64("text_agreement", comparison.TextAgreement),
+        )
+    }
+    ocrCanaryComparisons.WithLabelValues(candidate, result).Inc()
+    ocrCanaryAgreement.WithLabelValues(candidate).Observe(comparison.TextAgreement)
+    for _, field := range comparison.Fields {
+        ocrCanaryFields.WithLabelValues(candidate, field.Field, field.Outcome).Inc()
+    }
+    return nil
+}
+
+// Report aggregates the comparisons made in [from, to) by the candidate in
+// service
+func (s *OCRCanary) Report(ctx context.Context, from, to time.Time) (*models.OCRCanaryReport, error) {
+    if from.IsZero() || !from.Before(to) {
+        return nil, ErrInvalidReportPeriod
+    }
+
+    // Recording a comparison updates the document, so every document compared
+    // in the period was last updated at or after its start
+    docs, err := s.documents.ListUpdatedBetween(ctx, from, time.Now().Add(time.Second))
+    if err != nil {
+        return nil, fmt.Errorf("failed to list documents: %w", err)
+    }
+    comparisons := make([]models.OCRCanaryComparison, 0)
+    documentIDs := make([]string, 0)
+    for _, doc := range docs {
+        comparison := doc.OCRCanary
+        if comparison == nil || comparison.ComparedAt.Before(from) || !comparison.ComparedAt.Before(to) {
+            continue
+        }
+        comparisons = append(comparisons, *comparison)
+        documentIDs = append(documentIDs, doc.ID)
+    }
+
+    report := SummarizeOCRCanary(s.candidate.Provider(), comparisons, documentIDs)
+    report.From = from
+    report.To = to
+    return report, nil
+}
+
+// sampled deterministically places the configured percentage of documents in
+// the sample
+func (s *OCRCanary) sampled(documentID string) bool {
+    hash := fnv.New32a()
+    hash.Write([]byte(StepOCRCanary + ":" + documentID))
+    return float64(hash.Sum32()%10000)/100 < s.cfg.Percentage
+}
+
+// CompareOCRText compares the text a candidate provider recognized with the
+// text of the provider in service: by the share of their words in common and
+// by the fields extracted from each. The candidate diverges when the share is
+// below minAgreement or a field extracted from either text differs
+func CompareOCRText(inService, candidate string, minAgreement float64) models.OCRCanaryComparison {
+    comparison := models.OCRCanaryComparison{TextAgreement: wordAgreement(inService, candidate)}
+    comparison.Diverged = comparison.TextAgreement < minAgreement
+
+    for _, field := range canaryFields {
+        control, candidateValue := field.extract(inService), field.extract(candidate)
+        outcome := models.FieldAgreementMatch
+        switch {
+        case control == "" && candidateValue == "":
+            continue
+        case control == "":
+            outcome = models.FieldAgreementMissingControl
+        case candidateValue == "":
+            outcome = models.FieldAgreementMissingCandidate
+        case !strings.EqualFold(strings.TrimSpace(control), strings.TrimSpace(candidateValue)):
+            outcome = models.FieldAgreementMismatch
+        }
+        if outcome != models.FieldAgreementMatch {
+            comparison.Diverged = true
+        }
+        comparison.Fields = append(comparison.Fields, models.FieldAgreement{Field: field.name, Outcome: outcome})
+    }
+    return comparison
+}
+
+// wordAgreement returns the share of words two texts have in common, counting
+// repeated words as often as both texts hold them. Case and punctuation are
+// ignored, so identifiers match however they are punctuated; two texts
+// without words agree
+func wordAgreement(a, b string) float64 {
+    wordsA, wordsB := comparableWords(a), comparableWords(b)
+    if len(wordsA)+len(wordsB) == 0 {
+        return 1
+    }
+
+    counts := make(map[string]int, len(wordsA))
+    for _, word := range wordsA {
+        counts[word]++
+    }
+    common := 0
+    for _, word := range wordsB {
+        if counts[word] > 0 {
+            counts[word]--
+            common++
+        }
+    }
+    return 2 * float64(common) / float64(len(wordsA)+len(wordsB))
+}
+
+// comparableWords splits text at white space into lowercase words of their
+// letters and digits
+func comparableWords(text string) []string {
+    words := make([]string, 0)
+    for _, chunk := range strings.Fields(strings.ToLower(text)) {
+        word := strings.Map(func(r rune) rune {
+            if unicode.IsLetter(r) || unicode.IsDigit(r) {
+                return r
+            }
+            return -1
+        }, chunk)
+        if word != "" {
+            words = append(words, word)
+        }
+    }
+    return words
+}
+
+// SummarizeOCRCanary aggregates the comparisons of a candidate; documentIDs
+// lists the document of each comparison
+func SummarizeOCRCanary(candidate string, comparisons []models.OCRCanaryComparison, documentIDs []string) *models.OCRCanaryReport {
+    report := &models.OCRCanaryReport{
+        Candidate:   candidate,
+        Fields:      make([]models.FieldAgreementRate, 0, len(canaryFields)),
+        GeneratedAt: time.Now(),
+    }
+    rates := make(map[string]*models.FieldAgreementRate, len(canaryFields))
+    for _, field := range canaryFields {
+        report.Fields = append(report.Fields, models.FieldAgreementRate{Field: field.name})
+    }
+    for i := range report.Fields {
+        rates[report.Fields[i].Field] = &report.Fields[i]
+    }
+
+    agreement := 0.0
+    for i, comparison := range comparisons {
+        report.Compared++
+        if comparison.Failed {
+            report.Failed++
+            continue
+        }
+        agreement += comparison.TextAgreement
+        if comparison.Diverged {
+            report.Diverged++
+            if len(report.DivergedDocuments) < canaryReportedDocuments {
+                report.DivergedDocuments = append(report.DivergedDocuments, documentIDs[i])
+            }
+        }
+        for _, field := range comparison.Fields {
+            rate, ok := rates[field.Field]
+            if !ok {
+                continue
+            }
+            rate.Compared++
+            switch field.Outcome {
+            case models.FieldAgreementMatch:
+                rate.Matched++
+            case models.FieldAgreementMismatch:
+                rate.Mismatched++
+            case models.FieldAgreementMissingControl:
+                rate.MissingControl++
+            case models.FieldAgreementMissingCandidate:
+                rate.MissingCandidate++
+            }
+        }
+    }
+
+    if read := report.Compared - report.Failed; read > 0 {
+        report.MeanTextAgreement = agreement / float64(read)
+    }
+    for i := range report.Fields {
+        if report.Fields[i].Compared > 0 {
+            report.Fields[i].Agreement = float64(report.Fields[i].Matched) / float64(report.Fields[i].Compared)
+        }
+    }
+    return report
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Enrollment event types published by the enrollment service
+const (
+    EnrollmentEventCancelled = "enrollment.cancelled"
+)
+
+// Outbox topics reporting cancellation cleanup back to the enrollment service
+const (
+    TopicDispositionEvent   = "enrollment.document_disposition"
+    TopicCancellationReport = "enrollment.cancellation_report"
+)
+
+// Events sent to the enrollment service
+const (
+    DispositionEventApplied     = "disposition.applied"
+    DispositionEventCompensated = "disposition.compensated"
+    CancellationReportEvent     = "cancellation.report"
+)
+
+var (
+    ErrUnknownEnrollmentEvent = errors.New("unknown enrollment event type")
+    ErrInvalidEnrollmentEvent = errors.New("enrollment event is missing its id or enrollment")
+    ErrSagaInProgress         = errors.New("cancellation saga is already running")
+    ErrSagaCompensated        = errors.New("cancellation cleanup failed and was compensated")
+    ErrSagaIncomplete         = errors.New("cancellation cleanup is incomplete")
+)
+
+// EnrollmentEvent is an enrollment lifecycle change published by the
+// enrollment service
+type EnrollmentEvent struct {
+    EventID      string    `json:"event_id"`
+    Type         string    `json:"type"`
+    EnrollmentID string    `json:"enrollment_id"`
+    OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// DispositionEvent tells the enrollment service that the disposition of a
+// document was applied, or compensated after the saga failed
+type DispositionEvent struct {
+    Type         string    `json:"type"`
+    SagaID       string    `json:"saga_id"`
+    EnrollmentID string    `json:"enrollment_id"`
+    DocumentID   string    `json:"document_id"`
+    DocumentType string    `json:"document_type"`
+    Disposition  string    `json:"disposition"`
+    OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// CancellationReport is sent to the enrollment service once a saga completes
+// or is compensated
+type CancellationReport struct {
+    Type         string                   `json:"type"`
+    SagaID       string                   `json:"saga_id"`
+    EnrollmentID string                   `json:"enrollment_id"`
+    Status       string                   `json:"status"`
+    Retained     int                      `json:"retained"`
+    Anonymized   int                      `json:"anonymized"`
+    Deleted      int                      `json:"deleted"`
+    Documents    []models.DispositionStep `json:"documents"`
+    Error        string                   `json:"error,omitempty"`
+    ReportedAt   time.Time                `json:"reported_at"`
+}
+
+// CancellationService cleans up the documents of cancelled enrollments with
+// a saga. Every document first gets its disposition recorded, which can be
+// undone: if any of these reservations fails the others are compensated and
+// the enrollment service is told so. Once all are reserved the saga only
+// moves forward, anonymizing and deleting documents, and documents that fail
+// are retried when the event is redelivered
+type CancellationService struct {
+    cfg        config.CancellationConfig
+    sagas      repository.CancellationSagaRepository
+    documents  repository.DocumentRepository
+    shredder   *CryptoShredder
+    outbox     repository.OutboxRepository
+    httpClient *http.Client
+    logger     *zap.Logger
+
+    mu      sync.Mutex
+    running map[string]bool
+}
+
+// NewCancellationService creates a new enrollment cancellation service
+func NewCancellationService(cfg *config.Config, sagas repository.CancellationSagaRepository, documents repository.DocumentRepository, shredder *CryptoShredder, outbox repository.OutboxRepository, logger *zap.Logger) (*CancellationService, error) {
+    if cfg == nil || sagas == nil || documents == nil || shredder == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CancellationService{
+        cfg:       cfg.CancellationConfig,
+        sagas:     sagas,
+        documents: documents,
+        shredder:  shredder,
+        outbox:    outbox,
+        httpClient: &http.Client{
+            Timeout:   cfg.CancellationConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+        logger:  logger.With(zap.String("component", "cancellation")),
+        running: make(map[string]bool),
+    }, nil
+}
+
+// Disposition returns the disposition configured for a document type
+func (s *CancellationService) Disposition(documentType string) string {
+    if disposition, ok := s.cfg.Dispositions[documentType]; ok {
+        return disposition
+    }
+    return s.cfg.DefaultDisposition
+}
+
+// Handle runs the saga of a cancellation event. A completed saga is returned
+// as is, an interrupted one resumes and a compensated one is planned again,
+// so the enrollment service can redeliver the event until it succeeds
+func (s *CancellationService) Handle(ctx context.Context, event EnrollmentEvent) (*models.CancellationSaga, error) {
+    if event.Type != EnrollmentEventCancelled {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownEnrollmentEvent, event.Type)
+    }
+    if event.EventID == "" || event.EnrollmentID == "" {
+        return nil, ErrInvalidEnrollmentEvent
+    }
+    if event.OccurredAt.IsZero() {
+        event.OccurredAt = time.Now()
+    }
+    if !s.acquire(event.EventID) {
+        return nil, ErrSagaInProgress
+    }
+    defer s.release(event.EventID)
+
+    saga, err := s.sagas.Get(ctx, event.EventID)
+    switch {
+    case errors.Is(err, repository.ErrSagaNotFound):
+        if saga, err = s.plan(ctx, event); err != nil {
+            return nil, err
+        }
+        if err := s.sagas.Create(ctx, saga); err != nil {
+            return nil, fmt.Errorf("failed to record cancellation saga: %w", err)
+        }
+    case err != nil:
+        return nil, fmt.Errorf("failed to load cancellation saga: %w", err)
+    case saga.Finished():
+        return saga, nil
+    case saga.Status == models.SagaStatusCompensated:
+        replanned, err := s.plan(ctx, event)
+        if err != nil {
+            return nil, err
+        }
+        replanned.Attempts = saga.Attempts
+        replanned.StartedAt = saga.StartedAt
+        saga = replanned
+    }
+
+    saga.Attempts++
+    return saga, s.run(ctx, saga)
+}
+
+// Get returns a saga by its cancellation event ID
+func (s *CancellationService) Get(ctx context.Context, id string) (*models.CancellationSaga, error) {
+    return s.sagas.Get(ctx, id)
+}
+
+// ListByEnrollment returns the sagas of an enrollment
+func (s *CancellationService) ListByEnrollment(ctx context.Context, enrollmentID string) ([]*models.CancellationSaga, error) {
+    return s.sagas.ListByEnrollment(ctx, enrollmentID)
+}
+
+// plan decides the disposition of every document of the enrollment
+func (s *CancellationService) plan(ctx context.Context, event EnrollmentEvent) (*models.CancellationSaga, error) {
+    docs, err := s.documents.ListByEnrollment(ctx, event.EnrollmentID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list documents of enrollment %s: %w", event.EnrollmentID, err)
+    }
+
+    now := time.Now()
+    saga := &models.CancellationSaga{
+        ID:           event.EventID,
+        EnrollmentID: event.EnrollmentID,
+        CancelledAt:  event.OccurredAt,
+        Status:       models.SagaStatusReserving,
+        Steps:        make([]models.DispositionStep, 0, len(docs)),
+        StartedAt:    now,
+        UpdatedAt:    now,
+    }
+    for _, doc := range docs {
+        saga.Steps = append(saga.Steps, models.DispositionStep{
+            DocumentID:   doc.ID,
+            DocumentType: doc.DocumentType,
+            Disposition:  s.Disposition(doc.DocumentType),
+            Status:       models.DispositionPending,
+        })
+    }
+    return saga, nil
+}
+
+// run reserves the pending dispositions, then applies the reserved ones
+func (s *CancellationService) run(ctx context.Context, saga *models.CancellationSaga) error {
+    if saga.Status == models.SagaStatusReserving {
+        for i := range saga.Steps {
+            step := &saga.Steps[i]
+            if step.Status != models.DispositionPending {
+                continue
+            }
+            if err := s.reserve(ctx, saga, step); err != nil {
+                step.Status = models.DispositionFailed
+                step.Error = err.Error()
+                return s.compensate(ctx, saga, err)
+            }
+            if err := s.save(ctx, saga); err != nil {
+                return err
+            }
+        }
+        // Every document holds its disposition; from here on the saga only
+        // moves forward
+        saga.Status = models.SagaStatusApplying
+        if err := s.save(ctx, saga); err != nil {
+            return err
+        }
+    }
+
+    failed := 0
+    for i := range saga.Steps {
+        step := &saga.Steps[i]
+        if step.Status != models.DispositionReserved {
+            continue
+        }
+        if err := s.apply(ctx, step); err != nil {
+            failed++
+            step.Error = err.Error()
+            documentDispositions.WithLabelValues(step.Disposition, "error").Inc()
+            s.logger.Warn("Document disposition failed",
+                zap.String("saga_id", saga.ID),
+                zap.String("document_id", step.DocumentID),
+                zap.String("disposition", step.Disposition),
+                zap.Error(err),
+            )
+            continue
+        }
+        appliedAt := time.Now()
+        step.Status = models.DispositionApplied
+        step.Error = ""
+        step.AppliedAt = &appliedAt
+        documentDispositions.WithLabelValues(step.Disposition, "applied").Inc()
+        s.emit(ctx, saga, step, DispositionEventApplied)
+    }
+
+    if failed > 0 {
+        saga.Error = fmt.Sprintf("%d documents could not be disposed of", failed)
+        cancellationSagas.WithLabelValues("incomplete").Inc()
+        if err := s.save(ctx, saga); err != nil {
+            return err
+        }
+        return fmt.Errorf("%w: %d of %d documents failed", ErrSagaIncomplete, failed, len(saga.Steps))
+    }
+
+    completedAt := time.Now()
+    saga.Status = models.SagaStatusCompleted
+    saga.Error = ""
+    saga.CompletedAt = &completedAt
+    if err := s.save(ctx, saga); err != nil {
+        return err
+    }
+    cancellationSagas.WithLabelValues(models.SagaStatusCompleted).Inc()
+    s.report(ctx, saga)
+    s.logger.Info("Enrollment cancellation cleanup completed",
+        zap.String("saga_id", saga.ID),
+        zap.String("enrollment_id", saga.EnrollmentID),
+        zap.Int("retained", saga.Count(models.DispositionRetain, models.DispositionApplied)),
+        zap.Int("anonymized", saga.Count(models.DispositionAnonymize, models.DispositionApplied)),
+        zap.Int("deleted", saga.Count(models.DispositionDelete, models.DispositionApplied)),
+    )
+    return nil
+}
+
+// reserve records the disposition on the document, extending the retention
+// of retained documents. A document already reserved by this saga is left
+// untouched, so the retention date it had before is not lost
+func (s *CancellationService) reserve(ctx context.Context, saga *models.CancellationSaga, step *models.DispositionStep) error {
+    doc, err := s.documents.GetByID(ctx, step.DocumentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        // Deleted since the saga was planned; nothing is left to dispose of
+        appliedAt := time.Now()
+        step.Status = models.DispositionApplied
+        step.AppliedAt = &appliedAt
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    if doc.Disposition == nil || doc.Disposition.SagaID != saga.ID {
+        doc.Dispose(saga.ID, step.Disposition, time.Now(), saga.CancelledAt.Add(s.cfg.RetainFor))
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return fmt.Errorf("failed to reserve disposition of document %s: %w", doc.ID, err)
+        }
+    }
+    step.Status = models.DispositionReserved
+    return nil
+}
+
+// apply carries out a reserved disposition; it is safe to repeat
+func (s *CancellationService) apply(ctx context.Context, step *models.DispositionStep) error {
+    doc, err := s.documents.GetByID(ctx, step.DocumentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+
+    switch step.Disposition {
+    case models.DispositionRetain:
+        // Kept until the retention date extended by the reservation
+        return nil
+    case models.DispositionAnonymize:
+        if doc.Anonymized() {
+            return nil
+        }
+        return s.shredder.Anonymize(ctx, doc)
+    case models.DispositionDelete:
+        return s.shredder.Erase(ctx, doc)
+    }
+    return fmt.Errorf("unsupported disposition: %s", step.Disposition)
+}
+
+// compensate undoes the reservations made, newest first, after a reservation
+// failed. A reservation that cannot be undone keeps its error and is undone
+// when the redelivered event plans the saga again
+func (s *CancellationService) compensate(ctx context.Context, saga *models.CancellationSaga, cause error) error {
+    for i := len(saga.Steps) - 1; i >= 0; i-- {
+        step := &saga.Steps[i]
+        if step.Status != models.DispositionReserved {
+            continue
+        }
+
+        doc, err := s.documents.GetByID(ctx, step.DocumentID)
+        if err == nil && doc.Disposition != nil && doc.Disposition.SagaID == saga.ID {
+            doc.RevertDisposition(time.Now())
+            err = s.documents.Update(ctx, doc)
+        }
+        if err != nil && !errors.Is(err, repository.ErrDocumentNotFound) {
+            step.Error = "compensation failed: " + err.Error()
+            s.logger.Error("Failed to compensate document disposition",
+                zap.String("saga_id", saga.ID),
+                zap.String("document_id", step.DocumentID),
+                zap.Error(err),
+            )
+            continue
+        }
+        step.Status = models.DispositionCompensated
+        documentDispositions.WithLabelValues(step.Disposition, "compensated").Inc()
+        s.emit(ctx, saga, step, DispositionEventCompensated)
+    }
+
+    saga.Status = models.SagaStatusCompensated
+    saga.Error = cause.Error()
+    if err := s.save(ctx, saga); err != nil {
+        s.logger.Error("Failed to record compensated saga", zap.String("saga_id", saga.ID), zap.Error(err))
+    }
+    cancellationSagas.WithLabelValues(models.SagaStatusCompensated).Inc()
+    s.report(ctx, saga)
+    s.logger.Warn("Enrollment cancellation cleanup compensated",
+        zap.String("saga_id", saga.ID),
+        zap.String("enrollment_id", saga.EnrollmentID),
+        zap.Error(cause),
+    )
+    return fmt.Errorf("%w: %v", ErrSagaCompensated, cause)
+}
+
+func (s *CancellationService) save(ctx context.Context, saga *models.CancellationSaga) error {
+    saga.UpdatedAt = time.Now()
+    if err := s.sagas.Update(ctx, saga); err != nil {
+        return fmt.Errorf("failed to record cancellation saga: %w", err)
+    }
+    return nil
+}
+
+// emit queues a disposition event; events are keyed per attempt, so the
+// compensation of a retried saga is reported again
+func (s *CancellationService) emit(ctx context.Context, saga *models.CancellationSaga, step *models.DispositionStep, eventType string) {
+    s.enqueue(ctx, TopicDispositionEvent, fmt.Sprintf("%s/%d/%s/%s", saga.ID, saga.Attempts, step.DocumentID, eventType), DispositionEvent{
+        Type:         eventType,
+        SagaID:       saga.ID,
+        EnrollmentID: saga.EnrollmentID,
+        DocumentID:   step.DocumentID,
+        DocumentType: step.DocumentType,
+        Disposition:  step.Disposition,
+        OccurredAt:   time.Now().UTC(),
+    })
+}
+
+// report queues the report of a completed or compensated saga
+func (s *CancellationService) report(ctx context.Context, saga *models.CancellationSaga) {
+    s.enqueue(ctx, TopicCancellationReport, fmt.Sprintf("%s/%d", saga.ID, saga.Attempts), CancellationReport{
+        Type:         CancellationReportEvent,
+        SagaID:       saga.ID,
+        EnrollmentID: saga.EnrollmentID,
+        Status:       saga.Status,
+        Retained:     saga.Count(models.DispositionRetain, models.DispositionApplied),
+        Anonymized:   saga.Count(models.DispositionAnonymize, models.DispositionApplied),
+        Deleted:      saga.Count(models.DispositionDelete, models.DispositionApplied),
+        Documents:    append([]models.DispositionStep(nil), saga.Steps...),
+        Error:        saga.Error,
+        ReportedAt:   time.Now().UTC(),
+    })
+}
+
+func (s *CancellationService) enqueue(ctx context.Context, topic, key string, payload interface{}) {
+    msg, err := newOutboxMessage(topic, key, payload)
+    if err == nil {
+        err = s.outbox.Enqueue(ctx, msg)
+    }
+    if err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        s.logger.Error("Failed to queue cancellation event",
+            zap.String("topic", topic),
+            zap.String("key", key),
+            zap.Error(err),
+        )
+    }
+}
+
+// Deliver is the outbox handler posting disposition events and reports to
+// the enrollment service
+func (s *CancellationService) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    return postOutboxMessage(ctx, s.httpClient, s.cfg.ReportURL, msg)
+}
+
+// acquire marks a saga as running in this instance
+func (s *CancellationService) acquire(id string) bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if s.running[id] {
+        return false
+    }
+    s.running[id] = true
+    return true
+}
+
+func (s *CancellationService) release(id string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    delete(s.running, id)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+var (
+    ErrCaptchaRequired = errors.New("captcha token is required")
+    ErrCaptchaFailed   = errors.New("captcha verification failed")
+)
+
+// CaptchaVerifier checks the CAPTCHA token a client solved
+type CaptchaVerifier interface {
+    Verify(ctx context.Context, token, clientIP string) error
+}
+
+// SiteVerifyCaptcha verifies tokens with a siteverify endpoint, the protocol
+// shared by hCaptcha, reCAPTCHA and Cloudflare Turnstile
+type SiteVerifyCaptcha struct {
+    verifyURL  string
+    secret     string
+    httpClient *http.Client
+}
+
+// NewCaptchaVerifier creates the configured verifier, or nil when CAPTCHAs
+// are disabled
+func NewCaptchaVerifier(cfg *config.Config) (CaptchaVerifier, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+    if !cfg.AbuseConfig.Captcha.Enabled {
+        return nil, nil
+    }
+
+    return &SiteVerifyCaptcha{
+        verifyURL:  cfg.AbuseConfig.Captcha.VerifyURL,
+        secret:     cfg.AbuseConfig.Captcha.Secret,
+        httpClient: &http.Client{Timeout: cfg.AbuseConfig.Captcha.Timeout},
+    }, nil
+}
+
+// Verify asks the provider whether the token was solved, bound to the client IP
+func (v *SiteVerifyCaptcha) Verify(ctx context.Context, token, clientIP string) error {
+    if token == "" {
+        captchaVerifications.WithLabelValues("missing").Inc()
+        return ErrCaptchaRequired
+    }
+
+    form := url.Values{
+        "secret":   {v.secret},
+        "response": {token},
+        "remoteip": {clientIP},
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+    if err != nil {
+        return fmt.Errorf("failed to build captcha request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+    resp, err := v.httpClient.Do(req)
+    if err != nil {
+        captchaVerifications.WithLabelValues("error").Inc()
+        return fmt.Errorf("captcha request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        captchaVerifications.WithLabelValues("error").Inc()
+        return fmt.Errorf("captcha provider returned status %d", resp.StatusCode)
+    }
+
+    var result struct {
+        Success    bool     `json:"success"`
+        ErrorCodes []string `json:"error-codes"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        captchaVerifications.WithLabelValues("error").Inc()
+        return fmt.Errorf("failed to decode captcha response: %w", err)
+    }
+    if !result.Success {
+        captchaVerifications.WithLabelValues("failed").Inc()
+        return fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(result.ErrorCodes, ", "))
+    }
+
+    captchaVerifications.WithLabelValues("passed").Inc()
+    return nil
+}
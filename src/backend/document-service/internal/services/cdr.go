@@ -0,0 +1,186 @@
+package services
+
+import (
+    "bytes"
+    "compress/zlib"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "slices"
+    "strconv"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// maxInflatedStreams bounds the bytes inflated from the streams of one PDF
+// while looking for active content, against compression bombs
+const maxInflatedStreams = 64 << 20
+
+// ErrDisarmFailed is returned for PDFs carrying active content that cannot
+// be rebuilt without it
+var ErrDisarmFailed = errors.New("PDF with active content could not be disarmed")
+
+// activeContentNames maps the PDF names introducing active content to the
+// kind of content they introduce
+var activeContentNames = map[string]string{
+    "JavaScript":     models.ActiveContentJavaScript,
+    "JS":             models.ActiveContentJavaScript,
+    "Launch":         models.ActiveContentLaunch,
+    "EmbeddedFile":   models.ActiveContentEmbeddedFile,
+    "EmbeddedFiles":  models.ActiveContentEmbeddedFile,
+    "FileAttachment": models.ActiveContentEmbeddedFile,
+    "OpenAction":     models.ActiveContentAutomaticAction,
+    "AA":             models.ActiveContentAutomaticAction,
+    "SubmitForm":     models.ActiveContentFormSubmission,
+    "ImportData":     models.ActiveContentFormSubmission,
+    "XFA":            models.ActiveContentXFA,
+    "RichMedia":      models.ActiveContentRichMedia,
+}
+
+// PDFDisarmer rebuilds PDFs carrying active content from the images of their
+// pages, which keeps what the pages show and nothing that could run: no
+// JavaScript, actions, embedded files or forms survive
+type PDFDisarmer struct {
+    cfg config.CDRConfig
+}
+
+// NewPDFDisarmer creates the disarmer, or returns nil when CDR is disabled
+func NewPDFDisarmer(cfg *config.Config) (*PDFDisarmer, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+    if !cfg.CDRConfig.Enabled {
+        return nil, nil
+    }
+    return &PDFDisarmer{cfg: cfg.CDRConfig}, nil
+}
+
+// Disarm returns the PDF rebuilt without active content with the kinds of
+// active content found, or nil content when the PDF carries none and is not
+// to be rebuilt anyway
+func (d *PDFDisarmer) Disarm(ctx context.Context, content []byte, title string) ([]byte, []string, error) {
+    findings := FindActiveContent(content)
+    for _, finding := range findings {
+        pdfActiveContent.WithLabelValues(finding).Inc()
+    }
+    if len(findings) == 0 && !d.cfg.AlwaysRebuild {
+        pdfDisarms.WithLabelValues("clean").Inc()
+        return nil, nil, nil
+    }
+
+    disarmed, err := d.rebuild(ctx, content, title)
+    if err != nil {
+        pdfDisarms.WithLabelValues("failed").Inc()
+        return nil, findings, err
+    }
+    pdfDisarms.WithLabelValues("disarmed").Inc()
+    return disarmed, findings, nil
+}
+
+// rebuild renders the pages of a PDF and writes them as a new image-only PDF
+func (d *PDFDisarmer) rebuild(ctx context.Context, content []byte, title string) ([]byte, error) {
+    count, err := countPDFPages(content)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrDisarmFailed, err)
+    }
+    if count > d.cfg.MaxPages {
+        return nil, fmt.Errorf("%w: %d pages, at most %d can be rebuilt", ErrDisarmFailed, count, d.cfg.MaxPages)
+    }
+    pages, err := renderPages(ctx, "application/pdf", content, d.cfg.DPI)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrDisarmFailed, err)
+    }
+    disarmed, err := BuildSearchablePDF(pages, d.cfg.DPI, d.cfg.Quality, nil, title)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrDisarmFailed, err)
+    }
+    return disarmed, nil
+}
+
+// FindActiveContent lists, in a stable order, the kinds of active content a
+// PDF carries. Names are read from the file and from its Flate-compressed
+// streams, where object streams hide them, with #xx escapes decoded
+func FindActiveContent(content []byte) []string {
+    found := make(map[string]bool)
+    scanPDFNames(content, found)
+
+    inflated := int64(0)
+    for rest := content; ; {
+        start := bytes.Index(rest, []byte("stream"))
+        if start < 0 {
+            break
+        }
+        rest = rest[start+len("stream"):]
+        // The keyword ends the stream dictionary only when a line break follows
+        body := bytes.TrimPrefix(bytes.TrimPrefix(rest, []byte("\r")), []byte("\n"))
+        if len(body) == len(rest) {
+            continue
+        }
+        end := bytes.Index(body, []byte("endstream"))
+        if end < 0 {
+            break
+        }
+        if inflated < maxInflatedStreams {
+            if reader, err := zlib.NewReader(bytes.NewReader(body[:end])); err == nil {
+                data, _ := io.ReadAll(io.LimitReader(reader, maxInflatedStreams-inflated))
+                inflated += int64(len(data))
+                scanPDFNames(data, found)
+            }
+        }
+        rest = body[end+len("endstream"):]
+    }
+
+    kinds := make([]string, 0, len(found))
+    for kind := range found {
+        kinds = append(kinds, kind)
+    }
+    slices.Sort(kinds)
+    return kinds
+}
+
+// scanPDFNames records the active content kinds of the PDF names in data
+func scanPDFNames(data []byte, found map[string]bool) {
+    for i := 0; i < len(data); i++ {
+        if data[i] != '/' {
+            continue
+        }
+        end := i + 1
+        for end < len(data) && !isPDFDelimiter(data[end]) {
+            end++
+        }
+        if kind, ok := activeContentNames[decodePDFName(data[i+1:end])]; ok {
+            found[kind] = true
+        }
+        i = end - 1
+    }
+}
+
+// decodePDFName decodes the #xx escapes of a PDF name
+func decodePDFName(name []byte) string {
+    if bytes.IndexByte(name, '#') < 0 {
+        return string(name)
+    }
+    decoded := make([]byte, 0, len(name))
+    for i := 0; i < len(name); i++ {
+        if name[i] == '#' && i+2 < len(name) {
+            if b, err := strconv.ParseUint(string(name[i+1:i+3]), 16, 8); err == nil {
+                decoded = append(decoded, byte(b))
+                i += 2
+                continue
+            }
+        }
+        decoded = append(decoded, name[i])
+    }
+    return string(decoded)
+}
+
+// isPDFDelimiter reports whether a byte ends a PDF name
+func isPDFDelimiter(b byte) bool {
+    switch b {
+    case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+        return true
+    }
+    return false
+}
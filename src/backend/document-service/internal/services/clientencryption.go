@@ -0,0 +1,113 @@
+package services
+
+import (
+    "errors"
+    "fmt"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrClientEncryptionDisabled   = errors.New("client-side encryption is not enabled")
+    ErrClientEncryptionNotAllowed = errors.New("document type cannot be uploaded end-to-end encrypted")
+    ErrClientEncryptedDownload    = errors.New("role may not download end-to-end encrypted documents")
+)
+
+// ClientEncryption accepts documents the client encrypted to a public key of
+// the underwriting team. The service cannot decrypt them, so an envelope is
+// only checked for its structure and a known recipient; the document skips
+// every processing step and is served as stored to the roles allowed to
+// download it
+type ClientEncryption struct {
+    enabled    bool
+    recipients []utils.RecipientKey
+    types      map[string]bool
+    roles      map[string]bool
+}
+
+// NewClientEncryption parses the configured recipient keys
+func NewClientEncryption(cfg *config.Config) (*ClientEncryption, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    settings := cfg.ClientEncryptionConfig
+    encryption := &ClientEncryption{
+        enabled: settings.Enabled,
+        types:   make(map[string]bool),
+        roles:   make(map[string]bool),
+    }
+    for i, data := range settings.RecipientKeys {
+        key, err := utils.ParseRecipientKey(data)
+        if err != nil {
+            return nil, fmt.Errorf("invalid client encryption recipient key %d: %w", i, err)
+        }
+        encryption.recipients = append(encryption.recipients, key)
+    }
+    if encryption.enabled && len(encryption.recipients) == 0 {
+        return nil, errors.New("client encryption requires at least one recipient key")
+    }
+    for _, documentType := range settings.DocumentTypes {
+        encryption.types[documentType] = true
+    }
+    for _, role := range settings.DownloadRoles {
+        encryption.roles[role] = true
+    }
+    return encryption, nil
+}
+
+// RecipientKey returns the key clients seal new uploads to
+func (e *ClientEncryption) RecipientKey() (utils.RecipientKey, error) {
+    if e == nil || !e.enabled {
+        return utils.RecipientKey{}, ErrClientEncryptionDisabled
+    }
+    return e.recipients[0], nil
+}
+
+// DocumentTypes returns the document types that may be uploaded end-to-end
+// encrypted
+func (e *ClientEncryption) DocumentTypes() []string {
+    if e == nil {
+        return nil
+    }
+    types := make([]string, 0, len(e.types))
+    for documentType := range e.types {
+        types = append(types, documentType)
+    }
+    return types
+}
+
+// Accept validates an envelope uploaded as a document of the given type and
+// returns the client encryption to record on the document
+func (e *ClientEncryption) Accept(documentType string, envelope []byte) (*models.ClientEncryption, error) {
+    if e == nil || !e.enabled {
+        return nil, ErrClientEncryptionDisabled
+    }
+    if !e.types[documentType] {
+        clientEncryptedUploads.WithLabelValues("rejected").Inc()
+        return nil, fmt.Errorf("%w: %s", ErrClientEncryptionNotAllowed, documentType)
+    }
+
+    recipient, err := utils.ValidateEnvelope(envelope, e.recipients)
+    if err != nil {
+        clientEncryptedUploads.WithLabelValues("rejected").Inc()
+        return nil, err
+    }
+    clientEncryptedUploads.WithLabelValues("accepted").Inc()
+    return &models.ClientEncryption{
+        Algorithm:      models.ClientEncryptionAlgorithm,
+        RecipientKeyID: recipient.ID,
+    }, nil
+}
+
+// MayDownload reports whether the role may download end-to-end encrypted
+// documents. Downloads stay available when uploads are disabled, so envelopes
+// already stored can still be retrieved
+func (e *ClientEncryption) MayDownload(role string) bool {
+    if e == nil {
+        return false
+    }
+    return len(e.roles) == 0 || e.roles[role]
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Consent event types published by the consent service
+const (
+    ConsentEventRevoked = "consent.revoked"
+    ConsentEventGranted = "consent.granted"
+)
+
+var (
+    ErrConsentRevoked      = errors.New("subject consent revoked")
+    ErrUnknownConsentEvent = errors.New("unknown consent event type")
+    ErrNotHaltedForConsent = errors.New("document processing was not halted for consent")
+)
+
+// ConsentEvent is a consent change published by the consent service. The
+// subject is identified by CPF, enrollment IDs or both
+type ConsentEvent struct {
+    EventID       string    `json:"event_id"`
+    Type          string    `json:"type"`
+    SubjectCPF    string    `json:"subject_cpf"`
+    EnrollmentIDs []string  `json:"enrollment_ids"`
+    OccurredAt    time.Time `json:"occurred_at"`
+}
+
+// consentState is the latest consent event applied to an enrollment
+type consentState struct {
+    revoked bool
+    at      time.Time
+}
+
+// ConsentRegistry tracks enrollments whose subject revoked consent and the
+// pipeline runs in flight for each enrollment, so a revocation cancels them.
+// Like the in-memory document repository it is local to the instance
+type ConsentRegistry struct {
+    mu       sync.Mutex
+    states   map[string]consentState
+    inflight map[string]map[string]context.CancelCauseFunc
+}
+
+// NewConsentRegistry creates an empty registry
+func NewConsentRegistry() *ConsentRegistry {
+    return &ConsentRegistry{
+        states:   make(map[string]consentState),
+        inflight: make(map[string]map[string]context.CancelCauseFunc),
+    }
+}
+
+// Revoked reports whether consent is currently revoked for the enrollment. A
+// nil registry never reports revocations
+func (r *ConsentRegistry) Revoked(enrollmentID string) bool {
+    if r == nil {
+        return false
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.states[enrollmentID].revoked
+}
+
+// Track derives the context a document is processed under; it is cancelled
+// with ErrConsentRevoked when consent for the enrollment is revoked. The
+// returned func must be called once processing finishes
+func (r *ConsentRegistry) Track(ctx context.Context, doc *models.Document) (context.Context, func()) {
+    if r == nil {
+        return ctx, func() {}
+    }
+
+    runCtx, cancel := context.WithCancelCause(ctx)
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if r.states[doc.EnrollmentID].revoked {
+        cancel(ErrConsentRevoked)
+        return runCtx, func() {}
+    }
+    runs, ok := r.inflight[doc.EnrollmentID]
+    if !ok {
+        runs = make(map[string]context.CancelCauseFunc)
+        r.inflight[doc.EnrollmentID] = runs
+    }
+    runs[doc.ID] = cancel
+
+    return runCtx, func() {
+        r.mu.Lock()
+        defer r.mu.Unlock()
+        delete(r.inflight[doc.EnrollmentID], doc.ID)
+        if len(r.inflight[doc.EnrollmentID]) == 0 {
+            delete(r.inflight, doc.EnrollmentID)
+        }
+        cancel(nil)
+    }
+}
+
+// apply records a consent change unless a later event was already applied,
+// and cancels the runs in flight on revocation. It returns the number of runs
+// cancelled
+func (r *ConsentRegistry) apply(enrollmentID string, revoked bool, at time.Time) int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    if current, ok := r.states[enrollmentID]; ok && at.Before(current.at) {
+        return 0
+    }
+    r.states[enrollmentID] = consentState{revoked: revoked, at: at}
+    if !revoked {
+        return 0
+    }
+
+    cancelled := 0
+    for _, cancel := range r.inflight[enrollmentID] {
+        cancel(ErrConsentRevoked)
+        cancelled++
+    }
+    return cancelled
+}
+
+// HaltedForConsent reports whether a processing context was cancelled by a
+// consent revocation
+func HaltedForConsent(ctx context.Context) bool {
+    return errors.Is(context.Cause(ctx), ErrConsentRevoked)
+}
+
+// ConsentService applies consent events: a revocation cancels in-flight OCR
+// and classification of the subject's documents and halts documents not yet
+// processed; a new grant allows them to be reprocessed
+type ConsentService struct {
+    registry    *ConsentRegistry
+    documents   repository.DocumentRepository
+    enrollments *EnrollmentClient
+    logger      *zap.Logger
+}
+
+// NewConsentService creates a new consent service
+func NewConsentService(registry *ConsentRegistry, documents repository.DocumentRepository, enrollments *EnrollmentClient, logger *zap.Logger) (*ConsentService, error) {
+    if registry == nil || documents == nil || enrollments == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ConsentService{
+        registry:    registry,
+        documents:   documents,
+        enrollments: enrollments,
+        logger:      logger,
+    }, nil
+}
+
+// Handle applies a consent event to every enrollment of its subject
+func (s *ConsentService) Handle(ctx context.Context, event ConsentEvent) error {
+    if event.Type != ConsentEventRevoked && event.Type != ConsentEventGranted {
+        return fmt.Errorf("%w: %s", ErrUnknownConsentEvent, event.Type)
+    }
+    if event.OccurredAt.IsZero() {
+        event.OccurredAt = time.Now()
+    }
+
+    enrollmentIDs, err := s.resolveEnrollments(ctx, event)
+    if err != nil {
+        return err
+    }
+
+    consentEvents.WithLabelValues(event.Type).Inc()
+    revoked := event.Type == ConsentEventRevoked
+    for _, enrollmentID := range enrollmentIDs {
+        cancelled := s.registry.apply(enrollmentID, revoked, event.OccurredAt)
+
+        halted := 0
+        if revoked {
+            if halted, err = s.haltQueued(ctx, enrollmentID); err != nil {
+                return err
+            }
+        }
+
+        s.logger.Info("Consent event applied",
+            zap.String("event_id", event.EventID),
+            zap.String("type", event.Type),
+            zap.String("enrollment_id", enrollmentID),
+            zap.Int("runs_cancelled", cancelled),
+            zap.Int("documents_halted", halted),
+        )
+    }
+    return nil
+}
+
+// resolveEnrollments collects the enrollments named in the event and, when a
+// CPF is given, every enrollment of that beneficiary
+func (s *ConsentService) resolveEnrollments(ctx context.Context, event ConsentEvent) ([]string, error) {
+    enrollmentIDs := make([]string, 0, len(event.EnrollmentIDs))
+    for _, id := range event.EnrollmentIDs {
+        enrollmentIDs = appendUnique(enrollmentIDs, id)
+    }
+
+    if event.SubjectCPF != "" {
+        enrollments, err := s.enrollments.ListBeneficiaryEnrollments(ctx, digitsOnly(event.SubjectCPF))
+        if err != nil && !errors.Is(err, ErrEnrollmentNotFound) {
+            return nil, fmt.Errorf("failed to resolve subject enrollments: %w", err)
+        }
+        for _, enrollment := range enrollments {
+            enrollmentIDs = appendUnique(enrollmentIDs, enrollment.ID)
+        }
+    }
+    return enrollmentIDs, nil
+}
+
+// haltQueued marks the enrollment's documents that are stored but not yet
+// processed as halted, so they are not picked up again without consent
+func (s *ConsentService) haltQueued(ctx context.Context, enrollmentID string) (int, error) {
+    docs, err := s.documents.ListByEnrollment(ctx, enrollmentID)
+    if err != nil {
+        return 0, fmt.Errorf("failed to list documents of enrollment %s: %w", enrollmentID, err)
+    }
+
+    halted := 0
+    for _, doc := range docs {
+        if doc.Status != models.DocumentStatusPending && doc.Status != models.DocumentStatusProcessing && doc.Status != models.DocumentStatusOCRDeferred {
+            continue
+        }
+        if err := doc.UpdateStatus(models.DocumentStatusHaltedConsent, "Subject consent revoked"); err != nil {
+            return halted, err
+        }
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return halted, fmt.Errorf("failed to halt document %s: %w", doc.ID, err)
+        }
+        halted++
+    }
+    return halted, nil
+}
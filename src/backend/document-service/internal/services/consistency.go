@@ -0,0 +1,96 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// ConsistentReads lets a client read what it just wrote. Every write returns
+// the document's version; a read asking for that version is served by the
+// repository once it has caught up, by the write-through cache until then,
+// and waits a little for the version when neither has it yet, as when the
+// write is still on its way from another instance
+type ConsistentReads struct {
+    cfg       config.ConsistencyConfig
+    documents *repository.WriteThroughDocumentRepository
+    logger    *zap.Logger
+}
+
+// NewConsistentReads creates the read-your-writes reader over the
+// write-through repository every write goes through
+func NewConsistentReads(cfg *config.Config, documents *repository.WriteThroughDocumentRepository, logger *zap.Logger) (*ConsistentReads, error) {
+    if cfg == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ConsistentReads{
+        cfg:       cfg.ConsistencyConfig,
+        documents: documents,
+        logger:    logger.With(zap.String("component", "consistent_reads")),
+    }, nil
+}
+
+// Get returns the document at version or later. It fails with
+// repository.ErrVersionUnavailable when the version did not appear within
+// the wait timeout, and with repository.ErrDocumentNotFound when the
+// document is not known anywhere by then
+func (r *ConsistentReads) Get(ctx context.Context, id string, version int64) (*models.Document, error) {
+    timeout := time.NewTimer(r.cfg.WaitTimeout)
+    defer timeout.Stop()
+    ticker := time.NewTicker(r.cfg.PollInterval)
+    defer ticker.Stop()
+
+    for {
+        doc, cached, err := r.documents.GetVersion(ctx, id, version)
+        switch {
+        case err == nil && cached:
+            consistentReads.WithLabelValues("cache").Inc()
+            return doc, nil
+        case err == nil:
+            consistentReads.WithLabelValues("repository").Inc()
+            return doc, nil
+        case !errors.Is(err, repository.ErrVersionUnavailable) && !errors.Is(err, repository.ErrDocumentNotFound):
+            consistentReads.WithLabelValues("error").Inc()
+            return nil, err
+        }
+
+        select {
+        case <-ctx.Done():
+            return nil, ctx.Err()
+        case <-timeout.C:
+            consistentReads.WithLabelValues("unavailable").Inc()
+            r.logger.Warn("Document version not available",
+                zap.String("document_id", id),
+                zap.Int64("version", version),
+                zap.Error(err),
+            )
+            return nil, err
+        case <-ticker.C:
+        }
+    }
+}
+
+// Run drops expired documents from the write-through cache until the
+// context is cancelled
+func (r *ConsistentReads) Run(ctx context.Context) {
+    ticker := time.NewTicker(r.cfg.CacheTTL)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if _, err := r.documents.DeleteExpired(ctx, time.Now()); err != nil {
+                r.logger.Error("Failed to drop expired cached documents", zap.Error(err))
+            }
+        }
+    }
+}
@@ -0,0 +1,407 @@
+package services
+
+import (
+    "archive/zip"
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "strings"
+    "time"
+    "unicode/utf16"
+
+    "github.com/pdfcpu/pdfcpu/pkg/api" // v0.6.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Conversion failure reasons, reported to submitters so they can fix the file
+const (
+    ConversionReasonPasswordProtected = "password_protected"
+    ConversionReasonLegacyFormat      = "legacy_format"
+    ConversionReasonCorrupt           = "corrupt"
+    ConversionReasonUnsupported       = "unsupported"
+    ConversionReasonTooManyPages      = "too_many_pages"
+    ConversionReasonUnavailable       = "converter_unavailable"
+)
+
+// sidecarConvertPath is the LibreOffice route of the Gotenberg API
+const sidecarConvertPath = "/forms/libreoffice/convert"
+
+var (
+    // ErrConversionFailed is returned when an upload a channel converts to
+    // PDF cannot be converted because of the file itself
+    ErrConversionFailed = errors.New("document could not be converted to PDF")
+    // ErrConverterUnavailable is returned when the document converter could
+    // not be reached; the same upload may succeed later
+    ErrConverterUnavailable = errors.New("document converter is unavailable")
+)
+
+// oleSignature starts legacy Office files and password protected OOXML
+// files, which are wrapped in an OLE container
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// conversionActions tells submitters how to fix a file that failed for each
+// reason
+var conversionActions = map[string]string{
+    ConversionReasonPasswordProtected: "The document is password protected; remove the password and upload it again",
+    ConversionReasonLegacyFormat:      "The document is in an old Word format; save it as .docx or PDF and upload it again",
+    ConversionReasonCorrupt:           "The document could not be opened; export it to PDF and upload it again",
+    ConversionReasonUnsupported:       "The document type cannot be converted; upload it as PDF",
+    ConversionReasonTooManyPages:      "The document has too many pages; split it into smaller documents and upload them separately",
+    ConversionReasonUnavailable:       "The document could not be converted right now; try again in a few minutes",
+}
+
+// ConversionError is a failed conversion with the reason and what the
+// submitter can do about it. It matches ErrConverterUnavailable when the
+// converter could not be reached and ErrConversionFailed otherwise
+type ConversionError struct {
+    Reason string
+    // Action tells the submitter how to fix the file
+    Action string
+    cause  error
+}
+
+func conversionError(reason string, cause error) *ConversionError {
+    return &ConversionError{Reason: reason, Action: conversionActions[reason], cause: cause}
+}
+
+func (e *ConversionError) Error() string {
+    msg := fmt.Sprintf("%s (%s)", e.Unwrap(), e.Reason)
+    if e.cause != nil {
+        msg += ": " + e.cause.Error()
+    }
+    return msg
+}
+
+func (e *ConversionError) Unwrap() error {
+    if e.Reason == ConversionReasonUnavailable {
+        return ErrConverterUnavailable
+    }
+    return ErrConversionFailed
+}
+
+// DocumentConverter converts office documents to PDF
+type DocumentConverter interface {
+    Convert(ctx context.Context, contentType string, content []byte) ([]byte, error)
+}
+
+// SidecarConverter converts office documents with a LibreOffice headless
+// sidecar through the Gotenberg API
+type SidecarConverter struct {
+    baseURL    string
+    slots      chan struct{}
+    timeout    time.Duration
+    httpClient *http.Client
+}
+
+// NewSidecarConverter creates the sidecar client, or returns nil when the
+// converter is disabled
+func NewSidecarConverter(cfg *config.Config) (*SidecarConverter, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+    if !cfg.ConverterConfig.Enabled {
+        return nil, nil
+    }
+
+    return &SidecarConverter{
+        baseURL: strings.TrimSuffix(cfg.ConverterConfig.BaseURL, "/"),
+        slots:   make(chan struct{}, cfg.ConverterConfig.MaxConcurrent),
+        timeout: cfg.ConverterConfig.Timeout,
+        httpClient: &http.Client{
+            Transport: NewHTTPTransport("converter", cfg.ConverterConfig.Transport),
+        },
+    }, nil
+}
+
+// Ping checks the sidecar is up
+func (c *SidecarConverter) Ping(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/health", nil)
+    if err != nil {
+        return fmt.Errorf("failed to build converter health request: %w", err)
+    }
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("%w: %v", ErrConverterUnavailable, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("%w: health check returned status %d", ErrConverterUnavailable, resp.StatusCode)
+    }
+    return nil
+}
+
+// Convert sends a document to the sidecar, waiting for a free slot first.
+// The sidecar rejecting the file means it could not be opened
+func (c *SidecarConverter) Convert(ctx context.Context, contentType string, content []byte) ([]byte, error) {
+    select {
+    case c.slots <- struct{}{}:
+        defer func() { <-c.slots }()
+    case <-ctx.Done():
+        return nil, conversionError(ConversionReasonUnavailable, ctx.Err())
+    }
+
+    // The sidecar picks the input format from the file extension
+    contentTypeEntry, _ := models.LookupContentType(contentType)
+    filename := "document"
+    if len(contentTypeEntry.Extensions) > 0 {
+        filename += "." + contentTypeEntry.Extensions[0]
+    }
+
+    var body bytes.Buffer
+    form := multipart.NewWriter(&body)
+    part, err := form.CreateFormFile("files", filename)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build conversion request: %w", err)
+    }
+    part.Write(content)
+    if err := form.Close(); err != nil {
+        return nil, fmt.Errorf("failed to build conversion request: %w", err)
+    }
+
+    // The timeout starts once a slot is free
+    var converted []byte
+    err = utils.CallWithDeadline(ctx, config.DependencyConverter, c.timeout, func(ctx context.Context) error {
+        var err error
+        converted, err = c.send(ctx, form.FormDataContentType(), &body)
+        return err
+    })
+    return converted, err
+}
+
+// send posts a multipart conversion request to the sidecar
+func (c *SidecarConverter) send(ctx context.Context, contentType string, body *bytes.Buffer) ([]byte, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+sidecarConvertPath, body)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build conversion request: %w", err)
+    }
+    req.Header.Set("Content-Type", contentType)
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, conversionError(ConversionReasonUnavailable, err)
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity:
+        detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        return nil, conversionError(ConversionReasonCorrupt, fmt.Errorf("converter rejected the document: %s", strings.TrimSpace(string(detail))))
+    case resp.StatusCode != http.StatusOK:
+        return nil, conversionError(ConversionReasonUnavailable, fmt.Errorf("converter returned status %d", resp.StatusCode))
+    }
+
+    converted, err := io.ReadAll(io.LimitReader(resp.Body, models.MaxDocumentSize+1))
+    if err != nil {
+        return nil, conversionError(ConversionReasonUnavailable, err)
+    }
+    if int64(len(converted)) > models.MaxDocumentSize {
+        return nil, models.ErrInvalidSize
+    }
+    if !bytes.HasPrefix(converted, []byte("%PDF-")) {
+        return nil, conversionError(ConversionReasonUnavailable, errors.New("converter did not return a PDF"))
+    }
+    return converted, nil
+}
+
+// convertToPDF converts the content of a PDF-convertible type to a PDF of
+// at most maxPages pages and records the outcome. Images become one page per
+// image or TIFF page, sized to the image, and office documents go through
+// the document converter, which may be nil
+func convertToPDF(ctx context.Context, converter DocumentConverter, contentType string, content []byte, maxPages int) ([]byte, error) {
+    startTime := time.Now()
+    converted, err := convert(ctx, converter, contentType, content, maxPages)
+    observeConversion(contentType, startTime, err)
+    return converted, err
+}
+
+// composePDF makes one PDF of images, their pages in order, and records the
+// outcome
+func composePDF(images []imagePart, maxPages int) ([]byte, error) {
+    startTime := time.Now()
+    composed, err := imagesToPDF(images, maxPages)
+    observeConversion(contentTypeComposed, startTime, err)
+    return composed, err
+}
+
+// contentTypeComposed labels the metrics of documents composed from images
+const contentTypeComposed = "composed"
+
+func observeConversion(contentType string, startTime time.Time, err error) {
+    result := "converted"
+    var conversionErr *ConversionError
+    if errors.As(err, &conversionErr) {
+        result = conversionErr.Reason
+    } else if err != nil {
+        result = "error"
+    }
+    documentConversions.WithLabelValues(contentType, result).Inc()
+    conversionDuration.WithLabelValues(contentType).Observe(time.Since(startTime).Seconds())
+}
+
+func convert(ctx context.Context, converter DocumentConverter, contentType string, content []byte, maxPages int) ([]byte, error) {
+    entry, _ := models.LookupContentType(contentType)
+    switch {
+    case !entry.PDFConvertible:
+        return nil, conversionError(ConversionReasonUnsupported, nil)
+    case entry.NeedsConverter:
+        if converter == nil {
+            return nil, conversionError(ConversionReasonUnsupported, errors.New("document converter is not configured"))
+        }
+        // Files the converter cannot open are caught here with a precise reason
+        if err := inspectOfficeDocument(contentType, content); err != nil {
+            return nil, err
+        }
+        return converter.Convert(ctx, contentType, content)
+    default:
+        return imagesToPDF([]imagePart{{contentType: contentType, content: content}}, maxPages)
+    }
+}
+
+// imagePart is an image to be made into PDF pages
+type imagePart struct {
+    contentType string
+    content     []byte
+}
+
+// imagesToPDF makes a PDF with a page per image, or per page of a TIFF
+func imagesToPDF(images []imagePart, maxPages int) ([]byte, error) {
+    pages := make([]io.Reader, 0, len(images))
+    for _, image := range images {
+        if image.contentType != models.MimeTypeTIFF {
+            pages = append(pages, bytes.NewReader(image.content))
+            continue
+        }
+        tiffPages, err := splitTIFFPages(image.content)
+        if err != nil {
+            return nil, err
+        }
+        pages = append(pages, tiffPages...)
+    }
+    if len(pages) > maxPages {
+        return nil, conversionError(ConversionReasonTooManyPages, fmt.Errorf("%d pages, at most %d allowed", len(pages), maxPages))
+    }
+
+    var converted bytes.Buffer
+    if err := api.ImportImages(nil, &converted, pages, nil, nil); err != nil {
+        return nil, conversionError(ConversionReasonCorrupt, err)
+    }
+    return converted.Bytes(), nil
+}
+
+// splitTIFFPages returns a reader per page of a TIFF, which image decoders
+// read only the first page of. Offsets in a TIFF are absolute, so each page
+// is the whole file with the header pointing at the directory of the page.
+// BigTIFF files are read as a single page
+func splitTIFFPages(content []byte) ([]io.Reader, error) {
+    if len(content) < 8 {
+        return nil, conversionError(ConversionReasonCorrupt, errors.New("TIFF header is truncated"))
+    }
+    var order binary.ByteOrder
+    switch string(content[:2]) {
+    case "II":
+        order = binary.LittleEndian
+    case "MM":
+        order = binary.BigEndian
+    default:
+        return nil, conversionError(ConversionReasonCorrupt, errors.New("not a TIFF file"))
+    }
+    if order.Uint16(content[2:4]) != 42 {
+        return []io.Reader{bytes.NewReader(content)}, nil
+    }
+
+    var pages []io.Reader
+    seen := make(map[uint32]bool)
+    for offset := order.Uint32(content[4:8]); offset != 0; {
+        if seen[offset] || int64(offset)+2 > int64(len(content)) {
+            return nil, conversionError(ConversionReasonCorrupt, errors.New("TIFF page directory is invalid"))
+        }
+        seen[offset] = true
+
+        header := make([]byte, 4)
+        order.PutUint32(header, offset)
+        pages = append(pages, io.MultiReader(bytes.NewReader(content[:4]), bytes.NewReader(header), bytes.NewReader(content[8:])))
+
+        // A directory is a count of 12-byte entries followed by the offset of
+        // the next directory
+        next := int64(offset) + 2 + int64(order.Uint16(content[offset:]))*12
+        if next+4 > int64(len(content)) {
+            return nil, conversionError(ConversionReasonCorrupt, errors.New("TIFF page directory is truncated"))
+        }
+        offset = order.Uint32(content[next:])
+    }
+    return pages, nil
+}
+
+// inspectOfficeDocument checks that an office document is a readable,
+// unencrypted file of its declared type
+func inspectOfficeDocument(contentType string, content []byte) error {
+    if bytes.HasPrefix(content, oleSignature) {
+        // Encrypted OOXML keeps its encryption parameters in an OLE stream
+        if bytes.Contains(content, utf16LE("EncryptionInfo")) {
+            return conversionError(ConversionReasonPasswordProtected, nil)
+        }
+        return conversionError(ConversionReasonLegacyFormat, nil)
+    }
+
+    archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+    if err != nil {
+        return conversionError(ConversionReasonCorrupt, err)
+    }
+    switch contentType {
+    case models.MimeTypeDOCX:
+        if !zipHasFile(archive, "word/document.xml") {
+            return conversionError(ConversionReasonCorrupt, errors.New("document body is missing"))
+        }
+    case models.MimeTypeODT:
+        // Encrypted ODF files list the encryption of each entry in the manifest
+        manifest, err := readZipFile(archive, "META-INF/manifest.xml")
+        if err != nil {
+            return conversionError(ConversionReasonCorrupt, err)
+        }
+        if bytes.Contains(manifest, []byte("encryption-data")) {
+            return conversionError(ConversionReasonPasswordProtected, nil)
+        }
+    }
+    return nil
+}
+
+func zipHasFile(archive *zip.Reader, name string) bool {
+    for _, file := range archive.File {
+        if file.Name == name {
+            return true
+        }
+    }
+    return false
+}
+
+// readZipFile reads a small archive entry such as a manifest
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+    f, err := archive.Open(name)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+    return io.ReadAll(io.LimitReader(f, 1024*1024))
+}
+
+// utf16LE encodes a string as the UTF-16 of OLE stream names
+func utf16LE(s string) []byte {
+    units := utf16.Encode([]rune(s))
+    encoded := make([]byte, 0, len(units)*2)
+    for _, unit := range units {
+        encoded = append(encoded, byte(unit), byte(unit>>8))
+    }
+    return encoded
+}
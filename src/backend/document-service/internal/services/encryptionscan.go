@@ -0,0 +1,270 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math/rand"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrNotReencryptable = errors.New("stored content cannot be recovered for re-encryption")
+)
+
+// EncryptionScanner periodically samples stored documents and checks that
+// every object holding personal data is encrypted consistently with its
+// metadata: the ciphertext has the size the algorithm implies and
+// authenticates, and the key version and rotation date are current
+type EncryptionScanner struct {
+    cfg       *config.Config
+    documents repository.DocumentRepository
+    storage   *StorageService
+    logger    *zap.Logger
+
+    mu   sync.Mutex
+    last *models.EncryptionScanReport
+}
+
+// NewEncryptionScanner creates a new encryption-at-rest scanner
+func NewEncryptionScanner(cfg *config.Config, documents repository.DocumentRepository, storage *StorageService, logger *zap.Logger) (*EncryptionScanner, error) {
+    if cfg == nil || documents == nil || storage == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &EncryptionScanner{
+        cfg:       cfg,
+        documents: documents,
+        storage:   storage,
+        logger:    logger,
+    }, nil
+}
+
+// Run scans a sample on the configured interval until the context is cancelled
+func (s *EncryptionScanner) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.cfg.EncryptionScanConfig.Interval)
+    defer ticker.Stop()
+
+    for {
+        if _, err := s.Scan(ctx); err != nil {
+            s.logger.Error("Encryption scan failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// LastReport returns the report of the latest completed scan, or nil
+func (s *EncryptionScanner) LastReport() *models.EncryptionScanReport {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.last
+}
+
+// Scan checks the objects of a random sample of stored documents. With
+// reencrypt enabled, documents whose findings are all recoverable are
+// re-encrypted straight away
+func (s *EncryptionScanner) Scan(ctx context.Context) (*models.EncryptionScanReport, error) {
+    report := &models.EncryptionScanReport{
+        StartedAt: time.Now(),
+        Findings:  make([]models.EncryptionFinding, 0),
+    }
+
+    docs, err := s.documents.ListUpdatedBetween(ctx, time.Time{}, report.StartedAt.Add(time.Second))
+    if err != nil {
+        return nil, fmt.Errorf("failed to list documents: %w", err)
+    }
+    stored := make([]*models.Document, 0, len(docs))
+    for _, doc := range docs {
+        if doc.StoragePath != "" && !doc.EncryptionInfo.Shredded() {
+            stored = append(stored, doc)
+        }
+    }
+    rand.Shuffle(len(stored), func(i, j int) {
+        stored[i], stored[j] = stored[j], stored[i]
+    })
+    if len(stored) > s.cfg.EncryptionScanConfig.SampleSize {
+        stored = stored[:s.cfg.EncryptionScanConfig.SampleSize]
+    }
+
+    for _, doc := range stored {
+        findings, objects, err := s.inspectDocument(ctx, doc)
+        if err != nil {
+            return nil, err
+        }
+        report.Documents++
+        report.Objects += objects
+        report.Conforming += objects - distinctObjects(findings)
+        report.Findings = append(report.Findings, findings...)
+
+        if len(findings) == 0 || !s.cfg.EncryptionScanConfig.Reencrypt || !allReencryptable(findings) {
+            continue
+        }
+        if err := CheckJobLease(ctx); err != nil {
+            return nil, err
+        }
+        if _, err := s.Reencrypt(ctx, doc.ID); err != nil {
+            s.logger.Warn("Automatic re-encryption failed",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+            continue
+        }
+        report.Reencrypted++
+    }
+    report.FinishedAt = time.Now()
+
+    s.mu.Lock()
+    s.last = report
+    s.mu.Unlock()
+
+    s.logger.Info("Encryption scan completed",
+        zap.Int("documents", report.Documents),
+        zap.Int("objects", report.Objects),
+        zap.Int("conforming", report.Conforming),
+        zap.Int("findings", len(report.Findings)),
+        zap.Int("reencrypted", report.Reencrypted),
+    )
+    return report, nil
+}
+
+// Reencrypt re-encrypts the stored content of a document under the current key
+func (s *EncryptionScanner) Reencrypt(ctx context.Context, documentID string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := s.storage.ReencryptDocument(ctx, doc, s.documents.Update); err != nil {
+        reencryptions.WithLabelValues("failed").Inc()
+        return nil, err
+    }
+
+    reencryptions.WithLabelValues("succeeded").Inc()
+    s.logger.Info("Document re-encrypted",
+        zap.String("document_id", doc.ID),
+        zap.String("key_version", doc.EncryptionInfo.KeyVersion),
+    )
+    return doc, nil
+}
+
+// inspectDocument checks the original and the renditions that hold personal
+// data, returning the findings and the number of objects checked
+func (s *EncryptionScanner) inspectDocument(ctx context.Context, doc *models.Document) ([]models.EncryptionFinding, int, error) {
+    findings, err := s.inspectObject(ctx, doc, models.EncryptedObjectOriginal, doc.StoragePath, doc.Size, doc.EncryptionInfo)
+    if err != nil {
+        return nil, 0, err
+    }
+    objects := 1
+
+    for _, rendition := range doc.Renditions {
+        if rendition.Encryption == nil && !models.IsTextRendition(rendition.Name) {
+            continue
+        }
+        renditionFindings, err := s.inspectObject(ctx, doc, rendition.Name, rendition.StoragePath, rendition.Size, rendition.Encryption)
+        if err != nil {
+            return nil, 0, err
+        }
+        findings = append(findings, renditionFindings...)
+        objects++
+    }
+    return findings, objects, nil
+}
+
+// inspectObject checks one stored object against its metadata. Storage and key
+// management failures abort the scan rather than being reported as findings
+func (s *EncryptionScanner) inspectObject(ctx context.Context, doc *models.Document, object, key string, size int64, metadata *models.EncryptionMetadata) ([]models.EncryptionFinding, error) {
+    finding := func(issue, detail string, reencryptable bool) []models.EncryptionFinding {
+        encryptionScanObjects.WithLabelValues(issue).Inc()
+        return []models.EncryptionFinding{{
+            DocumentID:    doc.ID,
+            Object:        object,
+            StoragePath:   key,
+            Issue:         issue,
+            Detail:        detail,
+            Reencryptable: reencryptable,
+        }}
+    }
+
+    raw, err := s.storage.readObject(ctx, key)
+    if errors.Is(err, ErrObjectNotFound) {
+        return finding(models.EncryptionIssueMissingObject, "", false), nil
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read object %s: %w", key, err)
+    }
+
+    if metadata == nil {
+        if storedInClear(raw, size) {
+            return finding(models.EncryptionIssuePlaintext, "no encryption metadata", true), nil
+        }
+        return finding(models.EncryptionIssueMissingMetadata, "", false), nil
+    }
+
+    plaintext, err := utils.OpenCiphertext(ctx, doc.ID, raw, size, metadata, s.cfg)
+    switch {
+    case err == nil:
+        utils.PutBuffer(plaintext)
+    case storedInClear(raw, size):
+        return finding(models.EncryptionIssuePlaintext, "object size equals plaintext size", true), nil
+    case errors.Is(err, utils.ErrInvalidMetadata):
+        return finding(models.EncryptionIssueInvalidMetadata, err.Error(), false), nil
+    case errors.Is(err, utils.ErrCiphertextStructure):
+        return finding(models.EncryptionIssueSizeMismatch, err.Error(), false), nil
+    case errors.Is(err, utils.ErrDecryptionFailed):
+        return finding(models.EncryptionIssueAuthenticationFailed, err.Error(), false), nil
+    default:
+        return nil, fmt.Errorf("failed to verify object %s: %w", key, err)
+    }
+
+    var findings []models.EncryptionFinding
+    if current := s.cfg.SecurityConfig.KeyVersion; current != "" && metadata.KeyVersion != current {
+        findings = append(findings, finding(models.EncryptionIssueStaleKeyVersion,
+            fmt.Sprintf("key version %s, current %s", metadata.KeyVersion, current), true)...)
+    }
+    if metadata.KeyRotationDue.Before(time.Now()) {
+        findings = append(findings, finding(models.EncryptionIssueRotationOverdue,
+            "rotation was due "+metadata.KeyRotationDue.Format(time.RFC3339), true)...)
+    }
+    if len(findings) == 0 {
+        encryptionScanObjects.WithLabelValues("conforming").Inc()
+    }
+    return findings, nil
+}
+
+// storedInClear reports whether an object holds its plaintext. Every algorithm
+// appends authentication tags, so ciphertext is always larger than the content
+// it seals and an object of exactly the plaintext size was never encrypted
+func storedInClear(raw []byte, size int64) bool {
+    return int64(len(raw)) == size
+}
+
+// distinctObjects counts the objects named in findings
+func distinctObjects(findings []models.EncryptionFinding) int {
+    objects := make(map[string]bool, len(findings))
+    for _, finding := range findings {
+        objects[finding.StoragePath] = true
+    }
+    return len(objects)
+}
+
+func allReencryptable(findings []models.EncryptionFinding) bool {
+    for _, finding := range findings {
+        if !finding.Reencryptable {
+            return false
+        }
+    }
+    return true
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+    ErrEnrollmentNotFound = errors.New("enrollment not found")
+)
+
+// EnrollmentClient retrieves enrollment data from the enrollment service
+type EnrollmentClient struct {
+    baseURL    string
+    httpClient *http.Client
+}
+
+// NewEnrollmentClient creates a new enrollment service client
+func NewEnrollmentClient(cfg *config.Config) (*EnrollmentClient, error) {
+    if cfg == nil {
+        return nil, fmt.Errorf("config cannot be nil")
+    }
+
+    return &EnrollmentClient{
+        baseURL:    cfg.EnrollmentConfig.BaseURL,
+        httpClient: &http.Client{
+            Timeout:   cfg.EnrollmentConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+    }, nil
+}
+
+// GetEnrollment fetches an enrollment by ID
+func (c *EnrollmentClient) GetEnrollment(ctx context.Context, enrollmentID string) (*models.Enrollment, error) {
+    endpoint := fmt.Sprintf("%s/api/v1/enrollments/%s", c.baseURL, url.PathEscape(enrollmentID))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build enrollment request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("enrollment request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, ErrEnrollmentNotFound
+    case resp.StatusCode != http.StatusOK:
+        return nil, fmt.Errorf("enrollment service returned status %d", resp.StatusCode)
+    }
+
+    var enrollment models.Enrollment
+    if err := json.NewDecoder(resp.Body).Decode(&enrollment); err != nil {
+        return nil, fmt.Errorf("failed to decode enrollment: %w", err)
+    }
+
+    return &enrollment, nil
+}
+
+// ListEmployerEnrollments fetches the enrollment roster of a corporate employer
+func (c *EnrollmentClient) ListEmployerEnrollments(ctx context.Context, employerID string) ([]models.Enrollment, error) {
+    endpoint := fmt.Sprintf("%s/api/v1/employers/%s/enrollments", c.baseURL, url.PathEscape(employerID))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build roster request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("roster request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("enrollment service returned status %d", resp.StatusCode)
+    }
+
+    var roster []models.Enrollment
+    if err := json.NewDecoder(resp.Body).Decode(&roster); err != nil {
+        return nil, fmt.Errorf("failed to decode roster: %w", err)
+    }
+
+    return roster, nil
+}
+
+// ListBeneficiaryEnrollments fetches every enrollment of a beneficiary by CPF
+func (c *EnrollmentClient) ListBeneficiaryEnrollments(ctx context.Context, cpf string) ([]models.Enrollment, error) {
+    endpoint := fmt.Sprintf("%s/api/v1/beneficiaries/%s/enrollments", c.baseURL, url.PathEscape(cpf))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build beneficiary enrollments request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("beneficiary enrollments request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return nil, ErrEnrollmentNotFound
+    case resp.StatusCode != http.StatusOK:
+        return nil, fmt.Errorf("enrollment service returned status %d", resp.StatusCode)
+    }
+
+    var enrollments []models.Enrollment
+    if err := json.NewDecoder(resp.Body).Decode(&enrollments); err != nil {
+        return nil, fmt.Errorf("failed to decode beneficiary enrollments: %w", err)
+    }
+
+    return enrollments, nil
+}
+
+// digitsOnly strips formatting from identifiers such as CPF, CEP and phone numbers
+func digitsOnly(value string) string {
+    var digits strings.Builder
+    for _, r := range value {
+        if r >= '0' && r <= '9' {
+            digits.WriteRune(r)
+        }
+    }
+    return digits.String()
+}
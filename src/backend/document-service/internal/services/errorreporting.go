@@ -0,0 +1,357 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// ErrorReport is a panic recovered while serving a request, with what is
+// needed to find the request in the access log and its trace
+type ErrorReport struct {
+    Message    string
+    Stack      []byte
+    Method     string
+    Route      string
+    RequestID  string
+    TraceID    string
+    UserID     string
+    OccurredAt time.Time
+}
+
+// ErrorReporter sends error reports to an error tracking service
+type ErrorReporter interface {
+    Name() string
+    Send(ctx context.Context, report *ErrorReport) error
+}
+
+// NewErrorReporter returns the reporter of the configured provider, or nil
+// when panics are only logged and counted
+func NewErrorReporter(cfg *config.Config) (ErrorReporter, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    switch cfg.ErrorReportingConfig.Provider {
+    case "":
+        return nil, nil
+    case config.ErrorReportingSentry:
+        return NewSentryReporter(cfg.ErrorReportingConfig)
+    case config.ErrorReportingRollbar:
+        return NewRollbarReporter(cfg.ErrorReportingConfig), nil
+    default:
+        return nil, fmt.Errorf("unsupported error reporting provider: %s", cfg.ErrorReportingConfig.Provider)
+    }
+}
+
+// ErrorReporting queues error reports and sends them in the background, so
+// the goroutine recovering a panic never waits on the provider. Reports
+// arriving while the queue is full are dropped and counted
+type ErrorReporting struct {
+    reporter ErrorReporter
+    queue    chan *ErrorReport
+    timeout  time.Duration
+    logger   *zap.Logger
+}
+
+// NewErrorReporting creates the report queue, or returns nil when no
+// provider is configured. A nil *ErrorReporting drops every report
+func NewErrorReporting(cfg *config.Config, logger *zap.Logger) (*ErrorReporting, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    reporter, err := NewErrorReporter(cfg)
+    if err != nil || reporter == nil {
+        return nil, err
+    }
+
+    return &ErrorReporting{
+        reporter: reporter,
+        queue:    make(chan *ErrorReport, cfg.ErrorReportingConfig.QueueSize),
+        timeout:  cfg.ErrorReportingConfig.Timeout,
+        logger:   logger.With(zap.String("component", "error_reporting")),
+    }, nil
+}
+
+// Report queues a report without blocking
+func (r *ErrorReporting) Report(report *ErrorReport) {
+    if r == nil {
+        return
+    }
+    select {
+    case r.queue <- report:
+    default:
+        errorReports.WithLabelValues(r.reporter.Name(), "dropped").Inc()
+    }
+}
+
+// Run sends queued reports until ctx is done, then sends those still queued
+func (r *ErrorReporting) Run(ctx context.Context) {
+    for {
+        select {
+        case report := <-r.queue:
+            r.send(report)
+        case <-ctx.Done():
+            for {
+                select {
+                case report := <-r.queue:
+                    r.send(report)
+                default:
+                    return
+                }
+            }
+        }
+    }
+}
+
+func (r *ErrorReporting) send(report *ErrorReport) {
+    ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+    defer cancel()
+
+    if err := r.reporter.Send(ctx, report); err != nil {
+        errorReports.WithLabelValues(r.reporter.Name(), "failed").Inc()
+        r.logger.Warn("Failed to send error report",
+            zap.String("provider", r.reporter.Name()),
+            zap.String("request_id", report.RequestID),
+            zap.Error(err),
+        )
+        return
+    }
+    errorReports.WithLabelValues(r.reporter.Name(), "sent").Inc()
+}
+
+// stackFrame is one call of a goroutine stack trace
+type stackFrame struct {
+    Function string
+    File     string
+    Line     int
+}
+
+// parseStack reads the frames of a stack trace as printed by
+// runtime/debug.Stack, innermost call first. Each frame is a function line
+// followed by a tab-indented file:line line. Taken while recovering, the
+// trace starts in the recovering function; the frames up to the call to
+// panic are dropped so it starts where the panic was raised
+func parseStack(stack []byte) []stackFrame {
+    lines := strings.Split(string(stack), "\n")
+    var frames []stackFrame
+    for i := 1; i+1 < len(lines); i++ {
+        location := lines[i+1]
+        if !strings.HasPrefix(location, "\t") || strings.HasPrefix(lines[i], "\t") {
+            continue
+        }
+        function := lines[i]
+        if paren := strings.LastIndex(function, "("); paren > 0 {
+            function = function[:paren]
+        }
+        location = strings.TrimPrefix(location, "\t")
+        if offset := strings.LastIndex(location, " +0x"); offset > 0 {
+            location = location[:offset]
+        }
+        if function == "panic" {
+            frames = frames[:0]
+            i++
+            continue
+        }
+        frame := stackFrame{Function: function, File: location}
+        if colon := strings.LastIndex(location, ":"); colon > 0 {
+            frame.File = location[:colon]
+            frame.Line, _ = strconv.Atoi(location[colon+1:])
+        }
+        frames = append(frames, frame)
+        i++
+    }
+    return frames
+}
+
+// postJSON posts a report payload and fails on any status but 2xx
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, payload interface{}) error {
+    body, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("failed to encode error report: %w", err)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("failed to build error report request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    for name, value := range headers {
+        req.Header.Set(name, value)
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("error report request failed: %w", err)
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("error tracking service returned status %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// SentryReporter sends error reports as events to the Sentry store API
+type SentryReporter struct {
+    endpoint    string
+    publicKey   string
+    environment string
+    release     string
+    httpClient  *http.Client
+}
+
+// NewSentryReporter creates the reporter of the project the DSN names
+func NewSentryReporter(cfg config.ErrorReportingConfig) (*SentryReporter, error) {
+    dsn, err := url.Parse(cfg.DSN)
+    if err != nil || dsn.User == nil || dsn.User.Username() == "" || dsn.Host == "" {
+        return nil, errors.New("invalid sentry DSN")
+    }
+    project := strings.TrimPrefix(dsn.Path, "/")
+    if project == "" {
+        return nil, errors.New("sentry DSN names no project")
+    }
+
+    // A DSN under a path prefix keeps it: https://key@host/prefix/42
+    prefix := ""
+    if slash := strings.LastIndex(project, "/"); slash >= 0 {
+        prefix, project = "/"+project[:slash], project[slash+1:]
+    }
+    return &SentryReporter{
+        endpoint:    fmt.Sprintf("%s://%s%s/api/%s/store/", dsn.Scheme, dsn.Host, prefix, project),
+        publicKey:   dsn.User.Username(),
+        environment: cfg.Environment,
+        release:     cfg.Release,
+        httpClient:  &http.Client{Timeout: cfg.Timeout},
+    }, nil
+}
+
+// Name returns the provider name
+func (s *SentryReporter) Name() string {
+    return config.ErrorReportingSentry
+}
+
+type sentryFrame struct {
+    Function string `json:"function"`
+    Filename string `json:"filename"`
+    Lineno   int    `json:"lineno"`
+}
+
+// Send stores the report as a fatal event
+func (s *SentryReporter) Send(ctx context.Context, report *ErrorReport) error {
+    // Sentry lists frames outermost call first
+    frames := parseStack(report.Stack)
+    sentryFrames := make([]sentryFrame, len(frames))
+    for i, frame := range frames {
+        sentryFrames[len(frames)-1-i] = sentryFrame{Function: frame.Function, Filename: frame.File, Lineno: frame.Line}
+    }
+
+    eventID := make([]byte, 16)
+    if _, err := rand.Read(eventID); err != nil {
+        return err
+    }
+    event := map[string]interface{}{
+        "event_id":    hex.EncodeToString(eventID),
+        "timestamp":   report.OccurredAt.UTC().Format(time.RFC3339),
+        "level":       "fatal",
+        "platform":    "go",
+        "logger":      "document-service",
+        "environment": s.environment,
+        "release":     s.release,
+        "transaction": report.Method + " " + report.Route,
+        "exception": map[string]interface{}{
+            "values": []interface{}{map[string]interface{}{
+                "type":       "panic",
+                "value":      report.Message,
+                "stacktrace": map[string]interface{}{"frames": sentryFrames},
+            }},
+        },
+        "tags": map[string]string{
+            "route":      report.Route,
+            "request_id": report.RequestID,
+            "trace_id":   report.TraceID,
+        },
+        "user": map[string]string{"id": report.UserID},
+    }
+
+    auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=document-service/1.0, sentry_key=%s", s.publicKey)
+    return postJSON(ctx, s.httpClient, s.endpoint, map[string]string{"X-Sentry-Auth": auth}, event)
+}
+
+// RollbarReporter sends error reports as items to the Rollbar API
+type RollbarReporter struct {
+    endpoint    string
+    accessToken string
+    environment string
+    release     string
+    httpClient  *http.Client
+}
+
+// NewRollbarReporter creates the reporter posting to the configured endpoint
+func NewRollbarReporter(cfg config.ErrorReportingConfig) *RollbarReporter {
+    return &RollbarReporter{
+        endpoint:    strings.TrimSuffix(cfg.Endpoint, "/") + "/api/1/item/",
+        accessToken: cfg.AccessToken,
+        environment: cfg.Environment,
+        release:     cfg.Release,
+        httpClient:  &http.Client{Timeout: cfg.Timeout},
+    }
+}
+
+// Name returns the provider name
+func (r *RollbarReporter) Name() string {
+    return config.ErrorReportingRollbar
+}
+
+type rollbarFrame struct {
+    Filename string `json:"filename"`
+    Lineno   int    `json:"lineno"`
+    Method   string `json:"method"`
+}
+
+// Send posts the report as a critical item
+func (r *RollbarReporter) Send(ctx context.Context, report *ErrorReport) error {
+    // Rollbar lists frames outermost call first
+    frames := parseStack(report.Stack)
+    rollbarFrames := make([]rollbarFrame, len(frames))
+    for i, frame := range frames {
+        rollbarFrames[len(frames)-1-i] = rollbarFrame{Filename: frame.File, Lineno: frame.Line, Method: frame.Function}
+    }
+
+    item := map[string]interface{}{
+        "data": map[string]interface{}{
+            "environment":  r.environment,
+            "code_version": r.release,
+            "level":        "critical",
+            "timestamp":    report.OccurredAt.Unix(),
+            "platform":     "go",
+            "language":     "go",
+            "context":      report.Method + " " + report.Route,
+            "body": map[string]interface{}{
+                "trace": map[string]interface{}{
+                    "frames":    rollbarFrames,
+                    "exception": map[string]string{"class": "panic", "message": report.Message},
+                },
+            },
+            "person": map[string]string{"id": report.UserID},
+            "custom": map[string]string{
+                "request_id": report.RequestID,
+                "trace_id":   report.TraceID,
+            },
+        },
+    }
+    return postJSON(ctx, r.httpClient, r.endpoint, map[string]string{"X-Rollbar-Access-Token": r.accessToken}, item)
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// etaKey identifies the durations of a stage for a document type; an empty
+// type holds the durations of every type
+type etaKey struct {
+    documentType string
+    stage        string
+}
+
+// etaStat holds the smoothed duration of a stage and the queue depth it was
+// observed at
+type etaStat struct {
+    duration float64
+    depth    float64
+    samples  int
+    depths   int
+}
+
+// etaWaiter is a document waiting for review
+type etaWaiter struct {
+    since time.Time
+    depth int
+}
+
+// etaPrediction is the first completion time given for a document, kept to
+// measure how accurate it was
+type etaPrediction struct {
+    documentType string
+    completion   time.Time
+    issuedAt     time.Time
+}
+
+// ETAEstimator estimates when documents will be verified. It tracks the
+// duration of each pipeline step and of review by document type, with the
+// queue depth each was observed at, and scales them by the current depth:
+// a document behind twice as many others is expected to wait twice as long.
+// The first estimate given for each document is compared with the actual
+// decision time to measure accuracy
+type ETAEstimator struct {
+    cfg       config.ETAConfig
+    documents repository.DocumentRepository
+    pipeline  *DocumentPipeline
+    calendars *BusinessCalendars
+    logger    *zap.Logger
+
+    mu          sync.Mutex
+    stats       map[etaKey]*etaStat
+    running     map[string]int
+    awaiting    map[string]etaWaiter
+    predictions map[string]etaPrediction
+}
+
+// NewETAEstimator creates an estimator with no history; Load seeds it. The
+// pipeline may be nil, in which case review is the only stage estimated
+func NewETAEstimator(cfg *config.Config, documents repository.DocumentRepository, pipeline *DocumentPipeline, logger *zap.Logger) (*ETAEstimator, error) {
+    if cfg == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ETAEstimator{
+        cfg:         cfg.ETAConfig,
+        documents:   documents,
+        pipeline:    pipeline,
+        logger:      logger.With(zap.String("component", "eta")),
+        stats:       make(map[etaKey]*etaStat),
+        running:     make(map[string]int),
+        awaiting:    make(map[string]etaWaiter),
+        predictions: make(map[string]etaPrediction),
+    }, nil
+}
+
+// UseCalendars counts review durations in the business time of each
+// document's tenant, as reviewers work business hours; it must be called
+// before Load
+func (e *ETAEstimator) UseCalendars(calendars *BusinessCalendars) {
+    e.calendars = calendars
+}
+
+// StreamInterval returns how often status streams check for changes
+func (e *ETAEstimator) StreamInterval() time.Duration {
+    return e.cfg.StreamInterval
+}
+
+// Load seeds review durations with the decisions of the history window,
+// oldest first so the latest weigh most, and loads the review queue
+func (e *ETAEstimator) Load(ctx context.Context) error {
+    now := time.Now()
+    docs, err := e.documents.ListUpdatedBetween(ctx, now.Add(-e.cfg.HistoryWindow), now)
+    if err != nil {
+        return fmt.Errorf("failed to load review history: %w", err)
+    }
+
+    reviewed := make([]*models.Document, 0, len(docs))
+    for _, doc := range docs {
+        if doc.Verified() && doc.ReviewedAt != nil && doc.ProcessedAt != nil {
+            reviewed = append(reviewed, doc)
+        }
+    }
+    sort.Slice(reviewed, func(i, j int) bool {
+        return reviewed[i].ReviewedAt.Before(*reviewed[j].ReviewedAt)
+    })
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    // The queue depth these decisions were made at is unknown
+    for _, doc := range reviewed {
+        e.observe(doc.DocumentType, models.ETAStageReview, e.calendars.For(doc.TenantID).Elapsed(*doc.ProcessedAt, *doc.ReviewedAt), -1)
+    }
+    e.setQueue(docs)
+    e.logger.Info("Processing ETA history loaded",
+        zap.Int("reviews", len(reviewed)),
+        zap.Int("awaiting_review", len(e.awaiting)),
+    )
+    return nil
+}
+
+// Run reloads the review queue on the configured interval, so it reflects
+// documents processed and reviewed on other instances, and forgets the
+// predictions of documents never reviewed
+func (e *ETAEstimator) Run(ctx context.Context) {
+    ticker := time.NewTicker(e.cfg.RefreshInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if err := e.refresh(ctx); err != nil {
+                e.logger.Warn("Failed to reload review queue", zap.Error(err))
+            }
+        }
+    }
+}
+
+// Estimate reports the status of a document and when it is expected to be
+// verified
+func (e *ETAEstimator) Estimate(doc *models.Document) *models.ProcessingStatus {
+    now := time.Now()
+    status := &models.ProcessingStatus{
+        DocumentID:  doc.ID,
+        Status:      doc.Status,
+        Version:     doc.Version,
+        Verified:    doc.Verified(),
+        EstimatedAt: now,
+    }
+    if doc.Verified() {
+        status.EstimatedCompletion = doc.ReviewedAt
+        status.Basis = models.ETABasisHistory
+        return status
+    }
+    // Failed and halted documents wait for action no estimate covers
+    if !doc.AwaitingReview() {
+        return status
+    }
+
+    stages := append(e.pipeline.PendingSteps(doc), models.ETAStageReview)
+    status.Stage = stages[0]
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    // Pipeline steps run around the clock, review in business time
+    calendar := e.calendars.For(doc.TenantID)
+    var processing, review time.Duration
+    for i, stage := range stages {
+        depth := e.depth(doc.ID, stage)
+        if i == 0 {
+            status.QueueDepth = depth
+        }
+        estimate, ok := e.estimate(doc.DocumentType, stage, depth)
+        if !ok {
+            status.Basis = models.ETABasisInsufficientHistory
+            return status
+        }
+        if stage != models.ETAStageReview {
+            processing += max(estimate, 0)
+            continue
+        }
+        // Time already spent waiting for review counts against it
+        if waiter, waiting := e.awaiting[doc.ID]; waiting {
+            estimate -= calendar.Elapsed(waiter.since, now)
+        }
+        review = max(estimate, 0)
+    }
+
+    completion := calendar.Add(now.Add(processing), review)
+    status.EstimatedCompletion = &completion
+    status.Basis = models.ETABasisHistory
+    if _, ok := e.predictions[doc.ID]; !ok {
+        e.predictions[doc.ID] = etaPrediction{documentType: doc.DocumentType, completion: completion, issuedAt: now}
+    }
+    return status
+}
+
+// OnIngested is a pipeline hook adding processed documents to the review
+// queue
+func (e *ETAEstimator) OnIngested(ctx context.Context, doc *models.Document) error {
+    if !doc.AwaitingReview() {
+        return nil
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    if _, ok := e.awaiting[doc.ID]; !ok {
+        e.awaiting[doc.ID] = etaWaiter{since: time.Now(), depth: len(e.awaiting)}
+    }
+    return nil
+}
+
+// Reviewed records the review duration of a verified document and the
+// accuracy of the estimate it was given
+func (e *ETAEstimator) Reviewed(doc *models.Document) {
+    if e == nil || !doc.Verified() || doc.ReviewedAt == nil {
+        return
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+
+    waiter, ok := e.awaiting[doc.ID]
+    if !ok && doc.ProcessedAt != nil {
+        waiter = etaWaiter{since: *doc.ProcessedAt, depth: -1}
+        ok = true
+    }
+    if ok {
+        e.observe(doc.DocumentType, models.ETAStageReview, e.calendars.For(doc.TenantID).Elapsed(waiter.since, *doc.ReviewedAt), waiter.depth)
+    }
+    delete(e.awaiting, doc.ID)
+
+    prediction, ok := e.predictions[doc.ID]
+    if !ok {
+        return
+    }
+    delete(e.predictions, doc.ID)
+    miss := doc.ReviewedAt.Sub(prediction.completion)
+    outcome := "late"
+    if miss < 0 {
+        outcome = "early"
+        miss = -miss
+    }
+    etaErrors.WithLabelValues(prediction.documentType).Observe(miss.Seconds())
+    etaOutcomes.WithLabelValues(prediction.documentType, outcome).Inc()
+}
+
+// startStep counts a document entering a pipeline step, returning the
+// number of documents already in it
+func (e *ETAEstimator) startStep(step string) int {
+    if e == nil {
+        return 0
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    depth := e.running[step]
+    e.running[step]++
+    return depth
+}
+
+// finishStep records the duration of a pipeline step
+func (e *ETAEstimator) finishStep(documentType, step string, duration time.Duration, depth int) {
+    if e == nil {
+        return
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.running[step]--
+    e.observe(documentType, step, duration, depth)
+}
+
+// refresh reloads the review queue and drops predictions older than the
+// history window
+func (e *ETAEstimator) refresh(ctx context.Context) error {
+    now := time.Now()
+    docs, err := e.documents.ListUpdatedBetween(ctx, now.Add(-e.cfg.HistoryWindow), now)
+    if err != nil {
+        return err
+    }
+
+    e.mu.Lock()
+    defer e.mu.Unlock()
+    e.setQueue(docs)
+    for id, prediction := range e.predictions {
+        if now.Sub(prediction.issuedAt) > e.cfg.HistoryWindow {
+            delete(e.predictions, id)
+        }
+    }
+    return nil
+}
+
+// setQueue replaces the review queue with the documents awaiting review,
+// keeping the queue depth of those already known
+func (e *ETAEstimator) setQueue(docs []*models.Document) {
+    waiting := make([]*models.Document, 0)
+    for _, doc := range docs {
+        if doc.AwaitingReview() && doc.ProcessedAt != nil {
+            waiting = append(waiting, doc)
+        }
+    }
+    sort.Slice(waiting, func(i, j int) bool {
+        return waiting[i].ProcessedAt.Before(*waiting[j].ProcessedAt)
+    })
+
+    awaiting := make(map[string]etaWaiter, len(waiting))
+    for i, doc := range waiting {
+        waiter, ok := e.awaiting[doc.ID]
+        if !ok {
+            waiter = etaWaiter{since: *doc.ProcessedAt, depth: i}
+        }
+        awaiting[doc.ID] = waiter
+    }
+    e.awaiting = awaiting
+}
+
+// depth returns the number of documents ahead of a document in a stage:
+// those entering review before it, or every document in a pipeline step or
+// awaiting review it has not reached yet
+func (e *ETAEstimator) depth(documentID, stage string) int {
+    if stage != models.ETAStageReview {
+        return e.running[stage]
+    }
+    waiter, ok := e.awaiting[documentID]
+    if !ok {
+        return len(e.awaiting)
+    }
+    ahead := 0
+    for id, other := range e.awaiting {
+        if id != documentID && other.since.Before(waiter.since) {
+            ahead++
+        }
+    }
+    return ahead
+}
+
+// estimate returns the expected duration of a stage at a queue depth, from
+// the durations of the document type or else of every type
+func (e *ETAEstimator) estimate(documentType, stage string, depth int) (time.Duration, bool) {
+    stat := e.stats[etaKey{documentType, stage}]
+    if stat == nil {
+        stat = e.stats[etaKey{"", stage}]
+    }
+    if stat == nil {
+        return 0, false
+    }
+    scale := 1.0
+    if stat.depths > 0 {
+        scale = (float64(depth) + 1) / (stat.depth + 1)
+    }
+    return time.Duration(stat.duration * scale), true
+}
+
+// observe smooths a stage duration into the durations of the document type
+// and of every type; a negative depth is unknown and leaves the depth as is
+func (e *ETAEstimator) observe(documentType, stage string, duration time.Duration, depth int) {
+    if duration < 0 {
+        return
+    }
+    for _, key := range []etaKey{{documentType, stage}, {"", stage}} {
+        stat := e.stats[key]
+        if stat == nil {
+            stat = &etaStat{}
+            e.stats[key] = stat
+        }
+        stat.duration = e.smooth(stat.duration, float64(duration), stat.samples)
+        stat.samples++
+        if depth >= 0 {
+            stat.depth = e.smooth(stat.depth, float64(depth), stat.depths)
+            stat.depths++
+        }
+    }
+}
+
+// smooth folds a sample into a moving average; the first sample is taken as is
+func (e *ETAEstimator) smooth(average, sample float64, samples int) float64 {
+    if samples == 0 {
+        return sample
+    }
+    return average + e.cfg.Smoothing*(sample-average)
+}
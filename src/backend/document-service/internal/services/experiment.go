@@ -0,0 +1,164 @@
+package services
+
+import (
+    "context"
+    "fmt"
+    "hash/fnv"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    // VariantControl is the variant name of the original step implementation
+    VariantControl = "control"
+)
+
+// ExperimentVariant is an alternative step implementation receiving a share of documents
+type ExperimentVariant struct {
+    Name       string
+    Step       PipelineStep
+    Percentage float64
+}
+
+// ExperimentStep routes a configurable percentage of documents through
+// alternative implementations of a pipeline step and tags each document with
+// the variant used so accuracy and latency can be compared
+type ExperimentStep struct {
+    name     string
+    control  PipelineStep
+    variants []ExperimentVariant
+    flags    *FeatureFlags
+}
+
+// NewExperimentStep creates an experiment around the control step; flags may be
+// nil, otherwise the experiment's kill switch can send every document to control
+func NewExperimentStep(name string, control PipelineStep, flags *FeatureFlags, variants ...ExperimentVariant) *ExperimentStep {
+    return &ExperimentStep{
+        name:     name,
+        control:  control,
+        variants: variants,
+        flags:    flags,
+    }
+}
+
+// Name returns the name of the experimented step so the experiment takes its place
+func (s *ExperimentStep) Name() string {
+    return s.control.Name()
+}
+
+// Applies delegates to the control step; variants process the same documents
+func (s *ExperimentStep) Applies(doc *models.Document) bool {
+    return s.control.Applies(doc)
+}
+
+// Execute runs the variant assigned to the document and records the
+// assignment. Documents the assigned variant does not apply to are processed
+// by the control step
+func (s *ExperimentStep) Execute(ctx context.Context, run *PipelineRun) error {
+    variant, step := s.assign(run.Document.ID)
+    if variant != VariantControl && !step.Applies(run.Document) {
+        variant, step = VariantControl, s.control
+    }
+    if producer := run.Document.Producer(); producer != nil {
+        provenance := *producer
+        provenance.Variant = variant
+        run.describe(&provenance, step)
+        run.Document.SetProducer(&provenance)
+    }
+
+    startTime := time.Now()
+    err := step.Execute(ctx, run)
+    duration := time.Since(startTime)
+
+    experimentStepDuration.WithLabelValues(s.name, variant).Observe(duration.Seconds())
+    if err != nil {
+        experimentStepFailures.WithLabelValues(s.name, variant).Inc()
+    }
+
+    run.Document.RecordExperiment(models.ExperimentAssignment{
+        Experiment: s.name,
+        Step:       s.control.Name(),
+        Variant:    variant,
+        DurationMS: duration.Milliseconds(),
+        Failed:     err != nil,
+        AssignedAt: startTime,
+    })
+    return err
+}
+
+// assign deterministically buckets the document so reprocessing it always
+// selects the same variant
+func (s *ExperimentStep) assign(documentID string) (string, PipelineStep) {
+    if !s.flags.Enabled(ExperimentFlag(s.name), true) {
+        return VariantControl, s.control
+    }
+
+    hash := fnv.New32a()
+    hash.Write([]byte(s.name + ":" + documentID))
+    bucket := float64(hash.Sum32()%10000) / 100
+
+    threshold := 0.0
+    for _, variant := range s.variants {
+        threshold += variant.Percentage
+        if bucket < threshold {
+            return variant.Name, variant.Step
+        }
+    }
+    return VariantControl, s.control
+}
+
+// ApplyExperiments wraps the configured steps in experiments; alternatives maps
+// implementation names to the step implementations available for trials
+func ApplyExperiments(cfg *config.Config, steps []PipelineStep, alternatives map[string]PipelineStep, flags *FeatureFlags) ([]PipelineStep, error) {
+    if cfg == nil || !cfg.ExperimentsConfig.Enabled {
+        return steps, nil
+    }
+
+    wrapped := append([]PipelineStep(nil), steps...)
+    for _, experiment := range cfg.ExperimentsConfig.Experiments {
+        index := -1
+        for i, step := range wrapped {
+            if step.Name() == experiment.Step {
+                index = i
+                break
+            }
+        }
+        if index < 0 {
+            return nil, fmt.Errorf("experiment %s targets unknown step %s", experiment.Name, experiment.Step)
+        }
+
+        variants := make([]ExperimentVariant, 0, len(experiment.Variants))
+        for _, variant := range experiment.Variants {
+            step, ok := alternatives[variant.Implementation]
+            if !ok {
+                return nil, fmt.Errorf("experiment %s references unknown implementation %s", experiment.Name, variant.Implementation)
+            }
+            variants = append(variants, ExperimentVariant{
+                Name:       variant.Name,
+                Step:       step,
+                Percentage: variant.Percentage,
+            })
+        }
+
+        wrapped[index] = NewExperimentStep(experiment.Name, wrapped[index], flags, variants...)
+    }
+    return wrapped, nil
+}
+
+// OCRImplementation returns the implementation name under which experiments
+// trial OCR read with the provider
+func OCRImplementation(provider string) string {
+    return StepOCR + "-" + provider
+}
+
+// OCRAlternatives returns OCR steps reading with each of the providers, keyed
+// by their implementation name, for experiments on the OCR step
+func OCRAlternatives(ocr *OCRService, providers map[string]OCRProvider, storage *StorageService) map[string]PipelineStep {
+    alternatives := make(map[string]PipelineStep, len(providers))
+    for name, provider := range providers {
+        alternatives[OCRImplementation(name)] = NewProviderOCRStep(ocr, provider, storage)
+    }
+    return alternatives
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// Feature flags consulted by handlers and the pipeline
+const (
+    // FlagUploads is the kill switch for document uploads through the API
+    FlagUploads = "uploads"
+)
+
+// StepFlag returns the kill switch of a pipeline step; disabled steps are skipped
+func StepFlag(step string) string {
+    return "pipeline." + step
+}
+
+// ExperimentFlag returns the kill switch of an experiment; disabled experiments
+// route every document through the control step
+func ExperimentFlag(experiment string) string {
+    return "experiment." + experiment
+}
+
+// FlagSource fetches flag states from a remote feature flag service
+type FlagSource interface {
+    Name() string
+    Fetch(ctx context.Context) (map[string]bool, error)
+}
+
+// FeatureFlags answers flag lookups from the configured defaults overlaid with
+// the last states fetched from the remote source. A nil *FeatureFlags reports
+// every flag at its fallback value
+type FeatureFlags struct {
+    mu       sync.RWMutex
+    defaults map[string]bool
+    remote   map[string]bool
+    source   FlagSource
+    interval time.Duration
+    logger   *zap.Logger
+}
+
+// NewFeatureFlags creates the flag set; source may be nil for config-file driven flags
+func NewFeatureFlags(cfg *config.Config, source FlagSource, logger *zap.Logger) (*FeatureFlags, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    defaults := make(map[string]bool, len(cfg.FeatureFlagsConfig.Flags))
+    for _, flag := range cfg.FeatureFlagsConfig.Flags {
+        defaults[flag.Name] = flag.Enabled
+    }
+
+    flags := &FeatureFlags{
+        defaults: defaults,
+        remote:   map[string]bool{},
+        source:   source,
+        interval: cfg.FeatureFlagsConfig.RefreshInterval,
+        logger:   logger,
+    }
+    flags.exportStates()
+    return flags, nil
+}
+
+// NewFlagSource creates the remote source selected by configuration, or nil
+// when flags are read from the configuration file only
+func NewFlagSource(cfg *config.Config) (FlagSource, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    switch cfg.FeatureFlagsConfig.Provider {
+    case "", "config":
+        return nil, nil
+    case "unleash":
+        return NewUnleashFlagSource(cfg), nil
+    case "flagsmith":
+        return NewFlagsmithFlagSource(cfg), nil
+    default:
+        return nil, fmt.Errorf("unsupported feature flag provider: %s", cfg.FeatureFlagsConfig.Provider)
+    }
+}
+
+// Enabled reports the state of a flag, or fallback when the flag is unknown
+func (f *FeatureFlags) Enabled(name string, fallback bool) bool {
+    if f == nil {
+        return fallback
+    }
+
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+
+    if enabled, ok := f.remote[name]; ok {
+        return enabled
+    }
+    if enabled, ok := f.defaults[name]; ok {
+        return enabled
+    }
+    return fallback
+}
+
+// States returns the effective state of every known flag
+func (f *FeatureFlags) States() map[string]bool {
+    f.mu.RLock()
+    defer f.mu.RUnlock()
+
+    states := make(map[string]bool, len(f.defaults)+len(f.remote))
+    for name, enabled := range f.defaults {
+        states[name] = enabled
+    }
+    for name, enabled := range f.remote {
+        states[name] = enabled
+    }
+    return states
+}
+
+// Refresh replaces the remote flag states; on failure the previous states are kept
+func (f *FeatureFlags) Refresh(ctx context.Context) error {
+    if f.source == nil {
+        return nil
+    }
+
+    remote, err := f.source.Fetch(ctx)
+    if err != nil {
+        featureFlagRefreshes.WithLabelValues(f.source.Name(), "failed").Inc()
+        return fmt.Errorf("failed to fetch feature flags from %s: %w", f.source.Name(), err)
+    }
+    featureFlagRefreshes.WithLabelValues(f.source.Name(), "success").Inc()
+
+    f.mu.Lock()
+    previous := f.remote
+    f.remote = remote
+    f.mu.Unlock()
+
+    for name, enabled := range remote {
+        if was, ok := previous[name]; !ok || was != enabled {
+            f.logger.Info("Feature flag changed",
+                zap.String("flag", name),
+                zap.Bool("enabled", enabled),
+                zap.String("source", f.source.Name()),
+            )
+        }
+    }
+    f.exportStates()
+    return nil
+}
+
+// Run polls the remote source until the context is cancelled
+func (f *FeatureFlags) Run(ctx context.Context) {
+    if f.source == nil {
+        return
+    }
+
+    ticker := time.NewTicker(f.interval)
+    defer ticker.Stop()
+
+    for {
+        if err := f.Refresh(ctx); err != nil {
+            f.logger.Warn("Feature flag refresh failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// exportStates publishes the effective flag states as gauges
+func (f *FeatureFlags) exportStates() {
+    // Reset so flags removed from the remote source stop being reported
+    featureFlagStates.Reset()
+    for name, enabled := range f.States() {
+        value := 0.0
+        if enabled {
+            value = 1
+        }
+        featureFlagStates.WithLabelValues(name).Set(value)
+    }
+}
+
+// UnleashFlagSource reads flag states from the Unleash client API
+type UnleashFlagSource struct {
+    url        string
+    apiKey     string
+    appName    string
+    httpClient *http.Client
+}
+
+// NewUnleashFlagSource creates a new Unleash flag source
+func NewUnleashFlagSource(cfg *config.Config) *UnleashFlagSource {
+    return &UnleashFlagSource{
+        url:        cfg.FeatureFlagsConfig.URL,
+        apiKey:     cfg.FeatureFlagsConfig.APIKey,
+        appName:    cfg.FeatureFlagsConfig.AppName,
+        httpClient: &http.Client{Timeout: cfg.FeatureFlagsConfig.Timeout},
+    }
+}
+
+// Name returns the source name
+func (s *UnleashFlagSource) Name() string {
+    return "unleash"
+}
+
+// Fetch returns the enabled state of every feature toggle. Activation
+// strategies are not evaluated since the service has no per-request context
+// worth targeting; a toggle is a global switch
+func (s *UnleashFlagSource) Fetch(ctx context.Context) (map[string]bool, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/client/features", nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build unleash request: %w", err)
+    }
+    req.Header.Set("Authorization", s.apiKey)
+    req.Header.Set("UNLEASH-APPNAME", s.appName)
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("unleash request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("unleash returned status %d", resp.StatusCode)
+    }
+
+    var result struct {
+        Features []struct {
+            Name    string `json:"name"`
+            Enabled bool   `json:"enabled"`
+        } `json:"features"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to decode unleash response: %w", err)
+    }
+
+    states := make(map[string]bool, len(result.Features))
+    for _, feature := range result.Features {
+        states[feature.Name] = feature.Enabled
+    }
+    return states, nil
+}
+
+// FlagsmithFlagSource reads environment flag states from the Flagsmith API
+type FlagsmithFlagSource struct {
+    url        string
+    apiKey     string
+    httpClient *http.Client
+}
+
+// NewFlagsmithFlagSource creates a new Flagsmith flag source
+func NewFlagsmithFlagSource(cfg *config.Config) *FlagsmithFlagSource {
+    return &FlagsmithFlagSource{
+        url:        cfg.FeatureFlagsConfig.URL,
+        apiKey:     cfg.FeatureFlagsConfig.APIKey,
+        httpClient: &http.Client{Timeout: cfg.FeatureFlagsConfig.Timeout},
+    }
+}
+
+// Name returns the source name
+func (s *FlagsmithFlagSource) Name() string {
+    return "flagsmith"
+}
+
+// Fetch returns the enabled state of every flag of the environment
+func (s *FlagsmithFlagSource) Fetch(ctx context.Context) (map[string]bool, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/api/v1/flags/", nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to build flagsmith request: %w", err)
+    }
+    req.Header.Set("X-Environment-Key", s.apiKey)
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("flagsmith request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("flagsmith returned status %d", resp.StatusCode)
+    }
+
+    var result []struct {
+        Enabled bool `json:"enabled"`
+        Feature struct {
+            Name string `json:"name"`
+        } `json:"feature"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to decode flagsmith response: %w", err)
+    }
+
+    states := make(map[string]bool, len(result))
+    for _, flag := range result {
+        states[flag.Feature.Name] = flag.Enabled
+    }
+    return states, nil
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strings"
+    "time"
+
+    "github.com/gen2brain/go-fitz" // v1.23.1
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepGovBR = "govbr"
+)
+
+// Government issued digital document kinds
+const (
+    GovDocumentCNH  = "cnh"
+    GovDocumentCRLV = "crlv"
+)
+
+var (
+    ErrVerificationCodeNotFound = errors.New("verification code not found in document")
+
+    verificationCodePattern = regexp.MustCompile(`(?i)c[óo]digo\s+de\s+(?:valida[çc][ãa]o|seguran[çc]a)[^0-9]{0,40}([0-9][0-9 .-]{8,20}[0-9])`)
+)
+
+// GovernmentVerifier checks a document verification code with the issuing authority
+type GovernmentVerifier interface {
+    VerifyCode(ctx context.Context, kind, code string) (bool, error)
+}
+
+// GovBRClient validates verification codes against the gov.br document services
+type GovBRClient struct {
+    baseURL    string
+    apiKey     string
+    httpClient *http.Client
+}
+
+// NewGovBRClient creates a new gov.br verification client
+func NewGovBRClient(cfg *config.Config) (*GovBRClient, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    return &GovBRClient{
+        baseURL:    cfg.GovBRConfig.BaseURL,
+        apiKey:     cfg.GovBRConfig.APIKey,
+        httpClient: &http.Client{Timeout: cfg.GovBRConfig.Timeout},
+    }, nil
+}
+
+// VerifyCode reports whether gov.br recognizes the code for the document kind
+func (c *GovBRClient) VerifyCode(ctx context.Context, kind, code string) (bool, error) {
+    endpoint := fmt.Sprintf("%s/v1/%s/verifications/%s", c.baseURL, url.PathEscape(kind), url.PathEscape(code))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return false, fmt.Errorf("failed to build gov.br request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+    if c.apiKey != "" {
+        req.Header.Set("Authorization", "Bearer "+c.apiKey)
+    }
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return false, fmt.Errorf("gov.br request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusNotFound:
+        return false, nil
+    case resp.StatusCode != http.StatusOK:
+        return false, fmt.Errorf("gov.br returned status %d", resp.StatusCode)
+    }
+
+    var result struct {
+        Valid bool `json:"valid"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return false, fmt.Errorf("failed to decode gov.br response: %w", err)
+    }
+    return result.Valid, nil
+}
+
+// CachingVerifier wraps a GovernmentVerifier with a result cache and a client
+// side rate limit so repeated uploads never exceed the gov.br quota
+type CachingVerifier struct {
+    next    GovernmentVerifier
+    limiter *rate.Limiter
+    cache   *ttlCache[bool]
+}
+
+// NewCachingVerifier creates a rate limited, caching verifier
+func NewCachingVerifier(cfg *config.Config, next GovernmentVerifier) (*CachingVerifier, error) {
+    if cfg == nil || next == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CachingVerifier{
+        next:    next,
+        limiter: rate.NewLimiter(rate.Limit(cfg.GovBRConfig.RequestsPerSecond), cfg.GovBRConfig.Burst),
+        cache:   newTTLCache[bool](cfg.GovBRConfig.CacheTTL),
+    }, nil
+}
+
+// VerifyCode returns a cached outcome when available; only definitive answers are cached
+func (v *CachingVerifier) VerifyCode(ctx context.Context, kind, code string) (bool, error) {
+    key := kind + ":" + code
+    if valid, ok := v.cache.Get(key); ok {
+        govbrVerifications.WithLabelValues(kind, "cached").Inc()
+        return valid, nil
+    }
+
+    if err := v.limiter.Wait(ctx); err != nil {
+        return false, fmt.Errorf("gov.br rate limit wait aborted: %w", err)
+    }
+
+    valid, err := v.next.VerifyCode(ctx, kind, code)
+    if err != nil {
+        govbrVerifications.WithLabelValues(kind, "error").Inc()
+        return false, err
+    }
+    if valid {
+        govbrVerifications.WithLabelValues(kind, "valid").Inc()
+    } else {
+        govbrVerifications.WithLabelValues(kind, "invalid").Inc()
+    }
+
+    v.cache.Set(key, valid)
+    return valid, nil
+}
+
+// GovBRStep validates the verification code printed on CNH-e and CRLV-e PDFs
+type GovBRStep struct {
+    verifier      GovernmentVerifier
+    documentTypes map[string]bool
+}
+
+// NewGovBRStep creates a new gov.br verification step
+func NewGovBRStep(cfg *config.Config, verifier GovernmentVerifier) (*GovBRStep, error) {
+    if cfg == nil || verifier == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    documentTypes := make(map[string]bool, len(cfg.GovBRConfig.DocumentTypes))
+    for _, documentType := range cfg.GovBRConfig.DocumentTypes {
+        documentTypes[documentType] = true
+    }
+
+    return &GovBRStep{
+        verifier:      verifier,
+        documentTypes: documentTypes,
+    }, nil
+}
+
+// Name returns the step name
+func (s *GovBRStep) Name() string {
+    return StepGovBR
+}
+
+// Applies reports whether the document is a PDF of a type issued through gov.br
+func (s *GovBRStep) Applies(doc *models.Document) bool {
+    return doc.ContentType == "application/pdf" && s.documentTypes[doc.DocumentType]
+}
+
+// Execute finds the verification code and records the gov.br outcome; PDFs
+// that are not CNH-e or CRLV-e are skipped
+func (s *GovBRStep) Execute(ctx context.Context, run *PipelineRun) error {
+    // Digitally issued PDFs carry a text layer, so fall back to it without OCR
+    text := run.OCRText
+    if text == "" {
+        var err error
+        if text, err = pdfTextLayer(ctx, run.Content); err != nil {
+            return err
+        }
+    }
+
+    kind := detectGovDocumentKind(text)
+    if kind == "" {
+        return nil
+    }
+
+    code := extractVerificationCode(text)
+    if code == "" {
+        return ErrVerificationCodeNotFound
+    }
+
+    verified, err := s.verifier.VerifyCode(ctx, kind, code)
+    if err != nil {
+        return err
+    }
+
+    run.Document.SetGovernmentVerification(verified, time.Now())
+    return nil
+}
+
+// pdfTextLayer extracts the text layer of every page of a PDF. Content streams
+// are usually compressed, so the raw bytes never hold the printed text
+func pdfTextLayer(ctx context.Context, content []byte) (string, error) {
+    pdf, err := fitz.NewFromMemory(content)
+    if err != nil {
+        return "", fmt.Errorf("failed to open PDF: %w", err)
+    }
+    defer pdf.Close()
+
+    var text strings.Builder
+    for i := 0; i < pdf.NumPage(); i++ {
+        if err := ctx.Err(); err != nil {
+            return "", err
+        }
+        page, err := pdf.Text(i)
+        if err != nil {
+            return "", fmt.Errorf("failed to extract text of page %d: %w", i+1, err)
+        }
+        text.WriteString(page)
+        text.WriteString("\n")
+    }
+    return text.String(), nil
+}
+
+// detectGovDocumentKind identifies CNH-e and CRLV-e documents by their titles
+func detectGovDocumentKind(text string) string {
+    upper := strings.ToUpper(text)
+    switch {
+    case strings.Contains(upper, "CARTEIRA NACIONAL DE HABILITA"):
+        return GovDocumentCNH
+    case strings.Contains(upper, "CERTIFICADO DE REGISTRO E LICENCIAMENTO"):
+        return GovDocumentCRLV
+    default:
+        return ""
+    }
+}
+
+// extractVerificationCode returns the digits of the printed validation or security code
+func extractVerificationCode(text string) string {
+    match := verificationCodePattern.FindStringSubmatch(text)
+    if match == nil {
+        return ""
+    }
+    return digitsOnly(match[1])
+}
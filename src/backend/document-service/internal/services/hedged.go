@@ -0,0 +1,191 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/url"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// HedgedStore cuts tail latency of idempotent reads: when a Stat or Get has not
+// answered after the hedge delay, an identical request is sent and the first
+// successful response wins while the other one is cancelled. Get is hedged up to
+// the response headers; writes and deletes are passed through unchanged
+type HedgedStore struct {
+    store ObjectStore
+    delay time.Duration
+}
+
+// NewHedgedStore wraps store with hedged reads
+func NewHedgedStore(store ObjectStore, cfg config.HedgingConfig) *HedgedStore {
+    return &HedgedStore{
+        store: store,
+        delay: cfg.Delay,
+    }
+}
+
+// Name returns the backend name used in metrics
+func (s *HedgedStore) Name() string {
+    return s.store.Name()
+}
+
+// Warm warms the wrapped store
+func (s *HedgedStore) Warm(ctx context.Context, connections int) error {
+    if warmable, ok := s.store.(warmableStore); ok {
+        return warmable.Warm(ctx, connections)
+    }
+    return nil
+}
+
+// Put writes through to the wrapped store
+func (s *HedgedStore) Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+    return s.store.Put(ctx, key, content, contentType, metadata)
+}
+
+// Delete deletes through the wrapped store
+func (s *HedgedStore) Delete(ctx context.Context, key string) error {
+    return s.store.Delete(ctx, key)
+}
+
+// Stat reads object metadata with hedging
+func (s *HedgedStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    info, cancel, err := hedge(ctx, s.delay, "stat",
+        func(ctx context.Context) (ObjectInfo, error) {
+            return s.store.Stat(ctx, key)
+        },
+        func(ObjectInfo) {},
+    )
+    cancel()
+    return info, err
+}
+
+// Get opens an object with hedging; the winning request stays alive until the
+// returned reader is closed
+func (s *HedgedStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    reader, cancel, err := hedge(ctx, s.delay, "get",
+        func(ctx context.Context) (io.ReadCloser, error) {
+            return s.store.Get(ctx, key)
+        },
+        func(reader io.ReadCloser) { reader.Close() },
+    )
+    if err != nil {
+        cancel()
+        return nil, err
+    }
+    return &cancelOnClose{ReadCloser: reader, cancel: cancel}, nil
+}
+
+// Open opens an object for random access without hedging; ranged reads of an
+// opened object are issued as the caller seeks
+func (s *HedgedStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    return openObject(ctx, s.store, key)
+}
+
+// PresignGet presigns a download from the wrapped store
+func (s *HedgedStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (*url.URL, error) {
+    return presignObject(ctx, s.store, key, expiry)
+}
+
+// cancelOnClose releases the context of a hedged read once its body is consumed
+type cancelOnClose struct {
+    io.ReadCloser
+    cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+    err := r.ReadCloser.Close()
+    r.cancel()
+    return err
+}
+
+// hedgedResult is the outcome of one of the requests of a hedged call
+type hedgedResult[T any] struct {
+    value T
+    err   error
+    hedge bool
+}
+
+// hedge runs call, starting a second identical call when the first has not
+// answered after delay. It returns the first success, or the primary's error
+// once every started call failed; a missing object is a definitive answer. The
+// returned cancel releases the winning call, discard releases values of
+// successful calls that lost the race
+func hedge[T any](ctx context.Context, delay time.Duration, operation string, call func(ctx context.Context) (T, error), discard func(T)) (T, context.CancelFunc, error) {
+    results := make(chan hedgedResult[T], 2)
+    cancels := make(map[bool]context.CancelFunc, 2)
+    launch := func(isHedge bool) {
+        callCtx, cancel := context.WithCancel(ctx)
+        cancels[isHedge] = cancel
+        go func() {
+            value, err := call(callCtx)
+            results <- hedgedResult[T]{value: value, err: err, hedge: isHedge}
+        }()
+    }
+
+    launch(false)
+    timer := time.NewTimer(delay)
+    defer timer.Stop()
+
+    var (
+        result  hedgedResult[T]
+        primary hedgedResult[T]
+        hedged  bool
+        pending = 1
+    )
+    for pending > 0 {
+        select {
+        case <-timer.C:
+            hedged = true
+            pending++
+            launch(true)
+            continue
+        case result = <-results:
+            pending--
+        }
+        if !result.hedge {
+            primary = result
+        }
+
+        // A failure before the hedge fired is left to the caller's retries
+        if result.err == nil || errors.Is(result.err, ErrObjectNotFound) || !hedged {
+            break
+        }
+    }
+
+    // The hedge failing last does not hide why the primary failed
+    if result.hedge && result.err != nil && !errors.Is(result.err, ErrObjectNotFound) && primary.err != nil {
+        result = primary
+    }
+
+    // Cancel the loser and release whatever it still returns
+    for isHedge, cancel := range cancels {
+        if isHedge != result.hedge {
+            cancel()
+        }
+    }
+    if pending > 0 {
+        go func(pending int) {
+            for ; pending > 0; pending-- {
+                if loser := <-results; loser.err == nil {
+                    discard(loser.value)
+                }
+            }
+        }(pending)
+    }
+
+    outcome := "not_hedged"
+    switch {
+    case hedged && result.err != nil && !errors.Is(result.err, ErrObjectNotFound):
+        outcome = "both_failed"
+    case hedged && result.hedge:
+        outcome = "hedge_won"
+    case hedged:
+        outcome = "primary_won"
+    }
+    storageHedgedReads.WithLabelValues(operation, outcome).Inc()
+
+    return result.value, cancels[result.hedge], result.err
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+    "context"
+    "crypto"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rsa"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math/big"
+    "net/http"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    // maxTokenLength bounds the work spent on a presented token
+    maxTokenLength = 8192
+    // maxJWKSSize bounds the key set read from the identity provider
+    maxJWKSSize = 1 << 20
+    // jwksRetryInterval bounds how often an unknown key ID triggers a fetch
+    jwksRetryInterval = 30 * time.Second
+    // minRSAKeyBits is the smallest RSA key accepted from the key set
+    minRSAKeyBits = 2048
+)
+
+var (
+    ErrTokenInvalid = errors.New("invalid identity token")
+    ErrTokenExpired = errors.New("identity token expired")
+)
+
+// tokenClaims are the registered claims of an identity token
+type tokenClaims struct {
+    ID        string      `json:"jti"`
+    Issuer    string      `json:"iss"`
+    Subject   string      `json:"sub"`
+    Audience  audience    `json:"aud"`
+    ExpiresAt int64       `json:"exp"`
+    NotBefore int64       `json:"nbf"`
+    Actor     *actorClaim `json:"act"`
+}
+
+// GatewayTokens verifies the tokens of end users the gateway forwards in
+// Authorization. They are the verified source of who the user is and which
+// role they hold: the gateway authenticates the user but the service checks
+// the token again rather than trust headers any caller can set
+type GatewayTokens struct {
+    cfg      config.GatewayAuthConfig
+    provider *identityProvider
+}
+
+// NewGatewayTokens creates the verifier of gateway tokens, or returns nil
+// when gateway authentication is disabled
+func NewGatewayTokens(cfg *config.Config, logger *zap.Logger) (*GatewayTokens, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.GatewayAuthConfig.Enabled {
+        return nil, nil
+    }
+
+    gateway := cfg.GatewayAuthConfig
+    return &GatewayTokens{
+        cfg: gateway,
+        provider: newIdentityProvider(gateway.Issuer, gateway.Audience, gateway.JWKSURL,
+            gateway.JWKSRefresh, gateway.Timeout, gateway.ClockSkew, logger.With(zap.String("component", "gateway_auth"))),
+    }, nil
+}
+
+// Verify checks the signature, issuer, audience and validity of a gateway
+// token and returns the user it identifies. A role claim that is missing or
+// not a string leaves the role empty, which role checks treat as the most
+// restricted
+func (t *GatewayTokens) Verify(ctx context.Context, token string) (*models.UserIdentity, error) {
+    claims, custom, err := t.provider.verify(ctx, token)
+    if err != nil {
+        gatewayTokens.WithLabelValues("rejected").Inc()
+        return nil, err
+    }
+    gatewayTokens.WithLabelValues("accepted").Inc()
+
+    identity := &models.UserIdentity{
+        Subject:   claims.Subject,
+        TokenID:   claims.ID,
+        ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+    }
+    identity.TenantID, _ = custom[t.cfg.TenantClaim].(string)
+    identity.Role, _ = custom[t.cfg.RoleClaim].(string)
+    identity.SessionID, _ = custom[t.cfg.SessionClaim].(string)
+    return identity, nil
+}
+
+// identityProvider verifies the JWTs an identity provider issues: signed
+// with RS256 or ES256 under a key of the provider's JWKS, which is cached and
+// refetched when stale or when a token names a key not seen yet, by the
+// expected issuer for the expected audience, and currently valid
+type identityProvider struct {
+    issuer      string
+    audience    string
+    jwksURL     string
+    jwksRefresh time.Duration
+    clockSkew   time.Duration
+    httpClient  *http.Client
+    logger      *zap.Logger
+
+    mu          sync.RWMutex
+    keys        map[string]crypto.PublicKey
+    fetchedAt   time.Time
+    attemptedAt time.Time
+}
+
+func newIdentityProvider(issuer, audience, jwksURL string, jwksRefresh, timeout, clockSkew time.Duration, logger *zap.Logger) *identityProvider {
+    return &identityProvider{
+        issuer:      issuer,
+        audience:    audience,
+        jwksURL:     jwksURL,
+        jwksRefresh: jwksRefresh,
+        clockSkew:   clockSkew,
+        httpClient:  &http.Client{Timeout: timeout},
+        logger:      logger,
+        keys:        make(map[string]crypto.PublicKey),
+    }
+}
+
+// verify checks the signature, issuer, audience and validity of a token and
+// returns its registered claims along with all of its claims
+func (p *identityProvider) verify(ctx context.Context, token string) (*tokenClaims, map[string]interface{}, error) {
+    if len(token) > maxTokenLength {
+        return nil, nil, ErrTokenInvalid
+    }
+    parts := strings.Split(token, ".")
+    if len(parts) != 3 {
+        return nil, nil, ErrTokenInvalid
+    }
+
+    var header struct {
+        Alg string `json:"alg"`
+        Kid string `json:"kid"`
+    }
+    if err := decodeSegment(parts[0], &header); err != nil {
+        return nil, nil, ErrTokenInvalid
+    }
+    signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+    if err != nil {
+        return nil, nil, ErrTokenInvalid
+    }
+    key, err := p.key(ctx, header.Kid)
+    if err != nil {
+        return nil, nil, err
+    }
+    if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+        return nil, nil, err
+    }
+
+    var claims tokenClaims
+    if err := decodeSegment(parts[1], &claims); err != nil {
+        return nil, nil, ErrTokenInvalid
+    }
+    var custom map[string]interface{}
+    if err := decodeSegment(parts[1], &custom); err != nil {
+        return nil, nil, ErrTokenInvalid
+    }
+
+    now := time.Now()
+    switch {
+    case claims.Issuer != p.issuer:
+        return nil, nil, fmt.Errorf("%w: unexpected issuer", ErrTokenInvalid)
+    case !slices.Contains(claims.Audience, p.audience):
+        return nil, nil, fmt.Errorf("%w: issued for another audience", ErrTokenInvalid)
+    case claims.Subject == "":
+        return nil, nil, fmt.Errorf("%w: no subject", ErrTokenInvalid)
+    case claims.ExpiresAt == 0 || now.Add(-p.clockSkew).Unix() >= claims.ExpiresAt:
+        return nil, nil, ErrTokenExpired
+    case claims.NotBefore != 0 && now.Add(p.clockSkew).Unix() < claims.NotBefore:
+        return nil, nil, fmt.Errorf("%w: not valid yet", ErrTokenInvalid)
+    }
+    return &claims, custom, nil
+}
+
+func (p *identityProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+    p.mu.RLock()
+    key, ok := p.keys[kid]
+    fresh := time.Since(p.fetchedAt) < p.jwksRefresh
+    p.mu.RUnlock()
+    if ok && fresh {
+        return key, nil
+    }
+
+    if err := p.refresh(ctx); err != nil {
+        p.logger.Warn("Failed to fetch identity provider keys", zap.Error(err))
+    }
+
+    p.mu.RLock()
+    defer p.mu.RUnlock()
+    if key, ok := p.keys[kid]; ok {
+        return key, nil
+    }
+    return nil, fmt.Errorf("%w: unknown signing key", ErrTokenInvalid)
+}
+
+// refresh fetches the key set, at most once per retry interval. Keys of a
+// failed fetch are kept, so an unreachable provider does not lock callers out
+func (p *identityProvider) refresh(ctx context.Context) error {
+    p.mu.Lock()
+    if time.Since(p.attemptedAt) < jwksRetryInterval {
+        p.mu.Unlock()
+        return nil
+    }
+    p.attemptedAt = time.Now()
+    p.mu.Unlock()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.jwksURL, nil)
+    if err != nil {
+        return fmt.Errorf("failed to build JWKS request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+    resp, err := p.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("JWKS request failed: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+    }
+    data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+    if err != nil {
+        return fmt.Errorf("failed to read JWKS: %w", err)
+    }
+    keys, err := ParseJWKS(data)
+    if err != nil {
+        return err
+    }
+
+    p.mu.Lock()
+    p.keys = keys
+    p.fetchedAt = time.Now()
+    p.mu.Unlock()
+    return nil
+}
+
+// audience is the aud claim, a single string or an array of strings
+type audience []string
+
+// UnmarshalJSON accepts both forms of the aud claim
+func (a *audience) UnmarshalJSON(data []byte) error {
+    var single string
+    if err := json.Unmarshal(data, &single); err == nil {
+        *a = audience{single}
+        return nil
+    }
+    var many []string
+    if err := json.Unmarshal(data, &many); err != nil {
+        return err
+    }
+    *a = many
+    return nil
+}
+
+// jsonWebKey is a public key of the identity provider's key set
+type jsonWebKey struct {
+    KeyType string `json:"kty"`
+    KeyID   string `json:"kid"`
+    Use     string `json:"use"`
+    Alg     string `json:"alg"`
+    N       string `json:"n"`
+    E       string `json:"e"`
+    Curve   string `json:"crv"`
+    X       string `json:"x"`
+    Y       string `json:"y"`
+}
+
+// ParseJWKS parses the RS256 and ES256 signing keys of a JSON Web Key Set by
+// key ID. Keys of other types, for other uses or algorithms, or too weak are
+// skipped
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+    var set struct {
+        Keys []jsonWebKey `json:"keys"`
+    }
+    if err := json.Unmarshal(data, &set); err != nil {
+        return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+    }
+
+    keys := make(map[string]crypto.PublicKey)
+    for _, jwk := range set.Keys {
+        if jwk.Use != "" && jwk.Use != "sig" {
+            continue
+        }
+        switch jwk.KeyType {
+        case "RSA":
+            n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+            e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+            if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 || (jwk.Alg != "" && jwk.Alg != "RS256") {
+                continue
+            }
+            key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+            if key.N.BitLen() >= minRSAKeyBits {
+                keys[jwk.KeyID] = key
+            }
+        case "EC":
+            x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+            y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+            if jwk.Curve != "P-256" || errX != nil || errY != nil || (jwk.Alg != "" && jwk.Alg != "ES256") {
+                continue
+            }
+            key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+            if key.Curve.IsOnCurve(key.X, key.Y) {
+                keys[jwk.KeyID] = key
+            }
+        }
+    }
+    return keys, nil
+}
+
+// verifySignature checks a JWS signature over signed with the key, which must
+// suit the algorithm
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+    digest := sha256.Sum256([]byte(signed))
+    switch alg {
+    case "RS256":
+        if key, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil {
+            return nil
+        }
+    case "ES256":
+        if key, ok := key.(*ecdsa.PublicKey); ok && len(signature) == 64 {
+            r := new(big.Int).SetBytes(signature[:32])
+            s := new(big.Int).SetBytes(signature[32:])
+            if ecdsa.Verify(key, digest[:], r, s) {
+                return nil
+            }
+        }
+    }
+    return fmt.Errorf("%w: bad signature", ErrTokenInvalid)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+    data, err := base64.RawURLEncoding.DecodeString(segment)
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, v)
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net/http"
+    "regexp"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    TopicImpersonationEnded = "impersonation.ended"
+
+    // impersonationTokenSize is the random session token presented by the
+    // operator on every impersonated request
+    impersonationTokenSize = 32
+    // impersonationRetention is how long ended sessions remain available
+    impersonationRetention = 24 * time.Hour
+)
+
+var (
+    ErrImpersonationDisabled    = errors.New("impersonation is disabled")
+    ErrImpersonationNotAllowed  = errors.New("role may not impersonate")
+    ErrImpersonationTicket      = errors.New("ticket reference is invalid")
+    ErrJustificationTooShort    = errors.New("justification is too short")
+    ErrImpersonationDuration    = errors.New("impersonation duration exceeds the maximum")
+    ErrSelfImpersonation        = errors.New("operators cannot impersonate themselves")
+    ErrImpersonationNotFound    = errors.New("impersonation session not found")
+    ErrImpersonationEnded       = errors.New("impersonation session has ended")
+    ErrImpersonationNotOperator = errors.New("impersonation session belongs to another operator")
+)
+
+// ImpersonationRequest opens an impersonation session
+type ImpersonationRequest struct {
+    OperatorID    string
+    OperatorRole  string
+    SubjectID     string
+    Ticket        string
+    Justification string
+    Duration      time.Duration
+}
+
+// ImpersonationNotice tells a beneficiary that support staff acted on their
+// behalf, why, and what was done
+type ImpersonationNotice struct {
+    SessionID     string                      `json:"session_id"`
+    SubjectID     string                      `json:"subject_id"`
+    OperatorID    string                      `json:"operator_id"`
+    Ticket        string                      `json:"ticket"`
+    Justification string                      `json:"justification"`
+    StartedAt     time.Time                   `json:"started_at"`
+    EndedAt       time.Time                   `json:"ended_at"`
+    Actions       []models.ImpersonatedAction `json:"actions"`
+}
+
+// ImpersonationService opens, authorizes and closes impersonation sessions.
+// Sessions are identified by a random token known only to the operator, and
+// only the operator who opened a session can use it. Like the in-memory
+// document repository sessions are local to the instance
+type ImpersonationService struct {
+    mu         sync.Mutex
+    cfg        config.ImpersonationConfig
+    ticket     *regexp.Regexp
+    sessions   map[string]*models.ImpersonationSession
+    tokens     map[string]string
+    outbox     repository.OutboxRepository
+    httpClient *http.Client
+    logger     *zap.Logger
+}
+
+// NewImpersonationService creates the service; notices to beneficiaries are
+// queued in the outbox when a session ends or expires
+func NewImpersonationService(cfg *config.Config, outbox repository.OutboxRepository, logger *zap.Logger) (*ImpersonationService, error) {
+    if cfg == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    ticket, err := regexp.Compile(cfg.ImpersonationConfig.TicketPattern)
+    if err != nil {
+        return nil, fmt.Errorf("invalid impersonation ticket pattern: %w", err)
+    }
+
+    return &ImpersonationService{
+        cfg:      cfg.ImpersonationConfig,
+        ticket:   ticket,
+        sessions: make(map[string]*models.ImpersonationSession),
+        tokens:   make(map[string]string),
+        outbox:   outbox,
+        httpClient: &http.Client{
+            Timeout:   cfg.ImpersonationConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+        logger: logger.With(zap.String("component", "impersonation")),
+    }, nil
+}
+
+// Enabled reports whether impersonation sessions may be opened
+func (s *ImpersonationService) Enabled() bool {
+    return s != nil && s.cfg.Enabled
+}
+
+// Start opens a session after checking the operator's role, the ticket and the
+// justification. It returns the session and the token authorizing it, which
+// is not stored and cannot be retrieved again
+func (s *ImpersonationService) Start(ctx context.Context, req ImpersonationRequest) (*models.ImpersonationSession, string, error) {
+    if !s.Enabled() {
+        return nil, "", ErrImpersonationDisabled
+    }
+    if err := s.validate(req); err != nil {
+        impersonationSessions.WithLabelValues("rejected").Inc()
+        return nil, "", err
+    }
+
+    duration := req.Duration
+    if duration == 0 {
+        duration = s.cfg.DefaultDuration
+    }
+
+    raw := make([]byte, impersonationTokenSize)
+    if _, err := rand.Read(raw); err != nil {
+        return nil, "", fmt.Errorf("failed to generate impersonation token: %w", err)
+    }
+    token := base64.RawURLEncoding.EncodeToString(raw)
+
+    now := time.Now().UTC()
+    session := &models.ImpersonationSession{
+        ID:            uuid.New().String(),
+        OperatorID:    req.OperatorID,
+        OperatorRole:  req.OperatorRole,
+        SubjectID:     req.SubjectID,
+        SubjectRole:   s.cfg.SubjectRole,
+        Ticket:        req.Ticket,
+        Justification: strings.TrimSpace(req.Justification),
+        Status:        models.ImpersonationStatusActive,
+        StartedAt:     now,
+        ExpiresAt:     now.Add(duration),
+        Actions:       []models.ImpersonatedAction{},
+    }
+
+    s.mu.Lock()
+    s.sessions[session.ID] = session
+    s.tokens[impersonationTokenHash(token)] = session.ID
+    snapshot := copySession(session)
+    s.mu.Unlock()
+
+    impersonationSessions.WithLabelValues("started").Inc()
+    s.logger.Info("Impersonation session started",
+        zap.String("session_id", session.ID),
+        zap.String("operator_id", session.OperatorID),
+        zap.String("subject_id", session.SubjectID),
+        zap.String("ticket", session.Ticket),
+        zap.Time("expires_at", session.ExpiresAt),
+    )
+    return snapshot, token, nil
+}
+
+// mayImpersonate reports whether operators of role may open and use
+// sessions. A request without a role never may
+func (s *ImpersonationService) mayImpersonate(role string) bool {
+    return role != "" && slices.Contains(s.cfg.OperatorRoles, role)
+}
+
+func (s *ImpersonationService) validate(req ImpersonationRequest) error {
+    switch {
+    case !s.mayImpersonate(req.OperatorRole) || req.OperatorID == "":
+        return ErrImpersonationNotAllowed
+    case req.SubjectID == req.OperatorID:
+        return ErrSelfImpersonation
+    case !s.ticket.MatchString(req.Ticket):
+        return ErrImpersonationTicket
+    case len(strings.TrimSpace(req.Justification)) < s.cfg.MinJustificationLength:
+        return ErrJustificationTooShort
+    case req.Duration < 0 || req.Duration > s.cfg.MaxDuration:
+        return ErrImpersonationDuration
+    }
+    return nil
+}
+
+// Authorize returns the session a token opens when it is presented by the
+// operator who started it and the session is still active. The operator's
+// role is checked on every request, so an operator whose role was withdrawn
+// cannot keep using a session opened before
+func (s *ImpersonationService) Authorize(ctx context.Context, token, operatorID, operatorRole string) (*models.ImpersonationSession, error) {
+    if !s.Enabled() {
+        return nil, ErrImpersonationDisabled
+    }
+    if !s.mayImpersonate(operatorRole) {
+        return nil, ErrImpersonationNotAllowed
+    }
+
+    s.mu.Lock()
+    id, ok := s.tokens[impersonationTokenHash(token)]
+    if !ok {
+        s.mu.Unlock()
+        return nil, ErrImpersonationNotFound
+    }
+    session := s.sessions[id]
+    if session.OperatorID != operatorID {
+        s.mu.Unlock()
+        return nil, ErrImpersonationNotOperator
+    }
+    now := time.Now()
+    if !session.Active(now) {
+        notice := s.finish(session, models.ImpersonationStatusExpired, now)
+        s.mu.Unlock()
+        s.notify(ctx, notice)
+        return nil, ErrImpersonationEnded
+    }
+    snapshot := copySession(session)
+    s.mu.Unlock()
+
+    return snapshot, nil
+}
+
+// RecordAction adds a request made during the session to the actions reported
+// to the beneficiary
+func (s *ImpersonationService) RecordAction(sessionID string, action models.ImpersonatedAction) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if session, ok := s.sessions[sessionID]; ok {
+        session.Actions = append(session.Actions, action)
+    }
+    impersonatedActions.WithLabelValues(action.Method).Inc()
+}
+
+// Get returns a session
+func (s *ImpersonationService) Get(sessionID string) (*models.ImpersonationSession, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    session, ok := s.sessions[sessionID]
+    if !ok {
+        return nil, ErrImpersonationNotFound
+    }
+    return copySession(session), nil
+}
+
+// End closes a session before it expires; only its operator may end it
+func (s *ImpersonationService) End(ctx context.Context, sessionID, operatorID string) (*models.ImpersonationSession, error) {
+    s.mu.Lock()
+    session, ok := s.sessions[sessionID]
+    if !ok {
+        s.mu.Unlock()
+        return nil, ErrImpersonationNotFound
+    }
+    if session.OperatorID != operatorID {
+        s.mu.Unlock()
+        return nil, ErrImpersonationNotOperator
+    }
+    if session.Status != models.ImpersonationStatusActive {
+        s.mu.Unlock()
+        return nil, ErrImpersonationEnded
+    }
+    notice := s.finish(session, models.ImpersonationStatusEnded, time.Now())
+    snapshot := copySession(session)
+    s.mu.Unlock()
+
+    s.notify(ctx, notice)
+    return snapshot, nil
+}
+
+// Run expires sessions past their deadline, so the beneficiary is notified
+// even when the operator never ends the session, until the context is
+// cancelled
+func (s *ImpersonationService) Run(ctx context.Context) {
+    if !s.Enabled() {
+        return
+    }
+
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.expire(ctx, time.Now())
+        }
+    }
+}
+
+func (s *ImpersonationService) expire(ctx context.Context, now time.Time) {
+    var notices []*ImpersonationNotice
+
+    s.mu.Lock()
+    for id, session := range s.sessions {
+        switch {
+        case session.Status == models.ImpersonationStatusActive && !session.Active(now):
+            notices = append(notices, s.finish(session, models.ImpersonationStatusExpired, now))
+        case session.EndedAt != nil && now.Sub(*session.EndedAt) > impersonationRetention:
+            delete(s.sessions, id)
+        }
+    }
+    s.mu.Unlock()
+
+    for _, notice := range notices {
+        s.notify(ctx, notice)
+    }
+}
+
+// finish closes the session and revokes its token; callers hold the lock
+func (s *ImpersonationService) finish(session *models.ImpersonationSession, status string, at time.Time) *ImpersonationNotice {
+    endedAt := at.UTC()
+    if status == models.ImpersonationStatusExpired {
+        endedAt = session.ExpiresAt
+    }
+    session.Status = status
+    session.EndedAt = &endedAt
+    for hash, id := range s.tokens {
+        if id == session.ID {
+            delete(s.tokens, hash)
+        }
+    }
+    impersonationSessions.WithLabelValues(status).Inc()
+
+    return &ImpersonationNotice{
+        SessionID:     session.ID,
+        SubjectID:     session.SubjectID,
+        OperatorID:    session.OperatorID,
+        Ticket:        session.Ticket,
+        Justification: session.Justification,
+        StartedAt:     session.StartedAt,
+        EndedAt:       endedAt,
+        Actions:       append([]models.ImpersonatedAction(nil), session.Actions...),
+    }
+}
+
+// notify queues the notice to the beneficiary
+func (s *ImpersonationService) notify(ctx context.Context, notice *ImpersonationNotice) {
+    logger := s.logger.With(
+        zap.String("session_id", notice.SessionID),
+        zap.String("operator_id", notice.OperatorID),
+        zap.String("subject_id", notice.SubjectID),
+        zap.String("ticket", notice.Ticket),
+        zap.Int("actions", len(notice.Actions)),
+    )
+    logger.Info("Impersonation session ended")
+
+    msg, err := newOutboxMessage(TopicImpersonationEnded, notice.SessionID, notice)
+    if err == nil {
+        err = s.outbox.Enqueue(ctx, msg)
+    }
+    if err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        logger.Error("Failed to queue impersonation notice", zap.Error(err))
+    }
+}
+
+// Deliver is the outbox handler posting notices to the notification service
+func (s *ImpersonationService) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    return postOutboxMessage(ctx, s.httpClient, s.cfg.NotificationURL, msg)
+}
+
+func copySession(session *models.ImpersonationSession) *models.ImpersonationSession {
+    snapshot := *session
+    snapshot.Actions = append([]models.ImpersonatedAction(nil), session.Actions...)
+    return &snapshot
+}
+
+func impersonationTokenHash(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
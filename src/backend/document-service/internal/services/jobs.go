@@ -0,0 +1,231 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "os"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// JobCoordinator runs each background job on one instance at a time. Every
+// instance competes for the lock of each job it runs; the holder runs the job
+// and renews the lock, and the others retry on every renewal, taking over
+// once the holder releases the lock or dies. A holder whose lock was lost,
+// as when its database session dropped, only finds out on its next renewal
+// and may still be running when another instance starts the job. Each
+// acquisition therefore draws a new lease generation, and jobs call
+// CheckJobLease before every write so a superseded holder stops instead of
+// writing alongside the new one
+type JobCoordinator struct {
+    cfg        config.CoordinationConfig
+    instanceID string
+    locks      repository.JobLockRepository
+    logger     *zap.Logger
+
+    mu   sync.Mutex
+    jobs map[string]bool
+}
+
+// NewJobCoordinator creates the coordinator of this instance's background
+// jobs
+func NewJobCoordinator(cfg *config.Config, locks repository.JobLockRepository, logger *zap.Logger) (*JobCoordinator, error) {
+    if cfg == nil || locks == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    instanceID := cfg.CoordinationConfig.InstanceID
+    if instanceID == "" {
+        hostname, err := os.Hostname()
+        if err != nil {
+            hostname = "instance"
+        }
+        // Replicas may share a hostname, so the ID is made unique
+        instanceID = hostname + "-" + uuid.New().String()[:8]
+    }
+
+    return &JobCoordinator{
+        cfg:        cfg.CoordinationConfig,
+        instanceID: instanceID,
+        locks:      locks,
+        logger:     logger.With(zap.String("component", "jobs"), zap.String("instance_id", instanceID)),
+        jobs:       make(map[string]bool),
+    }, nil
+}
+
+// jobLeaseKey is the context key of the lease a job runs under
+type jobLeaseKey struct{}
+
+// jobLease is the lease generation a job was started under
+type jobLease struct {
+    locks      repository.JobLockRepository
+    job        string
+    owner      string
+    generation int64
+}
+
+// CheckJobLease returns the error of ctx, or repository.ErrJobLeaseLost once
+// the lease the job in ctx was started under was taken over by another
+// instance. Outside a coordinated job only the error of ctx is returned
+func CheckJobLease(ctx context.Context) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    lease, ok := ctx.Value(jobLeaseKey{}).(*jobLease)
+    if !ok {
+        return nil
+    }
+    if err := lease.locks.CheckLease(ctx, lease.job, lease.owner, lease.generation); err != nil {
+        if errors.Is(err, repository.ErrJobLeaseLost) {
+            jobLockAttempts.WithLabelValues(lease.job, "fenced").Inc()
+        }
+        return err
+    }
+    return nil
+}
+
+// InstanceID returns the name this instance holds job locks under
+func (j *JobCoordinator) InstanceID() string {
+    return j.instanceID
+}
+
+// Run runs the job while this instance holds its lock, until the context is
+// cancelled. The job is given a context cancelled when the lock is lost and
+// must return once it is, and passes it to CheckJobLease before writing
+func (j *JobCoordinator) Run(ctx context.Context, job string, run func(context.Context)) {
+    j.setRunning(job, false)
+    ticker := time.NewTicker(j.cfg.RenewInterval)
+    defer ticker.Stop()
+
+    var cancel context.CancelFunc
+    var done chan struct{}
+    stop := func() {
+        if cancel == nil {
+            return
+        }
+        cancel()
+        <-done
+        cancel = nil
+        j.setRunning(job, false)
+    }
+
+    for {
+        generation, held, err := j.locks.TryLock(ctx, job, j.instanceID)
+        switch {
+        case err != nil:
+            // A lock that cannot be renewed may already be free, so the job
+            // is stopped; another instance may have started it already, and
+            // the lease check fences what this one writes meanwhile
+            jobLockAttempts.WithLabelValues(job, "error").Inc()
+            j.logger.Error("Failed to lock job", zap.String("job", job), zap.Error(err))
+            stop()
+        case held && cancel == nil:
+            jobLockAttempts.WithLabelValues(job, "acquired").Inc()
+            j.logger.Info("Job lock acquired, running job", zap.String("job", job))
+            var jobCtx context.Context
+            jobCtx, cancel = context.WithCancel(context.WithValue(ctx, jobLeaseKey{}, &jobLease{
+                locks:      j.locks,
+                job:        job,
+                owner:      j.instanceID,
+                generation: generation,
+            }))
+            done = make(chan struct{})
+            go func(jobCtx context.Context, done chan struct{}) {
+                defer close(done)
+                run(jobCtx)
+            }(jobCtx, done)
+            j.setRunning(job, true)
+        case held:
+            jobLockAttempts.WithLabelValues(job, "renewed").Inc()
+        case cancel != nil:
+            jobLockAttempts.WithLabelValues(job, "lost").Inc()
+            j.logger.Warn("Job lock lost, stopping job", zap.String("job", job))
+            stop()
+        default:
+            jobLockAttempts.WithLabelValues(job, "busy").Inc()
+        }
+
+        select {
+        case <-ctx.Done():
+            wasRunning := cancel != nil
+            stop()
+            if wasRunning {
+                j.release(job)
+            }
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Status lists the jobs this instance runs or that any instance holds, with
+// their owners
+func (j *JobCoordinator) Status(ctx context.Context) ([]models.JobStatus, error) {
+    leases, err := j.locks.Leases(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    j.mu.Lock()
+    defer j.mu.Unlock()
+
+    // A lease not renewed for two intervals belongs to an instance that
+    // stopped without releasing it
+    staleBefore := time.Now().Add(-2 * j.cfg.RenewInterval)
+    statuses := make([]models.JobStatus, 0, len(leases)+len(j.jobs))
+    leased := make(map[string]bool, len(leases))
+    for _, lease := range leases {
+        leased[lease.Job] = true
+        acquiredAt, renewedAt := lease.AcquiredAt, lease.RenewedAt
+        statuses = append(statuses, models.JobStatus{
+            Job:        lease.Job,
+            Owner:      lease.Owner,
+            AcquiredAt: &acquiredAt,
+            RenewedAt:  &renewedAt,
+            Local:      lease.Owner == j.instanceID && j.jobs[lease.Job],
+            Stale:      renewedAt.Before(staleBefore),
+        })
+    }
+    for job := range j.jobs {
+        if !leased[job] {
+            statuses = append(statuses, models.JobStatus{Job: job})
+        }
+    }
+    sort.Slice(statuses, func(a, b int) bool {
+        return statuses[a].Job < statuses[b].Job
+    })
+    return statuses, nil
+}
+
+// release unlocks a job on shutdown so another instance takes it over
+// without waiting for this session to time out
+func (j *JobCoordinator) release(job string) {
+    ctx, cancel := context.WithTimeout(context.Background(), j.cfg.RenewInterval)
+    defer cancel()
+
+    if err := j.locks.Unlock(ctx, job, j.instanceID); err != nil {
+        j.logger.Error("Failed to release job lock", zap.String("job", job), zap.Error(err))
+        return
+    }
+    j.logger.Info("Job lock released", zap.String("job", job))
+}
+
+func (j *JobCoordinator) setRunning(job string, running bool) {
+    j.mu.Lock()
+    j.jobs[job] = running
+    j.mu.Unlock()
+
+    held := 0.0
+    if running {
+        held = 1
+    }
+    jobLockHeld.WithLabelValues(job).Set(held)
+}
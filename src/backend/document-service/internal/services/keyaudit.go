@@ -0,0 +1,246 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap"        // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// kmsOperationCached labels key uses served from the in-process key cache
+const kmsOperationCached = "cached"
+
+// KeyAuditService keeps the audit of data key uses: every encrypt and decrypt
+// is stored with the document, the principal it was made for and the KMS call
+// it required, and reported per key version with hourly anomaly thresholds
+type KeyAuditService struct {
+    cfg    config.KeyAuditConfig
+    events repository.KeyUsageRepository
+    logger *zap.Logger
+}
+
+// NewKeyAuditService creates a new key usage audit
+func NewKeyAuditService(cfg *config.Config, events repository.KeyUsageRepository, logger *zap.Logger) (*KeyAuditService, error) {
+    if cfg == nil || events == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &KeyAuditService{
+        cfg:    cfg.KeyAuditConfig,
+        events: events,
+        logger: logger,
+    }, nil
+}
+
+// Record stores a key use; it is installed as the utils key usage recorder.
+// A failure to store is logged rather than failing the encryption
+func (s *KeyAuditService) Record(ctx context.Context, event *models.KeyUsageEvent) {
+    event.ID = uuid.NewString()
+
+    kmsOperation := event.KMSOperation
+    if kmsOperation == "" {
+        kmsOperation = kmsOperationCached
+    }
+    outcome := models.ProcessingOutcomeSucceeded
+    if !event.Succeeded {
+        outcome = models.ProcessingOutcomeFailed
+    }
+    keyUsageEvents.WithLabelValues(event.Operation, kmsOperation, outcome).Inc()
+
+    if err := s.events.Append(context.WithoutCancel(ctx), event); err != nil {
+        s.logger.Error("Failed to record key usage",
+            zap.String("document_id", event.DocumentID),
+            zap.String("principal", event.Principal),
+            zap.String("operation", event.Operation),
+            zap.Error(err),
+        )
+    }
+}
+
+// Run removes events past the retention period every hour until the context
+// is cancelled
+func (s *KeyAuditService) Run(ctx context.Context) {
+    ticker := time.NewTicker(time.Hour)
+    defer ticker.Stop()
+
+    for {
+        if err := CheckJobLease(ctx); err != nil {
+            s.logger.Error("Key usage retention failed", zap.Error(err))
+        } else if removed, err := s.events.DeleteBefore(ctx, time.Now().Add(-s.cfg.Retention)); err != nil {
+            s.logger.Error("Key usage retention failed", zap.Error(err))
+        } else if removed > 0 {
+            s.logger.Info("Expired key usage events removed", zap.Int("removed", removed))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Events returns the key uses matching the filter, oldest first
+func (s *KeyAuditService) Events(ctx context.Context, filter repository.KeyUsageFilter) ([]*models.KeyUsageEvent, error) {
+    if !filter.To.IsZero() && !filter.From.Before(filter.To) {
+        return nil, ErrInvalidReportPeriod
+    }
+    return s.events.List(ctx, filter)
+}
+
+// keyVersionKey groups key uses into report entries
+type keyVersionKey struct {
+    keyID      string
+    keyVersion string
+}
+
+// hourlyKey counts key uses of a principal or key version within an hour
+type hourlyKey struct {
+    subject string
+    hour    time.Time
+}
+
+// Report aggregates the key uses in [from, to) per key version and flags the
+// hours in which a principal or key version exceeded a threshold
+func (s *KeyAuditService) Report(ctx context.Context, from, to time.Time) (*models.KeyUsageReport, error) {
+    if from.IsZero() || !from.Before(to) {
+        return nil, ErrInvalidReportPeriod
+    }
+
+    events, err := s.events.List(ctx, repository.KeyUsageFilter{From: from, To: to})
+    if err != nil {
+        return nil, fmt.Errorf("failed to list key usage: %w", err)
+    }
+
+    versions := make(map[keyVersionKey]*models.KeyVersionUsage)
+    documents := make(map[keyVersionKey]map[string]bool)
+    principals := make(map[keyVersionKey]map[string]bool)
+    grants := make(map[keyVersionKey]map[string]bool)
+
+    decrypts := make(map[hourlyKey]int)
+    spread := make(map[hourlyKey]map[string]bool)
+    failures := make(map[hourlyKey]int)
+    kmsCalls := make(map[hourlyKey]int)
+
+    for _, event := range events {
+        key := keyVersionKey{event.KeyID, event.KeyVersion}
+        usage, ok := versions[key]
+        if !ok {
+            usage = &models.KeyVersionUsage{
+                KeyID:      event.KeyID,
+                KeyVersion: event.KeyVersion,
+                Operations: make(map[string]int),
+                KMSCalls:   make(map[string]int),
+            }
+            versions[key] = usage
+            documents[key] = make(map[string]bool)
+            principals[key] = make(map[string]bool)
+            grants[key] = make(map[string]bool)
+        }
+
+        usage.Operations[event.Operation]++
+        if event.KMSOperation != "" {
+            usage.KMSCalls[event.KMSOperation]++
+        }
+        if !event.Succeeded {
+            usage.Failures++
+        }
+        if event.DocumentID != "" {
+            documents[key][event.DocumentID] = true
+        }
+        principals[key][event.Principal] = true
+        if event.GrantID != "" {
+            grants[key][event.GrantID] = true
+        }
+
+        hour := event.OccurredAt.UTC().Truncate(time.Hour)
+        byPrincipal := hourlyKey{event.Principal, hour}
+        if event.Operation == models.KeyOperationDecrypt {
+            decrypts[byPrincipal]++
+            if event.DocumentID != "" {
+                if spread[byPrincipal] == nil {
+                    spread[byPrincipal] = make(map[string]bool)
+                }
+                spread[byPrincipal][event.DocumentID] = true
+            }
+        }
+        if !event.Succeeded {
+            failures[byPrincipal]++
+        }
+        if event.KMSOperation != "" {
+            kmsCalls[hourlyKey{event.KeyVersion, hour}]++
+        }
+    }
+
+    report := &models.KeyUsageReport{
+        From:        from,
+        To:          to,
+        GeneratedAt: time.Now(),
+        Events:      len(events),
+        KeyVersions: make([]models.KeyVersionUsage, 0, len(versions)),
+        Anomalies:   make([]models.KeyUsageAnomaly, 0),
+    }
+    for key, usage := range versions {
+        usage.Documents = len(documents[key])
+        usage.Principals = len(principals[key])
+        usage.Grants = len(grants[key])
+        report.KeyVersions = append(report.KeyVersions, *usage)
+    }
+    sort.Slice(report.KeyVersions, func(i, j int) bool {
+        a, b := report.KeyVersions[i], report.KeyVersions[j]
+        if a.KeyID != b.KeyID {
+            return a.KeyID < b.KeyID
+        }
+        return a.KeyVersion < b.KeyVersion
+    })
+
+    thresholds := s.cfg.Thresholds
+    flag := func(anomaly string, key hourlyKey, count, threshold int) {
+        if threshold <= 0 || count <= threshold {
+            return
+        }
+        entry := models.KeyUsageAnomaly{
+            Type:      anomaly,
+            Hour:      key.hour,
+            Count:     count,
+            Threshold: threshold,
+        }
+        if anomaly == models.KeyAnomalyKMSCallVolume {
+            entry.KeyVersion = key.subject
+        } else {
+            entry.Principal = key.subject
+        }
+        report.Anomalies = append(report.Anomalies, entry)
+    }
+    for key, count := range decrypts {
+        flag(models.KeyAnomalyDecryptVolume, key, count, thresholds.DecryptsPerPrincipal)
+    }
+    for key, docs := range spread {
+        flag(models.KeyAnomalyDocumentSpread, key, len(docs), thresholds.DocumentsPerPrincipal)
+    }
+    for key, count := range failures {
+        flag(models.KeyAnomalyFailures, key, count, thresholds.FailuresPerPrincipal)
+    }
+    for key, count := range kmsCalls {
+        flag(models.KeyAnomalyKMSCallVolume, key, count, thresholds.KMSCallsPerKeyVersion)
+    }
+    sort.Slice(report.Anomalies, func(i, j int) bool {
+        a, b := report.Anomalies[i], report.Anomalies[j]
+        if !a.Hour.Equal(b.Hour) {
+            return a.Hour.Before(b.Hour)
+        }
+        if a.Type != b.Type {
+            return a.Type < b.Type
+        }
+        return a.Principal+a.KeyVersion < b.Principal+b.KeyVersion
+    })
+    return report, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "time"
+    "unicode"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+const (
+    StepLanguage = "language"
+
+    // minLanguageWords is the number of function words below which a text
+    // is too short, or too much like a form, to tell its language
+    minLanguageWords = 5
+
+    libreTranslatePath = "/translate"
+)
+
+var (
+    ErrTranslationFailed = errors.New("machine translation failed")
+
+    // languageWords are frequent function words telling Portuguese, Spanish
+    // and English apart. Words common to two of them are left out
+    languageWords = map[string]map[string]bool{
+        "pt": wordSet("não", "são", "com", "da", "dos", "das", "em", "na", "nas", "ao", "às", "os", "é", "e", "um", "uma", "pelo", "pela", "você", "também", "mais", "seu", "sua", "nome", "nascimento", "exame"),
+        "es": wordSet("el", "la", "los", "las", "del", "y", "en", "es", "un", "con", "al", "su", "sus", "lo", "también", "más", "nombre", "fecha", "nacimiento", "examen"),
+        "en": wordSet("the", "and", "of", "to", "in", "is", "for", "with", "on", "this", "that", "by", "from", "was", "are", "be", "name", "date", "birth", "patient", "doctor"),
+    }
+)
+
+func wordSet(words ...string) map[string]bool {
+    set := make(map[string]bool, len(words))
+    for _, word := range words {
+        set[word] = true
+    }
+    return set
+}
+
+// DetectLanguage guesses the language of a text from the function words it
+// uses. Confidence is the share of the function words found that belong to
+// the language chosen; a text with too few of them is undetermined
+func DetectLanguage(text string) (models.DocumentLanguage, bool) {
+    counts := make(map[string]int, len(languageWords))
+    total := 0
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+        return !unicode.IsLetter(r)
+    })
+    for _, word := range words {
+        for language, set := range languageWords {
+            if set[word] {
+                counts[language]++
+                total++
+            }
+        }
+    }
+    if total < minLanguageWords {
+        return models.DocumentLanguage{}, false
+    }
+
+    best := ""
+    for language, count := range counts {
+        if best == "" || count > counts[best] || (count == counts[best] && language < best) {
+            best = language
+        }
+    }
+    return models.DocumentLanguage{Code: best, Confidence: float64(counts[best]) / float64(total)}, true
+}
+
+// Translator translates text between languages given as ISO 639-1 codes
+type Translator interface {
+    Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// LibreTranslateClient translates through a LibreTranslate server, which can
+// run next to the service so text never leaves the network
+type LibreTranslateClient struct {
+    baseURL    string
+    apiKey     string
+    timeout    time.Duration
+    httpClient *http.Client
+}
+
+// NewLibreTranslateClient creates the translation client, or returns nil
+// when translation is disabled
+func NewLibreTranslateClient(cfg *config.Config) (*LibreTranslateClient, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+    if !cfg.TranslationConfig.Enabled {
+        return nil, nil
+    }
+
+    return &LibreTranslateClient{
+        baseURL: strings.TrimSuffix(cfg.TranslationConfig.BaseURL, "/"),
+        apiKey:  cfg.TranslationConfig.APIKey,
+        timeout: cfg.TranslationConfig.Timeout,
+        httpClient: &http.Client{
+            Transport: NewHTTPTransport("translation", cfg.TranslationConfig.Transport),
+        },
+    }, nil
+}
+
+type libreTranslateRequest struct {
+    Q      string `json:"q"`
+    Source string `json:"source"`
+    Target string `json:"target"`
+    Format string `json:"format"`
+    APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+    TranslatedText string `json:"translatedText"`
+    Error          string `json:"error"`
+}
+
+// Translate sends text to the LibreTranslate server
+func (c *LibreTranslateClient) Translate(ctx context.Context, text, source, target string) (string, error) {
+    var translated string
+    err := utils.CallWithDeadline(ctx, config.DependencyTranslation, c.timeout, func(ctx context.Context) error {
+        var err error
+        translated, err = c.translate(ctx, text, source, target)
+        return err
+    })
+    return translated, err
+}
+
+func (c *LibreTranslateClient) translate(ctx context.Context, text, source, target string) (string, error) {
+    body, err := json.Marshal(libreTranslateRequest{Q: text, Source: source, Target: target, Format: "text", APIKey: c.apiKey})
+    if err != nil {
+        return "", fmt.Errorf("failed to build translation request: %w", err)
+    }
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+libreTranslatePath, bytes.NewReader(body))
+    if err != nil {
+        return "", fmt.Errorf("failed to build translation request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+
+    resp, err := c.httpClient.Do(req)
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+    }
+    defer resp.Body.Close()
+
+    var result libreTranslateResponse
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 4*int64(len(body))+4096)).Decode(&result); err != nil {
+        return "", fmt.Errorf("%w: status %d: %v", ErrTranslationFailed, resp.StatusCode, err)
+    }
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("%w: status %d: %s", ErrTranslationFailed, resp.StatusCode, result.Error)
+    }
+    return result.TranslatedText, nil
+}
+
+// LanguageStep records the language of the text OCR extracted. Text in one
+// of the translated languages is machine translated into the target language
+// for reviewers; only the redacted text is sent to the provider, and the
+// translation is stored as an encrypted rendition labeled as such
+type LanguageStep struct {
+    cfg        config.LanguageConfig
+    translator Translator
+    provider   string
+    sources    map[string]bool
+    maxChars   int
+    storage    *StorageService
+}
+
+// NewLanguageStep creates a new language detection step; translator may be
+// nil, in which case the language is only detected
+func NewLanguageStep(cfg *config.Config, translator Translator, storage *StorageService) (*LanguageStep, error) {
+    if cfg == nil || storage == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    step := &LanguageStep{
+        cfg:      cfg.LanguageConfig,
+        storage:  storage,
+        sources:  make(map[string]bool),
+        maxChars: cfg.TranslationConfig.MaxCharacters,
+    }
+    if translator != nil {
+        step.translator = translator
+        step.provider = cfg.TranslationConfig.Provider
+        for _, language := range cfg.TranslationConfig.SourceLanguages {
+            step.sources[language] = true
+        }
+    }
+    return step, nil
+}
+
+// Name returns the step name
+func (s *LanguageStep) Name() string {
+    return StepLanguage
+}
+
+// Provenance reports the translation provider, if any, and that the step
+// reads the OCR text
+func (s *LanguageStep) Provenance() StepProvenance {
+    return StepProvenance{Version: languageStepVersion, Provider: s.provider, Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether OCR extracts text from the document type
+func (s *LanguageStep) Applies(doc *models.Document) bool {
+    return ocrDocumentType(doc.DocumentType)
+}
+
+// Execute detects the language and translates the text when it is in a
+// language other than the target one that is configured for translation
+func (s *LanguageStep) Execute(ctx context.Context, run *PipelineRun) error {
+    language, ok := DetectLanguage(run.OCRText)
+    if !ok || language.Confidence < s.cfg.MinConfidence {
+        languageDetections.WithLabelValues("undetermined").Inc()
+        return nil
+    }
+    languageDetections.WithLabelValues(language.Code).Inc()
+    run.Document.SetLanguage(language)
+
+    if s.translator == nil || language.Code == s.cfg.TargetLanguage || !s.sources[language.Code] {
+        return nil
+    }
+    text := []rune(RedactText(run.OCRText))
+    truncated := len(text) > s.maxChars
+    if truncated {
+        text = text[:s.maxChars]
+    }
+
+    translated, err := s.translator.Translate(ctx, string(text), language.Code, s.cfg.TargetLanguage)
+    if err != nil {
+        translations.WithLabelValues(language.Code, "failed").Inc()
+        return err
+    }
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionOCRTextTranslated, "text/plain; charset=utf-8", []byte(translated)); err != nil {
+        return fmt.Errorf("failed to store translated text: %w", err)
+    }
+    translations.WithLabelValues(language.Code, "translated").Inc()
+    run.Document.SetTranslation(models.MachineTranslation{
+        Provider:       s.provider,
+        SourceLanguage: language.Code,
+        TargetLanguage: s.cfg.TargetLanguage,
+        TranslatedAt:   time.Now(),
+        Truncated:      truncated,
+    })
+    return nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+var (
+    ErrLargeDocumentQuota = errors.New("large document quota exceeded")
+    ErrNotQueued          = errors.New("document is not queued for processing")
+)
+
+// LargeDocumentQuotaError refuses a large document over its tenant's quota
+type LargeDocumentQuotaError struct {
+    // RetryAfter is when the tenant may upload a large document again
+    RetryAfter time.Duration
+}
+
+func (e *LargeDocumentQuotaError) Error() string {
+    return fmt.Sprintf("%v, retry after %s", ErrLargeDocumentQuota, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LargeDocumentQuotaError) Unwrap() error {
+    return ErrLargeDocumentQuota
+}
+
+// LargeDocuments accepts uploads past the size limit of their channel as
+// large documents. A tenant may upload a few of them per window, with no
+// burst allowance, and they are stored without running the processing steps
+// in the request: the document is queued_for_processing and processed in
+// the background, a batch at a time, oldest first. With an orchestrator the
+// steps already run outside the request and nothing is queued. Quota
+// accounts are kept per instance, like the soft quotas
+type LargeDocuments struct {
+    cfg       config.LargeDocumentConfig
+    pipeline  *DocumentPipeline
+    documents repository.DocumentRepository
+    logger    *zap.Logger
+
+    mu       sync.Mutex
+    accounts map[string]*models.QuotaAccount
+}
+
+// NewLargeDocuments creates the large document flow, or returns nil when it
+// is disabled
+func NewLargeDocuments(cfg *config.Config, pipeline *DocumentPipeline, documents repository.DocumentRepository, logger *zap.Logger) (*LargeDocuments, error) {
+    if cfg == nil || pipeline == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.LargeDocumentConfig.Enabled {
+        return nil, nil
+    }
+
+    return &LargeDocuments{
+        cfg:       cfg.LargeDocumentConfig,
+        pipeline:  pipeline,
+        documents: documents,
+        accounts:  make(map[string]*models.QuotaAccount),
+        logger:    logger.With(zap.String("component", "large_documents")),
+    }, nil
+}
+
+// MaxSize returns the largest document accepted. A nil *LargeDocuments
+// returns zero
+func (l *LargeDocuments) MaxSize() int64 {
+    if l == nil {
+        return 0
+    }
+    return l.cfg.MaxSize
+}
+
+// Admit counts a large document of the tenant against its quota, refusing
+// it with a *LargeDocumentQuotaError once the quota is used up
+func (l *LargeDocuments) Admit(tenantID string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    now := time.Now()
+    limits := models.QuotaLimits{Requests: l.cfg.Uploads, Window: l.cfg.Window}
+    account, ok := l.accounts[tenantID]
+    if !ok {
+        account = models.NewQuotaAccount(tenantID, limits, now)
+        l.accounts[tenantID] = account
+    }
+    if allowed, _ := account.Take(limits, now); !allowed {
+        largeDocuments.WithLabelValues("rejected").Inc()
+        return &LargeDocumentQuotaError{RetryAfter: account.RetryAfter(limits, now)}
+    }
+    largeDocuments.WithLabelValues("accepted").Inc()
+    return nil
+}
+
+// Run processes queued documents every interval until the context is
+// cancelled. It runs on one instance at a time
+func (l *LargeDocuments) Run(ctx context.Context) {
+    if l == nil {
+        return
+    }
+
+    ticker := time.NewTicker(l.cfg.Interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if _, err := l.ProcessBatch(ctx); err != nil {
+                l.logger.Error("Large document processing failed", zap.Error(err))
+            }
+        }
+    }
+}
+
+// ProcessBatch processes up to a batch of queued documents, oldest first. It
+// returns the number processed
+func (l *LargeDocuments) ProcessBatch(ctx context.Context) (int, error) {
+    docs, err := l.documents.ListUpdatedBetween(ctx, time.Time{}, time.Now().Add(time.Second))
+    if err != nil {
+        return 0, fmt.Errorf("failed to list documents: %w", err)
+    }
+    queued := make([]*models.Document, 0)
+    for _, doc := range docs {
+        if doc.Status == models.DocumentStatusQueued {
+            queued = append(queued, doc)
+        }
+    }
+    sort.Slice(queued, func(i, j int) bool {
+        return queued[i].CreatedAt.Before(queued[j].CreatedAt)
+    })
+    largeDocumentsQueued.Set(float64(len(queued)))
+
+    processed := 0
+    for _, doc := range queued[:min(len(queued), l.cfg.BatchSize)] {
+        if ctx.Err() != nil {
+            break
+        }
+        if err := CheckJobLease(ctx); err != nil {
+            return processed, err
+        }
+        if _, err := l.pipeline.ProcessQueued(ctx, doc.ID); err != nil {
+            if errors.Is(err, ErrNotQueued) || errors.Is(err, repository.ErrDocumentNotFound) {
+                continue
+            }
+            largeDocuments.WithLabelValues("failed").Inc()
+            l.logger.Warn("Failed to process large document",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+            continue
+        }
+        largeDocuments.WithLabelValues("processed").Inc()
+        processed++
+    }
+    largeDocumentsQueued.Set(float64(len(queued) - processed))
+    return processed, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "math"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// AdaptiveLimiter bounds in-flight calls to a dependency with an AIMD limit.
+// After every window of samples the limit is cut multiplicatively when the
+// window's p99 latency rises above the healthy baseline, exceeds the latency
+// target or calls time out, and grows by one when the dependency is healthy
+// and the limit was actually reached. A nil *AdaptiveLimiter does not limit
+type AdaptiveLimiter struct {
+    mu        sync.Mutex
+    name      string
+    limit     float64
+    minLimit  float64
+    maxLimit  float64
+    inFlight  int
+    saturated bool
+    notify    chan struct{}
+    now       func() time.Time
+
+    window       int
+    samples      []time.Duration
+    timeouts     int
+    baseline     time.Duration
+    tolerance    float64
+    target       time.Duration
+    backoffRatio float64
+}
+
+// NewAdaptiveLimiter creates a limiter for the named dependency, or nil when
+// adaptive concurrency is disabled
+func NewAdaptiveLimiter(name string, cfg *config.Config, limits config.AdaptiveLimitConfig) *AdaptiveLimiter {
+    if cfg == nil || !cfg.ConcurrencyConfig.Enabled {
+        return nil
+    }
+
+    l := &AdaptiveLimiter{
+        name:         name,
+        limit:        float64(limits.InitialLimit),
+        minLimit:     float64(limits.MinLimit),
+        maxLimit:     float64(limits.MaxLimit),
+        notify:       make(chan struct{}),
+        now:          time.Now,
+        window:       limits.Window,
+        samples:      make([]time.Duration, 0, limits.Window),
+        tolerance:    limits.LatencyTolerance,
+        target:       limits.LatencyTarget,
+        backoffRatio: limits.BackoffRatio,
+    }
+    l.export()
+    return l
+}
+
+// Acquire waits for an in-flight slot; the returned release must be called
+// with the outcome of the call once it completes
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (func(err error), error) {
+    if l == nil {
+        return func(error) {}, nil
+    }
+
+    for {
+        l.mu.Lock()
+        if l.inFlight < int(l.limit) {
+            l.inFlight++
+            if l.inFlight >= int(l.limit) {
+                l.saturated = true
+            }
+            l.export()
+            l.mu.Unlock()
+            break
+        }
+        wait := l.notify
+        l.mu.Unlock()
+
+        select {
+        case <-wait:
+        case <-ctx.Done():
+            adaptiveLimitRejections.WithLabelValues(l.name).Inc()
+            return nil, ctx.Err()
+        }
+    }
+
+    startTime := l.now()
+    return func(err error) {
+        l.release(l.now().Sub(startTime), err)
+    }, nil
+}
+
+// Limit returns the current in-flight limit
+func (l *AdaptiveLimiter) Limit() int {
+    if l == nil {
+        return 0
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return int(l.limit)
+}
+
+func (l *AdaptiveLimiter) release(latency time.Duration, err error) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    l.inFlight--
+    if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrOCRTimeout) {
+        l.timeouts++
+    }
+    l.samples = append(l.samples, latency)
+    if len(l.samples) >= l.window {
+        l.adjust()
+    }
+    l.export()
+
+    // Wake every waiter; those not fitting under the limit wait again
+    close(l.notify)
+    l.notify = make(chan struct{})
+}
+
+// adjust applies AIMD to the completed window; callers hold the lock
+func (l *AdaptiveLimiter) adjust() {
+    p99 := percentile(l.samples, 0.99)
+    degraded := l.timeouts > 0 ||
+        (l.target > 0 && p99 > l.target) ||
+        (l.baseline > 0 && float64(p99) > float64(l.baseline)*l.tolerance)
+
+    switch {
+    case degraded:
+        l.limit = math.Max(l.minLimit, math.Floor(l.limit*l.backoffRatio))
+    case l.saturated:
+        l.limit = math.Min(l.maxLimit, l.limit+1)
+    }
+
+    // The baseline learns from healthy windows so a slow degradation cannot
+    // drag it upwards; at the minimum limit it keeps learning so a lasting
+    // latency shift is eventually accepted instead of pinning the limit
+    if !degraded || l.limit <= l.minLimit {
+        if l.baseline == 0 {
+            l.baseline = p99
+        } else {
+            l.baseline = time.Duration(0.9*float64(l.baseline) + 0.1*float64(p99))
+        }
+    }
+
+    l.samples = l.samples[:0]
+    l.timeouts = 0
+    l.saturated = false
+}
+
+// export publishes the limit and in-flight count; callers hold the lock
+func (l *AdaptiveLimiter) export() {
+    adaptiveLimit.WithLabelValues(l.name).Set(l.limit)
+    adaptiveInFlight.WithLabelValues(l.name).Set(float64(l.inFlight))
+}
+
+// percentile returns the q-th percentile of the samples
+func percentile(samples []time.Duration, q float64) time.Duration {
+    if len(samples) == 0 {
+        return 0
+    }
+
+    sorted := append([]time.Duration(nil), samples...)
+    sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+    index := int(math.Ceil(q*float64(len(sorted)))) - 1
+    if index < 0 {
+        index = 0
+    }
+    return sorted[index]
+}
@@ -0,0 +1,16 @@
+//go:build testhooks
+
+package services
+
+import (
+    "time"
+)
+
+// UseClock makes the limiter measure latencies on now instead of the wall
+// clock, so tests drive its windows with chosen latencies. Only built with
+// the testhooks tag
+func (l *AdaptiveLimiter) UseClock(now func() time.Time) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    l.now = now
+}
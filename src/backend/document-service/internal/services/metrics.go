@@ -0,0 +1,1244 @@
+package services
+
+import (
+    "fmt"
+
+    "github.com/prometheus/client_golang/prometheus" // v1.17.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Service-level Prometheus metrics
+var (
+    pipelineStepDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "document_pipeline_step_duration_seconds",
+            Help:    "Duration of document pipeline steps in seconds",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"step"},
+    )
+
+    pipelineStepFailures = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_pipeline_step_failures_total",
+            Help: "Total number of failed document pipeline steps",
+        },
+        []string{"step"},
+    )
+
+    pipelineWorkflowsStarted = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_pipeline_workflows_started_total",
+            Help: "Total number of document pipeline workflows submitted to the orchestrator by result",
+        },
+        []string{"result"},
+    )
+
+    whatsappMessages = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "whatsapp_messages_total",
+            Help: "Total number of inbound WhatsApp media messages by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    sftpBatchFiles = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "sftp_batch_files_total",
+            Help: "Total number of SFTP batch manifest entries by reconciliation outcome",
+        },
+        []string{"outcome"},
+    )
+
+    outboxDeliveries = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "outbox_deliveries_total",
+            Help: "Total number of outbox delivery attempts by topic and resulting status",
+        },
+        []string{"topic", "status"},
+    )
+
+    signatureVerifications = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_signature_verifications_total",
+            Help: "Total number of embedded PDF signatures verified by result",
+        },
+        []string{"result"},
+    )
+
+    govbrVerifications = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "govbr_verifications_total",
+            Help: "Total number of gov.br verification code checks by document kind and result",
+        },
+        []string{"kind", "result"},
+    )
+
+    cpfSituationChecks = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "cpf_situation_checks_total",
+            Help: "Total number of CPF situation lookups by resulting situation (other for unrecognized ones), cached or error",
+        },
+        []string{"situation"},
+    )
+
+    screeningResults = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_screening_results_total",
+            Help: "Total number of screened identity documents by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    addressComparisons = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_address_comparisons_total",
+            Help: "Total number of proof-of-address comparisons against enrollments by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    autoDecisions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_auto_decisions_total",
+            Help: "Total number of rules engine evaluations by decision",
+        },
+        []string{"decision"},
+    )
+
+    experimentStepDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "document_experiment_step_duration_seconds",
+            Help:    "Duration of experimented pipeline steps in seconds by experiment and variant",
+            Buckets: prometheus.DefBuckets,
+        },
+        []string{"experiment", "variant"},
+    )
+
+    experimentStepFailures = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_experiment_step_failures_total",
+            Help: "Total number of failed experimented pipeline steps by experiment and variant",
+        },
+        []string{"experiment", "variant"},
+    )
+
+    featureFlagStates = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "feature_flag_enabled",
+            Help: "Effective state of each feature flag (1 enabled, 0 disabled)",
+        },
+        []string{"flag"},
+    )
+
+    featureFlagRefreshes = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "feature_flag_refreshes_total",
+            Help: "Total number of remote feature flag refreshes by source and result",
+        },
+        []string{"source", "result"},
+    )
+
+    storageShadowWrites = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_shadow_writes_total",
+            Help: "Total number of dual-writes during storage migration by backend and result",
+        },
+        []string{"backend", "result"},
+    )
+
+    storageShadowComparisons = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_shadow_comparisons_total",
+            Help: "Total number of shadow read comparisons during storage migration by result",
+        },
+        []string{"result"},
+    )
+
+    storageShadowRepairs = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_shadow_repairs_total",
+            Help: "Total number of objects copied between backends during storage migration by target backend and result",
+        },
+        []string{"backend", "result"},
+    )
+
+    httpClientConnections = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "http_client_connections_total",
+            Help: "Total number of connections obtained by outbound HTTP clients by whether they were reused",
+        },
+        []string{"client", "reused"},
+    )
+
+    httpClientDNSDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "http_client_dns_lookup_duration_seconds",
+            Help:    "Duration of DNS lookups made by outbound HTTP clients in seconds",
+            Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
+        },
+        []string{"client"},
+    )
+
+    httpClientTLSHandshakes = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "http_client_tls_handshakes_total",
+            Help: "Total number of TLS handshakes by outbound HTTP clients by whether the session was resumed",
+        },
+        []string{"client", "resumed"},
+    )
+
+    adaptiveLimit = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "adaptive_concurrency_limit",
+            Help: "Current adaptive in-flight limit by dependency",
+        },
+        []string{"dependency"},
+    )
+
+    adaptiveInFlight = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "adaptive_concurrency_in_flight",
+            Help: "Current number of in-flight calls by dependency",
+        },
+        []string{"dependency"},
+    )
+
+    adaptiveLimitRejections = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "adaptive_concurrency_rejections_total",
+            Help: "Total number of calls abandoned while waiting for an in-flight slot by dependency",
+        },
+        []string{"dependency"},
+    )
+
+    storageHedgedReads = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_hedged_reads_total",
+            Help: "Total number of hedge-eligible object reads by operation and outcome (not_hedged, primary_won, hedge_won, both_failed)",
+        },
+        []string{"operation", "outcome"},
+    )
+
+    ocrPages = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "ocr_pages_total",
+            Help: "Total number of PDF pages recognized separately by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    storageChecksumMismatches = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_checksum_mismatches_total",
+            Help: "Total number of uploads whose checksum did not match the local content by backend and check (content_md5, etag)",
+        },
+        []string{"backend", "check"},
+    )
+
+    secureViewerEvents = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "secure_viewer_events_total",
+            Help: "Total number of secure viewer audit events by event (page_view, screenshot_attempt, print_attempt, copy_attempt, focus_lost)",
+        },
+        []string{"event"},
+    )
+
+    analyticsExports = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "analytics_exports_total",
+            Help: "Total number of analytics partition exports by outcome (exported, skipped, failed)",
+        },
+        []string{"outcome"},
+    )
+
+    consentEvents = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "consent_events_total",
+            Help: "Total number of consent events applied by type",
+        },
+        []string{"type"},
+    )
+
+    consentHalts = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "consent_processing_halts_total",
+            Help: "Total number of document pipeline runs halted by a consent revocation",
+        },
+    )
+
+    encryptionScanObjects = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "encryption_scan_objects_total",
+            Help: "Total number of stored objects checked by the encryption-at-rest scanner by result",
+        },
+        []string{"result"},
+    )
+
+    reencryptions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_reencryptions_total",
+            Help: "Total number of document re-encryptions by result",
+        },
+        []string{"result"},
+    )
+
+    cancellationSagas = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "enrollment_cancellation_sagas_total",
+            Help: "Total number of enrollment cancellation saga runs by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    documentDispositions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_dispositions_total",
+            Help: "Total number of document dispositions of cancelled enrollments by disposition and result",
+        },
+        []string{"disposition", "result"},
+    )
+
+    bulkOperationDocuments = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "bulk_operation_documents_total",
+            Help: "Total number of documents processed by bulk operations by action and outcome",
+        },
+        []string{"action", "outcome"},
+    )
+
+    retentionNotices = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "retention_purge_notices_total",
+            Help: "Total number of purge notices sent to tenant admins",
+        },
+    )
+
+    retentionPurges = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "retention_purges_total",
+            Help: "Total number of documents purged after their retention by result",
+        },
+        []string{"result"},
+    )
+
+    retentionArtifactPurges = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "retention_artifact_purges_total",
+            Help: "Total number of extracted data and renditions purged before their document by kind",
+        },
+        []string{"kind"},
+    )
+
+    quotaRequests = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "quota_requests_total",
+            Help: "Total number of requests counted against tenant soft quotas by tenant and result",
+        },
+        []string{"tenant", "result"},
+    )
+
+    maintenanceRejections = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "maintenance_rejected_writes_total",
+            Help: "Total number of write requests refused during maintenance",
+        },
+    )
+
+    uploadReplayChecks = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "upload_replay_checks_total",
+            Help: "Total number of anonymous upload nonce checks by result",
+        },
+        []string{"result"},
+    )
+
+    downloadThrottleEvents = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "download_throttle_events_total",
+            Help: "Total number of downloads slowed by their bandwidth limit by role",
+        },
+        []string{"role"},
+    )
+
+    downloadThrottledSeconds = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "download_throttled_seconds_total",
+            Help: "Total time downloads waited on their bandwidth limit by role",
+        },
+        []string{"role"},
+    )
+
+    accessEvents = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_access_events_total",
+            Help: "Total number of client reported document access events by event and result",
+        },
+        []string{"event", "result"},
+    )
+
+    accessEventAnomalies = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_access_anomalies_total",
+            Help: "Total number of access event anomalies by type (print_volume, export_volume, document_spread)",
+        },
+        []string{"type"},
+    )
+
+    enrollmentSeals = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "enrollment_seals_total",
+            Help: "Total number of enrollment seals by result (sealed, failed)",
+        },
+        []string{"result"},
+    )
+
+    sealVerifications = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "enrollment_seal_verifications_total",
+            Help: "Total number of enrollment seal verifications by result (valid, tampered)",
+        },
+        []string{"result"},
+    )
+
+    tenantDocumentsIngested = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "tenant_documents_ingested_total",
+            Help: "Total number of documents ingested by tenant; small tenants are counted under other",
+        },
+        []string{"tenant"},
+    )
+
+    consistentReads = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_consistent_reads_total",
+            Help: "Total number of reads at a version by source (repository, cache) or failure (unavailable, error)",
+        },
+        []string{"result"},
+    )
+
+    jobLockAttempts = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "job_lock_attempts_total",
+            Help: "Total number of background job lock attempts by job and result (acquired, renewed, busy, lost, error, fenced)",
+        },
+        []string{"job", "result"},
+    )
+
+    jobLockHeld = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "job_lock_held",
+            Help: "Whether this instance holds the lock of a background job and runs it (1 held, 0 not)",
+        },
+        []string{"job"},
+    )
+
+    spoolOperations = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "upload_spool_operations_total",
+            Help: "Total number of upload spool operations by result (spooled, full, drained, drain_failed)",
+        },
+        []string{"result"},
+    )
+
+    spoolBytes = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "upload_spool_bytes",
+            Help: "Bytes of ciphertext waiting in the upload spool",
+        },
+    )
+
+    spoolObjects = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "upload_spool_objects",
+            Help: "Objects waiting in the upload spool",
+        },
+    )
+
+    etaErrors = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "document_eta_error_seconds",
+            Help:    "Absolute difference between the first estimated and the actual verification time of documents",
+            Buckets: []float64{60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400, 172800},
+        },
+        []string{"document_type"},
+    )
+
+    etaOutcomes = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_eta_outcomes_total",
+            Help: "Total number of verified documents by whether they were verified before (early) or after (late) their first estimate",
+        },
+        []string{"document_type", "outcome"},
+    )
+
+    documentConversions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_conversions_total",
+            Help: "Uploads converted to PDF on ingestion by source type and result",
+        },
+        []string{"content_type", "result"},
+    )
+
+    conversionDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "document_conversion_duration_seconds",
+            Help:    "Time to convert an upload to PDF by source type",
+            Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+        },
+        []string{"content_type"},
+    )
+
+    pagesRemoved = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_pages_removed_total",
+            Help: "Pages left out of normalized PDFs by reason",
+        },
+        []string{"reason"},
+    )
+
+    languageDetections = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_languages_detected_total",
+            Help: "Languages detected in extracted text",
+        },
+        []string{"language"},
+    )
+
+    translations = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_translations_total",
+            Help: "Machine translations of extracted text by source language and result",
+        },
+        []string{"source_language", "result"},
+    )
+
+    searchOperations = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "search_index_operations_total",
+            Help: "Total number of documents indexed, searches and documents removed from the search index",
+        },
+        []string{"operation"},
+    )
+
+    ocrCanaryComparisons = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "ocr_canary_comparisons_total",
+            Help: "Documents read by the candidate OCR provider by candidate and result",
+        },
+        []string{"candidate", "result"},
+    )
+
+    ocrCanaryFields = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "ocr_canary_field_comparisons_total",
+            Help: "Fields extracted from the text of the candidate OCR provider compared with the provider in service, by field and outcome",
+        },
+        []string{"candidate", "field", "outcome"},
+    )
+
+    ocrCanaryAgreement = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "ocr_canary_text_agreement",
+            Help:    "Share of words the texts of the candidate OCR provider and the provider in service have in common",
+            Buckets: []float64{0.5, 0.7, 0.8, 0.9, 0.95, 0.98, 0.99, 1},
+        },
+        []string{"candidate"},
+    )
+
+    probeRuns = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "synthetic_probe_runs_total",
+            Help: "Synthetic probes run by result and the stage that failed first",
+        },
+        []string{"result", "failed_stage"},
+    )
+
+    probeDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "synthetic_probe_duration_seconds",
+            Help:    "Duration of the stages of synthetic probes in seconds, and of whole probes as total",
+            Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+        },
+        []string{"stage"},
+    )
+
+    probeLastSuccess = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "synthetic_probe_last_success_timestamp_seconds",
+            Help: "Unix time of the last synthetic probe that passed",
+        },
+    )
+
+    sloBudgetRemaining = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "slo_error_budget_remaining",
+            Help: "Share of the error budget left over the window by objective, negative once blown",
+        },
+        []string{"objective"},
+    )
+
+    sloDegraded = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "slo_degraded",
+            Help: "Whether processing is degraded because the error budget of the objective is blown",
+        },
+        []string{"objective"},
+    )
+
+    sloRecoveryTrials = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "slo_recovery_trials_total",
+            Help: "Trials of a degraded dependency by objective and result",
+        },
+        []string{"objective", "result"},
+    )
+
+    ocrDeferrals = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "ocr_deferrals_total",
+            Help: "Documents whose OCR was deferred, and deferred documents processed or failed",
+        },
+        []string{"result"},
+    )
+
+    ocrDeferredDocuments = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "ocr_deferred_documents",
+            Help: "Documents waiting for deferred OCR",
+        },
+    )
+
+    webhookDeliveries = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "webhook_deliveries_total",
+            Help: "Deliveries of tenant webhooks by event and outcome",
+        },
+        []string{"event", "outcome"},
+    )
+
+    apiKeyRequests = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "api_key_requests_total",
+            Help: "Requests presenting an API key by result",
+        },
+        []string{"result"},
+    )
+
+    delegatedRequests = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "delegated_requests_total",
+            Help: "Requests presenting a delegated token by result",
+        },
+        []string{"result"},
+    )
+
+    gatewayTokens = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "gateway_tokens_total",
+            Help: "Gateway tokens verified by result",
+        },
+        []string{"result"},
+    )
+
+    serviceAccountRequests = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "service_account_requests_total",
+            Help: "Requests presenting a service account token by role and result",
+        },
+        []string{"role", "result"},
+    )
+
+    virusScans = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "virus_scans_total",
+            Help: "Uploads scanned for malware by verdict",
+        },
+        []string{"verdict"},
+    )
+
+    virusScanDuration = prometheus.NewHistogram(
+        prometheus.HistogramOpts{
+            Name:    "virus_scan_duration_seconds",
+            Help:    "Time clamd took to scan an upload",
+            Buckets: prometheus.DefBuckets,
+        },
+    )
+
+    virusScanCache = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "virus_scan_cache_total",
+            Help: "Virus scan verdict cache lookups by result",
+        },
+        []string{"result"},
+    )
+
+    virusScanCacheBytes = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "virus_scan_cache_bytes_total",
+            Help: "Bytes of uploads not rescanned thanks to a cached verdict",
+        },
+    )
+
+    virusScanCacheInvalidations = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "virus_scan_cache_invalidations_total",
+            Help: "Times a new signature database dropped every cached verdict",
+        },
+    )
+
+    pdfDisarms = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "pdf_disarms_total",
+            Help: "PDF uploads checked for active content by result",
+        },
+        []string{"result"},
+    )
+
+    pdfActiveContent = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "pdf_active_content_total",
+            Help: "PDF uploads carrying active content by kind",
+        },
+        []string{"kind"},
+    )
+
+    quarantineActions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "quarantine_actions_total",
+            Help: "Total number of actions on quarantine items by quarantine reason and action (hold, fetch, release, destroy)",
+        },
+        []string{"reason", "action"},
+    )
+
+    storageCredentialRefreshes = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "storage_credential_refreshes_total",
+            Help: "Total number of object store credential refreshes by backend and result (refreshed, failed, rejected)",
+        },
+        []string{"backend", "result"},
+    )
+
+    retryBudgetRetries = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "retry_budget_retries_total",
+            Help: "Total number of retries requests made by layer and result (spent, denied)",
+        },
+        []string{"layer", "result"},
+    )
+
+    retryBudgetExhausted = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "retry_budget_exhausted_total",
+            Help: "Total number of requests that were denied a retry by their retry budget",
+        },
+    )
+
+    deadlineExceededBy = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "deadline_exceeded_by_total",
+            Help: "Total number of dependency calls cut off by a deadline, by dependency and whose deadline it was (dependency, request)",
+        },
+        []string{"layer", "deadline"},
+    )
+
+    panicsRecovered = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "panics_recovered_total",
+            Help: "Total number of panics recovered while serving requests by route",
+        },
+        []string{"route"},
+    )
+
+    errorReports = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "error_reports_total",
+            Help: "Total number of error reports by provider and result (sent, failed, dropped)",
+        },
+        []string{"provider", "result"},
+    )
+
+    bulkReviews = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "bulk_reviews_total",
+            Help: "Bulk review actions by result",
+        },
+        []string{"result"},
+    )
+
+    slaEscalations = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "sla_escalations_total",
+            Help: "SLA escalations raised by document type and escalation level",
+        },
+        []string{"document_type", "level"},
+    )
+
+    slaDecisions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "sla_decisions_total",
+            Help: "Final review decisions by document type and whether they met the SLA",
+        },
+        []string{"document_type", "result"},
+    )
+
+    providerQuotaUsed = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "provider_quota_used_transactions",
+            Help: "Transactions counted against each provider's monthly quota this month",
+        },
+        []string{"provider"},
+    )
+
+    providerQuotaUsedRatio = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "provider_quota_used_ratio",
+            Help: "Share of each provider's monthly quota used this month",
+        },
+        []string{"provider"},
+    )
+
+    providerQuotaPace = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "provider_quota_paced_transactions_per_second",
+            Help: "Rate calls to each provider are held to by this instance, 0 when unlimited",
+        },
+        []string{"provider"},
+    )
+
+    providerQuotaRejections = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "provider_quota_rejections_total",
+            Help: "Calls refused before reaching a provider by quota state",
+        },
+        []string{"provider", "state"},
+    )
+
+    providerQuotaThrottles = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "provider_quota_throttles_total",
+            Help: "429 responses received from each provider",
+        },
+        []string{"provider"},
+    )
+
+    providerQuotaFailovers = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "provider_quota_failovers_total",
+            Help: "Switches of the active OCR provider, on quota or health",
+        },
+        []string{"from", "to"},
+    )
+
+    providerCalls = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "provider_calls_total",
+            Help: "Scored provider calls by kind, provider and result",
+        },
+        []string{"kind", "provider", "result"},
+    )
+
+    providerCallDuration = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "provider_call_duration_seconds",
+            Help:    "Latency of successful provider calls by kind and provider",
+            Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+        },
+        []string{"kind", "provider"},
+    )
+
+    providerHealthScore = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "provider_health_score",
+            Help: "Health score of each provider from 0 to 1",
+        },
+        []string{"kind", "provider"},
+    )
+
+    providerHealthRank = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "provider_health_rank",
+            Help: "Rank of each provider among those of its kind, 1 receiving new work",
+        },
+        []string{"kind", "provider"},
+    )
+
+    processingTierDocuments = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "processing_tier_documents_total",
+            Help: "Documents routed to each processing tier",
+        },
+        []string{"tier"},
+    )
+
+    processingTierBusy = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "processing_tier_busy_workers",
+            Help: "Documents being processed in each tier by this instance",
+        },
+        []string{"tier"},
+    )
+
+    processingTierQueued = prometheus.NewGaugeVec(
+        prometheus.GaugeOpts{
+            Name: "processing_tier_queued_documents",
+            Help: "Documents waiting for a worker of each tier on this instance",
+        },
+        []string{"tier"},
+    )
+
+    processingTierWait = prometheus.NewHistogramVec(
+        prometheus.HistogramOpts{
+            Name:    "processing_tier_wait_seconds",
+            Help:    "Time documents waited for a worker of each tier",
+            Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+        },
+        []string{"tier"},
+    )
+
+    processingTierRejections = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "processing_tier_rejections_total",
+            Help: "Uploads refused because the queue of their processing tier was full",
+        },
+        []string{"tier"},
+    )
+
+    largeDocuments = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "large_documents_total",
+            Help: "Uploads past the channel size limit accepted, refused over the tenant quota, and large documents processed or failed in the background",
+        },
+        []string{"result"},
+    )
+
+    largeDocumentsQueued = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "large_documents_queued",
+            Help: "Large documents waiting for background processing",
+        },
+    )
+
+    metadataRepairs = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "metadata_repairs_total",
+            Help: "Metadata invariant violations found by the repair, by violation and whether they were fixed, planned in a dry run, failed or have no safe fix",
+        },
+        []string{"violation", "result"},
+    )
+
+    documentErasures = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_erasures_total",
+            Help: "Total number of documents erased by mode",
+        },
+        []string{"mode"},
+    )
+
+    garbageCollectedObjects = prometheus.NewCounter(
+        prometheus.CounterOpts{
+            Name: "storage_garbage_collected_objects_total",
+            Help: "Total number of shredded objects deleted from storage",
+        },
+    )
+
+    keyUsageEvents = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "data_key_usage_total",
+            Help: "Total number of data key uses by operation, KMS call and outcome",
+        },
+        []string{"operation", "kms_operation", "outcome"},
+    )
+
+    clientEncryptedUploads = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "client_encrypted_uploads_total",
+            Help: "Total number of end-to-end encrypted uploads by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    requestSignatures = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "service_request_signatures_total",
+            Help: "Total number of signed calls between internal services by direction, key and result",
+        },
+        []string{"direction", "key_id", "result"},
+    )
+
+    abuseTokenFailures = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "abuse_token_failures_total",
+            Help: "Total number of failed token and signed link checks by endpoint",
+        },
+        []string{"endpoint"},
+    )
+
+    abuseBans = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "abuse_bans_total",
+            Help: "Total number of clients banned after repeated token failures by endpoint",
+        },
+        []string{"endpoint"},
+    )
+
+    abuseBannedRequests = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "abuse_banned_requests_total",
+            Help: "Total number of requests rejected from banned clients by endpoint",
+        },
+        []string{"endpoint"},
+    )
+
+    abuseBannedClients = prometheus.NewGauge(
+        prometheus.GaugeOpts{
+            Name: "abuse_banned_clients",
+            Help: "Number of clients currently banned from token endpoints",
+        },
+    )
+
+    captchaVerifications = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "captcha_verifications_total",
+            Help: "Total number of CAPTCHA verifications by result",
+        },
+        []string{"result"},
+    )
+
+    impersonationSessions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "impersonation_sessions_total",
+            Help: "Total number of impersonation sessions by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    impersonatedActions = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "impersonated_actions_total",
+            Help: "Total number of requests made while impersonating a beneficiary by method",
+        },
+        []string{"method"},
+    )
+
+    downloadReceiptAccesses = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "download_receipt_accesses_total",
+            Help: "Total number of sensitive document accesses recorded on download receipts by access",
+        },
+        []string{"access"},
+    )
+
+    downloadReceipts = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "download_receipts_total",
+            Help: "Total number of download receipts by outcome",
+        },
+        []string{"outcome"},
+    )
+
+    textAccesses = prometheus.NewCounterVec(
+        prometheus.CounterOpts{
+            Name: "document_text_accesses_total",
+            Help: "Total number of extracted text reads by variant",
+        },
+        []string{"variant"},
+    )
+
+    dataKeyMessages = prometheus.NewCounterFunc(
+        prometheus.CounterOpts{
+            Name: "data_key_messages_total",
+            Help: "Total number of messages sealed under data keys with random nonces",
+        },
+        func() float64 {
+            sealed, _ := utils.NonceStats()
+            return float64(sealed)
+        },
+    )
+
+    dataKeyLimitRotations = prometheus.NewCounterFunc(
+        prometheus.CounterOpts{
+            Name: "data_key_limit_rotations_total",
+            Help: "Total number of shared data keys replaced on reaching their message limit",
+        },
+        func() float64 {
+            _, rotations := utils.NonceStats()
+            return float64(rotations)
+        },
+    )
+)
+
+// RegisterMetrics registers all service-level metrics with the given registerer
+func RegisterMetrics(registerer prometheus.Registerer) error {
+    collectors := []prometheus.Collector{
+        pipelineStepDuration,
+        pipelineStepFailures,
+        pipelineWorkflowsStarted,
+        whatsappMessages,
+        sftpBatchFiles,
+        outboxDeliveries,
+        signatureVerifications,
+        govbrVerifications,
+        cpfSituationChecks,
+        screeningResults,
+        addressComparisons,
+        autoDecisions,
+        experimentStepDuration,
+        experimentStepFailures,
+        featureFlagStates,
+        featureFlagRefreshes,
+        storageShadowWrites,
+        storageShadowComparisons,
+        storageShadowRepairs,
+        httpClientConnections,
+        httpClientDNSDuration,
+        httpClientTLSHandshakes,
+        adaptiveLimit,
+        adaptiveInFlight,
+        adaptiveLimitRejections,
+        storageHedgedReads,
+        ocrPages,
+        storageChecksumMismatches,
+        storageCredentialRefreshes,
+        retryBudgetRetries,
+        retryBudgetExhausted,
+        deadlineExceededBy,
+        panicsRecovered,
+        errorReports,
+        secureViewerEvents,
+        analyticsExports,
+        consentEvents,
+        consentHalts,
+        encryptionScanObjects,
+        reencryptions,
+        documentErasures,
+        cancellationSagas,
+        documentDispositions,
+        bulkOperationDocuments,
+        retentionNotices,
+        retentionPurges,
+        retentionArtifactPurges,
+        quotaRequests,
+        maintenanceRejections,
+        uploadReplayChecks,
+        downloadThrottleEvents,
+        downloadThrottledSeconds,
+        accessEvents,
+        accessEventAnomalies,
+        enrollmentSeals,
+        sealVerifications,
+        tenantDocumentsIngested,
+        consistentReads,
+        jobLockAttempts,
+        jobLockHeld,
+        spoolOperations,
+        spoolBytes,
+        spoolObjects,
+        etaErrors,
+        etaOutcomes,
+        documentConversions,
+        conversionDuration,
+        pagesRemoved,
+        languageDetections,
+        translations,
+        searchOperations,
+        ocrCanaryComparisons,
+        ocrCanaryFields,
+        ocrCanaryAgreement,
+        probeRuns,
+        probeDuration,
+        probeLastSuccess,
+        sloBudgetRemaining,
+        sloDegraded,
+        sloRecoveryTrials,
+        ocrDeferrals,
+        ocrDeferredDocuments,
+        webhookDeliveries,
+        apiKeyRequests,
+        bulkReviews,
+        slaEscalations,
+        slaDecisions,
+        providerQuotaUsed,
+        providerQuotaUsedRatio,
+        providerQuotaPace,
+        providerQuotaRejections,
+        providerQuotaThrottles,
+        providerQuotaFailovers,
+        providerCalls,
+        providerCallDuration,
+        providerHealthScore,
+        providerHealthRank,
+        processingTierDocuments,
+        processingTierBusy,
+        processingTierQueued,
+        processingTierWait,
+        processingTierRejections,
+        largeDocuments,
+        largeDocumentsQueued,
+        metadataRepairs,
+        delegatedRequests,
+        gatewayTokens,
+        serviceAccountRequests,
+        virusScans,
+        virusScanDuration,
+        virusScanCache,
+        virusScanCacheBytes,
+        virusScanCacheInvalidations,
+        pdfDisarms,
+        pdfActiveContent,
+        quarantineActions,
+        garbageCollectedObjects,
+        keyUsageEvents,
+        dataKeyMessages,
+        dataKeyLimitRotations,
+        clientEncryptedUploads,
+        requestSignatures,
+        abuseTokenFailures,
+        abuseBans,
+        abuseBannedRequests,
+        abuseBannedClients,
+        captchaVerifications,
+        impersonationSessions,
+        impersonatedActions,
+        downloadReceiptAccesses,
+        downloadReceipts,
+        textAccesses,
+    }
+
+    for _, collector := range collectors {
+        if err := registerer.Register(collector); err != nil {
+            return fmt.Errorf("failed to register service metric: %w", err)
+        }
+    }
+    return nil
+}
+
+// ObserveRetryBudget counts the retries a request spent and was denied
+func ObserveRetryBudget(budget *utils.RetryBudget) {
+    spent, denied := budget.Usage()
+    for layer, n := range spent {
+        retryBudgetRetries.WithLabelValues(layer, "spent").Add(float64(n))
+    }
+    for layer, n := range denied {
+        retryBudgetRetries.WithLabelValues(layer, "denied").Add(float64(n))
+    }
+    if len(denied) > 0 {
+        retryBudgetExhausted.Inc()
+    }
+}
+
+// ObserveDeadlineExceeded counts a dependency call cut off by its own timeout
+// or by the deadline of the request it was made for
+func ObserveDeadlineExceeded(dependency string, inherited bool) {
+    deadline := "dependency"
+    if inherited {
+        deadline = "request"
+    }
+    deadlineExceededBy.WithLabelValues(dependency, deadline).Inc()
+}
+
+// ObservePanic counts a panic recovered while serving a request on route
+func ObservePanic(route string) {
+    panicsRecovered.WithLabelValues(route).Inc()
+}
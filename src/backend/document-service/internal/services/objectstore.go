@@ -0,0 +1,390 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/md5"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net/url"
+    "strings"
+    "time"
+
+    "github.com/minio/minio-go/v7" // v7.0.63
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// uploadPartSize is pinned so the ETag of multipart uploads can be computed
+// locally; it matches the client's minimum part size
+const uploadPartSize = 16 << 20
+
+var (
+    ErrObjectNotFound     = errors.New("object not found")
+    ErrPresignUnsupported = errors.New("object store cannot presign URLs")
+    ErrUploadCorrupted    = errors.New("uploaded object checksum mismatch")
+)
+
+// ObjectInfo is the metadata of a stored object
+type ObjectInfo struct {
+    Size         int64
+    ContentType  string
+    ETag         string
+    LastModified time.Time
+    Metadata     map[string]string
+}
+
+// ObjectStore is a bucket holding encrypted document content and unencrypted renditions
+type ObjectStore interface {
+    Name() string
+    Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error
+    Get(ctx context.Context, key string) (io.ReadCloser, error)
+    Stat(ctx context.Context, key string) (ObjectInfo, error)
+    Delete(ctx context.Context, key string) error
+}
+
+// warmableStore is implemented by stores that can validate credentials and open
+// connections ahead of traffic
+type warmableStore interface {
+    Warm(ctx context.Context, connections int) error
+}
+
+// seekableStore is implemented by stores that can open objects for random
+// access, so range requests are served without reading the whole object
+type seekableStore interface {
+    Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error)
+}
+
+// presigningStore is implemented by stores that can hand out time-limited URLs
+// for direct downloads
+type presigningStore interface {
+    PresignGet(ctx context.Context, key string, expiry time.Duration) (*url.URL, error)
+}
+
+// openObject opens an object for random access, buffering it in memory when the
+// store cannot seek
+func openObject(ctx context.Context, store ObjectStore, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    if seekable, ok := store.(seekableStore); ok {
+        return seekable.Open(ctx, key)
+    }
+
+    info, err := store.Stat(ctx, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    reader, err := store.Get(ctx, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    defer reader.Close()
+
+    content, err := io.ReadAll(reader)
+    if err != nil {
+        return nil, ObjectInfo{}, fmt.Errorf("failed to read object from %s: %w", store.Name(), err)
+    }
+    return nopSeekCloser{bytes.NewReader(content)}, info, nil
+}
+
+// presignObject returns a presigned download URL, or ErrPresignUnsupported
+func presignObject(ctx context.Context, store ObjectStore, key string, expiry time.Duration) (*url.URL, error) {
+    presigning, ok := store.(presigningStore)
+    if !ok {
+        return nil, ErrPresignUnsupported
+    }
+    return presigning.PresignGet(ctx, key, expiry)
+}
+
+// nopSeekCloser adds a no-op Close to an in-memory reader
+type nopSeekCloser struct {
+    io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error {
+    return nil
+}
+
+// MinioObjectStore stores objects in a MinIO or S3 bucket through the MinIO client
+type MinioObjectStore struct {
+    name       string
+    client     *minio.Client
+    rotation   *credentialRotation
+    bucketName string
+    verify     bool
+}
+
+// NewMinioObjectStore connects to the configured MinIO bucket, creating it if
+// needed before ctx is done
+func NewMinioObjectStore(ctx context.Context, cfg *config.Config) (*MinioObjectStore, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    creds, rotation, err := newObjectStoreCredentials("minio", cfg.MinioConfig.Credentials, cfg.MinioConfig.AccessKey, cfg.MinioConfig.SecretKey, cfg.MinioConfig.Transport)
+    if err != nil {
+        return nil, err
+    }
+    client, err := minio.New(cfg.MinioConfig.Endpoint, &minio.Options{
+        Creds:     creds,
+        Secure:    cfg.MinioConfig.UseSSL,
+        Transport: NewHTTPTransport("minio", cfg.MinioConfig.Transport),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to initialize MinIO client: %w", err)
+    }
+
+    store := &MinioObjectStore{
+        name:       "minio",
+        client:     client,
+        rotation:   rotation,
+        bucketName: cfg.MinioConfig.BucketName,
+        verify:     cfg.MinioConfig.VerifyChecksums,
+    }
+    if err := store.ensureBucket(ctx, ""); err != nil {
+        return nil, err
+    }
+    return store, nil
+}
+
+// NewS3ObjectStore connects to an S3 bucket, creating it if needed before ctx is
+// done; the MinIO client speaks the S3 API so managed S3 needs no separate SDK
+func NewS3ObjectStore(ctx context.Context, s3cfg config.S3Config, transport config.HTTPTransportConfig) (*MinioObjectStore, error) {
+    creds, rotation, err := newObjectStoreCredentials("s3", s3cfg.Credentials, s3cfg.AccessKey, s3cfg.SecretKey, transport)
+    if err != nil {
+        return nil, err
+    }
+    client, err := minio.New(s3cfg.Endpoint, &minio.Options{
+        Creds:     creds,
+        Secure:    s3cfg.UseSSL,
+        Region:    s3cfg.Region,
+        Transport: NewHTTPTransport("s3", transport),
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to initialize S3 client: %w", err)
+    }
+
+    store := &MinioObjectStore{
+        name:       "s3",
+        client:     client,
+        rotation:   rotation,
+        bucketName: s3cfg.BucketName,
+        verify:     s3cfg.VerifyChecksums,
+    }
+    if err := store.ensureBucket(ctx, s3cfg.Region); err != nil {
+        return nil, err
+    }
+    return store, nil
+}
+
+// ensureBucket verifies the bucket exists or creates it
+func (s *MinioObjectStore) ensureBucket(ctx context.Context, region string) error {
+    var exists bool
+    err := s.withCredentials(func() (err error) {
+        exists, err = s.client.BucketExists(ctx, s.bucketName)
+        return err
+    })
+    if err != nil {
+        return fmt.Errorf("failed to check bucket existence: %w", err)
+    }
+
+    if !exists {
+        if err := s.client.MakeBucket(ctx, s.bucketName, minio.MakeBucketOptions{Region: region}); err != nil {
+            return fmt.Errorf("failed to create bucket: %w", err)
+        }
+    }
+    return nil
+}
+
+// Name returns the backend name used in metrics
+func (s *MinioObjectStore) Name() string {
+    return s.name
+}
+
+// Warm validates access to the bucket over the given number of concurrent
+// requests, leaving that many idle connections in the client's pool
+func (s *MinioObjectStore) Warm(ctx context.Context, connections int) error {
+    if connections < 1 {
+        connections = 1
+    }
+
+    errs := make(chan error, connections)
+    for i := 0; i < connections; i++ {
+        go func() {
+            var exists bool
+            err := s.withCredentials(func() (err error) {
+                exists, err = s.client.BucketExists(ctx, s.bucketName)
+                return err
+            })
+            if err == nil && !exists {
+                err = fmt.Errorf("bucket %s does not exist", s.bucketName)
+            }
+            errs <- err
+        }()
+    }
+
+    var firstErr error
+    for i := 0; i < connections; i++ {
+        if err := <-errs; err != nil && firstErr == nil {
+            firstErr = fmt.Errorf("%s: %w", s.name, err)
+        }
+    }
+    return firstErr
+}
+
+// Put uploads an object. With verification enabled the server checks the
+// Content-MD5 of every request and the returned ETag is compared with the
+// digest of the local content, so corruption in transit fails the upload
+func (s *MinioObjectStore) Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+    var info minio.UploadInfo
+    err := s.withCredentials(func() (err error) {
+        info, err = s.client.PutObject(ctx, s.bucketName, key, bytes.NewReader(content), int64(len(content)),
+            minio.PutObjectOptions{
+                ContentType:    contentType,
+                UserMetadata:   metadata,
+                SendContentMd5: s.verify,
+                PartSize:       uploadPartSize,
+            })
+        return err
+    })
+    if err != nil {
+        if minio.ToErrorResponse(err).Code == "BadDigest" {
+            storageChecksumMismatches.WithLabelValues(s.name, "content_md5").Inc()
+            return fmt.Errorf("%w: %s rejected the Content-MD5 of %s", ErrUploadCorrupted, s.name, key)
+        }
+        return err
+    }
+    if !s.verify {
+        return nil
+    }
+
+    expected := expectedETag(content, uploadPartSize)
+    if info.ETag == "" {
+        // Without an ETag the upload cannot be told apart from a corrupted one
+        storageChecksumMismatches.WithLabelValues(s.name, "etag").Inc()
+        return fmt.Errorf("%w: %s returned no ETag for %s", ErrUploadCorrupted, s.name, key)
+    }
+    if actual := strings.Trim(info.ETag, `"`); !strings.EqualFold(actual, expected) {
+        storageChecksumMismatches.WithLabelValues(s.name, "etag").Inc()
+        return fmt.Errorf("%w: %s returned ETag %s for %s, expected %s", ErrUploadCorrupted, s.name, actual, key, expected)
+    }
+    return nil
+}
+
+// expectedETag computes the S3 ETag of content: the hex MD5 digest for single
+// part uploads, and the MD5 of the concatenated part digests followed by the
+// part count for multipart uploads
+func expectedETag(content []byte, partSize int) string {
+    if len(content) < partSize {
+        sum := md5.Sum(content)
+        return hex.EncodeToString(sum[:])
+    }
+
+    digests := make([]byte, 0, (len(content)/partSize+1)*md5.Size)
+    parts := 0
+    for start := 0; start < len(content); start += partSize {
+        end := start + partSize
+        if end > len(content) {
+            end = len(content)
+        }
+        sum := md5.Sum(content[start:end])
+        digests = append(digests, sum[:]...)
+        parts++
+    }
+    sum := md5.Sum(digests)
+    return fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), parts)
+}
+
+// Get opens an object for reading
+func (s *MinioObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    var obj *minio.Object
+    err := s.withCredentials(func() error {
+        var err error
+        obj, err = s.client.GetObject(ctx, s.bucketName, key, minio.GetObjectOptions{})
+        if err != nil {
+            return err
+        }
+
+        // GetObject is lazy; Stat issues the request so missing objects surface here
+        if _, err := obj.Stat(); err != nil {
+            obj.Close()
+            return err
+        }
+        return nil
+    })
+    if err != nil {
+        if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+            return nil, ErrObjectNotFound
+        }
+        return nil, err
+    }
+    return obj, nil
+}
+
+// Stat reads the metadata of an object
+func (s *MinioObjectStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    var info minio.ObjectInfo
+    err := s.withCredentials(func() (err error) {
+        info, err = s.client.StatObject(ctx, s.bucketName, key, minio.StatObjectOptions{})
+        return err
+    })
+    if err != nil {
+        if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+            return ObjectInfo{}, ErrObjectNotFound
+        }
+        return ObjectInfo{}, err
+    }
+
+    return ObjectInfo{
+        Size:         info.Size,
+        ContentType:  info.ContentType,
+        ETag:         info.ETag,
+        LastModified: info.LastModified,
+        Metadata:     info.UserMetadata,
+    }, nil
+}
+
+// Open opens an object for random access; the MinIO object issues ranged
+// requests as it is read and seeked
+func (s *MinioObjectStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    var obj *minio.Object
+    var info minio.ObjectInfo
+    err := s.withCredentials(func() error {
+        var err error
+        obj, err = s.client.GetObject(ctx, s.bucketName, key, minio.GetObjectOptions{})
+        if err != nil {
+            return err
+        }
+        if info, err = obj.Stat(); err != nil {
+            obj.Close()
+            return err
+        }
+        return nil
+    })
+    if err != nil {
+        if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+            return nil, ObjectInfo{}, ErrObjectNotFound
+        }
+        return nil, ObjectInfo{}, err
+    }
+    return obj, ObjectInfo{
+        Size:         info.Size,
+        ContentType:  info.ContentType,
+        ETag:         info.ETag,
+        LastModified: info.LastModified,
+        Metadata:     info.UserMetadata,
+    }, nil
+}
+
+// PresignGet returns a URL that downloads the object directly from the bucket
+// until it expires
+func (s *MinioObjectStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (*url.URL, error) {
+    return s.client.PresignedGetObject(ctx, s.bucketName, key, expiry, url.Values{})
+}
+
+// Delete removes an object; removing a missing object succeeds
+func (s *MinioObjectStore) Delete(ctx context.Context, key string) error {
+    return s.withCredentials(func() error {
+        return s.client.RemoveObject(ctx, s.bucketName, key, minio.RemoveObjectOptions{})
+    })
+}
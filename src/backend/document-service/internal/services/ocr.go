@@ -5,6 +5,10 @@ import (
     "context"
     "errors"
     "fmt"
+    "math"
+    "net/http"
+    "strings"
+    "sync"
     "time"
     
     "github.com/Azure/azure-sdk-for-go/services/cognitiveservices/v3.0/computervision" // v68.0.0
@@ -13,13 +17,19 @@ import (
     
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
 )
 
 const (
     maxRetryAttempts      = 3
     retryBackoffDuration  = time.Second * 2
-    ocrTimeout           = time.Second * 8
-    maxDocumentSize      = 4 * 1024 * 1024 // 4MB for OCR processing
+)
+
+// Provider and model of the recognized text recorded in provenance: the
+// printed text operation of the Computer Vision v3.0 API
+const (
+    ocrProvider = "azure_computer_vision"
+    ocrModel    = "v3.0/recognize-printed-text"
 )
 
 var (
@@ -28,13 +38,37 @@ var (
     ErrAzureServiceUnavailable = errors.New("azure service unavailable")
 )
 
+// OCRResult is the text recognized in a document and the layout of its lines
+type OCRResult struct {
+    Text  string
+    Lines []models.OCRLine
+}
+
+// OCRProvider recognizes the lines of text in a document or page. Providers
+// are compared through the OCR canary before one replaces another
+type OCRProvider interface {
+    Provider() string
+    Recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error)
+}
+
 // OCRService manages OCR operations using Azure Computer Vision
 type OCRService struct {
     client    *computervision.Client
     timeout    time.Duration
+    maxSize    int64
     maxRetries int
     metrics    metric.Meter
     breaker    *gobreaker.CircuitBreaker
+    limiter    *AdaptiveLimiter
+    pages      config.PageOCRConfig
+    tenants    *tenantSlots
+    transport  http.RoundTripper
+    quotas     *ProviderQuotas
+    scoreboard *ProviderScoreboard
+    // resource names the Azure resource on the quotas and scoreboard
+    resource string
+    // fallback is the Azure resource calls switch to near the quota, if any
+    fallback *OCRService
 }
 
 // NewOCRService creates a new OCR service instance with Azure client configuration
@@ -43,13 +77,25 @@ func NewOCRService(cfg *config.Config) (*OCRService, error) {
         return nil, fmt.Errorf("invalid azure configuration: %w", err)
     }
 
-    client := computervision.New(cfg.AzureConfig.SubscriptionKey)
-    client.Authorizer = computervision.NewCognitiveServicesAuthorizer(cfg.AzureConfig.SubscriptionKey)
-    client.Endpoint = cfg.AzureConfig.Endpoint
+    service := newOCRService(cfg, QuotaProviderAzure, "ocr-service", "azure_ocr", cfg.AzureConfig.Endpoint, cfg.AzureConfig.SubscriptionKey)
+    if fallback := cfg.ProviderQuotaConfig.Fallback; cfg.ProviderQuotaConfig.Enabled && fallback.Endpoint != "" {
+        service.fallback = newOCRService(cfg, QuotaProviderAzureFallback, "ocr-service-fallback", "azure_ocr_fallback", fallback.Endpoint, fallback.SubscriptionKey)
+    }
+    return service, nil
+}
+
+// newOCRService creates the OCR service of an Azure resource, with its own
+// circuit breaker and adaptive limit
+func newOCRService(cfg *config.Config, resource, name, dependency, endpoint, subscriptionKey string) *OCRService {
+    transport := NewHTTPTransport("azure", cfg.AzureConfig.Transport)
+    client := computervision.New(subscriptionKey)
+    client.Authorizer = computervision.NewCognitiveServicesAuthorizer(subscriptionKey)
+    client.Endpoint = endpoint
+    client.Sender = &http.Client{Transport: transport}
 
     // Configure circuit breaker
     breakerSettings := gobreaker.Settings{
-        Name:        "ocr-service",
+        Name:        name,
         MaxRequests: 100,
         Interval:    time.Minute * 1,
         Timeout:     time.Minute * 2,
@@ -60,51 +106,100 @@ func NewOCRService(cfg *config.Config) (*OCRService, error) {
     }
 
     // Initialize metrics
-    meter := metric.NewMeterProvider().Meter("ocr-service")
+    meter := metric.NewMeterProvider().Meter(name)
 
     return &OCRService{
         client:     client,
         timeout:    cfg.AzureConfig.OCRTimeout,
+        maxSize:    cfg.AzureConfig.MaxDocumentSize,
         maxRetries: cfg.AzureConfig.MaxRetries,
         metrics:    meter,
         breaker:    gobreaker.NewCircuitBreaker(breakerSettings),
-    }, nil
+        limiter:    NewAdaptiveLimiter(dependency, cfg, cfg.ConcurrencyConfig.OCR),
+        pages:      cfg.PageOCRConfig,
+        tenants:    newTenantSlots(cfg.PageOCRConfig.PerTenantConcurrency),
+        transport:  transport,
+        resource:   resource,
+    }
+}
+
+// UseQuotas holds calls to Azure under its quota, and switches them to the
+// fallback resource as the quota nears; it must be called before serving
+// requests
+func (s *OCRService) UseQuotas(quotas *ProviderQuotas) {
+    if quotas == nil {
+        return
+    }
+    s.quotas = quotas
+    s.client.Sender = &http.Client{Transport: quotas.Transport(s.resource, s.transport)}
+    if s.fallback != nil {
+        s.fallback.client.Sender = &http.Client{Transport: quotas.Transport(s.fallback.resource, s.fallback.transport)}
+    }
+}
+
+// UseScoreboard scores the calls to Azure, and to the fallback resource, on
+// the provider scoreboard; it must be called before serving requests
+func (s *OCRService) UseScoreboard(scoreboard *ProviderScoreboard) {
+    if scoreboard == nil {
+        return
+    }
+    s.scoreboard = scoreboard
+    scoreboard.Register(ProviderKindOCR, s.resource)
+    if s.fallback != nil {
+        s.fallback.scoreboard = scoreboard
+        scoreboard.Register(ProviderKindOCR, s.fallback.resource)
+    }
+}
+
+// ProcessDocument processes a document through OCR with validation and
+// monitoring. Multi-page PDFs are recognized page by page in parallel; pages
+// that fail are recorded on the document instead of failing the whole document
+func (s *OCRService) ProcessDocument(ctx context.Context, doc *models.Document, content []byte) (OCRResult, error) {
+    return s.process(ctx, doc, content, s.recognize)
+}
+
+// ProcessDocumentWith processes a document like ProcessDocument, reading the
+// document or its pages with the provider instead of Azure
+func (s *OCRService) ProcessDocumentWith(ctx context.Context, doc *models.Document, content []byte, provider OCRProvider) (OCRResult, error) {
+    return s.process(ctx, doc, content, provider.Recognize)
 }
 
-// ProcessDocument processes a document through OCR with validation and monitoring
-func (s *OCRService) ProcessDocument(ctx context.Context, doc *models.Document, content []byte) (string, error) {
+// recognizer reads the text of a document or page
+type recognizer func(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error)
+
+// process validates a document, reads it with recognize and updates its status
+func (s *OCRService) process(ctx context.Context, doc *models.Document, content []byte, recognize recognizer) (OCRResult, error) {
     startTime := time.Now()
     defer func() {
         s.recordMetrics("ocr_processing_duration", time.Since(startTime).Seconds())
     }()
 
-    // Validate document
-    if err := s.validateDocument(doc, content); err != nil {
-        return "", fmt.Errorf("document validation failed: %w", err)
+    // Validate document; multi-page PDFs are validated page by page
+    pages := s.splitPages(doc, content)
+    if len(pages) <= 1 {
+        if err := s.validateDocument(doc, content); err != nil {
+            return OCRResult{}, fmt.Errorf("document validation failed: %w", err)
+        }
     }
 
     // Update document status
     if err := doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"); err != nil {
-        return "", fmt.Errorf("status update failed: %w", err)
+        return OCRResult{}, fmt.Errorf("status update failed: %w", err)
     }
 
-    // Process with timeout
-    ctx, cancel := context.WithTimeout(ctx, s.timeout)
-    defer cancel()
-
-    var extractedText string
+    var result OCRResult
     var processingErr error
+    if len(pages) > 1 {
+        result, processingErr = s.recognizePages(ctx, doc, pages, recognize)
+    } else {
+        result.Lines, processingErr = recognize(ctx, doc.TenantID, content)
+        result.Text = linesText(result.Lines)
+    }
 
-    // Execute OCR with circuit breaker
-    result, err := s.breaker.Execute(func() (interface{}, error) {
-        return s.executeOCRWithRetry(ctx, content)
-    })
-
-    if err != nil {
-        processingErr = fmt.Errorf("OCR processing failed: %w", err)
+    if processingErr != nil {
+        processingErr = fmt.Errorf("OCR processing failed: %w", processingErr)
         s.recordMetrics("ocr_failures", 1)
     } else {
-        extractedText = result.(string)
         s.recordMetrics("ocr_successes", 1)
     }
 
@@ -115,23 +210,168 @@ func (s *OCRService) ProcessDocument(ctx context.Context, doc *models.Document,
     }
     
     if err := doc.UpdateStatus(finalStatus, fmt.Sprintf("OCR processing %s", finalStatus)); err != nil {
-        return extractedText, fmt.Errorf("final status update failed: %w", err)
+        return result, fmt.Errorf("final status update failed: %w", err)
+    }
+
+    return result, processingErr
+}
+
+// Provider returns the name of the OCR provider recorded in provenance
+func (s *OCRService) Provider() string {
+    return ocrProvider
+}
+
+// Recognize runs OCR on a document or page as a whole, without updating the
+// document it belongs to
+func (s *OCRService) Recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error) {
+    return s.recognize(ctx, tenant, content)
+}
+
+// splitPages returns the pages of a PDF to recognize separately, or nil when
+// the document is recognized as a whole. Unsplittable PDFs fall back to
+// whole-document OCR
+func (s *OCRService) splitPages(doc *models.Document, content []byte) [][]byte {
+    if !s.pages.Enabled || doc == nil || doc.ContentType != "application/pdf" {
+        return nil
+    }
+
+    pages, err := splitPDFPages(content)
+    if err != nil {
+        return nil
     }
+    return pages
+}
+
+// recognize runs OCR on a single image or page under the tenant's slot, the
+// circuit breaker and the adaptive limit, on the fallback resource while it
+// is the active provider
+func (s *OCRService) recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error) {
+    if s.fallback != nil && s.quotas.Active(ctx) == QuotaProviderAzureFallback {
+        return s.fallback.recognize(ctx, tenant, content)
+    }
+
+    release, err := s.tenants.acquire(ctx, tenant)
+    if err != nil {
+        return nil, err
+    }
+    defer release()
+
+    // Process with timeout
+    var result interface{}
+    start := time.Now()
+    err = utils.CallWithDeadline(ctx, config.DependencyOCR, s.timeout, func(ctx context.Context) error {
+        var err error
+        result, err = s.breaker.Execute(func() (interface{}, error) {
+            return s.executeOCRWithRetry(ctx, content)
+        })
+        return err
+    })
+    s.scoreboard.Record(ProviderKindOCR, s.resource, time.Since(start), err)
+    if err != nil {
+        return nil, err
+    }
+    return result.([]models.OCRLine), nil
+}
+
+// recognizePages fans out page OCR with bounded concurrency and joins the text
+// of the recognized pages in page order. It fails only when no page was read
+func (s *OCRService) recognizePages(ctx context.Context, doc *models.Document, pages [][]byte, recognize recognizer) (OCRResult, error) {
+    results := make([]models.OCRPage, len(pages))
+    lines := make([][]models.OCRLine, len(pages))
+    errs := make([]error, len(pages))
+
+    indexes := make(chan int)
+    var wg sync.WaitGroup
+    for worker := 0; worker < s.pages.MaxConcurrentPages && worker < len(pages); worker++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range indexes {
+                if err := s.validateDocument(doc, pages[i]); err != nil {
+                    errs[i] = err
+                    continue
+                }
+                lines[i], errs[i] = recognize(ctx, doc.TenantID, pages[i])
+            }
+        }()
+    }
+    for i := range pages {
+        if i >= s.pages.MaxPages {
+            errs[i] = fmt.Errorf("page limit of %d exceeded", s.pages.MaxPages)
+            continue
+        }
+        indexes <- i
+    }
+    close(indexes)
+    wg.Wait()
+
+    var (
+        recognized []string
+        layout     []models.OCRLine
+        firstErr   error
+    )
+    for i := range pages {
+        results[i] = models.OCRPage{Number: i + 1, Succeeded: errs[i] == nil}
+        if errs[i] != nil {
+            results[i].Error = errs[i].Error()
+            ocrPages.WithLabelValues("failed").Inc()
+            if firstErr == nil {
+                firstErr = fmt.Errorf("page %d: %w", i+1, errs[i])
+            }
+            continue
+        }
+        ocrPages.WithLabelValues("succeeded").Inc()
+        recognized = append(recognized, linesText(lines[i]))
+        for _, line := range lines[i] {
+            line.Page = i + 1
+            layout = append(layout, line)
+        }
+    }
+    doc.SetOCRPages(results)
+
+    if len(recognized) == 0 {
+        return OCRResult{}, fmt.Errorf("all %d pages failed: %w", len(pages), firstErr)
+    }
+    return OCRResult{Text: strings.Join(recognized, "\n"), Lines: layout}, nil
+}
 
-    return extractedText, processingErr
+// Ping validates the Azure endpoint and subscription key with a request that
+// consumes no OCR quota
+func (s *OCRService) Ping(ctx context.Context) error {
+    if _, err := s.client.ListModels(ctx); err != nil {
+        return fmt.Errorf("%w: %v", ErrAzureServiceUnavailable, err)
+    }
+    return nil
 }
 
-// executeOCRWithRetry performs OCR operation with retry logic
-func (s *OCRService) executeOCRWithRetry(ctx context.Context, content []byte) (string, error) {
+// executeOCRWithRetry performs OCR operation with retry logic. Each attempt
+// holds its own slot of the adaptive limit, so the limiter samples the latency
+// of a single attempt and the slot is free while backing off
+func (s *OCRService) executeOCRWithRetry(ctx context.Context, content []byte) ([]models.OCRLine, error) {
     var lastErr error
 
     for attempt := 0; attempt < s.maxRetries; attempt++ {
         if attempt > 0 {
+            if utils.SpendRetry(ctx, utils.RetryLayerOCR) != nil {
+                return nil, utils.RetryExhausted(lastErr)
+            }
             time.Sleep(retryBackoffDuration * time.Duration(attempt))
         }
 
+        release, err := s.limiter.Acquire(ctx)
+        if err != nil {
+            return nil, err
+        }
+
         // Submit OCR request
         operation, err := s.submitOCR(ctx, content)
+        if err != nil {
+            release(err)
+        }
+        if errors.Is(err, ErrProviderQuotaExhausted) || errors.Is(err, ErrProviderThrottled) {
+            // Retrying at once would only be held back again
+            return nil, err
+        }
         if err != nil {
             lastErr = err
             continue
@@ -139,9 +379,10 @@ func (s *OCRService) executeOCRWithRetry(ctx context.Context, content []byte) (s
 
         // Poll for results
         result, err := s.getOCRResult(ctx, operation)
+        release(err)
         if err != nil {
             if errors.Is(err, context.DeadlineExceeded) {
-                return "", ErrOCRTimeout
+                return nil, ErrOCRTimeout
             }
             lastErr = err
             continue
@@ -150,7 +391,7 @@ func (s *OCRService) executeOCRWithRetry(ctx context.Context, content []byte) (s
         return result, nil
     }
 
-    return "", fmt.Errorf("all retry attempts failed: %w", lastErr)
+    return nil, fmt.Errorf("all retry attempts failed: %w", lastErr)
 }
 
 // submitOCR submits content to Azure OCR service
@@ -168,22 +409,22 @@ func (s *OCRService) submitOCR(ctx context.Context, content []byte) (string, err
 }
 
 // getOCRResult retrieves and processes OCR operation result
-func (s *OCRService) getOCRResult(ctx context.Context, operationURL string) (string, error) {
+func (s *OCRService) getOCRResult(ctx context.Context, operationURL string) ([]models.OCRLine, error) {
     for {
         select {
         case <-ctx.Done():
-            return "", ctx.Err()
+            return nil, ctx.Err()
         default:
             result, err := s.client.GetTextOperationResult(ctx, operationURL)
             if err != nil {
-                return "", fmt.Errorf("failed to get OCR result: %w", err)
+                return nil, fmt.Errorf("failed to get OCR result: %w", err)
             }
 
             switch result.Status {
             case computervision.Failed:
-                return "", fmt.Errorf("OCR operation failed: %v", result.Message)
+                return nil, fmt.Errorf("OCR operation failed: %v", result.Message)
             case computervision.Succeeded:
-                return s.extractText(result), nil
+                return s.extractLines(result), nil
             case computervision.Running, computervision.NotStarted:
                 time.Sleep(time.Millisecond * 500)
             }
@@ -197,26 +438,61 @@ func (s *OCRService) validateDocument(doc *models.Document, content []byte) erro
         return ErrInvalidDocument
     }
 
-    if len(content) > maxDocumentSize {
-        return fmt.Errorf("document size exceeds maximum allowed size for OCR")
+    if int64(len(content)) > s.maxSize {
+        return fmt.Errorf("%w: document size exceeds maximum allowed size for OCR", ErrInvalidDocument)
     }
 
     return nil
 }
 
-// extractText processes OCR result and extracts text content
-func (s *OCRService) extractText(result computervision.TextOperationResult) string {
-    if result.RecognitionResult == nil {
-        return ""
+// extractLines processes OCR result into its lines of text with their
+// bounding boxes relative to the page
+func (s *OCRService) extractLines(result computervision.TextOperationResult) []models.OCRLine {
+    recognition := result.RecognitionResult
+    if recognition == nil || recognition.Lines == nil {
+        return nil
+    }
+
+    page := 1
+    if recognition.Page != nil {
+        page = int(*recognition.Page)
+    }
+    var width, height float64
+    if recognition.Width != nil && recognition.Height != nil {
+        width, height = float64(*recognition.Width), float64(*recognition.Height)
     }
 
-    var text string
-    for _, line := range *result.RecognitionResult.Lines {
-        if line.Text != nil {
-            text += *line.Text + "\n"
+    lines := make([]models.OCRLine, 0, len(*recognition.Lines))
+    for _, line := range *recognition.Lines {
+        if line.Text == nil {
+            continue
         }
+        recognized := models.OCRLine{Page: page, Text: *line.Text}
+        // The bounding box lists the four corners clockwise from top left
+        if line.BoundingBox != nil && len(*line.BoundingBox) == 8 && width > 0 && height > 0 {
+            corners := *line.BoundingBox
+            left, top, right, bottom := float64(corners[0]), float64(corners[1]), float64(corners[0]), float64(corners[1])
+            for i := 0; i < 8; i += 2 {
+                left = math.Min(left, float64(corners[i]))
+                right = math.Max(right, float64(corners[i]))
+                top = math.Min(top, float64(corners[i+1]))
+                bottom = math.Max(bottom, float64(corners[i+1]))
+            }
+            recognized.Box = [4]float64{left / width, top / height, right / width, bottom / height}
+        }
+        lines = append(lines, recognized)
+    }
+    return lines
+}
+
+// linesText joins recognized lines into text, one line each
+func linesText(lines []models.OCRLine) string {
+    var text strings.Builder
+    for _, line := range lines {
+        text.WriteString(line.Text)
+        text.WriteString("\n")
     }
-    return text
+    return text.String()
 }
 
 // recordMetrics records OCR processing metrics
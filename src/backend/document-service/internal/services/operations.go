@@ -0,0 +1,509 @@
+package services
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// bulkOperationSample is how many matching documents a dry run lists
+const bulkOperationSample = 20
+
+var (
+    ErrUnknownBulkAction         = errors.New("unknown bulk operation action")
+    ErrEmptyBulkFilter           = errors.New("bulk operation filter must set at least one criterion")
+    ErrInvalidBulkOperation      = errors.New("invalid bulk operation")
+    ErrBulkOperationTooLarge     = errors.New("bulk operation matches too many documents")
+    ErrTooManyBulkOperations     = errors.New("too many bulk operations running")
+    ErrBulkOperationFinished     = errors.New("bulk operation already finished")
+    ErrBulkOperationNotResumable = errors.New("only cancelled bulk operations can be resumed")
+)
+
+// BulkOperationRequest asks for an action on every document matching the
+// filter. Rate is in documents per second; zero uses the configured default
+type BulkOperationRequest struct {
+    Action       string                     `json:"action"`
+    Filter       models.BulkOperationFilter `json:"filter"`
+    Steps        []string                   `json:"steps,omitempty"`
+    DocumentType string                     `json:"document_type,omitempty"`
+    DryRun       bool                       `json:"dry_run"`
+    Rate         float64                    `json:"rate,omitempty"`
+    RequestedBy  string                     `json:"requested_by,omitempty"`
+}
+
+// BulkOperations applies administrative actions to many stored documents:
+// reprocessing pipeline steps, backfilling the steps documents lack,
+// re-encryption, reclassification and content hash recomputation. Operations
+// run in the background one document at a time in creation order, throttled
+// to their rate, and checkpoint and report their progress until they complete
+// or are cancelled. A cancelled operation resumes after its checkpoint
+type BulkOperations struct {
+    cfg        config.BulkOperationsConfig
+    documents  repository.DocumentRepository
+    operations repository.BulkOperationRepository
+    pipeline   *DocumentPipeline
+    storage    *StorageService
+    logger     *zap.Logger
+
+    // base is cancelled on shutdown, stopping every running operation
+    base context.Context
+    stop context.CancelFunc
+
+    mu      sync.Mutex
+    running map[string]context.CancelFunc
+}
+
+// NewBulkOperations creates a new bulk operation runner
+func NewBulkOperations(cfg *config.Config, documents repository.DocumentRepository, operations repository.BulkOperationRepository, pipeline *DocumentPipeline, storage *StorageService, logger *zap.Logger) (*BulkOperations, error) {
+    if cfg == nil || documents == nil || operations == nil || pipeline == nil || storage == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    base, stop := context.WithCancel(context.Background())
+    return &BulkOperations{
+        cfg:        cfg.BulkOperationsConfig,
+        documents:  documents,
+        operations: operations,
+        pipeline:   pipeline,
+        storage:    storage,
+        logger:     logger,
+        base:       base,
+        stop:       stop,
+        running:    make(map[string]context.CancelFunc),
+    }, nil
+}
+
+// Run cancels the running operations once the context is cancelled
+func (s *BulkOperations) Run(ctx context.Context) {
+    <-ctx.Done()
+    s.stop()
+}
+
+// Start validates the request, resolves the matching documents and starts
+// the operation in the background. A dry run returns the number of matching
+// documents and a sample of them without changing anything
+func (s *BulkOperations) Start(ctx context.Context, req BulkOperationRequest) (*models.BulkOperation, error) {
+    if err := s.validate(&req); err != nil {
+        return nil, err
+    }
+    targets, err := matchDocuments(ctx, s.documents, req.Filter, s.needs(req.Action, req.Steps))
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve documents: %w", err)
+    }
+    if len(targets) > s.cfg.MaxDocuments {
+        return nil, fmt.Errorf("%w: %d documents, at most %d", ErrBulkOperationTooLarge, len(targets), s.cfg.MaxDocuments)
+    }
+
+    now := time.Now()
+    op := &models.BulkOperation{
+        ID:           uuid.NewString(),
+        Action:       req.Action,
+        Filter:       req.Filter,
+        Steps:        req.Steps,
+        DocumentType: req.DocumentType,
+        DryRun:       req.DryRun,
+        Rate:         req.Rate,
+        RequestedBy:  req.RequestedBy,
+        Status:       models.BulkOperationRunning,
+        Total:        len(targets),
+        CreatedAt:    now,
+        UpdatedAt:    now,
+    }
+
+    if req.DryRun {
+        op.Sample = make([]string, 0, bulkOperationSample)
+        for _, target := range targets[:min(len(targets), bulkOperationSample)] {
+            op.Sample = append(op.Sample, target.DocumentID)
+        }
+        op.Finish(models.BulkOperationCompleted, "", now)
+        if err := s.operations.Create(ctx, op); err != nil {
+            return nil, fmt.Errorf("failed to store bulk operation: %w", err)
+        }
+        return op, nil
+    }
+
+    runCtx, err := s.reserve(op.ID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.operations.Create(ctx, op); err != nil {
+        s.release(op.ID)
+        return nil, fmt.Errorf("failed to store bulk operation: %w", err)
+    }
+    started := *op
+    go s.run(runCtx, op, targets)
+
+    s.logger.Info("Bulk operation started",
+        zap.String("operation_id", op.ID),
+        zap.String("action", op.Action),
+        zap.Int("documents", op.Total),
+        zap.Float64("rate", op.Rate),
+        zap.String("requested_by", op.RequestedBy),
+    )
+    return &started, nil
+}
+
+// Resume continues a cancelled operation, including one stopped by a
+// shutdown, with the documents after its checkpoint. The filter is resolved
+// again, so documents created since that match it are included
+func (s *BulkOperations) Resume(ctx context.Context, id string) (*models.BulkOperation, error) {
+    op, err := s.operations.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if !op.Resumable() {
+        return nil, ErrBulkOperationNotResumable
+    }
+
+    targets, err := matchDocuments(ctx, s.documents, op.Filter, s.needs(op.Action, op.Steps))
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve documents: %w", err)
+    }
+    if op.Checkpoint != nil {
+        next := sort.Search(len(targets), func(i int) bool {
+            return op.Checkpoint.Before(targets[i])
+        })
+        targets = targets[next:]
+    }
+    if len(targets) > s.cfg.MaxDocuments {
+        return nil, fmt.Errorf("%w: %d documents, at most %d", ErrBulkOperationTooLarge, len(targets), s.cfg.MaxDocuments)
+    }
+
+    runCtx, err := s.reserve(op.ID)
+    if err != nil {
+        return nil, err
+    }
+    op.Resume(len(targets), time.Now())
+    if err := s.operations.Update(ctx, op); err != nil {
+        s.release(op.ID)
+        return nil, fmt.Errorf("failed to store bulk operation: %w", err)
+    }
+    resumed := *op
+    go s.run(runCtx, op, targets)
+
+    s.logger.Info("Bulk operation resumed",
+        zap.String("operation_id", op.ID),
+        zap.String("action", op.Action),
+        zap.Int("processed", op.Processed),
+        zap.Int("remaining", len(targets)),
+    )
+    return &resumed, nil
+}
+
+// Get returns an operation with its progress
+func (s *BulkOperations) Get(ctx context.Context, id string) (*models.BulkOperation, error) {
+    return s.operations.Get(ctx, id)
+}
+
+// List returns the most recent operations
+func (s *BulkOperations) List(ctx context.Context, limit int) ([]*models.BulkOperation, error) {
+    return s.operations.List(ctx, limit)
+}
+
+// Cancel stops a running operation after the document in progress; the
+// documents already processed keep their changes
+func (s *BulkOperations) Cancel(ctx context.Context, id string) (*models.BulkOperation, error) {
+    op, err := s.operations.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if op.Finished() {
+        return nil, ErrBulkOperationFinished
+    }
+
+    s.mu.Lock()
+    cancel, ok := s.running[id]
+    s.mu.Unlock()
+    if ok {
+        cancel()
+    }
+
+    s.logger.Info("Bulk operation cancellation requested",
+        zap.String("operation_id", id),
+        zap.Int("processed", op.Processed),
+        zap.Int("total", op.Total),
+    )
+    return op, nil
+}
+
+// validate checks the request and applies the default rate
+func (s *BulkOperations) validate(req *BulkOperationRequest) error {
+    switch req.Action {
+    case models.BulkActionReprocess, models.BulkActionBackfill, models.BulkActionReencrypt, models.BulkActionReclassify, models.BulkActionRecomputeHash:
+    default:
+        return fmt.Errorf("%w: %q", ErrUnknownBulkAction, req.Action)
+    }
+    if req.Filter.Empty() {
+        return ErrEmptyBulkFilter
+    }
+    if len(req.Steps) > 0 && req.Action != models.BulkActionReprocess && req.Action != models.BulkActionBackfill {
+        return fmt.Errorf("%w: steps apply to reprocess and backfill only", ErrInvalidBulkOperation)
+    }
+    for _, name := range req.Steps {
+        if s.pipeline.step(name) == nil {
+            return fmt.Errorf("%w: %s", ErrUnknownStep, name)
+        }
+    }
+    if (req.Action == models.BulkActionReclassify) != (req.DocumentType != "") {
+        return fmt.Errorf("%w: document type is required by reclassify only", ErrInvalidBulkOperation)
+    }
+
+    if req.Rate == 0 {
+        req.Rate = s.cfg.DefaultRate
+    }
+    if req.Rate < 0 || req.Rate > s.cfg.MaxRate {
+        return fmt.Errorf("%w: rate must be positive and at most %g documents per second", ErrInvalidBulkOperation, s.cfg.MaxRate)
+    }
+    return nil
+}
+
+// needs returns which matching documents an action has work on, or nil when
+// it has work on all of them. A backfill only needs the documents missing a
+// step
+func (s *BulkOperations) needs(action string, steps []string) func(*models.Document) bool {
+    if action != models.BulkActionBackfill {
+        return nil
+    }
+    return func(doc *models.Document) bool {
+        return doc.StoragePath != "" && !doc.EncryptionInfo.Shredded() && len(s.pipeline.MissingSteps(doc, steps)) > 0
+    }
+}
+
+// matchDocuments returns the positions of the documents matching the filter,
+// and needing the action when needs is set, in processing order. The lookup
+// is narrowed by the most selective criterion
+func matchDocuments(ctx context.Context, documents repository.DocumentRepository, filter models.BulkOperationFilter, needs func(*models.Document) bool) ([]models.BulkOperationPosition, error) {
+    var docs []*models.Document
+    switch {
+    case len(filter.DocumentIDs) > 0:
+        for _, id := range filter.DocumentIDs {
+            doc, err := documents.GetByID(ctx, id)
+            if errors.Is(err, repository.ErrDocumentNotFound) {
+                continue
+            }
+            if err != nil {
+                return nil, err
+            }
+            docs = append(docs, doc)
+        }
+    case filter.EnrollmentID != "":
+        var err error
+        if docs, err = documents.ListByEnrollment(ctx, filter.EnrollmentID); err != nil {
+            return nil, err
+        }
+    default:
+        // A document created after a time was last updated after it too
+        var from time.Time
+        if filter.CreatedFrom != nil {
+            from = *filter.CreatedFrom
+        }
+        var err error
+        if docs, err = documents.ListUpdatedBetween(ctx, from, time.Now().Add(time.Second)); err != nil {
+            return nil, err
+        }
+    }
+
+    seen := make(map[string]bool, len(docs))
+    targets := make([]models.BulkOperationPosition, 0, len(docs))
+    for _, doc := range docs {
+        if seen[doc.ID] || !filter.Matches(doc) || (needs != nil && !needs(doc)) {
+            continue
+        }
+        seen[doc.ID] = true
+        targets = append(targets, models.PositionOf(doc))
+    }
+    sort.Slice(targets, func(i, j int) bool {
+        return targets[i].Before(targets[j])
+    })
+    return targets, nil
+}
+
+// run applies the operation to each document at the operation's rate,
+// saving the progress and checkpoint after every document
+func (s *BulkOperations) run(ctx context.Context, op *models.BulkOperation, targets []models.BulkOperationPosition) {
+    defer s.release(op.ID)
+
+    limiter := rate.NewLimiter(rate.Limit(op.Rate), 1)
+    status, reason := models.BulkOperationCompleted, ""
+    for _, target := range targets {
+        if limiter.Wait(ctx) != nil {
+            status, reason = models.BulkOperationCancelled, s.cancelReason()
+            break
+        }
+
+        id := target.DocumentID
+        outcome, err := s.apply(ctx, op, id)
+        // A document interrupted by the cancellation is left unprocessed
+        if err != nil && ctx.Err() != nil {
+            status, reason = models.BulkOperationCancelled, s.cancelReason()
+            break
+        }
+        if err != nil {
+            s.logger.Warn("Bulk operation failed on document",
+                zap.String("operation_id", op.ID),
+                zap.String("document_id", id),
+                zap.Error(err),
+            )
+        }
+        bulkOperationDocuments.WithLabelValues(op.Action, outcome).Inc()
+        op.Record(id, outcome, err, time.Now())
+        checkpoint := target
+        op.Checkpoint = &checkpoint
+        s.save(ctx, op)
+    }
+
+    op.Finish(status, reason, time.Now())
+    s.save(ctx, op)
+    s.logger.Info("Bulk operation finished",
+        zap.String("operation_id", op.ID),
+        zap.String("action", op.Action),
+        zap.String("status", op.Status),
+        zap.Int("processed", op.Processed),
+        zap.Int("succeeded", op.Succeeded),
+        zap.Int("skipped", op.Skipped),
+        zap.Int("failed", op.Failed),
+    )
+}
+
+// apply performs the action on one document. Documents deleted since they
+// were matched, and those whose content was destroyed, are skipped
+func (s *BulkOperations) apply(ctx context.Context, op *models.BulkOperation, documentID string) (string, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return models.BulkOutcomeSkipped, nil
+    }
+    if err != nil {
+        return models.BulkOutcomeFailed, err
+    }
+    if doc.StoragePath == "" || doc.EncryptionInfo.Shredded() {
+        return models.BulkOutcomeSkipped, nil
+    }
+    performer := "BULK_OPERATION " + op.ID
+
+    switch op.Action {
+    case models.BulkActionReprocess:
+        return s.reprocess(ctx, doc.ID, op.Steps)
+
+    case models.BulkActionBackfill:
+        // Steps backfilled since the document was matched are not run again
+        missing := s.pipeline.MissingSteps(doc, op.Steps)
+        if len(missing) == 0 {
+            return models.BulkOutcomeSkipped, nil
+        }
+        return s.reprocess(ctx, doc.ID, missing)
+
+    case models.BulkActionReencrypt:
+        if doc.ClientEncrypted() {
+            return models.BulkOutcomeSkipped, nil
+        }
+        if err := s.storage.ReencryptDocument(ctx, doc, s.documents.Update); err != nil {
+            reencryptions.WithLabelValues("failed").Inc()
+            return models.BulkOutcomeFailed, err
+        }
+        reencryptions.WithLabelValues("succeeded").Inc()
+
+    case models.BulkActionReclassify:
+        if doc.DocumentType == op.DocumentType {
+            return models.BulkOutcomeSkipped, nil
+        }
+        if err := doc.Reclassify(op.DocumentType, performer); err != nil {
+            return models.BulkOutcomeFailed, err
+        }
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return models.BulkOutcomeFailed, fmt.Errorf("failed to persist document metadata: %w", err)
+        }
+        // The steps that apply depend on the document type
+        return s.reprocess(ctx, doc.ID, nil)
+
+    case models.BulkActionRecomputeHash:
+        content, err := s.pipeline.content(ctx, doc)
+        if err != nil {
+            return models.BulkOutcomeFailed, err
+        }
+        sum := sha256.Sum256(content)
+        hash := hex.EncodeToString(sum[:])
+        if hash == doc.ContentHash {
+            return models.BulkOutcomeSkipped, nil
+        }
+        doc.SetContentHash(hash, performer)
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return models.BulkOutcomeFailed, fmt.Errorf("failed to persist document metadata: %w", err)
+        }
+    }
+    return models.BulkOutcomeSucceeded, nil
+}
+
+// reprocess runs the named pipeline steps, or every step, on a stored
+// document. The ingest hooks are not run again, so nothing downstream is
+// notified twice of the same document
+func (s *BulkOperations) reprocess(ctx context.Context, documentID string, steps []string) (string, error) {
+    if len(steps) == 0 {
+        steps = s.pipeline.StepNames()
+    }
+    for _, name := range steps {
+        err := s.pipeline.RunStep(ctx, documentID, name)
+        if errors.Is(err, ErrConsentRevoked) {
+            return models.BulkOutcomeSkipped, nil
+        }
+        if err != nil {
+            return models.BulkOutcomeFailed, fmt.Errorf("%s: %w", name, err)
+        }
+    }
+    return models.BulkOutcomeSucceeded, nil
+}
+
+// save persists the progress of an operation, even once it was cancelled
+func (s *BulkOperations) save(ctx context.Context, op *models.BulkOperation) {
+    if err := s.operations.Update(context.WithoutCancel(ctx), op); err != nil {
+        s.logger.Error("Failed to save bulk operation progress",
+            zap.String("operation_id", op.ID),
+            zap.Error(err),
+        )
+    }
+}
+
+// cancelReason explains why a running operation stopped early
+func (s *BulkOperations) cancelReason() string {
+    if s.base.Err() != nil {
+        return "service stopped"
+    }
+    return "cancelled by operator"
+}
+
+// reserve registers a running operation, returning the context it runs in,
+// unless too many operations are running
+func (s *BulkOperations) reserve(id string) (context.Context, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if len(s.running) >= s.cfg.MaxRunning {
+        return nil, ErrTooManyBulkOperations
+    }
+    ctx, cancel := context.WithCancel(s.base)
+    s.running[id] = cancel
+    return ctx, nil
+}
+
+// release forgets a running operation
+func (s *BulkOperations) release(id string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if cancel, ok := s.running[id]; ok {
+        cancel()
+        delete(s.running, id)
+    }
+}
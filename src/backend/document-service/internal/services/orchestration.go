@@ -0,0 +1,165 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+    ErrUnknownStep = errors.New("unknown pipeline step")
+)
+
+// Orchestrator runs the processing steps of stored documents outside the
+// ingestion request, such as on a workflow engine with durable retries.
+// Without one the pipeline runs the steps in process before Ingest returns
+type Orchestrator interface {
+    // Start schedules processing of a document whose metadata is persisted;
+    // the orchestrator runs each step through RunStep and then Finish
+    Start(ctx context.Context, doc *models.Document) error
+}
+
+// UseOrchestrator hands processing to an orchestrator, so Ingest returns once
+// the document is stored; it must be called before the pipeline starts
+// serving requests
+func (p *DocumentPipeline) UseOrchestrator(orchestrator Orchestrator) {
+    p.orchestrator = orchestrator
+}
+
+// StepNames lists the configured steps in execution order
+func (p *DocumentPipeline) StepNames() []string {
+    names := make([]string, 0, len(p.steps))
+    for _, step := range p.steps {
+        names = append(names, step.Name())
+    }
+    return names
+}
+
+// RunStep runs one step on a stored document and persists its results. The
+// content, the extracted text and the provenance of earlier steps are loaded
+// from storage, so steps may run in different processes. A step that does
+// not apply or is switched off succeeds without running, and a document
+// whose subject revoked consent fails with ErrConsentRevoked
+func (p *DocumentPipeline) RunStep(ctx context.Context, documentID, name string) error {
+    step := p.step(name)
+    if step == nil {
+        return fmt.Errorf("%w: %s", ErrUnknownStep, name)
+    }
+    doc, err := p.repository.GetByID(ctx, documentID)
+    if err != nil {
+        return err
+    }
+    if doc.ClientEncrypted() || !p.stepEnabled(doc, step) {
+        return nil
+    }
+    if p.consent.Revoked(doc.EnrollmentID) {
+        return ErrConsentRevoked
+    }
+
+    run, err := p.resume(ctx, doc)
+    if err != nil {
+        return err
+    }
+    runCtx, release := p.consent.Track(ctx, doc)
+    stepErr := p.executeStep(runCtx, run, step)
+    halted := HaltedForConsent(runCtx)
+    release()
+    // Results of a step interrupted by a revocation are discarded
+    if halted {
+        return ErrConsentRevoked
+    }
+
+    if err := p.repository.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist document metadata: %w", err)
+    }
+    return stepErr
+}
+
+// Finish completes the processing of a document once its steps have run: a
+// document whose subject revoked consent is marked halted, any other is
+// handed to the ingest hooks
+func (p *DocumentPipeline) Finish(ctx context.Context, documentID string) error {
+    doc, err := p.repository.GetByID(ctx, documentID)
+    if err != nil {
+        return err
+    }
+    if p.consent.Revoked(doc.EnrollmentID) {
+        consentHalts.Inc()
+        if err := doc.UpdateStatus(models.DocumentStatusHaltedConsent, "Subject consent revoked during processing"); err != nil {
+            return err
+        }
+        if err := p.repository.Update(ctx, doc); err != nil {
+            return fmt.Errorf("failed to persist document metadata: %w", err)
+        }
+        return nil
+    }
+
+    p.notify(ctx, doc)
+    p.logger.Info("Document processing finished",
+        zap.String("document_id", doc.ID),
+        zap.String("status", doc.Status),
+    )
+    return nil
+}
+
+// step returns the configured step with the given name
+func (p *DocumentPipeline) step(name string) PipelineStep {
+    for _, step := range p.steps {
+        if step.Name() == name {
+            return step
+        }
+    }
+    return nil
+}
+
+// resume rebuilds the run state of a stored document: its content, the text
+// recognized by OCR with its layout and the provenance of the artifacts
+// produced so far
+func (p *DocumentPipeline) resume(ctx context.Context, doc *models.Document) (*PipelineRun, error) {
+    content, err := p.content(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    run := &PipelineRun{Document: doc, Content: content, produced: make(map[string]*models.Provenance)}
+
+    for _, rendition := range doc.Renditions {
+        if rendition.Provenance != nil {
+            run.produced[rendition.Provenance.Step] = rendition.Provenance
+        }
+    }
+    for _, field := range doc.ExtractedFields {
+        if field.Provenance != nil {
+            run.produced[field.Provenance.Step] = field.Provenance
+        }
+    }
+
+    if rendition, ok := doc.Rendition(models.RenditionOCRText); ok {
+        reader, _, err := p.storage.OpenRendition(ctx, doc.ID, rendition)
+        if err != nil {
+            return nil, err
+        }
+        defer reader.Close()
+        text, err := io.ReadAll(reader)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read extracted text: %w", err)
+        }
+        run.OCRText = string(text)
+    }
+    if rendition, ok := doc.Rendition(models.RenditionOCRLayout); ok {
+        reader, _, err := p.storage.OpenRendition(ctx, doc.ID, rendition)
+        if err != nil {
+            return nil, err
+        }
+        defer reader.Close()
+        if err := json.NewDecoder(reader).Decode(&run.OCRLines); err != nil {
+            return nil, fmt.Errorf("failed to read text layout: %w", err)
+        }
+    }
+    return run, nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    maxOutboxBackoff = time.Hour
+)
+
+var (
+    // ErrShadowed is returned by outbox handlers that intentionally skipped delivery
+    ErrShadowed = errors.New("delivery skipped in shadow mode")
+)
+
+// OutboxHandler delivers a single outbox message to its destination
+type OutboxHandler func(ctx context.Context, msg *models.OutboxMessage) error
+
+// OutboxDispatcher delivers pending outbox messages with exponential backoff
+type OutboxDispatcher struct {
+    cfg      config.OutboxConfig
+    outbox   repository.OutboxRepository
+    handlers map[string]OutboxHandler
+    logger   *zap.Logger
+}
+
+// NewOutboxDispatcher creates a new outbox dispatcher
+func NewOutboxDispatcher(cfg *config.Config, outbox repository.OutboxRepository, logger *zap.Logger) (*OutboxDispatcher, error) {
+    if cfg == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &OutboxDispatcher{
+        cfg:      cfg.OutboxConfig,
+        outbox:   outbox,
+        handlers: make(map[string]OutboxHandler),
+        logger:   logger,
+    }, nil
+}
+
+// Register sets the handler for a topic; it must be called before Run
+func (d *OutboxDispatcher) Register(topic string, handler OutboxHandler) {
+    d.handlers[topic] = handler
+}
+
+// Run dispatches due messages on the configured interval until the context is cancelled
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.cfg.PollInterval)
+    defer ticker.Stop()
+
+    for {
+        if err := d.DispatchDue(ctx); err != nil {
+            d.logger.Error("Outbox dispatch failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// DispatchDue delivers one batch of due messages
+func (d *OutboxDispatcher) DispatchDue(ctx context.Context) error {
+    messages, err := d.outbox.ListDue(ctx, time.Now(), d.cfg.BatchSize)
+    if err != nil {
+        return fmt.Errorf("failed to list due outbox messages: %w", err)
+    }
+
+    for _, msg := range messages {
+        if err := CheckJobLease(ctx); err != nil {
+            return err
+        }
+        d.deliver(ctx, msg)
+    }
+    return nil
+}
+
+// deliver invokes the topic handler and records the outcome on the message
+func (d *OutboxDispatcher) deliver(ctx context.Context, msg *models.OutboxMessage) {
+    handler, ok := d.handlers[msg.Topic]
+    if !ok {
+        d.logger.Warn("No outbox handler registered", zap.String("topic", msg.Topic))
+        return
+    }
+
+    now := time.Now()
+    msg.Attempts++
+    err := handler(ctx, msg)
+
+    switch {
+    case err == nil:
+        msg.Status = models.OutboxStatusDelivered
+        msg.DeliveredAt = &now
+        msg.LastError = ""
+    case errors.Is(err, ErrShadowed):
+        msg.Status = models.OutboxStatusShadowed
+        msg.LastError = ""
+    case msg.Attempts >= d.cfg.MaxAttempts:
+        msg.Status = models.OutboxStatusDead
+        msg.LastError = err.Error()
+    default:
+        backoff := d.cfg.RetryBackoff << uint(msg.Attempts-1)
+        if backoff <= 0 || backoff > maxOutboxBackoff {
+            backoff = maxOutboxBackoff
+        }
+        msg.NextAttemptAt = now.Add(backoff)
+        msg.LastError = err.Error()
+    }
+
+    outboxDeliveries.WithLabelValues(msg.Topic, msg.Status).Inc()
+
+    if err := d.outbox.Update(ctx, msg); err != nil {
+        d.logger.Error("Failed to record outbox delivery",
+            zap.String("message_id", msg.ID),
+            zap.Error(err),
+        )
+    }
+}
+
+// newOutboxMessage builds a pending message with a JSON payload
+func newOutboxMessage(topic, key string, payload interface{}) (*models.OutboxMessage, error) {
+    encoded, err := json.Marshal(payload)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode outbox payload: %w", err)
+    }
+
+    now := time.Now()
+    return &models.OutboxMessage{
+        ID:            uuid.NewString(),
+        Topic:         topic,
+        Key:           key,
+        Payload:       encoded,
+        Status:        models.OutboxStatusPending,
+        NextAttemptAt: now,
+        CreatedAt:     now,
+    }, nil
+}
+
+// postOutboxMessage posts a message's JSON payload to endpoint, keyed for
+// idempotency by the message key
+func postOutboxMessage(ctx context.Context, client *http.Client, endpoint string, msg *models.OutboxMessage) error {
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg.Payload))
+    if err != nil {
+        return fmt.Errorf("failed to build %s request: %w", msg.Topic, err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Idempotency-Key", msg.Key)
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return fmt.Errorf("%s request failed: %w", msg.Topic, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("%s delivery returned status %d", msg.Topic, resp.StatusCode)
+    }
+    return nil
+}
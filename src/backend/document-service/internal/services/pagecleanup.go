@@ -0,0 +1,233 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    "image/draw"
+    "math/bits"
+
+    "github.com/gen2brain/go-fitz" // v1.23.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepPageCleanup = "page_cleanup"
+
+    // inkLuminance is the gray level below which a pixel counts as ink
+    inkLuminance = 128
+    // scanMargin is the share of each edge ignored when measuring ink, where
+    // scanners leave shadows and punch holes show
+    scanMargin = 0.05
+    // hashSize is the side of the grid a page is reduced to for hashing; each
+    // row yields hashSize bits
+    hashSize = 16
+)
+
+// PageHash is a difference hash of a page: one bit per neighbouring pair of
+// cells of a hashSize grid, set when the right cell is brighter. Rescans of
+// the same page differ in a few bits, different pages in many
+type PageHash [hashSize * hashSize / 64]uint64
+
+// Distance returns the number of bits two hashes differ in
+func (h PageHash) Distance(other PageHash) int {
+    distance := 0
+    for i := range h {
+        distance += bits.OnesCount64(h[i] ^ other[i])
+    }
+    return distance
+}
+
+// PageFingerprint summarizes a rendered page: the share of it covered in ink
+// and its perceptual hash
+type PageFingerprint struct {
+    InkRatio float64
+    Hash     PageHash
+}
+
+// PageCleanupStep leaves blank separator pages and pages scanned twice out of
+// a normalized PDF rendition, so reviewers do not go through them. The
+// original upload keeps every page
+type PageCleanupStep struct {
+    cfg     config.PageCleanupConfig
+    storage *StorageService
+}
+
+// NewPageCleanupStep creates a new blank and duplicate page removal step
+func NewPageCleanupStep(cfg *config.Config, storage *StorageService) (*PageCleanupStep, error) {
+    if cfg == nil || storage == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    return &PageCleanupStep{cfg: cfg.PageCleanupConfig, storage: storage}, nil
+}
+
+// Name returns the step name
+func (s *PageCleanupStep) Name() string {
+    return StepPageCleanup
+}
+
+// Provenance reports the detector version; pages are analyzed locally
+func (s *PageCleanupStep) Provenance() StepProvenance {
+    return StepProvenance{Version: pageCleanupVersion}
+}
+
+// Applies reports whether the document is a PDF
+func (s *PageCleanupStep) Applies(doc *models.Document) bool {
+    return doc.ContentType == "application/pdf"
+}
+
+// Execute renders each page, finds the blank and duplicate ones and stores
+// the remaining pages as the normalized PDF. Single-page documents and those
+// over the page limit are left as uploaded
+func (s *PageCleanupStep) Execute(ctx context.Context, run *PipelineRun) error {
+    pdf, err := fitz.NewFromMemory(run.Content)
+    if err != nil {
+        return fmt.Errorf("failed to open PDF: %w", err)
+    }
+    defer pdf.Close()
+
+    count := pdf.NumPage()
+    if count < 2 || count > s.cfg.MaxPages {
+        return nil
+    }
+    prints := make([]PageFingerprint, count)
+    for i := range prints {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        img, err := pdf.ImageDPI(i, s.cfg.DPI)
+        if err != nil {
+            return fmt.Errorf("failed to render page %d: %w", i+1, err)
+        }
+        prints[i] = FingerprintPage(img)
+    }
+
+    removed := CleanupPages(s.cfg, prints)
+    if len(removed) > 0 {
+        pages, err := splitPDFPages(run.Content)
+        if err != nil {
+            return err
+        }
+        drop := make(map[int]bool, len(removed))
+        for _, page := range removed {
+            drop[page.Number] = true
+        }
+        kept := make([][]byte, 0, len(pages)-len(removed))
+        for i, page := range pages {
+            if !drop[i+1] {
+                kept = append(kept, page)
+            }
+        }
+        normalized, err := mergePDFPages(kept)
+        if err != nil {
+            return err
+        }
+        if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionNormalizedPDF, "application/pdf", normalized); err != nil {
+            return fmt.Errorf("failed to store normalized PDF: %w", err)
+        }
+        for _, page := range removed {
+            pagesRemoved.WithLabelValues(page.Reason).Inc()
+        }
+    }
+    run.Document.RecordPageCleanup(count, removed)
+    return nil
+}
+
+// CleanupPages returns the pages to leave out of a document: blank pages,
+// then pages within the duplicate distance of an earlier page that is kept.
+// A document whose every page is blank is left whole for a reviewer to see
+func CleanupPages(cfg config.PageCleanupConfig, prints []PageFingerprint) []models.RemovedPage {
+    removed := make([]models.RemovedPage, 0)
+    kept := make([]int, 0, len(prints))
+    for i, page := range prints {
+        if page.InkRatio < cfg.BlankInkRatio {
+            removed = append(removed, models.RemovedPage{Number: i + 1, Reason: models.PageRemovalBlank})
+            continue
+        }
+        duplicateOf := 0
+        for _, earlier := range kept {
+            if page.Hash.Distance(prints[earlier].Hash) <= cfg.DuplicateDistance {
+                duplicateOf = earlier + 1
+                break
+            }
+        }
+        if duplicateOf > 0 {
+            removed = append(removed, models.RemovedPage{Number: i + 1, Reason: models.PageRemovalDuplicate, DuplicateOf: duplicateOf})
+            continue
+        }
+        kept = append(kept, i)
+    }
+    if len(kept) == 0 {
+        return nil
+    }
+    return removed
+}
+
+// FingerprintPage measures the ink of a rendered page inside its margins and
+// hashes it
+func FingerprintPage(img image.Image) PageFingerprint {
+    bounds := img.Bounds()
+    gray := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+    draw.Draw(gray, gray.Bounds(), img, bounds.Min, draw.Src)
+
+    marginX := int(float64(gray.Rect.Dx()) * scanMargin)
+    marginY := int(float64(gray.Rect.Dy()) * scanMargin)
+    inner := image.Rect(marginX, marginY, gray.Rect.Dx()-marginX, gray.Rect.Dy()-marginY)
+    if inner.Empty() {
+        return PageFingerprint{}
+    }
+
+    ink := 0
+    for y := inner.Min.Y; y < inner.Max.Y; y++ {
+        row := gray.Pix[y*gray.Stride : y*gray.Stride+gray.Rect.Dx()]
+        for x := inner.Min.X; x < inner.Max.X; x++ {
+            if row[x] < inkLuminance {
+                ink++
+            }
+        }
+    }
+    return PageFingerprint{
+        InkRatio: float64(ink) / float64(inner.Dx()*inner.Dy()),
+        Hash:     differenceHash(gray, inner),
+    }
+}
+
+// differenceHash reduces an area of a page to a grid one cell wider than
+// hashSize by averaging, and compares each cell with its right neighbour
+func differenceHash(gray *image.Gray, area image.Rectangle) PageHash {
+    const columns = hashSize + 1
+    var cells [hashSize][columns]float64
+    for row := 0; row < hashSize; row++ {
+        y0 := area.Min.Y + row*area.Dy()/hashSize
+        y1 := max(area.Min.Y+(row+1)*area.Dy()/hashSize, y0+1)
+        for column := 0; column < columns; column++ {
+            x0 := area.Min.X + column*area.Dx()/columns
+            x1 := max(area.Min.X+(column+1)*area.Dx()/columns, x0+1)
+            sum, pixels := 0, 0
+            for y := y0; y < y1 && y < area.Max.Y; y++ {
+                for x := x0; x < x1 && x < area.Max.X; x++ {
+                    sum += int(gray.Pix[y*gray.Stride+x])
+                    pixels++
+                }
+            }
+            if pixels > 0 {
+                cells[row][column] = float64(sum) / float64(pixels)
+            }
+        }
+    }
+
+    var hash PageHash
+    bit := 0
+    for row := range cells {
+        for column := 0; column < hashSize; column++ {
+            if cells[row][column+1] > cells[row][column] {
+                hash[bit/64] |= 1 << (bit % 64)
+            }
+            bit++
+        }
+    }
+    return hash
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+    ErrNotPaginated  = errors.New("document does not support page-level review")
+    ErrInvalidRescan = errors.New("rescan must be a single-page PDF")
+)
+
+// ReviewPages records page-level decisions on a multi-page PDF. Once every
+// page is approved the document is approved and its enrollment checked
+func (s *ReviewService) ReviewPages(ctx context.Context, documentID string, decisions []models.PageDecision, reviewer string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if err := paginated(doc); err != nil {
+        return nil, err
+    }
+
+    content, err := s.currentPDF(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    pageCount, err := countPDFPages(content)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := doc.ReviewPages(pageCount, decisions, reviewer); err != nil {
+        return nil, err
+    }
+    if err := s.save(ctx, doc); err != nil {
+        return nil, err
+    }
+
+    s.eta.Reviewed(doc)
+    s.sla.Reviewed(doc)
+    return doc, nil
+}
+
+// ReplacePage splices a rescanned page into the document. The original
+// upload is never modified: the spliced PDF is stored as an encrypted
+// rendition that later rescans and underwriting read instead
+func (s *ReviewService) ReplacePage(ctx context.Context, documentID string, number int, content io.Reader, uploadedBy string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if err := paginated(doc); err != nil {
+        return nil, err
+    }
+
+    rescan, err := io.ReadAll(io.LimitReader(content, s.maxPageSize+1))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read rescanned page: %w", err)
+    }
+    if int64(len(rescan)) > s.maxPageSize {
+        return nil, models.ErrInvalidSize
+    }
+    if count, err := countPDFPages(rescan); err != nil || count != 1 {
+        return nil, ErrInvalidRescan
+    }
+
+    current, err := s.currentPDF(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    pages, err := splitPDFPages(current)
+    if err != nil {
+        return nil, err
+    }
+    if number < 1 || number > len(pages) {
+        return nil, fmt.Errorf("%w: page %d", models.ErrInvalidPage, number)
+    }
+
+    sum := sha256.Sum256(rescan)
+    if err := doc.RecordRescan(number, hex.EncodeToString(sum[:]), uploadedBy); err != nil {
+        return nil, err
+    }
+
+    pages[number-1] = rescan
+    spliced, err := mergePDFPages(pages)
+    if err != nil {
+        return nil, err
+    }
+    doc.SetProducer(&models.Provenance{
+        PipelineVersion: s.version,
+        Step:            StepPageRescan,
+        StepVersion:     pageRescanVersion,
+        ProducedAt:      time.Now(),
+    })
+    err = s.storage.StoreEncryptedRendition(ctx, doc, models.RenditionSplicedPDF, "application/pdf", spliced)
+    doc.SetProducer(nil)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Page rescan spliced",
+        zap.String("document_id", doc.ID),
+        zap.Int("page", number),
+        zap.Int("pages", len(pages)),
+    )
+    return doc, nil
+}
+
+// EditPages applies page operations to a document awaiting review. Like a
+// rescan, the edit never modifies the original upload: the edited PDF
+// replaces the spliced rendition, with the operations in its provenance
+func (s *ReviewService) EditPages(ctx context.Context, documentID string, operations []models.PageOperation, editedBy string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if err := paginated(doc); err != nil {
+        return nil, err
+    }
+    if !doc.AwaitingReview() {
+        return nil, models.ErrNotEditable
+    }
+
+    current, err := s.currentPDF(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    pages, err := splitPDFPages(current)
+    if err != nil {
+        return nil, err
+    }
+    layout, err := models.PageLayout(len(pages), operations)
+    if err != nil {
+        return nil, err
+    }
+
+    edited := make([][]byte, len(layout))
+    for i, page := range layout {
+        edited[i] = pages[page.Source-1]
+        if page.Rotation != 0 {
+            if edited[i], err = rotatePDFPage(edited[i], page.Rotation); err != nil {
+                return nil, err
+            }
+        }
+    }
+    merged, err := mergePDFPages(edited)
+    if err != nil {
+        return nil, err
+    }
+
+    // The edit is derived from the spliced or normalized PDF when there is one
+    var inputs []models.Provenance
+    if rendition, ok := doc.CurrentPDF(); ok && rendition.Provenance != nil {
+        inputs = append(inputs, *rendition.Provenance)
+    }
+    if err := doc.RecordPageOperations(operations, layout, editedBy); err != nil {
+        return nil, err
+    }
+    doc.SetProducer(&models.Provenance{
+        PipelineVersion: s.version,
+        Step:            StepPageOperations,
+        StepVersion:     pageOperationsVersion,
+        Inputs:          inputs,
+        PageOperations:  operations,
+        ProducedAt:      time.Now(),
+    })
+    err = s.storage.StoreEncryptedRendition(ctx, doc, models.RenditionSplicedPDF, "application/pdf", merged)
+    doc.SetProducer(nil)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return nil, err
+    }
+
+    s.logger.Info("Document pages edited",
+        zap.String("document_id", doc.ID),
+        zap.Int("operations", len(operations)),
+        zap.Int("pages_before", len(pages)),
+        zap.Int("pages", len(layout)),
+    )
+    return doc, nil
+}
+
+// currentPDF returns the spliced PDF when pages have been replaced, the
+// normalized PDF when pages were removed and the original upload otherwise
+func (s *ReviewService) currentPDF(ctx context.Context, doc *models.Document) ([]byte, error) {
+    if rendition, ok := doc.CurrentPDF(); ok {
+        reader, _, err := s.storage.OpenRendition(ctx, doc.ID, rendition)
+        if err != nil {
+            return nil, err
+        }
+        defer reader.Close()
+        return io.ReadAll(reader)
+    }
+
+    reader, err := s.storage.RetrieveDocument(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    var content bytes.Buffer
+    if _, err := io.Copy(&content, reader); err != nil {
+        return nil, fmt.Errorf("failed to read document: %w", err)
+    }
+    return content.Bytes(), nil
+}
+
+// paginated reports whether the service can read and splice the pages of a
+// document: only PDFs the service can decrypt
+func paginated(doc *models.Document) error {
+    if doc.ContentType != "application/pdf" || doc.ClientEncrypted() {
+        return ErrNotPaginated
+    }
+    return nil
+}
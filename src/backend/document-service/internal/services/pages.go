@@ -0,0 +1,101 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "sync"
+
+    "github.com/pdfcpu/pdfcpu/pkg/api" // v0.6.0
+)
+
+// defaultTenant groups documents ingested without a tenant, such as WhatsApp
+// and SFTP submissions
+const defaultTenant = "default"
+
+// splitPDFPages splits a PDF into single-page PDFs
+func splitPDFPages(content []byte) ([][]byte, error) {
+    spans, err := api.SplitRaw(bytes.NewReader(content), 1, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to split PDF: %w", err)
+    }
+
+    pages := make([][]byte, 0, len(spans))
+    for _, span := range spans {
+        page, err := io.ReadAll(span.Reader)
+        if err != nil {
+            return nil, fmt.Errorf("failed to read page %d: %w", span.From, err)
+        }
+        pages = append(pages, page)
+    }
+    return pages, nil
+}
+
+// countPDFPages returns the number of pages of a PDF
+func countPDFPages(content []byte) (int, error) {
+    count, err := api.PageCount(bytes.NewReader(content), nil)
+    if err != nil {
+        return 0, fmt.Errorf("failed to count PDF pages: %w", err)
+    }
+    return count, nil
+}
+
+// mergePDFPages joins PDFs into one, in order
+func mergePDFPages(pages [][]byte) ([]byte, error) {
+    readers := make([]io.ReadSeeker, len(pages))
+    for i, page := range pages {
+        readers[i] = bytes.NewReader(page)
+    }
+    var merged bytes.Buffer
+    if err := api.MergeRaw(readers, &merged, false, nil); err != nil {
+        return nil, fmt.Errorf("failed to merge PDF pages: %w", err)
+    }
+    return merged.Bytes(), nil
+}
+
+// rotatePDFPage rotates a single-page PDF clockwise by a multiple of 90 degrees
+func rotatePDFPage(page []byte, degrees int) ([]byte, error) {
+    var rotated bytes.Buffer
+    if err := api.Rotate(bytes.NewReader(page), &rotated, degrees, nil, nil); err != nil {
+        return nil, fmt.Errorf("failed to rotate page: %w", err)
+    }
+    return rotated.Bytes(), nil
+}
+
+// tenantSlots bounds concurrent OCR requests per tenant so one tenant's bulk
+// upload cannot take every Azure slot from the others
+type tenantSlots struct {
+    mu    sync.Mutex
+    size  int
+    slots map[string]chan struct{}
+}
+
+func newTenantSlots(size int) *tenantSlots {
+    return &tenantSlots{
+        size:  size,
+        slots: make(map[string]chan struct{}),
+    }
+}
+
+// acquire waits for a slot of the tenant; the returned func releases it
+func (t *tenantSlots) acquire(ctx context.Context, tenant string) (func(), error) {
+    if tenant == "" {
+        tenant = defaultTenant
+    }
+
+    t.mu.Lock()
+    slots, ok := t.slots[tenant]
+    if !ok {
+        slots = make(chan struct{}, t.size)
+        t.slots[tenant] = slots
+    }
+    t.mu.Unlock()
+
+    select {
+    case slots <- struct{}{}:
+        return func() { <-slots }, nil
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    }
+}
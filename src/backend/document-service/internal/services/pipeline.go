@@ -0,0 +1,985 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "slices"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "github.com/sony/gobreaker" // v0.5.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Pipeline step names
+const (
+    StepOCR = "ocr"
+)
+
+var (
+    ErrEmptyContent = errors.New("document content is empty")
+    // ErrNotComposable is returned for a composition with a part that is not
+    // an image, or of an end-to-end encrypted upload
+    ErrNotComposable = errors.New("only images can be composed into a document")
+)
+
+// IngestRequest describes a document entering the service through any ingestion channel
+type IngestRequest struct {
+    EnrollmentID string
+    TenantID     string
+    DocumentType string
+    Filename     string
+    ContentType  string
+    Channel      string
+    SubmittedBy  string
+    // ClientEncrypted marks Content as an envelope the client encrypted to
+    // the underwriting team; ContentType is then the type of the plaintext
+    ClientEncrypted bool
+    Content      io.Reader
+    // Size is the declared content size when known; it sizes the read buffer
+    Size         int64
+    // Parts are images composed in order into one PDF document, in place of
+    // Content; Filename and ContentType are then those of the PDF
+    Parts        []IngestPart
+    // Synthetic marks an upload of the synthetic probe
+    Synthetic    bool
+    // ReleasedFrom is the quarantine item a malware upload is released from;
+    // the reviewer's verdict stands in for the virus scanner
+    ReleasedFrom string
+}
+
+// IngestPart is one image of a composed document
+type IngestPart struct {
+    ContentType string
+    Content     io.Reader
+}
+
+// ingestOriginal is an upload kept as an encrypted rendition of the
+// document made from it
+type ingestOriginal struct {
+    name        string
+    contentType string
+    content     []byte
+}
+
+// PipelineRun carries per-document state shared between pipeline steps. Content
+// is held in a pooled buffer and must not be retained once the run completes
+type PipelineRun struct {
+    Document *models.Document
+    Content  []byte
+    OCRText  string
+    // OCRLines is the layout of the recognized text
+    OCRLines []models.OCRLine
+
+    // produced holds the provenance of each step that succeeded in the run
+    produced map[string]*models.Provenance
+}
+
+// PipelineStep is a processing stage executed after a document has been stored
+type PipelineStep interface {
+    Name() string
+    Applies(doc *models.Document) bool
+    Execute(ctx context.Context, run *PipelineRun) error
+}
+
+// IngestHook is notified once a document and its step results have been persisted
+type IngestHook func(ctx context.Context, doc *models.Document) error
+
+// DocumentIngester ingests an upload into a stored, processed document.
+// DocumentPipeline is the implementation; channels depend on this interface
+type DocumentIngester interface {
+    Ingest(ctx context.Context, req IngestRequest) (*models.Document, error)
+}
+
+// DocumentPipeline ingests documents from every channel through the same
+// validation, storage, persistence and processing stages
+type DocumentPipeline struct {
+    storage    *StorageService
+    repository repository.DocumentRepository
+    steps      []PipelineStep
+    hooks      []IngestHook
+    flags      *FeatureFlags
+    consent    *ConsentRegistry
+    clientEncryption *ClientEncryption
+    converter  DocumentConverter
+    // scanner rejects uploads carrying malware when set
+    scanner    *VirusScanner
+    // disarmer rebuilds PDFs carrying active content when set
+    disarmer   *PDFDisarmer
+    // quarantine holds infected uploads and disarmed originals when set
+    quarantine *QuarantineService
+    processing *ProcessingCatalog
+    service    config.ServiceConfig
+    version    string
+    // orchestrator runs the steps outside the request when set
+    orchestrator Orchestrator
+    eta        *ETAEstimator
+    // slo defers OCR while its error budget is blown
+    slo        *SLOMonitor
+    // tiers route documents to workers by size and page count when set
+    tiers      *ProcessingTiers
+    // large accepts uploads past the channel size limit when set
+    large      *LargeDocuments
+    // storageBreaker guards the storage calls when set
+    storageBreaker *gobreaker.CircuitBreaker
+    // maintenance refuses new documents while the service is read-only
+    maintenance *MaintenanceMode
+    logger     *zap.Logger
+}
+
+// NewDocumentPipeline creates a new pipeline executing the given steps in order;
+// flags may be nil, in which case every step runs
+func NewDocumentPipeline(cfg *config.Config, storage *StorageService, repo repository.DocumentRepository, flags *FeatureFlags, logger *zap.Logger, steps ...PipelineStep) (*DocumentPipeline, error) {
+    if cfg == nil || storage == nil || repo == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &DocumentPipeline{
+        storage:    storage,
+        repository: repo,
+        steps:      steps,
+        flags:      flags,
+        processing: NewProcessingCatalog(cfg),
+        service:    cfg.ServiceConfig,
+        version:    PipelineVersion(cfg),
+        logger:     logger,
+    }, nil
+}
+
+// OnIngested registers a hook run after every successful ingestion; hooks
+// must be registered before the pipeline starts serving requests
+func (p *DocumentPipeline) OnIngested(hook IngestHook) {
+    p.hooks = append(p.hooks, hook)
+}
+
+// UseConsent halts processing of documents whose subject revokes consent;
+// it must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseConsent(registry *ConsentRegistry) {
+    p.consent = registry
+}
+
+// UseClientEncryption accepts end-to-end encrypted uploads; it must be called
+// before the pipeline starts serving requests
+func (p *DocumentPipeline) UseClientEncryption(encryption *ClientEncryption) {
+    p.clientEncryption = encryption
+}
+
+// UseConverter converts office documents to PDF on the channels configured
+// to; it must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseConverter(converter DocumentConverter) {
+    p.converter = converter
+}
+
+// UseScanner scans uploads for malware before they are converted or stored;
+// it must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseScanner(scanner *VirusScanner) {
+    p.scanner = scanner
+}
+
+// UseDisarmer rebuilds PDF uploads carrying active content before they are
+// stored; it must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseDisarmer(disarmer *PDFDisarmer) {
+    p.disarmer = disarmer
+}
+
+// UseQuarantine holds infected uploads and the originals of disarmed PDFs
+// for review
+func (p *DocumentPipeline) UseQuarantine(quarantine *QuarantineService) {
+    p.quarantine = quarantine
+}
+
+// UseETA records step durations for processing estimates; it must be called
+// before the pipeline starts serving requests
+func (p *DocumentPipeline) UseETA(eta *ETAEstimator) {
+    p.eta = eta
+}
+
+// UseSLO records OCR outcomes against the OCR error budget and defers OCR
+// while it is blown; it must be called before the pipeline starts serving
+// requests
+func (p *DocumentPipeline) UseSLO(slo *SLOMonitor) {
+    p.slo = slo
+}
+
+// UseTiers processes documents in tiers by size and page count; it must be
+// called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseTiers(tiers *ProcessingTiers) {
+    p.tiers = tiers
+}
+
+// UseLargeDocuments accepts uploads past the size limit of their channel as
+// large documents; it must be called before serving requests
+func (p *DocumentPipeline) UseLargeDocuments(large *LargeDocuments) {
+    p.large = large
+}
+
+// UseStorageBreaker stores new documents through the breaker, so only
+// storage failures count against it and not uploads the pipeline rejects; it
+// must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseStorageBreaker(breaker *gobreaker.CircuitBreaker) {
+    p.storageBreaker = breaker
+}
+
+// UseMaintenance refuses new documents from every channel while the service
+// is read-only; it must be called before the pipeline starts serving requests
+func (p *DocumentPipeline) UseMaintenance(maintenance *MaintenanceMode) {
+    p.maintenance = maintenance
+}
+
+// ReadOnly reports whether new documents are refused for maintenance
+func (p *DocumentPipeline) ReadOnly() bool {
+    return p.maintenance.Active()
+}
+
+// MaxUploadSize returns the largest upload a channel accepts, that of large
+// documents when they are accepted
+func (p *DocumentPipeline) MaxUploadSize(channel string) int64 {
+    return max(p.ContentPolicy(channel).MaxFileSize, p.large.MaxSize())
+}
+
+// ContentPolicy returns the types, size cap and conversions of an ingestion
+// channel
+func (p *DocumentPipeline) ContentPolicy(channel string) config.ChannelPolicy {
+    return p.service.Channel(channel)
+}
+
+// PendingSteps lists the steps still to run on a stored document in
+// execution order: those that apply, are switched on and have not recorded
+// their processing activity. Without an orchestrator the steps run before
+// the document is stored, so none is ever pending
+func (p *DocumentPipeline) PendingSteps(doc *models.Document) []string {
+    if p == nil || p.orchestrator == nil || doc.ClientEncrypted() {
+        return nil
+    }
+
+    done := make(map[string]bool, len(doc.ProcessingActivities))
+    for _, activity := range doc.ProcessingActivities {
+        done[activity.Operation] = true
+    }
+    pending := make([]string, 0, len(p.steps))
+    for _, step := range p.steps {
+        if done[step.Name()] || !step.Applies(doc) || !p.flags.Enabled(StepFlag(step.Name()), true) {
+            continue
+        }
+        pending = append(pending, step.Name())
+    }
+    return pending
+}
+
+// MissingSteps lists, in execution order, the named steps, or every step,
+// that apply to a stored document and are switched on but never succeeded on
+// it, such as steps added after the document was processed
+func (p *DocumentPipeline) MissingSteps(doc *models.Document, names []string) []string {
+    if doc.ClientEncrypted() {
+        return nil
+    }
+
+    missing := make([]string, 0, len(p.steps))
+    for _, step := range p.steps {
+        if len(names) > 0 && !slices.Contains(names, step.Name()) {
+            continue
+        }
+        if doc.Processed(step.Name()) || !step.Applies(doc) || !p.flags.Enabled(StepFlag(step.Name()), true) {
+            continue
+        }
+        missing = append(missing, step.Name())
+    }
+    return missing
+}
+
+// Ingest validates, stores and processes a document, returning the persisted
+// model. With an orchestrator the document is returned once processing is
+// scheduled
+func (p *DocumentPipeline) Ingest(ctx context.Context, req IngestRequest) (*models.Document, error) {
+    if req.Content == nil && len(req.Parts) == 0 {
+        return nil, ErrEmptyContent
+    }
+    if _, refused := p.maintenance.RefuseWrite(); refused {
+        return nil, ErrMaintenance
+    }
+    if p.consent.Revoked(req.EnrollmentID) {
+        return nil, ErrConsentRevoked
+    }
+
+    policy := p.ContentPolicy(req.Channel)
+    if len(req.Parts) > 0 {
+        return p.compose(ctx, req, policy)
+    }
+
+    // Read at most one byte past the limit so oversize content is detected without buffering it all
+    content, err := utils.ReadPooled(io.LimitReader(req.Content, p.MaxUploadSize(req.Channel)+1), int(req.Size))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read document content: %w", err)
+    }
+    defer utils.PutBuffer(content)
+    if len(content) == 0 {
+        return nil, ErrEmptyContent
+    }
+    if int64(len(content)) > p.MaxUploadSize(req.Channel) {
+        return nil, models.ErrInvalidSize
+    }
+
+    var clientEncryption *models.ClientEncryption
+    if req.ClientEncrypted {
+        if clientEncryption, err = p.clientEncryption.Accept(req.DocumentType, content); err != nil {
+            return nil, err
+        }
+    }
+
+    // The model accepts every registered type; each channel may accept fewer
+    if !policy.Accepts(req.ContentType) {
+        return nil, models.ErrInvalidContentType
+    }
+    // Content past the channel limit counts against the tenant's quota of
+    // large documents before any work is spent on it
+    if int64(len(content)) > policy.MaxFileSize {
+        if err := p.large.Admit(req.TenantID); err != nil {
+            return nil, err
+        }
+    }
+    // The envelope of an end-to-end encrypted upload can neither be scanned
+    // nor converted
+    original := content
+    var virusScan *models.VirusScan
+    if !req.ClientEncrypted {
+        if virusScan, err = p.scan(ctx, req, req.ContentType, original); err != nil {
+            return nil, err
+        }
+    }
+    convert := policy.Conversion(req.ContentType) == models.ConversionPDF && !req.ClientEncrypted
+    if convert {
+        if content, err = convertToPDF(ctx, p.converter, req.ContentType, original, p.service.MaxImagePages); err != nil {
+            return nil, err
+        }
+    }
+    // PDFs made by the converter carry no active content; uploaded ones may
+    var disarmed []byte
+    var findings []string
+    if p.disarmer != nil && req.ContentType == "application/pdf" && !req.ClientEncrypted {
+        if disarmed, findings, err = p.disarmer.Disarm(ctx, original, req.Filename); err != nil {
+            return nil, err
+        }
+    }
+    doc, err := p.newDocument(req, req.ContentType, int64(len(original)), policy.MaxFileSize)
+    if err != nil {
+        return nil, err
+    }
+    doc.ClientEncryption = clientEncryption
+    if virusScan != nil {
+        doc.RecordVirusScan(virusScan)
+    }
+    if req.ReleasedFrom != "" {
+        doc.RecordRelease(req.ReleasedFrom)
+    }
+
+    var originals []ingestOriginal
+    if convert {
+        doc.MarkConverted(req.ContentType, int64(len(content)))
+        originals = append(originals, ingestOriginal{name: models.RenditionOriginal, contentType: req.ContentType, content: original})
+    }
+    if disarmed != nil {
+        content = disarmed
+        doc.MarkDisarmed(findings, int64(len(content)))
+        originals = append(originals, ingestOriginal{name: models.RenditionQuarantinedOriginal, contentType: req.ContentType, content: original})
+        p.logger.Warn("Upload rebuilt without active content",
+            zap.String("document_id", doc.ID),
+            zap.String("enrollment_id", doc.EnrollmentID),
+            zap.Strings("findings", findings),
+        )
+    }
+    if doc, err = p.store(ctx, req, doc, content, originals); err != nil {
+        return nil, err
+    }
+    // The quarantined original is withheld whether or not it is held for
+    // review, so the upload stands when holding it fails
+    if disarmed != nil && p.quarantine != nil {
+        if _, err := p.quarantine.HoldDisarmed(ctx, doc); err != nil {
+            p.logger.Error("Failed to hold quarantined original for review",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+        }
+    }
+    return doc, nil
+}
+
+// compose makes one PDF document of the image parts of a request, with the
+// pages of each part in order. The channel size cap bounds the parts
+// together, as it would a single upload
+func (p *DocumentPipeline) compose(ctx context.Context, req IngestRequest, policy config.ChannelPolicy) (*models.Document, error) {
+    if req.ClientEncrypted {
+        return nil, ErrNotComposable
+    }
+
+    images := make([]imagePart, 0, len(req.Parts))
+    originals := make([]ingestOriginal, 0, len(req.Parts))
+    partTypes := make([]string, 0, len(req.Parts))
+    var scans []*models.VirusScan
+    var size int64
+    for i, part := range req.Parts {
+        contentType, _ := models.LookupContentType(part.ContentType)
+        if !contentType.Image() {
+            return nil, ErrNotComposable
+        }
+        if !policy.Accepts(part.ContentType) {
+            return nil, models.ErrInvalidContentType
+        }
+        // Parts are kept until stored, so they are not read into pooled buffers
+        content, err := io.ReadAll(io.LimitReader(part.Content, policy.MaxFileSize-size+1))
+        if err != nil {
+            return nil, fmt.Errorf("failed to read part %d: %w", i+1, err)
+        }
+        if len(content) == 0 {
+            return nil, ErrEmptyContent
+        }
+        if size += int64(len(content)); size > policy.MaxFileSize {
+            return nil, models.ErrInvalidSize
+        }
+        scan, err := p.scan(ctx, req, part.ContentType, content)
+        if err != nil {
+            return nil, err
+        }
+        if scan != nil {
+            scans = append(scans, scan)
+        }
+        images = append(images, imagePart{contentType: part.ContentType, content: content})
+        originals = append(originals, ingestOriginal{name: models.OriginalRendition(i), contentType: part.ContentType, content: content})
+        partTypes = append(partTypes, part.ContentType)
+    }
+
+    content, err := composePDF(images, p.service.MaxImagePages)
+    if err != nil {
+        return nil, err
+    }
+    doc, err := p.newDocument(req, "application/pdf", int64(len(content)), policy.MaxFileSize)
+    if err != nil {
+        return nil, err
+    }
+    doc.MarkComposed(partTypes)
+    if len(scans) > 0 {
+        // Every part is clean, so the verdict of the last stands for all;
+        // it counts as cached only when each part's verdict was
+        combined := *scans[len(scans)-1]
+        for _, scan := range scans {
+            combined.Cached = combined.Cached && scan.Cached
+        }
+        doc.RecordVirusScan(&combined)
+    }
+    return p.store(ctx, req, doc, content, originals)
+}
+
+// scan returns the verdict of the virus scanner on an upload, or nil without
+// a scanner or for an upload released from quarantine. Uploads carrying
+// malware are rejected, and held in quarantine when it is enabled
+func (p *DocumentPipeline) scan(ctx context.Context, req IngestRequest, contentType string, content []byte) (*models.VirusScan, error) {
+    if p.scanner == nil || req.ReleasedFrom != "" {
+        return nil, nil
+    }
+    scan, err := p.scanner.Scan(ctx, content)
+    if err != nil {
+        return nil, err
+    }
+    if scan.Infected() {
+        p.logger.Warn("Upload rejected by the virus scanner",
+            zap.String("enrollment_id", req.EnrollmentID),
+            zap.String("channel", req.Channel),
+            zap.String("submitted_by", req.SubmittedBy),
+            zap.String("signature", scan.Signature),
+            zap.Bool("cached", scan.Cached),
+        )
+        if p.quarantine != nil {
+            if _, err := p.quarantine.HoldUpload(ctx, req, contentType, content, scan.Signature); err != nil {
+                p.logger.Error("Failed to quarantine infected upload",
+                    zap.String("enrollment_id", req.EnrollmentID),
+                    zap.Error(err),
+                )
+            }
+        }
+        return nil, fmt.Errorf("%w: %s", ErrMalwareDetected, scan.Signature)
+    }
+    return scan, nil
+}
+
+// newDocument creates the model of a document entering through a request,
+// a large document when it is past limit, the size limit of its channel.
+// Nothing waits on the processing of an end-to-end encrypted upload
+func (p *DocumentPipeline) newDocument(req IngestRequest, contentType string, size, limit int64) (*models.Document, error) {
+    var doc *models.Document
+    var err error
+    if size > limit {
+        var delayed []string
+        if !req.ClientEncrypted {
+            delayed = models.LargeDocumentCapabilities
+        }
+        doc, err = models.NewLargeDocument(req.EnrollmentID, req.DocumentType, req.Filename, contentType, size, limit, delayed)
+    } else {
+        doc, err = models.NewDocument(req.EnrollmentID, req.DocumentType, req.Filename, contentType, size)
+    }
+    if err != nil {
+        return nil, err
+    }
+    doc.ID = uuid.NewString()
+    doc.TenantID = req.TenantID
+    doc.IngestionChannel = req.Channel
+    doc.Synthetic = req.Synthetic
+    return doc, nil
+}
+
+// storeContent stores the content of a new document and the uploads it was
+// made from, through the storage breaker when one is set
+func (p *DocumentPipeline) storeContent(ctx context.Context, doc *models.Document, content []byte, originals []ingestOriginal) error {
+    put := func() error {
+        if err := p.storage.StoreDocument(ctx, doc, bytes.NewReader(content)); err != nil {
+            return err
+        }
+        for _, original := range originals {
+            if err := p.storage.StoreEncryptedRendition(ctx, doc, original.name, original.contentType, original.content); err != nil {
+                return fmt.Errorf("failed to store original upload: %w", err)
+            }
+        }
+        return nil
+    }
+    if p.storageBreaker == nil {
+        return put()
+    }
+    _, err := p.storageBreaker.Execute(func() (interface{}, error) {
+        return nil, put()
+    })
+    return err
+}
+
+// store stores, persists and processes a new document, keeping the uploads
+// it was made from as encrypted renditions
+func (p *DocumentPipeline) store(ctx context.Context, req IngestRequest, doc *models.Document, content []byte, originals []ingestOriginal) (*models.Document, error) {
+    // A document processed in the request waits for a worker of its tier
+    // before anything is stored, so a full queue refuses it cleanly
+    p.tiers.Route(doc, content)
+    if p.orchestrator == nil && !p.deferOCR(doc) && !p.queue(doc) {
+        release, err := p.tiers.Acquire(ctx, doc)
+        if err != nil {
+            return nil, err
+        }
+        defer release()
+    }
+
+    if err := p.storeContent(ctx, doc, content, originals); err != nil {
+        return nil, err
+    }
+
+    // A workflow processes the document as persisted, so its run is counted
+    // before the document is stored
+    if p.orchestrator != nil && !p.deferOCR(doc) && !p.queue(doc) {
+        doc.StartProcessingRun()
+    }
+    if err := p.repository.Create(ctx, doc); err != nil {
+        return nil, fmt.Errorf("failed to persist document metadata: %w", err)
+    }
+
+    var err error
+    switch {
+    case p.deferOCR(doc):
+        err = p.deferProcessing(ctx, doc)
+    case p.queue(doc):
+        err = p.queueProcessing(ctx, doc)
+    case p.orchestrator != nil:
+        err = p.orchestrator.Start(ctx, doc)
+    default:
+        err = p.process(ctx, doc, content)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    p.logger.Info("Document ingested",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("channel", doc.IngestionChannel),
+        zap.String("submitted_by", req.SubmittedBy),
+        zap.String("status", doc.Status),
+    )
+    return doc, nil
+}
+
+// Reprocess runs the processing steps again on a document halted by a consent
+// revocation, once consent has been granted again
+func (p *DocumentPipeline) Reprocess(ctx context.Context, documentID string) (*models.Document, error) {
+    doc, err := p.repository.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if doc.Status != models.DocumentStatusHaltedConsent {
+        return nil, ErrNotHaltedForConsent
+    }
+    if p.consent.Revoked(doc.EnrollmentID) {
+        return nil, ErrConsentRevoked
+    }
+
+    if err := p.restart(ctx, doc, "Reprocessing after consent was granted"); err != nil {
+        return nil, err
+    }
+
+    p.logger.Info("Document reprocessed",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("status", doc.Status),
+    )
+    return doc, nil
+}
+
+// ProcessDeferred runs the processing steps on a document stored while OCR
+// was deferred
+func (p *DocumentPipeline) ProcessDeferred(ctx context.Context, documentID string) (*models.Document, error) {
+    doc, err := p.repository.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if doc.Status != models.DocumentStatusOCRDeferred {
+        return nil, ErrNotOCRDeferred
+    }
+
+    if err := p.restart(ctx, doc, "Processing after deferred OCR"); err != nil {
+        return nil, err
+    }
+
+    p.logger.Info("Deferred document processed",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("status", doc.Status),
+    )
+    return doc, nil
+}
+
+// ProcessQueued runs the processing steps on a large document queued for
+// background processing
+func (p *DocumentPipeline) ProcessQueued(ctx context.Context, documentID string) (*models.Document, error) {
+    doc, err := p.repository.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if doc.Status != models.DocumentStatusQueued {
+        return nil, ErrNotQueued
+    }
+
+    if err := p.restart(ctx, doc, "Processing large document in the background"); err != nil {
+        return nil, err
+    }
+
+    p.logger.Info("Large document processed",
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.String("status", doc.Status),
+    )
+    return doc, nil
+}
+
+// restart runs the processing steps again on a stored document whose
+// processing stopped or never started
+func (p *DocumentPipeline) restart(ctx context.Context, doc *models.Document, reason string) error {
+    if err := doc.UpdateStatus(models.DocumentStatusCompleted, reason); err != nil {
+        return err
+    }
+    if p.orchestrator != nil {
+        doc.StartProcessingRun()
+        if err := p.repository.Update(ctx, doc); err != nil {
+            return fmt.Errorf("failed to persist document metadata: %w", err)
+        }
+        return p.orchestrator.Start(ctx, doc)
+    }
+
+    release, err := p.tiers.Acquire(ctx, doc)
+    if err != nil {
+        return err
+    }
+    defer release()
+    plaintext, err := p.content(ctx, doc)
+    if err != nil {
+        return err
+    }
+    return p.process(ctx, doc, plaintext)
+}
+
+// deferOCR reports whether OCR, and with it the processing of the document,
+// is deferred: the OCR error budget is blown and OCR would read the document.
+// Synthetic documents are never deferred, so the probe keeps exercising OCR
+func (p *DocumentPipeline) deferOCR(doc *models.Document) bool {
+    if !p.slo.DeferOCR() || doc.ClientEncrypted() || doc.Synthetic {
+        return false
+    }
+    for _, step := range p.steps {
+        if step.Name() == StepOCR {
+            return step.Applies(doc) && p.flags.Enabled(StepFlag(step.Name()), true)
+        }
+    }
+    return false
+}
+
+// deferProcessing persists a stored document as ocr_deferred without running
+// any step; hooks are skipped until it is processed
+func (p *DocumentPipeline) deferProcessing(ctx context.Context, doc *models.Document) error {
+    if err := doc.UpdateStatus(models.DocumentStatusOCRDeferred, "OCR deferred while its error budget is exhausted"); err != nil {
+        return err
+    }
+    if err := p.repository.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist document metadata: %w", err)
+    }
+    ocrDeferrals.WithLabelValues("deferred").Inc()
+    return nil
+}
+
+// queue reports whether the processing of a large document is queued for
+// the background, which it is unless an orchestrator already runs it
+// outside the request. End-to-end encrypted uploads have nothing to process
+func (p *DocumentPipeline) queue(doc *models.Document) bool {
+    return doc.LargeDocument != nil && p.orchestrator == nil && !doc.ClientEncrypted()
+}
+
+// queueProcessing persists a stored large document as queued_for_processing
+// without running any step; hooks are skipped until it is processed
+func (p *DocumentPipeline) queueProcessing(ctx context.Context, doc *models.Document) error {
+    if err := doc.UpdateStatus(models.DocumentStatusQueued, "Large document queued for background processing"); err != nil {
+        return err
+    }
+    if err := p.repository.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist document metadata: %w", err)
+    }
+    largeDocuments.WithLabelValues("queued").Inc()
+    return nil
+}
+
+// content reads the plaintext of a stored document
+func (p *DocumentPipeline) content(ctx context.Context, doc *models.Document) ([]byte, error) {
+    content, err := p.storage.RetrieveDocument(ctx, doc)
+    if err != nil {
+        return nil, err
+    }
+    if closer, ok := content.(io.Closer); ok {
+        defer closer.Close()
+    }
+    plaintext, err := io.ReadAll(content)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read document content: %w", err)
+    }
+    return plaintext, nil
+}
+
+// process runs the steps under a context cancelled if the subject revokes
+// consent or the timeout of the document's tier passes, persists the
+// results and notifies the ingest hooks. A halted document is persisted as
+// processing_halted_consent and hooks are skipped so nothing downstream acts
+// on it
+func (p *DocumentPipeline) process(ctx context.Context, doc *models.Document, content []byte) error {
+    runCtx, release := p.consent.Track(ctx, doc)
+    if timeout := p.tiers.Timeout(doc); timeout > 0 {
+        var cancel context.CancelFunc
+        runCtx, cancel = context.WithTimeout(runCtx, timeout)
+        defer cancel()
+    }
+    doc.StartProcessingRun()
+    p.runSteps(runCtx, &PipelineRun{Document: doc, Content: content, produced: make(map[string]*models.Provenance)})
+    halted := HaltedForConsent(runCtx)
+    release()
+
+    if halted {
+        consentHalts.Inc()
+        if err := doc.UpdateStatus(models.DocumentStatusHaltedConsent, "Subject consent revoked during processing"); err != nil {
+            return err
+        }
+    }
+
+    if err := p.repository.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist document metadata: %w", err)
+    }
+    if halted {
+        return nil
+    }
+    p.notify(ctx, doc)
+    return nil
+}
+
+// notify runs the ingest hooks on a processed document. Synthetic documents
+// exercise the pipeline only, so nothing downstream hears of them
+func (p *DocumentPipeline) notify(ctx context.Context, doc *models.Document) {
+    if doc.Synthetic {
+        return
+    }
+    for _, hook := range p.hooks {
+        if err := hook(ctx, doc); err != nil {
+            p.logger.Warn("Ingest hook failed",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+        }
+    }
+}
+
+// runSteps executes applicable steps in order; step failures are logged and do
+// not fail the ingestion since the document is already safely stored. Steps
+// whose kill switch is off are skipped, and no further step starts once the
+// subject revokes consent. End-to-end encrypted content is opaque to every
+// step, so none runs on it. The renditions and fields a step records carry
+// its provenance
+func (p *DocumentPipeline) runSteps(ctx context.Context, run *PipelineRun) {
+    if run.Document.ClientEncrypted() {
+        p.logger.Debug("Pipeline steps skipped for end-to-end encrypted document",
+            zap.String("document_id", run.Document.ID),
+        )
+        return
+    }
+
+    for _, step := range p.steps {
+        if HaltedForConsent(ctx) {
+            p.logger.Info("Pipeline halted by consent revocation",
+                zap.String("step", step.Name()),
+                zap.String("document_id", run.Document.ID),
+            )
+            return
+        }
+        if !p.stepEnabled(run.Document, step) {
+            continue
+        }
+        p.executeStep(ctx, run, step)
+    }
+}
+
+// stepEnabled reports whether a step applies to the document and its kill
+// switch is on
+func (p *DocumentPipeline) stepEnabled(doc *models.Document, step PipelineStep) bool {
+    if !step.Applies(doc) {
+        return false
+    }
+    if !p.flags.Enabled(StepFlag(step.Name()), true) {
+        p.logger.Debug("Pipeline step disabled by feature flag",
+            zap.String("step", step.Name()),
+            zap.String("document_id", doc.ID),
+        )
+        return false
+    }
+    return true
+}
+
+// executeStep runs one step, recording its duration, its processing activity
+// and, on success, the provenance later steps take as input
+func (p *DocumentPipeline) executeStep(ctx context.Context, run *PipelineRun, step PipelineStep) error {
+    run.Document.SetProducer(p.stepProvenance(run, step))
+    depth := p.eta.startStep(step.Name())
+    startTime := time.Now()
+    err := step.Execute(ctx, run)
+    // Steps wrapping others, such as experiments, may refine the producer
+    provenance := run.Document.Producer()
+    run.Document.SetProducer(nil)
+    duration := time.Since(startTime)
+    pipelineStepDuration.WithLabelValues(step.Name()).Observe(duration.Seconds())
+    p.eta.finishStep(run.Document.DocumentType, step.Name(), duration, depth)
+    p.processing.Record(run.Document, step.Name(), err)
+    if step.Name() == StepOCR && ctx.Err() == nil {
+        p.slo.RecordOCR(err)
+    }
+
+    if err != nil {
+        pipelineStepFailures.WithLabelValues(step.Name()).Inc()
+        p.logger.Warn("Pipeline step failed",
+            zap.String("step", step.Name()),
+            zap.String("document_id", run.Document.ID),
+            zap.Error(err),
+        )
+        return err
+    }
+    run.produced[step.Name()] = provenance
+    return nil
+}
+
+// OCRStep extracts text from document types carrying identity, address or
+// clinical data. The full and redacted text are stored as encrypted
+// renditions and only a preview is kept on the document
+type OCRStep struct {
+    ocr     *OCRService
+    storage *StorageService
+    // provider reads the text instead of Azure, if set
+    provider OCRProvider
+}
+
+// NewOCRStep creates a new OCR pipeline step
+func NewOCRStep(ocr *OCRService, storage *StorageService) *OCRStep {
+    return &OCRStep{ocr: ocr, storage: storage}
+}
+
+// NewProviderOCRStep creates an OCR pipeline step reading the text with the
+// provider, for trials of the provider in experiments
+func NewProviderOCRStep(ocr *OCRService, provider OCRProvider, storage *StorageService) *OCRStep {
+    return &OCRStep{ocr: ocr, storage: storage, provider: provider}
+}
+
+// Name returns the step name
+func (s *OCRStep) Name() string {
+    return StepOCR
+}
+
+// Provenance reports the OCR model the text comes from
+func (s *OCRStep) Provenance() StepProvenance {
+    if s.provider != nil {
+        return StepProvenance{Version: ocrStepVersion, Provider: s.provider.Provider()}
+    }
+    return StepProvenance{Version: ocrStepVersion, Provider: ocrProvider, Model: ocrModel}
+}
+
+// Applies reports whether the document type requires OCR
+func (s *OCRStep) Applies(doc *models.Document) bool {
+    return ocrDocumentType(doc.DocumentType)
+}
+
+// ocrDocumentType reports whether OCR extracts the text of a document type.
+// Vehicle registrations are read for the gov.br verification code of CRLVs
+// scanned or photographed rather than issued digitally
+func ocrDocumentType(documentType string) bool {
+    switch documentType {
+    case "identity", "proof_of_address", "medical_record", "vehicle_registration":
+        return true
+    default:
+        return false
+    }
+}
+
+// Execute runs OCR and shares the extracted text and its layout with later
+// steps
+func (s *OCRStep) Execute(ctx context.Context, run *PipelineRun) error {
+    var (
+        result OCRResult
+        err    error
+    )
+    if s.provider != nil {
+        result, err = s.ocr.ProcessDocumentWith(ctx, run.Document, run.Content, s.provider)
+    } else {
+        result, err = s.ocr.ProcessDocument(ctx, run.Document, run.Content)
+    }
+    if err != nil {
+        return err
+    }
+    text := result.Text
+    run.OCRText = text
+    run.OCRLines = result.Lines
+    run.Document.SetOCRPreview(text)
+
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionOCRText, "text/plain; charset=utf-8", []byte(text)); err != nil {
+        return fmt.Errorf("failed to store extracted text: %w", err)
+    }
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionOCRTextRedacted, "text/plain; charset=utf-8", []byte(RedactText(text))); err != nil {
+        return fmt.Errorf("failed to store redacted text: %w", err)
+    }
+    layout, err := json.Marshal(result.Lines)
+    if err != nil {
+        return fmt.Errorf("failed to encode text layout: %w", err)
+    }
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionOCRLayout, "application/json", layout); err != nil {
+        return fmt.Errorf("failed to store text layout: %w", err)
+    }
+    return nil
+}
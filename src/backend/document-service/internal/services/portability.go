@@ -0,0 +1,249 @@
+package services
+
+import (
+    "archive/zip"
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/url"
+    "path"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+var (
+    ErrInvalidSubject    = errors.New("invalid subject cpf")
+    ErrSubjectNotFound   = errors.New("no enrollments found for subject")
+    ErrExportLinkInvalid = errors.New("invalid export link")
+    ErrExportLinkExpired = errors.New("export link expired")
+)
+
+// PortabilityService builds LGPD portability exports: a ZIP of every document
+// of a data subject plus a JSON manifest, stored encrypted and handed out
+// through a signed, time-limited link
+type PortabilityService struct {
+    cfg         config.PortabilityConfig
+    documents   repository.DocumentRepository
+    storage     *StorageService
+    enrollments *EnrollmentClient
+    auditLogger *zap.Logger
+}
+
+// NewPortabilityService creates a new portability service
+func NewPortabilityService(cfg *config.Config, documents repository.DocumentRepository, storage *StorageService, enrollments *EnrollmentClient, auditLogger *zap.Logger) (*PortabilityService, error) {
+    if cfg == nil || documents == nil || storage == nil || enrollments == nil || auditLogger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &PortabilityService{
+        cfg:         cfg.PortabilityConfig,
+        documents:   documents,
+        storage:     storage,
+        enrollments: enrollments,
+        auditLogger: auditLogger,
+    }, nil
+}
+
+// Export builds and stores the export of a subject and returns it with its
+// download link
+func (s *PortabilityService) Export(ctx context.Context, cpf, requestedBy string) (*models.PortabilityExport, string, error) {
+    cpf = digitsOnly(cpf)
+    if !ValidCPF(cpf) {
+        return nil, "", ErrInvalidSubject
+    }
+
+    enrollments, err := s.enrollments.ListBeneficiaryEnrollments(ctx, cpf)
+    if err != nil && !errors.Is(err, ErrEnrollmentNotFound) {
+        return nil, "", fmt.Errorf("failed to list subject enrollments: %w", err)
+    }
+    if len(enrollments) == 0 {
+        return nil, "", ErrSubjectNotFound
+    }
+
+    now := time.Now()
+    export := &models.PortabilityExport{
+        ID:          uuid.New().String(),
+        RequestedBy: requestedBy,
+        CreatedAt:   now,
+        ExpiresAt:   now.Add(s.cfg.LinkTTL),
+    }
+
+    archive, err := s.buildArchive(ctx, export, cpf, enrollments)
+    if err != nil {
+        return nil, "", err
+    }
+    if err := s.storage.StoreExport(ctx, export, archive); err != nil {
+        return nil, "", err
+    }
+
+    s.auditLogger.Info("Portability export created",
+        zap.String("export_id", export.ID),
+        zap.String("subject", maskCPF(cpf)),
+        zap.String("requested_by", requestedBy),
+        zap.Int("document_count", export.DocumentCount),
+        zap.Time("expires_at", export.ExpiresAt),
+    )
+    return export, s.link(export), nil
+}
+
+// Open verifies a download link and opens the export archive it points to
+func (s *PortabilityService) Open(ctx context.Context, id, expires, signature string) (*models.PortabilityExport, io.ReadSeekCloser, ObjectInfo, error) {
+    expiresAt, err := strconv.ParseInt(expires, 10, 64)
+    if err != nil {
+        return nil, nil, ObjectInfo{}, ErrExportLinkInvalid
+    }
+    mac, err := hex.DecodeString(signature)
+    if err != nil || !hmac.Equal(mac, s.sign(id, expiresAt)) {
+        return nil, nil, ObjectInfo{}, ErrExportLinkInvalid
+    }
+    if time.Now().Unix() >= expiresAt {
+        return nil, nil, ObjectInfo{}, ErrExportLinkExpired
+    }
+
+    export, err := s.storage.LoadExport(ctx, id)
+    if err != nil {
+        return nil, nil, ObjectInfo{}, err
+    }
+    if export.Expired(time.Now()) {
+        return nil, nil, ObjectInfo{}, ErrExportLinkExpired
+    }
+
+    content, info, err := s.storage.OpenExport(ctx, export)
+    if err != nil {
+        return nil, nil, ObjectInfo{}, err
+    }
+    return export, content, info, nil
+}
+
+// buildArchive writes the documents of every enrollment and the manifest to a
+// ZIP. Documents whose content is no longer stored, for example after the
+// retention period, are listed in the manifest without a file
+func (s *PortabilityService) buildArchive(ctx context.Context, export *models.PortabilityExport, cpf string, enrollments []models.Enrollment) ([]byte, error) {
+    var buf bytes.Buffer
+    archive := zip.NewWriter(&buf)
+
+    manifest := models.PortabilityManifest{
+        Version:     models.PortabilityManifestVersion,
+        ExportID:    export.ID,
+        SubjectCPF:  cpf,
+        GeneratedAt: export.CreatedAt,
+        Enrollments: make([]models.PortabilityEnrollment, 0, len(enrollments)),
+    }
+
+    for _, enrollment := range enrollments {
+        // Guard against the enrollment service returning another beneficiary
+        if digitsOnly(enrollment.BeneficiaryCPF) != cpf {
+            continue
+        }
+
+        docs, err := s.documents.ListByEnrollment(ctx, enrollment.ID)
+        if err != nil {
+            return nil, fmt.Errorf("failed to list documents of enrollment %s: %w", enrollment.ID, err)
+        }
+
+        entry := models.PortabilityEnrollment{
+            EnrollmentID: enrollment.ID,
+            Status:       enrollment.Status,
+            Documents:    make([]models.PortabilityDocument, 0, len(docs)),
+        }
+        for _, doc := range docs {
+            exported := models.NewPortabilityDocument(doc)
+            if err := s.addDocument(ctx, archive, doc, &exported); err != nil {
+                return nil, err
+            }
+            entry.Documents = append(entry.Documents, exported)
+            export.DocumentCount++
+        }
+        manifest.Enrollments = append(manifest.Enrollments, entry)
+    }
+
+    writer, err := archive.Create("manifest.json")
+    if err != nil {
+        return nil, fmt.Errorf("failed to add manifest: %w", err)
+    }
+    encoder := json.NewEncoder(writer)
+    encoder.SetIndent("", "  ")
+    if err := encoder.Encode(manifest); err != nil {
+        return nil, fmt.Errorf("failed to encode manifest: %w", err)
+    }
+    if err := archive.Close(); err != nil {
+        return nil, fmt.Errorf("failed to finish archive: %w", err)
+    }
+    return buf.Bytes(), nil
+}
+
+// addDocument copies the decrypted content of a document into the archive and
+// records its path and digest
+func (s *PortabilityService) addDocument(ctx context.Context, archive *zip.Writer, doc *models.Document, exported *models.PortabilityDocument) error {
+    if doc.StoragePath == "" {
+        return nil
+    }
+
+    content, err := s.storage.RetrieveDocument(ctx, doc)
+    if errors.Is(err, ErrObjectNotFound) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to retrieve document %s: %w", doc.ID, err)
+    }
+    if closer, ok := content.(io.Closer); ok {
+        defer closer.Close()
+    }
+
+    name := path.Base(strings.ReplaceAll(doc.Filename, "\\", "/"))
+    if name == "." || name == "/" || name == ".." {
+        name = "document"
+    }
+    archivePath := path.Join("documents", doc.EnrollmentID, doc.ID, name)
+
+    writer, err := archive.Create(archivePath)
+    if err != nil {
+        return fmt.Errorf("failed to add document %s: %w", doc.ID, err)
+    }
+    digest := sha256.New()
+    if _, err := io.Copy(io.MultiWriter(writer, digest), content); err != nil {
+        return fmt.Errorf("failed to write document %s: %w", doc.ID, err)
+    }
+
+    exported.ArchivePath = archivePath
+    exported.SHA256 = hex.EncodeToString(digest.Sum(nil))
+    return nil
+}
+
+// link returns the signed download path of an export
+func (s *PortabilityService) link(export *models.PortabilityExport) string {
+    expires := export.ExpiresAt.Unix()
+    query := url.Values{
+        "expires":   {strconv.FormatInt(expires, 10)},
+        "signature": {hex.EncodeToString(s.sign(export.ID, expires))},
+    }
+    return "/api/v1/exports/" + url.PathEscape(export.ID) + "/download?" + query.Encode()
+}
+
+func (s *PortabilityService) sign(id string, expires int64) []byte {
+    mac := hmac.New(sha256.New, []byte(s.cfg.LinkSigningKey))
+    mac.Write([]byte(id + "." + strconv.FormatInt(expires, 10)))
+    return mac.Sum(nil)
+}
+
+// maskCPF keeps the middle digits of a CPF for audit logs
+func maskCPF(cpf string) string {
+    if len(cpf) != 11 {
+        return "***"
+    }
+    return "***." + cpf[3:6] + "." + cpf[6:9] + "-**"
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+const (
+    // previewTokenPurpose binds a token to previews so no other token signed
+    // with the same key can be presented as one
+    previewTokenPurpose = "preview"
+    // previewTokenIDSize is the random token ID, which makes every token
+    // unique and unpredictable even for the same rendition and expiry
+    previewTokenIDSize = 16
+    // maxPreviewTokenLength bounds the work spent on a presented token
+    maxPreviewTokenLength = 1024
+)
+
+var (
+    ErrPreviewDisabled     = errors.New("preview tokens are not configured")
+    ErrPreviewTokenInvalid = errors.New("invalid preview token")
+    ErrPreviewTokenExpired = errors.New("preview token expired")
+)
+
+// PreviewClaims is the scope of a preview token: a single rendition of a
+// single document, optionally bound to the client IP it was minted for
+type PreviewClaims struct {
+    ID         string `json:"jti"`
+    Purpose    string `json:"pur"`
+    DocumentID string `json:"doc"`
+    Rendition  string `json:"ren"`
+    Subject    string `json:"sub,omitempty"`
+    ClientIP   string `json:"ip,omitempty"`
+    ExpiresAt  int64  `json:"exp"`
+}
+
+// PreviewTokens mints and verifies short-lived HMAC signed preview tokens, which
+// let the portal embed renditions in <img> tags that cannot send credentials
+type PreviewTokens struct {
+    keys      [][]byte
+    ttl       time.Duration
+    requireIP bool
+}
+
+// NewPreviewTokens creates the token signer; tokens are signed with the current
+// key and verified against the current and previous keys so the key can be
+// rotated without breaking previews in flight
+func NewPreviewTokens(cfg *config.Config) *PreviewTokens {
+    tokens := &PreviewTokens{
+        ttl:       cfg.PreviewConfig.TokenTTL,
+        requireIP: cfg.PreviewConfig.RequireIPBinding,
+    }
+    for _, key := range []string{cfg.PreviewConfig.SigningKey, cfg.PreviewConfig.PreviousSigningKey} {
+        if key != "" {
+            tokens.keys = append(tokens.keys, []byte(key))
+        }
+    }
+    return tokens
+}
+
+// Mint issues a token for the rendition; clientIP is embedded when bindIP is
+// set or IP binding is required
+func (t *PreviewTokens) Mint(documentID, rendition, subject, clientIP string, bindIP bool) (string, time.Time, error) {
+    if len(t.keys) == 0 {
+        return "", time.Time{}, ErrPreviewDisabled
+    }
+
+    id := make([]byte, previewTokenIDSize)
+    if _, err := rand.Read(id); err != nil {
+        return "", time.Time{}, fmt.Errorf("failed to generate preview token ID: %w", err)
+    }
+
+    expiresAt := time.Now().Add(t.ttl)
+    claims := PreviewClaims{
+        ID:         base64.RawURLEncoding.EncodeToString(id),
+        Purpose:    previewTokenPurpose,
+        DocumentID: documentID,
+        Rendition:  rendition,
+        Subject:    subject,
+        ExpiresAt:  expiresAt.Unix(),
+    }
+    if bindIP || t.requireIP {
+        claims.ClientIP = clientIP
+    }
+
+    payload, err := json.Marshal(claims)
+    if err != nil {
+        return "", time.Time{}, fmt.Errorf("failed to encode preview token: %w", err)
+    }
+    encoded := base64.RawURLEncoding.EncodeToString(payload)
+    return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(t.keys[0], encoded)), expiresAt, nil
+}
+
+// Verify checks the signature, purpose, expiry, document and client IP of a
+// token
+func (t *PreviewTokens) Verify(token, documentID, clientIP string) (*PreviewClaims, error) {
+    if len(t.keys) == 0 {
+        return nil, ErrPreviewDisabled
+    }
+    if len(token) > maxPreviewTokenLength {
+        return nil, ErrPreviewTokenInvalid
+    }
+
+    encoded, signature, ok := strings.Cut(token, ".")
+    if !ok {
+        return nil, ErrPreviewTokenInvalid
+    }
+    mac, err := base64.RawURLEncoding.DecodeString(signature)
+    if err != nil {
+        return nil, ErrPreviewTokenInvalid
+    }
+
+    valid := false
+    for _, key := range t.keys {
+        if hmac.Equal(mac, t.sign(key, encoded)) {
+            valid = true
+            break
+        }
+    }
+    if !valid {
+        return nil, ErrPreviewTokenInvalid
+    }
+
+    payload, err := base64.RawURLEncoding.DecodeString(encoded)
+    if err != nil {
+        return nil, ErrPreviewTokenInvalid
+    }
+    var claims PreviewClaims
+    if err := json.Unmarshal(payload, &claims); err != nil {
+        return nil, ErrPreviewTokenInvalid
+    }
+    if claims.Purpose != previewTokenPurpose || claims.ID == "" {
+        return nil, fmt.Errorf("%w: not a preview token", ErrPreviewTokenInvalid)
+    }
+
+    if time.Now().Unix() >= claims.ExpiresAt {
+        return nil, ErrPreviewTokenExpired
+    }
+    if claims.DocumentID != documentID {
+        return nil, fmt.Errorf("%w: issued for another document", ErrPreviewTokenInvalid)
+    }
+    if claims.ClientIP != "" && claims.ClientIP != clientIP {
+        return nil, fmt.Errorf("%w: bound to another client", ErrPreviewTokenInvalid)
+    }
+    return &claims, nil
+}
+
+func (t *PreviewTokens) sign(key []byte, payload string) []byte {
+    mac := hmac.New(sha256.New, key)
+    mac.Write([]byte(payload))
+    return mac.Sum(nil)
+}
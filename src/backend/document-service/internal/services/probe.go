@@ -0,0 +1,282 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// probeFilename is the name the probe document is uploaded under
+const probeFilename = "synthetic-probe.pdf"
+
+var (
+    ErrProbeRunning         = errors.New("synthetic probe already running")
+    ErrProbeMaintenance     = errors.New("synthetic probe paused during maintenance")
+    ErrProbeStepFailed      = errors.New("pipeline step failed on the probe document")
+    ErrProbeContentMismatch = errors.New("downloaded probe document differs from the upload")
+    ErrProbeNotDeleted      = errors.New("probe document still exists after deletion")
+)
+
+// probeDocument is the known test file the probe uploads: a one-page PDF
+// reading a fixed line of text
+var probeDocument = ProbeDocument()
+
+// SyntheticProbe exercises the whole path a document takes, as monitoring of
+// the health endpoint cannot: it uploads a known document through the
+// pipeline, waits for every pipeline step, downloads and verifies the
+// content, then deletes the document the way an erasure does. The document
+// is deleted even when an earlier stage failed, so probes leave nothing
+// behind. Probe documents are marked synthetic and never reach the ingest
+// hooks
+type SyntheticProbe struct {
+    cfg       config.SyntheticProbeConfig
+    pipeline  *DocumentPipeline
+    documents repository.DocumentRepository
+    storage   *StorageService
+    shredder  *CryptoShredder
+    logger    *zap.Logger
+
+    // running allows one probe at a time
+    running sync.Mutex
+
+    mu   sync.RWMutex
+    last *models.ProbeResult
+}
+
+// NewSyntheticProbe creates the synthetic probe, or returns nil when it is
+// disabled
+func NewSyntheticProbe(cfg *config.Config, pipeline *DocumentPipeline, documents repository.DocumentRepository, storage *StorageService, shredder *CryptoShredder, logger *zap.Logger) (*SyntheticProbe, error) {
+    if cfg == nil || pipeline == nil || documents == nil || storage == nil || shredder == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SyntheticProbeConfig.Enabled {
+        return nil, nil
+    }
+
+    return &SyntheticProbe{
+        cfg:       cfg.SyntheticProbeConfig,
+        pipeline:  pipeline,
+        documents: documents,
+        storage:   storage,
+        shredder:  shredder,
+        logger:    logger.With(zap.String("component", "synthetic_probe")),
+    }, nil
+}
+
+// Run probes every interval until the context is cancelled
+func (s *SyntheticProbe) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.cfg.Interval)
+    defer ticker.Stop()
+
+    for {
+        _, err := s.Probe(ctx)
+        switch {
+        case errors.Is(err, ErrProbeMaintenance):
+            s.logger.Info("Synthetic probe skipped during maintenance")
+        case err != nil && !errors.Is(err, ErrProbeRunning):
+            s.logger.Error("Synthetic probe could not run", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Probe runs one probe and reports how each stage went. A failed stage fails
+// the probe, not the call; only a probe already running, or one that would
+// upload while the service is read-only for maintenance, is an error
+func (s *SyntheticProbe) Probe(ctx context.Context) (*models.ProbeResult, error) {
+    if s.pipeline.ReadOnly() {
+        return nil, ErrProbeMaintenance
+    }
+    if !s.running.TryLock() {
+        return nil, ErrProbeRunning
+    }
+    defer s.running.Unlock()
+    if err := CheckJobLease(ctx); err != nil {
+        return nil, err
+    }
+
+    result := &models.ProbeResult{StartedAt: time.Now()}
+    probeCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+    defer cancel()
+
+    var doc *models.Document
+    err := s.stage(result, models.ProbeStageUpload, func() error {
+        var err error
+        doc, err = s.pipeline.Ingest(probeCtx, IngestRequest{
+            EnrollmentID: s.cfg.EnrollmentID,
+            TenantID:     s.cfg.TenantID,
+            DocumentType: s.cfg.DocumentType,
+            Filename:     probeFilename,
+            ContentType:  "application/pdf",
+            Channel:      models.ChannelAPI,
+            SubmittedBy:  "SYNTHETIC_PROBE",
+            Content:      bytes.NewReader(probeDocument),
+            Size:         int64(len(probeDocument)),
+            Synthetic:    true,
+        })
+        return err
+    })
+    if doc != nil {
+        result.DocumentID = doc.ID
+    }
+    if err == nil {
+        err = s.stage(result, models.ProbeStageProcessing, func() error {
+            return s.await(probeCtx, doc.ID)
+        })
+    }
+    if err == nil {
+        s.stage(result, models.ProbeStageDownload, func() error {
+            return s.verify(probeCtx, doc.ID)
+        })
+    }
+    if doc != nil {
+        // The probe document is deleted even once the probe timed out
+        deleteCtx, cancelDelete := context.WithTimeout(context.WithoutCancel(ctx), s.cfg.Timeout)
+        s.stage(result, models.ProbeStageDelete, func() error {
+            return s.delete(deleteCtx, doc.ID)
+        })
+        cancelDelete()
+    }
+
+    result.Finish(time.Now())
+    s.report(result)
+    return result, nil
+}
+
+// Last returns the result of the last probe, or nil before the first
+func (s *SyntheticProbe) Last() *models.ProbeResult {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return s.last
+}
+
+// stage runs one stage of the probe, recording its outcome and duration
+func (s *SyntheticProbe) stage(result *models.ProbeResult, name string, run func() error) error {
+    startTime := time.Now()
+    err := run()
+    duration := time.Since(startTime)
+    probeDuration.WithLabelValues(name).Observe(duration.Seconds())
+    result.Record(name, duration, err)
+    return err
+}
+
+// await waits until no pipeline step is pending on the document, failing if
+// any step failed
+func (s *SyntheticProbe) await(ctx context.Context, documentID string) error {
+    for {
+        doc, err := s.documents.GetByID(ctx, documentID)
+        if err != nil {
+            return err
+        }
+        if len(s.pipeline.PendingSteps(doc)) == 0 {
+            failed := make([]string, 0)
+            for _, activity := range doc.ProcessingActivities {
+                if activity.Outcome == models.ProcessingOutcomeFailed {
+                    failed = append(failed, activity.Operation)
+                }
+            }
+            if len(failed) > 0 {
+                return fmt.Errorf("%w: %s", ErrProbeStepFailed, strings.Join(failed, ", "))
+            }
+            return nil
+        }
+
+        select {
+        case <-ctx.Done():
+            return fmt.Errorf("pipeline did not complete: %w", ctx.Err())
+        case <-time.After(s.cfg.PollInterval):
+        }
+    }
+}
+
+// verify downloads the document and compares it with the upload
+func (s *SyntheticProbe) verify(ctx context.Context, documentID string) error {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return err
+    }
+    content, err := s.storage.RetrieveDocument(ctx, doc)
+    if err != nil {
+        return err
+    }
+    if closer, ok := content.(io.Closer); ok {
+        defer closer.Close()
+    }
+    downloaded, err := io.ReadAll(content)
+    if err != nil {
+        return fmt.Errorf("failed to read probe document: %w", err)
+    }
+    if sha256.Sum256(downloaded) != sha256.Sum256(probeDocument) {
+        return ErrProbeContentMismatch
+    }
+    return nil
+}
+
+// delete erases the document and checks it is gone
+func (s *SyntheticProbe) delete(ctx context.Context, documentID string) error {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    if err := s.shredder.Erase(ctx, doc); err != nil {
+        return err
+    }
+    if _, err := s.documents.GetByID(ctx, documentID); !errors.Is(err, repository.ErrDocumentNotFound) {
+        return ErrProbeNotDeleted
+    }
+    return nil
+}
+
+// report exports the result and keeps it as the last one
+func (s *SyntheticProbe) report(result *models.ProbeResult) {
+    probeDuration.WithLabelValues("total").Observe(float64(result.DurationMS) / 1000)
+    if result.Passed {
+        probeRuns.WithLabelValues("passed", "").Inc()
+        probeLastSuccess.Set(float64(result.FinishedAt.Unix()))
+    } else {
+        probeRuns.WithLabelValues("failed", result.FailedStage).Inc()
+        s.logger.Warn("Synthetic probe failed",
+            zap.String("document_id", result.DocumentID),
+            zap.String("failed_stage", result.FailedStage),
+            zap.Any("stages", result.Stages),
+        )
+    }
+
+    s.mu.Lock()
+    s.last = result
+    s.mu.Unlock()
+}
+
+// ProbeDocument returns the known test file of the synthetic probe: a
+// one-page PDF reading a fixed line of text, the same on every call
+func ProbeDocument() []byte {
+    pdf := newPDFObjectWriter()
+    pdf.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+    pdf.object(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+    pdf.object(3, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>")
+    pdf.object(4, textLayerFont)
+    pdf.stream(5, "", []byte("BT /F1 24 Tf 72 720 Td (DOCUMENT SERVICE SYNTHETIC PROBE) Tj ET"))
+    pdf.object(6, "<< /Title (Synthetic probe) /Producer (document-service) >>")
+    content, _ := pdf.finish(1, 6)
+    return content
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+    "runtime/debug"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Step implementation versions recorded in provenance. Bump a step's version
+// whenever a change to it can change the artifacts it produces
+const (
+    ocrStepVersion        = "1"
+    receitaStepVersion    = "1"
+    holderNameStepVersion = "1"
+    addressStepVersion    = "1"
+    tissStepVersion       = "1"
+    pageRescanVersion     = "1"
+    pageOperationsVersion = "1"
+    pageCleanupVersion    = "1"
+    languageStepVersion   = "1"
+    accessiblePDFVersion  = "1"
+    searchablePDFVersion  = "1"
+    searchIndexVersion    = "1"
+    ocrCanaryVersion      = "1"
+    renditionsVersion     = "1"
+)
+
+// Steps recorded as the producer of PDFs edited after processing
+const (
+    // StepPageRescan produces PDFs with rescanned pages
+    StepPageRescan = "page_rescan"
+    // StepPageOperations produces PDFs with pages rotated, reordered or
+    // removed by a reviewer
+    StepPageOperations = "page_operations"
+)
+
+// StepProvenance describes the implementation behind a pipeline step: its
+// version, the external provider and model it consults, and the steps whose
+// output it reads
+type StepProvenance struct {
+    Version  string
+    Provider string
+    Model    string
+    Inputs   []string
+}
+
+// ProvenanceStep is implemented by steps reporting their implementation.
+// Artifacts of other steps are recorded with the pipeline version alone
+type ProvenanceStep interface {
+    Provenance() StepProvenance
+}
+
+// PipelineVersion returns the configured pipeline release, falling back to
+// the VCS revision the binary was built from
+func PipelineVersion(cfg *config.Config) string {
+    if cfg.ServiceConfig.PipelineVersion != "" {
+        return cfg.ServiceConfig.PipelineVersion
+    }
+    if info, ok := debug.ReadBuildInfo(); ok {
+        for _, setting := range info.Settings {
+            if setting.Key == "vcs.revision" && setting.Value != "" {
+                return setting.Value
+            }
+        }
+    }
+    return "dev"
+}
+
+// stepProvenance builds the provenance of the artifacts a step is about to
+// produce
+func (p *DocumentPipeline) stepProvenance(run *PipelineRun, step PipelineStep) *models.Provenance {
+    provenance := &models.Provenance{
+        PipelineVersion: p.version,
+        Step:            step.Name(),
+        ProducedAt:      time.Now(),
+    }
+    run.describe(provenance, step)
+    return provenance
+}
+
+// describe fills in the implementation of the step producing artifacts,
+// linking the provenance of the inputs already produced in the run
+func (run *PipelineRun) describe(provenance *models.Provenance, step PipelineStep) {
+    reporter, ok := step.(ProvenanceStep)
+    if !ok {
+        return
+    }
+
+    described := reporter.Provenance()
+    provenance.StepVersion = described.Version
+    provenance.Provider = described.Provider
+    provenance.Model = described.Model
+    provenance.Inputs = nil
+    for _, input := range described.Inputs {
+        if produced, ok := run.produced[input]; ok {
+            provenance.Inputs = append(provenance.Inputs, *produced)
+        }
+    }
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "sort"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Kinds of providers on the scoreboard
+const (
+    ProviderKindOCR       = "ocr"
+    ProviderKindScreening = "screening"
+)
+
+// ProviderScoreboard scores providers on the success rate and latency of
+// their recent calls and ranks those of each kind for new work. Scores are
+// kept per instance, from the calls it made
+type ProviderScoreboard struct {
+    mu        sync.Mutex
+    cfg       config.ProviderHealthConfig
+    providers map[string][]string
+    windows   map[string]*models.HealthWindow
+    leaders   map[string]string
+    logger    *zap.Logger
+}
+
+// NewProviderScoreboard creates the scoreboard, or returns nil when provider
+// health is disabled
+func NewProviderScoreboard(cfg *config.Config, logger *zap.Logger) *ProviderScoreboard {
+    if cfg == nil || !cfg.ProviderHealthConfig.Enabled {
+        return nil
+    }
+
+    return &ProviderScoreboard{
+        cfg:       cfg.ProviderHealthConfig,
+        providers: make(map[string][]string),
+        windows:   make(map[string]*models.HealthWindow),
+        leaders:   make(map[string]string),
+        logger:    logger.With(zap.String("component", "provider_scoreboard")),
+    }
+}
+
+// Register puts the providers of a kind on the scoreboard, in order of
+// preference; it must be called before serving requests
+func (b *ProviderScoreboard) Register(kind string, providers ...string) {
+    if b == nil {
+        return
+    }
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for _, provider := range providers {
+        if _, ok := b.windows[kind+"/"+provider]; ok {
+            continue
+        }
+        b.providers[kind] = append(b.providers[kind], provider)
+        b.windows[kind+"/"+provider] = models.NewHealthWindow(b.cfg.Window)
+    }
+    if _, ok := b.leaders[kind]; !ok && len(b.providers[kind]) > 0 {
+        b.leaders[kind] = b.providers[kind][0]
+    }
+}
+
+// Record scores a call to a provider that took latency and failed with err,
+// if it did. Calls the caller gave up on or held back under a quota say
+// nothing of the provider's health and are not scored
+func (b *ProviderScoreboard) Record(kind, provider string, latency time.Duration, err error) {
+    if b == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrProviderQuotaExhausted) || errors.Is(err, ErrProviderThrottled) {
+        return
+    }
+
+    result := "success"
+    if err != nil {
+        result = "failure"
+    } else {
+        providerCallDuration.WithLabelValues(kind, provider).Observe(latency.Seconds())
+    }
+    providerCalls.WithLabelValues(kind, provider, result).Inc()
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if window, ok := b.windows[kind+"/"+provider]; ok {
+        window.Record(time.Now(), latency, err != nil)
+    }
+}
+
+// Rank returns the providers of a kind in the order new work should try
+// them. A nil *ProviderScoreboard returns nil
+func (b *ProviderScoreboard) Rank(kind string) []string {
+    if b == nil {
+        return nil
+    }
+
+    health := b.score(kind, time.Now())
+    ranked := make([]string, len(health))
+    for i, provider := range health {
+        ranked[i] = provider.Provider
+    }
+    return ranked
+}
+
+// Scoreboard returns the health of every provider, by kind and rank
+func (b *ProviderScoreboard) Scoreboard() []models.ProviderHealth {
+    b.mu.Lock()
+    kinds := make([]string, 0, len(b.providers))
+    for kind := range b.providers {
+        kinds = append(kinds, kind)
+    }
+    b.mu.Unlock()
+    sort.Strings(kinds)
+
+    now := time.Now()
+    scoreboard := make([]models.ProviderHealth, 0)
+    for _, kind := range kinds {
+        scoreboard = append(scoreboard, b.score(kind, now)...)
+    }
+    return scoreboard
+}
+
+// score scores and ranks the providers of a kind, exporting their scores
+// and logging when another provider takes the lead
+func (b *ProviderScoreboard) score(kind string, now time.Time) []models.ProviderHealth {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+
+    health := make([]models.ProviderHealth, 0, len(b.providers[kind]))
+    for _, provider := range b.providers[kind] {
+        health = append(health, models.NewProviderHealth(kind, provider, b.windows[kind+"/"+provider],
+            b.cfg.LatencyTargetFor(kind), b.cfg.MinSamples, b.cfg.HealthyScore, now))
+    }
+    models.RankProviders(health)
+
+    for _, provider := range health {
+        providerHealthScore.WithLabelValues(kind, provider.Provider).Set(provider.Score)
+        providerHealthRank.WithLabelValues(kind, provider.Provider).Set(float64(provider.Rank))
+    }
+    if len(health) > 0 && health[0].Provider != b.leaders[kind] {
+        b.logger.Warn("Provider took the lead on health",
+            zap.String("kind", kind),
+            zap.String("from", b.leaders[kind]),
+            zap.String("to", health[0].Provider),
+            zap.Float64("score", health[0].Score))
+        b.leaders[kind] = health[0].Provider
+    }
+    return health
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "net/http"
+    "strconv"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/time/rate" // v0.3.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Providers whose quotas are tracked: the Azure Computer Vision resource and
+// its fallback
+const (
+    QuotaProviderAzure         = "azure"
+    QuotaProviderAzureFallback = "azure_fallback"
+)
+
+// defaultRetryAfter is how long a provider that throttles us without saying
+// for how long is left alone
+const defaultRetryAfter = time.Second
+
+var (
+    ErrProviderQuotaExhausted = errors.New("provider monthly quota exhausted")
+    ErrProviderThrottled      = errors.New("provider throttled")
+)
+
+// ProviderQuotas tracks the quota consumption of providers and holds calls
+// under it: submissions count against the monthly quota in the usage
+// repository, every call is paced under the per-second limit of this
+// instance, and a provider answering 429 is left alone for as long as its
+// Retry-After asks. Calls switch to the next provider in order as one nears
+// its monthly quota or throttles us
+type ProviderQuotas struct {
+    mu         sync.Mutex
+    usage      repository.ProviderUsageRepository
+    providers  map[string]*providerQuota
+    order      []string
+    active     string
+    scoreboard *ProviderScoreboard
+    throttleAt float64
+    switchAt   float64
+    logger     *zap.Logger
+}
+
+// providerQuota is the quota of one provider and its pacing
+type providerQuota struct {
+    mu             sync.Mutex
+    name           string
+    limits         models.ProviderQuotaLimits
+    limiter        *rate.Limiter
+    throttledUntil time.Time
+}
+
+// NewProviderQuotas creates the quota tracker, or returns nil when provider
+// quotas are disabled
+func NewProviderQuotas(cfg *config.Config, usage repository.ProviderUsageRepository, logger *zap.Logger) (*ProviderQuotas, error) {
+    if cfg == nil || usage == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.ProviderQuotaConfig.Enabled {
+        return nil, nil
+    }
+
+    quotas := &ProviderQuotas{
+        usage:      usage,
+        providers:  make(map[string]*providerQuota),
+        throttleAt: cfg.ProviderQuotaConfig.ThrottleAt,
+        switchAt:   cfg.ProviderQuotaConfig.SwitchAt,
+        logger:     logger.With(zap.String("component", "provider_quotas")),
+    }
+    quotas.add(QuotaProviderAzure, cfg.ProviderQuotaConfig.Azure)
+    if cfg.ProviderQuotaConfig.Fallback.Endpoint != "" {
+        quotas.add(QuotaProviderAzureFallback, cfg.ProviderQuotaConfig.Fallback.Limits)
+    }
+    quotas.active = quotas.order[0]
+    return quotas, nil
+}
+
+// add tracks the quota of the next provider in order
+func (q *ProviderQuotas) add(name string, limits config.ProviderLimitsConfig) {
+    burst := max(1, int(math.Ceil(limits.TransactionsPerSecond)))
+    q.providers[name] = &providerQuota{
+        name: name,
+        limits: models.ProviderQuotaLimits{
+            TransactionsPerSecond: limits.TransactionsPerSecond,
+            MonthlyTransactions:   limits.MonthlyTransactions,
+        },
+        limiter: rate.NewLimiter(rate.Inf, burst),
+    }
+    q.order = append(q.order, name)
+}
+
+// UseScoreboard puts the healthiest provider first when choosing the active
+// one; it must be called before serving requests
+func (q *ProviderQuotas) UseScoreboard(scoreboard *ProviderScoreboard) {
+    if q != nil {
+        q.scoreboard = scoreboard
+    }
+}
+
+// Transport holds the requests sent through next under a provider's quota.
+// A nil *ProviderQuotas returns next
+func (q *ProviderQuotas) Transport(provider string, next http.RoundTripper) http.RoundTripper {
+    quota, ok := q.quota(provider)
+    if !ok {
+        return next
+    }
+    return &quotaTransport{quotas: q, quota: quota, next: next}
+}
+
+// Active returns the provider calls go to: the first in order neither past
+// its switching share nor throttled, or else the first. With a scoreboard,
+// the order is that of health. A nil *ProviderQuotas returns an empty name
+func (q *ProviderQuotas) Active(ctx context.Context) string {
+    if q == nil {
+        return ""
+    }
+
+    now := time.Now()
+    order := q.ranked()
+    active := order[0]
+    for _, name := range order {
+        if !q.switching(ctx, q.providers[name], now) {
+            active = name
+            break
+        }
+    }
+
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    if active != q.active {
+        providerQuotaFailovers.WithLabelValues(q.active, active).Inc()
+        q.logger.Warn("Switching OCR provider",
+            zap.String("from", q.active),
+            zap.String("to", active))
+        q.active = active
+    }
+    return active
+}
+
+// Status reports the quota consumption of each provider, in order
+func (q *ProviderQuotas) Status(ctx context.Context) ([]models.ProviderQuotaStatus, error) {
+    active := q.Active(ctx)
+    now := time.Now()
+
+    statuses := make([]models.ProviderQuotaStatus, 0, len(q.order))
+    for _, name := range q.order {
+        quota := q.providers[name]
+        used, err := q.usage.Get(ctx, name, models.QuotaMonth(now))
+        if err != nil {
+            return nil, fmt.Errorf("failed to get %s quota usage: %w", name, err)
+        }
+        status := models.NewProviderQuotaStatus(name, quota.limits, used, q.throttleAt, quota.throttled(), now)
+        status.Active = name == active
+        statuses = append(statuses, status)
+    }
+    return statuses, nil
+}
+
+// ranked returns the providers in order of health, or in configured order
+// without a scoreboard
+func (q *ProviderQuotas) ranked() []string {
+    ranked := make([]string, 0, len(q.order))
+    for _, name := range q.scoreboard.Rank(ProviderKindOCR) {
+        if _, ok := q.providers[name]; ok {
+            ranked = append(ranked, name)
+        }
+    }
+    if len(ranked) != len(q.order) {
+        return q.order
+    }
+    return ranked
+}
+
+// quota returns the tracked quota of a provider
+func (q *ProviderQuotas) quota(provider string) (*providerQuota, bool) {
+    if q == nil {
+        return nil, false
+    }
+    quota, ok := q.providers[provider]
+    return quota, ok
+}
+
+// switching reports whether calls should leave a provider: it is throttling
+// us or past the switching share of its monthly quota
+func (q *ProviderQuotas) switching(ctx context.Context, quota *providerQuota, now time.Time) bool {
+    if quota.throttled().After(now) {
+        return true
+    }
+    if quota.limits.MonthlyTransactions <= 0 {
+        return false
+    }
+    used, err := q.usage.Get(ctx, quota.name, models.QuotaMonth(now))
+    if err != nil {
+        q.logger.Warn("Failed to get quota usage", zap.String("provider", quota.name), zap.Error(err))
+        return false
+    }
+    return quota.limits.UsedRatio(used) >= q.switchAt
+}
+
+// acquire holds a call to a provider until its quota allows it. Billable
+// calls, the submissions, count against the monthly quota and are refused
+// once it is used up
+func (q *ProviderQuotas) acquire(ctx context.Context, quota *providerQuota, billable bool) error {
+    now := time.Now()
+    if until := quota.throttled(); until.After(now) {
+        if deadline, ok := ctx.Deadline(); ok && deadline.Before(until) {
+            providerQuotaRejections.WithLabelValues(quota.name, models.ProviderQuotaThrottled).Inc()
+            return fmt.Errorf("%w: %s until %s", ErrProviderThrottled, quota.name, until.Format(time.RFC3339))
+        }
+        timer := time.NewTimer(until.Sub(now))
+        defer timer.Stop()
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-timer.C:
+        }
+        now = time.Now()
+    }
+
+    month := models.QuotaMonth(now)
+    used, err := q.usage.Get(ctx, quota.name, month)
+    if err != nil {
+        return fmt.Errorf("failed to get %s quota usage: %w", quota.name, err)
+    }
+    if billable && quota.limits.Exhausted(used) {
+        providerQuotaRejections.WithLabelValues(quota.name, models.ProviderQuotaExhausted).Inc()
+        return fmt.Errorf("%w: %s used %d of %d transactions in %s", ErrProviderQuotaExhausted, quota.name, used, quota.limits.MonthlyTransactions, month)
+    }
+
+    pace := quota.limits.Pace(used, q.throttleAt, now)
+    limit := rate.Inf
+    if pace > 0 {
+        limit = rate.Limit(pace)
+    }
+    quota.limiter.SetLimitAt(now, limit)
+    providerQuotaPace.WithLabelValues(quota.name).Set(pace)
+    if err := quota.limiter.Wait(ctx); err != nil {
+        providerQuotaRejections.WithLabelValues(quota.name, models.ProviderQuotaPaced).Inc()
+        return fmt.Errorf("%w: %s: %v", ErrProviderThrottled, quota.name, err)
+    }
+
+    if billable {
+        if used, err = q.usage.Add(ctx, quota.name, month, 1); err != nil {
+            return fmt.Errorf("failed to count %s quota usage: %w", quota.name, err)
+        }
+        providerQuotaUsed.WithLabelValues(quota.name).Set(float64(used))
+        providerQuotaUsedRatio.WithLabelValues(quota.name).Set(quota.limits.UsedRatio(used))
+    }
+    return nil
+}
+
+// observe leaves a provider alone for as long as a 429 response asks
+func (q *ProviderQuotas) observe(quota *providerQuota, resp *http.Response) {
+    if resp.StatusCode != http.StatusTooManyRequests {
+        return
+    }
+
+    wait := retryAfter(resp.Header)
+    quota.throttle(time.Now().Add(wait))
+    providerQuotaThrottles.WithLabelValues(quota.name).Inc()
+    q.logger.Warn("Provider throttled us",
+        zap.String("provider", quota.name),
+        zap.Duration("retry_after", wait))
+}
+
+// throttled returns until when the provider throttles us
+func (p *providerQuota) throttled() time.Time {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return p.throttledUntil
+}
+
+// throttle leaves the provider alone until a moment, unless it already is
+// for longer
+func (p *providerQuota) throttle(until time.Time) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if until.After(p.throttledUntil) {
+        p.throttledUntil = until
+    }
+}
+
+// retryAfter reads how long a throttling provider asks to be left alone,
+// from Azure's millisecond header or the standard Retry-After in seconds or
+// as a date
+func retryAfter(header http.Header) time.Duration {
+    if ms, err := strconv.Atoi(header.Get("x-ms-retry-after-ms")); err == nil && ms > 0 {
+        return time.Duration(ms) * time.Millisecond
+    }
+    value := header.Get("Retry-After")
+    if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+        return time.Duration(seconds) * time.Second
+    }
+    if at, err := http.ParseTime(value); err == nil && time.Until(at) > 0 {
+        return time.Until(at)
+    }
+    return defaultRetryAfter
+}
+
+// quotaTransport holds each request under a provider's quota and watches the
+// responses for throttling
+type quotaTransport struct {
+    quotas *ProviderQuotas
+    quota  *providerQuota
+    next   http.RoundTripper
+}
+
+// RoundTrip sends the request once the quota allows it
+func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    if err := t.quotas.acquire(req.Context(), t.quota, req.Method == http.MethodPost); err != nil {
+        if req.Body != nil {
+            req.Body.Close()
+        }
+        return nil, err
+    }
+
+    resp, err := t.next.RoundTrip(req)
+    if err != nil {
+        return nil, err
+    }
+    t.quotas.observe(t.quota, resp)
+    return resp, nil
+}
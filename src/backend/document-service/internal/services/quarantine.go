@@ -0,0 +1,426 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrAlreadyQuarantined = errors.New("document is already quarantined")
+    // ErrQuarantinedContentMissing is returned when the file an item holds is
+    // no longer stored, such as after the document was erased
+    ErrQuarantinedContentMissing = errors.New("quarantined content is no longer stored")
+)
+
+// QuarantineReview identifies who acts on a quarantine item and why. The
+// principal is the credential the request was made with, the reviewer the
+// person answering for the action
+type QuarantineReview struct {
+    Principal string
+    Reviewer  string
+    Reason    string
+}
+
+// QuarantineService holds suspicious files for review: uploads carrying
+// malware, originals of PDFs rebuilt without active content and documents
+// flagged as suspected fraud. Reviewers fetch held files sealed to the
+// forensic sandbox key, then release or destroy them. Every action is
+// recorded twice, on the item's history and in the audit log, so neither
+// store alone can hide it
+type QuarantineService struct {
+    repo      repository.QuarantineRepository
+    documents repository.DocumentRepository
+    storage   *StorageService
+    shredder  *CryptoShredder
+    // pipeline ingests malware uploads a reviewer releases
+    pipeline *DocumentPipeline
+    sandbox  utils.RecipientKey
+    logger   *zap.Logger
+}
+
+// NewQuarantineService creates the quarantine, or returns nil when it is
+// disabled
+func NewQuarantineService(cfg *config.Config, repo repository.QuarantineRepository, documents repository.DocumentRepository, storage *StorageService, shredder *CryptoShredder, logger *zap.Logger) (*QuarantineService, error) {
+    if cfg == nil || repo == nil || documents == nil || storage == nil || shredder == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.QuarantineConfig.Enabled {
+        return nil, nil
+    }
+
+    sandbox, err := utils.ParseRecipientKey(cfg.QuarantineConfig.SandboxKey)
+    if err != nil {
+        return nil, fmt.Errorf("invalid quarantine sandbox key: %w", err)
+    }
+    return &QuarantineService{
+        repo:      repo,
+        documents: documents,
+        storage:   storage,
+        shredder:  shredder,
+        sandbox:   sandbox,
+        logger:    logger.With(zap.String("component", "quarantine")),
+    }, nil
+}
+
+// UsePipeline ingests released malware uploads through the pipeline
+func (s *QuarantineService) UsePipeline(pipeline *DocumentPipeline) {
+    s.pipeline = pipeline
+}
+
+// SandboxKeyID identifies the key fetched files are sealed to
+func (s *QuarantineService) SandboxKeyID() string {
+    return s.sandbox.ID
+}
+
+// HoldUpload stores an upload the virus scanner found malware in, encrypted,
+// and holds it for review. contentType is that of the upload, which differs
+// from the request's for a part of a composition
+func (s *QuarantineService) HoldUpload(ctx context.Context, req IngestRequest, contentType string, content []byte, signature string) (*models.QuarantineItem, error) {
+    item := &models.QuarantineItem{
+        ID:            uuid.NewString(),
+        Reason:        models.QuarantineReasonMalware,
+        Status:        models.QuarantineStatusHeld,
+        Detail:        signature,
+        EnrollmentID:  req.EnrollmentID,
+        TenantID:      req.TenantID,
+        DocumentType:  req.DocumentType,
+        Filename:      req.Filename,
+        ContentType:   contentType,
+        Channel:       req.Channel,
+        SubmittedBy:   req.SubmittedBy,
+        QuarantinedAt: time.Now(),
+    }
+    if err := s.storage.StoreQuarantined(ctx, item, content); err != nil {
+        return nil, err
+    }
+    return s.hold(ctx, item)
+}
+
+// HoldDisarmed holds the quarantined original of a disarmed document for
+// review
+func (s *QuarantineService) HoldDisarmed(ctx context.Context, doc *models.Document) (*models.QuarantineItem, error) {
+    rendition, ok := doc.Rendition(models.RenditionQuarantinedOriginal)
+    if !ok || doc.Disarm == nil {
+        return nil, ErrQuarantinedContentMissing
+    }
+    return s.hold(ctx, &models.QuarantineItem{
+        ID:            uuid.NewString(),
+        Reason:        models.QuarantineReasonActiveContent,
+        Status:        models.QuarantineStatusHeld,
+        Detail:        strings.Join(doc.Disarm.Findings, ", "),
+        DocumentID:    doc.ID,
+        EnrollmentID:  doc.EnrollmentID,
+        TenantID:      doc.TenantID,
+        DocumentType:  doc.DocumentType,
+        Filename:      doc.Filename,
+        ContentType:   rendition.ContentType,
+        Channel:       doc.IngestionChannel,
+        Size:          rendition.Size,
+        QuarantinedAt: time.Now(),
+    })
+}
+
+// HoldDocument withholds a document flagged as suspected fraud, its content
+// and renditions alike, until a reviewer releases or destroys it
+func (s *QuarantineService) HoldDocument(ctx context.Context, documentID string, review QuarantineReview) (*models.QuarantineItem, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if doc.Quarantined() {
+        return nil, ErrAlreadyQuarantined
+    }
+
+    item := &models.QuarantineItem{
+        ID:            uuid.NewString(),
+        Reason:        models.QuarantineReasonFraud,
+        Status:        models.QuarantineStatusHeld,
+        Detail:        review.Reason,
+        DocumentID:    doc.ID,
+        EnrollmentID:  doc.EnrollmentID,
+        TenantID:      doc.TenantID,
+        DocumentType:  doc.DocumentType,
+        Filename:      doc.Filename,
+        ContentType:   doc.ContentType,
+        Channel:       doc.IngestionChannel,
+        Size:          doc.Size,
+        QuarantinedAt: time.Now(),
+    }
+    // The document is withheld before the item is recorded, so a failure
+    // leaves nothing served that should not be
+    doc.Quarantine(item.ID, review.Reason, review.Reviewer)
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return nil, fmt.Errorf("failed to persist quarantined document: %w", err)
+    }
+    return s.hold(ctx, item, review)
+}
+
+// hold records a new item, attributing the hold to the system unless a
+// review is given
+func (s *QuarantineService) hold(ctx context.Context, item *models.QuarantineItem, review ...QuarantineReview) (*models.QuarantineItem, error) {
+    action := models.QuarantineAction{Action: models.QuarantineActionHold, Principal: "SYSTEM", Reason: item.Detail, At: item.QuarantinedAt}
+    if len(review) > 0 {
+        action.Principal, action.Reviewer, action.Reason = review[0].Principal, review[0].Reviewer, review[0].Reason
+    }
+    item.Record(action)
+    if err := s.repo.Create(ctx, item); err != nil {
+        return nil, fmt.Errorf("failed to record quarantine item: %w", err)
+    }
+    s.audit(item, action)
+    return item, nil
+}
+
+// List returns the items matching the filter, oldest first
+func (s *QuarantineService) List(ctx context.Context, filter repository.QuarantineFilter) ([]*models.QuarantineItem, error) {
+    return s.repo.List(ctx, filter)
+}
+
+// Get returns an item
+func (s *QuarantineService) Get(ctx context.Context, id string) (*models.QuarantineItem, error) {
+    return s.repo.Get(ctx, id)
+}
+
+// Fetch returns the file a held item withholds sealed to the forensic
+// sandbox key. Only the sandbox can open it, so the plaintext never reaches
+// the reviewer's browser
+func (s *QuarantineService) Fetch(ctx context.Context, id string, review QuarantineReview) ([]byte, *models.QuarantineItem, error) {
+    item, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return nil, nil, err
+    }
+    if !item.Held() {
+        return nil, nil, models.ErrQuarantineResolved
+    }
+
+    content, _, err := s.content(ctx, item)
+    if err != nil {
+        return nil, nil, err
+    }
+    envelope, err := utils.SealEnvelope(content, s.sandbox)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to seal quarantined content: %w", err)
+    }
+
+    action := s.action(models.QuarantineActionFetch, review)
+    item.Record(action)
+    if err := s.repo.Update(ctx, item); err != nil {
+        return nil, nil, fmt.Errorf("failed to record quarantine fetch: %w", err)
+    }
+    s.audit(item, action)
+    return envelope, item, nil
+}
+
+// Release clears a held item. A malware upload is ingested as the document
+// it was sent as, with the reviewer's verdict standing in for the virus
+// scanner; a quarantined original is kept as the document's original
+// rendition; a document held for fraud is served again
+func (s *QuarantineService) Release(ctx context.Context, id string, review QuarantineReview) (*models.QuarantineItem, error) {
+    item, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if !item.Held() {
+        return nil, models.ErrQuarantineResolved
+    }
+
+    switch item.Reason {
+    case models.QuarantineReasonMalware:
+        content, _, err := s.content(ctx, item)
+        if err != nil {
+            return nil, err
+        }
+        if s.pipeline == nil {
+            return nil, errors.New("no pipeline to ingest released uploads")
+        }
+        doc, err := s.pipeline.Ingest(ctx, IngestRequest{
+            EnrollmentID: item.EnrollmentID,
+            TenantID:     item.TenantID,
+            DocumentType: item.DocumentType,
+            Filename:     item.Filename,
+            ContentType:  item.ContentType,
+            Channel:      item.Channel,
+            SubmittedBy:  item.SubmittedBy,
+            Content:      bytes.NewReader(content),
+            Size:         item.Size,
+            ReleasedFrom: item.ID,
+        })
+        if err != nil {
+            return nil, fmt.Errorf("failed to ingest released upload: %w", err)
+        }
+        item.DocumentID = doc.ID
+        if err := s.storage.DeleteQuarantined(ctx, item); err != nil {
+            return nil, err
+        }
+    case models.QuarantineReasonActiveContent:
+        content, doc, err := s.content(ctx, item)
+        if err != nil {
+            return nil, err
+        }
+        quarantined, _ := doc.Rendition(models.RenditionQuarantinedOriginal)
+        if err := s.storage.StoreEncryptedRendition(ctx, doc, models.RenditionOriginal, item.ContentType, content); err != nil {
+            return nil, err
+        }
+        doc.ReleaseQuarantinedOriginal(review.Reason, review.Reviewer)
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return nil, fmt.Errorf("failed to persist released document: %w", err)
+        }
+        if err := s.storage.delete(ctx, quarantined.StoragePath); err != nil && !errors.Is(err, ErrObjectNotFound) {
+            return nil, fmt.Errorf("failed to delete quarantined original: %w", err)
+        }
+    case models.QuarantineReasonFraud:
+        doc, err := s.documents.GetByID(ctx, item.DocumentID)
+        if err != nil {
+            return nil, err
+        }
+        doc.LiftQuarantine("RELEASED", review.Reason, review.Reviewer)
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return nil, fmt.Errorf("failed to persist released document: %w", err)
+        }
+    }
+    return s.resolve(ctx, item, models.QuarantineStatusReleased, s.action(models.QuarantineActionRelease, review))
+}
+
+// Destroy permanently deletes the file a held item withholds. A document
+// held for fraud is anonymized: its content is crypto-shredded and its
+// record kept with the personal data removed
+func (s *QuarantineService) Destroy(ctx context.Context, id string, review QuarantineReview) (*models.QuarantineItem, error) {
+    item, err := s.repo.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if !item.Held() {
+        return nil, models.ErrQuarantineResolved
+    }
+
+    switch item.Reason {
+    case models.QuarantineReasonMalware:
+        if err := s.storage.DeleteQuarantined(ctx, item); err != nil {
+            return nil, err
+        }
+    case models.QuarantineReasonActiveContent:
+        doc, err := s.documents.GetByID(ctx, item.DocumentID)
+        if err != nil {
+            return nil, err
+        }
+        if quarantined, ok := doc.Rendition(models.RenditionQuarantinedOriginal); ok {
+            if err := s.storage.delete(ctx, quarantined.StoragePath); err != nil && !errors.Is(err, ErrObjectNotFound) {
+                return nil, fmt.Errorf("failed to delete quarantined original: %w", err)
+            }
+        }
+        doc.DestroyQuarantinedOriginal(review.Reason, review.Reviewer)
+        if err := s.documents.Update(ctx, doc); err != nil {
+            return nil, fmt.Errorf("failed to persist document: %w", err)
+        }
+    case models.QuarantineReasonFraud:
+        doc, err := s.documents.GetByID(ctx, item.DocumentID)
+        if err != nil {
+            return nil, err
+        }
+        doc.LiftQuarantine("DESTROYED", review.Reason, review.Reviewer)
+        if err := s.shredder.Anonymize(ctx, doc); err != nil {
+            return nil, fmt.Errorf("failed to destroy quarantined document: %w", err)
+        }
+    }
+    return s.resolve(ctx, item, models.QuarantineStatusDestroyed, s.action(models.QuarantineActionDestroy, review))
+}
+
+// resolve records the release or destruction of an item
+func (s *QuarantineService) resolve(ctx context.Context, item *models.QuarantineItem, status string, action models.QuarantineAction) (*models.QuarantineItem, error) {
+    if err := item.Resolve(status, action); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(ctx, item); err != nil {
+        return nil, fmt.Errorf("failed to record quarantine resolution: %w", err)
+    }
+    s.audit(item, action)
+    return item, nil
+}
+
+// content reads the plaintext of the file an item withholds, with the
+// document it belongs to when it has one
+func (s *QuarantineService) content(ctx context.Context, item *models.QuarantineItem) ([]byte, *models.Document, error) {
+    if item.Reason == models.QuarantineReasonMalware {
+        reader, _, err := s.storage.OpenQuarantined(ctx, item)
+        if err != nil {
+            return nil, nil, err
+        }
+        defer reader.Close()
+        content, err := io.ReadAll(reader)
+        return content, nil, err
+    }
+
+    doc, err := s.documents.GetByID(ctx, item.DocumentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return nil, nil, ErrQuarantinedContentMissing
+    }
+    if err != nil {
+        return nil, nil, err
+    }
+    if item.Reason == models.QuarantineReasonActiveContent {
+        rendition, ok := doc.Rendition(models.RenditionQuarantinedOriginal)
+        if !ok {
+            return nil, nil, ErrQuarantinedContentMissing
+        }
+        reader, _, err := s.storage.OpenRendition(ctx, doc.ID, rendition)
+        if err != nil {
+            return nil, nil, err
+        }
+        defer reader.Close()
+        content, err := io.ReadAll(reader)
+        return content, doc, err
+    }
+
+    if doc.EncryptionInfo.Shredded() {
+        return nil, nil, ErrQuarantinedContentMissing
+    }
+    reader, err := s.storage.RetrieveDocument(ctx, doc)
+    if err != nil {
+        return nil, nil, err
+    }
+    // The plaintext is held in a pooled buffer released on close
+    if closer, ok := reader.(io.Closer); ok {
+        defer closer.Close()
+    }
+    content, err := io.ReadAll(reader)
+    return content, doc, err
+}
+
+// action builds the record of an action taken on review
+func (s *QuarantineService) action(action string, review QuarantineReview) models.QuarantineAction {
+    return models.QuarantineAction{
+        Action:    action,
+        Principal: review.Principal,
+        Reviewer:  review.Reviewer,
+        Reason:    review.Reason,
+        At:        time.Now(),
+    }
+}
+
+// audit writes an action to the audit log, the second record of every
+// action besides the item's history
+func (s *QuarantineService) audit(item *models.QuarantineItem, action models.QuarantineAction) {
+    quarantineActions.WithLabelValues(item.Reason, action.Action).Inc()
+    s.logger.Warn("Quarantine item "+action.Action,
+        zap.String("quarantine_id", item.ID),
+        zap.String("quarantine_reason", item.Reason),
+        zap.String("status", item.Status),
+        zap.String("document_id", item.DocumentID),
+        zap.String("enrollment_id", item.EnrollmentID),
+        zap.String("principal", action.Principal),
+        zap.String("reviewer", action.Reviewer),
+        zap.String("justification", action.Reason),
+    )
+}
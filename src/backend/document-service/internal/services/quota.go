@@ -0,0 +1,174 @@
+package services
+
+import (
+    "context"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// QuotaDecision is the outcome of counting a request against its tenant's
+// quota
+type QuotaDecision struct {
+    Allowed    bool
+    Burst      bool
+    Usage      models.QuotaUsage
+    RetryAfter time.Duration
+}
+
+// SoftQuotas enforces the soft request quotas of tenants. Requests over a
+// tenant's quota spend burst credits, which accrue while the tenant stays
+// under it, so brokers can absorb enrollment peaks without a hard cutoff.
+// Accounts are kept per instance, like the API rate limit
+type SoftQuotas struct {
+    mu       sync.Mutex
+    cfg      config.QuotaConfig
+    accounts map[string]*models.QuotaAccount
+    labels   *TenantLabels
+    logger   *zap.Logger
+}
+
+// NewSoftQuotas creates the quotas, or nil when quotas are disabled
+func NewSoftQuotas(cfg *config.Config, logger *zap.Logger) *SoftQuotas {
+    if cfg == nil || !cfg.QuotaConfig.Enabled {
+        return nil
+    }
+
+    return &SoftQuotas{
+        cfg:      cfg.QuotaConfig,
+        accounts: make(map[string]*models.QuotaAccount),
+        logger:   logger.With(zap.String("component", "soft_quotas")),
+    }
+}
+
+// UseTenantLabels labels quota metrics by tenant; it must be called before
+// serving requests
+func (q *SoftQuotas) UseTenantLabels(labels *TenantLabels) {
+    if q != nil {
+        q.labels = labels
+    }
+}
+
+// Applies reports whether requests of users with the role count against
+// their tenant's quota
+func (q *SoftQuotas) Applies(role string) bool {
+    return q != nil && slices.Contains(q.cfg.Roles, role)
+}
+
+// Take counts a request of the tenant
+func (q *SoftQuotas) Take(tenantID string) QuotaDecision {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    limits := q.limits(tenantID)
+    account := q.account(tenantID, limits, now)
+    allowed, burst := account.Take(limits, now)
+
+    decision := QuotaDecision{Allowed: allowed, Burst: burst, Usage: account.Usage(limits, now)}
+    tenant := q.labels.Label(MetricQuotaRequests, tenantID)
+    switch {
+    case !allowed:
+        decision.RetryAfter = account.RetryAfter(limits, now)
+        quotaRequests.WithLabelValues(tenant, "rejected").Inc()
+        if account.Rejected == 1 {
+            q.logger.Warn("Tenant exhausted its quota and burst credits",
+                zap.String("tenant_id", tenantID),
+                zap.Int("limit", limits.Requests),
+                zap.Int("burst_used", account.BurstUsed),
+            )
+        }
+    case burst:
+        quotaRequests.WithLabelValues(tenant, "burst").Inc()
+    default:
+        quotaRequests.WithLabelValues(tenant, "allowed").Inc()
+    }
+    return decision
+}
+
+// Usage reports the tenant's use of its quota in the current window
+func (q *SoftQuotas) Usage(tenantID string) models.QuotaUsage {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    limits := q.limits(tenantID)
+    return q.account(tenantID, limits, now).Usage(limits, now)
+}
+
+// List reports the usage of the tenants seen recently, ordered by tenant
+func (q *SoftQuotas) List() []models.QuotaUsage {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    usage := make([]models.QuotaUsage, 0, len(q.accounts))
+    for tenantID, account := range q.accounts {
+        usage = append(usage, account.Usage(q.limits(tenantID), now))
+    }
+    slices.SortFunc(usage, func(a, b models.QuotaUsage) int {
+        return strings.Compare(a.TenantID, b.TenantID)
+    })
+    return usage
+}
+
+// Run forgets idle tenants whose credits are full again until the context
+// is cancelled; a new account starts out the same
+func (q *SoftQuotas) Run(ctx context.Context) {
+    if q == nil {
+        return
+    }
+
+    ticker := time.NewTicker(q.cfg.Window)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            q.sweep()
+        }
+    }
+}
+
+func (q *SoftQuotas) sweep() {
+    q.mu.Lock()
+    defer q.mu.Unlock()
+
+    now := time.Now()
+    for tenantID, account := range q.accounts {
+        limits := q.limits(tenantID)
+        usage := account.Usage(limits, now)
+        if usage.Used == 0 && usage.Rejected == 0 && account.Credits >= limits.MaxCredits {
+            delete(q.accounts, tenantID)
+        }
+    }
+}
+
+// account returns the tenant's account, opening it on first use; callers
+// hold the lock
+func (q *SoftQuotas) account(tenantID string, limits models.QuotaLimits, now time.Time) *models.QuotaAccount {
+    account, ok := q.accounts[tenantID]
+    if !ok {
+        account = models.NewQuotaAccount(tenantID, limits, now)
+        q.accounts[tenantID] = account
+    }
+    return account
+}
+
+func (q *SoftQuotas) limits(tenantID string) models.QuotaLimits {
+    quota := q.cfg.QuotaFor(tenantID)
+    return models.QuotaLimits{
+        Requests:       quota.Requests,
+        Window:         q.cfg.Window,
+        CreditsPerHour: quota.BurstCreditsPerHour,
+        MaxCredits:     quota.MaxBurstCredits,
+    }
+}
@@ -0,0 +1,323 @@
+package services
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "net/http"
+    "sync"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    TopicReceiptIssued = "receipts.download_issued"
+)
+
+var ErrInvalidReceiptSignature = errors.New("download receipt signature is invalid")
+
+// ReceiptViewer identifies who accessed a document and in which session
+type ReceiptViewer struct {
+    UserID         string
+    Role           string
+    SessionID      string
+    ImpersonatorID string
+}
+
+// DownloadReceipts collects accesses to sensitive documents into one receipt
+// per session and enrollment, then signs and stores the receipt once the
+// session goes idle, optionally sending it to the data subject. Receipts are
+// signed with ECDSA P-256 so anyone holding the public key can verify them.
+// A nil *DownloadReceipts records nothing
+type DownloadReceipts struct {
+    mu         sync.Mutex
+    cfg        config.DownloadReceiptsConfig
+    types      map[string]bool
+    key        *ecdsa.PrivateKey
+    keyID      string
+    open       map[string]*models.DownloadReceipt
+    receipts   repository.ReceiptRepository
+    outbox     repository.OutboxRepository
+    httpClient *http.Client
+    logger     *zap.Logger
+}
+
+// NewDownloadReceipts creates the receipt service, or nil when receipts are
+// disabled
+func NewDownloadReceipts(cfg *config.Config, receipts repository.ReceiptRepository, outbox repository.OutboxRepository, logger *zap.Logger) (*DownloadReceipts, error) {
+    if cfg == nil || receipts == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.DownloadReceiptsConfig.Enabled {
+        return nil, nil
+    }
+
+    key, err := parseSigningKey(cfg.DownloadReceiptsConfig.SigningKey, "receipt")
+    if err != nil {
+        return nil, err
+    }
+    publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode receipt public key: %w", err)
+    }
+    keyID := sha256.Sum256(publicDER)
+
+    types := make(map[string]bool)
+    for _, documentType := range cfg.DownloadReceiptsConfig.DocumentTypes {
+        types[documentType] = true
+    }
+
+    return &DownloadReceipts{
+        cfg:      cfg.DownloadReceiptsConfig,
+        types:    types,
+        key:      key,
+        keyID:    hex.EncodeToString(keyID[:16]),
+        open:     make(map[string]*models.DownloadReceipt),
+        receipts: receipts,
+        outbox:   outbox,
+        httpClient: &http.Client{
+            Timeout:   cfg.DownloadReceiptsConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+        logger: logger.With(zap.String("component", "download_receipts")),
+    }, nil
+}
+
+// parseSigningKey parses a PEM encoded P-256 private key in SEC 1 or PKCS #8
+// form
+func parseSigningKey(data, purpose string) (*ecdsa.PrivateKey, error) {
+    block, _ := pem.Decode([]byte(data))
+    if block == nil {
+        return nil, fmt.Errorf("%s signing key is not PEM encoded", purpose)
+    }
+
+    var key *ecdsa.PrivateKey
+    if parsed, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+        key = parsed
+    } else if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+        key, _ = parsed.(*ecdsa.PrivateKey)
+    }
+    if key == nil || key.Curve != elliptic.P256() {
+        return nil, fmt.Errorf("%s signing key must be a P-256 private key", purpose)
+    }
+    return key, nil
+}
+
+// Record adds an access to the open receipt of the viewer's session for the
+// document's enrollment. Documents of types not covered by receipts are
+// ignored
+func (r *DownloadReceipts) Record(viewer ReceiptViewer, doc *models.Document, access, detail string) {
+    if r == nil || doc == nil || !r.types[doc.DocumentType] {
+        return
+    }
+
+    session := viewer.SessionID
+    if session == "" {
+        session = "user:" + viewer.UserID
+    }
+    key := session + "|" + viewer.UserID + "|" + doc.EnrollmentID
+    now := time.Now().UTC()
+
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    receipt, ok := r.open[key]
+    if !ok {
+        receipt = &models.DownloadReceipt{
+            ID:             uuid.New().String(),
+            SessionID:      viewer.SessionID,
+            EnrollmentID:   doc.EnrollmentID,
+            UserID:         viewer.UserID,
+            Role:           viewer.Role,
+            ImpersonatorID: viewer.ImpersonatorID,
+            OpenedAt:       now,
+        }
+        r.open[key] = receipt
+    }
+    downloadReceiptAccesses.WithLabelValues(access).Inc()
+
+    for i := range receipt.Accesses {
+        existing := &receipt.Accesses[i]
+        if existing.DocumentID == doc.ID && existing.Access == access && existing.Detail == detail && existing.ContentHash == doc.ContentHash {
+            existing.Count++
+            existing.LastAccessAt = now
+            return
+        }
+    }
+    receipt.Accesses = append(receipt.Accesses, models.ReceiptAccess{
+        DocumentID:    doc.ID,
+        DocumentType:  doc.DocumentType,
+        ContentHash:   doc.ContentHash,
+        Access:        access,
+        Detail:        detail,
+        Count:         1,
+        FirstAccessAt: now,
+        LastAccessAt:  now,
+    })
+}
+
+// Run issues receipts whose session went idle or stayed open too long until
+// the context is cancelled, then issues every open receipt
+func (r *DownloadReceipts) Run(ctx context.Context) {
+    if r == nil {
+        return
+    }
+
+    ticker := time.NewTicker(time.Minute)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            r.IssueDue(context.Background(), time.Time{})
+            return
+        case <-ticker.C:
+            r.IssueDue(ctx, time.Now())
+        }
+    }
+}
+
+// IssueDue signs, stores and sends the receipts due at now; a zero now issues
+// every open receipt
+func (r *DownloadReceipts) IssueDue(ctx context.Context, now time.Time) {
+    var due []*models.DownloadReceipt
+
+    r.mu.Lock()
+    for key, receipt := range r.open {
+        lastAccess := receipt.OpenedAt
+        for _, access := range receipt.Accesses {
+            if access.LastAccessAt.After(lastAccess) {
+                lastAccess = access.LastAccessAt
+            }
+        }
+        if now.IsZero() || now.Sub(lastAccess) >= r.cfg.SessionIdle || now.Sub(receipt.OpenedAt) >= r.cfg.MaxOpen {
+            due = append(due, receipt)
+            delete(r.open, key)
+        }
+    }
+    r.mu.Unlock()
+
+    for _, receipt := range due {
+        if err := r.issue(ctx, receipt); err != nil {
+            downloadReceipts.WithLabelValues("failed").Inc()
+            r.logger.Error("Failed to issue download receipt",
+                zap.String("receipt_id", receipt.ID),
+                zap.String("enrollment_id", receipt.EnrollmentID),
+                zap.Error(err),
+            )
+        }
+    }
+}
+
+func (r *DownloadReceipts) issue(ctx context.Context, receipt *models.DownloadReceipt) error {
+    receipt.IssuedAt = time.Now().UTC()
+    receipt.KeyID = r.keyID
+    digest, err := receiptDigest(receipt)
+    if err != nil {
+        return err
+    }
+    signature, err := ecdsa.SignASN1(rand.Reader, r.key, digest)
+    if err != nil {
+        return fmt.Errorf("failed to sign download receipt: %w", err)
+    }
+    receipt.Signature = base64.StdEncoding.EncodeToString(signature)
+
+    if err := r.receipts.Save(ctx, receipt); err != nil {
+        return fmt.Errorf("failed to store download receipt: %w", err)
+    }
+    downloadReceipts.WithLabelValues("issued").Inc()
+    r.logger.Info("Download receipt issued",
+        zap.String("receipt_id", receipt.ID),
+        zap.String("enrollment_id", receipt.EnrollmentID),
+        zap.String("user_id", receipt.UserID),
+        zap.Int("documents", len(receipt.Accesses)),
+    )
+
+    if !r.cfg.NotifySubjects {
+        return nil
+    }
+    msg, err := newOutboxMessage(TopicReceiptIssued, receipt.ID, receipt)
+    if err != nil {
+        return err
+    }
+    if err := r.outbox.Enqueue(ctx, msg); err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        return fmt.Errorf("failed to queue download receipt notice: %w", err)
+    }
+    return nil
+}
+
+// Get returns an issued receipt
+func (r *DownloadReceipts) Get(ctx context.Context, id string) (*models.DownloadReceipt, error) {
+    return r.receipts.Get(ctx, id)
+}
+
+// List returns the issued receipts matching the filter, oldest first
+func (r *DownloadReceipts) List(ctx context.Context, filter repository.ReceiptFilter) ([]*models.DownloadReceipt, error) {
+    if !filter.To.IsZero() && !filter.From.Before(filter.To) {
+        return nil, ErrInvalidReportPeriod
+    }
+    return r.receipts.List(ctx, filter)
+}
+
+// Verify checks that a receipt was signed with the current key and not
+// altered since
+func (r *DownloadReceipts) Verify(receipt *models.DownloadReceipt) error {
+    if receipt.KeyID != r.keyID {
+        return fmt.Errorf("%w: signed with key %s", ErrInvalidReceiptSignature, receipt.KeyID)
+    }
+    signature, err := base64.StdEncoding.DecodeString(receipt.Signature)
+    if err != nil {
+        return ErrInvalidReceiptSignature
+    }
+    digest, err := receiptDigest(receipt)
+    if err != nil {
+        return err
+    }
+    if !ecdsa.VerifyASN1(&r.key.PublicKey, digest, signature) {
+        return ErrInvalidReceiptSignature
+    }
+    return nil
+}
+
+// PublicKey returns the PEM encoded key receipts are verified with and its ID
+func (r *DownloadReceipts) PublicKey() (string, string, error) {
+    der, err := x509.MarshalPKIXPublicKey(&r.key.PublicKey)
+    if err != nil {
+        return "", "", fmt.Errorf("failed to encode receipt public key: %w", err)
+    }
+    return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), r.keyID, nil
+}
+
+// Deliver is the outbox handler posting issued receipts to the notification
+// service, which emails them to the data subject
+func (r *DownloadReceipts) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    return postOutboxMessage(ctx, r.httpClient, r.cfg.NotificationURL, msg)
+}
+
+// receiptDigest is the SHA-256 of the receipt's JSON encoding without its
+// signature
+func receiptDigest(receipt *models.DownloadReceipt) ([]byte, error) {
+    unsigned := *receipt
+    unsigned.Signature = ""
+    encoded, err := json.Marshal(&unsigned)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode download receipt: %w", err)
+    }
+    digest := sha256.Sum256(encoded)
+    return digest[:], nil
+}
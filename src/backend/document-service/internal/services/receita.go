@@ -0,0 +1,291 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "regexp"
+    "strings"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepReceita = "receita"
+)
+
+// Extracted CPF field names
+const (
+    FieldCPF          = "cpf"
+    FieldCPFSituation = "cpf.situation"
+)
+
+// Receita Federal CPF situations
+const (
+    CPFSituationRegular   = "regular"
+    CPFSituationPending   = "pendente_de_regularizacao"
+    CPFSituationSuspended = "suspensa"
+    CPFSituationCancelled = "cancelada"
+    CPFSituationNull      = "nula"
+    CPFSituationDeceased  = "titular_falecido"
+)
+
+// cpfSituationOther labels situations outside the known ones in metrics
+const cpfSituationOther = "other"
+
+var (
+    cpfPattern = regexp.MustCompile(`\b\d{3}\.?\d{3}\.?\d{3}-?\d{2}\b`)
+)
+
+// CPFSituation is the registration situation of a CPF at Receita Federal
+type CPFSituation struct {
+    CPF       string    `json:"cpf"`
+    Situation string    `json:"situation"`
+    CheckedAt time.Time `json:"checked_at"`
+}
+
+// Regular reports whether the CPF is in good standing
+func (s CPFSituation) Regular() bool {
+    return s.Situation == CPFSituationRegular
+}
+
+// CPFSituationProvider looks up CPF situations; Receita Federal is only
+// reachable through certified brokers so each broker gets its own provider
+type CPFSituationProvider interface {
+    Name() string
+    GetSituation(ctx context.Context, cpf string) (CPFSituation, error)
+}
+
+// BrokerCPFProvider queries a certified broker's REST API
+type BrokerCPFProvider struct {
+    name       string
+    baseURL    string
+    apiKey     string
+    httpClient *http.Client
+}
+
+// NewBrokerCPFProvider creates a new broker backed provider
+func NewBrokerCPFProvider(cfg *config.Config) (*BrokerCPFProvider, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    return &BrokerCPFProvider{
+        name:       cfg.ReceitaConfig.Provider,
+        baseURL:    cfg.ReceitaConfig.BaseURL,
+        apiKey:     cfg.ReceitaConfig.APIKey,
+        httpClient: &http.Client{Timeout: cfg.ReceitaConfig.Timeout},
+    }, nil
+}
+
+// Name returns the configured broker
+func (p *BrokerCPFProvider) Name() string {
+    return p.name
+}
+
+// GetSituation fetches the current situation of the CPF
+func (p *BrokerCPFProvider) GetSituation(ctx context.Context, cpf string) (CPFSituation, error) {
+    endpoint := fmt.Sprintf("%s/v1/cpf/%s/situation", p.baseURL, url.PathEscape(cpf))
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+    if err != nil {
+        return CPFSituation{}, fmt.Errorf("failed to build CPF situation request: %w", err)
+    }
+    req.Header.Set("Accept", "application/json")
+    req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+    resp, err := p.httpClient.Do(req)
+    if err != nil {
+        return CPFSituation{}, fmt.Errorf("CPF situation request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return CPFSituation{}, fmt.Errorf("CPF situation provider returned status %d", resp.StatusCode)
+    }
+
+    var result struct {
+        Situation string `json:"situation"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return CPFSituation{}, fmt.Errorf("failed to decode CPF situation: %w", err)
+    }
+
+    return CPFSituation{
+        CPF:       cpf,
+        Situation: normalizeCPFSituation(result.Situation),
+        CheckedAt: time.Now(),
+    }, nil
+}
+
+// CachingCPFProvider caches situations so repeated documents of the same
+// beneficiary do not incur additional broker charges
+type CachingCPFProvider struct {
+    next  CPFSituationProvider
+    cache *ttlCache[CPFSituation]
+}
+
+// NewCachingCPFProvider creates a caching provider
+func NewCachingCPFProvider(cfg *config.Config, next CPFSituationProvider) (*CachingCPFProvider, error) {
+    if cfg == nil || next == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CachingCPFProvider{
+        next:  next,
+        cache: newTTLCache[CPFSituation](cfg.ReceitaConfig.CacheTTL),
+    }, nil
+}
+
+// Name returns the wrapped provider's name
+func (p *CachingCPFProvider) Name() string {
+    return p.next.Name()
+}
+
+// GetSituation returns the cached situation or fetches it from the wrapped provider
+func (p *CachingCPFProvider) GetSituation(ctx context.Context, cpf string) (CPFSituation, error) {
+    if situation, ok := p.cache.Get(cpf); ok {
+        cpfSituationChecks.WithLabelValues("cached").Inc()
+        return situation, nil
+    }
+
+    situation, err := p.next.GetSituation(ctx, cpf)
+    if err != nil {
+        cpfSituationChecks.WithLabelValues("error").Inc()
+        return CPFSituation{}, err
+    }
+    cpfSituationChecks.WithLabelValues(cpfSituationLabel(situation.Situation)).Inc()
+
+    p.cache.Set(cpf, situation)
+    return situation, nil
+}
+
+// ReceitaStep extracts CPFs from identity document text and attaches their
+// Receita Federal situation to the document's extracted fields
+type ReceitaStep struct {
+    provider CPFSituationProvider
+}
+
+// NewReceitaStep creates a new CPF situation step
+func NewReceitaStep(provider CPFSituationProvider) (*ReceitaStep, error) {
+    if provider == nil {
+        return nil, errors.New("provider cannot be nil")
+    }
+    return &ReceitaStep{provider: provider}, nil
+}
+
+// Name returns the step name
+func (s *ReceitaStep) Name() string {
+    return StepReceita
+}
+
+// Provenance reports the broker consulted; CPFs are read from the OCR text
+func (s *ReceitaStep) Provenance() StepProvenance {
+    return StepProvenance{Version: receitaStepVersion, Provider: s.provider.Name(), Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether the document is an identity document
+func (s *ReceitaStep) Applies(doc *models.Document) bool {
+    return doc.DocumentType == "identity"
+}
+
+// Execute checks every valid CPF found in the OCR text
+func (s *ReceitaStep) Execute(ctx context.Context, run *PipelineRun) error {
+    cpfs := ExtractCPFs(run.OCRText)
+    if len(cpfs) == 0 {
+        return nil
+    }
+
+    fields := make([]models.ExtractedField, 0, len(cpfs)*2)
+    for _, cpf := range cpfs {
+        situation, err := s.provider.GetSituation(ctx, cpf)
+        if err != nil {
+            return fmt.Errorf("failed to check CPF situation: %w", err)
+        }
+
+        fields = append(fields,
+            models.ExtractedField{Name: FieldCPF, Value: cpf, Confidence: 1},
+            models.ExtractedField{Name: FieldCPFSituation, Value: situation.Situation, Confidence: 1, ExtractedAt: situation.CheckedAt},
+        )
+    }
+
+    run.Document.SetExtractedFields(StepReceita, fields)
+    return nil
+}
+
+// ExtractCPFs returns the distinct check-digit valid CPFs found in text
+func ExtractCPFs(text string) []string {
+    cpfs := make([]string, 0)
+    for _, match := range cpfPattern.FindAllString(text, -1) {
+        cpf := digitsOnly(match)
+        if ValidCPF(cpf) {
+            cpfs = appendUnique(cpfs, cpf)
+        }
+    }
+    return cpfs
+}
+
+// ValidCPF validates the length and both check digits of an unformatted CPF
+func ValidCPF(cpf string) bool {
+    if len(cpf) != 11 || digitsOnly(cpf) != cpf {
+        return false
+    }
+    // Repeated digits pass the checksum but are never issued
+    if strings.Count(cpf, cpf[:1]) == 11 {
+        return false
+    }
+
+    for _, length := range []int{9, 10} {
+        sum := 0
+        for i := 0; i < length; i++ {
+            sum += int(cpf[i]-'0') * (length + 1 - i)
+        }
+        digit := sum * 10 % 11
+        if digit == 10 {
+            digit = 0
+        }
+        if digit != int(cpf[length]-'0') {
+            return false
+        }
+    }
+    return true
+}
+
+// normalizeCPFSituation maps broker descriptions such as "PENDENTE DE REGULARIZAÇÃO"
+// onto the situation constants
+func normalizeCPFSituation(situation string) string {
+    normalized := strings.ToLower(strings.TrimSpace(situation))
+    switch {
+    case strings.HasPrefix(normalized, "regular"):
+        return CPFSituationRegular
+    case strings.HasPrefix(normalized, "pendente"):
+        return CPFSituationPending
+    case strings.HasPrefix(normalized, "suspens"):
+        return CPFSituationSuspended
+    case strings.HasPrefix(normalized, "cancelad"):
+        return CPFSituationCancelled
+    case strings.HasPrefix(normalized, "nul"):
+        return CPFSituationNull
+    case strings.Contains(normalized, "falecid"):
+        return CPFSituationDeceased
+    default:
+        return normalized
+    }
+}
+
+// cpfSituationLabel bounds the situation label of cpf_situation_checks_total
+// to the known situations, since brokers may return any description
+func cpfSituationLabel(situation string) string {
+    switch situation {
+    case CPFSituationRegular, CPFSituationPending, CPFSituationSuspended,
+        CPFSituationCancelled, CPFSituationNull, CPFSituationDeceased:
+        return situation
+    default:
+        return cpfSituationOther
+    }
+}
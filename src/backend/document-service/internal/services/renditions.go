@@ -0,0 +1,141 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    "image/color"
+    "image/jpeg"
+
+    "github.com/gen2brain/go-fitz" // v1.23.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const StepRenditions = "renditions"
+
+// RenditionsStep produces the thumbnail and preview renditions of a document
+// from its first page, as JPEGs stored unencrypted so they are downloaded
+// without decryption
+type RenditionsStep struct {
+    cfg     config.RenditionsConfig
+    storage *StorageService
+}
+
+// NewRenditionsStep creates a new thumbnail and preview step
+func NewRenditionsStep(cfg *config.Config, storage *StorageService) (*RenditionsStep, error) {
+    if cfg == nil || storage == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    return &RenditionsStep{cfg: cfg.RenditionsConfig, storage: storage}, nil
+}
+
+// Name returns the step name
+func (s *RenditionsStep) Name() string {
+    return StepRenditions
+}
+
+// Provenance reports the generator version
+func (s *RenditionsStep) Provenance() StepProvenance {
+    return StepProvenance{Version: renditionsVersion}
+}
+
+// Applies reports whether the first page of the document can be rendered
+func (s *RenditionsStep) Applies(doc *models.Document) bool {
+    return doc.ContentType == "application/pdf" || doc.ContentType == "image/jpeg" || doc.ContentType == "image/png"
+}
+
+// Execute renders the first page and stores it scaled down to the thumbnail
+// and preview widths
+func (s *RenditionsStep) Execute(ctx context.Context, run *PipelineRun) error {
+    page, err := renderFirstPage(run.Document.ContentType, run.Content, s.cfg.DPI)
+    if err != nil {
+        return err
+    }
+
+    for _, rendition := range []struct {
+        name  string
+        width int
+    }{
+        {models.RenditionThumbnail, s.cfg.ThumbnailWidth},
+        {models.RenditionPreview, s.cfg.PreviewWidth},
+    } {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        var out bytes.Buffer
+        if err := jpeg.Encode(&out, ScaleToWidth(page, rendition.width), &jpeg.Options{Quality: s.cfg.JPEGQuality}); err != nil {
+            return fmt.Errorf("failed to encode %s: %w", rendition.name, err)
+        }
+        if err := s.storage.StoreRendition(ctx, run.Document, rendition.name, "image/jpeg", out.Bytes()); err != nil {
+            return fmt.Errorf("failed to store %s: %w", rendition.name, err)
+        }
+    }
+    return nil
+}
+
+// renderFirstPage decodes an image, or renders the first page of a PDF at dpi
+func renderFirstPage(contentType string, content []byte, dpi float64) (image.Image, error) {
+    if contentType != "application/pdf" {
+        img, _, err := image.Decode(bytes.NewReader(content))
+        if err != nil {
+            return nil, fmt.Errorf("failed to decode image: %w", err)
+        }
+        return img, nil
+    }
+
+    pdf, err := fitz.NewFromMemory(content)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open PDF: %w", err)
+    }
+    defer pdf.Close()
+
+    if pdf.NumPage() == 0 {
+        return nil, fmt.Errorf("failed to render PDF: no pages")
+    }
+    page, err := pdf.ImageDPI(0, dpi)
+    if err != nil {
+        return nil, fmt.Errorf("failed to render page 1: %w", err)
+    }
+    return page, nil
+}
+
+// ScaleToWidth scales an image down to width, keeping its aspect ratio, by
+// averaging the source pixels under each scaled pixel. Images no wider are
+// returned as they are
+func ScaleToWidth(src image.Image, width int) image.Image {
+    bounds := src.Bounds()
+    if width <= 0 || bounds.Dx() <= width {
+        return src
+    }
+    height := max(1, bounds.Dy()*width/bounds.Dx())
+
+    dst := image.NewRGBA(image.Rect(0, 0, width, height))
+    for y := 0; y < height; y++ {
+        y0 := bounds.Min.Y + y*bounds.Dy()/height
+        y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+        for x := 0; x < width; x++ {
+            x0 := bounds.Min.X + x*bounds.Dx()/width
+            x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+            var r, g, b, a, n uint64
+            for sy := y0; sy < y1; sy++ {
+                for sx := x0; sx < x1; sx++ {
+                    pr, pg, pb, pa := src.At(sx, sy).RGBA()
+                    r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+                    n++
+                }
+            }
+            dst.SetRGBA(x, y, color.RGBA{
+                R: uint8(r / n >> 8),
+                G: uint8(g / n >> 8),
+                B: uint8(b / n >> 8),
+                A: uint8(a / n >> 8),
+            })
+        }
+    }
+    return dst
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models/invariants"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+var (
+    ErrRepairTooLarge    = errors.New("repair matches too many documents")
+    errUnknownLocation   = errors.New("storage path is unknown")
+    errUnknownEncryption = errors.New("encryption metadata is not valid")
+)
+
+// RepairRequest asks to check the documents matching the filter against the
+// metadata invariants, and to fix the violations unless it is a dry run
+type RepairRequest struct {
+    Filter      models.BulkOperationFilter `json:"filter"`
+    DryRun      bool                       `json:"dry_run"`
+    RequestedBy string                     `json:"requested_by,omitempty"`
+}
+
+// MetadataRepair checks stored documents against the invariants of their
+// metadata and fixes, in place, the violations a fix is provably right for:
+// a lost storage path is restored to the object labelled with the document's
+// ID, encryption metadata is rebuilt from the headers of the stored object
+// once the content authenticates under it, and a missing hash is computed
+// over the decrypted content. A hash that is present is never rewritten, as
+// a mismatch may be tampering. Repairs run in the request, on at most as many
+// documents as a bulk operation
+type MetadataRepair struct {
+    cfg       *config.Config
+    documents repository.DocumentRepository
+    storage   *StorageService
+    logger    *zap.Logger
+}
+
+// NewMetadataRepair creates the metadata repair
+func NewMetadataRepair(cfg *config.Config, documents repository.DocumentRepository, storage *StorageService, logger *zap.Logger) (*MetadataRepair, error) {
+    if cfg == nil || documents == nil || storage == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &MetadataRepair{
+        cfg:       cfg,
+        documents: documents,
+        storage:   storage,
+        logger:    logger.With(zap.String("component", "metadata_repair")),
+    }, nil
+}
+
+// Repair checks the documents matching the request's filter and reports
+// their violations, fixing them unless it is a dry run. A dry run probes the
+// object store as a repair would but persists nothing
+func (r *MetadataRepair) Repair(ctx context.Context, req RepairRequest) (*models.RepairReport, error) {
+    if req.Filter.Empty() {
+        return nil, ErrEmptyBulkFilter
+    }
+    targets, err := matchDocuments(ctx, r.documents, req.Filter, nil)
+    if err != nil {
+        return nil, fmt.Errorf("failed to resolve documents: %w", err)
+    }
+    if limit := r.cfg.BulkOperationsConfig.MaxDocuments; len(targets) > limit {
+        return nil, fmt.Errorf("%w: %d documents, at most %d", ErrRepairTooLarge, len(targets), limit)
+    }
+
+    report := &models.RepairReport{
+        DryRun:      req.DryRun,
+        RequestedBy: req.RequestedBy,
+        StartedAt:   time.Now(),
+        Repairs:     make([]models.DocumentRepair, 0),
+    }
+    for _, target := range targets {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        doc, err := r.documents.GetByID(ctx, target.DocumentID)
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            continue
+        }
+        if err != nil {
+            return nil, fmt.Errorf("failed to get document %s: %w", target.DocumentID, err)
+        }
+
+        report.Documents++
+        violations := invariants.Check(doc)
+        if len(violations) == 0 {
+            continue
+        }
+        repair := models.DocumentRepair{DocumentID: doc.ID, Violations: make([]models.MetadataViolation, len(violations))}
+        for i, violation := range violations {
+            repair.Violations[i] = models.MetadataViolation{Code: violation.Code, Detail: violation.Detail}
+        }
+        if err := r.repair(ctx, doc, repair.Violations, req); err != nil {
+            return nil, err
+        }
+        report.Violating++
+        if repair.Repaired() {
+            report.Repaired++
+        }
+        report.Repairs = append(report.Repairs, repair)
+    }
+    report.FinishedAt = time.Now()
+
+    r.logger.Info("Metadata repair finished",
+        zap.Bool("dry_run", report.DryRun),
+        zap.Int("documents", report.Documents),
+        zap.Int("violating", report.Violating),
+        zap.Int("repaired", report.Repaired),
+        zap.String("requested_by", report.RequestedBy))
+    return report, nil
+}
+
+// repair applies the fix of each violation of a document in order, as later
+// fixes read the content through the earlier ones, and persists the document
+// once. A fix that cannot be applied is recorded on its violation and leaves
+// the document as it was
+func (r *MetadataRepair) repair(ctx context.Context, doc *models.Document, violations []models.MetadataViolation, req RepairRequest) error {
+    performer := "METADATA_REPAIR"
+    if req.RequestedBy != "" {
+        performer += " " + req.RequestedBy
+    }
+    storagePath := doc.StoragePath
+    encryption := doc.EncryptionInfo
+    if encryption != nil && encryption.ValidateFormat() != nil {
+        encryption = nil
+    }
+
+    changed := false
+    for i := range violations {
+        violation := &violations[i]
+        var err error
+        switch violation.Code {
+        case invariants.MissingStoragePath:
+            violation.Fix = models.RepairRestoreStoragePath
+            var key string
+            if key, _, err = r.storage.LocateDocument(ctx, doc); err == nil {
+                storagePath = key
+                if !req.DryRun {
+                    doc.RepairStoragePath(key, performer)
+                }
+            }
+
+        case invariants.MissingEncryption, invariants.InvalidEncryption:
+            violation.Fix = models.RepairRebuildEncryption
+            var metadata *models.EncryptionMetadata
+            if metadata, err = r.rebuildEncryption(ctx, doc, storagePath); err == nil {
+                encryption = metadata
+                if !req.DryRun {
+                    doc.RepairEncryptionMetadata(metadata, performer)
+                }
+            }
+
+        case invariants.MissingContentHash:
+            violation.Fix = models.RepairRecomputeHash
+            var hash string
+            if hash, err = r.hash(ctx, doc, storagePath, encryption); err == nil && !req.DryRun {
+                doc.SetContentHash(hash, performer)
+            }
+
+        default:
+            // No fix is safe
+            metadataRepairs.WithLabelValues(violation.Code, "unfixable").Inc()
+            continue
+        }
+
+        switch {
+        case err != nil:
+            violation.Error = err.Error()
+            metadataRepairs.WithLabelValues(violation.Code, "failed").Inc()
+        case req.DryRun:
+            metadataRepairs.WithLabelValues(violation.Code, "planned").Inc()
+        default:
+            violation.Fixed = true
+            changed = true
+            metadataRepairs.WithLabelValues(violation.Code, "fixed").Inc()
+        }
+    }
+
+    if !changed {
+        return nil
+    }
+    if err := r.documents.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist repaired document %s: %w", doc.ID, err)
+    }
+    r.logger.Info("Document metadata repaired",
+        zap.String("document_id", doc.ID),
+        zap.String("requested_by", req.RequestedBy))
+    return nil
+}
+
+// rebuildEncryption rebuilds the encryption metadata of a document from the
+// headers of its stored object, provided the content authenticates under it
+func (r *MetadataRepair) rebuildEncryption(ctx context.Context, doc *models.Document, storagePath string) (*models.EncryptionMetadata, error) {
+    if storagePath == "" {
+        return nil, errUnknownLocation
+    }
+    info, err := r.storage.statObject(ctx, storagePath)
+    if err != nil {
+        return nil, err
+    }
+    metadata, err := models.EncryptionFromHeaders(info.Metadata, r.cfg.SecurityConfig.KeyRotationInterval)
+    if err != nil {
+        return nil, err
+    }
+
+    raw, err := r.storage.readObject(ctx, storagePath)
+    if err != nil {
+        return nil, err
+    }
+    plaintext, err := utils.OpenCiphertext(ctx, doc.ID, raw, doc.Size, metadata, r.cfg)
+    if err != nil {
+        return nil, fmt.Errorf("content does not authenticate under the object headers: %w", err)
+    }
+    utils.PutBuffer(plaintext)
+    return metadata, nil
+}
+
+// hash computes the digest of the decrypted content of a document
+func (r *MetadataRepair) hash(ctx context.Context, doc *models.Document, storagePath string, encryption *models.EncryptionMetadata) (string, error) {
+    if storagePath == "" {
+        return "", errUnknownLocation
+    }
+    if encryption == nil {
+        return "", errUnknownEncryption
+    }
+    plaintext, err := r.storage.recoverPlaintext(ctx, doc.ID, storagePath, doc.Size, encryption)
+    if err != nil {
+        return "", err
+    }
+    defer utils.PutBuffer(plaintext)
+    sum := sha256.Sum256(plaintext)
+    return hex.EncodeToString(sum[:]), nil
+}
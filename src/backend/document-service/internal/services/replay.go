@@ -0,0 +1,118 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "regexp"
+    "strconv"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+var (
+    ErrReplayHeadersMissing = errors.New("upload nonce and timestamp are required")
+    ErrInvalidReplayHeaders = errors.New("invalid upload nonce or timestamp")
+    ErrReplayWindowExpired  = errors.New("upload timestamp is outside the replay window")
+    ErrReplayedRequest      = errors.New("upload was already received")
+)
+
+// replayNoncePattern accepts nonces of 128 to 768 bits, hex or base64url
+// encoded
+var replayNoncePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,128}$`)
+
+// ReplayGuard rejects anonymous uploads replayed from captured traffic. Each
+// upload carries a fresh nonce and the time it was made; uploads outside the
+// window are refused as stale, and a nonce seen within the window as a replay
+type ReplayGuard struct {
+    window time.Duration
+    nonces repository.NonceRepository
+    logger *zap.Logger
+}
+
+// NewReplayGuard creates the guard, or nil when replay protection is disabled
+func NewReplayGuard(cfg *config.Config, nonces repository.NonceRepository, logger *zap.Logger) (*ReplayGuard, error) {
+    if cfg == nil || nonces == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.AbuseConfig.Replay.Enabled {
+        return nil, nil
+    }
+
+    return &ReplayGuard{
+        window: cfg.AbuseConfig.Replay.Window,
+        nonces: nonces,
+        logger: logger.With(zap.String("component", "replay_guard")),
+    }, nil
+}
+
+// Check accepts an upload's nonce and Unix timestamp once within the window
+func (g *ReplayGuard) Check(ctx context.Context, nonce, timestamp string) error {
+    err := g.check(ctx, nonce, timestamp)
+    result := "accepted"
+    switch {
+    case errors.Is(err, ErrReplayHeadersMissing):
+        result = "missing"
+    case errors.Is(err, ErrInvalidReplayHeaders):
+        result = "invalid"
+    case errors.Is(err, ErrReplayWindowExpired):
+        result = "expired"
+    case errors.Is(err, ErrReplayedRequest):
+        result = "replayed"
+        g.logger.Warn("Replayed upload rejected", zap.String("nonce", nonce))
+    case err != nil:
+        result = "error"
+    }
+    uploadReplayChecks.WithLabelValues(result).Inc()
+    return err
+}
+
+func (g *ReplayGuard) check(ctx context.Context, nonce, timestamp string) error {
+    if nonce == "" || timestamp == "" {
+        return ErrReplayHeadersMissing
+    }
+    sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+    if err != nil || !replayNoncePattern.MatchString(nonce) {
+        return ErrInvalidReplayHeaders
+    }
+    sent := time.Unix(sentAt, 0)
+    if skew := time.Since(sent); skew > g.window || skew < -g.window {
+        return ErrReplayWindowExpired
+    }
+
+    // Once the window has passed the timestamp itself is rejected, so the
+    // nonce is not kept any longer
+    err = g.nonces.Remember(ctx, nonce, sent.Add(g.window))
+    if errors.Is(err, repository.ErrNonceSeen) {
+        return ErrReplayedRequest
+    }
+    if err != nil {
+        return fmt.Errorf("failed to record upload nonce: %w", err)
+    }
+    return nil
+}
+
+// Run forgets expired nonces until the context is cancelled
+func (g *ReplayGuard) Run(ctx context.Context) {
+    if g == nil {
+        return
+    }
+
+    ticker := time.NewTicker(g.window)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if _, err := g.nonces.DeleteExpired(ctx, time.Now()); err != nil {
+                g.logger.Error("Failed to delete expired upload nonces", zap.Error(err))
+            }
+        }
+    }
+}
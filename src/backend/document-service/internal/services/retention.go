@@ -0,0 +1,421 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "slices"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Outbox topic notifying tenant admins of upcoming purges
+const (
+    TopicPurgeNotice = "retention.purge_notice"
+)
+
+var (
+    ErrDocumentNotInNotice = errors.New("document is not listed in the purge notice")
+    ErrDocumentPurged      = errors.New("document was already purged")
+    ErrNoRetentionHold     = errors.New("document has no retention hold")
+)
+
+// PurgeNoticeMessage is the summary sent to the admins of a tenant before
+// their documents are purged
+type PurgeNoticeMessage struct {
+    Type          string         `json:"type"`
+    NoticeID      string         `json:"notice_id"`
+    TenantID      string         `json:"tenant_id"`
+    Documents     int            `json:"documents"`
+    DocumentTypes map[string]int `json:"document_types"`
+    DocumentIDs   []string       `json:"document_ids"`
+    PurgeAfter    time.Time      `json:"purge_after"`
+    NotifiedAt    time.Time      `json:"notified_at"`
+}
+
+// RetentionHoldRequest places a hold on a document listed in a notice
+type RetentionHoldRequest struct {
+    DocumentID string `json:"document_id"`
+    Reason     string `json:"reason"`
+}
+
+// RetentionService purges documents past their retention date. The admins
+// of the tenant are notified first with a summary of what will be purged,
+// and documents are kept until the tenant's notice period has elapsed, so
+// admins can acknowledge the notice and place holds on documents they must
+// keep
+type RetentionService struct {
+    cfg         config.RetentionConfig
+    documents   repository.DocumentRepository
+    notices     repository.PurgeNoticeRepository
+    shredder    *CryptoShredder
+    storage     *StorageService
+    search      *SearchIndex
+    outbox      repository.OutboxRepository
+    maintenance *MaintenanceMode
+    httpClient  *http.Client
+    logger      *zap.Logger
+}
+
+// NewRetentionService creates a new retention purge service; sweeps pause
+// while the service is in maintenance mode
+func NewRetentionService(cfg *config.Config, documents repository.DocumentRepository, notices repository.PurgeNoticeRepository, shredder *CryptoShredder, storage *StorageService, outbox repository.OutboxRepository, maintenance *MaintenanceMode, logger *zap.Logger) (*RetentionService, error) {
+    if cfg == nil || documents == nil || notices == nil || shredder == nil || storage == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &RetentionService{
+        cfg:         cfg.RetentionConfig,
+        documents:   documents,
+        notices:     notices,
+        shredder:    shredder,
+        storage:     storage,
+        outbox:      outbox,
+        maintenance: maintenance,
+        httpClient: &http.Client{
+            Timeout:   cfg.RetentionConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+        logger: logger.With(zap.String("component", "retention")),
+    }, nil
+}
+
+// UseSearchIndex removes documents from the search index once their
+// extracted data is purged
+func (s *RetentionService) UseSearchIndex(search *SearchIndex) {
+    s.search = search
+}
+
+// Run sweeps on the configured interval until the context is cancelled
+func (s *RetentionService) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.cfg.Interval)
+    defer ticker.Stop()
+
+    for {
+        if err := s.Sweep(ctx); err != nil {
+            s.logger.Error("Retention sweep failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Sweep purges the documents whose notice period elapsed and notifies the
+// tenant admins of documents whose retention ends within their notice period
+func (s *RetentionService) Sweep(ctx context.Context) error {
+    if s.maintenance.Active() {
+        s.logger.Info("Retention sweep skipped during maintenance")
+        return nil
+    }
+
+    now := time.Now()
+    docs, err := s.documents.ListUpdatedBetween(ctx, time.Time{}, now.Add(time.Second))
+    if err != nil {
+        return fmt.Errorf("failed to list documents: %w", err)
+    }
+
+    purged, failed, trimmed := 0, 0, 0
+    due := make(map[string][]*models.Document)
+    for _, doc := range docs {
+        if err := CheckJobLease(ctx); err != nil {
+            return err
+        }
+        if doc.Purgeable(now) {
+            if err := s.shredder.Erase(ctx, doc); err != nil {
+                retentionPurges.WithLabelValues("failed").Inc()
+                s.logger.Error("Failed to purge document",
+                    zap.String("document_id", doc.ID),
+                    zap.Error(err),
+                )
+                failed++
+                continue
+            }
+            retentionPurges.WithLabelValues("purged").Inc()
+            purged++
+            continue
+        }
+
+        switch ok, err := s.purgeArtifacts(ctx, doc, now); {
+        case err != nil:
+            s.logger.Error("Failed to purge expired extracted data and renditions",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+        case ok:
+            trimmed++
+        }
+        if doc.ExpiryNoticeDue(now, s.cfg.NoticePeriodFor(doc.TenantID)) {
+            due[doc.TenantID] = append(due[doc.TenantID], doc)
+        }
+    }
+
+    notified := 0
+    for tenantID, tenantDocs := range due {
+        if err := CheckJobLease(ctx); err != nil {
+            return err
+        }
+        if err := s.notify(ctx, tenantID, tenantDocs, now); err != nil {
+            return err
+        }
+        notified += len(tenantDocs)
+    }
+
+    s.logger.Info("Retention sweep completed",
+        zap.Int("purged", purged),
+        zap.Int("failed", failed),
+        zap.Int("trimmed", trimmed),
+        zap.Int("notified", notified),
+        zap.Int("tenants_notified", len(due)),
+    )
+    return nil
+}
+
+// purgeArtifacts deletes the extracted data and renditions of a document
+// past their own retention, which is shorter than the document's, and
+// reports whether any were. No notice is given: the document itself is kept
+func (s *RetentionService) purgeArtifacts(ctx context.Context, doc *models.Document, now time.Time) (bool, error) {
+    if doc.EncryptionInfo.Shredded() {
+        return false, nil
+    }
+    renditions := doc.ExpiredRenditions(now, s.cfg.ExtractedDataRetention, s.cfg.RenditionRetention)
+    extractedData := doc.ExtractedDataExpired(now, s.cfg.ExtractedDataRetention)
+    if len(renditions) == 0 && !extractedData {
+        return false, nil
+    }
+
+    // A rendition whose object could not be deleted stays listed and is
+    // retried next sweep
+    deleted := make([]models.Rendition, 0, len(renditions))
+    var deleteErr error
+    for _, rendition := range renditions {
+        if err := s.storage.delete(ctx, rendition.StoragePath); err != nil && !errors.Is(err, ErrObjectNotFound) {
+            deleteErr = fmt.Errorf("failed to delete rendition %s: %w", rendition.Name, err)
+            continue
+        }
+        deleted = append(deleted, rendition)
+    }
+
+    // The index is built from the extracted text and goes with it
+    forget := extractedData
+    for _, rendition := range deleted {
+        forget = forget || models.IsExtractedDataRendition(rendition.Name)
+    }
+    if forget {
+        if err := s.search.Forget(ctx, doc); err != nil {
+            return false, err
+        }
+    }
+
+    doc.PurgeArtifacts(deleted, extractedData, now)
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return false, fmt.Errorf("failed to persist purged document: %w", err)
+    }
+    for _, rendition := range deleted {
+        if models.IsExtractedDataRendition(rendition.Name) {
+            retentionArtifactPurges.WithLabelValues("extracted_data").Inc()
+        } else {
+            retentionArtifactPurges.WithLabelValues("rendition").Inc()
+        }
+    }
+    if extractedData {
+        retentionArtifactPurges.WithLabelValues("extracted_data").Inc()
+    }
+
+    s.logger.Info("Expired extracted data and renditions purged",
+        zap.String("document_id", doc.ID),
+        zap.Int("renditions", len(deleted)),
+        zap.Bool("extracted_data", extractedData),
+    )
+    return true, deleteErr
+}
+
+// notify records one notice for the documents of a tenant and queues it for
+// the tenant admins. The notice is stored before the documents refer to it
+func (s *RetentionService) notify(ctx context.Context, tenantID string, docs []*models.Document, now time.Time) error {
+    period := s.cfg.NoticePeriodFor(tenantID)
+    notice := &models.PurgeNotice{
+        ID:            uuid.NewString(),
+        TenantID:      tenantID,
+        DocumentIDs:   make([]string, 0, len(docs)),
+        DocumentTypes: make(map[string]int),
+        NotifiedAt:    now,
+    }
+    for _, doc := range docs {
+        doc.NoticeExpiry(notice.ID, now, period)
+        notice.DocumentIDs = append(notice.DocumentIDs, doc.ID)
+        notice.DocumentTypes[doc.DocumentType]++
+        if notice.PurgeAfter.IsZero() || doc.Expiry.PurgeAfter.Before(notice.PurgeAfter) {
+            notice.PurgeAfter = doc.Expiry.PurgeAfter
+        }
+    }
+    if err := s.notices.Create(ctx, notice); err != nil {
+        return fmt.Errorf("failed to record purge notice: %w", err)
+    }
+
+    // A document not updated keeps no notice and is listed again next sweep
+    for _, doc := range docs {
+        if err := s.documents.Update(ctx, doc); err != nil {
+            s.logger.Error("Failed to record expiry notice on document",
+                zap.String("document_id", doc.ID),
+                zap.String("notice_id", notice.ID),
+                zap.Error(err),
+            )
+        }
+    }
+
+    msg, err := newOutboxMessage(TopicPurgeNotice, notice.ID, PurgeNoticeMessage{
+        Type:          TopicPurgeNotice,
+        NoticeID:      notice.ID,
+        TenantID:      tenantID,
+        Documents:     len(notice.DocumentIDs),
+        DocumentTypes: notice.DocumentTypes,
+        DocumentIDs:   notice.DocumentIDs,
+        PurgeAfter:    notice.PurgeAfter.UTC(),
+        NotifiedAt:    now.UTC(),
+    })
+    if err == nil {
+        err = s.outbox.Enqueue(ctx, msg)
+    }
+    if err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        return fmt.Errorf("failed to queue purge notice: %w", err)
+    }
+
+    retentionNotices.Inc()
+    s.logger.Info("Tenant admins notified of upcoming purge",
+        zap.String("notice_id", notice.ID),
+        zap.String("tenant_id", tenantID),
+        zap.Int("documents", len(notice.DocumentIDs)),
+        zap.Time("purge_after", notice.PurgeAfter),
+    )
+    return nil
+}
+
+// MayManage reports whether users with the role may acknowledge notices and
+// manage holds for their tenant
+func (s *RetentionService) MayManage(role string) bool {
+    return slices.Contains(s.cfg.AdminRoles, role)
+}
+
+// ListNotices returns the notices of a tenant, most recent first
+func (s *RetentionService) ListNotices(ctx context.Context, tenantID string) ([]*models.PurgeNotice, error) {
+    return s.notices.ListByTenant(ctx, tenantID)
+}
+
+// GetNotice returns a notice of the tenant; notices of other tenants are
+// reported as not found
+func (s *RetentionService) GetNotice(ctx context.Context, tenantID, id string) (*models.PurgeNotice, error) {
+    notice, err := s.notices.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if notice.TenantID != tenantID {
+        return nil, repository.ErrNoticeNotFound
+    }
+    return notice, nil
+}
+
+// Acknowledge records that a tenant admin reviewed a notice and places the
+// requested holds, which keep their documents until released. Documents
+// already purged cannot be held
+func (s *RetentionService) Acknowledge(ctx context.Context, tenantID, noticeID, userID string, holds []RetentionHoldRequest) (*models.PurgeNotice, error) {
+    notice, err := s.GetNotice(ctx, tenantID, noticeID)
+    if err != nil {
+        return nil, err
+    }
+    for _, hold := range holds {
+        if !notice.Includes(hold.DocumentID) {
+            return nil, fmt.Errorf("%w: %s", ErrDocumentNotInNotice, hold.DocumentID)
+        }
+        if hold.Reason == "" {
+            return nil, fmt.Errorf("%w: hold reason", models.ErrMissingField)
+        }
+    }
+
+    now := time.Now()
+    held := make([]string, 0, len(holds))
+    var holdErr error
+    for _, hold := range holds {
+        if holdErr = s.placeHold(ctx, notice.ID, hold, userID, now); holdErr != nil {
+            break
+        }
+        held = append(held, hold.DocumentID)
+    }
+
+    // Holds placed before a failure are recorded on the notice too
+    notice.Acknowledge(userID, held, now)
+    if err := s.notices.Update(ctx, notice); err != nil {
+        return nil, fmt.Errorf("failed to record acknowledgment: %w", err)
+    }
+    if holdErr != nil {
+        return nil, holdErr
+    }
+
+    s.logger.Info("Purge notice acknowledged",
+        zap.String("notice_id", notice.ID),
+        zap.String("tenant_id", tenantID),
+        zap.String("user_id", userID),
+        zap.Int("holds", len(held)),
+    )
+    return notice, nil
+}
+
+func (s *RetentionService) placeHold(ctx context.Context, noticeID string, hold RetentionHoldRequest, userID string, at time.Time) error {
+    doc, err := s.documents.GetByID(ctx, hold.DocumentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return fmt.Errorf("%w: %s", ErrDocumentPurged, hold.DocumentID)
+    }
+    if err != nil {
+        return err
+    }
+    if err := doc.PlaceHold(hold.Reason, userID, noticeID, at); err != nil {
+        return err
+    }
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist retention hold: %w", err)
+    }
+    return nil
+}
+
+// ReleaseHold lets a held document of the tenant be purged; its admins are
+// notified again before it is
+func (s *RetentionService) ReleaseHold(ctx context.Context, tenantID, documentID, userID string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+    if doc.TenantID != tenantID {
+        return nil, repository.ErrDocumentNotFound
+    }
+    if doc.Hold == nil {
+        return nil, ErrNoRetentionHold
+    }
+
+    doc.ReleaseHold(userID, time.Now())
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return nil, fmt.Errorf("failed to persist hold release: %w", err)
+    }
+
+    s.logger.Info("Retention hold released",
+        zap.String("document_id", doc.ID),
+        zap.String("tenant_id", tenantID),
+        zap.String("user_id", userID),
+    )
+    return doc, nil
+}
+
+// Deliver is the outbox handler posting purge notices to the notification
+// service, which forwards them to the tenant admins
+func (s *RetentionService) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    return postOutboxMessage(ctx, s.httpClient, s.cfg.NotificationURL, msg)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+    "context"
+    "errors"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// ReviewService applies reviewer decisions to processed documents
+type ReviewService struct {
+    documents    repository.DocumentRepository
+    storage      *StorageService
+    underwriting *UnderwritingService
+    maxPageSize  int64
+    version      string
+    eta          *ETAEstimator
+    webhooks     *TenantWebhooks
+    sla          *SLATracker
+    logger       *zap.Logger
+}
+
+// NewReviewService creates a new review service
+func NewReviewService(cfg *config.Config, documents repository.DocumentRepository, storage *StorageService, underwriting *UnderwritingService, logger *zap.Logger) (*ReviewService, error) {
+    if cfg == nil || documents == nil || storage == nil || underwriting == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ReviewService{
+        documents:    documents,
+        storage:      storage,
+        underwriting: underwriting,
+        maxPageSize:  cfg.ServiceConfig.MaxFileSize,
+        version:      PipelineVersion(cfg),
+        logger:       logger,
+    }, nil
+}
+
+// UseETA reports reviewer decisions to the processing estimates; it must be
+// called before the service starts serving requests
+func (s *ReviewService) UseETA(eta *ETAEstimator) {
+    s.eta = eta
+}
+
+// UseWebhooks announces reviewer decisions to the webhooks of the document's
+// tenant; it must be called before the service starts serving requests
+func (s *ReviewService) UseWebhooks(webhooks *TenantWebhooks) {
+    s.webhooks = webhooks
+}
+
+// UseSLA counts reviewer decisions against the SLA; it must be called
+// before the service starts serving requests
+func (s *ReviewService) UseSLA(sla *SLATracker) {
+    s.sla = sla
+}
+
+// GetDocument returns a document with the extracted fields and signature
+// verification results reviewers need to reach a decision
+func (s *ReviewService) GetDocument(ctx context.Context, documentID string) (*models.Document, error) {
+    return s.documents.GetByID(ctx, documentID)
+}
+
+// Review records the decision and, on approval, checks whether the enrollment
+// is ready to be handed over to underwriting
+func (s *ReviewService) Review(ctx context.Context, documentID, decision, reason, reviewer string) (*models.Document, error) {
+    doc, err := s.documents.GetByID(ctx, documentID)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := doc.Review(decision, reason, reviewer); err != nil {
+        return nil, err
+    }
+
+    if err := s.save(ctx, doc); err != nil {
+        return nil, err
+    }
+
+    s.reviewed(ctx, doc)
+    return doc, nil
+}
+
+// save stores a reviewed document. An approval completing its enrollment
+// is saved together with the notification to underwriting
+func (s *ReviewService) save(ctx context.Context, doc *models.Document) error {
+    return s.underwriting.SaveReview(ctx, doc, func(ctx context.Context) error {
+        return s.documents.Update(ctx, doc)
+    })
+}
+
+// reviewed reports a saved decision to the processing estimates, the SLA
+// and the tenant's webhooks
+func (s *ReviewService) reviewed(ctx context.Context, doc *models.Document) {
+    s.eta.Reviewed(doc)
+    s.sla.Reviewed(doc)
+    if err := s.webhooks.Publish(ctx, models.WebhookEventDocumentReviewed, doc); err != nil {
+        s.logger.Warn("Failed to publish review to webhooks",
+            zap.String("document_id", doc.ID),
+            zap.Error(err),
+        )
+    }
+}
+
+// Checklist reports the required documents of an enrollment
+func (s *ReviewService) Checklist(ctx context.Context, enrollmentID string) (*models.EnrollmentChecklist, error) {
+    return s.underwriting.Checklist(ctx, enrollmentID)
+}
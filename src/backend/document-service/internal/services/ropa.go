@@ -0,0 +1,225 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Processing operations performed outside the pipeline steps
+const (
+    OperationScreening    = "screening"
+    OperationAutoDecision = "auto_decision"
+    OperationFaceMatch    = "face_match"
+)
+
+// Purpose recorded for operations missing from the catalog, so gaps show up in
+// the report instead of being dropped
+const PurposeUnclassified = "unclassified"
+
+var (
+    ErrInvalidReportPeriod = errors.New("invalid report period")
+)
+
+// defaultProcessingActivities is the catalog of processing operations with
+// their purpose and LGPD legal basis. Codes read LGPD-<article>-<item>; the
+// sensitive basis applies to health data (art. 11) and is overridable through
+// ROPAConfig.Activities
+var defaultProcessingActivities = []config.ProcessingActivityConfig{
+    {Operation: StepOCR, Category: models.ProcessingCategoryOCR, Purpose: "enrollment_data_extraction", LegalBasis: "LGPD-7-V", SensitiveLegalBasis: "LGPD-11-II-D"},
+    {Operation: StepOCRCanary, Category: models.ProcessingCategoryOCR, Purpose: "ocr_provider_evaluation", LegalBasis: "LGPD-7-IX", SensitiveLegalBasis: "LGPD-11-II-D"},
+    {Operation: StepTISS, Category: models.ProcessingCategoryClassification, Purpose: "medical_guide_classification", LegalBasis: "LGPD-7-V", SensitiveLegalBasis: "LGPD-11-II-D"},
+    {Operation: StepHolderName, Category: models.ProcessingCategoryIdentityVerification, Purpose: "holder_identification", LegalBasis: "LGPD-7-V"},
+    {Operation: StepSignature, Category: models.ProcessingCategorySignatureVerification, Purpose: "signature_validation", LegalBasis: "LGPD-7-II"},
+    {Operation: StepGovBR, Category: models.ProcessingCategoryIdentityVerification, Purpose: "identity_fraud_prevention", LegalBasis: "LGPD-7-IX", SensitiveLegalBasis: "LGPD-11-II-G"},
+    {Operation: StepReceita, Category: models.ProcessingCategoryIdentityVerification, Purpose: "registration_data_validation", LegalBasis: "LGPD-7-II"},
+    {Operation: StepAddress, Category: models.ProcessingCategoryAddressValidation, Purpose: "address_normalization", LegalBasis: "LGPD-7-V"},
+    {Operation: OperationScreening, Category: models.ProcessingCategoryScreening, Purpose: "sanctions_pep_screening", LegalBasis: "LGPD-7-II"},
+    {Operation: OperationAutoDecision, Category: models.ProcessingCategoryAutomatedDecision, Purpose: "enrollment_decision", LegalBasis: "LGPD-7-V", SensitiveLegalBasis: "LGPD-11-II-D"},
+    {Operation: OperationFaceMatch, Category: models.ProcessingCategoryFaceMatch, Purpose: "biometric_identity_verification", LegalBasis: "LGPD-11-II-G"},
+}
+
+// ProcessingCatalog resolves the purpose and legal basis of processing
+// operations and records them on documents
+type ProcessingCatalog struct {
+    activities map[string]config.ProcessingActivityConfig
+    sensitive  map[string]bool
+}
+
+// NewProcessingCatalog merges the configured activities over the defaults
+func NewProcessingCatalog(cfg *config.Config) *ProcessingCatalog {
+    catalog := &ProcessingCatalog{
+        activities: make(map[string]config.ProcessingActivityConfig),
+        sensitive:  make(map[string]bool),
+    }
+    for _, activity := range defaultProcessingActivities {
+        catalog.activities[activity.Operation] = activity
+    }
+    if cfg == nil {
+        return catalog
+    }
+    for _, activity := range cfg.ROPAConfig.Activities {
+        if activity.Category == "" {
+            activity.Category = catalog.activities[activity.Operation].Category
+        }
+        catalog.activities[activity.Operation] = activity
+    }
+    for _, docType := range cfg.ROPAConfig.SensitiveDocumentTypes {
+        catalog.sensitive[docType] = true
+    }
+    return catalog
+}
+
+// Activity returns the processing activity an operation on the document
+// represents; err is the operation's failure, if any
+func (c *ProcessingCatalog) Activity(doc *models.Document, operation string, err error) models.ProcessingActivity {
+    activity := models.ProcessingActivity{
+        Operation: operation,
+        Category:  operation,
+        Purpose:   PurposeUnclassified,
+        Outcome:   models.ProcessingOutcomeSucceeded,
+    }
+    if err != nil {
+        activity.Outcome = models.ProcessingOutcomeFailed
+    }
+
+    entry, ok := c.activities[operation]
+    if !ok {
+        return activity
+    }
+    activity.Category = entry.Category
+    activity.Purpose = entry.Purpose
+    activity.LegalBasis = entry.LegalBasis
+    if c.sensitive[doc.DocumentType] && entry.SensitiveLegalBasis != "" {
+        activity.LegalBasis = entry.SensitiveLegalBasis
+    }
+    return activity
+}
+
+// Record appends the activity of an operation to the document. A nil catalog
+// records nothing
+func (c *ProcessingCatalog) Record(doc *models.Document, operation string, err error) {
+    if c == nil {
+        return
+    }
+    doc.RecordProcessing(c.Activity(doc, operation, err))
+}
+
+// ROPAService builds the records of processing activities report from the
+// activities recorded on documents. Documents already purged by retention are
+// no longer held and drop out of the report
+type ROPAService struct {
+    cfg       config.ROPAConfig
+    documents repository.DocumentRepository
+}
+
+// NewROPAService creates a new report service
+func NewROPAService(cfg *config.Config, documents repository.DocumentRepository) (*ROPAService, error) {
+    if cfg == nil || documents == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ROPAService{
+        cfg:       cfg.ROPAConfig,
+        documents: documents,
+    }, nil
+}
+
+// ropaKey groups activities into report entries
+type ropaKey struct {
+    category     string
+    operation    string
+    purpose      string
+    legalBasis   string
+    documentType string
+}
+
+// Report aggregates the activities performed in [from, to)
+func (s *ROPAService) Report(ctx context.Context, from, to time.Time) (*models.ROPAReport, error) {
+    if from.IsZero() || !from.Before(to) {
+        return nil, ErrInvalidReportPeriod
+    }
+
+    // Recording an activity updates the document, so every document processed
+    // in the period was last updated at or after its start
+    now := time.Now()
+    docs, err := s.documents.ListUpdatedBetween(ctx, from, now.Add(time.Second))
+    if err != nil {
+        return nil, fmt.Errorf("failed to list documents: %w", err)
+    }
+
+    entries := make(map[ropaKey]*models.ROPAEntry)
+    seen := make(map[ropaKey]map[string]bool)
+    for _, doc := range docs {
+        for _, activity := range doc.ProcessingActivities {
+            if activity.PerformedAt.Before(from) || !activity.PerformedAt.Before(to) {
+                continue
+            }
+
+            key := ropaKey{activity.Category, activity.Operation, activity.Purpose, activity.LegalBasis, doc.DocumentType}
+            entry, ok := entries[key]
+            if !ok {
+                entry = &models.ROPAEntry{
+                    Category:         activity.Category,
+                    Operation:        activity.Operation,
+                    Purpose:          activity.Purpose,
+                    LegalBasis:       activity.LegalBasis,
+                    DocumentType:     doc.DocumentType,
+                    FirstPerformedAt: activity.PerformedAt,
+                    LastPerformedAt:  activity.PerformedAt,
+                }
+                entries[key] = entry
+                seen[key] = make(map[string]bool)
+            }
+
+            entry.Operations++
+            if activity.Outcome == models.ProcessingOutcomeFailed {
+                entry.Failed++
+            }
+            if !seen[key][doc.ID] {
+                seen[key][doc.ID] = true
+                entry.Documents++
+            }
+            if activity.PerformedAt.Before(entry.FirstPerformedAt) {
+                entry.FirstPerformedAt = activity.PerformedAt
+            }
+            if activity.PerformedAt.After(entry.LastPerformedAt) {
+                entry.LastPerformedAt = activity.PerformedAt
+            }
+        }
+    }
+
+    report := &models.ROPAReport{
+        Controller:  s.cfg.Controller,
+        From:        from,
+        To:          to,
+        GeneratedAt: now,
+        Activities:  make([]models.ROPAEntry, 0, len(entries)),
+    }
+    for _, entry := range entries {
+        report.Activities = append(report.Activities, *entry)
+    }
+    sort.Slice(report.Activities, func(i, j int) bool {
+        a, b := report.Activities[i], report.Activities[j]
+        if a.Category != b.Category {
+            return a.Category < b.Category
+        }
+        if a.Operation != b.Operation {
+            return a.Operation < b.Operation
+        }
+        if a.DocumentType != b.DocumentType {
+            return a.DocumentType < b.DocumentType
+        }
+        if a.Purpose != b.Purpose {
+            return a.Purpose < b.Purpose
+        }
+        return a.LegalBasis < b.LegalBasis
+    })
+    return report, nil
+}
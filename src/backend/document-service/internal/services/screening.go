@@ -0,0 +1,332 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+    "unicode"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    StepHolderName = "holder_name"
+
+    FieldHolderName = "holder_name"
+
+    TopicScreeningRequested = "screening.identity_document"
+)
+
+// ScreeningRequest is the outbox payload asking for a document's names to be screened
+type ScreeningRequest struct {
+    DocumentID    string   `json:"document_id"`
+    ProcessingRun int      `json:"processing_run,omitempty"`
+    Names         []string `json:"names"`
+}
+
+// ScreeningProvider screens a name against PEP and sanctions lists
+type ScreeningProvider interface {
+    Name() string
+    Screen(ctx context.Context, name string, lists []string) ([]models.ScreeningMatch, error)
+}
+
+// RESTScreeningProvider screens names through a compliance vendor REST API
+type RESTScreeningProvider struct {
+    baseURL    string
+    apiKey     string
+    httpClient *http.Client
+}
+
+// NewRESTScreeningProvider creates a new REST screening provider
+func NewRESTScreeningProvider(cfg *config.Config) (*RESTScreeningProvider, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    return &RESTScreeningProvider{
+        baseURL:    cfg.ScreeningConfig.BaseURL,
+        apiKey:     cfg.ScreeningConfig.APIKey,
+        httpClient: &http.Client{Timeout: cfg.ScreeningConfig.Timeout},
+    }, nil
+}
+
+// Name returns the provider name recorded on screening results
+func (p *RESTScreeningProvider) Name() string {
+    return "rest"
+}
+
+// Screen returns every list entry the vendor considers a candidate match
+func (p *RESTScreeningProvider) Screen(ctx context.Context, name string, lists []string) ([]models.ScreeningMatch, error) {
+    body, err := json.Marshal(map[string]interface{}{
+        "name":  name,
+        "lists": lists,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode screening request: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/screenings", bytes.NewReader(body))
+    if err != nil {
+        return nil, fmt.Errorf("failed to build screening request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+    resp, err := p.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("screening request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("screening provider returned status %d", resp.StatusCode)
+    }
+
+    var result struct {
+        Matches []models.ScreeningMatch `json:"matches"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("failed to decode screening response: %w", err)
+    }
+    return result.Matches, nil
+}
+
+// ScreeningService screens holder names of identity documents after ingestion.
+// Screening runs from the outbox so a slow or unavailable provider never
+// delays or fails an upload
+type ScreeningService struct {
+    cfg        config.ScreeningConfig
+    documents  repository.DocumentRepository
+    outbox     repository.OutboxRepository
+    provider   ScreeningProvider
+    processing *ProcessingCatalog
+    scoreboard *ProviderScoreboard
+    hooks      []IngestHook
+    logger     *zap.Logger
+}
+
+// NewScreeningService creates a new screening service
+func NewScreeningService(cfg *config.Config, documents repository.DocumentRepository, outbox repository.OutboxRepository, provider ScreeningProvider, logger *zap.Logger) (*ScreeningService, error) {
+    if cfg == nil || documents == nil || outbox == nil || provider == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ScreeningService{
+        cfg:        cfg.ScreeningConfig,
+        documents:  documents,
+        outbox:     outbox,
+        provider:   provider,
+        processing: NewProcessingCatalog(cfg),
+        logger:     logger,
+    }, nil
+}
+
+// OnScreened registers a hook run after screening results have been stored
+func (s *ScreeningService) OnScreened(hook IngestHook) {
+    s.hooks = append(s.hooks, hook)
+}
+
+// UseScoreboard scores the calls to the screening provider on the provider
+// scoreboard; it must be called before serving requests
+func (s *ScreeningService) UseScoreboard(scoreboard *ProviderScoreboard) {
+    s.scoreboard = scoreboard
+    scoreboard.Register(ProviderKindScreening, s.provider.Name())
+}
+
+// OnIngested is a pipeline hook queueing identity documents with extracted
+// names for screening. Requests are keyed on the processing run, so a
+// reprocessed document is screened again on the names it now holds while a
+// hook notified twice of the same run queues one request
+func (s *ScreeningService) OnIngested(ctx context.Context, doc *models.Document) error {
+    if doc.DocumentType != "identity" {
+        return nil
+    }
+
+    names := doc.ExtractedValues(FieldHolderName)
+    for _, signature := range doc.Signatures {
+        if signature.Valid && signature.SignerName != "" {
+            names = appendUnique(names, signature.SignerName)
+        }
+    }
+    if len(names) == 0 {
+        return nil
+    }
+
+    key := fmt.Sprintf("%s/run-%d", doc.ID, doc.ProcessingRun)
+    msg, err := newOutboxMessage(TopicScreeningRequested, key, ScreeningRequest{
+        DocumentID:    doc.ID,
+        ProcessingRun: doc.ProcessingRun,
+        Names:         names,
+    })
+    if err != nil {
+        return err
+    }
+    if err := s.outbox.Enqueue(ctx, msg); err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        return fmt.Errorf("failed to enqueue screening request: %w", err)
+    }
+    return nil
+}
+
+// Deliver is the outbox handler screening the queued names and storing the results
+func (s *ScreeningService) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    var req ScreeningRequest
+    if err := json.Unmarshal(msg.Payload, &req); err != nil {
+        return fmt.Errorf("invalid screening request payload: %w", err)
+    }
+
+    results := make([]models.ScreeningResult, 0, len(req.Names))
+    for _, name := range req.Names {
+        start := time.Now()
+        matches, err := s.provider.Screen(ctx, name, s.cfg.Lists)
+        s.scoreboard.Record(ProviderKindScreening, s.provider.Name(), time.Since(start), err)
+        if err != nil {
+            return err
+        }
+
+        result := models.ScreeningResult{
+            Name:       name,
+            Provider:   s.provider.Name(),
+            ScreenedAt: time.Now(),
+        }
+        for _, match := range matches {
+            if match.Score >= s.cfg.MinScore {
+                result.Matches = append(result.Matches, match)
+            }
+        }
+        result.Hit = len(result.Matches) > 0
+        results = append(results, result)
+    }
+
+    doc, err := s.documents.GetByID(ctx, req.DocumentID)
+    if err != nil {
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            // Deleted before screening completed; nothing left to flag
+            return nil
+        }
+        return err
+    }
+    if req.ProcessingRun < doc.ProcessingRun {
+        // Reprocessed since it was queued; the request of the later run
+        // screens the names the document holds now
+        return nil
+    }
+
+    s.processing.Record(doc, OperationScreening, nil)
+    doc.SetScreeningResults(results)
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to store screening results: %w", err)
+    }
+
+    outcome := "no_hit"
+    if doc.HasReviewFlag(models.ReviewFlagScreeningHit) {
+        outcome = "hit"
+        s.logger.Warn("Screening hit on identity document",
+            zap.String("document_id", doc.ID),
+            zap.String("enrollment_id", doc.EnrollmentID),
+        )
+    }
+    screeningResults.WithLabelValues(outcome).Inc()
+
+    for _, hook := range s.hooks {
+        if err := hook(ctx, doc); err != nil {
+            s.logger.Warn("Screening hook failed",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+        }
+    }
+    return nil
+}
+
+// HolderNameStep extracts the holder name printed on identity documents
+type HolderNameStep struct{}
+
+// NewHolderNameStep creates a new holder name extraction step
+func NewHolderNameStep() *HolderNameStep {
+    return &HolderNameStep{}
+}
+
+// Name returns the step name
+func (s *HolderNameStep) Name() string {
+    return StepHolderName
+}
+
+// Provenance reports that the name is read from the OCR text
+func (s *HolderNameStep) Provenance() StepProvenance {
+    return StepProvenance{Version: holderNameStepVersion, Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether the document is an identity document
+func (s *HolderNameStep) Applies(doc *models.Document) bool {
+    return doc.DocumentType == "identity"
+}
+
+// Execute stores the holder name found in the OCR text, if any
+func (s *HolderNameStep) Execute(ctx context.Context, run *PipelineRun) error {
+    name := ExtractHolderName(run.OCRText)
+    if name == "" {
+        return nil
+    }
+
+    run.Document.SetExtractedFields(StepHolderName, []models.ExtractedField{
+        {Name: FieldHolderName, Value: name, Confidence: 0.8},
+    })
+    return nil
+}
+
+// ExtractHolderName finds the value of the "NOME" label used by RG and CNH
+// layouts, either on the label line or on the line below it
+func ExtractHolderName(text string) string {
+    lines := strings.Split(text, "\n")
+    for i, line := range lines {
+        label := strings.ToUpper(strings.TrimSpace(line))
+        if !strings.HasPrefix(label, "NOME") {
+            continue
+        }
+
+        rest := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line)[len("NOME"):], " :"))
+        // Skip labels such as "NOME DO PAI" or "NOME SOCIAL"
+        if rest != "" && !strings.HasPrefix(label, "NOME:") && !strings.HasPrefix(label, "NOME ") {
+            continue
+        }
+        if strings.HasPrefix(label, "NOME DO") || strings.HasPrefix(label, "NOME DA") || strings.HasPrefix(label, "NOME SOCIAL") {
+            continue
+        }
+
+        if rest == "" {
+            for _, next := range lines[i+1:] {
+                if next = strings.TrimSpace(next); next != "" {
+                    rest = next
+                    break
+                }
+            }
+        }
+        if isPersonName(rest) {
+            return strings.Join(strings.Fields(rest), " ")
+        }
+    }
+    return ""
+}
+
+// isPersonName accepts at least two words made only of letters
+func isPersonName(value string) bool {
+    words := strings.Fields(value)
+    if len(words) < 2 {
+        return false
+    }
+    for _, r := range value {
+        if !unicode.IsLetter(r) && !unicode.IsSpace(r) && r != '\'' {
+            return false
+        }
+    }
+    return true
+}
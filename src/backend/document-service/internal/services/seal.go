@@ -0,0 +1,345 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/ecdsa"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/asn1"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "encoding/pem"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Underwriting event types published by the underwriting service
+const (
+    UnderwritingEventCompleted = "underwriting.completed"
+)
+
+const (
+    // maxTimestampResponseBytes bounds the time-stamp authority response read
+    maxTimestampResponseBytes = 1 << 20
+    // tsaStatusGrantedWithMods is the highest RFC 3161 status that grants a
+    // token
+    tsaStatusGrantedWithMods = 1
+)
+
+var (
+    ErrUnknownUnderwritingEvent = errors.New("unknown underwriting event type")
+    ErrInvalidUnderwritingEvent = errors.New("underwriting event is missing its id or enrollment")
+    ErrTimestampFailed          = errors.New("time-stamp authority did not grant a token")
+)
+
+// oidSHA256 identifies SHA-256 in the RFC 3161 message imprint
+var oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+// UnderwritingEvent is an underwriting lifecycle change published by the
+// underwriting service
+type UnderwritingEvent struct {
+    EventID      string    `json:"event_id"`
+    Type         string    `json:"type"`
+    EnrollmentID string    `json:"enrollment_id"`
+    Decision     string    `json:"decision"`
+    OccurredAt   time.Time `json:"occurred_at"`
+}
+
+// timeStampReq is the RFC 3161 TimeStampReq, without the optional policy,
+// nonce and extensions
+type timeStampReq struct {
+    Version        int
+    MessageImprint messageImprint
+    CertReq        bool `asn1:"optional,default:false"`
+}
+
+type messageImprint struct {
+    HashAlgorithm pkix.AlgorithmIdentifier
+    HashedMessage []byte
+}
+
+// timeStampResp is the RFC 3161 TimeStampResp; the token is kept as is for
+// auditors to verify with their own tools
+type timeStampResp struct {
+    Status         pkiStatusInfo
+    TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+    Status int
+}
+
+// SealService seals the document set of an enrollment when underwriting
+// closes: the documents and their content hashes are committed to under a
+// Merkle root, and the seal is signed with ECDSA P-256 and optionally
+// timestamped by an RFC 3161 authority. Verifying a seal later recomputes the
+// root from the enrollment's current documents, so a document altered, added
+// or removed after the seal is detected. A nil *SealService seals nothing
+type SealService struct {
+    cfg        config.SealConfig
+    key        *ecdsa.PrivateKey
+    keyID      string
+    documents  repository.DocumentRepository
+    seals      repository.SealRepository
+    httpClient *http.Client
+    logger     *zap.Logger
+}
+
+// NewSealService creates the seal service, or nil when seals are disabled
+func NewSealService(cfg *config.Config, documents repository.DocumentRepository, seals repository.SealRepository, logger *zap.Logger) (*SealService, error) {
+    if cfg == nil || documents == nil || seals == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SealConfig.Enabled {
+        return nil, nil
+    }
+
+    key, err := parseSigningKey(cfg.SealConfig.SigningKey, "seal")
+    if err != nil {
+        return nil, err
+    }
+    publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode seal public key: %w", err)
+    }
+    keyID := sha256.Sum256(publicDER)
+
+    return &SealService{
+        cfg:        cfg.SealConfig,
+        key:        key,
+        keyID:      hex.EncodeToString(keyID[:16]),
+        documents:  documents,
+        seals:      seals,
+        httpClient: &http.Client{Timeout: cfg.SealConfig.Timeout},
+        logger:     logger.With(zap.String("component", "enrollment_seal")),
+    }, nil
+}
+
+// Handle seals the enrollment closed by an underwriting event. A redelivered
+// event returns the seal already made for it
+func (s *SealService) Handle(ctx context.Context, event UnderwritingEvent) (*models.EnrollmentSeal, error) {
+    if event.Type != UnderwritingEventCompleted {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownUnderwritingEvent, event.Type)
+    }
+    if event.EventID == "" || event.EnrollmentID == "" {
+        return nil, ErrInvalidUnderwritingEvent
+    }
+
+    seal, err := s.seals.GetByEvent(ctx, event.EventID)
+    if err == nil {
+        return seal, nil
+    }
+    if !errors.Is(err, repository.ErrSealNotFound) {
+        return nil, fmt.Errorf("failed to load enrollment seal: %w", err)
+    }
+
+    seal, err = s.seal(ctx, event)
+    if err != nil {
+        enrollmentSeals.WithLabelValues("failed").Inc()
+        return nil, err
+    }
+    if err := s.seals.Create(ctx, seal); err != nil {
+        if errors.Is(err, repository.ErrSealExists) {
+            return s.seals.GetByEvent(ctx, event.EventID)
+        }
+        enrollmentSeals.WithLabelValues("failed").Inc()
+        return nil, fmt.Errorf("failed to store enrollment seal: %w", err)
+    }
+
+    enrollmentSeals.WithLabelValues("sealed").Inc()
+    s.logger.Info("Enrollment sealed",
+        zap.String("seal_id", seal.ID),
+        zap.String("enrollment_id", seal.EnrollmentID),
+        zap.String("event_id", seal.EventID),
+        zap.Int("documents", len(seal.Documents)),
+        zap.String("merkle_root", seal.MerkleRoot),
+        zap.Bool("timestamped", seal.TimestampToken != ""),
+    )
+    return seal, nil
+}
+
+// Get returns the most recent seal of an enrollment
+func (s *SealService) Get(ctx context.Context, enrollmentID string) (*models.EnrollmentSeal, error) {
+    return s.seals.Latest(ctx, enrollmentID)
+}
+
+// Verify checks the signature and root of the most recent seal of an
+// enrollment and compares it with the enrollment's current documents
+func (s *SealService) Verify(ctx context.Context, enrollmentID string) (*models.SealVerification, error) {
+    seal, err := s.seals.Latest(ctx, enrollmentID)
+    if err != nil {
+        return nil, err
+    }
+    docs, err := s.documents.ListByEnrollment(ctx, enrollmentID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list enrollment documents: %w", err)
+    }
+
+    verification := &models.SealVerification{
+        SignatureValid: s.verifySignature(seal) == nil,
+        RootValid:      seal.Algorithm == models.SealAlgorithm && models.MerkleRoot(seal.Documents) == seal.MerkleRoot,
+        Timestamped:    seal.TimestampToken != "",
+        Seal:           seal,
+        VerifiedAt:     time.Now(),
+    }
+    verification.Missing, verification.Added, verification.Changed = seal.Compare(models.NewSealedDocuments(docs))
+    verification.Valid = verification.SignatureValid && verification.RootValid &&
+        len(verification.Missing) == 0 && len(verification.Added) == 0 && len(verification.Changed) == 0
+
+    result := "valid"
+    if !verification.Valid {
+        result = "tampered"
+        s.logger.Warn("Enrollment seal verification failed",
+            zap.String("seal_id", seal.ID),
+            zap.String("enrollment_id", enrollmentID),
+            zap.Bool("signature_valid", verification.SignatureValid),
+            zap.Bool("root_valid", verification.RootValid),
+            zap.Strings("missing", verification.Missing),
+            zap.Strings("added", verification.Added),
+            zap.Strings("changed", verification.Changed),
+        )
+    }
+    sealVerifications.WithLabelValues(result).Inc()
+    return verification, nil
+}
+
+// PublicKey returns the PEM encoded key seals are verified with and its ID
+func (s *SealService) PublicKey() (string, string, error) {
+    der, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+    if err != nil {
+        return "", "", fmt.Errorf("failed to encode seal public key: %w", err)
+    }
+    return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), s.keyID, nil
+}
+
+// seal builds, signs and timestamps the seal of the enrollment's documents
+func (s *SealService) seal(ctx context.Context, event UnderwritingEvent) (*models.EnrollmentSeal, error) {
+    docs, err := s.documents.ListByEnrollment(ctx, event.EnrollmentID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list enrollment documents: %w", err)
+    }
+
+    sealed := models.NewSealedDocuments(docs)
+    seal := &models.EnrollmentSeal{
+        ID:           uuid.NewString(),
+        EnrollmentID: event.EnrollmentID,
+        EventID:      event.EventID,
+        Decision:     event.Decision,
+        Documents:    sealed,
+        Algorithm:    models.SealAlgorithm,
+        MerkleRoot:   models.MerkleRoot(sealed),
+        // Whole seconds survive any store, so the signed encoding does not
+        // change when the seal is loaded again
+        SealedAt: time.Now().UTC().Truncate(time.Second),
+        KeyID:    s.keyID,
+    }
+
+    digest, err := sealDigest(seal)
+    if err != nil {
+        return nil, err
+    }
+    signature, err := ecdsa.SignASN1(rand.Reader, s.key, digest)
+    if err != nil {
+        return nil, fmt.Errorf("failed to sign enrollment seal: %w", err)
+    }
+    seal.Signature = base64.StdEncoding.EncodeToString(signature)
+
+    if s.cfg.TimestampURL != "" {
+        token, err := s.timestamp(ctx, digest)
+        if err != nil {
+            return nil, err
+        }
+        seal.TimestampToken = base64.StdEncoding.EncodeToString(token)
+    }
+    return seal, nil
+}
+
+func (s *SealService) verifySignature(seal *models.EnrollmentSeal) error {
+    if seal.KeyID != s.keyID {
+        return fmt.Errorf("seal signed with key %s", seal.KeyID)
+    }
+    signature, err := base64.StdEncoding.DecodeString(seal.Signature)
+    if err != nil {
+        return err
+    }
+    digest, err := sealDigest(seal)
+    if err != nil {
+        return err
+    }
+    if !ecdsa.VerifyASN1(&s.key.PublicKey, digest, signature) {
+        return errors.New("seal signature does not match")
+    }
+    return nil
+}
+
+// timestamp requests an RFC 3161 time-stamp token over the seal digest
+func (s *SealService) timestamp(ctx context.Context, digest []byte) ([]byte, error) {
+    query, err := asn1.Marshal(timeStampReq{
+        Version: 1,
+        MessageImprint: messageImprint{
+            HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+            HashedMessage: digest,
+        },
+        CertReq: true,
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode time-stamp request: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TimestampURL, bytes.NewReader(query))
+    if err != nil {
+        return nil, fmt.Errorf("failed to create time-stamp request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/timestamp-query")
+    req.Header.Set("Accept", "application/timestamp-reply")
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return nil, fmt.Errorf("time-stamp request failed: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("%w: HTTP %d", ErrTimestampFailed, resp.StatusCode)
+    }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, maxTimestampResponseBytes))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read time-stamp response: %w", err)
+    }
+
+    var reply timeStampResp
+    if _, err := asn1.Unmarshal(body, &reply); err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrTimestampFailed, err)
+    }
+    if reply.Status.Status > tsaStatusGrantedWithMods || len(reply.TimeStampToken.FullBytes) == 0 {
+        return nil, fmt.Errorf("%w: status %d", ErrTimestampFailed, reply.Status.Status)
+    }
+    return reply.TimeStampToken.FullBytes, nil
+}
+
+// sealDigest is the SHA-256 of the seal's JSON encoding without its
+// signature and timestamp token
+func sealDigest(seal *models.EnrollmentSeal) ([]byte, error) {
+    unsigned := *seal
+    unsigned.Signature = ""
+    unsigned.TimestampToken = ""
+    encoded, err := json.Marshal(&unsigned)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode enrollment seal: %w", err)
+    }
+    digest := sha256.Sum256(encoded)
+    return digest[:], nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "slices"
+    "strings"
+    "unicode"
+
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/crypto/hkdf" // v0.12.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    StepSearchIndex = "search_index"
+
+    // searchKeyInfo binds derived keys to the search index
+    searchKeyInfo = "document-service search index"
+    // searchTokenSize is the length in bytes of a stored token
+    searchTokenSize = 16
+)
+
+var (
+    ErrEmptySearch = errors.New("search has no terms")
+)
+
+// accentFolding lets a term match whether or not it was typed with accents
+var accentFolding = strings.NewReplacer(
+    "á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+    "é", "e", "è", "e", "ê", "e", "ë", "e",
+    "í", "i", "ì", "i", "î", "i", "ï", "i",
+    "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+    "ú", "u", "ù", "u", "û", "u", "ü", "u",
+    "ç", "c", "ñ", "n",
+)
+
+// SearchIndex finds documents by the text extracted from them without
+// storing that text. Each term is replaced by a keyed token under a key
+// derived for the tenant, and a search looks up the tokens of its terms, so
+// the index is no store of personal data on its own. Documents are removed
+// from it when they are erased or their extracted data is purged
+type SearchIndex struct {
+    cfg    config.SearchConfig
+    index  repository.SearchIndexRepository
+    logger *zap.Logger
+}
+
+// NewSearchIndex creates the search index, or returns nil when search is
+// disabled
+func NewSearchIndex(cfg *config.Config, index repository.SearchIndexRepository, logger *zap.Logger) (*SearchIndex, error) {
+    if cfg == nil || index == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SearchConfig.Enabled {
+        return nil, nil
+    }
+    return &SearchIndex{
+        cfg:    cfg.SearchConfig,
+        index:  index,
+        logger: logger.With(zap.String("component", "search")),
+    }, nil
+}
+
+// MaySearch reports whether users with the role may search
+func (s *SearchIndex) MaySearch(role string) bool {
+    return slices.Contains(s.cfg.Roles, role)
+}
+
+// IndexDocument replaces the indexed terms of a document with those of text
+func (s *SearchIndex) IndexDocument(ctx context.Context, doc *models.Document, text string) error {
+    tokens, err := s.Tokens(doc.TenantID, text)
+    if err != nil {
+        return err
+    }
+    if err := s.index.Index(ctx, doc.TenantID, doc.ID, tokens); err != nil {
+        return fmt.Errorf("failed to index document: %w", err)
+    }
+    searchOperations.WithLabelValues("index").Inc()
+    return nil
+}
+
+// Search returns the documents of the tenant whose text holds every term of
+// the query
+func (s *SearchIndex) Search(ctx context.Context, tenantID, query string) ([]string, error) {
+    tokens, err := s.Tokens(tenantID, query)
+    if err != nil {
+        return nil, err
+    }
+    if len(tokens) == 0 {
+        return nil, ErrEmptySearch
+    }
+    searchOperations.WithLabelValues("search").Inc()
+    return s.index.Search(ctx, tenantID, tokens, s.cfg.MaxResults)
+}
+
+// Forget removes a document from the index. It does nothing when search is
+// disabled, so erasure and retention call it unconditionally
+func (s *SearchIndex) Forget(ctx context.Context, doc *models.Document) error {
+    if s == nil {
+        return nil
+    }
+    if err := s.index.Delete(ctx, doc.TenantID, doc.ID); err != nil {
+        return fmt.Errorf("failed to remove document from search index: %w", err)
+    }
+    searchOperations.WithLabelValues("forget").Inc()
+    return nil
+}
+
+// Tokens returns the distinct keyed tokens of the terms in text under the
+// key of the tenant
+func (s *SearchIndex) Tokens(tenantID, text string) ([]string, error) {
+    key := make([]byte, sha256.Size)
+    if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(s.cfg.IndexKey), []byte(tenantID), []byte(searchKeyInfo)), key); err != nil {
+        return nil, fmt.Errorf("failed to derive tenant search key: %w", err)
+    }
+
+    terms := SearchTerms(text, s.cfg.MinTermLength)
+    tokens := make([]string, len(terms))
+    for i, term := range terms {
+        mac := hmac.New(sha256.New, key)
+        mac.Write([]byte(term))
+        tokens[i] = hex.EncodeToString(mac.Sum(nil)[:searchTokenSize])
+    }
+    return tokens, nil
+}
+
+// SearchTerms returns the distinct terms of text, lowercased and without
+// accents, of at least minLength characters. Identifiers written with
+// punctuation, such as a CPF, are also indexed by their digits, so they are
+// found however they are typed
+func SearchTerms(text string, minLength int) []string {
+    folded := accentFolding.Replace(strings.ToLower(text))
+    seen := make(map[string]bool)
+    terms := make([]string, 0)
+    add := func(term string) {
+        if len([]rune(term)) >= minLength && !seen[term] {
+            seen[term] = true
+            terms = append(terms, term)
+        }
+    }
+
+    for _, chunk := range strings.Fields(folded) {
+        words := strings.FieldsFunc(chunk, func(r rune) bool {
+            return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+        })
+        for _, word := range words {
+            add(word)
+        }
+        if len(words) > 1 {
+            digits := strings.Map(func(r rune) rune {
+                if unicode.IsDigit(r) {
+                    return r
+                }
+                return -1
+            }, chunk)
+            if len(digits) > len(words[0]) {
+                add(digits)
+            }
+        }
+    }
+    return terms
+}
+
+// SearchIndexStep indexes the text OCR extracted from a document
+type SearchIndexStep struct {
+    search *SearchIndex
+}
+
+// NewSearchIndexStep creates a new search index step
+func NewSearchIndexStep(search *SearchIndex) (*SearchIndexStep, error) {
+    if search == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    return &SearchIndexStep{search: search}, nil
+}
+
+// Name returns the step name
+func (s *SearchIndexStep) Name() string {
+    return StepSearchIndex
+}
+
+// Provenance reports the index version and that the step reads the OCR text
+func (s *SearchIndexStep) Provenance() StepProvenance {
+    return StepProvenance{Version: searchIndexVersion, Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether OCR reads the document
+func (s *SearchIndexStep) Applies(doc *models.Document) bool {
+    return ocrDocumentType(doc.DocumentType)
+}
+
+// Execute indexes the extracted text; documents OCR found no text in are
+// skipped
+func (s *SearchIndexStep) Execute(ctx context.Context, run *PipelineRun) error {
+    if strings.TrimSpace(run.OCRText) == "" {
+        return nil
+    }
+    return s.search.IndexDocument(ctx, run.Document, run.OCRText)
+}
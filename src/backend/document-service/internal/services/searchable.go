@@ -0,0 +1,140 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    "strings"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const StepSearchablePDF = "searchable_pdf"
+
+// Fixed object numbers of the searchable PDF; the objects of each page follow
+const (
+    searchableCatalog = iota + 1
+    searchablePages
+    searchableFont
+    searchableInfo
+    searchableFirstPage
+)
+
+// SearchablePDFStep lays the text OCR recognized over scans as an invisible
+// text layer, each line over where it appears, so downloaded documents can be
+// searched and their text selected
+type SearchablePDFStep struct {
+    cfg     config.SearchablePDFConfig
+    storage *StorageService
+}
+
+// NewSearchablePDFStep creates a new searchable PDF step
+func NewSearchablePDFStep(cfg *config.Config, storage *StorageService) (*SearchablePDFStep, error) {
+    if cfg == nil || storage == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    return &SearchablePDFStep{cfg: cfg.SearchablePDFConfig, storage: storage}, nil
+}
+
+// Name returns the step name
+func (s *SearchablePDFStep) Name() string {
+    return StepSearchablePDF
+}
+
+// Provenance reports the generator version and that the step reads the OCR
+// text layout
+func (s *SearchablePDFStep) Provenance() StepProvenance {
+    return StepProvenance{Version: searchablePDFVersion, Inputs: []string{StepOCR}}
+}
+
+// Applies reports whether OCR reads the document and its pages can be
+// rendered
+func (s *SearchablePDFStep) Applies(doc *models.Document) bool {
+    if !ocrDocumentType(doc.DocumentType) {
+        return false
+    }
+    return doc.ContentType == "application/pdf" || doc.ContentType == "image/jpeg" || doc.ContentType == "image/png"
+}
+
+// Execute stores the scan with its text layer as an encrypted rendition.
+// JPEG scans are embedded as uploaded; PDFs and PNGs are rendered and
+// compressed. Documents OCR found no text in are skipped
+func (s *SearchablePDFStep) Execute(ctx context.Context, run *PipelineRun) error {
+    if len(run.OCRLines) == 0 {
+        return nil
+    }
+
+    var pages []pdfPage
+    if page, ok := jpegPDFPage(run.Content, s.cfg.DPI); ok && run.Document.ContentType == "image/jpeg" {
+        pages = []pdfPage{page}
+    } else {
+        rendered, err := renderPages(ctx, run.Document.ContentType, run.Content, s.cfg.DPI)
+        if err != nil {
+            return err
+        }
+        for i, img := range rendered {
+            page, err := encodePDFPage(img, s.cfg.DPI, s.cfg.JPEGQuality)
+            if err != nil {
+                return fmt.Errorf("failed to encode page %d: %w", i+1, err)
+            }
+            pages = append(pages, page)
+        }
+    }
+
+    searchable, err := buildSearchablePDF(pages, run.OCRLines, run.Document.Filename)
+    if err != nil {
+        return err
+    }
+    if err := s.storage.StoreEncryptedRendition(ctx, run.Document, models.RenditionSearchablePDF, "application/pdf", searchable); err != nil {
+        return fmt.Errorf("failed to store searchable PDF: %w", err)
+    }
+    return nil
+}
+
+// BuildSearchablePDF writes rendered pages compressed at quality under an
+// invisible layer of their recognized lines, in reading order so copied text
+// reads as the page does
+func BuildSearchablePDF(pages []image.Image, dpi float64, quality int, lines []models.OCRLine, title string) ([]byte, error) {
+    encoded := make([]pdfPage, len(pages))
+    for i, img := range pages {
+        page, err := encodePDFPage(img, dpi, quality)
+        if err != nil {
+            return nil, fmt.Errorf("failed to encode page %d: %w", i+1, err)
+        }
+        encoded[i] = page
+    }
+    return buildSearchablePDF(encoded, lines, title)
+}
+
+func buildSearchablePDF(pages []pdfPage, lines []models.OCRLine, title string) ([]byte, error) {
+    if len(pages) == 0 {
+        return nil, errors.New("no pages to write")
+    }
+
+    pageLines := make([][]models.OCRLine, len(pages))
+    for _, line := range ReadingOrder(lines) {
+        page := line.Page - 1
+        if page < 0 || page >= len(pages) || strings.TrimSpace(line.Text) == "" {
+            continue
+        }
+        pageLines[page] = append(pageLines[page], line)
+    }
+    pageObject := func(page int) int { return searchableFirstPage + 3*page }
+
+    pdf := newPDFObjectWriter()
+    pdf.object(searchableCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", searchablePages))
+    kids := make([]string, len(pages))
+    for page := range pages {
+        kids[page] = fmt.Sprintf("%d 0 R", pageObject(page))
+    }
+    pdf.object(searchablePages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+    pdf.object(searchableFont, textLayerFont)
+    pdf.object(searchableInfo, fmt.Sprintf("<< /Title %s /Producer (document-service) >>", pdfText(title)))
+
+    for page, encoded := range pages {
+        writeTextLayerPage(pdf, pageObject(page), searchablePages, searchableFont, encoded, pageLines[page], -1)
+    }
+    return pdf.finish(searchableCatalog, searchableInfo)
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/hex"
+    "errors"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+    ErrInvalidServiceToken = errors.New("invalid service account token")
+    ErrServicePermission   = errors.New("service account role lacks the permission")
+)
+
+// serviceAccount is a configured account with the digest of its token
+type serviceAccount struct {
+    account models.ServiceAccount
+    digest  []byte
+}
+
+// ServiceAccounts authenticates internal callers by the bearer token of
+// their account. Each account holds the permissions of its internal role
+// only, so the credential of one worker cannot reach what others do
+type ServiceAccounts struct {
+    accounts []serviceAccount
+    logger   *zap.Logger
+}
+
+// NewServiceAccounts creates the registry of service accounts, or returns nil
+// when service accounts are disabled
+func NewServiceAccounts(cfg *config.Config, logger *zap.Logger) (*ServiceAccounts, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.ServiceAccountsConfig.Enabled {
+        return nil, nil
+    }
+
+    accounts := make([]serviceAccount, 0, len(cfg.ServiceAccountsConfig.Accounts))
+    for _, account := range cfg.ServiceAccountsConfig.Accounts {
+        digest, err := hex.DecodeString(account.TokenHash)
+        if err != nil {
+            return nil, errors.New("service account token hash must be hex encoded")
+        }
+        accounts = append(accounts, serviceAccount{
+            account: models.ServiceAccount{Name: account.Name, Role: account.Role},
+            digest:  digest,
+        })
+    }
+
+    return &ServiceAccounts{
+        accounts: accounts,
+        logger:   logger.With(zap.String("component", "service_accounts")),
+    }, nil
+}
+
+// Authenticate returns the account whose token was presented. Every account
+// is compared in constant time, so timing does not reveal which exist
+func (s *ServiceAccounts) Authenticate(token string) (*models.ServiceAccount, error) {
+    digest := sha256.Sum256([]byte(token))
+    var found *models.ServiceAccount
+    for i := range s.accounts {
+        if subtle.ConstantTimeCompare(digest[:], s.accounts[i].digest) == 1 {
+            found = &s.accounts[i].account
+        }
+    }
+    if found == nil {
+        serviceAccountRequests.WithLabelValues("unknown", "unauthenticated").Inc()
+        return nil, ErrInvalidServiceToken
+    }
+    account := *found
+    return &account, nil
+}
+
+// Authorize checks that the account's role holds the permission
+func (s *ServiceAccounts) Authorize(account *models.ServiceAccount, permission string) error {
+    return authorizeServiceAccount(account, permission, s.logger)
+}
+
+// authorizeServiceAccount checks and counts a permission of an internal
+// caller, however it authenticated
+func authorizeServiceAccount(account *models.ServiceAccount, permission string, logger *zap.Logger) error {
+    if permission == "" || !account.Allows(permission) {
+        serviceAccountRequests.WithLabelValues(account.Role, "denied").Inc()
+        logger.Warn("Service account denied",
+            zap.String("account", account.Name),
+            zap.String("role", account.Role),
+            zap.String("permission", permission),
+        )
+        return ErrServicePermission
+    }
+    serviceAccountRequests.WithLabelValues(account.Role, "allowed").Inc()
+    return nil
+}
@@ -0,0 +1,606 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/csv"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net"
+    "os"
+    "path"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/pkg/sftp" // v1.13.6
+    "go.uber.org/zap" // v1.24.0
+    "golang.org/x/crypto/ssh" // v0.12.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Reconciliation outcomes reported back to the employer for every manifest row
+const (
+    sftpOutcomeIngested = "INGESTED"
+    sftpOutcomeRejected = "REJECTED"
+    sftpOutcomeFailed   = "FAILED"
+)
+
+// SSH algorithms negotiated in FIPS mode: NIST curve key exchange, AES
+// ciphers, SHA-2 MACs and ECDSA or RSA SHA-2 signatures
+var (
+    fipsSSHConfig = ssh.Config{
+        KeyExchanges: []string{"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521", "diffie-hellman-group14-sha256"},
+        Ciphers:      []string{"aes128-gcm@openssh.com", "aes256-gcm@openssh.com", "aes128-ctr", "aes192-ctr", "aes256-ctr"},
+        MACs:         []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-512-etm@openssh.com", "hmac-sha2-256", "hmac-sha2-512"},
+    }
+    fipsSSHSignatureAlgorithms = []string{
+        ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512,
+    }
+)
+
+var (
+    ErrInvalidManifest  = errors.New("invalid batch manifest")
+    ErrNotInRoster      = errors.New("enrollment not in employer roster")
+    ErrCPFMismatch      = errors.New("beneficiary cpf does not match enrollment")
+    ErrChecksumMismatch = errors.New("file checksum does not match manifest")
+
+    sftpManifestHeader = []string{"file", "enrollment_id", "document_type", "beneficiary_cpf", "sha256"}
+)
+
+// sftpManifestEntry is a single row of a batch manifest
+type sftpManifestEntry struct {
+    File           string
+    EnrollmentID   string
+    DocumentType   string
+    BeneficiaryCPF string
+    SHA256         string
+}
+
+// sftpReconciliationRow is a single row of the reconciliation report
+type sftpReconciliationRow struct {
+    File         string
+    EnrollmentID string
+    Outcome      string
+    DocumentID   string
+    Reason       string
+}
+
+// sftpProgressDir holds, inside a claimed batch, the reconciliation row of
+// every manifest entry already handled, one file per entry
+const sftpProgressDir = ".progress"
+
+// SFTPIngestor periodically pulls corporate document batches from an SFTP server.
+// Batches are laid out as <inbound>/<employer id>/<batch id>/ with a manifest
+// describing every file; a batch is only picked up once its manifest exists.
+// A batch is claimed by moving it to <processing>/<employer id>/<batch id>/
+// before any entry is ingested, and the outcome of each entry is recorded
+// there as soon as it is known, so a batch interrupted partway is resumed
+// from the first entry without an outcome instead of being ingested again
+type SFTPIngestor struct {
+    cfg         config.SFTPConfig
+    maxFileSize int64
+    pipeline    DocumentIngester
+    enrollments *EnrollmentClient
+    logger      *zap.Logger
+}
+
+// NewSFTPIngestor creates a new SFTP batch ingestor
+func NewSFTPIngestor(cfg *config.Config, pipeline DocumentIngester, enrollments *EnrollmentClient, logger *zap.Logger) (*SFTPIngestor, error) {
+    if cfg == nil || pipeline == nil || enrollments == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &SFTPIngestor{
+        cfg:         cfg.SFTPConfig,
+        maxFileSize: cfg.ServiceConfig.Channel(models.ChannelSFTP).MaxFileSize,
+        pipeline:    pipeline,
+        enrollments: enrollments,
+        logger:      logger,
+    }, nil
+}
+
+// Run polls the SFTP server on the configured interval until the context is cancelled
+func (s *SFTPIngestor) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.cfg.PollInterval)
+    defer ticker.Stop()
+
+    for {
+        if err := s.Poll(ctx); errors.Is(err, ErrMaintenance) {
+            s.logger.Info("SFTP batch poll paused during maintenance")
+        } else if err != nil {
+            s.logger.Error("SFTP batch poll failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Poll processes every ready batch currently on the server
+func (s *SFTPIngestor) Poll(ctx context.Context) error {
+    client, closeFn, err := s.connect()
+    if err != nil {
+        return err
+    }
+    defer closeFn()
+
+    return s.PollClient(ctx, client)
+}
+
+// PollClient processes every ready batch over an open SFTP session. Batches
+// left claimed by an interrupted poll are finished before new ones are claimed.
+// Polling stops with ErrMaintenance once the pipeline refuses documents for
+// maintenance, leaving the batch claimed to be resumed afterwards
+func (s *SFTPIngestor) PollClient(ctx context.Context, client *sftp.Client) error {
+    if err := s.resumeBatches(ctx, client); err != nil {
+        return err
+    }
+
+    employers, err := client.ReadDir(s.cfg.InboundDir)
+    if err != nil {
+        return fmt.Errorf("failed to list inbound directory: %w", err)
+    }
+
+    for _, employer := range employers {
+        if !employer.IsDir() {
+            continue
+        }
+
+        employerDir := path.Join(s.cfg.InboundDir, employer.Name())
+        batches, err := client.ReadDir(employerDir)
+        if err != nil {
+            s.logger.Warn("Failed to list employer batches", zap.String("employer_id", employer.Name()), zap.Error(err))
+            continue
+        }
+
+        for _, batch := range batches {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            if !batch.IsDir() {
+                continue
+            }
+
+            batchDir := path.Join(employerDir, batch.Name())
+            if _, err := client.Stat(path.Join(batchDir, s.cfg.ManifestName)); err != nil {
+                // Manifest not uploaded yet, batch still in transfer
+                continue
+            }
+
+            if err := s.claimBatch(client, employer.Name(), batch.Name()); err != nil {
+                s.logger.Warn("Failed to claim SFTP batch",
+                    zap.String("employer_id", employer.Name()),
+                    zap.String("batch_id", batch.Name()),
+                    zap.Error(err),
+                )
+                continue
+            }
+            if err := s.processClaimed(ctx, client, employer.Name(), batch.Name()); err != nil {
+                return err
+            }
+        }
+    }
+
+    return nil
+}
+
+// resumeBatches finishes the batches claimed by an earlier poll that was
+// interrupted. The job runs on one instance at a time, so every claimed
+// batch found is one nobody is processing
+func (s *SFTPIngestor) resumeBatches(ctx context.Context, client *sftp.Client) error {
+    if err := client.MkdirAll(s.cfg.ProcessingDir); err != nil {
+        return fmt.Errorf("failed to create processing directory: %w", err)
+    }
+    employers, err := client.ReadDir(s.cfg.ProcessingDir)
+    if err != nil {
+        return fmt.Errorf("failed to list processing directory: %w", err)
+    }
+
+    for _, employer := range employers {
+        if !employer.IsDir() {
+            continue
+        }
+        batches, err := client.ReadDir(path.Join(s.cfg.ProcessingDir, employer.Name()))
+        if err != nil {
+            s.logger.Warn("Failed to list claimed batches", zap.String("employer_id", employer.Name()), zap.Error(err))
+            continue
+        }
+        for _, batch := range batches {
+            if ctx.Err() != nil {
+                return ctx.Err()
+            }
+            if !batch.IsDir() {
+                continue
+            }
+            if err := s.processClaimed(ctx, client, employer.Name(), batch.Name()); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// claimBatch moves a ready batch out of the inbound directory. The rename
+// is atomic and fails when the target exists, so a batch is claimed once
+func (s *SFTPIngestor) claimBatch(client *sftp.Client, employerID, batchID string) error {
+    processingDir := path.Join(s.cfg.ProcessingDir, employerID)
+    if err := client.MkdirAll(processingDir); err != nil {
+        return fmt.Errorf("failed to create processing directory: %w", err)
+    }
+    if err := client.Rename(path.Join(s.cfg.InboundDir, employerID, batchID), path.Join(processingDir, batchID)); err != nil {
+        return fmt.Errorf("failed to claim batch: %w", err)
+    }
+    return nil
+}
+
+// processClaimed processes a claimed batch, logging a failure; the batch
+// stays claimed and is resumed by the next poll. Only ErrMaintenance is
+// returned, as no other batch can be processed until maintenance ends
+func (s *SFTPIngestor) processClaimed(ctx context.Context, client *sftp.Client, employerID, batchID string) error {
+    err := s.processBatch(ctx, client, employerID, batchID)
+    if errors.Is(err, ErrMaintenance) {
+        return err
+    }
+    if err != nil {
+        s.logger.Error("SFTP batch processing failed",
+            zap.String("employer_id", employerID),
+            zap.String("batch_id", batchID),
+            zap.Error(err),
+        )
+    }
+    return nil
+}
+
+// processBatch validates and ingests every manifest entry of a claimed batch
+// not handled yet, writes the reconciliation report and moves the batch to
+// the processed directory
+func (s *SFTPIngestor) processBatch(ctx context.Context, client *sftp.Client, employerID, batchID string) error {
+    batchDir := path.Join(s.cfg.ProcessingDir, employerID, batchID)
+
+    entries, err := s.readManifest(client, path.Join(batchDir, s.cfg.ManifestName))
+    if err != nil {
+        return err
+    }
+    progress, err := s.readProgress(client, batchDir)
+    if err != nil {
+        return err
+    }
+
+    roster, err := s.enrollments.ListEmployerEnrollments(ctx, employerID)
+    if err != nil {
+        return fmt.Errorf("failed to fetch employer roster: %w", err)
+    }
+    rosterByID := make(map[string]models.Enrollment, len(roster))
+    for _, enrollment := range roster {
+        rosterByID[enrollment.ID] = enrollment
+    }
+
+    report := make([]sftpReconciliationRow, 0, len(entries))
+    for i, entry := range entries {
+        if row, ok := progress[i]; ok {
+            report = append(report, row)
+            continue
+        }
+        if err := CheckJobLease(ctx); err != nil {
+            return err
+        }
+
+        row, err := s.ingestEntry(ctx, client, batchDir, entry, rosterByID)
+        // An entry cut short by shutdown or refused for maintenance has no
+        // outcome yet; it is retried when the batch is resumed
+        if err != nil {
+            return err
+        }
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        if err := s.recordProgress(client, batchDir, i, row); err != nil {
+            return err
+        }
+        sftpBatchFiles.WithLabelValues(strings.ToLower(row.Outcome)).Inc()
+        report = append(report, row)
+    }
+
+    if err := CheckJobLease(ctx); err != nil {
+        return err
+    }
+    if err := s.writeReport(client, employerID, batchID, report); err != nil {
+        return err
+    }
+
+    processedDir := path.Join(s.cfg.ProcessedDir, employerID)
+    if err := client.MkdirAll(processedDir); err != nil {
+        return fmt.Errorf("failed to create processed directory: %w", err)
+    }
+    if err := client.Rename(batchDir, path.Join(processedDir, batchID)); err != nil {
+        return fmt.Errorf("failed to move processed batch: %w", err)
+    }
+
+    s.logger.Info("SFTP batch processed",
+        zap.String("employer_id", employerID),
+        zap.String("batch_id", batchID),
+        zap.Int("files", len(entries)),
+    )
+    return nil
+}
+
+// ingestEntry validates a single manifest row against the roster and ingests
+// its file. An error means the entry has no outcome yet
+func (s *SFTPIngestor) ingestEntry(ctx context.Context, client *sftp.Client, batchDir string, entry sftpManifestEntry, roster map[string]models.Enrollment) (sftpReconciliationRow, error) {
+    row := sftpReconciliationRow{File: entry.File, EnrollmentID: entry.EnrollmentID}
+
+    enrollment, ok := roster[entry.EnrollmentID]
+    if !ok {
+        row.Outcome, row.Reason = sftpOutcomeRejected, ErrNotInRoster.Error()
+        return row, nil
+    }
+    if digitsOnly(enrollment.BeneficiaryCPF) != digitsOnly(entry.BeneficiaryCPF) {
+        row.Outcome, row.Reason = sftpOutcomeRejected, ErrCPFMismatch.Error()
+        return row, nil
+    }
+
+    // Manifest paths are relative to the batch and must not escape it
+    filePath := path.Join(batchDir, path.Clean("/"+entry.File))
+    content, err := s.readFile(client, filePath)
+    if err != nil {
+        row.Outcome, row.Reason = sftpOutcomeRejected, err.Error()
+        return row, nil
+    }
+
+    if entry.SHA256 != "" {
+        sum := sha256.Sum256(content)
+        if !strings.EqualFold(hex.EncodeToString(sum[:]), entry.SHA256) {
+            row.Outcome, row.Reason = sftpOutcomeRejected, ErrChecksumMismatch.Error()
+            return row, nil
+        }
+    }
+
+    fileCtx, cancel := context.WithTimeout(ctx, s.cfg.FileTimeout)
+    defer cancel()
+
+    doc, err := s.pipeline.Ingest(fileCtx, IngestRequest{
+        EnrollmentID: enrollment.ID,
+        DocumentType: entry.DocumentType,
+        Filename:     path.Base(entry.File),
+        ContentType:  mime.TypeByExtension(path.Ext(entry.File)),
+        Channel:      models.ChannelSFTP,
+        SubmittedBy:  "sftp:" + path.Base(path.Dir(batchDir)),
+        Content:      bytes.NewReader(content),
+        Size:         int64(len(content)),
+    })
+    if errors.Is(err, ErrMaintenance) {
+        return row, err
+    }
+    if err != nil {
+        row.Outcome, row.Reason = sftpOutcomeFailed, err.Error()
+        if errors.Is(err, models.ErrInvalidContentType) || errors.Is(err, models.ErrInvalidSize) || errors.Is(err, models.ErrMissingField) || errors.Is(err, ErrMalwareDetected) || errors.Is(err, ErrDisarmFailed) {
+            row.Outcome = sftpOutcomeRejected
+        }
+        // Employers get what to fix in the report
+        var conversionErr *ConversionError
+        if errors.As(err, &conversionErr) && errors.Is(err, ErrConversionFailed) {
+            row.Outcome, row.Reason = sftpOutcomeRejected, conversionErr.Action
+        }
+        return row, nil
+    }
+
+    row.Outcome, row.DocumentID = sftpOutcomeIngested, doc.ID
+    return row, nil
+}
+
+// readProgress returns the recorded outcomes of a batch by manifest entry
+func (s *SFTPIngestor) readProgress(client *sftp.Client, batchDir string) (map[int]sftpReconciliationRow, error) {
+    progressDir := path.Join(batchDir, sftpProgressDir)
+    if err := client.MkdirAll(progressDir); err != nil {
+        return nil, fmt.Errorf("failed to create batch progress directory: %w", err)
+    }
+    files, err := client.ReadDir(progressDir)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list batch progress: %w", err)
+    }
+
+    progress := make(map[int]sftpReconciliationRow, len(files))
+    for _, file := range files {
+        index, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".csv"))
+        if err != nil || !strings.HasSuffix(file.Name(), ".csv") {
+            // Left over from a write that was interrupted; the entry is redone
+            continue
+        }
+        f, err := client.Open(path.Join(progressDir, file.Name()))
+        if err != nil {
+            return nil, fmt.Errorf("failed to open batch progress: %w", err)
+        }
+        record, err := csv.NewReader(f).Read()
+        f.Close()
+        if err != nil || len(record) != 5 {
+            return nil, fmt.Errorf("invalid batch progress record %s", file.Name())
+        }
+        progress[index] = sftpReconciliationRow{File: record[0], EnrollmentID: record[1], Outcome: record[2], DocumentID: record[3], Reason: record[4]}
+    }
+    return progress, nil
+}
+
+// recordProgress records the outcome of a manifest entry. The row is written
+// under a temporary name and renamed, so a record is either whole or absent
+func (s *SFTPIngestor) recordProgress(client *sftp.Client, batchDir string, index int, row sftpReconciliationRow) error {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+    w.Write([]string{row.File, row.EnrollmentID, row.Outcome, row.DocumentID, row.Reason})
+    w.Flush()
+    if err := w.Error(); err != nil {
+        return fmt.Errorf("failed to encode batch progress: %w", err)
+    }
+
+    name := path.Join(batchDir, sftpProgressDir, strconv.Itoa(index))
+    f, err := client.Create(name + ".tmp")
+    if err != nil {
+        return fmt.Errorf("failed to create batch progress: %w", err)
+    }
+    if _, err := f.Write(buf.Bytes()); err != nil {
+        f.Close()
+        return fmt.Errorf("failed to write batch progress: %w", err)
+    }
+    if err := f.Close(); err != nil {
+        return fmt.Errorf("failed to write batch progress: %w", err)
+    }
+    if err := client.Rename(name+".tmp", name+".csv"); err != nil {
+        return fmt.Errorf("failed to record batch progress: %w", err)
+    }
+    return nil
+}
+
+// readManifest parses and validates the batch manifest CSV
+func (s *SFTPIngestor) readManifest(client *sftp.Client, manifestPath string) ([]sftpManifestEntry, error) {
+    f, err := client.Open(manifestPath)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open manifest: %w", err)
+    }
+    defer f.Close()
+
+    records, err := csv.NewReader(f).ReadAll()
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrInvalidManifest, err)
+    }
+    if len(records) == 0 || len(records[0]) != len(sftpManifestHeader) {
+        return nil, fmt.Errorf("%w: expected header %s", ErrInvalidManifest, strings.Join(sftpManifestHeader, ","))
+    }
+    for i, column := range sftpManifestHeader {
+        if strings.TrimSpace(strings.ToLower(records[0][i])) != column {
+            return nil, fmt.Errorf("%w: expected header %s", ErrInvalidManifest, strings.Join(sftpManifestHeader, ","))
+        }
+    }
+
+    entries := make([]sftpManifestEntry, 0, len(records)-1)
+    for line, record := range records[1:] {
+        entry := sftpManifestEntry{
+            File:           strings.TrimSpace(record[0]),
+            EnrollmentID:   strings.TrimSpace(record[1]),
+            DocumentType:   strings.TrimSpace(record[2]),
+            BeneficiaryCPF: strings.TrimSpace(record[3]),
+            SHA256:         strings.TrimSpace(record[4]),
+        }
+        if entry.File == "" || entry.EnrollmentID == "" || entry.DocumentType == "" {
+            return nil, fmt.Errorf("%w: missing required field on line %d", ErrInvalidManifest, line+2)
+        }
+        entries = append(entries, entry)
+    }
+
+    return entries, nil
+}
+
+// readFile reads a batch file enforcing the channel size limit
+func (s *SFTPIngestor) readFile(client *sftp.Client, filePath string) ([]byte, error) {
+    f, err := client.Open(filePath)
+    if err != nil {
+        return nil, fmt.Errorf("file not found in batch")
+    }
+    defer f.Close()
+
+    content, err := io.ReadAll(io.LimitReader(f, s.maxFileSize+1))
+    if err != nil {
+        return nil, fmt.Errorf("failed to read file: %w", err)
+    }
+    if int64(len(content)) > s.maxFileSize {
+        return nil, models.ErrInvalidSize
+    }
+    return content, nil
+}
+
+// writeReport uploads the reconciliation report for the batch
+func (s *SFTPIngestor) writeReport(client *sftp.Client, employerID, batchID string, report []sftpReconciliationRow) error {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+    w.Write([]string{"file", "enrollment_id", "outcome", "document_id", "reason"})
+    for _, row := range report {
+        w.Write([]string{row.File, row.EnrollmentID, row.Outcome, row.DocumentID, row.Reason})
+    }
+    w.Flush()
+    if err := w.Error(); err != nil {
+        return fmt.Errorf("failed to encode reconciliation report: %w", err)
+    }
+
+    reportDir := path.Join(s.cfg.ReportDir, employerID)
+    if err := client.MkdirAll(reportDir); err != nil {
+        return fmt.Errorf("failed to create report directory: %w", err)
+    }
+
+    f, err := client.Create(path.Join(reportDir, batchID+"-reconciliation.csv"))
+    if err != nil {
+        return fmt.Errorf("failed to create reconciliation report: %w", err)
+    }
+    defer f.Close()
+
+    if _, err := f.Write(buf.Bytes()); err != nil {
+        return fmt.Errorf("failed to write reconciliation report: %w", err)
+    }
+    return nil
+}
+
+// connect opens an SFTP session authenticated by private key with a pinned host key
+func (s *SFTPIngestor) connect() (*sftp.Client, func(), error) {
+    keyBytes, err := os.ReadFile(s.cfg.PrivateKeyPath)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to read sftp private key: %w", err)
+    }
+    signer, err := ssh.ParsePrivateKey(keyBytes)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to parse sftp private key: %w", err)
+    }
+
+    hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s.cfg.HostKey))
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to parse sftp host key: %w", err)
+    }
+
+    clientConfig := &ssh.ClientConfig{
+        User:            s.cfg.Username,
+        Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+        HostKeyCallback: ssh.FixedHostKey(hostKey),
+        Timeout:         s.cfg.ConnectTimeout,
+    }
+    if utils.StrictCrypto() {
+        // Ed25519 keys and SHA-1 RSA signatures are outside the approved set
+        algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+        if !ok || signer.PublicKey().Type() == ssh.KeyAlgoED25519 {
+            return nil, nil, fmt.Errorf("%w: sftp private key type %s", utils.ErrNonCompliantCrypto, signer.PublicKey().Type())
+        }
+        algorithms := []string{signer.PublicKey().Type()}
+        if signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+            algorithms = []string{ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSASHA512}
+        }
+        fipsSigner, err := ssh.NewSignerWithAlgorithms(algorithmSigner, algorithms)
+        if err != nil {
+            return nil, nil, fmt.Errorf("%w: sftp private key: %v", utils.ErrNonCompliantCrypto, err)
+        }
+        clientConfig.Auth = []ssh.AuthMethod{ssh.PublicKeys(fipsSigner)}
+        clientConfig.Config = fipsSSHConfig
+        clientConfig.HostKeyAlgorithms = fipsSSHSignatureAlgorithms
+    }
+
+    sshClient, err := ssh.Dial("tcp", net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port)), clientConfig)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to connect to sftp server: %w", err)
+    }
+
+    client, err := sftp.NewClient(sshClient)
+    if err != nil {
+        sshClient.Close()
+        return nil, nil, fmt.Errorf("failed to start sftp session: %w", err)
+    }
+
+    return client, func() {
+        client.Close()
+        sshClient.Close()
+    }, nil
+}
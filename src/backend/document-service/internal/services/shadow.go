@@ -0,0 +1,466 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "errors"
+    "fmt"
+    "io"
+    "net/url"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    // FlagStorageReadSecondary makes the migration target authoritative at
+    // runtime, so cutover and rollback need no restart
+    FlagStorageReadSecondary = "storage.read_secondary"
+
+    // maxPendingRepairs bounds the diverged keys remembered for repair; keys
+    // past it are left to the backfill
+    maxPendingRepairs = 10000
+)
+
+// ShadowStore migrates objects between two backends without downtime. Writes
+// and deletes go to both, reads are served by the authoritative backend and
+// compared against the other one in the background. Keys found diverged or
+// whose shadow write failed are remembered and repaired by Run
+type ShadowStore struct {
+    primary        ObjectStore
+    secondary      ObjectStore
+    readSecondary  bool
+    compareReads   bool
+    compareTimeout time.Duration
+    repairInterval time.Duration
+    compares       chan struct{}
+    flags          *FeatureFlags
+    logger         *zap.Logger
+
+    mu      sync.Mutex
+    pending map[string]struct{}
+}
+
+// NewShadowStore creates a shadow store migrating from primary to secondary
+func NewShadowStore(cfg *config.Config, primary, secondary ObjectStore, flags *FeatureFlags, logger *zap.Logger) (*ShadowStore, error) {
+    if cfg == nil || primary == nil || secondary == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ShadowStore{
+        primary:        primary,
+        secondary:      secondary,
+        readSecondary:  cfg.StorageMigrationConfig.ReadFromSecondary,
+        compareReads:   cfg.StorageMigrationConfig.CompareReads,
+        compareTimeout: cfg.StorageMigrationConfig.CompareTimeout,
+        repairInterval: cfg.StorageMigrationConfig.ReconcileInterval,
+        compares:       make(chan struct{}, cfg.StorageMigrationConfig.MaxConcurrentCompares),
+        flags:          flags,
+        logger:         logger,
+        pending:        make(map[string]struct{}),
+    }, nil
+}
+
+// Name returns the backend name used in metrics
+func (s *ShadowStore) Name() string {
+    return "shadow"
+}
+
+// backends returns the authoritative backend followed by the shadow backend
+func (s *ShadowStore) backends() (ObjectStore, ObjectStore) {
+    if s.flags.Enabled(FlagStorageReadSecondary, s.readSecondary) {
+        return s.secondary, s.primary
+    }
+    return s.primary, s.secondary
+}
+
+// Warm warms both backends; the migration target must be reachable too
+func (s *ShadowStore) Warm(ctx context.Context, connections int) error {
+    for _, store := range []ObjectStore{s.primary, s.secondary} {
+        if warmable, ok := store.(warmableStore); ok {
+            if err := warmable.Warm(ctx, connections); err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// Put writes to both backends; only a failure of the authoritative write fails
+// the operation, a failed shadow write is reported as divergence
+func (s *ShadowStore) Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+    authoritative, shadow := s.backends()
+
+    if err := authoritative.Put(ctx, key, content, contentType, metadata); err != nil {
+        storageShadowWrites.WithLabelValues(authoritative.Name(), "failed").Inc()
+        return err
+    }
+    storageShadowWrites.WithLabelValues(authoritative.Name(), "success").Inc()
+
+    if err := shadow.Put(ctx, key, content, contentType, metadata); err != nil {
+        storageShadowWrites.WithLabelValues(shadow.Name(), "failed").Inc()
+        s.markDiverged(key)
+        s.logger.Warn("Shadow storage write failed",
+            zap.String("backend", shadow.Name()),
+            zap.String("key", key),
+            zap.Error(err),
+        )
+        return nil
+    }
+    storageShadowWrites.WithLabelValues(shadow.Name(), "success").Inc()
+    return nil
+}
+
+// Get reads from the authoritative backend, falling back to the shadow backend
+// for objects written before dual-writes started
+func (s *ShadowStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+    authoritative, shadow := s.backends()
+
+    reader, err := authoritative.Get(ctx, key)
+    if errors.Is(err, ErrObjectNotFound) {
+        storageShadowComparisons.WithLabelValues("missing_" + authoritative.Name()).Inc()
+        s.markDiverged(key)
+        return shadow.Get(ctx, key)
+    }
+    if err != nil {
+        return nil, err
+    }
+    if !s.compareReads {
+        return reader, nil
+    }
+
+    // Buffer the content so its hash can be compared once the shadow read completes
+    defer reader.Close()
+    content, err := io.ReadAll(reader)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read object from %s: %w", authoritative.Name(), err)
+    }
+
+    // Comparisons past the limit are skipped rather than queued, so a burst
+    // of reads cannot pile up goroutines holding whole objects
+    select {
+    case s.compares <- struct{}{}:
+        go func(expected [sha256.Size]byte) {
+            defer func() { <-s.compares }()
+            s.compare(shadow, key, expected)
+        }(sha256.Sum256(content))
+    default:
+        storageShadowComparisons.WithLabelValues("skipped").Inc()
+    }
+    return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// Stat reads metadata from the authoritative backend, falling back to the
+// shadow backend like Get
+func (s *ShadowStore) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+    authoritative, shadow := s.backends()
+
+    info, err := authoritative.Stat(ctx, key)
+    if errors.Is(err, ErrObjectNotFound) {
+        return shadow.Stat(ctx, key)
+    }
+    return info, err
+}
+
+// Open opens the object on the authoritative backend for random access, falling
+// back to the shadow backend like Get; opened objects are not compared
+func (s *ShadowStore) Open(ctx context.Context, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    authoritative, shadow := s.backends()
+
+    reader, info, err := openObject(ctx, authoritative, key)
+    if errors.Is(err, ErrObjectNotFound) {
+        return openObject(ctx, shadow, key)
+    }
+    return reader, info, err
+}
+
+// PresignGet presigns a download from the authoritative backend, or from the
+// shadow backend for objects that were never copied
+func (s *ShadowStore) PresignGet(ctx context.Context, key string, expiry time.Duration) (*url.URL, error) {
+    authoritative, shadow := s.backends()
+
+    if _, err := authoritative.Stat(ctx, key); errors.Is(err, ErrObjectNotFound) {
+        return presignObject(ctx, shadow, key, expiry)
+    } else if err != nil {
+        return nil, err
+    }
+    return presignObject(ctx, authoritative, key, expiry)
+}
+
+// compare reads the object from the shadow backend and reports whether it matches
+func (s *ShadowStore) compare(shadow ObjectStore, key string, expected [sha256.Size]byte) {
+    ctx, cancel := context.WithTimeout(context.Background(), s.compareTimeout)
+    defer cancel()
+
+    result := "match"
+    reader, err := shadow.Get(ctx, key)
+    if err == nil {
+        hash := sha256.New()
+        _, err = io.Copy(hash, reader)
+        reader.Close()
+        if err == nil && !bytes.Equal(hash.Sum(nil), expected[:]) {
+            result = "hash_mismatch"
+        }
+    }
+    if errors.Is(err, ErrObjectNotFound) {
+        result = "missing_" + shadow.Name()
+    } else if err != nil {
+        result = "error"
+    }
+
+    storageShadowComparisons.WithLabelValues(result).Inc()
+    if result != "match" {
+        s.markDiverged(key)
+        s.logger.Warn("Shadow storage read diverged",
+            zap.String("backend", shadow.Name()),
+            zap.String("key", key),
+            zap.String("result", result),
+            zap.Error(err),
+        )
+    }
+}
+
+// Delete removes the object from both backends
+func (s *ShadowStore) Delete(ctx context.Context, key string) error {
+    authoritative, shadow := s.backends()
+
+    if err := authoritative.Delete(ctx, key); err != nil {
+        return err
+    }
+    if err := shadow.Delete(ctx, key); err != nil {
+        s.logger.Warn("Shadow storage delete failed",
+            zap.String("backend", shadow.Name()),
+            zap.String("key", key),
+            zap.Error(err),
+        )
+    }
+    return nil
+}
+
+// markDiverged remembers a key for the next repair
+func (s *ShadowStore) markDiverged(key string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if len(s.pending) < maxPendingRepairs {
+        s.pending[key] = struct{}{}
+    }
+}
+
+// Run repairs the keys this instance found diverged on every reconcile
+// interval until the context is cancelled. Each instance repairs its own, so
+// it runs on every replica
+func (s *ShadowStore) Run(ctx context.Context) {
+    ticker := time.NewTicker(s.repairInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            s.RepairPending(ctx)
+        }
+    }
+}
+
+// RepairPending repairs the keys found diverged since the last repair. Keys
+// that fail to repair are kept for the next one
+func (s *ShadowStore) RepairPending(ctx context.Context) {
+    s.mu.Lock()
+    keys := make([]string, 0, len(s.pending))
+    for key := range s.pending {
+        keys = append(keys, key)
+    }
+    s.pending = make(map[string]struct{})
+    s.mu.Unlock()
+
+    for _, key := range keys {
+        if ctx.Err() != nil {
+            s.markDiverged(key)
+            continue
+        }
+        if _, err := s.Repair(ctx, key); err != nil {
+            s.markDiverged(key)
+            s.logger.Warn("Shadow storage repair failed", zap.String("key", key), zap.Error(err))
+        }
+    }
+}
+
+// Reconcile repairs the object when one backend lacks it or the two copies
+// differ in size, reporting whether it was copied. Objects held by both with
+// the same size are left alone, so reconciling does not read every object
+func (s *ShadowStore) Reconcile(ctx context.Context, key string) (bool, error) {
+    authoritative, shadow := s.backends()
+
+    authoritativeInfo, authoritativeErr := authoritative.Stat(ctx, key)
+    if authoritativeErr != nil && !errors.Is(authoritativeErr, ErrObjectNotFound) {
+        return false, authoritativeErr
+    }
+    shadowInfo, shadowErr := shadow.Stat(ctx, key)
+    if shadowErr != nil && !errors.Is(shadowErr, ErrObjectNotFound) {
+        return false, shadowErr
+    }
+    if authoritativeErr == nil && shadowErr == nil && authoritativeInfo.Size == shadowInfo.Size {
+        return false, nil
+    }
+    return s.Repair(ctx, key)
+}
+
+// Repair copies an object to the backend lacking it, or over the shadow copy
+// when both hold it, reporting whether it was copied. Objects written before
+// dual-writes started are copied from the backend holding them whichever is
+// authoritative. An object deleted while it was copied is removed again, so
+// a repair racing a delete does not bring it back
+func (s *ShadowStore) Repair(ctx context.Context, key string) (bool, error) {
+    authoritative, shadow := s.backends()
+
+    from, to := authoritative, shadow
+    content, info, err := readObjectWithInfo(ctx, from, key)
+    if errors.Is(err, ErrObjectNotFound) {
+        from, to = shadow, authoritative
+        content, info, err = readObjectWithInfo(ctx, from, key)
+    }
+    if errors.Is(err, ErrObjectNotFound) {
+        // Deleted from both backends since it was found diverged
+        return false, nil
+    }
+    if err != nil {
+        return false, err
+    }
+
+    if err := to.Put(ctx, key, content, info.ContentType, info.Metadata); err != nil {
+        storageShadowRepairs.WithLabelValues(to.Name(), "failed").Inc()
+        return false, fmt.Errorf("failed to copy object to %s: %w", to.Name(), err)
+    }
+    if _, err := from.Stat(ctx, key); errors.Is(err, ErrObjectNotFound) {
+        return false, to.Delete(ctx, key)
+    }
+    storageShadowRepairs.WithLabelValues(to.Name(), "repaired").Inc()
+    return true, nil
+}
+
+// readObjectWithInfo reads an object along with its content type and metadata
+func readObjectWithInfo(ctx context.Context, store ObjectStore, key string) ([]byte, ObjectInfo, error) {
+    info, err := store.Stat(ctx, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    reader, err := store.Get(ctx, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    defer reader.Close()
+
+    content, err := io.ReadAll(reader)
+    if err != nil {
+        return nil, ObjectInfo{}, fmt.Errorf("failed to read object from %s: %w", store.Name(), err)
+    }
+    return content, info, nil
+}
+
+// ShadowBackfill copies the objects of stored documents that one backend of
+// the migration lacks, including those stored before dual-writes started. It
+// walks documents in update order, a batch per interval, and once caught up
+// keeps following newly updated documents. The position is kept in memory,
+// so an instance taking the job over starts from the oldest document again
+type ShadowBackfill struct {
+    cfg       config.StorageMigrationConfig
+    documents repository.DocumentRepository
+    shadow    *ShadowStore
+    logger    *zap.Logger
+    from      time.Time
+}
+
+// NewShadowBackfill creates the backfill of a shadow store
+func NewShadowBackfill(cfg *config.Config, documents repository.DocumentRepository, shadow *ShadowStore, logger *zap.Logger) (*ShadowBackfill, error) {
+    if cfg == nil || documents == nil || shadow == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &ShadowBackfill{
+        cfg:       cfg.StorageMigrationConfig,
+        documents: documents,
+        shadow:    shadow,
+        logger:    logger.With(zap.String("component", "shadow_backfill")),
+    }, nil
+}
+
+// Run backfills a batch on every reconcile interval until the context is
+// cancelled
+func (b *ShadowBackfill) Run(ctx context.Context) {
+    ticker := time.NewTicker(b.cfg.ReconcileInterval)
+    defer ticker.Stop()
+
+    for {
+        if _, _, err := b.Backfill(ctx); err != nil && ctx.Err() == nil {
+            b.logger.Error("Shadow storage backfill failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Backfill reconciles the objects of the next batch of documents, returning
+// the number of objects checked and copied. A batch is only cut between
+// documents updated at different times, so the walk always moves forward
+func (b *ShadowBackfill) Backfill(ctx context.Context) (int, int, error) {
+    docs, err := b.documents.ListUpdatedBetween(ctx, b.from, time.Now())
+    if err != nil {
+        return 0, 0, fmt.Errorf("failed to list documents: %w", err)
+    }
+
+    checked, copied := 0, 0
+    for i, doc := range docs {
+        if i >= b.cfg.ReconcileBatchSize && !doc.UpdatedAt.Equal(docs[i-1].UpdatedAt) {
+            break
+        }
+        for _, key := range storedObjectKeys(doc) {
+            if err := CheckJobLease(ctx); err != nil {
+                return checked, copied, err
+            }
+            repaired, err := b.shadow.Reconcile(ctx, key)
+            if err != nil {
+                return checked, copied, fmt.Errorf("failed to reconcile %s: %w", key, err)
+            }
+            checked++
+            if repaired {
+                copied++
+            }
+        }
+        b.from = doc.UpdatedAt
+    }
+
+    if copied > 0 {
+        b.logger.Info("Shadow storage backfilled",
+            zap.Int("checked", checked),
+            zap.Int("copied", copied),
+            zap.Time("through", b.from),
+        )
+    }
+    return checked, copied, nil
+}
+
+// storedObjectKeys lists the objects stored for a document
+func storedObjectKeys(doc *models.Document) []string {
+    if doc.StoragePath == "" {
+        return nil
+    }
+    keys := []string{doc.StoragePath}
+    for _, rendition := range doc.Renditions {
+        if rendition.StoragePath != "" {
+            keys = append(keys, rendition.StoragePath)
+        }
+    }
+    return keys
+}
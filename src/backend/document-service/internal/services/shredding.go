@@ -0,0 +1,183 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Outbox topic deleting the ciphertext left behind by crypto-shredding
+const (
+    TopicStorageGarbage = "storage.garbage_collect"
+)
+
+// GarbageCollectionRequest is the outbox payload listing objects to delete
+type GarbageCollectionRequest struct {
+    DocumentID string   `json:"document_id"`
+    Keys       []string `json:"keys"`
+}
+
+// CryptoShredder erases documents. Content sealed under a document's own data
+// key is made unrecoverable at once by destroying the key, and the ciphertext
+// is deleted later from the outbox, so erasure does not wait on deleting large
+// objects from every backend
+type CryptoShredder struct {
+    cfg       *config.Config
+    storage   *StorageService
+    documents repository.DocumentRepository
+    outbox    repository.OutboxRepository
+    search    *SearchIndex
+    logger    *zap.Logger
+}
+
+// NewCryptoShredder creates a new crypto-shredder
+func NewCryptoShredder(cfg *config.Config, storage *StorageService, documents repository.DocumentRepository, outbox repository.OutboxRepository, logger *zap.Logger) (*CryptoShredder, error) {
+    if cfg == nil || storage == nil || documents == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &CryptoShredder{
+        cfg:       cfg,
+        storage:   storage,
+        documents: documents,
+        outbox:    outbox,
+        logger:    logger,
+    }, nil
+}
+
+// UseSearchIndex removes erased and anonymized documents from the search
+// index
+func (s *CryptoShredder) UseSearchIndex(search *SearchIndex) {
+    s.search = search
+}
+
+// Erase makes the content of a document unrecoverable and removes its record.
+// Documents sealed under the shared data key, stored before crypto-shredding
+// was enabled, or under a key without a grant are deleted synchronously
+// instead
+func (s *CryptoShredder) Erase(ctx context.Context, doc *models.Document) error {
+    outcome, err := s.destroyContent(ctx, doc)
+    if err != nil {
+        return err
+    }
+    if err := s.search.Forget(ctx, doc); err != nil {
+        return err
+    }
+    if err := s.documents.Delete(ctx, doc.ID); err != nil {
+        return err
+    }
+    documentErasures.WithLabelValues(outcome).Inc()
+    return nil
+}
+
+// Anonymize makes the content of a document unrecoverable like Erase but
+// keeps its record with the personal data removed. Repositories keeping past
+// states of the document forget them too
+func (s *CryptoShredder) Anonymize(ctx context.Context, doc *models.Document) error {
+    if _, err := s.destroyContent(ctx, doc); err != nil {
+        return err
+    }
+    if err := s.search.Forget(ctx, doc); err != nil {
+        return err
+    }
+
+    doc.Anonymize(time.Now())
+    if redactor, ok := s.documents.(documentRedactor); ok {
+        if err := redactor.Redact(ctx, doc); err != nil {
+            return fmt.Errorf("failed to persist anonymized document: %w", err)
+        }
+    } else if err := s.documents.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist anonymized document: %w", err)
+    }
+    documentErasures.WithLabelValues("anonymized").Inc()
+    return nil
+}
+
+// documentRedactor is implemented by document repositories keeping past
+// states of a document, which must forget them when it is anonymized
+type documentRedactor interface {
+    Redact(ctx context.Context, doc *models.Document) error
+}
+
+// destroyContent makes the content and renditions of a document
+// unrecoverable, reporting whether they were shredded or deleted
+func (s *CryptoShredder) destroyContent(ctx context.Context, doc *models.Document) (string, error) {
+    // A retry after the key was destroyed has nothing left to do
+    if doc.EncryptionInfo.Shredded() {
+        return "shredded", nil
+    }
+    // Without a grant to revoke, KMS would still unwrap the key for the
+    // service, so the content is deleted like content under the shared key
+    if doc.EncryptionInfo == nil || doc.EncryptionInfo.WrappedKey == "" || doc.EncryptionInfo.GrantID == "" {
+        if doc.StoragePath != "" {
+            if err := s.storage.DeleteDocument(ctx, doc); err != nil {
+                return "", err
+            }
+        }
+        return "deleted", nil
+    }
+
+    if err := utils.ShredDataKey(ctx, s.cfg, doc.EncryptionInfo); err != nil {
+        return "", fmt.Errorf("failed to shred document data key: %w", err)
+    }
+    doc.MarkShredded(time.Now())
+    // From here on no replica can decrypt the content
+    if err := s.documents.Update(ctx, doc); err != nil {
+        return "", fmt.Errorf("failed to persist shredded document: %w", err)
+    }
+
+    // Renditions stored in the clear are small and deleted right away
+    keys := []string{doc.StoragePath}
+    for _, rendition := range doc.Renditions {
+        if rendition.Encryption != nil {
+            keys = append(keys, rendition.StoragePath)
+            continue
+        }
+        if err := s.storage.delete(ctx, rendition.StoragePath); err != nil && !errors.Is(err, ErrObjectNotFound) {
+            return "", fmt.Errorf("failed to delete rendition %s: %w", rendition.Name, err)
+        }
+    }
+
+    msg, err := newOutboxMessage(TopicStorageGarbage, doc.ID, GarbageCollectionRequest{
+        DocumentID: doc.ID,
+        Keys:       keys,
+    })
+    if err != nil {
+        return "", err
+    }
+    if err := s.outbox.Enqueue(ctx, msg); err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+        return "", fmt.Errorf("failed to enqueue ciphertext garbage collection: %w", err)
+    }
+
+    s.logger.Info("Document crypto-shredded",
+        zap.String("document_id", doc.ID),
+        zap.Int("objects_queued", len(keys)),
+    )
+    return "shredded", nil
+}
+
+// Deliver is the outbox handler deleting the ciphertext of shredded documents;
+// objects already gone are skipped, so redelivery is harmless
+func (s *CryptoShredder) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    var req GarbageCollectionRequest
+    if err := json.Unmarshal(msg.Payload, &req); err != nil {
+        return fmt.Errorf("invalid garbage collection payload: %w", err)
+    }
+
+    for _, key := range req.Keys {
+        if err := s.storage.delete(ctx, key); err != nil && !errors.Is(err, ErrObjectNotFound) {
+            return fmt.Errorf("failed to delete %s: %w", key, err)
+        }
+        garbageCollectedObjects.Inc()
+    }
+    return nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/x509"
+    "encoding/asn1"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "os"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "go.mozilla.org/pkcs7" // v0.10.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepSignature = "signature"
+)
+
+var (
+    ErrNoTrustedRoots = errors.New("no trusted ICP-Brasil root certificates loaded")
+
+    byteRangePattern = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+
+    // ICP-Brasil subject alternative name otherName identifiers
+    oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+    oidICPBrasilCPF   = asn1.ObjectIdentifier{2, 16, 76, 1, 3, 1}
+    oidICPBrasilCNPJ  = asn1.ObjectIdentifier{2, 16, 76, 1, 3, 3}
+)
+
+// SignatureStep verifies embedded PAdES signatures of PDF documents against
+// the ICP-Brasil root certificates and records signer identity and validity
+type SignatureStep struct {
+    roots *x509.CertPool
+}
+
+// NewSignatureStep creates a new signature verification step using the
+// configured ICP-Brasil root bundle
+func NewSignatureStep(cfg *config.Config) (*SignatureStep, error) {
+    if cfg == nil {
+        return nil, errors.New("config cannot be nil")
+    }
+
+    bundle, err := os.ReadFile(cfg.SignatureConfig.TrustedRootsPath)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read trusted roots: %w", err)
+    }
+
+    roots := x509.NewCertPool()
+    if !roots.AppendCertsFromPEM(bundle) {
+        return nil, ErrNoTrustedRoots
+    }
+
+    return &SignatureStep{roots: roots}, nil
+}
+
+// Name returns the step name
+func (s *SignatureStep) Name() string {
+    return StepSignature
+}
+
+// Applies reports whether the document is a PDF
+func (s *SignatureStep) Applies(doc *models.Document) bool {
+    return doc.ContentType == "application/pdf"
+}
+
+// Execute verifies every signature dictionary found in the PDF; unsigned
+// documents are left untouched and invalid signatures are recorded, not failed
+func (s *SignatureStep) Execute(ctx context.Context, run *PipelineRun) error {
+    matches := byteRangePattern.FindAllSubmatch(run.Content, -1)
+    if len(matches) == 0 {
+        return nil
+    }
+
+    signatures := make([]models.SignatureInfo, 0, len(matches))
+    for _, match := range matches {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        info := s.verify(run.Content, match[1:])
+        if info.Valid {
+            signatureVerifications.WithLabelValues("valid").Inc()
+        } else {
+            signatureVerifications.WithLabelValues("invalid").Inc()
+        }
+        signatures = append(signatures, info)
+    }
+
+    run.Document.SetSignatures(signatures)
+    return nil
+}
+
+// verify checks a single signature identified by its /ByteRange values
+func (s *SignatureStep) verify(content []byte, rangeValues [][]byte) models.SignatureInfo {
+    info := models.SignatureInfo{VerifiedAt: time.Now()}
+
+    byteRange := make([]int, len(rangeValues))
+    for i, value := range rangeValues {
+        n, err := strconv.Atoi(string(value))
+        if err != nil {
+            info.FailureReason = "malformed byte range"
+            return info
+        }
+        byteRange[i] = n
+    }
+
+    offset1, length1, offset2, length2 := byteRange[0], byteRange[1], byteRange[2], byteRange[3]
+    if offset1 < 0 || length1 < 0 || offset2 < offset1+length1 || length2 < 0 || offset2+length2 > len(content) {
+        info.FailureReason = "byte range outside document bounds"
+        return info
+    }
+    // Content appended after signing (incremental updates) is not covered by the signature
+    info.CoversDocument = offset1 == 0 && offset2+length2 == len(content)
+
+    // The gap between both ranges holds the hex encoded CMS structure: <3082...000>
+    contents := bytes.Trim(bytes.TrimSpace(content[offset1+length1:offset2]), "<>")
+    der, err := hex.DecodeString(string(contents))
+    if err != nil {
+        info.FailureReason = "malformed signature contents"
+        return info
+    }
+    der = trimDERPadding(der)
+
+    p7, err := pkcs7.Parse(der)
+    if err != nil {
+        info.FailureReason = "invalid CMS signature: " + err.Error()
+        return info
+    }
+
+    signer := p7.GetOnlySigner()
+    if signer == nil {
+        info.FailureReason = "signature must have exactly one signer"
+        return info
+    }
+    info.SignerName, info.SignerCPF, info.SignerCNPJ = icpBrasilIdentity(signer)
+    info.Issuer = signer.Issuer.CommonName
+    info.SerialNumber = signer.SerialNumber.String()
+    info.NotBefore = signer.NotBefore
+    info.NotAfter = signer.NotAfter
+
+    var signedAt time.Time
+    if err := p7.UnmarshalSignedAttribute(pkcs7.OIDAttributeSigningTime, &signedAt); err == nil {
+        info.SignedAt = &signedAt
+    }
+
+    // PAdES signatures are detached; the signed data is the PDF minus the contents gap
+    signed := make([]byte, 0, length1+length2)
+    signed = append(signed, content[offset1:offset1+length1]...)
+    signed = append(signed, content[offset2:offset2+length2]...)
+    p7.Content = signed
+
+    if err := p7.VerifyWithChain(s.roots); err != nil {
+        info.FailureReason = err.Error()
+        return info
+    }
+
+    info.Valid = true
+    return info
+}
+
+// trimDERPadding drops the zero padding PDF writers leave after the CMS structure
+func trimDERPadding(der []byte) []byte {
+    var raw asn1.RawValue
+    rest, err := asn1.Unmarshal(der, &raw)
+    if err != nil {
+        return der
+    }
+    return der[:len(der)-len(rest)]
+}
+
+// icpBrasilIdentity extracts the signer name and CPF or CNPJ; ICP-Brasil
+// certificates carry them as "NAME:DIGITS" in the common name and as
+// otherName entries of the subject alternative name
+func icpBrasilIdentity(cert *x509.Certificate) (name, cpf, cnpj string) {
+    name = cert.Subject.CommonName
+    if i := strings.LastIndex(name, ":"); i > 0 {
+        switch digits := digitsOnly(name[i+1:]); len(digits) {
+        case 11:
+            cpf = digits
+            name = name[:i]
+        case 14:
+            cnpj = digits
+            name = name[:i]
+        }
+    }
+
+    for _, ext := range cert.Extensions {
+        if !ext.Id.Equal(oidSubjectAltName) {
+            continue
+        }
+
+        var generalNames []asn1.RawValue
+        if _, err := asn1.Unmarshal(ext.Value, &generalNames); err != nil {
+            break
+        }
+        for _, generalName := range generalNames {
+            // otherName is the context specific [0] choice of GeneralName
+            if generalName.Class != asn1.ClassContextSpecific || generalName.Tag != 0 {
+                continue
+            }
+            var other struct {
+                TypeID asn1.ObjectIdentifier
+                Value  asn1.RawValue `asn1:"explicit,tag:0"`
+            }
+            if _, err := asn1.UnmarshalWithParams(generalName.FullBytes, &other, "tag:0"); err != nil {
+                continue
+            }
+
+            value := digitsOnly(string(other.Value.Bytes))
+            switch {
+            case other.TypeID.Equal(oidICPBrasilCPF) && cpf == "" && len(value) >= 19:
+                // Holder birth date (DDMMYYYY) followed by the CPF
+                cpf = value[8:19]
+            case other.TypeID.Equal(oidICPBrasilCNPJ) && cnpj == "" && len(value) >= 14:
+                cnpj = value[:14]
+            }
+        }
+    }
+
+    return name, cpf, cnpj
+}
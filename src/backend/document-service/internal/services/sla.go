@@ -0,0 +1,184 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strconv"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// SLATracker runs an SLA clock per document from upload to the final review
+// decision. It escalates undecided documents as they pass the configured
+// shares of their deadline, announcing each escalation to the tenant's
+// webhooks, and reports how each tenant's documents met their deadlines
+type SLATracker struct {
+    cfg         config.SLAConfig
+    documents   repository.DocumentRepository
+    webhooks    *TenantWebhooks
+    maintenance *MaintenanceMode
+    calendars   *BusinessCalendars
+    logger      *zap.Logger
+}
+
+// NewSLATracker creates the SLA tracker, or returns nil when the SLA clock
+// is disabled. webhooks may be nil, in which case escalations are recorded
+// and counted only
+func NewSLATracker(cfg *config.Config, documents repository.DocumentRepository, webhooks *TenantWebhooks, maintenance *MaintenanceMode, logger *zap.Logger) (*SLATracker, error) {
+    if cfg == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SLAConfig.Enabled {
+        return nil, nil
+    }
+
+    return &SLATracker{
+        cfg:         cfg.SLAConfig,
+        documents:   documents,
+        webhooks:    webhooks,
+        maintenance: maintenance,
+        logger:      logger.With(zap.String("component", "sla")),
+    }, nil
+}
+
+// UseCalendars runs SLA clocks on the business calendar of each document's
+// tenant; it must be called before the tracker starts
+func (t *SLATracker) UseCalendars(calendars *BusinessCalendars) {
+    if t != nil {
+        t.calendars = calendars
+    }
+}
+
+// Clock returns the SLA clock of a document at now, or nil for documents
+// without one. A nil *SLATracker returns nil
+func (t *SLATracker) Clock(doc *models.Document, now time.Time) *models.SLAClock {
+    if t == nil || !doc.TracksSLA() {
+        return nil
+    }
+    return models.NewSLAClock(doc, t.cfg.DeadlineFor(doc.DocumentType), t.calendars.For(doc.TenantID), now)
+}
+
+// Reviewed counts a final review decision against the document's deadline.
+// A nil *SLATracker counts nothing
+func (t *SLATracker) Reviewed(doc *models.Document) {
+    clock := t.Clock(doc, time.Now())
+    if clock == nil || clock.StoppedAt == nil {
+        return
+    }
+    result := "met"
+    if clock.Breached {
+        result = "breached"
+    }
+    slaDecisions.WithLabelValues(doc.DocumentType, result).Inc()
+}
+
+// Run escalates overdue documents every scan interval until ctx is done
+func (t *SLATracker) Run(ctx context.Context) {
+    ticker := time.NewTicker(t.cfg.ScanInterval)
+    defer ticker.Stop()
+
+    for {
+        if err := t.Scan(ctx); err != nil {
+            t.logger.Error("SLA scan failed", zap.Error(err))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Scan escalates the undecided documents that passed another escalation
+// threshold since the last scan. A document failing to update is escalated
+// by the next scan
+func (t *SLATracker) Scan(ctx context.Context) error {
+    if t.maintenance.Active() {
+        t.logger.Info("SLA scan skipped during maintenance")
+        return nil
+    }
+
+    now := time.Now()
+    docs, err := t.documents.ListUpdatedBetween(ctx, time.Time{}, now.Add(time.Second))
+    if err != nil {
+        return fmt.Errorf("failed to list documents: %w", err)
+    }
+
+    for _, doc := range docs {
+        if err := CheckJobLease(ctx); err != nil {
+            return err
+        }
+        clock := t.Clock(doc, now)
+        if clock == nil || models.SLAThresholdsPassed(clock, t.cfg.Escalations) <= doc.SLALevel() {
+            continue
+        }
+        // Escalate the latest state, so a decision made since the listing
+        // is neither overwritten nor escalated
+        doc, err = t.documents.GetByID(ctx, doc.ID)
+        if errors.Is(err, repository.ErrDocumentNotFound) {
+            continue
+        }
+        if err != nil {
+            return fmt.Errorf("failed to read document: %w", err)
+        }
+        if clock = t.Clock(doc, now); clock == nil {
+            continue
+        }
+        escalation := doc.Escalate(clock, t.cfg.Escalations)
+        if escalation == nil {
+            continue
+        }
+        if err := t.documents.Update(ctx, doc); err != nil {
+            t.logger.Warn("Failed to record SLA escalation",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+            continue
+        }
+
+        slaEscalations.WithLabelValues(doc.DocumentType, strconv.Itoa(escalation.Level)).Inc()
+        t.logger.Info("Document escalated",
+            zap.String("document_id", doc.ID),
+            zap.String("tenant_id", doc.TenantID),
+            zap.Int("level", escalation.Level),
+            zap.Time("due_at", escalation.DueAt),
+        )
+        if err := t.webhooks.Publish(ctx, models.WebhookEventDocumentSLAEscalated, doc); err != nil {
+            t.logger.Warn("Failed to publish SLA escalation to webhooks",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+        }
+    }
+    return nil
+}
+
+// Report returns the SLA compliance of each tenant over the documents that
+// came due in [from, to)
+func (t *SLATracker) Report(ctx context.Context, from, to time.Time) (*models.SLAReport, error) {
+    if from.IsZero() || !from.Before(to) {
+        return nil, ErrInvalidReportPeriod
+    }
+
+    docs, err := t.documents.ListUpdatedBetween(ctx, time.Time{}, time.Now().Add(time.Second))
+    if err != nil {
+        return nil, fmt.Errorf("failed to list documents: %w", err)
+    }
+
+    now := time.Now()
+    report := models.NewSLAReport(from, to)
+    for _, doc := range docs {
+        if clock := t.Clock(doc, now); clock != nil {
+            report.Add(doc.TenantID, clock)
+        }
+    }
+    report.Finish()
+    return report, nil
+}
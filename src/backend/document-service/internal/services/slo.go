@@ -0,0 +1,304 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// sloBuckets is the number of buckets the window of OCR outcomes is counted in
+const sloBuckets = 30
+
+var (
+    ErrNotOCRDeferred = errors.New("document OCR was not deferred")
+)
+
+// sloBucket counts the OCR outcomes of one slice of the window
+type sloBucket struct {
+    start    time.Time
+    events   int
+    failures int
+}
+
+// SLOMonitor keeps OCR within its error budget. Outcomes of OCR are counted
+// over a sliding window, and once the share that failed blows the budget the
+// service switches to accept now, OCR later: documents are stored and marked
+// ocr_deferred without running the pipeline. The provider is then tried with
+// the synthetic probe document every drain interval; after enough successes
+// in a row OCR resumes and the deferred documents are processed in batches,
+// oldest first. The mode is kept per instance, like the breaker it runs
+// ahead of
+type SLOMonitor struct {
+    cfg       config.SLOConfig
+    pipeline  *DocumentPipeline
+    documents repository.DocumentRepository
+    ocr       OCRProvider
+    logger    *zap.Logger
+
+    mu        sync.Mutex
+    buckets   [sloBuckets]sloBucket
+    degraded  *time.Time
+    successes int
+    deferred  int
+}
+
+// NewSLOMonitor creates the monitor of the OCR error budget, or returns nil
+// when it is disabled
+func NewSLOMonitor(cfg *config.Config, pipeline *DocumentPipeline, documents repository.DocumentRepository, ocr OCRProvider, logger *zap.Logger) (*SLOMonitor, error) {
+    if cfg == nil || pipeline == nil || documents == nil || ocr == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SLOConfig.Enabled {
+        return nil, nil
+    }
+
+    sloBudgetRemaining.WithLabelValues(StepOCR).Set(1)
+    return &SLOMonitor{
+        cfg:       cfg.SLOConfig,
+        pipeline:  pipeline,
+        documents: documents,
+        ocr:       ocr,
+        logger:    logger.With(zap.String("component", "slo")),
+    }, nil
+}
+
+// DeferOCR reports whether OCR is deferred. A nil *SLOMonitor never defers
+func (m *SLOMonitor) DeferOCR() bool {
+    if m == nil {
+        return false
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return m.degraded != nil
+}
+
+// RecordOCR counts the outcome of OCR on a document against the budget,
+// deferring OCR once the budget is blown. Documents OCR refused as invalid
+// say nothing of the provider and are not counted, nor is anything while OCR
+// is deferred. A nil *SLOMonitor records nothing
+func (m *SLOMonitor) RecordOCR(err error) {
+    if m == nil || errors.Is(err, ErrInvalidDocument) {
+        return
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.degraded != nil {
+        return
+    }
+
+    now := time.Now()
+    bucket := m.bucket(now)
+    bucket.events++
+    if err != nil {
+        bucket.failures++
+    }
+
+    events, failures := m.counts(now)
+    remaining := models.BudgetRemaining(m.cfg.OCRObjective, events, failures)
+    sloBudgetRemaining.WithLabelValues(StepOCR).Set(remaining)
+    if events < m.cfg.MinEvents || remaining >= 0 {
+        return
+    }
+
+    m.degraded = &now
+    m.successes = 0
+    sloDegraded.WithLabelValues(StepOCR).Set(1)
+    m.logger.Warn("OCR error budget exhausted, deferring OCR",
+        zap.Int("events", events),
+        zap.Int("failures", failures),
+        zap.Float64("objective", m.cfg.OCRObjective),
+    )
+}
+
+// Status reports the budget and the processing mode. A nil *SLOMonitor
+// reports normal processing
+func (m *SLOMonitor) Status() models.SLOStatus {
+    if m == nil {
+        return models.SLOStatus{Mode: models.ProcessingModeNormal, BudgetRemaining: 1}
+    }
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    events, failures := m.counts(time.Now())
+    status := models.SLOStatus{
+        Mode:              models.ProcessingModeNormal,
+        Objective:         m.cfg.OCRObjective,
+        WindowSeconds:     int64(m.cfg.Window.Seconds()),
+        Events:            events,
+        Failures:          failures,
+        BudgetRemaining:   models.BudgetRemaining(m.cfg.OCRObjective, events, failures),
+        DeferredDocuments: m.deferred,
+    }
+    if events > 0 {
+        status.ErrorRate = float64(failures) / float64(events)
+    }
+    if m.degraded != nil {
+        since := *m.degraded
+        status.Mode = models.ProcessingModeOCRDeferred
+        status.DegradedSince = &since
+        status.RecoverySuccesses = m.successes
+    }
+    return status
+}
+
+// Run tries the provider every drain interval while OCR is deferred, until
+// the context is cancelled. It runs on every instance, since each keeps its
+// own mode
+func (m *SLOMonitor) Run(ctx context.Context) {
+    if m == nil {
+        return
+    }
+
+    ticker := time.NewTicker(m.cfg.DrainInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if m.DeferOCR() {
+                m.Recover(ctx)
+            }
+        }
+    }
+}
+
+// Recover tries the provider once with the synthetic probe document, which
+// belongs to no one. OCR resumes, with a fresh window, after the configured
+// number of successes in a row
+func (m *SLOMonitor) Recover(ctx context.Context) error {
+    _, err := m.ocr.Recognize(ctx, "", probeDocument)
+
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if m.degraded == nil {
+        return err
+    }
+    if err != nil {
+        m.successes = 0
+        sloRecoveryTrials.WithLabelValues(StepOCR, "failed").Inc()
+        return err
+    }
+    sloRecoveryTrials.WithLabelValues(StepOCR, "succeeded").Inc()
+    if m.successes++; m.successes < m.cfg.RecoverySuccesses {
+        return nil
+    }
+
+    m.logger.Info("OCR provider recovered, resuming OCR",
+        zap.Duration("deferred_for", time.Since(*m.degraded)),
+    )
+    m.degraded = nil
+    m.successes = 0
+    m.buckets = [sloBuckets]sloBucket{}
+    sloDegraded.WithLabelValues(StepOCR).Set(0)
+    sloBudgetRemaining.WithLabelValues(StepOCR).Set(1)
+    return nil
+}
+
+// Drain processes deferred documents every drain interval until the context
+// is cancelled. It runs on one instance at a time
+func (m *SLOMonitor) Drain(ctx context.Context) {
+    if m == nil {
+        return
+    }
+
+    ticker := time.NewTicker(m.cfg.DrainInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if _, err := m.DrainBatch(ctx); err != nil {
+                m.logger.Error("Deferred OCR drain failed", zap.Error(err))
+            }
+        }
+    }
+}
+
+// DrainBatch processes up to a batch of deferred documents, oldest first,
+// unless OCR is deferred on this instance. It returns the number processed
+func (m *SLOMonitor) DrainBatch(ctx context.Context) (int, error) {
+    docs, err := m.documents.ListUpdatedBetween(ctx, time.Time{}, time.Now().Add(time.Second))
+    if err != nil {
+        return 0, fmt.Errorf("failed to list documents: %w", err)
+    }
+    deferred := make([]*models.Document, 0)
+    for _, doc := range docs {
+        if doc.Status == models.DocumentStatusOCRDeferred {
+            deferred = append(deferred, doc)
+        }
+    }
+    sort.Slice(deferred, func(i, j int) bool {
+        return deferred[i].CreatedAt.Before(deferred[j].CreatedAt)
+    })
+    m.setDeferred(len(deferred))
+
+    processed := 0
+    for _, doc := range deferred[:min(len(deferred), m.cfg.BatchSize)] {
+        if m.DeferOCR() || ctx.Err() != nil {
+            break
+        }
+        if err := CheckJobLease(ctx); err != nil {
+            return processed, err
+        }
+        if _, err := m.pipeline.ProcessDeferred(ctx, doc.ID); err != nil {
+            if errors.Is(err, ErrNotOCRDeferred) || errors.Is(err, repository.ErrDocumentNotFound) {
+                continue
+            }
+            ocrDeferrals.WithLabelValues("failed").Inc()
+            m.logger.Warn("Failed to process deferred document",
+                zap.String("document_id", doc.ID),
+                zap.Error(err),
+            )
+            continue
+        }
+        ocrDeferrals.WithLabelValues("processed").Inc()
+        processed++
+    }
+    m.setDeferred(len(deferred) - processed)
+    return processed, nil
+}
+
+// setDeferred records the number of documents waiting for OCR
+func (m *SLOMonitor) setDeferred(count int) {
+    m.mu.Lock()
+    m.deferred = count
+    m.mu.Unlock()
+    ocrDeferredDocuments.Set(float64(count))
+}
+
+// bucket returns the bucket counting outcomes at now, resetting it when it
+// last counted an earlier slice of the window
+func (m *SLOMonitor) bucket(now time.Time) *sloBucket {
+    width := max(m.cfg.Window/sloBuckets, time.Millisecond)
+    start := now.Truncate(width)
+    bucket := &m.buckets[int(start.UnixNano()/int64(width))%sloBuckets]
+    if !bucket.start.Equal(start) {
+        *bucket = sloBucket{start: start}
+    }
+    return bucket
+}
+
+// counts sums the outcomes of the buckets within the window
+func (m *SLOMonitor) counts(now time.Time) (events, failures int) {
+    for _, bucket := range m.buckets {
+        if !bucket.start.IsZero() && now.Sub(bucket.start) < m.cfg.Window {
+            events += bucket.events
+            failures += bucket.failures
+        }
+    }
+    return events, failures
+}
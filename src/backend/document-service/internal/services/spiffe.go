@@ -0,0 +1,196 @@
+package services
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+    ErrInvalidSVID     = errors.New("peer certificate is not a valid X.509 SVID")
+    ErrUnknownWorkload = errors.New("no SPIFFE policy matches the workload")
+)
+
+// svidMaterial is the SVID served to peers and the bundle theirs must chain to
+type svidMaterial struct {
+    certificate tls.Certificate
+    bundle      *x509.CertPool
+    expiresAt   time.Time
+}
+
+// WorkloadIdentity authenticates internal services by the X.509 SVIDs they
+// present over mutual TLS, as issued by SPIRE. The SVID served, its key and
+// the trust bundle are reread from the files the SPIRE agent rotates, so
+// short-lived SVIDs are picked up without a restart. The SPIFFE ID of a peer
+// is mapped to a service account role by the configured policies
+type WorkloadIdentity struct {
+    cfg    config.SPIFFEConfig
+    logger *zap.Logger
+
+    mu       sync.RWMutex
+    material *svidMaterial
+}
+
+// NewWorkloadIdentity loads the SVID and bundle, or returns nil when SPIFFE is
+// disabled
+func NewWorkloadIdentity(cfg *config.Config, logger *zap.Logger) (*WorkloadIdentity, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SPIFFEConfig.Enabled {
+        return nil, nil
+    }
+
+    identity := &WorkloadIdentity{
+        cfg:    cfg.SPIFFEConfig,
+        logger: logger.With(zap.String("component", "spiffe")),
+    }
+    if err := identity.Reload(); err != nil {
+        return nil, err
+    }
+    return identity, nil
+}
+
+// Run rereads the SVID and bundle every reload interval until ctx is done
+func (w *WorkloadIdentity) Run(ctx context.Context) {
+    ticker := time.NewTicker(w.cfg.ReloadInterval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            if err := w.Reload(); err != nil {
+                // The material last loaded keeps serving until it expires
+                w.logger.Error("Failed to reload SVID", zap.Error(err))
+            }
+        }
+    }
+}
+
+// Reload reads the SVID, its key and the trust bundle from disk
+func (w *WorkloadIdentity) Reload() error {
+    certificate, err := tls.LoadX509KeyPair(w.cfg.SVIDPath, w.cfg.SVIDKeyPath)
+    if err != nil {
+        return fmt.Errorf("failed to load SVID: %w", err)
+    }
+    leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+    if err != nil {
+        return fmt.Errorf("failed to parse SVID: %w", err)
+    }
+    if _, err := w.spiffeID(leaf); err != nil {
+        return fmt.Errorf("served certificate: %w", err)
+    }
+    certificate.Leaf = leaf
+
+    pem, err := os.ReadFile(w.cfg.BundlePath)
+    if err != nil {
+        return fmt.Errorf("failed to read trust bundle: %w", err)
+    }
+    bundle := x509.NewCertPool()
+    if !bundle.AppendCertsFromPEM(pem) {
+        return errors.New("trust bundle holds no certificates")
+    }
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    if w.material == nil || !w.material.expiresAt.Equal(leaf.NotAfter) {
+        w.logger.Info("SVID loaded",
+            zap.String("spiffe_id", leaf.URIs[0].String()),
+            zap.Time("expires_at", leaf.NotAfter),
+        )
+    }
+    w.material = &svidMaterial{certificate: certificate, bundle: bundle, expiresAt: leaf.NotAfter}
+    return nil
+}
+
+// TLSConfig returns the configuration of the mutual TLS listener. Each
+// handshake uses the SVID and bundle last loaded, and peers must present a
+// certificate chaining to the bundle
+func (w *WorkloadIdentity) TLSConfig() *tls.Config {
+    return &tls.Config{
+        MinVersion: tls.VersionTLS12,
+        GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+            w.mu.RLock()
+            material := w.material
+            w.mu.RUnlock()
+            return &tls.Config{
+                MinVersion:   tls.VersionTLS12,
+                Certificates: []tls.Certificate{material.certificate},
+                ClientAuth:   tls.RequireAndVerifyClientCert,
+                ClientCAs:    material.bundle,
+            }, nil
+        },
+    }
+}
+
+// Authenticate returns the service account of the peer that presented the
+// verified chain, named after its SPIFFE ID and holding the role of the
+// policy matching it
+func (w *WorkloadIdentity) Authenticate(peer []*x509.Certificate) (*models.ServiceAccount, error) {
+    if len(peer) == 0 {
+        serviceAccountRequests.WithLabelValues("unknown", "unauthenticated").Inc()
+        return nil, ErrInvalidSVID
+    }
+    id, err := w.spiffeID(peer[0])
+    if err != nil {
+        serviceAccountRequests.WithLabelValues("unknown", "unauthenticated").Inc()
+        return nil, err
+    }
+
+    role := w.role(id)
+    if role == "" {
+        serviceAccountRequests.WithLabelValues("unknown", "unauthenticated").Inc()
+        w.logger.Warn("Workload matches no SPIFFE policy", zap.String("spiffe_id", id))
+        return nil, fmt.Errorf("%w: %s", ErrUnknownWorkload, id)
+    }
+    return &models.ServiceAccount{Name: id, Role: role}, nil
+}
+
+// Authorize checks that the workload's role holds the permission
+func (w *WorkloadIdentity) Authorize(account *models.ServiceAccount, permission string) error {
+    return authorizeServiceAccount(account, permission, w.logger)
+}
+
+// spiffeID returns the SPIFFE ID of an X.509 SVID: its only URI SAN, in the
+// configured trust domain. SVIDs identify workloads, so CA certificates are
+// refused
+func (w *WorkloadIdentity) spiffeID(cert *x509.Certificate) (string, error) {
+    if cert.IsCA || len(cert.URIs) != 1 {
+        return "", ErrInvalidSVID
+    }
+    id := cert.URIs[0]
+    if id.Scheme != "spiffe" || id.Host != w.cfg.TrustDomain || id.Path == "" || id.RawQuery != "" || id.Fragment != "" || id.User != nil {
+        return "", fmt.Errorf("%w: %s", ErrInvalidSVID, id.Redacted())
+    }
+    return id.String(), nil
+}
+
+// role returns the role of the first policy matching the SPIFFE ID. A policy
+// ending in /* matches the IDs under it, not the prefix itself
+func (w *WorkloadIdentity) role(id string) string {
+    for _, policy := range w.cfg.Policies {
+        if prefix, ok := strings.CutSuffix(policy.ID, "*"); ok {
+            if strings.HasPrefix(id, prefix) && len(id) > len(prefix) {
+                return policy.Role
+            }
+            continue
+        }
+        if policy.ID == id {
+            return policy.Role
+        }
+    }
+    return ""
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Spool file suffixes; an entry's record is written after its content, so a
+// record always has its content
+const (
+    spoolContentSuffix = ".obj"
+    spoolRecordSuffix  = ".json"
+    spoolTempPrefix    = ".spool-"
+)
+
+var (
+    ErrSpoolFull       = errors.New("upload spool is full")
+    ErrSpoolNotFound   = errors.New("object is not in the upload spool")
+    ErrDocumentSpooled = errors.New("document content is spooled on another instance")
+)
+
+// SpoolEntry describes an object waiting in the upload spool
+type SpoolEntry struct {
+    Key         string            `json:"key"`
+    DocumentID  string            `json:"document_id"`
+    ContentType string            `json:"content_type"`
+    Metadata    map[string]string `json:"metadata,omitempty"`
+    Size        int64             `json:"size"`
+    SpooledAt   time.Time         `json:"spooled_at"`
+}
+
+// UploadSpool is a write-ahead buffer on local disk for objects that could
+// not be written to object storage. It only ever holds ciphertext, the same
+// bytes that would have been uploaded, and refuses objects once MaxBytes are
+// spooled. Entries are kept oldest first and survive restarts
+type UploadSpool struct {
+    cfg    config.SpoolConfig
+    logger *zap.Logger
+
+    mu      sync.Mutex
+    entries map[string]SpoolEntry
+    used    int64
+}
+
+// NewUploadSpool opens the spool directory and loads the entries left by an
+// earlier run, or returns nil when the spool is disabled
+func NewUploadSpool(cfg *config.Config, logger *zap.Logger) (*UploadSpool, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.SpoolConfig.Enabled {
+        return nil, nil
+    }
+
+    if err := os.MkdirAll(cfg.SpoolConfig.Directory, 0o700); err != nil {
+        return nil, fmt.Errorf("failed to create upload spool: %w", err)
+    }
+    spool := &UploadSpool{
+        cfg:     cfg.SpoolConfig,
+        logger:  logger.With(zap.String("component", "upload_spool")),
+        entries: make(map[string]SpoolEntry),
+    }
+    if err := spool.load(); err != nil {
+        return nil, err
+    }
+    spool.observe()
+    return spool, nil
+}
+
+// Put spools an object, failing with ErrSpoolFull when it does not fit
+func (s *UploadSpool) Put(entry SpoolEntry, content []byte) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entry.Size = int64(len(content))
+    previous, replaced := s.entries[entry.Key]
+    used := s.used + entry.Size
+    if replaced {
+        used -= previous.Size
+    }
+    if used > s.cfg.MaxBytes {
+        spoolOperations.WithLabelValues("full").Inc()
+        return ErrSpoolFull
+    }
+
+    record, err := json.Marshal(entry)
+    if err != nil {
+        return fmt.Errorf("failed to encode spool entry: %w", err)
+    }
+    name := spoolName(entry.Key)
+    if err := writeFileAtomic(filepath.Join(s.cfg.Directory, name+spoolContentSuffix), content); err != nil {
+        return fmt.Errorf("failed to spool object: %w", err)
+    }
+    if err := writeFileAtomic(filepath.Join(s.cfg.Directory, name+spoolRecordSuffix), record); err != nil {
+        os.Remove(filepath.Join(s.cfg.Directory, name+spoolContentSuffix))
+        return fmt.Errorf("failed to spool object: %w", err)
+    }
+
+    s.entries[entry.Key] = entry
+    s.used = used
+    spoolOperations.WithLabelValues("spooled").Inc()
+    s.observe()
+    return nil
+}
+
+// Get returns the content of a spooled object
+func (s *UploadSpool) Get(key string) ([]byte, error) {
+    s.mu.Lock()
+    _, ok := s.entries[key]
+    s.mu.Unlock()
+    if !ok {
+        return nil, ErrSpoolNotFound
+    }
+
+    content, err := os.ReadFile(filepath.Join(s.cfg.Directory, spoolName(key)+spoolContentSuffix))
+    if errors.Is(err, os.ErrNotExist) {
+        return nil, ErrSpoolNotFound
+    }
+    return content, err
+}
+
+// Entries lists the spooled objects, oldest first
+func (s *UploadSpool) Entries() []SpoolEntry {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entries := make([]SpoolEntry, 0, len(s.entries))
+    for _, entry := range s.entries {
+        entries = append(entries, entry)
+    }
+    sort.Slice(entries, func(i, j int) bool {
+        return entries[i].SpooledAt.Before(entries[j].SpooledAt)
+    })
+    return entries
+}
+
+// Remove drops an object from the spool once it reached object storage
+func (s *UploadSpool) Remove(key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    entry, ok := s.entries[key]
+    if !ok {
+        return nil
+    }
+    name := spoolName(key)
+    // The record goes first so a partial removal leaves no entry behind
+    for _, suffix := range []string{spoolRecordSuffix, spoolContentSuffix} {
+        if err := os.Remove(filepath.Join(s.cfg.Directory, name+suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+            return fmt.Errorf("failed to remove spooled object: %w", err)
+        }
+    }
+    delete(s.entries, key)
+    s.used -= entry.Size
+    s.observe()
+    return nil
+}
+
+// Usage returns the bytes and objects spooled
+func (s *UploadSpool) Usage() (int64, int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.used, len(s.entries)
+}
+
+// load reads the entries of an earlier run. Content without a record and
+// temporary files were never acknowledged and are removed
+func (s *UploadSpool) load() error {
+    files, err := os.ReadDir(s.cfg.Directory)
+    if err != nil {
+        return fmt.Errorf("failed to read upload spool: %w", err)
+    }
+
+    records := make(map[string]bool)
+    for _, file := range files {
+        name, ok := strings.CutSuffix(file.Name(), spoolRecordSuffix)
+        if !ok {
+            continue
+        }
+        data, err := os.ReadFile(filepath.Join(s.cfg.Directory, file.Name()))
+        if err != nil {
+            return fmt.Errorf("failed to read spool entry: %w", err)
+        }
+        var entry SpoolEntry
+        if err := json.Unmarshal(data, &entry); err != nil {
+            return fmt.Errorf("failed to decode spool entry %s: %w", file.Name(), err)
+        }
+        records[name] = true
+        s.entries[entry.Key] = entry
+        s.used += entry.Size
+    }
+    for _, file := range files {
+        name, content := strings.CutSuffix(file.Name(), spoolContentSuffix)
+        if (content && !records[name]) || strings.HasPrefix(file.Name(), spoolTempPrefix) {
+            os.Remove(filepath.Join(s.cfg.Directory, file.Name()))
+        }
+    }
+
+    if len(s.entries) > 0 {
+        s.logger.Info("Upload spool holds objects from an earlier run",
+            zap.Int("objects", len(s.entries)),
+            zap.Int64("bytes", s.used),
+        )
+    }
+    return nil
+}
+
+func (s *UploadSpool) observe() {
+    spoolBytes.Set(float64(s.used))
+    spoolObjects.Set(float64(len(s.entries)))
+}
+
+// SpoolDrainer writes spooled objects to object storage once it recovers
+// and clears the spooled mark of their documents
+type SpoolDrainer struct {
+    cfg       config.SpoolConfig
+    spool     *UploadSpool
+    storage   *StorageService
+    documents repository.DocumentRepository
+    logger    *zap.Logger
+}
+
+// NewSpoolDrainer creates the drainer of the spool
+func NewSpoolDrainer(cfg *config.Config, spool *UploadSpool, storage *StorageService, documents repository.DocumentRepository, logger *zap.Logger) (*SpoolDrainer, error) {
+    if cfg == nil || spool == nil || storage == nil || documents == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &SpoolDrainer{
+        cfg:       cfg.SpoolConfig,
+        spool:     spool,
+        storage:   storage,
+        documents: documents,
+        logger:    logger.With(zap.String("component", "upload_spool")),
+    }, nil
+}
+
+// Run drains the spool on the configured interval until the context is
+// cancelled
+func (d *SpoolDrainer) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.cfg.DrainInterval)
+    defer ticker.Stop()
+
+    for {
+        if drained, err := d.Drain(ctx); err != nil {
+            d.logger.Warn("Upload spool drain stopped", zap.Int("drained", drained), zap.Error(err))
+        } else if drained > 0 {
+            d.logger.Info("Upload spool drained", zap.Int("drained", drained))
+        }
+
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// Drain stores the spooled objects oldest first, stopping at the first
+// failure since storage is then likely still unavailable
+func (d *SpoolDrainer) Drain(ctx context.Context) (int, error) {
+    drained := 0
+    for _, entry := range d.spool.Entries() {
+        content, err := d.spool.Get(entry.Key)
+        if err != nil {
+            return drained, err
+        }
+        if err := d.storage.put(ctx, entry.Key, content, entry.ContentType, entry.Metadata); err != nil {
+            spoolOperations.WithLabelValues("drain_failed").Inc()
+            return drained, fmt.Errorf("failed to drain %s: %w", entry.Key, err)
+        }
+        // The object is stored before the document is unmarked, so a
+        // failure here only leaves it to be drained again
+        if err := d.unmark(ctx, entry.DocumentID); err != nil {
+            return drained, err
+        }
+        if err := d.spool.Remove(entry.Key); err != nil {
+            return drained, err
+        }
+        spoolOperations.WithLabelValues("drained").Inc()
+        drained++
+    }
+    return drained, nil
+}
+
+// unmark clears the spooled mark of a document; a document deleted while
+// spooled has nothing to clear
+func (d *SpoolDrainer) unmark(ctx context.Context, documentID string) error {
+    doc, err := d.documents.GetByID(ctx, documentID)
+    if errors.Is(err, repository.ErrDocumentNotFound) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to load spooled document %s: %w", documentID, err)
+    }
+    if !doc.Spooled() {
+        return nil
+    }
+    doc.MarkDrained(time.Now())
+    if err := d.documents.Update(ctx, doc); err != nil {
+        return fmt.Errorf("failed to persist drained document %s: %w", documentID, err)
+    }
+    return nil
+}
+
+// spoolName maps an object key, which may contain separators, to a file name
+func spoolName(key string) string {
+    sum := sha256.Sum256([]byte(key))
+    return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes through a temporary file renamed into place, so a
+// crash never leaves a partial file under the final name
+func writeFileAtomic(name string, data []byte) error {
+    tmp, err := os.CreateTemp(filepath.Dir(name), spoolTempPrefix+"*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+    return os.Rename(tmp.Name(), name)
+}
@@ -2,14 +2,21 @@
 package services
 
 import (
+    "bytes"
     "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
     "fmt"
     "io"
+    "net/url"
     "path"
+    "strconv"
+    "strings"
     "time"
 
-    "github.com/minio/minio-go/v7" // v7.0.63
-    "github.com/minio/minio-go/v7/pkg/credentials" // v7.0.63
+    "go.uber.org/zap" // v1.24.0
 
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
     "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
@@ -18,47 +25,54 @@ import (
 
 const (
     defaultStoragePrefix = "documents/"
+    renditionStoragePrefix = "renditions/"
+    exportStoragePrefix = "exports/portability/"
+    quarantineStoragePrefix = "quarantine/"
     defaultContentType  = "application/octet-stream"
     maxRetries         = 3
     retryBackoff       = 500 * time.Millisecond
 )
 
-// StorageService manages document storage operations using MinIO
+// StorageService manages document storage operations on the configured object store
 type StorageService struct {
-    client           *minio.Client
-    bucketName       string
+    store            ObjectStore
+    shadow           *ShadowStore
     config           *config.Config
     metricsCollector *metrics.Collector
     cb               *circuitbreaker.CircuitBreaker
+    limiter          *AdaptiveLimiter
+    spool            *UploadSpool
 }
 
-// NewStorageService creates a new instance of StorageService
-func NewStorageService(cfg *config.Config) (*StorageService, error) {
+// NewStorageService creates a new instance of StorageService. With storage
+// migration enabled, objects are dual-written to MinIO and the secondary bucket.
+// The buckets are checked before ctx is done
+func NewStorageService(ctx context.Context, cfg *config.Config, flags *FeatureFlags, logger *zap.Logger) (*StorageService, error) {
     if cfg == nil {
         return nil, fmt.Errorf("config cannot be nil")
     }
 
-    // Initialize MinIO client
-    client, err := minio.New(cfg.MinioConfig.Endpoint, &minio.Options{
-        Creds:  credentials.NewStaticV4(cfg.MinioConfig.AccessKey, cfg.MinioConfig.SecretKey, ""),
-        Secure: cfg.MinioConfig.UseSSL,
-    })
-    if err != nil {
-        return nil, fmt.Errorf("failed to initialize MinIO client: %w", err)
-    }
-
-    // Verify bucket exists or create it
-    ctx := context.Background()
-    exists, err := client.BucketExists(ctx, cfg.MinioConfig.BucketName)
+    var store ObjectStore
+    var shadow *ShadowStore
+    minioStore, err := NewMinioObjectStore(ctx, cfg)
     if err != nil {
-        return nil, fmt.Errorf("failed to check bucket existence: %w", err)
+        return nil, err
     }
+    store = minioStore
 
-    if !exists {
-        err = client.MakeBucket(ctx, cfg.MinioConfig.BucketName, minio.MakeBucketOptions{})
+    if cfg.StorageMigrationConfig.Enabled {
+        secondary, err := NewS3ObjectStore(ctx, cfg.StorageMigrationConfig.Secondary, cfg.MinioConfig.Transport)
         if err != nil {
-            return nil, fmt.Errorf("failed to create bucket: %w", err)
+            return nil, err
         }
+        shadow, err = NewShadowStore(cfg, minioStore, secondary, flags, logger)
+        if err != nil {
+            return nil, err
+        }
+        store = shadow
+    }
+    if cfg.MinioConfig.Hedging.Enabled {
+        store = NewHedgedStore(store, cfg.MinioConfig.Hedging)
     }
 
     // Initialize circuit breaker
@@ -70,15 +84,31 @@ func NewStorageService(cfg *config.Config) (*StorageService, error) {
     })
 
     return &StorageService{
-        client:           client,
-        bucketName:       cfg.MinioConfig.BucketName,
+        store:            store,
+        shadow:           shadow,
         config:           cfg,
         metricsCollector: metrics.NewCollector("storage_service"),
         cb:               cb,
+        limiter:          NewAdaptiveLimiter("object_storage", cfg, cfg.ConcurrencyConfig.Storage),
     }, nil
 }
 
-// StoreDocument stores an encrypted document in MinIO
+// Shadow returns the shadow store of the storage migration, or nil when no
+// migration is enabled
+func (s *StorageService) Shadow() *ShadowStore {
+    return s.shadow
+}
+
+// UseSpool buffers uploads in the spool while object storage is unavailable
+func (s *StorageService) UseSpool(spool *UploadSpool) {
+    s.spool = spool
+}
+
+// StoreDocument stores an encrypted document in MinIO, recording the digest
+// of its content. The object carries the encryption metadata as headers, so
+// the metadata can be rebuilt should the document lose it. When the upload
+// fails and a spool is configured, the ciphertext is spooled instead and the
+// document is marked spooled until the spool drains
 func (s *StorageService) StoreDocument(ctx context.Context, doc *models.Document, content io.Reader) error {
     startTime := time.Now()
     defer s.metricsCollector.ObserveOperation("store_document", startTime)
@@ -87,35 +117,47 @@ func (s *StorageService) StoreDocument(ctx context.Context, doc *models.Document
         return fmt.Errorf("failed to update document status: %w", err)
     }
 
-    // Encrypt document content
-    encryptedContent, err := utils.EncryptDocument(doc, content, s.config)
+    // Encrypt document content, hashing it on the way
+    hash := sha256.New()
+    encryptedContent, err := utils.EncryptDocument(ctx, doc, io.TeeReader(content, hash), s.config)
     if err != nil {
         doc.UpdateStatus(models.DocumentStatusFailed, fmt.Sprintf("Encryption failed: %v", err))
         return fmt.Errorf("document encryption failed: %w", err)
     }
 
+    // Retries and dual-writes upload the same ciphertext; the pooled buffer
+    // holding it is released once the upload is done
+    var ciphertext []byte
+    if pooled, ok := encryptedContent.(*utils.PooledReader); ok {
+        ciphertext = pooled.Bytes()
+        defer pooled.Close()
+    } else if ciphertext, err = io.ReadAll(encryptedContent); err != nil {
+        doc.UpdateStatus(models.DocumentStatusFailed, fmt.Sprintf("Encryption failed: %v", err))
+        return fmt.Errorf("document encryption failed: %w", err)
+    }
+
+    doc.ContentHash = hex.EncodeToString(hash.Sum(nil))
+
     // Generate storage path with sharding if enabled
     storagePath := s.generateStoragePath(doc)
-    
+    objectMetadata := documentObjectMetadata(doc)
+
     // Upload with retry logic
     var uploadErr error
     for attempt := 0; attempt < maxRetries; attempt++ {
         if attempt > 0 {
+            if utils.SpendRetry(ctx, utils.RetryLayerStorage) != nil {
+                uploadErr = utils.RetryExhausted(uploadErr)
+                break
+            }
             time.Sleep(retryBackoff << uint(attempt))
         }
 
         // Execute upload with circuit breaker
         uploadErr = s.cb.Execute(func() error {
-            _, err := s.client.PutObject(ctx, s.bucketName, storagePath, encryptedContent, -1,
-                minio.PutObjectOptions{
-                    ContentType: doc.ContentType,
-                    UserMetadata: map[string]string{
-                        "document-id":    doc.ID,
-                        "enrollment-id":  doc.EnrollmentID,
-                        "document-type": doc.DocumentType,
-                    },
-                })
-            return err
+            return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+                return s.store.Put(ctx, storagePath, ciphertext, doc.ContentType, objectMetadata)
+            })
         })
 
         if uploadErr == nil {
@@ -123,6 +165,10 @@ func (s *StorageService) StoreDocument(ctx context.Context, doc *models.Document
         }
     }
 
+    // A cancelled request is not an outage, so it is not spooled
+    if uploadErr != nil && s.spool != nil && ctx.Err() == nil {
+        return s.spoolDocument(doc, storagePath, ciphertext, objectMetadata, uploadErr)
+    }
     if uploadErr != nil {
         doc.UpdateStatus(models.DocumentStatusFailed, fmt.Sprintf("Upload failed: %v", uploadErr))
         return fmt.Errorf("failed to upload document after %d attempts: %w", maxRetries, uploadErr)
@@ -137,7 +183,32 @@ func (s *StorageService) StoreDocument(ctx context.Context, doc *models.Document
     return nil
 }
 
-// RetrieveDocument retrieves and decrypts a document from storage
+// spoolDocument spools the ciphertext of a document whose upload failed
+func (s *StorageService) spoolDocument(doc *models.Document, storagePath string, ciphertext []byte, metadata map[string]string, uploadErr error) error {
+    now := time.Now()
+    err := s.spool.Put(SpoolEntry{
+        Key:         storagePath,
+        DocumentID:  doc.ID,
+        ContentType: doc.ContentType,
+        Metadata:    metadata,
+        SpooledAt:   now,
+    }, ciphertext)
+    if err != nil {
+        doc.UpdateStatus(models.DocumentStatusFailed, fmt.Sprintf("Upload failed: %v", uploadErr))
+        return fmt.Errorf("failed to upload document after %d attempts (%v) and to spool it: %w", maxRetries, uploadErr, err)
+    }
+
+    doc.StoragePath = storagePath
+    doc.MarkSpooled(now)
+    if err := doc.UpdateStatus(models.DocumentStatusCompleted, "Document spooled until storage recovers"); err != nil {
+        return fmt.Errorf("failed to update document status: %w", err)
+    }
+    return nil
+}
+
+// RetrieveDocument retrieves and decrypts a document from storage. The returned
+// reader holds the plaintext in a pooled buffer; closing it when it implements
+// io.Closer zeroes the buffer and returns it to the pool
 func (s *StorageService) RetrieveDocument(ctx context.Context, doc *models.Document) (io.Reader, error) {
     startTime := time.Now()
     defer s.metricsCollector.ObserveOperation("retrieve_document", startTime)
@@ -146,40 +217,63 @@ func (s *StorageService) RetrieveDocument(ctx context.Context, doc *models.Docum
         return nil, fmt.Errorf("document storage path is empty")
     }
 
-    // Retrieve encrypted content with retry logic
+    // A spooled document is served from this instance's spool, or from
+    // storage if another instance drained it meanwhile
+    if doc.Spooled() && s.spool != nil {
+        if content, err := s.spool.Get(doc.StoragePath); err == nil {
+            return s.decrypt(ctx, doc, io.NopCloser(bytes.NewReader(content)))
+        }
+    }
+
+    // Retrieve encrypted content with retry logic. The object is read whole
+    // within the download timeout
     var (
-        encryptedContent io.Reader
+        encryptedContent []byte
         retrieveErr      error
     )
 
     for attempt := 0; attempt < maxRetries; attempt++ {
         if attempt > 0 {
+            if utils.SpendRetry(ctx, utils.RetryLayerStorage) != nil {
+                retrieveErr = utils.RetryExhausted(retrieveErr)
+                break
+            }
             time.Sleep(retryBackoff << uint(attempt))
         }
 
         // Execute retrieval with circuit breaker
-        var obj *minio.Object
         retrieveErr = s.cb.Execute(func() error {
-            var err error
-            obj, err = s.client.GetObject(ctx, s.bucketName, doc.StoragePath, minio.GetObjectOptions{})
-            if err != nil {
+            return s.limited(ctx, s.config.MinioConfig.DownloadTimeout, func(ctx context.Context) error {
+                obj, err := s.store.Get(ctx, doc.StoragePath)
+                if err != nil {
+                    return err
+                }
+                defer obj.Close()
+                encryptedContent, err = io.ReadAll(obj)
                 return err
-            }
-            encryptedContent = obj
-            return nil
+            })
         })
 
-        if retrieveErr == nil {
+        // A missing object will not appear on retry
+        if retrieveErr == nil || errors.Is(retrieveErr, ErrObjectNotFound) {
             break
         }
     }
 
+    if retrieveErr != nil && doc.Spooled() {
+        return nil, fmt.Errorf("%w: %v", ErrDocumentSpooled, retrieveErr)
+    }
     if retrieveErr != nil {
         return nil, fmt.Errorf("failed to retrieve document after %d attempts: %w", maxRetries, retrieveErr)
     }
 
-    // Decrypt document content
-    decryptedContent, err := utils.DecryptDocument(doc, encryptedContent, s.config)
+    return s.decrypt(ctx, doc, io.NopCloser(bytes.NewReader(encryptedContent)))
+}
+
+// decrypt decrypts and closes the encrypted content of a document
+func (s *StorageService) decrypt(ctx context.Context, doc *models.Document, encryptedContent io.ReadCloser) (io.Reader, error) {
+    decryptedContent, err := utils.DecryptDocument(ctx, doc, encryptedContent, s.config)
+    encryptedContent.Close()
     if err != nil {
         return nil, fmt.Errorf("document decryption failed: %w", err)
     }
@@ -188,6 +282,481 @@ func (s *StorageService) RetrieveDocument(ctx context.Context, doc *models.Docum
     return decryptedContent, nil
 }
 
+// StatDocument reads the metadata of a document's stored object
+func (s *StorageService) StatDocument(ctx context.Context, doc *models.Document) (ObjectInfo, error) {
+    if doc.StoragePath == "" {
+        return ObjectInfo{}, fmt.Errorf("document storage path is empty")
+    }
+
+    info, err := s.statObject(ctx, doc.StoragePath)
+    if err != nil {
+        return ObjectInfo{}, fmt.Errorf("failed to stat document: %w", err)
+    }
+    return info, nil
+}
+
+// Warm validates object storage credentials and pre-opens pooled connections
+func (s *StorageService) Warm(ctx context.Context) error {
+    warmable, ok := s.store.(warmableStore)
+    if !ok {
+        return nil
+    }
+    return warmable.Warm(ctx, s.config.StartupConfig.WarmConnections)
+}
+
+// DeleteDocument removes the stored content and renditions of a document
+func (s *StorageService) DeleteDocument(ctx context.Context, doc *models.Document) error {
+    if doc.StoragePath == "" {
+        return fmt.Errorf("document storage path is empty")
+    }
+
+    for _, rendition := range doc.Renditions {
+        if err := s.delete(ctx, rendition.StoragePath); err != nil {
+            return fmt.Errorf("failed to delete rendition %s: %w", rendition.Name, err)
+        }
+    }
+    if err := s.delete(ctx, doc.StoragePath); err != nil {
+        return fmt.Errorf("failed to delete document: %w", err)
+    }
+    return nil
+}
+
+func (s *StorageService) delete(ctx context.Context, key string) error {
+    return s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+            return s.store.Delete(ctx, key)
+        })
+    })
+}
+
+// StoreRendition stores an unencrypted rendition of a document and records it
+// on the document
+func (s *StorageService) StoreRendition(ctx context.Context, doc *models.Document, name, contentType string, content []byte) error {
+    return s.putRendition(ctx, doc, models.Rendition{
+        Name:        name,
+        ContentType: contentType,
+        Size:        int64(len(content)),
+    }, content)
+}
+
+// StoreEncryptedRendition stores a rendition holding personal data, encrypted
+// in chunks so it can be streamed by range, and records it on the document
+func (s *StorageService) StoreEncryptedRendition(ctx context.Context, doc *models.Document, name, contentType string, content []byte) error {
+    ciphertext, encryption, err := utils.EncryptStream(ctx, doc.ID, content, doc.EncryptionInfo, s.config)
+    if err != nil {
+        return fmt.Errorf("rendition encryption failed: %w", err)
+    }
+    defer ciphertext.Close()
+
+    return s.putRendition(ctx, doc, models.Rendition{
+        Name:        name,
+        ContentType: contentType,
+        Size:        int64(len(content)),
+        Encryption:  encryption,
+    }, ciphertext.Bytes())
+}
+
+func (s *StorageService) putRendition(ctx context.Context, doc *models.Document, rendition models.Rendition, content []byte) error {
+    startTime := time.Now()
+    defer s.metricsCollector.ObserveOperation("store_rendition", startTime)
+
+    rendition.StoragePath = path.Join(renditionStoragePrefix, doc.ID, rendition.Name)
+    err := s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+            return s.store.Put(ctx, rendition.StoragePath, content, rendition.ContentType, map[string]string{
+                "document-id": doc.ID,
+                "rendition":   rendition.Name,
+            })
+        })
+    })
+    if err != nil {
+        return fmt.Errorf("failed to store rendition %s: %w", rendition.Name, err)
+    }
+
+    rendition.CreatedAt = time.Now()
+    doc.SetRendition(rendition)
+    return nil
+}
+
+// OpenRendition opens a rendition of a document for streaming with range
+// support. Encrypted renditions are decrypted chunk by chunk as they are read
+func (s *StorageService) OpenRendition(ctx context.Context, documentID string, rendition models.Rendition) (io.ReadSeekCloser, ObjectInfo, error) {
+    var (
+        reader io.ReadSeekCloser
+        info   ObjectInfo
+    )
+    err := s.cb.Execute(func() error {
+        return s.limited(ctx, 0, func(ctx context.Context) error {
+            var err error
+            reader, info, err = openObject(ctx, s.store, rendition.StoragePath)
+            return err
+        })
+    })
+    if err != nil {
+        return nil, ObjectInfo{}, fmt.Errorf("failed to open rendition %s: %w", rendition.Name, err)
+    }
+    if rendition.Encryption == nil {
+        return reader, info, nil
+    }
+
+    plaintext, err := utils.NewStreamDecrypter(ctx, documentID, reader, rendition.Size, rendition.Encryption, s.config)
+    if err != nil {
+        reader.Close()
+        return nil, ObjectInfo{}, fmt.Errorf("failed to decrypt rendition %s: %w", rendition.Name, err)
+    }
+    info.Size = rendition.Size
+    return decryptedObject{ReadSeeker: plaintext, Closer: reader}, info, nil
+}
+
+// decryptedObject reads plaintext while closing the underlying object
+type decryptedObject struct {
+    io.ReadSeeker
+    io.Closer
+}
+
+// RenditionURL returns a presigned URL downloading the rendition directly from
+// the bucket, or ErrPresignUnsupported when presigned redirects are disabled
+// or the rendition is encrypted
+func (s *StorageService) RenditionURL(ctx context.Context, rendition models.Rendition) (*url.URL, error) {
+    if !s.config.RenditionsConfig.PresignedRedirects || rendition.Encryption != nil {
+        return nil, ErrPresignUnsupported
+    }
+    return presignObject(ctx, s.store, rendition.StoragePath, s.config.RenditionsConfig.PresignExpiry)
+}
+
+// StoreExport encrypts and stores a portability export archive together with
+// its record, which holds the encryption metadata needed to serve it
+func (s *StorageService) StoreExport(ctx context.Context, export *models.PortabilityExport, archive []byte) error {
+    startTime := time.Now()
+    defer s.metricsCollector.ObserveOperation("store_export", startTime)
+
+    ciphertext, encryption, err := utils.EncryptStream(ctx, "", archive, nil, s.config)
+    if err != nil {
+        return fmt.Errorf("export encryption failed: %w", err)
+    }
+    defer ciphertext.Close()
+
+    export.StoragePath = path.Join(exportStoragePrefix, export.ID+".zip")
+    export.Size = int64(len(archive))
+    export.Encryption = encryption
+    record, err := json.Marshal(export)
+    if err != nil {
+        return fmt.Errorf("failed to encode export record: %w", err)
+    }
+
+    return s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+            if err := s.store.Put(ctx, export.StoragePath, ciphertext.Bytes(), defaultContentType, map[string]string{"export-id": export.ID}); err != nil {
+                return fmt.Errorf("failed to store export archive: %w", err)
+            }
+            // The record is written last so a visible record always has its archive
+            return s.store.Put(ctx, path.Join(exportStoragePrefix, export.ID+".json"), record, "application/json", nil)
+        })
+    })
+}
+
+// LoadExport reads the record of a portability export
+func (s *StorageService) LoadExport(ctx context.Context, id string) (*models.PortabilityExport, error) {
+    var record bytes.Buffer
+    err := s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.DownloadTimeout, func(ctx context.Context) error {
+            obj, err := s.store.Get(ctx, path.Join(exportStoragePrefix, id+".json"))
+            if err != nil {
+                return err
+            }
+            defer obj.Close()
+            _, err = record.ReadFrom(obj)
+            return err
+        })
+    })
+    if err != nil {
+        return nil, fmt.Errorf("failed to load export %s: %w", id, err)
+    }
+
+    var export models.PortabilityExport
+    if err := json.Unmarshal(record.Bytes(), &export); err != nil {
+        return nil, fmt.Errorf("failed to decode export %s: %w", id, err)
+    }
+    return &export, nil
+}
+
+// OpenExport opens a portability export archive for streaming, decrypting it
+// chunk by chunk as it is read
+func (s *StorageService) OpenExport(ctx context.Context, export *models.PortabilityExport) (io.ReadSeekCloser, ObjectInfo, error) {
+    return s.OpenRendition(ctx, "", models.Rendition{
+        Name:        "portability_export",
+        StoragePath: export.StoragePath,
+        ContentType: "application/zip",
+        Size:        export.Size,
+        Encryption:  export.Encryption,
+    })
+}
+
+// StoreQuarantined encrypts and stores an upload held in quarantine without
+// a document, recording where and how on the item
+func (s *StorageService) StoreQuarantined(ctx context.Context, item *models.QuarantineItem, content []byte) error {
+    startTime := time.Now()
+    defer s.metricsCollector.ObserveOperation("store_quarantined", startTime)
+
+    ciphertext, encryption, err := utils.EncryptStream(ctx, "", content, nil, s.config)
+    if err != nil {
+        return fmt.Errorf("quarantined upload encryption failed: %w", err)
+    }
+    defer ciphertext.Close()
+
+    item.StoragePath = path.Join(quarantineStoragePrefix, item.ID)
+    item.Size = int64(len(content))
+    item.Encryption = encryption
+    return s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+            return s.store.Put(ctx, item.StoragePath, ciphertext.Bytes(), defaultContentType, map[string]string{"quarantine-id": item.ID})
+        })
+    })
+}
+
+// OpenQuarantined opens an upload held in quarantine, decrypting it chunk by
+// chunk as it is read
+func (s *StorageService) OpenQuarantined(ctx context.Context, item *models.QuarantineItem) (io.ReadSeekCloser, ObjectInfo, error) {
+    return s.OpenRendition(ctx, "", models.Rendition{
+        Name:        "quarantined_upload",
+        StoragePath: item.StoragePath,
+        ContentType: item.ContentType,
+        Size:        item.Size,
+        Encryption:  item.Encryption,
+    })
+}
+
+// DeleteQuarantined deletes an upload held in quarantine
+func (s *StorageService) DeleteQuarantined(ctx context.Context, item *models.QuarantineItem) error {
+    if err := s.delete(ctx, item.StoragePath); err != nil && !errors.Is(err, ErrObjectNotFound) {
+        return fmt.Errorf("failed to delete quarantined upload: %w", err)
+    }
+    return nil
+}
+
+// ReencryptDocument re-encrypts the original and the encrypted renditions of a
+// document under the current key. Content is recovered from ciphertext that
+// still authenticates or from objects stored in the clear, and written to new
+// keys; persist stores the updated document before the superseded objects are
+// deleted, so a failure at any point leaves the document readable
+func (s *StorageService) ReencryptDocument(ctx context.Context, doc *models.Document, persist func(context.Context, *models.Document) error) error {
+    startTime := time.Now()
+    defer s.metricsCollector.ObserveOperation("reencrypt_document", startTime)
+
+    if doc.StoragePath == "" {
+        return fmt.Errorf("document storage path is empty")
+    }
+
+    updated := *doc
+    updated.Renditions = append([]models.Rendition(nil), doc.Renditions...)
+    updated.AuditTrail = append([]models.AuditLog(nil), doc.AuditTrail...)
+    suffix := "." + strconv.FormatInt(startTime.Unix(), 10)
+
+    var written, superseded []string
+    discard := func() {
+        for _, key := range written {
+            s.delete(ctx, key)
+        }
+    }
+
+    plaintext, err := s.recoverPlaintext(ctx, doc.ID, doc.StoragePath, doc.Size, doc.EncryptionInfo)
+    if err != nil {
+        return fmt.Errorf("failed to recover document content: %w", err)
+    }
+    encrypted, err := utils.EncryptDocument(ctx, &updated, bytes.NewReader(plaintext), s.config)
+    utils.PutBuffer(plaintext)
+    if err != nil {
+        return fmt.Errorf("document encryption failed: %w", err)
+    }
+    ciphertext := encrypted.(*utils.PooledReader)
+    key := s.generateStoragePath(doc) + suffix
+    err = s.put(ctx, key, ciphertext.Bytes(), doc.ContentType, documentObjectMetadata(&updated))
+    ciphertext.Close()
+    if err != nil {
+        return fmt.Errorf("failed to store re-encrypted document: %w", err)
+    }
+    written = append(written, key)
+    superseded = append(superseded, doc.StoragePath)
+    updated.StoragePath = key
+
+    for i, rendition := range updated.Renditions {
+        if rendition.Encryption == nil && !models.IsTextRendition(rendition.Name) {
+            continue
+        }
+
+        plaintext, err := s.recoverPlaintext(ctx, doc.ID, rendition.StoragePath, rendition.Size, rendition.Encryption)
+        if err != nil {
+            discard()
+            return fmt.Errorf("failed to recover rendition %s: %w", rendition.Name, err)
+        }
+        ciphertext, encryption, err := utils.EncryptStream(ctx, doc.ID, plaintext, updated.EncryptionInfo, s.config)
+        utils.PutBuffer(plaintext)
+        if err != nil {
+            discard()
+            return fmt.Errorf("rendition encryption failed: %w", err)
+        }
+        key := path.Join(renditionStoragePrefix, doc.ID, rendition.Name) + suffix
+        err = s.put(ctx, key, ciphertext.Bytes(), rendition.ContentType, map[string]string{
+            "document-id": doc.ID,
+            "rendition":   rendition.Name,
+        })
+        ciphertext.Close()
+        if err != nil {
+            discard()
+            return fmt.Errorf("failed to store re-encrypted rendition %s: %w", rendition.Name, err)
+        }
+        written = append(written, key)
+        superseded = append(superseded, rendition.StoragePath)
+        updated.Renditions[i].StoragePath = key
+        updated.Renditions[i].Encryption = encryption
+    }
+
+    updated.RecordReencryption()
+    if err := persist(ctx, &updated); err != nil {
+        discard()
+        return fmt.Errorf("failed to persist re-encrypted document: %w", err)
+    }
+    previous := doc.EncryptionInfo
+    *doc = updated
+
+    for _, key := range superseded {
+        if err := s.delete(ctx, key); err != nil && !errors.Is(err, ErrObjectNotFound) {
+            return fmt.Errorf("document re-encrypted but superseded object %s was not deleted: %w", key, err)
+        }
+    }
+    // A superseded data key of the document's own is no longer needed
+    if previous != nil && previous.WrappedKey != "" && previous.GrantID != "" {
+        if err := utils.ShredDataKey(ctx, s.config, previous); err != nil {
+            return fmt.Errorf("document re-encrypted but superseded data key was not shredded: %w", err)
+        }
+    }
+    return nil
+}
+
+// LocateDocument finds the object holding the content of a document that
+// lost its storage path: the object under the key the document is stored
+// at, with or without sharding, labelled with the document's ID
+func (s *StorageService) LocateDocument(ctx context.Context, doc *models.Document) (string, ObjectInfo, error) {
+    keys := []string{path.Join(defaultStoragePrefix, doc.ID)}
+    if len(doc.EnrollmentID) >= 2 {
+        keys = append(keys, path.Join(defaultStoragePrefix, doc.EnrollmentID[:2], doc.ID))
+    }
+    for _, key := range keys {
+        info, err := s.statObject(ctx, key)
+        if errors.Is(err, ErrObjectNotFound) {
+            continue
+        }
+        if err != nil {
+            return "", ObjectInfo{}, err
+        }
+        if objectHeader(info.Metadata, "document-id") == doc.ID {
+            return key, info, nil
+        }
+    }
+    return "", ObjectInfo{}, ErrObjectNotFound
+}
+
+// statObject reads the metadata of a stored object
+func (s *StorageService) statObject(ctx context.Context, key string) (ObjectInfo, error) {
+    var info ObjectInfo
+    err := s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.DownloadTimeout, func(ctx context.Context) error {
+            var err error
+            info, err = s.store.Stat(ctx, key)
+            return err
+        })
+    })
+    return info, err
+}
+
+// documentObjectMetadata returns the headers of the object holding the
+// content of a document
+func documentObjectMetadata(doc *models.Document) map[string]string {
+    metadata := map[string]string{
+        "document-id":   doc.ID,
+        "enrollment-id": doc.EnrollmentID,
+        "document-type": doc.DocumentType,
+    }
+    for name, value := range models.EncryptionHeaders(doc.EncryptionInfo) {
+        metadata[name] = value
+    }
+    return metadata
+}
+
+// objectHeader returns a header of a stored object, which stores may return
+// in any case
+func objectHeader(metadata map[string]string, name string) string {
+    for key, value := range metadata {
+        if strings.EqualFold(key, name) {
+            return value
+        }
+    }
+    return ""
+}
+
+// readObject reads a whole stored object without decrypting it
+func (s *StorageService) readObject(ctx context.Context, key string) ([]byte, error) {
+    var content []byte
+    err := s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.DownloadTimeout, func(ctx context.Context) error {
+            obj, err := s.store.Get(ctx, key)
+            if err != nil {
+                return err
+            }
+            defer obj.Close()
+            content, err = io.ReadAll(obj)
+            return err
+        })
+    })
+    return content, err
+}
+
+// recoverPlaintext returns the content of a stored object: the decrypted
+// ciphertext when it authenticates, or the object itself when it was stored in
+// the clear. The plaintext is pooled; release it with utils.PutBuffer
+func (s *StorageService) recoverPlaintext(ctx context.Context, documentID, key string, size int64, metadata *models.EncryptionMetadata) ([]byte, error) {
+    raw, err := s.readObject(ctx, key)
+    if err != nil {
+        return nil, err
+    }
+
+    if metadata != nil {
+        plaintext, err := utils.OpenCiphertext(ctx, documentID, raw, size, metadata, s.config)
+        if err == nil {
+            return plaintext, nil
+        }
+        if !storedInClear(raw, size) {
+            return nil, fmt.Errorf("%w: %v", ErrNotReencryptable, err)
+        }
+    } else if !storedInClear(raw, size) {
+        return nil, ErrNotReencryptable
+    }
+    return append(utils.GetBuffer(len(raw)), raw...), nil
+}
+
+func (s *StorageService) put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+    return s.cb.Execute(func() error {
+        return s.limited(ctx, s.config.MinioConfig.UploadTimeout, func(ctx context.Context) error {
+            return s.store.Put(ctx, key, content, contentType, metadata)
+        })
+    })
+}
+
+// limited runs an object store call under the adaptive concurrency limit,
+// bounded by timeout within the deadline of ctx. Calls returning a stream read
+// after they return pass no timeout
+func (s *StorageService) limited(ctx context.Context, timeout time.Duration, call func(ctx context.Context) error) error {
+    return utils.CallWithDeadline(ctx, config.DependencyStorage, timeout, func(ctx context.Context) error {
+        release, err := s.limiter.Acquire(ctx)
+        if err != nil {
+            return err
+        }
+        err = call(ctx)
+        release(err)
+        return err
+    })
+}
+
 // generateStoragePath generates a storage path for the document with optional sharding
 func (s *StorageService) generateStoragePath(doc *models.Document) string {
     if s.config.MinioConfig.EnableSharding {
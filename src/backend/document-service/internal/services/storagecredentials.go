@@ -0,0 +1,217 @@
+package services
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/minio/minio-go/v7" // v7.0.63
+    "github.com/minio/minio-go/v7/pkg/credentials" // v7.0.63
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// credentialRotationCodes are the S3 error codes of requests signed with
+// credentials that expired or were revoked while being rotated. Responses to
+// HEAD requests carry no body, so those surface as AccessDenied
+var credentialRotationCodes = map[string]bool{
+    "AccessDenied":          true,
+    "ExpiredToken":          true,
+    "InvalidAccessKeyId":    true,
+    "InvalidToken":          true,
+    "SignatureDoesNotMatch": true,
+}
+
+// newObjectStoreCredentials returns the credentials of a store, and for
+// credentials that rotate the means of refreshing them early. Rotating
+// credentials are otherwise refreshed by the client ahead of their expiry
+func newObjectStoreCredentials(backend string, cfg config.ObjectStoreCredentialsConfig, accessKey, secretKey string, transport config.HTTPTransportConfig) (*credentials.Credentials, *credentialRotation, error) {
+    if cfg.Provider == "" || cfg.Provider == config.CredentialsStatic {
+        return credentials.NewStaticV4(accessKey, secretKey, ""), nil, nil
+    }
+
+    client := &http.Client{
+        Transport: NewHTTPTransport(backend+"_credentials", transport),
+        Timeout:   cfg.Timeout,
+    }
+    var provider credentials.Provider
+    switch cfg.Provider {
+    case config.CredentialsSTS:
+        provider = &credentials.STSAssumeRole{
+            Client:      client,
+            STSEndpoint: cfg.STSEndpoint,
+            Options: credentials.STSAssumeRoleOptions{
+                AccessKey:       accessKey,
+                SecretKey:       secretKey,
+                RoleARN:         cfg.RoleARN,
+                RoleSessionName: "document-service",
+                DurationSeconds: int(cfg.Duration.Seconds()),
+            },
+        }
+    case config.CredentialsIAM:
+        provider = &credentials.IAM{Client: client, Endpoint: cfg.IAMEndpoint}
+    case config.CredentialsVault:
+        provider = &vaultCredentials{
+            client:    client,
+            address:   strings.TrimSuffix(cfg.VaultAddress, "/"),
+            path:      strings.Trim(cfg.VaultPath, "/"),
+            tokenPath: cfg.VaultTokenPath,
+        }
+    default:
+        return nil, nil, fmt.Errorf("unsupported credentials provider: %s", cfg.Provider)
+    }
+
+    creds := credentials.New(&refreshingCredentials{
+        provider:      provider,
+        backend:       backend,
+        retryInterval: cfg.RetryInterval,
+    })
+    return creds, &credentialRotation{creds: creds, interval: cfg.RetryInterval}, nil
+}
+
+// credentialRotation refreshes rotating credentials a store rejected. Requests
+// in flight when credentials rotate are all rejected at once, so the refresh
+// is forced once per interval and the others retry with its result
+type credentialRotation struct {
+    creds    *credentials.Credentials
+    interval time.Duration
+
+    mu       sync.Mutex
+    forcedAt time.Time
+}
+
+// refresh expires the credentials unless they were refreshed this interval
+func (r *credentialRotation) refresh() {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    if time.Since(r.forcedAt) < r.interval {
+        return
+    }
+    r.forcedAt = time.Now()
+    r.creds.Expire()
+}
+
+// refreshingCredentials keeps serving the last credentials retrieved when a
+// refresh fails. Providers refresh well ahead of expiry, so the failed
+// refresh is retried every retry interval while the credentials still hold
+type refreshingCredentials struct {
+    provider      credentials.Provider
+    backend       string
+    retryInterval time.Duration
+
+    last    credentials.Value
+    retryAt time.Time
+}
+
+// Retrieve refreshes the credentials; the client serializes calls
+func (r *refreshingCredentials) Retrieve() (credentials.Value, error) {
+    value, err := r.provider.Retrieve()
+    if err != nil {
+        storageCredentialRefreshes.WithLabelValues(r.backend, "failed").Inc()
+        if r.last.AccessKeyID == "" {
+            return credentials.Value{}, fmt.Errorf("failed to retrieve %s credentials: %w", r.backend, err)
+        }
+        r.retryAt = time.Now().Add(r.retryInterval)
+        return r.last, nil
+    }
+
+    storageCredentialRefreshes.WithLabelValues(r.backend, "refreshed").Inc()
+    r.last = value
+    r.retryAt = time.Time{}
+    return value, nil
+}
+
+// IsExpired reports whether the credentials are due for a refresh
+func (r *refreshingCredentials) IsExpired() bool {
+    if !r.retryAt.IsZero() {
+        return time.Now().After(r.retryAt)
+    }
+    return r.provider.IsExpired()
+}
+
+// vaultCredentials reads dynamic object store credentials from a Vault
+// secrets engine, such as aws/creds/<role>. The Vault token is reread on
+// every refresh, so tokens renewed by the Vault agent are picked up
+type vaultCredentials struct {
+    expiry    credentials.Expiry
+    client    *http.Client
+    address   string
+    path      string
+    tokenPath string
+}
+
+// vaultSecret is the response of a Vault dynamic secret
+type vaultSecret struct {
+    LeaseDuration int `json:"lease_duration"`
+    Data          struct {
+        AccessKey     string `json:"access_key"`
+        SecretKey     string `json:"secret_key"`
+        SecurityToken string `json:"security_token"`
+    } `json:"data"`
+}
+
+// Retrieve leases new credentials from Vault
+func (v *vaultCredentials) Retrieve() (credentials.Value, error) {
+    token, err := os.ReadFile(v.tokenPath)
+    if err != nil {
+        return credentials.Value{}, fmt.Errorf("failed to read vault token: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, v.address+"/v1/"+v.path, nil)
+    if err != nil {
+        return credentials.Value{}, err
+    }
+    req.Header.Set("X-Vault-Token", strings.TrimSpace(string(token)))
+
+    resp, err := v.client.Do(req)
+    if err != nil {
+        return credentials.Value{}, fmt.Errorf("vault request failed: %w", err)
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+        return credentials.Value{}, fmt.Errorf("vault returned status %d", resp.StatusCode)
+    }
+
+    var secret vaultSecret
+    if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&secret); err != nil {
+        return credentials.Value{}, fmt.Errorf("failed to decode vault secret: %w", err)
+    }
+    if secret.Data.AccessKey == "" || secret.Data.SecretKey == "" || secret.LeaseDuration <= 0 {
+        return credentials.Value{}, errors.New("vault secret holds no leased credentials")
+    }
+
+    v.expiry.SetExpiration(time.Now().Add(time.Duration(secret.LeaseDuration)*time.Second), credentials.DefaultExpiryWindow)
+    return credentials.Value{
+        AccessKeyID:     secret.Data.AccessKey,
+        SecretAccessKey: secret.Data.SecretKey,
+        SessionToken:    secret.Data.SecurityToken,
+        SignerType:      credentials.SignatureV4,
+    }, nil
+}
+
+// IsExpired reports whether the lease is due for renewal
+func (v *vaultCredentials) IsExpired() bool {
+    return v.expiry.IsExpired()
+}
+
+// withCredentials runs an object store call. A call rejected because its
+// credentials were rotated out from under it is retried once with freshly
+// retrieved credentials
+func (s *MinioObjectStore) withCredentials(call func() error) error {
+    err := call()
+    if err == nil || s.rotation == nil || !credentialRotationCodes[minio.ToErrorResponse(err).Code] {
+        return err
+    }
+
+    storageCredentialRefreshes.WithLabelValues(s.name, "rejected").Inc()
+    s.rotation.refresh()
+    return call()
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "go.temporal.io/sdk/activity" // v1.25.1
+    "go.temporal.io/sdk/client" // v1.25.1
+    "go.temporal.io/sdk/temporal" // v1.25.1
+    "go.temporal.io/sdk/worker" // v1.25.1
+    "go.temporal.io/sdk/workflow" // v1.25.1
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// Temporal workflow and activity names; they are part of the history of
+// running workflows and must not change
+const (
+    DocumentWorkflowName    = "DocumentPipeline"
+    RunStepActivityName     = "RunPipelineStep"
+    FinishActivityName      = "FinishDocumentPipeline"
+    ConsentRevokedErrorType = "ConsentRevoked"
+)
+
+// DocumentWorkflowInput is the input of the document pipeline workflow. The
+// steps and retry policy travel with it, so a workflow replays the same way
+// after the configuration changes
+type DocumentWorkflowInput struct {
+    DocumentID           string
+    Steps                []string
+    StepTimeout          time.Duration
+    RetryInitialInterval time.Duration
+    RetryMaxInterval     time.Duration
+    MaxAttempts          int32
+}
+
+// DocumentPipelineWorkflow runs each pipeline step of a document as an
+// activity retried by Temporal, then finishes the document. A step that
+// exhausts its attempts is skipped as in the internal pipeline; a consent
+// revocation stops the remaining steps
+func DocumentPipelineWorkflow(ctx workflow.Context, input DocumentWorkflowInput) error {
+    logger := workflow.GetLogger(ctx)
+    ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+        StartToCloseTimeout: input.StepTimeout,
+        RetryPolicy: &temporal.RetryPolicy{
+            InitialInterval:        input.RetryInitialInterval,
+            BackoffCoefficient:     2,
+            MaximumInterval:        input.RetryMaxInterval,
+            MaximumAttempts:        input.MaxAttempts,
+            NonRetryableErrorTypes: []string{ConsentRevokedErrorType},
+        },
+    })
+
+    for _, step := range input.Steps {
+        err := workflow.ExecuteActivity(ctx, RunStepActivityName, input.DocumentID, step).Get(ctx, nil)
+        if err == nil {
+            continue
+        }
+        var applicationErr *temporal.ApplicationError
+        if errors.As(err, &applicationErr) && applicationErr.Type() == ConsentRevokedErrorType {
+            logger.Info("Pipeline halted by consent revocation", "document_id", input.DocumentID, "step", step)
+            break
+        }
+        logger.Warn("Pipeline step failed", "document_id", input.DocumentID, "step", step, "error", err)
+    }
+
+    return workflow.ExecuteActivity(ctx, FinishActivityName, input.DocumentID).Get(ctx, nil)
+}
+
+// pipelineActivities exposes the pipeline to Temporal workers
+type pipelineActivities struct {
+    pipeline *DocumentPipeline
+}
+
+// RunStep runs one step; failures that a retry cannot fix are not retried
+func (a *pipelineActivities) RunStep(ctx context.Context, documentID, step string) error {
+    err := a.pipeline.RunStep(ctx, documentID, step)
+    switch {
+    case errors.Is(err, ErrConsentRevoked):
+        return temporal.NewNonRetryableApplicationError(err.Error(), ConsentRevokedErrorType, err)
+    case errors.Is(err, ErrUnknownStep), errors.Is(err, repository.ErrDocumentNotFound):
+        return temporal.NewNonRetryableApplicationError(err.Error(), "", err)
+    }
+    return err
+}
+
+// Finish completes the processing of a document
+func (a *pipelineActivities) Finish(ctx context.Context, documentID string) error {
+    return a.pipeline.Finish(ctx, documentID)
+}
+
+// TemporalOrchestrator runs the document pipeline on Temporal. Every step is
+// an activity with Temporal's retries, and each document is a workflow
+// visible in the Temporal UI under the ID document-<id>. The orchestrator
+// also hosts a worker, so every replica processes workflow tasks. With
+// processing tiers, each tier has a task queue and a worker of its own
+type TemporalOrchestrator struct {
+    client  client.Client
+    workers []worker.Worker
+    cfg     config.TemporalConfig
+    tiers   map[string]config.ProcessingTierConfig
+    steps   []string
+    logger  *zap.Logger
+}
+
+// NewTemporalOrchestrator connects to Temporal and registers the pipeline
+// workflow and activities on the configured task queue
+func NewTemporalOrchestrator(cfg *config.Config, pipeline *DocumentPipeline, logger *zap.Logger) (*TemporalOrchestrator, error) {
+    if cfg == nil || pipeline == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    temporalCfg := cfg.OrchestrationConfig.Temporal
+    temporalClient, err := client.Dial(client.Options{
+        HostPort:  temporalCfg.HostPort,
+        Namespace: temporalCfg.Namespace,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    orchestrator := &TemporalOrchestrator{
+        client: temporalClient,
+        cfg:    temporalCfg,
+        tiers:  make(map[string]config.ProcessingTierConfig),
+        steps:  pipeline.StepNames(),
+        logger: logger,
+    }
+    activities := &pipelineActivities{pipeline: pipeline}
+    // The untiered queue keeps serving workflows started before tiers were
+    // enabled
+    orchestrator.addWorker(temporalCfg.TaskQueue, temporalCfg.MaxConcurrentSteps, activities)
+    if cfg.ProcessingTiersConfig.Enabled {
+        for _, tier := range cfg.ProcessingTiersConfig.Tiers {
+            orchestrator.tiers[tier.Name] = tier
+            orchestrator.addWorker(orchestrator.taskQueue(tier.Name), tier.Concurrency, activities)
+        }
+    }
+    return orchestrator, nil
+}
+
+// addWorker registers the pipeline workflow and activities on a task queue
+func (o *TemporalOrchestrator) addWorker(taskQueue string, maxConcurrentSteps int, activities *pipelineActivities) {
+    temporalWorker := worker.New(o.client, taskQueue, worker.Options{
+        MaxConcurrentActivityExecutionSize: maxConcurrentSteps,
+    })
+    temporalWorker.RegisterWorkflowWithOptions(DocumentPipelineWorkflow, workflow.RegisterOptions{Name: DocumentWorkflowName})
+    temporalWorker.RegisterActivityWithOptions(activities.RunStep, activity.RegisterOptions{Name: RunStepActivityName})
+    temporalWorker.RegisterActivityWithOptions(activities.Finish, activity.RegisterOptions{Name: FinishActivityName})
+    o.workers = append(o.workers, temporalWorker)
+}
+
+// taskQueue returns the task queue of a processing tier, or the configured
+// one for untiered documents
+func (o *TemporalOrchestrator) taskQueue(tier string) string {
+    if tier == "" {
+        return o.cfg.TaskQueue
+    }
+    return o.cfg.TaskQueue + "-" + tier
+}
+
+// Start submits the pipeline workflow of a document to the task queue of its
+// tier, with the tier's timeout for each step. The memo carries only
+// identifiers, so the Temporal UI shows no personal data
+func (o *TemporalOrchestrator) Start(ctx context.Context, doc *models.Document) error {
+    stepTimeout := o.cfg.StepTimeout
+    tier, ok := o.tiers[doc.ProcessingTier]
+    if ok {
+        stepTimeout = tier.Timeout
+    }
+    options := client.StartWorkflowOptions{
+        ID:                       "document-" + doc.ID,
+        TaskQueue:                o.taskQueue(tier.Name),
+        WorkflowExecutionTimeout: o.cfg.WorkflowTimeout,
+        Memo: map[string]interface{}{
+            "enrollment_id": doc.EnrollmentID,
+            "document_type": doc.DocumentType,
+            "channel":       doc.IngestionChannel,
+        },
+    }
+    run, err := o.client.ExecuteWorkflow(ctx, options, DocumentWorkflowName, DocumentWorkflowInput{
+        DocumentID:           doc.ID,
+        Steps:                o.steps,
+        StepTimeout:          stepTimeout,
+        RetryInitialInterval: o.cfg.RetryInitialInterval,
+        RetryMaxInterval:     o.cfg.RetryMaxInterval,
+        MaxAttempts:          int32(o.cfg.MaxAttempts),
+    })
+    if err != nil {
+        pipelineWorkflowsStarted.WithLabelValues("error").Inc()
+        return fmt.Errorf("failed to start document workflow: %w", err)
+    }
+
+    pipelineWorkflowsStarted.WithLabelValues("started").Inc()
+    o.logger.Info("Document workflow started",
+        zap.String("document_id", doc.ID),
+        zap.String("workflow_id", run.GetID()),
+        zap.String("run_id", run.GetRunID()),
+    )
+    return nil
+}
+
+// Run processes workflow and activity tasks until ctx is cancelled
+func (o *TemporalOrchestrator) Run(ctx context.Context) {
+    defer o.client.Close()
+
+    for i, temporalWorker := range o.workers {
+        if err := temporalWorker.Start(); err != nil {
+            o.logger.Error("Failed to start Temporal worker", zap.Error(err))
+            for _, started := range o.workers[:i] {
+                started.Stop()
+            }
+            return
+        }
+    }
+    <-ctx.Done()
+    for _, temporalWorker := range o.workers {
+        temporalWorker.Stop()
+    }
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+    "context"
+    "sync"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Tenant label values used in place of a tenant ID
+const (
+    // TenantLabelNone labels requests and documents without a tenant
+    TenantLabelNone = "none"
+    // TenantLabelOther aggregates the tenants too small to be labelled
+    TenantLabelOther = "other"
+    // TenantLabelSuppressed replaces the tenant of sensitive families
+    TenantLabelSuppressed = "suppressed"
+)
+
+// Metric families labelled by tenant
+const (
+    MetricQuotaRequests           = "quota_requests_total"
+    MetricTenantDocumentsIngested = "tenant_documents_ingested_total"
+)
+
+// TenantLabels chooses the tenant label of per-tenant metrics. Counting a
+// tenant's documents could reveal how many people a small corporate client
+// enrolled, so a tenant is labelled by its ID only once enough distinct
+// enrollments were seen for it; until then it is counted under "other". Once
+// labelled a tenant stays labelled, and the number of labelled tenants is
+// capped to bound the series of every family. Sensitive families are never
+// labelled by tenant. A nil *TenantLabels suppresses every tenant label
+type TenantLabels struct {
+    mu        sync.Mutex
+    cfg       config.MetricsPrivacyConfig
+    sensitive map[string]bool
+    // subjects holds the distinct enrollments of tenants not labelled yet,
+    // at most MinTenantSubjects per tenant
+    subjects map[string]map[string]bool
+    labelled map[string]bool
+}
+
+// NewTenantLabels creates the tenant labeler
+func NewTenantLabels(cfg *config.Config) *TenantLabels {
+    labels := &TenantLabels{
+        cfg:       cfg.MetricsPrivacyConfig,
+        sensitive: make(map[string]bool),
+        subjects:  make(map[string]map[string]bool),
+        labelled:  make(map[string]bool),
+    }
+    for _, family := range cfg.MetricsPrivacyConfig.SensitiveFamilies {
+        labels.sensitive[family] = true
+    }
+    return labels
+}
+
+// Observe records an enrollment of the tenant; the tenant is labelled by its
+// ID once it reaches the threshold and the cap allows
+func (l *TenantLabels) Observe(tenantID, enrollmentID string) {
+    if l == nil || !l.cfg.Enabled || tenantID == "" || enrollmentID == "" {
+        return
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if l.labelled[tenantID] {
+        return
+    }
+    seen, ok := l.subjects[tenantID]
+    if !ok {
+        seen = make(map[string]bool)
+        l.subjects[tenantID] = seen
+    }
+    seen[enrollmentID] = true
+    if len(seen) >= l.cfg.MinTenantSubjects && len(l.labelled) < l.cfg.MaxTenants {
+        l.labelled[tenantID] = true
+        delete(l.subjects, tenantID)
+    }
+}
+
+// Label returns the tenant label value of a metric family
+func (l *TenantLabels) Label(family, tenantID string) string {
+    switch {
+    case l == nil || l.sensitive[family]:
+        return TenantLabelSuppressed
+    case tenantID == "":
+        return TenantLabelNone
+    case !l.cfg.Enabled:
+        return tenantID
+    }
+
+    l.mu.Lock()
+    defer l.mu.Unlock()
+
+    if l.labelled[tenantID] {
+        return tenantID
+    }
+    return TenantLabelOther
+}
+
+// OnIngested is the ingestion hook counting documents per tenant
+func (l *TenantLabels) OnIngested(ctx context.Context, doc *models.Document) error {
+    l.Observe(doc.TenantID, doc.EnrollmentID)
+    tenantDocumentsIngested.WithLabelValues(l.Label(MetricTenantDocumentsIngested, doc.TenantID)).Inc()
+    return nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "regexp"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Placeholders replacing masked identifiers in redacted text
+const (
+    redactedEmail  = "[EMAIL]"
+    redactedCPF    = "[CPF]"
+    redactedNumber = "[NUMBER]"
+)
+
+var (
+    ErrTextNotAvailable        = errors.New("document has no extracted text")
+    ErrTranslationNotAvailable = errors.New("document has no machine translation")
+    ErrAccessPurposeRequired   = errors.New("an access purpose is required for this document type")
+    ErrInvalidAccessPurpose    = errors.New("access purpose is not recognized")
+
+    emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+    // numberPattern matches runs of five or more characters of digits and
+    // separators, which covers RG, CNS, phone numbers, postcodes and dates
+    numberPattern = regexp.MustCompile(`\d[\d .\-/]{3,}\d`)
+)
+
+// RedactText masks the identifiers in extracted text: e-mail addresses, CPFs
+// and every other number of five or more digits. Names and free text are kept
+func RedactText(text string) string {
+    text = emailPattern.ReplaceAllString(text, redactedEmail)
+    text = cpfPattern.ReplaceAllString(text, redactedCPF)
+    return numberPattern.ReplaceAllString(text, redactedNumber)
+}
+
+// TextAccess serves the text extracted from documents. Roles allowed the full
+// text receive it as extracted and every other caller the redacted rendition
+type TextAccess struct {
+    storage      *StorageService
+    fullRoles    map[string]bool
+    purposeTypes map[string]bool
+    purposes     map[string]bool
+}
+
+// NewTextAccess creates the extracted text access service
+func NewTextAccess(cfg *config.Config, storage *StorageService) *TextAccess {
+    access := &TextAccess{
+        storage:      storage,
+        fullRoles:    make(map[string]bool),
+        purposeTypes: make(map[string]bool),
+        purposes:     make(map[string]bool),
+    }
+    for _, role := range cfg.TextAccessConfig.FullTextRoles {
+        access.fullRoles[role] = true
+    }
+    for _, documentType := range cfg.TextAccessConfig.PurposeDocumentTypes {
+        access.purposeTypes[documentType] = true
+    }
+    for _, purpose := range cfg.TextAccessConfig.Purposes {
+        access.purposes[purpose] = true
+    }
+    return access
+}
+
+// Authorize checks the purpose stated for reading the text of a document and
+// reports whether the role receives the full text. A purpose is required for
+// the configured document types and must be a known one whenever it is given
+func (a *TextAccess) Authorize(doc *models.Document, role, purpose string) (bool, error) {
+    if purpose == "" && a.purposeTypes[doc.DocumentType] {
+        return false, ErrAccessPurposeRequired
+    }
+    if purpose != "" && !a.purposes[purpose] {
+        return false, ErrInvalidAccessPurpose
+    }
+    return a.fullRoles[role], nil
+}
+
+// Read returns the full or the redacted text of a document. Documents
+// processed before redacted renditions were stored are redacted on read
+func (a *TextAccess) Read(ctx context.Context, doc *models.Document, full bool) (string, error) {
+    name, variant := models.RenditionOCRTextRedacted, "redacted"
+    if full {
+        name, variant = models.RenditionOCRText, "full"
+    }
+    rendition, ok := doc.Rendition(name)
+    redact := false
+    if !ok && !full {
+        rendition, ok = doc.Rendition(models.RenditionOCRText)
+        redact = true
+    }
+    if !ok {
+        return "", ErrTextNotAvailable
+    }
+
+    content, _, err := a.storage.OpenRendition(ctx, doc.ID, rendition)
+    if err != nil {
+        return "", err
+    }
+    defer content.Close()
+    text, err := io.ReadAll(content)
+    if err != nil {
+        return "", fmt.Errorf("failed to read extracted text: %w", err)
+    }
+
+    textAccesses.WithLabelValues(variant).Inc()
+    if redact {
+        return RedactText(string(text)), nil
+    }
+    return string(text), nil
+}
+
+// ReadTranslation returns the machine translation of the redacted text of a
+// document, with the label stating how it was produced
+func (a *TextAccess) ReadTranslation(ctx context.Context, doc *models.Document) (string, *models.MachineTranslation, error) {
+    rendition, ok := doc.Rendition(models.RenditionOCRTextTranslated)
+    if !ok || doc.Translation == nil {
+        return "", nil, ErrTranslationNotAvailable
+    }
+
+    content, _, err := a.storage.OpenRendition(ctx, doc.ID, rendition)
+    if err != nil {
+        return "", nil, err
+    }
+    defer content.Close()
+    text, err := io.ReadAll(content)
+    if err != nil {
+        return "", nil, fmt.Errorf("failed to read translated text: %w", err)
+    }
+
+    textAccesses.WithLabelValues("translated").Inc()
+    return string(text), doc.Translation, nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+    "bytes"
+    "compress/zlib"
+    "context"
+    "fmt"
+    "image"
+    "image/color"
+    "image/jpeg"
+    "strings"
+    "unicode/utf16"
+
+    "github.com/gen2brain/go-fitz" // v1.23.1
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// pdfPage is a page image ready to embed in a PDF
+type pdfPage struct {
+    jpeg       []byte
+    width      int
+    height     int
+    colorSpace string
+    dpi        float64
+}
+
+// size returns the page size in points
+func (p pdfPage) size() (float64, float64) {
+    return float64(p.width) * 72 / p.dpi, float64(p.height) * 72 / p.dpi
+}
+
+// encodePDFPage compresses a rendered page as JPEG
+func encodePDFPage(img image.Image, dpi float64, quality int) (pdfPage, error) {
+    var encoded bytes.Buffer
+    if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: quality}); err != nil {
+        return pdfPage{}, err
+    }
+    colorSpace := "/DeviceRGB"
+    if _, gray := img.(*image.Gray); gray {
+        colorSpace = "/DeviceGray"
+    }
+    bounds := img.Bounds()
+    return pdfPage{jpeg: encoded.Bytes(), width: bounds.Dx(), height: bounds.Dy(), colorSpace: colorSpace, dpi: dpi}, nil
+}
+
+// jpegPDFPage embeds a JPEG as it is, without recompressing it, when PDF
+// readers display its colors unchanged
+func jpegPDFPage(content []byte, dpi float64) (pdfPage, bool) {
+    cfg, err := jpeg.DecodeConfig(bytes.NewReader(content))
+    if err != nil {
+        return pdfPage{}, false
+    }
+    page := pdfPage{jpeg: content, width: cfg.Width, height: cfg.Height, dpi: dpi}
+    switch cfg.ColorModel {
+    case color.GrayModel:
+        page.colorSpace = "/DeviceGray"
+    case color.YCbCrModel:
+        page.colorSpace = "/DeviceRGB"
+    default:
+        return pdfPage{}, false
+    }
+    return page, true
+}
+
+// renderPages rasterizes the pages of a PDF at dpi, or decodes an image as its
+// only page
+func renderPages(ctx context.Context, contentType string, content []byte, dpi float64) ([]image.Image, error) {
+    if contentType != "application/pdf" {
+        img, _, err := image.Decode(bytes.NewReader(content))
+        if err != nil {
+            return nil, fmt.Errorf("failed to decode image: %w", err)
+        }
+        return []image.Image{img}, nil
+    }
+
+    pdf, err := fitz.NewFromMemory(content)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open PDF: %w", err)
+    }
+    defer pdf.Close()
+
+    pages := make([]image.Image, pdf.NumPage())
+    for i := range pages {
+        if err := ctx.Err(); err != nil {
+            return nil, err
+        }
+        if pages[i], err = pdf.ImageDPI(i, dpi); err != nil {
+            return nil, fmt.Errorf("failed to render page %d: %w", i+1, err)
+        }
+    }
+    return pages, nil
+}
+
+// textLayer draws the page image and each line as invisible text sized to its
+// box. Tagged pages mark the image as an artifact and each line as a
+// marked-content sequence numbered for its paragraph. The stream is
+// compressed
+func textLayer(width, height float64, lines []models.OCRLine, tagged bool) []byte {
+    var content bytes.Buffer
+    if tagged {
+        content.WriteString("/Artifact BMC ")
+    }
+    fmt.Fprintf(&content, "q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", width, height)
+    if tagged {
+        content.WriteString(" EMC")
+    }
+    content.WriteString("\n")
+
+    for mcid, line := range lines {
+        left, top, right, bottom := line.Box[0]*width, line.Box[1]*height, line.Box[2]*width, line.Box[3]*height
+        size := bottom - top
+        if size <= 0 {
+            size = 10
+        }
+        text := winAnsi(line.Text)
+        // Helvetica averages about half an em per character
+        scale := 100.0
+        if estimate := 0.5 * size * float64(len(text)); estimate > 0 && right > left {
+            scale = min(max((right-left)/estimate*100, 10), 1000)
+        }
+        if tagged {
+            fmt.Fprintf(&content, "/P << /MCID %d >> BDC ", mcid)
+        }
+        fmt.Fprintf(&content, "BT 3 Tr /F1 %.2f Tf %.2f Tz 1 0 0 1 %.2f %.2f Tm <%X> Tj ET", size, scale, left, height-bottom+0.2*size, text)
+        if tagged {
+            content.WriteString(" EMC")
+        }
+        content.WriteString("\n")
+    }
+
+    var compressed bytes.Buffer
+    writer := zlib.NewWriter(&compressed)
+    writer.Write(content.Bytes())
+    writer.Close()
+    return compressed.Bytes()
+}
+
+// writeTextLayerPage writes a page object, its content stream and its image as
+// the objects numbered from object. Pages of a tagged PDF give their key in
+// the parent tree as structParents; untagged pages give -1
+func writeTextLayerPage(pdf *pdfObjectWriter, object, parent, font int, page pdfPage, lines []models.OCRLine, structParents int) {
+    width, height := page.size()
+    tagging := ""
+    if structParents >= 0 {
+        tagging = fmt.Sprintf(" /StructParents %d /Tabs /S", structParents)
+    }
+    pdf.object(object, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> /Font << /F1 %d 0 R >> >> /Contents %d 0 R%s >>",
+        parent, width, height, object+2, font, object+1, tagging))
+    pdf.stream(object+1, "/Filter /FlateDecode", textLayer(width, height, lines, structParents >= 0))
+    pdf.stream(object+2, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+        page.width, page.height, page.colorSpace), page.jpeg)
+}
+
+// textLayerFont is the font invisible text is drawn in
+const textLayerFont = "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"
+
+// winAnsi encodes text for the standard Helvetica font: ASCII and Latin-1
+// characters map to themselves and anything else to a question mark
+func winAnsi(text string) []byte {
+    encoded := make([]byte, 0, len(text))
+    for _, r := range text {
+        switch {
+        case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+            encoded = append(encoded, byte(r))
+        default:
+            encoded = append(encoded, '?')
+        }
+    }
+    return encoded
+}
+
+// pdfText encodes a text string as UTF-16 with a byte order mark, which
+// carries any character
+func pdfText(text string) string {
+    var encoded strings.Builder
+    encoded.WriteString("<FEFF")
+    for _, unit := range utf16.Encode([]rune(text)) {
+        fmt.Fprintf(&encoded, "%04X", unit)
+    }
+    encoded.WriteString(">")
+    return encoded.String()
+}
+
+// pdfObjectWriter writes numbered PDF objects and the cross-reference table
+// locating them
+type pdfObjectWriter struct {
+    buf     bytes.Buffer
+    offsets map[int]int
+}
+
+func newPDFObjectWriter() *pdfObjectWriter {
+    w := &pdfObjectWriter{offsets: make(map[int]int)}
+    w.buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+    return w
+}
+
+func (w *pdfObjectWriter) object(number int, body string) {
+    w.begin(number)
+    fmt.Fprintf(&w.buf, "%s\nendobj\n", body)
+}
+
+func (w *pdfObjectWriter) stream(number int, dictionary string, data []byte) {
+    w.begin(number)
+    fmt.Fprintf(&w.buf, "<< %s /Length %d >>\nstream\n", dictionary, len(data))
+    w.buf.Write(data)
+    w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (w *pdfObjectWriter) begin(number int) {
+    w.offsets[number] = w.buf.Len()
+    fmt.Fprintf(&w.buf, "%d 0 obj\n", number)
+}
+
+// finish writes the cross-reference table and trailer. Objects must be
+// numbered from 1 without gaps
+func (w *pdfObjectWriter) finish(root, info int) ([]byte, error) {
+    count := len(w.offsets)
+    xref := w.buf.Len()
+    fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", count+1)
+    for number := 1; number <= count; number++ {
+        offset, ok := w.offsets[number]
+        if !ok {
+            return nil, fmt.Errorf("PDF object %d was not written", number)
+        }
+        fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+    }
+    fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", count+1, root, info, xref)
+    return w.buf.Bytes(), nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var ErrProcessingTierFull = errors.New("processing tier queue is full")
+
+// ProcessingTiers routes documents by size and page count to tiers, each
+// with its own workers, queue and timeout, so small interactive uploads
+// stay fast during bulk ingestion. Workers and queues are per instance
+type ProcessingTiers struct {
+    cfg   config.ProcessingTiersConfig
+    tiers map[string]*processingTier
+}
+
+// processingTier holds the worker slots of a tier and the room left in its
+// queue
+type processingTier struct {
+    cfg   config.ProcessingTierConfig
+    slots chan struct{}
+    queue chan struct{}
+}
+
+// NewProcessingTiers creates the tiers, or returns nil when processing tiers
+// are disabled
+func NewProcessingTiers(cfg *config.Config) *ProcessingTiers {
+    if cfg == nil || !cfg.ProcessingTiersConfig.Enabled {
+        return nil
+    }
+
+    tiers := &ProcessingTiers{
+        cfg:   cfg.ProcessingTiersConfig,
+        tiers: make(map[string]*processingTier, len(cfg.ProcessingTiersConfig.Tiers)),
+    }
+    for _, tier := range cfg.ProcessingTiersConfig.Tiers {
+        tiers.tiers[tier.Name] = &processingTier{
+            cfg:   tier,
+            slots: make(chan struct{}, tier.Concurrency),
+            queue: make(chan struct{}, tier.Concurrency+tier.QueueSize),
+        }
+        processingTierBusy.WithLabelValues(tier.Name).Set(0)
+        processingTierQueued.WithLabelValues(tier.Name).Set(0)
+    }
+    return tiers
+}
+
+// Route assigns a document to the tier its content fits in. Pages are
+// counted only on PDFs whose text the service can read, and only when a
+// tier routes on them. A nil *ProcessingTiers leaves the document untiered
+func (t *ProcessingTiers) Route(doc *models.Document, content []byte) {
+    if t == nil {
+        return
+    }
+
+    pages := -1
+    if t.cfg.CountsPages() && doc.ContentType == "application/pdf" && !doc.ClientEncrypted() {
+        if count, err := countPDFPages(content); err == nil {
+            pages = count
+        }
+    }
+    doc.ProcessingTier = t.cfg.TierFor(int64(len(content)), pages).Name
+    processingTierDocuments.WithLabelValues(doc.ProcessingTier).Inc()
+}
+
+// Acquire waits for a worker of the document's tier, refusing it with
+// ErrProcessingTierFull when the queue of the tier is full; the returned
+// func releases the worker. Untiered documents, and every document on a nil
+// *ProcessingTiers, run at once
+func (t *ProcessingTiers) Acquire(ctx context.Context, doc *models.Document) (func(), error) {
+    tier, ok := t.tier(doc)
+    if !ok {
+        return func() {}, nil
+    }
+
+    select {
+    case tier.queue <- struct{}{}:
+    default:
+        processingTierRejections.WithLabelValues(tier.cfg.Name).Inc()
+        return nil, fmt.Errorf("%w: %s", ErrProcessingTierFull, tier.cfg.Name)
+    }
+    processingTierQueued.WithLabelValues(tier.cfg.Name).Inc()
+
+    start := time.Now()
+    select {
+    case tier.slots <- struct{}{}:
+    case <-ctx.Done():
+        <-tier.queue
+        processingTierQueued.WithLabelValues(tier.cfg.Name).Dec()
+        return nil, ctx.Err()
+    }
+    processingTierQueued.WithLabelValues(tier.cfg.Name).Dec()
+    processingTierBusy.WithLabelValues(tier.cfg.Name).Inc()
+    processingTierWait.WithLabelValues(tier.cfg.Name).Observe(time.Since(start).Seconds())
+
+    return func() {
+        <-tier.slots
+        <-tier.queue
+        processingTierBusy.WithLabelValues(tier.cfg.Name).Dec()
+    }, nil
+}
+
+// Timeout returns how long the steps of a document may run, zero for no
+// limit
+func (t *ProcessingTiers) Timeout(doc *models.Document) time.Duration {
+    if tier, ok := t.tier(doc); ok {
+        return tier.cfg.Timeout
+    }
+    return 0
+}
+
+// Tiers lists the configured tiers in routing order
+func (t *ProcessingTiers) Tiers() []config.ProcessingTierConfig {
+    if t == nil {
+        return nil
+    }
+    return t.cfg.Tiers
+}
+
+// tier returns the tier of a document
+func (t *ProcessingTiers) tier(doc *models.Document) (*processingTier, bool) {
+    if t == nil {
+        return nil, false
+    }
+    tier, ok := t.tiers[doc.ProcessingTier]
+    return tier, ok
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/xml"
+    "errors"
+    "fmt"
+    "io"
+    "strings"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+const (
+    StepTISS = "tiss"
+
+    tissNamespace   = "http://www.ans.gov.br/padroes/tiss/schemas"
+    tissRootElement = "mensagemTISS"
+)
+
+// Extracted TISS field names
+const (
+    FieldTISSVersion       = "tiss.schema_version"
+    FieldTISSGuideNumber   = "tiss.guide_number"
+    FieldTISSProcedureCode = "tiss.procedure_code"
+    FieldTISSProviderCode  = "tiss.provider_code"
+    FieldTISSProviderName  = "tiss.provider_name"
+)
+
+var (
+    ErrInvalidTISS            = errors.New("invalid TISS document")
+    ErrUnsupportedTISSVersion = errors.New("unsupported TISS schema version")
+
+    // SupportedTISSVersions lists the ANS TISS schema versions accepted for parsing
+    SupportedTISSVersions = []string{"3.05.00", "4.00.01", "4.01.00"}
+)
+
+// TISSGuide holds the key fields of a parsed TISS message
+type TISSGuide struct {
+    Version        string
+    GuideNumbers   []string
+    ProcedureCodes []string
+    ProviderCode   string
+    ProviderName   string
+}
+
+// TISSStep parses attached TISS guides into structured extracted fields for claims systems
+type TISSStep struct{}
+
+// NewTISSStep creates a new TISS parsing step
+func NewTISSStep() *TISSStep {
+    return &TISSStep{}
+}
+
+// Name returns the step name
+func (s *TISSStep) Name() string {
+    return StepTISS
+}
+
+// Provenance reports the parser version; guides are parsed locally
+func (s *TISSStep) Provenance() StepProvenance {
+    return StepProvenance{Version: tissStepVersion}
+}
+
+// Applies reports whether the document is an XML attachment
+func (s *TISSStep) Applies(doc *models.Document) bool {
+    return doc.ContentType == "application/xml" || doc.ContentType == "text/xml"
+}
+
+// Execute parses the guide and stores its key fields on the document
+func (s *TISSStep) Execute(ctx context.Context, run *PipelineRun) error {
+    guide, err := ParseTISS(run.Content)
+    if err != nil {
+        return err
+    }
+
+    fields := []models.ExtractedField{
+        {Name: FieldTISSVersion, Value: guide.Version, Confidence: 1},
+    }
+    for _, number := range guide.GuideNumbers {
+        fields = append(fields, models.ExtractedField{Name: FieldTISSGuideNumber, Value: number, Confidence: 1})
+    }
+    for _, code := range guide.ProcedureCodes {
+        fields = append(fields, models.ExtractedField{Name: FieldTISSProcedureCode, Value: code, Confidence: 1})
+    }
+    if guide.ProviderCode != "" {
+        fields = append(fields, models.ExtractedField{Name: FieldTISSProviderCode, Value: guide.ProviderCode, Confidence: 1})
+    }
+    if guide.ProviderName != "" {
+        fields = append(fields, models.ExtractedField{Name: FieldTISSProviderName, Value: guide.ProviderName, Confidence: 1})
+    }
+
+    run.Document.SetExtractedFields(StepTISS, fields)
+    return nil
+}
+
+// ParseTISS validates the TISS message envelope and schema version and extracts
+// guide numbers, procedure codes and the executing provider
+func ParseTISS(content []byte) (*TISSGuide, error) {
+    decoder := xml.NewDecoder(bytes.NewReader(content))
+    decoder.CharsetReader = tissCharsetReader
+    guide := &TISSGuide{}
+
+    var (
+        path     []string
+        rootSeen bool
+    )
+    for {
+        token, err := decoder.Token()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("%w: %v", ErrInvalidTISS, err)
+        }
+
+        switch t := token.(type) {
+        case xml.StartElement:
+            if !rootSeen {
+                if t.Name.Local != tissRootElement || t.Name.Space != tissNamespace {
+                    return nil, fmt.Errorf("%w: unexpected root element %s", ErrInvalidTISS, t.Name.Local)
+                }
+                rootSeen = true
+            }
+            path = append(path, t.Name.Local)
+        case xml.EndElement:
+            if len(path) > 0 {
+                path = path[:len(path)-1]
+            }
+        case xml.CharData:
+            if len(path) == 0 {
+                continue
+            }
+            value := strings.TrimSpace(string(t))
+            if value == "" {
+                continue
+            }
+            collectTISSValue(guide, path, value)
+        }
+    }
+
+    if !rootSeen {
+        return nil, fmt.Errorf("%w: empty document", ErrInvalidTISS)
+    }
+    if !isSupportedTISSVersion(guide.Version) {
+        return nil, fmt.Errorf("%w: %q", ErrUnsupportedTISSVersion, guide.Version)
+    }
+    if len(guide.GuideNumbers) == 0 {
+        return nil, fmt.Errorf("%w: no guide number found", ErrInvalidTISS)
+    }
+
+    return guide, nil
+}
+
+// collectTISSValue maps element text to guide fields based on the element path
+func collectTISSValue(guide *TISSGuide, path []string, value string) {
+    element := path[len(path)-1]
+    parent := ""
+    if len(path) > 1 {
+        parent = path[len(path)-2]
+    }
+
+    switch {
+    case element == "Padrao" && parent == "cabecalho":
+        guide.Version = value
+    case element == "numeroGuiaPrestador":
+        guide.GuideNumbers = appendUnique(guide.GuideNumbers, value)
+    case element == "codigoProcedimento":
+        guide.ProcedureCodes = appendUnique(guide.ProcedureCodes, value)
+    case element == "codigoPrestadorNaOperadora" && guide.ProviderCode == "" && hasTISSAncestor(path, "contratadoExecutante", "dadosContratado"):
+        guide.ProviderCode = value
+    case element == "nomeContratado" && guide.ProviderName == "" && hasTISSAncestor(path, "contratadoExecutante", "dadosContratado"):
+        guide.ProviderName = value
+    }
+}
+
+// hasTISSAncestor reports whether any of the named elements encloses the current element
+func hasTISSAncestor(path []string, names ...string) bool {
+    for _, element := range path[:len(path)-1] {
+        for _, name := range names {
+            if element == name {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// tissCharsetReader decodes the ISO-8859-1 encoding that ANS guides commonly declare
+func tissCharsetReader(label string, input io.Reader) (io.Reader, error) {
+    switch strings.ToLower(label) {
+    case "utf-8", "utf8":
+        return input, nil
+    case "iso-8859-1", "iso8859-1", "latin1":
+        raw, err := io.ReadAll(input)
+        if err != nil {
+            return nil, err
+        }
+        runes := make([]rune, len(raw))
+        for i, b := range raw {
+            runes[i] = rune(b)
+        }
+        return strings.NewReader(string(runes)), nil
+    default:
+        return nil, fmt.Errorf("unsupported charset %q", label)
+    }
+}
+
+func isSupportedTISSVersion(version string) bool {
+    for _, supported := range SupportedTISSVersions {
+        if version == supported {
+            return true
+        }
+    }
+    return false
+}
+
+func appendUnique(values []string, value string) []string {
+    for _, existing := range values {
+        if existing == value {
+            return values
+        }
+    }
+    return append(values, value)
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+    "crypto/tls"
+    "net"
+    "net/http"
+    "net/http/httptrace"
+    "strconv"
+    "time"
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// NewHTTPTransport builds a pooled keep-alive transport from the tuning
+// settings. Requests made through it record connection reuse, TLS session
+// resumption and DNS lookup latency labelled with the client name
+func NewHTTPTransport(client string, cfg config.HTTPTransportConfig) http.RoundTripper {
+    dialer := &net.Dialer{
+        Timeout:   cfg.DialTimeout,
+        KeepAlive: cfg.KeepAlive,
+    }
+
+    tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+    if cfg.TLSSessionCacheSize > 0 {
+        tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.TLSSessionCacheSize)
+    }
+    utils.ApplyTLSPolicy(tlsConfig)
+
+    return &instrumentedTransport{
+        client: client,
+        next: &http.Transport{
+            Proxy:                 http.ProxyFromEnvironment,
+            DialContext:           dialer.DialContext,
+            ForceAttemptHTTP2:     true,
+            MaxIdleConns:          cfg.MaxIdleConns,
+            MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+            MaxConnsPerHost:       cfg.MaxConnsPerHost,
+            IdleConnTimeout:       cfg.IdleConnTimeout,
+            TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+            ExpectContinueTimeout: time.Second,
+            TLSClientConfig:       tlsConfig,
+        },
+    }
+}
+
+// instrumentedTransport traces the connection lifecycle of each request
+type instrumentedTransport struct {
+    client string
+    next   http.RoundTripper
+}
+
+// RoundTrip executes the request with a client trace attached
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+    var dnsStart time.Time
+    trace := &httptrace.ClientTrace{
+        DNSStart: func(httptrace.DNSStartInfo) {
+            dnsStart = time.Now()
+        },
+        DNSDone: func(httptrace.DNSDoneInfo) {
+            httpClientDNSDuration.WithLabelValues(t.client).Observe(time.Since(dnsStart).Seconds())
+        },
+        TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+            if err == nil {
+                httpClientTLSHandshakes.WithLabelValues(t.client, strconv.FormatBool(state.DidResume)).Inc()
+            }
+        },
+        GotConn: func(info httptrace.GotConnInfo) {
+            httpClientConnections.WithLabelValues(t.client, strconv.FormatBool(info.Reused)).Inc()
+        },
+    }
+
+    return t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "crypto/tls"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/url"
+    "sort"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+    "google.golang.org/grpc" // v1.58.3
+    "google.golang.org/grpc/credentials" // v1.58.3
+    "google.golang.org/grpc/metadata" // v1.58.3
+    "google.golang.org/protobuf/types/known/emptypb" // v1.31.0
+    "google.golang.org/protobuf/types/known/structpb" // v1.31.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+const (
+    TopicUnderwritingReady = "underwriting.enrollment_documents_ready"
+
+    // underwritingNotifyMethod is the gRPC method receiving notifications as
+    // a google.protobuf.Struct and answering google.protobuf.Empty
+    underwritingNotifyMethod = "/underwriting.v1.UnderwritingService/NotifyEnrollmentReady"
+)
+
+// EnrollmentReadyNotification tells underwriting that an enrollment's required documents are approved
+type EnrollmentReadyNotification struct {
+    // NotificationID identifies the approvals the notification reports; an
+    // enrollment approved again after a rejection is notified under a new ID
+    NotificationID string          `json:"notification_id"`
+    EnrollmentID   string          `json:"enrollment_id"`
+    Documents      []ReadyDocument `json:"documents"`
+    ReadyAt        time.Time       `json:"ready_at"`
+}
+
+// ReadyDocument describes an approved document included in the notification
+type ReadyDocument struct {
+    ID           string    `json:"id"`
+    DocumentType string    `json:"document_type"`
+    ContentHash  string    `json:"content_hash"`
+    ApprovedAt   time.Time `json:"approved_at"`
+    // Rendition names the spliced or normalized PDF when pages were rescanned,
+    // edited or removed after upload; ContentHash remains that of the original
+    Rendition    string    `json:"rendition,omitempty"`
+}
+
+// UnderwritingTransport delivers notifications to the underwriting decision engine
+type UnderwritingTransport interface {
+    NotifyEnrollmentReady(ctx context.Context, notification EnrollmentReadyNotification) error
+}
+
+// NewUnderwritingTransport creates the transport selected by underwriting.transport
+func NewUnderwritingTransport(cfg *config.Config) (UnderwritingTransport, error) {
+    switch cfg.UnderwritingConfig.Transport {
+    case "grpc":
+        return NewGRPCUnderwritingTransport(cfg)
+    default:
+        return NewRESTUnderwritingTransport(cfg), nil
+    }
+}
+
+// RESTUnderwritingTransport delivers notifications over the underwriting REST API
+type RESTUnderwritingTransport struct {
+    baseURL    string
+    httpClient *http.Client
+}
+
+// NewRESTUnderwritingTransport creates a new REST transport
+func NewRESTUnderwritingTransport(cfg *config.Config) *RESTUnderwritingTransport {
+    return &RESTUnderwritingTransport{
+        baseURL:    cfg.UnderwritingConfig.BaseURL,
+        httpClient: &http.Client{
+            Timeout:   cfg.UnderwritingConfig.Timeout,
+            Transport: SignedTransport(NewRequestSigner(cfg), nil),
+        },
+    }
+}
+
+// NotifyEnrollmentReady posts the notification; its ID doubles as idempotency key
+func (t *RESTUnderwritingTransport) NotifyEnrollmentReady(ctx context.Context, notification EnrollmentReadyNotification) error {
+    body, err := json.Marshal(notification)
+    if err != nil {
+        return fmt.Errorf("failed to encode notification: %w", err)
+    }
+
+    endpoint := fmt.Sprintf("%s/api/v1/underwriting/enrollments/%s/documents-ready", t.baseURL, url.PathEscape(notification.EnrollmentID))
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("failed to build underwriting request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Idempotency-Key", notification.NotificationID)
+
+    resp, err := t.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("underwriting request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("underwriting service returned status %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// GRPCUnderwritingTransport delivers notifications over the underwriting
+// gRPC API. The notification travels as a google.protobuf.Struct with the
+// same fields as the REST body, and the idempotency key as request metadata
+type GRPCUnderwritingTransport struct {
+    conn    *grpc.ClientConn
+    timeout time.Duration
+}
+
+// NewGRPCUnderwritingTransport creates a gRPC transport to the configured
+// target over TLS. The connection is made lazily on the first notification
+func NewGRPCUnderwritingTransport(cfg *config.Config) (*GRPCUnderwritingTransport, error) {
+    conn, err := grpc.Dial(cfg.UnderwritingConfig.GRPCTarget,
+        grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})),
+    )
+    if err != nil {
+        return nil, fmt.Errorf("failed to create underwriting gRPC client: %w", err)
+    }
+    return &GRPCUnderwritingTransport{
+        conn:    conn,
+        timeout: cfg.UnderwritingConfig.Timeout,
+    }, nil
+}
+
+// NotifyEnrollmentReady calls the underwriting gRPC method with the notification
+func (t *GRPCUnderwritingTransport) NotifyEnrollmentReady(ctx context.Context, notification EnrollmentReadyNotification) error {
+    request, err := notificationStruct(notification)
+    if err != nil {
+        return err
+    }
+
+    ctx, cancel := context.WithTimeout(ctx, t.timeout)
+    defer cancel()
+    ctx = metadata.AppendToOutgoingContext(ctx, "idempotency-key", notification.NotificationID)
+
+    if err := t.conn.Invoke(ctx, underwritingNotifyMethod, request, &emptypb.Empty{}); err != nil {
+        return fmt.Errorf("underwriting call failed: %w", err)
+    }
+    return nil
+}
+
+// Close closes the connection to the underwriting service
+func (t *GRPCUnderwritingTransport) Close() error {
+    return t.conn.Close()
+}
+
+// notificationStruct converts a notification to a google.protobuf.Struct
+// holding its JSON fields
+func notificationStruct(notification EnrollmentReadyNotification) (*structpb.Struct, error) {
+    encoded, err := json.Marshal(notification)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode notification: %w", err)
+    }
+    var fields map[string]interface{}
+    if err := json.Unmarshal(encoded, &fields); err != nil {
+        return nil, fmt.Errorf("failed to encode notification: %w", err)
+    }
+    request, err := structpb.NewStruct(fields)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode notification: %w", err)
+    }
+    return request, nil
+}
+
+// UnderwritingService detects when an enrollment's required documents are all
+// approved and queues a notification to underwriting through the outbox
+type UnderwritingService struct {
+    cfg       config.UnderwritingConfig
+    documents repository.DocumentRepository
+    outbox    repository.OutboxRepository
+    transport UnderwritingTransport
+    logger    *zap.Logger
+}
+
+// NewUnderwritingService creates a new underwriting integration service
+func NewUnderwritingService(cfg *config.Config, documents repository.DocumentRepository, outbox repository.OutboxRepository, transport UnderwritingTransport, logger *zap.Logger) (*UnderwritingService, error) {
+    if cfg == nil || documents == nil || outbox == nil || transport == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &UnderwritingService{
+        cfg:       cfg.UnderwritingConfig,
+        documents: documents,
+        outbox:    outbox,
+        transport: transport,
+        logger:    logger,
+    }, nil
+}
+
+// Checklist reports the state of each required document type of an
+// enrollment. Partially approved documents do not complete the checklist
+func (s *UnderwritingService) Checklist(ctx context.Context, enrollmentID string) (*models.EnrollmentChecklist, error) {
+    checklist, _, err := s.checklist(ctx, enrollmentID, nil)
+    return checklist, err
+}
+
+// checklist builds the checklist of an enrollment along with the approved
+// document of each required type. A changed document not saved yet takes
+// the place of its stored version
+func (s *UnderwritingService) checklist(ctx context.Context, enrollmentID string, changed *models.Document) (*models.EnrollmentChecklist, []*models.Document, error) {
+    docs, err := s.documents.ListByEnrollment(ctx, enrollmentID)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to list enrollment documents: %w", err)
+    }
+    if changed != nil {
+        for i, doc := range docs {
+            if doc.ID == changed.ID {
+                docs[i] = changed
+            }
+        }
+    }
+
+    best := make(map[string]models.ChecklistItem)
+    chosen := make(map[string]*models.Document)
+    for _, doc := range docs {
+        item := models.ChecklistItemFor(doc)
+        if current, ok := best[doc.DocumentType]; !ok || item.Advances(current) {
+            best[doc.DocumentType] = item
+            chosen[doc.DocumentType] = doc
+        }
+    }
+
+    checklist := &models.EnrollmentChecklist{
+        EnrollmentID: enrollmentID,
+        Complete:     true,
+        Items:        make([]models.ChecklistItem, 0, len(s.cfg.RequiredDocumentTypes)),
+    }
+    approved := make([]*models.Document, 0, len(s.cfg.RequiredDocumentTypes))
+    for _, documentType := range s.cfg.RequiredDocumentTypes {
+        item, ok := best[documentType]
+        if !ok {
+            item = models.ChecklistItem{DocumentType: documentType, Status: models.ChecklistMissing}
+        }
+        if item.Status == models.ChecklistApproved {
+            approved = append(approved, chosen[documentType])
+        } else {
+            checklist.Complete = false
+        }
+        checklist.Items = append(checklist.Items, item)
+    }
+    return checklist, approved, nil
+}
+
+// CheckEnrollment queues a notification once every required document type has an approved document
+func (s *UnderwritingService) CheckEnrollment(ctx context.Context, enrollmentID string) error {
+    msg, err := s.readyMessage(ctx, enrollmentID, nil)
+    if err != nil || msg == nil {
+        return err
+    }
+    if err := s.outbox.Enqueue(ctx, msg); err != nil {
+        if errors.Is(err, repository.ErrDuplicateMessage) {
+            return nil
+        }
+        return fmt.Errorf("failed to enqueue underwriting notification: %w", err)
+    }
+    s.logReady(enrollmentID)
+    return nil
+}
+
+// SaveReview saves a reviewed document through save and, when its approval
+// completes the enrollment, queues the notification in the same outbox
+// transaction, so a saved approval is never left without its notification
+func (s *UnderwritingService) SaveReview(ctx context.Context, doc *models.Document, save func(ctx context.Context) error) error {
+    if !s.cfg.Enabled || doc.Status != models.DocumentStatusApproved {
+        return save(ctx)
+    }
+
+    var ready bool
+    err := s.outbox.EnqueueWith(ctx, func(ctx context.Context) ([]*models.OutboxMessage, error) {
+        msg, err := s.readyMessage(ctx, doc.EnrollmentID, doc)
+        if err != nil {
+            return nil, err
+        }
+        if err := save(ctx); err != nil {
+            return nil, err
+        }
+        if msg == nil {
+            return nil, nil
+        }
+        ready = true
+        return []*models.OutboxMessage{msg}, nil
+    })
+    if err != nil {
+        return err
+    }
+    if ready {
+        s.logReady(doc.EnrollmentID)
+    }
+    return nil
+}
+
+// readyMessage builds the notification of an enrollment whose required
+// documents are all approved, or returns nil while any is not
+func (s *UnderwritingService) readyMessage(ctx context.Context, enrollmentID string, changed *models.Document) (*models.OutboxMessage, error) {
+    if !s.cfg.Enabled {
+        return nil, nil
+    }
+
+    checklist, approved, err := s.checklist(ctx, enrollmentID, changed)
+    if err != nil {
+        return nil, err
+    }
+    if !checklist.Complete {
+        return nil, nil
+    }
+
+    notification := EnrollmentReadyNotification{
+        EnrollmentID: enrollmentID,
+        ReadyAt:      time.Now(),
+    }
+    for _, doc := range approved {
+        approvedAt := doc.UpdatedAt
+        if doc.ReviewedAt != nil {
+            approvedAt = *doc.ReviewedAt
+        }
+        ready := ReadyDocument{
+            ID:           doc.ID,
+            DocumentType: doc.DocumentType,
+            ContentHash:  doc.ContentHash,
+            ApprovedAt:   approvedAt,
+        }
+        if rendition, ok := doc.CurrentPDF(); ok {
+            ready.Rendition = rendition.Name
+        }
+        notification.Documents = append(notification.Documents, ready)
+    }
+    notification.NotificationID = approvalKey(enrollmentID, notification.Documents)
+
+    return newOutboxMessage(TopicUnderwritingReady, notification.NotificationID, notification)
+}
+
+// approvalKey identifies the set of approvals completing an enrollment. The
+// same approvals always give the same key, so a repeated check is queued
+// once, while a document rejected and approved again gives a new one
+func approvalKey(enrollmentID string, documents []ReadyDocument) string {
+    approvals := make([]string, 0, len(documents))
+    for _, doc := range documents {
+        approvals = append(approvals, fmt.Sprintf("%s@%d", doc.ID, doc.ApprovedAt.UnixNano()))
+    }
+    sort.Strings(approvals)
+
+    hash := sha256.New()
+    for _, approval := range approvals {
+        hash.Write([]byte(approval))
+        hash.Write([]byte{0})
+    }
+    return enrollmentID + "/" + hex.EncodeToString(hash.Sum(nil))
+}
+
+func (s *UnderwritingService) logReady(enrollmentID string) {
+    s.logger.Info("Enrollment documents ready for underwriting",
+        zap.String("enrollment_id", enrollmentID),
+        zap.Bool("shadow_mode", s.cfg.ShadowMode),
+    )
+}
+
+// Deliver is the outbox handler sending queued notifications; in shadow mode
+// the notification is only logged so payloads can be verified before go-live
+func (s *UnderwritingService) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    var notification EnrollmentReadyNotification
+    if err := json.Unmarshal(msg.Payload, &notification); err != nil {
+        return fmt.Errorf("invalid underwriting notification payload: %w", err)
+    }
+
+    if s.cfg.ShadowMode {
+        s.logger.Info("Underwriting notification shadowed",
+            zap.String("enrollment_id", notification.EnrollmentID),
+            zap.Int("documents", len(notification.Documents)),
+        )
+        return ErrShadowed
+    }
+
+    return s.transport.NotifyEnrollmentReady(ctx, notification)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "image"
+    "image/color"
+    "image/draw"
+    "image/jpeg"
+    _ "image/png"
+    "io"
+    "time"
+
+    "github.com/gen2brain/go-fitz" // v1.23.1
+    xdraw "golang.org/x/image/draw" // v0.14.0
+    "golang.org/x/image/font" // v0.14.0
+    "golang.org/x/image/font/basicfont"
+    "golang.org/x/image/math/fixed"
+    "go.uber.org/zap" // v1.26.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Secure viewer events reported by the viewer beacon in addition to the
+// page_view events recorded when a page is rendered
+const (
+    ViewerEventPageView          = "page_view"
+    ViewerEventScreenshotAttempt = "screenshot_attempt"
+    ViewerEventPrintAttempt      = "print_attempt"
+    ViewerEventCopyAttempt       = "copy_attempt"
+    ViewerEventFocusLost         = "focus_lost"
+)
+
+const (
+    // viewerJPEGQuality keeps rendered scans legible at a fraction of PNG size
+    viewerJPEGQuality = 80
+    // watermarkOpacity is the alpha of the watermark ink
+    watermarkOpacity = 72
+)
+
+var (
+    ErrSecureViewerOnly        = errors.New("document is only available in the secure viewer")
+    ErrSecureViewerUnsupported = errors.New("content type cannot be rendered by the secure viewer")
+    ErrPageOutOfRange          = errors.New("page out of range")
+)
+
+// ViewerSession identifies who is looking at a page; it is stamped on every
+// rendered page so a photographed or leaked page can be traced to its session
+type ViewerSession struct {
+    UserID    string
+    Role      string
+    SessionID string
+    ClientIP  string
+}
+
+// SecureViewer renders the pages of high-sensitivity documents to watermarked
+// images served one page at a time, so restricted roles can read them without
+// the original file ever leaving the service. Every role not configured as
+// unrestricted is restricted, including an empty or unknown one
+type SecureViewer struct {
+    config       *config.Config
+    storage      *StorageService
+    types        map[string]bool
+    unrestricted map[string]bool
+    auditLogger  *zap.Logger
+}
+
+// NewSecureViewer creates the secure viewer
+func NewSecureViewer(cfg *config.Config, storage *StorageService, auditLogger *zap.Logger) *SecureViewer {
+    viewer := &SecureViewer{
+        config:       cfg,
+        storage:      storage,
+        types:        make(map[string]bool),
+        unrestricted: make(map[string]bool),
+        auditLogger:  auditLogger,
+    }
+    for _, documentType := range cfg.SecureViewerConfig.DocumentTypes {
+        viewer.types[documentType] = true
+    }
+    for _, role := range cfg.SecureViewerConfig.UnrestrictedRoles {
+        viewer.unrestricted[role] = true
+    }
+    return viewer
+}
+
+// Restricted reports whether the role may only read the document through the
+// secure viewer, which rules out downloads, renditions and preview tokens.
+// Only the unrestricted roles may have the file; a request without a role
+// is restricted
+func (v *SecureViewer) Restricted(doc *models.Document, role string) bool {
+    return v.config.SecureViewerConfig.Enabled && v.types[doc.DocumentType] && (role == "" || !v.unrestricted[role])
+}
+
+// PageCount returns the number of pages the viewer can render
+func (v *SecureViewer) PageCount(ctx context.Context, doc *models.Document) (int, error) {
+    _, count, err := v.render(ctx, doc, 0)
+    return count, err
+}
+
+// RenderPage renders a 1-based page as a JPEG watermarked with the session,
+// and records a page_view audit event
+func (v *SecureViewer) RenderPage(ctx context.Context, doc *models.Document, page int, session ViewerSession) ([]byte, int, error) {
+    img, count, err := v.render(ctx, doc, page)
+    if err != nil {
+        return nil, count, err
+    }
+
+    label := fmt.Sprintf("%s  %s  %s  %s", session.UserID, session.SessionID, session.ClientIP, time.Now().UTC().Format("2006-01-02 15:04 UTC"))
+    var out bytes.Buffer
+    if err := jpeg.Encode(&out, watermark(img, label), &jpeg.Options{Quality: viewerJPEGQuality}); err != nil {
+        return nil, count, fmt.Errorf("failed to encode page %d: %w", page, err)
+    }
+
+    v.RecordEvent(doc, session, ViewerEventPageView, page)
+    return out.Bytes(), count, nil
+}
+
+// RecordEvent writes a secure viewer audit event
+func (v *SecureViewer) RecordEvent(doc *models.Document, session ViewerSession, event string, page int) {
+    secureViewerEvents.WithLabelValues(event).Inc()
+    v.auditLogger.Info("Secure viewer event",
+        zap.String("event", event),
+        zap.String("document_id", doc.ID),
+        zap.String("enrollment_id", doc.EnrollmentID),
+        zap.Int("page", page),
+        zap.String("user_id", session.UserID),
+        zap.String("role", session.Role),
+        zap.String("session_id", session.SessionID),
+        zap.String("client_ip", session.ClientIP),
+    )
+}
+
+// render decrypts the document and rasterizes a page; page 0 only counts the
+// pages
+func (v *SecureViewer) render(ctx context.Context, doc *models.Document, page int) (image.Image, int, error) {
+    if doc.ClientEncrypted() {
+        return nil, 0, fmt.Errorf("%w: content is end-to-end encrypted", ErrSecureViewerUnsupported)
+    }
+    if doc.ContentType != "application/pdf" && doc.ContentType != "image/jpeg" && doc.ContentType != "image/png" {
+        return nil, 0, fmt.Errorf("%w: %s", ErrSecureViewerUnsupported, doc.ContentType)
+    }
+
+    content, err := v.storage.RetrieveDocument(ctx, doc)
+    if err != nil {
+        return nil, 0, err
+    }
+    var data []byte
+    if pooled, ok := content.(*utils.PooledReader); ok {
+        defer pooled.Close()
+        data = pooled.Bytes()
+    } else if data, err = io.ReadAll(content); err != nil {
+        return nil, 0, fmt.Errorf("failed to read document: %w", err)
+    }
+
+    if doc.ContentType != "application/pdf" {
+        if page > 1 {
+            return nil, 1, ErrPageOutOfRange
+        }
+        if page == 0 {
+            return nil, 1, nil
+        }
+        img, _, err := image.Decode(bytes.NewReader(data))
+        if err != nil {
+            return nil, 1, fmt.Errorf("failed to decode image: %w", err)
+        }
+        return img, 1, nil
+    }
+
+    pdf, err := fitz.NewFromMemory(data)
+    if err != nil {
+        return nil, 0, fmt.Errorf("failed to open PDF: %w", err)
+    }
+    defer pdf.Close()
+
+    count := pdf.NumPage()
+    if page == 0 {
+        return nil, count, nil
+    }
+    if page < 0 || page > count {
+        return nil, count, ErrPageOutOfRange
+    }
+    img, err := pdf.ImageDPI(page-1, v.config.SecureViewerConfig.DPI)
+    if err != nil {
+        return nil, count, fmt.Errorf("failed to render page %d: %w", page, err)
+    }
+    return img, count, nil
+}
+
+// watermark tiles the label diagonally across a copy of the page, scaled so
+// each copy spans about half the page width and cannot be cropped away
+func watermark(page image.Image, label string) *image.RGBA {
+    bounds := page.Bounds()
+    dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+    draw.Draw(dst, dst.Bounds(), page, bounds.Min, draw.Src)
+
+    face := basicfont.Face7x13
+    width := font.MeasureString(face, label).Ceil()
+    if width == 0 {
+        return dst
+    }
+    text := image.NewAlpha(image.Rect(0, 0, width, face.Height))
+    drawer := font.Drawer{Dst: text, Src: image.Opaque, Face: face, Dot: fixed.P(0, face.Ascent)}
+    drawer.DrawString(label)
+
+    scale := float64(dst.Bounds().Dx()) / 2 / float64(width)
+    if scale < 1 {
+        scale = 1
+    }
+    mask := image.NewAlpha(image.Rect(0, 0, int(float64(width)*scale), int(float64(face.Height)*scale)))
+    xdraw.BiLinear.Scale(mask, mask.Bounds(), text, text.Bounds(), xdraw.Src, nil)
+
+    ink := image.NewUniform(color.NRGBA{R: 160, G: 0, B: 0, A: watermarkOpacity})
+    labelWidth, labelHeight := mask.Bounds().Dx(), mask.Bounds().Dy()
+    for row, y := 0, 0; y < dst.Bounds().Dy(); row, y = row+1, y+labelHeight*4 {
+        // Shift each row so the copies form diagonals down the page
+        for x := (row%3)*labelWidth/3 - labelWidth; x < dst.Bounds().Dx(); x += labelWidth * 3 / 2 {
+            draw.DrawMask(dst, image.Rect(x, y, x+labelWidth, y+labelHeight), ink, image.Point{}, mask, image.Point{}, draw.Over)
+        }
+    }
+    return dst
+}
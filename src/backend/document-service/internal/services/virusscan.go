@@ -0,0 +1,246 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "strings"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// clamdChunkSize is the size of the chunks content is streamed to clamd in
+const clamdChunkSize = 64 << 10
+
+// maxClamdReply bounds the reply read from clamd
+const maxClamdReply = 4096
+
+var (
+    // ErrMalwareDetected is returned for uploads the virus scanner found
+    // malware in
+    ErrMalwareDetected = errors.New("document content contains malware")
+    // ErrScannerUnavailable is returned when the virus scanner could not
+    // scan an upload; the same upload may succeed later
+    ErrScannerUnavailable = errors.New("virus scanner is unavailable")
+)
+
+// cachedVerdict is a verdict remembered for content of one hash
+type cachedVerdict struct {
+    verdict   string
+    signature string
+}
+
+// VirusScanner scans uploads with a ClamAV daemon over its INSTREAM command.
+// Verdicts are cached by content hash and signature database version, so
+// identical files of a batch import are scanned once, and a new database
+// drops every cached verdict
+type VirusScanner struct {
+    address    string
+    timeout    time.Duration
+    versionTTL time.Duration
+    cache      *ttlCache[cachedVerdict]
+    dialer     net.Dialer
+    logger     *zap.Logger
+
+    mu        sync.Mutex
+    version   string
+    checkedAt time.Time
+}
+
+// NewVirusScanner creates the ClamAV client, or returns nil when virus
+// scanning is disabled
+func NewVirusScanner(cfg *config.Config, logger *zap.Logger) (*VirusScanner, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.VirusScanConfig.Enabled {
+        return nil, nil
+    }
+
+    scanner := &VirusScanner{
+        address:    cfg.VirusScanConfig.Address,
+        timeout:    cfg.VirusScanConfig.Timeout,
+        versionTTL: cfg.VirusScanConfig.VersionCheckInterval,
+        logger:     logger.With(zap.String("component", "virus_scan")),
+    }
+    if cfg.VirusScanConfig.CacheTTL > 0 {
+        scanner.cache = newTTLCache[cachedVerdict](cfg.VirusScanConfig.CacheTTL)
+    }
+    return scanner, nil
+}
+
+// Ping checks clamd answers and refreshes the signature database version
+func (s *VirusScanner) Ping(ctx context.Context) error {
+    _, err := s.refreshVersion(ctx, true)
+    return err
+}
+
+// Scan returns the verdict on content, reusing the verdict on identical
+// content reached with the same signature database
+func (s *VirusScanner) Scan(ctx context.Context, content []byte) (*models.VirusScan, error) {
+    version, err := s.refreshVersion(ctx, false)
+    if err != nil {
+        virusScans.WithLabelValues("error").Inc()
+        return nil, err
+    }
+
+    sum := sha256.Sum256(content)
+    key := hex.EncodeToString(sum[:]) + "/" + version
+    scan := &models.VirusScan{DefinitionsVersion: version, ScannedAt: time.Now()}
+    if s.cache != nil {
+        if cached, ok := s.cache.Get(key); ok {
+            virusScanCache.WithLabelValues("hit").Inc()
+            virusScanCacheBytes.Add(float64(len(content)))
+            scan.Verdict, scan.Signature, scan.Cached = cached.verdict, cached.signature, true
+            return scan, nil
+        }
+        virusScanCache.WithLabelValues("miss").Inc()
+    }
+
+    startTime := time.Now()
+    signature, err := s.instream(ctx, content)
+    virusScanDuration.Observe(time.Since(startTime).Seconds())
+    if err != nil {
+        virusScans.WithLabelValues("error").Inc()
+        return nil, err
+    }
+    scan.Verdict, scan.Signature = models.VirusVerdictClean, signature
+    if signature != "" {
+        scan.Verdict = models.VirusVerdictInfected
+    }
+    virusScans.WithLabelValues(scan.Verdict).Inc()
+    if s.cache != nil {
+        s.cache.Set(key, cachedVerdict{verdict: scan.Verdict, signature: scan.Signature})
+    }
+    return scan, nil
+}
+
+// refreshVersion asks clamd for its signature database version when forced
+// or when the version last seen is stale. A new version drops every cached
+// verdict. Should clamd not answer, the version last seen is kept
+func (s *VirusScanner) refreshVersion(ctx context.Context, force bool) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if !force && s.version != "" && time.Since(s.checkedAt) < s.versionTTL {
+        return s.version, nil
+    }
+
+    reply, err := s.command(ctx, "zVERSION\x00", nil)
+    if err != nil {
+        if s.version != "" && !force {
+            s.logger.Warn("Failed to refresh virus definitions version", zap.Error(err))
+            return s.version, nil
+        }
+        return "", err
+    }
+    version := parseDefinitionsVersion(reply)
+    if s.version != "" && version != s.version {
+        dropped := 0
+        if s.cache != nil {
+            dropped = s.cache.Clear()
+        }
+        virusScanCacheInvalidations.Inc()
+        s.logger.Info("Virus definitions updated",
+            zap.String("previous_version", s.version),
+            zap.String("version", version),
+            zap.Int("dropped_verdicts", dropped),
+        )
+    }
+    s.version = version
+    s.checkedAt = time.Now()
+    return version, nil
+}
+
+// instream streams content to clamd, returning the name of the signature
+// found or an empty string for clean content
+func (s *VirusScanner) instream(ctx context.Context, content []byte) (string, error) {
+    reply, err := s.command(ctx, "zINSTREAM\x00", content)
+    if err != nil {
+        return "", err
+    }
+    // Replies are "stream: OK", "stream: <signature> FOUND" or "<reason> ERROR"
+    reply = strings.TrimPrefix(reply, "stream: ")
+    switch {
+    case reply == "OK":
+        return "", nil
+    case strings.HasSuffix(reply, " FOUND"):
+        return strings.TrimSuffix(reply, " FOUND"), nil
+    default:
+        return "", fmt.Errorf("%w: %s", ErrScannerUnavailable, reply)
+    }
+}
+
+// command sends a command to clamd, followed by content in length-prefixed
+// chunks when content is not nil, and returns its reply
+func (s *VirusScanner) command(ctx context.Context, command string, content []byte) (string, error) {
+    var reply string
+    err := utils.CallWithDeadline(ctx, config.DependencyVirusScan, s.timeout, func(ctx context.Context) error {
+        var err error
+        reply, err = s.exchange(ctx, command, content)
+        return err
+    })
+    return reply, err
+}
+
+// exchange runs one command on a new connection to clamd, which is bounded
+// by the deadline of ctx
+func (s *VirusScanner) exchange(ctx context.Context, command string, content []byte) (string, error) {
+    conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    if _, err := io.WriteString(conn, command); err != nil {
+        return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+    }
+    if content != nil {
+        var size [4]byte
+        for len(content) > 0 {
+            chunk := content[:min(len(content), clamdChunkSize)]
+            content = content[len(chunk):]
+            binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+            if _, err := conn.Write(size[:]); err != nil {
+                return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+            }
+            if _, err := conn.Write(chunk); err != nil {
+                return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+            }
+        }
+        // A zero-length chunk ends the stream
+        if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+            return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+        }
+    }
+
+    reply, err := io.ReadAll(io.LimitReader(conn, maxClamdReply))
+    if err != nil {
+        return "", fmt.Errorf("%w: %v", ErrScannerUnavailable, err)
+    }
+    return string(bytes.TrimRight(reply, "\x00\n")), nil
+}
+
+// parseDefinitionsVersion extracts the signature database version from the
+// reply to VERSION, "ClamAV <engine>/<database>/<date>"
+func parseDefinitionsVersion(reply string) string {
+    parts := strings.Split(reply, "/")
+    if len(parts) >= 2 {
+        return parts[1]
+    }
+    return reply
+}
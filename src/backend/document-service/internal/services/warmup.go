@@ -0,0 +1,149 @@
+package services
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// Warm-up check states
+const (
+    WarmupPending = "pending"
+    WarmupRunning = "running"
+    WarmupPassed  = "passed"
+    WarmupFailed  = "failed"
+)
+
+// WarmupCheck validates or pre-warms a dependency before traffic is accepted
+type WarmupCheck struct {
+    Name     string
+    Critical bool
+    Run      func(ctx context.Context) error
+}
+
+// WarmupCheckState is the progress of a single check
+type WarmupCheckState struct {
+    Name       string `json:"name"`
+    Critical   bool   `json:"critical"`
+    Status     string `json:"status"`
+    Error      string `json:"error,omitempty"`
+    DurationMS int64  `json:"duration_ms"`
+}
+
+// WarmupProgress is the state of the startup phase reported by the readiness probe
+type WarmupProgress struct {
+    Ready  bool               `json:"ready"`
+    Done   bool               `json:"done"`
+    Checks []WarmupCheckState `json:"checks"`
+}
+
+// Warmup runs startup checks in registration order, stopping at the first
+// failed critical check so the process fails fast instead of on a real request
+type Warmup struct {
+    mu      sync.RWMutex
+    checks  []WarmupCheck
+    states  []WarmupCheckState
+    done    bool
+    failed  bool
+    timeout time.Duration
+    logger  *zap.Logger
+}
+
+// NewWarmup creates an empty startup phase
+func NewWarmup(cfg *config.Config, logger *zap.Logger) (*Warmup, error) {
+    if cfg == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &Warmup{
+        timeout: cfg.StartupConfig.CheckTimeout,
+        logger:  logger,
+    }, nil
+}
+
+// Add registers a check; checks must be added before Run
+func (w *Warmup) Add(name string, critical bool, run func(ctx context.Context) error) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    w.checks = append(w.checks, WarmupCheck{Name: name, Critical: critical, Run: run})
+    w.states = append(w.states, WarmupCheckState{Name: name, Critical: critical, Status: WarmupPending})
+}
+
+// Run executes the checks, returning the error of the first failed critical check
+func (w *Warmup) Run(ctx context.Context) error {
+    defer func() {
+        w.mu.Lock()
+        w.done = true
+        w.mu.Unlock()
+    }()
+
+    for i, check := range w.checks {
+        w.setState(i, WarmupRunning, nil, 0)
+
+        checkCtx, cancel := context.WithTimeout(ctx, w.timeout)
+        startTime := time.Now()
+        err := check.Run(checkCtx)
+        cancel()
+        duration := time.Since(startTime)
+
+        if err == nil {
+            w.setState(i, WarmupPassed, nil, duration)
+            w.logger.Info("Startup check passed",
+                zap.String("check", check.Name),
+                zap.Duration("duration", duration),
+            )
+            continue
+        }
+
+        w.setState(i, WarmupFailed, err, duration)
+        if check.Critical {
+            w.mu.Lock()
+            w.failed = true
+            w.mu.Unlock()
+            return fmt.Errorf("startup check %s failed: %w", check.Name, err)
+        }
+        w.logger.Warn("Non-critical startup check failed",
+            zap.String("check", check.Name),
+            zap.Error(err),
+        )
+    }
+    return nil
+}
+
+// Ready reports whether every critical check has passed
+func (w *Warmup) Ready() bool {
+    w.mu.RLock()
+    defer w.mu.RUnlock()
+
+    return w.done && !w.failed
+}
+
+// Progress returns the state of every check
+func (w *Warmup) Progress() WarmupProgress {
+    w.mu.RLock()
+    defer w.mu.RUnlock()
+
+    return WarmupProgress{
+        Ready:  w.done && !w.failed,
+        Done:   w.done,
+        Checks: append([]WarmupCheckState(nil), w.states...),
+    }
+}
+
+func (w *Warmup) setState(index int, status string, err error, duration time.Duration) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+
+    w.states[index].Status = status
+    w.states[index].DurationMS = duration.Milliseconds()
+    if err != nil {
+        w.states[index].Error = err.Error()
+    }
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/tls"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "net/url"
+    "slices"
+    "strconv"
+    "strings"
+    "syscall"
+    "time"
+
+    "github.com/google/uuid" // v1.3.0
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// Outbox topic delivering events to tenant webhooks
+const (
+    TopicTenantWebhook = "webhooks.tenant_event"
+)
+
+// Headers of webhook deliveries
+const (
+    WebhookSignatureHeader = "X-Webhook-Signature"
+    WebhookEventHeader     = "X-Webhook-Event"
+)
+
+// webhookSecretPrefix marks webhook signing secrets so they are recognized
+// when leaked
+const webhookSecretPrefix = "whsec_"
+
+var (
+    ErrWebhookLimit         = errors.New("tenant reached its webhook subscription limit")
+    ErrInvalidWebhookURL    = errors.New("invalid webhook URL")
+    ErrWebhookAddressDenied = errors.New("webhook endpoint resolves to a non-public address")
+)
+
+// WebhookSubscriptionRequest creates or replaces a webhook subscription
+type WebhookSubscriptionRequest struct {
+    URL         string   `json:"url"`
+    Description string   `json:"description"`
+    Events      []string `json:"events"`
+}
+
+// webhookMessage is the outbox payload of an event for one subscription
+type webhookMessage struct {
+    SubscriptionID string              `json:"subscription_id"`
+    Event          models.WebhookEvent `json:"event"`
+}
+
+// TenantWebhooks lets tenant admins manage the webhook endpoints of their
+// tenant and delivers document events to them. Events are queued in the
+// outbox per subscription, so a failing endpoint is retried with backoff
+// without holding back the others. Every delivery is signed with HMAC-SHA256
+// over its timestamp and body, and recorded in the subscription's history
+type TenantWebhooks struct {
+    cfg        config.WebhooksConfig
+    webhooks   repository.WebhookRepository
+    outbox     repository.OutboxRepository
+    httpClient *http.Client
+    logger     *zap.Logger
+}
+
+// NewTenantWebhooks creates the tenant webhook service, or returns nil when
+// webhooks are disabled
+func NewTenantWebhooks(cfg *config.Config, webhooks repository.WebhookRepository, outbox repository.OutboxRepository, logger *zap.Logger) (*TenantWebhooks, error) {
+    if cfg == nil || webhooks == nil || outbox == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+    if !cfg.WebhooksConfig.Enabled {
+        return nil, nil
+    }
+
+    return &TenantWebhooks{
+        cfg:        cfg.WebhooksConfig,
+        webhooks:   webhooks,
+        outbox:     outbox,
+        httpClient: newWebhookClient(cfg.WebhooksConfig),
+        logger:     logger.With(zap.String("component", "webhooks")),
+    }, nil
+}
+
+// MayManage reports whether users with the role may manage the webhooks of
+// their tenant
+func (s *TenantWebhooks) MayManage(role string) bool {
+    return slices.Contains(s.cfg.AdminRoles, role)
+}
+
+// Create adds a subscription for the tenant and returns it with its signing
+// secret, which is not shown again
+func (s *TenantWebhooks) Create(ctx context.Context, tenantID, userID string, req WebhookSubscriptionRequest) (*models.WebhookSubscription, string, error) {
+    if err := s.validate(req); err != nil {
+        return nil, "", err
+    }
+
+    existing, err := s.webhooks.ListByTenant(ctx, tenantID)
+    if err != nil {
+        return nil, "", fmt.Errorf("failed to list webhook subscriptions: %w", err)
+    }
+    if len(existing) >= s.cfg.MaxSubscriptions {
+        return nil, "", ErrWebhookLimit
+    }
+
+    secret, err := newWebhookSecret()
+    if err != nil {
+        return nil, "", err
+    }
+    now := time.Now()
+    subscription := &models.WebhookSubscription{
+        ID:          uuid.NewString(),
+        TenantID:    tenantID,
+        URL:         req.URL,
+        Description: req.Description,
+        Events:      sortedEvents(req.Events),
+        Secret:      secret,
+        CreatedBy:   userID,
+        CreatedAt:   now,
+        UpdatedAt:   now,
+    }
+    if err := s.webhooks.Create(ctx, subscription); err != nil {
+        return nil, "", fmt.Errorf("failed to store webhook subscription: %w", err)
+    }
+    return subscription, secret, nil
+}
+
+// List returns the subscriptions of a tenant, oldest first
+func (s *TenantWebhooks) List(ctx context.Context, tenantID string) ([]*models.WebhookSubscription, error) {
+    return s.webhooks.ListByTenant(ctx, tenantID)
+}
+
+// Get returns a subscription of the tenant; subscriptions of other tenants
+// are reported as not found
+func (s *TenantWebhooks) Get(ctx context.Context, tenantID, id string) (*models.WebhookSubscription, error) {
+    subscription, err := s.webhooks.Get(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if subscription.TenantID != tenantID {
+        return nil, repository.ErrWebhookNotFound
+    }
+    return subscription, nil
+}
+
+// Update replaces the endpoint, description and events of a subscription
+func (s *TenantWebhooks) Update(ctx context.Context, tenantID, id string, req WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+    if err := s.validate(req); err != nil {
+        return nil, err
+    }
+    return s.modify(ctx, tenantID, id, func(subscription *models.WebhookSubscription, now time.Time) {
+        subscription.URL = req.URL
+        subscription.Description = req.Description
+        subscription.Events = sortedEvents(req.Events)
+        subscription.UpdatedAt = now
+    })
+}
+
+// Delete removes a subscription; events still queued for it are dropped
+func (s *TenantWebhooks) Delete(ctx context.Context, tenantID, id string) error {
+    if _, err := s.Get(ctx, tenantID, id); err != nil {
+        return err
+    }
+    return s.webhooks.Delete(ctx, id)
+}
+
+// Pause stops deliveries to a subscription. Events raised or retried while
+// it is paused are recorded as skipped and not sent later
+func (s *TenantWebhooks) Pause(ctx context.Context, tenantID, id string) (*models.WebhookSubscription, error) {
+    return s.modify(ctx, tenantID, id, func(subscription *models.WebhookSubscription, now time.Time) {
+        subscription.Pause(now)
+    })
+}
+
+// Resume restarts deliveries to a paused subscription
+func (s *TenantWebhooks) Resume(ctx context.Context, tenantID, id string) (*models.WebhookSubscription, error) {
+    return s.modify(ctx, tenantID, id, func(subscription *models.WebhookSubscription, now time.Time) {
+        subscription.Resume(now)
+    })
+}
+
+// RotateSecret gives a subscription a new signing secret, returned once.
+// Deliveries carry a signature with the previous secret too for the grace
+// period, so receivers can switch without rejecting events
+func (s *TenantWebhooks) RotateSecret(ctx context.Context, tenantID, id string) (*models.WebhookSubscription, string, error) {
+    secret, err := newWebhookSecret()
+    if err != nil {
+        return nil, "", err
+    }
+    subscription, err := s.modify(ctx, tenantID, id, func(subscription *models.WebhookSubscription, now time.Time) {
+        subscription.RotateSecret(secret, now, s.cfg.SecretGracePeriod)
+    })
+    if err != nil {
+        return nil, "", err
+    }
+    return subscription, secret, nil
+}
+
+// Test sends a webhook.test event to a subscription now, even while it is
+// paused, and returns the recorded delivery. A failed delivery is reported in
+// its outcome, not as an error
+func (s *TenantWebhooks) Test(ctx context.Context, tenantID, id string) (*models.WebhookDelivery, error) {
+    subscription, err := s.Get(ctx, tenantID, id)
+    if err != nil {
+        return nil, err
+    }
+
+    event := models.WebhookEvent{
+        ID:         uuid.NewString(),
+        Type:       models.WebhookEventTest,
+        TenantID:   tenantID,
+        OccurredAt: time.Now().UTC(),
+    }
+    delivery, _ := s.send(ctx, subscription, event, 1, true)
+    return delivery, nil
+}
+
+// Deliveries returns the delivery history of a subscription of the tenant,
+// most recent first
+func (s *TenantWebhooks) Deliveries(ctx context.Context, tenantID, id string) ([]*models.WebhookDelivery, error) {
+    if _, err := s.Get(ctx, tenantID, id); err != nil {
+        return nil, err
+    }
+    return s.webhooks.ListDeliveries(ctx, id)
+}
+
+// OnIngested is the pipeline hook announcing processed documents
+func (s *TenantWebhooks) OnIngested(ctx context.Context, doc *models.Document) error {
+    return s.Publish(ctx, models.WebhookEventDocumentProcessed, doc)
+}
+
+// Publish queues an event about a document for every active subscription of
+// its tenant to the event. Documents without a tenant and synthetic documents
+// are not announced. A nil *TenantWebhooks publishes nothing
+func (s *TenantWebhooks) Publish(ctx context.Context, eventType string, doc *models.Document) error {
+    if s == nil || doc.TenantID == "" || doc.Synthetic {
+        return nil
+    }
+
+    subscriptions, err := s.webhooks.ListByTenant(ctx, doc.TenantID)
+    if err != nil {
+        return fmt.Errorf("failed to list webhook subscriptions: %w", err)
+    }
+
+    event := models.WebhookEvent{
+        ID:         uuid.NewString(),
+        Type:       eventType,
+        TenantID:   doc.TenantID,
+        OccurredAt: time.Now().UTC(),
+        Data: &models.WebhookDocumentData{
+            DocumentID:   doc.ID,
+            EnrollmentID: doc.EnrollmentID,
+            DocumentType: doc.DocumentType,
+            Status:       doc.Status,
+        },
+    }
+    for _, subscription := range subscriptions {
+        if !subscription.Subscribed(eventType) {
+            continue
+        }
+        msg, err := newOutboxMessage(TopicTenantWebhook, subscription.ID+"/"+event.ID, webhookMessage{
+            SubscriptionID: subscription.ID,
+            Event:          event,
+        })
+        if err == nil {
+            err = s.outbox.Enqueue(ctx, msg)
+        }
+        if err != nil && !errors.Is(err, repository.ErrDuplicateMessage) {
+            return fmt.Errorf("failed to queue webhook event: %w", err)
+        }
+    }
+    return nil
+}
+
+// Deliver is the outbox handler posting an event to its subscription. Events
+// of deleted subscriptions are dropped, and those of paused subscriptions are
+// recorded as skipped; failed deliveries are retried by the outbox
+func (s *TenantWebhooks) Deliver(ctx context.Context, msg *models.OutboxMessage) error {
+    var payload webhookMessage
+    if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+        return fmt.Errorf("failed to decode webhook event: %w", err)
+    }
+
+    subscription, err := s.webhooks.Get(ctx, payload.SubscriptionID)
+    if errors.Is(err, repository.ErrWebhookNotFound) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to load webhook subscription: %w", err)
+    }
+
+    if !subscription.Subscribed(payload.Event.Type) {
+        s.record(ctx, &models.WebhookDelivery{
+            ID:             uuid.NewString(),
+            SubscriptionID: subscription.ID,
+            EventID:        payload.Event.ID,
+            EventType:      payload.Event.Type,
+            Attempt:        msg.Attempts,
+            Outcome:        models.WebhookDeliverySkipped,
+            AttemptedAt:    time.Now(),
+        })
+        return nil
+    }
+
+    _, err = s.send(ctx, subscription, payload.Event, msg.Attempts, false)
+    return err
+}
+
+// send posts an event to a subscription and records the delivery
+func (s *TenantWebhooks) send(ctx context.Context, subscription *models.WebhookSubscription, event models.WebhookEvent, attempt int, test bool) (*models.WebhookDelivery, error) {
+    delivery := &models.WebhookDelivery{
+        ID:             uuid.NewString(),
+        SubscriptionID: subscription.ID,
+        EventID:        event.ID,
+        EventType:      event.Type,
+        Attempt:        attempt,
+        Outcome:        models.WebhookDeliveryFailed,
+        Test:           test,
+        AttemptedAt:    time.Now(),
+    }
+
+    err := s.post(ctx, subscription, event, delivery)
+    delivery.DurationMS = time.Since(delivery.AttemptedAt).Milliseconds()
+    if err == nil {
+        delivery.Outcome = models.WebhookDeliveryDelivered
+    } else {
+        delivery.Error = err.Error()
+    }
+    s.record(ctx, delivery)
+    return delivery, err
+}
+
+// post sends the signed event, recording the status code the endpoint
+// answered with
+func (s *TenantWebhooks) post(ctx context.Context, subscription *models.WebhookSubscription, event models.WebhookEvent, delivery *models.WebhookDelivery) error {
+    body, err := json.Marshal(event)
+    if err != nil {
+        return fmt.Errorf("failed to encode webhook event: %w", err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+    if err != nil {
+        return fmt.Errorf("failed to build webhook request: %w", err)
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Idempotency-Key", event.ID)
+    req.Header.Set(WebhookEventHeader, event.Type)
+    req.Header.Set(WebhookSignatureHeader, SignWebhook(subscription.Secrets(time.Now()), time.Now(), body))
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("webhook request failed: %w", err)
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+    delivery.StatusCode = resp.StatusCode
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+    }
+    return nil
+}
+
+// record adds a delivery to the history of its subscription
+func (s *TenantWebhooks) record(ctx context.Context, delivery *models.WebhookDelivery) {
+    webhookDeliveries.WithLabelValues(delivery.EventType, delivery.Outcome).Inc()
+    if err := s.webhooks.AddDelivery(ctx, delivery, s.cfg.HistorySize); err != nil && !errors.Is(err, repository.ErrWebhookNotFound) {
+        s.logger.Error("Failed to record webhook delivery",
+            zap.String("subscription_id", delivery.SubscriptionID),
+            zap.String("event_id", delivery.EventID),
+            zap.Error(err),
+        )
+    }
+}
+
+// modify applies a change to a subscription of the tenant and stores it
+func (s *TenantWebhooks) modify(ctx context.Context, tenantID, id string, change func(*models.WebhookSubscription, time.Time)) (*models.WebhookSubscription, error) {
+    subscription, err := s.Get(ctx, tenantID, id)
+    if err != nil {
+        return nil, err
+    }
+    change(subscription, time.Now())
+    if err := s.webhooks.Update(ctx, subscription); err != nil {
+        return nil, err
+    }
+    return subscription, nil
+}
+
+// validate checks the endpoint and events of a subscription request.
+// Endpoints must use https unless private networks are allowed, and may not
+// carry credentials
+func (s *TenantWebhooks) validate(req WebhookSubscriptionRequest) error {
+    endpoint, err := url.Parse(req.URL)
+    if err != nil {
+        return fmt.Errorf("%w: %v", ErrInvalidWebhookURL, err)
+    }
+    if endpoint.Scheme != "https" && !(s.cfg.AllowPrivateNetworks && endpoint.Scheme == "http") {
+        return fmt.Errorf("%w: scheme must be https", ErrInvalidWebhookURL)
+    }
+    if endpoint.Hostname() == "" || endpoint.User != nil {
+        return fmt.Errorf("%w: a host without credentials is required", ErrInvalidWebhookURL)
+    }
+    return models.ValidateWebhookEvents(req.Events)
+}
+
+// SignWebhook returns the signature header of a delivery of body at
+// timestamp: t=<unix seconds> followed by one v1=<hex HMAC-SHA256 of
+// "<t>.<body>"> per secret. Receivers accept the delivery when any v1 matches
+// the signature computed with their secret
+func SignWebhook(secrets []string, timestamp time.Time, body []byte) string {
+    t := strconv.FormatInt(timestamp.Unix(), 10)
+    parts := []string{"t=" + t}
+    for _, secret := range secrets {
+        mac := hmac.New(sha256.New, []byte(secret))
+        mac.Write([]byte(t))
+        mac.Write([]byte("."))
+        mac.Write(body)
+        parts = append(parts, "v1="+hex.EncodeToString(mac.Sum(nil)))
+    }
+    return strings.Join(parts, ",")
+}
+
+// sortedEvents returns the events sorted without duplicates
+func sortedEvents(events []string) []string {
+    sorted := slices.Clone(events)
+    slices.Sort(sorted)
+    return slices.Compact(sorted)
+}
+
+// newWebhookSecret generates a random signing secret
+func newWebhookSecret() (string, error) {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil {
+        return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+    }
+    return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// newWebhookClient builds the client posting to tenant endpoints. Endpoints
+// are chosen by tenants, so the client dials public addresses only, checked
+// on the resolved address to defeat DNS rebinding, bypasses proxies and does
+// not follow redirects
+func newWebhookClient(cfg config.WebhooksConfig) *http.Client {
+    dialer := &net.Dialer{
+        Timeout: cfg.Timeout,
+        Control: func(network, address string, conn syscall.RawConn) error {
+            if cfg.AllowPrivateNetworks {
+                return nil
+            }
+            host, _, err := net.SplitHostPort(address)
+            if err != nil {
+                return err
+            }
+            if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+                return fmt.Errorf("%w: %s", ErrWebhookAddressDenied, host)
+            }
+            return nil
+        },
+    }
+
+    tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+    utils.ApplyTLSPolicy(tlsConfig)
+
+    return &http.Client{
+        Timeout: cfg.Timeout,
+        Transport: &http.Transport{
+            DialContext:         dialer.DialContext,
+            TLSClientConfig:     tlsConfig,
+            TLSHandshakeTimeout: cfg.Timeout,
+            MaxIdleConnsPerHost: 2,
+            IdleConnTimeout:     time.Minute,
+        },
+        CheckRedirect: func(req *http.Request, via []*http.Request) error {
+            return http.ErrUseLastResponse
+        },
+    }
+}
+
+// publicAddress reports whether ip is routable on the public internet
+func publicAddress(ip net.IP) bool {
+    return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+        !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+        !ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
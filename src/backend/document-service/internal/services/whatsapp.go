@@ -0,0 +1,315 @@
+package services
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "strings"
+
+    "go.uber.org/zap" // v1.24.0
+
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+    "github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// WhatsApp reply template keys, mapped to approved template names in configuration
+const (
+    whatsappTemplateReceived = "received"
+    whatsappTemplateRejected = "rejected"
+    whatsappTemplateFailed   = "failed"
+)
+
+// whatsappConversionReasons explains conversion failures to beneficiaries
+var whatsappConversionReasons = map[string]string{
+    ConversionReasonPasswordProtected: "o documento está protegido por senha; remova a senha e envie novamente",
+    ConversionReasonLegacyFormat:      "salve o documento como .docx ou PDF e envie novamente",
+    ConversionReasonCorrupt:           "não foi possível abrir o documento; exporte para PDF e envie novamente",
+    ConversionReasonUnsupported:       "envie o documento em PDF",
+    ConversionReasonTooManyPages:      "o documento tem páginas demais; divida-o e envie as partes separadamente",
+}
+
+var (
+    ErrWhatsAppMissingReference = errors.New("message does not reference an enrollment")
+    ErrWhatsAppSenderMismatch   = errors.New("sender does not match enrollment phone")
+    ErrWhatsAppMediaDownload    = errors.New("failed to download whatsapp media")
+)
+
+// WhatsAppMediaMessage is an inbound media message received through the WhatsApp webhook
+type WhatsAppMediaMessage struct {
+    MessageID string
+    From      string
+    MediaID   string
+    MimeType  string
+    Filename  string
+    Caption   string
+}
+
+// WhatsAppService ingests documents sent through the WhatsApp Business Cloud API
+type WhatsAppService struct {
+    cfg         config.WhatsAppConfig
+    httpClient  *http.Client
+    maxSize     int64
+    pipeline    DocumentIngester
+    enrollments *EnrollmentClient
+    logger      *zap.Logger
+}
+
+// NewWhatsAppService creates a new WhatsApp ingestion service
+func NewWhatsAppService(cfg *config.Config, pipeline DocumentIngester, enrollments *EnrollmentClient, logger *zap.Logger) (*WhatsAppService, error) {
+    if cfg == nil || pipeline == nil || enrollments == nil || logger == nil {
+        return nil, errors.New("required dependencies cannot be nil")
+    }
+
+    return &WhatsAppService{
+        cfg:         cfg.WhatsAppConfig,
+        httpClient:  &http.Client{Timeout: cfg.WhatsAppConfig.MediaTimeout},
+        maxSize:     cfg.ServiceConfig.Channel(models.ChannelWhatsApp).MaxFileSize,
+        pipeline:    pipeline,
+        enrollments: enrollments,
+        logger:      logger,
+    }, nil
+}
+
+// HandleMediaMessage validates the sender against the referenced enrollment,
+// downloads the media and ingests it, replying with a templated status message
+func (s *WhatsAppService) HandleMediaMessage(ctx context.Context, msg WhatsAppMediaMessage) error {
+    enrollmentID, documentType := s.parseCaption(msg.Caption)
+    if enrollmentID == "" {
+        s.finish(ctx, msg, whatsappTemplateRejected, "informe o número da proposta na legenda")
+        return ErrWhatsAppMissingReference
+    }
+
+    enrollment, err := s.enrollments.GetEnrollment(ctx, enrollmentID)
+    if err != nil {
+        if errors.Is(err, ErrEnrollmentNotFound) {
+            s.finish(ctx, msg, whatsappTemplateRejected, "proposta não encontrada")
+            return err
+        }
+        s.finish(ctx, msg, whatsappTemplateFailed, "tente novamente mais tarde")
+        return fmt.Errorf("failed to fetch enrollment: %w", err)
+    }
+
+    // Never reveal whether the enrollment exists to a sender that does not own it
+    expectedPhone := normalizePhone(enrollment.BeneficiaryPhone)
+    if expectedPhone == "" || expectedPhone != normalizePhone(msg.From) {
+        s.finish(ctx, msg, whatsappTemplateRejected, "proposta não encontrada")
+        return ErrWhatsAppSenderMismatch
+    }
+
+    content, contentType, err := s.downloadMedia(ctx, msg.MediaID)
+    if errors.Is(err, models.ErrInvalidSize) {
+        s.finish(ctx, msg, whatsappTemplateRejected, "formato ou tamanho de arquivo não suportado")
+        return err
+    }
+    if err != nil {
+        s.finish(ctx, msg, whatsappTemplateFailed, "não foi possível baixar o arquivo")
+        return err
+    }
+    if contentType == "" {
+        contentType = msg.MimeType
+    }
+
+    doc, err := s.pipeline.Ingest(ctx, IngestRequest{
+        EnrollmentID: enrollment.ID,
+        DocumentType: documentType,
+        Filename:     s.filename(msg, contentType),
+        ContentType:  contentType,
+        Channel:      models.ChannelWhatsApp,
+        SubmittedBy:  "whatsapp:" + normalizePhone(msg.From),
+        Content:      bytes.NewReader(content),
+        Size:         int64(len(content)),
+    })
+    if err != nil {
+        if errors.Is(err, models.ErrInvalidContentType) || errors.Is(err, models.ErrInvalidSize) || errors.Is(err, ErrEmptyContent) {
+            s.finish(ctx, msg, whatsappTemplateRejected, "formato ou tamanho de arquivo não suportado")
+            return err
+        }
+        if errors.Is(err, ErrMalwareDetected) {
+            s.finish(ctx, msg, whatsappTemplateRejected, "arquivo bloqueado pelo antivírus")
+            return err
+        }
+        if errors.Is(err, ErrDisarmFailed) {
+            s.finish(ctx, msg, whatsappTemplateRejected, "PDF com conteúdo ativo; imprima como PDF e envie novamente")
+            return err
+        }
+        var conversionErr *ConversionError
+        if errors.As(err, &conversionErr) && errors.Is(err, ErrConversionFailed) {
+            s.finish(ctx, msg, whatsappTemplateRejected, whatsappConversionReasons[conversionErr.Reason])
+            return err
+        }
+        s.finish(ctx, msg, whatsappTemplateFailed, "tente novamente mais tarde")
+        return fmt.Errorf("failed to ingest whatsapp media: %w", err)
+    }
+
+    s.finish(ctx, msg, whatsappTemplateReceived, doc.ID)
+    return nil
+}
+
+// parseCaption extracts "<enrollment id> [document type]" from the media caption
+func (s *WhatsAppService) parseCaption(caption string) (string, string) {
+    fields := strings.Fields(caption)
+    if len(fields) == 0 {
+        return "", ""
+    }
+
+    documentType := s.cfg.DefaultDocumentType
+    if len(fields) > 1 {
+        documentType = strings.ToLower(fields[1])
+    }
+    return fields[0], documentType
+}
+
+// filename returns the original filename or derives one for media without it
+func (s *WhatsAppService) filename(msg WhatsAppMediaMessage, contentType string) string {
+    if msg.Filename != "" {
+        return msg.Filename
+    }
+
+    ext := ""
+    if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+        ext = exts[0]
+    }
+    return fmt.Sprintf("whatsapp-%s%s", msg.MessageID, ext)
+}
+
+// downloadMedia resolves a media ID to its download URL and fetches the
+// content, refusing media larger than the channel accepts without reading it
+func (s *WhatsAppService) downloadMedia(ctx context.Context, mediaID string) ([]byte, string, error) {
+    var media struct {
+        URL      string `json:"url"`
+        MimeType string `json:"mime_type"`
+    }
+    if err := s.doJSON(ctx, http.MethodGet, fmt.Sprintf("%s/%s", s.cfg.APIBaseURL, mediaID), nil, &media); err != nil {
+        return nil, "", fmt.Errorf("%w: %v", ErrWhatsAppMediaDownload, err)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, media.URL, nil)
+    if err != nil {
+        return nil, "", fmt.Errorf("%w: %v", ErrWhatsAppMediaDownload, err)
+    }
+    req.Header.Set("Authorization", "Bearer "+s.cfg.AccessToken)
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return nil, "", fmt.Errorf("%w: %v", ErrWhatsAppMediaDownload, err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, "", fmt.Errorf("%w: status %d", ErrWhatsAppMediaDownload, resp.StatusCode)
+    }
+
+    if resp.ContentLength > s.maxSize {
+        return nil, "", fmt.Errorf("%w: media of %d bytes", models.ErrInvalidSize, resp.ContentLength)
+    }
+
+    // One byte past the limit tells an oversized body without a length apart
+    content, err := io.ReadAll(io.LimitReader(resp.Body, s.maxSize+1))
+    if err != nil {
+        return nil, "", fmt.Errorf("%w: %v", ErrWhatsAppMediaDownload, err)
+    }
+    if int64(len(content)) > s.maxSize {
+        return nil, "", fmt.Errorf("%w: media larger than %d bytes", models.ErrInvalidSize, s.maxSize)
+    }
+    return content, media.MimeType, nil
+}
+
+// finish records the outcome and replies to the sender with the matching template
+func (s *WhatsAppService) finish(ctx context.Context, msg WhatsAppMediaMessage, templateKey, detail string) {
+    whatsappMessages.WithLabelValues(templateKey).Inc()
+
+    if err := s.sendTemplate(ctx, msg.From, templateKey, detail); err != nil {
+        s.logger.Warn("Failed to send WhatsApp status reply",
+            zap.String("message_id", msg.MessageID),
+            zap.String("template", templateKey),
+            zap.Error(err),
+        )
+    }
+}
+
+// sendTemplate sends an approved template message with a single body parameter
+func (s *WhatsAppService) sendTemplate(ctx context.Context, to, templateKey, detail string) error {
+    templateName, ok := s.cfg.StatusTemplates[templateKey]
+    if !ok {
+        return fmt.Errorf("no template configured for %q", templateKey)
+    }
+
+    payload := map[string]interface{}{
+        "messaging_product": "whatsapp",
+        "to":                to,
+        "type":              "template",
+        "template": map[string]interface{}{
+            "name":     templateName,
+            "language": map[string]string{"code": s.cfg.TemplateLanguage},
+            "components": []map[string]interface{}{
+                {
+                    "type": "body",
+                    "parameters": []map[string]string{
+                        {"type": "text", "text": detail},
+                    },
+                },
+            },
+        },
+    }
+
+    return s.doJSON(ctx, http.MethodPost, fmt.Sprintf("%s/%s/messages", s.cfg.APIBaseURL, s.cfg.PhoneNumberID), payload, nil)
+}
+
+// doJSON performs an authenticated Graph API call with JSON request and response bodies
+func (s *WhatsAppService) doJSON(ctx context.Context, method, endpoint string, body, out interface{}) error {
+    var reader io.Reader
+    if body != nil {
+        encoded, err := json.Marshal(body)
+        if err != nil {
+            return fmt.Errorf("failed to encode request: %w", err)
+        }
+        reader = bytes.NewReader(encoded)
+    }
+
+    req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+    if err != nil {
+        return fmt.Errorf("failed to build request: %w", err)
+    }
+    req.Header.Set("Authorization", "Bearer "+s.cfg.AccessToken)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+
+    resp, err := s.httpClient.Do(req)
+    if err != nil {
+        return fmt.Errorf("graph api request failed: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("graph api returned status %d", resp.StatusCode)
+    }
+
+    if out != nil {
+        if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+            return fmt.Errorf("failed to decode response: %w", err)
+        }
+    }
+    return nil
+}
+
+// normalizePhone reduces a phone number to digits, adding the Brazilian
+// country code to national numbers so it matches WhatsApp sender IDs.
+// WhatsApp reports many Brazilian mobiles without the ninth digit, so it is
+// added to mobile numbers of eight digits, which start with 6 to 9 after the
+// area code where landlines start with 2 to 5
+func normalizePhone(phone string) string {
+    normalized := digitsOnly(phone)
+    if len(normalized) == 10 || len(normalized) == 11 {
+        normalized = "55" + normalized
+    }
+    if len(normalized) == 12 && strings.HasPrefix(normalized, "55") && normalized[4] >= '6' {
+        normalized = normalized[:4] + "9" + normalized[4:]
+    }
+    return normalized
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+
+	"golang.org/x/crypto/chacha20poly1305" // v0.12.0
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// Values of security.encryption_algorithm
+const (
+	ConfigAlgorithmAES     = "AES-256"
+	ConfigAlgorithmXChaCha = "XCHACHA20-POLY1305"
+)
+
+const (
+	// aeadTagSize is the authentication tag appended by every supported AEAD
+	aeadTagSize = 16
+	// streamCounterSize is the nonce suffix of a stream chunk: a 4 byte chunk
+	// counter and a final-chunk flag. The rest of the nonce is random
+	streamCounterSize = 5
+	// collisionBoundBits keeps the probability that any two random nonces
+	// drawn under one key collide below 2^-32, the bound NIST SP 800-38D sets
+	// for GCM with random IVs
+	collisionBoundBits = 32
+)
+
+var (
+	ErrKeyUsageLimit = errors.New("data key reached its message limit")
+
+	// Process-wide nonce accounting exported as metrics
+	sealedMessages atomic.Uint64
+	limitRotations atomic.Uint64
+)
+
+// keyCiphers are the AEADs of one data key. XChaCha20-Poly1305 uses a subkey
+// derived with HKDF, so the raw key is never shared between algorithms. Go's
+// AEADs keep no per-call state and are shared by concurrent callers
+type keyCiphers struct {
+	gcm     cipher.AEAD
+	xchacha cipher.AEAD
+	// messages counts the random nonces drawn under the key by this process
+	messages atomic.Uint64
+}
+
+// newKeyCiphers builds the ciphers of a raw data key and zeroes the key
+func newKeyCiphers(key []byte) (*keyCiphers, error) {
+	defer zero(key)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher block: %w", ErrKeyManagement)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM cipher: %w", ErrKeyManagement)
+	}
+
+	subkey := make([]byte, chacha20poly1305.KeySize)
+	defer zero(subkey)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(models.EncryptionAlgorithmXChaCha)), subkey); err != nil {
+		return nil, fmt.Errorf("failed to derive XChaCha20 key: %w", ErrKeyManagement)
+	}
+	xchacha, err := chacha20poly1305.NewX(subkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create XChaCha20-Poly1305 cipher: %w", ErrKeyManagement)
+	}
+
+	return &keyCiphers{gcm: gcm, xchacha: xchacha}, nil
+}
+
+// forAlgorithm returns the cipher of an encryption algorithm
+func (k *keyCiphers) forAlgorithm(algorithm string) (cipher.AEAD, error) {
+	switch algorithm {
+	case models.EncryptionAlgorithmGCM, models.EncryptionAlgorithmGCMStream:
+		return k.gcm, nil
+	case models.EncryptionAlgorithmXChaCha, models.EncryptionAlgorithmXChaChaStream:
+		return k.xchacha, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidMetadata, algorithm)
+	}
+}
+
+// reserve accounts for one random nonce drawn under the key, failing once the
+// key has sealed as many messages as the algorithm's nonce space allows
+func (k *keyCiphers) reserve(cfg *config.Config, algorithm string) error {
+	if k.messages.Add(1) > messageLimit(cfg, algorithm) {
+		return ErrKeyUsageLimit
+	}
+	sealedMessages.Add(1)
+	return nil
+}
+
+// messageLimit returns how many messages may be sealed under one key with an
+// algorithm before its random nonces risk colliding: for b random bits,
+// 2^((b-32)/2). That is the NIST limit of 2^32 for whole-object GCM, 2^12 for
+// GCM streams with their 56 bit random prefix, and no practical limit for
+// XChaCha20. security.max_messages_per_key lowers it further
+func messageLimit(cfg *config.Config, algorithm string) uint64 {
+	bits := 8 * nonceSize(algorithm)
+	if isStreamAlgorithm(algorithm) {
+		bits -= 8 * streamCounterSize
+	}
+
+	limit := uint64(math.MaxUint64)
+	if exponent := (bits - collisionBoundBits) / 2; exponent < 64 {
+		limit = 1 << uint(exponent)
+	}
+	if max := cfg.SecurityConfig.MaxMessagesPerKey; max > 0 && uint64(max) < limit {
+		limit = uint64(max)
+	}
+	return limit
+}
+
+// contentAlgorithm returns the algorithm new whole objects are sealed with
+func contentAlgorithm(cfg *config.Config) string {
+	if cfg.SecurityConfig.EncryptionAlgorithm == ConfigAlgorithmXChaCha {
+		return models.EncryptionAlgorithmXChaCha
+	}
+	return models.EncryptionAlgorithmGCM
+}
+
+// streamAlgorithm returns the algorithm new chunked objects are sealed with
+func streamAlgorithm(cfg *config.Config) string {
+	if cfg.SecurityConfig.EncryptionAlgorithm == ConfigAlgorithmXChaCha {
+		return models.EncryptionAlgorithmXChaChaStream
+	}
+	return models.EncryptionAlgorithmGCMStream
+}
+
+func isStreamAlgorithm(algorithm string) bool {
+	return algorithm == models.EncryptionAlgorithmGCMStream || algorithm == models.EncryptionAlgorithmXChaChaStream
+}
+
+// nonceSize returns the nonce size of an algorithm's AEAD
+func nonceSize(algorithm string) int {
+	switch algorithm {
+	case models.EncryptionAlgorithmXChaCha, models.EncryptionAlgorithmXChaChaStream:
+		return chacha20poly1305.NonceSizeX
+	default:
+		return ivSize
+	}
+}
+
+// generateNonce returns size cryptographically secure random bytes
+func generateNonce(size int) ([]byte, error) {
+	nonce := make([]byte, size)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	}
+	return nonce, nil
+}
+
+// NonceStats returns the messages sealed under data keys by this process and
+// the number of times the shared data key was replaced on reaching its limit
+func NonceStats() (sealed, rotations uint64) {
+	return sealedMessages.Load(), limitRotations.Load()
+}
+
+func zero(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// bufferClasses are the capacities of pooled buffers, sized for thumbnails,
+// typical scans and the largest accepted uploads. Larger buffers are allocated
+// directly so a rare oversized document cannot pin memory in a pool
+var bufferClasses = []int{64 << 10, 512 << 10, 4 << 20, 16 << 20}
+
+var bufferPools = func() []*sync.Pool {
+	pools := make([]*sync.Pool, len(bufferClasses))
+	for i, size := range bufferClasses {
+		size := size
+		pools[i] = &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, 0, size)
+				return &buf
+			},
+		}
+	}
+	return pools
+}()
+
+// GetBuffer returns an empty buffer with capacity for at least size bytes,
+// taken from the smallest fitting pool
+func GetBuffer(size int) []byte {
+	for i, class := range bufferClasses {
+		if size <= class {
+			return (*bufferPools[i].Get().(*[]byte))[:0]
+		}
+	}
+	return make([]byte, 0, size)
+}
+
+// PutBuffer zeroes the buffer, which may have held document content, and
+// returns it to its pool. Buffers that did not come from a pool are dropped
+func PutBuffer(buf []byte) {
+	buf = buf[:cap(buf)]
+	for i, class := range bufferClasses {
+		if len(buf) == class {
+			clear(buf)
+			buf = buf[:0]
+			bufferPools[i].Put(&buf)
+			return
+		}
+	}
+}
+
+// ReadPooled reads r to EOF into a pooled buffer sized from the hint; the
+// caller returns the buffer with PutBuffer once it is no longer referenced
+func ReadPooled(r io.Reader, sizeHint int) ([]byte, error) {
+	// ReadFrom needs MinRead spare bytes to observe EOF without growing
+	buf := bytes.NewBuffer(GetBuffer(sizeHint + bytes.MinRead))
+	if _, err := buf.ReadFrom(r); err != nil {
+		PutBuffer(buf.Bytes())
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// PooledReader reads content held in a pooled buffer. Close zeroes the buffer
+// and returns it to the pool; the content must not be used afterwards.
+// Readers that are never closed are simply garbage collected
+type PooledReader struct {
+	*bytes.Reader
+	data []byte
+	once sync.Once
+}
+
+func newPooledReader(data []byte) *PooledReader {
+	return &PooledReader{Reader: bytes.NewReader(data), data: data}
+}
+
+// Bytes returns the whole content without copying
+func (r *PooledReader) Bytes() []byte {
+	return r.data
+}
+
+// Close releases the buffer
+func (r *PooledReader) Close() error {
+	r.once.Do(func() {
+		r.Reader.Reset(nil)
+		PutBuffer(r.data)
+		r.data = nil
+	})
+	return nil
+}
@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"         // v1.21.2
+	"github.com/aws/aws-sdk-go-v2/service/kms" // v1.26.0
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// documentContextKey binds a wrapped data key to its document through the
+// KMS encryption context, so it cannot be unwrapped for another document
+const documentContextKey = "document_id"
+
+var (
+	ErrKeyShredded   = errors.New("document data key was shredded")
+	ErrNoDocumentKey = errors.New("document has no data key of its own")
+	ErrNoKeyGrant    = errors.New("document data key has no grant to revoke")
+
+	// Unwrapped per-document data keys by wrapped key
+	documentKeyCache sync.Map
+
+	shreddedKeys atomic.Value // shreddedKeysHolder
+)
+
+// ShreddedKeys records the shredded document data keys where every instance
+// sees them. Each instance checks it before using a key, so a key shredded by
+// one instance is refused by the others although they still cache it
+type ShreddedKeys interface {
+	Record(ctx context.Context, wrappedKey string) error
+	Shredded(ctx context.Context, wrappedKey string) (bool, error)
+}
+
+type shreddedKeysHolder struct {
+	keys ShreddedKeys
+}
+
+// SetShreddedKeys installs the registry of shredded keys; nil leaves shredding
+// to evict the key from the local cache only
+func SetShreddedKeys(keys ShreddedKeys) {
+	shreddedKeys.Store(shreddedKeysHolder{keys: keys})
+}
+
+// checkShredded fails with ErrKeyShredded when the wrapped key was shredded by
+// any instance, and closed when that cannot be checked
+func checkShredded(ctx context.Context, wrappedKey string) error {
+	holder, _ := shreddedKeys.Load().(shreddedKeysHolder)
+	if holder.keys == nil {
+		return nil
+	}
+	shredded, err := holder.keys.Shredded(ctx, wrappedKey)
+	if err != nil {
+		return fmt.Errorf("%w: failed to check whether the document data key was shredded: %v", ErrKeyManagement, err)
+	}
+	if shredded {
+		documentKeyCache.Delete(wrappedKey)
+		return ErrKeyShredded
+	}
+	return nil
+}
+
+// cachedDocumentKey holds the ciphers of an unwrapped per-document data key
+type cachedDocumentKey struct {
+	keys    *keyCiphers
+	expires time.Time
+}
+
+// newDocumentKey generates a data key used by a single document and returns
+// its ciphers with metadata holding the wrapped key. With a grantee principal
+// configured, unwrapping is allowed through a grant constrained to the
+// document, which is revoked when the key is shredded
+func newDocumentKey(ctx context.Context, cfg *config.Config, documentID string, usage *models.KeyUsageEvent) (*keyCiphers, *models.EncryptionMetadata, error) {
+	if documentID == "" {
+		return nil, nil, ErrInvalidInput
+	}
+
+	usage.KMSOperation = models.KMSOperationGenerateDataKey
+	client := newKMSClient()
+	encryptionContext := map[string]string{documentContextKey: documentID}
+
+	var result *kms.GenerateDataKeyOutput
+	err := withKMSRetry(ctx, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+		var err error
+		result, err = client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+			KeyId:             &cfg.SecurityConfig.EncryptionKey,
+			KeySpec:           types.DataKeySpecAes256,
+			EncryptionContext: encryptionContext,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate document data key: %w", err)
+	}
+
+	keys, err := newKeyCiphers(result.Plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata := &models.EncryptionMetadata{
+		KeyID:             *result.KeyId,
+		WrappedKey:        base64.StdEncoding.EncodeToString(result.CiphertextBlob),
+		EncryptionContext: encryptionContext,
+	}
+
+	if principal := cfg.CryptoShreddingConfig.GranteePrincipal; principal != "" {
+		var grant *kms.CreateGrantOutput
+		err := withKMSRetry(ctx, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+			var err error
+			grant, err = client.CreateGrant(ctx, &kms.CreateGrantInput{
+				KeyId:            result.KeyId,
+				GranteePrincipal: &principal,
+				Operations:       []types.GrantOperation{types.GrantOperationDecrypt},
+				Constraints:      &types.GrantConstraints{EncryptionContextEquals: encryptionContext},
+			})
+			return err
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create document key grant: %w", err)
+		}
+		metadata.GrantID = *grant.GrantId
+	}
+
+	documentKeyCache.Store(metadata.WrappedKey, cachedDocumentKey{
+		keys:    keys,
+		expires: time.Now().Add(cfg.CryptoShreddingConfig.KeyCacheTTL),
+	})
+	return keys, metadata, nil
+}
+
+// cipherFor returns the cipher content under the metadata was sealed with. A
+// KMS call made to unwrap the key is noted on usage
+func cipherFor(ctx context.Context, cfg *config.Config, metadata *models.EncryptionMetadata, usage *models.KeyUsageEvent) (cipher.AEAD, error) {
+	keys, err := keysFor(ctx, cfg, metadata, usage)
+	if err != nil {
+		return nil, err
+	}
+	return keys.forAlgorithm(metadata.Algorithm)
+}
+
+// keysFor returns the ciphers of the data key content under the metadata was
+// sealed with: the document's own data key when it has one, otherwise the
+// shared data key recorded in the metadata. Content sealed before shared keys
+// were recorded can only be opened with the current shared key. A KMS call
+// made to unwrap the key is noted on usage
+func keysFor(ctx context.Context, cfg *config.Config, metadata *models.EncryptionMetadata, usage *models.KeyUsageEvent) (*keyCiphers, error) {
+	if metadata.ShreddedAt != nil {
+		return nil, ErrKeyShredded
+	}
+	if metadata.WrappedKey == "" {
+		if metadata.SharedKey != "" {
+			return sharedKeysFor(ctx, cfg, metadata, usage)
+		}
+		entry, err := getCipher(ctx, cfg, usage)
+		return entry.keys, err
+	}
+	if err := checkShredded(ctx, metadata.WrappedKey); err != nil {
+		return nil, err
+	}
+
+	if cached, ok := documentKeyCache.Load(metadata.WrappedKey); ok {
+		entry := cached.(cachedDocumentKey)
+		if time.Now().Before(entry.expires) {
+			return entry.keys, nil
+		}
+		documentKeyCache.Delete(metadata.WrappedKey)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped key: %w", ErrInvalidMetadata)
+	}
+
+	usage.KMSOperation = models.KMSOperationDecrypt
+	var result *kms.DecryptOutput
+	err = withKMSRetry(ctx, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+		var err error
+		result, err = newKMSClient().Decrypt(ctx, &kms.DecryptInput{
+			CiphertextBlob:    wrapped,
+			KeyId:             &metadata.KeyID,
+			EncryptionContext: metadata.EncryptionContext,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to unwrap document data key: %v", ErrKeyManagement, err)
+	}
+
+	keys, err := newKeyCiphers(result.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	documentKeyCache.Store(metadata.WrappedKey, cachedDocumentKey{
+		keys:    keys,
+		expires: time.Now().Add(cfg.CryptoShreddingConfig.KeyCacheTTL),
+	})
+	return keys, nil
+}
+
+// sharedKeysFor returns the ciphers of the shared data key recorded in the
+// metadata, unwrapping it with KMS when it is no longer cached
+func sharedKeysFor(ctx context.Context, cfg *config.Config, metadata *models.EncryptionMetadata, usage *models.KeyUsageEvent) (*keyCiphers, error) {
+	if cached, ok := sharedKeyCache.Load(metadata.SharedKey); ok {
+		entry := cached.(cachedCipher)
+		if time.Now().Before(entry.expires) {
+			return entry.keys, nil
+		}
+		sharedKeyCache.Delete(metadata.SharedKey)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata.SharedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode shared data key: %w", ErrInvalidMetadata)
+	}
+
+	usage.KMSOperation = models.KMSOperationDecrypt
+	var result *kms.DecryptOutput
+	err = withKMSRetry(ctx, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+		var err error
+		result, err = newKMSClient().Decrypt(ctx, &kms.DecryptInput{
+			CiphertextBlob: wrapped,
+			KeyId:          &metadata.KeyID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to unwrap shared data key: %v", ErrKeyManagement, err)
+	}
+
+	keys, err := newKeyCiphers(result.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	// Only opened with from here on, so its message count is not needed
+	sharedKeyCache.Store(metadata.SharedKey, cachedCipher{
+		keys:      keys,
+		keyID:     metadata.KeyID,
+		sharedKey: metadata.SharedKey,
+		expires:   time.Now().Add(keyCacheTTL),
+	})
+	return keys, nil
+}
+
+// ShredDataKey destroys a document data key: its KMS grant is revoked, the key
+// is recorded as shredded for every instance and the local unwrapped copy is
+// dropped. A key without a grant cannot be destroyed, since KMS would still
+// unwrap it for the service, and fails with ErrNoKeyGrant
+func ShredDataKey(ctx context.Context, cfg *config.Config, metadata *models.EncryptionMetadata) error {
+	if cfg == nil || metadata == nil {
+		return ErrInvalidInput
+	}
+	if metadata.WrappedKey == "" {
+		return ErrNoDocumentKey
+	}
+
+	if metadata.GrantID == "" {
+		return ErrNoKeyGrant
+	}
+
+	err := withKMSRetry(ctx, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+		_, err := newKMSClient().RevokeGrant(ctx, &kms.RevokeGrantInput{
+			KeyId:   &metadata.KeyID,
+			GrantId: &metadata.GrantID,
+		})
+		var notFound *types.NotFoundException
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to revoke document key grant: %v", ErrKeyManagement, err)
+	}
+
+	// KMS refuses new unwraps from here on; the registry stops the instances
+	// still caching the key
+	if holder, _ := shreddedKeys.Load().(shreddedKeysHolder); holder.keys != nil {
+		if err := holder.keys.Record(ctx, metadata.WrappedKey); err != nil {
+			return fmt.Errorf("%w: failed to record shredded key: %v", ErrKeyManagement, err)
+		}
+	}
+	documentKeyCache.Delete(metadata.WrappedKey)
+	return nil
+}
+
+func newKMSClient() *kms.Client {
+	options := kms.Options{
+		Region: "us-east-1", // Configure based on your requirements
+	}
+	// FIPS mode sends key operations to the FIPS 140 validated KMS endpoints
+	if StrictCrypto() {
+		options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+	return kms.New(options)
+}
+
+// withKMSRetry retries a KMS call with the same backoff as data key
+// generation, drawing the retries from the budget of ctx. Each attempt is
+// bounded by timeout, and none is made once the deadline of ctx has passed
+func withKMSRetry(ctx context.Context, timeout time.Duration, call func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if attempt > 0 {
+			if ctx.Err() != nil {
+				return err
+			}
+			if SpendRetry(ctx, RetryLayerKMS) != nil {
+				return RetryExhausted(err)
+			}
+			time.Sleep(retryBackoffBase << uint(attempt))
+		}
+		if err = CallWithDeadline(ctx, config.DependencyKMS, timeout, call); err == nil {
+			return nil
+		}
+	}
+	return err
+}
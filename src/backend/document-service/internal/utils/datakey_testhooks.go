@@ -0,0 +1,46 @@
+//go:build testhooks
+
+package utils
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// UseDataKey caches key as the shared data key, as if KMS had generated it
+// under keyID, until it expires like a generated one, so tests seal and open
+// content without KMS access. The key is zeroed. Only built with the
+// testhooks tag
+func UseDataKey(cfg *config.Config, keyID string, key []byte) error {
+	if cfg == nil || keyID == "" || len(key) != aesKeySize {
+		return ErrInvalidInput
+	}
+
+	keys, err := newKeyCiphers(key)
+	if err != nil {
+		return err
+	}
+	// Content sealed under it names it by a random local ID, since there is
+	// no wrapped key to record
+	localID, err := generateNonce(16)
+	if err != nil {
+		return err
+	}
+	entry := cachedCipher{
+		keys:      keys,
+		keyID:     keyID,
+		sharedKey: "local:" + hex.EncodeToString(localID),
+		expires:   time.Now().Add(keyCacheTTL),
+	}
+	keyCache.Store(cfg.SecurityConfig.EncryptionKey, entry)
+	sharedKeyCache.Store(entry.sharedKey, entry)
+	return nil
+}
+
+// SweepKeyCachesAt drops the cached data keys expired at now, as the periodic
+// sweep does. Only built with the testhooks tag
+func SweepKeyCachesAt(now time.Time) {
+	sweepKeyCaches(now)
+}
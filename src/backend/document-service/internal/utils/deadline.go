@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// DeadlineObserver receives every dependency call cut off by a deadline,
+// with whether the deadline was inherited from the caller rather than the
+// dependency's own timeout. It must not block
+type DeadlineObserver func(dependency string, inherited bool)
+
+var deadlineObserver atomic.Value // DeadlineObserver
+
+// SetDeadlineObserver installs the observer of exceeded deadlines; nil
+// disables observing
+func SetDeadlineObserver(observer DeadlineObserver) {
+	deadlineObserver.Store(observer)
+}
+
+// DeadlineError is the error of a dependency call that ran out of time. Only
+// the innermost dependency is blamed; the layers wrapping it pass the error on
+type DeadlineError struct {
+	Dependency string
+	// Inherited is set when the caller's deadline, usually the request's,
+	// expired before the dependency's own timeout
+	Inherited bool
+	Err       error
+}
+
+func (e *DeadlineError) Error() string {
+	if e.Inherited {
+		return fmt.Sprintf("request deadline exceeded waiting on %s: %v", e.Dependency, e.Err)
+	}
+	return fmt.Sprintf("%s timed out: %v", e.Dependency, e.Err)
+}
+
+// Unwrap matches both the error of the call and context.DeadlineExceeded,
+// which dependencies do not always wrap
+func (e *DeadlineError) Unwrap() []error {
+	return []error{e.Err, context.DeadlineExceeded}
+}
+
+// CallWithDeadline runs a call to dependency bounded by timeout, or by the
+// deadline of ctx when that is sooner; a timeout of 0 leaves only the deadline
+// of ctx. A call cut off by either deadline returns a *DeadlineError blaming
+// the dependency
+func CallWithDeadline(ctx context.Context, dependency string, timeout time.Duration, call func(ctx context.Context) error) error {
+	callCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	err := call(callCtx)
+	if err == nil || !deadlinePassed(callCtx) {
+		return err
+	}
+	var exceeded *DeadlineError
+	if errors.As(err, &exceeded) {
+		return err
+	}
+
+	inherited := deadlinePassed(ctx)
+	if observer, ok := deadlineObserver.Load().(DeadlineObserver); ok && observer != nil {
+		observer(dependency, inherited)
+	}
+	return &DeadlineError{Dependency: dependency, Inherited: inherited, Err: err}
+}
+
+// deadlinePassed reports whether the deadline of ctx has passed. Calls bound
+// to it through connection deadlines may fail just before its timer fires
+func deadlinePassed(ctx context.Context) bool {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
@@ -2,10 +2,8 @@
 package utils
 
 import (
-	"bytes"
-	"crypto/aes"
+	"context"
 	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -23,9 +21,9 @@ import (
 const (
 	aesKeySize                = 32 // 256 bits
 	ivSize                    = 12 // GCM recommended IV size
-	defaultEncryptionAlgorithm = "AES-256-GCM"
 	maxRetries                = 3
 	retryBackoffBase         = 100 * time.Millisecond
+	keyCacheSweepInterval    = 10 * time.Minute
 )
 
 var (
@@ -39,72 +37,78 @@ var (
 	// Key cache
 	keyCache     sync.Map
 	keyCacheTTL  = 1 * time.Hour
+
+	// Ciphers of shared data keys by wrapped key, for opening content sealed
+	// under a key that is no longer the current one. Expired keys are
+	// dropped by RunKeyCacheSweep
+	sharedKeyCache sync.Map
 )
 
-// EncryptDocument encrypts document content using AES-256-GCM with KMS-managed keys
-func EncryptDocument(doc *models.Document, content io.Reader, cfg *config.Config) (io.Reader, error) {
+// EncryptDocument encrypts document content with the configured AEAD under
+// KMS-managed keys. The key use is recorded for the principal in ctx
+func EncryptDocument(ctx context.Context, doc *models.Document, content io.Reader, cfg *config.Config) (io.Reader, error) {
 	if doc == nil || content == nil || cfg == nil {
 		return nil, ErrInvalidInput
 	}
 
-	// Generate random IV
-	iv, err := generateIV()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate IV: %w", err)
+	// With crypto-shredding every document gets its own data key, otherwise
+	// the cipher for the current shared KMS data key is used
+	var (
+		algorithm = contentAlgorithm(cfg)
+		gcm       cipher.AEAD
+		metadata  *models.EncryptionMetadata
+		usage     = newKeyUsage(ctx, models.KeyOperationEncrypt, doc.ID)
+		err       error
+	)
+	if cfg.CryptoShreddingConfig.Enabled {
+		var keys *keyCiphers
+		keys, metadata, err = newDocumentKey(ctx, cfg, doc.ID, usage)
+		if err == nil {
+			gcm, err = sealWith(cfg, keys, algorithm)
+		}
+	} else {
+		metadata = &models.EncryptionMetadata{}
+		gcm, err = sharedSealingCipher(ctx, cfg, algorithm, metadata, usage)
 	}
-
-	// Get encryption key from KMS
-	key, keyID, err := getEncryptionKey(cfg)
+	recordKeyUsage(ctx, cfg, usage, metadata, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get encryption key: %w", err)
 	}
-	defer func() {
-		// Zero out key material after use
-		for i := range key {
-			key[i] = 0
-		}
-	}()
 
-	// Create cipher block
-	block, err := aes.NewCipher(key)
+	// Generate random IV
+	iv, err := generateNonce(gcm.NonceSize())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher block: %w", ErrEncryptionFailed)
+		return nil, err
 	}
 
-	// Create GCM cipher
-	gcm, err := cipher.NewGCM(block)
+	// Read content into a pooled buffer for encryption
+	plaintext, err := ReadPooled(content, int(doc.Size))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM cipher: %w", ErrEncryptionFailed)
-	}
-
-	// Read content into buffer for encryption
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, content); err != nil {
 		return nil, fmt.Errorf("failed to read content: %w", err)
 	}
+	defer PutBuffer(plaintext)
 
-	// Encrypt content
-	ciphertext := gcm.Seal(nil, iv, buf.Bytes(), nil)
+	// Encrypt content into a pooled buffer released when the reader is closed
+	ciphertext := gcm.Seal(GetBuffer(len(plaintext)+gcm.Overhead()), iv, plaintext, nil)
 
 	// Update document encryption metadata
-	metadata := &models.EncryptionMetadata{
-		KeyID:         keyID,
-		Algorithm:     defaultEncryptionAlgorithm,
-		IV:            base64.StdEncoding.EncodeToString(iv),
-		KeyVersion:    "1", // Set initial version
-		EncryptedAt:   time.Now(),
-		KeyRotationDue: time.Now().Add(cfg.SecurityConfig.KeyRotationInterval),
-	}
+	metadata.Algorithm = algorithm
+	metadata.Version = models.EncryptionMetadataVersion
+	metadata.IV = base64.StdEncoding.EncodeToString(iv)
+	metadata.KeyVersion = keyVersion(cfg)
+	metadata.EncryptedAt = time.Now()
+	metadata.KeyRotationDue = time.Now().Add(cfg.SecurityConfig.KeyRotationInterval)
 
 	if err := doc.SetEncryptionMetadata(metadata); err != nil {
 		return nil, fmt.Errorf("failed to set encryption metadata: %w", err)
 	}
 
-	return bytes.NewReader(ciphertext), nil
+	return newPooledReader(ciphertext), nil
 }
 
-// DecryptDocument decrypts document content using stored encryption metadata
-func DecryptDocument(doc *models.Document, encryptedContent io.Reader, cfg *config.Config) (io.Reader, error) {
+// DecryptDocument decrypts document content using stored encryption metadata.
+// The key use is recorded for the principal in ctx
+func DecryptDocument(ctx context.Context, doc *models.Document, encryptedContent io.Reader, cfg *config.Config) (io.Reader, error) {
 	if doc == nil || encryptedContent == nil || cfg == nil || doc.EncryptionInfo == nil {
 		return nil, ErrInvalidInput
 	}
@@ -114,93 +118,119 @@ func DecryptDocument(doc *models.Document, encryptedContent io.Reader, cfg *conf
 		return nil, fmt.Errorf("invalid encryption metadata: %w", err)
 	}
 
-	// Get decryption key from KMS
-	key, _, err := getEncryptionKey(cfg)
+	// Get the cipher the document was sealed with
+	usage := newKeyUsage(ctx, models.KeyOperationDecrypt, doc.ID)
+	gcm, err := cipherFor(ctx, cfg, doc.EncryptionInfo, usage)
+	recordKeyUsage(ctx, cfg, usage, doc.EncryptionInfo, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get decryption key: %w", err)
 	}
-	defer func() {
-		// Zero out key material after use
-		for i := range key {
-			key[i] = 0
-		}
-	}()
 
 	// Decode IV from metadata
 	iv, err := base64.StdEncoding.DecodeString(doc.EncryptionInfo.IV)
-	if err != nil {
+	if err != nil || len(iv) != gcm.NonceSize() {
 		return nil, fmt.Errorf("failed to decode IV: %w", ErrInvalidMetadata)
 	}
 
-	// Create cipher block
-	block, err := aes.NewCipher(key)
+	// Read encrypted content into a pooled buffer
+	ciphertext, err := ReadPooled(encryptedContent, int(doc.Size)+gcm.Overhead())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher block: %w", ErrDecryptionFailed)
+		return nil, fmt.Errorf("failed to read encrypted content: %w", err)
 	}
+	defer PutBuffer(ciphertext)
 
-	// Create GCM cipher
-	gcm, err := cipher.NewGCM(block)
+	// Decrypt content into a pooled buffer released when the reader is closed
+	dst := GetBuffer(len(ciphertext))
+	plaintext, err := gcm.Open(dst, iv, ciphertext, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM cipher: %w", ErrDecryptionFailed)
+		PutBuffer(dst)
+		return nil, fmt.Errorf("failed to decrypt content: %w", ErrDecryptionFailed)
 	}
 
-	// Read encrypted content
-	var buf bytes.Buffer
-	if _, err := io.Copy(&buf, encryptedContent); err != nil {
-		return nil, fmt.Errorf("failed to read encrypted content: %w", err)
+	return newPooledReader(plaintext), nil
+}
+
+// WarmEncryptionKey fetches the data key ahead of the first upload, validating
+// KMS credentials and leaving the key cached. KMS is called before ctx is done
+func WarmEncryptionKey(ctx context.Context, cfg *config.Config) error {
+	if cfg == nil {
+		return ErrInvalidInput
 	}
 
-	// Decrypt content
-	plaintext, err := gcm.Open(nil, iv, buf.Bytes(), nil)
+	usage := newKeyUsage(ctx, models.KeyOperationWarm, "")
+	_, err := getCipher(ctx, cfg, usage)
+	recordKeyUsage(ctx, cfg, usage, nil, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decrypt content: %w", ErrDecryptionFailed)
+		return fmt.Errorf("%w: %v", ErrKeyManagement, err)
 	}
-
-	return bytes.NewReader(plaintext), nil
+	return nil
 }
 
-// generateIV generates a cryptographically secure random initialization vector
-func generateIV() ([]byte, error) {
-	iv := make([]byte, ivSize)
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return nil, fmt.Errorf("failed to generate IV: %w", err)
+// keyVersion returns the configured version stamped on new encryption metadata
+func keyVersion(cfg *config.Config) string {
+	if cfg.SecurityConfig.KeyVersion == "" {
+		return "1"
 	}
-	return iv, nil
+	return cfg.SecurityConfig.KeyVersion
 }
 
-// getEncryptionKey retrieves encryption key from AWS KMS with retries and caching
-func getEncryptionKey(cfg *config.Config) ([]byte, string, error) {
+// cachedCipher holds the ciphers built from a KMS data key. A single instance
+// is shared by concurrent uploads and the raw key is zeroed as soon as the
+// ciphers are built. sharedKey is the wrapped key recorded in the metadata of
+// the content sealed under it
+type cachedCipher struct {
+	keys      *keyCiphers
+	keyID     string
+	sharedKey string
+	expires   time.Time
+}
+
+// getCipher returns the ciphers of the current data key, generating the key
+// with AWS KMS with retries when the cached one is missing or expired. Every
+// process seals under data keys it generated itself, so the message count
+// each key keeps in the process is the count of every message sealed under
+// it. The KMS call is noted on usage
+func getCipher(ctx context.Context, cfg *config.Config, usage *models.KeyUsageEvent) (cachedCipher, error) {
 	// Check key cache
-	if cachedKey, ok := keyCache.Load(cfg.SecurityConfig.EncryptionKey); ok {
-		keyData := cachedKey.(struct {
-			key     []byte
-			keyID   string
-			expires time.Time
-		})
-		if time.Now().Before(keyData.expires) {
-			return keyData.key, keyData.keyID, nil
+	if cached, ok := keyCache.Load(cfg.SecurityConfig.EncryptionKey); ok {
+		entry := cached.(cachedCipher)
+		if time.Now().Before(entry.expires) {
+			return entry, nil
 		}
 	}
 
 	var (
-		key    []byte
-		keyID  string
-		err    error
-		client = kms.New(kms.Options{
-			Region: "us-east-1", // Configure based on your requirements
-		})
+		key       []byte
+		keyID     string
+		sharedKey string
+		err       error
+		client    = newKMSClient()
 	)
 
+	usage.KMSOperation = models.KMSOperationGenerateDataKey
+
 	// Retry logic for KMS operations
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
+			if ctx.Err() != nil {
+				break
+			}
+			if SpendRetry(ctx, RetryLayerKMS) != nil {
+				err = RetryExhausted(err)
+				break
+			}
 			time.Sleep(retryBackoffBase << uint(attempt))
 		}
 
 		// Generate data key
-		result, err := client.GenerateDataKey(cfg.SecurityConfig.EncryptionKey, &kms.GenerateDataKeyInput{
-			KeyId:   &cfg.SecurityConfig.EncryptionKey,
-			KeySpec: types.DataKeySpecAes256,
+		var result *kms.GenerateDataKeyOutput
+		err = CallWithDeadline(ctx, config.DependencyKMS, cfg.SecurityConfig.KMSTimeout, func(ctx context.Context) error {
+			var err error
+			result, err = client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+				KeyId:   &cfg.SecurityConfig.EncryptionKey,
+				KeySpec: types.DataKeySpecAes256,
+			})
+			return err
 		})
 		if err != nil {
 			continue
@@ -208,23 +238,102 @@ func getEncryptionKey(cfg *config.Config) ([]byte, string, error) {
 
 		key = result.Plaintext
 		keyID = *result.KeyId
+		sharedKey = base64.StdEncoding.EncodeToString(result.CiphertextBlob)
 		break
 	}
 
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate data key after %d attempts: %w", maxRetries, err)
-	}
-
-	// Cache the key
-	keyCache.Store(cfg.SecurityConfig.EncryptionKey, struct {
-		key     []byte
-		keyID   string
-		expires time.Time
-	}{
-		key:     key,
-		keyID:   keyID,
-		expires: time.Now().Add(keyCacheTTL),
+		return cachedCipher{}, fmt.Errorf("failed to generate data key after %d attempts: %w", maxRetries, err)
+	}
+
+	keys, err := newKeyCiphers(key)
+	if err != nil {
+		return cachedCipher{}, err
+	}
+
+	// Cache the ciphers, for sealing while the key is current and for
+	// opening what it sealed after it is replaced
+	entry := cachedCipher{
+		keys:      keys,
+		keyID:     keyID,
+		sharedKey: sharedKey,
+		expires:   time.Now().Add(keyCacheTTL),
+	}
+	keyCache.Store(cfg.SecurityConfig.EncryptionKey, entry)
+	sharedKeyCache.Store(sharedKey, entry)
+
+	return entry, nil
+}
+
+// sharedSealingCipher returns the cipher of the shared data key for sealing
+// one message with the algorithm and records the key in metadata. A key that
+// has reached its message limit stops sealing and is replaced with a freshly
+// generated one; content it sealed stays readable through its wrapped key
+func sharedSealingCipher(ctx context.Context, cfg *config.Config, algorithm string, metadata *models.EncryptionMetadata, usage *models.KeyUsageEvent) (cipher.AEAD, error) {
+	entry, err := getCipher(ctx, cfg, usage)
+	if err != nil {
+		return nil, err
+	}
+	if err := entry.keys.reserve(cfg, algorithm); errors.Is(err, ErrKeyUsageLimit) {
+		// Only the exhausted key is dropped, not one another upload already
+		// generated in its place
+		keyCache.CompareAndDelete(cfg.SecurityConfig.EncryptionKey, entry)
+		limitRotations.Add(1)
+		if entry, err = getCipher(ctx, cfg, usage); err != nil {
+			return nil, err
+		}
+		if err := entry.keys.reserve(cfg, algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	metadata.KeyID = entry.keyID
+	metadata.SharedKey = entry.sharedKey
+	return entry.keys.forAlgorithm(algorithm)
+}
+
+// RunKeyCacheSweep drops the expired data keys cached for opening content
+// until the context is cancelled. Every key rotated out or unwrapped once is
+// cached, so without the sweep the caches grow for the life of the process;
+// a key swept away is unwrapped with KMS again when content sealed under it
+// is read
+func RunKeyCacheSweep(ctx context.Context) {
+	ticker := time.NewTicker(keyCacheSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepKeyCaches(time.Now())
+		}
+	}
+}
+
+// sweepKeyCaches drops the shared and per-document data keys expired at now.
+// An entry replaced since it was seen is kept
+func sweepKeyCaches(now time.Time) {
+	sharedKeyCache.Range(func(key, value interface{}) bool {
+		if !now.Before(value.(cachedCipher).expires) {
+			sharedKeyCache.CompareAndDelete(key, value)
+		}
+		return true
 	})
+	documentKeyCache.Range(func(key, value interface{}) bool {
+		if !now.Before(value.(cachedDocumentKey).expires) {
+			documentKeyCache.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
+
+// sealWith returns the cipher of a document data key for sealing one message
+// with the algorithm
+func sealWith(cfg *config.Config, keys *keyCiphers, algorithm string) (cipher.AEAD, error) {
+	if err := keys.reserve(cfg, algorithm); err != nil {
+		return nil, err
+	}
+	return keys.forAlgorithm(algorithm)
+}
 
-	return key, keyID, nil
-}
\ No newline at end of file
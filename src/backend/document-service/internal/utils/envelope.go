@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// A client encryption envelope is the magic, the recipient key ID, the
+// sender's ephemeral P-256 public key, a nonce and the AES-256-GCM ciphertext.
+// Everything before the nonce is authenticated as associated data
+const (
+	envelopeMagic         = "E2E1"
+	envelopeKeyIDSize     = 16
+	envelopeEphemeralSize = 65 // uncompressed P-256 point
+	envelopeHeaderSize    = len(envelopeMagic) + envelopeKeyIDSize + envelopeEphemeralSize
+	envelopeKDFInfo       = "onboarding-portal client encryption v1"
+)
+
+var (
+	ErrInvalidEnvelope  = errors.New("invalid client encryption envelope")
+	ErrUnknownRecipient = errors.New("envelope is sealed to an unknown recipient key")
+)
+
+// RecipientKey is a public key clients seal end-to-end encrypted documents to.
+// ID is the hex encoded first 16 bytes of the SHA-256 of the uncompressed point
+type RecipientKey struct {
+	ID  string
+	PEM string
+	Key *ecdh.PublicKey
+}
+
+// ParseRecipientKey parses a PEM encoded P-256 public key
+func ParseRecipientKey(data string) (RecipientKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return RecipientKey{}, errors.New("recipient key is not a PEM encoded public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return RecipientKey{}, fmt.Errorf("failed to parse recipient key: %w", err)
+	}
+	ecdsaKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || ecdsaKey.Curve != elliptic.P256() {
+		return RecipientKey{}, errors.New("recipient key must be a P-256 key")
+	}
+	key, err := ecdsaKey.ECDH()
+	if err != nil {
+		return RecipientKey{}, fmt.Errorf("failed to parse recipient key: %w", err)
+	}
+	return RecipientKey{
+		ID:  hex.EncodeToString(recipientKeyID(key)),
+		PEM: string(pem.EncodeToMemory(block)),
+		Key: key,
+	}, nil
+}
+
+// ValidateEnvelope checks the structure of an envelope without decrypting it:
+// its magic and length, that it is sealed to one of the recipients, and that
+// the ephemeral key is a valid P-256 point. It returns the recipient
+func ValidateEnvelope(envelope []byte, recipients []RecipientKey) (RecipientKey, error) {
+	if len(envelope) < envelopeHeaderSize+ivSize+aeadTagSize || string(envelope[:len(envelopeMagic)]) != envelopeMagic {
+		return RecipientKey{}, ErrInvalidEnvelope
+	}
+
+	keyID := hex.EncodeToString(envelope[len(envelopeMagic) : len(envelopeMagic)+envelopeKeyIDSize])
+	for _, recipient := range recipients {
+		if recipient.ID != keyID {
+			continue
+		}
+		if _, err := ecdh.P256().NewPublicKey(envelope[len(envelopeMagic)+envelopeKeyIDSize : envelopeHeaderSize]); err != nil {
+			return RecipientKey{}, fmt.Errorf("%w: ephemeral key is not a P-256 point", ErrInvalidEnvelope)
+		}
+		return recipient, nil
+	}
+	return RecipientKey{}, ErrUnknownRecipient
+}
+
+// SealEnvelope encrypts plaintext to a recipient. It is the reference for
+// clients implementing the envelope
+func SealEnvelope(plaintext []byte, recipient RecipientKey) ([]byte, error) {
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	secret, err := ephemeral.ECDH(recipient.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key agreement failed", ErrEncryptionFailed)
+	}
+	gcm, err := envelopeCipher(secret, ephemeral.PublicKey(), recipient.Key)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateNonce(gcm.NonceSize())
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := make([]byte, 0, envelopeHeaderSize+len(nonce)+len(plaintext)+gcm.Overhead())
+	envelope = append(envelope, envelopeMagic...)
+	envelope = append(envelope, recipientKeyID(recipient.Key)...)
+	envelope = append(envelope, ephemeral.PublicKey().Bytes()...)
+	// The associated data may not overlap the output
+	header := append([]byte(nil), envelope...)
+	envelope = append(envelope, nonce...)
+	return gcm.Seal(envelope, nonce, plaintext, header), nil
+}
+
+// OpenEnvelope decrypts an envelope with the recipient's private key. It is
+// the reference for the underwriting client; the service never holds a
+// recipient private key
+func OpenEnvelope(envelope []byte, private *ecdh.PrivateKey) ([]byte, error) {
+	recipient := RecipientKey{ID: hex.EncodeToString(recipientKeyID(private.PublicKey())), Key: private.PublicKey()}
+	if _, err := ValidateEnvelope(envelope, []RecipientKey{recipient}); err != nil {
+		return nil, err
+	}
+
+	ephemeral, err := ecdh.P256().NewPublicKey(envelope[len(envelopeMagic)+envelopeKeyIDSize : envelopeHeaderSize])
+	if err != nil {
+		return nil, ErrInvalidEnvelope
+	}
+	secret, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key agreement failed", ErrDecryptionFailed)
+	}
+	gcm, err := envelopeCipher(secret, ephemeral, recipient.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := envelope[envelopeHeaderSize : envelopeHeaderSize+gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, envelope[envelopeHeaderSize+gcm.NonceSize():], envelope[:envelopeHeaderSize])
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// envelopeCipher derives the content key of an envelope from the ECDH shared
+// secret, salted with both public keys
+func envelopeCipher(secret []byte, ephemeral, recipient *ecdh.PublicKey) (cipher.AEAD, error) {
+	defer zero(secret)
+
+	salt := append(append([]byte{}, ephemeral.Bytes()...), recipient.Bytes()...)
+	key := make([]byte, aesKeySize)
+	defer zero(key)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(envelopeKDFInfo)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive envelope key: %w", ErrKeyManagement)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher block: %w", ErrKeyManagement)
+	}
+	return cipher.NewGCM(block)
+}
+
+func recipientKeyID(key *ecdh.PublicKey) []byte {
+	sum := sha256.Sum256(key.Bytes())
+	return sum[:envelopeKeyIDSize]
+}
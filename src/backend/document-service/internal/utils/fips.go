@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+// Crypto modes reported by the readiness probe
+const (
+	CryptoModeStandard = "standard"
+	CryptoModeFIPS     = "fips"
+)
+
+var (
+	ErrNonCompliantCrypto = errors.New("setting is not allowed in FIPS mode")
+
+	strictCrypto atomic.Bool
+)
+
+// fipsCipherSuites are the TLS 1.2 suites built only from FIPS-approved
+// primitives. TLS 1.3 suites cannot be configured; BoringCrypto builds
+// restrict them to AES-GCM
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the NIST curves approved for key agreement
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+// fipsSSHKeyTypes are the SSH public key types backed by approved signature
+// algorithms; RSA keys are only used with SHA-2 signatures in FIPS mode
+var fipsSSHKeyTypes = map[string]bool{
+	"ecdsa-sha2-nistp256": true,
+	"ecdsa-sha2-nistp384": true,
+	"ecdsa-sha2-nistp521": true,
+	"ssh-rsa":             true,
+}
+
+// ConfigureCryptoMode selects the crypto mode at startup. FIPS mode is
+// enabled by security.strict_crypto or by building with BoringCrypto, and is
+// refused when any setting relies on an algorithm outside the approved set
+func ConfigureCryptoMode(cfg *config.Config) error {
+	if cfg == nil {
+		return ErrInvalidInput
+	}
+
+	strict := cfg.SecurityConfig.StrictCrypto || BoringCrypto()
+	if strict {
+		if err := CheckCryptoPolicy(cfg); err != nil {
+			return err
+		}
+	}
+	strictCrypto.Store(strict)
+	return nil
+}
+
+// StrictCrypto reports whether the process runs in FIPS mode
+func StrictCrypto() bool {
+	return strictCrypto.Load()
+}
+
+// CryptoMode returns the crypto mode the process runs in
+func CryptoMode() string {
+	if StrictCrypto() {
+		return CryptoModeFIPS
+	}
+	return CryptoModeStandard
+}
+
+// BoringCrypto reports whether the binary was built with the FIPS-validated
+// BoringCrypto module (GOEXPERIMENT=boringcrypto)
+func BoringCrypto() bool {
+	return boringCryptoEnabled()
+}
+
+// CheckCryptoPolicy returns every setting that conflicts with FIPS mode
+func CheckCryptoPolicy(cfg *config.Config) error {
+	var violations []error
+	violate := func(setting, reason string) {
+		violations = append(violations, fmt.Errorf("%w: %s %s", ErrNonCompliantCrypto, setting, reason))
+	}
+
+	if cfg.SecurityConfig.EncryptionAlgorithm != "AES-256" {
+		violate("security.encryption_algorithm", "must be AES-256")
+	}
+	if !cfg.MinioConfig.UseSSL {
+		violate("minio.use_ssl", "must be enabled")
+	}
+	if cfg.StorageMigrationConfig.Enabled && !cfg.StorageMigrationConfig.Secondary.UseSSL {
+		violate("storage_migration.secondary.use_ssl", "must be enabled")
+	}
+	if cfg.AnalyticsExportConfig.Enabled && !cfg.AnalyticsExportConfig.Destination.UseSSL {
+		violate("analytics_export.destination.use_ssl", "must be enabled")
+	}
+	if cfg.SFTPConfig.Enabled {
+		keyType := ""
+		if fields := strings.Fields(cfg.SFTPConfig.HostKey); len(fields) > 0 {
+			keyType = fields[0]
+		}
+		if !fipsSSHKeyTypes[keyType] {
+			violate("sftp.host_key", "must be an ECDSA or RSA key, got "+keyType)
+		}
+	}
+	return errors.Join(violations...)
+}
+
+// ApplyTLSPolicy restricts a TLS configuration to approved cipher suites and
+// curves when the process runs in FIPS mode
+func ApplyTLSPolicy(tlsConfig *tls.Config) {
+	if !StrictCrypto() {
+		return
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.CipherSuites = fipsCipherSuites
+	tlsConfig.CurvePreferences = fipsCurves
+}
@@ -0,0 +1,14 @@
+//go:build boringcrypto
+
+package utils
+
+import (
+	"crypto/boring"
+
+	// Restricts crypto/tls to FIPS-approved settings process-wide
+	_ "crypto/tls/fipsonly"
+)
+
+func boringCryptoEnabled() bool {
+	return boring.Enabled()
+}
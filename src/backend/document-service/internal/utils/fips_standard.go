@@ -0,0 +1,7 @@
+//go:build !boringcrypto
+
+package utils
+
+func boringCryptoEnabled() bool {
+	return false
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// KeyUsageRecorder receives every use of a data key, whether or not it needed
+// a KMS call. It runs on the encrypting or decrypting goroutine and must not
+// block
+type KeyUsageRecorder func(ctx context.Context, event *models.KeyUsageEvent)
+
+type principalContextKey struct{}
+
+var keyUsageRecorder atomic.Value // KeyUsageRecorder
+
+// SetKeyUsageRecorder installs the recorder of data key uses; nil disables
+// recording
+func SetKeyUsageRecorder(recorder KeyUsageRecorder) {
+	keyUsageRecorder.Store(recorder)
+}
+
+// WithPrincipal returns a context whose data key uses are attributed to
+// principal
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal data key uses made with ctx are
+// attributed to, the system when none was set
+func PrincipalFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if principal, ok := ctx.Value(principalContextKey{}).(string); ok && principal != "" {
+			return principal
+		}
+	}
+	return models.PrincipalSystem
+}
+
+// newKeyUsage starts the record of a data key use; the functions obtaining
+// the key fill in the KMS call they made
+func newKeyUsage(ctx context.Context, operation, documentID string) *models.KeyUsageEvent {
+	return &models.KeyUsageEvent{
+		DocumentID: documentID,
+		Principal:  PrincipalFromContext(ctx),
+		Operation:  operation,
+		OccurredAt: time.Now(),
+	}
+}
+
+// recordKeyUsage completes a key use with the key it resolved to and its
+// outcome and hands it to the recorder. Without metadata the use is
+// attributed to the configured master key
+func recordKeyUsage(ctx context.Context, cfg *config.Config, usage *models.KeyUsageEvent, metadata *models.EncryptionMetadata, err error) {
+	recorder, _ := keyUsageRecorder.Load().(KeyUsageRecorder)
+	if recorder == nil {
+		return
+	}
+
+	usage.KeyID = cfg.SecurityConfig.EncryptionKey
+	usage.KeyVersion = keyVersion(cfg)
+	if metadata != nil {
+		if metadata.KeyID != "" {
+			usage.KeyID = metadata.KeyID
+		}
+		if metadata.KeyVersion != "" {
+			usage.KeyVersion = metadata.KeyVersion
+		}
+		usage.GrantID = metadata.GrantID
+	}
+	usage.Succeeded = err == nil
+	if err != nil {
+		usage.Error = err.Error()
+	}
+	recorder(ctx, usage)
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Layers retrying on behalf of a request
+const (
+	RetryLayerStorage = "storage"
+	RetryLayerKMS     = "kms"
+	RetryLayerOCR     = "ocr"
+)
+
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+type retryBudgetContextKey struct{}
+
+// RetryBudget is the pool of retries a request may make across every layer
+// it passes through. Layers nested in one another draw from the same pool,
+// so their retries add up instead of multiplying
+type RetryBudget struct {
+	mu        sync.Mutex
+	remaining int
+	spent     map[string]int
+	denied    map[string]int
+}
+
+// NewRetryBudget returns a budget of retries; first attempts are free
+func NewRetryBudget(retries int) *RetryBudget {
+	return &RetryBudget{
+		remaining: retries,
+		spent:     make(map[string]int),
+		denied:    make(map[string]int),
+	}
+}
+
+// WithRetryBudget returns a context whose retries draw from budget
+func WithRetryBudget(ctx context.Context, budget *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetContextKey{}, budget)
+}
+
+// RetryBudgetFromContext returns the budget of ctx, or nil when its retries
+// are unbounded
+func RetryBudgetFromContext(ctx context.Context) *RetryBudget {
+	if ctx == nil {
+		return nil
+	}
+	budget, _ := ctx.Value(retryBudgetContextKey{}).(*RetryBudget)
+	return budget
+}
+
+// SpendRetry takes one retry of layer from the budget of ctx. It returns
+// ErrRetryBudgetExhausted when none is left, in which case the layer gives
+// up with its last error. Contexts without a budget always may retry
+func SpendRetry(ctx context.Context, layer string) error {
+	budget := RetryBudgetFromContext(ctx)
+	if budget == nil {
+		return nil
+	}
+	return budget.spend(layer)
+}
+
+// RetryExhausted wraps the last error of a layer that gave up because the
+// budget ran out
+func RetryExhausted(err error) error {
+	return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+}
+
+func (b *RetryBudget) spend(layer string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining <= 0 {
+		b.denied[layer]++
+		return ErrRetryBudgetExhausted
+	}
+	b.remaining--
+	b.spent[layer]++
+	return nil
+}
+
+// Usage returns the retries each layer made and was denied
+func (b *RetryBudget) Usage() (spent, denied map[string]int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	spent = make(map[string]int, len(b.spent))
+	for layer, n := range b.spent {
+		spent[layer] = n
+	}
+	denied = make(map[string]int, len(b.denied))
+	for layer, n := range b.denied {
+		denied[layer] = n
+	}
+	return spent, denied
+}
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values of scrubbed fields
+const Redacted = "[REDACTED]"
+
+// MaskedCPF replaces CPF-like numbers
+const MaskedCPF = "***.***.***-**"
+
+// cpfPattern matches CPF numbers, formatted or not
+var cpfPattern = regexp.MustCompile(`[0-9]{3}\.?[0-9]{3}\.?[0-9]{3}-?[0-9]{2}`)
+
+// Scrubber removes personal data from what is logged. Values of the listed
+// fields are redacted wherever they appear in a JSON document, and CPF-like
+// numbers are masked in every string
+type Scrubber struct {
+	fields map[string]bool
+}
+
+// NewScrubber creates a scrubber redacting the fields, matched without
+// regard to case
+func NewScrubber(fields []string) *Scrubber {
+	s := &Scrubber{fields: make(map[string]bool, len(fields))}
+	for _, field := range fields {
+		s.fields[strings.ToLower(field)] = true
+	}
+	return s
+}
+
+// Field reports whether values of the field are redacted
+func (s *Scrubber) Field(name string) bool {
+	return s.fields[strings.ToLower(name)]
+}
+
+// String masks the CPF-like numbers in a string; digits that are part of a
+// longer run are left alone
+func (s *Scrubber) String(value string) string {
+	matches := cpfPattern.FindAllStringIndex(value, -1)
+	if matches == nil {
+		return value
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if (m[0] > 0 && isDigit(value[m[0]-1])) || (m[1] < len(value) && isDigit(value[m[1]])) {
+			continue
+		}
+		b.WriteString(value[last:m[0]])
+		b.WriteString(MaskedCPF)
+		last = m[1]
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// JSON returns the document with the listed fields redacted and CPF-like
+// numbers masked. Bodies that are not valid JSON are replaced entirely, as
+// they cannot be scrubbed field by field
+func (s *Scrubber) JSON(body []byte) string {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Redacted
+	}
+	scrubbed, err := json.Marshal(s.value(doc))
+	if err != nil {
+		return Redacted
+	}
+	return string(scrubbed)
+}
+
+func (s *Scrubber) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if s.Field(key) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = s.value(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.value(item)
+		}
+		return v
+	case string:
+		return s.String(v)
+	case float64:
+		// A CPF sent as a number has up to 11 digits
+		if v >= 1e9 && v < 1e11 && v == math.Trunc(v) {
+			return MaskedCPF
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// streamChunkSize is the plaintext size of each independently sealed chunk
+const streamChunkSize = 64 << 10
+
+// EncryptStream encrypts content as a sequence of AEAD chunks so that
+// any byte range can later be decrypted without reading the whole object. Each
+// chunk nonce binds its position and whether it is the last chunk, so reordered
+// or truncated ciphertext fails authentication. The returned reader holds the
+// ciphertext in a pooled buffer. parent is the encryption metadata of the
+// document the content derives from, if any; when the document has its own
+// data key the content is sealed under it so shredding the key covers both.
+// The key use is recorded against documentID for the principal in ctx
+func EncryptStream(ctx context.Context, documentID string, content []byte, parent *models.EncryptionMetadata, cfg *config.Config) (*PooledReader, *models.EncryptionMetadata, error) {
+	if cfg == nil {
+		return nil, nil, ErrInvalidInput
+	}
+
+	var (
+		algorithm = streamAlgorithm(cfg)
+		gcm       cipher.AEAD
+		metadata  = &models.EncryptionMetadata{}
+		usage     = newKeyUsage(ctx, models.KeyOperationEncrypt, documentID)
+		err       error
+	)
+	if parent != nil && parent.WrappedKey != "" {
+		var keys *keyCiphers
+		if keys, err = keysFor(ctx, cfg, parent, usage); err == nil {
+			gcm, err = sealWith(cfg, keys, algorithm)
+		}
+		metadata.KeyID = parent.KeyID
+		metadata.WrappedKey = parent.WrappedKey
+		metadata.GrantID = parent.GrantID
+		metadata.EncryptionContext = parent.EncryptionContext
+	} else {
+		gcm, err = sharedSealingCipher(ctx, cfg, algorithm, metadata, usage)
+	}
+	recordKeyUsage(ctx, cfg, usage, metadata, err)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get encryption key: %w", err)
+	}
+
+	// The random prefix fills the nonce up to the chunk counter and flag
+	prefix, err := generateNonce(gcm.NonceSize() - streamCounterSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunks := streamChunks(int64(len(content)))
+	ciphertext := GetBuffer(len(content) + int(chunks)*gcm.Overhead())
+	for i := int64(0); i < chunks; i++ {
+		start := i * streamChunkSize
+		end := start + streamChunkSize
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		ciphertext = gcm.Seal(ciphertext, streamNonce(prefix, i, i == chunks-1), content[start:end], nil)
+	}
+
+	metadata.Algorithm = algorithm
+	metadata.Version = models.EncryptionMetadataVersion
+	metadata.IV = base64.StdEncoding.EncodeToString(prefix)
+	metadata.KeyVersion = keyVersion(cfg)
+	metadata.EncryptedAt = time.Now()
+	metadata.KeyRotationDue = time.Now().Add(cfg.SecurityConfig.KeyRotationInterval)
+	return newPooledReader(ciphertext), metadata, nil
+}
+
+// NewStreamDecrypter returns a reader over the plaintext of chunked ciphertext
+// read from src. Only the chunks covering the bytes read are fetched and
+// decrypted, so seeking serves range requests; size is the plaintext size. The
+// key use is recorded against documentID for the principal in ctx
+func NewStreamDecrypter(ctx context.Context, documentID string, src io.ReadSeeker, size int64, metadata *models.EncryptionMetadata, cfg *config.Config) (io.ReadSeeker, error) {
+	if src == nil || metadata == nil || cfg == nil || size < 0 {
+		return nil, ErrInvalidInput
+	}
+	if !isStreamAlgorithm(metadata.Algorithm) {
+		return nil, fmt.Errorf("%w: unexpected algorithm %s", ErrInvalidMetadata, metadata.Algorithm)
+	}
+	if err := metadata.ValidateFormat(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	prefix, err := base64.StdEncoding.DecodeString(metadata.IV)
+	if err != nil || len(prefix) != nonceSize(metadata.Algorithm)-streamCounterSize {
+		return nil, fmt.Errorf("failed to decode IV: %w", ErrInvalidMetadata)
+	}
+
+	usage := newKeyUsage(ctx, models.KeyOperationDecrypt, documentID)
+	gcm, err := cipherFor(ctx, cfg, metadata, usage)
+	recordKeyUsage(ctx, cfg, usage, metadata, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decryption key: %w", err)
+	}
+
+	return &streamDecrypter{
+		src:    src,
+		gcm:    gcm,
+		prefix: prefix,
+		size:   size,
+		chunks: streamChunks(size),
+		loaded: -1,
+	}, nil
+}
+
+// streamDecrypter decrypts chunked ciphertext one chunk at a time
+type streamDecrypter struct {
+	src    io.ReadSeeker
+	gcm    cipher.AEAD
+	prefix []byte
+	size   int64
+	chunks int64
+	offset int64
+
+	loaded    int64
+	plaintext []byte
+	sealed    []byte
+}
+
+func (d *streamDecrypter) Read(p []byte) (int, error) {
+	if d.offset >= d.size {
+		return 0, io.EOF
+	}
+
+	chunk := d.offset / streamChunkSize
+	if chunk != d.loaded {
+		if err := d.load(chunk); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.plaintext[d.offset-chunk*streamChunkSize:])
+	d.offset += int64(n)
+	return n, nil
+}
+
+func (d *streamDecrypter) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.offset
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	d.offset = offset
+	return offset, nil
+}
+
+// load reads and authenticates a chunk
+func (d *streamDecrypter) load(chunk int64) error {
+	overhead := int64(d.gcm.Overhead())
+	length := int64(streamChunkSize)
+	if chunk == d.chunks-1 {
+		length = d.size - chunk*streamChunkSize
+	}
+
+	if _, err := d.src.Seek(chunk*(streamChunkSize+overhead), io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek to chunk %d: %w", chunk, err)
+	}
+	if d.sealed == nil {
+		d.sealed = make([]byte, streamChunkSize+overhead)
+		d.plaintext = make([]byte, 0, streamChunkSize)
+	}
+	sealed := d.sealed[:length+overhead]
+	if _, err := io.ReadFull(d.src, sealed); err != nil {
+		return fmt.Errorf("failed to read chunk %d: %w", chunk, err)
+	}
+
+	plaintext, err := d.gcm.Open(d.plaintext[:0], streamNonce(d.prefix, chunk, chunk == d.chunks-1), sealed, nil)
+	if err != nil {
+		d.loaded = -1
+		return fmt.Errorf("failed to decrypt chunk %d: %w", chunk, ErrDecryptionFailed)
+	}
+	d.plaintext = plaintext
+	d.loaded = chunk
+	return nil
+}
+
+// streamChunks returns the number of chunks for a plaintext size; empty
+// content is still sealed as one final chunk
+func streamChunks(size int64) int64 {
+	if size == 0 {
+		return 1
+	}
+	return (size + streamChunkSize - 1) / streamChunkSize
+}
+
+// streamNonce derives the nonce of a chunk from the random prefix
+func streamNonce(prefix []byte, chunk int64, last bool) []byte {
+	nonce := make([]byte, len(prefix)+streamCounterSize)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[len(prefix):], uint32(chunk))
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
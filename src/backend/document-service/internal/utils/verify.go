@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+var (
+	ErrCiphertextStructure = errors.New("ciphertext does not match its encryption metadata")
+)
+
+// CiphertextSize returns the stored size of size plaintext bytes sealed with
+// the algorithm
+func CiphertextSize(algorithm string, size int64) (int64, error) {
+	switch algorithm {
+	case models.EncryptionAlgorithmGCM, models.EncryptionAlgorithmXChaCha:
+		return size + aeadTagSize, nil
+	case models.EncryptionAlgorithmGCMStream, models.EncryptionAlgorithmXChaChaStream:
+		return size + streamChunks(size)*aeadTagSize, nil
+	default:
+		return 0, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidMetadata, algorithm)
+	}
+}
+
+// OpenCiphertext checks the structure of a whole stored object against its
+// metadata and authenticates and decrypts it. Unlike DecryptDocument it does
+// not reject metadata past its key rotation date, so overdue objects can still
+// be verified and re-encrypted. The plaintext is pooled; release it with
+// PutBuffer. The key use is recorded against documentID for the principal in
+// ctx
+func OpenCiphertext(ctx context.Context, documentID string, ciphertext []byte, size int64, metadata *models.EncryptionMetadata, cfg *config.Config) ([]byte, error) {
+	if metadata == nil || cfg == nil || size < 0 {
+		return nil, ErrInvalidInput
+	}
+	if err := metadata.ValidateFormat(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
+	expected, err := CiphertextSize(metadata.Algorithm, size)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(ciphertext)) != expected {
+		return nil, fmt.Errorf("%w: %d bytes stored, %d expected", ErrCiphertextStructure, len(ciphertext), expected)
+	}
+
+	iv, err := base64.StdEncoding.DecodeString(metadata.IV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode IV: %w", ErrInvalidMetadata)
+	}
+
+	if isStreamAlgorithm(metadata.Algorithm) {
+		if len(iv) != nonceSize(metadata.Algorithm)-streamCounterSize {
+			return nil, fmt.Errorf("%w: IV length %d", ErrInvalidMetadata, len(iv))
+		}
+		decrypter, err := NewStreamDecrypter(ctx, documentID, bytes.NewReader(ciphertext), size, metadata, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return ReadPooled(decrypter, int(size))
+	}
+
+	if len(iv) != nonceSize(metadata.Algorithm) {
+		return nil, fmt.Errorf("%w: IV length %d", ErrInvalidMetadata, len(iv))
+	}
+	usage := newKeyUsage(ctx, models.KeyOperationDecrypt, documentID)
+	gcm, err := cipherFor(ctx, cfg, metadata, usage)
+	recordKeyUsage(ctx, cfg, usage, metadata, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decryption key: %w", err)
+	}
+	dst := GetBuffer(len(ciphertext))
+	plaintext, err := gcm.Open(dst, iv, ciphertext, nil)
+	if err != nil {
+		PutBuffer(dst)
+		return nil, fmt.Errorf("failed to decrypt content: %w", ErrDecryptionFailed)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestAbuseGuard(maxFailures int) *services.AbuseGuard {
+	cfg := &config.Config{}
+	cfg.AbuseConfig = config.AbuseConfig{
+		Enabled:       true,
+		MaxFailures:   maxFailures,
+		FailureWindow: time.Minute,
+		BanDuration:   time.Hour,
+	}
+	return services.NewAbuseGuard(cfg, zap.NewNop())
+}
+
+func TestAbuseGuardBansRepeatedFailures(t *testing.T) {
+	guard := newTestAbuseGuard(3)
+
+	assert.False(t, guard.RecordFailure(services.AbuseEndpointPreview, "203.0.113.7"))
+	assert.False(t, guard.RecordFailure(services.AbuseEndpointExport, "203.0.113.7"))
+	_, banned := guard.Banned(services.AbuseEndpointPreview, "203.0.113.7")
+	assert.False(t, banned, "Clients under the limit should not be banned")
+
+	assert.True(t, guard.RecordFailure(services.AbuseEndpointPreview, "203.0.113.7"), "Failures on every endpoint should count together")
+	remaining, banned := guard.Banned(services.AbuseEndpointExport, "203.0.113.7")
+	assert.True(t, banned)
+	assert.True(t, remaining > 59*time.Minute && remaining <= time.Hour)
+
+	_, banned = guard.Banned(services.AbuseEndpointPreview, "203.0.113.8")
+	assert.False(t, banned, "Other clients should not be affected")
+}
+
+func TestAbuseGuardGroupsIPv6Subscribers(t *testing.T) {
+	guard := newTestAbuseGuard(2)
+
+	// Rotating addresses within one /64 should not evade the limit
+	guard.RecordFailure(services.AbuseEndpointPreview, "2001:db8:1:2::1")
+	guard.RecordFailure(services.AbuseEndpointPreview, "2001:db8:1:2:ffff::9")
+	_, banned := guard.Banned(services.AbuseEndpointPreview, "2001:db8:1:2::abcd")
+	assert.True(t, banned)
+
+	_, banned = guard.Banned(services.AbuseEndpointPreview, "2001:db8:1:3::1")
+	assert.False(t, banned, "Other /64 blocks should not be affected")
+}
+
+// newTestGuardedRouter serves a token route that rejects every token, guarded
+// by the abuse guard behind the trusted proxies
+func newTestGuardedRouter(t *testing.T, guard *services.AbuseGuard, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.SecurityConfig.TrustedProxies = trustedProxies
+	router := gin.New()
+	assert.NoError(t, handlers.TrustProxies(router, cfg))
+	router.GET("/preview", handlers.GuardTokenEndpoint(guard, services.AbuseEndpointPreview), func(c *gin.Context) {
+		c.Status(http.StatusForbidden)
+	})
+	return router
+}
+
+func guardedRequest(router *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/preview", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAbuseGuardIgnoresSpoofedForwardedFor(t *testing.T) {
+	// A direct client naming a new IP on every request is still banned
+	direct := newTestGuardedRouter(t, newTestAbuseGuard(3), nil)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusForbidden, guardedRequest(direct, "203.0.113.7:4711", "198.51.100."+strconv.Itoa(i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, guardedRequest(direct, "203.0.113.7:4711", "198.51.100.99"))
+
+	// Behind a trusted proxy the clients it forwards are banned one by one
+	proxied := newTestGuardedRouter(t, newTestAbuseGuard(3), []string{"10.0.0.0/8"})
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusForbidden, guardedRequest(proxied, "10.1.2.3:4711", "198.51.100.1"))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, guardedRequest(proxied, "10.1.2.3:4711", "198.51.100.1"))
+	assert.Equal(t, http.StatusForbidden, guardedRequest(proxied, "10.1.2.3:4711", "198.51.100.2"), "Other clients of the proxy are not banned")
+}
+
+func TestAbuseGuardDisabled(t *testing.T) {
+	guard := services.NewAbuseGuard(&config.Config{}, zap.NewNop())
+	assert.Nil(t, guard)
+
+	for i := 0; i < 100; i++ {
+		assert.False(t, guard.RecordFailure(services.AbuseEndpointPreview, "203.0.113.7"))
+	}
+	_, banned := guard.Banned(services.AbuseEndpointPreview, "203.0.113.7")
+	assert.False(t, banned)
+}
+
+func TestReplayGuardRejectsReplayedUploads(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.AbuseConfig.Replay = config.ReplayConfig{Enabled: true, Window: 5 * time.Minute}
+	nonces := repository.NewMemoryNonceRepository()
+	guard, err := services.NewReplayGuard(cfg, nonces, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := "3f9a1c7e5b2d4e6f8a0b1c2d3e4f5a6b"
+
+	assert.NoError(t, guard.Check(ctx, nonce, now))
+	assert.ErrorIs(t, guard.Check(ctx, nonce, now), services.ErrReplayedRequest, "A nonce is accepted once")
+	assert.NoError(t, guard.Check(ctx, "7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a29", now))
+
+	stale := strconv.FormatInt(time.Now().Add(-6*time.Minute).Unix(), 10)
+	assert.ErrorIs(t, guard.Check(ctx, "0a1b2c3d4e5f60718293a4b5c6d7e8f9", stale), services.ErrReplayWindowExpired)
+	assert.ErrorIs(t, guard.Check(ctx, "", now), services.ErrReplayHeadersMissing)
+	assert.ErrorIs(t, guard.Check(ctx, "short", now), services.ErrInvalidReplayHeaders)
+
+	// Nonces are kept until their timestamp leaves the window
+	deleted, err := nonces.DeleteExpired(ctx, time.Now().Add(6*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+}
+
+func TestReplayGuardDisabled(t *testing.T) {
+	guard, err := services.NewReplayGuard(&config.Config{}, repository.NewMemoryNonceRepository(), zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, guard)
+}
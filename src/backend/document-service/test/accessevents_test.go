@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestAccessEvents(t *testing.T, thresholds config.AccessEventThresholds) (*services.AccessEvents, *repository.MemoryDocumentRepository) {
+	cfg := &config.Config{}
+	cfg.AccessEventsConfig = config.AccessEventsConfig{
+		Enabled:      true,
+		SigningKey:   strings.Repeat("k", 32),
+		MaxClockSkew: 5 * time.Minute,
+		Window:       time.Hour,
+		Thresholds:   thresholds,
+	}
+	documents := repository.NewMemoryDocumentRepository()
+	events, err := services.NewAccessEvents(cfg, documents, repository.NewMemoryNonceRepository(), zap.NewNop())
+	assert.NoError(t, err)
+	return events, documents
+}
+
+func signedAccessEvent(t *testing.T, key, documentID, event string, n int) services.AccessEventReport {
+	report := services.AccessEventReport{
+		Event:      event,
+		Page:       1,
+		OccurredAt: time.Now().Unix(),
+		Nonce:      "nonce-0123456789-" + strconv.Itoa(n),
+	}
+	signature, err := services.SignAccessEvent(key, documentID, report)
+	assert.NoError(t, err)
+	report.Signature = signature
+	return report
+}
+
+func TestAccessEventsAreSignedPerSession(t *testing.T) {
+	ctx := context.Background()
+	events, documents := newTestAccessEvents(t, config.AccessEventThresholds{})
+
+	doc := &models.Document{ID: "doc-1", EnrollmentID: "enr-1", Status: models.DocumentStatusCompleted}
+	assert.NoError(t, documents.Create(ctx, doc))
+	session := services.ViewerSession{UserID: "reviewer-1", SessionID: "session-1"}
+	key := events.SessionKey(doc.ID, session)
+
+	report := signedAccessEvent(t, key, doc.ID, models.AccessEventPrint, 1)
+	_, err := events.Record(ctx, doc, session, report)
+	assert.NoError(t, err)
+
+	stored, err := documents.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	last := stored.AuditTrail[len(stored.AuditTrail)-1]
+	assert.Equal(t, "ACCESS_EVENT", last.Action)
+	assert.Equal(t, "reviewer-1", last.PerformedBy)
+
+	_, err = events.Record(ctx, doc, session, report)
+	assert.ErrorIs(t, err, services.ErrAccessEventReplayed)
+
+	tampered := signedAccessEvent(t, key, doc.ID, models.AccessEventView, 2)
+	tampered.Event = models.AccessEventExport
+	_, err = events.Record(ctx, doc, session, tampered)
+	assert.ErrorIs(t, err, services.ErrAccessEventSignature)
+
+	other := services.ViewerSession{UserID: "reviewer-1", SessionID: "session-2"}
+	_, err = events.Record(ctx, doc, other, signedAccessEvent(t, key, doc.ID, models.AccessEventPrint, 3))
+	assert.ErrorIs(t, err, services.ErrAccessEventSignature, "A key must not sign events of another session")
+
+	stale := services.AccessEventReport{Event: models.AccessEventView, OccurredAt: time.Now().Add(-time.Hour).Unix(), Nonce: "nonce-0123456789-stale"}
+	stale.Signature, err = services.SignAccessEvent(key, doc.ID, stale)
+	assert.NoError(t, err)
+	_, err = events.Record(ctx, doc, session, stale)
+	assert.ErrorIs(t, err, services.ErrAccessEventExpired)
+}
+
+func TestAccessEventsRaiseAnomaliesOncePerWindow(t *testing.T) {
+	ctx := context.Background()
+	events, documents := newTestAccessEvents(t, config.AccessEventThresholds{PrintsPerUser: 2, DocumentsPerUser: 3})
+	session := services.ViewerSession{UserID: "reviewer-1", SessionID: "session-1"}
+
+	var raised []models.AccessAnomaly
+	for i := 0; i < 5; i++ {
+		doc := &models.Document{ID: "doc-" + strconv.Itoa(i), EnrollmentID: "enr-1"}
+		assert.NoError(t, documents.Create(ctx, doc))
+		anomalies, err := events.Record(ctx, doc, session, signedAccessEvent(t, events.SessionKey(doc.ID, session), doc.ID, models.AccessEventPrint, i))
+		assert.NoError(t, err)
+		raised = append(raised, anomalies...)
+	}
+
+	assert.Len(t, raised, 2)
+	assert.Equal(t, models.AccessAnomalyPrintVolume, raised[0].Type)
+	assert.Equal(t, 3, raised[0].Count)
+	assert.Equal(t, models.AccessAnomalyDocumentSpread, raised[1].Type)
+	assert.Equal(t, 4, raised[1].Count)
+
+	flagged, err := documents.GetByID(ctx, "doc-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "ACCESS_ANOMALY", flagged.AuditTrail[len(flagged.AuditTrail)-1].Action)
+}
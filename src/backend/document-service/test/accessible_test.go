@@ -0,0 +1,101 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func lineTexts(lines []models.OCRLine) []string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+	}
+	return texts
+}
+
+func TestReadingOrderReadsColumnsInTurn(t *testing.T) {
+	// OCR returns a two-column page row by row across both columns
+	lines := []models.OCRLine{
+		{Page: 2, Text: "Second page", Box: [4]float64{0.1, 0.1, 0.5, 0.13}},
+		{Page: 1, Text: "Declaração de Saúde", Box: [4]float64{0.1, 0.05, 0.9, 0.09}},
+		{Page: 1, Text: "left one", Box: [4]float64{0.1, 0.12, 0.45, 0.15}},
+		{Page: 1, Text: "right one", Box: [4]float64{0.55, 0.12, 0.9, 0.15}},
+		{Page: 1, Text: "left two", Box: [4]float64{0.1, 0.16, 0.45, 0.19}},
+		{Page: 1, Text: "right two", Box: [4]float64{0.55, 0.16, 0.9, 0.19}},
+		{Page: 1, Text: "Assinatura", Box: [4]float64{0.1, 0.8, 0.9, 0.84}},
+		{Page: 1, Text: "left three", Box: [4]float64{0.1, 0.86, 0.45, 0.89}},
+		{Page: 1, Text: "right three", Box: [4]float64{0.55, 0.86, 0.9, 0.89}},
+		{Page: 1, Text: "left four", Box: [4]float64{0.1, 0.9, 0.45, 0.93}},
+		{Page: 1, Text: "right four", Box: [4]float64{0.55, 0.9, 0.9, 0.93}},
+	}
+
+	assert.Equal(t, []string{
+		"Declaração de Saúde",
+		"left one", "left two", "right one", "right two",
+		"Assinatura",
+		"left three", "left four", "right three", "right four",
+		"Second page",
+	}, lineTexts(services.ReadingOrder(lines)))
+}
+
+func TestReadingOrderReadsRowsLeftToRight(t *testing.T) {
+	// A form of labels and values is not mistaken for columns
+	lines := []models.OCRLine{
+		{Page: 1, Text: "Smith", Box: [4]float64{0.4, 0.1, 0.6, 0.13}},
+		{Page: 1, Text: "Name:", Box: [4]float64{0.1, 0.105, 0.3, 0.135}},
+		{Page: 1, Text: "1980-01-01", Box: [4]float64{0.4, 0.2, 0.6, 0.23}},
+		{Page: 1, Text: "Born:", Box: [4]float64{0.1, 0.2, 0.3, 0.23}},
+	}
+	assert.Equal(t, []string{"Name:", "Smith", "Born:", "1980-01-01"}, lineTexts(services.ReadingOrder(lines)))
+
+	// Without positions, the order OCR returned is kept
+	unplaced := []models.OCRLine{{Page: 1, Text: "b"}, {Page: 1, Text: "a"}}
+	assert.Equal(t, []string{"b", "a"}, lineTexts(services.ReadingOrder(unplaced)))
+}
+
+func TestBuildAccessiblePDFTagsText(t *testing.T) {
+	pages := []image.Image{image.NewGray(image.Rect(0, 0, 150, 200)), image.NewRGBA(image.Rect(0, 0, 150, 200))}
+	lines := []models.OCRLine{
+		{Page: 1, Text: "Declaração", Box: [4]float64{0.1, 0.1, 0.6, 0.15}},
+		{Page: 2, Text: "Página 2", Box: [4]float64{0.1, 0.1, 0.6, 0.15}},
+		{Page: 2, Text: "   ", Box: [4]float64{0.1, 0.2, 0.6, 0.25}},
+	}
+
+	pdf, err := services.BuildAccessiblePDF(pages, 150, 85, lines, services.AccessiblePDFMetadata{Title: "Saúde", Language: "pt-BR"})
+	assert.NoError(t, err)
+
+	text := string(pdf)
+	assert.Contains(t, text, "/MarkInfo << /Marked true >>")
+	assert.Contains(t, text, "/Lang <FEFF00700074002D00420052>")
+	assert.Contains(t, text, "/DisplayDocTitle true")
+	assert.Contains(t, text, "/Title <FEFF0053006100FA00640065>")
+	assert.Contains(t, text, "/MediaBox [0 0 72.00 96.00]")
+	assert.Contains(t, text, "/ColorSpace /DeviceGray")
+	assert.Contains(t, text, "/ColorSpace /DeviceRGB")
+	// Each line with text becomes one paragraph holding its exact text
+	assert.Len(t, regexp.MustCompile(`/S /P /P`).FindAllString(text, -1), 2)
+	assert.Contains(t, text, "/ActualText <FEFF004400650063006C00610072006100E700E3006F>")
+	assert.Contains(t, text, "/ParentTree << /Nums [0 [13 0 R] 1 [14 0 R]] >>")
+	assert.Regexp(t, `startxref\n\d+\n%%EOF\n$`, text)
+
+	// The first page's content hides the image from screen readers and draws
+	// its line as invisible marked text
+	stream := regexp.MustCompile(`(?s)/Filter /FlateDecode /Length \d+ >>\nstream\n(.*?)\nendstream`).FindStringSubmatch(text)
+	if assert.Len(t, stream, 2) {
+		reader, err := zlib.NewReader(bytes.NewReader([]byte(stream[1])))
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "/Artifact BMC")
+		assert.Contains(t, string(content), "/P << /MCID 0 >> BDC BT 3 Tr")
+	}
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func TestScrubberMasksPersonalData(t *testing.T) {
+	scrubber := utils.NewScrubber([]string{"cpf", "Email"})
+
+	assert.Equal(t, "/subjects/***.***.***-**/export", scrubber.String("/subjects/52998224725/export"))
+	assert.Equal(t, "CPF ***.***.***-** informado", scrubber.String("CPF 529.982.247-25 informado"))
+	assert.Equal(t, "order 529982247250", scrubber.String("order 529982247250"), "Longer digit runs are not CPFs")
+
+	scrubbed := scrubber.JSON([]byte(`{"email":"ana@example.com","holder":{"CPF":"52998224725"},"notes":["doc 529.982.247-25"],"pages":3}`))
+	assert.NotContains(t, scrubbed, "ana@example.com")
+	assert.NotContains(t, scrubbed, "52998224725")
+	assert.NotContains(t, scrubbed, "529.982.247-25")
+	assert.Contains(t, scrubbed, `"pages":3`)
+	assert.Equal(t, utils.Redacted, scrubber.JSON([]byte(`{"cpf": "5299822`)), "Bodies that do not parse are redacted whole")
+}
+
+func TestAccessLogScrubsAndCorrelates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zap.InfoLevel)
+	cfg := config.AccessLogConfig{
+		Enabled:      true,
+		SampleRate:   0,
+		LogBodies:    true,
+		MaxBodyBytes: 1024,
+		ScrubFields:  []string{"cpf"},
+		SkipPaths:    []string{"/health"},
+	}
+
+	router := gin.New()
+	router.Use(handlers.AccessLog(cfg, zap.New(core)))
+	router.POST("/subjects/:cpf", func(c *gin.Context) {
+		var body map[string]string
+		assert.NoError(t, c.ShouldBindJSON(&body), "The logged body is still readable by the handler")
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "cpf": body["cpf"]})
+	})
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	req := httptest.NewRequest(http.MethodPost, "/subjects/52998224725?cpf=52998224725", strings.NewReader(`{"cpf":"52998224725"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(handlers.RequestIDHeader, "req-123")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Successful requests are sampled out and skipped paths never logged
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	entries := logs.All()
+	if assert.Len(t, entries, 1, "Failed requests are always logged") {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "req-123", fields["request_id"])
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", fields["trace_id"])
+		assert.Equal(t, "/subjects/:cpf", fields["route"])
+		for _, value := range fields {
+			if s, ok := value.(string); ok {
+				assert.NotContains(t, s, "52998224725")
+			}
+		}
+		assert.Contains(t, fields["request_body"], utils.Redacted)
+	}
+}
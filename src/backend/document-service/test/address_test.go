@@ -0,0 +1,42 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestExtractCEP(t *testing.T) {
+	assert.Equal(t, "01310100", services.ExtractCEP("Av. Paulista, 1000\nCEP: 01.310-100 São Paulo/SP"))
+	assert.Equal(t, "01310100", services.ExtractCEP("Av. Paulista, 1000 - 01310-100"))
+	assert.Equal(t, "", services.ExtractCEP("Conta de luz referente a 03/2024"))
+}
+
+func TestAddressesMatch(t *testing.T) {
+	standardized := models.Address{
+		Street:     "Avenida Paulista",
+		City:       "São Paulo",
+		State:      "SP",
+		PostalCode: "01310100",
+	}
+
+	tests := []struct {
+		name     string
+		declared models.Address
+		match    bool
+	}{
+		{"Abbreviated street without accents", models.Address{Street: "AV. PAULISTA", City: "SAO PAULO", State: "sp", PostalCode: "01310-100"}, true},
+		{"Different CEP", models.Address{Street: "Avenida Paulista", City: "São Paulo", State: "SP", PostalCode: "01310200"}, false},
+		{"Different city", models.Address{Street: "Avenida Paulista", City: "Santos", State: "SP", PostalCode: "01310100"}, false},
+		{"Different street", models.Address{Street: "Rua Augusta", City: "São Paulo", State: "SP", PostalCode: "01310100"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, services.AddressesMatch(standardized, tt.declared))
+		})
+	}
+}
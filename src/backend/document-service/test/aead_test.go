@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func TestEncryptionMetadataVersions(t *testing.T) {
+	legacy := &models.EncryptionMetadata{Algorithm: models.EncryptionAlgorithmGCMStream}
+	assert.NoError(t, legacy.ValidateFormat(), "Unversioned AES-GCM metadata should stay readable")
+
+	legacy.Algorithm = models.EncryptionAlgorithmXChaCha
+	assert.Error(t, legacy.ValidateFormat(), "Unversioned metadata cannot name XChaCha20")
+
+	current := &models.EncryptionMetadata{
+		Version:   models.EncryptionMetadataVersion,
+		Algorithm: models.EncryptionAlgorithmXChaChaStream,
+	}
+	assert.NoError(t, current.ValidateFormat())
+
+	current.Version = models.EncryptionMetadataVersion + 1
+	assert.Error(t, current.ValidateFormat())
+}
+
+func TestCiphertextSizeXChaCha(t *testing.T) {
+	size, err := utils.CiphertextSize(models.EncryptionAlgorithmXChaCha, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(116), size)
+
+	// 64 KiB chunks each carry their own tag
+	size, err = utils.CiphertextSize(models.EncryptionAlgorithmXChaChaStream, 3<<16)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3<<16+3*16), size)
+
+	_, err = utils.CiphertextSize("AES-256-GCM-SIV", 100)
+	assert.ErrorIs(t, err, utils.ErrInvalidMetadata)
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// TestAnalyticsSpecCoversRecord fails when a column is added to the export
+// without being documented in the anonymization spec
+func TestAnalyticsSpecCoversRecord(t *testing.T) {
+	recordType := reflect.TypeOf(models.AnalyticsRecord{})
+	assert.Equal(t, len(models.AnalyticsSpec), recordType.NumField())
+	for i, column := range models.AnalyticsSpec {
+		field := recordType.Field(i)
+		assert.Equal(t, column.Name, field.Tag.Get("json"), "Field %s is out of spec", field.Name)
+		assert.Equal(t, column.Name, field.Tag.Get("parquet"), "Field %s is out of spec", field.Name)
+		assert.NotEmpty(t, column.Rule)
+	}
+	assert.Len(t, models.AnalyticsRecord{}.CSVRow(), len(models.AnalyticsSpec))
+}
+
+func TestAnonymize(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, testDocumentType, testFilename, "application/pdf", 2<<20)
+	assert.NoError(t, err)
+	doc.ID = "3f1c9a52-6a34-4c1e-9d0b-2f4e8c7a1b90"
+	doc.SetExtractedFields("ocr", []models.ExtractedField{
+		{Name: "cpf", Value: "12345678909", Confidence: 0.9},
+		{Name: "name", Value: "Maria Silva", Confidence: 0.8},
+	})
+	processed := doc.CreatedAt.Add(90 * time.Second)
+	doc.ProcessedAt = &processed
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	record := models.Anonymize(doc, key)
+	row := strings.Join(record.CSVRow(), ",")
+
+	for _, identifying := range []string{doc.ID, testEnrollmentID, testFilename, "12345678909", "Maria Silva"} {
+		assert.NotContains(t, row, identifying)
+	}
+	assert.Equal(t, "1m_5m", record.SizeBucket)
+	assert.Equal(t, int64(90), record.ProcessingSeconds)
+	assert.Equal(t, 0.85, record.MeanExtractionConfidence)
+	assert.Equal(t, record.DocumentKey, models.Anonymize(doc, key).DocumentKey, "Pseudonyms should be stable for a key")
+	assert.NotEqual(t, record.DocumentKey, models.Anonymize(doc, []byte("another key")).DocumentKey)
+}
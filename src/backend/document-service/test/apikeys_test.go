@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestAPIKeyRotationAndRevocation(t *testing.T) {
+	now := time.Now()
+	key := &models.APIKey{ID: "key-1", SecretHash: "hash-1", Scopes: []string{models.APIKeyScopeUpload}}
+	assert.True(t, key.Active(now))
+	assert.True(t, key.Matches("hash-1", now))
+	assert.False(t, key.Matches("hash-2", now))
+
+	key.Rotate("hash-2", now, time.Hour)
+	assert.True(t, key.Matches("hash-2", now))
+	assert.True(t, key.Matches("hash-1", now), "The previous secret works during the grace period")
+	assert.False(t, key.Matches("hash-1", now.Add(2*time.Hour)))
+
+	encoded, err := json.Marshal(key)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), "hash-", "Secret hashes are never serialized")
+
+	key.Revoke("admin", now)
+	key.Revoke("someone-else", now.Add(time.Minute))
+	assert.False(t, key.Active(now))
+	assert.Equal(t, "admin", key.RevokedBy)
+
+	expiresAt := now.Add(time.Hour)
+	expiring := &models.APIKey{ExpiresAt: &expiresAt}
+	assert.True(t, expiring.Active(now))
+	assert.False(t, expiring.Active(expiresAt))
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	key := &models.APIKey{Scopes: []string{models.APIKeyScopeUpload}}
+	assert.True(t, key.Allows(models.APIKeyScopeUpload))
+	assert.False(t, key.Allows(models.APIKeyScopeRead), "Upload-only keys cannot read documents back")
+
+	assert.True(t, key.CoversEmployer("employer-1"), "Keys without employers cover every enrollment of the tenant")
+	key.EmployerIDs = []string{"employer-1"}
+	assert.True(t, key.CoversEmployer("employer-1"))
+	assert.False(t, key.CoversEmployer("employer-2"))
+	assert.False(t, key.CoversEmployer(""), "Enrollments of no employer are outside a restricted key")
+
+	assert.ErrorIs(t, models.ValidateAPIKeyScopes(nil), models.ErrNoAPIKeyScopes)
+	assert.ErrorIs(t, models.ValidateAPIKeyScopes([]string{"documents:delete"}), models.ErrUnknownAPIKeyScope)
+	assert.NoError(t, models.ValidateAPIKeyScopes(models.APIKeyScopes))
+}
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	keys := repository.NewMemoryAPIKeyRepository()
+	now := time.Now()
+	assert.NoError(t, keys.Create(ctx, &models.APIKey{ID: "key-1", TenantID: "tenant-a", CreatedAt: now}))
+	assert.NoError(t, keys.Create(ctx, &models.APIKey{ID: "key-2", TenantID: "tenant-b", CreatedAt: now.Add(time.Second)}))
+
+	all, err := keys.List(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, "key-2", all[0].ID)
+
+	tenantKeys, err := keys.List(ctx, "tenant-a")
+	assert.NoError(t, err)
+	assert.Len(t, tenantKeys, 1)
+
+	_, err = keys.Get(ctx, "key-3")
+	assert.ErrorIs(t, err, repository.ErrAPIKeyNotFound)
+	assert.ErrorIs(t, keys.Update(ctx, &models.APIKey{ID: "key-3"}), repository.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func TestBufferPool(t *testing.T) {
+	buf := utils.GetBuffer(1000)
+	assert.Equal(t, 0, len(buf))
+	assert.GreaterOrEqual(t, cap(buf), 1000)
+
+	buf = append(buf, []byte("sensitive")...)
+	utils.PutBuffer(buf)
+	assert.Equal(t, make([]byte, len("sensitive")), buf[:len("sensitive")], "Released buffers should be zeroed")
+
+	oversized := utils.GetBuffer(64 << 20)
+	assert.GreaterOrEqual(t, cap(oversized), 64<<20)
+	utils.PutBuffer(oversized)
+}
+
+func TestReadPooled(t *testing.T) {
+	content := make([]byte, 700<<10)
+	_, err := rand.Read(content)
+	assert.NoError(t, err)
+
+	for _, hint := range []int{0, len(content)} {
+		read, err := utils.ReadPooled(bytes.NewReader(content), hint)
+		assert.NoError(t, err)
+		assert.Equal(t, content, read)
+		utils.PutBuffer(read)
+	}
+}
+
+// BenchmarkUploadBuffer compares reading an upload with io.ReadAll, as the
+// pipeline did, with reading it into a pooled buffer sized from the declared size
+func BenchmarkUploadBuffer(b *testing.B) {
+	content := make([]byte, 3<<20)
+	if _, err := rand.Read(content); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("read_all", func(b *testing.B) {
+		b.SetBytes(int64(len(content)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := io.ReadAll(bytes.NewReader(content)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.SetBytes(int64(len(content)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf, err := utils.ReadPooled(bytes.NewReader(content), len(content))
+			if err != nil {
+				b.Fatal(err)
+			}
+			utils.PutBuffer(buf)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func awaitingReview(t *testing.T, documentType string) *models.Document {
+	doc, err := models.NewDocument(testEnrollmentID, documentType, testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "OCR completed"))
+	return doc
+}
+
+func TestReviewFilterMatchesDocumentsAwaitingReview(t *testing.T) {
+	doc := awaitingReview(t, "identity")
+	doc.AddReviewFlag(models.ReviewFlagAddressMismatch)
+
+	assert.True(t, models.ReviewFilter{}.Matches(doc))
+	assert.True(t, models.ReviewFilter{
+		EnrollmentID:  testEnrollmentID,
+		DocumentTypes: []string{"proof_of_address", "identity"},
+		ReviewFlags:   []string{models.ReviewFlagScreeningHit, models.ReviewFlagAddressMismatch},
+	}.Matches(doc))
+	assert.False(t, models.ReviewFilter{DocumentTypes: []string{"proof_of_address"}}.Matches(doc))
+	assert.False(t, models.ReviewFilter{ReviewFlags: []string{models.ReviewFlagScreeningHit}}.Matches(doc))
+
+	later := doc.CreatedAt.Add(time.Minute)
+	assert.False(t, models.ReviewFilter{CreatedFrom: &later}.Matches(doc))
+	assert.True(t, models.ReviewFilter{CreatedTo: &later}.Matches(doc))
+
+	assert.NoError(t, doc.Review(models.ReviewDecisionApprove, "", "reviewer-1"))
+	assert.False(t, models.ReviewFilter{}.Matches(doc), "Decided documents leave the list")
+}
+
+func TestSavedFilterValidation(t *testing.T) {
+	now := time.Now()
+	saved := &models.SavedFilter{Owner: "reviewer-1", Name: "  "}
+	assert.ErrorIs(t, saved.Validate(), models.ErrInvalidReviewFilter)
+
+	saved.Name = "Flagged IDs"
+	saved.Filter = models.ReviewFilter{CreatedFrom: &now, CreatedTo: &now}
+	assert.ErrorIs(t, saved.Validate(), models.ErrInvalidReviewFilter, "The creation period cannot be empty")
+
+	saved.Filter.CreatedFrom = nil
+	assert.NoError(t, saved.Validate())
+}
+
+func TestSavedFiltersAreListedByOwner(t *testing.T) {
+	ctx := context.Background()
+	filters := repository.NewMemorySavedFilterRepository()
+	for i, saved := range []*models.SavedFilter{
+		{ID: "f1", Owner: "reviewer-1", Name: "Proofs of address"},
+		{ID: "f2", Owner: "reviewer-2", Name: "Flagged"},
+		{ID: "f3", Owner: "reviewer-1", Name: "Flagged IDs"},
+	} {
+		saved.CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		assert.NoError(t, filters.Create(ctx, saved))
+	}
+
+	owned, err := filters.ListByOwner(ctx, "reviewer-1")
+	assert.NoError(t, err)
+	assert.Len(t, owned, 2)
+	assert.Equal(t, "Flagged IDs", owned[0].Name)
+
+	assert.NoError(t, filters.Delete(ctx, "f3"))
+	_, err = filters.Get(ctx, "f3")
+	assert.ErrorIs(t, err, repository.ErrSavedFilterNotFound)
+	assert.ErrorIs(t, filters.Delete(ctx, "f3"), repository.ErrSavedFilterNotFound)
+}
+
+func TestRollbackReviewKeepsDecisionInHistory(t *testing.T) {
+	doc := awaitingReview(t, "identity")
+	previous := *doc
+
+	assert.NoError(t, doc.Review(models.ReviewDecisionReject, "Illegible", "reviewer-1"))
+	doc.RollbackReview(&previous, "reviewer-1")
+	assert.Equal(t, models.DocumentStatusCompleted, doc.Status)
+	assert.Nil(t, doc.ReviewedAt)
+	assert.Empty(t, doc.ReviewedBy)
+	assert.True(t, models.ReviewFilter{}.Matches(doc), "A rolled back document awaits review again")
+
+	events := models.EventsForAudit(doc.ID, doc.AuditTrail[len(doc.AuditTrail)-2:])
+	assert.Equal(t, models.EventReviewed, events[0].Type)
+	assert.Equal(t, models.EventReviewRolledBack, events[1].Type)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func saoPauloCalendar(t *testing.T, holidays ...string) *models.BusinessCalendar {
+	calendar, err := models.NewBusinessCalendar(models.CalendarSpec{
+		Name:             "sp",
+		Timezone:         "America/Sao_Paulo",
+		OpensAt:          "09:00",
+		ClosesAt:         "18:00",
+		Holidays:         holidays,
+		NationalHolidays: true,
+		OptionalHolidays: true,
+	})
+	assert.NoError(t, err)
+	return calendar
+}
+
+func TestBusinessCalendarObservesBrazilianHolidays(t *testing.T) {
+	calendar := saoPauloCalendar(t, "07-09 Revolução Constitucionalista", "2026-01-25 Aniversário de São Paulo")
+	day := func(date string) time.Time {
+		at, err := time.ParseInLocation("2006-01-02 15:04", date+" 12:00", calendar.Location())
+		assert.NoError(t, err)
+		return at
+	}
+
+	for date, name := range map[string]string{
+		"2026-02-16": "Carnaval",
+		"2026-02-17": "Carnaval",
+		"2026-04-03": "Sexta-feira Santa",
+		"2026-06-04": "Corpus Christi",
+		"2026-11-20": "Dia Nacional de Zumbi e da Consciência Negra",
+		"2026-07-09": "Revolução Constitucionalista",
+		"2026-01-25": "Aniversário de São Paulo",
+	} {
+		holiday, ok := calendar.Holiday(day(date))
+		assert.True(t, ok, date)
+		assert.Equal(t, name, holiday, date)
+	}
+	_, ok := calendar.Holiday(day("2023-11-20"))
+	assert.False(t, ok, "Consciência Negra is a national holiday from 2024")
+	assert.Equal(t, models.ClosedWeekend, calendar.Closed(day("2026-02-14")))
+	assert.Empty(t, calendar.Closed(day("2026-02-18")))
+
+	_, err := models.NewBusinessCalendar(models.CalendarSpec{Timezone: "America/Sao_Paulo", OpensAt: "18:00", ClosesAt: "09:00"})
+	assert.ErrorIs(t, err, models.ErrInvalidCalendar)
+	_, err = models.NewBusinessCalendar(models.CalendarSpec{Timezone: "America/Sao_Paulo", OpensAt: "09:00", ClosesAt: "18:00", Holidays: []string{"13-01"}})
+	assert.ErrorIs(t, err, models.ErrInvalidCalendar)
+}
+
+func TestBusinessCalendarPausesOffHours(t *testing.T) {
+	calendar := saoPauloCalendar(t)
+	// Friday before Carnival, 17:00
+	start := time.Date(2026, time.February, 13, 17, 0, 0, 0, calendar.Location())
+
+	due := calendar.Add(start, 2*time.Hour)
+	assert.Equal(t, time.Date(2026, time.February, 18, 10, 0, 0, 0, calendar.Location()), due, "Weekend and Carnival are skipped")
+	assert.Equal(t, 2*time.Hour, calendar.Elapsed(start, due))
+	assert.Equal(t, -2*time.Hour, calendar.Elapsed(due, start))
+	assert.Equal(t, []models.ClosedDay{
+		{Date: "2026-02-14", Reason: models.ClosedWeekend},
+		{Date: "2026-02-15", Reason: models.ClosedWeekend},
+		{Date: "2026-02-16", Reason: "Carnaval"},
+		{Date: "2026-02-17", Reason: "Carnaval"},
+	}, calendar.ClosedDays(start, due))
+
+	// Uploaded on Saturday, the clock starts on Monday's opening
+	saturday := time.Date(2026, time.March, 7, 11, 0, 0, 0, calendar.Location())
+	assert.Equal(t, time.Date(2026, time.March, 9, 9, 0, 0, 0, calendar.Location()), calendar.Add(saturday, 0))
+
+	var continuous *models.BusinessCalendar
+	assert.Equal(t, start.Add(2*time.Hour), continuous.Add(start, 2*time.Hour))
+}
+
+func TestSLAClockRunsOnBusinessCalendar(t *testing.T) {
+	calendar := saoPauloCalendar(t)
+	doc := awaitingReview(t, "identity")
+	doc.CreatedAt = time.Date(2026, time.February, 13, 17, 0, 0, 0, calendar.Location())
+
+	clock := models.NewSLAClock(doc, 9*time.Hour, calendar, time.Date(2026, time.February, 18, 9, 0, 0, 0, calendar.Location()))
+	assert.Equal(t, "sp", clock.Calendar)
+	assert.Equal(t, time.Date(2026, time.February, 18, 17, 0, 0, 0, calendar.Location()), clock.DueAt)
+	assert.Equal(t, int64(8*3600), clock.RemainingSeconds)
+	assert.InDelta(t, 1.0/9, clock.Elapsed, 0.001)
+	assert.False(t, clock.Breached)
+}
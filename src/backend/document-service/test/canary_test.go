@@ -0,0 +1,70 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const canaryText = "REPUBLICA FEDERATIVA DO BRASIL\nNOME\nMARIA DA SILVA\nCPF 529.982.247-25\nCEP: 01310-100\n"
+
+func fieldOutcomes(comparison models.OCRCanaryComparison) map[string]string {
+	outcomes := make(map[string]string)
+	for _, field := range comparison.Fields {
+		outcomes[field.Field] = field.Outcome
+	}
+	return outcomes
+}
+
+func TestCompareOCRText(t *testing.T) {
+	same := services.CompareOCRText(canaryText, "republica federativa do brasil\nNOME\nMaria da Silva\nCPF 52998224725\nCEP 01310100\n", 0.9)
+	assert.False(t, same.Diverged, "Case, punctuation and formatting do not count")
+	assert.Equal(t, map[string]string{
+		services.FieldHolderName:        models.FieldAgreementMatch,
+		services.FieldCPF:               models.FieldAgreementMatch,
+		services.FieldAddressPostalCode: models.FieldAgreementMatch,
+	}, fieldOutcomes(same))
+
+	misread := services.CompareOCRText(canaryText, "REPUBLICA FEDERATIVA DO BRASIL\nNOME\nMARIA DA SILVA\nCPF 529.982.247-26\nCEP: 01310-100\n", 0.5)
+	assert.True(t, misread.Diverged, "A misread field diverges whatever the word agreement")
+	assert.Greater(t, misread.TextAgreement, 0.8)
+	assert.Equal(t, models.FieldAgreementMissingCandidate, fieldOutcomes(misread)[services.FieldCPF], "An invalid CPF is not extracted")
+
+	garbled := services.CompareOCRText(canaryText, "REPUBL1CA FEDERAT1VA D0 BRAS1L\nN0ME\nMARIA DA SILVA\nCPF 529.982.247-25\nCEP: 01310-100\n", 0.9)
+	assert.True(t, garbled.Diverged)
+	assert.Less(t, garbled.TextAgreement, 0.9)
+	assert.Equal(t, models.FieldAgreementMissingCandidate, fieldOutcomes(garbled)[services.FieldHolderName])
+
+	empty := services.CompareOCRText("", "", 0.9)
+	assert.Equal(t, 1.0, empty.TextAgreement)
+	assert.Empty(t, empty.Fields, "Fields found in neither text are not compared")
+}
+
+func TestSummarizeOCRCanary(t *testing.T) {
+	comparisons := []models.OCRCanaryComparison{
+		services.CompareOCRText(canaryText, canaryText, 0.9),
+		services.CompareOCRText(canaryText, "NOME\nMARIA DA SILVA\nCEP: 04538-133\n", 0.9),
+		{Failed: true},
+	}
+	report := services.SummarizeOCRCanary("candidate", comparisons, []string{"doc-1", "doc-2", "doc-3"})
+
+	assert.Equal(t, "candidate", report.Candidate)
+	assert.Equal(t, 3, report.Compared)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 1, report.Diverged)
+	assert.Equal(t, []string{"doc-2"}, report.DivergedDocuments)
+	assert.InDelta(t, (1+comparisons[1].TextAgreement)/2, report.MeanTextAgreement, 1e-9, "Failed reads are left out of the mean")
+
+	rates := make(map[string]models.FieldAgreementRate)
+	for _, rate := range report.Fields {
+		rates[rate.Field] = rate
+	}
+	assert.Equal(t, 2, rates[services.FieldHolderName].Matched)
+	assert.Equal(t, 1.0, rates[services.FieldHolderName].Agreement)
+	assert.Equal(t, 1, rates[services.FieldCPF].MissingCandidate)
+	assert.Equal(t, 1, rates[services.FieldAddressPostalCode].Mismatched)
+	assert.Equal(t, 0.5, rates[services.FieldAddressPostalCode].Agreement)
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestDocumentDisposition(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	retention := doc.RetentionDate
+	cancelledAt := time.Now()
+
+	doc.Dispose("event-1", models.DispositionRetain, cancelledAt, cancelledAt.AddDate(20, 0, 0))
+	assert.Equal(t, "event-1", doc.Disposition.SagaID)
+	assert.True(t, doc.RetentionDate.After(retention), "Retained documents should be kept longer")
+
+	doc.RevertDisposition(time.Now())
+	assert.Nil(t, doc.Disposition)
+	assert.Equal(t, retention, doc.RetentionDate, "Compensation should restore the retention date")
+
+	// A shorter retention never shortens the existing one
+	doc.Dispose("event-2", models.DispositionRetain, cancelledAt, cancelledAt.AddDate(1, 0, 0))
+	assert.Equal(t, retention, doc.RetentionDate)
+}
+
+func TestAnonymizeForgetsPastStates(t *testing.T) {
+	ctx := context.Background()
+	documents := repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository())
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, documents.Create(ctx, doc))
+	doc.SetExtractedFields("receita", []models.ExtractedField{{Name: "cpf", Value: "52998224725", Confidence: 1}})
+	doc.OCRPreview = "NOME MARIA"
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	doc.Anonymize(time.Now())
+	assert.True(t, doc.Anonymized())
+	assert.NoError(t, documents.Redact(ctx, doc))
+
+	history, err := documents.History(ctx, doc.ID)
+	assert.NoError(t, err, "The chain should still verify after redaction")
+	last := history[len(history)-1]
+	assert.Equal(t, models.EventDocumentAnonymized, last.Type)
+	for _, event := range history[:len(history)-1] {
+		assert.Empty(t, event.Patch)
+	}
+	assert.NotContains(t, string(last.Patch), "52998224725")
+	assert.NotContains(t, string(last.Patch), testFilename)
+
+	replayed, err := documents.Replay(ctx, doc.ID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, replayed) {
+		assert.True(t, replayed.Anonymized())
+		assert.Empty(t, replayed.ExtractedFields)
+		assert.Equal(t, "identity", replayed.DocumentType)
+		assert.Equal(t, doc.Status, replayed.Status)
+	}
+}
+
+func TestEnrollmentEventsRequireRequestSignature(t *testing.T) {
+	cfg := signingConfig("k1", map[string]string{"k1": strings.Repeat("a", 32)})
+	cfg.CancellationConfig.Enabled = true
+	cfg.CancellationConfig.Timeout = time.Second
+
+	documents := repository.NewMemoryDocumentRepository()
+	outbox := repository.NewMemoryOutboxRepository()
+	shredder, err := services.NewCryptoShredder(cfg, &services.StorageService{}, documents, outbox, zap.NewNop())
+	assert.NoError(t, err)
+	cancellation, err := services.NewCancellationService(cfg, repository.NewMemoryCancellationSagaRepository(), documents, shredder, outbox, zap.NewNop())
+	assert.NoError(t, err)
+	handler, err := handlers.NewCancellationHandler(cfg, cancellation, zap.NewNop())
+	assert.NoError(t, err)
+
+	event := []byte(`{"event_id":"event-1","type":"enrollment.renamed","enrollment_id":"enr-1"}`)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(t, cfg, "/webhooks/enrollment", handler.ReceiveEvent, event, false), "Unsigned events are rejected")
+	// A signed event reaches the handler, which rejects the unknown type
+	assert.Equal(t, http.StatusBadRequest, postWebhook(t, cfg, "/webhooks/enrollment", handler.ReceiveEvent, event, true))
+}
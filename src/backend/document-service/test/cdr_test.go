@@ -0,0 +1,88 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// flateStream wraps data in a Flate-compressed PDF stream object
+func flateStream(t *testing.T, data string) string {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	_, err := writer.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return "5 0 obj\n<< /Type /ObjStm /Filter /FlateDecode >>\nstream\n" + compressed.String() + "\nendstream\nendobj\n"
+}
+
+func TestFindActiveContent(t *testing.T) {
+	header := "%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R"
+
+	tests := []struct {
+		name     string
+		pdf      string
+		expected []string
+	}{
+		{
+			name:     "clean",
+			pdf:      header + " >>\nendobj\n3 0 obj\n<< /Length 12 >>\nstream\nBT (Ok) Tj ET\nendstream\nendobj\n%%EOF",
+			expected: []string{},
+		},
+		{
+			name:     "plain names",
+			pdf:      header + " /OpenAction 4 0 R >>\nendobj\n4 0 obj\n<< /S /JavaScript /JS (app.alert(1)) >>\nendobj\n%%EOF",
+			expected: []string{models.ActiveContentAutomaticAction, models.ActiveContentJavaScript},
+		},
+		{
+			name:     "escaped names",
+			pdf:      header + " /Names << /J#61vaScript 4 0 R /Emb#65dd#65dFiles 6 0 R >> >>\nendobj\n%%EOF",
+			expected: []string{models.ActiveContentEmbeddedFile, models.ActiveContentJavaScript},
+		},
+		{
+			name:     "names hidden in an object stream",
+			pdf:      header + " >>\nendobj\n" + flateStream(t, "<< /S /Launch /F (cmd.exe) >> << /XFA 7 0 R >>") + "%%EOF",
+			expected: []string{models.ActiveContentLaunch, models.ActiveContentXFA},
+		},
+		{
+			name:     "names that only start like active content",
+			pdf:      header + " /JavaScripts /AAA /XFAForm >>\nendobj\n%%EOF",
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.FindActiveContent([]byte(tt.pdf)))
+		})
+	}
+}
+
+func TestMarkDisarmedKeepsOriginalSize(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 4096)
+	assert.NoError(t, err)
+
+	doc.MarkDisarmed([]string{models.ActiveContentJavaScript}, 1024)
+	assert.Equal(t, int64(1024), doc.Size)
+	assert.Equal(t, int64(4096), doc.Disarm.OriginalSize)
+	assert.Equal(t, []string{models.ActiveContentJavaScript}, doc.Disarm.Findings)
+	assert.Equal(t, "DISARM", doc.AuditTrail[len(doc.AuditTrail)-1].Action)
+
+	assert.True(t, models.IsQuarantinedRendition(models.RenditionQuarantinedOriginal))
+	assert.False(t, models.IsQuarantinedRendition(models.RenditionOriginal))
+}
+
+func TestPDFDisarmerDisabled(t *testing.T) {
+	disarmer, err := services.NewPDFDisarmer(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, disarmer)
+
+	_, err = services.NewPDFDisarmer(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// checksumBucket is an S3 endpoint checking the Content-MD5 of uploads and
+// answering with the ETag its etag func picks for the stored content
+type checksumBucket struct {
+	mu       sync.Mutex
+	previous []byte
+	etag     func(previous, stored []byte) string
+}
+
+func (b *checksumBucket) s3(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		// The bucket exists
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err == nil && strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		body, err = decodeAWSChunked(body)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sum := md5.Sum(body)
+	if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>BadDigest</Code><Message>The Content-MD5 you specified did not match what we received</Message></Error>`)
+		return
+	}
+
+	b.mu.Lock()
+	previous := b.previous
+	b.previous = body
+	b.mu.Unlock()
+	if etag := b.etag(previous, body); etag != "" {
+		w.Header().Set("ETag", `"`+etag+`"`)
+	}
+}
+
+// decodeAWSChunked strips the chunk framing of a streaming signed upload
+func decodeAWSChunked(body []byte) ([]byte, error) {
+	decoded := make([]byte, 0, len(body))
+	for {
+		header, rest, ok := strings.Cut(string(body), "\r\n")
+		if !ok {
+			return nil, fmt.Errorf("truncated chunk header")
+		}
+		size, err := strconv.ParseInt(strings.SplitN(header, ";", 2)[0], 16, 64)
+		if err != nil || int64(len(rest)) < size+2 {
+			return nil, fmt.Errorf("malformed chunk")
+		}
+		if size == 0 {
+			return decoded, nil
+		}
+		decoded = append(decoded, rest[:size]...)
+		body = []byte(rest[size+2:])
+	}
+}
+
+func md5Hex(content []byte) string {
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func newChecksumStore(t *testing.T, etag func(previous, stored []byte) string) *services.MinioObjectStore {
+	bucket := &checksumBucket{etag: etag}
+	s3 := httptest.NewServer(http.HandlerFunc(bucket.s3))
+	t.Cleanup(s3.Close)
+
+	store, err := services.NewS3ObjectStore(context.Background(), config.S3Config{
+		Endpoint:        strings.TrimPrefix(s3.URL, "http://"),
+		Region:          "us-east-1",
+		BucketName:      "documents",
+		AccessKey:       "AK1",
+		SecretKey:       "secret",
+		VerifyChecksums: true,
+	}, config.HTTPTransportConfig{DialTimeout: time.Second, TLSHandshakeTimeout: time.Second})
+	assert.NoError(t, err)
+	return store
+}
+
+func TestObjectStoreAcceptsMatchingETag(t *testing.T) {
+	store := newChecksumStore(t, func(previous, stored []byte) string {
+		return md5Hex(stored)
+	})
+
+	err := store.Put(context.Background(), "documents/doc-1", []byte("ciphertext"), "application/octet-stream", nil)
+	assert.NoError(t, err)
+}
+
+func TestObjectStoreRejectsStaleETag(t *testing.T) {
+	// The endpoint answers with the ETag of the object it held before
+	store := newChecksumStore(t, func(previous, stored []byte) string {
+		return md5Hex(previous)
+	})
+	ctx := context.Background()
+
+	assert.Error(t, store.Put(ctx, "documents/doc-1", []byte("version 1"), "application/octet-stream", nil))
+	err := store.Put(ctx, "documents/doc-1", []byte("version 2"), "application/octet-stream", nil)
+	assert.ErrorIs(t, err, services.ErrUploadCorrupted)
+	assert.Contains(t, err.Error(), md5Hex([]byte("version 1")))
+}
+
+func TestObjectStoreRejectsMissingETag(t *testing.T) {
+	store := newChecksumStore(t, func(previous, stored []byte) string {
+		return ""
+	})
+
+	err := store.Put(context.Background(), "documents/doc-1", []byte("ciphertext"), "application/octet-stream", nil)
+	assert.ErrorIs(t, err, services.ErrUploadCorrupted, "An upload without an ETag cannot be verified")
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func newRecipientKey(t *testing.T) (*ecdh.PrivateKey, string) {
+	private, err := ecdh.P256().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(private.PublicKey())
+	assert.NoError(t, err)
+	return private, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestClientEncryptionEnvelope(t *testing.T) {
+	private, publicPEM := newRecipientKey(t)
+	_, currentPEM := newRecipientKey(t)
+
+	cfg := &config.Config{}
+	cfg.ClientEncryptionConfig = config.ClientEncryptionConfig{
+		Enabled:       true,
+		RecipientKeys: []string{currentPEM, publicPEM},
+		DocumentTypes: []string{"medical_record"},
+		DownloadRoles: []string{"underwriter"},
+	}
+	encryption, err := services.NewClientEncryption(cfg)
+	assert.NoError(t, err)
+
+	recipient, err := utils.ParseRecipientKey(publicPEM)
+	assert.NoError(t, err)
+	report := []byte("%PDF-1.7 laudo medico")
+	envelope, err := utils.SealEnvelope(report, recipient)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(envelope), "laudo", "The envelope should not carry plaintext")
+
+	// Envelopes sealed to an earlier team key are still accepted
+	accepted, err := encryption.Accept("medical_record", envelope)
+	assert.NoError(t, err)
+	assert.Equal(t, recipient.ID, accepted.RecipientKeyID)
+	assert.Equal(t, models.ClientEncryptionAlgorithm, accepted.Algorithm)
+
+	_, err = encryption.Accept("identity", envelope)
+	assert.ErrorIs(t, err, services.ErrClientEncryptionNotAllowed)
+	_, err = encryption.Accept("medical_record", report)
+	assert.ErrorIs(t, err, utils.ErrInvalidEnvelope, "Plaintext uploaded as encrypted should be rejected")
+
+	_, strangerPEM := newRecipientKey(t)
+	stranger, err := utils.ParseRecipientKey(strangerPEM)
+	assert.NoError(t, err)
+	foreign, err := utils.SealEnvelope(report, stranger)
+	assert.NoError(t, err)
+	_, err = encryption.Accept("medical_record", foreign)
+	assert.ErrorIs(t, err, utils.ErrUnknownRecipient)
+
+	opened, err := utils.OpenEnvelope(envelope, private)
+	assert.NoError(t, err)
+	assert.Equal(t, report, opened)
+
+	envelope[len(envelope)-1] ^= 1
+	_, err = utils.OpenEnvelope(envelope, private)
+	assert.ErrorIs(t, err, utils.ErrDecryptionFailed)
+
+	assert.True(t, encryption.MayDownload("underwriter"))
+	assert.False(t, encryption.MayDownload("broker"))
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// baseConfigYAML is the least configuration the service loads with
+const baseConfigYAML = `
+minio:
+  endpoint: minio:9000
+  bucket_name: documents
+azure:
+  endpoint: https://ocr.example.com
+  subscription_key: subscription-key
+security:
+  encryption_key: alias/documents
+  trusted_origins: [https://portal.example.com]
+enrollment:
+  base_url: https://enrollment.example.com
+`
+
+// loadTestConfig loads the base configuration followed by sections, as the
+// service loads its configuration file
+func loadTestConfig(t *testing.T, sections string) (*config.Config, error) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(baseConfigYAML+sections), 0o600))
+	return config.LoadConfig(dir)
+}
+
+func TestBaseConfigLoads(t *testing.T) {
+	cfg, err := loadTestConfig(t, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "documents", cfg.MinioConfig.BucketName)
+	}
+}
+
+func TestFeatureFlagsWithDottedNamesLoad(t *testing.T) {
+	// The documented example, with the flag names the service consults
+	cfg, err := loadTestConfig(t, `
+feature_flags:
+  provider: unleash
+  url: https://unleash.internal
+  api_key: ${UNLEASH_API_KEY}
+  flags:
+    - name: experiment.classifier-v2
+      enabled: false
+    - name: pipeline.ocr
+      enabled: false
+    - name: storage.read_secondary
+      enabled: true
+`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	flags, err := services.NewFeatureFlags(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	assert.False(t, flags.Enabled(services.ExperimentFlag("classifier-v2"), true))
+	assert.False(t, flags.Enabled(services.StepFlag(services.StepOCR), true))
+	assert.True(t, flags.Enabled(services.FlagStorageReadSecondary, false))
+	assert.True(t, flags.Enabled(services.FlagUploads, true), "Flags missing from the file keep their fallback")
+
+	_, err = loadTestConfig(t, `
+feature_flags:
+  flags:
+    - name: pipeline.ocr
+      enabled: false
+    - name: pipeline.ocr
+      enabled: true
+`)
+	assert.ErrorContains(t, err, "duplicate feature flag")
+}
+
+func TestUnderwritingTransportConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+underwriting:
+  enabled: true
+  transport: grpc
+  grpc_target: underwriting.internal:443
+`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "underwriting.internal:443", cfg.UnderwritingConfig.GRPCTarget)
+	}
+
+	_, err = loadTestConfig(t, `
+underwriting:
+  enabled: true
+  transport: grpc
+`)
+	assert.ErrorContains(t, err, "underwriting grpc target is required")
+
+	_, err = loadTestConfig(t, `
+underwriting:
+  enabled: true
+  transport: amqp
+  base_url: https://underwriting.internal
+`)
+	assert.ErrorContains(t, err, "unsupported underwriting transport")
+}
+
+func TestEnrollmentBaseURLRequiredOnlyByItsUsers(t *testing.T) {
+	// The base configuration without the enrollment service
+	dir := t.TempDir()
+	base := strings.Replace(baseConfigYAML, "enrollment:\n  base_url: https://enrollment.example.com\n", "", 1)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(base), 0o600))
+	_, err := config.LoadConfig(dir)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(base+`
+whatsapp:
+  enabled: true
+  phone_number_id: phone-1
+  access_token: access-token
+  app_secret: app-secret-with-enough-characters-0
+  verify_token: verify-token
+`), 0o600))
+	_, err = config.LoadConfig(dir)
+	assert.ErrorContains(t, err, "enrollment service base url is required by whatsapp")
+}
+
+func TestTrustedProxiesConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, "")
+	if assert.NoError(t, err) {
+		assert.Empty(t, cfg.SecurityConfig.TrustedProxies, "No proxy is trusted by default")
+	}
+
+	// The base security section is repeated, as a second one would replace it
+	withProxies := func(proxies string) string {
+		return strings.Replace(baseConfigYAML, "  trusted_origins: [https://portal.example.com]\n", "  trusted_origins: [https://portal.example.com]\n  trusted_proxies: "+proxies+"\n", 1)
+	}
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(withProxies("[10.0.0.0/8, 192.0.2.1]")), 0o600))
+	cfg, err = config.LoadConfig(dir)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"10.0.0.0/8", "192.0.2.1"}, cfg.SecurityConfig.TrustedProxies)
+	}
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(withProxies("[ingress.internal]")), 0o600))
+	_, err = config.LoadConfig(dir)
+	assert.ErrorContains(t, err, "is not an IP address or CIDR")
+}
+
+func TestCancellationRequiresRequestSigning(t *testing.T) {
+	_, err := loadTestConfig(t, `
+cancellation:
+  enabled: true
+  report_url: https://enrollment.example.com/cancellations
+`)
+	assert.ErrorContains(t, err, "enrollment cancellation requires request signing")
+}
+
+func TestSealsRequireRequestSigning(t *testing.T) {
+	_, err := loadTestConfig(t, `
+seal:
+  enabled: true
+  signing_key: seal-signing-key
+`)
+	assert.ErrorContains(t, err, "enrollment seals require request signing")
+}
+
+func TestConsentRequiresRequestSigning(t *testing.T) {
+	_, err := loadTestConfig(t, `
+consent:
+  enabled: true
+`)
+	assert.ErrorContains(t, err, "consent revocation requires request signing")
+}
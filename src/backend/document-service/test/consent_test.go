@@ -0,0 +1,83 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.24.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestConsentRevocationCancelsInFlightRuns(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.EnrollmentConfig.BaseURL = "http://enrollment.invalid"
+	enrollments, err := services.NewEnrollmentClient(cfg)
+	assert.NoError(t, err)
+
+	registry := services.NewConsentRegistry()
+	consent, err := services.NewConsentService(registry, repository.NewMemoryDocumentRepository(), enrollments, zap.NewNop())
+	assert.NoError(t, err)
+
+	doc := &models.Document{ID: "doc-1", EnrollmentID: testEnrollmentID}
+	runCtx, release := registry.Track(context.Background(), doc)
+	defer release()
+
+	revokedAt := time.Now()
+	err = consent.Handle(context.Background(), services.ConsentEvent{
+		EventID:       "evt-1",
+		Type:          services.ConsentEventRevoked,
+		EnrollmentIDs: []string{testEnrollmentID},
+		OccurredAt:    revokedAt,
+	})
+	assert.NoError(t, err)
+
+	assert.Error(t, runCtx.Err(), "In-flight runs should be cancelled")
+	assert.True(t, services.HaltedForConsent(runCtx))
+	assert.True(t, registry.Revoked(testEnrollmentID))
+
+	lateCtx, lateRelease := registry.Track(context.Background(), &models.Document{ID: "doc-2", EnrollmentID: testEnrollmentID})
+	defer lateRelease()
+	assert.True(t, services.HaltedForConsent(lateCtx), "Runs started after the revocation should be halted")
+
+	// A grant older than the revocation arriving out of order is ignored
+	err = consent.Handle(context.Background(), services.ConsentEvent{
+		Type:          services.ConsentEventGranted,
+		EnrollmentIDs: []string{testEnrollmentID},
+		OccurredAt:    revokedAt.Add(-time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.True(t, registry.Revoked(testEnrollmentID))
+
+	err = consent.Handle(context.Background(), services.ConsentEvent{
+		Type:          services.ConsentEventGranted,
+		EnrollmentIDs: []string{testEnrollmentID},
+		OccurredAt:    revokedAt.Add(time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.False(t, registry.Revoked(testEnrollmentID))
+}
+
+func TestConsentEventsRequireRequestSignature(t *testing.T) {
+	cfg := signingConfig("k1", map[string]string{"k1": strings.Repeat("a", 32)})
+	cfg.EnrollmentConfig.BaseURL = "http://enrollment.invalid"
+	enrollments, err := services.NewEnrollmentClient(cfg)
+	assert.NoError(t, err)
+	consent, err := services.NewConsentService(services.NewConsentRegistry(), repository.NewMemoryDocumentRepository(), enrollments, zap.NewNop())
+	assert.NoError(t, err)
+	handler, err := handlers.NewConsentHandler(cfg, consent, zap.NewNop())
+	assert.NoError(t, err)
+
+	event := []byte(`{"event_id":"evt-1","type":"consent.paused","enrollment_ids":["enr-1"]}`)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(t, cfg, "/webhooks/consent", handler.ReceiveEvent, event, false), "Unsigned events are rejected")
+	// A signed event reaches the handler, which rejects the unknown type
+	assert.Equal(t, http.StatusBadRequest, postWebhook(t, cfg, "/webhooks/consent", handler.ReceiveEvent, event, true))
+}
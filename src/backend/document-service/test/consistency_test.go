@@ -0,0 +1,111 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestDocumentVersions(t *testing.T) {
+	ctx := context.Background()
+	documents := repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository())
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, documents.Create(ctx, doc))
+	assert.Equal(t, int64(1), doc.Version)
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	assert.NoError(t, documents.Update(ctx, doc))
+	assert.Equal(t, int64(2), doc.Version)
+
+	// Saving an unchanged document keeps its version, even from a stale copy
+	stale, err := documents.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	stale.Version = 1
+	assert.NoError(t, documents.Update(ctx, stale))
+	assert.Equal(t, int64(2), stale.Version)
+
+	stored, err := documents.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stored.Version)
+	replayed, err := documents.Replay(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), replayed.Version)
+}
+
+func TestWriteThroughReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	cache := repository.NewMemoryDocumentCache()
+	writer := repository.NewWriteThroughDocumentRepository(
+		repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository()),
+		cache, time.Minute)
+	// The other instance reads from a replica that has not seen the upload
+	replica := repository.NewMemoryDocumentRepository()
+	reader := repository.NewWriteThroughDocumentRepository(replica, cache, time.Minute)
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, writer.Create(ctx, doc))
+
+	_, err = reader.GetByID(ctx, doc.ID)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound, "Plain reads should not use the cache")
+	read, cached, err := reader.GetVersion(ctx, doc.ID, doc.Version)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, doc.ID, read.ID)
+
+	// The replica catches up with the upload but not with the next change
+	assert.NoError(t, replica.Create(ctx, read))
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	assert.NoError(t, writer.Update(ctx, doc))
+
+	read, cached, err = reader.GetVersion(ctx, doc.ID, 1)
+	assert.NoError(t, err)
+	assert.False(t, cached, "A replica that caught up should serve the read")
+	assert.Equal(t, int64(1), read.Version)
+	read, cached, err = reader.GetVersion(ctx, doc.ID, doc.Version)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, models.DocumentStatusProcessing, read.Status)
+
+	_, _, err = reader.GetVersion(ctx, doc.ID, doc.Version+1)
+	assert.ErrorIs(t, err, repository.ErrVersionUnavailable)
+	_, _, err = reader.GetVersion(ctx, "missing", 1)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+
+	// An older write arriving late does not replace the cached version
+	older := *read
+	older.Version = 1
+	assert.NoError(t, cache.Put(ctx, &older, time.Now().Add(time.Minute)))
+	_, _, err = reader.GetVersion(ctx, doc.ID, doc.Version)
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Delete(ctx, doc.ID))
+	_, err = cache.Get(ctx, doc.ID)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+}
+
+func TestDocumentCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache := repository.NewMemoryDocumentCache()
+	now := time.Now()
+
+	assert.NoError(t, cache.Put(ctx, &models.Document{ID: "expired", Version: 1}, now.Add(-time.Second)))
+	assert.NoError(t, cache.Put(ctx, &models.Document{ID: "fresh", Version: 1}, now.Add(time.Minute)))
+
+	_, err := cache.Get(ctx, "expired")
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+	deleted, err := cache.DeleteExpired(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = cache.Get(ctx, "fresh")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newSidecarConverter(t *testing.T, handler http.HandlerFunc) *services.SidecarConverter {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	cfg.ConverterConfig = config.ConverterConfig{
+		Enabled:       true,
+		Provider:      "sidecar",
+		BaseURL:       server.URL,
+		Timeout:       5 * time.Second,
+		MaxConcurrent: 1,
+	}
+	converter, err := services.NewSidecarConverter(cfg)
+	assert.NoError(t, err)
+	return converter
+}
+
+func TestSidecarConverterReturnsPDF(t *testing.T) {
+	filenames := make(chan string, 1)
+	converter := newSidecarConverter(t, func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("files")
+		if assert.NoError(t, err) {
+			io.Copy(io.Discard, file)
+			filenames <- header.Filename
+		}
+		w.Write([]byte("%PDF-1.7 converted"))
+	})
+
+	converted, err := converter.Convert(context.Background(), models.MimeTypeODT, []byte("odt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "%PDF-1.7 converted", string(converted))
+	assert.Equal(t, "document.odt", <-filenames, "The sidecar picks the input format from the extension")
+}
+
+func TestSidecarConverterReportsActionableErrors(t *testing.T) {
+	converter := newSidecarConverter(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "LibreOffice failed to process a document", http.StatusBadRequest)
+	})
+
+	_, err := converter.Convert(context.Background(), models.MimeTypeDOCX, []byte("docx"))
+	assert.ErrorIs(t, err, services.ErrConversionFailed)
+	conversionErr, ok := err.(*services.ConversionError)
+	if assert.True(t, ok) {
+		assert.Equal(t, services.ConversionReasonCorrupt, conversionErr.Reason)
+		assert.NotEmpty(t, conversionErr.Action)
+	}
+
+	// A failing sidecar says nothing about the file, which may be sent again
+	converter = newSidecarConverter(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	})
+	_, err = converter.Convert(context.Background(), models.MimeTypeDOCX, []byte("docx"))
+	assert.ErrorIs(t, err, services.ErrConverterUnavailable)
+	assert.NotErrorIs(t, err, services.ErrConversionFailed)
+}
+
+func TestSidecarConverterDisabled(t *testing.T) {
+	converter, err := services.NewSidecarConverter(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, converter)
+}
+
+func TestComposedDocumentRecordsPartOrder(t *testing.T) {
+	tiff, ok := models.LookupContentType(models.MimeTypeTIFF)
+	assert.True(t, ok)
+	assert.True(t, tiff.Image(), "TIFF pages are converted by the service itself")
+	docx, _ := models.LookupContentType(models.MimeTypeDOCX)
+	assert.False(t, docx.Image(), "Office documents cannot be composed")
+
+	doc, err := models.NewDocument("enrollment-1", "ID", "scan.pdf", "application/pdf", 4096)
+	assert.NoError(t, err)
+	doc.MarkComposed([]string{models.MimeTypeTIFF, "image/jpeg"})
+	assert.Equal(t, []string{models.MimeTypeTIFF, "image/jpeg"}, doc.ComposedFrom)
+	assert.Equal(t, "original_1", models.OriginalRendition(0))
+	assert.Equal(t, "original_2", models.OriginalRendition(1))
+}
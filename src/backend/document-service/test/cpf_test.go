@@ -0,0 +1,34 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestValidCPF(t *testing.T) {
+	tests := []struct {
+		name  string
+		cpf   string
+		valid bool
+	}{
+		{"Valid CPF", "52998224725", true},
+		{"Wrong check digit", "52998224726", false},
+		{"Repeated digits", "11111111111", false},
+		{"Too short", "5299822472", false},
+		{"Formatted", "529.982.247-25", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.valid, services.ValidCPF(tt.cpf))
+		})
+	}
+}
+
+func TestExtractCPFs(t *testing.T) {
+	text := "NOME JOAO DA SILVA CPF 529.982.247-25 RG 12.345.678-9 CPF 52998224725 DOC 111.111.111-11"
+	assert.Equal(t, []string{"52998224725"}, services.ExtractCPFs(text))
+}
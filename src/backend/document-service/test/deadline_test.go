@@ -0,0 +1,122 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// deadlineRecorder collects the exceeded deadlines reported to the observer
+type deadlineRecorder struct {
+	mu       sync.Mutex
+	exceeded []string
+}
+
+func (r *deadlineRecorder) observe(dependency string, inherited bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if inherited {
+		dependency += "/request"
+	}
+	r.exceeded = append(r.exceeded, dependency)
+}
+
+func recordDeadlines(t *testing.T) *deadlineRecorder {
+	recorder := &deadlineRecorder{}
+	utils.SetDeadlineObserver(recorder.observe)
+	t.Cleanup(func() { utils.SetDeadlineObserver(nil) })
+	return recorder
+}
+
+// blockUntilDone waits on a dependency that never answers
+func blockUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestCallWithDeadlineBlamesDependencyTimeout(t *testing.T) {
+	recorder := recordDeadlines(t)
+
+	err := utils.CallWithDeadline(context.Background(), config.DependencyKMS, 10*time.Millisecond, blockUntilDone)
+	var exceeded *utils.DeadlineError
+	assert.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, config.DependencyKMS, exceeded.Dependency)
+	assert.False(t, exceeded.Inherited, "The dependency's own timeout fired")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{config.DependencyKMS}, recorder.exceeded)
+}
+
+func TestCallWithDeadlineNeverExtendsRequestDeadline(t *testing.T) {
+	recorder := recordDeadlines(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := utils.CallWithDeadline(ctx, config.DependencyStorage, time.Minute, blockUntilDone)
+	assert.Less(t, time.Since(start), time.Second)
+	var exceeded *utils.DeadlineError
+	assert.True(t, errors.As(err, &exceeded))
+	assert.True(t, exceeded.Inherited, "The request deadline expired first")
+	assert.Equal(t, []string{config.DependencyStorage + "/request"}, recorder.exceeded)
+}
+
+func TestCallWithDeadlineBlamesInnermostDependency(t *testing.T) {
+	recorder := recordDeadlines(t)
+
+	// Storage encrypts under a KMS call that outlives its own timeout
+	err := utils.CallWithDeadline(context.Background(), config.DependencyStorage, time.Minute, func(ctx context.Context) error {
+		return utils.CallWithDeadline(ctx, config.DependencyKMS, 10*time.Millisecond, blockUntilDone)
+	})
+	var exceeded *utils.DeadlineError
+	assert.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, config.DependencyKMS, exceeded.Dependency)
+	assert.Equal(t, []string{config.DependencyKMS}, recorder.exceeded)
+}
+
+func TestCallWithDeadlinePassesOtherErrors(t *testing.T) {
+	recorder := recordDeadlines(t)
+	refused := errors.New("connection refused")
+
+	err := utils.CallWithDeadline(context.Background(), config.DependencyOCR, time.Minute, func(context.Context) error {
+		return refused
+	})
+	assert.Equal(t, refused, err)
+
+	// A timeout of 0 leaves the call bounded by the caller alone
+	err = utils.CallWithDeadline(context.Background(), config.DependencyOCR, 0, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// A cancelled request is not a deadline
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = utils.CallWithDeadline(ctx, config.DependencyOCR, time.Minute, blockUntilDone)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, recorder.exceeded)
+}
+
+func TestDependencyTimeoutsFitRouteTimeouts(t *testing.T) {
+	cfg := &config.Config{ServiceConfig: newTestServiceConfig()}
+	cfg.MinioConfig.UploadTimeout = 30 * time.Second
+	cfg.MinioConfig.DownloadTimeout = 30 * time.Second
+	cfg.AzureConfig.OCRTimeout = 10 * time.Second
+	cfg.SecurityConfig.KMSTimeout = 5 * time.Second
+	cfg.VirusScanConfig.Enabled = true
+	cfg.VirusScanConfig.Timeout = 20 * time.Second
+
+	snapshot := cfg.Limits()
+	assert.Equal(t, 65*time.Second, snapshot.IngestTimeout, "Uploads are scanned, encrypted, stored and recognized in turn")
+	assert.Equal(t, 35*time.Second, snapshot.RetrieveTimeout)
+	assert.Equal(t, 5*time.Second, snapshot.KMSTimeout)
+	assert.LessOrEqual(t, snapshot.IngestTimeout, snapshot.Routes[config.RouteGroupUpload].Timeout)
+}
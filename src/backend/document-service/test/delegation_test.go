@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const testIssuer = "https://idp.example.test"
+
+// newTestIdentityProvider serves the JWKS of a fresh P-256 key and returns a
+// function signing claims with it
+func newTestIdentityProvider(t *testing.T) (*httptest.Server, func(claims map[string]interface{}) string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	encode := func(v []byte) string { return base64.RawURLEncoding.EncodeToString(v) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "EC",
+				"kid": "key-1",
+				"use": "sig",
+				"crv": "P-256",
+				"x":   encode(key.X.FillBytes(make([]byte, 32))),
+				"y":   encode(key.Y.FillBytes(make([]byte, 32))),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	sign := func(claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "key-1", "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := encode(header) + "." + encode(payload)
+		digest := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		assert.NoError(t, err)
+		signature := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		return signed + "." + encode(signature)
+	}
+	return server, sign
+}
+
+func newTestDelegatedTokens(t *testing.T, jwksURL string) *services.DelegatedTokens {
+	cfg := &config.Config{}
+	cfg.DelegationConfig = config.DelegationConfig{
+		Enabled:       true,
+		Issuer:        testIssuer,
+		Audience:      "document-service",
+		JWKSURL:       jwksURL,
+		JWKSRefresh:   time.Minute,
+		Timeout:       time.Second,
+		AllowedActors: []string{"enrollment-service"},
+		TenantClaim:   "tenant_id",
+		RoleClaim:     "role",
+		ClockSkew:     time.Second,
+	}
+	tokens, err := services.NewDelegatedTokens(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	return tokens
+}
+
+func delegatedClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":       testIssuer,
+		"aud":       []string{"document-service"},
+		"sub":       "user-1",
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"jti":       "token-1",
+		"tenant_id": "tenant-a",
+		"role":      "beneficiary",
+		"act": map[string]interface{}{
+			"sub": "enrollment-service",
+			"act": map[string]interface{}{"sub": "portal-bff"},
+		},
+	}
+}
+
+func TestDelegatedTokenIdentifiesEndUserAndActor(t *testing.T) {
+	server, sign := newTestIdentityProvider(t)
+	tokens := newTestDelegatedTokens(t, server.URL)
+
+	identity, err := tokens.Verify(context.Background(), sign(delegatedClaims()))
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", identity.Subject)
+	assert.Equal(t, "tenant-a", identity.TenantID)
+	assert.Equal(t, "beneficiary", identity.Role)
+	assert.Equal(t, "enrollment-service", identity.Actor)
+	assert.Equal(t, []string{"enrollment-service", "portal-bff"}, identity.ActorChain)
+}
+
+func TestDelegatedTokenRejections(t *testing.T) {
+	server, sign := newTestIdentityProvider(t)
+	tokens := newTestDelegatedTokens(t, server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		change func(claims map[string]interface{})
+		err    error
+	}{
+		{"another issuer", func(claims map[string]interface{}) { claims["iss"] = "https://other.example.test" }, services.ErrDelegatedTokenInvalid},
+		{"another audience", func(claims map[string]interface{}) { claims["aud"] = "portal" }, services.ErrDelegatedTokenInvalid},
+		{"expired", func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, services.ErrDelegatedTokenExpired},
+		{"no actor", func(claims map[string]interface{}) { delete(claims, "act") }, services.ErrDelegatedTokenInvalid},
+		{"unknown actor", func(claims map[string]interface{}) { claims["act"] = map[string]string{"sub": "billing"} }, services.ErrActorNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := delegatedClaims()
+			tt.change(claims)
+			_, err := tokens.Verify(ctx, sign(claims))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// A token whose claims were altered after signing
+	token := sign(delegatedClaims())
+	parts := strings.Split(token, ".")
+	forged, _ := json.Marshal(map[string]interface{}{"iss": testIssuer, "aud": "document-service", "sub": "admin-1",
+		"exp": time.Now().Add(time.Minute).Unix(), "act": map[string]string{"sub": "enrollment-service"}})
+	_, err := tokens.Verify(ctx, fmt.Sprintf("%s.%s.%s", parts[0], base64.RawURLEncoding.EncodeToString(forged), parts[2]))
+	assert.ErrorIs(t, err, services.ErrDelegatedTokenInvalid)
+
+	unsigned := strings.Replace(token, parts[0], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"key-1"}`)), 1)
+	_, err = tokens.Verify(ctx, unsigned)
+	assert.ErrorIs(t, err, services.ErrDelegatedTokenInvalid)
+}
+
+func TestParseJWKSSkipsUnsuitableKeys(t *testing.T) {
+	keys, err := services.ParseJWKS([]byte(`{"keys":[
+		{"kty":"RSA","kid":"short","n":"AQAB","e":"AQAB"},
+		{"kty":"EC","kid":"encryption","use":"enc","crv":"P-256","x":"AA","y":"AA"},
+		{"kty":"EC","kid":"off-curve","crv":"P-256","x":"AQ","y":"AQ"},
+		{"kty":"oct","kid":"shared","k":"c2VjcmV0"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,138 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	mathrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"pgregory.net/rapid"                 // v1.1.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// streamChunk is the plaintext size of one chunk of a stream-encrypted object
+const streamChunk = 64 << 10
+
+// propertyEncryptionConfig returns a configuration sealing with a configured
+// algorithm under a random shared data key
+func propertyEncryptionConfig(t *testing.T, algorithm string) *config.Config {
+	cfg := &config.Config{}
+	cfg.SecurityConfig.EncryptionKey = "alias/property-" + algorithm
+	cfg.SecurityConfig.EncryptionAlgorithm = algorithm
+	cfg.SecurityConfig.KeyVersion = "v1"
+	cfg.SecurityConfig.KeyRotationInterval = 90 * 24 * time.Hour
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	assert.NoError(t, utils.UseDataKey(cfg, "arn:aws:kms:us-east-1:000000000000:key/property", key))
+	return cfg
+}
+
+// propertyContent returns size bytes filled from seed, so contents up to the
+// maximum document size are cheap to draw
+func propertyContent(size int, seed int64) []byte {
+	content := make([]byte, size)
+	mathrand.New(mathrand.NewSource(seed)).Read(content)
+	return content
+}
+
+// checkRoundTrip encrypts content as a whole object and as a stream, and
+// checks decrypting either returns the content, as does reading length bytes
+// of the stream from offset. Content sealed with AES-GCM must also open under
+// the unversioned metadata of earlier releases
+func checkRoundTrip(t assert.TestingT, cfg *config.Config, content []byte, offset, length int) {
+	ctx := context.Background()
+	size := int64(len(content))
+
+	doc := &models.Document{ID: "property-document", Size: size}
+	sealed, err := utils.EncryptDocument(ctx, doc, bytes.NewReader(content), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ciphertext, err := io.ReadAll(sealed)
+	assert.NoError(t, err)
+	opened, err := utils.DecryptDocument(ctx, doc, bytes.NewReader(ciphertext), cfg)
+	if assert.NoError(t, err) {
+		plaintext, err := io.ReadAll(opened)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(content, plaintext), "Decrypting a whole object should return its content")
+	}
+
+	stream, metadata, err := utils.EncryptStream(ctx, doc.ID, content, nil, cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	streamed := bytes.Clone(stream.Bytes())
+	stream.Close()
+	plaintext, err := utils.OpenCiphertext(ctx, doc.ID, streamed, size, metadata, cfg)
+	if assert.NoError(t, err) {
+		assert.True(t, bytes.Equal(content, plaintext), "Decrypting a stream should return its content")
+		utils.PutBuffer(plaintext)
+	}
+
+	decrypter, err := utils.NewStreamDecrypter(ctx, doc.ID, bytes.NewReader(streamed), size, metadata, cfg)
+	if assert.NoError(t, err) {
+		_, err = decrypter.Seek(int64(offset), io.SeekStart)
+		assert.NoError(t, err)
+		window := make([]byte, length)
+		_, err = io.ReadFull(decrypter, window)
+		assert.NoError(t, err)
+		assert.True(t, bytes.Equal(content[offset:offset+length], window), "A range of a stream should decrypt to the same range of its content")
+	}
+
+	for _, sealed := range []struct {
+		metadata   *models.EncryptionMetadata
+		ciphertext []byte
+	}{{doc.EncryptionInfo, ciphertext}, {metadata, streamed}} {
+		if sealed.metadata.Algorithm != models.EncryptionAlgorithmGCM && sealed.metadata.Algorithm != models.EncryptionAlgorithmGCMStream {
+			continue
+		}
+		legacy := *sealed.metadata
+		legacy.Version = 0
+		plaintext, err := utils.OpenCiphertext(ctx, doc.ID, sealed.ciphertext, size, &legacy, cfg)
+		if assert.NoError(t, err) {
+			assert.True(t, bytes.Equal(content, plaintext), "Unversioned AES-GCM metadata should stay readable")
+			utils.PutBuffer(plaintext)
+		}
+	}
+}
+
+func TestEncryptionRoundTripProperties(t *testing.T) {
+	configs := map[string]*config.Config{
+		utils.ConfigAlgorithmAES:     propertyEncryptionConfig(t, utils.ConfigAlgorithmAES),
+		utils.ConfigAlgorithmXChaCha: propertyEncryptionConfig(t, utils.ConfigAlgorithmXChaCha),
+	}
+	algorithms := []string{utils.ConfigAlgorithmAES, utils.ConfigAlgorithmXChaCha}
+
+	// Sizes around the chunk boundaries of streams, and anything up to a few
+	// chunks
+	sizes := rapid.OneOf(
+		rapid.SampledFrom([]int{0, 1, streamChunk - 1, streamChunk, streamChunk + 1, 2 * streamChunk}),
+		rapid.IntRange(0, 3*streamChunk+1),
+	)
+	rapid.Check(t, func(rt *rapid.T) {
+		cfg := configs[rapid.SampledFrom(algorithms).Draw(rt, "algorithm")]
+		content := propertyContent(sizes.Draw(rt, "size"), rapid.Int64().Draw(rt, "seed"))
+		offset := rapid.IntRange(0, len(content)).Draw(rt, "offset")
+		length := rapid.IntRange(0, len(content)-offset).Draw(rt, "length")
+		checkRoundTrip(rt, cfg, content, offset, length)
+	})
+
+	// The extremes once per algorithm, as the largest document is too costly
+	// to draw repeatedly
+	for _, algorithm := range algorithms {
+		checkRoundTrip(t, configs[algorithm], nil, 0, 0)
+		content := propertyContent(models.MaxDocumentSize, 1)
+		checkRoundTrip(t, configs[algorithm], content, models.MaxDocumentSize-streamChunk-1, streamChunk+1)
+	}
+}
@@ -0,0 +1,192 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// scanFixture is an encryption scanner over documents stored in a memory bucket
+type scanFixture struct {
+	cfg       *config.Config
+	bucket    *memoryBucket
+	storage   *services.StorageService
+	documents *repository.MemoryDocumentRepository
+	scanner   *services.EncryptionScanner
+}
+
+func newScanFixture(t *testing.T, sampleSize int, reencrypt bool) *scanFixture {
+	cfg := &config.Config{}
+	cfg.EncryptionScanConfig = config.EncryptionScanConfig{
+		Enabled:    true,
+		Interval:   time.Hour,
+		SampleSize: sampleSize,
+		Reencrypt:  reencrypt,
+	}
+	bucket, storage := newMemoryStorage(t, cfg)
+	documents := repository.NewMemoryDocumentRepository()
+	scanner, err := services.NewEncryptionScanner(cfg, documents, storage, zap.NewNop())
+	assert.NoError(t, err)
+	return &scanFixture{cfg: cfg, bucket: bucket, storage: storage, documents: documents, scanner: scanner}
+}
+
+// store encrypts and stores a document, returning it with its content
+func (f *scanFixture) store(t *testing.T, id string) (*models.Document, []byte) {
+	ctx := context.Background()
+	content := []byte(fmt.Sprintf("%%PDF-1.7 content of %s", id))
+	doc, err := models.NewDocument("enr-scan", "identity", id+".pdf", "application/pdf", int64(len(content)))
+	assert.NoError(t, err)
+	doc.ID = id
+	assert.NoError(t, f.storage.StoreDocument(ctx, doc, bytes.NewReader(content)))
+	assert.NoError(t, f.documents.Create(ctx, doc))
+	return doc, content
+}
+
+// issues returns the issues found on each document, by document ID
+func issues(report *models.EncryptionScanReport) map[string][]string {
+	found := make(map[string][]string)
+	for _, finding := range report.Findings {
+		found[finding.DocumentID] = append(found[finding.DocumentID], finding.Issue)
+	}
+	return found
+}
+
+func TestEncryptionScanReportsNonConformingObjects(t *testing.T) {
+	fixture := newScanFixture(t, 10, false)
+	ctx := context.Background()
+
+	// A conforming document with its extracted text
+	conforming, _ := fixture.store(t, "doc-conforming")
+	assert.NoError(t, fixture.storage.StoreEncryptedRendition(ctx, conforming, models.RenditionOCRText, "text/plain; charset=utf-8", []byte("João da Silva")))
+	assert.NoError(t, fixture.documents.Update(ctx, conforming))
+
+	plain, content := fixture.store(t, "doc-plaintext")
+	fixture.bucket.replace("documents/"+plain.StoragePath, content)
+
+	tampered, _ := fixture.store(t, "doc-tampered")
+	fixture.bucket.corrupt("documents/"+tampered.StoragePath, 3)
+
+	truncated, _ := fixture.store(t, "doc-truncated")
+	stored, _ := fixture.bucket.object("documents/" + truncated.StoragePath)
+	fixture.bucket.replace("documents/"+truncated.StoragePath, stored[:len(stored)-4])
+
+	missing, _ := fixture.store(t, "doc-missing")
+	fixture.bucket.replace("documents/"+missing.StoragePath, nil)
+
+	report, err := fixture.scanner.Scan(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 5, report.Documents)
+	assert.Equal(t, 6, report.Objects, "The extracted text should be checked with the original")
+	assert.Equal(t, 2, report.Conforming)
+	assert.Equal(t, map[string][]string{
+		"doc-plaintext": {models.EncryptionIssuePlaintext},
+		"doc-tampered":  {models.EncryptionIssueAuthenticationFailed},
+		"doc-truncated": {models.EncryptionIssueSizeMismatch},
+		"doc-missing":   {models.EncryptionIssueMissingObject},
+	}, issues(report))
+	for _, finding := range report.Findings {
+		assert.Equal(t, finding.Issue == models.EncryptionIssuePlaintext, finding.Reencryptable, finding.Issue)
+	}
+	assert.Zero(t, report.Reencrypted, "Findings should only be reported when re-encryption is off")
+	assert.Same(t, report, fixture.scanner.LastReport())
+}
+
+func TestEncryptionScanSamplesStoredDocuments(t *testing.T) {
+	fixture := newScanFixture(t, 3, false)
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		fixture.store(t, fmt.Sprintf("doc-%d", i))
+	}
+	unstored, err := models.NewDocument("enr-scan", "identity", "pending.pdf", "application/pdf", 10)
+	assert.NoError(t, err)
+	unstored.ID = "doc-pending"
+	assert.NoError(t, fixture.documents.Create(ctx, unstored))
+
+	for i := 0; i < 5; i++ {
+		report, err := fixture.scanner.Scan(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 3, report.Documents, "Each scan should check the sample size")
+		assert.Equal(t, 3, report.Conforming)
+	}
+
+	fixture.cfg.EncryptionScanConfig.SampleSize = 10
+	report, err := fixture.scanner.Scan(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, report.Documents, "Documents without stored content should not be sampled")
+}
+
+func TestEncryptionScanReencryptsRecoverableDocuments(t *testing.T) {
+	fixture := newScanFixture(t, 10, true)
+	ctx := context.Background()
+
+	plain, plainContent := fixture.store(t, "doc-plaintext")
+	fixture.bucket.replace("documents/"+plain.StoragePath, plainContent)
+	stale, staleContent := fixture.store(t, "doc-stale")
+	tampered, _ := fixture.store(t, "doc-tampered")
+	fixture.bucket.corrupt("documents/"+tampered.StoragePath, 3)
+
+	// The key is rotated after the documents were stored
+	fixture.cfg.SecurityConfig.KeyVersion = "v2"
+
+	report, err := fixture.scanner.Scan(ctx)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string][]string{
+		"doc-plaintext": {models.EncryptionIssuePlaintext},
+		"doc-stale":     {models.EncryptionIssueStaleKeyVersion},
+		"doc-tampered":  {models.EncryptionIssueAuthenticationFailed},
+	}, issues(report))
+	assert.Equal(t, 2, report.Reencrypted, "Only documents whose content can be recovered should be re-encrypted")
+
+	for _, original := range []struct {
+		doc     *models.Document
+		content []byte
+	}{{plain, plainContent}, {stale, staleContent}} {
+		doc, err := fixture.documents.GetByID(ctx, original.doc.ID)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.NotEqual(t, original.doc.StoragePath, doc.StoragePath)
+		assert.Equal(t, "v2", doc.EncryptionInfo.KeyVersion)
+		assert.Equal(t, "REENCRYPT", doc.AuditTrail[len(doc.AuditTrail)-1].Action)
+		_, kept := fixture.bucket.object("documents/" + original.doc.StoragePath)
+		assert.False(t, kept, "The superseded object should be deleted")
+
+		reader, err := fixture.storage.RetrieveDocument(ctx, doc)
+		if assert.NoError(t, err) {
+			content, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, original.content, content)
+		}
+	}
+
+	doc, err := fixture.documents.GetByID(ctx, tampered.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tampered.StoragePath, doc.StoragePath, "A document that cannot be recovered should be left in place")
+	_, err = fixture.scanner.Reencrypt(ctx, tampered.ID)
+	assert.ErrorIs(t, err, services.ErrNotReencryptable)
+
+	report, err = fixture.scanner.Scan(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"doc-tampered": {models.EncryptionIssueAuthenticationFailed},
+	}, issues(report), "Re-encrypted documents should conform on the next scan")
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newETAEstimator(t *testing.T, documents repository.DocumentRepository) *services.ETAEstimator {
+	cfg := &config.Config{}
+	cfg.ETAConfig = config.ETAConfig{
+		Smoothing:       0.2,
+		HistoryWindow:   24 * time.Hour,
+		RefreshInterval: time.Minute,
+		StreamInterval:  time.Second,
+	}
+	eta, err := services.NewETAEstimator(cfg, documents, nil, zap.NewNop())
+	assert.NoError(t, err)
+	return eta
+}
+
+func newProcessedDocument(t *testing.T, id string) *models.Document {
+	doc, err := models.NewDocument("enrollment-1", "identity", id+".pdf", "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = id
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "Document stored successfully"))
+	return doc
+}
+
+func TestETANeedsHistory(t *testing.T) {
+	eta := newETAEstimator(t, repository.NewMemoryDocumentRepository())
+	doc := newProcessedDocument(t, "doc-1")
+	assert.NoError(t, eta.OnIngested(context.Background(), doc))
+
+	status := eta.Estimate(doc)
+	assert.Equal(t, models.ETAStageReview, status.Stage)
+	assert.Equal(t, models.ETABasisInsufficientHistory, status.Basis)
+	assert.Nil(t, status.EstimatedCompletion)
+	assert.False(t, status.Verified)
+}
+
+func TestETAFollowsReviewDurationsAndQueue(t *testing.T) {
+	ctx := context.Background()
+	documents := repository.NewMemoryDocumentRepository()
+
+	// A document reviewed two hours after it was processed
+	reviewed := newProcessedDocument(t, "reviewed")
+	processedAt := time.Now().Add(-3 * time.Hour)
+	reviewedAt := processedAt.Add(2 * time.Hour)
+	reviewed.Status = models.DocumentStatusApproved
+	reviewed.ProcessedAt = &processedAt
+	reviewed.ReviewedAt = &reviewedAt
+	reviewed.UpdatedAt = reviewedAt
+	assert.NoError(t, documents.Create(ctx, reviewed))
+
+	eta := newETAEstimator(t, documents)
+	assert.NoError(t, eta.Load(ctx))
+
+	first, second := newProcessedDocument(t, "first"), newProcessedDocument(t, "second")
+	assert.NoError(t, eta.OnIngested(ctx, first))
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, eta.OnIngested(ctx, second))
+
+	status := eta.Estimate(second)
+	assert.Equal(t, models.ETABasisHistory, status.Basis)
+	assert.Equal(t, 1, status.QueueDepth)
+	if assert.NotNil(t, status.EstimatedCompletion) {
+		assert.WithinDuration(t, time.Now().Add(2*time.Hour), *status.EstimatedCompletion, time.Minute)
+	}
+	assert.NotNil(t, eta.Estimate(first).EstimatedCompletion)
+
+	// Reviewing the first document at once shortens the smoothed duration
+	// and moves the second one up the queue
+	assert.NoError(t, first.Review(models.ReviewDecisionApprove, "Legible", "reviewer-1"))
+	eta.Reviewed(first)
+	status = eta.Estimate(first)
+	assert.True(t, status.Verified)
+	assert.Empty(t, status.Stage)
+
+	status = eta.Estimate(second)
+	assert.Equal(t, 0, status.QueueDepth)
+	if assert.NotNil(t, status.EstimatedCompletion) {
+		assert.WithinDuration(t, time.Now().Add(96*time.Minute), *status.EstimatedCompletion, time.Minute)
+	}
+}
+
+func TestETAIgnoresHaltedDocuments(t *testing.T) {
+	eta := newETAEstimator(t, repository.NewMemoryDocumentRepository())
+	doc := newProcessedDocument(t, "halted")
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusHaltedConsent, "Subject consent revoked during processing"))
+
+	status := eta.Estimate(doc)
+	assert.Empty(t, status.Stage)
+	assert.Empty(t, status.Basis)
+	assert.Nil(t, status.EstimatedCompletion)
+}
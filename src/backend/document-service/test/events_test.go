@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestEventSourcedDocumentLifecycle(t *testing.T) {
+	ctx := context.Background()
+	projection := repository.NewMemoryDocumentRepository()
+	documents := repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), projection)
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, documents.Create(ctx, doc))
+	assert.ErrorIs(t, documents.Create(ctx, doc), repository.ErrDocumentExists)
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	doc.SetExtractedFields("receita", []models.ExtractedField{{Name: "cpf", Value: "52998224725", Confidence: 1}})
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "OCR completed"))
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	assert.NoError(t, doc.Review(models.ReviewDecisionApprove, "", "reviewer-1"))
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	doc.OCRPreview = "NOME MARIA"
+	assert.NoError(t, documents.Update(ctx, doc))
+	// Saving an unchanged document records nothing
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	history, err := documents.History(ctx, doc.ID)
+	assert.NoError(t, err)
+	types := make([]string, 0, len(history))
+	for i, event := range history {
+		assert.Equal(t, int64(i+1), event.Sequence)
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		models.EventDocumentUploaded,
+		models.EventStatusChanged,
+		models.EventFieldsExtracted,
+		models.EventProcessingCompleted,
+		models.EventReviewed,
+		models.EventDocumentUpdated,
+	}, types)
+	assert.Empty(t, history[1].Patch, "Only the last event of a batch should carry the change")
+	assert.NotEmpty(t, history[3].Patch)
+
+	// A projection corrupted by a bug is rebuilt from the events
+	corrupted, err := projection.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	corrupted.Status = models.DocumentStatusFailed
+	corrupted.ExtractedFields = nil
+	assert.NoError(t, projection.Update(ctx, corrupted))
+
+	assert.NoError(t, documents.Rebuild(ctx, doc.ID))
+	rebuilt, err := documents.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DocumentStatusApproved, rebuilt.Status)
+	assert.Equal(t, "reviewer-1", rebuilt.ReviewedBy)
+	assert.Equal(t, "NOME MARIA", rebuilt.OCRPreview)
+	assert.Equal(t, []string{"52998224725"}, rebuilt.ExtractedValues("cpf"))
+	assert.Len(t, rebuilt.AuditTrail, len(doc.AuditTrail))
+
+	// Deletion erases the recorded state while the chain stays verifiable
+	assert.NoError(t, documents.Delete(ctx, doc.ID))
+	history, err = documents.History(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.EventDocumentDeleted, history[len(history)-1].Type)
+	for _, event := range history {
+		assert.Empty(t, event.Patch)
+	}
+	replayed, err := documents.Replay(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, replayed)
+
+	rebuiltCount, err := documents.RebuildAll(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, rebuiltCount)
+	_, err = documents.GetByID(ctx, doc.ID)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+}
+
+func TestReplayAtReconstructsPastMetadata(t *testing.T) {
+	ctx := context.Background()
+	documents := repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository())
+	beforeUpload := time.Now()
+	time.Sleep(time.Millisecond)
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, documents.Create(ctx, doc))
+	uploaded := time.Now()
+	time.Sleep(time.Millisecond)
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	doc.SetExtractedFields("receita", []models.ExtractedField{{Name: "cpf", Value: "52998224725", Confidence: 1}})
+	assert.NoError(t, documents.Update(ctx, doc))
+	processed := time.Now()
+	time.Sleep(time.Millisecond)
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "OCR completed"))
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	_, err = documents.ReplayAt(ctx, doc.ID, beforeUpload)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+
+	past, err := documents.ReplayAt(ctx, doc.ID, uploaded)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DocumentStatusPending, past.Status)
+	assert.Empty(t, past.ExtractedFields)
+
+	past, err = documents.ReplayAt(ctx, doc.ID, processed)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DocumentStatusProcessing, past.Status)
+	assert.Equal(t, []string{"52998224725"}, past.ExtractedValues("cpf"))
+
+	// States holding personal data since erased are not reconstructed
+	doc.Anonymize(time.Now())
+	assert.NoError(t, documents.Redact(ctx, doc))
+	_, err = documents.ReplayAt(ctx, doc.ID, processed)
+	assert.ErrorIs(t, err, repository.ErrStateErased)
+
+	current, err := documents.ReplayAt(ctx, doc.ID, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, current.Anonymized())
+}
+
+func TestDocumentEventChainDetectsTampering(t *testing.T) {
+	first := &models.DocumentEvent{DocumentID: "doc-1", Sequence: 1, Type: models.EventDocumentUploaded, Patch: []byte(`{"status":"pending"}`)}
+	first.Seal("")
+	second := &models.DocumentEvent{DocumentID: "doc-1", Sequence: 2, Type: models.EventReviewed, Actor: "reviewer-1", Patch: []byte(`{"status":"approved"}`)}
+	second.Seal(first.Hash)
+
+	assert.NoError(t, first.Verify(""))
+	assert.NoError(t, second.Verify(first.Hash))
+
+	second.Actor = "someone-else"
+	assert.ErrorIs(t, second.Verify(first.Hash), models.ErrEventChainBroken)
+	second.Actor = "reviewer-1"
+
+	second.Patch = []byte(`{"status":"rejected"}`)
+	assert.ErrorIs(t, second.Verify(first.Hash), models.ErrEventChainBroken)
+
+	second.Erase()
+	assert.NoError(t, second.Verify(first.Hash), "Erasing a patch should keep the chain verifiable")
+	assert.ErrorIs(t, second.Verify(""), models.ErrEventChainBroken)
+}
+
+func TestDocumentEventSummary(t *testing.T) {
+	event := &models.DocumentEvent{
+		DocumentID: "doc-1",
+		Sequence:   4,
+		Type:       models.EventReviewed,
+		Actor:      "reviewer-1",
+		Patch:      []byte(`{"status":"approved","reviewed_by":"reviewer-1","extracted_fields":[{"name":"cpf","value":"52998224725"}]}`),
+	}
+	event.Seal("previous")
+
+	summary := event.Summary()
+	assert.Equal(t, []string{"extracted_fields", "reviewed_by", "status"}, summary.Changes)
+	assert.Equal(t, event.Hash, summary.Hash)
+	assert.Equal(t, "previous", summary.PrevHash)
+	assert.Equal(t, event.PatchDigest, summary.PatchDigest)
+
+	data, err := json.Marshal(summary)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "52998224725", "Summaries should not expose field values")
+
+	event.Erase()
+	summary = event.Summary()
+	assert.True(t, summary.Erased)
+	assert.Empty(t, summary.Changes)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// countingStep is a pipeline step counting the documents it processes
+type countingStep struct {
+	name    string
+	skips   string
+	handled map[string]int
+}
+
+func newCountingStep(name string) *countingStep {
+	return &countingStep{name: name, handled: make(map[string]int)}
+}
+
+func (s *countingStep) Name() string {
+	return s.name
+}
+
+func (s *countingStep) Applies(doc *models.Document) bool {
+	return doc.DocumentType != s.skips
+}
+
+func (s *countingStep) Execute(ctx context.Context, run *services.PipelineRun) error {
+	s.handled[run.Document.ID]++
+	return nil
+}
+
+// stubOCRProvider is an OCR provider that is never called
+type stubOCRProvider string
+
+func (p stubOCRProvider) Provider() string {
+	return string(p)
+}
+
+func (p stubOCRProvider) Recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error) {
+	return nil, nil
+}
+
+func experimentDocuments(count int) []*models.Document {
+	documents := make([]*models.Document, count)
+	for i := range documents {
+		documents[i] = &models.Document{ID: fmt.Sprintf("document-%d", i), DocumentType: "identity"}
+	}
+	return documents
+}
+
+func TestExperimentAssignmentIsDeterministic(t *testing.T) {
+	control, candidate := newCountingStep(services.StepOCR), newCountingStep("ocr-candidate")
+	experiment := services.NewExperimentStep("classifier-v2", control, nil, services.ExperimentVariant{Name: "v2", Step: candidate, Percentage: 50})
+
+	for _, doc := range experimentDocuments(200) {
+		assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: doc}))
+		first := doc.Experiments[0].Variant
+
+		// Reprocessing selects the same variant and replaces the record
+		assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: doc}))
+		assert.Len(t, doc.Experiments, 1, "A document is recorded once per experiment")
+		assert.Equal(t, first, doc.Experiments[0].Variant)
+		assert.Equal(t, "classifier-v2", doc.Experiments[0].Experiment)
+		assert.Equal(t, services.StepOCR, doc.Experiments[0].Step)
+
+		if first == services.VariantControl {
+			assert.Equal(t, 2, control.handled[doc.ID])
+			assert.Zero(t, candidate.handled[doc.ID])
+		} else {
+			assert.Equal(t, 2, candidate.handled[doc.ID])
+			assert.Zero(t, control.handled[doc.ID])
+		}
+	}
+
+	// Another experiment buckets the same documents independently
+	other := services.NewExperimentStep("layout-v2", newCountingStep(services.StepOCR), nil, services.ExperimentVariant{Name: "v2", Step: newCountingStep("ocr-layout"), Percentage: 50})
+	differ := 0
+	for _, doc := range experimentDocuments(200) {
+		assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: doc}))
+		assert.NoError(t, other.Execute(context.Background(), &services.PipelineRun{Document: doc}))
+		if doc.Experiments[0].Variant != doc.Experiments[1].Variant {
+			differ++
+		}
+	}
+	assert.Greater(t, differ, 0)
+}
+
+func TestExperimentSplitHonoursPercentages(t *testing.T) {
+	control := newCountingStep(services.StepOCR)
+	small, large := newCountingStep("ocr-small"), newCountingStep("ocr-large")
+	experiment := services.NewExperimentStep("classifier-v2", control, nil,
+		services.ExperimentVariant{Name: "small", Step: small, Percentage: 10},
+		services.ExperimentVariant{Name: "large", Step: large, Percentage: 25},
+	)
+
+	const documents = 20000
+	for _, doc := range experimentDocuments(documents) {
+		assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: doc}))
+	}
+	assert.InDelta(t, 0.10, float64(len(small.handled))/documents, 0.01)
+	assert.InDelta(t, 0.25, float64(len(large.handled))/documents, 0.01)
+	assert.InDelta(t, 0.65, float64(len(control.handled))/documents, 0.01)
+}
+
+func TestExperimentVariantMustApply(t *testing.T) {
+	control, candidate := newCountingStep(services.StepOCR), newCountingStep("ocr-candidate")
+	candidate.skips = "identity"
+	experiment := services.NewExperimentStep("classifier-v2", control, nil, services.ExperimentVariant{Name: "v2", Step: candidate, Percentage: 100})
+
+	identity := &models.Document{ID: "identity-document", DocumentType: "identity"}
+	assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: identity}))
+	assert.Equal(t, services.VariantControl, identity.Experiments[0].Variant, "Documents the variant does not apply to stay on control")
+	assert.Equal(t, 1, control.handled[identity.ID])
+	assert.Empty(t, candidate.handled)
+
+	address := &models.Document{ID: "address-document", DocumentType: "proof_of_address"}
+	assert.NoError(t, experiment.Execute(context.Background(), &services.PipelineRun{Document: address}))
+	assert.Equal(t, "v2", address.Experiments[0].Variant)
+	assert.Equal(t, 1, candidate.handled[address.ID])
+}
+
+func TestApplyExperimentsWithOCRAlternatives(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ExperimentsConfig.Enabled = true
+	cfg.ExperimentsConfig.Experiments = []config.ExperimentConfig{{
+		Name: "classifier-v2",
+		Step: services.StepOCR,
+		Variants: []config.ExperimentVariantConfig{{
+			Name:           "v2",
+			Implementation: services.OCRImplementation("candidate_ocr"),
+			Percentage:     10,
+		}},
+	}}
+	alternatives := services.OCRAlternatives(nil, map[string]services.OCRProvider{"candidate_ocr": stubOCRProvider("candidate_ocr")}, nil)
+	assert.Contains(t, alternatives, "ocr-candidate_ocr")
+
+	steps, err := services.ApplyExperiments(cfg, []services.PipelineStep{newCountingStep(services.StepOCR)}, alternatives, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, steps, 1) {
+		_, wrapped := steps[0].(*services.ExperimentStep)
+		assert.True(t, wrapped)
+		assert.Equal(t, services.StepOCR, steps[0].Name())
+	}
+
+	_, err = services.ApplyExperiments(cfg, []services.PipelineStep{newCountingStep(services.StepOCR)}, map[string]services.PipelineStep{}, nil)
+	assert.Error(t, err, "An unknown implementation is refused")
+}
@@ -0,0 +1,56 @@
+package test
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func compliantCryptoConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.SecurityConfig.EncryptionAlgorithm = "AES-256"
+	cfg.SecurityConfig.StrictCrypto = true
+	cfg.MinioConfig.UseSSL = true
+	return cfg
+}
+
+func TestCryptoPolicyRejectsNonCompliantSettings(t *testing.T) {
+	assert.NoError(t, utils.CheckCryptoPolicy(compliantCryptoConfig()))
+
+	cfg := compliantCryptoConfig()
+	cfg.MinioConfig.UseSSL = false
+	cfg.SFTPConfig.Enabled = true
+	cfg.SFTPConfig.HostKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+
+	err := utils.CheckCryptoPolicy(cfg)
+	assert.ErrorIs(t, err, utils.ErrNonCompliantCrypto)
+	assert.Contains(t, err.Error(), "minio.use_ssl")
+	assert.Contains(t, err.Error(), "sftp.host_key", "Every violation should be reported at once")
+
+	cfg.MinioConfig.UseSSL = true
+	cfg.SFTPConfig.HostKey = "ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTY="
+	assert.NoError(t, utils.CheckCryptoPolicy(cfg))
+}
+
+func TestStrictCryptoModeRestrictsTLS(t *testing.T) {
+	cfg := compliantCryptoConfig()
+	cfg.MinioConfig.UseSSL = false
+	assert.True(t, errors.Is(utils.ConfigureCryptoMode(cfg), utils.ErrNonCompliantCrypto), "Startup should fail on non-compliant settings")
+
+	assert.NoError(t, utils.ConfigureCryptoMode(compliantCryptoConfig()))
+	defer utils.ConfigureCryptoMode(&config.Config{})
+	assert.Equal(t, utils.CryptoModeFIPS, utils.CryptoMode())
+
+	tlsConfig := &tls.Config{}
+	utils.ApplyTLSPolicy(tlsConfig)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.NotEmpty(t, tlsConfig.CipherSuites)
+	for _, suite := range tlsConfig.CipherSuites {
+		assert.Contains(t, tls.CipherSuiteName(suite), "AES", "Only AES-GCM suites are approved")
+	}
+}
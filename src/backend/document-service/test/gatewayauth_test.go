@@ -0,0 +1,153 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestGatewayConfig(jwksURL string) *config.Config {
+	cfg := &config.Config{}
+	cfg.GatewayAuthConfig = config.GatewayAuthConfig{
+		Enabled:      true,
+		Issuer:       testIssuer,
+		Audience:     "document-service",
+		JWKSURL:      jwksURL,
+		JWKSRefresh:  time.Minute,
+		Timeout:      time.Second,
+		TenantClaim:  "tenant_id",
+		RoleClaim:    "role",
+		SessionClaim: "sid",
+		ClockSkew:    time.Second,
+	}
+	cfg.SecureViewerConfig = config.SecureViewerConfig{
+		Enabled:           true,
+		DocumentTypes:     []string{"medical_record"},
+		UnrestrictedRoles: []string{"underwriter"},
+	}
+	return cfg
+}
+
+func gatewayClaims(role string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":       testIssuer,
+		"aud":       "document-service",
+		"sub":       "user-1",
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"tenant_id": "tenant-a",
+		"sid":       "session-1",
+	}
+	if role != "" {
+		claims["role"] = role
+	}
+	return claims
+}
+
+// newSecureViewerRouter serves a medical record download guarded like the
+// document routes: the role is the one the gateway token states
+func newSecureViewerRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tokens, err := services.NewGatewayTokens(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	viewer := services.NewSecureViewer(cfg, nil, zap.NewNop())
+	record := &models.Document{ID: "record-1", DocumentType: "medical_record"}
+
+	router := gin.New()
+	router.GET("/documents/:id/download", handlers.AuthenticateGatewayUser(tokens, zap.NewNop()), func(c *gin.Context) {
+		if viewer.Restricted(record, c.GetString("user_role")) {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":    c.GetString("user_id"),
+			"tenant_id":  c.GetString("tenant_id"),
+			"session_id": c.GetString("session_id"),
+		})
+	})
+	return router
+}
+
+func TestSecureViewerRoleComesFromGatewayToken(t *testing.T) {
+	server, sign := newTestIdentityProvider(t)
+	router := newSecureViewerRouter(t, newTestGatewayConfig(server.URL))
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"underwriter", "Bearer " + sign(gatewayClaims("underwriter")), http.StatusOK},
+		{"broker", "Bearer " + sign(gatewayClaims("broker")), http.StatusForbidden},
+		{"unknown role", "Bearer " + sign(gatewayClaims("auditor")), http.StatusForbidden},
+		{"token without a role", "Bearer " + sign(gatewayClaims("")), http.StatusForbidden},
+		{"no token", "", http.StatusForbidden},
+		{"expired token", "Bearer " + sign(func() map[string]interface{} {
+			claims := gatewayClaims("underwriter")
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return claims
+		}()), http.StatusUnauthorized},
+		{"token of another audience", "Bearer " + sign(func() map[string]interface{} {
+			claims := gatewayClaims("underwriter")
+			claims["aud"] = "portal"
+			return claims
+		}()), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/documents/record-1/download", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			// A role header set by the caller is never trusted
+			req.Header.Set("X-User-Role", "underwriter")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/documents/record-1/download", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(gatewayClaims("underwriter")))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.JSONEq(t, `{"user_id":"user-1","tenant_id":"tenant-a","session_id":"session-1"}`, rec.Body.String())
+}
+
+func TestSecureViewerRestrictsWithoutGatewayAuth(t *testing.T) {
+	server, sign := newTestIdentityProvider(t)
+	cfg := newTestGatewayConfig(server.URL)
+	cfg.GatewayAuthConfig.Enabled = false
+	router := newSecureViewerRouter(t, cfg)
+
+	// Without verification no request has a role, so every one is restricted
+	req := httptest.NewRequest(http.MethodGet, "/documents/record-1/download", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(gatewayClaims("underwriter")))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestSecureViewerRestrictedRoles(t *testing.T) {
+	cfg := newTestGatewayConfig("")
+	viewer := services.NewSecureViewer(cfg, nil, zap.NewNop())
+	record := &models.Document{DocumentType: "medical_record"}
+	identity := &models.Document{DocumentType: "identity"}
+
+	assert.False(t, viewer.Restricted(record, "underwriter"))
+	assert.True(t, viewer.Restricted(record, "broker"))
+	assert.True(t, viewer.Restricted(record, ""), "A request without a role is restricted")
+	assert.False(t, viewer.Restricted(identity, ""), "Only the configured document types are restricted")
+
+	cfg.SecureViewerConfig.UnrestrictedRoles = append(cfg.SecureViewerConfig.UnrestrictedRoles, "")
+	assert.True(t, services.NewSecureViewer(cfg, nil, zap.NewNop()).Restricted(record, ""), "An empty role cannot be unrestricted")
+}
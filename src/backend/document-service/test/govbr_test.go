@@ -0,0 +1,59 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// recordingVerifier accepts every code and records the ones it was asked about
+type recordingVerifier struct {
+	codes []string
+}
+
+func (v *recordingVerifier) VerifyCode(ctx context.Context, kind, code string) (bool, error) {
+	v.codes = append(v.codes, kind+":"+code)
+	return true, nil
+}
+
+func TestGovBRStepReadsCompressedTextLayer(t *testing.T) {
+	pages := []image.Image{image.NewRGBA(image.Rect(0, 0, 400, 600))}
+	lines := []models.OCRLine{
+		{Page: 1, Text: "CERTIFICADO DE REGISTRO E LICENCIAMENTO DE VEICULO", Box: [4]float64{0.1, 0.1, 0.9, 0.15}},
+		{Page: 1, Text: "Código de segurança 1234567890", Box: [4]float64{0.1, 0.2, 0.9, 0.25}},
+	}
+	pdf, err := services.BuildSearchablePDF(pages, 200, 90, lines, "crlv.pdf")
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(pdf, []byte("LICENCIAMENTO")), "The text layer should only be held compressed")
+
+	cfg := &config.Config{}
+	cfg.GovBRConfig.DocumentTypes = []string{"vehicle_registration"}
+	verifier := &recordingVerifier{}
+	step, err := services.NewGovBRStep(cfg, verifier)
+	assert.NoError(t, err)
+
+	doc := &models.Document{ID: "doc-1", DocumentType: "vehicle_registration", ContentType: "application/pdf"}
+	assert.True(t, step.Applies(doc))
+	assert.NoError(t, step.Execute(context.Background(), &services.PipelineRun{Document: doc, Content: pdf}))
+	assert.Equal(t, []string{services.GovDocumentCRLV + ":1234567890"}, verifier.codes)
+	assert.True(t, doc.GovernmentVerified)
+	assert.NotNil(t, doc.GovernmentVerifiedAt)
+
+	// A PDF that cannot be opened fails the step rather than being skipped
+	err = step.Execute(context.Background(), &services.PipelineRun{Document: doc, Content: []byte("%PDF-1.4 truncated")})
+	assert.Error(t, err)
+}
+
+func TestOCRReadsVehicleRegistrations(t *testing.T) {
+	step := services.NewOCRStep(nil, nil)
+	assert.True(t, step.Applies(&models.Document{DocumentType: "vehicle_registration"}), "Scanned CRLVs need OCR for their verification code")
+	assert.True(t, step.Applies(&models.Document{DocumentType: "identity"}))
+	assert.False(t, step.Applies(&models.Document{DocumentType: "signature_page"}))
+}
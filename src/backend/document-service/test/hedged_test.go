@@ -0,0 +1,293 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus" // v1.17.0
+	"github.com/stretchr/testify/assert"             // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const hedgeDelay = 40 * time.Millisecond
+
+// hedgeStore is an object store whose reads answer as respond says for the
+// number of the call; call 0 is the primary and call 1 the hedge
+type hedgeStore struct {
+	mu      sync.Mutex
+	respond func(ctx context.Context, call int) error
+	calls   []context.Context
+	readers []*trackedReader
+}
+
+// trackedReader is an object body that records whether it was closed
+type trackedReader struct {
+	io.Reader
+	mu     sync.Mutex
+	closed bool
+}
+
+func (r *trackedReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *trackedReader) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+func (s *hedgeStore) Name() string { return "hedge" }
+
+func (s *hedgeStore) Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+	return nil
+}
+
+func (s *hedgeStore) Delete(ctx context.Context, key string) error { return nil }
+
+func (s *hedgeStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	call := s.start(ctx)
+	if err := s.respond(ctx, call); err != nil {
+		return nil, err
+	}
+	reader := &trackedReader{Reader: strings.NewReader(fmt.Sprintf("call %d", call))}
+	s.mu.Lock()
+	s.readers[call] = reader
+	s.mu.Unlock()
+	return reader, nil
+}
+
+func (s *hedgeStore) Stat(ctx context.Context, key string) (services.ObjectInfo, error) {
+	call := s.start(ctx)
+	if err := s.respond(ctx, call); err != nil {
+		return services.ObjectInfo{}, err
+	}
+	return services.ObjectInfo{Size: int64(call)}, nil
+}
+
+func (s *hedgeStore) start(ctx context.Context) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, ctx)
+	s.readers = append(s.readers, nil)
+	return len(s.calls) - 1
+}
+
+func (s *hedgeStore) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+func (s *hedgeStore) callContext(call int) context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[call]
+}
+
+func (s *hedgeStore) reader(call int) *trackedReader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readers[call]
+}
+
+// answerAfter answers after d, or fails when the call is cancelled first
+func answerAfter(ctx context.Context, d time.Duration, err error) error {
+	select {
+	case <-time.After(d):
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newHedgedStore(respond func(ctx context.Context, call int) error) (*hedgeStore, *services.HedgedStore) {
+	store := &hedgeStore{respond: respond}
+	return store, services.NewHedgedStore(store, config.HedgingConfig{Enabled: true, Delay: hedgeDelay})
+}
+
+// hedgedReads returns the hedged reads counted for an operation and outcome
+func hedgedReads(t *testing.T, operation, outcome string) float64 {
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, services.RegisterMetrics(registry))
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "storage_hedged_reads_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["operation"] == operation && labels["outcome"] == outcome {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestHedgedReadFiresOnlyAfterDelay(t *testing.T) {
+	// The counters are shared by every hedged store of the process
+	notHedged := hedgedReads(t, "stat", "not_hedged")
+	hedgeWon := hedgedReads(t, "stat", "hedge_won")
+
+	store, hedged := newHedgedStore(func(ctx context.Context, call int) error {
+		return answerAfter(ctx, hedgeDelay/4, nil)
+	})
+	_, err := hedged.Stat(context.Background(), "fast")
+	assert.NoError(t, err)
+	time.Sleep(2 * hedgeDelay)
+	assert.Equal(t, 1, store.callCount(), "A read answering before the delay should not be hedged")
+	assert.Equal(t, float64(1), hedgedReads(t, "stat", "not_hedged")-notHedged)
+
+	store, hedged = newHedgedStore(func(ctx context.Context, call int) error {
+		if call == 0 {
+			return answerAfter(ctx, time.Minute, nil)
+		}
+		return nil
+	})
+	started := time.Now()
+	info, err := hedged.Stat(context.Background(), "slow")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(started), hedgeDelay, "The hedge should wait for the delay")
+	assert.Equal(t, int64(1), info.Size, "The hedge should answer")
+	assert.Equal(t, 2, store.callCount())
+	assert.Equal(t, float64(1), hedgedReads(t, "stat", "hedge_won")-hedgeWon)
+}
+
+func TestHedgedReadCancelsAndDiscardsLoser(t *testing.T) {
+	hedgeWon := hedgedReads(t, "get", "hedge_won")
+	primaryWon := hedgedReads(t, "get", "primary_won")
+	baseline := runtime.NumGoroutine()
+
+	// The primary only answers once it is cancelled, with a body nobody reads
+	primaryCancelled := make(chan struct{})
+	store, hedged := newHedgedStore(func(ctx context.Context, call int) error {
+		if call == 0 {
+			<-ctx.Done()
+			close(primaryCancelled)
+		}
+		return nil
+	})
+	reader, err := hedged.Get(context.Background(), "doc")
+	if !assert.NoError(t, err) {
+		return
+	}
+	content, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "call 1", string(content))
+
+	select {
+	case <-primaryCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("The losing primary should be cancelled")
+	}
+	assert.Eventually(t, func() bool {
+		loser := store.reader(0)
+		return loser != nil && loser.isClosed()
+	}, time.Second, 5*time.Millisecond, "The loser's body should be closed")
+	assert.NoError(t, store.callContext(1).Err(), "The winner should stay alive until its body is closed")
+	assert.NoError(t, reader.Close())
+	assert.Error(t, store.callContext(1).Err(), "Closing the body should release the winner")
+	assert.Equal(t, float64(1), hedgedReads(t, "get", "hedge_won")-hedgeWon)
+
+	// The hedge loses to a primary answering after the delay
+	store, hedged = newHedgedStore(func(ctx context.Context, call int) error {
+		if call == 0 {
+			return answerAfter(ctx, hedgeDelay+hedgeDelay/2, nil)
+		}
+		return answerAfter(ctx, time.Minute, nil)
+	})
+	reader, err = hedged.Get(context.Background(), "doc")
+	if assert.NoError(t, err) {
+		content, _ := io.ReadAll(reader)
+		assert.Equal(t, "call 0", string(content))
+		assert.NoError(t, reader.Close())
+	}
+	assert.Error(t, store.callContext(1).Err(), "The losing hedge should be cancelled")
+	assert.Equal(t, float64(1), hedgedReads(t, "get", "primary_won")-primaryWon)
+
+	// Polled here, since Eventually runs its condition on a goroutine
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline, "No goroutine should outlive the reads")
+}
+
+func TestHedgedReadDoesNotHedgeMissingObjects(t *testing.T) {
+	notHedged := hedgedReads(t, "get", "not_hedged")
+	primaryWon := hedgedReads(t, "stat", "primary_won")
+
+	store, hedged := newHedgedStore(func(ctx context.Context, call int) error {
+		return services.ErrObjectNotFound
+	})
+	_, err := hedged.Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+	time.Sleep(2 * hedgeDelay)
+	assert.Equal(t, 1, store.callCount(), "A missing object should not be hedged")
+	assert.Equal(t, float64(1), hedgedReads(t, "get", "not_hedged")-notHedged)
+
+	// A missing object reported by the primary after the hedge fired is
+	// final, without waiting for the hedge
+	store, hedged = newHedgedStore(func(ctx context.Context, call int) error {
+		if call == 0 {
+			return answerAfter(ctx, hedgeDelay+hedgeDelay/2, services.ErrObjectNotFound)
+		}
+		return answerAfter(ctx, time.Minute, nil)
+	})
+	started := time.Now()
+	_, err = hedged.Stat(context.Background(), "missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Error(t, store.callContext(1).Err(), "The hedge should be cancelled")
+	assert.Equal(t, float64(1), hedgedReads(t, "stat", "primary_won")-primaryWon)
+}
+
+func TestHedgedReadReturnsPrimaryErrorWhenBothFail(t *testing.T) {
+	bothFailed := hedgedReads(t, "stat", "both_failed")
+	errPrimary := errors.New("primary timed out")
+	errHedge := errors.New("hedge refused")
+
+	for _, order := range []struct {
+		name         string
+		primaryAfter time.Duration
+		hedgeAfter   time.Duration
+	}{
+		{"hedge fails last", hedgeDelay + hedgeDelay/4, hedgeDelay},
+		{"primary fails last", 3 * hedgeDelay, 0},
+	} {
+		_, hedged := newHedgedStore(func(ctx context.Context, call int) error {
+			if call == 0 {
+				return answerAfter(ctx, order.primaryAfter, errPrimary)
+			}
+			return answerAfter(ctx, order.hedgeAfter, errHedge)
+		})
+		_, err := hedged.Stat(context.Background(), "doc")
+		assert.ErrorIs(t, err, errPrimary, order.name)
+	}
+	assert.Equal(t, float64(2), hedgedReads(t, "stat", "both_failed")-bothFailed)
+
+	// A primary failing before the delay is left to the caller's retries
+	store, hedged := newHedgedStore(func(ctx context.Context, call int) error {
+		return errPrimary
+	})
+	_, err := hedged.Stat(context.Background(), "doc")
+	assert.ErrorIs(t, err, errPrimary)
+	assert.Equal(t, 1, store.callCount())
+}
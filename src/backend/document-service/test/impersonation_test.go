@@ -0,0 +1,194 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestImpersonation(t *testing.T) (*services.ImpersonationService, *repository.MemoryOutboxRepository) {
+	cfg := &config.Config{}
+	cfg.ImpersonationConfig = config.ImpersonationConfig{
+		Enabled:                true,
+		OperatorRoles:          []string{"support"},
+		SubjectRole:            "beneficiary",
+		TicketPattern:          `^[A-Z][A-Z0-9]*-[0-9]+$`,
+		MinJustificationLength: 20,
+		DefaultDuration:        15 * time.Minute,
+		MaxDuration:            time.Hour,
+		NotificationURL:        "http://notifications.invalid/impersonation",
+	}
+	outbox := repository.NewMemoryOutboxRepository()
+	impersonation, err := services.NewImpersonationService(cfg, outbox, zap.NewNop())
+	assert.NoError(t, err)
+	return impersonation, outbox
+}
+
+func validImpersonationRequest() services.ImpersonationRequest {
+	return services.ImpersonationRequest{
+		OperatorID:    "agent-1",
+		OperatorRole:  "support",
+		SubjectID:     "beneficiary-1",
+		Ticket:        "SUP-1234",
+		Justification: "Beneficiary cannot upload the proof of address from their phone",
+	}
+}
+
+func TestImpersonationRequiresJustification(t *testing.T) {
+	impersonation, _ := newTestImpersonation(t)
+	ctx := context.Background()
+
+	req := validImpersonationRequest()
+	req.OperatorRole = "broker"
+	_, _, err := impersonation.Start(ctx, req)
+	assert.ErrorIs(t, err, services.ErrImpersonationNotAllowed)
+
+	req = validImpersonationRequest()
+	req.Ticket = "see slack"
+	_, _, err = impersonation.Start(ctx, req)
+	assert.ErrorIs(t, err, services.ErrImpersonationTicket)
+
+	req = validImpersonationRequest()
+	req.Justification = "   helping out   "
+	_, _, err = impersonation.Start(ctx, req)
+	assert.ErrorIs(t, err, services.ErrJustificationTooShort)
+
+	req = validImpersonationRequest()
+	req.Duration = 2 * time.Hour
+	_, _, err = impersonation.Start(ctx, req)
+	assert.ErrorIs(t, err, services.ErrImpersonationDuration)
+
+	req = validImpersonationRequest()
+	req.SubjectID = req.OperatorID
+	_, _, err = impersonation.Start(ctx, req)
+	assert.ErrorIs(t, err, services.ErrSelfImpersonation)
+}
+
+func TestImpersonationSessionLifecycle(t *testing.T) {
+	impersonation, outbox := newTestImpersonation(t)
+	ctx := context.Background()
+
+	session, token, err := impersonation.Start(ctx, validImpersonationRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, "beneficiary", session.SubjectRole)
+	assert.Equal(t, 15*time.Minute, session.ExpiresAt.Sub(session.StartedAt))
+
+	_, err = impersonation.Authorize(ctx, token, "agent-2", "support")
+	assert.ErrorIs(t, err, services.ErrImpersonationNotOperator, "Only the operator who opened the session may use it")
+
+	authorized, err := impersonation.Authorize(ctx, token, "agent-1", "support")
+	assert.NoError(t, err)
+	assert.Equal(t, session.ID, authorized.ID)
+	impersonation.RecordAction(session.ID, models.ImpersonatedAction{Method: "POST", Path: "/api/v1/documents", Status: 201})
+
+	ended, err := impersonation.End(ctx, session.ID, "agent-1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ImpersonationStatusEnded, ended.Status)
+
+	_, err = impersonation.Authorize(ctx, token, "agent-1", "support")
+	assert.ErrorIs(t, err, services.ErrImpersonationNotFound, "Ending a session should revoke its token")
+
+	due, err := outbox.ListDue(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, services.TopicImpersonationEnded, due[0].Topic)
+		var notice services.ImpersonationNotice
+		assert.NoError(t, json.Unmarshal(due[0].Payload, &notice))
+		assert.Equal(t, "beneficiary-1", notice.SubjectID)
+		assert.Equal(t, "SUP-1234", notice.Ticket)
+		assert.True(t, strings.HasPrefix(notice.Justification, "Beneficiary cannot"))
+		assert.Len(t, notice.Actions, 1)
+	}
+}
+
+// newImpersonationRouter guards its routes like the API group: the gateway
+// token is verified before Impersonate runs. A request carrying
+// X-Service-Role stands for one a service account or API key identified
+func newImpersonationRouter(t *testing.T, jwksURL string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	tokens, err := services.NewGatewayTokens(newTestGatewayConfig(jwksURL), zap.NewNop())
+	assert.NoError(t, err)
+	impersonation, _ := newTestImpersonation(t)
+	handler, err := handlers.NewImpersonationHandler(impersonation, zap.NewNop())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	api := router.Group("", func(c *gin.Context) {
+		if role := c.GetHeader("X-Service-Role"); role != "" {
+			c.Set("user_id", "sa:batch")
+			c.Set("user_role", role)
+		}
+	}, handlers.AuthenticateGatewayUser(tokens, zap.NewNop()), handlers.Impersonate(impersonation, zap.NewNop()))
+	api.POST("/impersonations", handler.StartImpersonation)
+	api.GET("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetString("user_id"), "user_role": c.GetString("user_role")})
+	})
+	return router
+}
+
+func TestImpersonationOperatorRoleComesFromGatewayToken(t *testing.T) {
+	server, sign := newTestIdentityProvider(t)
+	router := newImpersonationRouter(t, server.URL)
+
+	start := func(header http.Header) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{
+			"subject_id":    "beneficiary-1",
+			"ticket":        "SUP-1234",
+			"justification": "Beneficiary cannot upload the proof of address from their phone",
+		})
+		req := httptest.NewRequest(http.MethodPost, "/impersonations", bytes.NewReader(body))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A role header set by the caller is never trusted
+	rec := start(http.Header{"Authorization": {"Bearer " + sign(gatewayClaims("broker"))}, "X-User-Role": {"support"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = start(http.Header{"X-Service-Role": {"support"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "Service identities cannot open sessions")
+
+	rec = start(http.Header{"Authorization": {"Bearer " + sign(gatewayClaims("support"))}})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var started struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &started))
+
+	whoami := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header = header
+		req.Header.Set(handlers.ImpersonationTokenHeader, started.Data.Token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = whoami(http.Header{"Authorization": {"Bearer " + sign(gatewayClaims("support"))}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"user_id":"beneficiary-1","user_role":"beneficiary"}`, rec.Body.String())
+
+	rec = whoami(http.Header{"Authorization": {"Bearer " + sign(gatewayClaims("broker"))}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "An operator whose role was withdrawn cannot keep using the session")
+	rec = whoami(http.Header{"X-Service-Role": {"support"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "Service identities cannot use sessions")
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models/invariants"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+// storedDocument returns a completed document holding every invariant
+func storedDocument(t assert.TestingT) *models.Document {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusProcessing, "Starting OCR processing"))
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "OCR completed"))
+	doc.StoragePath = "documents/" + doc.ID
+	doc.ContentHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	doc.EncryptionInfo = repairEncryption()
+	return doc
+}
+
+func TestInvariantsOfStoredDocuments(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	assert.Empty(t, invariants.Check(doc), "Content not yet stored has no metadata to check")
+
+	doc.Status = models.DocumentStatusCompleted
+	codes := func() []string {
+		var codes []string
+		for _, violation := range invariants.Check(doc) {
+			codes = append(codes, violation.Code)
+		}
+		return codes
+	}
+	assert.Equal(t, []string{invariants.MissingStoragePath, invariants.MissingEncryption, invariants.MissingContentHash}, codes())
+
+	doc.StoragePath = "documents/" + doc.ID
+	doc.ContentHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	doc.EncryptionInfo = repairEncryption()
+	assert.Empty(t, codes())
+
+	doc.EncryptionInfo.IV = ""
+	assert.Equal(t, []string{invariants.InvalidEncryption}, codes())
+
+	doc.EncryptionInfo = repairEncryption()
+	doc.EncryptionInfo.Version = models.EncryptionMetadataVersion + 1
+	assert.Equal(t, []string{invariants.InvalidEncryption}, codes())
+}
+
+func TestInvariantsExemptErasedContent(t *testing.T) {
+	doc := storedDocument(t)
+	doc.EncryptionInfo.WrappedKey = "d3JhcHBlZA=="
+	doc.MarkShredded(doc.UpdatedAt)
+	doc.EncryptionInfo.IV = ""
+	doc.ContentHash = ""
+	assert.Empty(t, invariants.Check(doc), "Shredded content is gone for good")
+
+	doc = storedDocument(t)
+	doc.Anonymize(doc.UpdatedAt)
+	assert.Empty(t, invariants.Check(doc), "Anonymized content is gone for good")
+}
+
+func TestInvariantsOfRenditions(t *testing.T) {
+	doc := storedDocument(t)
+	encryption := repairEncryption()
+	encryption.Algorithm = "rot13"
+	doc.SetRendition(models.Rendition{Name: "preview", StoragePath: "renditions/preview", Encryption: encryption})
+
+	violations := invariants.Check(doc)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, invariants.InvalidRenditionEncryption, violations[0].Code)
+	assert.Contains(t, violations[0].Detail, "preview")
+}
+
+func TestValidateChangeKeepsKnownViolations(t *testing.T) {
+	doc := storedDocument(t)
+	assert.NoError(t, invariants.Validate(doc))
+
+	legacy := storedDocument(t)
+	legacy.ContentHash = ""
+	err := invariants.Validate(legacy)
+	assert.ErrorIs(t, err, invariants.ErrViolated)
+	var violation *invariants.Error
+	assert.True(t, errors.As(err, &violation))
+	assert.Equal(t, legacy.ID, violation.DocumentID)
+	assert.Equal(t, []invariants.Violation{{Code: invariants.MissingContentHash}}, violation.Violations)
+
+	// A document stored before the hash was recorded can still change
+	changed := *legacy
+	changed.DocumentType = "proof_of_address"
+	assert.NoError(t, invariants.ValidateChange(legacy, &changed))
+
+	// but a change must not break an invariant the document held
+	changed.StoragePath = ""
+	err = invariants.ValidateChange(legacy, &changed)
+	assert.True(t, errors.As(err, &violation))
+	assert.Equal(t, []invariants.Violation{{Code: invariants.MissingStoragePath}}, violation.Violations)
+}
+
+func TestCheckedRepositoryRefusesViolations(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewCheckedDocumentRepository(repository.NewMemoryDocumentRepository())
+
+	broken := storedDocument(t)
+	broken.StoragePath = ""
+	assert.ErrorIs(t, repo.Create(ctx, broken), invariants.ErrViolated)
+	_, err := repo.GetByID(ctx, broken.ID)
+	assert.ErrorIs(t, err, repository.ErrDocumentNotFound)
+
+	doc := storedDocument(t)
+	assert.NoError(t, repo.Create(ctx, doc))
+	doc.ContentHash = ""
+	assert.ErrorIs(t, repo.Update(ctx, doc), invariants.ErrViolated)
+
+	stored, err := repo.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stored.ContentHash, "A refused change must not be persisted")
+}
@@ -0,0 +1,129 @@
+package test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestMemoryJobLocks(t *testing.T) {
+	ctx := context.Background()
+	locks := repository.NewMemoryJobLockRepository()
+
+	generation, held, err := locks.TryLock(ctx, models.JobRetention, "instance-a")
+	assert.NoError(t, err)
+	assert.True(t, held)
+	_, held, err = locks.TryLock(ctx, models.JobRetention, "instance-b")
+	assert.NoError(t, err)
+	assert.False(t, held, "A job should be locked by one instance at a time")
+	renewed, held, err := locks.TryLock(ctx, models.JobRetention, "instance-a")
+	assert.NoError(t, err)
+	assert.True(t, held, "The holder should renew its lock")
+	assert.Equal(t, generation, renewed, "Renewing should keep the lease generation")
+
+	// Only the holder releases the lock
+	assert.NoError(t, locks.Unlock(ctx, models.JobRetention, "instance-b"))
+	leases, err := locks.Leases(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, leases, 1) {
+		assert.Equal(t, "instance-a", leases[0].Owner)
+	}
+
+	assert.NoError(t, locks.Unlock(ctx, models.JobRetention, "instance-a"))
+	next, held, err := locks.TryLock(ctx, models.JobRetention, "instance-b")
+	assert.NoError(t, err)
+	assert.True(t, held)
+	assert.Greater(t, next, generation, "Every acquisition should draw a new generation")
+
+	assert.NoError(t, locks.CheckLease(ctx, models.JobRetention, "instance-b", next))
+	assert.ErrorIs(t, locks.CheckLease(ctx, models.JobRetention, "instance-a", generation), repository.ErrJobLeaseLost)
+}
+
+func TestJobCoordinatorRunsJobOnOneInstance(t *testing.T) {
+	locks := repository.NewMemoryJobLockRepository()
+	newInstance := func(id string) *services.JobCoordinator {
+		cfg := &config.Config{}
+		cfg.CoordinationConfig = config.CoordinationConfig{InstanceID: id, RenewInterval: 10 * time.Millisecond}
+		jobs, err := services.NewJobCoordinator(cfg, locks, zap.NewNop())
+		assert.NoError(t, err)
+		return jobs
+	}
+	first, second := newInstance("instance-a"), newInstance("instance-b")
+
+	var running, started int32
+	job := func(ctx context.Context) {
+		assert.Equal(t, int32(1), atomic.AddInt32(&running, 1), "The job should never run twice at once")
+		atomic.AddInt32(&started, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&running, -1)
+	}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		first.Run(firstCtx, models.JobRetention, job)
+		close(firstDone)
+	}()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&started) == 1 }, time.Second, time.Millisecond)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	go second.Run(secondCtx, models.JobRetention, job)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started))
+
+	statuses, err := second.Status(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, "instance-a", statuses[0].Owner)
+		assert.False(t, statuses[0].Local)
+	}
+
+	// Once the holder shuts down the other instance takes the job over
+	stopFirst()
+	<-firstDone
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&started) == 2 }, time.Second, time.Millisecond)
+	statuses, err = second.Status(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 1) {
+		assert.Equal(t, "instance-b", statuses[0].Owner)
+		assert.True(t, statuses[0].Local)
+	}
+}
+
+func TestJobCoordinatorFencesSupersededHolder(t *testing.T) {
+	locks := repository.NewMemoryJobLockRepository()
+	cfg := &config.Config{}
+	cfg.CoordinationConfig = config.CoordinationConfig{InstanceID: "instance-a", RenewInterval: time.Hour}
+	jobs, err := services.NewJobCoordinator(cfg, locks, zap.NewNop())
+	assert.NoError(t, err)
+
+	started := make(chan context.Context)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go jobs.Run(ctx, models.JobRetention, func(jobCtx context.Context) {
+		started <- jobCtx
+		<-jobCtx.Done()
+	})
+	jobCtx := <-started
+	assert.NoError(t, services.CheckJobLease(jobCtx))
+
+	// The lock is lost, as with a dropped session, and another instance
+	// takes the job over before the holder renews
+	assert.NoError(t, locks.Unlock(context.Background(), models.JobRetention, "instance-a"))
+	_, held, err := locks.TryLock(context.Background(), models.JobRetention, "instance-b")
+	assert.NoError(t, err)
+	assert.True(t, held)
+
+	assert.ErrorIs(t, services.CheckJobLease(jobCtx), repository.ErrJobLeaseLost, "The superseded holder should not write")
+	assert.NoError(t, services.CheckJobLease(context.Background()), "Work outside a coordinated job is not fenced")
+}
@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestKeyUsageReportFlagsAnomalies(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.KeyAuditConfig.Thresholds = config.KeyUsageThresholds{
+		DecryptsPerPrincipal:  3,
+		DocumentsPerPrincipal: 2,
+		FailuresPerPrincipal:  1,
+	}
+	audit, err := services.NewKeyAuditService(cfg, repository.NewMemoryKeyUsageRepository(), zap.NewNop())
+	assert.NoError(t, err)
+
+	hour := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	use := func(principal, documentID, operation, version string, succeeded bool, at time.Time) {
+		audit.Record(ctx, &models.KeyUsageEvent{
+			DocumentID: documentID,
+			Principal:  principal,
+			Operation:  operation,
+			KeyID:      "alias/documents",
+			KeyVersion: version,
+			Succeeded:  succeeded,
+			OccurredAt: at,
+		})
+	}
+
+	// A reviewer reading one document repeatedly stays under the spread limit
+	for i := 0; i < 3; i++ {
+		use("reviewer", "doc-1", models.KeyOperationDecrypt, "2", true, hour.Add(time.Duration(i)*time.Minute))
+	}
+	// Another principal sweeps through many documents within the hour
+	for _, id := range []string{"doc-1", "doc-2", "doc-3", "doc-4"} {
+		use("intruder", id, models.KeyOperationDecrypt, "2", true, hour.Add(10*time.Minute))
+	}
+	use("intruder", "doc-5", models.KeyOperationDecrypt, "1", false, hour.Add(11*time.Minute))
+	use("intruder", "doc-6", models.KeyOperationDecrypt, "1", false, hour.Add(12*time.Minute))
+	use(models.PrincipalSystem, "doc-7", models.KeyOperationEncrypt, "2", true, hour.Add(time.Hour))
+
+	report, err := audit.Report(ctx, hour, hour.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, report.Events)
+
+	if assert.Len(t, report.KeyVersions, 2) {
+		assert.Equal(t, "1", report.KeyVersions[0].KeyVersion)
+		assert.Equal(t, 2, report.KeyVersions[0].Failures)
+
+		current := report.KeyVersions[1]
+		assert.Equal(t, 7, current.Operations[models.KeyOperationDecrypt])
+		assert.Equal(t, 1, current.Operations[models.KeyOperationEncrypt])
+		assert.Equal(t, 5, current.Documents)
+		assert.Equal(t, 3, current.Principals)
+	}
+
+	flagged := make(map[string]string)
+	for _, anomaly := range report.Anomalies {
+		assert.Equal(t, hour, anomaly.Hour)
+		flagged[anomaly.Type] = anomaly.Principal
+	}
+	assert.Len(t, report.Anomalies, 3, "Only the sweeping principal should be flagged")
+	assert.Equal(t, "intruder", flagged[models.KeyAnomalyDecryptVolume])
+	assert.Equal(t, "intruder", flagged[models.KeyAnomalyDocumentSpread])
+	assert.Equal(t, "intruder", flagged[models.KeyAnomalyFailures])
+
+	events, err := audit.Events(ctx, repository.KeyUsageFilter{DocumentID: "doc-1"})
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+	assert.Equal(t, "reviewer", events[0].Principal, "Events should be listed oldest first")
+
+	_, err = audit.Report(ctx, hour, hour)
+	assert.ErrorIs(t, err, services.ErrInvalidReportPeriod)
+}
+
+func TestKeyUsageRetention(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryKeyUsageRepository()
+	now := time.Now()
+
+	assert.NoError(t, repo.Append(ctx, &models.KeyUsageEvent{ID: "old", OccurredAt: now.Add(-48 * time.Hour)}))
+	assert.NoError(t, repo.Append(ctx, &models.KeyUsageEvent{ID: "new", OccurredAt: now}))
+
+	removed, err := repo.DeleteBefore(ctx, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	events, err := repo.List(ctx, repository.KeyUsageFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "new", events[0].ID)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	texts := map[string]string{
+		"pt": "Laudo médico. O paciente não apresenta alterações e o exame foi realizado com sucesso. Nome da mãe e data de nascimento conferem com os documentos.",
+		"es": "Informe médico. El paciente no presenta alteraciones y el examen fue realizado con éxito en el hospital. Nombre y fecha de nacimiento del titular.",
+		"en": "Medical report. The patient shows no changes and the exam was performed with success. Name and date of birth of the holder are on this page.",
+	}
+	for expected, text := range texts {
+		language, ok := services.DetectLanguage(text)
+		if assert.True(t, ok, expected) {
+			assert.Equal(t, expected, language.Code)
+			assert.Greater(t, language.Confidence, 0.6)
+		}
+	}
+
+	// Machine-readable zones and short labels carry no function words
+	_, ok := services.DetectLanguage("P<BRASILVA<<MARIA<<<<<<<<<<<<<<<<<<<<<<<<<< 1234567890BRA8001014F3001019")
+	assert.False(t, ok)
+}
+
+func TestLibreTranslateClient(t *testing.T) {
+	requests := make(chan map[string]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		requests <- body
+		if body["source"] == "xx" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "xx is not supported"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"translatedText": "O paciente está bem"})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.TranslationConfig = config.TranslationConfig{
+		Enabled:  true,
+		Provider: "libretranslate",
+		BaseURL:  server.URL + "/",
+		APIKey:   "key",
+		Timeout:  5 * time.Second,
+	}
+	client, err := services.NewLibreTranslateClient(cfg)
+	assert.NoError(t, err)
+
+	translated, err := client.Translate(context.Background(), "The patient is well", "en", "pt")
+	assert.NoError(t, err)
+	assert.Equal(t, "O paciente está bem", translated)
+	request := <-requests
+	assert.Equal(t, "en", request["source"])
+	assert.Equal(t, "pt", request["target"])
+	assert.Equal(t, "key", request["api_key"])
+
+	_, err = client.Translate(context.Background(), "text", "xx", "pt")
+	assert.ErrorIs(t, err, services.ErrTranslationFailed)
+	<-requests
+
+	cfg.TranslationConfig.Enabled = false
+	client, err = services.NewLibreTranslateClient(cfg)
+	assert.NoError(t, err)
+	assert.Nil(t, client)
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestLargeDocumentAcceptedPastChannelLimit(t *testing.T) {
+	size := int64(120 * 1024 * 1024)
+	_, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", size)
+	assert.ErrorIs(t, err, models.ErrInvalidSize)
+
+	doc, err := models.NewLargeDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", size, 50*1024*1024, models.LargeDocumentCapabilities)
+	assert.NoError(t, err)
+	assert.Equal(t, size, doc.Size)
+	assert.Equal(t, int64(50*1024*1024), doc.LargeDocument.Limit)
+	assert.Equal(t, []string{models.CapabilityPreview, models.CapabilitySearch, models.CapabilityText}, doc.LargeDocument.DelayedCapabilities)
+
+	_, err = models.NewLargeDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", models.MaxLargeDocumentSize+1, 50*1024*1024, nil)
+	assert.ErrorIs(t, err, models.ErrInvalidSize)
+}
+
+func TestLargeDocumentCapabilitiesWaitOnProcessing(t *testing.T) {
+	doc, err := models.NewLargeDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 120*1024*1024, 50*1024*1024, []string{models.CapabilityPreview, models.CapabilitySearch})
+	assert.NoError(t, err)
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusQueued, "Large document queued for background processing"))
+	assert.True(t, doc.Delayed(models.CapabilityPreview))
+	assert.True(t, doc.Delayed(models.CapabilitySearch))
+	assert.False(t, doc.Delayed(models.CapabilityText), "Only the capabilities named on acceptance are delayed")
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "Processing large document in the background"))
+	assert.False(t, doc.Delayed(models.CapabilityPreview))
+	assert.WithinDuration(t, time.Now(), *doc.ProcessedAt, time.Second)
+
+	regular, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	assert.Nil(t, regular.LargeDocument)
+	assert.False(t, regular.Delayed(models.CapabilityPreview))
+}
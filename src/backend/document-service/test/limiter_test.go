@@ -0,0 +1,122 @@
+//go:build testhooks
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// limiterClock is a clock advanced by hand
+type limiterClock struct {
+	now time.Time
+}
+
+func (c *limiterClock) Now() time.Time { return c.now }
+
+func newTestLimiter(t *testing.T) (*services.AdaptiveLimiter, *limiterClock) {
+	cfg := &config.Config{}
+	cfg.ConcurrencyConfig.Enabled = true
+	limiter := services.NewAdaptiveLimiter("test", cfg, config.AdaptiveLimitConfig{
+		InitialLimit:     4,
+		MinLimit:         2,
+		MaxLimit:         5,
+		Window:           4,
+		LatencyTolerance: 2,
+		BackoffRatio:     0.5,
+	})
+	clock := &limiterClock{now: time.Unix(0, 0)}
+	limiter.UseClock(clock.Now)
+	return limiter, clock
+}
+
+// runWindow completes a window of calls taking latency each, concurrently up
+// to the current limit when saturate is set and one at a time otherwise
+func runWindow(t *testing.T, limiter *services.AdaptiveLimiter, clock *limiterClock, latency time.Duration, saturate bool, errs ...error) {
+	ctx := context.Background()
+	for done := 0; done < 4; {
+		batch := 1
+		if saturate {
+			batch = limiter.Limit()
+		}
+		if batch > 4-done {
+			batch = 4 - done
+		}
+		releases := make([]func(error), 0, batch)
+		for i := 0; i < batch; i++ {
+			release, err := limiter.Acquire(ctx)
+			if !assert.NoError(t, err) {
+				return
+			}
+			releases = append(releases, release)
+		}
+		clock.now = clock.now.Add(latency)
+		for _, release := range releases {
+			var err error
+			if done < len(errs) {
+				err = errs[done]
+			}
+			release(err)
+			done++
+		}
+	}
+}
+
+func TestAdaptiveLimiterGrowsOnlyWhenSaturated(t *testing.T) {
+	limiter, clock := newTestLimiter(t)
+
+	runWindow(t, limiter, clock, 10*time.Millisecond, false)
+	assert.Equal(t, 4, limiter.Limit(), "A healthy window that never reached the limit should keep it")
+
+	runWindow(t, limiter, clock, 10*time.Millisecond, true)
+	assert.Equal(t, 5, limiter.Limit(), "A healthy saturated window should grow the limit by one")
+
+	runWindow(t, limiter, clock, 10*time.Millisecond, true)
+	assert.Equal(t, 5, limiter.Limit(), "The limit should not grow past the maximum")
+}
+
+func TestAdaptiveLimiterBacksOffOnLatencyAboveBaseline(t *testing.T) {
+	limiter, clock := newTestLimiter(t)
+
+	// The first healthy window sets the 10ms baseline
+	runWindow(t, limiter, clock, 10*time.Millisecond, false)
+	runWindow(t, limiter, clock, 15*time.Millisecond, false)
+	assert.Equal(t, 4, limiter.Limit(), "Latency within the tolerance should keep the limit")
+
+	runWindow(t, limiter, clock, 30*time.Millisecond, true)
+	assert.Equal(t, 2, limiter.Limit(), "Latency above twice the baseline should halve the limit")
+
+	runWindow(t, limiter, clock, time.Second, false)
+	assert.Equal(t, 2, limiter.Limit(), "The limit should not drop below the minimum")
+}
+
+func TestAdaptiveLimiterBacksOffOnTimeouts(t *testing.T) {
+	limiter, clock := newTestLimiter(t)
+
+	runWindow(t, limiter, clock, 10*time.Millisecond, false, nil, context.DeadlineExceeded)
+	assert.Equal(t, 2, limiter.Limit(), "A timed out call should cut the limit even at baseline latency")
+}
+
+func TestAdaptiveLimiterBlocksAtLimit(t *testing.T) {
+	limiter, _ := newTestLimiter(t)
+
+	for i := 0; i < limiter.Limit(); i++ {
+		_, err := limiter.Acquire(context.Background())
+		assert.NoError(t, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := limiter.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var disabled *services.AdaptiveLimiter
+	release, err := disabled.Acquire(context.Background())
+	assert.NoError(t, err)
+	release(nil)
+}
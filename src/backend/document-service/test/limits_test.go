@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestServiceConfig() config.ServiceConfig {
+	return config.ServiceConfig{
+		MaxFileSize:    10 * 1024 * 1024,
+		RequestTimeout: time.Minute,
+		Routes: config.RouteGroupsConfig{
+			API:     config.RouteLimits{MaxBodySize: 16, Timeout: 30 * time.Second},
+			Upload:  config.RouteLimits{Timeout: 2 * time.Minute},
+			Webhook: config.RouteLimits{MaxBodySize: 1024},
+			Admin:   config.RouteLimits{Timeout: 10 * time.Minute},
+		},
+	}
+}
+
+func TestRouteLimitsFallBackToServiceDefaults(t *testing.T) {
+	cfg := newTestServiceConfig()
+
+	upload := cfg.Limits(config.RouteGroupUpload)
+	assert.Equal(t, int64(11*1024*1024), upload.MaxBodySize, "Uploads should accept the max file size plus multipart framing")
+	assert.Equal(t, 2*time.Minute, upload.Timeout)
+
+	webhook := cfg.Limits(config.RouteGroupWebhook)
+	assert.Equal(t, int64(1024), webhook.MaxBodySize)
+	assert.Equal(t, time.Minute, webhook.Timeout, "Groups without a timeout should use the request timeout")
+
+	download := cfg.Limits(config.RouteGroupDownload)
+	assert.Equal(t, int64(16), download.MaxBodySize, "Groups without a body limit should use the API limit")
+
+	assert.Equal(t, 10*time.Minute, cfg.LongestTimeout())
+}
+
+func TestLimitsSnapshotResolvesFallbacks(t *testing.T) {
+	cfg := &config.Config{ServiceConfig: newTestServiceConfig()}
+	cfg.ServiceConfig.AllowedMimeTypes = []string{"application/pdf"}
+	cfg.MinioConfig.UploadTimeout = 30 * time.Second
+	cfg.AzureConfig.OCRTimeout = 10 * time.Second
+	cfg.AzureConfig.MaxDocumentSize = 4 * 1024 * 1024
+
+	snapshot := cfg.Limits()
+	assert.Equal(t, 40*time.Second, snapshot.IngestTimeout, "Uploads should be bounded by the storage and OCR timeouts together")
+	assert.Equal(t, int64(4*1024*1024), snapshot.OCRMaxDocumentSize)
+	assert.Equal(t, []string{"application/pdf"}, snapshot.AllowedMimeTypes)
+	assert.Len(t, snapshot.Routes, len(config.RouteGroups))
+	assert.Equal(t, int64(11*1024*1024), snapshot.Routes[config.RouteGroupUpload].MaxBodySize)
+	assert.Equal(t, time.Minute, snapshot.Routes[config.RouteGroupWebhook].Timeout)
+}
+
+func TestChannelPolicyOverridesServiceDefaults(t *testing.T) {
+	cfg := newTestServiceConfig()
+	cfg.AllowedMimeTypes = []string{"application/pdf", "image/jpeg"}
+	cfg.Channels.WhatsApp = config.ChannelPolicy{
+		AllowedMimeTypes: []string{"application/pdf", "image/jpeg", models.MimeTypeHEIC, models.MimeTypeDOCX},
+		MaxFileSize:      5 * 1024 * 1024,
+		ConvertToPDF:     []string{"image/jpeg"},
+	}
+
+	api := cfg.Channel(models.ChannelAPI)
+	assert.Equal(t, cfg.AllowedMimeTypes, api.AllowedMimeTypes, "Channels without types should accept the service-wide types")
+	assert.Equal(t, cfg.MaxFileSize, api.MaxFileSize)
+	assert.False(t, api.Accepts(models.MimeTypeDOCX))
+	assert.Equal(t, models.ConversionNone, api.Conversion("image/jpeg"))
+
+	whatsapp := cfg.Channel(models.ChannelWhatsApp)
+	assert.True(t, whatsapp.Accepts(models.MimeTypeHEIC))
+	assert.True(t, whatsapp.Accepts(models.MimeTypeDOCX))
+	assert.Equal(t, int64(5*1024*1024), whatsapp.MaxFileSize)
+	assert.Equal(t, models.ConversionPDF, whatsapp.Conversion("image/jpeg"))
+	assert.Equal(t, models.ConversionNone, whatsapp.Conversion("application/pdf"))
+
+	snapshot := (&config.Config{ServiceConfig: cfg}).Limits()
+	assert.Len(t, snapshot.Channels, len(models.Channels))
+	assert.Equal(t, int64(5*1024*1024), snapshot.Channels[models.ChannelWhatsApp].MaxFileSize)
+}
+
+func TestMarkConvertedKeepsUploadType(t *testing.T) {
+	doc, err := models.NewDocument("enrollment-1", "ID", "photo.jpeg", "image/jpeg", 2048)
+	assert.NoError(t, err)
+
+	doc.MarkConverted("image/jpeg", 4096)
+	assert.Equal(t, "application/pdf", doc.ContentType)
+	assert.Equal(t, "image/jpeg", doc.ConvertedFrom)
+	assert.Equal(t, "photo.pdf", doc.Filename)
+	assert.Equal(t, int64(4096), doc.Size)
+
+	contentType, ok := models.LookupContentType(models.MimeTypeHEIC)
+	assert.True(t, ok)
+	assert.False(t, contentType.PDFConvertible, "HEIC has no built-in converter")
+	_, ok = models.LookupContentType("application/zip")
+	assert.False(t, ok)
+}
+
+func newLimitedRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/limited", handlers.LimitRequest(newTestServiceConfig(), config.RouteGroupAPI, zap.NewNop()), handler)
+	return router
+}
+
+func TestLimitRequestRejectsDeclaredOversizeBody(t *testing.T) {
+	called := false
+	router := newLimitedRouter(func(c *gin.Context) {
+		called = true
+		c.Status(http.StatusOK)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(strings.Repeat("x", 17))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.False(t, called, "Oversize bodies should be rejected before the handler runs")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(strings.Repeat("x", 16))))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestLimitRequestBoundsUndeclaredBody(t *testing.T) {
+	var readErr error
+	router := newLimitedRouter(func(c *gin.Context) {
+		_, readErr = io.ReadAll(c.Request.Body)
+		c.Status(http.StatusOK)
+	})
+
+	// A chunked body declares no length and is cut off while it is read
+	req := httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader(strings.Repeat("x", 64)))
+	req.ContentLength = -1
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var maxBytesErr *http.MaxBytesError
+	assert.True(t, errors.As(readErr, &maxBytesErr))
+}
+
+func TestLimitRequestSetsDeadline(t *testing.T) {
+	var deadline time.Time
+	router := newLimitedRouter(func(c *gin.Context) {
+		deadline, _ = c.Request.Context().Deadline()
+		c.Status(http.StatusOK)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/limited", nil))
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), deadline, time.Second)
+}
+
+func TestBandwidthShaperLimitsRole(t *testing.T) {
+	cfg := &config.Config{ServiceConfig: newTestServiceConfig()}
+	cfg.ServiceConfig.DownloadBandwidth.Roles = map[string]config.BandwidthLimit{
+		"broker": {BytesPerSecond: 64 * 1024, Burst: 32 * 1024},
+	}
+	shaper := services.NewBandwidthShaper(cfg)
+
+	var out bytes.Buffer
+	assert.Same(t, &out, shaper.Writer(context.Background(), &out, "beneficiary", "user-1"), "Roles without a limit are not shaped")
+
+	start := time.Now()
+	n, err := shaper.Writer(context.Background(), &out, "broker", "user-2").Write(make([]byte, 96*1024))
+	assert.NoError(t, err)
+	assert.Equal(t, 96*1024, n)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond, "Bytes past the burst should stream at the limit")
+
+	// The bucket is per user, and the same user's next download waits
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = shaper.Writer(ctx, &out, "broker", "user-2").Write(make([]byte, 64*1024))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestMaintenanceConfig() *config.Config {
+	return &config.Config{MaintenanceConfig: config.MaintenanceConfig{
+		Message:    "Storage migration in progress",
+		RetryAfter: 5 * time.Minute,
+	}}
+}
+
+func TestMaintenanceModeRefusesWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mode, err := services.NewMaintenanceMode(newTestMaintenanceConfig(), nil, zap.NewNop())
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(handlers.RejectWritesInMaintenance(mode, zap.NewNop(), "/documents/:id/preview-token"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/documents/:id", ok)
+	router.POST("/documents", ok)
+	router.POST("/documents/:id/preview-token", ok)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/documents").Code)
+
+	status := mode.Set(true, "", handlers.PrincipalAdmin)
+	assert.True(t, status.Active)
+	assert.Equal(t, services.MaintenanceSourceAdmin, status.Source)
+	assert.Equal(t, "Storage migration in progress", status.Message, "The configured message is used by default")
+
+	w := serve(http.MethodPost, "/documents")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "300", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Storage migration in progress")
+	assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/documents/doc-1").Code, "Reads are served during maintenance")
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/documents/doc-1/preview-token").Code)
+
+	assert.False(t, mode.Clear(handlers.PrincipalAdmin).Active)
+	assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/documents").Code)
+}
+
+func TestMaintenanceModeSources(t *testing.T) {
+	cfg := newTestMaintenanceConfig()
+	cfg.FeatureFlagsConfig.Flags = []config.FeatureFlagConfig{{Name: services.FlagMaintenance, Enabled: true}}
+	flags, err := services.NewFeatureFlags(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+
+	mode, err := services.NewMaintenanceMode(cfg, flags, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, services.MaintenanceSourceFlag, mode.Status().Source)
+
+	// The admin API overrides the flag on this instance
+	assert.False(t, mode.Set(false, "", handlers.PrincipalAdmin).Active)
+	assert.True(t, mode.Clear(handlers.PrincipalAdmin).Active)
+
+	cfg.MaintenanceConfig.Enabled = true
+	cfg.FeatureFlagsConfig.Flags = nil
+	mode, err = services.NewMaintenanceMode(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Equal(t, services.MaintenanceSourceConfig, mode.Status().Source)
+
+	var disabled *services.MaintenanceMode
+	assert.False(t, disabled.Active())
+}
+
+func TestMaintenanceModeRefusesPipelineIngestion(t *testing.T) {
+	cfg := newTestMaintenanceConfig()
+	mode, err := services.NewMaintenanceMode(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	pipeline, err := services.NewDocumentPipeline(cfg, &services.StorageService{}, repository.NewMemoryDocumentRepository(), nil, zap.NewNop())
+	assert.NoError(t, err)
+	pipeline.UseMaintenance(mode)
+
+	mode.Set(true, "", handlers.PrincipalAdmin)
+	assert.True(t, pipeline.ReadOnly())
+
+	// Channels outside the HTTP API, such as SFTP, reach the pipeline directly
+	_, err = pipeline.Ingest(context.Background(), services.IngestRequest{
+		EnrollmentID: "enr-1",
+		DocumentType: "identity",
+		Filename:     "a.pdf",
+		ContentType:  "application/pdf",
+		Channel:      models.ChannelSFTP,
+		Content:      bytes.NewReader([]byte("%PDF-1.4")),
+		Size:         8,
+	})
+	assert.ErrorIs(t, err, services.ErrMaintenance)
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/migrations"
+)
+
+func TestEmbeddedMigrationsFollowConvention(t *testing.T) {
+	loaded, err := migrations.Load()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, loaded)
+
+	for i, migration := range loaded {
+		assert.Contains(t, []migrations.Phase{migrations.PhaseExpand, migrations.PhaseContract}, migration.Phase)
+		if i > 0 {
+			assert.Greater(t, migration.Version, loaded[i-1].Version)
+		}
+	}
+}
+
+func TestStatusPendingContract(t *testing.T) {
+	status := &migrations.Status{
+		Pending: []migrations.Migration{
+			{Version: 3, Name: "add_page_count", Phase: migrations.PhaseExpand},
+			{Version: 4, Name: "drop_legacy_hash", Phase: migrations.PhaseContract},
+		},
+	}
+
+	contract := status.PendingContract()
+	assert.Len(t, contract, 1)
+	assert.Equal(t, uint(4), contract[0].Version)
+}
+
+func TestStatusCheckOnlyWarnsOnPendingContract(t *testing.T) {
+	expand := migrations.Migration{Version: 3, Name: "add_page_count", Phase: migrations.PhaseExpand}
+	contract := migrations.Migration{Version: 4, Name: "drop_legacy_hash", Phase: migrations.PhaseContract}
+	later := migrations.Migration{Version: 5, Name: "add_checksum", Phase: migrations.PhaseExpand}
+
+	// The previous release may still be serving until the rollout completes
+	status := &migrations.Status{Version: 3, Pending: []migrations.Migration{contract}}
+	assert.NoError(t, status.Check())
+
+	status = &migrations.Status{Version: 2, Pending: []migrations.Migration{expand, contract}}
+	assert.ErrorIs(t, status.Check(), migrations.ErrPending)
+
+	// An expand migration queued behind a contract cannot be applied at startup
+	status = &migrations.Status{Version: 3, Pending: []migrations.Migration{contract, later}}
+	assert.ErrorIs(t, status.Check(), migrations.ErrBlockedByContract)
+
+	status = &migrations.Status{Version: 4, Dirty: true}
+	assert.ErrorIs(t, status.Check(), migrations.ErrDirty)
+
+	status = &migrations.Status{Version: 5}
+	assert.NoError(t, status.Check())
+
+	// A release must not serve on a schema it does not know
+	status = &migrations.Status{Version: 6, AheadOfBinary: true}
+	assert.ErrorIs(t, status.Check(), migrations.ErrAheadOfBinary)
+}
@@ -0,0 +1,256 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.24.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// memoryBucket is an S3 endpoint keeping objects in memory and serving them
+// with range support
+type memoryBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (b *memoryBucket) s3(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	if key == "" {
+		if r.URL.Query().Has("location") {
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+		}
+		// The bucket exists
+		return
+	}
+	key = bucket + "/" + key
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err == nil && strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+			body, err = decodeAWSChunked(body)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b.mu.Lock()
+		b.objects[key] = body
+		b.mu.Unlock()
+		w.Header().Set("ETag", `"`+md5Hex(body)+`"`)
+	case http.MethodGet, http.MethodHead:
+		content, ok := b.object(key)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>Not found</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("ETag", `"`+md5Hex(content)+`"`)
+		http.ServeContent(w, r, key, time.Now(), bytes.NewReader(content))
+	case http.MethodDelete:
+		b.mu.Lock()
+		delete(b.objects, key)
+		b.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (b *memoryBucket) object(key string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	content, ok := b.objects[key]
+	return content, ok
+}
+
+func (b *memoryBucket) corrupt(key string, offset int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key][offset] ^= 0xff
+}
+
+// replace overwrites an object behind the service's back
+func (b *memoryBucket) replace(key string, content []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if content == nil {
+		delete(b.objects, key)
+		return
+	}
+	b.objects[key] = content
+}
+
+// newRenditionStorage returns a storage service on a memory bucket, sealing
+// with a random data key
+func newRenditionStorage(t testing.TB) (*memoryBucket, *services.StorageService) {
+	return newMemoryStorage(t, &config.Config{})
+}
+
+// newMemoryStorage returns a storage service on a memory bucket configured in
+// cfg, sealing with a random data key under key version v1
+func newMemoryStorage(t testing.TB, cfg *config.Config) (*memoryBucket, *services.StorageService) {
+	bucket := &memoryBucket{objects: make(map[string][]byte)}
+	s3 := httptest.NewServer(http.HandlerFunc(bucket.s3))
+	t.Cleanup(s3.Close)
+
+	cfg.MinioConfig.Endpoint = strings.TrimPrefix(s3.URL, "http://")
+	cfg.MinioConfig.BucketName = "documents"
+	cfg.MinioConfig.AccessKey = "AK1"
+	cfg.MinioConfig.SecretKey = "secret"
+	cfg.MinioConfig.Transport = config.HTTPTransportConfig{DialTimeout: time.Second, TLSHandshakeTimeout: time.Second}
+	cfg.MinioConfig.UploadTimeout = 5 * time.Second
+	cfg.MinioConfig.DownloadTimeout = 5 * time.Second
+	cfg.SecurityConfig.EncryptionKey = "alias/ocr-text"
+	cfg.SecurityConfig.KeyVersion = "v1"
+	cfg.SecurityConfig.KeyRotationInterval = 90 * 24 * time.Hour
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	assert.NoError(t, utils.UseDataKey(cfg, "arn:aws:kms:us-east-1:000000000000:key/ocr-text", key))
+
+	storage, err := services.NewStorageService(context.Background(), cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	return bucket, storage
+}
+
+// textProvider is an OCR provider reading the same text from every document
+type textProvider struct {
+	text string
+}
+
+func (p *textProvider) Provider() string { return "text" }
+
+func (p *textProvider) Recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error) {
+	return []models.OCRLine{{Text: p.text}}, nil
+}
+
+// longOCRText returns text spanning several encryption chunks, with
+// multi-byte characters so the preview is cut on a character boundary
+func longOCRText() string {
+	var text strings.Builder
+	for i := 0; text.Len() < 200<<10; i++ {
+		fmt.Fprintf(&text, "Linha %06d: João da Conceição, CPF 123.456.789-09\n", i)
+	}
+	return text.String()
+}
+
+// runOCRTextStep runs the OCR step on an identity scan whose text is read as text
+func runOCRTextStep(t *testing.T, storage *services.StorageService, text string) *models.Document {
+	service := newPageOCRService(t, 2, 10)
+	step := services.NewProviderOCRStep(service, &textProvider{text: text}, storage)
+	doc := &models.Document{ID: "doc-ocr", DocumentType: "identity", ContentType: "image/jpeg", Status: models.DocumentStatusPending}
+	assert.True(t, step.Applies(doc))
+	assert.NoError(t, step.Execute(context.Background(), &services.PipelineRun{Document: doc, Content: []byte("scan")}))
+	return doc
+}
+
+func TestOCRTextStoredAsEncryptedRendition(t *testing.T) {
+	bucket, storage := newRenditionStorage(t)
+	text := longOCRText()
+	doc := runOCRTextStep(t, storage, text)
+	recognized := text + "\n"
+
+	assert.Equal(t, string([]rune(recognized)[:models.OCRPreviewLength]), doc.OCRPreview, "Only a preview of the text should be kept on the document")
+	metadata, err := json.Marshal(doc)
+	assert.NoError(t, err)
+	assert.Less(t, len(metadata), 16<<10, "The full text should not be kept in the document metadata")
+
+	rendition, ok := doc.Rendition(models.RenditionOCRText)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, int64(len(recognized)), rendition.Size)
+	if assert.NotNil(t, rendition.Encryption) {
+		assert.Equal(t, models.EncryptionAlgorithmGCMStream, rendition.Encryption.Algorithm)
+	}
+	stored, ok := bucket.object("documents/" + rendition.StoragePath)
+	if assert.True(t, ok) {
+		assert.NotContains(t, string(stored), "CPF 123.456.789-09", "The text should not be stored in the clear")
+	}
+
+	_, err = storage.RenditionURL(context.Background(), rendition)
+	assert.ErrorIs(t, err, services.ErrPresignUnsupported, "An encrypted rendition cannot be downloaded from the bucket directly")
+
+	reader, info, err := storage.OpenRendition(context.Background(), doc.ID, rendition)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer reader.Close()
+	assert.Equal(t, rendition.Size, info.Size)
+	plaintext, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, recognized, string(plaintext))
+}
+
+func TestOCRTextRenditionServesRanges(t *testing.T) {
+	_, storage := newRenditionStorage(t)
+	text := longOCRText()
+	doc := runOCRTextStep(t, storage, text)
+	rendition, ok := doc.Rendition(models.RenditionOCRText)
+	if !assert.True(t, ok) {
+		return
+	}
+
+	reader, _, err := storage.OpenRendition(context.Background(), doc.ID, rendition)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer reader.Close()
+
+	// The range crosses the boundary between the first two chunks
+	start, end := (64<<10)-10, (64<<10)+20
+	req := httptest.NewRequest(http.MethodGet, "/text", nil)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	rec := httptest.NewRecorder()
+	http.ServeContent(rec, req, "ocr.txt", time.Time{}, reader)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, text[start:end+1], rec.Body.String())
+}
+
+func TestOCRTextRenditionRejectsTamperedChunk(t *testing.T) {
+	bucket, storage := newRenditionStorage(t)
+	doc := runOCRTextStep(t, storage, longOCRText())
+	rendition, ok := doc.Rendition(models.RenditionOCRText)
+	if !assert.True(t, ok) {
+		return
+	}
+	// Flip a byte in the second chunk
+	bucket.corrupt("documents/"+rendition.StoragePath, (64<<10)+100)
+
+	reader, _, err := storage.OpenRendition(context.Background(), doc.ID, rendition)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer reader.Close()
+
+	head := make([]byte, 1024)
+	_, err = io.ReadFull(reader, head)
+	assert.NoError(t, err, "Chunks before the tampered one should still decrypt")
+	_, err = reader.Seek(64<<10, io.SeekStart)
+	assert.NoError(t, err)
+	_, err = reader.Read(head)
+	assert.ErrorIs(t, err, utils.ErrDecryptionFailed)
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestBulkOperationFilter(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	doc.TenantID = "tenant-a"
+	doc.IngestionChannel = "sftp"
+
+	assert.True(t, models.BulkOperationFilter{}.Empty())
+	assert.True(t, models.BulkOperationFilter{DocumentTypes: []string{"identity", "medical_record"}}.Matches(doc))
+	assert.True(t, models.BulkOperationFilter{TenantID: "tenant-a", Channel: "sftp", Statuses: []string{doc.Status}}.Matches(doc))
+	assert.False(t, models.BulkOperationFilter{TenantID: "tenant-a", Channel: "web"}.Matches(doc), "Every criterion must match")
+	assert.False(t, models.BulkOperationFilter{DocumentIDs: []string{"doc-2"}}.Matches(doc))
+
+	from := doc.CreatedAt.Add(-time.Hour)
+	to := doc.CreatedAt
+	assert.True(t, models.BulkOperationFilter{CreatedFrom: &from}.Matches(doc))
+	assert.False(t, models.BulkOperationFilter{CreatedFrom: &from, CreatedTo: &to}.Matches(doc), "The creation period excludes its end")
+}
+
+func TestBulkOperationProgress(t *testing.T) {
+	ctx := context.Background()
+	operations := repository.NewMemoryBulkOperationRepository()
+	op := &models.BulkOperation{ID: "op-1", Action: models.BulkActionRecomputeHash, Status: models.BulkOperationRunning, Total: 150, CreatedAt: time.Now()}
+	assert.NoError(t, operations.Create(ctx, op))
+
+	for i := 0; i < 150; i++ {
+		op.Record("doc", models.BulkOutcomeFailed, errors.New("storage unavailable"), time.Now())
+	}
+	op.Record("doc", models.BulkOutcomeSkipped, nil, time.Now())
+	assert.NoError(t, operations.Update(ctx, op))
+
+	stored, err := operations.Get(ctx, "op-1")
+	assert.NoError(t, err)
+	assert.False(t, stored.Finished())
+	assert.Equal(t, 151, stored.Processed)
+	assert.Equal(t, 150, stored.Failed)
+	assert.Equal(t, 1, stored.Skipped)
+	assert.Len(t, stored.Failures, 100, "Only the first failures are kept")
+
+	op.Finish(models.BulkOperationCancelled, "cancelled by operator", time.Now())
+	assert.NoError(t, operations.Update(ctx, op))
+	stored, err = operations.Get(ctx, "op-1")
+	assert.NoError(t, err)
+	assert.True(t, stored.Finished())
+	assert.NotNil(t, stored.FinishedAt)
+
+	_, err = operations.Get(ctx, "op-2")
+	assert.ErrorIs(t, err, repository.ErrOperationNotFound)
+}
+
+func TestBulkOperationCheckpoint(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := models.BulkOperationPosition{DocumentID: "doc-b", CreatedAt: created}
+	tied := models.BulkOperationPosition{DocumentID: "doc-c", CreatedAt: created}
+	later := models.BulkOperationPosition{DocumentID: "doc-a", CreatedAt: created.Add(time.Second)}
+
+	assert.True(t, first.Before(tied), "Documents created together are ordered by ID")
+	assert.True(t, tied.Before(later), "Documents are ordered by creation time first")
+	assert.False(t, later.Before(first))
+	assert.False(t, first.Before(first))
+
+	op := &models.BulkOperation{ID: "op-1", Action: models.BulkActionBackfill, Status: models.BulkOperationRunning, Total: 3, CreatedAt: created}
+	assert.False(t, op.Resumable(), "A running operation cannot be resumed")
+	op.Record(first.DocumentID, models.BulkOutcomeSucceeded, nil, time.Now())
+	op.Checkpoint = &first
+	op.Finish(models.BulkOperationCancelled, "service stopped", time.Now())
+	assert.True(t, op.Resumable())
+
+	op.Resume(4, time.Now())
+	assert.False(t, op.Finished())
+	assert.Nil(t, op.FinishedAt)
+	assert.Empty(t, op.Error)
+	assert.Equal(t, 5, op.Total, "Documents created since the cancellation are included")
+	assert.Equal(t, 1, op.Succeeded, "Counts carry on from before the cancellation")
+
+	op.Finish(models.BulkOperationCompleted, "", time.Now())
+	assert.False(t, op.Resumable(), "A completed operation has nothing left to resume")
+	dryRun := &models.BulkOperation{Status: models.BulkOperationCancelled, DryRun: true}
+	assert.False(t, dryRun.Resumable())
+}
+
+func TestProcessedOnlyCountsSuccesses(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "image/jpeg", 1024)
+	assert.NoError(t, err)
+
+	assert.False(t, doc.Processed("ocr"))
+	doc.RecordProcessing(models.ProcessingActivity{Operation: "ocr", Outcome: models.ProcessingOutcomeFailed})
+	assert.False(t, doc.Processed("ocr"), "A failed step is backfilled again")
+	doc.RecordProcessing(models.ProcessingActivity{Operation: "ocr", Outcome: models.ProcessingOutcomeSucceeded})
+	assert.True(t, doc.Processed("ocr"))
+	assert.False(t, doc.Processed("search_index"))
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// scannedPage draws a page of text-like lines from a seed, with the dark
+// border a scanner leaves and a sprinkle of dust
+func scannedPage(seed int64, lines int, dust int) *image.RGBA {
+	page := image.NewRGBA(image.Rect(0, 0, 300, 420))
+	draw.Draw(page, page.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(0, 0, 300, 8), image.Black, image.Point{}, draw.Src)
+
+	random := rand.New(rand.NewSource(seed))
+	for line := 0; line < lines; line++ {
+		y := 40 + line*14
+		for x := 30; x < 270; {
+			word := 10 + random.Intn(30)
+			draw.Draw(page, image.Rect(x, y, min(x+word, 270), y+7), image.Black, image.Point{}, draw.Src)
+			x += word + 6
+		}
+	}
+
+	noise := rand.New(rand.NewSource(seed + 1000))
+	for i := 0; i < dust; i++ {
+		page.Set(20+noise.Intn(260), 30+noise.Intn(360), color.Black)
+	}
+	return page
+}
+
+func TestCleanupPagesRemovesBlankAndDuplicatePages(t *testing.T) {
+	cfg := config.PageCleanupConfig{BlankInkRatio: 0.002, DuplicateDistance: 6}
+
+	prints := []services.PageFingerprint{
+		services.FingerprintPage(scannedPage(1, 24, 0)),
+		services.FingerprintPage(scannedPage(2, 0, 40)),
+		services.FingerprintPage(scannedPage(3, 20, 0)),
+		// Page 1 scanned again, with dust
+		services.FingerprintPage(scannedPage(1, 24, 60)),
+		services.FingerprintPage(scannedPage(4, 24, 0)),
+	}
+	assert.Less(t, prints[1].InkRatio, cfg.BlankInkRatio, "Border and dust should not count as content")
+	assert.Greater(t, prints[0].Hash.Distance(prints[4].Hash), cfg.DuplicateDistance)
+
+	assert.Equal(t, []models.RemovedPage{
+		{Number: 2, Reason: models.PageRemovalBlank},
+		{Number: 4, Reason: models.PageRemovalDuplicate, DuplicateOf: 1},
+	}, services.CleanupPages(cfg, prints))
+
+	// A document of blank pages is left whole
+	blank := []services.PageFingerprint{prints[1], prints[1]}
+	assert.Empty(t, services.CleanupPages(cfg, blank))
+
+	doc := &models.Document{ID: "doc-1", Status: models.DocumentStatusProcessing}
+	doc.RecordPageCleanup(5, services.CleanupPages(cfg, prints))
+	assert.Len(t, doc.RemovedPages, 2)
+	_, ok := doc.CurrentPDF()
+	assert.False(t, ok)
+
+	doc.SetRendition(models.Rendition{Name: models.RenditionNormalizedPDF})
+	current, ok := doc.CurrentPDF()
+	assert.True(t, ok)
+	assert.Equal(t, models.RenditionNormalizedPDF, current.Name)
+	doc.SetRendition(models.Rendition{Name: models.RenditionSplicedPDF})
+	current, _ = doc.CurrentPDF()
+	assert.Equal(t, models.RenditionSplicedPDF, current.Name)
+}
@@ -0,0 +1,146 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"   // v0.6.0
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// pageProvider is an OCR provider reading each page as "page N", where N
+// comes from the page width, and failing the pages in fail
+type pageProvider struct {
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	fail     map[int]bool
+}
+
+func (p *pageProvider) Provider() string { return "pages" }
+
+func (p *pageProvider) Recognize(ctx context.Context, tenant string, content []byte) ([]models.OCRLine, error) {
+	p.mu.Lock()
+	p.inFlight++
+	if p.inFlight > p.peak {
+		p.peak = p.inFlight
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.inFlight--
+		p.mu.Unlock()
+	}()
+	// Hold the call so pages overlap
+	time.Sleep(20 * time.Millisecond)
+
+	dims, err := api.PageDims(bytes.NewReader(content), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(dims) != 1 {
+		return nil, fmt.Errorf("expected a single page, got %d", len(dims))
+	}
+	page := int(dims[0].Width+0.5) / 100
+	if p.fail[page] {
+		return nil, errors.New("unreadable page")
+	}
+	return []models.OCRLine{{Text: fmt.Sprintf("page %d", page)}}, nil
+}
+
+func (p *pageProvider) peakInFlight() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peak
+}
+
+// multiPagePDF returns a PDF of count pages where page N is N*100 points wide
+func multiPagePDF(t *testing.T, count int) []byte {
+	pages := make([]image.Image, count)
+	for i := range pages {
+		pages[i] = image.NewRGBA(image.Rect(0, 0, 100*(i+1), 200))
+	}
+	pdf, err := services.BuildSearchablePDF(pages, 72, 90, nil, "pages.pdf")
+	assert.NoError(t, err)
+	return pdf
+}
+
+func newPageOCRService(t *testing.T, maxConcurrentPages, maxPages int) *services.OCRService {
+	cfg := &config.Config{}
+	cfg.AzureConfig.Endpoint = "https://ocr.invalid"
+	cfg.AzureConfig.SubscriptionKey = "key"
+	cfg.AzureConfig.OCRTimeout = time.Second
+	cfg.AzureConfig.MaxDocumentSize = 10 << 20
+	cfg.PageOCRConfig = config.PageOCRConfig{
+		Enabled:              true,
+		MaxConcurrentPages:   maxConcurrentPages,
+		PerTenantConcurrency: maxConcurrentPages,
+		MaxPages:             maxPages,
+	}
+	service, err := services.NewOCRService(cfg)
+	assert.NoError(t, err)
+	return service
+}
+
+func newPageDocument() *models.Document {
+	return &models.Document{ID: "doc-1", TenantID: "tenant-1", ContentType: "application/pdf", Status: models.DocumentStatusPending}
+}
+
+func TestPageOCRBoundsConcurrencyAndKeepsPageOrder(t *testing.T) {
+	service := newPageOCRService(t, 2, 10)
+	provider := &pageProvider{}
+	doc := newPageDocument()
+
+	result, err := service.ProcessDocumentWith(context.Background(), doc, multiPagePDF(t, 6), provider)
+	assert.NoError(t, err)
+	assert.Equal(t, "page 1\n\npage 2\n\npage 3\n\npage 4\n\npage 5\n\npage 6\n", result.Text)
+	if assert.Len(t, result.Lines, 6) {
+		for i, line := range result.Lines {
+			assert.Equal(t, i+1, line.Page)
+		}
+	}
+	assert.Equal(t, 2, provider.peakInFlight(), "Pages should be read in parallel up to the page concurrency")
+	assert.Len(t, doc.OCRPages, 6)
+	assert.Empty(t, doc.FailedOCRPages())
+	assert.False(t, doc.HasReviewFlag(models.ReviewFlagOCRIncomplete))
+	assert.Equal(t, models.DocumentStatusCompleted, doc.Status)
+}
+
+func TestPageOCRRecordsFailedPages(t *testing.T) {
+	service := newPageOCRService(t, 3, 4)
+	provider := &pageProvider{fail: map[int]bool{2: true}}
+	doc := newPageDocument()
+
+	result, err := service.ProcessDocumentWith(context.Background(), doc, multiPagePDF(t, 5), provider)
+	assert.NoError(t, err, "A document with readable pages should not fail")
+	assert.Equal(t, "page 1\n\npage 3\n\npage 4\n", result.Text)
+	assert.Equal(t, []int{2, 5}, doc.FailedOCRPages(), "The unreadable page and the page past the limit should fail")
+	if assert.Len(t, doc.OCRPages, 5) {
+		assert.Contains(t, doc.OCRPages[1].Error, "unreadable page")
+		assert.Contains(t, doc.OCRPages[4].Error, "page limit of 4 exceeded")
+	}
+	assert.True(t, doc.HasReviewFlag(models.ReviewFlagOCRIncomplete))
+	assert.Equal(t, models.DocumentStatusCompleted, doc.Status)
+}
+
+func TestPageOCRFailsWhenNoPageIsRead(t *testing.T) {
+	service := newPageOCRService(t, 2, 10)
+	provider := &pageProvider{fail: map[int]bool{1: true, 2: true, 3: true}}
+	doc := newPageDocument()
+
+	_, err := service.ProcessDocumentWith(context.Background(), doc, multiPagePDF(t, 3), provider)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "all 3 pages failed")
+	assert.Equal(t, []int{1, 2, 3}, doc.FailedOCRPages())
+	assert.Equal(t, models.DocumentStatusFailed, doc.Status)
+}
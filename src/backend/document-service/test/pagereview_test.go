@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestPageReviewWithRescans(t *testing.T) {
+	doc := &models.Document{ID: "doc-1", EnrollmentID: "enr-1", DocumentType: "medical_record", Status: models.DocumentStatusCompleted}
+
+	decisions := make([]models.PageDecision, 0, 30)
+	for page := 1; page <= 30; page++ {
+		decisions = append(decisions, models.PageDecision{Number: page, Decision: models.PageDecisionApprove})
+	}
+	decisions[3] = models.PageDecision{Number: 4, Decision: models.PageDecisionRescan, Reason: "blurred"}
+	decisions[17] = models.PageDecision{Number: 18, Decision: models.PageDecisionRescan, Reason: "cut off"}
+
+	assert.NoError(t, doc.ReviewPages(30, decisions, "reviewer-1"))
+	assert.Equal(t, models.DocumentStatusPartiallyApproved, doc.Status)
+	assert.Equal(t, 28, doc.ApprovedPages())
+	assert.Equal(t, []int{4, 18}, doc.PagesAwaitingRescan())
+	assert.ErrorIs(t, doc.Review(models.ReviewDecisionApprove, "", "reviewer-1"), models.ErrPagesPending)
+
+	// Only pages awaiting a rescan may be replaced
+	assert.ErrorIs(t, doc.RecordRescan(5, "hash", "member"), models.ErrPageNotAwaitingRescan)
+	assert.ErrorIs(t, doc.RecordRescan(31, "hash", "member"), models.ErrInvalidPage)
+	assert.NoError(t, doc.RecordRescan(4, "hash-4", "member"))
+	assert.NoError(t, doc.RecordRescan(18, "hash-18", "member"))
+	assert.Empty(t, doc.PagesAwaitingRescan())
+	assert.Equal(t, []int{4, 18}, doc.PendingPages(), "Rescanned pages should await review again")
+
+	assert.ErrorIs(t, doc.ReviewPages(30, []models.PageDecision{{Number: 4, Decision: models.PageDecisionRescan}}, "reviewer-1"), models.ErrMissingField)
+	assert.ErrorIs(t, doc.ReviewPages(30, []models.PageDecision{{Number: 0, Decision: models.PageDecisionApprove}}, "reviewer-1"), models.ErrInvalidPage)
+
+	assert.NoError(t, doc.ReviewPages(30, []models.PageDecision{
+		{Number: 4, Decision: models.PageDecisionApprove},
+		{Number: 18, Decision: models.PageDecisionApprove},
+	}, "reviewer-2"))
+	assert.Equal(t, models.DocumentStatusApproved, doc.Status)
+	assert.Equal(t, 1, doc.PageReviews[3].Rescans)
+}
+
+func TestEnrollmentChecklistWithPartialApproval(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.UnderwritingConfig.RequiredDocumentTypes = []string{"id_card", "medical_record", "proof_of_address"}
+
+	documents := repository.NewMemoryDocumentRepository()
+	underwriting, err := services.NewUnderwritingService(cfg, documents, repository.NewMemoryOutboxRepository(), services.NewRESTUnderwritingTransport(cfg), zap.NewNop())
+	assert.NoError(t, err)
+
+	id := &models.Document{ID: "doc-1", EnrollmentID: "enr-1", DocumentType: "id_card", Status: models.DocumentStatusApproved}
+	rejected := &models.Document{ID: "doc-2", EnrollmentID: "enr-1", DocumentType: "medical_record", Status: models.DocumentStatusRejected}
+	partial := &models.Document{ID: "doc-3", EnrollmentID: "enr-1", DocumentType: "medical_record", Status: models.DocumentStatusCompleted}
+	assert.NoError(t, partial.ReviewPages(3, []models.PageDecision{
+		{Number: 1, Decision: models.PageDecisionApprove},
+		{Number: 2, Decision: models.PageDecisionRescan, Reason: "illegible"},
+		{Number: 3, Decision: models.PageDecisionApprove},
+	}, "reviewer-1"))
+	for _, doc := range []*models.Document{id, rejected, partial} {
+		assert.NoError(t, documents.Create(ctx, doc))
+	}
+
+	checklist, err := underwriting.Checklist(ctx, "enr-1")
+	assert.NoError(t, err)
+	assert.False(t, checklist.Complete)
+	if assert.Len(t, checklist.Items, 3) {
+		assert.Equal(t, models.ChecklistApproved, checklist.Items[0].Status)
+
+		medical := checklist.Items[1]
+		assert.Equal(t, models.ChecklistPartiallyApproved, medical.Status, "The most advanced document of a type should be reported")
+		assert.Equal(t, "doc-3", medical.DocumentID)
+		assert.Equal(t, 2, medical.ApprovedPages)
+		assert.Equal(t, 3, medical.TotalPages)
+		assert.Equal(t, []int{2}, medical.RescanPages)
+
+		assert.Equal(t, models.ChecklistMissing, checklist.Items[2].Status)
+	}
+}
+
+func TestPageOperationsKeepPageReviews(t *testing.T) {
+	layout, err := models.PageLayout(4, []models.PageOperation{
+		{Operation: models.PageOperationRotate, Pages: []int{2}, Degrees: 270},
+		{Operation: models.PageOperationRotate, Pages: []int{2, 3}, Degrees: 180},
+		{Operation: models.PageOperationReorder, Order: []int{4, 3, 2, 1}},
+		{Operation: models.PageOperationRemove, Pages: []int{1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.LayoutPage{{Source: 3, Rotation: 180}, {Source: 2, Rotation: 90}, {Source: 1}}, layout)
+
+	for _, ops := range [][]models.PageOperation{
+		{{Operation: models.PageOperationRotate, Pages: []int{1}, Degrees: 45}},
+		{{Operation: models.PageOperationRotate, Pages: []int{5}, Degrees: 90}},
+		{{Operation: models.PageOperationReorder, Order: []int{1, 1, 2, 3}}},
+		{{Operation: models.PageOperationReorder, Order: []int{1, 2}}},
+		{{Operation: models.PageOperationRemove, Pages: []int{1, 2, 3, 4}}},
+		{{Operation: models.PageOperationRemove, Pages: []int{4}}, {Operation: models.PageOperationRotate, Pages: []int{4}, Degrees: 90}},
+		{{Operation: "crop", Pages: []int{1}}},
+	} {
+		_, err := models.PageLayout(4, ops)
+		assert.ErrorIs(t, err, models.ErrInvalidPageOperation)
+	}
+	_, err = models.PageLayout(4, nil)
+	assert.ErrorIs(t, err, models.ErrMissingField)
+
+	doc := &models.Document{ID: "doc-1", DocumentType: "medical_record", Status: models.DocumentStatusCompleted}
+	assert.NoError(t, doc.ReviewPages(4, []models.PageDecision{
+		{Number: 1, Decision: models.PageDecisionApprove},
+		{Number: 3, Decision: models.PageDecisionRescan, Reason: "blurred"},
+	}, "reviewer-1"))
+	assert.NoError(t, doc.RecordPageOperations(nil, layout, "reviewer-1"))
+	if assert.Len(t, doc.PageReviews, 3) {
+		assert.Equal(t, models.PageStatusRescanRequested, doc.PageReviews[0].Status, "Page 3 moved to the front keeps its decision")
+		assert.Equal(t, 1, doc.PageReviews[0].Number)
+		assert.Equal(t, models.PageStatusApproved, doc.PageReviews[2].Status)
+		assert.Equal(t, 3, doc.PageReviews[2].Number)
+	}
+	assert.Equal(t, []int{1}, doc.PagesAwaitingRescan())
+
+	doc.Status = models.DocumentStatusApproved
+	assert.ErrorIs(t, doc.RecordPageOperations(nil, layout, "reviewer-1"), models.ErrNotEditable)
+}
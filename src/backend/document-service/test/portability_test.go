@@ -0,0 +1,244 @@
+//go:build testhooks
+
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const (
+	portabilitySubject = "52998224725"
+	portabilityOther   = "11144477735"
+)
+
+// portabilityFixture is a portability API over a memory bucket holding the
+// documents of a subject, with the audit log captured
+type portabilityFixture struct {
+	router   *gin.Engine
+	audit    *observer.ObservedLogs
+	identity []byte
+}
+
+// newPortabilityFixture stores an identity scan and a document whose content
+// is gone for the subject, and a document of another beneficiary listed under
+// an enrollment the enrollment service wrongly returns for the subject
+func newPortabilityFixture(t *testing.T, linkTTL time.Duration) *portabilityFixture {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	_, storage := newRenditionStorage(t)
+
+	enrollmentService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/beneficiaries/"+portabilitySubject+"/enrollments" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]models.Enrollment{
+			{ID: "enr-subject", Status: "active", BeneficiaryCPF: "529.982.247-25"},
+			{ID: "enr-other", Status: "active", BeneficiaryCPF: portabilityOther},
+		})
+	}))
+	t.Cleanup(enrollmentService.Close)
+
+	cfg := &config.Config{}
+	cfg.EnrollmentConfig.BaseURL = enrollmentService.URL
+	cfg.EnrollmentConfig.Timeout = time.Second
+	cfg.PortabilityConfig.Enabled = true
+	cfg.PortabilityConfig.LinkSigningKey = strings.Repeat("k", 32)
+	cfg.PortabilityConfig.LinkTTL = linkTTL
+	enrollments, err := services.NewEnrollmentClient(cfg)
+	assert.NoError(t, err)
+
+	fixture := &portabilityFixture{identity: []byte("%PDF-1.7 identity of the subject")}
+	documents := repository.NewMemoryDocumentRepository()
+	addDocument := func(id, enrollmentID, filename string, content []byte) {
+		doc, err := models.NewDocument(enrollmentID, "identity", filename, "application/pdf", int64(len(content)))
+		assert.NoError(t, err)
+		doc.ID = id
+		if content != nil {
+			assert.NoError(t, storage.StoreDocument(ctx, doc, bytes.NewReader(content)))
+		}
+		assert.NoError(t, documents.Create(ctx, doc))
+	}
+	addDocument("doc-identity", "enr-subject", "../../rg.pdf", fixture.identity)
+	addDocument("doc-expired", "enr-subject", "address.pdf", nil)
+	addDocument("doc-other", "enr-other", "other.pdf", []byte("%PDF-1.7 another beneficiary"))
+
+	core, logs := observer.New(zap.InfoLevel)
+	fixture.audit = logs
+	portability, err := services.NewPortabilityService(cfg, documents, storage, enrollments, zap.New(core))
+	assert.NoError(t, err)
+	handler, err := handlers.NewPortabilityHandler(portability, zap.New(core))
+	assert.NoError(t, err)
+
+	fixture.router = gin.New()
+	fixture.router.POST("/api/v1/subjects/:cpf/portability-export", func(c *gin.Context) {
+		c.Set("user_id", "dpo-1")
+	}, handler.CreateExport)
+	fixture.router.GET("/api/v1/exports/:id/download", handler.DownloadExport)
+	return fixture
+}
+
+func (f *portabilityFixture) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// export requests the export of a subject and returns its download link
+func (f *portabilityFixture) export(t *testing.T, cpf string) string {
+	rec := f.serve(httptest.NewRequest(http.MethodPost, "/api/v1/subjects/"+cpf+"/portability-export", nil))
+	if !assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String()) {
+		return ""
+	}
+	var body struct {
+		Data struct {
+			DocumentCount int    `json:"document_count"`
+			URL           string `json:"url"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, 2, body.Data.DocumentCount, "Only the documents of the subject should be exported")
+	return body.Data.URL
+}
+
+// auditMessages returns the messages of the audit entries written so far
+func (f *portabilityFixture) auditMessages() []string {
+	messages := make([]string, 0)
+	for _, entry := range f.audit.All() {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+func TestPortabilityExportArchivesSubjectDocuments(t *testing.T) {
+	fixture := newPortabilityFixture(t, time.Hour)
+	link := fixture.export(t, "529.982.247-25")
+
+	rec := fixture.serve(httptest.NewRequest(http.MethodGet, link, nil))
+	if !assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String()) {
+		return
+	}
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	archive, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		files[file.Name], err = io.ReadAll(reader)
+		assert.NoError(t, err)
+		reader.Close()
+	}
+	assert.Len(t, files, 2, "The archive should hold the stored document and the manifest")
+	assert.Equal(t, fixture.identity, files["documents/enr-subject/doc-identity/rg.pdf"], "File names should not escape the document folder")
+
+	var manifest models.PortabilityManifest
+	if !assert.NoError(t, json.Unmarshal(files["manifest.json"], &manifest)) {
+		return
+	}
+	assert.Equal(t, models.PortabilityManifestVersion, manifest.Version)
+	assert.Equal(t, portabilitySubject, manifest.SubjectCPF)
+	if !assert.Len(t, manifest.Enrollments, 1) || !assert.Len(t, manifest.Enrollments[0].Documents, 2) {
+		return
+	}
+	assert.Equal(t, "enr-subject", manifest.Enrollments[0].EnrollmentID)
+	sum := sha256.Sum256(fixture.identity)
+	identity, expired := manifest.Enrollments[0].Documents[0], manifest.Enrollments[0].Documents[1]
+	if identity.DocumentID != "doc-identity" {
+		identity, expired = expired, identity
+	}
+	assert.Equal(t, "documents/enr-subject/doc-identity/rg.pdf", identity.ArchivePath)
+	assert.Equal(t, hex.EncodeToString(sum[:]), identity.SHA256)
+	assert.Empty(t, expired.ArchivePath, "A document whose content is gone should be listed without a file")
+	assert.NotContains(t, string(files["manifest.json"]), "storage_path")
+
+	// The archive is served by range
+	req := httptest.NewRequest(http.MethodGet, link, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	rec = fixture.serve(req)
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "PK\x03\x04", rec.Body.String())
+}
+
+func TestPortabilityExportIsAudited(t *testing.T) {
+	fixture := newPortabilityFixture(t, time.Hour)
+	link := fixture.export(t, portabilitySubject)
+	fixture.serve(httptest.NewRequest(http.MethodGet, link, nil))
+
+	assert.Equal(t, []string{"Portability export created", "Portability export downloaded"}, fixture.auditMessages())
+	created := fixture.audit.FilterMessage("Portability export created").All()
+	if assert.Len(t, created, 1) {
+		fields := created[0].ContextMap()
+		assert.Equal(t, "***.982.247-**", fields["subject"], "The subject should be masked in the audit log")
+		assert.Equal(t, "dpo-1", fields["requested_by"])
+		assert.Equal(t, int64(2), fields["document_count"])
+	}
+	for _, entry := range fixture.audit.All() {
+		for _, value := range entry.ContextMap() {
+			if text, ok := value.(string); ok {
+				assert.NotContains(t, text, portabilitySubject)
+			}
+		}
+	}
+}
+
+func TestPortabilityExportLinkIsTimeLimited(t *testing.T) {
+	fixture := newPortabilityFixture(t, time.Hour)
+	link := fixture.export(t, portabilitySubject)
+	parsed, err := url.Parse(link)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Extending the link invalidates its signature
+	query := parsed.Query()
+	query.Set("expires", "99999999999")
+	rec := fixture.serve(httptest.NewRequest(http.MethodGet, parsed.Path+"?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = fixture.serve(httptest.NewRequest(http.MethodGet, parsed.Path, nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code, "A link without a signature should be refused")
+
+	expired := newPortabilityFixture(t, -time.Minute)
+	rec = expired.serve(httptest.NewRequest(http.MethodGet, expired.export(t, portabilitySubject), nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code, "An expired link should be refused")
+	assert.NotContains(t, expired.auditMessages(), "Portability export downloaded")
+}
+
+func TestPortabilityExportRejectsUnknownSubjects(t *testing.T) {
+	fixture := newPortabilityFixture(t, time.Hour)
+
+	rec := fixture.serve(httptest.NewRequest(http.MethodPost, "/api/v1/subjects/12345678900/portability-export", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "An invalid CPF should be rejected")
+
+	rec = fixture.serve(httptest.NewRequest(http.MethodPost, "/api/v1/subjects/"+portabilityOther+"/portability-export", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, fixture.auditMessages(), "Portability export created")
+}
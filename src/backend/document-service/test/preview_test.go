@@ -0,0 +1,121 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const testPreviewKey = "0123456789abcdef0123456789abcdef"
+
+func newTestPreviewTokens(key, previous string, ttl time.Duration) *services.PreviewTokens {
+	cfg := &config.Config{}
+	cfg.PreviewConfig.SigningKey = key
+	cfg.PreviewConfig.PreviousSigningKey = previous
+	cfg.PreviewConfig.TokenTTL = ttl
+	return services.NewPreviewTokens(cfg)
+}
+
+func TestPreviewTokens(t *testing.T) {
+	tokens := newTestPreviewTokens(testPreviewKey, "", time.Minute)
+
+	token, _, err := tokens.Mint("doc-1", "preview", "user-1", "10.0.0.1", true)
+	assert.NoError(t, err)
+
+	claims, err := tokens.Verify(token, "doc-1", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "preview", claims.Rendition)
+	assert.Equal(t, "user-1", claims.Subject)
+
+	_, err = tokens.Verify(token, "doc-2", "10.0.0.1")
+	assert.ErrorIs(t, err, services.ErrPreviewTokenInvalid, "Tokens should be scoped to one document")
+
+	_, err = tokens.Verify(token, "doc-1", "10.0.0.2")
+	assert.ErrorIs(t, err, services.ErrPreviewTokenInvalid, "IP bound tokens should reject other clients")
+
+	payload, signature, _ := strings.Cut(token, ".")
+	_, err = tokens.Verify(payload+"x."+signature, "doc-1", "10.0.0.1")
+	assert.ErrorIs(t, err, services.ErrPreviewTokenInvalid, "Tampered tokens should be rejected")
+
+	again, _, err := tokens.Mint("doc-1", "preview", "user-1", "10.0.0.1", true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, again, "Every token should carry a unique random ID")
+
+	_, err = tokens.Verify(token+strings.Repeat("A", 1024), "doc-1", "10.0.0.1")
+	assert.ErrorIs(t, err, services.ErrPreviewTokenInvalid, "Oversized tokens should be rejected")
+}
+
+func TestPreviewTokenExpiryAndRotation(t *testing.T) {
+	expired := newTestPreviewTokens(testPreviewKey, "", -time.Second)
+	token, _, err := expired.Mint("doc-1", "preview", "", "", false)
+	assert.NoError(t, err)
+	_, err = expired.Verify(token, "doc-1", "")
+	assert.ErrorIs(t, err, services.ErrPreviewTokenExpired)
+
+	old := newTestPreviewTokens(testPreviewKey, "", time.Minute)
+	token, _, err = old.Mint("doc-1", "preview", "", "", false)
+	assert.NoError(t, err)
+
+	rotated := newTestPreviewTokens(strings.Repeat("k", 32), testPreviewKey, time.Minute)
+	_, err = rotated.Verify(token, "doc-1", "10.0.0.9")
+	assert.NoError(t, err, "Tokens signed with the previous key should verify during rotation")
+
+	disabled := newTestPreviewTokens("", "", time.Minute)
+	_, _, err = disabled.Mint("doc-1", "preview", "", "", false)
+	assert.ErrorIs(t, err, services.ErrPreviewDisabled)
+}
+
+// newTestPreviewRouter serves a preview route that accepts a token bound to
+// the client IP, behind the trusted proxies
+func newTestPreviewRouter(t *testing.T, tokens *services.PreviewTokens, trustedProxies []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.SecurityConfig.TrustedProxies = trustedProxies
+	router := gin.New()
+	assert.NoError(t, handlers.TrustProxies(router, cfg))
+	router.GET("/documents/:id/preview", func(c *gin.Context) {
+		if _, err := tokens.Verify(c.Query("token"), c.Param("id"), c.ClientIP()); err != nil {
+			c.Status(http.StatusForbidden)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func previewFrom(router *gin.Engine, token, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/documents/doc-1/preview?token="+url.QueryEscape(token), nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPreviewTokenRejectsSpoofedForwardedFor(t *testing.T) {
+	tokens := newTestPreviewTokens(testPreviewKey, "", time.Minute)
+	token, _, err := tokens.Mint("doc-1", "preview", "user-1", "198.51.100.4", true)
+	assert.NoError(t, err)
+
+	// Without trusted proxies the header is ignored
+	direct := newTestPreviewRouter(t, tokens, nil)
+	assert.Equal(t, http.StatusForbidden, previewFrom(direct, token, "203.0.113.9:4711", "198.51.100.4"), "A client cannot claim the IP a token is bound to")
+	assert.Equal(t, http.StatusOK, previewFrom(direct, token, "198.51.100.4:4711", ""))
+
+	// Behind a trusted proxy the header names the client, from that proxy only
+	proxied := newTestPreviewRouter(t, tokens, []string{"10.0.0.0/8"})
+	assert.Equal(t, http.StatusOK, previewFrom(proxied, token, "10.1.2.3:4711", "198.51.100.4"))
+	assert.Equal(t, http.StatusForbidden, previewFrom(proxied, token, "203.0.113.9:4711", "198.51.100.4"))
+}
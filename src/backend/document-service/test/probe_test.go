@@ -0,0 +1,48 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestProbeResultReportsFirstFailedStage(t *testing.T) {
+	startedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	result := &models.ProbeResult{StartedAt: startedAt}
+	result.Record(models.ProbeStageUpload, 120*time.Millisecond, nil)
+	result.Record(models.ProbeStageProcessing, 2*time.Second, errors.New("pipeline did not complete"))
+	result.Record(models.ProbeStageDelete, 30*time.Millisecond, errors.New("storage unavailable"))
+	result.Finish(startedAt.Add(3 * time.Second))
+
+	assert.False(t, result.Passed)
+	assert.Equal(t, models.ProbeStageProcessing, result.FailedStage, "The first failure is reported, not the cleanup's")
+	if assert.Len(t, result.Stages, 3) {
+		assert.Equal(t, int64(120), result.Stages[0].DurationMS)
+		assert.Empty(t, result.Stages[0].Error)
+		assert.Equal(t, "pipeline did not complete", result.Stages[1].Error)
+	}
+	assert.Equal(t, int64(3000), result.DurationMS)
+
+	passed := &models.ProbeResult{StartedAt: startedAt}
+	for _, stage := range []string{models.ProbeStageUpload, models.ProbeStageProcessing, models.ProbeStageDownload, models.ProbeStageDelete} {
+		passed.Record(stage, time.Millisecond, nil)
+	}
+	passed.Finish(startedAt.Add(time.Second))
+	assert.True(t, passed.Passed)
+	assert.Empty(t, passed.FailedStage)
+}
+
+func TestProbeDocumentIsStablePDF(t *testing.T) {
+	document := services.ProbeDocument()
+
+	assert.True(t, bytes.HasPrefix(document, []byte("%PDF-")))
+	assert.Contains(t, string(document), "(DOCUMENT SERVICE SYNTHETIC PROBE) Tj")
+	assert.Regexp(t, `startxref\n\d+\n%%EOF\n$`, string(document))
+	assert.Equal(t, document, services.ProbeDocument(), "Every probe uploads the same bytes")
+}
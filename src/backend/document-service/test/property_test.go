@@ -0,0 +1,128 @@
+package test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"pgregory.net/rapid"                 // v1.1.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models/invariants"
+)
+
+// processingStatuses are the statuses the pipeline moves a document through;
+// the review statuses are reached by reviews only
+var processingStatuses = []string{
+	models.DocumentStatusPending,
+	models.DocumentStatusProcessing,
+	models.DocumentStatusValidating,
+	models.DocumentStatusEncrypting,
+	models.DocumentStatusCompleted,
+	models.DocumentStatusFailed,
+	models.DocumentStatusHaltedConsent,
+	models.DocumentStatusOCRDeferred,
+	models.DocumentStatusQueued,
+}
+
+// documentSnapshot is what a refused transition must leave unchanged
+type documentSnapshot struct {
+	Status       string
+	DocumentType string
+	ReviewedBy   string
+	AuditEntries int
+	PendingPages int
+}
+
+func snapshotDocument(doc *models.Document) documentSnapshot {
+	return documentSnapshot{
+		Status:       doc.Status,
+		DocumentType: doc.DocumentType,
+		ReviewedBy:   doc.ReviewedBy,
+		AuditEntries: len(doc.AuditTrail),
+		PendingPages: len(doc.PendingPages()),
+	}
+}
+
+// transition applies a transition to a document: a refused one leaves the
+// document as it was, an accepted one is audited with the status it left
+// the document in
+func transition(t *rapid.T, doc *models.Document, apply func() error) {
+	before := snapshotDocument(doc)
+	if err := apply(); err != nil {
+		assert.Equal(t, before, snapshotDocument(doc), "A refused transition must leave the document as it was: %v", err)
+		return
+	}
+	if assert.Len(t, doc.AuditTrail, before.AuditEntries+1, "Every transition should be audited") {
+		assert.Equal(t, doc.Status, doc.AuditTrail[len(doc.AuditTrail)-1].Status)
+	}
+}
+
+// checkDocumentState checks a document is in a valid state
+func checkDocumentState(t *rapid.T, doc *models.Document) {
+	assert.Contains(t, models.AllowedStatuses, doc.Status)
+	assert.Empty(t, invariants.Check(doc), "Transitions must not break the metadata invariants")
+
+	switch doc.Status {
+	case models.DocumentStatusCompleted:
+		assert.NotNil(t, doc.ProcessedAt, "A completed document records when it was processed")
+	case models.DocumentStatusApproved, models.DocumentStatusRejected, models.DocumentStatusPartiallyApproved:
+		assert.NotEmpty(t, doc.ReviewedBy, "A reviewed document records its reviewer")
+		assert.NotNil(t, doc.ReviewedAt)
+	}
+	switch doc.Status {
+	case models.DocumentStatusApproved:
+		assert.Empty(t, doc.PendingPages(), "An approved document has no page awaiting a decision")
+	case models.DocumentStatusPartiallyApproved:
+		assert.NotEmpty(t, doc.PendingPages(), "A partially approved document has a page awaiting a decision")
+	}
+}
+
+func TestDocumentStateMachineProperties(t *testing.T) {
+	rapid.Check(t, func(rt *rapid.T) {
+		doc := storedDocument(rt)
+		pages := rapid.IntRange(1, 4).Draw(rt, "pages")
+		reviewers := rapid.SampledFrom([]string{"", "underwriter@example.com"})
+
+		rt.Repeat(map[string]func(*rapid.T){
+			"update status": func(rt *rapid.T) {
+				// Unknown statuses must be refused
+				status := rapid.SampledFrom(append(slices.Clone(processingStatuses), "", "archived")).Draw(rt, "status")
+				transition(rt, doc, func() error {
+					return doc.UpdateStatus(status, "Pipeline step")
+				})
+			},
+			"review": func(rt *rapid.T) {
+				decision := rapid.SampledFrom([]string{models.ReviewDecisionApprove, models.ReviewDecisionReject, "escalate"}).Draw(rt, "decision")
+				reviewer := reviewers.Draw(rt, "reviewer")
+				transition(rt, doc, func() error {
+					return doc.Review(decision, "Reviewed", reviewer)
+				})
+			},
+			"review pages": func(rt *rapid.T) {
+				decisions := make([]models.PageDecision, rapid.IntRange(0, pages).Draw(rt, "decisions"))
+				for i := range decisions {
+					decisions[i] = models.PageDecision{
+						// Page numbers out of range must be refused
+						Number:   rapid.IntRange(0, pages+1).Draw(rt, "page"),
+						Decision: rapid.SampledFrom([]string{models.PageDecisionApprove, models.PageDecisionRescan, "skip"}).Draw(rt, "page decision"),
+						Reason:   rapid.SampledFrom([]string{"", "Page is blurred"}).Draw(rt, "reason"),
+					}
+				}
+				reviewer := reviewers.Draw(rt, "reviewer")
+				transition(rt, doc, func() error {
+					return doc.ReviewPages(pages, decisions, reviewer)
+				})
+			},
+			"reclassify": func(rt *rapid.T) {
+				documentType := rapid.SampledFrom([]string{"", "identity", "proof_of_address"}).Draw(rt, "document type")
+				transition(rt, doc, func() error {
+					return doc.Reclassify(documentType, "BULK_OPERATION")
+				})
+			},
+			"": func(rt *rapid.T) {
+				checkDocumentState(rt, doc)
+			},
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestArtifactProvenance(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+
+	ocr := &models.Provenance{
+		PipelineVersion: "2024.06.1",
+		Step:            "ocr",
+		StepVersion:     "1",
+		Provider:        "azure_computer_vision",
+		Model:           "v3.0/recognize-printed-text",
+		ProducedAt:      time.Now(),
+	}
+	doc.SetProducer(ocr)
+	doc.SetRendition(models.Rendition{Name: models.RenditionOCRText, StoragePath: "renditions/a/ocr_text"})
+	doc.SetProducer(nil)
+
+	receita := &models.Provenance{
+		PipelineVersion: "2024.06.1",
+		Step:            "receita",
+		StepVersion:     "1",
+		Provider:        "serpro",
+		Inputs:          []models.Provenance{*ocr},
+		ProducedAt:      time.Now(),
+	}
+	doc.SetProducer(receita)
+	doc.SetExtractedFields("receita", []models.ExtractedField{
+		{Name: "cpf", Value: "52998224725", Confidence: 1},
+		{Name: "cpf", Value: "11144477735", Confidence: 1},
+		{Name: "cpf_situation", Value: "regular", Confidence: 1},
+	})
+	doc.SetProducer(nil)
+
+	// Artifacts recorded outside a step carry no provenance
+	doc.SetRendition(models.Rendition{Name: models.RenditionThumbnail, StoragePath: "renditions/a/thumbnail"})
+
+	cpfs := doc.ArtifactProvenance("cpf")
+	if assert.Len(t, cpfs, 2) {
+		assert.Equal(t, models.ArtifactExtractedField, cpfs[0].Kind)
+		assert.Equal(t, 0, cpfs[0].Index)
+		assert.Equal(t, 1, cpfs[1].Index)
+		assert.Equal(t, "receita", cpfs[1].Source)
+		if assert.NotNil(t, cpfs[1].Provenance) && assert.Len(t, cpfs[1].Provenance.Inputs, 1) {
+			assert.Equal(t, "serpro", cpfs[1].Provenance.Provider)
+			assert.Equal(t, "v3.0/recognize-printed-text", cpfs[1].Provenance.Inputs[0].Model, "The CPF should trace back to the OCR model")
+		}
+	}
+
+	all := doc.ArtifactProvenance("")
+	assert.Len(t, all, 5)
+	thumbnail := doc.ArtifactProvenance(models.RenditionThumbnail)
+	if assert.Len(t, thumbnail, 1) {
+		assert.Nil(t, thumbnail[0].Provenance)
+	}
+	text := doc.ArtifactProvenance(models.RenditionOCRText)
+	if assert.Len(t, text, 1) {
+		assert.Equal(t, "ocr", text[0].Provenance.Step)
+	}
+
+	// Provenance is internal and stays out of portability exports
+	exported := models.NewPortabilityDocument(doc)
+	if assert.Len(t, exported.ExtractedFields, 3) {
+		assert.Nil(t, exported.ExtractedFields[0].Provenance)
+	}
+	assert.NotNil(t, doc.ExtractedFields[0].Provenance)
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestHealthWindowForgetsCallsOutsideWindow(t *testing.T) {
+	window := models.NewHealthWindow(10 * time.Minute)
+	start := time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC)
+
+	window.Record(start, 0, true)
+	window.Record(start.Add(5*time.Minute), 2*time.Second, false)
+	window.Record(start.Add(6*time.Minute), 4*time.Second, false)
+
+	successes, failures, latency := window.Totals(start.Add(7 * time.Minute))
+	assert.Equal(t, 2, successes)
+	assert.Equal(t, 1, failures)
+	assert.Equal(t, 3*time.Second, latency)
+
+	successes, failures, _ = window.Totals(start.Add(11 * time.Minute))
+	assert.Equal(t, 2, successes)
+	assert.Equal(t, 0, failures, "The failure aged out of the window")
+
+	successes, failures, latency = window.Totals(start.Add(time.Hour))
+	assert.Equal(t, 0, successes+failures)
+	assert.Equal(t, time.Duration(0), latency)
+}
+
+func TestProviderHealthScoresSuccessAndLatency(t *testing.T) {
+	now := time.Date(2026, time.October, 16, 9, 0, 0, 0, time.UTC)
+	window := func(successes, failures int, latency time.Duration) *models.HealthWindow {
+		w := models.NewHealthWindow(5 * time.Minute)
+		for i := 0; i < successes; i++ {
+			w.Record(now, latency, false)
+		}
+		for i := 0; i < failures; i++ {
+			w.Record(now, 0, true)
+		}
+		return w
+	}
+
+	health := models.NewProviderHealth("ocr", "azure", window(3, 1, time.Second), 10*time.Second, 20, 0.8, now)
+	assert.Equal(t, 1.0, health.Score, "Too few calls to score")
+	assert.Equal(t, 0.75, health.SuccessRate)
+	assert.True(t, health.Healthy)
+
+	health = models.NewProviderHealth("ocr", "azure", window(18, 2, 20*time.Second), 10*time.Second, 20, 0.8, now)
+	assert.InDelta(t, 0.45, health.Score, 1e-9)
+	assert.Equal(t, int64(20000), health.MeanLatencyMs)
+	assert.False(t, health.Healthy)
+}
+
+func TestRankProvidersPrefersHealthyInOrder(t *testing.T) {
+	providers := []models.ProviderHealth{
+		{Provider: "primary", Score: 0.3},
+		{Provider: "secondary", Score: 0.85, Healthy: true},
+		{Provider: "tertiary", Score: 0.5},
+		{Provider: "quaternary", Score: 0.99, Healthy: true},
+	}
+	models.RankProviders(providers)
+
+	var order []string
+	for _, provider := range providers {
+		order = append(order, provider.Provider)
+	}
+	assert.Equal(t, []string{"secondary", "quaternary", "tertiary", "primary"}, order)
+	assert.Equal(t, 1, providers[0].Rank)
+	assert.Equal(t, 4, providers[3].Rank)
+}
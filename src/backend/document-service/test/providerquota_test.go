@@ -0,0 +1,68 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestProviderQuotaPaceSpreadsRestOfMonth(t *testing.T) {
+	limits := models.ProviderQuotaLimits{TransactionsPerSecond: 10, MonthlyTransactions: 1_000_000}
+	// Ten days before the month ends
+	now := time.Date(2026, time.October, 22, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 10.0, limits.Pace(800_000, 0.9, now), "Under the throttling share only the per-second limit applies")
+	assert.InDelta(t, 100_000.0/(10*24*3600), limits.Pace(900_000, 0.9, now), 1e-9)
+	assert.Equal(t, 0.0, limits.Pace(1_000_000, 0.9, now))
+
+	unlimited := models.ProviderQuotaLimits{}
+	assert.Equal(t, 0.0, unlimited.Pace(5_000_000, 0.9, now))
+	assert.Equal(t, time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), models.QuotaMonthEnd(now))
+	assert.Equal(t, "2026-10", models.QuotaMonth(now))
+}
+
+func TestProviderQuotaStatus(t *testing.T) {
+	limits := models.ProviderQuotaLimits{TransactionsPerSecond: 10, MonthlyTransactions: 1000}
+	now := time.Date(2026, time.October, 31, 12, 0, 0, 0, time.UTC)
+
+	status := models.NewProviderQuotaStatus("azure", limits, 500, 0.9, time.Time{}, now)
+	assert.Equal(t, models.ProviderQuotaOK, status.State)
+	assert.Equal(t, 0.5, status.UsedRatio)
+	assert.Nil(t, status.ThrottledUntil)
+
+	status = models.NewProviderQuotaStatus("azure", limits, 950, 0.9, time.Time{}, now)
+	assert.Equal(t, models.ProviderQuotaPaced, status.State)
+	assert.InDelta(t, 50.0/(12*3600), status.PacedTransactionsPerSecond, 1e-9)
+
+	status = models.NewProviderQuotaStatus("azure", limits, 950, 0.9, now.Add(time.Minute), now)
+	assert.Equal(t, models.ProviderQuotaThrottled, status.State)
+	assert.Equal(t, now.Add(time.Minute), *status.ThrottledUntil)
+
+	status = models.NewProviderQuotaStatus("azure", limits, 1000, 0.9, now.Add(time.Minute), now)
+	assert.Equal(t, models.ProviderQuotaExhausted, status.State)
+}
+
+func TestProviderUsageCountsPerMonth(t *testing.T) {
+	ctx := context.Background()
+	usage := repository.NewMemoryProviderUsageRepository()
+
+	for i := 0; i < 3; i++ {
+		_, err := usage.Add(ctx, "azure", "2026-10", 1)
+		assert.NoError(t, err)
+	}
+	used, err := usage.Add(ctx, "azure_fallback", "2026-10", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), used)
+
+	used, err = usage.Get(ctx, "azure", "2026-10")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), used)
+	used, err = usage.Get(ctx, "azure", "2026-11")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestQuarantineItemResolvesOnce(t *testing.T) {
+	item := &models.QuarantineItem{ID: "q-1", Reason: models.QuarantineReasonMalware, Status: models.QuarantineStatusHeld}
+	release := models.QuarantineAction{Action: models.QuarantineActionRelease, Principal: "admin", Reviewer: "ana", Reason: "False positive", At: time.Now()}
+
+	assert.NoError(t, item.Resolve(models.QuarantineStatusReleased, release))
+	assert.False(t, item.Held())
+	assert.Equal(t, models.QuarantineStatusReleased, item.Status)
+	assert.NotNil(t, item.ResolvedAt)
+	assert.Len(t, item.History, 1)
+
+	destroy := models.QuarantineAction{Action: models.QuarantineActionDestroy, Principal: "admin", Reviewer: "bruno", At: time.Now()}
+	assert.ErrorIs(t, item.Resolve(models.QuarantineStatusDestroyed, destroy), models.ErrQuarantineResolved)
+	assert.Equal(t, models.QuarantineStatusReleased, item.Status, "A resolved item keeps its resolution")
+	assert.Len(t, item.History, 1)
+}
+
+func TestDocumentQuarantine(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+
+	doc.Quarantine("q-1", "Forged letterhead", "ana")
+	assert.True(t, doc.Quarantined())
+	assert.Equal(t, "q-1", doc.QuarantineID)
+	assert.Equal(t, "QUARANTINE", doc.AuditTrail[len(doc.AuditTrail)-1].Action)
+	assert.Equal(t, "ana", doc.AuditTrail[len(doc.AuditTrail)-1].PerformedBy)
+
+	doc.LiftQuarantine("RELEASED", "Letterhead confirmed with the issuer", "bruno")
+	assert.False(t, doc.Quarantined())
+	assert.Equal(t, "QUARANTINE_RELEASED", doc.AuditTrail[len(doc.AuditTrail)-1].Action)
+}
+
+func TestReleaseQuarantinedOriginalDropsRendition(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.SetRendition(models.Rendition{Name: models.RenditionThumbnail})
+	doc.SetRendition(models.Rendition{Name: models.RenditionQuarantinedOriginal})
+
+	doc.ReleaseQuarantinedOriginal("Form fields are needed", "ana")
+	_, ok := doc.Rendition(models.RenditionQuarantinedOriginal)
+	assert.False(t, ok)
+	_, ok = doc.Rendition(models.RenditionThumbnail)
+	assert.True(t, ok, "Other renditions are kept")
+}
+
+func TestMemoryQuarantineRepositoryList(t *testing.T) {
+	repo := repository.NewMemoryQuarantineRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	items := []*models.QuarantineItem{
+		{ID: "fraud", Reason: models.QuarantineReasonFraud, Status: models.QuarantineStatusHeld, QuarantinedAt: now},
+		{ID: "malware", Reason: models.QuarantineReasonMalware, Status: models.QuarantineStatusHeld, QuarantinedAt: now.Add(-2 * time.Hour)},
+		{ID: "released", Reason: models.QuarantineReasonMalware, Status: models.QuarantineStatusReleased, QuarantinedAt: now.Add(-time.Hour)},
+	}
+	for _, item := range items {
+		assert.NoError(t, repo.Create(ctx, item))
+	}
+
+	held, err := repo.List(ctx, repository.QuarantineFilter{Status: models.QuarantineStatusHeld})
+	assert.NoError(t, err)
+	assert.Len(t, held, 2)
+	assert.Equal(t, "malware", held[0].ID, "The longest waiting item comes first")
+
+	malware, err := repo.List(ctx, repository.QuarantineFilter{Reason: models.QuarantineReasonMalware})
+	assert.NoError(t, err)
+	assert.Len(t, malware, 2)
+
+	// Items handed out are copies
+	held[0].Record(models.QuarantineAction{Action: models.QuarantineActionFetch})
+	stored, err := repo.Get(ctx, "malware")
+	assert.NoError(t, err)
+	assert.Empty(t, stored.History)
+
+	_, err = repo.Get(ctx, "missing")
+	assert.ErrorIs(t, err, repository.ErrQuarantineItemNotFound)
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestQuotaBurstCredits(t *testing.T) {
+	limits := models.QuotaLimits{Requests: 2, Window: time.Minute, CreditsPerHour: 60, MaxCredits: 2}
+	start := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	account := models.NewQuotaAccount("tenant-1", limits, start)
+
+	for i := 0; i < 2; i++ {
+		allowed, burst := account.Take(limits, start)
+		assert.True(t, allowed)
+		assert.False(t, burst, "Requests within the quota spend no credits")
+	}
+	for i := 0; i < 2; i++ {
+		allowed, burst := account.Take(limits, start)
+		assert.True(t, allowed, "Accounts start with full burst credits")
+		assert.True(t, burst)
+	}
+	allowed, _ := account.Take(limits, start)
+	assert.False(t, allowed, "Requests are rejected once credits run out")
+
+	usage := account.Usage(limits, start)
+	assert.Equal(t, 4, usage.Used)
+	assert.Equal(t, 0, usage.Remaining)
+	assert.Equal(t, 2, usage.BurstUsed)
+	assert.Equal(t, 1, usage.Rejected)
+	assert.Equal(t, start.Add(time.Minute), usage.ResetsAt)
+	assert.Equal(t, time.Minute, account.RetryAfter(limits, start), "A credit accrues as the window resets")
+
+	// Credits accrue over time, up to the maximum
+	later := start.Add(30 * time.Second)
+	assert.Equal(t, 30*time.Second, account.RetryAfter(limits, later))
+	assert.Equal(t, 0.5, account.Usage(limits, later).BurstCredits)
+
+	next := start.Add(time.Hour)
+	usage = account.Usage(limits, next)
+	assert.Equal(t, 0, usage.Used, "A new window restores the quota")
+	assert.Equal(t, 2.0, usage.BurstCredits)
+}
+
+func TestQuotaLimitsLowered(t *testing.T) {
+	limits := models.QuotaLimits{Requests: 1, Window: time.Minute, CreditsPerHour: 60, MaxCredits: 10}
+	now := time.Now()
+	account := models.NewQuotaAccount("tenant-1", limits, now)
+
+	limits.MaxCredits = 1
+	account.Take(limits, now)
+	allowed, burst := account.Take(limits, now)
+	assert.True(t, allowed && burst)
+	allowed, _ = account.Take(limits, now)
+	assert.False(t, allowed, "Credits above a lowered maximum are dropped")
+}
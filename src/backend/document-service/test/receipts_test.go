@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestReceipts(t *testing.T) (*services.DownloadReceipts, *repository.MemoryOutboxRepository) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.DownloadReceiptsConfig = config.DownloadReceiptsConfig{
+		Enabled:         true,
+		DocumentTypes:   []string{"medical_record"},
+		SigningKey:      string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+		SessionIdle:     15 * time.Minute,
+		MaxOpen:         time.Hour,
+		NotifySubjects:  true,
+		NotificationURL: "http://notifications.invalid/receipts",
+	}
+	outbox := repository.NewMemoryOutboxRepository()
+	receipts, err := services.NewDownloadReceipts(cfg, repository.NewMemoryReceiptRepository(), outbox, zap.NewNop())
+	assert.NoError(t, err)
+	return receipts, outbox
+}
+
+func TestDownloadReceiptsAreSessionScopedAndSigned(t *testing.T) {
+	ctx := context.Background()
+	receipts, outbox := newTestReceipts(t)
+
+	report := &models.Document{ID: "doc-1", EnrollmentID: "enr-1", DocumentType: "medical_record", ContentHash: "abc"}
+	other := &models.Document{ID: "doc-2", EnrollmentID: "enr-2", DocumentType: "medical_record", ContentHash: "def"}
+	id := &models.Document{ID: "doc-3", EnrollmentID: "enr-1", DocumentType: "id_card", ContentHash: "ghi"}
+	reviewer := services.ReceiptViewer{UserID: "reviewer-1", Role: "underwriter", SessionID: "session-1"}
+
+	receipts.Record(reviewer, report, models.ReceiptAccessDownload, "")
+	receipts.Record(reviewer, report, models.ReceiptAccessDownload, "")
+	receipts.Record(reviewer, report, models.ReceiptAccessViewer, "")
+	receipts.Record(reviewer, other, models.ReceiptAccessDownload, "")
+	receipts.Record(reviewer, id, models.ReceiptAccessDownload, "")
+
+	receipts.IssueDue(ctx, time.Now())
+	issued, err := receipts.List(ctx, repository.ReceiptFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, issued, "Receipts should stay open while the session is active")
+
+	receipts.IssueDue(ctx, time.Now().Add(16*time.Minute))
+	issued, err = receipts.List(ctx, repository.ReceiptFilter{EnrollmentID: "enr-1"})
+	assert.NoError(t, err)
+	if assert.Len(t, issued, 1, "Each enrollment should get its own receipt") {
+		receipt := issued[0]
+		assert.Equal(t, "reviewer-1", receipt.UserID)
+		assert.Equal(t, "session-1", receipt.SessionID)
+		if assert.Len(t, receipt.Accesses, 2, "Only sensitive document types should be recorded") {
+			assert.Equal(t, 2, receipt.Accesses[0].Count)
+			assert.Equal(t, "abc", receipt.Accesses[0].ContentHash)
+		}
+		assert.NoError(t, receipts.Verify(receipt))
+
+		receipt.Accesses[0].Count = 1
+		assert.ErrorIs(t, receipts.Verify(receipt), services.ErrInvalidReceiptSignature, "Altered receipts should fail verification")
+	}
+
+	due, err := outbox.ListDue(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 2, "Every issued receipt should be sent to its data subject")
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus" // v1.17.0
+	"github.com/stretchr/testify/assert"             // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// fixedCPFProvider reports the same situation for every CPF
+type fixedCPFProvider struct {
+	situation string
+}
+
+func (p *fixedCPFProvider) Name() string { return "fixed" }
+
+func (p *fixedCPFProvider) GetSituation(ctx context.Context, cpf string) (services.CPFSituation, error) {
+	return services.CPFSituation{CPF: cpf, Situation: p.situation}, nil
+}
+
+// situationLabels returns the situation labels of cpf_situation_checks_total
+func situationLabels(t *testing.T, registry *prometheus.Registry) map[string]bool {
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	labels := make(map[string]bool)
+	for _, family := range families {
+		if family.GetName() != "cpf_situation_checks_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				labels[label.GetValue()] = true
+			}
+		}
+	}
+	return labels
+}
+
+func TestCPFSituationMetricBoundsLabels(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, services.RegisterMetrics(registry))
+
+	cfg := &config.Config{}
+	ctx := context.Background()
+	for i, situation := range []string{services.CPFSituationSuspended, "em análise pela receita", "código 4711"} {
+		provider, err := services.NewCachingCPFProvider(cfg, &fixedCPFProvider{situation: situation})
+		assert.NoError(t, err)
+		result, err := provider.GetSituation(ctx, []string{"11144477735", "52998224725", "39053344705"}[i])
+		assert.NoError(t, err)
+		assert.Equal(t, situation, result.Situation, "The stored situation keeps what the broker reported")
+	}
+
+	labels := situationLabels(t, registry)
+	assert.True(t, labels[services.CPFSituationSuspended])
+	assert.True(t, labels["other"], "Unknown situations should be counted as other")
+	for label := range labels {
+		assert.Contains(t, []string{
+			services.CPFSituationRegular, services.CPFSituationPending, services.CPFSituationSuspended,
+			services.CPFSituationCancelled, services.CPFSituationNull, services.CPFSituationDeceased,
+			"other", "cached", "error",
+		}, label)
+	}
+}
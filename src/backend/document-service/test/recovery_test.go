@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// errorTracker receives error reports as Sentry or Rollbar would
+type errorTracker struct {
+	server  *httptest.Server
+	reports chan *http.Request
+	bodies  chan map[string]interface{}
+}
+
+func newErrorTracker(t *testing.T) *errorTracker {
+	tracker := &errorTracker{reports: make(chan *http.Request, 1), bodies: make(chan map[string]interface{}, 1)}
+	tracker.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		tracker.reports <- r
+		tracker.bodies <- body
+	}))
+	t.Cleanup(tracker.server.Close)
+	return tracker
+}
+
+// newPanickingRouter serves a route that panics behind the recovery
+// middleware, with the request ID the access log would have set
+func newPanickingRouter(t *testing.T, cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	reporting, err := services.NewErrorReporting(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if reporting != nil {
+		go reporting.Run(ctx)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("request_id", "req-42")
+		c.Next()
+	})
+	router.Use(handlers.Recover(reporting, zap.NewNop()))
+	router.GET("/documents/:id", func(c *gin.Context) {
+		panic("no metadata for document " + c.Param("id"))
+	})
+	router.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("after the response")
+	})
+	router.GET("/aborted", func(c *gin.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	return router
+}
+
+func reportingConfig(provider, dsn, endpoint string) *config.Config {
+	cfg := &config.Config{}
+	cfg.ErrorReportingConfig = config.ErrorReportingConfig{
+		Provider:    provider,
+		DSN:         dsn,
+		AccessToken: "rollbar-token",
+		Endpoint:    endpoint,
+		Environment: "test",
+		Release:     "1.2.3",
+		Timeout:     time.Second,
+		QueueSize:   10,
+	}
+	return cfg
+}
+
+func TestRecoverReturnsStructuredErrorAndReportsToRollbar(t *testing.T) {
+	tracker := newErrorTracker(t)
+	router := newPanickingRouter(t, reportingConfig(config.ErrorReportingRollbar, "", tracker.server.URL))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/documents/7", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	var body map[string]string
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{"status": "error", "message": "Internal server error", "request_id": "req-42"}, body)
+
+	select {
+	case r := <-tracker.reports:
+		assert.Equal(t, "/api/1/item/", r.URL.Path)
+		assert.Equal(t, "rollbar-token", r.Header.Get("X-Rollbar-Access-Token"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Panic was not reported")
+	}
+	data := (<-tracker.bodies)["data"].(map[string]interface{})
+	assert.Equal(t, "GET /documents/:id", data["context"])
+	assert.Equal(t, "req-42", data["custom"].(map[string]interface{})["request_id"])
+	trace := data["body"].(map[string]interface{})["trace"].(map[string]interface{})
+	assert.Equal(t, "no metadata for document 7", trace["exception"].(map[string]interface{})["message"])
+
+	// Frames run outermost first and end where the panic was raised
+	frames := trace["frames"].([]interface{})
+	last := frames[len(frames)-1].(map[string]interface{})
+	assert.Contains(t, last["method"], "newPanickingRouter")
+	assert.True(t, strings.HasSuffix(last["filename"].(string), "recovery_test.go"))
+}
+
+func TestRecoverReportsToSentry(t *testing.T) {
+	tracker := newErrorTracker(t)
+	dsn := strings.Replace(tracker.server.URL, "http://", "http://public-key@", 1) + "/sentry/42"
+	router := newPanickingRouter(t, reportingConfig(config.ErrorReportingSentry, dsn, ""))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/documents/7", nil))
+	select {
+	case r := <-tracker.reports:
+		assert.Equal(t, "/sentry/api/42/store/", r.URL.Path)
+		assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=public-key")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Panic was not reported")
+	}
+	event := <-tracker.bodies
+	assert.Equal(t, "fatal", event["level"])
+	assert.Equal(t, "req-42", event["tags"].(map[string]interface{})["request_id"])
+
+	_, err := services.NewSentryReporter(config.ErrorReportingConfig{DSN: "https://sentry.io/42"})
+	assert.Error(t, err, "DSNs carry the public key")
+}
+
+func TestRecoverAfterResponseWasWritten(t *testing.T) {
+	router := newPanickingRouter(t, &config.Config{})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/written", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String(), "The written response is left as is")
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/aborted", nil))
+	}, "Deliberate aborts reach the server")
+}
@@ -0,0 +1,48 @@
+package test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestDocumentRenditions(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, testDocumentType, testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+
+	doc.SetRendition(models.Rendition{Name: models.RenditionThumbnail, StoragePath: "renditions/a/thumbnail", Size: 10})
+	doc.SetRendition(models.Rendition{Name: models.RenditionThumbnail, StoragePath: "renditions/a/thumbnail", Size: 20})
+
+	assert.Len(t, doc.Renditions, 1, "Renditions with the same name should be replaced")
+	rendition, ok := doc.Rendition(models.RenditionThumbnail)
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), rendition.Size)
+
+	_, ok = doc.Rendition(models.RenditionPreview)
+	assert.False(t, ok)
+}
+
+func TestRenditionScaledToWidth(t *testing.T) {
+	// Alternating black and white columns average to grey
+	src := image.NewGray(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x += 2 {
+		for y := 0; y < 200; y++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	scaled := services.ScaleToWidth(src, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), scaled.Bounds(), "Aspect ratio should be kept")
+	r, g, b, a := scaled.At(40, 20).RGBA()
+	assert.InDelta(t, 0x7f, r>>8, 1)
+	assert.Equal(t, r, g)
+	assert.Equal(t, r, b)
+	assert.Equal(t, uint32(0xffff), a)
+
+	assert.Same(t, src, services.ScaleToWidth(src, 1024), "Narrower images should not be enlarged")
+}
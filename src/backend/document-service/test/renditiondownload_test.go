@@ -0,0 +1,231 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"                       // v1.9.1
+	"github.com/prometheus/client_golang/prometheus" // v1.17.0
+	"github.com/stretchr/testify/assert"             // v1.8.4
+	"go.uber.org/zap"                                // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// renditionFixture is the document download API over a memory bucket, with
+// the caller's role taken from the X-Role header
+type renditionFixture struct {
+	cfg       *config.Config
+	bucket    *memoryBucket
+	storage   *services.StorageService
+	documents *repository.WriteThroughDocumentRepository
+	router    *gin.Engine
+}
+
+func newRenditionFixture(tb testing.TB) *renditionFixture {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	bucket, storage := newMemoryStorage(tb, cfg)
+	cfg.RenditionsConfig.PresignExpiry = time.Minute
+	cfg.RenditionsConfig.CacheMaxAge = time.Hour
+	cfg.ConsistencyConfig = config.ConsistencyConfig{CacheTTL: time.Minute, WaitTimeout: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+	cfg.SecureViewerConfig.DocumentTypes = []string{"identity"}
+	cfg.SecureViewerConfig.UnrestrictedRoles = []string{"underwriter"}
+
+	documents := repository.NewWriteThroughDocumentRepository(
+		repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository()),
+		repository.NewMemoryDocumentCache(), cfg.ConsistencyConfig.CacheTTL)
+	pipeline, err := services.NewDocumentPipeline(cfg, storage, documents, nil, zap.NewNop())
+	assert.NoError(tb, err)
+	handler, err := handlers.NewDocumentHandler(cfg, storage, pipeline, documents, nil, prometheus.NewRegistry(), zap.NewNop())
+	assert.NoError(tb, err)
+	reads, err := services.NewConsistentReads(cfg, documents, zap.NewNop())
+	assert.NoError(tb, err)
+	handler.UseConsistentReads(reads)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "user-1")
+		c.Set("user_role", c.GetHeader("X-Role"))
+	})
+	router.GET("/api/v1/documents/:id", handler.DownloadDocument)
+	router.GET("/api/v1/documents/:id/renditions/:name", handler.DownloadRendition)
+	return &renditionFixture{cfg: cfg, bucket: bucket, storage: storage, documents: documents, router: router}
+}
+
+// store encrypts and stores a document with a thumbnail and an encrypted preview
+func (f *renditionFixture) store(tb testing.TB, content, thumbnail []byte) *models.Document {
+	ctx := context.Background()
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", int64(len(content)))
+	assert.NoError(tb, err)
+	doc.ID = "doc-rendition"
+	assert.NoError(tb, f.storage.StoreDocument(ctx, doc, bytes.NewReader(content)))
+	assert.NoError(tb, f.storage.StoreRendition(ctx, doc, models.RenditionThumbnail, "image/png", thumbnail))
+	assert.NoError(tb, f.storage.StoreEncryptedRendition(ctx, doc, models.RenditionPreview, "image/jpeg", []byte("preview of the scan")))
+	assert.NoError(tb, f.documents.Create(ctx, doc))
+	return doc
+}
+
+func (f *renditionFixture) get(path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRenditionRangeRequest(t *testing.T) {
+	fixture := newRenditionFixture(t)
+	doc := fixture.store(t, []byte("%PDF-1.7 scan"), []byte("0123456789"))
+	path := "/api/v1/documents/" + doc.ID + "/renditions/" + models.RenditionThumbnail
+
+	rec := fixture.get(path, http.Header{"Range": {"bytes=2-5"}})
+	if !assert.Equal(t, http.StatusPartialContent, rec.Code, rec.Body.String()) {
+		return
+	}
+	assert.Equal(t, "2345", rec.Body.String())
+	assert.Equal(t, "bytes 2-5/10", rec.Header().Get("Content-Range"))
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "private, max-age=3600", rec.Header().Get("Cache-Control"))
+
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	rec = fixture.get(path, http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code, "A cached rendition should not be sent again")
+
+	// An encrypted rendition is decrypted by range
+	rec = fixture.get("/api/v1/documents/"+doc.ID+"/renditions/"+models.RenditionPreview, http.Header{"Range": {"bytes=0-6"}})
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "preview", rec.Body.String())
+}
+
+func TestRenditionDownloadRedirectsToPresignedURL(t *testing.T) {
+	fixture := newRenditionFixture(t)
+	doc := fixture.store(t, []byte("%PDF-1.7 scan"), []byte("0123456789"))
+	fixture.cfg.RenditionsConfig.PresignedRedirects = true
+
+	rec := fixture.get("/api/v1/documents/"+doc.ID+"/renditions/"+models.RenditionThumbnail, nil)
+	if assert.Equal(t, http.StatusTemporaryRedirect, rec.Code, rec.Body.String()) {
+		location := rec.Header().Get("Location")
+		assert.Contains(t, location, "/documents/renditions/"+doc.ID+"/"+models.RenditionThumbnail)
+		assert.Contains(t, location, "X-Amz-Signature=")
+	}
+
+	rec = fixture.get("/api/v1/documents/"+doc.ID+"/renditions/"+models.RenditionPreview, nil)
+	assert.Equal(t, http.StatusOK, rec.Code, "An encrypted rendition cannot be served from the bucket")
+	assert.Equal(t, "preview of the scan", rec.Body.String())
+}
+
+func TestRenditionDownloadHonorsIfVersion(t *testing.T) {
+	fixture := newRenditionFixture(t)
+	doc := fixture.store(t, []byte("%PDF-1.7 scan"), []byte("0123456789"))
+	path := "/api/v1/documents/" + doc.ID + "/renditions/" + models.RenditionThumbnail
+
+	rec := fixture.get(path, http.Header{"If-Version": {strconv.FormatInt(doc.Version, 10)}})
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "0123456789", rec.Body.String())
+
+	rec = fixture.get(path, http.Header{"If-Version": {strconv.FormatInt(doc.Version+1, 10)}})
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "A version not written yet should be retried")
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	rec = fixture.get(path, http.Header{"If-Version": {"0"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = fixture.get("/api/v1/documents/doc-unknown/renditions/"+models.RenditionThumbnail, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestRenditionDownloadRestrictedToSecureViewer(t *testing.T) {
+	fixture := newRenditionFixture(t)
+	doc := fixture.store(t, []byte("%PDF-1.7 scan"), []byte("0123456789"))
+	fixture.cfg.SecureViewerConfig.Enabled = true
+	path := "/api/v1/documents/" + doc.ID + "/renditions/" + models.RenditionThumbnail
+
+	rec := fixture.get(path, http.Header{"X-Role": {"broker"}})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "Restricted documents should only be shown in the secure viewer")
+	rec = fixture.get(path, nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code, "A caller without a role should be restricted")
+
+	rec = fixture.get(path, http.Header{"X-Role": {"underwriter"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0123456789", rec.Body.String())
+}
+
+func TestRenditionDownloadMissingRendition(t *testing.T) {
+	fixture := newRenditionFixture(t)
+	doc := fixture.store(t, []byte("%PDF-1.7 scan"), []byte("0123456789"))
+
+	rec := fixture.get("/api/v1/documents/"+doc.ID+"/renditions/unknown", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// The rendition is recorded but its object is gone
+	rendition, _ := doc.Rendition(models.RenditionThumbnail)
+	fixture.bucket.replace("documents/"+rendition.StoragePath, nil)
+	rec = fixture.get("/api/v1/documents/"+doc.ID+"/renditions/"+models.RenditionThumbnail, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// BenchmarkRenditionDownload compares the buffered path used for encrypted
+// documents, where the whole object is read and decrypted into memory before
+// it is written, with the streamed path serving renditions
+func BenchmarkRenditionDownload(b *testing.B) {
+	fixture := newRenditionFixture(b)
+	content := make([]byte, 1<<20)
+	if _, err := rand.Read(content); err != nil {
+		b.Fatal(err)
+	}
+	doc := fixture.store(b, content, content)
+
+	for _, download := range []struct {
+		name string
+		path string
+	}{
+		{"buffered", "/api/v1/documents/" + doc.ID},
+		{"streamed", "/api/v1/documents/" + doc.ID + "/renditions/" + models.RenditionThumbnail},
+	} {
+		b.Run(download.name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, download.path, nil)
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := &discardResponseWriter{header: http.Header{}}
+				fixture.router.ServeHTTP(w, req)
+				if w.status != http.StatusOK {
+					b.Fatalf("download answered %d", w.status)
+				}
+			}
+		})
+	}
+}
+
+// discardResponseWriter drops the body so benchmarks measure the server side only
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(p), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(status int) { w.status = status }
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func repairEncryption() *models.EncryptionMetadata {
+	encryptedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &models.EncryptionMetadata{
+		KeyID:          "master",
+		Algorithm:      models.EncryptionAlgorithmXChaChaStream,
+		IV:             "bm9uY2U=",
+		KeyVersion:     "v2",
+		EncryptedAt:    encryptedAt,
+		KeyRotationDue: encryptedAt.Add(90 * 24 * time.Hour),
+		Version:        models.EncryptionMetadataVersion,
+	}
+}
+
+func TestEncryptionHeadersRoundTrip(t *testing.T) {
+	metadata := repairEncryption()
+	metadata.WrappedKey = "d3JhcHBlZA=="
+	headers := models.EncryptionHeaders(metadata)
+	for _, value := range headers {
+		assert.NotEqual(t, metadata.WrappedKey, value, "The wrapped data key must never be stored as a header")
+	}
+
+	// Object stores return user metadata under their own prefix and case
+	returned := make(map[string]string, len(headers))
+	for name, value := range headers {
+		returned["X-Amz-Meta-"+name] = value
+	}
+	rebuilt, err := models.EncryptionFromHeaders(returned, 90*24*time.Hour)
+	assert.NoError(t, err)
+	metadata.WrappedKey = ""
+	assert.Equal(t, metadata, rebuilt)
+
+	_, err = models.EncryptionFromHeaders(map[string]string{"document-id": "doc"}, time.Hour)
+	assert.ErrorIs(t, err, models.ErrMissingField)
+
+	delete(headers, "encryption-iv")
+	_, err = models.EncryptionFromHeaders(headers, time.Hour)
+	assert.ErrorIs(t, err, models.ErrMissingField)
+}
+
+func TestDocumentRepairAudited(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+
+	doc.RepairStoragePath("documents/"+doc.ID, "METADATA_REPAIR admin")
+	doc.RepairEncryptionMetadata(repairEncryption(), "METADATA_REPAIR admin")
+	assert.Equal(t, "documents/"+doc.ID, doc.StoragePath)
+	assert.Equal(t, "master", doc.EncryptionInfo.KeyID)
+
+	repairs := 0
+	for _, entry := range doc.AuditTrail {
+		if entry.Action == "REPAIR" {
+			repairs++
+			assert.Equal(t, "METADATA_REPAIR admin", entry.PerformedBy)
+		}
+	}
+	assert.Equal(t, 2, repairs)
+
+	repair := models.DocumentRepair{Violations: []models.MetadataViolation{{Fixed: true}, {Fixed: false}}}
+	assert.False(t, repair.Repaired())
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestExpiryNoticeBeforePurge(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	period := 30 * 24 * time.Hour
+	now := time.Now()
+	doc.RetentionDate = now.Add(10 * 24 * time.Hour)
+
+	assert.False(t, doc.ExpiryNoticeDue(now, 7*24*time.Hour), "Retention ends after the notice period")
+	assert.True(t, doc.ExpiryNoticeDue(now, period))
+	assert.False(t, doc.Purgeable(doc.RetentionDate), "Documents are never purged without notice")
+
+	doc.NoticeExpiry("notice-1", now, period)
+	assert.False(t, doc.ExpiryNoticeDue(now, period))
+	assert.Equal(t, now.Add(period), doc.Expiry.PurgeAfter, "The notice period outlasts the retention")
+	assert.False(t, doc.Purgeable(doc.RetentionDate))
+	assert.True(t, doc.Purgeable(doc.Expiry.PurgeAfter))
+
+	// A longer retention decided after the notice needs a new one
+	doc.RetentionDate = doc.RetentionDate.AddDate(1, 0, 0)
+	assert.False(t, doc.Purgeable(doc.RetentionDate))
+	assert.True(t, doc.ExpiryNoticeDue(doc.RetentionDate, period))
+}
+
+func TestRetentionHold(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	now := time.Now()
+	doc.RetentionDate = now
+	doc.NoticeExpiry("notice-1", now, time.Hour)
+	later := now.Add(2 * time.Hour)
+	assert.True(t, doc.Purgeable(later))
+
+	assert.ErrorIs(t, doc.PlaceHold("", "admin-1", "notice-1", now), models.ErrMissingField)
+	assert.NoError(t, doc.PlaceHold("Pending litigation", "admin-1", "notice-1", now))
+	assert.False(t, doc.Purgeable(later))
+	assert.False(t, doc.ExpiryNoticeDue(later, time.Hour))
+
+	doc.ReleaseHold("admin-1", later)
+	assert.Nil(t, doc.Hold)
+	assert.False(t, doc.Purgeable(later), "A released document is noticed again before it is purged")
+	assert.True(t, doc.ExpiryNoticeDue(later, time.Hour))
+
+	notice := &models.PurgeNotice{ID: "notice-1", DocumentIDs: []string{doc.ID}}
+	notice.Acknowledge("admin-1", []string{doc.ID}, now)
+	notice.Acknowledge("admin-2", []string{doc.ID}, later)
+	assert.Equal(t, "admin-1", notice.AcknowledgedBy)
+	assert.Equal(t, []string{doc.ID}, notice.Held)
+}
+
+func TestExtractedDataRetentionIsShorter(t *testing.T) {
+	doc, err := models.NewDocument(testEnrollmentID, "medical_record", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	processed := time.Now().AddDate(-1, 0, 0)
+	doc.ProcessedAt = &processed
+	doc.OCRPreview = "Diagnóstico: hipertensão"
+	doc.ExtractedFields = []models.ExtractedField{{Name: "cpf", Value: "123.456.789-09"}}
+	doc.Translation = &models.MachineTranslation{Provider: "libretranslate"}
+	doc.Renditions = []models.Rendition{
+		{Name: models.RenditionOCRText, CreatedAt: processed},
+		{Name: models.RenditionOCRTextTranslated, CreatedAt: processed},
+		{Name: models.RenditionSearchablePDF, CreatedAt: processed},
+		{Name: models.RenditionThumbnail, CreatedAt: processed},
+		{Name: models.RenditionPreview, CreatedAt: time.Now()},
+		{Name: models.OriginalRendition(0), CreatedAt: processed},
+	}
+	year := 365 * 24 * time.Hour
+	now := time.Now()
+
+	// Without separate retention, everything is kept as long as the document
+	assert.Empty(t, doc.ExpiredRenditions(now, 0, 0))
+	assert.False(t, doc.ExtractedDataExpired(now, 0))
+
+	names := func(renditions []models.Rendition) []string {
+		result := make([]string, len(renditions))
+		for i, rendition := range renditions {
+			result[i] = rendition.Name
+		}
+		return result
+	}
+	assert.Equal(t, []string{models.RenditionOCRText, models.RenditionOCRTextTranslated, models.RenditionSearchablePDF},
+		names(doc.ExpiredRenditions(now, year, 0)), "Text and PDFs carrying it are extracted data")
+	assert.Equal(t, []string{models.RenditionThumbnail},
+		names(doc.ExpiredRenditions(now, 0, 180*24*time.Hour)), "Originals and recent renditions are kept")
+	assert.True(t, doc.ExtractedDataExpired(now, year))
+	assert.False(t, doc.ExtractedDataExpired(now, 2*year))
+
+	assert.NoError(t, doc.PlaceHold("Pending litigation", "admin-1", "", now))
+	assert.Empty(t, doc.ExpiredRenditions(now, year, year))
+	assert.False(t, doc.ExtractedDataExpired(now, year))
+	doc.ReleaseHold("admin-1", now)
+
+	doc.PurgeArtifacts(doc.ExpiredRenditions(now, year, 180*24*time.Hour), true, now)
+	assert.Equal(t, []string{models.RenditionPreview, models.OriginalRendition(0)}, names(doc.Renditions))
+	assert.Empty(t, doc.OCRPreview)
+	assert.Empty(t, doc.ExtractedFields)
+	assert.Nil(t, doc.Translation, "The translation label goes with the translation")
+
+	// Extracted data and renditions are audited separately
+	var actions []string
+	for _, entry := range doc.AuditTrail {
+		if strings.HasSuffix(entry.Action, "_PURGE") {
+			actions = append(actions, entry.Action)
+		}
+	}
+	assert.Equal(t, []string{"EXTRACTED_DATA_PURGE", "RENDITION_PURGE"}, actions)
+	assert.False(t, doc.ExtractedDataExpired(now, year), "Purged data is not purged again")
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func TestRetryBudgetIsSharedAcrossLayers(t *testing.T) {
+	budget := utils.NewRetryBudget(3)
+	ctx := utils.WithRetryBudget(context.Background(), budget)
+
+	// Storage retries once, the KMS call under it twice, and then nothing
+	// may retry any more
+	assert.NoError(t, utils.SpendRetry(ctx, utils.RetryLayerStorage))
+	assert.NoError(t, utils.SpendRetry(ctx, utils.RetryLayerKMS))
+	assert.NoError(t, utils.SpendRetry(ctx, utils.RetryLayerKMS))
+	assert.ErrorIs(t, utils.SpendRetry(ctx, utils.RetryLayerStorage), utils.ErrRetryBudgetExhausted)
+	assert.ErrorIs(t, utils.SpendRetry(ctx, utils.RetryLayerOCR), utils.ErrRetryBudgetExhausted)
+
+	spent, denied := budget.Usage()
+	assert.Equal(t, map[string]int{utils.RetryLayerStorage: 1, utils.RetryLayerKMS: 2}, spent)
+	assert.Equal(t, map[string]int{utils.RetryLayerStorage: 1, utils.RetryLayerOCR: 1}, denied)
+
+	// Usage hands out copies
+	spent[utils.RetryLayerStorage] = 10
+	spent, _ = budget.Usage()
+	assert.Equal(t, 1, spent[utils.RetryLayerStorage])
+}
+
+func TestRetryBudgetConcurrentSpends(t *testing.T) {
+	ctx := utils.WithRetryBudget(context.Background(), utils.NewRetryBudget(5))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if utils.SpendRetry(ctx, utils.RetryLayerStorage) == nil {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, granted)
+}
+
+func TestRetryBudgetAbsentIsUnbounded(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, utils.RetryBudgetFromContext(ctx))
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, utils.SpendRetry(ctx, utils.RetryLayerStorage))
+	}
+
+	// A zero budget allows first attempts only
+	none := utils.WithRetryBudget(ctx, utils.NewRetryBudget(0))
+	assert.ErrorIs(t, utils.SpendRetry(none, utils.RetryLayerKMS), utils.ErrRetryBudgetExhausted)
+}
+
+func TestRetryExhaustedKeepsLastError(t *testing.T) {
+	timeout := errors.New("kms: request timed out")
+	err := utils.RetryExhausted(timeout)
+	assert.ErrorIs(t, err, utils.ErrRetryBudgetExhausted)
+	assert.ErrorIs(t, err, timeout)
+	assert.Contains(t, err.Error(), "request timed out")
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestProcessingCatalogLegalBasis(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ROPAConfig.SensitiveDocumentTypes = []string{"medical_record"}
+	cfg.ROPAConfig.Activities = []config.ProcessingActivityConfig{
+		{Operation: services.StepAddress, Purpose: "premium_region_pricing", LegalBasis: "LGPD-7-V"},
+	}
+	catalog := services.NewProcessingCatalog(cfg)
+
+	identity := &models.Document{DocumentType: "identity"}
+	medical := &models.Document{DocumentType: "medical_record"}
+
+	activity := catalog.Activity(identity, services.StepOCR, nil)
+	assert.Equal(t, models.ProcessingCategoryOCR, activity.Category)
+	assert.Equal(t, "LGPD-7-V", activity.LegalBasis)
+	assert.Equal(t, models.ProcessingOutcomeSucceeded, activity.Outcome)
+
+	activity = catalog.Activity(medical, services.StepOCR, errors.New("ocr unavailable"))
+	assert.Equal(t, "LGPD-11-II-D", activity.LegalBasis, "Health data should use the sensitive legal basis")
+	assert.Equal(t, models.ProcessingOutcomeFailed, activity.Outcome)
+
+	activity = catalog.Activity(identity, services.StepAddress, nil)
+	assert.Equal(t, "premium_region_pricing", activity.Purpose, "Configured activities should override the defaults")
+	assert.Equal(t, models.ProcessingCategoryAddressValidation, activity.Category, "Overrides without a category should keep the default one")
+
+	activity = catalog.Activity(identity, "liveness", nil)
+	assert.Equal(t, services.PurposeUnclassified, activity.Purpose)
+	assert.Empty(t, activity.LegalBasis)
+}
+
+func TestROPAReportAggregatesActivities(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryDocumentRepository()
+	catalog := services.NewProcessingCatalog(&config.Config{})
+
+	from := time.Now().Add(-time.Hour)
+	for _, id := range []string{"doc-1", "doc-2"} {
+		doc, err := models.NewDocument(testEnrollmentID, "identity", id+".pdf", "application/pdf", 1024)
+		assert.NoError(t, err)
+		doc.ID = id
+		catalog.Record(doc, services.StepOCR, nil)
+		catalog.Record(doc, services.OperationScreening, nil)
+		assert.NoError(t, repo.Create(ctx, doc))
+	}
+	failed, err := repo.GetByID(ctx, "doc-2")
+	assert.NoError(t, err)
+	catalog.Record(failed, services.StepOCR, errors.New("ocr unavailable"))
+	assert.NoError(t, repo.Update(ctx, failed))
+
+	ropa, err := services.NewROPAService(&config.Config{}, repo)
+	assert.NoError(t, err)
+
+	report, err := ropa.Report(ctx, from, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, report.Activities, 2)
+
+	ocr := report.Activities[0]
+	assert.Equal(t, models.ProcessingCategoryOCR, ocr.Category)
+	assert.Equal(t, 2, ocr.Documents)
+	assert.Equal(t, 3, ocr.Operations)
+	assert.Equal(t, 1, ocr.Failed)
+	assert.False(t, ocr.LastPerformedAt.Before(ocr.FirstPerformedAt))
+
+	screening := report.Activities[1]
+	assert.Equal(t, models.ProcessingCategoryScreening, screening.Category)
+	assert.Equal(t, "LGPD-7-II", screening.LegalBasis)
+	assert.Equal(t, 2, screening.Documents)
+
+	report, err = ropa.Report(ctx, from.Add(-2*time.Hour), from)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Activities, "Activities outside the period should be left out")
+
+	_, err = ropa.Report(ctx, from, from)
+	assert.ErrorIs(t, err, services.ErrInvalidReportPeriod)
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/rules"
+)
+
+func TestRuleExpressions(t *testing.T) {
+	facts := rules.Facts{
+		"document.type":          "identity",
+		"fields.min_confidence":  0.93,
+		"cpf.matches_enrollment": true,
+		"field.cpf.situation":    "regular",
+		"flags.count":            0,
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{"Conjunction", `document.type == "identity" and fields.min_confidence >= 0.9`, true},
+		{"Disjunction with grouping", `(document.type == "cnh" or document.type == "identity") and flags.count == 0`, true},
+		{"Negated boolean fact", `not cpf.matches_enrollment`, false},
+		{"Single quoted string", `field.cpf.situation != 'regular'`, false},
+		{"Unknown fact comparison", `screening.hit == true`, false},
+		{"Unknown fact negation", `not screening.hit`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := rules.Parse(tt.expression)
+			assert.NoError(t, err)
+
+			result, err := expression.Evaluate(facts)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRuleExpressionErrors(t *testing.T) {
+	for _, source := range []string{`document.type ==`, `a = 1`, `(a and b`, `"unterminated`} {
+		_, err := rules.Parse(source)
+		assert.ErrorIs(t, err, rules.ErrSyntax, source)
+	}
+
+	expression, err := rules.Parse(`document.type > 1`)
+	assert.NoError(t, err)
+	_, err = expression.Evaluate(rules.Facts{"document.type": "identity"})
+	assert.ErrorIs(t, err, rules.ErrTypeMismatch)
+}
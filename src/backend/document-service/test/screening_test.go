@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// listScreeningProvider reports a sanctions hit on the listed names
+type listScreeningProvider struct {
+	sanctioned map[string]bool
+	screened   []string
+}
+
+func (p *listScreeningProvider) Name() string { return "list" }
+
+func (p *listScreeningProvider) Screen(ctx context.Context, name string, lists []string) ([]models.ScreeningMatch, error) {
+	p.screened = append(p.screened, name)
+	if !p.sanctioned[name] {
+		return nil, nil
+	}
+	return []models.ScreeningMatch{{List: "sanctions", MatchedName: name, Score: 1}}, nil
+}
+
+func holderName(name string) models.ExtractedField {
+	return models.ExtractedField{Name: services.FieldHolderName, Value: name, Confidence: 1}
+}
+
+func TestScreeningQueuedOncePerProcessingRun(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.ScreeningConfig.MinScore = 0.8
+	documents := repository.NewMemoryDocumentRepository()
+	outbox := repository.NewMemoryOutboxRepository()
+	provider := &listScreeningProvider{sanctioned: map[string]bool{"JOSE DA SILVA": true}}
+	screening, err := services.NewScreeningService(cfg, documents, outbox, provider, zap.NewNop())
+	assert.NoError(t, err)
+
+	doc := &models.Document{ID: "doc-1", DocumentType: "identity", ExtractedFields: []models.ExtractedField{holderName("MARIA SOUZA")}}
+	doc.StartProcessingRun()
+	assert.NoError(t, documents.Create(ctx, doc))
+
+	// A hook notified twice of the same run queues one request
+	assert.NoError(t, screening.OnIngested(ctx, doc))
+	assert.NoError(t, screening.OnIngested(ctx, doc))
+	first, err := outbox.ListDue(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, first, 1)
+
+	// Reprocessing reads a different name, which is screened again
+	doc.ExtractedFields = []models.ExtractedField{holderName("JOSE DA SILVA")}
+	doc.StartProcessingRun()
+	assert.NoError(t, documents.Update(ctx, doc))
+	assert.NoError(t, screening.OnIngested(ctx, doc))
+	due, err := outbox.ListDue(ctx, time.Now(), 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, due, 2) {
+		return
+	}
+
+	var latest *models.OutboxMessage
+	for _, msg := range due {
+		if msg.ID != first[0].ID {
+			latest = msg
+		}
+	}
+	assert.NoError(t, screening.Deliver(ctx, latest))
+	// The request of the earlier run arrives late and is superseded
+	assert.NoError(t, screening.Deliver(ctx, first[0]))
+
+	stored, err := documents.GetByID(ctx, doc.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, stored.Screening, 1) {
+		assert.Equal(t, "JOSE DA SILVA", stored.Screening[0].Name)
+		assert.True(t, stored.Screening[0].Hit)
+	}
+	assert.True(t, stored.HasReviewFlag(models.ReviewFlagScreeningHit))
+}
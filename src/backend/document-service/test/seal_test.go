@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestMerkleRootCommitsToEveryDocument(t *testing.T) {
+	docs := []*models.Document{
+		{ID: "doc-b", DocumentType: "id_card", ContentHash: "hash-b"},
+		{ID: "doc-a", DocumentType: "medical_record", ContentHash: "hash-a"},
+		{ID: "doc-c", DocumentType: "proof_of_address", ContentHash: "hash-c"},
+	}
+	sealed := models.NewSealedDocuments(docs)
+	root := models.MerkleRoot(sealed)
+
+	reordered := models.NewSealedDocuments([]*models.Document{docs[2], docs[0], docs[1]})
+	assert.Equal(t, root, models.MerkleRoot(reordered), "The root must not depend on listing order")
+
+	altered := append([]models.SealedDocument(nil), sealed...)
+	altered[1].ContentHash = "hash-x"
+	assert.NotEqual(t, root, models.MerkleRoot(altered))
+
+	duplicated := append(append([]models.SealedDocument(nil), sealed...), sealed[2])
+	assert.NotEqual(t, root, models.MerkleRoot(duplicated), "Repeating the last document must change the root")
+
+	shifted := append([]models.SealedDocument(nil), sealed...)
+	shifted[0].DocumentID, shifted[0].DocumentType = sealed[0].DocumentID+"m", sealed[0].DocumentType[1:]
+	assert.NotEqual(t, root, models.MerkleRoot(shifted), "Bytes moved between fields must change the root")
+}
+
+func newTestSealService(t *testing.T, timestampURL string) (*services.SealService, *repository.MemoryDocumentRepository) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.SealConfig = config.SealConfig{
+		Enabled:      true,
+		SigningKey:   string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+		TimestampURL: timestampURL,
+		Timeout:      time.Second,
+	}
+	documents := repository.NewMemoryDocumentRepository()
+	seals, err := services.NewSealService(cfg, documents, repository.NewMemorySealRepository(), zap.NewNop())
+	assert.NoError(t, err)
+	return seals, documents
+}
+
+func TestEnrollmentSealDetectsTampering(t *testing.T) {
+	ctx := context.Background()
+	tsa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/timestamp-query", r.Header.Get("Content-Type"))
+		reply, _ := asn1.Marshal(struct {
+			Status struct{ Status int }
+			Token  asn1.RawValue
+		}{Token: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: []byte{0x02, 0x01, 0x01}}})
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(reply)
+	}))
+	defer tsa.Close()
+	seals, documents := newTestSealService(t, tsa.URL)
+
+	for _, doc := range []*models.Document{
+		{ID: "doc-1", EnrollmentID: "enr-1", DocumentType: "id_card", ContentHash: "hash-1"},
+		{ID: "doc-2", EnrollmentID: "enr-1", DocumentType: "medical_record", ContentHash: "hash-2"},
+	} {
+		assert.NoError(t, documents.Create(ctx, doc))
+	}
+
+	event := services.UnderwritingEvent{EventID: "evt-1", Type: services.UnderwritingEventCompleted, EnrollmentID: "enr-1", Decision: "approved"}
+	seal, err := seals.Handle(ctx, event)
+	assert.NoError(t, err)
+	assert.Len(t, seal.Documents, 2)
+	assert.NotEmpty(t, seal.Signature)
+	assert.NotEmpty(t, seal.TimestampToken)
+
+	again, err := seals.Handle(ctx, event)
+	assert.NoError(t, err)
+	assert.Equal(t, seal.ID, again.ID, "A redelivered event must return the same seal")
+
+	verification, err := seals.Verify(ctx, "enr-1")
+	assert.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.True(t, verification.Timestamped)
+
+	doc, err := documents.GetByID(ctx, "doc-2")
+	assert.NoError(t, err)
+	doc.ContentHash = "hash-forged"
+	assert.NoError(t, documents.Update(ctx, doc))
+	assert.NoError(t, documents.Create(ctx, &models.Document{ID: "doc-3", EnrollmentID: "enr-1", ContentHash: "hash-3"}))
+
+	verification, err = seals.Verify(ctx, "enr-1")
+	assert.NoError(t, err)
+	assert.False(t, verification.Valid)
+	assert.True(t, verification.SignatureValid)
+	assert.True(t, verification.RootValid)
+	assert.Equal(t, []string{"doc-2"}, verification.Changed)
+	assert.Equal(t, []string{"doc-3"}, verification.Added)
+	assert.Empty(t, verification.Missing)
+}
+
+func TestEnrollmentSealRequiresTimestampWhenConfigured(t *testing.T) {
+	ctx := context.Background()
+	tsa := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply, _ := asn1.Marshal(struct{ Status struct{ Status int } }{Status: struct{ Status int }{Status: 2}})
+		w.Write(reply)
+	}))
+	defer tsa.Close()
+	seals, _ := newTestSealService(t, tsa.URL)
+
+	_, err := seals.Handle(ctx, services.UnderwritingEvent{EventID: "evt-1", Type: services.UnderwritingEventCompleted, EnrollmentID: "enr-1"})
+	assert.ErrorIs(t, err, services.ErrTimestampFailed)
+
+	_, err = seals.Verify(ctx, "enr-1")
+	assert.ErrorIs(t, err, repository.ErrSealNotFound, "A seal that could not be timestamped must not be stored")
+}
+
+func TestUnderwritingEventsRequireRequestSignature(t *testing.T) {
+	seals, _ := newTestSealService(t, "")
+	cfg := signingConfig("k1", map[string]string{"k1": strings.Repeat("a", 32)})
+	handler, err := handlers.NewSealHandler(cfg, seals, zap.NewNop())
+	assert.NoError(t, err)
+
+	event := []byte(`{"event_id":"evt-1","type":"underwriting.reopened","enrollment_id":"enr-1"}`)
+	assert.Equal(t, http.StatusUnauthorized, postWebhook(t, cfg, "/webhooks/underwriting", handler.ReceiveEvent, event, false), "Unsigned events are rejected")
+	// A signed event reaches the handler, which rejects the unknown type
+	assert.Equal(t, http.StatusBadRequest, postWebhook(t, cfg, "/webhooks/underwriting", handler.ReceiveEvent, event, true))
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestSearchTerms(t *testing.T) {
+	terms := services.SearchTerms("Diagnóstico: HIPERTENSÃO arterial. CPF 123.456.789-09 em SP", 3)
+	assert.Equal(t, []string{"diagnostico", "hipertensao", "arterial", "cpf", "123", "456", "789", "12345678909"}, terms)
+}
+
+func TestSearchIndexStoresNoText(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{SearchConfig: config.SearchConfig{
+		Enabled:       true,
+		IndexKey:      strings.Repeat("k", 32),
+		Roles:         []string{"underwriter"},
+		MinTermLength: 3,
+		MaxResults:    10,
+	}}
+	index := repository.NewMemorySearchIndexRepository()
+	search, err := services.NewSearchIndex(cfg, index, zap.NewNop())
+	assert.NoError(t, err)
+	assert.True(t, search.MaySearch("underwriter"))
+	assert.False(t, search.MaySearch("broker"))
+
+	first := &models.Document{ID: "doc-1", TenantID: "tenant-a"}
+	second := &models.Document{ID: "doc-2", TenantID: "tenant-a"}
+	other := &models.Document{ID: "doc-3", TenantID: "tenant-b"}
+	assert.NoError(t, search.IndexDocument(ctx, first, "Laudo de hipertensão arterial"))
+	assert.NoError(t, search.IndexDocument(ctx, second, "Declaração de saúde sem hipertensão"))
+	assert.NoError(t, search.IndexDocument(ctx, other, "Laudo de hipertensão arterial"))
+
+	found, err := search.Search(ctx, "tenant-a", "Hipertensao")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-1", "doc-2"}, found, "Only the tenant's own documents are found")
+	found, err = search.Search(ctx, "tenant-a", "laudo hipertensão")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-1"}, found, "Every term must match")
+	_, err = search.Search(ctx, "tenant-a", "de")
+	assert.ErrorIs(t, err, services.ErrEmptySearch)
+
+	// Tokens are keyed per tenant and reveal nothing of the term
+	tenantA, err := search.Tokens("tenant-a", "hipertensao")
+	assert.NoError(t, err)
+	tenantB, err := search.Tokens("tenant-b", "hipertensao")
+	assert.NoError(t, err)
+	assert.NotEqual(t, tenantA, tenantB)
+	assert.NotContains(t, tenantA[0], "hipertensao")
+	assert.Len(t, tenantA[0], 32)
+
+	// Erased documents are forgotten
+	assert.NoError(t, search.Forget(ctx, first))
+	found, err = search.Search(ctx, "tenant-a", "hipertensão")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-2"}, found)
+	found, err = search.Search(ctx, "tenant-b", "laudo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-3"}, found, "Forgetting a document leaves other tenants alone")
+
+	// Forgetting does nothing when search is disabled
+	var disabled *services.SearchIndex
+	assert.NoError(t, disabled.Forget(ctx, second))
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"io"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestBuildSearchablePDFOverlaysText(t *testing.T) {
+	pages := []image.Image{image.NewRGBA(image.Rect(0, 0, 400, 600))}
+	lines := []models.OCRLine{
+		{Page: 1, Text: "CPF 123.456.789-09", Box: [4]float64{0.1, 0.2, 0.5, 0.25}},
+		{Page: 1, Text: "Nome: João", Box: [4]float64{0.1, 0.1, 0.5, 0.15}},
+		{Page: 3, Text: "beyond the last page", Box: [4]float64{0.1, 0.1, 0.5, 0.15}},
+	}
+
+	pdf, err := services.BuildSearchablePDF(pages, 200, 90, lines, "scan.pdf")
+	assert.NoError(t, err)
+
+	text := string(pdf)
+	assert.Contains(t, text, "/MediaBox [0 0 144.00 216.00]")
+	assert.Contains(t, text, "/Filter /DCTDecode")
+	assert.NotContains(t, text, "/StructTreeRoot", "A searchable PDF is not tagged")
+	assert.Regexp(t, `startxref\n\d+\n%%EOF\n$`, text)
+
+	stream := regexp.MustCompile(`(?s)/Filter /FlateDecode /Length \d+ >>\nstream\n(.*?)\nendstream`).FindStringSubmatch(text)
+	if assert.Len(t, stream, 2) {
+		reader, err := zlib.NewReader(bytes.NewReader([]byte(stream[1])))
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+
+		// Lines are invisible, in reading order, and Latin-1 encoded
+		drawn := regexp.MustCompile(`3 Tr .*?<([0-9A-F]+)> Tj`).FindAllStringSubmatch(string(content), -1)
+		if assert.Len(t, drawn, 2) {
+			assert.Equal(t, "4E6F6D653A204A6FE36F", drawn[0][1])
+			assert.Equal(t, "435046203132332E3435362E3738392D3039", drawn[1][1])
+		}
+		assert.NotContains(t, string(content), "BDC")
+	}
+
+	_, err = services.BuildSearchablePDF(nil, 200, 90, lines, "scan.pdf")
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func tokenHash(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+func TestServiceRolesAreLeastPrivilege(t *testing.T) {
+	worker := &models.ServiceAccount{Name: "ocr-1", Role: models.ServiceRoleOCRWorker}
+	assert.True(t, worker.Allows(models.PermissionDocumentReprocess))
+	assert.False(t, worker.Allows(models.PermissionDocumentDelete), "OCR workers cannot delete documents")
+	assert.False(t, worker.Allows(models.PermissionBulkOperations))
+
+	retention := &models.ServiceAccount{Name: "retention", Role: models.ServiceRoleRetentionJob}
+	assert.True(t, retention.Allows(models.PermissionDocumentDelete))
+	assert.False(t, retention.Allows(models.PermissionReencrypt))
+
+	unknown := &models.ServiceAccount{Name: "legacy", Role: "admin"}
+	assert.False(t, unknown.Allows(models.PermissionDocumentStatus))
+	assert.False(t, models.ValidServiceRole("admin"))
+	assert.True(t, models.ValidServiceRole(models.ServiceRoleMigrationTool))
+}
+
+func TestServiceAccountAuthentication(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.ServiceAccountsConfig = config.ServiceAccountsConfig{
+		Enabled: true,
+		Accounts: []config.ServiceAccountConfig{
+			{Name: "ocr-1", Role: models.ServiceRoleOCRWorker, TokenHash: tokenHash("worker-token")},
+			{Name: "migrator", Role: models.ServiceRoleMigrationTool, TokenHash: tokenHash("migration-token")},
+		},
+	}
+	accounts, err := services.NewServiceAccounts(cfg, zap.NewNop())
+	assert.NoError(t, err)
+
+	account, err := accounts.Authenticate("worker-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "ocr-1", account.Name)
+	assert.Equal(t, models.ServiceRoleOCRWorker, account.Role)
+
+	_, err = accounts.Authenticate("leaked-token")
+	assert.ErrorIs(t, err, services.ErrInvalidServiceToken)
+
+	assert.NoError(t, accounts.Authorize(account, models.PermissionDocumentStatus))
+	assert.ErrorIs(t, accounts.Authorize(account, models.PermissionReencrypt), services.ErrServicePermission)
+	assert.ErrorIs(t, accounts.Authorize(account, ""), services.ErrServicePermission, "Routes without a permission are closed")
+
+	disabled, err := services.NewServiceAccounts(&config.Config{}, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, disabled)
+}
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"                // v1.13.6
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// recordingIngester stands in for the document pipeline, counting the files
+// it ingests and calling fail before each one
+type recordingIngester struct {
+	mu       sync.Mutex
+	ingested map[string]int
+	fail     func(ctx context.Context, filename string) error
+}
+
+func (r *recordingIngester) Ingest(ctx context.Context, req services.IngestRequest) (*models.Document, error) {
+	if r.fail != nil {
+		if err := r.fail(ctx, req.Filename); err != nil {
+			return nil, err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ingested[req.Filename]++
+	return &models.Document{ID: "doc-" + req.Filename, EnrollmentID: req.EnrollmentID}, nil
+}
+
+// newTestSFTPClient connects to an in-memory SFTP server
+func newTestSFTPClient(t *testing.T) *sftp.Client {
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client
+}
+
+func writeSFTPFile(t *testing.T, client *sftp.Client, name string, content []byte) {
+	assert.NoError(t, client.MkdirAll(path.Dir(name)))
+	f, err := client.Create(name)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+	_, err = f.Write(content)
+	assert.NoError(t, err)
+}
+
+func readSFTPReport(t *testing.T, client *sftp.Client, name string) [][]string {
+	f, err := client.Open(name)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	assert.NoError(t, err)
+	return records
+}
+
+func newTestSFTPIngestor(t *testing.T, ingester services.DocumentIngester) *services.SFTPIngestor {
+	roster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]models.Enrollment{{ID: "enr-1", BeneficiaryCPF: "529.982.247-25"}})
+	}))
+	t.Cleanup(roster.Close)
+
+	cfg := &config.Config{}
+	cfg.EnrollmentConfig.BaseURL = roster.URL
+	cfg.EnrollmentConfig.Timeout = time.Second
+	cfg.ServiceConfig.MaxFileSize = 1 << 20
+	cfg.SFTPConfig.InboundDir = "/inbound"
+	cfg.SFTPConfig.ProcessingDir = "/processing"
+	cfg.SFTPConfig.ProcessedDir = "/processed"
+	cfg.SFTPConfig.ReportDir = "/reports"
+	cfg.SFTPConfig.ManifestName = "manifest.csv"
+	cfg.SFTPConfig.FileTimeout = time.Second
+
+	enrollments, err := services.NewEnrollmentClient(cfg)
+	assert.NoError(t, err)
+	ingestor, err := services.NewSFTPIngestor(cfg, ingester, enrollments, zap.NewNop())
+	assert.NoError(t, err)
+	return ingestor
+}
+
+func TestSFTPBatchResumesAfterFailurePartway(t *testing.T) {
+	client := newTestSFTPClient(t)
+	manifest := []string{"file,enrollment_id,document_type,beneficiary_cpf,sha256"}
+	for _, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+		content := []byte("%PDF-1.4 " + name)
+		sum := sha256.Sum256(content)
+		writeSFTPFile(t, client, "/inbound/emp-1/batch-1/"+name, content)
+		manifest = append(manifest, name+",enr-1,identity,52998224725,"+hex.EncodeToString(sum[:]))
+	}
+	writeSFTPFile(t, client, "/inbound/emp-1/batch-1/manifest.csv", []byte(strings.Join(manifest, "\n")+"\n"))
+
+	// The poll is cut short while the second file is being ingested
+	ctx, cancel := context.WithCancel(context.Background())
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	ingester.fail = func(ctx context.Context, filename string) error {
+		if filename == "b.pdf" {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+	ingestor := newTestSFTPIngestor(t, ingester)
+	ingestor.PollClient(ctx, client)
+	assert.Equal(t, map[string]int{"a.pdf": 1}, ingester.ingested)
+
+	_, err := client.Stat("/inbound/emp-1/batch-1")
+	assert.Error(t, err, "The batch is claimed before any file is ingested")
+	_, err = client.Stat("/processing/emp-1/batch-1/manifest.csv")
+	assert.NoError(t, err)
+	_, err = client.Stat("/reports/emp-1/batch-1-reconciliation.csv")
+	assert.Error(t, err, "An interrupted batch is not reported")
+
+	// The next poll resumes after the file already ingested
+	ingester.fail = nil
+	assert.NoError(t, ingestor.PollClient(context.Background(), client))
+	assert.Equal(t, map[string]int{"a.pdf": 1, "b.pdf": 1, "c.pdf": 1}, ingester.ingested)
+
+	report := readSFTPReport(t, client, "/reports/emp-1/batch-1-reconciliation.csv")
+	if assert.Len(t, report, 4) {
+		for i, name := range []string{"a.pdf", "b.pdf", "c.pdf"} {
+			assert.Equal(t, []string{name, "enr-1", "INGESTED", "doc-" + name, ""}, report[i+1])
+		}
+	}
+	_, err = client.Stat("/processed/emp-1/batch-1/manifest.csv")
+	assert.NoError(t, err)
+
+	// A finished batch is not picked up again
+	assert.NoError(t, ingestor.PollClient(context.Background(), client))
+	assert.Equal(t, map[string]int{"a.pdf": 1, "b.pdf": 1, "c.pdf": 1}, ingester.ingested)
+}
+
+func TestSFTPPollPausesDuringMaintenance(t *testing.T) {
+	client := newTestSFTPClient(t)
+	for _, batch := range []string{"batch-1", "batch-2"} {
+		writeSFTPFile(t, client, "/inbound/emp-1/"+batch+"/a.pdf", []byte("%PDF-1.4 a"))
+		writeSFTPFile(t, client, "/inbound/emp-1/"+batch+"/manifest.csv", []byte(
+			"file,enrollment_id,document_type,beneficiary_cpf,sha256\n"+
+				"a.pdf,enr-1,identity,52998224725,\n"))
+	}
+
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	ingester.fail = func(ctx context.Context, filename string) error {
+		return services.ErrMaintenance
+	}
+	ingestor := newTestSFTPIngestor(t, ingester)
+	assert.ErrorIs(t, ingestor.PollClient(context.Background(), client), services.ErrMaintenance)
+	assert.Empty(t, ingester.ingested)
+
+	_, err := client.Stat("/processing/emp-1/batch-1/manifest.csv")
+	assert.NoError(t, err, "The refused batch stays claimed without an outcome")
+	_, err = client.Stat("/inbound/emp-1/batch-2/manifest.csv")
+	assert.NoError(t, err, "No further batch is claimed during maintenance")
+
+	// Once maintenance ends both batches are ingested
+	ingester.fail = nil
+	assert.NoError(t, ingestor.PollClient(context.Background(), client))
+	assert.Equal(t, map[string]int{"a.pdf": 2}, ingester.ingested)
+	for _, batch := range []string{"batch-1", "batch-2"} {
+		report := readSFTPReport(t, client, "/reports/emp-1/"+batch+"-reconciliation.csv")
+		if assert.Len(t, report, 2) {
+			assert.Equal(t, "INGESTED", report[1][2])
+		}
+	}
+}
+
+func TestSFTPBatchRecordsRejectedEntries(t *testing.T) {
+	client := newTestSFTPClient(t)
+	writeSFTPFile(t, client, "/inbound/emp-1/batch-2/a.pdf", []byte("%PDF-1.4 a"))
+	writeSFTPFile(t, client, "/inbound/emp-1/batch-2/manifest.csv", []byte(
+		"file,enrollment_id,document_type,beneficiary_cpf,sha256\n"+
+			"a.pdf,enr-2,identity,52998224725,\n"+
+			"a.pdf,enr-1,identity,52998224725,\n"))
+
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	ingestor := newTestSFTPIngestor(t, ingester)
+	assert.NoError(t, ingestor.PollClient(context.Background(), client))
+
+	report := readSFTPReport(t, client, "/reports/emp-1/batch-2-reconciliation.csv")
+	if assert.Len(t, report, 3) {
+		assert.Equal(t, []string{"a.pdf", "enr-2", "REJECTED", "", services.ErrNotInRoster.Error()}, report[1])
+		assert.Equal(t, "INGESTED", report[2][2])
+	}
+	assert.Equal(t, map[string]int{"a.pdf": 1}, ingester.ingested)
+}
@@ -0,0 +1,255 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.24.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// memoryStore is an in-memory object store
+type memoryStore struct {
+	mu      sync.Mutex
+	name    string
+	objects map[string][]byte
+	failPut bool
+}
+
+func newMemoryStore(name string) *memoryStore {
+	return &memoryStore{name: name, objects: map[string][]byte{}}
+}
+
+func (s *memoryStore) Name() string { return s.name }
+
+func (s *memoryStore) Put(ctx context.Context, key string, content []byte, contentType string, metadata map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failPut {
+		return errors.New("put failed")
+	}
+	s.objects[key] = append([]byte(nil), content...)
+	return nil
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[key]
+	if !ok {
+		return nil, services.ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (s *memoryStore) Stat(ctx context.Context, key string) (services.ObjectInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.objects[key]
+	if !ok {
+		return services.ObjectInfo{}, services.ErrObjectNotFound
+	}
+	return services.ObjectInfo{Size: int64(len(content))}, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryStore) has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.objects[key]
+	return ok
+}
+
+func newShadowStore(t *testing.T, primary, secondary services.ObjectStore, flagStates map[string]bool) *services.ShadowStore {
+	cfg := &config.Config{}
+	cfg.StorageMigrationConfig.CompareReads = true
+	cfg.StorageMigrationConfig.CompareTimeout = time.Second
+	cfg.StorageMigrationConfig.MaxConcurrentCompares = 1
+	cfg.StorageMigrationConfig.ReconcileInterval = time.Minute
+	cfg.StorageMigrationConfig.ReconcileBatchSize = 2
+	for name, enabled := range flagStates {
+		cfg.FeatureFlagsConfig.Flags = append(cfg.FeatureFlagsConfig.Flags, config.FeatureFlagConfig{Name: name, Enabled: enabled})
+	}
+
+	flags, err := services.NewFeatureFlags(cfg, nil, zap.NewNop())
+	assert.NoError(t, err)
+	store, err := services.NewShadowStore(cfg, primary, secondary, flags, zap.NewNop())
+	assert.NoError(t, err)
+	return store
+}
+
+func TestShadowStoreDualWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStore("minio"), newMemoryStore("s3")
+	store := newShadowStore(t, primary, secondary, nil)
+
+	assert.NoError(t, store.Put(ctx, "documents/1", []byte("ciphertext"), "application/pdf", nil))
+	assert.True(t, primary.has("documents/1"))
+	assert.True(t, secondary.has("documents/1"))
+
+	// A failed shadow write does not fail the upload
+	secondary.failPut = true
+	assert.NoError(t, store.Put(ctx, "documents/2", []byte("ciphertext"), "application/pdf", nil))
+	assert.True(t, primary.has("documents/2"))
+	assert.False(t, secondary.has("documents/2"))
+
+	// A failed authoritative write does
+	primary.failPut = true
+	assert.Error(t, store.Put(ctx, "documents/3", []byte("ciphertext"), "application/pdf", nil))
+
+	assert.NoError(t, store.Delete(ctx, "documents/1"))
+	assert.False(t, primary.has("documents/1"))
+	assert.False(t, secondary.has("documents/1"))
+}
+
+func TestShadowStoreReads(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStore("minio"), newMemoryStore("s3")
+	primary.objects["documents/old"] = []byte("written before dual-writes")
+	primary.objects["documents/new"] = []byte("minio copy")
+	secondary.objects["documents/new"] = []byte("s3 copy")
+
+	read := func(store *services.ShadowStore, key string) string {
+		reader, err := store.Get(ctx, key)
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	store := newShadowStore(t, primary, secondary, nil)
+	assert.Equal(t, "minio copy", read(store, "documents/new"))
+	assert.Equal(t, "written before dual-writes", read(store, "documents/old"))
+
+	// Cutover through the feature flag serves reads from the secondary and
+	// still falls back for objects only MinIO holds
+	cutover := newShadowStore(t, primary, secondary, map[string]bool{services.FlagStorageReadSecondary: true})
+	assert.Equal(t, "s3 copy", read(cutover, "documents/new"))
+	assert.Equal(t, "written before dual-writes", read(cutover, "documents/old"))
+
+	_, err := store.Get(ctx, "documents/missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+}
+
+// blockingStore holds shadow reads until released
+type blockingStore struct {
+	*memoryStore
+	gets    atomic.Int32
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.gets.Add(1)
+	<-s.release
+	return s.memoryStore.Get(ctx, key)
+}
+
+func TestShadowStoreBoundsComparisons(t *testing.T) {
+	ctx := context.Background()
+	primary := newMemoryStore("minio")
+	secondary := &blockingStore{memoryStore: newMemoryStore("s3"), release: make(chan struct{})}
+	defer close(secondary.release)
+	primary.objects["documents/1"] = []byte("ciphertext")
+	store := newShadowStore(t, primary, secondary, nil)
+
+	for i := 0; i < 5; i++ {
+		reader, err := store.Get(ctx, "documents/1")
+		assert.NoError(t, err)
+		reader.Close()
+	}
+	// The first comparison holds the only slot, so the others are skipped
+	assert.Eventually(t, func() bool { return secondary.gets.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), secondary.gets.Load())
+}
+
+func TestShadowStoreRepairsDivergedObjects(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStore("minio"), newMemoryStore("s3")
+	primary.objects["documents/old"] = []byte("written before dual-writes")
+	store := newShadowStore(t, primary, secondary, nil)
+
+	secondary.failPut = true
+	assert.NoError(t, store.Put(ctx, "documents/1", []byte("ciphertext"), "application/pdf", nil))
+	assert.False(t, secondary.has("documents/1"))
+
+	// A repair failing again keeps the key for the next one
+	store.RepairPending(ctx)
+	assert.False(t, secondary.has("documents/1"))
+	secondary.failPut = false
+	store.RepairPending(ctx)
+	assert.True(t, secondary.has("documents/1"))
+
+	// After cutover, an object only MinIO holds is copied to the new
+	// authoritative backend once read
+	cutover := newShadowStore(t, primary, secondary, map[string]bool{services.FlagStorageReadSecondary: true})
+	_, err := cutover.Get(ctx, "documents/old")
+	assert.NoError(t, err)
+	cutover.RepairPending(ctx)
+	assert.True(t, secondary.has("documents/old"))
+
+	// An object deleted from both backends is not brought back
+	assert.NoError(t, store.Delete(ctx, "documents/1"))
+	copied, err := store.Repair(ctx, "documents/1")
+	assert.NoError(t, err)
+	assert.False(t, copied)
+	assert.False(t, primary.has("documents/1"))
+	assert.False(t, secondary.has("documents/1"))
+}
+
+func TestShadowBackfillCopiesMissingObjects(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStore("minio"), newMemoryStore("s3")
+	store := newShadowStore(t, primary, secondary, nil)
+
+	documents := repository.NewMemoryDocumentRepository()
+	start := time.Now().Add(-time.Hour)
+	for i := 1; i <= 3; i++ {
+		doc := &models.Document{
+			ID:          fmt.Sprintf("doc-%d", i),
+			StoragePath: fmt.Sprintf("documents/%d", i),
+			UpdatedAt:   start.Add(time.Duration(i) * time.Minute),
+		}
+		assert.NoError(t, documents.Create(ctx, doc))
+		primary.objects[doc.StoragePath] = []byte("stored before dual-writes")
+	}
+	// Already copied objects are left alone
+	secondary.objects["documents/1"] = []byte("stored before dual-writes")
+
+	cfg := &config.Config{}
+	cfg.StorageMigrationConfig.ReconcileInterval = time.Minute
+	cfg.StorageMigrationConfig.ReconcileBatchSize = 2
+	backfill, err := services.NewShadowBackfill(cfg, documents, store, zap.NewNop())
+	assert.NoError(t, err)
+
+	checked, copied, err := backfill.Backfill(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, checked, "A pass covers one batch")
+	assert.Equal(t, 1, copied)
+	assert.False(t, secondary.has("documents/3"))
+
+	_, copied, err = backfill.Backfill(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, copied)
+	for i := 1; i <= 3; i++ {
+		assert.True(t, secondary.has(fmt.Sprintf("documents/%d", i)))
+	}
+}
@@ -0,0 +1,100 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+func TestContentSealedUnderReplacedSharedKeyStaysReadable(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.SecurityConfig.EncryptionKey = "alias/replaced-shared-key"
+	cfg.SecurityConfig.EncryptionAlgorithm = utils.ConfigAlgorithmAES
+	cfg.SecurityConfig.KeyRotationInterval = 90 * 24 * time.Hour
+
+	useKey := func() {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		assert.NoError(t, err)
+		assert.NoError(t, utils.UseDataKey(cfg, "arn:aws:kms:us-east-1:000000000000:key/shared", key))
+	}
+	seal := func(id string, content []byte) (*models.Document, []byte) {
+		doc := &models.Document{ID: id, Size: int64(len(content))}
+		sealed, err := utils.EncryptDocument(ctx, doc, bytes.NewReader(content), cfg)
+		if !assert.NoError(t, err) {
+			return doc, nil
+		}
+		ciphertext, err := io.ReadAll(sealed)
+		assert.NoError(t, err)
+		return doc, ciphertext
+	}
+
+	useKey()
+	first, firstCiphertext := seal("doc-1", []byte("sealed under the first key"))
+	// The shared key is replaced, as on reaching its message limit or expiring
+	useKey()
+	second, secondCiphertext := seal("doc-2", []byte("sealed under the second key"))
+
+	assert.NotEmpty(t, first.EncryptionInfo.SharedKey)
+	assert.NotEqual(t, first.EncryptionInfo.SharedKey, second.EncryptionInfo.SharedKey, "Each shared key is recorded with the content it sealed")
+
+	for _, sealed := range []struct {
+		doc        *models.Document
+		ciphertext []byte
+		content    string
+	}{
+		{first, firstCiphertext, "sealed under the first key"},
+		{second, secondCiphertext, "sealed under the second key"},
+	} {
+		opened, err := utils.DecryptDocument(ctx, sealed.doc, bytes.NewReader(sealed.ciphertext), cfg)
+		if assert.NoError(t, err) {
+			plaintext, err := io.ReadAll(opened)
+			assert.NoError(t, err)
+			assert.Equal(t, sealed.content, string(plaintext))
+		}
+	}
+}
+
+func TestExpiredSharedKeysAreSwept(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.SecurityConfig.EncryptionKey = "alias/swept-shared-key"
+	cfg.SecurityConfig.EncryptionAlgorithm = utils.ConfigAlgorithmAES
+	cfg.SecurityConfig.KeyRotationInterval = 90 * 24 * time.Hour
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	assert.NoError(t, utils.UseDataKey(cfg, "arn:aws:kms:us-east-1:000000000000:key/swept", key))
+
+	doc := &models.Document{ID: "doc-swept", Size: 6}
+	sealed, err := utils.EncryptDocument(ctx, doc, bytes.NewReader([]byte("sealed")), cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	ciphertext, err := io.ReadAll(sealed)
+	assert.NoError(t, err)
+	open := func() error {
+		_, err := utils.DecryptDocument(ctx, doc, bytes.NewReader(ciphertext), cfg)
+		return err
+	}
+
+	utils.SweepKeyCachesAt(time.Now().Add(30 * time.Minute))
+	assert.NoError(t, open(), "A key that has not expired should be kept")
+
+	// The local key cannot be unwrapped with KMS once it is dropped
+	utils.SweepKeyCachesAt(time.Now().Add(2 * time.Hour))
+	assert.ErrorIs(t, open(), utils.ErrInvalidMetadata, "An expired key should be dropped from the cache")
+}
@@ -0,0 +1,118 @@
+//go:build testhooks
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/utils"
+)
+
+// unavailableShreddedKeys is a shredded key registry that cannot be reached
+type unavailableShreddedKeys struct{}
+
+func (unavailableShreddedKeys) Record(ctx context.Context, wrappedKey string) error {
+	return errors.New("database unavailable")
+}
+
+func (unavailableShreddedKeys) Shredded(ctx context.Context, wrappedKey string) (bool, error) {
+	return false, errors.New("database unavailable")
+}
+
+// documentKeyCiphertext seals content and returns its ciphertext with
+// metadata naming a document data key
+func documentKeyCiphertext(t *testing.T, content []byte) (*config.Config, []byte, *models.EncryptionMetadata) {
+	cfg := propertyEncryptionConfig(t, utils.ConfigAlgorithmAES)
+	doc := &models.Document{ID: "shredded-document", Size: int64(len(content))}
+	sealed, err := utils.EncryptDocument(context.Background(), doc, bytes.NewReader(content), cfg)
+	assert.NoError(t, err)
+	ciphertext, err := io.ReadAll(sealed)
+	assert.NoError(t, err)
+
+	metadata := *doc.EncryptionInfo
+	metadata.KeyID = "arn:aws:kms:us-east-1:000000000000:key/shredding"
+	metadata.WrappedKey = "d3JhcHBlZCBkb2N1bWVudCBrZXk="
+	metadata.GrantID = "grant-shredded-document"
+	return cfg, ciphertext, &metadata
+}
+
+func TestCryptoShreddingRequiresGranteePrincipal(t *testing.T) {
+	_, err := loadTestConfig(t, `
+crypto_shredding:
+  enabled: true
+`)
+	assert.ErrorContains(t, err, "grantee principal", "Without a grant to revoke a shredded key stays unwrappable")
+
+	cfg, err := loadTestConfig(t, `
+crypto_shredding:
+  enabled: true
+  grantee_principal: arn:aws:iam::000000000000:role/document-service
+`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "arn:aws:iam::000000000000:role/document-service", cfg.CryptoShreddingConfig.GranteePrincipal)
+	}
+}
+
+func TestShreddedKeyIsRefusedByEveryInstance(t *testing.T) {
+	content := []byte("Document content sealed under its own key")
+	cfg, ciphertext, metadata := documentKeyCiphertext(t, content)
+
+	// Another instance shredded the key; this one may still cache it
+	keys := repository.NewMemoryShreddedKeyRepository()
+	assert.NoError(t, keys.Record(context.Background(), metadata.WrappedKey))
+	assert.NoError(t, keys.Record(context.Background(), metadata.WrappedKey), "Recording a key again is harmless")
+	utils.SetShreddedKeys(keys)
+	defer utils.SetShreddedKeys(nil)
+
+	_, err := utils.OpenCiphertext(context.Background(), "shredded-document", ciphertext, int64(len(content)), metadata, cfg)
+	assert.ErrorIs(t, err, utils.ErrKeyShredded)
+
+	shredded, err := keys.Shredded(context.Background(), "b3RoZXIgd3JhcHBlZCBrZXk=")
+	assert.NoError(t, err)
+	assert.False(t, shredded)
+
+	// Content under the shared key is not affected
+	opened, err := utils.OpenCiphertext(context.Background(), "shredded-document", ciphertext, int64(len(content)), func() *models.EncryptionMetadata {
+		shared := *metadata
+		shared.KeyID, shared.WrappedKey, shared.GrantID = "", "", ""
+		return &shared
+	}(), cfg)
+	if assert.NoError(t, err) {
+		assert.Equal(t, content, opened)
+	}
+}
+
+func TestShreddedKeyCheckFailsClosed(t *testing.T) {
+	content := []byte("Document content sealed under its own key")
+	cfg, ciphertext, metadata := documentKeyCiphertext(t, content)
+
+	utils.SetShreddedKeys(unavailableShreddedKeys{})
+	defer utils.SetShreddedKeys(nil)
+
+	_, err := utils.OpenCiphertext(context.Background(), "shredded-document", ciphertext, int64(len(content)), metadata, cfg)
+	assert.ErrorIs(t, err, utils.ErrKeyManagement, "A key that may be shredded must not be used")
+}
+
+func TestShreddingKeyWithoutGrantFails(t *testing.T) {
+	cfg, _, metadata := documentKeyCiphertext(t, []byte("Document content"))
+	metadata.GrantID = ""
+
+	keys := repository.NewMemoryShreddedKeyRepository()
+	utils.SetShreddedKeys(keys)
+	defer utils.SetShreddedKeys(nil)
+
+	err := utils.ShredDataKey(context.Background(), cfg, metadata)
+	assert.ErrorIs(t, err, utils.ErrNoKeyGrant, "A key KMS still unwraps cannot be reported as shredded")
+	shredded, err := keys.Shredded(context.Background(), metadata.WrappedKey)
+	assert.NoError(t, err)
+	assert.False(t, shredded)
+}
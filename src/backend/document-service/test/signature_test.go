@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.mozilla.org/pkcs7"               // v0.10.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// icpAuthority is a certificate authority standing in for an ICP-Brasil root
+type icpAuthority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newICPAuthority(t *testing.T, name string) *icpAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &icpAuthority{cert: cert, key: key}
+}
+
+// issue returns a signing certificate for the ICP-Brasil common name
+func (ca *icpAuthority) issue(t *testing.T, commonName string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+// signedPDF returns a PDF with one detached PAdES signature of the signer
+// over everything but its /Contents gap
+func signedPDF(t *testing.T, ca *icpAuthority, cert *x509.Certificate, key *ecdsa.PrivateKey) []byte {
+	const gapSize = 8192
+	body := "%%PDF-1.7\n1 0 obj\n<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /ETSI.CAdES.detached " +
+		"/ByteRange [%010d %010d %010d %010d] /Contents <" + strings.Repeat("0", gapSize) + "> >>\nendobj\n%%%%EOF\n"
+	template := fmt.Sprintf(body, 0, 0, 0, 0)
+	gapStart := strings.Index(template, "/Contents <") + len("/Contents ")
+	gapEnd := gapStart + gapSize + 2
+	pdf := []byte(fmt.Sprintf(body, 0, gapStart, gapEnd, len(template)-gapEnd))
+
+	signed, err := pkcs7.NewSignedData(append(append([]byte(nil), pdf[:gapStart]...), pdf[gapEnd:]...))
+	assert.NoError(t, err)
+	assert.NoError(t, signed.AddSignerChain(cert, key, []*x509.Certificate{ca.cert}, pkcs7.SignerInfoConfig{}))
+	signed.Detach()
+	der, err := signed.Finish()
+	assert.NoError(t, err)
+
+	contents := hex.EncodeToString(der)
+	assert.Less(t, len(contents), gapSize)
+	copy(pdf[gapStart+1:], contents)
+	return pdf
+}
+
+func newTestSignatureStep(t *testing.T, roots ...*icpAuthority) *services.SignatureStep {
+	bundle := make([]byte, 0)
+	for _, root := range roots {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.cert.Raw})...)
+	}
+	path := filepath.Join(t.TempDir(), "icp-brasil.pem")
+	assert.NoError(t, os.WriteFile(path, bundle, 0o600))
+
+	cfg := &config.Config{}
+	cfg.SignatureConfig.TrustedRootsPath = path
+	step, err := services.NewSignatureStep(cfg)
+	assert.NoError(t, err)
+	return step
+}
+
+// verifySignature runs the step on the PDF and returns its only signature
+func verifySignature(t *testing.T, step *services.SignatureStep, pdf []byte) models.SignatureInfo {
+	doc := &models.Document{ID: "doc-1", ContentType: "application/pdf"}
+	assert.True(t, step.Applies(doc))
+	assert.NoError(t, step.Execute(context.Background(), &services.PipelineRun{Document: doc, Content: pdf}))
+	if !assert.Len(t, doc.Signatures, 1) {
+		return models.SignatureInfo{}
+	}
+	return doc.Signatures[0]
+}
+
+func TestSignatureStepVerifiesICPBrasilSignature(t *testing.T) {
+	root := newICPAuthority(t, "AC Raiz Teste")
+	cert, key := root.issue(t, "MARIA SOUZA:12345678901")
+	step := newTestSignatureStep(t, root)
+
+	info := verifySignature(t, step, signedPDF(t, root, cert, key))
+	assert.True(t, info.Valid, info.FailureReason)
+	assert.True(t, info.CoversDocument)
+	assert.Equal(t, "MARIA SOUZA", info.SignerName)
+	assert.Equal(t, "12345678901", info.SignerCPF)
+	assert.Equal(t, "AC Raiz Teste", info.Issuer)
+	assert.NotNil(t, info.SignedAt)
+}
+
+func TestSignatureStepRejectsAlteredDocument(t *testing.T) {
+	root := newICPAuthority(t, "AC Raiz Teste")
+	cert, key := root.issue(t, "MARIA SOUZA:12345678901")
+	step := newTestSignatureStep(t, root)
+
+	pdf := signedPDF(t, root, cert, key)
+	// Alter a byte covered by the signature
+	pdf[len("%PDF-1.")] = '4'
+
+	info := verifySignature(t, step, pdf)
+	assert.False(t, info.Valid)
+	assert.NotEmpty(t, info.FailureReason)
+	assert.Equal(t, "MARIA SOUZA", info.SignerName, "The signer is still reported on an invalid signature")
+}
+
+func TestSignatureStepRejectsUntrustedChain(t *testing.T) {
+	root := newICPAuthority(t, "AC Raiz Teste")
+	untrusted := newICPAuthority(t, "AC Desconhecida")
+	cert, key := untrusted.issue(t, "JOSE DA SILVA:98765432100")
+	step := newTestSignatureStep(t, root)
+
+	info := verifySignature(t, step, signedPDF(t, untrusted, cert, key))
+	assert.False(t, info.Valid, "A signature chaining to a root outside ICP-Brasil must not be valid")
+	assert.NotEmpty(t, info.FailureReason)
+	assert.Equal(t, "AC Desconhecida", info.Issuer)
+}
@@ -0,0 +1,117 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"           // v1.9.1
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/handlers"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func signingConfig(keyID string, keys map[string]string) *config.Config {
+	cfg := &config.Config{}
+	cfg.RequestSigningConfig = config.RequestSigningConfig{
+		Enabled: true,
+		KeyID:   keyID,
+		Keys:    keys,
+		MaxSkew: time.Minute,
+	}
+	return cfg
+}
+
+// postWebhook posts body to path of a server routing it through
+// RequireSignedRequest to handler, signed under cfg when signed is set, and
+// returns the response status
+func postWebhook(t *testing.T, cfg *config.Config, path string, handler gin.HandlerFunc, body []byte, signed bool) int {
+	gin.SetMode(gin.TestMode)
+	signer := services.NewRequestSigner(cfg)
+	router := gin.New()
+	router.POST(path, handlers.RequireSignedRequest(signer, zap.NewNop()), handler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := http.DefaultClient
+	if signed {
+		client = &http.Client{Transport: services.SignedTransport(signer, nil)}
+	}
+	resp, err := client.Post(server.URL+path, "application/json", bytes.NewReader(body))
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRequestSigningAcrossKeyRotation(t *testing.T) {
+	keys := map[string]string{"k1": strings.Repeat("a", 32), "k2": strings.Repeat("b", 32)}
+
+	// The receiver already accepts the new key while the caller still signs
+	// with the old one, then the caller switches
+	receiver := services.NewRequestSigner(signingConfig("k2", keys))
+	var verified []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		keyID, err := receiver.Verify(r, body)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		verified = append(verified, keyID)
+	}))
+	defer server.Close()
+
+	for _, keyID := range []string{"k1", "k2"} {
+		signer := services.NewRequestSigner(signingConfig(keyID, map[string]string{keyID: keys[keyID]}))
+		client := &http.Client{Transport: services.SignedTransport(signer, nil)}
+		resp, err := client.Post(server.URL+"/webhooks/consent?source=test", "application/json", bytes.NewReader([]byte(`{"event_id":"1"}`)))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+	}
+	assert.Equal(t, []string{"k1", "k2"}, verified)
+}
+
+func TestRequestSignatureRejections(t *testing.T) {
+	signer := services.NewRequestSigner(signingConfig("k1", map[string]string{"k1": strings.Repeat("a", 32)}))
+	body := []byte(`{"event_id":"1"}`)
+
+	signed := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/consent", bytes.NewReader(body))
+		assert.NoError(t, signer.Sign(req))
+		return req
+	}
+
+	_, err := signer.Verify(signed(), body)
+	assert.NoError(t, err)
+
+	_, err = signer.Verify(signed(), []byte(`{"event_id":"2"}`))
+	assert.ErrorIs(t, err, services.ErrContentDigestMismatch)
+
+	req := signed()
+	req.URL.Path = "/webhooks/other"
+	_, err = signer.Verify(req, body)
+	assert.ErrorIs(t, err, services.ErrInvalidRequestSignature, "The signature should cover the path")
+
+	req = signed()
+	req.Header.Set(services.SignatureTimestampHeader, "1000")
+	_, err = signer.Verify(req, body)
+	assert.ErrorIs(t, err, services.ErrSignatureExpired)
+
+	req = signed()
+	req.Header.Set(services.SignatureKeyIDHeader, "retired")
+	_, err = signer.Verify(req, body)
+	assert.ErrorIs(t, err, services.ErrUnknownSigningKey)
+
+	_, err = signer.Verify(httptest.NewRequest(http.MethodPost, "/webhooks/consent", nil), nil)
+	assert.ErrorIs(t, err, services.ErrRequestNotSigned)
+}
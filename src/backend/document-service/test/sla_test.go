@@ -0,0 +1,87 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+func TestSLAClockStopsAtFinalDecision(t *testing.T) {
+	doc := awaitingReview(t, "identity")
+	doc.CreatedAt = time.Now().Add(-30 * time.Hour)
+
+	clock := models.NewSLAClock(doc, 24*time.Hour, nil, time.Now())
+	assert.True(t, clock.Breached)
+	assert.Nil(t, clock.StoppedAt)
+	assert.InDelta(t, -6*3600, clock.RemainingSeconds, 1)
+	assert.InDelta(t, 1.25, clock.Elapsed, 0.01)
+
+	assert.NoError(t, doc.Review(models.ReviewDecisionApprove, "", "reviewer-1"))
+	clock = models.NewSLAClock(doc, 48*time.Hour, nil, time.Now().Add(72*time.Hour))
+	assert.False(t, clock.Breached, "The clock stops at the decision")
+	assert.NotNil(t, clock.StoppedAt)
+	assert.InDelta(t, 18*3600, clock.RemainingSeconds, 1)
+}
+
+func TestEscalateRaisesOneLevelPerThresholdPassed(t *testing.T) {
+	thresholds := []float64{0.5, 0.8, 1}
+	doc := awaitingReview(t, "identity")
+	now := doc.CreatedAt
+
+	assert.Nil(t, doc.Escalate(models.NewSLAClock(doc, 10*time.Hour, nil, now.Add(4*time.Hour)), thresholds))
+
+	escalation := doc.Escalate(models.NewSLAClock(doc, 10*time.Hour, nil, now.Add(5*time.Hour)), thresholds)
+	assert.NotNil(t, escalation)
+	assert.Equal(t, 1, escalation.Level)
+	assert.Nil(t, doc.Escalate(models.NewSLAClock(doc, 10*time.Hour, nil, now.Add(6*time.Hour)), thresholds), "A level is raised once")
+
+	escalation = doc.Escalate(models.NewSLAClock(doc, 10*time.Hour, nil, now.Add(11*time.Hour)), thresholds)
+	assert.NotNil(t, escalation)
+	assert.Equal(t, 3, escalation.Level, "Thresholds passed together raise one escalation")
+	assert.Equal(t, 1.0, escalation.Threshold)
+	assert.Equal(t, 3, doc.SLALevel())
+	assert.Len(t, doc.SLAEscalations, 2)
+
+	events := models.EventsForAudit(doc.ID, doc.AuditTrail[len(doc.AuditTrail)-1:])
+	assert.Equal(t, models.EventSLAEscalated, events[0].Type)
+
+	assert.NoError(t, doc.Review(models.ReviewDecisionReject, "Illegible", "reviewer-1"))
+	assert.Equal(t, 0, models.SLAThresholdsPassed(models.NewSLAClock(doc, time.Hour, nil, now.Add(48*time.Hour)), thresholds))
+}
+
+func TestSLAReportCountsDocumentsDueInPeriodPerTenant(t *testing.T) {
+	now := time.Now()
+	from, to := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	stopped := now.Add(-2 * time.Hour)
+	clock := func(due time.Time, stoppedAt *time.Time, level int) *models.SLAClock {
+		end := now
+		if stoppedAt != nil {
+			end = *stoppedAt
+		}
+		return &models.SLAClock{DueAt: due, StoppedAt: stoppedAt, Breached: end.After(due), EscalationLevel: level}
+	}
+
+	report := models.NewSLAReport(from, to)
+	report.Add("tenant-b", clock(now.Add(-time.Hour), &stopped, 1))
+	report.Add("tenant-b", clock(now.Add(-3*time.Hour), &stopped, 2))
+	report.Add("tenant-b", clock(now.Add(-time.Hour), nil, 3))
+	report.Add("tenant-b", clock(now.Add(time.Hour), nil, 0))
+	report.Add("tenant-a", clock(now.Add(time.Hour), nil, 0))
+	report.Add("tenant-a", clock(now.Add(-48*time.Hour), nil, 3))
+	report.Finish()
+
+	assert.Len(t, report.Tenants, 2)
+	assert.Equal(t, models.SLATenantCompliance{TenantID: "tenant-a", Pending: 1, Compliance: 1}, report.Tenants[0])
+	assert.Equal(t, models.SLATenantCompliance{
+		TenantID:   "tenant-b",
+		Met:        1,
+		Breached:   2,
+		Open:       1,
+		Pending:    1,
+		Escalated:  3,
+		Compliance: 1.0 / 3,
+	}, report.Tenants[1])
+}
@@ -0,0 +1,49 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+)
+
+func TestBudgetRemaining(t *testing.T) {
+	assert.Equal(t, 1.0, models.BudgetRemaining(0.99, 0, 0), "No events spend no budget")
+	assert.Equal(t, 1.0, models.BudgetRemaining(0.99, 200, 0))
+	assert.InDelta(t, 0.5, models.BudgetRemaining(0.99, 200, 1), 1e-9)
+	assert.InDelta(t, 0.0, models.BudgetRemaining(0.99, 200, 2), 1e-9, "An error rate at 1-objective spends the whole budget")
+	assert.InDelta(t, -4.0, models.BudgetRemaining(0.9, 20, 10), 1e-9)
+}
+
+func TestDeferredDocumentRecordsProcessingDeferred(t *testing.T) {
+	ctx := context.Background()
+	documents := repository.NewEventSourcedDocumentRepository(repository.NewMemoryDocumentEventRepository(), repository.NewMemoryDocumentRepository())
+
+	doc, err := models.NewDocument(testEnrollmentID, "identity", testFilename, "application/pdf", 1024)
+	assert.NoError(t, err)
+	doc.ID = "doc-1"
+	assert.NoError(t, documents.Create(ctx, doc))
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusOCRDeferred, "OCR deferred while its error budget is exhausted"))
+	assert.NoError(t, documents.Update(ctx, doc))
+	assert.Nil(t, doc.ProcessedAt, "A deferred document is not processed")
+	assert.False(t, doc.AwaitingReview(), "A deferred document has no estimate")
+
+	assert.NoError(t, doc.UpdateStatus(models.DocumentStatusCompleted, "Processing after deferred OCR"))
+	assert.NoError(t, documents.Update(ctx, doc))
+
+	history, err := documents.History(ctx, doc.ID)
+	assert.NoError(t, err)
+	types := make([]string, 0, len(history))
+	for _, event := range history {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		models.EventDocumentUploaded,
+		models.EventProcessingDeferred,
+		models.EventProcessingCompleted,
+	}, types)
+}
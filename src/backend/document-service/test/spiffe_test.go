@@ -0,0 +1,183 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// spiffeCA issues X.509 SVIDs for tests
+type spiffeCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newSPIFFECA(t *testing.T) *spiffeCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test SPIRE CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return &spiffeCA{cert: cert, key: key}
+}
+
+// issue returns an SVID for the SPIFFE ID with its key
+func (ca *spiffeCA) issue(t *testing.T, spiffeID string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	id, err := url.Parse(spiffeID)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{id},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+// newWorkloadIdentity writes the SVID of the document service and the bundle
+// the SPIRE agent would, and loads them
+func newWorkloadIdentity(t *testing.T, ca *spiffeCA) *services.WorkloadIdentity {
+	dir := t.TempDir()
+	cert, key := ca.issue(t, "spiffe://austa.example/document-service")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.SPIFFEConfig = config.SPIFFEConfig{
+		Enabled:        true,
+		TrustDomain:    "austa.example",
+		SVIDPath:       filepath.Join(dir, "svid.pem"),
+		SVIDKeyPath:    filepath.Join(dir, "svid_key.pem"),
+		BundlePath:     filepath.Join(dir, "bundle.pem"),
+		ReloadInterval: time.Minute,
+		Policies: []config.SPIFFEPolicyConfig{
+			{ID: "spiffe://austa.example/ocr-worker", Role: models.ServiceRoleOCRWorker},
+			{ID: "spiffe://austa.example/jobs/*", Role: models.ServiceRoleRetentionJob},
+		},
+	}
+	assert.NoError(t, os.WriteFile(cfg.SPIFFEConfig.SVIDPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600))
+	assert.NoError(t, os.WriteFile(cfg.SPIFFEConfig.SVIDKeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	assert.NoError(t, os.WriteFile(cfg.SPIFFEConfig.BundlePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600))
+
+	identity, err := services.NewWorkloadIdentity(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	return identity
+}
+
+func TestWorkloadIdentityAuthenticate(t *testing.T) {
+	ca := newSPIFFECA(t)
+	identity := newWorkloadIdentity(t, ca)
+
+	worker, _ := ca.issue(t, "spiffe://austa.example/ocr-worker")
+	account, err := identity.Authenticate([]*x509.Certificate{worker})
+	assert.NoError(t, err)
+	assert.Equal(t, "spiffe://austa.example/ocr-worker", account.Name)
+	assert.Equal(t, models.ServiceRoleOCRWorker, account.Role)
+	assert.NoError(t, identity.Authorize(account, models.PermissionDocumentReprocess))
+	assert.ErrorIs(t, identity.Authorize(account, models.PermissionDocumentDelete), services.ErrServicePermission)
+
+	retention, _ := ca.issue(t, "spiffe://austa.example/jobs/retention")
+	account, err = identity.Authenticate([]*x509.Certificate{retention})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ServiceRoleRetentionJob, account.Role, "Wildcard policies match the IDs under them")
+
+	jobs, _ := ca.issue(t, "spiffe://austa.example/jobs/")
+	_, err = identity.Authenticate([]*x509.Certificate{jobs})
+	assert.ErrorIs(t, err, services.ErrUnknownWorkload, "Wildcard policies do not match their own prefix")
+
+	unknown, _ := ca.issue(t, "spiffe://austa.example/ocr-worker-2")
+	_, err = identity.Authenticate([]*x509.Certificate{unknown})
+	assert.ErrorIs(t, err, services.ErrUnknownWorkload)
+
+	foreign, _ := ca.issue(t, "spiffe://other.example/ocr-worker")
+	_, err = identity.Authenticate([]*x509.Certificate{foreign})
+	assert.ErrorIs(t, err, services.ErrInvalidSVID, "IDs of other trust domains are refused")
+
+	_, err = identity.Authenticate([]*x509.Certificate{ca.cert})
+	assert.ErrorIs(t, err, services.ErrInvalidSVID, "CA certificates are not SVIDs")
+
+	_, err = identity.Authenticate(nil)
+	assert.ErrorIs(t, err, services.ErrInvalidSVID)
+}
+
+func TestWorkloadIdentityRequiresClientSVID(t *testing.T) {
+	ca := newSPIFFECA(t)
+	identity := newWorkloadIdentity(t, ca)
+
+	var peer string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		account, err := identity.Authenticate(r.TLS.PeerCertificates)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		peer = account.Name
+	}))
+	server.TLS = identity.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	client := func(certificates ...tls.Certificate) *http.Client {
+		// SVIDs carry no DNS names; the server's identity is not under test
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates:       certificates,
+			InsecureSkipVerify: true,
+		}}}
+	}
+
+	cert, key := ca.issue(t, "spiffe://austa.example/ocr-worker")
+	resp, err := client(tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}).Get(server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "spiffe://austa.example/ocr-worker", peer)
+
+	_, err = client().Get(server.URL)
+	assert.Error(t, err, "Peers without an SVID fail the handshake")
+
+	rogue := newSPIFFECA(t)
+	cert, key = rogue.issue(t, "spiffe://austa.example/ocr-worker")
+	_, err = client(tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key}).Get(server.URL)
+	assert.Error(t, err, "SVIDs outside the trust bundle fail the handshake")
+
+	disabled, err := services.NewWorkloadIdentity(&config.Config{}, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, disabled)
+}
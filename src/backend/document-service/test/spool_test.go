@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newSpoolConfig(t *testing.T, maxBytes int64) *config.Config {
+	cfg := &config.Config{}
+	cfg.SpoolConfig = config.SpoolConfig{
+		Enabled:       true,
+		Directory:     t.TempDir(),
+		MaxBytes:      maxBytes,
+		DrainInterval: time.Second,
+	}
+	return cfg
+}
+
+func TestUploadSpoolRefusesObjectsOnceFull(t *testing.T) {
+	spool, err := services.NewUploadSpool(newSpoolConfig(t, 10), zap.NewNop())
+	assert.NoError(t, err)
+
+	assert.NoError(t, spool.Put(services.SpoolEntry{Key: "documents/a", DocumentID: "a", SpooledAt: time.Now()}, []byte("123456")))
+	err = spool.Put(services.SpoolEntry{Key: "documents/b", DocumentID: "b", SpooledAt: time.Now()}, []byte("123456"))
+	assert.ErrorIs(t, err, services.ErrSpoolFull)
+
+	// Removing a drained object frees its space
+	assert.NoError(t, spool.Remove("documents/a"))
+	assert.NoError(t, spool.Put(services.SpoolEntry{Key: "documents/b", DocumentID: "b", SpooledAt: time.Now()}, []byte("123456")))
+	used, objects := spool.Usage()
+	assert.Equal(t, int64(6), used)
+	assert.Equal(t, 1, objects)
+
+	_, err = spool.Get("documents/a")
+	assert.ErrorIs(t, err, services.ErrSpoolNotFound)
+}
+
+func TestUploadSpoolSurvivesRestart(t *testing.T) {
+	cfg := newSpoolConfig(t, 1024)
+	spool, err := services.NewUploadSpool(cfg, zap.NewNop())
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.NoError(t, spool.Put(services.SpoolEntry{Key: "documents/new", DocumentID: "new", SpooledAt: now}, []byte("newer")))
+	assert.NoError(t, spool.Put(services.SpoolEntry{Key: "documents/old", DocumentID: "old", SpooledAt: now.Add(-time.Minute)}, []byte("older")))
+
+	reopened, err := services.NewUploadSpool(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	entries := reopened.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "documents/old", entries[0].Key, "Entries should drain oldest first")
+		assert.Equal(t, int64(5), entries[0].Size)
+	}
+	content, err := reopened.Get("documents/new")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("newer"), content)
+}
+
+func TestSpooledDocumentIsMarkedUntilDrained(t *testing.T) {
+	doc, err := models.NewDocument("enrollment-1", "id_card", "id.pdf", "application/pdf", 1024)
+	assert.NoError(t, err)
+
+	doc.MarkSpooled(time.Now())
+	assert.True(t, doc.Spooled())
+	assert.NotNil(t, doc.SpooledAt)
+
+	doc.MarkDrained(time.Now())
+	assert.False(t, doc.Spooled())
+	assert.Nil(t, doc.SpooledAt)
+
+	actions := make([]string, 0, len(doc.AuditTrail))
+	for _, entry := range doc.AuditTrail {
+		actions = append(actions, entry.Action)
+	}
+	assert.Contains(t, actions, "SPOOL")
+	assert.Contains(t, actions, "SPOOL_DRAINED")
+}
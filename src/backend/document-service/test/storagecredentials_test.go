@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// rotatingBucket is an S3 endpoint accepting requests signed with one access
+// key at a time, and a Vault leasing a new key on every read
+type rotatingBucket struct {
+	mu         sync.Mutex
+	valid      string
+	rejectNext bool
+	leases     int
+	vaultUp    bool
+}
+
+func (b *rotatingBucket) s3(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	valid, reject := b.valid, b.rejectNext
+	b.rejectNext = false
+	b.mu.Unlock()
+
+	if reject || !strings.Contains(r.Header.Get("Authorization"), "Credential="+valid+"/") {
+		w.WriteHeader(http.StatusForbidden)
+		if r.Method != http.MethodHead {
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>InvalidAccessKeyId</Code><Message>The access key does not exist</Message></Error>`)
+		}
+		return
+	}
+	if strings.Count(strings.Trim(r.URL.Path, "/"), "/") == 0 {
+		// The bucket exists
+		return
+	}
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	if r.Method != http.MethodHead {
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>Not found</Message></Error>`)
+	}
+}
+
+func (b *rotatingBucket) vault(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.vaultUp || r.Header.Get("X-Vault-Token") != "vault-token" || r.URL.Path != "/v1/aws/creds/document-service" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	b.leases++
+	fmt.Fprintf(w, `{"lease_duration": 3600, "data": {"access_key": "AK%d", "secret_key": "secret", "security_token": "token"}}`, b.leases)
+}
+
+func (b *rotatingBucket) rotate(valid string, vaultUp bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.valid = valid
+	b.vaultUp = vaultUp
+}
+
+func newRotatingStore(t *testing.T) (*rotatingBucket, *services.MinioObjectStore) {
+	bucket := &rotatingBucket{valid: "AK1", vaultUp: true}
+	s3 := httptest.NewServer(http.HandlerFunc(bucket.s3))
+	t.Cleanup(s3.Close)
+	vault := httptest.NewServer(http.HandlerFunc(bucket.vault))
+	t.Cleanup(vault.Close)
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenPath, []byte("vault-token\n"), 0o600))
+
+	store, err := services.NewS3ObjectStore(context.Background(), config.S3Config{
+		Endpoint:   strings.TrimPrefix(s3.URL, "http://"),
+		Region:     "us-east-1",
+		BucketName: "documents",
+		Credentials: config.ObjectStoreCredentialsConfig{
+			Provider:       config.CredentialsVault,
+			VaultAddress:   vault.URL,
+			VaultPath:      "aws/creds/document-service",
+			VaultTokenPath: tokenPath,
+			Timeout:        time.Second,
+			RetryInterval:  time.Millisecond,
+		},
+	}, config.HTTPTransportConfig{DialTimeout: time.Second, TLSHandshakeTimeout: time.Second})
+	assert.NoError(t, err)
+	return bucket, store
+}
+
+func TestObjectStoreRefreshesRevokedCredentials(t *testing.T) {
+	bucket, store := newRotatingStore(t)
+	ctx := context.Background()
+
+	// AK1 is revoked before it expires; the rejected request refreshes the
+	// credentials and is retried with AK2
+	bucket.rotate("AK2", true)
+	_, err := store.Stat(ctx, "documents/missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+	_, err = store.Get(ctx, "documents/missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+	assert.Equal(t, 2, bucket.leases)
+}
+
+func TestObjectStoreKeepsCredentialsWhileProviderIsDown(t *testing.T) {
+	bucket, store := newRotatingStore(t)
+	ctx := context.Background()
+
+	// A request is rejected while Vault is down; the failed refresh keeps the
+	// credentials last leased, which still work
+	bucket.rotate("AK1", false)
+	bucket.mu.Lock()
+	bucket.rejectNext = true
+	bucket.mu.Unlock()
+	assert.NoError(t, store.Warm(ctx, 1))
+	assert.NoError(t, store.Delete(ctx, "documents/missing"))
+	assert.Equal(t, 1, bucket.leases)
+
+	// Once the provider recovers, revoked credentials are replaced
+	bucket.rotate("AK2", true)
+	time.Sleep(5 * time.Millisecond)
+	_, err := store.Stat(ctx, "documents/missing")
+	assert.ErrorIs(t, err, services.ErrObjectNotFound)
+	assert.Equal(t, 2, bucket.leases)
+}
+
+func TestObjectStoreCredentialsValidation(t *testing.T) {
+	vault := config.ObjectStoreCredentialsConfig{Provider: config.CredentialsVault, Timeout: time.Second, RetryInterval: time.Second}
+	assert.Error(t, vault.Validate("", ""), "Vault needs an address, path and token")
+	vault.VaultAddress, vault.VaultPath, vault.VaultTokenPath = "https://vault:8200", "aws/creds/document-service", "/var/run/secrets/vault-token"
+	assert.NoError(t, vault.Validate("", ""))
+
+	sts := config.ObjectStoreCredentialsConfig{Provider: config.CredentialsSTS, STSEndpoint: "https://sts.amazonaws.com", Timeout: time.Second, RetryInterval: time.Second}
+	assert.Error(t, sts.Validate("", ""), "AssumeRole is signed with static keys")
+	assert.NoError(t, sts.Validate("AKIA", "secret"))
+
+	static := config.ObjectStoreCredentialsConfig{}
+	assert.NoError(t, static.Validate("AKIA", "secret"))
+	assert.Error(t, (&config.ObjectStoreCredentialsConfig{Provider: "kerberos"}).Validate("", ""))
+}
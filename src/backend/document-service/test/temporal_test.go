@@ -0,0 +1,71 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.temporal.io/sdk/activity"        // v1.25.1
+	"go.temporal.io/sdk/temporal"        // v1.25.1
+	"go.temporal.io/sdk/testsuite"       // v1.25.1
+	"go.temporal.io/sdk/workflow"        // v1.25.1
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestDocumentPipelineWorkflow(t *testing.T) {
+	input := services.DocumentWorkflowInput{
+		DocumentID:           "doc-1",
+		Steps:                []string{"ocr", "receita", "address"},
+		StepTimeout:          time.Minute,
+		RetryInitialInterval: time.Second,
+		RetryMaxInterval:     time.Second,
+		MaxAttempts:          3,
+	}
+
+	run := func(t *testing.T, step func(step string) error) ([]string, bool) {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterWorkflowWithOptions(services.DocumentPipelineWorkflow, workflow.RegisterOptions{Name: services.DocumentWorkflowName})
+
+		var ran []string
+		env.RegisterActivityWithOptions(func(ctx context.Context, documentID, name string) error {
+			ran = append(ran, name)
+			return step(name)
+		}, activity.RegisterOptions{Name: services.RunStepActivityName})
+		finished := false
+		env.RegisterActivityWithOptions(func(ctx context.Context, documentID string) error {
+			finished = true
+			return nil
+		}, activity.RegisterOptions{Name: services.FinishActivityName})
+
+		env.ExecuteWorkflow(services.DocumentWorkflowName, input)
+		assert.True(t, env.IsWorkflowCompleted())
+		assert.NoError(t, env.GetWorkflowError())
+		return ran, finished
+	}
+
+	t.Run("a failing step is retried and then skipped", func(t *testing.T) {
+		ran, finished := run(t, func(step string) error {
+			if step == "receita" {
+				return errors.New("receita unavailable")
+			}
+			return nil
+		})
+		assert.Equal(t, []string{"ocr", "receita", "receita", "receita", "address"}, ran)
+		assert.True(t, finished)
+	})
+
+	t.Run("a consent revocation stops the remaining steps", func(t *testing.T) {
+		ran, finished := run(t, func(step string) error {
+			if step == "receita" {
+				return temporal.NewNonRetryableApplicationError("consent revoked", services.ConsentRevokedErrorType, nil)
+			}
+			return nil
+		})
+		assert.Equal(t, []string{"ocr", "receita"}, ran)
+		assert.True(t, finished, "A halted document should still be finished")
+	})
+}
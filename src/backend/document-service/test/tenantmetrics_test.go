@@ -0,0 +1,45 @@
+package test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestTenantLabelsAggregateSmallTenants(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.MetricsPrivacyConfig = config.MetricsPrivacyConfig{
+		Enabled:           true,
+		MinTenantSubjects: 3,
+		MaxTenants:        1,
+		SensitiveFamilies: []string{services.MetricTenantDocumentsIngested},
+	}
+	labels := services.NewTenantLabels(cfg)
+
+	// Repeated documents of one enrollment do not make a tenant larger
+	for i := 0; i < 5; i++ {
+		labels.Observe("tenant-a", "enr-1")
+	}
+	assert.Equal(t, services.TenantLabelOther, labels.Label(services.MetricQuotaRequests, "tenant-a"))
+
+	for i := 2; i <= 3; i++ {
+		labels.Observe("tenant-a", "enr-"+strconv.Itoa(i))
+	}
+	assert.Equal(t, "tenant-a", labels.Label(services.MetricQuotaRequests, "tenant-a"))
+	assert.Equal(t, services.TenantLabelSuppressed, labels.Label(services.MetricTenantDocumentsIngested, "tenant-a"),
+		"Sensitive families are never labelled by tenant")
+
+	for i := 1; i <= 3; i++ {
+		labels.Observe("tenant-b", "enr-b"+strconv.Itoa(i))
+	}
+	assert.Equal(t, services.TenantLabelOther, labels.Label(services.MetricQuotaRequests, "tenant-b"),
+		"Tenants beyond the cap are aggregated")
+	assert.Equal(t, services.TenantLabelNone, labels.Label(services.MetricQuotaRequests, ""))
+
+	var disabled *services.TenantLabels
+	assert.Equal(t, services.TenantLabelSuppressed, disabled.Label(services.MetricQuotaRequests, "tenant-a"))
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestRedactTextMasksIdentifiers(t *testing.T) {
+	text := "Paciente: Maria Souza\nCPF 123.456.789-09, RG 12.345.678-9\n" +
+		"Nascimento 02/03/1980, tel (11) 98765-4321, maria@example.com\nCID F32.1, 3 comprimidos"
+
+	redacted := services.RedactText(text)
+	assert.Contains(t, redacted, "CPF [CPF]")
+	assert.Contains(t, redacted, "[EMAIL]")
+	assert.NotContains(t, redacted, "12.345.678")
+	assert.NotContains(t, redacted, "1980")
+	assert.NotContains(t, redacted, "98765")
+	assert.Contains(t, redacted, "Maria Souza", "Free text should be kept")
+	assert.Contains(t, redacted, "CID F32.1, 3 comprimidos", "Short numbers should be kept")
+}
+
+func TestTextAccessAuthorize(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.TextAccessConfig = config.TextAccessConfig{
+		FullTextRoles:        []string{"underwriter"},
+		PurposeDocumentTypes: []string{"medical_record"},
+		Purposes:             []string{"underwriting", "subject_request"},
+	}
+	access := services.NewTextAccess(cfg, nil)
+	medical := &models.Document{DocumentType: "medical_record"}
+	identity := &models.Document{DocumentType: "identity"}
+
+	full, err := access.Authorize(medical, "underwriter", "underwriting")
+	assert.NoError(t, err)
+	assert.True(t, full)
+
+	full, err = access.Authorize(medical, "broker", "subject_request")
+	assert.NoError(t, err)
+	assert.False(t, full, "Other roles should receive the redacted text")
+
+	_, err = access.Authorize(medical, "underwriter", "")
+	assert.ErrorIs(t, err, services.ErrAccessPurposeRequired)
+
+	_, err = access.Authorize(medical, "underwriter", "curiosity")
+	assert.ErrorIs(t, err, services.ErrInvalidAccessPurpose)
+
+	full, err = access.Authorize(identity, "underwriter", "")
+	assert.NoError(t, err, "Other document types should not require a purpose")
+	assert.True(t, full)
+}
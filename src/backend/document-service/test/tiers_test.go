@@ -0,0 +1,31 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+)
+
+func TestProcessingTierRouting(t *testing.T) {
+	tiers := config.ProcessingTiersConfig{
+		Enabled: true,
+		Tiers: []config.ProcessingTierConfig{
+			{Name: "interactive", MaxSize: 5 << 20, MaxPages: 5, Concurrency: 16, Timeout: time.Minute},
+			{Name: "standard", MaxSize: 20 << 20, MaxPages: 40, Concurrency: 8, Timeout: 5 * time.Minute},
+			{Name: "bulk", Concurrency: 2, Timeout: 30 * time.Minute},
+		},
+	}
+
+	assert.Equal(t, "interactive", tiers.TierFor(200<<10, 1).Name, "A one-page CNH")
+	assert.Equal(t, "standard", tiers.TierFor(2<<20, 12).Name, "Small but with too many pages")
+	assert.Equal(t, "bulk", tiers.TierFor(60<<20, 200).Name, "A 200-page hospital record")
+	assert.Equal(t, "interactive", tiers.TierFor(1<<20, -1).Name, "Unknown page counts route on size alone")
+	assert.True(t, tiers.CountsPages())
+
+	tiers.Tiers[0].MaxPages, tiers.Tiers[1].MaxPages = 0, 0
+	assert.False(t, tiers.CountsPages())
+	assert.Equal(t, "interactive", tiers.TierFor(200<<10, 300).Name, "Tiers without a page limit take any page count")
+}
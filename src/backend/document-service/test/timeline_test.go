@@ -0,0 +1,86 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+)
+
+// timelineEvents are the events of two documents of an enrollment, each
+// document's history oldest first as the event store returns it
+func timelineEvents() []*models.DocumentEvent {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	return []*models.DocumentEvent{
+		{DocumentID: "doc-a", Sequence: 1, Type: models.EventDocumentUploaded, Actor: "beneficiary-1", OccurredAt: start},
+		{DocumentID: "doc-a", Sequence: 2, Type: models.EventOCRCompleted, Actor: "SYSTEM", OccurredAt: start.Add(2 * time.Minute)},
+		{DocumentID: "doc-a", Sequence: 3, Type: models.EventReviewed, Actor: "reviewer-1", OccurredAt: start.Add(time.Hour)},
+		{DocumentID: "doc-b", Sequence: 1, Type: models.EventDocumentUploaded, Actor: "beneficiary-1", OccurredAt: start.Add(time.Minute)},
+		{DocumentID: "doc-b", Sequence: 2, Type: models.EventOCRCompleted, Actor: "SYSTEM", OccurredAt: start.Add(2 * time.Minute)},
+		{DocumentID: "doc-b", Sequence: 3, Type: models.EventAccessReported, Actor: "reviewer-1", OccurredAt: start.Add(2 * time.Hour)},
+	}
+}
+
+func timelineOrder(page *models.TimelinePage) []string {
+	order := make([]string, len(page.Entries))
+	for i, entry := range page.Entries {
+		order[i] = entry.DocumentID + "/" + entry.Type
+	}
+	return order
+}
+
+func TestTimelineMergesDocumentsChronologically(t *testing.T) {
+	types := map[string]string{"doc-a": "identity", "doc-b": "proof_of_address"}
+	page, err := models.NewTimeline(timelineEvents(), types, models.TimelineQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"doc-a/" + models.EventDocumentUploaded,
+		"doc-b/" + models.EventDocumentUploaded,
+		"doc-a/" + models.EventOCRCompleted,
+		"doc-b/" + models.EventOCRCompleted,
+		"doc-a/" + models.EventReviewed,
+		"doc-b/" + models.EventAccessReported,
+	}, timelineOrder(page), "Events at the same instant are ordered by document")
+	assert.Equal(t, "proof_of_address", page.Entries[1].DocumentType)
+	assert.Empty(t, page.NextCursor)
+
+	page, err = models.NewTimeline(timelineEvents(), types, models.TimelineQuery{Actor: "reviewer-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc-a/" + models.EventReviewed, "doc-b/" + models.EventAccessReported}, timelineOrder(page))
+}
+
+func TestTimelinePagesWithCursor(t *testing.T) {
+	var seen []string
+	query := models.TimelineQuery{Limit: 2}
+	for pages := 0; ; pages++ {
+		assert.Less(t, pages, 3)
+		page, err := models.NewTimeline(timelineEvents(), nil, query)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(page.Entries), 2)
+		seen = append(seen, timelineOrder(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	full, err := models.NewTimeline(timelineEvents(), nil, models.TimelineQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, timelineOrder(full), seen, "Pages neither skip nor repeat events")
+
+	// Events appended after the first page do not shift the pages after it
+	first, err := models.NewTimeline(timelineEvents(), nil, models.TimelineQuery{Limit: 3})
+	assert.NoError(t, err)
+	events := append(timelineEvents(), &models.DocumentEvent{
+		DocumentID: "doc-a", Sequence: 4, Type: models.EventHoldPlaced, Actor: "SYSTEM",
+		OccurredAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+	})
+	rest, err := models.NewTimeline(events, nil, models.TimelineQuery{Cursor: first.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, "doc-b/"+models.EventOCRCompleted, timelineOrder(rest)[0])
+	assert.Len(t, rest.Entries, 4)
+
+	_, err = models.NewTimeline(timelineEvents(), nil, models.TimelineQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, models.ErrInvalidTimelineCursor)
+}
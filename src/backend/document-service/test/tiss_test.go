@@ -0,0 +1,51 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+const tissGuideTemplate = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<ans:mensagemTISS xmlns:ans="http://www.ans.gov.br/padroes/tiss/schemas">
+	<ans:cabecalho>
+		<ans:identificacaoTransacao><ans:tipoTransacao>ENVIO_LOTE_GUIAS</ans:tipoTransacao></ans:identificacaoTransacao>
+		<ans:Padrao>%s</ans:Padrao>
+	</ans:cabecalho>
+	<ans:prestadorParaOperadora><ans:loteGuias><ans:guiasTISS><ans:guiaSP-SADT>
+		<ans:cabecalhoGuia><ans:numeroGuiaPrestador>20240001</ans:numeroGuiaPrestador></ans:cabecalhoGuia>
+		<ans:dadosExecutante><ans:contratadoExecutante>
+			<ans:codigoPrestadorNaOperadora>778899</ans:codigoPrestadorNaOperadora>
+			<ans:nomeContratado>Hospital Santa Clara</ans:nomeContratado>
+		</ans:contratadoExecutante></ans:dadosExecutante>
+		<ans:procedimentosExecutados>
+			<ans:procedimentoExecutado><ans:procedimento><ans:codigoProcedimento>40301630</ans:codigoProcedimento></ans:procedimento></ans:procedimentoExecutado>
+			<ans:procedimentoExecutado><ans:procedimento><ans:codigoProcedimento>40302040</ans:codigoProcedimento></ans:procedimento></ans:procedimentoExecutado>
+		</ans:procedimentosExecutados>
+	</ans:guiaSP-SADT></ans:guiasTISS></ans:loteGuias></ans:prestadorParaOperadora>
+</ans:mensagemTISS>`
+
+func TestParseTISS(t *testing.T) {
+	t.Run("Extracts guide fields", func(t *testing.T) {
+		guide, err := services.ParseTISS([]byte(fmt.Sprintf(tissGuideTemplate, "4.01.00")))
+		assert.NoError(t, err)
+		assert.Equal(t, "4.01.00", guide.Version)
+		assert.Equal(t, []string{"20240001"}, guide.GuideNumbers)
+		assert.Equal(t, []string{"40301630", "40302040"}, guide.ProcedureCodes)
+		assert.Equal(t, "778899", guide.ProviderCode)
+		assert.Equal(t, "Hospital Santa Clara", guide.ProviderName)
+	})
+
+	t.Run("Rejects unsupported schema version", func(t *testing.T) {
+		_, err := services.ParseTISS([]byte(fmt.Sprintf(tissGuideTemplate, "2.02.03")))
+		assert.ErrorIs(t, err, services.ErrUnsupportedTISSVersion)
+	})
+
+	t.Run("Rejects non TISS XML", func(t *testing.T) {
+		_, err := services.ParseTISS([]byte(`<invoice><number>1</number></invoice>`))
+		assert.ErrorIs(t, err, services.ErrInvalidTISS)
+	})
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus" // v1.17.0
+	"github.com/stretchr/testify/assert"             // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// connectionCounter is an endpoint recording the client connections its
+// requests arrived on
+type connectionCounter struct {
+	mu     sync.Mutex
+	remote map[string]bool
+	hold   time.Duration
+}
+
+func (c *connectionCounter) serve(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	c.remote[r.RemoteAddr] = true
+	c.mu.Unlock()
+	time.Sleep(c.hold)
+	io.WriteString(w, "ok")
+}
+
+func (c *connectionCounter) connections() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.remote)
+}
+
+func newConnectionCounter(t *testing.T, hold time.Duration) (*connectionCounter, *httptest.Server) {
+	counter := &connectionCounter{remote: make(map[string]bool), hold: hold}
+	server := httptest.NewServer(http.HandlerFunc(counter.serve))
+	t.Cleanup(server.Close)
+	return counter, server
+}
+
+func testTransportConfig() config.HTTPTransportConfig {
+	return config.HTTPTransportConfig{
+		MaxIdleConns:        8,
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     time.Minute,
+		DialTimeout:         time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: time.Second,
+	}
+}
+
+// getAndDrain reads a response to the end so its connection returns to the pool
+func getAndDrain(t *testing.T, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	if !assert.NoError(t, err) {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// connectionCount returns http_client_connections_total of the client by reuse
+func connectionCount(t *testing.T, registry *prometheus.Registry, client, reused string) float64 {
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "http_client_connections_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["client"] == client && labels["reused"] == reused {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestHTTPTransportReusesKeepAliveConnections(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, services.RegisterMetrics(registry))
+	counter, server := newConnectionCounter(t, 0)
+	client := &http.Client{Transport: services.NewHTTPTransport("transport-reuse", testTransportConfig())}
+	// The counters are shared by every transport of the process
+	dialed := connectionCount(t, registry, "transport-reuse", "false")
+	reused := connectionCount(t, registry, "transport-reuse", "true")
+
+	for i := 0; i < 5; i++ {
+		getAndDrain(t, client, server.URL)
+	}
+	assert.Equal(t, 1, counter.connections(), "Sequential requests should share one pooled connection")
+	assert.Equal(t, float64(1), connectionCount(t, registry, "transport-reuse", "false")-dialed)
+	assert.Equal(t, float64(4), connectionCount(t, registry, "transport-reuse", "true")-reused)
+}
+
+func TestHTTPTransportBoundsConnectionsPerHost(t *testing.T) {
+	counter, server := newConnectionCounter(t, 20*time.Millisecond)
+	cfg := testTransportConfig()
+	cfg.MaxConnsPerHost = 2
+	cfg.MaxIdleConnsPerHost = 2
+	client := &http.Client{Transport: services.NewHTTPTransport("transport-bounded", cfg)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getAndDrain(t, client, server.URL)
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, counter.connections(), 2, "Concurrent requests should wait for one of the host's connections")
+}
+
+func TestHTTPTransportConfigValidation(t *testing.T) {
+	cfg, err := loadTestConfig(t, "")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 64, cfg.MinioConfig.Transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 128, cfg.AzureConfig.Transport.TLSSessionCacheSize)
+	}
+
+	transport := testTransportConfig()
+	transport.MaxConnsPerHost = 2
+	assert.Error(t, transport.Validate(), "More idle than total connections per host should be rejected")
+
+	transport = testTransportConfig()
+	transport.MaxIdleConns = -1
+	assert.Error(t, transport.Validate())
+
+	transport = testTransportConfig()
+	transport.DialTimeout = 0
+	assert.Error(t, transport.Validate())
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func newTestUnderwriting(t *testing.T, baseURL string) (*services.UnderwritingService, *repository.MemoryDocumentRepository, *repository.MemoryOutboxRepository) {
+	cfg := &config.Config{}
+	cfg.UnderwritingConfig.Enabled = true
+	cfg.UnderwritingConfig.Transport = "rest"
+	cfg.UnderwritingConfig.BaseURL = baseURL
+	cfg.UnderwritingConfig.Timeout = time.Second
+	cfg.UnderwritingConfig.RequiredDocumentTypes = []string{"id_card", "proof_of_address"}
+
+	documents := repository.NewMemoryDocumentRepository()
+	outbox := repository.NewMemoryOutboxRepository()
+	underwriting, err := services.NewUnderwritingService(cfg, documents, outbox, services.NewRESTUnderwritingTransport(cfg), zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	id := &models.Document{ID: "doc-1", EnrollmentID: "enr-1", DocumentType: "id_card", Status: models.DocumentStatusCompleted}
+	assert.NoError(t, id.Review(models.ReviewDecisionApprove, "", "reviewer-1"))
+	assert.NoError(t, documents.Create(ctx, id))
+	address := &models.Document{ID: "doc-2", EnrollmentID: "enr-1", DocumentType: "proof_of_address", Status: models.DocumentStatusCompleted}
+	assert.NoError(t, documents.Create(ctx, address))
+	return underwriting, documents, outbox
+}
+
+// reviewDocument reviews a stored document and saves it through underwriting
+func reviewDocument(t *testing.T, underwriting *services.UnderwritingService, documents repository.DocumentRepository, id, decision string, save func(ctx context.Context, doc *models.Document) error) error {
+	ctx := context.Background()
+	doc, err := documents.GetByID(ctx, id)
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Review(decision, "unreadable", "reviewer-2"))
+	return underwriting.SaveReview(ctx, doc, func(ctx context.Context) error {
+		return save(ctx, doc)
+	})
+}
+
+func pendingNotifications(t *testing.T, outbox repository.OutboxRepository) []services.EnrollmentReadyNotification {
+	due, err := outbox.ListDue(context.Background(), time.Now().Add(time.Minute), 0)
+	assert.NoError(t, err)
+	notifications := make([]services.EnrollmentReadyNotification, 0, len(due))
+	for _, msg := range due {
+		var notification services.EnrollmentReadyNotification
+		assert.NoError(t, json.Unmarshal(msg.Payload, &notification))
+		assert.Equal(t, services.TopicUnderwritingReady, msg.Topic)
+		assert.Equal(t, notification.NotificationID, msg.Key)
+		notifications = append(notifications, notification)
+	}
+	return notifications
+}
+
+func TestUnderwritingNotifiedWithCompletingApproval(t *testing.T) {
+	underwriting, documents, outbox := newTestUnderwriting(t, "http://underwriting.invalid")
+	save := func(ctx context.Context, doc *models.Document) error {
+		return documents.Update(ctx, doc)
+	}
+
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionReject, save))
+	assert.Empty(t, pendingNotifications(t, outbox), "A rejection does not complete the enrollment")
+
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionApprove, save))
+	notifications := pendingNotifications(t, outbox)
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, "enr-1", notifications[0].EnrollmentID)
+		assert.Len(t, notifications[0].Documents, 2)
+	}
+
+	// Checking again reports the same approvals and queues nothing new
+	assert.NoError(t, underwriting.CheckEnrollment(context.Background(), "enr-1"))
+	assert.Len(t, pendingNotifications(t, outbox), 1)
+}
+
+func TestUnderwritingNotifiedAgainAfterReapproval(t *testing.T) {
+	underwriting, documents, outbox := newTestUnderwriting(t, "http://underwriting.invalid")
+	save := func(ctx context.Context, doc *models.Document) error {
+		return documents.Update(ctx, doc)
+	}
+
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionApprove, save))
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionReject, save))
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionApprove, save))
+
+	notifications := pendingNotifications(t, outbox)
+	if assert.Len(t, notifications, 2, "Each approval completing the enrollment is notified") {
+		assert.NotEqual(t, notifications[0].NotificationID, notifications[1].NotificationID)
+	}
+}
+
+func TestUnderwritingNotificationNotQueuedWhenApprovalFails(t *testing.T) {
+	underwriting, documents, outbox := newTestUnderwriting(t, "http://underwriting.invalid")
+	errStore := errors.New("store unavailable")
+
+	err := reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionApprove, func(ctx context.Context, doc *models.Document) error {
+		return errStore
+	})
+	assert.ErrorIs(t, err, errStore)
+	assert.Empty(t, pendingNotifications(t, outbox), "No notification is queued for an approval that was not saved")
+
+	stored, err := documents.GetByID(context.Background(), "doc-2")
+	assert.NoError(t, err)
+	assert.Equal(t, models.DocumentStatusCompleted, stored.Status)
+}
+
+func TestUnderwritingDeliverySendsNotificationID(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	underwriting, documents, outbox := newTestUnderwriting(t, server.URL)
+	assert.NoError(t, reviewDocument(t, underwriting, documents, "doc-2", models.ReviewDecisionApprove, func(ctx context.Context, doc *models.Document) error {
+		return documents.Update(ctx, doc)
+	}))
+
+	due, err := outbox.ListDue(context.Background(), time.Now().Add(time.Minute), 0)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.NoError(t, underwriting.Deliver(context.Background(), due[0]))
+		request := <-received
+		assert.Equal(t, due[0].Key, request.Header.Get("Idempotency-Key"))
+		assert.Equal(t, "/api/v1/underwriting/enrollments/enr-1/documents-ready", request.URL.Path)
+	}
+}
+
+func TestUnderwritingTransportSelection(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.UnderwritingConfig.Transport = "grpc"
+	cfg.UnderwritingConfig.GRPCTarget = "underwriting.internal:443"
+	transport, err := services.NewUnderwritingTransport(cfg)
+	assert.NoError(t, err)
+	if grpcTransport, ok := transport.(*services.GRPCUnderwritingTransport); assert.True(t, ok) {
+		assert.NoError(t, grpcTransport.Close())
+	}
+
+	cfg.UnderwritingConfig.Transport = "rest"
+	transport, err = services.NewUnderwritingTransport(cfg)
+	assert.NoError(t, err)
+	assert.IsType(t, &services.RESTUnderwritingTransport{}, transport)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// fakeClamd answers VERSION with its database version and INSTREAM with
+// FOUND for content holding the EICAR marker, counting the streams scanned
+type fakeClamd struct {
+	listener net.Listener
+	scans    atomic.Int32
+
+	mu       sync.Mutex
+	database string
+}
+
+func newFakeClamd(t *testing.T) *fakeClamd {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	clamd := &fakeClamd{listener: listener, database: "27100"}
+	t.Cleanup(func() { listener.Close() })
+	go clamd.serve()
+	return clamd
+}
+
+func (f *fakeClamd) setDatabase(version string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.database = version
+}
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+	switch command {
+	case "zVERSION\x00":
+		f.mu.Lock()
+		database := f.database
+		f.mu.Unlock()
+		io.WriteString(conn, "ClamAV 1.2.1/"+database+"/Mon Oct 12 08:00:00 2026\x00")
+	case "zINSTREAM\x00":
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+				return
+			}
+		}
+		f.scans.Add(1)
+		if strings.Contains(content.String(), "EICAR") {
+			io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+			return
+		}
+		io.WriteString(conn, "stream: OK\x00")
+	}
+}
+
+func newTestVirusScanner(t *testing.T, address string) *services.VirusScanner {
+	cfg := &config.Config{}
+	cfg.VirusScanConfig = config.VirusScanConfig{
+		Enabled:              true,
+		Address:              address,
+		Timeout:              time.Second,
+		CacheTTL:             time.Hour,
+		VersionCheckInterval: time.Nanosecond,
+	}
+	scanner, err := services.NewVirusScanner(cfg, zap.NewNop())
+	assert.NoError(t, err)
+	return scanner
+}
+
+func TestVirusScanCachesVerdictsByContentHash(t *testing.T) {
+	clamd := newFakeClamd(t)
+	scanner := newTestVirusScanner(t, clamd.listener.Addr().String())
+	ctx := context.Background()
+
+	// Larger than one chunk, so the stream is split
+	clean := bytes.Repeat([]byte("%PDF-1.7 beneficiary card "), 5000)
+	scan, err := scanner.Scan(ctx, clean)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VirusVerdictClean, scan.Verdict)
+	assert.Equal(t, "27100", scan.DefinitionsVersion)
+	assert.False(t, scan.Cached)
+
+	scan, err = scanner.Scan(ctx, clean)
+	assert.NoError(t, err)
+	assert.True(t, scan.Cached, "Identical content is not scanned again")
+	assert.Equal(t, int32(1), clamd.scans.Load())
+
+	infected := []byte("X5O!P%@AP EICAR test file")
+	for i := 0; i < 2; i++ {
+		scan, err = scanner.Scan(ctx, infected)
+		assert.NoError(t, err)
+		assert.True(t, scan.Infected())
+		assert.Equal(t, "Eicar-Test-Signature", scan.Signature)
+	}
+	assert.Equal(t, int32(2), clamd.scans.Load(), "Infected verdicts are cached as well")
+
+	clamd.setDatabase("27101")
+	scan, err = scanner.Scan(ctx, clean)
+	assert.NoError(t, err)
+	assert.False(t, scan.Cached, "New definitions drop every cached verdict")
+	assert.Equal(t, "27101", scan.DefinitionsVersion)
+	assert.Equal(t, int32(3), clamd.scans.Load())
+}
+
+func TestVirusScannerUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner := newTestVirusScanner(t, address)
+	_, err = scanner.Scan(context.Background(), []byte("%PDF-1.7"))
+	assert.ErrorIs(t, err, services.ErrScannerUnavailable)
+	assert.ErrorIs(t, scanner.Ping(context.Background()), services.ErrScannerUnavailable)
+}
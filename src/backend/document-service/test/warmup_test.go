@@ -0,0 +1,62 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestWarmupFailsStuckCheckAtTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.StartupConfig.CheckTimeout = 50 * time.Millisecond
+	warmup, err := services.NewWarmup(cfg, zap.NewNop())
+	assert.NoError(t, err)
+
+	warmup.Add("stuck", true, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	start := time.Now()
+	err = warmup.Run(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.False(t, warmup.Ready())
+	assert.Equal(t, services.WarmupFailed, warmup.Progress().Checks[0].Status)
+}
+
+func TestObjectStoreStopsWaitingForStuckBucket(t *testing.T) {
+	// The bucket endpoint accepts connections but never answers
+	release := make(chan struct{})
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(bucket.Close)
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := services.NewS3ObjectStore(ctx, config.S3Config{
+		Endpoint:   strings.TrimPrefix(bucket.URL, "http://"),
+		Region:     "us-east-1",
+		BucketName: "documents",
+		AccessKey:  "AK1",
+		SecretKey:  "secret",
+	}, config.HTTPTransportConfig{DialTimeout: time.Second, TLSHandshakeTimeout: time.Second})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second, "The bucket check should end with ctx")
+}
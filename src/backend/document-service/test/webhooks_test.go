@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/repository"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+	at := time.Unix(1700000000, 0)
+
+	header := services.SignWebhook([]string{"whsec_new", "whsec_old"}, at, body)
+	parts := strings.Split(header, ",")
+	assert.Equal(t, []string{"t=1700000000"}, parts[:1])
+	assert.Len(t, parts, 3, "Deliveries carry one signature per valid secret")
+
+	// Receivers verify with their secret over "<t>.<body>"
+	mac := hmac.New(sha256.New, []byte("whsec_old"))
+	mac.Write([]byte("1700000000." + string(body)))
+	assert.Contains(t, parts, "v1="+hex.EncodeToString(mac.Sum(nil)))
+
+	assert.NotEqual(t, header, services.SignWebhook([]string{"whsec_new", "whsec_old"}, at, []byte(`{"id":"event-2"}`)))
+}
+
+func TestWebhookSubscriptionLifecycle(t *testing.T) {
+	now := time.Now()
+	subscription := &models.WebhookSubscription{
+		Secret: "whsec_first",
+		Events: []string{models.WebhookEventDocumentReviewed},
+	}
+	assert.True(t, subscription.Subscribed(models.WebhookEventDocumentReviewed))
+	assert.False(t, subscription.Subscribed(models.WebhookEventDocumentProcessed))
+
+	subscription.Pause(now)
+	assert.False(t, subscription.Subscribed(models.WebhookEventDocumentReviewed), "Paused subscriptions receive nothing")
+	subscription.Resume(now)
+	assert.True(t, subscription.Subscribed(models.WebhookEventDocumentReviewed))
+
+	subscription.RotateSecret("whsec_second", now, time.Hour)
+	assert.Equal(t, []string{"whsec_second", "whsec_first"}, subscription.Secrets(now))
+	assert.Equal(t, []string{"whsec_second"}, subscription.Secrets(now.Add(2*time.Hour)), "The previous secret expires after the grace period")
+
+	encoded, err := json.Marshal(subscription)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), "whsec_", "Secrets are never serialized")
+
+	assert.ErrorIs(t, models.ValidateWebhookEvents(nil), models.ErrNoWebhookEvents)
+	assert.ErrorIs(t, models.ValidateWebhookEvents([]string{models.WebhookEventTest}), models.ErrUnknownWebhookEvent)
+	assert.NoError(t, models.ValidateWebhookEvents(models.WebhookEvents))
+}
+
+func TestWebhookDeliveryHistory(t *testing.T) {
+	ctx := context.Background()
+	webhooks := repository.NewMemoryWebhookRepository()
+	assert.NoError(t, webhooks.Create(ctx, &models.WebhookSubscription{ID: "webhook-1", TenantID: "tenant-a", CreatedAt: time.Now()}))
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		assert.NoError(t, webhooks.AddDelivery(ctx, &models.WebhookDelivery{ID: "delivery", SubscriptionID: "webhook-1", Attempt: attempt}, 3))
+	}
+	deliveries, err := webhooks.ListDeliveries(ctx, "webhook-1")
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 3, "Only the most recent deliveries are kept")
+	assert.Equal(t, 5, deliveries[0].Attempt)
+
+	assert.NoError(t, webhooks.Delete(ctx, "webhook-1"))
+	_, err = webhooks.ListDeliveries(ctx, "webhook-1")
+	assert.ErrorIs(t, err, repository.ErrWebhookNotFound)
+	assert.ErrorIs(t, webhooks.AddDelivery(ctx, &models.WebhookDelivery{SubscriptionID: "webhook-1"}, 3), repository.ErrWebhookNotFound)
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert" // v1.8.4
+	"go.uber.org/zap"                    // v1.26.0
+
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/config"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/models"
+	"github.com/rodaquino-OMNI/onboarding-portal-v3-hrqnmc/src/backend/document-service/internal/services"
+)
+
+// testGraphAPI stands in for the WhatsApp Cloud API, serving media-1 with
+// content and recording the templates replied with
+type testGraphAPI struct {
+	mu        sync.Mutex
+	templates []string
+}
+
+func newTestGraphAPI(t *testing.T, content []byte) (*testGraphAPI, string) {
+	api := &testGraphAPI{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/media-1":
+			json.NewEncoder(w).Encode(map[string]string{"url": server.URL + "/download/media-1", "mime_type": "application/pdf"})
+		case "/download/media-1":
+			// Streamed without a length, as a body is read until it ends
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			w.Write(content)
+		case "/phone-1/messages":
+			var payload struct {
+				Template struct {
+					Name string `json:"name"`
+				} `json:"template"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			api.mu.Lock()
+			api.templates = append(api.templates, payload.Template.Name)
+			api.mu.Unlock()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return api, server.URL
+}
+
+func newTestWhatsApp(t *testing.T, beneficiaryPhone string, content []byte, ingester services.DocumentIngester) (*services.WhatsAppService, *testGraphAPI) {
+	api, apiURL := newTestGraphAPI(t, content)
+	enrollments := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(models.Enrollment{ID: "enr-1", BeneficiaryPhone: beneficiaryPhone})
+	}))
+	t.Cleanup(enrollments.Close)
+
+	cfg := &config.Config{}
+	cfg.EnrollmentConfig.BaseURL = enrollments.URL
+	cfg.EnrollmentConfig.Timeout = time.Second
+	cfg.ServiceConfig.MaxFileSize = 1 << 20
+	cfg.ServiceConfig.Channels.WhatsApp.MaxFileSize = 64
+	cfg.WhatsAppConfig.APIBaseURL = apiURL
+	cfg.WhatsAppConfig.PhoneNumberID = "phone-1"
+	cfg.WhatsAppConfig.MediaTimeout = time.Second
+	cfg.WhatsAppConfig.DefaultDocumentType = "identity"
+	cfg.WhatsAppConfig.StatusTemplates = map[string]string{"received": "received", "rejected": "rejected", "failed": "failed"}
+
+	client, err := services.NewEnrollmentClient(cfg)
+	assert.NoError(t, err)
+	whatsapp, err := services.NewWhatsAppService(cfg, ingester, client, zap.NewNop())
+	assert.NoError(t, err)
+	return whatsapp, api
+}
+
+func whatsappMedia(from string) services.WhatsAppMediaMessage {
+	return services.WhatsAppMediaMessage{MessageID: "wamid-1", From: from, MediaID: "media-1", Caption: "enr-1"}
+}
+
+func TestWhatsAppAcceptsSenderWithoutNinthDigit(t *testing.T) {
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	whatsapp, api := newTestWhatsApp(t, "(31) 99999-8888", []byte("%PDF-1.4"), ingester)
+
+	// WhatsApp reports the mobile as 55 31 9999-8888
+	assert.NoError(t, whatsapp.HandleMediaMessage(context.Background(), whatsappMedia("553199998888")))
+	assert.Len(t, ingester.ingested, 1)
+	assert.Equal(t, []string{"received"}, api.templates)
+}
+
+func TestWhatsAppLandlineIsNotGivenNinthDigit(t *testing.T) {
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	whatsapp, api := newTestWhatsApp(t, "(31) 93333-4444", []byte("%PDF-1.4"), ingester)
+
+	err := whatsapp.HandleMediaMessage(context.Background(), whatsappMedia("553133334444"))
+	assert.ErrorIs(t, err, services.ErrWhatsAppSenderMismatch)
+	assert.Empty(t, ingester.ingested)
+	assert.Equal(t, []string{"rejected"}, api.templates)
+}
+
+func TestWhatsAppRefusesMediaPastChannelSize(t *testing.T) {
+	ingester := &recordingIngester{ingested: make(map[string]int)}
+	whatsapp, api := newTestWhatsApp(t, "+55 31 99999-8888", []byte(strings.Repeat("x", 65)), ingester)
+
+	err := whatsapp.HandleMediaMessage(context.Background(), whatsappMedia("5531999998888"))
+	assert.ErrorIs(t, err, models.ErrInvalidSize)
+	assert.Empty(t, ingester.ingested, "Oversized media is not ingested")
+	assert.Equal(t, []string{"rejected"}, api.templates)
+}